@@ -0,0 +1,413 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/olympus/v1/olympus.proto
+
+package olympusv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Olympus_Submit_FullMethodName          = "/tartarus.olympus.v1.Olympus/Submit"
+	Olympus_Get_FullMethodName             = "/tartarus.olympus.v1.Olympus/Get"
+	Olympus_List_FullMethodName            = "/tartarus.olympus.v1.Olympus/List"
+	Olympus_Kill_FullMethodName            = "/tartarus.olympus.v1.Olympus/Kill"
+	Olympus_Snapshot_FullMethodName        = "/tartarus.olympus.v1.Olympus/Snapshot"
+	Olympus_StreamLogs_FullMethodName      = "/tartarus.olympus.v1.Olympus/StreamLogs"
+	Olympus_Exec_FullMethodName            = "/tartarus.olympus.v1.Olympus/Exec"
+	Olympus_ExecInteractive_FullMethodName = "/tartarus.olympus.v1.Olympus/ExecInteractive"
+)
+
+// OlympusClient is the client API for Olympus service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Olympus is the sandbox control plane. Every RPC is also served as REST by
+// grpc-gateway on the path the HTTP API uses for it.
+type OlympusClient interface {
+	// Submit queues a sandbox request.
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error)
+	// Get returns a sandbox run.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Sandbox, error)
+	// List returns sandbox runs, optionally of one submitter or tenant.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Kill stops a running sandbox.
+	Kill(ctx context.Context, in *KillRequest, opts ...grpc.CallOption) (*KillResponse, error)
+	// Snapshot asks the node running a sandbox to snapshot it.
+	Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error)
+	// StreamLogs streams the logs of a sandbox, until it ends if follow is
+	// set.
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error)
+	// Exec runs a command in a sandbox without waiting for it.
+	Exec(ctx context.Context, in *ExecRequest, opts ...grpc.CallOption) (*ExecResponse, error)
+	// ExecInteractive runs a command in a running sandbox, relaying its
+	// input and output. The first message must be start. The last response
+	// is exit; cancelling the call kills the command.
+	ExecInteractive(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecInput, ExecOutput], error)
+}
+
+type olympusClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOlympusClient(cc grpc.ClientConnInterface) OlympusClient {
+	return &olympusClient{cc}
+}
+
+func (c *olympusClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitResponse)
+	err := c.cc.Invoke(ctx, Olympus_Submit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *olympusClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Sandbox, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sandbox)
+	err := c.cc.Invoke(ctx, Olympus_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *olympusClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, Olympus_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *olympusClient) Kill(ctx context.Context, in *KillRequest, opts ...grpc.CallOption) (*KillResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KillResponse)
+	err := c.cc.Invoke(ctx, Olympus_Kill_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *olympusClient) Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SnapshotResponse)
+	err := c.cc.Invoke(ctx, Olympus_Snapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *olympusClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Olympus_ServiceDesc.Streams[0], Olympus_StreamLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamLogsRequest, LogChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Olympus_StreamLogsClient = grpc.ServerStreamingClient[LogChunk]
+
+func (c *olympusClient) Exec(ctx context.Context, in *ExecRequest, opts ...grpc.CallOption) (*ExecResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecResponse)
+	err := c.cc.Invoke(ctx, Olympus_Exec_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *olympusClient) ExecInteractive(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecInput, ExecOutput], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Olympus_ServiceDesc.Streams[1], Olympus_ExecInteractive_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExecInput, ExecOutput]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Olympus_ExecInteractiveClient = grpc.BidiStreamingClient[ExecInput, ExecOutput]
+
+// OlympusServer is the server API for Olympus service.
+// All implementations must embed UnimplementedOlympusServer
+// for forward compatibility.
+//
+// Olympus is the sandbox control plane. Every RPC is also served as REST by
+// grpc-gateway on the path the HTTP API uses for it.
+type OlympusServer interface {
+	// Submit queues a sandbox request.
+	Submit(context.Context, *SubmitRequest) (*SubmitResponse, error)
+	// Get returns a sandbox run.
+	Get(context.Context, *GetRequest) (*Sandbox, error)
+	// List returns sandbox runs, optionally of one submitter or tenant.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Kill stops a running sandbox.
+	Kill(context.Context, *KillRequest) (*KillResponse, error)
+	// Snapshot asks the node running a sandbox to snapshot it.
+	Snapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error)
+	// StreamLogs streams the logs of a sandbox, until it ends if follow is
+	// set.
+	StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogChunk]) error
+	// Exec runs a command in a sandbox without waiting for it.
+	Exec(context.Context, *ExecRequest) (*ExecResponse, error)
+	// ExecInteractive runs a command in a running sandbox, relaying its
+	// input and output. The first message must be start. The last response
+	// is exit; cancelling the call kills the command.
+	ExecInteractive(grpc.BidiStreamingServer[ExecInput, ExecOutput]) error
+	mustEmbedUnimplementedOlympusServer()
+}
+
+// UnimplementedOlympusServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOlympusServer struct{}
+
+func (UnimplementedOlympusServer) Submit(context.Context, *SubmitRequest) (*SubmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedOlympusServer) Get(context.Context, *GetRequest) (*Sandbox, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedOlympusServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedOlympusServer) Kill(context.Context, *KillRequest) (*KillResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Kill not implemented")
+}
+func (UnimplementedOlympusServer) Snapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Snapshot not implemented")
+}
+func (UnimplementedOlympusServer) StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedOlympusServer) Exec(context.Context, *ExecRequest) (*ExecResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exec not implemented")
+}
+func (UnimplementedOlympusServer) ExecInteractive(grpc.BidiStreamingServer[ExecInput, ExecOutput]) error {
+	return status.Errorf(codes.Unimplemented, "method ExecInteractive not implemented")
+}
+func (UnimplementedOlympusServer) mustEmbedUnimplementedOlympusServer() {}
+func (UnimplementedOlympusServer) testEmbeddedByValue()                 {}
+
+// UnsafeOlympusServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OlympusServer will
+// result in compilation errors.
+type UnsafeOlympusServer interface {
+	mustEmbedUnimplementedOlympusServer()
+}
+
+func RegisterOlympusServer(s grpc.ServiceRegistrar, srv OlympusServer) {
+	// If the following call pancis, it indicates UnimplementedOlympusServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Olympus_ServiceDesc, srv)
+}
+
+func _Olympus_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OlympusServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Olympus_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OlympusServer).Submit(ctx, req.(*SubmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Olympus_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OlympusServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Olympus_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OlympusServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Olympus_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OlympusServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Olympus_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OlympusServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Olympus_Kill_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KillRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OlympusServer).Kill(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Olympus_Kill_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OlympusServer).Kill(ctx, req.(*KillRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Olympus_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OlympusServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Olympus_Snapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OlympusServer).Snapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Olympus_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OlympusServer).StreamLogs(m, &grpc.GenericServerStream[StreamLogsRequest, LogChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Olympus_StreamLogsServer = grpc.ServerStreamingServer[LogChunk]
+
+func _Olympus_Exec_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OlympusServer).Exec(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Olympus_Exec_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OlympusServer).Exec(ctx, req.(*ExecRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Olympus_ExecInteractive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OlympusServer).ExecInteractive(&grpc.GenericServerStream[ExecInput, ExecOutput]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Olympus_ExecInteractiveServer = grpc.BidiStreamingServer[ExecInput, ExecOutput]
+
+// Olympus_ServiceDesc is the grpc.ServiceDesc for Olympus service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Olympus_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tartarus.olympus.v1.Olympus",
+	HandlerType: (*OlympusServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Submit",
+			Handler:    _Olympus_Submit_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _Olympus_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Olympus_List_Handler,
+		},
+		{
+			MethodName: "Kill",
+			Handler:    _Olympus_Kill_Handler,
+		},
+		{
+			MethodName: "Snapshot",
+			Handler:    _Olympus_Snapshot_Handler,
+		},
+		{
+			MethodName: "Exec",
+			Handler:    _Olympus_Exec_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _Olympus_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExecInteractive",
+			Handler:       _Olympus_ExecInteractive_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/olympus/v1/olympus.proto",
+}
@@ -9,10 +9,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
 	"github.com/tartarus-sandbox/tartarus/pkg/charon"
 	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
@@ -44,7 +46,7 @@ func main() {
 	}
 
 	// Initialize metrics
-	metrics := hermes.NewPrometheusMetrics()
+	metrics := hermes.NewCardinalityGuard(hermes.NewPrometheusMetrics(), hermes.CardinalityConfig{})
 	config.Ferry.Metrics = metrics
 
 	// Create ferry
@@ -70,6 +72,9 @@ func main() {
 	ferry.Start(ctx)
 	slog.Info("Ferry started, health checking enabled")
 
+	// Shores, rate limits and retry settings are reloaded without a restart
+	go watchConfig(ctx, *configFile, *listenAddr, config.ListenAddr, ferry, metrics)
+
 	// Create HTTP server
 	mux := http.NewServeMux()
 
@@ -81,7 +86,7 @@ func main() {
 	mux.Handle("/metrics", promhttp.Handler())
 
 	// Proxy all other requests
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	var proxy http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp, err := ferry.Cross(r.Context(), r)
 		if err != nil {
 			httpErr := charon.ToHTTPError(err)
@@ -102,6 +107,27 @@ func main() {
 			// Body is already written by the reverse proxy
 		}
 	})
+	if keyID := os.Getenv("CHARON_IDENTITY_KEY_ID"); keyID != "" {
+		propagator, err := newIdentityPropagator(ctx, keyID)
+		if err != nil {
+			slog.Error("Failed to configure identity propagation", "error", err)
+			os.Exit(1)
+		}
+		proxy = propagator.Handler(proxy)
+		slog.Info("Enabled edge authentication with signed identity propagation", "key_id", keyID)
+	}
+	shield, err := newShield(metrics)
+	if err != nil {
+		slog.Error("Failed to configure shield", "error", err)
+		os.Exit(1)
+	}
+	if shield != nil {
+		// Outside edge authentication, so its 401s greylist the client
+		proxy = shield.Handler(proxy)
+	}
+	// Outermost, so requests rejected at the edge are traced too
+	proxy = charon.NewTraceMiddleware(logger).Handler(proxy)
+	mux.Handle("/", proxy)
 
 	server := &http.Server{
 		Addr:    config.ListenAddr,
@@ -139,6 +165,179 @@ func main() {
 	slog.Info("Charon proxy stopped")
 }
 
+// newIdentityPropagator authenticates clients at the edge with the same API
+// key, signed API key and OIDC settings as Olympus, and signs the verified
+// identity with the key Olympus trusts via TRUSTED_PROXY_KEY_ID.
+func newIdentityPropagator(ctx context.Context, keyID string) (*charon.IdentityPropagator, error) {
+	secretProviders := []cerberus.SecretProvider{cerberus.NewEnvSecretProvider()}
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" {
+		secretProviders = append(secretProviders, cerberus.NewRealVaultSecretProvider(cerberus.VaultConfig{
+			Address:   addr,
+			Token:     os.Getenv("VAULT_TOKEN"),
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+			Timeout:   10 * time.Second,
+		}))
+	}
+	secrets := cerberus.NewCompositeSecretProvider(secretProviders...)
+
+	var authenticators []cerberus.Authenticator
+	if apiKey := os.Getenv("TARTARUS_API_KEY"); apiKey != "" {
+		authenticators = append(authenticators, cerberus.NewSimpleAPIKeyAuthenticator(apiKey))
+	}
+	authenticators = append(authenticators, cerberus.NewSignedAPIKeyAuthenticator(secrets))
+	if issuer, clientID := os.Getenv("OIDC_ISSUER_URL"), os.Getenv("OIDC_CLIENT_ID"); issuer != "" && clientID != "" {
+		oidcAuth, err := cerberus.NewOIDCAuthenticator(ctx, issuer, clientID, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize OIDC authenticator: %w", err)
+		}
+		authenticators = append(authenticators, oidcAuth)
+	}
+
+	identityCfg := cerberus.ProxyIdentityConfig{KeyID: keyID}
+	if ttl := os.Getenv("CHARON_IDENTITY_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHARON_IDENTITY_TTL: %w", err)
+		}
+		identityCfg.TTL = d
+	}
+
+	return charon.NewIdentityPropagator(
+		cerberus.NewMultiAuthenticator(authenticators...),
+		cerberus.NewBearerTokenExtractor(),
+		cerberus.NewProxyIdentitySigner(secrets, identityCfg),
+	), nil
+}
+
+// newShield configures abuse mitigation from the environment, or returns
+// nil if none is configured. The block list and ASN table files are
+// reloaded on SIGHUP.
+func newShield(metrics hermes.Metrics) (*charon.Shield, error) {
+	var config charon.ShieldConfig
+	for env, dst := range map[string]*int{
+		"CHARON_SHIELD_MAX_CONNS_PER_IP":   &config.MaxConnsPerIP,
+		"CHARON_SHIELD_GREYLIST_THRESHOLD": &config.GreylistThreshold,
+	} {
+		if v := os.Getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", env, err)
+			}
+			*dst = n
+		}
+	}
+	for env, dst := range map[string]*time.Duration{
+		"CHARON_SHIELD_GREYLIST_DELAY":     &config.GreylistDelay,
+		"CHARON_SHIELD_GREYLIST_MAX_DELAY": &config.GreylistMaxDelay,
+		"CHARON_SHIELD_GREYLIST_TTL":       &config.GreylistTTL,
+	} {
+		if v := os.Getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", env, err)
+			}
+			*dst = d
+		}
+	}
+	config.TrustForwardedFor = os.Getenv("CHARON_SHIELD_TRUST_FORWARDED_FOR") == "true"
+
+	blockListPath := os.Getenv("CHARON_SHIELD_BLOCKLIST")
+	asnPath := os.Getenv("CHARON_SHIELD_ASN_TABLE")
+	if !config.Enabled() && blockListPath == "" {
+		return nil, nil
+	}
+
+	shield := charon.NewShield(config, metrics)
+	if err := shield.LoadFiles(blockListPath, asnPath); err != nil {
+		return nil, err
+	}
+	slog.Info("Enabled shield",
+		"max_conns_per_ip", config.MaxConnsPerIP,
+		"greylist_threshold", config.GreylistThreshold,
+		"blocklist", blockListPath,
+		"asn_table", asnPath,
+	)
+
+	if blockListPath != "" {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				// A bad file keeps the previous lists in force
+				if err := shield.LoadFiles(blockListPath, asnPath); err != nil {
+					slog.Error("Failed to reload shield block list", "error", err)
+					continue
+				}
+				slog.Info("Reloaded shield block list", "blocklist", blockListPath, "asn_table", asnPath)
+			}
+		}()
+	}
+	return shield, nil
+}
+
+// watchConfig reloads the configuration file into the ferry on SIGHUP, and
+// when the file changes if CHARON_CONFIG_WATCH_INTERVAL is set (e.g. "5s").
+// A file that fails to load or apply leaves the running configuration in
+// force. The listen address cannot change without a restart.
+func watchConfig(ctx context.Context, path, listenAddr, listening string, ferry *charon.BoatFerry, metrics hermes.Metrics) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var poll <-chan time.Time
+	if v := os.Getenv("CHARON_CONFIG_WATCH_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			slog.Error("Invalid CHARON_CONFIG_WATCH_INTERVAL; watching the config file is disabled", "value", v)
+		} else {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+	}
+
+	modTime := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+	lastMod := modTime()
+
+	reload := func(reason string) {
+		config, err := loadConfig(path, listenAddr)
+		if err != nil {
+			slog.Error("Failed to reload configuration", "reason", reason, "error", err)
+			return
+		}
+		if config.ListenAddr != listening {
+			slog.Warn("Listen address changes need a restart", "listen_addr", config.ListenAddr)
+		}
+		config.Ferry.Metrics = metrics
+		if err := ferry.Reload(config.Ferry, config.Shores); err != nil {
+			slog.Error("Failed to apply reloaded configuration", "reason", reason, "error", err)
+			return
+		}
+		slog.Info("Reloaded configuration", "reason", reason, "shores", ferry.Shores())
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			lastMod = modTime()
+			reload("sighup")
+		case <-poll:
+			if mod := modTime(); !mod.IsZero() && !mod.Equal(lastMod) {
+				lastMod = mod
+				reload("file_changed")
+			}
+		}
+	}
+}
+
 // loadConfig loads configuration from file or uses defaults.
 func loadConfig(configFile, listenAddr string) (*Config, error) {
 	// Try to load from file
@@ -152,6 +351,12 @@ func loadConfig(configFile, listenAddr string) (*Config, error) {
 		if err := json.Unmarshal(data, &config); err != nil {
 			return nil, fmt.Errorf("failed to parse config file: %w", err)
 		}
+		if config.Ferry == nil {
+			config.Ferry = charon.DefaultFerryConfig()
+		}
+		if config.ListenAddr == "" {
+			config.ListenAddr = listenAddr
+		}
 
 		return &config, nil
 	}
@@ -6,11 +6,16 @@ import (
 	"log/slog"
 	"net/netip"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	goruntime "runtime"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/mem"
@@ -61,29 +66,61 @@ func main() {
 	}
 
 	var registry hades.Registry
-	if cfg.RedisAddress != "" {
+	if cfg.HadesPostgresDSN != "" {
+		r, err := hades.NewPostgresRegistry(cfg.HadesPostgresDSN)
+		if err != nil {
+			logger.Error("Failed to initialize Postgres registry", "error", err)
+			os.Exit(1)
+		}
+		registry = r.WithMetrics(metrics)
+		logger.Info("Using Postgres registry")
+	} else if cfg.RedisAddress != "" {
 		r, err := hades.NewRedisRegistry(cfg.RedisAddress, cfg.RedisDB, cfg.RedisPass)
 		if err != nil {
 			logger.Error("Failed to initialize Redis registry", "error", err)
 			os.Exit(1)
 		}
-		registry = r
+		registry = r.WithMetrics(metrics)
 		logger.Info("Using Redis registry", "addr", cfg.RedisAddress)
 	} else {
-		registry = hades.NewMemoryRegistry()
+		registry = hades.NewMemoryRegistry().WithMetrics(metrics)
 		logger.Info("Using in-memory registry")
 	}
 
 	// Erebus Store
 	var store erebus.Store
-	if cfg.S3Endpoint != "" || cfg.S3Region != "" {
-		s3Store, err := erebus.NewS3Store(context.Background(), cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.SnapshotPath)
+	if cfg.OfflineMode {
+		// Air-gapped nodes only read the seed bundle copied to local disk
+		localStore, err := erebus.NewLocalStore(cfg.SnapshotPath)
+		if err != nil {
+			logger.Error("Failed to initialize local store", "error", err)
+			os.Exit(1)
+		}
+		store = localStore
+		logger.Info("Offline mode: using local seed store", "path", cfg.SnapshotPath)
+	} else if cfg.S3Endpoint != "" || cfg.S3Region != "" {
+		// In tiered mode the local tier is the cache, so S3 streams downloads
+		s3Cache := cfg.SnapshotPath
+		if cfg.StoreTiered {
+			s3Cache = ""
+		}
+		s3Store, err := erebus.NewS3Store(context.Background(), cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, s3Cache)
 		if err != nil {
 			logger.Error("Failed to initialize S3 store", "error", err)
 			os.Exit(1)
 		}
 		store = s3Store
-		logger.Info("Using S3 store", "bucket", cfg.S3Bucket)
+		if cfg.StoreTiered {
+			localStore, err := erebus.NewLocalStore(cfg.SnapshotPath)
+			if err != nil {
+				logger.Error("Failed to initialize local store", "error", err)
+				os.Exit(1)
+			}
+			store = erebus.NewTieredStore(localStore, s3Store, metrics, hermes.NewSlogAdapter())
+			logger.Info("Using tiered local and S3 store", "path", cfg.SnapshotPath, "bucket", cfg.S3Bucket)
+		} else {
+			logger.Info("Using S3 store", "bucket", cfg.S3Bucket)
+		}
 	} else {
 		localStore, err := erebus.NewLocalStore(cfg.SnapshotPath)
 		if err != nil {
@@ -134,17 +171,70 @@ func main() {
 
 	compositeSecrets := cerberus.NewCompositeSecretProvider(secretProviders...)
 
+	// Node capacity, for the cgroup slices and batch preemption; swap is
+	// disk for microVM swap disks and host swap for cgroup runtimes
+	nodeCapacity := domain.ResourceCapacity{Swap: domain.Megabytes(cfg.SandboxSwapMB)}
+	if vmStat, err := mem.VirtualMemory(); err == nil {
+		nodeCapacity.Mem = domain.Megabytes(vmStat.Total / 1024 / 1024)
+	}
+	if cpuCount, err := cpu.Counts(true); err == nil {
+		nodeCapacity.CPU = domain.MilliCPU(cpuCount * 1000)
+	}
+
+	nodeTaints, err := domain.ParseTaints(cfg.NodeTaints)
+	if err != nil {
+		logger.Error("Invalid NODE_TAINTS", "error", err)
+		os.Exit(1)
+	}
+
+	// Cgroup slices: the agent, firecracker and runsc run in separate
+	// cgroups, and sandboxes can never use the host reserve
+	var cgroupSlices *hecatoncheir.CgroupSlices
+	if cfg.CgroupRoot != "" {
+		cgroupSlices = hecatoncheir.NewCgroupSlices(hecatoncheir.CgroupConfig{
+			Root:            cfg.CgroupRoot,
+			HostReserveCPU:  domain.MilliCPU(cfg.CgroupHostReserveCPU),
+			HostReserveMem:  domain.Megabytes(cfg.CgroupHostReserveMem),
+			AgentReserveMem: domain.Megabytes(cfg.CgroupAgentReserveMem),
+		}, nodeCapacity)
+		if err := cgroupSlices.Setup(os.Getpid()); err != nil {
+			logger.Warn("Failed to set up cgroup slices, running without them", "root", cfg.CgroupRoot, "error", err)
+			cgroupSlices = nil
+		} else {
+			logger.Info("Running in cgroup slices", "root", cfg.CgroupRoot, "host_reserve_cpu", cfg.CgroupHostReserveCPU, "host_reserve_mem_mb", cfg.CgroupHostReserveMem)
+		}
+	}
+
 	// Firecracker Runtime
-	fcKernel := os.Getenv("FC_KERNEL_IMAGE")
-	fcRootFS := os.Getenv("FC_ROOTFS_BASE")
+	// Per-architecture images (e.g. FC_KERNEL_IMAGE_ARM64) take precedence
+	archSuffix := "_" + strings.ToUpper(goruntime.GOARCH)
+	fcKernel := os.Getenv("FC_KERNEL_IMAGE" + archSuffix)
+	if fcKernel == "" {
+		fcKernel = os.Getenv("FC_KERNEL_IMAGE")
+	}
+	fcRootFS := os.Getenv("FC_ROOTFS_BASE" + archSuffix)
+	if fcRootFS == "" {
+		fcRootFS = os.Getenv("FC_ROOTFS_BASE")
+	}
 	fcSocketDir := os.Getenv("FC_SOCKET_DIR")
 	if fcSocketDir == "" {
 		fcSocketDir = "/run/firecracker"
 	}
 
 	if fcKernel != "" && fcRootFS != "" {
-		logger.Info("Initializing Firecracker Runtime", "kernel", fcKernel, "rootfs", fcRootFS)
-		firecrackerRuntime = tartarus.NewFirecrackerRuntime(logger, fcSocketDir, fcKernel, fcRootFS, compositeSecrets)
+		logger.Info("Initializing Firecracker Runtime", "kernel", fcKernel, "rootfs", fcRootFS, "arch", goruntime.GOARCH)
+		fr := tartarus.NewFirecrackerRuntime(logger, fcSocketDir, fcKernel, fcRootFS, compositeSecrets)
+		if cgroupSlices != nil {
+			fr.Cgroup = cgroupSlices.Path(hecatoncheir.SliceFirecracker)
+		}
+		if cfg.GuestAgent {
+			fr.GuestAgent = &tartarus.GuestAgentConfig{
+				Port:         uint32(cfg.GuestAgentPort),
+				ReadyTimeout: time.Duration(cfg.GuestAgentReadyTimeout) * time.Second,
+			}
+			logger.Info("Reaching Firecracker guests through the guest agent", "port", cfg.GuestAgentPort)
+		}
+		firecrackerRuntime = fr
 	} else {
 		logger.Warn("Firecracker config missing, using Mock Runtime for microVM")
 		firecrackerRuntime = tartarus.NewMockRuntime(logger)
@@ -157,7 +247,10 @@ func main() {
 			wasmWorkDir = "/var/run/tartarus/wasm"
 		}
 		logger.Info("Initializing WASM Runtime", "engine", cfg.WasmEngine, "workdir", wasmWorkDir)
-		wasmRuntime = tartarus.NewWasmRuntime(logger, wasmWorkDir)
+		wr := tartarus.NewWasmRuntime(logger, wasmWorkDir)
+		wr.TailBytes = cfg.ResultTailBytes
+		wr.Metrics = metrics
+		wasmRuntime = wr
 	}
 
 	// gVisor Runtime
@@ -167,10 +260,16 @@ func main() {
 			gvisorRootDir = "/var/run/gvisor"
 		}
 		logger.Info("Initializing gVisor Runtime", "runsc", cfg.GVisorRunscPath, "rootdir", gvisorRootDir)
-		gvisorRuntime = tartarus.NewGVisorRuntime(logger, cfg.GVisorRunscPath, gvisorRootDir)
+		gr := tartarus.NewGVisorRuntime(logger, cfg.GVisorRunscPath, gvisorRootDir)
+		if cgroupSlices != nil {
+			gr.Cgroup = cgroupSlices.Path(hecatoncheir.SliceGVisor)
+		}
+		gvisorRuntime = gr
 	}
 
-	// Select runtime based on configuration
+	// Select runtime based on configuration; the isolation classes it can
+	// launch are advertised to Olympus in heartbeats
+	var runtimeClasses []string
 	if cfg.RuntimeType == "auto" || cfg.RuntimeAutoSelect {
 		// Use unified runtime with auto-selection
 		logger.Info("Using unified runtime with automatic selection")
@@ -183,6 +282,13 @@ func main() {
 			AutoSelect:     true,
 			Logger:         logger,
 		})
+		runtimeClasses = append(runtimeClasses, string(tartarus.IsolationMicroVM))
+		if gvisorRuntime != nil {
+			runtimeClasses = append(runtimeClasses, string(tartarus.IsolationGVisor))
+		}
+		if wasmRuntime != nil {
+			runtimeClasses = append(runtimeClasses, string(tartarus.IsolationWASM))
+		}
 	} else {
 		// Use specific runtime
 		switch cfg.RuntimeType {
@@ -190,6 +296,7 @@ func main() {
 			if wasmRuntime != nil {
 				logger.Info("Using WASM runtime exclusively")
 				runtime = wasmRuntime
+				runtimeClasses = []string{string(tartarus.IsolationWASM)}
 			} else {
 				logger.Error("WASM runtime not initialized")
 				os.Exit(1)
@@ -198,6 +305,7 @@ func main() {
 			if gvisorRuntime != nil {
 				logger.Info("Using gVisor runtime exclusively")
 				runtime = gvisorRuntime
+				runtimeClasses = []string{string(tartarus.IsolationGVisor)}
 			} else {
 				logger.Error("gVisor runtime not initialized")
 				os.Exit(1)
@@ -207,6 +315,7 @@ func main() {
 		default:
 			logger.Info("Using Firecracker runtime (default)")
 			runtime = firecrackerRuntime
+			runtimeClasses = []string{string(tartarus.IsolationMicroVM)}
 		}
 	}
 
@@ -229,16 +338,63 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Lethe File Overlay Pool
-	lethePool, err := lethe.NewFileOverlayPool(os.TempDir(), hermesLogger)
+	// Lethe overlay pool; overlayfs keeps the sandbox's writes apart, so
+	// policies can ask for them as outputs
+	var lethePool lethe.Pool
+	switch cfg.LetheBackend {
+	case "overlayfs":
+		lethePool, err = lethe.NewOverlayFSPool(filepath.Join(os.TempDir(), "lethe"), hermesLogger)
+	case "file":
+		lethePool, err = lethe.NewFileOverlayPool(os.TempDir(), hermesLogger)
+	default:
+		err = fmt.Errorf("unknown LETHE_BACKEND %q", cfg.LetheBackend)
+	}
 	if err != nil {
-		logger.Error("Failed to initialize Lethe File Overlay Pool", "error", err)
+		logger.Error("Failed to initialize Lethe overlay pool", "backend", cfg.LetheBackend, "error", err)
 		os.Exit(1)
 	}
 
+	// Artifact references keep layers and snapshots in use from being deleted
+	refs := erebus.NewRefCounter(store)
+	store = erebus.NewGuardedStore(store, refs)
+
 	// OCI Builder
 	ociBuilder := erebus.NewOCIBuilder(store, hermesLogger)
+	ociBuilder.Refs = refs
 	ociBuilder.InitPath = cfg.InitBinaryPath
+	ociBuilder.InitPaths = cfg.InitBinaryPaths
+	ociBuilder.GuestAgentPath = cfg.GuestAgentBinary
+	ociBuilder.ValidateRootFS = cfg.InitSmokeTest
+	ociBuilder.CompressZstd = cfg.LayerZstd
+	ociBuilder.Metrics = metrics
+	if cfg.OfflineMode {
+		seed, err := loadSeedBundle(store, cfg)
+		if err != nil {
+			logger.Error("Offline seed bundle is not usable", "path", cfg.SnapshotPath, "error", err)
+			os.Exit(1)
+		}
+		// Images the bundle lacks fail at once instead of timing out on a registry
+		ociBuilder.Fetcher = seed.Fetcher()
+		if trivy, ok := ociBuilder.Scanner.(*erebus.TrivyScanner); ok {
+			trivy.Offline = true
+		}
+		logger.Info("Offline mode: images resolve from the seed bundle only",
+			"images", len(seed.Manifest.Images), "objects", len(seed.Manifest.Objects), "created_at", seed.Manifest.CreatedAt)
+	}
+
+	// Image cache for operator-requested pre-pulls
+	imageCache, err := erebus.NewImageCache(ociBuilder, filepath.Join(cfg.SnapshotPath, "images"))
+	if err != nil {
+		logger.Error("Failed to initialize image cache", "error", err)
+		os.Exit(1)
+	}
+
+	// Input artifacts staged for sandboxes, reported for locality-aware scheduling
+	artifactCache, err := erebus.NewArtifactCache(store, filepath.Join(cfg.SnapshotPath, "artifacts"))
+	if err != nil {
+		logger.Error("Failed to initialize artifact cache", "error", err)
+		os.Exit(1)
+	}
 
 	// Nyx Local Manager
 	nyxManager, err := nyx.NewLocalManager(store, ociBuilder, cfg.SnapshotPath, hermesLogger)
@@ -246,12 +402,28 @@ func main() {
 		logger.Error("Failed to initialize Nyx Local Manager", "error", err)
 		os.Exit(1)
 	}
+	nyxManager.Refs = refs
 
-	// Cocytus Log Sink
-	cocytusSink := cocytus.NewLogSink(logger)
+	// Cocytus Sink: classifies and fingerprints dead letters. The catalog is
+	// shared through Redis so Olympus can summarize it.
+	var deadLetterCatalog cocytus.Catalog = cocytus.NewMemoryCatalog()
+	if rdb != nil {
+		deadLetterCatalog = cocytus.NewRedisCatalog(rdb, "", 0)
+	}
+	cocytusSink := cocytus.NewClassifyingSink(cocytus.NewLogSink(logger), deadLetterCatalog, cfg.DeadLetterSuppressThreshold)
 
 	// Queue Setup (needs cocytusSink for poison-pill handling)
-	if redisAddr != "" {
+	if len(cfg.KafkaBrokers) > 0 {
+		topic := acheron.KafkaNodeTopic(cfg.KafkaTopic, string(nodeID))
+		kq, err := acheron.NewKafkaQueue(cfg.KafkaBrokers, topic, "acheron-workers", string(nodeID), false, metrics, cocytusSink)
+		if err != nil {
+			logger.Error("Failed to initialize Kafka queue", "error", err)
+			os.Exit(1)
+		}
+		defer kq.Close()
+		queue = kq
+		logger.Info("Using Kafka queue", "brokers", cfg.KafkaBrokers, "topic", topic)
+	} else if redisAddr != "" {
 		redisDB := 0
 		if dbStr := os.Getenv("REDIS_DB"); dbStr != "" {
 			if db, err := strconv.Atoi(dbStr); err == nil {
@@ -277,9 +449,32 @@ func main() {
 		logger.Info("Using in-memory queue")
 	}
 
+	// Skip requests that outlived their queue TTL instead of launching them
+	expiringQueue := acheron.NewExpiringQueue(queue, time.Duration(cfg.QueueMessageTTL)*time.Second, metrics)
+	queue = expiringQueue
+
+	// Copy every consumed payload to Erebus for replay and debugging
+	archiveCtx, stopArchive := context.WithCancel(context.Background())
+	var archiveDone chan struct{}
+	if cfg.QueueArchive {
+		archiver := acheron.NewArchivingQueue(queue, store, string(nodeID), metrics, hermesLogger)
+		if cfg.QueueArchiveInterval > 0 {
+			archiver.Interval = time.Duration(cfg.QueueArchiveInterval) * time.Second
+		}
+		queue = archiver
+		archiveDone = make(chan struct{})
+		go func() {
+			defer close(archiveDone)
+			archiver.Run(archiveCtx)
+		}()
+		logger.Info("Archiving consumed queue payloads", "interval", archiver.Interval)
+	}
+
 	// Fury Watchdog
 	networkStats := erinyes.NewLinuxNetworkStatsProvider()
 	fury := erinyes.NewPollFury(runtime, hermesLogger, metrics, networkStats, 1*time.Second)
+	auditSink := judges.NewLogAuditSink(hermesLogger)
+	integrity := erinyes.NewIntegrityMonitor(runtime, auditSink, hermesLogger, metrics, time.Duration(cfg.IntegrityCheckInterval)*time.Second)
 
 	// Judges
 	judgeChain := &judges.Chain{}
@@ -289,6 +484,23 @@ func main() {
 	if cfg.EnableHypnos {
 		hypnosManager = hypnos.NewManager(runtime, store, os.TempDir())
 		hypnosManager.Metrics = metrics
+		if cfg.HypnosSharedImagesDir != "" {
+			shared, err := hypnos.NewSharedImages(cfg.HypnosSharedImagesDir, time.Duration(cfg.HypnosSharedImageTTL)*time.Second)
+			if err != nil {
+				logger.Warn("Failed to set up shared memory images, waking with private copies", "error", err)
+			} else {
+				hypnosManager.Shared = shared
+				logger.Info("Sharing identical memory images between woken sandboxes", "dir", shared.Dir)
+			}
+		}
+		if cfg.HypnosKSM {
+			if err := hypnos.EnableKSM(); err != nil {
+				logger.Warn("Failed to enable KSM", "error", err)
+			} else {
+				hypnosManager.KSM = true
+				logger.Info("KSM merging enabled for sandbox memory")
+			}
+		}
 		logger.Info("Hypnos hibernation enabled")
 	} else {
 		logger.Info("Hypnos hibernation disabled (set ENABLE_HYPNOS=true to enable)")
@@ -298,10 +510,26 @@ func main() {
 	thanatosHandler := thanatos.NewHandler(runtime, hypnosManager)
 	thanatosHandler.Metrics = metrics
 	logger.Info("Thanatos graceful termination enabled")
+	crashBundler := thanatos.NewCrashBundler(runtime, store, os.TempDir())
+	crashBundler.Timeout = time.Duration(cfg.CrashBundleTimeout) * time.Second
+	crashBundler.Metrics = metrics
 
 	// Control Listener
 	var controlListener hecatoncheir.ControlListener
-	if rdb != nil {
+	if cfg.NATSURL != "" {
+		nc, err := nats.Connect(cfg.NATSURL, nats.Name("hecatoncheir-agent-"+string(nodeID)), nats.MaxReconnects(-1))
+		if err != nil {
+			logger.Error("Failed to connect to NATS", "url", cfg.NATSURL, "error", err)
+			os.Exit(1)
+		}
+		nl, err := hecatoncheir.NewNATSControlListener(nc, nodeID)
+		if err != nil {
+			logger.Error("Failed to initialize NATS control listener", "error", err)
+			os.Exit(1)
+		}
+		controlListener = nl
+		logger.Info("Enabled NATS control listener", "url", cfg.NATSURL)
+	} else if rdb != nil {
 		controlListener = hecatoncheir.NewRedisControlListener(rdb, nodeID)
 		logger.Info("Enabled Redis control listener")
 	}
@@ -314,14 +542,81 @@ func main() {
 		Styx:       styxGateway,
 		Judges:     judgeChain,
 		Furies:     fury,
+		Integrity:  integrity,
 		Hypnos:     hypnosManager,
 		Thanatos:   thanatosHandler,
 		Queue:      queue,
 		Registry:   registry,
 		DeadLetter: cocytusSink,
 		Control:    controlListener,
+		Secrets:    compositeSecrets,
+		Audit:      auditSink,
+		Images:     imageCache,
+		Artifacts:  artifactCache,
+		Outputs:    store,
 		Metrics:    metrics,
 		Logger:     hermesLogger,
+
+		CrashBundles:    crashBundler,
+		ResultTailBytes: cfg.ResultTailBytes,
+		Capacity:        nodeCapacity,
+		PreemptBatch:    cfg.QueuePreemptBatch,
+		ProcessLimits: domain.ProcessLimits{
+			MaxPIDs:      int64(cfg.SandboxMaxPIDs),
+			MaxOpenFiles: uint64(cfg.SandboxMaxOpenFiles),
+			CoreDumps:    cfg.SandboxCoreDumps,
+		},
+	}
+	if err := agent.ProcessLimits.Validate(); err != nil {
+		logger.Error("Invalid sandbox process limits", "error", err)
+		os.Exit(1)
+	}
+	if cfg.FirecrackerJailer {
+		agent.Jailer = &tartarus.JailerConfig{
+			Binary:        cfg.FirecrackerJailerBinary,
+			ExecFile:      cfg.FirecrackerBinary,
+			ChrootBaseDir: cfg.FirecrackerChrootBase,
+			UID:           cfg.FirecrackerJailerUID,
+			GID:           cfg.FirecrackerJailerGID,
+			IDCount:       cfg.FirecrackerJailerIDCount,
+			NewPIDNS:      cfg.FirecrackerNewPIDNS,
+			NetNS:         cfg.FirecrackerJailerNetNS,
+		}
+		if err := agent.Jailer.Validate(); err != nil {
+			logger.Error("Invalid Firecracker jailer configuration", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Running Firecracker VMMs under the jailer", "chroot_base", cfg.FirecrackerChrootBase, "uid", cfg.FirecrackerJailerUID, "ids", cfg.FirecrackerJailerIDCount)
+	}
+	if cfg.HostHooksDir != "" {
+		agent.Hooks = hecatoncheir.NewHookRunner(cfg.HostHooksDir, time.Duration(cfg.HostHookTimeout)*time.Second, metrics, hermesLogger)
+		logger.Info("Host hooks enabled", "dir", cfg.HostHooksDir)
+	}
+	if cfg.GPUDiscovery {
+		if _, err := exec.LookPath(cfg.NvidiaSMIPath); err == nil {
+			agent.GPUs = hecatoncheir.NewGPUInventory(&hecatoncheir.NvidiaSMIProber{Path: cfg.NvidiaSMIPath}, time.Duration(cfg.GPURefresh)*time.Second)
+			if devices, err := agent.GPUs.Devices(context.Background()); err != nil {
+				logger.Error("Failed to probe GPUs", "error", err)
+			} else {
+				logger.Info("GPU inventory enabled", "devices", len(devices))
+			}
+		}
+	}
+	expiringQueue.OnExpired = agent.MarkExpired
+	integrity.OnTamper = agent.FlagTampered
+	fury.BeforeKillHook = agent.CaptureCrashBundle
+	fury.ViolationHook = agent.RecordViolation
+	fury.HibernateHook = agent.HibernateForViolation
+
+	// RESTART drains the agent, then shuts it down like SIGTERM so the
+	// supervisor starts the upgraded binary
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	agent.Restart = func() {
+		select {
+		case quit <- syscall.SIGTERM:
+		default: // Already shutting down
+		}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -334,9 +629,35 @@ func main() {
 		}
 	}()
 
+	// Migrate layers cached gzip-compressed to zstd, which extracts faster
+	if cfg.LayerZstd && cfg.OfflineMode {
+		// Recompressed layers would no longer match the seed manifest
+		logger.Warn("Not recompressing cached layers in offline mode")
+	} else if cfg.LayerZstd {
+		recompressor := erebus.NewLayerRecompressor(store, hermesLogger, metrics)
+		if cfg.LayerRecompressInterval > 0 {
+			recompressor.Interval = time.Duration(cfg.LayerRecompressInterval) * time.Second
+		}
+		go recompressor.Run(ctx)
+		logger.Info("Recompressing cached layers to zstd", "interval", recompressor.Interval)
+	}
+
 	// Heartbeat Ticker
+	heartbeatCfg := hecatoncheir.DefaultHeartbeatConfig()
+	if cfg.HeartbeatInterval > 0 {
+		heartbeatCfg.Interval = time.Duration(cfg.HeartbeatInterval) * time.Second
+	}
+	heartbeatCfg.IncludeSandboxes = cfg.HeartbeatIncludeSandboxes
+	heartbeatCfg.IncludeConditions = cfg.HeartbeatConditions
+	heartbeatCfg.DiskPath = cfg.HeartbeatDiskPath
+	heartbeatCfg.DiskPressurePercent = cfg.DiskPressureThreshold
+	heartbeatCfg.MemoryPressurePercent = cfg.MemoryPressureThreshold
+	nodeConditions := hecatoncheir.NewNodeConditions(heartbeatCfg)
+	agentBuild := hecatoncheir.Build()
+	agentStartedAt := time.Now()
+
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
+		ticker := time.NewTicker(heartbeatCfg.Interval)
 		defer ticker.Stop()
 		for {
 			select {
@@ -363,9 +684,9 @@ func main() {
 				totalCPU := domain.MilliCPU(cpuCount * 1000)
 
 				// Get active sandboxes
-				activeSandboxes, err := runtime.List(ctx)
-				if err != nil {
-					logger.Error("Failed to list active sandboxes", "error", err)
+				activeSandboxes, runtimeErr := runtime.List(ctx)
+				if runtimeErr != nil {
+					logger.Error("Failed to list active sandboxes", "error", runtimeErr)
 					activeSandboxes = []domain.SandboxRun{}
 				}
 
@@ -382,16 +703,53 @@ func main() {
 					Node: domain.NodeInfo{
 						ID:      agent.NodeID,
 						Address: "localhost", // In production, this would be actual node address
-						Labels:  map[string]string{"region": cfg.Region},
+						Labels:  nodeLabels(cfg, runtimeClasses),
 						Capacity: domain.ResourceCapacity{
-							CPU: totalCPU,
-							Mem: totalMemMB,
-							GPU: 0,
+							CPU:  totalCPU,
+							Mem:  totalMemMB,
+							GPU:  0,
+							Swap: nodeCapacity.Swap,
 						},
+						Taints: nodeTaints,
 					},
-					Load:            allocated,
-					ActiveSandboxes: activeSandboxes,
-					Time:            time.Now(),
+					Load:           allocated,
+					AgentVersion:   hecatoncheir.Version,
+					AgentBuild:     agentBuild,
+					AgentStartedAt: agentStartedAt,
+					Time:           time.Now(),
+				}
+				if heartbeatCfg.IncludeSandboxes {
+					payload.ActiveSandboxes = activeSandboxes
+				}
+				if agent.GPUs != nil {
+					if runtimeErr == nil {
+						agent.GPUs.Retain(activeSandboxes)
+					}
+					devices, err := agent.GPUs.Devices(ctx)
+					if err != nil {
+						logger.Error("Failed to probe GPUs", "error", err)
+					}
+					total, inUse := agent.GPUs.Units()
+					payload.Node.GPUs = devices
+					payload.Node.Capacity.GPU = total
+					payload.Load.GPU = max(payload.Load.GPU, inUse)
+				}
+				if heartbeatCfg.IncludeConditions {
+					payload.Conditions = nodeConditions.Collect(runtimeErr)
+				}
+				if images, err := imageCache.List(); err == nil {
+					payload.CachedImages = images
+				}
+				if artifacts, err := artifactCache.List(); err == nil {
+					payload.CachedArtifacts = artifacts
+				}
+				if cgroupSlices != nil {
+					payload.CgroupSlices = cgroupSlices.Usage()
+					hecatoncheir.ReportSliceMetrics(metrics, payload.CgroupSlices)
+				}
+				if hypnosManager != nil {
+					payload.MemorySharing = hypnosManager.MemorySharing(ctx)
+					hypnos.ReportSharingMetrics(metrics, payload.MemorySharing)
 				}
 
 				// Send heartbeat to registry
@@ -407,8 +765,6 @@ func main() {
 		}
 	}()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("Shutting down agent...")
@@ -434,5 +790,50 @@ func main() {
 		time.Sleep(12 * time.Second)
 	}
 
+	// Write out the deliveries archived since the last batch
+	stopArchive()
+	if archiveDone != nil {
+		<-archiveDone
+	}
+
 	logger.Info("Agent shutdown complete")
 }
+
+// nodeLabels returns the labels the agent reports in heartbeats: NODE_LABELS,
+// and the region, architecture and runtimes, which NODE_LABELS cannot
+// override.
+// loadSeedBundle verifies the offline seed bundle in store against the
+// configured public key, and checks that everything it lists is present.
+func loadSeedBundle(store erebus.Store, cfg *config.Config) (*erebus.SeedBundle, error) {
+	if cfg.OfflineSeedPublicKey == "" {
+		return nil, fmt.Errorf("OFFLINE_SEED_PUBLIC_KEY is required in offline mode")
+	}
+	pemBytes, err := os.ReadFile(cfg.OfflineSeedPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("reading seed public key: %w", err)
+	}
+	publicKey, err := erebus.ParseSeedPublicKey(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	seed, err := erebus.LoadSeedBundle(ctx, store, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := seed.Validate(ctx, cfg.OfflineVerifyDigests); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+func nodeLabels(cfg *config.Config, runtimeClasses []string) map[string]string {
+	labels := make(map[string]string, len(cfg.NodeLabels)+3)
+	for k, v := range cfg.NodeLabels {
+		labels[k] = v
+	}
+	labels[domain.NodeLabelRegion] = cfg.Region
+	labels[domain.NodeLabelArch] = goruntime.GOARCH
+	labels[domain.NodeLabelRuntimes] = strings.Join(runtimeClasses, ",")
+	return labels
+}
@@ -0,0 +1,32 @@
+// Command tartarus-guest-agent runs inside Firecracker sandboxes and serves
+// exec, health and metrics requests from the host runtime over vsock.
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// Version is set at build time.
+var Version = "dev"
+
+func main() {
+	port := flag.Uint("port", tartarus.DefaultGuestAgentPort, "vsock port to listen on")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	l, err := tartarus.ListenVsock(uint32(*port))
+	if err != nil {
+		logger.Error("Failed to listen", "port", *port, "error", err)
+		os.Exit(1)
+	}
+	server := &tartarus.GuestAgentServer{Version: Version}
+	if err := server.Serve(l); err != nil {
+		logger.Error("Guest agent stopped", "error", err)
+		os.Exit(1)
+	}
+}
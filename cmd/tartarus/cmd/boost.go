@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var boostReason string
+
+var boostCmd = &cobra.Command{
+	Use:   "boost [sandbox-id]",
+	Short: "Move a queued sandbox to the front of its node's queue",
+	Long: `Move a sandbox that is still waiting in the queue ahead of the requests
+that have not been boosted. Boosts are audited.
+
+Examples:
+  tartarus boost sandbox-abc123 --reason "blocking the release"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+		body, _ := json.Marshal(map[string]string{"reason": boostReason})
+
+		resp, err := doRequest(http.MethodPost, "/sandboxes/"+id+"/priority", bytes.NewReader(body))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error boosting sandbox: %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Request failed with status %d: %s\n", resp.StatusCode, string(respBody))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Sandbox %s boosted\n", id)
+	},
+}
+
+func init() {
+	boostCmd.Flags().StringVar(&boostReason, "reason", "", "Why the sandbox is boosted, for the audit log")
+
+	rootCmd.AddCommand(boostCmd)
+}
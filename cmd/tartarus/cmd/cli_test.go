@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/websocket"
@@ -58,21 +62,52 @@ func startMockServer(t *testing.T) *httptest.Server {
 		}
 	})
 
-	// Exec
+	// Exec, interactive over WS on GET
 	mux.HandleFunc("/sandboxes/test-id/exec", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			w.WriteHeader(http.StatusAccepted)
+			return
 		}
-	})
-
-	// Exec Interactive (WS)
-	mux.HandleFunc("/sandboxes/exec/sock/test-id", func(w http.ResponseWriter, r *http.Request) {
 		c, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			return
 		}
 		defer c.Close()
-		c.WriteMessage(websocket.TextMessage, []byte("Interactive output"))
+		c.WriteMessage(websocket.BinaryMessage, domain.ExecFrame(domain.ExecStdout, []byte(strings.Join(r.URL.Query()["cmd"], " ")+"\n")))
+		// Echo stdin until it closes, then exit 3
+		for {
+			_, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			channel, data, _ := domain.ParseExecFrame(message)
+			if channel == domain.ExecClose {
+				break
+			}
+			c.WriteMessage(websocket.BinaryMessage, domain.ExecFrame(domain.ExecStderr, data))
+		}
+		c.WriteMessage(websocket.BinaryMessage, domain.ExecResult{ExitCode: 3}.StatusFrame())
+	})
+
+	// Scripts
+	mux.HandleFunc("/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"digest": "sha256:abc", "bytes": 10})
+	})
+	mux.HandleFunc("/scripts/run", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Artifact   string   `json:"artifact"`
+			Entrypoint string   `json:"entrypoint"`
+			Args       []string `json:"args"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":        "script-id",
+			"status":    domain.RunStatusSucceeded,
+			"exit_code": 0,
+			"stdout":    fmt.Sprintf("%s %s %s\n", req.Artifact, req.Entrypoint, strings.Join(req.Args, " ")),
+		})
 	})
 
 	// Inspect
@@ -184,12 +219,16 @@ func TestExecInteractive(t *testing.T) {
 	defer server.Close()
 	host = server.URL
 
-	// We can't easily test interactive mode because it captures Stdin/Stdout and uses raw mode.
-	// However, we can test that it attempts to connect.
-	// But runInteractive calls os.Exit on error, which is hard to test.
-	// For now, we will skip the full interactive test or refactor runInteractive to be testable.
-	// A better approach is to mock the dialer or just verify the URL construction if possible.
-	// Given the constraints, we will skip this specific test for now or assume the manual verification covers it.
+	u := "ws" + strings.TrimPrefix(server.URL, "http") + "/sandboxes/test-id/exec?cmd=ls&cmd=-la"
+	c, _, err := websocket.DefaultDialer.Dial(u, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := execSession(c, strings.NewReader("hello"), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "ls -la\n", stdout.String())
+	assert.Equal(t, "hello", stderr.String())
 }
 
 func TestInspect(t *testing.T) {
@@ -205,6 +244,57 @@ func TestInspect(t *testing.T) {
 	assert.Contains(t, output, "RUNNING")
 }
 
+func TestScript(t *testing.T) {
+	server := startMockServer(t)
+	defer server.Close()
+	host = server.URL
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "main.py")
+	require.NoError(t, os.WriteFile(script, []byte("print('hi')"), 0644))
+
+	output, err := executeCommand(rootCmd, "script", script, "--", "--epochs", "3")
+	require.NoError(t, err)
+	assert.Contains(t, output, "sha256:abc main.py --epochs 3")
+}
+
+func TestPackScript(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.py"), []byte("print('hi')"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("numpy\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "util.py"), []byte("X = 1"), 0644))
+
+	list := func(data []byte) []string {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		tr := tar.NewReader(zr)
+		var names []string
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return names
+			}
+			require.NoError(t, err)
+			names = append(names, hdr.Name)
+		}
+	}
+
+	// A file brings its requirements.txt, but not its siblings
+	data, entry, err := packScript(filepath.Join(dir, "main.py"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "main.py", entry)
+	assert.ElementsMatch(t, []string{"main.py", "requirements.txt"}, list(data))
+
+	// A directory is packed whole and needs an entrypoint
+	_, _, err = packScript(dir, "")
+	assert.Error(t, err)
+	data, entry, err = packScript(dir, "lib/util.py")
+	require.NoError(t, err)
+	assert.Equal(t, "lib/util.py", entry)
+	assert.ElementsMatch(t, []string{"main.py", "requirements.txt", "lib/util.py"}, list(data))
+}
+
 func TestConfig(t *testing.T) {
 	// Setup a temporary config file
 	tmpDir := t.TempDir()
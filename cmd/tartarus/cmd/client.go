@@ -51,18 +51,25 @@ func doRequest(method, path string, body io.Reader) (*http.Response, error) {
 		return nil, err
 	}
 
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	} else if apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-	} else if envKey := os.Getenv("TARTARUS_API_KEY"); envKey != "" {
-		req.Header.Set("Authorization", "Bearer "+envKey)
-	}
+	setAuthHeader(req.Header)
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	// Pin the API version so the unversioned paths are not served as deprecated aliases
+	req.Header.Set("API-Version", "v1")
 
 	client := getHTTPClient()
 	return client.Do(req)
 }
+
+// setAuthHeader sets the bearer credentials of the current context.
+func setAuthHeader(h http.Header) {
+	if token != "" {
+		h.Set("Authorization", "Bearer "+token)
+	} else if apiKey != "" {
+		h.Set("Authorization", "Bearer "+apiKey)
+	} else if envKey := os.Getenv("TARTARUS_API_KEY"); envKey != "" {
+		h.Set("Authorization", "Bearer "+envKey)
+	}
+}
@@ -4,15 +4,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 
 	"github.com/gorilla/websocket"
 	"github.com/spf13/cobra"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 	"golang.org/x/term"
 )
 
@@ -73,26 +74,38 @@ func runInteractive(cmd *cobra.Command, id string, command []string) {
 		scheme = "wss"
 	}
 
-	path := fmt.Sprintf("/sandboxes/exec/sock/%s", id)
+	path := fmt.Sprintf("/sandboxes/%s/exec", id)
 	wsURL := url.URL{Scheme: scheme, Host: u.Host, Path: path}
 
-	// Add command as query param
-	q := wsURL.Query()
-	q.Set("cmd", strings.Join(command, " "))
-	wsURL.RawQuery = q.Encode()
+	// Each argument is its own cmd query param
+	wsURL.RawQuery = url.Values{"cmd": command}.Encode()
 
-	fmt.Printf("Connecting to %s...\n", wsURL.String())
-
-	c, _, err := websocket.DefaultDialer.Dial(wsURL.String(), nil)
+	header := http.Header{}
+	setAuthHeader(header)
+	header.Set("API-Version", "v1")
+	c, resp, err := websocket.DefaultDialer.Dial(wsURL.String(), header)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "dial: %v\n", err)
+		if resp != nil {
+			fmt.Fprintf(os.Stderr, "Error executing command: status %d\n", resp.StatusCode)
+		} else {
+			fmt.Fprintf(os.Stderr, "dial: %v\n", err)
+		}
 		os.Exit(1)
 	}
 	defer c.Close()
 
+	code := execSession(c, os.Stdin, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// execSession relays a terminal over an exec session until the command
+// exits, and returns its exit code.
+func execSession(c *websocket.Conn, stdin io.Reader, stdout, stderr io.Writer) int {
 	// Handle terminal raw mode
-	fd := int(os.Stdin.Fd())
-	if term.IsTerminal(fd) {
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fd := int(f.Fd())
 		oldState, err := term.MakeRaw(fd)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "failed to set raw mode: %v\n", err)
@@ -104,42 +117,58 @@ func runInteractive(cmd *cobra.Command, id string, command []string) {
 	// Handle SIGINT to restore terminal
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
 	go func() {
-		<-sigCh
-		c.Close()
-		os.Exit(0)
+		if _, ok := <-sigCh; ok {
+			c.WriteMessage(websocket.BinaryMessage, domain.ExecFrame(domain.ExecCancel, nil))
+		}
 	}()
 
-	done := make(chan struct{})
-
-	// Read from WS -> Stdout
+	// Read from Stdin -> WS, closing the command's input at EOF
 	go func() {
-		defer close(done)
+		buf := make([]byte, 1024)
 		for {
-			_, message, err := c.ReadMessage()
+			n, err := stdin.Read(buf)
+			if n > 0 {
+				if err := c.WriteMessage(websocket.BinaryMessage, domain.ExecFrame(domain.ExecStdin, buf[:n])); err != nil {
+					return
+				}
+			}
 			if err != nil {
-				// Normal closure?
+				c.WriteMessage(websocket.BinaryMessage, domain.ExecFrame(domain.ExecClose, nil))
 				return
 			}
-			os.Stdout.Write(message)
 		}
 	}()
 
-	// Read from Stdin -> WS
-	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := os.Stdin.Read(buf)
-			if err != nil {
-				return
+	// Read from WS -> Stdout/Stderr until the exit status
+	for {
+		_, message, err := c.ReadMessage()
+		if err != nil {
+			fmt.Fprintf(stderr, "connection closed: %v\n", err)
+			return 1
+		}
+		channel, data, err := domain.ParseExecFrame(message)
+		if err != nil {
+			continue
+		}
+		switch channel {
+		case domain.ExecStdout:
+			stdout.Write(data)
+		case domain.ExecStderr:
+			stderr.Write(data)
+		case domain.ExecStatus:
+			var result domain.ExecResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return 1
 			}
-			if err := c.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
-				return
+			if result.Error != "" {
+				fmt.Fprintf(stderr, "Error executing command: %s\n", result.Error)
+				return 1
 			}
+			return result.ExitCode
 		}
-	}()
-
-	<-done
+	}
 }
 
 var interactive bool
@@ -47,6 +47,18 @@ var inspectCmd = &cobra.Command{
 		if run.Error != "" {
 			fmt.Fprintf(w, "Error:\t%s\n", run.Error)
 		}
+		if r := run.Retry; r != nil {
+			fmt.Fprintf(w, "Attempt:\t%d of %d\n", r.Attempt, r.Policy.MaxAttempts)
+			if r.PreviousID != "" {
+				fmt.Fprintf(w, "Retry Of:\t%s\n", r.PreviousID)
+			}
+			if r.NextID != "" {
+				fmt.Fprintf(w, "Retried By:\t%s (%s)\n", r.NextID, r.Class)
+			}
+			if r.Stopped != "" {
+				fmt.Fprintf(w, "Not Retried:\t%s\n", r.Stopped)
+			}
+		}
 		if len(run.Metadata) > 0 {
 			fmt.Fprintln(w, "Metadata:")
 			for k, v := range run.Metadata {
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,7 +12,7 @@ import (
 
 var killCmd = &cobra.Command{
 	Use:   "kill [id]",
-	Short: "Terminate a sandbox",
+	Short: "Terminate a sandbox, or cancel it while it is queued",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		id := args[0]
@@ -36,6 +37,11 @@ var killCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		var result map[string]string
+		if json.NewDecoder(resp.Body).Decode(&result) == nil && result["status"] == "cancelled" {
+			fmt.Printf("Sandbox %s cancelled before it started\n", id)
+			return
+		}
 		fmt.Printf("Sandbox %s terminated\n", id)
 	},
 }
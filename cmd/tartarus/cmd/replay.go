@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var replayShow bool
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [sandbox-id]",
+	Short: "Re-submit a request from the queue archive",
+	Long: `Re-submit a request exactly as an agent dequeued it, under a fresh ID.
+Requires the agents to run with ACHERON_ARCHIVE=true.
+
+Examples:
+  # Replay a launch that failed once
+  tartarus replay sandbox-abc123
+
+  # Show the archived payload and its outcome without replaying it
+  tartarus replay sandbox-abc123 --show`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runReplay(args[0])
+	},
+}
+
+func runReplay(id string) {
+	method, path := http.MethodPost, "/queue/archive/"+id+"/replay"
+	if replayShow {
+		method, path = http.MethodGet, "/queue/archive/"+id
+	}
+
+	resp, err := doRequest(method, path, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error replaying request: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		fmt.Fprintf(os.Stderr, "Request not found in the queue archive: %s\n", id)
+		os.Exit(1)
+	}
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Request failed with status %d: %s\n", resp.StatusCode, string(respBody))
+		os.Exit(1)
+	}
+
+	if replayShow {
+		fmt.Println(string(respBody))
+		return
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		fmt.Printf("Request %s replayed\n", id)
+		return
+	}
+
+	sandboxID, _ := result["id"].(string)
+	status, _ := result["status"].(string)
+
+	fmt.Printf("Sandbox ID: %s\n", sandboxID)
+	fmt.Printf("Status: %s\n", status)
+	fmt.Printf("Replay Of: %s\n", id)
+}
+
+func init() {
+	replayCmd.Flags().BoolVar(&replayShow, "show", false, "Print the archived payload instead of replaying it")
+
+	rootCmd.AddCommand(replayCmd)
+}
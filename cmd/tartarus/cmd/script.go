@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+var (
+	scriptEntrypoint string
+	scriptTemplate   string
+	scriptOutputs    []string
+	scriptEnv        []string
+	scriptTimeout    time.Duration
+)
+
+// scriptResult is the outcome of a script run as Olympus reports it.
+type scriptResult struct {
+	ID         string            `json:"id"`
+	Status     domain.RunStatus  `json:"status"`
+	ExitCode   *int              `json:"exit_code,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Stdout     string            `json:"stdout,omitempty"`
+	Stderr     string            `json:"stderr,omitempty"`
+	Truncated  bool              `json:"output_truncated,omitempty"`
+	DurationMs int64             `json:"duration_ms,omitempty"`
+	Output     *domain.RunOutput `json:"output,omitempty"`
+}
+
+var scriptCmd = &cobra.Command{
+	Use:   "script [file-or-dir] [-- args...]",
+	Short: "Run a script and wait for its result",
+	Long: `Upload a script with its requirements, run it in the template for its
+language and print its output once it finishes.
+
+A file is uploaded together with the requirements.txt next to it, if any. A
+directory is uploaded as a whole and needs --entrypoint. The command exits
+with the script's exit code.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		artifact, entrypoint, err := packScript(args[0], scriptEntrypoint)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error packing script: %v\n", err)
+			os.Exit(1)
+		}
+
+		digest, err := uploadArtifact(artifact)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error uploading script: %v\n", err)
+			os.Exit(1)
+		}
+
+		env := map[string]string{}
+		for _, kv := range scriptEnv {
+			k, v, _ := strings.Cut(kv, "=")
+			env[k] = v
+		}
+		result, err := runScript(map[string]any{
+			"artifact":   digest,
+			"entrypoint": entrypoint,
+			"template":   scriptTemplate,
+			"args":       args[1:],
+			"env":        env,
+			"outputs":    scriptOutputs,
+		}, scriptTimeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running script: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), result.Stdout)
+		fmt.Fprint(cmd.ErrOrStderr(), result.Stderr)
+		if result.Truncated {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Output truncated; see tartarus logs %s\n", result.ID)
+		}
+		if result.Output != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Outputs: %s (%d files, %d bytes)\n", result.Output.Key, result.Output.Files, result.Output.Bytes)
+		}
+		if result.Error != "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %s\n", result.Error)
+		}
+		if result.ExitCode != nil && *result.ExitCode != 0 {
+			os.Exit(*result.ExitCode)
+		}
+		if result.Status != domain.RunStatusSucceeded {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Run %s ended %s\n", result.ID, result.Status)
+			os.Exit(1)
+		}
+	},
+}
+
+// packScript gzips a script file, with its requirements.txt, or a whole
+// directory into a tarball and returns it with the entrypoint inside it.
+func packScript(src, entrypoint string) ([]byte, string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []string
+	root := src
+	if info.IsDir() {
+		if entrypoint == "" {
+			return nil, "", fmt.Errorf("--entrypoint is required for a directory")
+		}
+		err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				rel, err := filepath.Rel(src, p)
+				if err != nil {
+					return err
+				}
+				files = append(files, rel)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		root = filepath.Dir(src)
+		entrypoint = filepath.Base(src)
+		files = []string{entrypoint}
+		if _, err := os.Stat(filepath.Join(root, "requirements.txt")); err == nil && entrypoint != "requirements.txt" {
+			files = append(files, "requirements.txt")
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, rel := range files {
+		data, err := os.ReadFile(filepath.Join(root, rel))
+		if err != nil {
+			return nil, "", err
+		}
+		fi, err := os.Stat(filepath.Join(root, rel))
+		if err != nil {
+			return nil, "", err
+		}
+		hdr := &tar.Header{
+			Name:     filepath.ToSlash(rel),
+			Mode:     int64(fi.Mode().Perm()),
+			Size:     int64(len(data)),
+			ModTime:  fi.ModTime(),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, "", err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), filepath.ToSlash(entrypoint), nil
+}
+
+// uploadArtifact uploads data to Olympus and returns its digest.
+func uploadArtifact(data []byte) (string, error) {
+	resp, err := doRequest(http.MethodPost, "/artifacts", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var uploaded struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return "", err
+	}
+	return uploaded.Digest, nil
+}
+
+// runScript submits a script run and waits up to timeout for its result.
+func runScript(req map[string]any, timeout time.Duration) (*scriptResult, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doRequest(http.MethodPost, "/scripts/run?timeout="+url.QueryEscape(timeout.String()), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var result scriptResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	// Olympus answered before the run finished: keep waiting on the run
+	deadline := time.Now().Add(timeout)
+	for resp.StatusCode == http.StatusAccepted {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("run %s did not finish within %s", result.ID, timeout)
+		}
+		resp, err = doRequest(http.MethodGet, "/sandboxes/"+url.PathEscape(result.ID)+"/wait?timeout="+url.QueryEscape(remaining.Round(time.Second).String()), nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("waiting for run %s: status %d: %s", result.ID, resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		var run domain.SandboxRun
+		err = json.NewDecoder(resp.Body).Decode(&run)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		result = scriptResult{
+			ID:       string(run.ID),
+			Status:   run.Status,
+			ExitCode: run.ExitCode,
+			Error:    run.Error,
+			Output:   run.Output,
+		}
+		if run.Result != nil {
+			result.Stdout = run.Result.StdoutTail
+			result.Stderr = run.Result.StderrTail
+			result.Truncated = run.Result.OutputTruncated
+			result.DurationMs = run.Result.DurationMs
+		}
+	}
+	return &result, nil
+}
+
+func init() {
+	rootCmd.AddCommand(scriptCmd)
+	scriptCmd.Flags().StringVar(&scriptEntrypoint, "entrypoint", "", "Script to run inside the directory")
+	scriptCmd.Flags().StringVar(&scriptTemplate, "template", "", "Template to run in (default: the one for the script's language)")
+	scriptCmd.Flags().StringArrayVar(&scriptOutputs, "output", nil, "Guest path to keep when the script exits (repeatable)")
+	scriptCmd.Flags().StringArrayVarP(&scriptEnv, "env", "e", nil, "Environment variable KEY=VALUE (repeatable)")
+	scriptCmd.Flags().DurationVar(&scriptTimeout, "timeout", 10*time.Minute, "How long to wait for the result")
+}
@@ -0,0 +1,262 @@
+package acheron
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ArchivePrefix is the Erebus key prefix of the queue payload archive.
+const ArchivePrefix = "acheron/archive/"
+
+// DefaultArchiveInterval is how often archived payloads are written out.
+const DefaultArchiveInterval = time.Hour
+
+// ErrNotArchived is returned for requests missing from the archive.
+var ErrNotArchived = errors.New("request not found in the queue archive")
+
+// ArchiveStore holds archive batches; erebus.Store implements it.
+type ArchiveStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// ArchiveOutcome is how a consumer settled an archived delivery.
+type ArchiveOutcome string
+
+const (
+	ArchiveAcked     ArchiveOutcome = "acked"
+	ArchiveNacked    ArchiveOutcome = "nacked"
+	ArchiveUnsettled ArchiveOutcome = "unsettled" // Still in flight when the archiver stopped
+)
+
+// ArchivedPayload is one delivery of a request, as the consumer dequeued it.
+type ArchivedPayload struct {
+	Request    *domain.SandboxRequest `json:"request"`
+	Consumer   string                 `json:"consumer,omitempty"`
+	DequeuedAt time.Time              `json:"dequeued_at"`
+	SettledAt  time.Time              `json:"settled_at,omitempty"`
+	Outcome    ArchiveOutcome         `json:"outcome"`
+	Reason     string                 `json:"reason,omitempty"` // Nack reason
+}
+
+// ArchivingQueue wraps a Queue and copies every dequeued request to an
+// ArchiveStore once it is acked or nacked, so a launch can be inspected or
+// replayed later. Deliveries are written in batches every Interval, one
+// JSON line each, under ArchivePrefix/<yyyy>/<mm>/<dd>/<hh>/. Run must be
+// running for batches to be written.
+type ArchivingQueue struct {
+	Queue
+	Store    ArchiveStore
+	Consumer string        // Recorded with each delivery, e.g. the node ID
+	Interval time.Duration // Between batches (DefaultArchiveInterval if zero)
+
+	metrics  hermes.Metrics
+	logger   hermes.Logger
+	now      func() time.Time
+	mu       sync.Mutex
+	inflight map[string]*ArchivedPayload
+	settled  []ArchivedPayload
+}
+
+func NewArchivingQueue(next Queue, store ArchiveStore, consumer string, metrics hermes.Metrics, logger hermes.Logger) *ArchivingQueue {
+	return &ArchivingQueue{
+		Queue:    next,
+		Store:    store,
+		Consumer: consumer,
+		Interval: DefaultArchiveInterval,
+		metrics:  metrics,
+		logger:   logger,
+		now:      time.Now,
+		inflight: make(map[string]*ArchivedPayload),
+	}
+}
+
+func (q *ArchivingQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, string, error) {
+	req, receipt, err := q.Queue.Dequeue(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Keep the payload as it was delivered, before the consumer changes it
+	payload, err := copyRequest(req)
+	if err != nil {
+		q.metrics.IncCounter("queue_archive_errors_total", 1)
+		return req, receipt, nil
+	}
+	q.mu.Lock()
+	q.inflight[receipt] = &ArchivedPayload{Request: payload, Consumer: q.Consumer, DequeuedAt: q.now()}
+	q.mu.Unlock()
+	return req, receipt, nil
+}
+
+func (q *ArchivingQueue) Ack(ctx context.Context, receipt string) error {
+	err := q.Queue.Ack(ctx, receipt)
+	q.settle(receipt, ArchiveAcked, "")
+	return err
+}
+
+func (q *ArchivingQueue) Nack(ctx context.Context, receipt string, reason string) error {
+	err := q.Queue.Nack(ctx, receipt, reason)
+	q.settle(receipt, ArchiveNacked, reason)
+	return err
+}
+
+func (q *ArchivingQueue) settle(receipt string, outcome ArchiveOutcome, reason string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.inflight[receipt]
+	if !ok {
+		return
+	}
+	delete(q.inflight, receipt)
+	entry.SettledAt = q.now()
+	entry.Outcome = outcome
+	entry.Reason = reason
+	q.settled = append(q.settled, *entry)
+}
+
+// Run writes a batch every Interval until ctx is cancelled, then writes the
+// remaining deliveries, including those still in flight.
+func (q *ArchivingQueue) Run(ctx context.Context) {
+	interval := q.Interval
+	if interval <= 0 {
+		interval = DefaultArchiveInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			for receipt, entry := range q.inflight {
+				entry.Outcome = ArchiveUnsettled
+				q.settled = append(q.settled, *entry)
+				delete(q.inflight, receipt)
+			}
+			q.mu.Unlock()
+			// The caller's context is gone; give the last batch its own
+			flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			q.Flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			q.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes the settled deliveries as one batch and indexes each request
+// to it. Deliveries that cannot be written are kept for the next batch.
+func (q *ArchivingQueue) Flush(ctx context.Context) error {
+	q.mu.Lock()
+	batch := q.settled
+	q.settled = nil
+	q.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	now := q.now().UTC()
+	key := fmt.Sprintf("%s%s/%s-%d.jsonl", ArchivePrefix, now.Format("2006/01/02/15"), q.Consumer, now.UnixNano())
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range batch {
+		if err := enc.Encode(&batch[i]); err != nil {
+			return q.retry(ctx, batch, fmt.Errorf("failed to encode archived payload: %w", err))
+		}
+	}
+	if err := q.Store.Put(ctx, key, &buf); err != nil {
+		return q.retry(ctx, batch, fmt.Errorf("failed to write archive batch: %w", err))
+	}
+
+	indexed := make(map[domain.SandboxID]bool, len(batch))
+	for _, entry := range batch {
+		if indexed[entry.Request.ID] {
+			continue
+		}
+		indexed[entry.Request.ID] = true
+		if err := q.Store.Put(ctx, archiveIndexKey(entry.Request.ID), bytes.NewReader([]byte(key))); err != nil {
+			q.metrics.IncCounter("queue_archive_errors_total", 1)
+			q.logger.Error(ctx, "Failed to index archived payload", map[string]any{"sandbox_id": entry.Request.ID, "batch": key, "error": err})
+		}
+	}
+
+	q.metrics.IncCounter("queue_archived_payloads_total", float64(len(batch)))
+	q.logger.Info(ctx, "Archived queue payloads", map[string]any{"batch": key, "payloads": len(batch)})
+	return nil
+}
+
+func (q *ArchivingQueue) retry(ctx context.Context, batch []ArchivedPayload, err error) error {
+	q.mu.Lock()
+	q.settled = append(batch, q.settled...)
+	q.mu.Unlock()
+	q.metrics.IncCounter("queue_archive_errors_total", 1)
+	q.logger.Error(ctx, "Failed to archive queue payloads", map[string]any{"payloads": len(batch), "error": err})
+	return err
+}
+
+// LoadArchived returns the latest archived delivery of a request.
+func LoadArchived(ctx context.Context, store ArchiveStore, id domain.SandboxID) (*ArchivedPayload, error) {
+	r, err := store.Get(ctx, archiveIndexKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotArchived, id)
+	}
+	batchKey, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+
+	r, err = store.Get(ctx, string(batchKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive batch %s: %w", batchKey, err)
+	}
+	defer r.Close()
+
+	var found *ArchivedPayload
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var entry ArchivedPayload
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Request == nil {
+			continue
+		}
+		if entry.Request.ID == id {
+			found = &entry
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read archive batch %s: %w", batchKey, err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotArchived, id)
+	}
+	return found, nil
+}
+
+func archiveIndexKey(id domain.SandboxID) string {
+	return ArchivePrefix + "index/" + string(id)
+}
+
+func copyRequest(req *domain.SandboxRequest) (*domain.SandboxRequest, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var out domain.SandboxRequest
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,111 @@
+package acheron
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+type memoryArchiveStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func (s *memoryArchiveStore) Put(ctx context.Context, key string, r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryArchiveStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestArchivingQueue(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryArchiveStore{objects: make(map[string][]byte), fail: true}
+	inner := NewMemoryQueue()
+	q := NewArchivingQueue(inner, store, "node-1", hermes.NewNoopMetrics(), hermes.NewNoopLogger())
+	q.now = func() time.Time { return time.Date(2026, 10, 17, 14, 5, 0, 0, time.UTC) }
+
+	for _, id := range []domain.SandboxID{"sb-ok", "sb-flaky"} {
+		if err := inner.Enqueue(ctx, &domain.SandboxRequest{ID: id, Template: "python", Env: map[string]string{"MODE": "prod"}}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	req, receipt, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	// Changes made by the consumer are not archived
+	req.Env["MODE"] = "changed"
+	q.Ack(ctx, receipt)
+
+	_, receipt, err = q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	q.Nack(ctx, receipt, "failed to launch")
+
+	// A failed batch is kept for the next one
+	if err := q.Flush(ctx); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+	store.fail = false
+	if err := q.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	var batches int
+	for key := range store.objects {
+		if strings.HasPrefix(key, ArchivePrefix+"2026/10/17/14/node-1-") {
+			batches++
+		}
+	}
+	if batches != 1 {
+		t.Errorf("expected one hourly batch, got %d in %v", batches, store.objects)
+	}
+
+	ok, err := LoadArchived(ctx, store, "sb-ok")
+	if err != nil {
+		t.Fatalf("LoadArchived failed: %v", err)
+	}
+	if ok.Outcome != ArchiveAcked || ok.Consumer != "node-1" || ok.Request.Env["MODE"] != "prod" {
+		t.Errorf("unexpected archived payload %+v", ok)
+	}
+	flaky, err := LoadArchived(ctx, store, "sb-flaky")
+	if err != nil {
+		t.Fatalf("LoadArchived failed: %v", err)
+	}
+	if flaky.Outcome != ArchiveNacked || flaky.Reason != "failed to launch" {
+		t.Errorf("expected the nack to be recorded, got %+v", flaky)
+	}
+
+	if _, err := LoadArchived(ctx, store, "sb-unknown"); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("expected ErrNotArchived, got %v", err)
+	}
+}
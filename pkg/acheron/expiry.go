@@ -0,0 +1,93 @@
+package acheron
+
+import (
+	"context"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ExpiryHandler is called for every request dropped because its TTL passed.
+type ExpiryHandler func(ctx context.Context, req *domain.SandboxRequest)
+
+// ExpiringQueue wraps a Queue with per-message TTLs. Requests without an
+// ExpiresAt are stamped with DefaultTTL on enqueue, and expired requests are
+// acknowledged and skipped on dequeue instead of being handed to the caller.
+type ExpiringQueue struct {
+	Queue
+	DefaultTTL time.Duration // Zero leaves requests without an explicit ExpiresAt unbounded
+	OnExpired  ExpiryHandler // Optional, e.g. to mark the run EXPIRED in Hades
+	metrics    hermes.Metrics
+	now        func() time.Time
+}
+
+func NewExpiringQueue(next Queue, defaultTTL time.Duration, metrics hermes.Metrics) *ExpiringQueue {
+	return &ExpiringQueue{
+		Queue:      next,
+		DefaultTTL: defaultTTL,
+		metrics:    metrics,
+		now:        time.Now,
+	}
+}
+
+func (q *ExpiringQueue) Enqueue(ctx context.Context, req *domain.SandboxRequest) error {
+	q.stamp(req, q.now())
+	return q.Queue.Enqueue(ctx, req)
+}
+
+// EnqueueAt measures the default TTL from when the request becomes visible.
+// Queues without delayed delivery enqueue immediately.
+func (q *ExpiringQueue) EnqueueAt(ctx context.Context, req *domain.SandboxRequest, at time.Time) error {
+	delayed, ok := q.Queue.(DelayedEnqueuer)
+	if !ok {
+		return q.Enqueue(ctx, req)
+	}
+	if now := q.now(); at.Before(now) {
+		at = now
+	}
+	q.stamp(req, at)
+	return delayed.EnqueueAt(ctx, req, at)
+}
+
+func (q *ExpiringQueue) Cancel(ctx context.Context, req *domain.SandboxRequest) error {
+	reorderer, ok := q.Queue.(Reorderer)
+	if !ok {
+		return ErrReorderUnsupported
+	}
+	return reorderer.Cancel(ctx, req)
+}
+
+func (q *ExpiringQueue) Boost(ctx context.Context, req *domain.SandboxRequest) error {
+	reorderer, ok := q.Queue.(Reorderer)
+	if !ok {
+		return ErrReorderUnsupported
+	}
+	return reorderer.Boost(ctx, req)
+}
+
+func (q *ExpiringQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, string, error) {
+	for {
+		req, receipt, err := q.Queue.Dequeue(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if !req.Expired(q.now()) {
+			return req, receipt, nil
+		}
+
+		q.metrics.IncCounter("queue_expired_total", 1, hermes.Label{Key: "template", Value: string(req.Template)})
+		if err := q.Queue.Ack(ctx, receipt); err != nil {
+			q.metrics.IncCounter("queue_expired_ack_errors_total", 1)
+		}
+		if q.OnExpired != nil {
+			q.OnExpired(ctx, req)
+		}
+	}
+}
+
+func (q *ExpiringQueue) stamp(req *domain.SandboxRequest, from time.Time) {
+	if req.ExpiresAt.IsZero() && q.DefaultTTL > 0 {
+		req.ExpiresAt = from.Add(q.DefaultTTL)
+	}
+}
@@ -0,0 +1,76 @@
+package acheron
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+func TestExpiringQueue_SkipsExpired(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	rq, err := NewRedisQueue(s.Addr(), 0, "test-queue", "group1", "consumer1", false, hermes.NewNoopMetrics(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	q := NewExpiringQueue(rq, time.Minute, hermes.NewNoopMetrics())
+
+	var expired []domain.SandboxID
+	q.OnExpired = func(ctx context.Context, req *domain.SandboxRequest) {
+		expired = append(expired, req.ID)
+	}
+
+	// Explicit TTL already passed
+	if err := q.Enqueue(ctx, &domain.SandboxRequest{ID: "stale", ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	// Default TTL stamped on enqueue
+	fresh := &domain.SandboxRequest{ID: "fresh"}
+	if err := q.Enqueue(ctx, fresh); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if fresh.ExpiresAt.IsZero() {
+		t.Fatal("Expected default TTL to be stamped")
+	}
+
+	req, receipt, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if req.ID != "fresh" {
+		t.Errorf("Expected fresh request, got %s", req.ID)
+	}
+	if len(expired) != 1 || expired[0] != "stale" {
+		t.Errorf("Expected stale request to be reported expired, got %v", expired)
+	}
+	if err := q.Ack(ctx, receipt); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+
+	// The expired message was acknowledged, not left pending
+	pending, err := rq.client.XPending(ctx, "test-queue", "group1").Result()
+	if err != nil {
+		t.Fatalf("XPending failed: %v", err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("Expected no pending messages, got %d", pending.Count)
+	}
+}
+
+func TestExpiringQueue_DelayedTTLStartsWhenVisible(t *testing.T) {
+	q := NewExpiringQueue(NewMemoryQueue(), time.Minute, hermes.NewNoopMetrics())
+	at := time.Now().Add(time.Hour)
+
+	req := &domain.SandboxRequest{ID: "later"}
+	if err := q.EnqueueAt(context.Background(), req, at); err != nil {
+		t.Fatalf("EnqueueAt failed: %v", err)
+	}
+	if want := at.Add(time.Minute); !req.ExpiresAt.Equal(want) {
+		t.Errorf("Expected ExpiresAt %v, got %v", want, req.ExpiresAt)
+	}
+}
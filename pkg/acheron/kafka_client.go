@@ -0,0 +1,604 @@
+package acheron
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// kafkaVersions pins the version of every request the Kafka client sends,
+// by API key. They are the newest versions without flexible encoding and
+// are accepted by brokers from Kafka 2.1 to 4.x, so the client needs no
+// version negotiation.
+var kafkaVersions = map[int16]int16{
+	0:  3, // Produce
+	1:  4, // Fetch
+	2:  1, // ListOffsets
+	3:  4, // Metadata
+	8:  2, // OffsetCommit
+	9:  1, // OffsetFetch
+	10: 1, // FindCoordinator
+	11: 2, // JoinGroup
+	12: 1, // Heartbeat
+	13: 1, // LeaveGroup
+	14: 1, // SyncGroup
+}
+
+// Kafka error codes the queue acts on.
+const (
+	kafkaOffsetOutOfRange        int16 = 1
+	kafkaUnknownTopicOrPartition int16 = 3
+	kafkaLeaderNotAvailable      int16 = 5
+	kafkaNotLeaderForPartition   int16 = 6
+	kafkaCoordinatorLoading      int16 = 14
+	kafkaCoordinatorNotAvailable int16 = 15
+	kafkaNotCoordinator          int16 = 16
+	kafkaIllegalGeneration       int16 = 22
+	kafkaUnknownMemberID         int16 = 25
+	kafkaRebalanceInProgress     int16 = 27
+)
+
+// kafkaError is an error code returned by a broker.
+type kafkaError int16
+
+func (e kafkaError) Error() string {
+	return fmt.Sprintf("kafka error code %d", int16(e))
+}
+
+func kafkaErr(code int16) error {
+	if code == 0 {
+		return nil
+	}
+	return kafkaError(code)
+}
+
+// kafkaCode returns the broker error code carried by err, 0 if there is none.
+func kafkaCode(err error) int16 {
+	var kerr kafkaError
+	if errors.As(err, &kerr) {
+		return int16(kerr)
+	}
+	return 0
+}
+
+// Offsets that ListOffsets resolves to the end and the start of a partition.
+const (
+	kafkaLatestOffset   int64 = -1
+	kafkaEarliestOffset int64 = -2
+)
+
+const kafkaRequestTimeout = 30 * time.Second
+
+// kafkaMessage is a record read from a partition. Batches the client
+// cannot decode come back as one message at their last offset, with the
+// reason as unreadable and the raw batch as value, so that consumers can
+// dead-letter them and move on.
+type kafkaMessage struct {
+	partition  int32
+	offset     int64
+	key        []byte
+	value      []byte
+	headers    []kmsg.Header
+	unreadable string
+}
+
+// kafkaConn is a connection to one broker. Requests on it are serialized.
+type kafkaConn struct {
+	mu          sync.Mutex
+	conn        net.Conn
+	correlation int32
+}
+
+// kafkaClient speaks the subset of the Kafka protocol the queue needs. It
+// keeps a connection per broker and caches partition leaders.
+type kafkaClient struct {
+	seeds     []string
+	formatter *kmsg.RequestFormatter
+
+	mu      sync.Mutex
+	conns   map[string]*kafkaConn
+	brokers map[int32]string
+	leaders map[string][]int32 // Topic -> leader broker by partition
+}
+
+func newKafkaClient(seeds []string, clientID string) *kafkaClient {
+	return &kafkaClient{
+		seeds:     seeds,
+		formatter: kmsg.NewRequestFormatter(kmsg.FormatterClientID(clientID)),
+		conns:     make(map[string]*kafkaConn),
+		brokers:   make(map[int32]string),
+		leaders:   make(map[string][]int32),
+	}
+}
+
+// request sends req to the broker at addr and returns its response.
+// Connections that fail are dropped and redialed by the next request.
+func (c *kafkaClient) request(ctx context.Context, addr string, req kmsg.Request) (kmsg.Response, error) {
+	req.SetVersion(kafkaVersions[req.Key()])
+
+	c.mu.Lock()
+	kc, ok := c.conns[addr]
+	if !ok {
+		kc = &kafkaConn{}
+		c.conns[addr] = kc
+	}
+	c.mu.Unlock()
+
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+
+	if kc.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to kafka broker %s: %w", addr, err)
+		}
+		kc.conn = conn
+	}
+
+	resp, err := kc.roundTrip(ctx, c.formatter, req)
+	if err != nil {
+		kc.conn.Close()
+		kc.conn = nil
+		return nil, fmt.Errorf("kafka request to %s failed: %w", addr, err)
+	}
+	return resp, nil
+}
+
+func (kc *kafkaConn) roundTrip(ctx context.Context, formatter *kmsg.RequestFormatter, req kmsg.Request) (kmsg.Response, error) {
+	deadline := time.Now().Add(kafkaRequestTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := kc.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	kc.correlation++
+	if _, err := kc.conn.Write(formatter.AppendRequest(nil, req, kc.correlation)); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(kc.conn, size[:]); err != nil {
+		return nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(kc.conn, body); err != nil {
+		return nil, err
+	}
+	if len(body) < 4 || int32(binary.BigEndian.Uint32(body)) != kc.correlation {
+		return nil, errors.New("response does not match the request")
+	}
+
+	resp := req.ResponseKind()
+	resp.SetVersion(req.GetVersion())
+	if err := resp.ReadFrom(body[4:]); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp, nil
+}
+
+// anyBroker sends req to the first broker that answers, known brokers
+// before seeds.
+func (c *kafkaClient) anyBroker(ctx context.Context, req kmsg.Request) (kmsg.Response, error) {
+	c.mu.Lock()
+	addrs := make([]string, 0, len(c.brokers)+len(c.seeds))
+	for _, addr := range c.brokers {
+		addrs = append(addrs, addr)
+	}
+	c.mu.Unlock()
+	addrs = append(addrs, c.seeds...)
+
+	err := errors.New("no kafka brokers configured")
+	for _, addr := range addrs {
+		var resp kmsg.Response
+		if resp, err = c.request(ctx, addr, req); err == nil {
+			return resp, nil
+		}
+	}
+	return nil, err
+}
+
+// refreshMetadata reloads the brokers and the partition leaders of topic.
+// Brokers that allow it create missing topics with their default settings.
+func (c *kafkaClient) refreshMetadata(ctx context.Context, topic string) error {
+	req := kmsg.NewPtrMetadataRequest()
+	mt := kmsg.NewMetadataRequestTopic()
+	mt.Topic = kmsg.StringPtr(topic)
+	req.Topics = append(req.Topics, mt)
+	req.AllowAutoTopicCreation = true
+
+	r, err := c.anyBroker(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp := r.(*kmsg.MetadataResponse)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range resp.Brokers {
+		c.brokers[b.NodeID] = net.JoinHostPort(b.Host, strconv.Itoa(int(b.Port)))
+	}
+	for _, t := range resp.Topics {
+		if t.Topic == nil || *t.Topic != topic {
+			continue
+		}
+		if err := kafkaErr(t.ErrorCode); err != nil {
+			delete(c.leaders, topic)
+			return fmt.Errorf("topic %s is not available: %w", topic, err)
+		}
+		leaders := make([]int32, len(t.Partitions))
+		for _, p := range t.Partitions {
+			if int(p.Partition) < len(leaders) {
+				leaders[p.Partition] = p.Leader
+			}
+		}
+		c.leaders[topic] = leaders
+		return nil
+	}
+	return fmt.Errorf("topic %s is not available: %w", topic, kafkaError(kafkaUnknownTopicOrPartition))
+}
+
+// partitions returns the partition leaders of topic, loading them on first
+// use. New topics may take a moment to get leaders, so it retries briefly.
+func (c *kafkaClient) partitions(ctx context.Context, topic string) ([]int32, error) {
+	c.mu.Lock()
+	leaders, ok := c.leaders[topic]
+	c.mu.Unlock()
+	if ok {
+		return leaders, nil
+	}
+
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		if err = c.refreshMetadata(ctx, topic); err == nil {
+			c.mu.Lock()
+			leaders = c.leaders[topic]
+			c.mu.Unlock()
+			return leaders, nil
+		}
+		code := kafkaCode(err)
+		if code != kafkaLeaderNotAvailable && code != kafkaUnknownTopicOrPartition {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 100 * time.Millisecond):
+		}
+	}
+	return nil, err
+}
+
+// leader returns the address of the broker leading the partition.
+func (c *kafkaClient) leader(ctx context.Context, topic string, partition int32) (string, error) {
+	leaders, err := c.partitions(ctx, topic)
+	if err != nil {
+		return "", err
+	}
+	if int(partition) >= len(leaders) {
+		return "", fmt.Errorf("topic %s has no partition %d", topic, partition)
+	}
+	c.mu.Lock()
+	addr, ok := c.brokers[leaders[partition]]
+	c.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("partition %s/%d has no leader", topic, partition)
+	}
+	return addr, nil
+}
+
+// forget drops the cached leaders of topic after a broker reported that
+// they moved.
+func (c *kafkaClient) forget(topic string) {
+	c.mu.Lock()
+	delete(c.leaders, topic)
+	c.mu.Unlock()
+}
+
+// produce appends a record to the partition, waiting for all in-sync
+// replicas, and returns its offset.
+func (c *kafkaClient) produce(ctx context.Context, topic string, partition int32, key, value []byte, headers ...kmsg.Header) (int64, error) {
+	addr, err := c.leader(ctx, topic, partition)
+	if err != nil {
+		return 0, err
+	}
+
+	req := kmsg.NewPtrProduceRequest()
+	req.Acks = -1
+	req.TimeoutMillis = 10000
+	rt := kmsg.NewProduceRequestTopic()
+	rt.Topic = topic
+	rp := kmsg.NewProduceRequestTopicPartition()
+	rp.Partition = partition
+	rp.Records = encodeKafkaBatch(key, value, headers, time.Now())
+	rt.Partitions = append(rt.Partitions, rp)
+	req.Topics = append(req.Topics, rt)
+
+	r, err := c.request(ctx, addr, req)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range r.(*kmsg.ProduceResponse).Topics {
+		for _, p := range t.Partitions {
+			if err := kafkaErr(p.ErrorCode); err != nil {
+				if p.ErrorCode == kafkaNotLeaderForPartition || p.ErrorCode == kafkaUnknownTopicOrPartition {
+					c.forget(topic)
+				}
+				return 0, err
+			}
+			return p.BaseOffset, nil
+		}
+	}
+	return 0, errors.New("produce response carries no partition")
+}
+
+// fetch reads records of the partitions from the given offsets, waiting up
+// to maxWait for new ones. Partitions the broker no longer leads are
+// skipped and their leaders reloaded. Offsets out of range are returned
+// separately so the caller can reset them.
+func (c *kafkaClient) fetch(ctx context.Context, topic string, offsets map[int32]int64, maxWait time.Duration) ([]kafkaMessage, []int32, error) {
+	byLeader := make(map[string][]int32)
+	for partition := range offsets {
+		addr, err := c.leader(ctx, topic, partition)
+		if err != nil {
+			return nil, nil, err
+		}
+		byLeader[addr] = append(byLeader[addr], partition)
+	}
+
+	var messages []kafkaMessage
+	var outOfRange []int32
+	for addr, partitions := range byLeader {
+		req := kmsg.NewPtrFetchRequest()
+		req.MaxWaitMillis = int32(maxWait / time.Millisecond)
+		req.MinBytes = 1
+		req.MaxBytes = 16 << 20
+		req.IsolationLevel = 1 // Read committed
+		rt := kmsg.NewFetchRequestTopic()
+		rt.Topic = topic
+		for _, partition := range partitions {
+			rp := kmsg.NewFetchRequestTopicPartition()
+			rp.Partition = partition
+			rp.FetchOffset = offsets[partition]
+			rp.PartitionMaxBytes = 4 << 20
+			rt.Partitions = append(rt.Partitions, rp)
+		}
+		req.Topics = append(req.Topics, rt)
+
+		r, err := c.request(ctx, addr, req)
+		if err != nil {
+			return messages, outOfRange, err
+		}
+		for _, t := range r.(*kmsg.FetchResponse).Topics {
+			for _, p := range t.Partitions {
+				switch p.ErrorCode {
+				case 0:
+				case kafkaOffsetOutOfRange:
+					outOfRange = append(outOfRange, p.Partition)
+					continue
+				case kafkaNotLeaderForPartition, kafkaUnknownTopicOrPartition:
+					c.forget(topic)
+					continue
+				default:
+					return messages, outOfRange, kafkaError(p.ErrorCode)
+				}
+				messages = append(messages, decodeKafkaBatches(p.Partition, p.RecordBatches, offsets[p.Partition])...)
+			}
+		}
+	}
+	return messages, outOfRange, nil
+}
+
+// listOffsets resolves kafkaLatestOffset or kafkaEarliestOffset for every
+// partition of topic.
+func (c *kafkaClient) listOffsets(ctx context.Context, topic string, at int64) (map[int32]int64, error) {
+	leaders, err := c.partitions(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	byLeader := make(map[string][]int32)
+	for partition := range leaders {
+		addr, err := c.leader(ctx, topic, int32(partition))
+		if err != nil {
+			return nil, err
+		}
+		byLeader[addr] = append(byLeader[addr], int32(partition))
+	}
+
+	offsets := make(map[int32]int64, len(leaders))
+	for addr, partitions := range byLeader {
+		req := kmsg.NewPtrListOffsetsRequest()
+		req.ReplicaID = -1
+		rt := kmsg.NewListOffsetsRequestTopic()
+		rt.Topic = topic
+		for _, partition := range partitions {
+			rp := kmsg.NewListOffsetsRequestTopicPartition()
+			rp.Partition = partition
+			rp.Timestamp = at
+			rt.Partitions = append(rt.Partitions, rp)
+		}
+		req.Topics = append(req.Topics, rt)
+
+		r, err := c.request(ctx, addr, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range r.(*kmsg.ListOffsetsResponse).Topics {
+			for _, p := range t.Partitions {
+				if err := kafkaErr(p.ErrorCode); err != nil {
+					c.forget(topic)
+					return nil, err
+				}
+				offsets[p.Partition] = p.Offset
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// coordinator returns the address of the group's coordinator.
+func (c *kafkaClient) coordinator(ctx context.Context, group string) (string, error) {
+	req := kmsg.NewPtrFindCoordinatorRequest()
+	req.CoordinatorKey = group
+	r, err := c.anyBroker(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	resp := r.(*kmsg.FindCoordinatorResponse)
+	if err := kafkaErr(resp.ErrorCode); err != nil {
+		return "", fmt.Errorf("no coordinator for group %s: %w", group, err)
+	}
+	return net.JoinHostPort(resp.Host, strconv.Itoa(int(resp.Port))), nil
+}
+
+// close closes every broker connection.
+func (c *kafkaClient) close() {
+	c.mu.Lock()
+	conns := c.conns
+	c.conns = make(map[string]*kafkaConn)
+	c.mu.Unlock()
+	for _, kc := range conns {
+		kc.mu.Lock()
+		if kc.conn != nil {
+			kc.conn.Close()
+			kc.conn = nil
+		}
+		kc.mu.Unlock()
+	}
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// encodeKafkaBatch encodes a single uncompressed record as a v2 record
+// batch.
+func encodeKafkaBatch(key, value []byte, headers []kmsg.Header, at time.Time) []byte {
+	var body []byte
+	body = append(body, 0)              // Attributes
+	body = binary.AppendVarint(body, 0) // Timestamp delta
+	body = binary.AppendVarint(body, 0) // Offset delta
+	body = binary.AppendVarint(body, int64(len(key)))
+	body = append(body, key...)
+	body = binary.AppendVarint(body, int64(len(value)))
+	body = append(body, value...)
+	body = binary.AppendVarint(body, int64(len(headers)))
+	for _, h := range headers {
+		body = binary.AppendVarint(body, int64(len(h.Key)))
+		body = append(body, h.Key...)
+		body = binary.AppendVarint(body, int64(len(h.Value)))
+		body = append(body, h.Value...)
+	}
+	record := binary.AppendVarint(nil, int64(len(body)))
+	record = append(record, body...)
+
+	batch := kmsg.NewRecordBatch()
+	batch.Magic = 2
+	batch.FirstTimestamp = at.UnixMilli()
+	batch.MaxTimestamp = batch.FirstTimestamp
+	batch.ProducerID = -1
+	batch.ProducerEpoch = -1
+	batch.FirstSequence = -1
+	batch.NumRecords = 1
+	batch.Records = record
+	b := batch.AppendTo(nil)
+
+	binary.BigEndian.PutUint32(b[8:12], uint32(len(b)-12))
+	binary.BigEndian.PutUint32(b[17:21], crc32.Checksum(b[21:], crc32c))
+	return b
+}
+
+// decodeKafkaBatches decodes the record batches of a fetch response,
+// skipping records before from and control batches. Brokers may cut the
+// last batch short; it is dropped and fetched again next time. Batches
+// that are not v2, are compressed with another codec than gzip or are
+// corrupt come back unreadable.
+func decodeKafkaBatches(partition int32, data []byte, from int64) []kafkaMessage {
+	var messages []kafkaMessage
+	for len(data) >= 17 {
+		size := 12 + int(int32(binary.BigEndian.Uint32(data[8:12])))
+		if size > len(data) {
+			break
+		}
+		raw := data[:size]
+		data = data[size:]
+
+		var batch kmsg.RecordBatch
+		if raw[16] != 2 || batch.ReadFrom(raw) != nil {
+			messages = appendUnreadable(messages, partition, raw, from, "unsupported_record_batch")
+			continue
+		}
+		if batch.Attributes&0x20 != 0 { // Control batch
+			continue
+		}
+
+		records := batch.Records
+		switch batch.Attributes & 0x07 {
+		case 0:
+		case 1:
+			var err error
+			if records, err = gunzip(records); err != nil {
+				messages = appendUnreadable(messages, partition, raw, from, "corrupt_record_batch")
+				continue
+			}
+		default:
+			messages = appendUnreadable(messages, partition, raw, from, "unsupported_compression")
+			continue
+		}
+
+		for i := int32(0); i < batch.NumRecords && len(records) > 0; i++ {
+			length, n := binary.Varint(records)
+			var record kmsg.Record
+			if n <= 0 || length < 0 || int(length) > len(records)-n || record.ReadFrom(records[:n+int(length)]) != nil {
+				messages = appendUnreadable(messages, partition, raw, from, "corrupt_record_batch")
+				break
+			}
+			records = records[n+int(length):]
+
+			offset := batch.FirstOffset + int64(record.OffsetDelta)
+			if offset < from {
+				continue
+			}
+			messages = append(messages, kafkaMessage{
+				partition: partition,
+				offset:    offset,
+				key:       record.Key,
+				value:     record.Value,
+				headers:   record.Headers,
+			})
+		}
+	}
+	return messages
+}
+
+// appendUnreadable appends the placeholder of a batch that cannot be
+// decoded, unless it ends before from.
+func appendUnreadable(messages []kafkaMessage, partition int32, raw []byte, from int64, reason string) []kafkaMessage {
+	last := int64(binary.BigEndian.Uint64(raw[0:8]))
+	if len(raw) >= 27 {
+		last += int64(int32(binary.BigEndian.Uint32(raw[23:27])))
+	}
+	if last < from {
+		return messages
+	}
+	return append(messages, kafkaMessage{partition: partition, offset: last, value: raw, unreadable: reason})
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
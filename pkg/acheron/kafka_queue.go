@@ -0,0 +1,799 @@
+package acheron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cocytus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+const (
+	kafkaSessionTimeout   = 30 * time.Second
+	kafkaRebalanceTimeout = 20 * time.Second
+	kafkaHeartbeat        = 3 * time.Second
+	kafkaFetchWait        = 500 * time.Millisecond
+)
+
+// KafkaNodeTopic is the topic requests routed to nodeID are kept in.
+// Characters Kafka does not allow in topic names are replaced by '_'.
+func KafkaNodeTopic(topic, nodeID string) string {
+	name := []byte(nodeID)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			name[i] = '_'
+		}
+	}
+	return topic + "." + string(name)
+}
+
+// kafkaPartition is the consumer's position in an assigned partition.
+type kafkaPartition struct {
+	fetch     int64            // Next offset to fetch
+	delivered int64            // Offset after the last delivered message
+	committed int64            // Offset last committed for the group
+	unacked   map[int64][]byte // Delivered and not acked yet, with their payload
+}
+
+// commitPoint is the offset the group may resume the partition from: the
+// first message not acked yet.
+func (p *kafkaPartition) commitPoint() int64 {
+	point := p.delivered
+	for offset := range p.unacked {
+		if offset < point {
+			point = offset
+		}
+	}
+	return point
+}
+
+// KafkaQueue keeps requests in Kafka topics: routed requests in the topic
+// of their node (see KafkaNodeTopic), the others in the topic itself, and
+// poison pills in "<topic>.dlq". Consumers join the consumer group, which
+// shares the partitions of the topic among them, and commit each partition
+// up to its first message not acked yet, so a message is delivered again
+// after a restart or rebalance unless it was acked: delivery is at least
+// once. Nack re-produces the request to the end of the topic.
+//
+// Topics are created by the brokers on first use and take their partition
+// count and replication from the broker defaults. Requests are spread over
+// partitions by ID. The queue has a single lane and does not hold requests
+// back, cancel or boost them.
+type KafkaQueue struct {
+	client        *kafkaClient
+	topic         string
+	dlqTopic      string
+	consumerGroup string
+	consumerName  string
+	routing       bool
+	metrics       hermes.Metrics
+	sink          cocytus.Sink // Optional: for poison-pill audit trail
+
+	joinMu   sync.Mutex // Serializes joins
+	commitMu sync.Mutex // Serializes offset commits
+
+	mu          sync.Mutex
+	coordinator string
+	memberID    string
+	generation  int32
+	joined      bool
+	stopBeat    chan struct{}
+	partitions  map[int32]*kafkaPartition
+	buffered    []kafkaMessage
+}
+
+func NewKafkaQueue(brokers []string, topic string, consumerGroup string, consumerName string, routing bool, metrics hermes.Metrics, sink cocytus.Sink) (*KafkaQueue, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("no kafka brokers configured")
+	}
+	clientID := consumerName
+	if clientID == "" {
+		clientID = "acheron"
+	}
+
+	q := &KafkaQueue{
+		client:        newKafkaClient(brokers, clientID),
+		topic:         topic,
+		dlqTopic:      topic + ".dlq",
+		consumerGroup: consumerGroup,
+		consumerName:  consumerName,
+		routing:       routing,
+		metrics:       metrics,
+		sink:          sink,
+		partitions:    make(map[int32]*kafkaPartition),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := q.client.partitions(ctx, topic); err != nil {
+		q.client.close()
+		return nil, fmt.Errorf("failed to connect to kafka: %w", err)
+	}
+	return q, nil
+}
+
+// targetTopic is the topic a request is routed to.
+func (q *KafkaQueue) targetTopic(req *domain.SandboxRequest) string {
+	if q.routing && req.NodeID != "" {
+		return KafkaNodeTopic(q.topic, string(req.NodeID))
+	}
+	return q.topic
+}
+
+// produce appends a record to the partition of topic its key hashes to,
+// retrying once if the partition leader moved.
+func (q *KafkaQueue) produce(ctx context.Context, topic string, key, value []byte, headers ...kmsg.Header) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var leaders []int32
+		if leaders, err = q.client.partitions(ctx, topic); err != nil {
+			return err
+		}
+		h := fnv.New32a()
+		h.Write(key)
+		partition := int32(h.Sum32() % uint32(len(leaders)))
+
+		if _, err = q.client.produce(ctx, topic, partition, key, value, headers...); err == nil {
+			return nil
+		}
+		if code := kafkaCode(err); code != kafkaNotLeaderForPartition && code != kafkaUnknownTopicOrPartition {
+			return err
+		}
+	}
+	return err
+}
+
+func (q *KafkaQueue) Enqueue(ctx context.Context, req *domain.SandboxRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	topic := q.targetTopic(req)
+	if err := q.produce(ctx, topic, []byte(req.ID), data); err != nil {
+		q.metrics.IncCounter("queue_enqueue_errors_total", 1, hermes.Label{Key: "queue", Value: topic})
+		return fmt.Errorf("failed to enqueue request: %w", err)
+	}
+
+	q.metrics.IncCounter("queue_enqueue_total", 1, hermes.Label{Key: "queue", Value: topic})
+	return nil
+}
+
+func (q *KafkaQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, string, error) {
+	if q.consumerGroup == "" || q.consumerName == "" {
+		return nil, "", fmt.Errorf("consumer group/name not configured for dequeue")
+	}
+
+	for {
+		msg, err := q.next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, "", ctx.Err()
+			}
+			return nil, "", fmt.Errorf("failed to dequeue: %w", err)
+		}
+		receipt := fmt.Sprintf("%d:%d", msg.partition, msg.offset)
+
+		if msg.unreadable != "" {
+			q.moveToDLQ(ctx, msg, receipt, msg.unreadable)
+			continue
+		}
+
+		var req domain.SandboxRequest
+		if err := json.Unmarshal(msg.value, &req); err != nil {
+			// Corrupt JSON payload
+			q.moveToDLQ(ctx, msg, receipt, "json_unmarshal_error")
+			continue
+		}
+
+		q.metrics.IncCounter("queue_dequeue_total", 1, hermes.Label{Key: "queue", Value: q.topic})
+		return &req, receipt, nil
+	}
+}
+
+// next returns the next message of the assigned partitions, joining the
+// group first if the consumer is not a member, or after a rebalance.
+func (q *KafkaQueue) next(ctx context.Context) (kafkaMessage, error) {
+	for {
+		if ctx.Err() != nil {
+			return kafkaMessage{}, ctx.Err()
+		}
+
+		q.mu.Lock()
+		if !q.joined {
+			q.mu.Unlock()
+			if err := q.join(ctx); err != nil {
+				return kafkaMessage{}, err
+			}
+			continue
+		}
+		if len(q.buffered) > 0 {
+			msg := q.buffered[0]
+			q.buffered = q.buffered[1:]
+			p := q.partitions[msg.partition]
+			p.unacked[msg.offset] = msg.value
+			p.delivered = msg.offset + 1
+			q.mu.Unlock()
+			return msg, nil
+		}
+		generation := q.generation
+		offsets := make(map[int32]int64, len(q.partitions))
+		for partition, p := range q.partitions {
+			offsets[partition] = p.fetch
+		}
+		q.mu.Unlock()
+
+		if len(offsets) == 0 {
+			// More consumers than partitions: wait for a rebalance
+			select {
+			case <-ctx.Done():
+			case <-time.After(kafkaFetchWait):
+			}
+			continue
+		}
+
+		messages, outOfRange, err := q.client.fetch(ctx, q.topic, offsets, kafkaFetchWait)
+		if err != nil {
+			return kafkaMessage{}, err
+		}
+
+		var earliest map[int32]int64
+		if len(outOfRange) > 0 {
+			if earliest, err = q.client.listOffsets(ctx, q.topic, kafkaEarliestOffset); err != nil {
+				return kafkaMessage{}, err
+			}
+		}
+
+		q.mu.Lock()
+		if q.generation == generation && q.joined {
+			for _, msg := range messages {
+				if p := q.partitions[msg.partition]; p != nil && msg.offset >= p.fetch {
+					q.buffered = append(q.buffered, msg)
+					p.fetch = msg.offset + 1
+				}
+			}
+			for _, partition := range outOfRange {
+				if p := q.partitions[partition]; p != nil {
+					p.fetch = earliest[partition]
+				}
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+// moveToDLQ writes a message that cannot be delivered to the dead-letter
+// topic, with the reason and where it came from as headers, and acks it.
+// If the write fails the message stays unacked and is delivered again
+// after a restart.
+func (q *KafkaQueue) moveToDLQ(ctx context.Context, msg kafkaMessage, receipt string, errorReason string) {
+	// Write poison-pill to Cocytus for audit trail (best-effort)
+	if q.sink != nil {
+		id := string(msg.key)
+		if id == "" {
+			id = receipt
+		}
+		rec := &cocytus.Record{
+			RequestID: domain.SandboxID(id),
+			Reason:    fmt.Sprintf("poison_pill: %s", errorReason),
+			Payload:   msg.value,
+			CreatedAt: time.Now(),
+		}
+
+		// Use a detached context with timeout to avoid blocking
+		cocytusCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		if wErr := q.sink.Write(cocytusCtx, rec); wErr != nil {
+			q.metrics.IncCounter("queue_poison_pill_cocytus_write_errors_total", 1, hermes.Label{Key: "queue", Value: q.topic})
+		}
+	}
+
+	err := q.produce(ctx, q.dlqTopic, msg.key, msg.value,
+		kmsg.Header{Key: "error_reason", Value: []byte(errorReason)},
+		kmsg.Header{Key: "source", Value: []byte(q.topic + "/" + receipt)},
+		kmsg.Header{Key: "timestamp", Value: []byte(strconv.FormatInt(time.Now().Unix(), 10))},
+	)
+	if err == nil {
+		err = q.Ack(ctx, receipt)
+	}
+	if err != nil {
+		q.metrics.IncCounter("queue_dlq_move_errors_total", 1, hermes.Label{Key: "queue", Value: q.topic})
+		return
+	}
+	q.metrics.IncCounter("queue_poison_pill_total", 1, hermes.Label{Key: "queue", Value: q.topic}, hermes.Label{Key: "reason", Value: errorReason})
+}
+
+// parseKafkaReceipt splits a receipt into partition and offset.
+func parseKafkaReceipt(receipt string) (int32, int64, error) {
+	p, o, ok := strings.Cut(receipt, ":")
+	partition, perr := strconv.ParseInt(p, 10, 32)
+	offset, oerr := strconv.ParseInt(o, 10, 64)
+	if !ok || perr != nil || oerr != nil {
+		return 0, 0, fmt.Errorf("invalid receipt %q", receipt)
+	}
+	return int32(partition), offset, nil
+}
+
+// Ack marks the message as done and commits its partition up to the first
+// message not acked yet. Receipts of partitions the consumer lost in a
+// rebalance are ignored: their messages go to the new owner again.
+func (q *KafkaQueue) Ack(ctx context.Context, receipt string) error {
+	partition, offset, err := parseKafkaReceipt(receipt)
+	if err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+
+	q.mu.Lock()
+	if p := q.partitions[partition]; p != nil {
+		delete(p.unacked, offset)
+	}
+	q.mu.Unlock()
+
+	if err := q.commit(ctx); err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+	return nil
+}
+
+// commit commits every partition whose commit point has advanced.
+func (q *KafkaQueue) commit(ctx context.Context) error {
+	q.commitMu.Lock()
+	defer q.commitMu.Unlock()
+
+	q.mu.Lock()
+	if !q.joined {
+		q.mu.Unlock()
+		return nil
+	}
+	req := kmsg.NewPtrOffsetCommitRequest()
+	req.Group = q.consumerGroup
+	req.Generation = q.generation
+	req.MemberID = q.memberID
+	req.RetentionTimeMillis = -1
+	rt := kmsg.NewOffsetCommitRequestTopic()
+	rt.Topic = q.topic
+	points := make(map[int32]int64)
+	for partition, p := range q.partitions {
+		if point := p.commitPoint(); point > p.committed {
+			rp := kmsg.NewOffsetCommitRequestTopicPartition()
+			rp.Partition = partition
+			rp.Offset = point
+			rt.Partitions = append(rt.Partitions, rp)
+			points[partition] = point
+		}
+	}
+	q.mu.Unlock()
+	if len(points) == 0 {
+		return nil
+	}
+	req.Topics = append(req.Topics, rt)
+
+	r, err := q.groupRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, t := range r.(*kmsg.OffsetCommitResponse).Topics {
+		for _, p := range t.Partitions {
+			if err := q.groupError(req.Generation, p.ErrorCode); err != nil {
+				return err
+			}
+		}
+	}
+
+	q.mu.Lock()
+	for partition, point := range points {
+		if p := q.partitions[partition]; p != nil && point > p.committed {
+			p.committed = point
+		}
+	}
+	q.mu.Unlock()
+	return nil
+}
+
+// Nack re-produces the request to the end of the topic and acks it.
+func (q *KafkaQueue) Nack(ctx context.Context, receipt string, reason string) error {
+	partition, offset, err := parseKafkaReceipt(receipt)
+	if err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+
+	q.mu.Lock()
+	var data []byte
+	if p := q.partitions[partition]; p != nil {
+		data = p.unacked[offset]
+	}
+	q.mu.Unlock()
+	if data == nil {
+		// Acked already, or lost in a rebalance and delivered again anyway
+		return nil
+	}
+
+	var req domain.SandboxRequest
+	_ = json.Unmarshal(data, &req)
+	if err := q.produce(ctx, q.topic, []byte(req.ID), data); err != nil {
+		q.metrics.IncCounter("queue_nack_errors_total", 1, hermes.Label{Key: "queue", Value: q.topic})
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+
+	q.metrics.IncCounter("queue_nack_total", 1, hermes.Label{Key: "queue", Value: q.topic})
+	return q.Ack(ctx, receipt)
+}
+
+// Len returns the number of messages behind the consumer group's committed
+// offsets, or the number of messages kept in the topic without a group.
+func (q *KafkaQueue) Len(ctx context.Context) int {
+	latest, err := q.client.listOffsets(ctx, q.topic, kafkaLatestOffset)
+	if err != nil {
+		return 0
+	}
+	start, err := q.client.listOffsets(ctx, q.topic, kafkaEarliestOffset)
+	if err != nil {
+		return 0
+	}
+	if q.consumerGroup != "" {
+		partitions := make([]int32, 0, len(latest))
+		for partition := range latest {
+			partitions = append(partitions, partition)
+		}
+		committed, err := q.committedOffsets(ctx, partitions)
+		if err != nil {
+			return 0
+		}
+		for partition, offset := range committed {
+			if offset > start[partition] {
+				start[partition] = offset
+			}
+		}
+	}
+
+	depth := 0
+	for partition, end := range latest {
+		if end > start[partition] {
+			depth += int(end - start[partition])
+		}
+	}
+	q.metrics.SetGauge("queue_depth", float64(depth), hermes.Label{Key: "queue", Value: q.topic})
+	return depth
+}
+
+// groupRequest sends req to the group's coordinator, looking it up first
+// if it is not known. The coordinator is forgotten when it fails.
+func (q *KafkaQueue) groupRequest(ctx context.Context, req kmsg.Request) (kmsg.Response, error) {
+	q.mu.Lock()
+	addr := q.coordinator
+	q.mu.Unlock()
+	if addr == "" {
+		var err error
+		if addr, err = q.client.coordinator(ctx, q.consumerGroup); err != nil {
+			return nil, err
+		}
+		q.mu.Lock()
+		q.coordinator = addr
+		q.mu.Unlock()
+	}
+
+	resp, err := q.client.request(ctx, addr, req)
+	if err != nil {
+		q.forgetCoordinator()
+	}
+	return resp, err
+}
+
+func (q *KafkaQueue) forgetCoordinator() {
+	q.mu.Lock()
+	q.coordinator = ""
+	q.mu.Unlock()
+}
+
+// groupError handles an error code of a request made as a member of the
+// given generation. Codes that mean the membership is over make the
+// consumer rejoin; a lost coordinator is looked up again.
+func (q *KafkaQueue) groupError(generation int32, code int16) error {
+	switch code {
+	case 0:
+		return nil
+	case kafkaCoordinatorLoading, kafkaCoordinatorNotAvailable, kafkaNotCoordinator:
+		q.forgetCoordinator()
+	case kafkaRebalanceInProgress, kafkaIllegalGeneration, kafkaUnknownMemberID:
+		q.mu.Lock()
+		if q.generation == generation {
+			q.leave(code == kafkaUnknownMemberID)
+		}
+		q.mu.Unlock()
+	}
+	return kafkaError(code)
+}
+
+// leave ends the current membership locally. Callers hold q.mu.
+func (q *KafkaQueue) leave(forgetMember bool) {
+	q.joined = false
+	if forgetMember {
+		q.memberID = ""
+	}
+	if q.stopBeat != nil {
+		close(q.stopBeat)
+		q.stopBeat = nil
+	}
+}
+
+// committedOffsets returns the group's committed offsets of the partitions.
+// Partitions without one are left out.
+func (q *KafkaQueue) committedOffsets(ctx context.Context, partitions []int32) (map[int32]int64, error) {
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.Group = q.consumerGroup
+	rt := kmsg.NewOffsetFetchRequestTopic()
+	rt.Topic = q.topic
+	rt.Partitions = partitions
+	req.Topics = append(req.Topics, rt)
+
+	r, err := q.groupRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make(map[int32]int64)
+	for _, t := range r.(*kmsg.OffsetFetchResponse).Topics {
+		for _, p := range t.Partitions {
+			if err := q.groupError(-1, p.ErrorCode); err != nil {
+				return nil, err
+			}
+			if p.Offset >= 0 {
+				offsets[p.Partition] = p.Offset
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// join (re)joins the consumer group and takes over the partitions it is
+// assigned. Positions in partitions the consumer keeps survive, so
+// messages in flight during a rebalance are not delivered twice.
+func (q *KafkaQueue) join(ctx context.Context) error {
+	q.joinMu.Lock()
+	defer q.joinMu.Unlock()
+
+	for {
+		q.mu.Lock()
+		joined := q.joined
+		q.mu.Unlock()
+		if joined {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := q.joinOnce(ctx)
+		switch kafkaCode(err) {
+		case 0:
+			return err
+		case kafkaCoordinatorLoading, kafkaCoordinatorNotAvailable, kafkaNotCoordinator,
+			kafkaRebalanceInProgress, kafkaIllegalGeneration, kafkaUnknownMemberID:
+			// Retry with a fresh coordinator or membership
+		default:
+			return err
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (q *KafkaQueue) joinOnce(ctx context.Context) error {
+	metadata := kmsg.NewConsumerMemberMetadata()
+	metadata.Topics = []string{q.topic}
+
+	q.mu.Lock()
+	memberID := q.memberID
+	q.mu.Unlock()
+
+	joinReq := kmsg.NewPtrJoinGroupRequest()
+	joinReq.Group = q.consumerGroup
+	joinReq.SessionTimeoutMillis = int32(kafkaSessionTimeout / time.Millisecond)
+	joinReq.RebalanceTimeoutMillis = int32(kafkaRebalanceTimeout / time.Millisecond)
+	joinReq.MemberID = memberID
+	joinReq.ProtocolType = "consumer"
+	protocol := kmsg.NewJoinGroupRequestProtocol()
+	protocol.Name = "roundrobin"
+	protocol.Metadata = metadata.AppendTo(nil)
+	joinReq.Protocols = append(joinReq.Protocols, protocol)
+
+	r, err := q.groupRequest(ctx, joinReq)
+	if err != nil {
+		return err
+	}
+	joined := r.(*kmsg.JoinGroupResponse)
+	if joined.ErrorCode == kafkaUnknownMemberID {
+		q.mu.Lock()
+		q.memberID = ""
+		q.mu.Unlock()
+	}
+	if err := q.groupError(-1, joined.ErrorCode); err != nil {
+		return err
+	}
+
+	syncReq := kmsg.NewPtrSyncGroupRequest()
+	syncReq.Group = q.consumerGroup
+	syncReq.Generation = joined.Generation
+	syncReq.MemberID = joined.MemberID
+	if joined.LeaderID == joined.MemberID {
+		if syncReq.GroupAssignment, err = q.assign(ctx, joined.Members); err != nil {
+			return err
+		}
+	}
+	r, err = q.groupRequest(ctx, syncReq)
+	if err != nil {
+		return err
+	}
+	synced := r.(*kmsg.SyncGroupResponse)
+	if err := q.groupError(-1, synced.ErrorCode); err != nil {
+		return err
+	}
+
+	var assignment kmsg.ConsumerMemberAssignment
+	var assigned []int32
+	if len(synced.MemberAssignment) > 0 {
+		if err := assignment.ReadFrom(synced.MemberAssignment); err != nil {
+			return fmt.Errorf("failed to decode partition assignment: %w", err)
+		}
+	}
+	for _, t := range assignment.Topics {
+		if t.Topic == q.topic {
+			assigned = append(assigned, t.Partitions...)
+		}
+	}
+
+	// Keep the member ID so that a retry rejoins as the same member
+	q.mu.Lock()
+	q.memberID = joined.MemberID
+	q.mu.Unlock()
+	committed, err := q.committedOffsets(ctx, assigned)
+	if err != nil {
+		return err
+	}
+	var earliest map[int32]int64
+	if len(committed) < len(assigned) {
+		if earliest, err = q.client.listOffsets(ctx, q.topic, kafkaEarliestOffset); err != nil {
+			return err
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	partitions := make(map[int32]*kafkaPartition, len(assigned))
+	for _, partition := range assigned {
+		offset, ok := committed[partition]
+		if !ok {
+			offset = earliest[partition]
+		}
+		if p := q.partitions[partition]; p != nil && p.delivered >= offset {
+			partitions[partition] = p
+			continue
+		}
+		partitions[partition] = &kafkaPartition{fetch: offset, delivered: offset, committed: offset, unacked: make(map[int64][]byte)}
+	}
+	buffered := q.buffered[:0]
+	for _, msg := range q.buffered {
+		if partitions[msg.partition] != nil {
+			buffered = append(buffered, msg)
+		}
+	}
+	q.buffered = buffered
+	q.partitions = partitions
+	q.generation = joined.Generation
+	q.joined = true
+	q.stopBeat = make(chan struct{})
+	go q.heartbeat(q.generation, q.stopBeat)
+	return nil
+}
+
+// assign shares the partitions of every topic the members subscribe to
+// among its subscribers, round-robin in member order.
+func (q *KafkaQueue) assign(ctx context.Context, members []kmsg.JoinGroupResponseMember) ([]kmsg.SyncGroupRequestGroupAssignment, error) {
+	subscribers := make(map[string][]string)
+	for _, member := range members {
+		var metadata kmsg.ConsumerMemberMetadata
+		if err := metadata.ReadFrom(member.ProtocolMetadata); err != nil {
+			return nil, fmt.Errorf("failed to decode member metadata: %w", err)
+		}
+		for _, topic := range metadata.Topics {
+			subscribers[topic] = append(subscribers[topic], member.MemberID)
+		}
+	}
+
+	assigned := make(map[string]map[string][]int32)
+	for topic, ids := range subscribers {
+		leaders, err := q.client.partitions(ctx, topic)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(ids)
+		for partition := range leaders {
+			id := ids[partition%len(ids)]
+			if assigned[id] == nil {
+				assigned[id] = make(map[string][]int32)
+			}
+			assigned[id][topic] = append(assigned[id][topic], int32(partition))
+		}
+	}
+
+	var result []kmsg.SyncGroupRequestGroupAssignment
+	for _, member := range members {
+		assignment := kmsg.NewConsumerMemberAssignment()
+		for topic, partitions := range assigned[member.MemberID] {
+			t := kmsg.NewConsumerMemberAssignmentTopic()
+			t.Topic = topic
+			t.Partitions = partitions
+			assignment.Topics = append(assignment.Topics, t)
+		}
+		a := kmsg.NewSyncGroupRequestGroupAssignment()
+		a.MemberID = member.MemberID
+		a.MemberAssignment = assignment.AppendTo(nil)
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+// heartbeat keeps the membership of the generation alive until stop is
+// closed or the coordinator reports a rebalance.
+func (q *KafkaQueue) heartbeat(generation int32, stop chan struct{}) {
+	ticker := time.NewTicker(kafkaHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		q.mu.Lock()
+		req := kmsg.NewPtrHeartbeatRequest()
+		req.Group = q.consumerGroup
+		req.Generation = generation
+		req.MemberID = q.memberID
+		q.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), kafkaHeartbeat)
+		r, err := q.groupRequest(ctx, req)
+		cancel()
+		if err != nil {
+			continue
+		}
+		if err := q.groupError(generation, r.(*kmsg.HeartbeatResponse).ErrorCode); err != nil {
+			if code := kafkaCode(err); code == kafkaRebalanceInProgress || code == kafkaIllegalGeneration || code == kafkaUnknownMemberID {
+				return
+			}
+		}
+	}
+}
+
+// Close leaves the consumer group, so its partitions move to the other
+// members right away, and closes the broker connections.
+func (q *KafkaQueue) Close() error {
+	q.mu.Lock()
+	joined, memberID := q.joined, q.memberID
+	q.leave(true)
+	q.mu.Unlock()
+
+	if joined {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req := kmsg.NewPtrLeaveGroupRequest()
+		req.Group = q.consumerGroup
+		req.MemberID = memberID
+		_, _ = q.groupRequest(ctx, req)
+		cancel()
+	}
+	q.client.close()
+	return nil
+}
@@ -0,0 +1,407 @@
+package acheron
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cocytus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// fakeKafka is a single-broker Kafka that keeps partitions and group
+// offsets in memory. Topics are created on first use with one partition.
+// Groups support one member at a time, which is enough for the queue.
+type fakeKafka struct {
+	ln   net.Listener
+	host string
+	port int32
+
+	mu         sync.Mutex
+	batches    map[string][][]byte // Topic -> batches of its partition 0
+	next       map[string]int64    // Topic -> next offset
+	committed  map[string]int64    // Group/topic -> committed offset of partition 0
+	generation int32
+	members    int
+	assignment []byte
+}
+
+func newFakeKafka(t *testing.T) *fakeKafka {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	f := &fakeKafka{
+		ln:        ln,
+		host:      host,
+		port:      int32(p),
+		batches:   make(map[string][][]byte),
+		next:      make(map[string]int64),
+		committed: make(map[string]int64),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeKafka) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeKafka) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		key := int16(binary.BigEndian.Uint16(body[0:2]))
+		version := int16(binary.BigEndian.Uint16(body[2:4]))
+		correlation := body[4:8]
+		clientID := int(int16(binary.BigEndian.Uint16(body[8:10])))
+		if clientID < 0 {
+			clientID = 0
+		}
+
+		req := kmsg.RequestForKey(key)
+		req.SetVersion(version)
+		if err := req.ReadFrom(body[10+clientID:]); err != nil {
+			return
+		}
+		resp := f.handle(req)
+		resp.SetVersion(version)
+
+		out := append([]byte{0, 0, 0, 0}, correlation...)
+		out = resp.AppendTo(out)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeKafka) handle(req kmsg.Request) kmsg.Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch req := req.(type) {
+	case *kmsg.MetadataRequest:
+		resp := req.ResponseKind().(*kmsg.MetadataResponse)
+		b := kmsg.NewMetadataResponseBroker()
+		b.Host, b.Port = f.host, f.port
+		resp.Brokers = append(resp.Brokers, b)
+		for _, rt := range req.Topics {
+			t := kmsg.NewMetadataResponseTopic()
+			t.Topic = rt.Topic
+			t.Partitions = append(t.Partitions, kmsg.NewMetadataResponseTopicPartition())
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.ProduceRequest:
+		resp := req.ResponseKind().(*kmsg.ProduceResponse)
+		for _, rt := range req.Topics {
+			t := kmsg.NewProduceResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				p := kmsg.NewProduceResponseTopicPartition()
+				raw := append([]byte(nil), rp.Records...)
+				p.BaseOffset = f.next[rt.Topic]
+				binary.BigEndian.PutUint64(raw[0:8], uint64(p.BaseOffset))
+				f.batches[rt.Topic] = append(f.batches[rt.Topic], raw)
+				f.next[rt.Topic] += int64(binary.BigEndian.Uint32(raw[23:27])) + 1
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.FetchRequest:
+		resp := req.ResponseKind().(*kmsg.FetchResponse)
+		for _, rt := range req.Topics {
+			t := kmsg.NewFetchResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				p := kmsg.NewFetchResponseTopicPartition()
+				p.HighWatermark = f.next[rt.Topic]
+				if rp.FetchOffset > f.next[rt.Topic] {
+					p.ErrorCode = kafkaOffsetOutOfRange
+				}
+				for _, raw := range f.batches[rt.Topic] {
+					last := int64(binary.BigEndian.Uint64(raw[0:8])) + int64(binary.BigEndian.Uint32(raw[23:27]))
+					if last >= rp.FetchOffset {
+						p.RecordBatches = append(p.RecordBatches, raw...)
+					}
+				}
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.ListOffsetsRequest:
+		resp := req.ResponseKind().(*kmsg.ListOffsetsResponse)
+		for _, rt := range req.Topics {
+			t := kmsg.NewListOffsetsResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				p := kmsg.NewListOffsetsResponseTopicPartition()
+				p.Partition, p.Offset = rp.Partition, 0
+				if rp.Timestamp == kafkaLatestOffset {
+					p.Offset = f.next[rt.Topic]
+				}
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.FindCoordinatorRequest:
+		resp := req.ResponseKind().(*kmsg.FindCoordinatorResponse)
+		resp.Host, resp.Port = f.host, f.port
+		return resp
+
+	case *kmsg.JoinGroupRequest:
+		resp := req.ResponseKind().(*kmsg.JoinGroupResponse)
+		f.generation++
+		f.members++
+		resp.Generation = f.generation
+		resp.MemberID = fmt.Sprintf("member-%d", f.members)
+		resp.LeaderID = resp.MemberID
+		resp.Protocol = kmsg.StringPtr(req.Protocols[0].Name)
+		m := kmsg.NewJoinGroupResponseMember()
+		m.MemberID = resp.MemberID
+		m.ProtocolMetadata = req.Protocols[0].Metadata
+		resp.Members = append(resp.Members, m)
+		return resp
+
+	case *kmsg.SyncGroupRequest:
+		resp := req.ResponseKind().(*kmsg.SyncGroupResponse)
+		for _, a := range req.GroupAssignment {
+			if a.MemberID == req.MemberID {
+				f.assignment = a.MemberAssignment
+			}
+		}
+		resp.MemberAssignment = f.assignment
+		return resp
+
+	case *kmsg.OffsetCommitRequest:
+		resp := req.ResponseKind().(*kmsg.OffsetCommitResponse)
+		for _, rt := range req.Topics {
+			t := kmsg.NewOffsetCommitResponseTopic()
+			t.Topic = rt.Topic
+			for _, rp := range rt.Partitions {
+				p := kmsg.NewOffsetCommitResponseTopicPartition()
+				p.Partition = rp.Partition
+				if req.Generation != f.generation {
+					p.ErrorCode = kafkaIllegalGeneration
+				} else {
+					f.committed[req.Group+"/"+rt.Topic] = rp.Offset
+				}
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	case *kmsg.OffsetFetchRequest:
+		resp := req.ResponseKind().(*kmsg.OffsetFetchResponse)
+		for _, rt := range req.Topics {
+			t := kmsg.NewOffsetFetchResponseTopic()
+			t.Topic = rt.Topic
+			for _, partition := range rt.Partitions {
+				p := kmsg.NewOffsetFetchResponseTopicPartition()
+				p.Partition = partition
+				p.Offset = -1
+				if offset, ok := f.committed[req.Group+"/"+rt.Topic]; ok {
+					p.Offset = offset
+				}
+				t.Partitions = append(t.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, t)
+		}
+		return resp
+
+	default:
+		// Heartbeat and LeaveGroup always succeed
+		return req.ResponseKind()
+	}
+}
+
+func newTestKafkaQueue(t *testing.T, broker *fakeKafka, topic string, group string, routing bool, sink cocytus.Sink) *KafkaQueue {
+	t.Helper()
+	q, err := NewKafkaQueue([]string{broker.addr()}, topic, group, "node-1", routing, hermes.NewNoopMetrics(), sink)
+	if err != nil {
+		t.Fatalf("NewKafkaQueue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func dequeueID(t *testing.T, q *KafkaQueue) (domain.SandboxID, string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, receipt, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	return req.ID, receipt
+}
+
+func TestKafkaQueue_RoutingAndAtLeastOnce(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeKafka(t)
+	producer := newTestKafkaQueue(t, broker, "tartarus.queue", "", true, nil)
+
+	for _, id := range []domain.SandboxID{"r1", "r2"} {
+		if err := producer.Enqueue(ctx, &domain.SandboxRequest{ID: id, NodeID: "node-1"}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	if producer.Len(ctx) != 0 {
+		t.Errorf("expected routed requests to stay out of the base topic")
+	}
+
+	topic := KafkaNodeTopic("tartarus.queue", "node-1")
+	consumer := newTestKafkaQueue(t, broker, topic, "workers", false, nil)
+	if got := consumer.Len(ctx); got != 2 {
+		t.Errorf("expected depth 2, got %d", got)
+	}
+
+	id1, _ := dequeueID(t, consumer)
+	id2, receipt2 := dequeueID(t, consumer)
+	if id1 != "r1" || id2 != "r2" {
+		t.Fatalf("expected r1 then r2, got %s then %s", id1, id2)
+	}
+
+	// r1 is not acked, so the group may only resume from it
+	if err := consumer.Ack(ctx, receipt2); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if got := consumer.Len(ctx); got != 2 {
+		t.Errorf("expected depth 2 while r1 is unacked, got %d", got)
+	}
+	consumer.Close()
+
+	// A new consumer gets everything after the first unacked message again
+	restarted := newTestKafkaQueue(t, broker, topic, "workers", false, nil)
+	id1, receipt1 := dequeueID(t, restarted)
+	id2, receipt2 = dequeueID(t, restarted)
+	if id1 != "r1" || id2 != "r2" {
+		t.Fatalf("expected r1 and r2 to be delivered again, got %s and %s", id1, id2)
+	}
+	for _, receipt := range []string{receipt1, receipt2} {
+		if err := restarted.Ack(ctx, receipt); err != nil {
+			t.Fatalf("Ack: %v", err)
+		}
+	}
+	if got := restarted.Len(ctx); got != 0 {
+		t.Errorf("expected depth 0 after acking everything, got %d", got)
+	}
+}
+
+func TestKafkaQueue_Nack(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeKafka(t)
+	q := newTestKafkaQueue(t, broker, "tartarus.queue.node-1", "workers", false, nil)
+
+	if err := q.Enqueue(ctx, &domain.SandboxRequest{ID: "r1"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	id, receipt := dequeueID(t, q)
+	if err := q.Nack(ctx, receipt, "node busy"); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	again, receipt := dequeueID(t, q)
+	if id != "r1" || again != "r1" {
+		t.Fatalf("expected r1 to be delivered again, got %s", again)
+	}
+	if receipt != "0:1" {
+		t.Errorf("expected the nacked request at the end of the topic, got receipt %s", receipt)
+	}
+}
+
+func TestKafkaQueue_PoisonPill(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeKafka(t)
+	sink := &mockCocytusSink{}
+	q := newTestKafkaQueue(t, broker, "tartarus.queue.node-1", "workers", false, sink)
+
+	if _, err := q.client.produce(ctx, q.topic, 0, []byte("bad-1"), []byte("{invalid-json")); err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+	if err := q.Enqueue(ctx, &domain.SandboxRequest{ID: "valid-1"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	id, _ := dequeueID(t, q)
+	if id != "valid-1" {
+		t.Fatalf("expected the poison pill to be skipped, got %s", id)
+	}
+
+	if sink.written == nil || sink.written.RequestID != "bad-1" || sink.written.Reason != "poison_pill: json_unmarshal_error" {
+		t.Errorf("expected the poison pill in the cocytus sink, got %+v", sink.written)
+	}
+
+	messages, _, err := q.client.fetch(ctx, q.dlqTopic, map[int32]int64{0: 0}, 0)
+	if err != nil {
+		t.Fatalf("fetch dead letters: %v", err)
+	}
+	if len(messages) != 1 || string(messages[0].value) != "{invalid-json" {
+		t.Fatalf("expected the poison pill in the dead-letter topic, got %+v", messages)
+	}
+	reason := ""
+	for _, h := range messages[0].headers {
+		if h.Key == "error_reason" {
+			reason = string(h.Value)
+		}
+	}
+	if reason != "json_unmarshal_error" {
+		t.Errorf("expected error_reason header, got %q", reason)
+	}
+}
+
+func TestKafkaQueue_UnreadableBatch(t *testing.T) {
+	batch := encodeKafkaBatch([]byte("k"), []byte("v"), nil, time.Now())
+	binary.BigEndian.PutUint16(batch[21:23], 4) // zstd
+
+	messages := decodeKafkaBatches(0, batch, 0)
+	if len(messages) != 1 || messages[0].unreadable != "unsupported_compression" {
+		t.Fatalf("expected an unreadable placeholder, got %+v", messages)
+	}
+}
+
+func TestKafkaNodeTopic(t *testing.T) {
+	if got := KafkaNodeTopic("tartarus.queue", "node/1:a"); got != "tartarus.queue.node_1_a" {
+		t.Errorf("unexpected topic %q", got)
+	}
+}
+
+func TestNewKafkaQueue_NoBrokers(t *testing.T) {
+	if _, err := NewKafkaQueue(nil, "t", "", "", false, hermes.NewNoopMetrics(), nil); err == nil {
+		t.Fatal("expected an error without brokers")
+	}
+}
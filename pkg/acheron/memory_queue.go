@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
@@ -11,9 +12,11 @@ import (
 // MemoryQueue is an in-memory implementation of Queue for testing.
 // It maintains O(1) Ack/Nack operations using a processing map,
 // matching the performance characteristics of RedisQueue.
+// Requests wait in one lane per domain.Priority and are dequeued from the
+// highest non-empty lane.
 type MemoryQueue struct {
 	mu         sync.Mutex
-	items      []*domain.SandboxRequest
+	lanes      [][]*domain.SandboxRequest        // Indexed like domain.Priorities
 	processing map[string]*domain.SandboxRequest // O(1) lookup for Ack/Nack
 	cond       *sync.Cond
 	nextID     int // For generating receipt IDs
@@ -21,6 +24,7 @@ type MemoryQueue struct {
 
 func NewMemoryQueue() *MemoryQueue {
 	q := &MemoryQueue{
+		lanes:      make([][]*domain.SandboxRequest, len(domain.Priorities)),
 		processing: make(map[string]*domain.SandboxRequest),
 	}
 	q.cond = sync.NewCond(&q.mu)
@@ -30,8 +34,23 @@ func NewMemoryQueue() *MemoryQueue {
 func (q *MemoryQueue) Enqueue(ctx context.Context, req *domain.SandboxRequest) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	q.items = append(q.items, req)
-	q.cond.Signal()
+	q.push(req)
+	return nil
+}
+
+// EnqueueAt holds the request back until at, then enqueues it.
+// Delayed requests are not counted by Len until they become visible.
+func (q *MemoryQueue) EnqueueAt(ctx context.Context, req *domain.SandboxRequest, at time.Time) error {
+	delay := time.Until(at)
+	if delay <= 0 {
+		return q.Enqueue(ctx, req)
+	}
+
+	time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.push(req)
+	})
 	return nil
 }
 
@@ -39,7 +58,7 @@ func (q *MemoryQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, stri
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	for len(q.items) == 0 {
+	for q.waiting() == 0 {
 		// In a real implementation, we would respect context cancellation here.
 		// For this simple sync.Cond implementation, we just wait.
 		// To properly handle context, we'd need a channel-based approach or polling.
@@ -54,8 +73,14 @@ func (q *MemoryQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, stri
 		return nil, "", ctx.Err()
 	}
 
-	item := q.items[0]
-	q.items = q.items[1:]
+	var item *domain.SandboxRequest
+	for i, lane := range q.lanes {
+		if len(lane) > 0 {
+			item = lane[0]
+			q.lanes[i] = lane[1:]
+			break
+		}
+	}
 
 	// Generate receipt and track in processing map
 	q.nextID++
@@ -92,17 +117,93 @@ func (q *MemoryQueue) Nack(ctx context.Context, receipt string, reason string) e
 		return nil
 	}
 
-	// Re-enqueue at the end
-	q.items = append(q.items, item)
+	// Re-enqueue at the end of its lane
+	q.push(item)
 	delete(q.processing, receipt)
-	q.cond.Signal()
 
 	return nil
 }
 
+// Cancel removes a waiting request. Requests held back by EnqueueAt are not
+// waiting until they become visible.
+func (q *MemoryQueue) Cancel(ctx context.Context, req *domain.SandboxRequest) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	lane, i := q.indexOf(req.ID)
+	if i < 0 {
+		return ErrNotQueued
+	}
+	q.lanes[lane] = append(q.lanes[lane][:i], q.lanes[lane][i+1:]...)
+	return nil
+}
+
+// Boost moves a waiting request to the front of the highest lane, ahead of
+// every request of any priority.
+func (q *MemoryQueue) Boost(ctx context.Context, req *domain.SandboxRequest) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	lane, i := q.indexOf(req.ID)
+	if i < 0 {
+		return ErrNotQueued
+	}
+	item := q.lanes[lane][i]
+	q.lanes[lane] = append(q.lanes[lane][:i], q.lanes[lane][i+1:]...)
+	q.lanes[0] = append([]*domain.SandboxRequest{item}, q.lanes[0]...)
+	return nil
+}
+
+// push appends the request to its lane and wakes a consumer.
+func (q *MemoryQueue) push(req *domain.SandboxRequest) {
+	lane := laneIndex(req.Lane())
+	q.lanes[lane] = append(q.lanes[lane], req)
+	q.cond.Signal()
+}
+
+// indexOf returns the lane and position of a waiting request, or -1 as the
+// position if it is not waiting.
+func (q *MemoryQueue) indexOf(id domain.SandboxID) (int, int) {
+	for lane, items := range q.lanes {
+		for i, item := range items {
+			if item.ID == id {
+				return lane, i
+			}
+		}
+	}
+	return 0, -1
+}
+
+// waiting returns the number of requests waiting in all lanes.
+func (q *MemoryQueue) waiting() int {
+	n := 0
+	for _, lane := range q.lanes {
+		n += len(lane)
+	}
+	return n
+}
+
+// LaneLen returns the number of requests waiting in the lane.
+func (q *MemoryQueue) LaneLen(ctx context.Context, priority domain.Priority) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.lanes[laneIndex(priority)])
+}
+
 // Len returns the current queue depth (pending + processing).
 func (q *MemoryQueue) Len(ctx context.Context) int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	return len(q.items) + len(q.processing)
+	return q.waiting() + len(q.processing)
+}
+
+// laneIndex returns the position of the priority in domain.Priorities, or that
+// of the normal lane for an unknown priority.
+func laneIndex(priority domain.Priority) int {
+	for i, p := range domain.Priorities {
+		if p == priority {
+			return i
+		}
+	}
+	return laneIndex(domain.PriorityNormal)
 }
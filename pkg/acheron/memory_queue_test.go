@@ -0,0 +1,91 @@
+package acheron
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func TestMemoryQueue_CancelAndBoost(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue()
+	for _, id := range []domain.SandboxID{"req-1", "req-2", "req-3", "req-4"} {
+		if err := q.Enqueue(ctx, &domain.SandboxRequest{ID: id}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	if err := q.Cancel(ctx, &domain.SandboxRequest{ID: "req-2"}); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if err := q.Boost(ctx, &domain.SandboxRequest{ID: "req-4"}); err != nil {
+		t.Fatalf("Boost failed: %v", err)
+	}
+	if err := q.Boost(ctx, &domain.SandboxRequest{ID: "req-2"}); err != ErrNotQueued {
+		t.Errorf("Expected ErrNotQueued for a cancelled request, got %v", err)
+	}
+
+	for _, want := range []domain.SandboxID{"req-4", "req-1", "req-3"} {
+		req, _, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if req.ID != want {
+			t.Errorf("Expected %s, got %s", want, req.ID)
+		}
+	}
+	if err := q.Cancel(ctx, &domain.SandboxRequest{ID: "req-3"}); err != ErrNotQueued {
+		t.Errorf("Expected ErrNotQueued for a delivered request, got %v", err)
+	}
+}
+
+func TestMemoryQueue_PriorityLanes(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue()
+	for _, req := range []*domain.SandboxRequest{
+		{ID: "batch-1", Priority: domain.PriorityBatch},
+		{ID: "normal-1"},
+		{ID: "high-1", Priority: domain.PriorityHigh},
+		{ID: "batch-2", Priority: domain.PriorityBatch},
+		{ID: "high-2", Priority: domain.PriorityHigh},
+	} {
+		if err := q.Enqueue(ctx, req); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	if n := q.LaneLen(ctx, domain.PriorityBatch); n != 2 {
+		t.Errorf("Expected 2 batch requests, got %d", n)
+	}
+
+	// Boosted requests go ahead of every lane
+	if err := q.Boost(ctx, &domain.SandboxRequest{ID: "batch-2"}); err != nil {
+		t.Fatalf("Boost failed: %v", err)
+	}
+
+	var receipts []string
+	for _, want := range []domain.SandboxID{"batch-2", "high-1", "high-2", "normal-1"} {
+		req, receipt, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if req.ID != want {
+			t.Errorf("Expected %s, got %s", want, req.ID)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	// A nacked request returns to its own lane
+	if err := q.Nack(ctx, receipts[3], "retry"); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	for _, want := range []domain.SandboxID{"normal-1", "batch-1"} {
+		req, _, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if req.ID != want {
+			t.Errorf("Expected %s, got %s", want, req.ID)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package acheron
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// OutboxEntry is the intent to enqueue a request whose run has been persisted.
+type OutboxEntry struct {
+	Request   *domain.SandboxRequest `json:"request"`
+	NotBefore time.Time              `json:"not_before,omitempty"` // Enqueue delayed until then, if the queue supports it
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Outbox persists a run and the intent to enqueue its request in one
+// transaction, so a crash between the two can never leave a run that is
+// recorded but never launched. An OutboxRelay delivers the intents.
+type Outbox interface {
+	// Commit atomically stores run and an enqueue intent for req.
+	Commit(ctx context.Context, run domain.SandboxRun, entry OutboxEntry) error
+	// Pending returns up to limit undelivered entries created before the
+	// given time, oldest first.
+	Pending(ctx context.Context, before time.Time, limit int) ([]OutboxEntry, error)
+	// MarkDelivered removes the entry for a request once it is on the queue.
+	MarkDelivered(ctx context.Context, id domain.SandboxID) error
+}
+
+// RunStore is where an outbox persists runs; hades.Registry implements it.
+type RunStore interface {
+	UpdateRun(ctx context.Context, run domain.SandboxRun) error
+}
+
+// MemoryOutbox keeps entries in memory next to an in-memory run store. A
+// crash loses both, so the two writes need no further coordination.
+type MemoryOutbox struct {
+	runs RunStore
+
+	mu      sync.Mutex
+	entries map[domain.SandboxID]OutboxEntry
+}
+
+// NewMemoryOutbox creates an outbox persisting runs to runs.
+func NewMemoryOutbox(runs RunStore) *MemoryOutbox {
+	return &MemoryOutbox{runs: runs, entries: make(map[domain.SandboxID]OutboxEntry)}
+}
+
+func (o *MemoryOutbox) Commit(ctx context.Context, run domain.SandboxRun, entry OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.runs.UpdateRun(ctx, run); err != nil {
+		return err
+	}
+	o.entries[entry.Request.ID] = entry
+	return nil
+}
+
+func (o *MemoryOutbox) Pending(ctx context.Context, before time.Time, limit int) ([]OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var pending []OutboxEntry
+	for _, entry := range o.entries {
+		if entry.CreatedAt.Before(before) {
+			pending = append(pending, entry)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+func (o *MemoryOutbox) MarkDelivered(ctx context.Context, id domain.SandboxID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.entries, id)
+	return nil
+}
+
+// Deliver enqueues an outbox entry, holding it back until NotBefore when
+// the queue supports delayed enqueue.
+func Deliver(ctx context.Context, queue Queue, entry OutboxEntry) error {
+	if delayed, ok := queue.(DelayedEnqueuer); ok && entry.NotBefore.After(time.Now()) {
+		return delayed.EnqueueAt(ctx, entry.Request, entry.NotBefore)
+	}
+	return queue.Enqueue(ctx, entry.Request)
+}
+
+// OutboxRelay drains the outbox into the queue. Submitters deliver their own
+// entries right after committing them; the relay only picks up entries older
+// than MinAge, left behind by a crash or a failed enqueue. Delivery is at
+// least once: a crash between enqueue and MarkDelivered enqueues again.
+type OutboxRelay struct {
+	Outbox    Outbox
+	Queue     Queue
+	Logger    hermes.Logger
+	Metrics   hermes.Metrics
+	Interval  time.Duration // How often to drain (default 5s)
+	MinAge    time.Duration // Entries younger than this are left to their submitter (default 10s)
+	BatchSize int           // Entries delivered per drain (default 100)
+}
+
+// NewOutboxRelay creates a relay with default timings.
+func NewOutboxRelay(outbox Outbox, queue Queue, logger hermes.Logger, metrics hermes.Metrics) *OutboxRelay {
+	return &OutboxRelay{
+		Outbox:    outbox,
+		Queue:     queue,
+		Logger:    logger,
+		Metrics:   metrics,
+		Interval:  5 * time.Second,
+		MinAge:    10 * time.Second,
+		BatchSize: 100,
+	}
+}
+
+// Run drains the outbox every Interval until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Drain(ctx); err != nil {
+				r.Logger.Error(ctx, "Outbox relay failed", map[string]any{"error": err})
+			}
+		}
+	}
+}
+
+// Drain delivers one batch of pending entries and returns how many were
+// delivered. Entries that fail to enqueue stay in the outbox for the next drain.
+func (r *OutboxRelay) Drain(ctx context.Context) (int, error) {
+	entries, err := r.Outbox.Pending(ctx, time.Now().Add(-r.MinAge), r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	r.Metrics.SetGauge("outbox_pending", float64(len(entries)))
+
+	delivered := 0
+	for _, entry := range entries {
+		if err := Deliver(ctx, r.Queue, entry); err != nil {
+			r.Metrics.IncCounter("outbox_relay_total", 1, hermes.Label{Key: "result", Value: "error"})
+			r.Logger.Error(ctx, "Failed to relay outbox entry", map[string]any{
+				"sandbox_id": entry.Request.ID,
+				"error":      err,
+			})
+			continue
+		}
+		if err := r.Outbox.MarkDelivered(ctx, entry.Request.ID); err != nil {
+			return delivered, err
+		}
+		r.Metrics.IncCounter("outbox_relay_total", 1, hermes.Label{Key: "result", Value: "delivered"})
+		r.Logger.Info(ctx, "Relayed outbox entry", map[string]any{
+			"sandbox_id": entry.Request.ID,
+			"age":        time.Since(entry.CreatedAt).String(),
+		})
+		delivered++
+	}
+	return delivered, nil
+}
@@ -0,0 +1,87 @@
+package acheron
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+func TestRedisOutbox_CommitIsVisibleToRegistry(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	outbox, err := NewRedisOutbox(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create outbox: %v", err)
+	}
+	registry, err := hades.NewRedisRegistry(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	old := time.Now().Add(-time.Minute)
+	for _, id := range []domain.SandboxID{"run-2", "run-1"} {
+		created := old
+		if id == "run-2" {
+			created = old.Add(time.Second)
+		}
+		run := domain.SandboxRun{ID: id, Status: domain.RunStatusScheduled, NodeID: "node-1"}
+		entry := OutboxEntry{Request: &domain.SandboxRequest{ID: id, NodeID: "node-1"}, CreatedAt: created}
+		if err := outbox.Commit(ctx, run, entry); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+	// An entry its submitter may still be delivering
+	if err := outbox.Commit(ctx, domain.SandboxRun{ID: "run-3"}, OutboxEntry{Request: &domain.SandboxRequest{ID: "run-3"}, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	run, err := registry.GetRun(ctx, "run-1")
+	if err != nil || run.Status != domain.RunStatusScheduled {
+		t.Fatalf("committed run not readable by the registry: %v %v", run, err)
+	}
+	if ttl := s.TTL(hades.RedisRunKey("run-1")); ttl != hades.RedisRunTTL {
+		t.Errorf("run TTL = %v, want %v", ttl, hades.RedisRunTTL)
+	}
+
+	pending, err := outbox.Pending(ctx, time.Now().Add(-10*time.Second), 10)
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if len(pending) != 2 || pending[0].Request.ID != "run-1" || pending[1].Request.ID != "run-2" {
+		t.Fatalf("expected run-1 then run-2 pending, got %+v", pending)
+	}
+
+	queue := NewMemoryQueue()
+	relay := NewOutboxRelay(outbox, queue, hermes.NewNoopLogger(), hermes.NewNoopMetrics())
+	delivered, err := relay.Drain(ctx)
+	if err != nil || delivered != 2 {
+		t.Fatalf("expected 2 deliveries, got %d (%v)", delivered, err)
+	}
+	if n := queue.Len(ctx); n != 2 {
+		t.Errorf("expected 2 queued requests, got %d", n)
+	}
+
+	pending, err = outbox.Pending(ctx, time.Now().Add(time.Second), 10)
+	if err != nil || len(pending) != 1 || pending[0].Request.ID != "run-3" {
+		t.Errorf("expected only run-3 left, got %+v (%v)", pending, err)
+	}
+}
+
+func TestDeliver_HonorsNotBefore(t *testing.T) {
+	ctx := context.Background()
+	queue := NewMemoryQueue()
+
+	entry := OutboxEntry{Request: &domain.SandboxRequest{ID: "later"}, NotBefore: time.Now().Add(time.Hour)}
+	if err := Deliver(ctx, queue, entry); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if n := queue.Len(ctx); n != 0 {
+		t.Errorf("request must not be visible before its window opens, got %d queued", n)
+	}
+}
@@ -2,6 +2,8 @@ package acheron
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
@@ -16,3 +18,31 @@ type Queue interface {
 	// Len returns the current queue depth for metrics/scaling decisions.
 	Len(ctx context.Context) int
 }
+
+// DelayedEnqueuer is implemented by queues that can hold a request back until
+// a point in time (e.g. the opening of its run window).
+
+type DelayedEnqueuer interface {
+	// EnqueueAt makes the request visible to consumers no earlier than at.
+	EnqueueAt(ctx context.Context, req *domain.SandboxRequest, at time.Time) error
+}
+
+// ErrNotQueued is returned when a request is no longer waiting in the queue,
+// for instance because a consumer has already received it.
+var ErrNotQueued = errors.New("request is not waiting in the queue")
+
+// ErrReorderUnsupported is returned by queue wrappers whose inner queue
+// cannot cancel or boost requests.
+var ErrReorderUnsupported = errors.New("queue cannot cancel or boost requests")
+
+// Reorderer is implemented by queues that can change the fate of a request
+// that has not been delivered yet. Requests are found by ID and routed by
+// NodeID, as on Enqueue, so req needs no other field. Both return
+// ErrNotQueued when the request is not waiting.
+
+type Reorderer interface {
+	// Cancel removes the request from the queue.
+	Cancel(ctx context.Context, req *domain.SandboxRequest) error
+	// Boost moves the request ahead of every request not boosted yet.
+	Boost(ctx context.Context, req *domain.SandboxRequest) error
+}
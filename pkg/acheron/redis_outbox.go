@@ -0,0 +1,112 @@
+package acheron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+const (
+	outboxEntriesKey = "tartarus:outbox"       // Hash: sandbox ID -> entry JSON
+	outboxOrderKey   = "tartarus:outbox:order" // Sorted set: sandbox ID scored by creation unix millis
+)
+
+// RedisOutbox stores enqueue intents in the Redis instance that holds the
+// Hades runs, writing run and intent in one MULTI/EXEC transaction. It must
+// use the same address and database as the hades.RedisRegistry.
+type RedisOutbox struct {
+	client *redis.Client
+}
+
+// NewRedisOutbox connects to the registry's Redis.
+func NewRedisOutbox(addr string, db int, password string) (*RedisOutbox, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisOutbox{client: client}, nil
+}
+
+func (o *RedisOutbox) Commit(ctx context.Context, run domain.SandboxRun, entry OutboxEntry) error {
+	runData, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	entryData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+
+	id := string(entry.Request.ID)
+	_, err = o.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, hades.RedisRunKey(run.ID), runData, hades.RedisRunTTL)
+		pipe.HSet(ctx, outboxEntriesKey, id, entryData)
+		pipe.ZAdd(ctx, outboxOrderKey, redis.Z{Score: float64(entry.CreatedAt.UnixMilli()), Member: id})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit run and outbox entry: %w", err)
+	}
+	return nil
+}
+
+func (o *RedisOutbox) Pending(ctx context.Context, before time.Time, limit int) ([]OutboxEntry, error) {
+	ids, err := o.client.ZRangeByScore(ctx, outboxOrderKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "(" + strconv.FormatInt(before.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	values, err := o.client.HMGet(ctx, outboxEntriesKey, ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox entries: %w", err)
+	}
+	entries := make([]OutboxEntry, 0, len(values))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			// Delivered between the two reads
+			continue
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("corrupt outbox entry %s: %w", ids[i], err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (o *RedisOutbox) MarkDelivered(ctx context.Context, id domain.SandboxID) error {
+	_, err := o.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, outboxEntriesKey, string(id))
+		pipe.ZRem(ctx, outboxOrderKey, string(id))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to mark outbox entry delivered: %w", err)
+	}
+	return nil
+}
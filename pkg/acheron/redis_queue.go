@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -85,6 +86,126 @@ var deadLetterScript = redis.NewScript(`
 	return 1
 `)
 
+// promoteDelayedScript atomically moves due delayed requests into the
+// stream of their lane.
+// KEYS[1]: delayed sorted set key (score = visible-at unix millis)
+// KEYS[2]: high lane stream key
+// KEYS[3]: normal lane stream key
+// KEYS[4]: batch lane stream key
+// ARGV[1]: current unix millis
+// ARGV[2]: maximum number of requests to promote
+var promoteDelayedScript = redis.NewScript(`
+	local delayed = KEYS[1]
+	local now = ARGV[1]
+	local limit = tonumber(ARGV[2])
+
+	local due = redis.call("ZRANGEBYSCORE", delayed, "-inf", now, "LIMIT", 0, limit)
+	for _, data in ipairs(due) do
+		local stream = KEYS[3]
+		local ok, req = pcall(cjson.decode, data)
+		if ok and type(req) == "table" then
+			if req.priority == "high" then
+				stream = KEYS[2]
+			elseif req.priority == "batch" then
+				stream = KEYS[4]
+			end
+		end
+		redis.call("XADD", stream, "*", "data", data)
+		redis.call("ZREM", delayed, data)
+	end
+
+	return #due
+`)
+
+// withdrawScript atomically finds a request that no consumer group has read
+// yet and cancels or boosts it. Boosting moves the entry from its lane to the
+// boosted stream, which consumers read first; cancelling deletes it,
+// wherever it is.
+// KEYS[1]: boosted stream key
+// KEYS[2]: delayed sorted set key
+// KEYS[3...]: lane stream keys
+// ARGV[1]: request ID
+// ARGV[2]: "cancel" or "boost"
+var withdrawScript = redis.NewScript(`
+	local id = ARGV[1]
+	local mode = ARGV[2]
+
+	local function is_request(data)
+		local ok, req = pcall(cjson.decode, data)
+		return ok and type(req) == "table" and req.id == id
+	end
+
+	-- Stream IDs compare by time, then sequence; "0" is "0-0"
+	local function parse(sid)
+		local ms, seq = string.match(sid, "^(%d+)-?(%d*)$")
+		return tonumber(ms) or 0, tonumber(seq) or 0
+	end
+	local function after(a, b)
+		local ams, aseq = parse(a)
+		local bms, bseq = parse(b)
+		return ams > bms or (ams == bms and aseq > bseq)
+	end
+
+	-- Entries up to the furthest last-delivered-id have been read
+	local function find(stream)
+		if redis.call("EXISTS", stream) == 0 then
+			return nil
+		end
+		local delivered = "0-0"
+		for _, group in ipairs(redis.call("XINFO", "GROUPS", stream)) do
+			for i = 1, #group, 2 do
+				if group[i] == "last-delivered-id" and after(group[i+1], delivered) then
+					delivered = group[i+1]
+				end
+			end
+		end
+		for _, msg in ipairs(redis.call("XRANGE", stream, delivered, "+")) do
+			local values = msg[2]
+			for i = 1, #values, 2 do
+				if msg[1] ~= delivered and values[i] == "data" and is_request(values[i+1]) then
+					return msg
+				end
+			end
+		end
+		return nil
+	end
+
+	local boosted = find(KEYS[1])
+	if boosted then
+		if mode == "cancel" then
+			redis.call("XDEL", KEYS[1], boosted[1])
+		end
+		return 1
+	end
+
+	for i = 3, #KEYS do
+		local msg = find(KEYS[i])
+		if msg then
+			redis.call("XDEL", KEYS[i], msg[1])
+			if mode == "boost" then
+				redis.call("XADD", KEYS[1], "*", unpack(msg[2]))
+			end
+			return 1
+		end
+	end
+
+	if mode == "cancel" then
+		for _, data in ipairs(redis.call("ZRANGE", KEYS[2], 0, -1)) do
+			if is_request(data) then
+				redis.call("ZREM", KEYS[2], data)
+				return 1
+			end
+		end
+	end
+	return 0
+`)
+
+// boostedPrefix marks receipts of messages read from the boosted stream.
+const boostedPrefix = "boosted:"
+
+// RedisQueue keeps each lane in a stream of its own: the normal lane in the
+// stream key itself, the others in "<key>:high" and "<key>:batch". Receipts
+// of messages read from other lanes than normal carry the lane as prefix.
 type RedisQueue struct {
 	client        *redis.Client
 	streamKey     string
@@ -126,6 +247,12 @@ func NewRedisQueue(addr string, db int, streamKey string, consumerGroup string,
 		// If the stream doesn't exist, MKSTREAM will create it.
 		// 0 means start consuming from the beginning (all undelivered messages).
 		err := client.XGroupCreateMkStream(ctx, streamKey, consumerGroup, "0").Err()
+		client.XGroupCreateMkStream(ctx, boostedKey(streamKey), consumerGroup, "0")
+		for _, priority := range domain.Priorities {
+			if priority != domain.PriorityNormal {
+				client.XGroupCreateMkStream(ctx, laneKey(streamKey, priority), consumerGroup, "0")
+			}
+		}
 		if err != nil {
 			// Ignore "BUSYGROUP Consumer Group name already exists"
 			if err.Error() != "BUSYGROUP Consumer Group name already exists" {
@@ -147,10 +274,7 @@ func (q *RedisQueue) Enqueue(ctx context.Context, req *domain.SandboxRequest) er
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	targetKey := q.streamKey
-	if q.routing && req.NodeID != "" {
-		targetKey = fmt.Sprintf("%s:%s", q.streamKey, req.NodeID)
-	}
+	targetKey := laneKey(q.targetKey(req), req.Lane())
 
 	// XADD
 	// We use "*" for ID to let Redis generate it.
@@ -177,29 +301,216 @@ func (q *RedisQueue) Enqueue(ctx context.Context, req *domain.SandboxRequest) er
 	return nil
 }
 
-func (q *RedisQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, string, error) {
-	if q.consumerGroup == "" || q.consumerName == "" {
-		return nil, "", fmt.Errorf("consumer group/name not configured for dequeue")
+// EnqueueAt parks the request in a sorted set keyed by its visibility time.
+// Consumers promote due requests into the stream before each read.
+func (q *RedisQueue) EnqueueAt(ctx context.Context, req *domain.SandboxRequest, at time.Time) error {
+	if !at.After(time.Now()) {
+		return q.Enqueue(ctx, req)
 	}
 
-	for {
-		if ctx.Err() != nil {
-			return nil, "", ctx.Err()
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	targetKey := q.targetKey(req)
+
+	if err := q.client.ZAdd(ctx, delayedKey(targetKey), redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: data,
+	}).Err(); err != nil {
+		q.metrics.IncCounter("queue_enqueue_errors_total", 1, hermes.Label{Key: "queue", Value: targetKey})
+		return fmt.Errorf("failed to enqueue delayed request: %w", err)
+	}
+
+	q.metrics.IncCounter("queue_delayed_enqueue_total", 1, hermes.Label{Key: "queue", Value: targetKey})
+	return nil
+}
+
+// targetKey is the stream a request is routed to.
+func (q *RedisQueue) targetKey(req *domain.SandboxRequest) string {
+	if q.routing && req.NodeID != "" {
+		return fmt.Sprintf("%s:%s", q.streamKey, req.NodeID)
+	}
+	return q.streamKey
+}
+
+// Cancel deletes the request from its stream, or from the delayed set if it
+// is held back until its run window.
+func (q *RedisQueue) Cancel(ctx context.Context, req *domain.SandboxRequest) error {
+	return q.withdraw(ctx, req, "cancel")
+}
+
+// Boost moves the request to the boosted stream, which consumers read before
+// the stream itself. Boosted requests keep their order among themselves.
+// Requests held back until their run window cannot be boosted.
+func (q *RedisQueue) Boost(ctx context.Context, req *domain.SandboxRequest) error {
+	return q.withdraw(ctx, req, "boost")
+}
+
+func (q *RedisQueue) withdraw(ctx context.Context, req *domain.SandboxRequest, mode string) error {
+	targetKey := q.targetKey(req)
+	keys := append([]string{boostedKey(targetKey), delayedKey(targetKey)}, laneKeys(targetKey)...)
+	n, err := withdrawScript.Run(ctx, q.client, keys, string(req.ID), mode).Int()
+	if err != nil {
+		q.metrics.IncCounter("queue_"+mode+"_errors_total", 1, hermes.Label{Key: "queue", Value: targetKey})
+		return fmt.Errorf("failed to %s request: %w", mode, err)
+	}
+	if n == 0 {
+		return ErrNotQueued
+	}
+	q.metrics.IncCounter("queue_"+mode+"_total", 1, hermes.Label{Key: "queue", Value: targetKey})
+	return nil
+}
+
+// promoteDelayed moves delayed requests whose time has come into the stream.
+func (q *RedisQueue) promoteDelayed(ctx context.Context) {
+	n, err := promoteDelayedScript.Run(ctx, q.client,
+		append([]string{delayedKey(q.streamKey)}, laneKeys(q.streamKey)...),
+		time.Now().UnixMilli(), 100,
+	).Int()
+	if err != nil {
+		q.metrics.IncCounter("queue_delayed_promote_errors_total", 1, hermes.Label{Key: "queue", Value: q.streamKey})
+		return
+	}
+	if n > 0 {
+		q.metrics.IncCounter("queue_delayed_promoted_total", float64(n), hermes.Label{Key: "queue", Value: q.streamKey})
+	}
+}
+
+func delayedKey(streamKey string) string {
+	return streamKey + ":delayed"
+}
+
+func boostedKey(streamKey string) string {
+	return streamKey + ":boosted"
+}
+
+// laneKey is the stream of a lane; the normal lane is the stream itself.
+func laneKey(streamKey string, priority domain.Priority) string {
+	if !priority.Valid() || priority == domain.PriorityNormal {
+		return streamKey
+	}
+	return streamKey + ":" + string(priority)
+}
+
+// laneKeys lists the lane streams from the highest lane to the lowest.
+func laneKeys(streamKey string) []string {
+	keys := make([]string, len(domain.Priorities))
+	for i, priority := range domain.Priorities {
+		keys[i] = laneKey(streamKey, priority)
+	}
+	return keys
+}
+
+// lanePrefix marks receipts of messages read from the lane's stream.
+func lanePrefix(priority domain.Priority) string {
+	if priority == domain.PriorityNormal {
+		return ""
+	}
+	return string(priority) + ":"
+}
+
+// receiptStream returns the stream a receipt was read from and its message ID.
+func (q *RedisQueue) receiptStream(receipt string) (string, string) {
+	if id, ok := strings.CutPrefix(receipt, boostedPrefix); ok {
+		return boostedKey(q.streamKey), id
+	}
+	for _, priority := range domain.Priorities {
+		if prefix := lanePrefix(priority); prefix != "" {
+			if id, ok := strings.CutPrefix(receipt, prefix); ok {
+				return laneKey(q.streamKey, priority), id
+			}
 		}
+	}
+	return q.streamKey, receipt
+}
 
-		// XREADGROUP
-		// Block for 1 second.
-		// Streams: key -> ">" (means messages never delivered to other consumers)
-		streams := []string{q.streamKey, ">"}
+// receiptPrefix returns the receipt prefix of messages read from stream.
+func (q *RedisQueue) receiptPrefix(stream string) string {
+	if stream == boostedKey(q.streamKey) {
+		return boostedPrefix
+	}
+	for _, priority := range domain.Priorities {
+		if stream == laneKey(q.streamKey, priority) {
+			return lanePrefix(priority)
+		}
+	}
+	return ""
+}
 
+// readNext reads the next message for this consumer: a boosted one, or one
+// from the highest lane that has any. Only when every lane is empty does it
+// block, on all lanes at once, for up to a second. If messages arrived on
+// several lanes in the meantime, all but the highest are handed back to the
+// end of their lanes.
+func (q *RedisQueue) readNext(ctx context.Context) (string, redis.XMessage, error) {
+	lanes := laneKeys(q.streamKey)
+	for _, stream := range append([]string{boostedKey(q.streamKey)}, lanes...) {
+		// A negative Block does not wait
 		res, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
 			Group:    q.consumerGroup,
 			Consumer: q.consumerName,
-			Streams:  streams,
+			Streams:  []string{stream, ">"},
 			Count:    1,
-			Block:    1 * time.Second,
+			Block:    -1,
 		}).Result()
+		if err == nil && len(res) > 0 && len(res[0].Messages) > 0 {
+			return stream, res[0].Messages[0], nil
+		}
+	}
+
+	// XREADGROUP
+	// Block for 1 second.
+	// Streams: keys, then ">" for each (messages never delivered to other consumers)
+	streams := append([]string(nil), lanes...)
+	for range lanes {
+		streams = append(streams, ">")
+	}
+	res, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.consumerGroup,
+		Consumer: q.consumerName,
+		Streams:  streams,
+		Count:    1,
+		Block:    1 * time.Second,
+	}).Result()
+	if err != nil {
+		return "", redis.XMessage{}, err
+	}
 
+	var stream string
+	var msg redis.XMessage
+	for _, lane := range lanes {
+		for _, r := range res {
+			if r.Stream != lane || len(r.Messages) == 0 {
+				continue
+			}
+			if stream == "" {
+				stream, msg = r.Stream, r.Messages[0]
+			} else if err := nackScript.Run(ctx, q.client, []string{r.Stream}, q.consumerGroup, r.Messages[0].ID).Err(); err != nil {
+				q.metrics.IncCounter("queue_nack_errors_total", 1, hermes.Label{Key: "queue", Value: r.Stream})
+			}
+		}
+	}
+	if stream == "" {
+		return "", redis.XMessage{}, redis.Nil
+	}
+	return stream, msg, nil
+}
+
+func (q *RedisQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, string, error) {
+	if q.consumerGroup == "" || q.consumerName == "" {
+		return nil, "", fmt.Errorf("consumer group/name not configured for dequeue")
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+
+		q.promoteDelayed(ctx)
+
+		stream, msg, err := q.readNext(ctx)
 		if err != nil {
 			if err == redis.Nil {
 				continue // Timeout, retry
@@ -210,37 +521,32 @@ func (q *RedisQueue) Dequeue(ctx context.Context) (*domain.SandboxRequest, strin
 			return nil, "", fmt.Errorf("failed to dequeue: %w", err)
 		}
 
-		if len(res) == 0 || len(res[0].Messages) == 0 {
-			continue
-		}
-
-		msg := res[0].Messages[0]
 		dataStr, ok := msg.Values["data"].(string)
 		if !ok {
 			// Invalid payload format (not a string in "data" field)
-			q.moveToDLQ(ctx, msg.ID, "invalid_payload_format")
+			q.moveToDLQ(ctx, stream, msg.ID, "invalid_payload_format")
 			continue
 		}
 
 		var req domain.SandboxRequest
 		if err := json.Unmarshal([]byte(dataStr), &req); err != nil {
 			// Corrupt JSON payload
-			q.moveToDLQ(ctx, msg.ID, "json_unmarshal_error")
+			q.moveToDLQ(ctx, stream, msg.ID, "json_unmarshal_error")
 			continue
 		}
 
 		q.metrics.IncCounter("queue_dequeue_total", 1, hermes.Label{Key: "queue", Value: q.streamKey})
-		q.updateDepth(ctx)
+		q.updateDepth(ctx, stream)
 
-		return &req, msg.ID, nil
+		return &req, q.receiptPrefix(stream) + msg.ID, nil
 	}
 }
 
-func (q *RedisQueue) moveToDLQ(ctx context.Context, id string, errorReason string) {
+func (q *RedisQueue) moveToDLQ(ctx context.Context, stream string, id string, errorReason string) {
 	// Write poison-pill to Cocytus for audit trail (best-effort)
 	if q.sink != nil {
 		// Get the raw message payload before moving to DLQ
-		range_res, err := q.client.XRange(ctx, q.streamKey, id, id).Result()
+		range_res, err := q.client.XRange(ctx, stream, id, id).Result()
 		if err == nil && len(range_res) > 0 {
 			msg := range_res[0]
 			var payload []byte
@@ -267,7 +573,7 @@ func (q *RedisQueue) moveToDLQ(ctx context.Context, id string, errorReason strin
 
 	// Use Lua script to atomically move to DLQ and Ack
 	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	err := deadLetterScript.Run(ctx, q.client, []string{q.streamKey, q.dlqKey}, q.consumerGroup, id, errorReason, timestamp).Err()
+	err := deadLetterScript.Run(ctx, q.client, []string{stream, q.dlqKey}, q.consumerGroup, id, errorReason, timestamp).Err()
 	if err != nil {
 		// If script fails, we might be in trouble. Log it?
 		// We can't easily log here without a logger.
@@ -279,9 +585,9 @@ func (q *RedisQueue) moveToDLQ(ctx context.Context, id string, errorReason strin
 	}
 }
 
-func (q *RedisQueue) updateDepth(ctx context.Context) {
-	if depth, err := q.client.XLen(ctx, q.streamKey).Result(); err == nil {
-		q.metrics.SetGauge("queue_depth", float64(depth), hermes.Label{Key: "queue", Value: q.streamKey})
+func (q *RedisQueue) updateDepth(ctx context.Context, stream string) {
+	if depth, err := q.client.XLen(ctx, stream).Result(); err == nil {
+		q.metrics.SetGauge("queue_depth", float64(depth), hermes.Label{Key: "queue", Value: stream})
 	}
 }
 
@@ -298,15 +604,18 @@ func (q *RedisQueue) updateDLQDepth(ctx context.Context) {
 func (q *RedisQueue) Ack(ctx context.Context, receipt string) error {
 	// XACK
 	// O(1)
-	if err := q.client.XAck(ctx, q.streamKey, q.consumerGroup, receipt).Err(); err != nil {
+	stream, id := q.receiptStream(receipt)
+	if err := q.client.XAck(ctx, stream, q.consumerGroup, id).Err(); err != nil {
 		return fmt.Errorf("failed to ack message: %w", err)
 	}
 	return nil
 }
 
 func (q *RedisQueue) Nack(ctx context.Context, receipt string, reason string) error {
-	// Use Lua script to atomically re-enqueue and Ack
-	err := nackScript.Run(ctx, q.client, []string{q.streamKey}, q.consumerGroup, receipt).Err()
+	// Use Lua script to atomically re-enqueue and Ack. Boosted requests are
+	// re-enqueued to the boosted stream, others to their lane.
+	stream, id := q.receiptStream(receipt)
+	err := nackScript.Run(ctx, q.client, []string{stream}, q.consumerGroup, id).Err()
 	if err != nil {
 		q.metrics.IncCounter("queue_nack_errors_total", 1, hermes.Label{Key: "queue", Value: q.streamKey})
 		return fmt.Errorf("failed to nack message: %w", err)
@@ -316,11 +625,28 @@ func (q *RedisQueue) Nack(ctx context.Context, receipt string, reason string) er
 	return nil
 }
 
-// Len returns the current queue depth using XLEN.
+// Len returns the current queue depth using XLEN, summed over the lanes and
+// boosted requests.
 func (q *RedisQueue) Len(ctx context.Context) int {
 	depth, err := q.client.XLen(ctx, q.streamKey).Result()
 	if err != nil {
 		return 0
 	}
+	for _, priority := range domain.Priorities {
+		if priority != domain.PriorityNormal {
+			n, _ := q.client.XLen(ctx, laneKey(q.streamKey, priority)).Result()
+			depth += n
+		}
+	}
+	boosted, _ := q.client.XLen(ctx, boostedKey(q.streamKey)).Result()
+	return int(depth + boosted)
+}
+
+// LaneLen returns the length of the lane's stream.
+func (q *RedisQueue) LaneLen(ctx context.Context, priority domain.Priority) int {
+	depth, err := q.client.XLen(ctx, laneKey(q.streamKey, priority)).Result()
+	if err != nil {
+		return 0
+	}
 	return int(depth)
 }
@@ -2,7 +2,9 @@ package acheron
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
@@ -235,3 +237,214 @@ func BenchmarkRedisQueue_Ack(b *testing.B) {
 		q.Ack(ctx, receipts[idx])
 	}
 }
+
+func TestRedisQueue_EnqueueAt(t *testing.T) {
+	s := miniredis.RunT(t)
+	metrics := hermes.NewLogMetrics()
+
+	q, err := NewRedisQueue(s.Addr(), 0, "test-queue", "group1", "consumer1", false, metrics, nil)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+
+	ctx := context.Background()
+	req := &domain.SandboxRequest{ID: "req-delayed", Template: "tpl-1"}
+
+	if err := q.EnqueueAt(ctx, req, time.Now().Add(200*time.Millisecond)); err != nil {
+		t.Fatalf("EnqueueAt failed: %v", err)
+	}
+
+	// Not yet visible in the stream
+	if n, _ := q.client.XLen(ctx, "test-queue").Result(); n != 0 {
+		t.Fatalf("Expected empty stream before due time, got %d", n)
+	}
+
+	dctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	dequeued, _, err := q.Dequeue(dctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if dequeued.ID != req.ID {
+		t.Errorf("Expected ID %s, got %s", req.ID, dequeued.ID)
+	}
+
+	if n, _ := q.client.ZCard(ctx, "test-queue:delayed").Result(); n != 0 {
+		t.Errorf("Expected delayed set to be drained, got %d", n)
+	}
+}
+
+func TestRedisQueue_CancelAndBoost(t *testing.T) {
+	s := miniredis.RunT(t)
+	metrics := hermes.NewLogMetrics()
+	ctx := context.Background()
+
+	// Olympus produces to per-node streams; the agent consumes its own
+	producer, err := NewRedisQueue(s.Addr(), 0, "test-queue", "", "", true, metrics, nil)
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	consumer, err := NewRedisQueue(s.Addr(), 0, "test-queue:node-1", "group1", "consumer1", false, metrics, nil)
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+
+	for _, id := range []domain.SandboxID{"req-1", "req-2", "req-3", "req-4"} {
+		if err := producer.Enqueue(ctx, &domain.SandboxRequest{ID: id, NodeID: "node-1"}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	if err := producer.Cancel(ctx, &domain.SandboxRequest{ID: "req-2", NodeID: "node-1"}); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if err := producer.Boost(ctx, &domain.SandboxRequest{ID: "req-4", NodeID: "node-1"}); err != nil {
+		t.Fatalf("Boost failed: %v", err)
+	}
+	if err := producer.Cancel(ctx, &domain.SandboxRequest{ID: "missing", NodeID: "node-1"}); err != ErrNotQueued {
+		t.Errorf("Expected ErrNotQueued for a missing request, got %v", err)
+	}
+	if n := consumer.Len(ctx); n != 3 {
+		t.Errorf("Expected 3 queued requests, got %d", n)
+	}
+
+	// The boosted request is delivered first, then the rest in order
+	var receipts []string
+	for _, want := range []domain.SandboxID{"req-4", "req-1"} {
+		req, receipt, err := consumer.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if req.ID != want {
+			t.Errorf("Expected %s, got %s", want, req.ID)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	// Delivered requests can no longer be cancelled or boosted
+	if err := producer.Cancel(ctx, &domain.SandboxRequest{ID: "req-1", NodeID: "node-1"}); err != ErrNotQueued {
+		t.Errorf("Expected ErrNotQueued for a delivered request, got %v", err)
+	}
+	if err := producer.Boost(ctx, &domain.SandboxRequest{ID: "req-4", NodeID: "node-1"}); err != ErrNotQueued {
+		t.Errorf("Expected ErrNotQueued for a delivered boosted request, got %v", err)
+	}
+
+	// Receipts of boosted requests settle on the boosted stream
+	if err := consumer.Ack(ctx, receipts[0]); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	pending, err := consumer.client.XPending(ctx, "test-queue:node-1:boosted", "group1").Result()
+	if err != nil {
+		t.Fatalf("XPending failed: %v", err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("Expected the boosted request to be acked, %d pending", pending.Count)
+	}
+
+	req, _, err := consumer.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if req.ID != "req-3" {
+		t.Errorf("Expected req-3 after the cancelled req-2, got %s", req.ID)
+	}
+}
+
+func TestRedisQueue_CancelDelayed(t *testing.T) {
+	s := miniredis.RunT(t)
+	metrics := hermes.NewLogMetrics()
+	ctx := context.Background()
+
+	q, err := NewRedisQueue(s.Addr(), 0, "test-queue", "group1", "consumer1", false, metrics, nil)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	req := &domain.SandboxRequest{ID: "req-1"}
+	if err := q.EnqueueAt(ctx, req, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("EnqueueAt failed: %v", err)
+	}
+	if err := q.Boost(ctx, req); err != ErrNotQueued {
+		t.Errorf("Expected delayed requests not to be boosted, got %v", err)
+	}
+	if err := q.Cancel(ctx, req); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if n, _ := q.client.ZCard(ctx, "test-queue:delayed").Result(); n != 0 {
+		t.Errorf("Expected the delayed request to be removed, %d left", n)
+	}
+}
+
+func TestRedisQueue_PriorityLanes(t *testing.T) {
+	s := miniredis.RunT(t)
+	metrics := hermes.NewLogMetrics()
+	ctx := context.Background()
+
+	q, err := NewRedisQueue(s.Addr(), 0, "test-queue", "group1", "consumer1", false, metrics, nil)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+
+	for _, req := range []*domain.SandboxRequest{
+		{ID: "batch-1", Priority: domain.PriorityBatch},
+		{ID: "normal-1"},
+		{ID: "high-1", Priority: domain.PriorityHigh},
+	} {
+		if err := q.Enqueue(ctx, req); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	// A due delayed request is promoted into its lane
+	if err := q.client.ZAdd(ctx, "test-queue:delayed", redis.Z{Score: 0, Member: `{"id":"high-2","priority":"high"}`}).Err(); err != nil {
+		t.Fatalf("ZAdd failed: %v", err)
+	}
+	if n := q.Len(ctx); n != 3 {
+		t.Errorf("Expected 3 queued requests, got %d", n)
+	}
+	if n := q.LaneLen(ctx, domain.PriorityBatch); n != 1 {
+		t.Errorf("Expected 1 batch request, got %d", n)
+	}
+
+	var receipts []string
+	for _, want := range []domain.SandboxID{"high-1", "high-2", "normal-1"} {
+		req, receipt, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if req.ID != want {
+			t.Errorf("Expected %s, got %s", want, req.ID)
+		}
+		receipts = append(receipts, receipt)
+	}
+	if !strings.HasPrefix(receipts[0], "high:") || strings.Contains(receipts[2], ":") {
+		t.Errorf("Expected receipts to name their lane, got %v", receipts)
+	}
+
+	// Receipts settle on their lane; nacked requests return to it
+	if err := q.Ack(ctx, receipts[0]); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	pending, err := q.client.XPending(ctx, "test-queue:high", "group1").Result()
+	if err != nil {
+		t.Fatalf("XPending failed: %v", err)
+	}
+	if pending.Count != 1 {
+		t.Errorf("Expected 1 pending high request, got %d", pending.Count)
+	}
+	if err := q.Nack(ctx, receipts[1], "retry"); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	req, _, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if req.ID != "high-2" {
+		t.Errorf("Expected the nacked request before the batch lane, got %s", req.ID)
+	}
+	req, _, err = q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if req.ID != "batch-1" {
+		t.Errorf("Expected batch-1 last, got %s", req.ID)
+	}
+}
@@ -0,0 +1,59 @@
+package cerberus
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"time"
+)
+
+// AnonymousIdentityID is the identity requests without credentials are
+// served as when anonymous access allows them.
+const AnonymousIdentityID = "anonymous"
+
+// AnonymousAccess lets requests without credentials read an explicit
+// allow-list of paths, such as a public template catalog. They are served as
+// the anonymous identity and audited like any other request; everything else
+// still requires credentials.
+type AnonymousAccess struct {
+	paths []string
+}
+
+// NewAnonymousAccess allows anonymous GET and HEAD requests to paths, each
+// an exact path or a path.Match pattern ("/templates/*").
+func NewAnonymousAccess(paths []string) (*AnonymousAccess, error) {
+	for _, p := range paths {
+		if _, err := path.Match(p, ""); err != nil || !path.IsAbs(p) {
+			return nil, fmt.Errorf("invalid anonymous path %q", p)
+		}
+	}
+	return &AnonymousAccess{paths: paths}, nil
+}
+
+// Allows reports whether r may be served without credentials.
+func (a *AnonymousAccess) Allows(r *http.Request) bool {
+	if a == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	p := path.Clean(r.URL.Path)
+	return slices.ContainsFunc(a.paths, func(pattern string) bool {
+		ok, _ := path.Match(pattern, p)
+		return ok
+	})
+}
+
+// Identity returns the identity anonymous requests are served as. It has no
+// roles, so RBAC rules grant it nothing.
+func (a *AnonymousAccess) Identity() *Identity {
+	return &Identity{
+		ID:          AnonymousIdentityID,
+		Type:        IdentityTypeAnonymous,
+		TenantID:    AnonymousIdentityID,
+		DisplayName: "Anonymous",
+		Roles:       []string{},
+		Groups:      []string{},
+		Attributes:  map[string]string{},
+		AuthTime:    time.Now(),
+	}
+}
@@ -0,0 +1,73 @@
+package cerberus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPMiddleware_AnonymousAccess(t *testing.T) {
+	audit := &recordingAuditor{}
+	gateway := NewGateway(NewSimpleAPIKeyAuthenticator("valid-key"), NewDenyAllAuthorizer(), audit)
+	middleware := NewHTTPMiddleware(gateway, NewBearerTokenExtractor(), NewDefaultResourceMapper())
+	anonymous, err := NewAnonymousAccess([]string{"/templates", "/templates/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware.SetAnonymousAccess(anonymous)
+
+	var served *Identity
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served, _ = GetIdentity(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		authHeader string
+		wantStatus int
+	}{
+		{"catalog", http.MethodGet, "/templates", "", http.StatusOK},
+		{"template", http.MethodGet, "/templates/python", "", http.StatusOK},
+		{"head", http.MethodHead, "/templates/python", "", http.StatusOK},
+		{"nested path", http.MethodGet, "/templates/python/golden", "", http.StatusUnauthorized},
+		{"write", http.MethodPost, "/templates", "", http.StatusUnauthorized},
+		{"other path", http.MethodGet, "/sandboxes", "", http.StatusUnauthorized},
+		{"invalid credentials", http.MethodGet, "/templates", "Bearer wrong-key", http.StatusUnauthorized},
+		{"malformed credentials", http.MethodGet, "/templates", "Basic abc", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served = nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && (served == nil || served.ID != AnonymousIdentityID || served.Type != IdentityTypeAnonymous) {
+				t.Errorf("expected the anonymous identity, got %+v", served)
+			}
+		})
+	}
+
+	// Anonymous requests are audited under the anonymous identity
+	entry := audit.entries[0]
+	if entry.Identity == nil || entry.Identity.ID != AnonymousIdentityID || entry.Result != AuditResultSuccess || entry.Resource.Type != ResourceTypeTemplate {
+		t.Errorf("unexpected audit entry %+v", entry)
+	}
+}
+
+func TestNewAnonymousAccess_InvalidPath(t *testing.T) {
+	for _, p := range []string{"templates", "/templates/["} {
+		if _, err := NewAnonymousAccess([]string{p}); err == nil {
+			t.Errorf("expected %q to be rejected", p)
+		}
+	}
+}
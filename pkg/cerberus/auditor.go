@@ -44,6 +44,9 @@ func (a *HermesAuditor) RecordAccess(ctx context.Context, entry *AuditEntry) err
 		Latency:      entry.Latency,
 		ErrorMessage: entry.ErrorMessage,
 	}
+	if entry.Event != "" {
+		event.Metadata = map[string]interface{}{"event": entry.Event}
+	}
 
 	if entry.Identity != nil {
 		event.Identity = &audit.Identity{
@@ -101,6 +104,12 @@ func (m *MetricsAuditor) RecordAccess(ctx context.Context, entry *AuditEntry) er
 		)
 	}
 
+	if entry.Event != "" {
+		m.metrics.IncCounter("cerberus_security_events_total", 1,
+			hermes.Label{Key: "type", Value: entry.Event},
+		)
+	}
+
 	return nil
 }
 
@@ -171,6 +180,7 @@ func (l *LogAuditor) RecordAccess(ctx context.Context, entry *AuditEntry) error
 
 	l.logger.Info("access audit",
 		"request_id", entry.RequestID,
+		"event", entry.Event,
 		"action", entry.Action,
 		"result", entry.Result,
 		"resource_type", entry.Resource.Type,
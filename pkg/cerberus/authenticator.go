@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
 
 // Authenticator verifies credentials and returns an identity.
@@ -57,7 +58,7 @@ func NewSimpleAPIKeyAuthenticator(apiKey string) *APIKeyAuthenticator {
 func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
 	apiKeyCred, ok := creds.(*APIKeyCredential)
 	if !ok {
-		return nil, NewAuthenticationError("invalid credential type, expected API key", nil)
+		return nil, NewAuthenticationError("invalid credential type, expected API key", nil).WithCode(AuthCodeUnsupportedCredentials)
 	}
 
 	// Look up the identity for this key
@@ -73,7 +74,7 @@ func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, creds Credential
 
 	// Check if identity has expired
 	if !identity.ExpiresAt.IsZero() && time.Now().After(identity.ExpiresAt) {
-		return nil, NewAuthenticationError("API key has expired", nil)
+		return nil, NewAuthenticationError("API key has expired", nil).WithCode(AuthCodeExpiredCredentials)
 	}
 
 	// Return a copy with updated auth time
@@ -99,24 +100,24 @@ func NewSignedAPIKeyAuthenticator(secretProvider SecretProvider) *SignedAPIKeyAu
 func (a *SignedAPIKeyAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
 	apiKeyCred, ok := creds.(*APIKeyCredential)
 	if !ok {
-		return nil, NewAuthenticationError("invalid credential type, expected API key", nil)
+		return nil, NewAuthenticationError("invalid credential type, expected API key", nil).WithCode(AuthCodeUnsupportedCredentials)
 	}
 
 	// Parse the JWS
 	object, err := jose.ParseSigned(apiKeyCred.Secret, []jose.SignatureAlgorithm{jose.HS256, jose.RS256, jose.ES256})
 	if err != nil {
-		return nil, NewAuthenticationError("invalid API key format", err)
+		return nil, NewAuthenticationError("invalid API key format", err).WithCode(AuthCodeMalformedCredentials)
 	}
 
 	// We expect at least one signature
 	if len(object.Signatures) == 0 {
-		return nil, NewAuthenticationError("API key has no signature", nil)
+		return nil, NewAuthenticationError("API key has no signature", nil).WithCode(AuthCodeMalformedCredentials)
 	}
 
 	// Get the Key ID from the header
 	kid := object.Signatures[0].Header.KeyID
 	if kid == "" {
-		return nil, NewAuthenticationError("API key missing key ID (kid)", nil)
+		return nil, NewAuthenticationError("API key missing key ID (kid)", nil).WithCode(AuthCodeMalformedCredentials)
 	}
 
 	// Resolve the key
@@ -126,7 +127,7 @@ func (a *SignedAPIKeyAuthenticator) Authenticate(ctx context.Context, creds Cred
 	// Let's assume it's a shared secret for HS256 for now, or we can try to parse it.
 	keyStr, err := a.secretProvider.Resolve(ctx, "key:"+kid)
 	if err != nil {
-		return nil, NewAuthenticationError(fmt.Sprintf("unknown key ID: %s", kid), err)
+		return nil, NewAuthenticationError(fmt.Sprintf("unknown key ID: %s", kid), err).WithCode(AuthCodeUnknownKey)
 	}
 
 	// Verify the signature
@@ -134,18 +135,18 @@ func (a *SignedAPIKeyAuthenticator) Authenticate(ctx context.Context, creds Cred
 	// Here we assume HMAC with the resolved secret.
 	payload, err := object.Verify([]byte(keyStr))
 	if err != nil {
-		return nil, NewAuthenticationError("invalid API key signature", err)
+		return nil, NewAuthenticationError("invalid API key signature", err).WithCode(AuthCodeInvalidSignature)
 	}
 
 	// Parse the payload into an Identity
 	var identity Identity
 	if err := json.Unmarshal(payload, &identity); err != nil {
-		return nil, NewAuthenticationError("invalid API key payload", err)
+		return nil, NewAuthenticationError("invalid API key payload", err).WithCode(AuthCodeMalformedCredentials)
 	}
 
 	// Check expiration
 	if !identity.ExpiresAt.IsZero() && time.Now().After(identity.ExpiresAt) {
-		return nil, NewAuthenticationError("API key has expired", nil)
+		return nil, NewAuthenticationError("API key has expired", nil).WithCode(AuthCodeExpiredCredentials)
 	}
 
 	// Update AuthTime
@@ -154,9 +155,30 @@ func (a *SignedAPIKeyAuthenticator) Authenticate(ctx context.Context, creds Cred
 	return &identity, nil
 }
 
+// NamedAuthenticator is an Authenticator that MultiAuthenticator reports
+// metrics and health for under its own name, such as a plugin.
+type NamedAuthenticator interface {
+	Authenticator
+	Name() string
+}
+
+// HealthChecker is implemented by authenticators that depend on something
+// that can fail independently of a request, such as an external IdP.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// AuthenticatorHealth is the health of one named authenticator.
+type AuthenticatorHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
 // MultiAuthenticator tries multiple authenticators in order until one succeeds.
 type MultiAuthenticator struct {
 	authenticators []Authenticator
+	metrics        hermes.Metrics
 }
 
 // NewMultiAuthenticator creates an authenticator that tries multiple strategies.
@@ -166,21 +188,74 @@ func NewMultiAuthenticator(authenticators ...Authenticator) *MultiAuthenticator
 	}
 }
 
-// Authenticate tries each authenticator until one succeeds.
+// WithMetrics makes the authenticator count successes and failures of each
+// NamedAuthenticator in cerberus_authenticator_attempts_total.
+func (m *MultiAuthenticator) WithMetrics(metrics hermes.Metrics) *MultiAuthenticator {
+	m.metrics = metrics
+	return m
+}
+
+// Authenticate tries each authenticator until one succeeds. If none does,
+// it returns the error with the most specific code, so that an expired token
+// is not reported as the wrong credential type for an API key.
 func (m *MultiAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
-	var lastErr error
+	var bestErr error
 
 	for _, auth := range m.authenticators {
 		identity, err := auth.Authenticate(ctx, creds)
+		m.record(auth, err)
 		if err == nil {
 			return identity, nil
 		}
-		lastErr = err
+		bestErr = preferAuthError(bestErr, err)
 	}
 
-	if lastErr != nil {
-		return nil, lastErr
+	if bestErr != nil {
+		return nil, bestErr
 	}
 
 	return nil, NewAuthenticationError("no authenticators configured", nil)
 }
+
+func (m *MultiAuthenticator) record(auth Authenticator, err error) {
+	named, ok := auth.(NamedAuthenticator)
+	if !ok || m.metrics == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.metrics.IncCounter("cerberus_authenticator_attempts_total", 1,
+		hermes.Label{Key: "source", Value: named.Name()},
+		hermes.Label{Key: "result", Value: result},
+	)
+}
+
+// Health checks every named authenticator. Those that do not implement
+// HealthChecker are reported healthy.
+func (m *MultiAuthenticator) Health(ctx context.Context) []AuthenticatorHealth {
+	var health []AuthenticatorHealth
+	for _, auth := range m.authenticators {
+		named, ok := auth.(NamedAuthenticator)
+		if !ok {
+			continue
+		}
+		h := AuthenticatorHealth{Name: named.Name(), Healthy: true}
+		if checker, ok := auth.(HealthChecker); ok {
+			if err := checker.Health(ctx); err != nil {
+				h.Healthy = false
+				h.Error = err.Error()
+			}
+		}
+		if m.metrics != nil {
+			healthy := 0.0
+			if h.Healthy {
+				healthy = 1
+			}
+			m.metrics.SetGauge("cerberus_authenticator_healthy", healthy, hermes.Label{Key: "source", Value: h.Name})
+		}
+		health = append(health, h)
+	}
+	return health
+}
@@ -1,6 +1,9 @@
 package cerberus
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 // Authorizer checks if an identity has permission to perform an action on a resource.
 type Authorizer interface {
@@ -37,6 +40,22 @@ type Permission struct {
 	Actions   []Action       `yaml:"actions" json:"actions"`
 	Resources []ResourceType `yaml:"resources" json:"resources"`
 	AllowAll  bool           `yaml:"allowAll" json:"allow_all"`
+
+	// Require limits the permission to identities whose attributes hold
+	// one of the listed values for every key, e.g. {"ca": ["Prod Agents CA"]}
+	// for client certificates issued by the prod CA.
+	Require map[string][]string `yaml:"require,omitempty" json:"require,omitempty"`
+}
+
+// admits reports whether the identity's attributes satisfy Require.
+func (p Permission) admits(identity *Identity) bool {
+	for key, allowed := range p.Require {
+		value, ok := identity.Attributes[key]
+		if !ok || !slices.Contains(allowed, value) {
+			return false
+		}
+	}
+	return true
 }
 
 // NewRBACAuthorizer creates a role-based authorizer.
@@ -57,6 +76,9 @@ func (r *RBACAuthorizer) Authorize(ctx context.Context, identity *Identity, acti
 
 		// Check if any permission in this policy allows the action
 		for _, perm := range policy.Permissions {
+			if !perm.admits(identity) {
+				continue
+			}
 			if perm.AllowAll {
 				return nil // Full access
 			}
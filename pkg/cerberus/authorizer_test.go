@@ -78,6 +78,16 @@ func TestRBACAuthorizer(t *testing.T) {
 				},
 			},
 		},
+		"agent": {
+			Role: "agent",
+			Permissions: []Permission{
+				{
+					Actions:   []Action{ActionUpdate},
+					Resources: []ResourceType{ResourceTypeNode},
+					Require:   map[string][]string{"ca": {"Prod Agents CA"}},
+				},
+			},
+		},
 		"readonly": {
 			Role: "readonly",
 			Permissions: []Permission{
@@ -168,6 +178,38 @@ func TestRBACAuthorizer(t *testing.T) {
 			resource: Resource{Type: ResourceTypeSandbox, ID: "sandbox-123"},
 			wantErr:  true,
 		},
+		{
+			name: "agent from required CA is allowed",
+			identity: &Identity{
+				ID:         "agent-1",
+				Roles:      []string{"agent"},
+				Attributes: map[string]string{"ca": "Prod Agents CA"},
+			},
+			action:   ActionUpdate,
+			resource: Resource{Type: ResourceTypeNode, ID: "node-1"},
+			wantErr:  false,
+		},
+		{
+			name: "agent from other CA is denied",
+			identity: &Identity{
+				ID:         "agent-2",
+				Roles:      []string{"agent"},
+				Attributes: map[string]string{"ca": "Staging Agents CA"},
+			},
+			action:   ActionUpdate,
+			resource: Resource{Type: ResourceTypeNode, ID: "node-2"},
+			wantErr:  true,
+		},
+		{
+			name: "agent without attributes is denied",
+			identity: &Identity{
+				ID:    "agent-3",
+				Roles: []string{"agent"},
+			},
+			action:   ActionUpdate,
+			resource: Resource{Type: ResourceTypeNode, ID: "node-3"},
+			wantErr:  true,
+		},
 		{
 			name: "user with multiple roles uses first matching",
 			identity: &Identity{
@@ -0,0 +1,103 @@
+package cerberus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Realm is the realm of the WWW-Authenticate challenges Cerberus answers
+// refused requests with.
+const Realm = "tartarus"
+
+// AuthErrorResponse is the body of a request Cerberus refused.
+type AuthErrorResponse struct {
+	Error string        `json:"error"`
+	Code  AuthErrorCode `json:"code"`
+}
+
+// Bearer error codes of RFC 6750.
+const (
+	bearerInvalidRequest    = "invalid_request"
+	bearerInvalidToken      = "invalid_token"
+	bearerInsufficientScope = "insufficient_scope"
+)
+
+// bearerError maps a code to the RFC 6750 error of its challenge. Requests
+// without credentials get a challenge without error.
+func (c AuthErrorCode) bearerError() string {
+	switch c {
+	case AuthCodeMissingCredentials:
+		return ""
+	case AuthCodeMalformedCredentials, AuthCodeUnsupportedCredentials:
+		return bearerInvalidRequest
+	case AuthCodeInsufficientScope:
+		return bearerInsufficientScope
+	}
+	return bearerInvalidToken
+}
+
+// Challenge returns the WWW-Authenticate value for a request refused with
+// err: a Bearer challenge with the RFC 6750 error and the error's message
+// as error_description. Refused authorizations name the scope they lacked.
+func Challenge(err error) string {
+	code := AuthErrorCodeOf(err)
+	if code == "" {
+		code = AuthCodeInvalidCredentials
+	}
+	params := []string{fmt.Sprintf("realm=%q", Realm)}
+	if bearer := code.bearerError(); bearer != "" {
+		params = append(params, fmt.Sprintf("error=%q", bearer))
+		params = append(params, fmt.Sprintf("error_description=%q", challengeText(authErrorMessage(err))))
+	}
+	var authzErr *AuthorizationError
+	if errors.As(err, &authzErr) {
+		params = append(params, fmt.Sprintf("scope=%q", challengeText(string(authzErr.Resource.Type)+":"+string(authzErr.Action))))
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// challengeText drops the characters RFC 6750 does not allow in challenge
+// parameters: anything but printable ASCII, quotes and backslashes.
+func challengeText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// authErrorMessage is the message clients see for err. Authentication
+// errors hide their cause, which may describe keys or the IdP.
+func authErrorMessage(err error) string {
+	var authnErr *AuthenticationError
+	var authzErr *AuthorizationError
+	switch {
+	case errors.As(err, &authnErr):
+		return authnErr.Message
+	case errors.As(err, &authzErr):
+		return authzErr.Message
+	}
+	return err.Error()
+}
+
+// WriteAuthError refuses a request with err: 403 for authorization errors
+// and 401 otherwise, with a WWW-Authenticate challenge and an
+// AuthErrorResponse body.
+func WriteAuthError(w http.ResponseWriter, err error) {
+	code := AuthErrorCodeOf(err)
+	if code == "" {
+		code = AuthCodeInvalidCredentials
+	}
+	status := http.StatusUnauthorized
+	if code == AuthCodeInsufficientScope {
+		status = http.StatusForbidden
+	}
+	w.Header().Set("WWW-Authenticate", Challenge(err))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(AuthErrorResponse{Error: authErrorMessage(err), Code: code})
+}
@@ -0,0 +1,118 @@
+package cerberus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPMiddleware_AuthErrors(t *testing.T) {
+	expired := &Identity{ID: "old", Roles: []string{"admin"}, ExpiresAt: time.Now().Add(-time.Hour)}
+	auth := NewAPIKeyAuthenticator(map[string]*Identity{"expired-key": expired})
+	revocations := NewMemoryRevocationList()
+	gateway := NewGateway(NewMultiAuthenticator(NewMTLSAuthenticator(nil), auth), NewDenyAllAuthorizer(), NewNoopAuditor())
+	gateway.SetRevocationList(revocations)
+	middleware := NewHTTPMiddleware(gateway, NewCompositeCredentialExtractor(NewBearerTokenExtractor(), NewMTLSExtractor()), NewDefaultResourceMapper())
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	}))
+
+	tests := []struct {
+		name       string
+		authHeader string
+		wantStatus int
+		wantCode   AuthErrorCode
+		wantChall  string
+	}{
+		{
+			name:       "missing credentials",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   AuthCodeMissingCredentials,
+			wantChall:  `Bearer realm="tartarus"`,
+		},
+		{
+			name:       "malformed header",
+			authHeader: "Basic abc",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   AuthCodeMalformedCredentials,
+			wantChall:  `Bearer realm="tartarus", error="invalid_request", error_description="invalid Authorization header format"`,
+		},
+		{
+			name:       "unknown key",
+			authHeader: "Bearer wrong-key",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   AuthCodeInvalidCredentials,
+			wantChall:  `Bearer realm="tartarus", error="invalid_token", error_description="invalid API key"`,
+		},
+		{
+			name:       "expired key",
+			authHeader: "Bearer expired-key",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   AuthCodeExpiredCredentials,
+			wantChall:  `Bearer realm="tartarus", error="invalid_token", error_description="API key has expired"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/sandboxes", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.wantChall {
+				t.Errorf("got challenge %s, want %s", got, tt.wantChall)
+			}
+			var body AuthErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("got code %s, want %s", body.Code, tt.wantCode)
+			}
+		})
+	}
+
+	t.Run("revoked", func(t *testing.T) {
+		expired.ExpiresAt = time.Time{}
+		revocations.Revoke(context.Background(), Revocation{Kind: RevokeIdentity, Subject: "old", Reason: "key leaked"})
+		req := httptest.NewRequest("POST", "/sandboxes", nil)
+		req.Header.Set("Authorization", "Bearer expired-key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		want := `Bearer realm="tartarus", error="invalid_token", error_description="identity revoked: key leaked"`
+		if got := rec.Header().Get("WWW-Authenticate"); rec.Code != http.StatusUnauthorized || got != want {
+			t.Errorf("got %d with challenge %s, want 401 with %s", rec.Code, got, want)
+		}
+	})
+
+	t.Run("insufficient scope", func(t *testing.T) {
+		revocations.Lift(context.Background(), RevokeIdentity, "old")
+		req := httptest.NewRequest("POST", "/sandboxes", nil)
+		req.Header.Set("Authorization", "Bearer expired-key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		want := `Bearer realm="tartarus", error="insufficient_scope", error_description="all access denied (maintenance mode)", scope="sandbox:create"`
+		if got := rec.Header().Get("WWW-Authenticate"); rec.Code != http.StatusForbidden || got != want {
+			t.Errorf("got %d with challenge %s, want 403 with %s", rec.Code, got, want)
+		}
+	})
+}
+
+func TestChallenge_DropsDisallowedCharacters(t *testing.T) {
+	err := NewAuthenticationError("bad \"key\" \\ é", nil)
+	want := `Bearer realm="tartarus", error="invalid_token", error_description="bad key  "`
+	if got := Challenge(err); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
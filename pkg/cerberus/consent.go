@@ -0,0 +1,241 @@
+package cerberus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTermsNotAccepted is returned for identities that have not accepted
+	// the current terms of service.
+	ErrTermsNotAccepted = errors.New("terms of service not accepted")
+	// ErrTermsVersionMismatch is returned when an identity accepts a version
+	// of the terms other than the current one.
+	ErrTermsVersionMismatch = errors.New("terms of service version is not current")
+)
+
+// Audit events recorded by the ConsentGate.
+const (
+	EventTermsRequired = "terms_required"
+	EventTermsAccepted = "terms_accepted"
+)
+
+// TermsVersionHeader reports the terms version a refused request must accept.
+const TermsVersionHeader = "Terms-Version"
+
+// Terms describes the terms of service identities must accept before they
+// are authorized.
+type Terms struct {
+	Version       string         // Current version; bumping it requires everyone to accept again
+	URL           string         // Where the terms can be read
+	IdentityTypes []IdentityType // Identity types that must accept (default users only)
+	ExemptPaths   []string       // Path prefixes served without acceptance (default "/terms")
+	DenyStatus    int            // Status for refused requests: 451 (default) or 403
+}
+
+// Acknowledgement records an identity accepting a version of the terms.
+type Acknowledgement struct {
+	IdentityID string    `json:"identity_id"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	SourceIP   string    `json:"source_ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// ConsentStore keeps the terms acknowledgements of each identity.
+type ConsentStore interface {
+	// Accept records an acknowledgement. Accepting a version again keeps
+	// the first acknowledgement.
+	Accept(ctx context.Context, ack Acknowledgement) error
+
+	// Acknowledgement returns the identity's acknowledgement of version,
+	// or nil if it has not accepted it.
+	Acknowledgement(ctx context.Context, identityID, version string) (*Acknowledgement, error)
+}
+
+// MemoryConsentStore is an in-memory ConsentStore for single-replica deployments.
+type MemoryConsentStore struct {
+	mu   sync.Mutex
+	acks map[string]map[string]Acknowledgement
+}
+
+// NewMemoryConsentStore creates an empty in-memory consent store.
+func NewMemoryConsentStore() *MemoryConsentStore {
+	return &MemoryConsentStore{acks: make(map[string]map[string]Acknowledgement)}
+}
+
+// Accept implements ConsentStore.
+func (m *MemoryConsentStore) Accept(ctx context.Context, ack Acknowledgement) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.acks[ack.IdentityID] == nil {
+		m.acks[ack.IdentityID] = make(map[string]Acknowledgement)
+	}
+	if _, ok := m.acks[ack.IdentityID][ack.Version]; !ok {
+		m.acks[ack.IdentityID][ack.Version] = ack
+	}
+	return nil
+}
+
+// Acknowledgement implements ConsentStore.
+func (m *MemoryConsentStore) Acknowledgement(ctx context.Context, identityID, version string) (*Acknowledgement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ack, ok := m.acks[identityID][version]
+	if !ok {
+		return nil, nil
+	}
+	return &ack, nil
+}
+
+// TermsStatus is an identity's standing against the current terms.
+type TermsStatus struct {
+	Version  string           `json:"version"`
+	URL      string           `json:"url,omitempty"`
+	Required bool             `json:"required"`           // Whether the identity must accept before it is authorized
+	Accepted *Acknowledgement `json:"accepted,omitempty"` // The identity's acceptance of the current version
+}
+
+// ConsentGate refuses identities that have not accepted the current terms
+// of service, and records acceptances. Refusals and acceptances are audited.
+type ConsentGate struct {
+	store   ConsentStore
+	terms   Terms
+	auditor Auditor
+}
+
+// NewConsentGate creates a gate for terms backed by store. The auditor may be nil.
+func NewConsentGate(store ConsentStore, terms Terms, auditor Auditor) *ConsentGate {
+	if len(terms.IdentityTypes) == 0 {
+		terms.IdentityTypes = []IdentityType{IdentityTypeUser}
+	}
+	if terms.ExemptPaths == nil {
+		terms.ExemptPaths = []string{"/terms"}
+	}
+	if terms.DenyStatus != http.StatusForbidden {
+		terms.DenyStatus = http.StatusUnavailableForLegalReasons
+	}
+	return &ConsentGate{
+		store:   store,
+		terms:   terms,
+		auditor: auditor,
+	}
+}
+
+// Terms returns the terms the gate enforces.
+func (g *ConsentGate) Terms() Terms {
+	return g.terms
+}
+
+// required reports whether the identity must accept the terms.
+func (g *ConsentGate) required(identity *Identity) bool {
+	return identity != nil && slices.Contains(g.terms.IdentityTypes, identity.Type)
+}
+
+// Status returns the identity's standing against the current terms.
+func (g *ConsentGate) Status(ctx context.Context, identity *Identity) (*TermsStatus, error) {
+	status := &TermsStatus{
+		Version:  g.terms.Version,
+		URL:      g.terms.URL,
+		Required: g.required(identity),
+	}
+	if identity == nil {
+		return status, nil
+	}
+	ack, err := g.store.Acknowledgement(ctx, identity.ID, g.terms.Version)
+	if err != nil {
+		return nil, err
+	}
+	status.Accepted = ack
+	return status, nil
+}
+
+// Check returns ErrTermsNotAccepted if the request's identity must accept
+// the current terms and has not. Exempt paths always pass.
+func (g *ConsentGate) Check(ctx context.Context, identity *Identity, r *http.Request) error {
+	if !g.required(identity) {
+		return nil
+	}
+	for _, prefix := range g.terms.ExemptPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return nil
+		}
+	}
+
+	ack, err := g.store.Acknowledgement(ctx, identity.ID, g.terms.Version)
+	if err != nil {
+		return fmt.Errorf("failed to look up terms acknowledgement: %w", err)
+	}
+	if ack == nil {
+		g.record(ctx, r, identity, EventTermsRequired, AuditResultDenied,
+			fmt.Sprintf("terms version %s not accepted", g.terms.Version))
+		return fmt.Errorf("%w: version %s", ErrTermsNotAccepted, g.terms.Version)
+	}
+	return nil
+}
+
+// Accept records the identity accepting version, which must be the current
+// version of the terms.
+func (g *ConsentGate) Accept(ctx context.Context, identity *Identity, version string, r *http.Request) (*Acknowledgement, error) {
+	if version != g.terms.Version {
+		return nil, fmt.Errorf("%w: accepted %q, current is %q", ErrTermsVersionMismatch, version, g.terms.Version)
+	}
+
+	ack := Acknowledgement{
+		IdentityID: identity.ID,
+		Version:    version,
+		AcceptedAt: time.Now().UTC(),
+		SourceIP:   getSourceIP(r),
+		UserAgent:  r.UserAgent(),
+	}
+	if err := g.store.Accept(ctx, ack); err != nil {
+		return nil, fmt.Errorf("failed to record terms acknowledgement: %w", err)
+	}
+	stored, err := g.store.Acknowledgement(ctx, identity.ID, version)
+	if err != nil || stored == nil {
+		stored = &ack
+	}
+	g.record(ctx, r, identity, EventTermsAccepted, AuditResultSuccess,
+		fmt.Sprintf("accepted terms version %s", version))
+	return stored, nil
+}
+
+// Deny writes the response for a request refused by Check.
+func (g *ConsentGate) Deny(w http.ResponseWriter) {
+	w.Header().Set(TermsVersionHeader, g.terms.Version)
+	if g.terms.URL != "" {
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"terms-of-service\"", g.terms.URL))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(g.terms.DenyStatus)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":         ErrTermsNotAccepted.Error(),
+		"terms_version": g.terms.Version,
+		"terms_url":     g.terms.URL,
+	})
+}
+
+func (g *ConsentGate) record(ctx context.Context, r *http.Request, identity *Identity, event string, result AuditResult, message string) {
+	if g.auditor == nil {
+		return
+	}
+	_ = g.auditor.RecordAccess(ctx, &AuditEntry{
+		Timestamp:    time.Now(),
+		RequestID:    r.Header.Get("X-Request-ID"),
+		Event:        event,
+		Identity:     identity,
+		Result:       result,
+		SourceIP:     getSourceIP(r),
+		UserAgent:    r.UserAgent(),
+		ErrorMessage: message,
+	})
+}
@@ -0,0 +1,111 @@
+package cerberus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestHTTPMiddleware_ConsentGate(t *testing.T) {
+	audit := &recordingAuditor{}
+	gateway := NewGateway(NewSimpleAPIKeyAuthenticator("valid-key"), NewAllowAllAuthorizer(), audit)
+	middleware := NewHTTPMiddleware(gateway, NewBearerTokenExtractor(), NewDefaultResourceMapper())
+	gate := NewConsentGate(NewMemoryConsentStore(), Terms{
+		Version:       "2026-10",
+		URL:           "https://example.com/terms",
+		IdentityTypes: []IdentityType{IdentityTypeService},
+	}, audit)
+	middleware.SetConsentGate(gate)
+
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer valid-key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("/sandboxes")
+	if rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("before acceptance: got status %d, want 451", rec.Code)
+	}
+	if rec.Header().Get(TermsVersionHeader) != "2026-10" || rec.Header().Get("Link") != `<https://example.com/terms>; rel="terms-of-service"` {
+		t.Errorf("expected the required terms in the response, got %v", rec.Header())
+	}
+
+	// The acceptance endpoint is reachable before accepting
+	if rec := request("/terms"); rec.Code != http.StatusOK {
+		t.Errorf("terms endpoint: got status %d", rec.Code)
+	}
+
+	identity := &Identity{ID: "api-key-user", Type: IdentityTypeService}
+	req := httptest.NewRequest("POST", "/terms", nil)
+	if _, err := gate.Accept(context.Background(), identity, "2025-01", req); !errors.Is(err, ErrTermsVersionMismatch) {
+		t.Errorf("expected ErrTermsVersionMismatch for an old version, got %v", err)
+	}
+	if _, err := gate.Accept(context.Background(), identity, "2026-10", req); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if rec := request("/sandboxes"); rec.Code != http.StatusOK {
+		t.Errorf("after acceptance: got status %d", rec.Code)
+	}
+
+	events := audit.events()
+	if len(events) != 2 || events[0] != EventTermsRequired || events[1] != EventTermsAccepted {
+		t.Errorf("expected terms_required then terms_accepted, got %v", events)
+	}
+}
+
+func TestConsentGate_Scope(t *testing.T) {
+	gate := NewConsentGate(NewMemoryConsentStore(), Terms{Version: "v1", DenyStatus: http.StatusForbidden}, nil)
+	req := httptest.NewRequest("GET", "/sandboxes", nil)
+
+	// Only users must accept by default
+	if err := gate.Check(context.Background(), &Identity{ID: "svc", Type: IdentityTypeService}, req); err != nil {
+		t.Errorf("expected services to pass, got %v", err)
+	}
+	if err := gate.Check(context.Background(), &Identity{ID: "alice", Type: IdentityTypeUser}, req); !errors.Is(err, ErrTermsNotAccepted) {
+		t.Errorf("expected ErrTermsNotAccepted for a user, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gate.Deny(rec)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected the configured deny status, got %d", rec.Code)
+	}
+}
+
+func TestRedisConsentStore(t *testing.T) {
+	s := miniredis.RunT(t)
+	store, err := NewRedisConsentStore(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	if ack, err := store.Acknowledgement(ctx, "alice", "v1"); err != nil || ack != nil {
+		t.Fatalf("expected no acknowledgement, got %+v, %v", ack, err)
+	}
+	if err := store.Accept(ctx, Acknowledgement{IdentityID: "alice", Version: "v1", SourceIP: "10.0.0.1"}); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	// Accepting again keeps the original acknowledgement
+	if err := store.Accept(ctx, Acknowledgement{IdentityID: "alice", Version: "v1", SourceIP: "10.0.0.2"}); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+
+	ack, err := store.Acknowledgement(ctx, "alice", "v1")
+	if err != nil || ack == nil || ack.SourceIP != "10.0.0.1" {
+		t.Errorf("expected the first acknowledgement, got %+v, %v", ack, err)
+	}
+	if ack, _ := store.Acknowledgement(ctx, "alice", "v2"); ack != nil {
+		t.Errorf("expected a new version to need acceptance, got %+v", ack)
+	}
+}
@@ -1,9 +1,90 @@
 package cerberus
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// AuthErrorCode is the stable, machine-readable reason a request was
+// refused. Clients act on the code; messages may change.
+type AuthErrorCode string
+
+const (
+	// AuthCodeMissingCredentials means the request carried no credentials.
+	AuthCodeMissingCredentials AuthErrorCode = "missing_credentials"
+	// AuthCodeMalformedCredentials means the credentials could not be parsed.
+	AuthCodeMalformedCredentials AuthErrorCode = "malformed_credentials"
+	// AuthCodeUnsupportedCredentials means an authenticator does not take
+	// this kind of credentials; another one may.
+	AuthCodeUnsupportedCredentials AuthErrorCode = "unsupported_credentials"
+	// AuthCodeInvalidCredentials means the credentials are not known.
+	AuthCodeInvalidCredentials AuthErrorCode = "invalid_credentials"
+	// AuthCodeExpiredCredentials means the credentials were valid but expired.
+	AuthCodeExpiredCredentials AuthErrorCode = "expired_credentials"
+	// AuthCodeInvalidSignature means the credentials' signature did not verify.
+	AuthCodeInvalidSignature AuthErrorCode = "invalid_signature"
+	// AuthCodeUnknownKey means the credentials were signed with an unknown key.
+	AuthCodeUnknownKey AuthErrorCode = "unknown_key"
+	// AuthCodeWrongAudience means the token was issued for another audience.
+	AuthCodeWrongAudience AuthErrorCode = "wrong_audience"
+	// AuthCodeWrongIssuer means the token was issued by another provider.
+	AuthCodeWrongIssuer AuthErrorCode = "wrong_issuer"
+	// AuthCodeUntrustedCertificate means the client certificate did not
+	// chain to a trusted CA.
+	AuthCodeUntrustedCertificate AuthErrorCode = "untrusted_certificate"
+	// AuthCodeRevoked means the credentials or identity were revoked.
+	AuthCodeRevoked AuthErrorCode = "revoked_credentials"
+	// AuthCodeSessionEvicted means the session was evicted by a newer one.
+	AuthCodeSessionEvicted AuthErrorCode = "session_evicted"
+	// AuthCodeInsufficientScope means the identity may not perform the action.
+	AuthCodeInsufficientScope AuthErrorCode = "insufficient_scope"
+)
+
+// specificity ranks codes by how much they tell a client, so that
+// MultiAuthenticator reports the most useful of its authenticators' errors.
+func (c AuthErrorCode) specificity() int {
+	switch c {
+	case AuthCodeMissingCredentials, AuthCodeUnsupportedCredentials:
+		return 0
+	case AuthCodeInvalidCredentials:
+		return 1
+	}
+	return 2
+}
+
+// preferAuthError returns whichever of best and err tells a client more,
+// keeping best on a tie.
+func preferAuthError(best, err error) error {
+	if best == nil || AuthErrorCodeOf(err).specificity() > AuthErrorCodeOf(best).specificity() {
+		return err
+	}
+	return best
+}
+
+// AuthErrorCodeOf returns the code of an authentication or authorization
+// error, or "" for other errors.
+func AuthErrorCodeOf(err error) AuthErrorCode {
+	var authnErr *AuthenticationError
+	var authzErr *AuthorizationError
+	switch {
+	case errors.Is(err, ErrRevoked):
+		return AuthCodeRevoked
+	case errors.Is(err, ErrSessionEvicted):
+		return AuthCodeSessionEvicted
+	case errors.As(err, &authnErr):
+		if authnErr.Code == "" {
+			return AuthCodeInvalidCredentials
+		}
+		return authnErr.Code
+	case errors.As(err, &authzErr):
+		return AuthCodeInsufficientScope
+	}
+	return ""
+}
 
 // AuthenticationError indicates that credentials are invalid or missing.
 type AuthenticationError struct {
+	Code    AuthErrorCode // AuthCodeInvalidCredentials if empty
 	Message string
 	Cause   error
 }
@@ -27,6 +108,12 @@ func NewAuthenticationError(message string, cause error) *AuthenticationError {
 	}
 }
 
+// WithCode sets the error's code.
+func (e *AuthenticationError) WithCode(code AuthErrorCode) *AuthenticationError {
+	e.Code = code
+	return e
+}
+
 // AuthorizationError indicates that the identity lacks permission.
 type AuthorizationError struct {
 	Message  string
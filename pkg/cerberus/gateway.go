@@ -37,6 +37,7 @@ const (
 	CredentialTypeOAuth2   CredentialType = "oauth2"
 	CredentialTypeMTLS     CredentialType = "mtls"
 	CredentialTypeInternal CredentialType = "internal"
+	CredentialTypeProxy    CredentialType = "proxy"
 )
 
 // APIKeyCredential represents API key-based authentication.
@@ -76,6 +77,9 @@ const (
 	IdentityTypeService IdentityType = "service"
 	IdentityTypeAgent   IdentityType = "agent"
 	IdentityTypeSystem  IdentityType = "system"
+	// IdentityTypeAnonymous is the identity of requests served without
+	// credentials by AnonymousAccess.
+	IdentityTypeAnonymous IdentityType = "anonymous"
 )
 
 // Action represents an operation being performed.
@@ -108,6 +112,8 @@ const (
 	ResourceTypeSnapshot ResourceType = "snapshot"
 	ResourceTypePolicy   ResourceType = "policy"
 	ResourceTypeNode     ResourceType = "node"
+	ResourceTypeSession  ResourceType = "session"
+	ResourceTypeUsage    ResourceType = "usage"
 	ResourceTypeAll      ResourceType = "*"
 )
 
@@ -115,6 +121,7 @@ const (
 type AuditEntry struct {
 	Timestamp    time.Time
 	RequestID    string
+	Event        string // Security event other than a plain access, e.g. "session_evicted"
 	Identity     *Identity
 	Action       Action
 	Resource     Resource
@@ -140,6 +147,7 @@ type DefaultGateway struct {
 	authenticator Authenticator
 	authorizer    Authorizer
 	auditor       Auditor
+	revocations   RevocationList // Optional; consulted on every Authenticate
 }
 
 // NewGateway creates a new Gateway with the three heads.
@@ -151,9 +159,22 @@ func NewGateway(auth Authenticator, authz Authorizer, audit Auditor) *DefaultGat
 	}
 }
 
-// Authenticate delegates to the configured Authenticator.
+// SetRevocationList refuses revoked credentials and identities.
+func (g *DefaultGateway) SetRevocationList(l RevocationList) {
+	g.revocations = l
+}
+
+// Authenticate delegates to the configured Authenticator, then refuses
+// credentials the revocation list names.
 func (g *DefaultGateway) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
-	return g.authenticator.Authenticate(ctx, creds)
+	identity, err := g.authenticator.Authenticate(ctx, creds)
+	if err != nil || g.revocations == nil {
+		return identity, err
+	}
+	if err := checkRevoked(ctx, g.revocations, creds, identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
 }
 
 // Authorize delegates to the configured Authorizer.
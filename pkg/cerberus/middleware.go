@@ -2,6 +2,7 @@ package cerberus
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -20,6 +21,9 @@ type HTTPMiddleware struct {
 	gateway   Gateway
 	extractor CredentialExtractor
 	mapper    ResourceMapper
+	sessions  *SessionLimiter  // Optional per-identity session limits
+	consent   *ConsentGate     // Optional terms of service gate
+	anonymous *AnonymousAccess // Optional paths readable without credentials
 }
 
 // CredentialExtractor extracts credentials from an HTTP request.
@@ -41,6 +45,23 @@ func NewHTTPMiddleware(gateway Gateway, extractor CredentialExtractor, mapper Re
 	}
 }
 
+// SetSessionLimiter enforces per-identity session limits on authenticated requests.
+func (m *HTTPMiddleware) SetSessionLimiter(l *SessionLimiter) {
+	m.sessions = l
+}
+
+// SetConsentGate refuses authenticated identities that have not accepted the
+// current terms of service.
+func (m *HTTPMiddleware) SetConsentGate(g *ConsentGate) {
+	m.consent = g
+}
+
+// SetAnonymousAccess serves requests without credentials to the paths a
+// allows as the anonymous identity.
+func (m *HTTPMiddleware) SetAnonymousAccess(a *AnonymousAccess) {
+	m.anonymous = a
+}
+
 // Wrap returns an HTTP handler that enforces authentication, authorization, and audit.
 func (m *HTTPMiddleware) Wrap(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -48,9 +69,13 @@ func (m *HTTPMiddleware) Wrap(next http.Handler) http.Handler {
 
 		// Extract credentials from request
 		creds, err := m.extractor.Extract(r)
+		if err != nil && AuthErrorCodeOf(err) == AuthCodeMissingCredentials && m.anonymous.Allows(r) {
+			m.serveAnonymous(w, r, next, startTime)
+			return
+		}
 		if err != nil {
 			m.recordAndRespond(r.Context(), w, r, nil, AuditResultDenied, err, startTime)
-			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			WriteAuthError(w, err)
 			return
 		}
 
@@ -58,10 +83,43 @@ func (m *HTTPMiddleware) Wrap(next http.Handler) http.Handler {
 		identity, err := m.gateway.Authenticate(r.Context(), creds)
 		if err != nil {
 			m.recordAndRespond(r.Context(), w, r, nil, AuditResultDenied, err, startTime)
-			http.Error(w, "Unauthorized: Invalid credentials", http.StatusUnauthorized)
+			if errors.Is(err, ErrRevocationUnavailable) {
+				http.Error(w, "Service Unavailable: revocation list unavailable", http.StatusServiceUnavailable)
+			} else {
+				WriteAuthError(w, err)
+			}
 			return
 		}
 
+		// Enforce per-identity session limits
+		if m.sessions != nil {
+			if err := m.sessions.Admit(r.Context(), identity, creds, r); err != nil {
+				m.recordAndRespond(r.Context(), w, r, identity, AuditResultDenied, err, startTime)
+				switch {
+				case errors.Is(err, ErrSessionLimitExceeded):
+					http.Error(w, "Too Many Requests: "+err.Error(), http.StatusTooManyRequests)
+				case errors.Is(err, ErrSessionEvicted):
+					WriteAuthError(w, err)
+				default:
+					http.Error(w, "Service Unavailable: session store unavailable", http.StatusServiceUnavailable)
+				}
+				return
+			}
+		}
+
+		// Require acceptance of the current terms of service
+		if m.consent != nil {
+			if err := m.consent.Check(r.Context(), identity, r); err != nil {
+				m.recordAndRespond(r.Context(), w, r, identity, AuditResultDenied, err, startTime)
+				if errors.Is(err, ErrTermsNotAccepted) {
+					m.consent.Deny(w)
+				} else {
+					http.Error(w, "Service Unavailable: consent store unavailable", http.StatusServiceUnavailable)
+				}
+				return
+			}
+		}
+
 		// Map request to action and resource
 		action, resource, err := m.mapper.MapRequest(r, identity)
 		if err != nil {
@@ -73,7 +131,11 @@ func (m *HTTPMiddleware) Wrap(next http.Handler) http.Handler {
 		// Authorize
 		if err := m.gateway.Authorize(r.Context(), identity, action, resource); err != nil {
 			m.recordAndRespond(r.Context(), w, r, identity, AuditResultDenied, err, startTime)
-			http.Error(w, "Forbidden: Insufficient permissions", http.StatusForbidden)
+			var authzErr *AuthorizationError
+			if !errors.As(err, &authzErr) {
+				err = NewAuthorizationError("insufficient permissions", identity, action, resource)
+			}
+			WriteAuthError(w, err)
 			return
 		}
 
@@ -89,6 +151,17 @@ func (m *HTTPMiddleware) Wrap(next http.Handler) http.Handler {
 	})
 }
 
+// serveAnonymous serves a request without credentials that anonymous access
+// allows. The allow-list is its authorization: the anonymous identity is not
+// subject to the authorizer, session limits or terms of service.
+func (m *HTTPMiddleware) serveAnonymous(w http.ResponseWriter, r *http.Request, next http.Handler, startTime time.Time) {
+	identity := m.anonymous.Identity()
+	ctx := context.WithValue(r.Context(), IdentityContextKey, identity)
+	r = r.WithContext(ctx)
+	m.recordAndRespond(ctx, w, r, identity, AuditResultSuccess, nil, startTime)
+	next.ServeHTTP(w, r)
+}
+
 // recordAndRespond creates an audit entry and records it.
 func (m *HTTPMiddleware) recordAndRespond(ctx context.Context, w http.ResponseWriter, r *http.Request, identity *Identity, result AuditResult, err error, startTime time.Time) {
 	action, resource, _ := m.mapper.MapRequest(r, identity)
@@ -125,12 +198,12 @@ func NewBearerTokenExtractor() *BearerTokenExtractor {
 func (e *BearerTokenExtractor) Extract(r *http.Request) (Credentials, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return nil, NewAuthenticationError("missing Authorization header", nil)
+		return nil, NewAuthenticationError("missing Authorization header", nil).WithCode(AuthCodeMissingCredentials)
 	}
 
 	parts := strings.SplitN(authHeader, " ", 2)
 	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-		return nil, NewAuthenticationError("invalid Authorization header format", nil)
+		return nil, NewAuthenticationError("invalid Authorization header format", nil).WithCode(AuthCodeMalformedCredentials)
 	}
 
 	return &APIKeyCredential{
@@ -150,11 +223,11 @@ func NewMTLSExtractor() *MTLSExtractor {
 // Extract returns mTLS credentials if a client certificate is present.
 func (e *MTLSExtractor) Extract(r *http.Request) (Credentials, error) {
 	if r.TLS == nil {
-		return nil, NewAuthenticationError("no TLS connection", nil)
+		return nil, NewAuthenticationError("no TLS connection", nil).WithCode(AuthCodeMissingCredentials)
 	}
 
 	if len(r.TLS.PeerCertificates) == 0 {
-		return nil, NewAuthenticationError("no client certificate provided", nil)
+		return nil, NewAuthenticationError("no client certificate provided", nil).WithCode(AuthCodeMissingCredentials)
 	}
 
 	return &MTLSCredential{
@@ -175,23 +248,25 @@ func NewCompositeCredentialExtractor(extractors ...CredentialExtractor) *Composi
 	}
 }
 
-// Extract tries each extractor in order until one succeeds.
+// Extract tries each extractor in order until one succeeds. If none does,
+// it returns the error with the most specific code, so that a malformed
+// Authorization header is not reported as a missing client certificate.
 func (e *CompositeCredentialExtractor) Extract(r *http.Request) (Credentials, error) {
-	var lastErr error
+	var bestErr error
 
 	for _, extractor := range e.extractors {
 		creds, err := extractor.Extract(r)
 		if err == nil {
 			return creds, nil
 		}
-		lastErr = err
+		bestErr = preferAuthError(bestErr, err)
 	}
 
-	if lastErr != nil {
-		return nil, lastErr
+	if bestErr != nil {
+		return nil, bestErr
 	}
 
-	return nil, NewAuthenticationError("no credentials found", nil)
+	return nil, NewAuthenticationError("no credentials found", nil).WithCode(AuthCodeMissingCredentials)
 }
 
 // DefaultResourceMapper provides a simple mapping from HTTP requests to resources.
@@ -218,6 +293,21 @@ func (m *DefaultResourceMapper) MapRequest(r *http.Request, identity *Identity)
 	default:
 		action = ActionRead
 	}
+	// Running commands in a sandbox is the same whether fired with POST or
+	// interactively over a WebSocket upgraded from GET
+	if strings.HasPrefix(r.URL.Path, "/sandboxes/") && strings.HasSuffix(r.URL.Path, "/exec") {
+		action = ActionCreate
+	}
+	// Accepting the terms of service only concerns the caller
+	if strings.HasPrefix(r.URL.Path, "/terms") {
+		action = ActionRead
+	}
+	// Listing sessions, revoking credentials and reading API usage are
+	// administrative
+	if strings.HasPrefix(r.URL.Path, "/sessions") || strings.HasPrefix(r.URL.Path, "/revocations") ||
+		strings.HasPrefix(r.URL.Path, "/admin/usage") {
+		action = ActionAdmin
+	}
 
 	// Parse path to determine resource type
 	// This is a simple implementation; a real one would parse the path more carefully
@@ -235,8 +325,14 @@ func (m *DefaultResourceMapper) MapRequest(r *http.Request, identity *Identity)
 		}
 	case strings.HasPrefix(path, "/templates"):
 		resourceType = ResourceTypeTemplate
-	case strings.HasPrefix(path, "/policies"):
+	case strings.HasPrefix(path, "/policies"), strings.HasPrefix(path, "/quotas"), strings.HasPrefix(path, "/applications"):
+		// Tenant quotas are kept in tenant policies; applications write a
+		// template and its policy
 		resourceType = ResourceTypePolicy
+	case strings.HasPrefix(path, "/sessions"), strings.HasPrefix(path, "/revocations"):
+		resourceType = ResourceTypeSession
+	case strings.HasPrefix(path, "/admin/usage"):
+		resourceType = ResourceTypeUsage
 	default:
 		resourceType = ResourceTypeSandbox // Default
 	}
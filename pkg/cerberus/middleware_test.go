@@ -108,6 +108,14 @@ func TestDefaultResourceMapper(t *testing.T) {
 			wantResource:   ResourceTypeSandbox,
 			wantResourceID: "123",
 		},
+		{
+			name:           "GET /sandboxes/123/exec",
+			method:         "GET",
+			path:           "/sandboxes/123/exec",
+			wantAction:     ActionCreate,
+			wantResource:   ResourceTypeSandbox,
+			wantResourceID: "123",
+		},
 		{
 			name:           "GET /templates",
 			method:         "GET",
@@ -124,6 +132,38 @@ func TestDefaultResourceMapper(t *testing.T) {
 			wantResource:   ResourceTypePolicy,
 			wantResourceID: "",
 		},
+		{
+			name:           "PUT /quotas/acme",
+			method:         "PUT",
+			path:           "/quotas/acme",
+			wantAction:     ActionUpdate,
+			wantResource:   ResourceTypePolicy,
+			wantResourceID: "",
+		},
+		{
+			name:           "PUT /applications/web",
+			method:         "PUT",
+			path:           "/applications/web",
+			wantAction:     ActionUpdate,
+			wantResource:   ResourceTypePolicy,
+			wantResourceID: "",
+		},
+		{
+			name:           "GET /quota",
+			method:         "GET",
+			path:           "/quota",
+			wantAction:     ActionRead,
+			wantResource:   ResourceTypeSandbox,
+			wantResourceID: "",
+		},
+		{
+			name:           "GET /admin/usage",
+			method:         "GET",
+			path:           "/admin/usage",
+			wantAction:     ActionAdmin,
+			wantResource:   ResourceTypeUsage,
+			wantResourceID: "",
+		},
 	}
 
 	for _, tt := range tests {
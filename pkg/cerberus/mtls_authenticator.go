@@ -2,16 +2,35 @@ package cerberus
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"time"
 )
 
 // MTLSAuthenticator validates client certificates.
+//
+// Besides the identity, the certificate's posture is copied into the
+// identity's attributes, so authorization policies can require it:
+//
+//   - "ca" and "ca_fingerprint": the common name and SHA-256 fingerprint of
+//     the trusted root the certificate chains to
+//   - "ou": the subject's organizational units, comma-separated
+//   - one attribute per extension listed in ExtensionAttributes
 type MTLSAuthenticator struct {
 	// TrustedCAs is the pool of trusted CAs for client certificates.
 	// If nil, the system's default root CAs are used (which might not be what we want for mTLS).
 	TrustedCAs *x509.CertPool
+
+	// ExtensionAttributes maps certificate extension OIDs, in dotted form,
+	// to the attribute each is copied to, e.g. a device ID, environment or
+	// build fingerprint set by the issuing CA. String values are copied as
+	// is, others hex-encoded. They cannot replace the built-in attributes.
+	ExtensionAttributes map[string]string
 }
 
 // NewMTLSAuthenticator creates a new mTLS authenticator.
@@ -26,11 +45,11 @@ func NewMTLSAuthenticator(trustedCAs *x509.CertPool) *MTLSAuthenticator {
 func (a *MTLSAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
 	mtlsCred, ok := creds.(*MTLSCredential)
 	if !ok {
-		return nil, NewAuthenticationError("invalid credential type, expected mTLS", nil)
+		return nil, NewAuthenticationError("invalid credential type, expected mTLS", nil).WithCode(AuthCodeUnsupportedCredentials)
 	}
 
 	if len(mtlsCred.ConnectionState.PeerCertificates) == 0 {
-		return nil, NewAuthenticationError("no client certificate provided", nil)
+		return nil, NewAuthenticationError("no client certificate provided", nil).WithCode(AuthCodeMissingCredentials)
 	}
 
 	// The first certificate is the leaf
@@ -41,8 +60,14 @@ func (a *MTLSAuthenticator) Authenticate(ctx context.Context, creds Credentials)
 		Roots:     a.TrustedCAs,
 		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 	}
-	if _, err := cert.Verify(opts); err != nil {
-		return nil, NewAuthenticationError("failed to verify client certificate", err)
+	chains, err := cert.Verify(opts)
+	if err != nil {
+		code := AuthCodeUntrustedCertificate
+		var invalid x509.CertificateInvalidError
+		if errors.As(err, &invalid) && invalid.Reason == x509.Expired {
+			code = AuthCodeExpiredCredentials
+		}
+		return nil, NewAuthenticationError("failed to verify client certificate", err).WithCode(code)
 	}
 
 	// Extract identity from Subject CN or SANs
@@ -75,17 +100,46 @@ func (a *MTLSAuthenticator) Authenticate(ctx context.Context, creds Credentials)
 		DisplayName: cert.Subject.CommonName,
 		Roles:       []string{"agent"}, // Default role for mTLS agents
 		Groups:      cert.Subject.Organization,
-		Attributes: map[string]string{
-			"serial_number": cert.SerialNumber.String(),
-			"issuer":        cert.Issuer.CommonName,
-		},
-		AuthTime:  time.Now(),
-		ExpiresAt: cert.NotAfter,
+		Attributes:  a.postureAttributes(cert, chains[0]),
+		AuthTime:    time.Now(),
+		ExpiresAt:   cert.NotAfter,
 	}
 
 	return identity, nil
 }
 
+// postureAttributes describes the certificate and the chain it was verified
+// through.
+func (a *MTLSAuthenticator) postureAttributes(cert *x509.Certificate, chain []*x509.Certificate) map[string]string {
+	attrs := make(map[string]string)
+	for _, ext := range cert.Extensions {
+		if name := a.ExtensionAttributes[ext.Id.String()]; name != "" {
+			attrs[name] = extensionValue(ext.Value)
+		}
+	}
+
+	root := chain[len(chain)-1]
+	fingerprint := sha256.Sum256(root.Raw)
+	attrs["serial_number"] = cert.SerialNumber.String()
+	attrs["issuer"] = cert.Issuer.CommonName
+	attrs["ca"] = root.Subject.CommonName
+	attrs["ca_fingerprint"] = hex.EncodeToString(fingerprint[:])
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		attrs["ou"] = strings.Join(cert.Subject.OrganizationalUnit, ",")
+	}
+	return attrs
+}
+
+// extensionValue decodes an extension holding an ASN.1 string, and
+// hex-encodes any other value.
+func extensionValue(der []byte) string {
+	var s string
+	if rest, err := asn1.Unmarshal(der, &s); err == nil && len(rest) == 0 {
+		return s
+	}
+	return hex.EncodeToString(der)
+}
+
 // MTLSCredential holds the TLS connection state.
 type MTLSCredential struct {
 	ConnectionState tls.ConnectionState
@@ -2,7 +2,9 @@ package cerberus
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 )
@@ -45,28 +47,59 @@ func NewOIDCAuthenticator(ctx context.Context, issuerURL, clientID string, apiAu
 }
 
 // Authenticate validates the OIDC ID token or Access Token.
-// The credential must be a BearerTokenCredential.
+// The credential must be a BearerTokenCredential, or an APIKeyCredential as
+// produced by the BearerTokenExtractor for the Authorization header.
 func (a *OIDCAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
-	tokenCred, ok := creds.(*BearerTokenCredential)
-	if !ok {
-		return nil, NewAuthenticationError("invalid credential type, expected bearer token", nil)
+	var token string
+	switch c := creds.(type) {
+	case *BearerTokenCredential:
+		token = c.Token
+	case *APIKeyCredential:
+		token = c.Secret
+	default:
+		return nil, NewAuthenticationError("invalid credential type, expected bearer token", nil).WithCode(AuthCodeUnsupportedCredentials)
 	}
 
 	// Try verifying as ID Token (User Flow)
-	idToken, err := a.idTokenVerifier.Verify(ctx, tokenCred.Token)
+	idToken, err := a.idTokenVerifier.Verify(ctx, token)
 	if err == nil {
 		return a.identityFromToken(idToken, IdentityTypeUser)
 	}
 
 	// If failed, and we have an access token verifier, try that (Service Flow)
 	if a.accessTokenVerifier != nil {
-		accessToken, err := a.accessTokenVerifier.Verify(ctx, tokenCred.Token)
-		if err == nil {
+		accessToken, accessErr := a.accessTokenVerifier.Verify(ctx, token)
+		if accessErr == nil {
 			return a.identityFromToken(accessToken, IdentityTypeService)
 		}
+		// An audience mismatch against both says less than the other error
+		if oidcErrorCode(err) == AuthCodeWrongAudience {
+			err = accessErr
+		}
 	}
 
-	return nil, NewAuthenticationError("invalid token", err)
+	return nil, NewAuthenticationError("invalid token", err).WithCode(oidcErrorCode(err))
+}
+
+// oidcErrorCode classifies a token verification error. go-oidc has an error
+// type for expiry only; the rest are told apart by their messages.
+func oidcErrorCode(err error) AuthErrorCode {
+	var expired *oidc.TokenExpiredError
+	if errors.As(err, &expired) {
+		return AuthCodeExpiredCredentials
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "expected audience"):
+		return AuthCodeWrongAudience
+	case strings.Contains(msg, "issued by a different provider"):
+		return AuthCodeWrongIssuer
+	case strings.Contains(msg, "failed to verify signature"):
+		return AuthCodeInvalidSignature
+	case strings.Contains(msg, "malformed jwt"), strings.Contains(msg, "not signed"):
+		return AuthCodeMalformedCredentials
+	}
+	return AuthCodeInvalidCredentials
 }
 
 func (a *OIDCAuthenticator) identityFromToken(token *oidc.IDToken, idType IdentityType) (*Identity, error) {
@@ -81,7 +114,7 @@ func (a *OIDCAuthenticator) identityFromToken(token *oidc.IDToken, idType Identi
 		ClientID string `json:"client_id"`
 	}
 	if err := token.Claims(&claims); err != nil {
-		return nil, NewAuthenticationError("failed to parse claims", err)
+		return nil, NewAuthenticationError("failed to parse claims", err).WithCode(AuthCodeMalformedCredentials)
 	}
 
 	// For service accounts, Subject might be the client ID or a UUID.
@@ -0,0 +1,282 @@
+package cerberus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// SessionCookieName holds the ID token issued by the OIDC login flow.
+	SessionCookieName = "tartarus_session"
+
+	// loginCookieName carries state, nonce and PKCE verifier between
+	// /auth/login and /auth/callback.
+	loginCookieName = "tartarus_oidc_login"
+	loginCookieTTL  = 10 * time.Minute
+)
+
+// OIDCLoginConfig configures the browser login endpoints.
+type OIDCLoginConfig struct {
+	IssuerURL          string
+	ClientID           string
+	ClientSecret       string   // Optional for public clients; PKCE is always used
+	RedirectURL        string   // Absolute URL of /auth/callback as registered with the provider
+	Scopes             []string // Default: openid, profile, email
+	PostLogoutRedirect string   // Where /auth/logout sends the browser (default "/")
+	InsecureCookies    bool     // Drop the Secure flag, for plain-HTTP development only
+}
+
+// OIDCLogin implements the OIDC authorization-code flow with PKCE:
+//
+//   - GET /auth/login?return_to=/path redirects to the provider
+//   - GET /auth/callback exchanges the code and sets the session cookie
+//   - GET or POST /auth/logout clears the session and ends the provider session if supported
+//
+// The session cookie holds the provider's ID token, so the OIDCAuthenticator
+// validates it like a bearer token.
+type OIDCLogin struct {
+	oauth         *oauth2.Config
+	verifier      *oidc.IDTokenVerifier
+	endSessionURL string
+	cfg           OIDCLoginConfig
+}
+
+// loginState is stored in the short-lived login cookie.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+// NewOIDCLogin discovers the provider configuration from the issuer URL.
+func NewOIDCLogin(ctx context.Context, cfg OIDCLoginConfig) (*OIDCLogin, error) {
+	if cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC login requires a redirect URL")
+	}
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query provider: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if cfg.PostLogoutRedirect == "" {
+		cfg.PostLogoutRedirect = "/"
+	}
+
+	// end_session_endpoint is optional (RP-initiated logout)
+	var metadata struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	_ = provider.Claims(&metadata)
+
+	return &OIDCLogin{
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+		},
+		verifier:      provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		endSessionURL: metadata.EndSessionEndpoint,
+		cfg:           cfg,
+	}, nil
+}
+
+// ServeHTTP routes the /auth/ endpoints. These must be served outside the
+// Cerberus middleware since the caller is not yet authenticated.
+func (l *OIDCLogin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/auth/login":
+		l.login(w, r)
+	case "/auth/callback":
+		l.callback(w, r)
+	case "/auth/logout":
+		l.logout(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (l *OIDCLogin) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	st := loginState{
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		Verifier: oauth2.GenerateVerifier(),
+		ReturnTo: safeReturnTo(r.URL.Query().Get("return_to")),
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, l.cookie(loginCookieName, base64.RawURLEncoding.EncodeToString(data), "/auth", time.Now().Add(loginCookieTTL)))
+
+	authURL := l.oauth.AuthCodeURL(st.State, oidc.Nonce(st.Nonce), oauth2.S256ChallengeOption(st.Verifier))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (l *OIDCLogin) callback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "Login failed: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	st, err := readLoginState(r)
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The login cookie is single-use
+	http.SetCookie(w, l.cookie(loginCookieName, "", "/auth", time.Unix(0, 0)))
+
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(st.State)) != 1 {
+		http.Error(w, "Login failed: state mismatch", http.StatusBadRequest)
+		return
+	}
+
+	token, err := l.oauth.Exchange(r.Context(), q.Get("code"), oauth2.VerifierOption(st.Verifier))
+	if err != nil {
+		http.Error(w, "Login failed: code exchange failed", http.StatusUnauthorized)
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		http.Error(w, "Login failed: provider returned no ID token", http.StatusUnauthorized)
+		return
+	}
+	idToken, err := l.verifier.Verify(r.Context(), rawIDToken)
+	if err != nil {
+		http.Error(w, "Login failed: invalid ID token", http.StatusUnauthorized)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(st.Nonce)) != 1 {
+		http.Error(w, "Login failed: nonce mismatch", http.StatusUnauthorized)
+		return
+	}
+
+	http.SetCookie(w, l.cookie(SessionCookieName, rawIDToken, "/", idToken.Expiry))
+	http.Redirect(w, r, st.ReturnTo, http.StatusFound)
+}
+
+func (l *OIDCLogin) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var idTokenHint string
+	if c, err := r.Cookie(SessionCookieName); err == nil {
+		idTokenHint = c.Value
+	}
+	http.SetCookie(w, l.cookie(SessionCookieName, "", "/", time.Unix(0, 0)))
+
+	target := l.cfg.PostLogoutRedirect
+	if l.endSessionURL != "" && idTokenHint != "" {
+		if u, err := url.Parse(l.endSessionURL); err == nil {
+			v := u.Query()
+			v.Set("id_token_hint", idTokenHint)
+			v.Set("client_id", l.cfg.ClientID)
+			if strings.HasPrefix(l.cfg.PostLogoutRedirect, "http") {
+				v.Set("post_logout_redirect_uri", l.cfg.PostLogoutRedirect)
+			}
+			u.RawQuery = v.Encode()
+			target = u.String()
+		}
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// cookie builds an HttpOnly cookie. SameSite=Lax keeps the session from
+// being sent on cross-site POSTs while still allowing the provider redirect.
+func (l *OIDCLogin) cookie(name, value, path string, expires time.Time) *http.Cookie {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   !l.cfg.InsecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		c.MaxAge = -1
+	}
+	return c
+}
+
+func readLoginState(r *http.Request) (*loginState, error) {
+	c, err := r.Cookie(loginCookieName)
+	if err != nil {
+		return nil, fmt.Errorf("login session not found or expired")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return nil, fmt.Errorf("malformed login session")
+	}
+	var st loginState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("malformed login session")
+	}
+	return &st, nil
+}
+
+// safeReturnTo only allows local paths so the login flow cannot be used as
+// an open redirect.
+func safeReturnTo(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return "/"
+	}
+	return p
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// SessionCookieExtractor reads the OIDC session cookie set by OIDCLogin.
+type SessionCookieExtractor struct{}
+
+// NewSessionCookieExtractor creates a credential extractor for session cookies.
+func NewSessionCookieExtractor() *SessionCookieExtractor {
+	return &SessionCookieExtractor{}
+}
+
+// Extract returns the session's ID token as a bearer token credential.
+func (e *SessionCookieExtractor) Extract(r *http.Request) (Credentials, error) {
+	c, err := r.Cookie(SessionCookieName)
+	if err != nil || c.Value == "" {
+		return nil, NewAuthenticationError("missing session cookie", nil).WithCode(AuthCodeMissingCredentials)
+	}
+	return &BearerTokenCredential{Token: c.Value}, nil
+}
@@ -0,0 +1,210 @@
+package cerberus_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+)
+
+// fakeProvider is a minimal OIDC provider that issues RS256 ID tokens and
+// enforces PKCE on the token endpoint.
+type fakeProvider struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	signer jose.Signer
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "k1"))
+	require.NoError(t, err)
+
+	p := &fakeProvider{key: key, signer: signer}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.srv.URL,
+			"authorization_endpoint":                p.srv.URL + "/authorize",
+			"token_endpoint":                        p.srv.URL + "/token",
+			"jwks_uri":                              p.srv.URL + "/keys",
+			"end_session_endpoint":                  p.srv.URL + "/logout",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "k1", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "code-1" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		claims, _ := json.Marshal(map[string]any{
+			"iss":   p.srv.URL,
+			"aud":   "tartarus",
+			"sub":   "user-42",
+			"email": "user@example.com",
+			"nonce": p.nonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		obj, err := p.signer.Sign(claims)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		idToken, _ := obj.CompactSerialize()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+func TestOIDCLogin_AuthCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	provider := newFakeProvider(t)
+
+	login, err := cerberus.NewOIDCLogin(ctx, cerberus.OIDCLoginConfig{
+		IssuerURL:          provider.srv.URL,
+		ClientID:           "tartarus",
+		RedirectURL:        "https://olympus.example.com/auth/callback",
+		PostLogoutRedirect: "https://olympus.example.com/",
+	})
+	require.NoError(t, err)
+
+	// 1. Login redirects to the provider with PKCE and a nonce
+	rec := httptest.NewRecorder()
+	login.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/login?return_to=/sandboxes", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	q := authURL.Query()
+	assert.Equal(t, provider.srv.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, "code", q.Get("response_type"))
+	require.NotEmpty(t, q.Get("state"))
+
+	provider.mu.Lock()
+	provider.challenge = q.Get("code_challenge")
+	provider.nonce = q.Get("nonce")
+	provider.mu.Unlock()
+
+	loginCookies := rec.Result().Cookies()
+	require.Len(t, loginCookies, 1)
+	assert.True(t, loginCookies[0].HttpOnly)
+	assert.True(t, loginCookies[0].Secure)
+
+	callback := func(state string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth/callback?code=code-1&state="+url.QueryEscape(state), nil)
+		req.AddCookie(loginCookies[0])
+		rec := httptest.NewRecorder()
+		login.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 2. A forged state is rejected
+	assert.Equal(t, http.StatusBadRequest, callback("forged").Code)
+
+	// 3. The callback sets the session cookie and returns to the original page
+	rec = callback(q.Get("state"))
+	require.Equal(t, http.StatusFound, rec.Code, rec.Body.String())
+	assert.Equal(t, "/sandboxes", rec.Header().Get("Location"))
+
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == cerberus.SessionCookieName {
+			session = c
+		}
+	}
+	require.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, session.SameSite)
+
+	// 4. The session cookie authenticates API requests
+	auth, err := cerberus.NewOIDCAuthenticator(ctx, provider.srv.URL, "tartarus", "")
+	require.NoError(t, err)
+	apiReq := httptest.NewRequest(http.MethodGet, "/sandboxes", nil)
+	apiReq.AddCookie(session)
+	creds, err := cerberus.NewSessionCookieExtractor().Extract(apiReq)
+	require.NoError(t, err)
+	identity, err := auth.Authenticate(ctx, creds)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", identity.ID)
+
+	// 5. Logout clears the session and ends the provider session
+	logoutReq := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	logoutReq.AddCookie(session)
+	rec = httptest.NewRecorder()
+	login.ServeHTTP(rec, logoutReq)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	logoutURL, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/logout", logoutURL.Path)
+	assert.Equal(t, session.Value, logoutURL.Query().Get("id_token_hint"))
+	assert.Equal(t, "https://olympus.example.com/", logoutURL.Query().Get("post_logout_redirect_uri"))
+	cleared := rec.Result().Cookies()
+	require.Len(t, cleared, 1)
+	assert.Equal(t, cerberus.SessionCookieName, cleared[0].Name)
+	assert.Less(t, cleared[0].MaxAge, 0)
+}
+
+func TestOIDCLogin_RejectsOpenRedirect(t *testing.T) {
+	provider := newFakeProvider(t)
+	login, err := cerberus.NewOIDCLogin(context.Background(), cerberus.OIDCLoginConfig{
+		IssuerURL:   provider.srv.URL,
+		ClientID:    "tartarus",
+		RedirectURL: "https://olympus.example.com/auth/callback",
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	login.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/login?return_to=//evil.example.com", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	cookie := rec.Result().Cookies()[0]
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	require.NoError(t, err)
+	var st struct {
+		ReturnTo string `json:"return_to"`
+	}
+	require.NoError(t, json.Unmarshal(data, &st))
+	assert.Equal(t, "/", st.ReturnTo)
+}
@@ -0,0 +1,300 @@
+package cerberus
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// DefaultOPAQuery is the Rego rule evaluated for every request.
+const DefaultOPAQuery = "data.tartarus.authz.allow"
+
+// OPAAuthorizer makes authorization decisions with Rego policies.
+//
+// Policies are loaded as an OPA bundle from a directory, a .tar.gz bundle
+// file, or a bundle server URL. Each request is evaluated with the input
+//
+//	{"identity": {...}, "action": "read", "resource": {"type": "sandbox", ...}}
+//
+// and allowed when Query yields true, or an object whose "allow" is true
+// (its "reason", if any, is reported on denial). Start polls the source and
+// swaps in changed bundles without a restart.
+type OPAAuthorizer struct {
+	// Source is a bundle directory, a bundle .tar.gz file, or an http(s) URL.
+	Source string
+	// Query is the Rego query to evaluate; defaults to DefaultOPAQuery.
+	Query string
+	// Token, if set, is sent as a bearer token to the bundle server.
+	Token string
+
+	Client *http.Client
+	Logger *slog.Logger
+
+	mu       sync.RWMutex
+	prepared *rego.PreparedEvalQuery
+	revision string // Content hash (files) or ETag (bundle server) of the loaded bundle
+}
+
+// NewOPAAuthorizer creates an authorizer for the bundle at source. An empty
+// query uses DefaultOPAQuery. Every request is denied until Reload has loaded
+// a bundle.
+func NewOPAAuthorizer(source, query string, logger *slog.Logger) *OPAAuthorizer {
+	if query == "" {
+		query = DefaultOPAQuery
+	}
+	return &OPAAuthorizer{
+		Source: source,
+		Query:  query,
+		Client: &http.Client{Timeout: 30 * time.Second},
+		Logger: logger,
+	}
+}
+
+// Reload fetches the bundle and, if it changed since the last load, compiles
+// and swaps it in. It reports whether a new bundle was loaded. On error the
+// previous bundle stays in effect.
+func (a *OPAAuthorizer) Reload(ctx context.Context) (bool, error) {
+	a.mu.RLock()
+	current := a.revision
+	a.mu.RUnlock()
+
+	b, revision, err := a.fetch(ctx, current)
+	if err != nil {
+		return false, err
+	}
+	if b == nil {
+		return false, nil
+	}
+
+	prepared, err := rego.New(
+		rego.Query(a.Query),
+		rego.ParsedBundle("tartarus", b),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to compile policy bundle: %w", err)
+	}
+
+	a.mu.Lock()
+	a.prepared = &prepared
+	a.revision = revision
+	a.mu.Unlock()
+
+	if a.Logger != nil {
+		a.Logger.Info("Loaded OPA policy bundle", "source", a.Source, "revision", revision, "modules", len(b.Modules))
+	}
+	return true, nil
+}
+
+// Start polls the bundle source for changes until ctx is done.
+func (a *OPAAuthorizer) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.Reload(ctx); err != nil && a.Logger != nil {
+				a.Logger.Error("Failed to reload OPA policy bundle", "source", a.Source, "error", err)
+			}
+		}
+	}
+}
+
+// Revision returns the content hash or ETag of the bundle in effect.
+func (a *OPAAuthorizer) Revision() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.revision
+}
+
+// Authorize evaluates the policy for the request.
+func (a *OPAAuthorizer) Authorize(ctx context.Context, identity *Identity, action Action, resource Resource) error {
+	a.mu.RLock()
+	prepared := a.prepared
+	a.mu.RUnlock()
+	if prepared == nil {
+		return NewAuthorizationError("no policy bundle loaded", identity, action, resource)
+	}
+
+	rs, err := prepared.Eval(ctx, rego.EvalInput(opaInput(identity, action, resource)))
+	if err != nil {
+		return NewAuthorizationError(fmt.Sprintf("policy evaluation failed: %v", err), identity, action, resource)
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		// Undefined decisions deny
+		return NewAuthorizationError("denied by policy", identity, action, resource)
+	}
+
+	switch decision := rs[0].Expressions[0].Value.(type) {
+	case bool:
+		if decision {
+			return nil
+		}
+	case map[string]any:
+		if allow, _ := decision["allow"].(bool); allow {
+			return nil
+		}
+		if reason, _ := decision["reason"].(string); reason != "" {
+			return NewAuthorizationError(reason, identity, action, resource)
+		}
+	}
+	return NewAuthorizationError("denied by policy", identity, action, resource)
+}
+
+// opaInput is the document policies see as input.
+func opaInput(identity *Identity, action Action, resource Resource) map[string]any {
+	return map[string]any{
+		"identity": map[string]any{
+			"id":           identity.ID,
+			"type":         string(identity.Type),
+			"tenant_id":    identity.TenantID,
+			"display_name": identity.DisplayName,
+			"roles":        nonNil(identity.Roles),
+			"groups":       nonNil(identity.Groups),
+			"attributes":   identity.Attributes,
+		},
+		"action": string(action),
+		"resource": map[string]any{
+			"type":      string(resource.Type),
+			"id":        resource.ID,
+			"tenant_id": resource.TenantID,
+			"namespace": resource.Namespace,
+		},
+	}
+}
+
+// nonNil keeps empty lists from reaching policies as null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// fetch reads the bundle, returning nil if its revision is still current.
+func (a *OPAAuthorizer) fetch(ctx context.Context, current string) (*bundle.Bundle, string, error) {
+	if strings.HasPrefix(a.Source, "http://") || strings.HasPrefix(a.Source, "https://") {
+		return a.fetchRemote(ctx, current)
+	}
+
+	info, err := os.Stat(a.Source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read policy bundle: %w", err)
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(a.Source)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read policy bundle: %w", err)
+		}
+		sum := sha256.Sum256(data)
+		revision := hex.EncodeToString(sum[:])
+		if revision == current {
+			return nil, "", nil
+		}
+		b, err := bundle.NewReader(bytes.NewReader(data)).Read()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read policy bundle: %w", err)
+		}
+		return &b, revision, nil
+	}
+
+	revision, err := hashDir(a.Source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read policy bundle: %w", err)
+	}
+	if revision == current {
+		return nil, "", nil
+	}
+	b, err := bundle.NewCustomReader(bundle.NewDirectoryLoader(a.Source)).Read()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read policy bundle: %w", err)
+	}
+	return &b, revision, nil
+}
+
+// fetchRemote downloads the bundle, sending the ETag of the current one so
+// the server can answer 304 Not Modified.
+func (a *OPAAuthorizer) fetchRemote(ctx context.Context, current string) (*bundle.Bundle, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.Source, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if current != "" {
+		req.Header.Set("If-None-Match", current)
+	}
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download policy bundle: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, "", nil
+	case http.StatusOK:
+	default:
+		return nil, "", fmt.Errorf("failed to download policy bundle: bundle server returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download policy bundle: %w", err)
+	}
+	revision := resp.Header.Get("ETag")
+	if revision == "" {
+		// Servers without ETags are compared by content
+		sum := sha256.Sum256(data)
+		revision = hex.EncodeToString(sum[:])
+	}
+	if revision == current {
+		return nil, "", nil
+	}
+
+	b, err := bundle.NewReader(bytes.NewReader(data)).WithBundleEtag(resp.Header.Get("ETag")).Read()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read policy bundle: %w", err)
+	}
+	return &b, revision, nil
+}
+
+// hashDir hashes the names and contents of the files under dir.
+func hashDir(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "%s\x00%d\x00", rel, len(data))
+		h.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,181 @@
+package cerberus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+const opaTestPolicy = `package tartarus.authz
+
+default allow := false
+
+allow if {
+	input.identity.attributes.ca == "Prod Agents CA"
+	input.resource.type == "node"
+}
+
+allow if {
+	"admin" in input.identity.roles
+}
+`
+
+func TestOPAAuthorizer_Directory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writePolicy(t, dir, opaTestPolicy)
+
+	authz := NewOPAAuthorizer(dir, "", nil)
+	if err := authz.Authorize(ctx, &Identity{ID: "admin"}, ActionRead, Resource{Type: ResourceTypeSandbox}); err == nil {
+		t.Fatal("expected denial before a bundle is loaded")
+	}
+	if loaded, err := authz.Reload(ctx); err != nil || !loaded {
+		t.Fatalf("Reload() = %v, %v; want true, nil", loaded, err)
+	}
+
+	tests := []struct {
+		name     string
+		identity *Identity
+		resource Resource
+		wantErr  bool
+	}{
+		{"admin role", &Identity{ID: "root", Roles: []string{"admin"}}, Resource{Type: ResourceTypeSandbox}, false},
+		{"prod agent on node", &Identity{ID: "agent-1", Attributes: map[string]string{"ca": "Prod Agents CA"}}, Resource{Type: ResourceTypeNode}, false},
+		{"staging agent on node", &Identity{ID: "agent-2", Attributes: map[string]string{"ca": "Staging CA"}}, Resource{Type: ResourceTypeNode}, true},
+		{"prod agent on sandbox", &Identity{ID: "agent-1", Attributes: map[string]string{"ca": "Prod Agents CA"}}, Resource{Type: ResourceTypeSandbox}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authz.Authorize(ctx, tt.identity, ActionUpdate, tt.resource)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Unchanged files are not reloaded
+	if loaded, err := authz.Reload(ctx); err != nil || loaded {
+		t.Fatalf("Reload() of unchanged bundle = %v, %v; want false, nil", loaded, err)
+	}
+
+	// Edits are picked up without a new authorizer
+	writePolicy(t, dir, "package tartarus.authz\n\nallow := true\n")
+	if loaded, err := authz.Reload(ctx); err != nil || !loaded {
+		t.Fatalf("Reload() after edit = %v, %v; want true, nil", loaded, err)
+	}
+	if err := authz.Authorize(ctx, &Identity{ID: "agent-2"}, ActionUpdate, Resource{Type: ResourceTypeNode}); err != nil {
+		t.Errorf("expected reloaded policy to allow, got %v", err)
+	}
+
+	// A broken bundle keeps the previous one in effect
+	writePolicy(t, dir, "package tartarus.authz\n\nallow if {")
+	if _, err := authz.Reload(ctx); err == nil {
+		t.Fatal("expected error for invalid policy")
+	}
+	if err := authz.Authorize(ctx, &Identity{ID: "agent-2"}, ActionUpdate, Resource{Type: ResourceTypeNode}); err != nil {
+		t.Errorf("expected previous policy to stay in effect, got %v", err)
+	}
+}
+
+func TestOPAAuthorizer_DecisionReason(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writePolicy(t, dir, `package tartarus.authz
+
+decision := {"allow": false, "reason": "tenant suspended"}
+`)
+
+	authz := NewOPAAuthorizer(dir, "data.tartarus.authz.decision", nil)
+	if _, err := authz.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	err := authz.Authorize(ctx, &Identity{ID: "user-1"}, ActionCreate, Resource{Type: ResourceTypeSandbox})
+	authzErr, ok := err.(*AuthorizationError)
+	if !ok {
+		t.Fatalf("expected AuthorizationError, got %v", err)
+	}
+	if authzErr.Message != "tenant suspended" {
+		t.Errorf("expected policy reason, got %q", authzErr.Message)
+	}
+}
+
+func TestOPAAuthorizer_BundleServer(t *testing.T) {
+	ctx := context.Background()
+	bundle := tarball(t, map[string]string{"/authz.rego": opaTestPolicy})
+
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(bundle)
+	}))
+	defer srv.Close()
+
+	authz := NewOPAAuthorizer(srv.URL+"/bundles/tartarus.tar.gz", "", nil)
+	if _, err := authz.Reload(ctx); err == nil {
+		t.Fatal("expected error without bundle server token")
+	}
+
+	authz.Token = "s3cr3t"
+	if loaded, err := authz.Reload(ctx); err != nil || !loaded {
+		t.Fatalf("Reload() = %v, %v; want true, nil", loaded, err)
+	}
+	if authz.Revision() != `"v1"` {
+		t.Errorf("expected ETag revision, got %q", authz.Revision())
+	}
+	if err := authz.Authorize(ctx, &Identity{ID: "root", Roles: []string{"admin"}}, ActionDelete, Resource{Type: ResourceTypeSandbox}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if loaded, err := authz.Reload(ctx); err != nil || loaded {
+		t.Fatalf("Reload() of unchanged bundle = %v, %v; want false, nil", loaded, err)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("expected 1 download, got %d", n)
+	}
+}
+
+func writePolicy(t *testing.T, dir, policy string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "authz.rego"), []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
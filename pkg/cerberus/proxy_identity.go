@@ -0,0 +1,206 @@
+package cerberus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// IdentityHeader carries an identity already verified by a trusted proxy
+// (Charon) as a short-lived signed JWT, so services behind the proxy need
+// not validate the client's credentials again.
+const IdentityHeader = "X-Tartarus-Identity"
+
+// ProxyIdentityConfig configures signing and accepting proxy identity tokens.
+// The proxy and the services behind it must share the same settings.
+type ProxyIdentityConfig struct {
+	KeyID    string        // HMAC key, resolved from the SecretProvider as "key:<KeyID>"
+	TTL      time.Duration // Token lifetime (default 30s)
+	Issuer   string        // Default "charon"
+	Audience string        // Default "tartarus"
+}
+
+func (c ProxyIdentityConfig) withDefaults() ProxyIdentityConfig {
+	if c.TTL <= 0 {
+		c.TTL = 30 * time.Second
+	}
+	if c.Issuer == "" {
+		c.Issuer = "charon"
+	}
+	if c.Audience == "" {
+		c.Audience = "tartarus"
+	}
+	return c
+}
+
+// proxyIdentityClaims are the claims of a proxy identity token; the subject
+// is the identity ID.
+type proxyIdentityClaims struct {
+	jwt.Claims
+	IdentityType IdentityType      `json:"identity_type,omitempty"`
+	TenantID     string            `json:"tenant,omitempty"`
+	DisplayName  string            `json:"name,omitempty"`
+	Roles        []string          `json:"roles,omitempty"`
+	Groups       []string          `json:"groups,omitempty"`
+	Attributes   map[string]string `json:"attrs,omitempty"`
+	AuthTime     int64             `json:"auth_time,omitempty"`
+}
+
+// ProxyIdentitySigner issues proxy identity tokens for verified identities.
+type ProxyIdentitySigner struct {
+	secrets SecretProvider
+	config  ProxyIdentityConfig
+}
+
+// NewProxyIdentitySigner creates a signer; zero config fields take their defaults.
+func NewProxyIdentitySigner(secrets SecretProvider, config ProxyIdentityConfig) *ProxyIdentitySigner {
+	return &ProxyIdentitySigner{secrets: secrets, config: config.withDefaults()}
+}
+
+// Sign returns a token asserting identity, valid for the configured TTL.
+func (s *ProxyIdentitySigner) Sign(ctx context.Context, identity *Identity) (string, error) {
+	key, err := s.secrets.Resolve(ctx, "key:"+s.config.KeyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve identity signing key %s: %w", s.config.KeyID, err)
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.HS256, Key: []byte(key)},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader(jose.HeaderKey("kid"), s.config.KeyID),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create identity signer: %w", err)
+	}
+
+	now := time.Now()
+	expiry := now.Add(s.config.TTL)
+	// Never outlive the credential the identity was verified with
+	if !identity.ExpiresAt.IsZero() && identity.ExpiresAt.Before(expiry) {
+		expiry = identity.ExpiresAt
+	}
+	claims := proxyIdentityClaims{
+		Claims: jwt.Claims{
+			Issuer:    s.config.Issuer,
+			Subject:   identity.ID,
+			Audience:  jwt.Audience{s.config.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Expiry:    jwt.NewNumericDate(expiry),
+		},
+		IdentityType: identity.Type,
+		TenantID:     identity.TenantID,
+		DisplayName:  identity.DisplayName,
+		Roles:        identity.Roles,
+		Groups:       identity.Groups,
+		Attributes:   identity.Attributes,
+	}
+	if !identity.AuthTime.IsZero() {
+		claims.AuthTime = identity.AuthTime.Unix()
+	}
+	return jwt.Signed(signer).Claims(claims).Serialize()
+}
+
+// ProxyIdentityCredential is a proxy identity token taken from IdentityHeader.
+type ProxyIdentityCredential struct {
+	Token string
+}
+
+func (c *ProxyIdentityCredential) Type() CredentialType { return CredentialTypeProxy }
+
+// ProxyIdentityExtractor extracts proxy identity tokens from IdentityHeader.
+type ProxyIdentityExtractor struct{}
+
+// NewProxyIdentityExtractor creates a credential extractor for proxy identity tokens.
+func NewProxyIdentityExtractor() *ProxyIdentityExtractor {
+	return &ProxyIdentityExtractor{}
+}
+
+// Extract returns the proxy identity credential if the header is present.
+func (e *ProxyIdentityExtractor) Extract(r *http.Request) (Credentials, error) {
+	token := r.Header.Get(IdentityHeader)
+	if token == "" {
+		return nil, NewAuthenticationError("missing "+IdentityHeader+" header", nil).WithCode(AuthCodeMissingCredentials)
+	}
+	return &ProxyIdentityCredential{Token: token}, nil
+}
+
+// TrustedProxyAuthenticator accepts identities asserted by a trusted proxy
+// in proxy identity tokens signed with the configured key.
+type TrustedProxyAuthenticator struct {
+	secrets SecretProvider
+	config  ProxyIdentityConfig
+}
+
+// NewTrustedProxyAuthenticator creates an authenticator for proxy identity
+// tokens; zero config fields take their defaults.
+func NewTrustedProxyAuthenticator(secrets SecretProvider, config ProxyIdentityConfig) *TrustedProxyAuthenticator {
+	return &TrustedProxyAuthenticator{secrets: secrets, config: config.withDefaults()}
+}
+
+// Authenticate verifies a proxy identity token and returns the identity it asserts.
+func (a *TrustedProxyAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Identity, error) {
+	proxyCred, ok := creds.(*ProxyIdentityCredential)
+	if !ok {
+		return nil, NewAuthenticationError("invalid credential type, expected proxy identity", nil).WithCode(AuthCodeUnsupportedCredentials)
+	}
+
+	token, err := jwt.ParseSigned(proxyCred.Token, []jose.SignatureAlgorithm{jose.HS256})
+	if err != nil {
+		return nil, NewAuthenticationError("invalid proxy identity format", err).WithCode(AuthCodeMalformedCredentials)
+	}
+	// Only the proxy's key is trusted, not any signing key the provider knows
+	if len(token.Headers) == 0 || token.Headers[0].KeyID != a.config.KeyID {
+		return nil, NewAuthenticationError("proxy identity not signed with the trusted proxy key", nil).WithCode(AuthCodeUnknownKey)
+	}
+	key, err := a.secrets.Resolve(ctx, "key:"+a.config.KeyID)
+	if err != nil {
+		return nil, NewAuthenticationError(fmt.Sprintf("unknown key ID: %s", a.config.KeyID), err).WithCode(AuthCodeUnknownKey)
+	}
+
+	var claims proxyIdentityClaims
+	if err := token.Claims([]byte(key), &claims); err != nil {
+		return nil, NewAuthenticationError("invalid proxy identity signature", err).WithCode(AuthCodeInvalidSignature)
+	}
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:      a.config.Issuer,
+		AnyAudience: jwt.Audience{a.config.Audience},
+		Time:        time.Now(),
+	}, 5*time.Second); err != nil {
+		code := AuthCodeInvalidCredentials
+		switch {
+		case errors.Is(err, jwt.ErrExpired):
+			code = AuthCodeExpiredCredentials
+		case errors.Is(err, jwt.ErrInvalidAudience):
+			code = AuthCodeWrongAudience
+		case errors.Is(err, jwt.ErrInvalidIssuer):
+			code = AuthCodeWrongIssuer
+		}
+		return nil, NewAuthenticationError("invalid proxy identity claims", err).WithCode(code)
+	}
+	if claims.Subject == "" || claims.Expiry == nil {
+		return nil, NewAuthenticationError("proxy identity missing subject or expiry", nil).WithCode(AuthCodeMalformedCredentials)
+	}
+
+	identity := &Identity{
+		ID:          claims.Subject,
+		Type:        claims.IdentityType,
+		TenantID:    claims.TenantID,
+		DisplayName: claims.DisplayName,
+		Roles:       claims.Roles,
+		Groups:      claims.Groups,
+		Attributes:  claims.Attributes,
+		AuthTime:    time.Now(),
+		ExpiresAt:   claims.Expiry.Time(),
+	}
+	if claims.AuthTime > 0 {
+		identity.AuthTime = time.Unix(claims.AuthTime, 0)
+	}
+	if identity.Attributes == nil {
+		identity.Attributes = make(map[string]string)
+	}
+	return identity, nil
+}
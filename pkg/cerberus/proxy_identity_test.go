@@ -0,0 +1,77 @@
+package cerberus_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+)
+
+func TestProxyIdentity_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	secrets := &MockSecretProvider{Secrets: map[string]string{
+		"key:charon":   "charon-shared-secret-at-least-32-bytes",
+		"key:api-keys": "api-key-signing-secret-at-least-32-bytes",
+	}}
+	config := cerberus.ProxyIdentityConfig{KeyID: "charon"}
+	identity := &cerberus.Identity{
+		ID:         "alice",
+		Type:       cerberus.IdentityTypeUser,
+		TenantID:   "acme",
+		Roles:      []string{"developer"},
+		Groups:     []string{"ml"},
+		Attributes: map[string]string{"email": "alice@example.com"},
+		AuthTime:   time.Now().Add(-time.Minute),
+	}
+
+	token, err := cerberus.NewProxyIdentitySigner(secrets, config).Sign(ctx, identity)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/sandboxes", nil)
+	req.Header.Set(cerberus.IdentityHeader, token)
+	creds, err := cerberus.NewProxyIdentityExtractor().Extract(req)
+	require.NoError(t, err)
+
+	got, err := cerberus.NewTrustedProxyAuthenticator(secrets, config).Authenticate(ctx, creds)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.ID)
+	assert.Equal(t, cerberus.IdentityTypeUser, got.Type)
+	assert.Equal(t, "acme", got.TenantID)
+	assert.Equal(t, []string{"developer"}, got.Roles)
+	assert.Equal(t, []string{"ml"}, got.Groups)
+	assert.Equal(t, "alice@example.com", got.Attributes["email"])
+	assert.WithinDuration(t, identity.AuthTime, got.AuthTime, time.Second)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), got.ExpiresAt, 2*time.Second)
+
+	// Tokens signed with any other key are not trusted, even ones Cerberus knows
+	otherToken, err := cerberus.NewProxyIdentitySigner(secrets, cerberus.ProxyIdentityConfig{KeyID: "api-keys"}).Sign(ctx, identity)
+	require.NoError(t, err)
+	_, err = cerberus.NewTrustedProxyAuthenticator(secrets, config).Authenticate(ctx, &cerberus.ProxyIdentityCredential{Token: otherToken})
+	assert.Error(t, err)
+
+	// Tampered tokens fail verification
+	_, err = cerberus.NewTrustedProxyAuthenticator(secrets, config).Authenticate(ctx, &cerberus.ProxyIdentityCredential{Token: token[:len(token)-2] + "xx"})
+	assert.Error(t, err)
+
+	// Other credential types are rejected
+	_, err = cerberus.NewTrustedProxyAuthenticator(secrets, config).Authenticate(ctx, &cerberus.APIKeyCredential{Secret: token})
+	assert.Error(t, err)
+}
+
+func TestProxyIdentity_Expiry(t *testing.T) {
+	ctx := context.Background()
+	secrets := &MockSecretProvider{Secrets: map[string]string{"key:charon": "charon-shared-secret-at-least-32-bytes"}}
+	config := cerberus.ProxyIdentityConfig{KeyID: "charon"}
+
+	// A token never outlives the credential it was issued for
+	identity := &cerberus.Identity{ID: "bob", ExpiresAt: time.Now().Add(-time.Minute)}
+	token, err := cerberus.NewProxyIdentitySigner(secrets, config).Sign(ctx, identity)
+	require.NoError(t, err)
+
+	_, err = cerberus.NewTrustedProxyAuthenticator(secrets, config).Authenticate(ctx, &cerberus.ProxyIdentityCredential{Token: token})
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package cerberus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConsentStore keeps terms acknowledgements in Redis so they hold
+// across Olympus replicas. Each identity's acknowledgements are a hash from
+// terms version to acknowledgement.
+type RedisConsentStore struct {
+	client *redis.Client
+}
+
+// NewRedisConsentStore creates a Redis-backed consent store.
+func NewRedisConsentStore(addr string, db int, password string) (*RedisConsentStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisConsentStore{client: client}, nil
+}
+
+func consentKey(identityID string) string {
+	return "cerberus:consent:" + identityID
+}
+
+// Accept implements ConsentStore.
+func (s *RedisConsentStore) Accept(ctx context.Context, ack Acknowledgement) error {
+	data, err := json.Marshal(ack)
+	if err != nil {
+		return err
+	}
+	if err := s.client.HSetNX(ctx, consentKey(ack.IdentityID), ack.Version, data).Err(); err != nil {
+		return fmt.Errorf("failed to record acknowledgement: %w", err)
+	}
+	return nil
+}
+
+// Acknowledgement implements ConsentStore.
+func (s *RedisConsentStore) Acknowledgement(ctx context.Context, identityID, version string) (*Acknowledgement, error) {
+	val, err := s.client.HGet(ctx, consentKey(identityID), version).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load acknowledgement: %w", err)
+	}
+	var ack Acknowledgement
+	if err := json.Unmarshal([]byte(val), &ack); err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
@@ -0,0 +1,125 @@
+package cerberus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRevocationList keeps revocations in Redis so they take effect on every
+// Olympus replica. Each revocation is one JSON value that Redis expires with
+// the revocation.
+type RedisRevocationList struct {
+	client *redis.Client
+}
+
+// NewRedisRevocationList creates a Redis-backed revocation list.
+func NewRedisRevocationList(addr string, db int, password string) (*RedisRevocationList, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisRevocationList{client: client}, nil
+}
+
+const revocationKeyPrefix = "cerberus:revoked:"
+
+func revocationKey(kind RevocationKind, subject string) string {
+	return revocationKeyPrefix + string(kind) + ":" + subject
+}
+
+// Revoke implements RevocationList.
+func (s *RedisRevocationList) Revoke(ctx context.Context, r Revocation) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if !r.ExpiresAt.IsZero() {
+		ttl = time.Until(r.ExpiresAt)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, revocationKey(r.Kind, r.Subject), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to record revocation: %w", err)
+	}
+	return nil
+}
+
+// Lift implements RevocationList.
+func (s *RedisRevocationList) Lift(ctx context.Context, kind RevocationKind, subject string) error {
+	n, err := s.client.Del(ctx, revocationKey(kind, subject)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to lift revocation: %w", err)
+	}
+	if n == 0 {
+		return ErrRevocationNotFound
+	}
+	return nil
+}
+
+// Revoked implements RevocationList. Both subjects are read in one round trip.
+func (s *RedisRevocationList) Revoked(ctx context.Context, tokenHash, identityID string) (*Revocation, error) {
+	keys := []string{revocationKey(RevokeIdentity, identityID)}
+	if tokenHash != "" {
+		keys = append([]string{revocationKey(RevokeToken, tokenHash)}, keys...)
+	}
+	vals, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check revocations: %w", err)
+	}
+	for _, val := range vals {
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+		var r Revocation
+		if err := json.Unmarshal([]byte(str), &r); err != nil {
+			return nil, err
+		}
+		return &r, nil
+	}
+	return nil, nil
+}
+
+// ListRevocations implements RevocationList.
+func (s *RedisRevocationList) ListRevocations(ctx context.Context) ([]Revocation, error) {
+	var out []Revocation
+	iter := s.client.Scan(ctx, 0, revocationKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		val, err := s.client.Get(ctx, iter.Val()).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list revocations: %w", err)
+		}
+		var r Revocation
+		if err := json.Unmarshal([]byte(val), &r); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list revocations: %w", err)
+	}
+	sortRevocations(out)
+	return out, nil
+}
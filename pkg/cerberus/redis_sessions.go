@@ -0,0 +1,126 @@
+package cerberus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisSessionRetries bounds optimistic-lock retries when replicas admit
+// sessions of the same identity concurrently.
+const redisSessionRetries = 5
+
+// RedisSessionStore keeps sessions in Redis so limits hold across Olympus
+// replicas. Each identity's sessions are one JSON value updated under WATCH.
+type RedisSessionStore struct {
+	client *redis.Client
+}
+
+// NewRedisSessionStore creates a Redis-backed session store.
+func NewRedisSessionStore(addr string, db int, password string) (*RedisSessionStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisSessionStore{client: client}, nil
+}
+
+const sessionsKeyPrefix = "cerberus:sessions:"
+
+func sessionsKey(identityID string) string {
+	return sessionsKeyPrefix + identityID
+}
+
+// Admit implements SessionStore.
+func (s *RedisSessionStore) Admit(ctx context.Context, identityID string, session Session, limits SessionLimits) ([]Session, error) {
+	key := sessionsKey(identityID)
+
+	var evicted []Session
+	var admitErr error
+	txf := func(tx *redis.Tx) error {
+		sessions, err := s.load(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		var kept []Session
+		kept, evicted, admitErr = admitSession(sessions, session, limits)
+		data, err := json.Marshal(kept)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			// Sessions expire together once the identity is idle
+			pipe.Set(ctx, key, data, limits.IdleTimeout)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < redisSessionRetries; i++ {
+		err := s.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to admit session: %w", err)
+		}
+		return evicted, admitErr
+	}
+	return nil, fmt.Errorf("failed to admit session: too many concurrent updates")
+}
+
+// ListSessions implements SessionStore.
+func (s *RedisSessionStore) ListSessions(ctx context.Context, identityID string) ([]Session, error) {
+	sessions, err := s.load(ctx, s.client, sessionsKey(identityID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// ListIdentities implements SessionStore.
+func (s *RedisSessionStore) ListIdentities(ctx context.Context) ([]string, error) {
+	var ids []string
+	iter := s.client.Scan(ctx, 0, sessionsKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		ids = append(ids, strings.TrimPrefix(iter.Val(), sessionsKeyPrefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *RedisSessionStore) load(ctx context.Context, c redis.Cmdable, key string) ([]Session, error) {
+	val, err := c.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []Session
+	if err := json.Unmarshal([]byte(val), &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
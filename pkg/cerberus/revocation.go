@@ -0,0 +1,200 @@
+package cerberus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRevoked is returned for credentials that were revoked, or that
+	// belong to a revoked identity.
+	ErrRevoked = errors.New("credentials revoked")
+	// ErrRevocationNotFound is returned when lifting a revocation that does
+	// not exist.
+	ErrRevocationNotFound = errors.New("revocation not found")
+	// ErrRevocationUnavailable is returned when the revocation list cannot
+	// be consulted. Requests are refused rather than risk admitting revoked
+	// credentials.
+	ErrRevocationUnavailable = errors.New("revocation list unavailable")
+)
+
+// Audit events recorded for revocations.
+const (
+	EventCredentialRevoked = "credential_revoked"
+	EventRevocationLifted  = "revocation_lifted"
+)
+
+// RevocationKind says what a revocation applies to.
+type RevocationKind string
+
+const (
+	// RevokeToken refuses one token, API key or client certificate,
+	// identified by its fingerprint (the token_hash of its sessions).
+	RevokeToken RevocationKind = "token"
+	// RevokeIdentity refuses every credential of an identity.
+	RevokeIdentity RevocationKind = "identity"
+)
+
+// Revocation refuses credentials before their natural expiry.
+type Revocation struct {
+	Kind      RevocationKind `json:"kind"`
+	Subject   string         `json:"subject"` // Token fingerprint or identity ID
+	Reason    string         `json:"reason,omitempty"`
+	RevokedBy string         `json:"revoked_by,omitempty"`
+	RevokedAt time.Time      `json:"revoked_at"`
+	ExpiresAt time.Time      `json:"expires_at,omitempty"` // Lifted automatically after this (zero = never)
+}
+
+// Validate checks the kind and subject.
+func (r Revocation) Validate() error {
+	if r.Kind != RevokeToken && r.Kind != RevokeIdentity {
+		return fmt.Errorf("unknown revocation kind %q", r.Kind)
+	}
+	if r.Subject == "" {
+		return fmt.Errorf("revocation subject is required")
+	}
+	return nil
+}
+
+// expired reports whether the revocation no longer applies at now.
+func (r Revocation) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// RevocationList keeps revoked tokens and identities.
+type RevocationList interface {
+	// Revoke adds or replaces a revocation.
+	Revoke(ctx context.Context, r Revocation) error
+
+	// Lift removes a revocation, or returns ErrRevocationNotFound.
+	Lift(ctx context.Context, kind RevocationKind, subject string) error
+
+	// Revoked returns the revocation refusing the token fingerprint or the
+	// identity, or nil if neither is revoked. An empty fingerprint is not
+	// checked.
+	Revoked(ctx context.Context, tokenHash, identityID string) (*Revocation, error)
+
+	// ListRevocations returns the revocations in effect, newest first.
+	ListRevocations(ctx context.Context) ([]Revocation, error)
+}
+
+// TokenFingerprint returns the fingerprint Cerberus tracks a bearer token or
+// API key secret by, as reported in the token_hash of its sessions.
+func TokenFingerprint(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// MemoryRevocationList is an in-memory RevocationList for single-replica
+// deployments.
+type MemoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[RevocationKind]map[string]Revocation
+}
+
+// NewMemoryRevocationList creates an empty in-memory revocation list.
+func NewMemoryRevocationList() *MemoryRevocationList {
+	return &MemoryRevocationList{revoked: map[RevocationKind]map[string]Revocation{
+		RevokeToken:    {},
+		RevokeIdentity: {},
+	}}
+}
+
+// Revoke implements RevocationList.
+func (m *MemoryRevocationList) Revoke(ctx context.Context, r Revocation) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revoked[r.Kind][r.Subject] = r
+	return nil
+}
+
+// Lift implements RevocationList.
+func (m *MemoryRevocationList) Lift(ctx context.Context, kind RevocationKind, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.revoked[kind][subject]
+	if !ok || r.expired(time.Now()) {
+		return ErrRevocationNotFound
+	}
+	delete(m.revoked[kind], subject)
+	return nil
+}
+
+// Revoked implements RevocationList.
+func (m *MemoryRevocationList) Revoked(ctx context.Context, tokenHash, identityID string) (*Revocation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	check := func(kind RevocationKind, subject string) *Revocation {
+		r, ok := m.revoked[kind][subject]
+		if !ok {
+			return nil
+		}
+		if r.expired(now) {
+			delete(m.revoked[kind], subject)
+			return nil
+		}
+		return &r
+	}
+	if tokenHash != "" {
+		if r := check(RevokeToken, tokenHash); r != nil {
+			return r, nil
+		}
+	}
+	return check(RevokeIdentity, identityID), nil
+}
+
+// ListRevocations implements RevocationList.
+func (m *MemoryRevocationList) ListRevocations(ctx context.Context) ([]Revocation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var out []Revocation
+	for _, bySubject := range m.revoked {
+		for _, r := range bySubject {
+			if !r.expired(now) {
+				out = append(out, r)
+			}
+		}
+	}
+	sortRevocations(out)
+	return out, nil
+}
+
+// sortRevocations orders revocations newest first.
+func sortRevocations(revocations []Revocation) {
+	sort.Slice(revocations, func(i, j int) bool { return revocations[i].RevokedAt.After(revocations[j].RevokedAt) })
+}
+
+// checkRevoked returns an AuthenticationError wrapping ErrRevoked if the
+// credentials or identity are revoked.
+func checkRevoked(ctx context.Context, list RevocationList, creds Credentials, identity *Identity) error {
+	r, err := list.Revoked(ctx, credentialFingerprint(creds), identity.ID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRevocationUnavailable, err)
+	}
+	if r == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("%s revoked", r.Kind)
+	if r.Reason != "" {
+		msg += ": " + r.Reason
+	}
+	return NewAuthenticationError(msg, ErrRevoked)
+}
@@ -0,0 +1,118 @@
+package cerberus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRevocationList(t *testing.T) {
+	s := miniredis.RunT(t)
+	redisList, err := NewRedisRevocationList(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	ctx := context.Background()
+	for name, list := range map[string]RevocationList{"memory": NewMemoryRevocationList(), "redis": redisList} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			if err := list.Revoke(ctx, Revocation{Kind: RevokeToken, Subject: TokenFingerprint("leaked"), Reason: "posted in chat", RevokedAt: now}); err != nil {
+				t.Fatalf("Revoke: %v", err)
+			}
+			if err := list.Revoke(ctx, Revocation{Kind: RevokeIdentity, Subject: "mallory", RevokedAt: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)}); err != nil {
+				t.Fatalf("Revoke: %v", err)
+			}
+			if err := list.Revoke(ctx, Revocation{Kind: "device", Subject: "x"}); err == nil {
+				t.Error("expected error for unknown kind")
+			}
+
+			r, err := list.Revoked(ctx, TokenFingerprint("leaked"), "alice")
+			if err != nil || r == nil || r.Reason != "posted in chat" {
+				t.Errorf("expected token revocation, got %+v, %v", r, err)
+			}
+			if r, _ := list.Revoked(ctx, TokenFingerprint("other"), "mallory"); r == nil || r.Kind != RevokeIdentity {
+				t.Errorf("expected identity revocation, got %+v", r)
+			}
+			if r, _ := list.Revoked(ctx, TokenFingerprint("other"), "alice"); r != nil {
+				t.Errorf("expected no revocation, got %+v", r)
+			}
+
+			revs, err := list.ListRevocations(ctx)
+			if err != nil || len(revs) != 2 || revs[0].Subject != "mallory" {
+				t.Errorf("expected two revocations newest first, got %+v, %v", revs, err)
+			}
+
+			if err := list.Lift(ctx, RevokeIdentity, "mallory"); err != nil {
+				t.Fatalf("Lift: %v", err)
+			}
+			if err := list.Lift(ctx, RevokeIdentity, "mallory"); !errors.Is(err, ErrRevocationNotFound) {
+				t.Errorf("expected ErrRevocationNotFound, got %v", err)
+			}
+			if r, _ := list.Revoked(ctx, "", "mallory"); r != nil {
+				t.Errorf("expected lifted revocation, got %+v", r)
+			}
+		})
+	}
+
+	// Revocations expire with their TTL
+	if err := redisList.Revoke(ctx, Revocation{Kind: RevokeIdentity, Subject: "eve", ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	s.FastForward(2 * time.Minute)
+	if r, _ := redisList.Revoked(ctx, "", "eve"); r != nil {
+		t.Errorf("expected expired revocation, got %+v", r)
+	}
+}
+
+func TestHTTPMiddleware_Revocation(t *testing.T) {
+	ctx := context.Background()
+	list := NewMemoryRevocationList()
+	gateway := NewGateway(NewSimpleAPIKeyAuthenticator("valid-key"), NewAllowAllAuthorizer(), NewNoopAuditor())
+	gateway.SetRevocationList(list)
+	middleware := NewHTTPMiddleware(gateway, NewBearerTokenExtractor(), NewDefaultResourceMapper())
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func() int {
+		req := httptest.NewRequest("GET", "/sandboxes", nil)
+		req.Header.Set("Authorization", "Bearer valid-key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("before revocation: got status %d", code)
+	}
+	if err := list.Revoke(ctx, Revocation{Kind: RevokeToken, Subject: TokenFingerprint("valid-key")}); err != nil {
+		t.Fatal(err)
+	}
+	if code := request(); code != http.StatusUnauthorized {
+		t.Errorf("after revocation: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if err := list.Lift(ctx, RevokeToken, TokenFingerprint("valid-key")); err != nil {
+		t.Fatal(err)
+	}
+	if code := request(); code != http.StatusOK {
+		t.Errorf("after lifting: got status %d", code)
+	}
+
+	// The list failing refuses requests rather than admitting revoked credentials
+	gateway.SetRevocationList(failingRevocationList{list})
+	if code := request(); code != http.StatusServiceUnavailable {
+		t.Errorf("list unavailable: got status %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+type failingRevocationList struct{ RevocationList }
+
+func (failingRevocationList) Revoked(ctx context.Context, tokenHash, identityID string) (*Revocation, error) {
+	return nil, errors.New("connection refused")
+}
@@ -0,0 +1,309 @@
+package cerberus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSessionLimitExceeded is returned when a new session would exceed
+	// the identity's limits in reject mode.
+	ErrSessionLimitExceeded = errors.New("session limit exceeded")
+	// ErrSessionEvicted is returned for sessions evicted to make room for
+	// newer ones.
+	ErrSessionEvicted = errors.New("session evicted")
+)
+
+// Audit events recorded by the SessionLimiter.
+const (
+	EventSessionLimitExceeded = "session_limit_exceeded"
+	EventSessionEvicted       = "session_evicted"
+)
+
+// SessionLimitMode decides what happens when a new session would exceed a limit.
+type SessionLimitMode string
+
+const (
+	// SessionLimitReject refuses the new session.
+	SessionLimitReject SessionLimitMode = "reject"
+	// SessionLimitEvict evicts the oldest sessions to make room.
+	SessionLimitEvict SessionLimitMode = "evict"
+)
+
+// SessionLimits bound how many sessions and tokens an identity may hold at
+// once. A session is one token used from one source IP, so a leaked API key
+// used from many addresses opens many sessions.
+type SessionLimits struct {
+	MaxSessions int              // Concurrent sessions per identity (0 = unlimited)
+	MaxTokens   int              // Concurrent distinct tokens per identity (0 = unlimited)
+	IdleTimeout time.Duration    // Sessions unused this long end; evicted sessions stay refused this long
+	Mode        SessionLimitMode // Reject new sessions or evict the oldest (default reject)
+	Track       bool             // Record sessions for listing even when no limit is set
+}
+
+// Enabled reports whether sessions are recorded: any limit is set, or
+// tracking is on.
+func (l SessionLimits) Enabled() bool {
+	return l.MaxSessions > 0 || l.MaxTokens > 0 || l.Track
+}
+
+// Session is a token in use from one source IP. Tokens are only stored as
+// hashes.
+type Session struct {
+	ID        string    `json:"id"`
+	TokenHash string    `json:"token_hash"`
+	SourceIP  string    `json:"source_ip"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Evicted   bool      `json:"evicted,omitempty"`
+}
+
+// SessionStore tracks the sessions of each identity.
+type SessionStore interface {
+	// Admit records use of session s by the identity and applies the limits
+	// atomically. It returns the sessions evicted to make room, or
+	// ErrSessionLimitExceeded / ErrSessionEvicted if s is refused.
+	Admit(ctx context.Context, identityID string, s Session, limits SessionLimits) ([]Session, error)
+
+	// ListSessions returns the identity's live and evicted sessions.
+	ListSessions(ctx context.Context, identityID string) ([]Session, error)
+
+	// ListIdentities returns the identities that have sessions.
+	ListIdentities(ctx context.Context) ([]string, error)
+}
+
+// admitSession applies the limits to the identity's sessions. It returns the
+// sessions to store and those evicted by this call.
+func admitSession(sessions []Session, s Session, limits SessionLimits) ([]Session, []Session, error) {
+	now := s.LastSeen
+
+	// Idle and evicted sessions expire
+	kept := sessions[:0:0]
+	for _, existing := range sessions {
+		if limits.IdleTimeout > 0 && now.Sub(existing.LastSeen) > limits.IdleTimeout {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+
+	for i := range kept {
+		if kept[i].ID != s.ID {
+			continue
+		}
+		if kept[i].Evicted {
+			return kept, nil, ErrSessionEvicted
+		}
+		kept[i].LastSeen = now
+		return kept, nil, nil
+	}
+
+	var evicted []Session
+	for {
+		active, tokens := 0, make(map[string]time.Time)
+		for _, existing := range kept {
+			if existing.Evicted {
+				continue
+			}
+			active++
+			if first, ok := tokens[existing.TokenHash]; !ok || existing.FirstSeen.Before(first) {
+				tokens[existing.TokenHash] = existing.FirstSeen
+			}
+		}
+		_, knownToken := tokens[s.TokenHash]
+		overSessions := limits.MaxSessions > 0 && active >= limits.MaxSessions
+		overTokens := limits.MaxTokens > 0 && !knownToken && len(tokens) >= limits.MaxTokens
+		if !overSessions && !overTokens {
+			break
+		}
+		if limits.Mode != SessionLimitEvict {
+			if overSessions {
+				return kept, nil, fmt.Errorf("%w: %d of %d sessions in use", ErrSessionLimitExceeded, active, limits.MaxSessions)
+			}
+			return kept, nil, fmt.Errorf("%w: %d of %d tokens in use", ErrSessionLimitExceeded, len(tokens), limits.MaxTokens)
+		}
+
+		// Evict the oldest session, or every session of the oldest token
+		var oldestToken string
+		if !overSessions {
+			for token, first := range tokens {
+				if oldestToken == "" || first.Before(tokens[oldestToken]) {
+					oldestToken = token
+				}
+			}
+		}
+		victim := -1
+		for i, existing := range kept {
+			if existing.Evicted || (oldestToken != "" && existing.TokenHash != oldestToken) {
+				continue
+			}
+			if oldestToken != "" {
+				kept[i].Evicted, kept[i].LastSeen = true, now
+				evicted = append(evicted, kept[i])
+				continue
+			}
+			if victim < 0 || existing.FirstSeen.Before(kept[victim].FirstSeen) {
+				victim = i
+			}
+		}
+		if victim >= 0 {
+			kept[victim].Evicted, kept[victim].LastSeen = true, now
+			evicted = append(evicted, kept[victim])
+		}
+	}
+
+	s.FirstSeen = now
+	return append(kept, s), evicted, nil
+}
+
+// MemorySessionStore is an in-memory SessionStore for single-replica deployments.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string][]Session
+}
+
+// NewMemorySessionStore creates an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string][]Session)}
+}
+
+// Admit implements SessionStore.
+func (m *MemorySessionStore) Admit(ctx context.Context, identityID string, s Session, limits SessionLimits) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept, evicted, err := admitSession(m.sessions[identityID], s, limits)
+	m.sessions[identityID] = kept
+	return evicted, err
+}
+
+// ListSessions implements SessionStore.
+func (m *MemorySessionStore) ListSessions(ctx context.Context, identityID string) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := append([]Session(nil), m.sessions[identityID]...)
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// ListIdentities implements SessionStore.
+func (m *MemorySessionStore) ListIdentities(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.sessions))
+	for id, sessions := range m.sessions {
+		if len(sessions) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// SessionLimiter enforces SessionLimits on authenticated requests and audits
+// rejections and evictions.
+type SessionLimiter struct {
+	store   SessionStore
+	limits  SessionLimits
+	auditor Auditor
+}
+
+// DefaultSessionIdleTimeout is used when the limits set no idle timeout.
+const DefaultSessionIdleTimeout = time.Hour
+
+// NewSessionLimiter creates a limiter backed by store. The auditor may be nil.
+func NewSessionLimiter(store SessionStore, limits SessionLimits, auditor Auditor) *SessionLimiter {
+	if limits.IdleTimeout <= 0 {
+		limits.IdleTimeout = DefaultSessionIdleTimeout
+	}
+	return &SessionLimiter{
+		store:   store,
+		limits:  limits,
+		auditor: auditor,
+	}
+}
+
+// Admit records the request's session for the identity. Credentials that
+// cannot be fingerprinted are not limited.
+func (l *SessionLimiter) Admit(ctx context.Context, identity *Identity, creds Credentials, r *http.Request) error {
+	tokenHash := credentialFingerprint(creds)
+	if tokenHash == "" || !l.limits.Enabled() {
+		return nil
+	}
+
+	// One session per address, not per connection
+	sourceIP := getSourceIP(r)
+	if host, _, err := net.SplitHostPort(sourceIP); err == nil {
+		sourceIP = host
+	}
+	sum := sha256.Sum256([]byte(tokenHash + "|" + sourceIP))
+	session := Session{
+		ID:        hex.EncodeToString(sum[:16]),
+		TokenHash: tokenHash,
+		SourceIP:  sourceIP,
+		LastSeen:  time.Now(),
+	}
+
+	evicted, err := l.store.Admit(ctx, identity.ID, session, l.limits)
+	for _, e := range evicted {
+		l.record(ctx, r, identity, EventSessionEvicted, AuditResultSuccess, e.SourceIP,
+			fmt.Sprintf("session from %s evicted by new session from %s", e.SourceIP, sourceIP))
+	}
+	if errors.Is(err, ErrSessionLimitExceeded) {
+		l.record(ctx, r, identity, EventSessionLimitExceeded, AuditResultDenied, sourceIP, err.Error())
+	}
+	return err
+}
+
+func (l *SessionLimiter) record(ctx context.Context, r *http.Request, identity *Identity, event string, result AuditResult, sourceIP, message string) {
+	if l.auditor == nil {
+		return
+	}
+	_ = l.auditor.RecordAccess(ctx, &AuditEntry{
+		Timestamp:    time.Now(),
+		RequestID:    r.Header.Get("X-Request-ID"),
+		Event:        event,
+		Identity:     identity,
+		Result:       result,
+		SourceIP:     sourceIP,
+		UserAgent:    r.UserAgent(),
+		ErrorMessage: message,
+	})
+}
+
+// credentialFingerprint returns a stable hash of the secret part of the
+// credentials, or "" for credential types that carry none.
+func credentialFingerprint(creds Credentials) string {
+	var secret []byte
+	switch c := creds.(type) {
+	case *APIKeyCredential:
+		secret = []byte(c.Secret)
+	case *OAuth2Credential:
+		secret = []byte(c.AccessToken)
+	case *BearerTokenCredential:
+		secret = []byte(c.Token)
+	case *MTLSCredential:
+		if len(c.ConnectionState.PeerCertificates) > 0 {
+			secret = c.ConnectionState.PeerCertificates[0].Raw
+		}
+	}
+	if len(secret) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:16])
+}
+
+// sortSessions orders sessions oldest first.
+func sortSessions(sessions []Session) {
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].FirstSeen.Before(sessions[j].FirstSeen) })
+}
@@ -0,0 +1,220 @@
+package cerberus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type recordingAuditor struct {
+	mu      sync.Mutex
+	entries []*AuditEntry
+}
+
+func (a *recordingAuditor) RecordAccess(ctx context.Context, entry *AuditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func (a *recordingAuditor) events() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var events []string
+	for _, e := range a.entries {
+		if e.Event != "" {
+			events = append(events, e.Event)
+		}
+	}
+	return events
+}
+
+func session(token, ip string, at time.Time) Session {
+	return Session{ID: token + "|" + ip, TokenHash: token, SourceIP: ip, LastSeen: at}
+}
+
+func TestAdmitSession_Reject(t *testing.T) {
+	limits := SessionLimits{MaxSessions: 2, IdleTimeout: time.Hour, Mode: SessionLimitReject}
+	now := time.Now()
+
+	var sessions []Session
+	var err error
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		sessions, _, err = admitSession(sessions, session("key", ip, now.Add(time.Duration(i)*time.Second)), limits)
+		if err != nil {
+			t.Fatalf("admit %s: %v", ip, err)
+		}
+	}
+
+	// Known sessions are refreshed, new ones refused
+	if sessions, _, err = admitSession(sessions, session("key", "10.0.0.1", now.Add(time.Minute)), limits); err != nil {
+		t.Errorf("expected existing session to be admitted, got %v", err)
+	}
+	if _, _, err = admitSession(sessions, session("key", "10.0.0.3", now.Add(time.Minute)), limits); !errors.Is(err, ErrSessionLimitExceeded) {
+		t.Errorf("expected ErrSessionLimitExceeded, got %v", err)
+	}
+
+	// Once 10.0.0.2 has been idle past the timeout it no longer counts
+	if _, _, err = admitSession(sessions, session("key", "10.0.0.3", now.Add(time.Hour+30*time.Second)), limits); err != nil {
+		t.Errorf("expected idle session to expire, got %v", err)
+	}
+}
+
+func TestAdmitSession_EvictOldest(t *testing.T) {
+	limits := SessionLimits{MaxSessions: 2, IdleTimeout: time.Hour, Mode: SessionLimitEvict}
+	now := time.Now()
+
+	var sessions []Session
+	var evicted []Session
+	var err error
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		sessions, evicted, err = admitSession(sessions, session("key", ip, now.Add(time.Duration(i)*time.Second)), limits)
+		if err != nil {
+			t.Fatalf("admit %s: %v", ip, err)
+		}
+	}
+	if len(evicted) != 1 || evicted[0].SourceIP != "10.0.0.1" {
+		t.Fatalf("expected the oldest session to be evicted, got %+v", evicted)
+	}
+
+	// The evicted session stays refused
+	if _, _, err = admitSession(sessions, session("key", "10.0.0.1", now.Add(time.Minute)), limits); !errors.Is(err, ErrSessionEvicted) {
+		t.Errorf("expected ErrSessionEvicted, got %v", err)
+	}
+}
+
+func TestAdmitSession_TokenLimit(t *testing.T) {
+	limits := SessionLimits{MaxTokens: 1, IdleTimeout: time.Hour, Mode: SessionLimitEvict}
+	now := time.Now()
+
+	sessions, _, _ := admitSession(nil, session("old", "10.0.0.1", now), limits)
+	sessions, _, _ = admitSession(sessions, session("old", "10.0.0.2", now.Add(time.Second)), limits)
+
+	// A second token evicts every session of the first
+	sessions, evicted, err := admitSession(sessions, session("new", "10.0.0.1", now.Add(2*time.Second)), limits)
+	if err != nil {
+		t.Fatalf("admit: %v", err)
+	}
+	if len(evicted) != 2 {
+		t.Errorf("expected both sessions of the old token evicted, got %+v", evicted)
+	}
+
+	limits.Mode = SessionLimitReject
+	if _, _, err := admitSession(sessions, session("third", "10.0.0.1", now.Add(3*time.Second)), limits); !errors.Is(err, ErrSessionLimitExceeded) {
+		t.Errorf("expected ErrSessionLimitExceeded, got %v", err)
+	}
+}
+
+func TestHTTPMiddleware_SessionLimits(t *testing.T) {
+	audit := &recordingAuditor{}
+	gateway := NewGateway(NewSimpleAPIKeyAuthenticator("valid-key"), NewAllowAllAuthorizer(), audit)
+	middleware := NewHTTPMiddleware(gateway, NewBearerTokenExtractor(), NewDefaultResourceMapper())
+	middleware.SetSessionLimiter(NewSessionLimiter(NewMemorySessionStore(), SessionLimits{MaxSessions: 1}, audit))
+
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/sandboxes", nil)
+		req.Header.Set("Authorization", "Bearer valid-key")
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request("192.0.2.1:1000"); code != http.StatusOK {
+		t.Fatalf("first session: got status %d", code)
+	}
+	// A new connection from the same address is the same session
+	if code := request("192.0.2.1:2000"); code != http.StatusOK {
+		t.Errorf("same address: got status %d", code)
+	}
+	if code := request("198.51.100.7:1000"); code != http.StatusTooManyRequests {
+		t.Errorf("second address: got status %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	if events := audit.events(); len(events) != 1 || events[0] != EventSessionLimitExceeded {
+		t.Errorf("expected one session_limit_exceeded event, got %v", events)
+	}
+}
+
+func TestRedisSessionStore(t *testing.T) {
+	s := miniredis.RunT(t)
+	store, err := NewRedisSessionStore(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	ctx := context.Background()
+	limits := SessionLimits{MaxSessions: 1, IdleTimeout: time.Hour, Mode: SessionLimitEvict}
+	now := time.Now()
+
+	if _, err := store.Admit(ctx, "alice", session("key", "10.0.0.1", now), limits); err != nil {
+		t.Fatalf("Admit: %v", err)
+	}
+	evicted, err := store.Admit(ctx, "alice", session("key", "10.0.0.2", now.Add(time.Second)), limits)
+	if err != nil {
+		t.Fatalf("Admit: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0].SourceIP != "10.0.0.1" {
+		t.Errorf("expected 10.0.0.1 evicted, got %+v", evicted)
+	}
+	if _, err := store.Admit(ctx, "alice", session("key", "10.0.0.1", now.Add(2*time.Second)), limits); !errors.Is(err, ErrSessionEvicted) {
+		t.Errorf("expected ErrSessionEvicted, got %v", err)
+	}
+
+	sessions, err := store.ListSessions(ctx, "alice")
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 2 || !sessions[0].Evicted || sessions[1].Evicted {
+		t.Errorf("unexpected sessions %+v", sessions)
+	}
+	if ttl := s.TTL(sessionsKey("alice")); ttl != time.Hour {
+		t.Errorf("expected sessions to expire after the idle timeout, got %v", ttl)
+	}
+
+	// Identities are limited independently
+	if _, err := store.Admit(ctx, "bob", session("other", "10.0.0.1", now), limits); err != nil {
+		t.Errorf("Admit for bob: %v", err)
+	}
+}
+
+func TestSessionStore_ListIdentities(t *testing.T) {
+	s := miniredis.RunT(t)
+	redisStore, err := NewRedisSessionStore(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	ctx := context.Background()
+	// Tracking records sessions without limiting them
+	limits := SessionLimits{Track: true, IdleTimeout: time.Hour}
+	for name, store := range map[string]SessionStore{"memory": NewMemorySessionStore(), "redis": redisStore} {
+		t.Run(name, func(t *testing.T) {
+			for _, id := range []string{"bob", "alice"} {
+				for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+					if _, err := store.Admit(ctx, id, session("key-"+id, ip, time.Now()), limits); err != nil {
+						t.Fatalf("Admit: %v", err)
+					}
+				}
+			}
+			ids, err := store.ListIdentities(ctx)
+			if err != nil {
+				t.Fatalf("ListIdentities: %v", err)
+			}
+			if len(ids) != 2 || ids[0] != "alice" || ids[1] != "bob" {
+				t.Errorf("expected [alice bob], got %v", ids)
+			}
+		})
+	}
+}
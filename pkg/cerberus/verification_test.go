@@ -4,9 +4,12 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
 	"encoding/json"
 	"math/big"
 	"testing"
@@ -96,11 +99,17 @@ func TestMTLSAuthenticator(t *testing.T) {
 	require.NoError(t, err)
 	clientTemplate := &x509.Certificate{
 		SerialNumber: big.NewInt(2),
-		Subject:      pkix.Name{CommonName: "agent-1", Organization: []string{"agents"}},
+		Subject:      pkix.Name{CommonName: "agent-1", Organization: []string{"agents"}, OrganizationalUnit: []string{"fleet"}},
 		NotBefore:    time.Now(),
 		NotAfter:     time.Now().Add(1 * time.Hour),
 		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 	}
+	deviceID, err := asn1.Marshal("dev-42")
+	require.NoError(t, err)
+	clientTemplate.ExtraExtensions = []pkix.Extension{
+		{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 55555, 1, 1}, Value: deviceID},
+		{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 55555, 1, 2}, Value: []byte{0xbe, 0xef}},
+	}
 	clientBytes, err := x509.CreateCertificate(rand.Reader, clientTemplate, caCert, &clientKey.PublicKey, caKey)
 	require.NoError(t, err)
 	clientCert, err := x509.ParseCertificate(clientBytes)
@@ -110,6 +119,10 @@ func TestMTLSAuthenticator(t *testing.T) {
 	roots := x509.NewCertPool()
 	roots.AddCert(caCert)
 	auth := cerberus.NewMTLSAuthenticator(roots)
+	auth.ExtensionAttributes = map[string]string{
+		"1.3.6.1.4.1.55555.1.1": "device_id",
+		"1.3.6.1.4.1.55555.1.2": "build",
+	}
 
 	// Test Authenticate
 	creds := &cerberus.MTLSCredential{
@@ -124,4 +137,12 @@ func TestMTLSAuthenticator(t *testing.T) {
 	assert.Equal(t, "agent-1", id.ID)
 	assert.Equal(t, "agent-1", id.DisplayName)
 	assert.Contains(t, id.Groups, "agents")
+
+	// Posture attributes
+	fingerprint := sha256.Sum256(caCert.Raw)
+	assert.Equal(t, "Test CA", id.Attributes["ca"])
+	assert.Equal(t, hex.EncodeToString(fingerprint[:]), id.Attributes["ca_fingerprint"])
+	assert.Equal(t, "fleet", id.Attributes["ou"])
+	assert.Equal(t, "dev-42", id.Attributes["device_id"])
+	assert.Equal(t, "beef", id.Attributes["build"])
 }
@@ -0,0 +1,194 @@
+package charon
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AffinityCookieConfig configures issuance of a signed cookie that pins
+// browser clients to the shore chosen by consistent hashing.
+type AffinityCookieConfig struct {
+	Enabled  bool          // Issue the cookie on responses (consistent_hash only)
+	Name     string        // Cookie name (default "charon_affinity")
+	Domain   string        // Cookie domain (empty = host-only)
+	Path     string        // Cookie path (default "/")
+	TTL      time.Duration // Lifetime of an issued cookie (default 1h)
+	Secure   bool          // Only send the cookie over HTTPS
+	Secret   string        // HMAC key; shared by all Charon instances. Random per process if empty
+	SameSite http.SameSite // SameSite attribute (default Lax)
+
+	// RotateOnRemoval re-pins clients whose shore was deregistered to the
+	// newly selected shore. When false the stale cookie is cleared and the
+	// client falls back to its hash key until the next issuance.
+	RotateOnRemoval bool
+}
+
+const (
+	defaultAffinityCookieName = "charon_affinity"
+	defaultAffinityCookieTTL  = time.Hour
+)
+
+// withDefaults fills zero-valued fields and generates a signing key if none
+// was configured.
+func (c AffinityCookieConfig) withDefaults() AffinityCookieConfig {
+	if c.Name == "" {
+		c.Name = defaultAffinityCookieName
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.TTL <= 0 {
+		c.TTL = defaultAffinityCookieTTL
+	}
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	if c.Secret == "" {
+		key := make([]byte, 32)
+		_, _ = rand.Read(key)
+		c.Secret = string(key)
+	}
+	return c
+}
+
+// affinityCookies signs and verifies affinity cookies. The value has the form
+// base64(shoreID).expiryUnix.base64(hmac).
+type affinityCookies struct {
+	config AffinityCookieConfig
+	now    func() time.Time
+}
+
+func newAffinityCookies(config AffinityCookieConfig) *affinityCookies {
+	return &affinityCookies{config: config.withDefaults(), now: time.Now}
+}
+
+// shore returns the shore ID pinned by the request's cookie. ok is false if
+// the cookie is missing, expired or has an invalid signature.
+func (a *affinityCookies) shore(req *http.Request) (shoreID string, ok bool) {
+	cookie, err := req.Cookie(a.config.Name)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	id, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || a.now().Unix() >= expiry {
+		return "", false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, a.sign(string(id), expiry)) {
+		return "", false
+	}
+	return string(id), true
+}
+
+// issue returns a cookie pinning the client to shoreID.
+func (a *affinityCookies) issue(shoreID string) *http.Cookie {
+	expiry := a.now().Add(a.config.TTL)
+	value := base64.RawURLEncoding.EncodeToString([]byte(shoreID)) + "." +
+		strconv.FormatInt(expiry.Unix(), 10) + "." +
+		base64.RawURLEncoding.EncodeToString(a.sign(shoreID, expiry.Unix()))
+
+	cookie := a.base()
+	cookie.Value = value
+	cookie.Expires = expiry
+	cookie.MaxAge = int(a.config.TTL.Seconds())
+	return cookie
+}
+
+// clear returns a cookie that deletes the affinity cookie in the browser.
+func (a *affinityCookies) clear() *http.Cookie {
+	cookie := a.base()
+	cookie.MaxAge = -1
+	return cookie
+}
+
+func (a *affinityCookies) base() *http.Cookie {
+	return &http.Cookie{
+		Name:     a.config.Name,
+		Domain:   a.config.Domain,
+		Path:     a.config.Path,
+		Secure:   a.config.Secure,
+		HttpOnly: true,
+		SameSite: a.config.SameSite,
+	}
+}
+
+func (a *affinityCookies) sign(shoreID string, expiry int64) []byte {
+	mac := hmac.New(sha256.New, []byte(a.config.Secret))
+	mac.Write([]byte(shoreID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expiry, 10)))
+	return mac.Sum(nil)
+}
+
+// applyAffinityCookie sets, rotates or clears the affinity cookie on resp
+// after req was served by shore.
+func (f *BoatFerry) applyAffinityCookie(req *http.Request, resp *http.Response, shore *Shore) {
+	f.mu.RLock()
+	affinity, strategy := f.affinity, f.config.Strategy
+	f.mu.RUnlock()
+	if affinity == nil || strategy != StrategyConsistentHash {
+		return
+	}
+
+	pinned, ok := affinity.shore(req)
+	action := "issued"
+	var cookie *http.Cookie
+	switch {
+	case !ok:
+		cookie = affinity.issue(shore.ID)
+	case f.hasShore(pinned):
+		// Still pinned; a failover to another shore is temporary
+		return
+	case affinity.config.RotateOnRemoval:
+		cookie = affinity.issue(shore.ID)
+		action = "rotated"
+	default:
+		cookie = affinity.clear()
+		action = "cleared"
+	}
+
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	resp.Header.Add("Set-Cookie", cookie.String())
+	f.telemetry.RecordAffinityCookie(shore.ID, action)
+}
+
+// pinnedShore returns the healthy shore named by a valid affinity cookie.
+func (f *BoatFerry) pinnedShore(shores []*Shore, req *http.Request) *Shore {
+	if f.affinity == nil {
+		return nil
+	}
+	pinned, ok := f.affinity.shore(req)
+	if !ok {
+		return nil
+	}
+	for _, shore := range shores {
+		if shore.ID == pinned {
+			return shore
+		}
+	}
+	return nil
+}
+
+func (f *BoatFerry) hasShore(shoreID string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	_, ok := f.shoreMap[shoreID]
+	return ok
+}
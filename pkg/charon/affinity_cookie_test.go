@@ -0,0 +1,139 @@
+package charon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAffinityFerry(t *testing.T, cookie AffinityCookieConfig, shoreIDs ...string) *BoatFerry {
+	t.Helper()
+
+	config := DefaultFerryConfig()
+	config.Strategy = StrategyConsistentHash
+	config.RateLimiting.Enabled = false
+	config.AffinityCookie = cookie
+
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+
+	for _, id := range shoreIDs {
+		id := id
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Shore", id)
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+		require.NoError(t, ferry.RegisterShore(&Shore{ID: id, Address: server.URL}))
+	}
+	return ferry
+}
+
+func cross(t *testing.T, ferry *BoatFerry, cookies ...*http.Cookie) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	resp, err := ferry.Cross(context.Background(), req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func responseCookie(resp *http.Response, name string) *http.Cookie {
+	for _, c := range (&http.Response{Header: resp.Header}).Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestAffinityCookies_SignAndVerify(t *testing.T) {
+	cookies := newAffinityCookies(AffinityCookieConfig{Secret: "s3cret", TTL: time.Minute})
+
+	issued := cookies.issue("shore-1")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(issued)
+	shoreID, ok := cookies.shore(req)
+	assert.True(t, ok)
+	assert.Equal(t, "shore-1", shoreID)
+
+	t.Run("Tampered", func(t *testing.T) {
+		forged := cookies.issue("shore-1")
+		forged.Value = "c2hvcmUtMg" + forged.Value[len("c2hvcmUtMQ"):] // shore-2
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(forged)
+		_, ok := cookies.shore(req)
+		assert.False(t, ok)
+	})
+
+	t.Run("OtherSecret", func(t *testing.T) {
+		other := newAffinityCookies(AffinityCookieConfig{Secret: "different", TTL: time.Minute})
+		_, ok := other.shore(req)
+		assert.False(t, ok)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		cookies.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		defer func() { cookies.now = time.Now }()
+		_, ok := cookies.shore(req)
+		assert.False(t, ok)
+	})
+}
+
+func TestBoatFerry_AffinityCookie_IssuedOnceAndPins(t *testing.T) {
+	ferry := newAffinityFerry(t, AffinityCookieConfig{Enabled: true, Secret: "s3cret", Domain: "example.com"}, "shore-1", "shore-2", "shore-3")
+
+	first := cross(t, ferry)
+	cookie := responseCookie(first, defaultAffinityCookieName)
+	require.NotNil(t, cookie, "expected affinity cookie on first response")
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.True(t, cookie.HttpOnly)
+	pinned := first.Header.Get("X-Shore")
+
+	// Different client IPs would hash elsewhere; the cookie keeps them pinned
+	for i := 0; i < 10; i++ {
+		resp := cross(t, ferry, cookie)
+		assert.Equal(t, pinned, resp.Header.Get("X-Shore"))
+		assert.Nil(t, responseCookie(resp, defaultAffinityCookieName), "cookie should not be re-issued while valid")
+	}
+}
+
+func TestBoatFerry_AffinityCookie_ShoreRemoval(t *testing.T) {
+	for _, rotate := range []bool{true, false} {
+		ferry := newAffinityFerry(t, AffinityCookieConfig{Enabled: true, Secret: "s3cret", RotateOnRemoval: rotate}, "shore-1", "shore-2")
+		stale := ferry.affinity.issue("shore-1")
+		require.NoError(t, ferry.DeregisterShore("shore-1"))
+
+		resp := cross(t, ferry, stale)
+		assert.Equal(t, "shore-2", resp.Header.Get("X-Shore"))
+
+		cookie := responseCookie(resp, defaultAffinityCookieName)
+		require.NotNil(t, cookie)
+		if rotate {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(cookie)
+			shoreID, ok := ferry.affinity.shore(req)
+			assert.True(t, ok)
+			assert.Equal(t, "shore-2", shoreID)
+		} else {
+			assert.Equal(t, -1, cookie.MaxAge, "stale cookie should be cleared")
+		}
+	}
+}
+
+func TestBoatFerry_AffinityCookie_DisabledOrOtherStrategy(t *testing.T) {
+	ferry := newAffinityFerry(t, AffinityCookieConfig{}, "shore-1")
+	assert.Nil(t, responseCookie(cross(t, ferry), defaultAffinityCookieName))
+
+	ferry = newAffinityFerry(t, AffinityCookieConfig{Enabled: true}, "shore-1")
+	ferry.config.Strategy = StrategyRoundRobin
+	assert.Nil(t, responseCookie(cross(t, ferry), defaultAffinityCookieName))
+}
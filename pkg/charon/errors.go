@@ -13,6 +13,12 @@ var (
 	// ErrRateLimitExceeded indicates rate limit has been exceeded
 	ErrRateLimitExceeded = errors.New("rate limit exceeded - the ferryman demands patience")
 
+	// ErrClientBlocked indicates the client is on the shield's block list
+	ErrClientBlocked = errors.New("client blocked - the ferryman refuses passage")
+
+	// ErrTooManyConnections indicates the client has too many requests in flight
+	ErrTooManyConnections = errors.New("too many connections - the ferryman carries one soul at a time")
+
 	// ErrCircuitOpen indicates circuit breaker is open
 	ErrCircuitOpen = errors.New("circuit breaker open - passage denied")
 
@@ -76,6 +82,10 @@ func ToHTTPError(err error) *CrossingError {
 		return NewCrossingError(http.StatusServiceUnavailable, err.Error(), err)
 	case errors.Is(err, ErrRateLimitExceeded):
 		return NewCrossingError(http.StatusTooManyRequests, err.Error(), err)
+	case errors.Is(err, ErrClientBlocked):
+		return NewCrossingError(http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ErrTooManyConnections):
+		return NewCrossingError(http.StatusTooManyRequests, err.Error(), err)
 	case errors.Is(err, ErrCircuitOpen):
 		return NewCrossingError(http.StatusServiceUnavailable, err.Error(), err)
 	case errors.Is(err, ErrShoreNotFound):
@@ -36,6 +36,7 @@ type Shore struct {
 	Zone        string            // Geographic zone for zone-aware routing
 	Priority    int               // Failover priority (lower = higher priority)
 	HealthCheck *HealthCheck      // Health check configuration
+	Transport   *TransportConfig  // Connection pool override (nil = use FerryConfig.Transport)
 	Metadata    map[string]string // Additional metadata
 }
 
@@ -63,6 +64,7 @@ type ShoreHealth struct {
 	Latency     time.Duration // Average latency
 	ActiveConns int           // Active connections
 	ErrorRate   float64       // Error rate (0.0-1.0)
+	ConnReuse   float64       // Fraction of upstream connections reused from the pool (0.0-1.0)
 	LastCheck   time.Time     // Last health check time
 }
 
@@ -85,6 +87,10 @@ type FerryConfig struct {
 	// If empty, defaults to "ip"
 	SessionAffinityKey string
 
+	// Signed affinity cookie issued to pin clients to a shore
+	// (consistent_hash only)
+	AffinityCookie AffinityCookieConfig
+
 	// Circuit breaker settings
 	CircuitBreaker CircuitBreakerConfig
 
@@ -94,9 +100,17 @@ type FerryConfig struct {
 	// Retry configuration
 	Retry RetryConfig
 
+	// Upstream connection pool settings, applied to every shore unless
+	// the shore carries its own Transport override
+	Transport TransportConfig
+
 	// Timeout for crossing
 	CrossingTimeout time.Duration
 
+	// Per-route timeout, retry and circuit breaker overrides. The most
+	// specific matching route applies.
+	Routes []RouteConfig
+
 	// Metrics for telemetry (optional)
 	Metrics interface{}
 
@@ -148,6 +162,8 @@ func DefaultFerryConfig() *FerryConfig {
 		CrossingTimeout: 30 * time.Second,
 		MaxConcurrent:   0, // Unlimited
 
+		Transport: DefaultTransportConfig(),
+
 		CircuitBreaker: CircuitBreakerConfig{
 			Enabled:          true,
 			Threshold:        5,
@@ -14,8 +14,9 @@ type HealthChecker struct {
 	client *http.Client
 	mu     sync.RWMutex
 
+	ctx      context.Context // Set by Start; shores added later are checked from then on
 	stopChan chan struct{}
-	doneChan chan struct{}
+	wg       sync.WaitGroup
 
 	telemetry *Telemetry
 }
@@ -31,6 +32,8 @@ type shoreHealthState struct {
 	errorRate          float64
 	totalRequests      int64
 	failedRequests     int64
+
+	stop chan struct{} // Closed when the shore is removed or replaced
 }
 
 // NewHealthChecker creates a new health checker.
@@ -41,7 +44,6 @@ func NewHealthChecker() *HealthChecker {
 			Timeout: 5 * time.Second,
 		},
 		stopChan: make(chan struct{}),
-		doneChan: make(chan struct{}),
 	}
 }
 
@@ -50,10 +52,46 @@ func (hc *HealthChecker) AddShore(shore *Shore) {
 	hc.mu.Lock()
 	defer hc.mu.Unlock()
 
-	hc.shores[shore.ID] = &shoreHealthState{
+	if old, ok := hc.shores[shore.ID]; ok {
+		close(old.stop)
+	}
+	hc.watchLocked(&shoreHealthState{
 		shore:  shore,
 		status: HealthStatusHealthy, // Assume healthy initially
+	})
+}
+
+// UpdateShore replaces the configuration of a monitored shore, keeping its
+// health status, counters and error rate. Unknown shores are added.
+func (hc *HealthChecker) UpdateShore(shore *Shore) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	old, ok := hc.shores[shore.ID]
+	if !ok {
+		hc.watchLocked(&shoreHealthState{shore: shore, status: HealthStatusHealthy})
+		return
 	}
+	close(old.stop)
+	state := *old
+	state.shore = shore
+	hc.watchLocked(&state)
+}
+
+// watchLocked registers the state and, once started, checks it until it is
+// removed or replaced. Callers hold hc.mu.
+func (hc *HealthChecker) watchLocked(state *shoreHealthState) {
+	state.stop = make(chan struct{})
+	hc.shores[state.shore.ID] = state
+	if hc.ctx == nil {
+		return
+	}
+	ctx := hc.ctx
+	hc.wg.Add(1)
+	go func() {
+		defer hc.wg.Done()
+		hc.checkShore(ctx, state)
+	}()
 }
 
 // RemoveShore removes a shore from monitoring.
@@ -61,43 +99,31 @@ func (hc *HealthChecker) RemoveShore(shoreID string) {
 	hc.mu.Lock()
 	defer hc.mu.Unlock()
 
-	delete(hc.shores, shoreID)
+	if state, ok := hc.shores[shoreID]; ok {
+		close(state.stop)
+		delete(hc.shores, shoreID)
+	}
 }
 
 // Start begins periodic health checks.
 func (hc *HealthChecker) Start(ctx context.Context) {
-	go hc.run(ctx)
-}
-
-// Stop stops health checking.
-func (hc *HealthChecker) Stop() {
-	close(hc.stopChan)
-	<-hc.doneChan
-}
-
-// run performs periodic health checks.
-func (hc *HealthChecker) run(ctx context.Context) {
-	defer close(hc.doneChan)
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
 
-	// Stagger initial checks to avoid thundering herd
-	hc.mu.RLock()
-	shores := make([]*shoreHealthState, 0, len(hc.shores))
+	hc.ctx = ctx
 	for _, state := range hc.shores {
-		shores = append(shores, state)
-	}
-	hc.mu.RUnlock()
-
-	// Start a goroutine for each shore
-	var wg sync.WaitGroup
-	for _, state := range shores {
-		wg.Add(1)
+		hc.wg.Add(1)
 		go func(state *shoreHealthState) {
-			defer wg.Done()
+			defer hc.wg.Done()
 			hc.checkShore(ctx, state)
 		}(state)
 	}
+}
 
-	wg.Wait()
+// Stop stops health checking.
+func (hc *HealthChecker) Stop() {
+	close(hc.stopChan)
+	hc.wg.Wait()
 }
 
 // checkShore performs health checks for a single shore.
@@ -119,6 +145,9 @@ func (hc *HealthChecker) checkShore(ctx context.Context, state *shoreHealthState
 		case <-ticker.C:
 			hc.performCheck(ctx, state)
 
+		case <-state.stop:
+			return
+
 		case <-hc.stopChan:
 			return
 
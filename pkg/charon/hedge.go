@@ -0,0 +1,207 @@
+package charon
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeConfig enables hedged requests on a route: when the primary shore has
+// not answered within the route's recent latency percentile, the same request
+// is sent to a second healthy shore and the first response wins. Only GET and
+// HEAD requests without a body are hedged.
+type HedgeConfig struct {
+	Enabled    bool          // Hedge requests on this route
+	Percentile float64       // Latency percentile after which to hedge (default 95)
+	MinDelay   time.Duration // Lower bound on the hedge delay (default 10ms)
+	MaxDelay   time.Duration // Upper bound, and the delay until enough samples are seen (default 1s)
+	MinSamples int           // Latency samples needed before the percentile is used (default 20)
+}
+
+const (
+	defaultHedgePercentile = 95
+	defaultHedgeMinDelay   = 10 * time.Millisecond
+	defaultHedgeMaxDelay   = time.Second
+	defaultHedgeMinSamples = 20
+
+	// hedgeWindow is how many recent latencies the percentile is taken over.
+	hedgeWindow = 128
+)
+
+// Hedge outcomes recorded in charon_hedged_requests_total.
+const (
+	hedgePrimaryWon = "primary_won"
+	hedgeHedgeWon   = "hedge_won"
+	hedgeNoShore    = "no_shore"
+)
+
+// withDefaults fills zero-valued fields.
+func (c HedgeConfig) withDefaults() HedgeConfig {
+	if c.Percentile <= 0 || c.Percentile > 100 {
+		c.Percentile = defaultHedgePercentile
+	}
+	if c.MinDelay <= 0 {
+		c.MinDelay = defaultHedgeMinDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaultHedgeMaxDelay
+	}
+	if c.MaxDelay < c.MinDelay {
+		c.MaxDelay = c.MinDelay
+	}
+	if c.MinSamples <= 0 {
+		c.MinSamples = defaultHedgeMinSamples
+	}
+	return c
+}
+
+// hedger tracks a route's recent response latencies to derive its hedge delay.
+type hedger struct {
+	config HedgeConfig
+
+	mu      sync.Mutex
+	samples []time.Duration // Ring buffer of the last hedgeWindow latencies
+	next    int
+}
+
+func newHedger(config HedgeConfig) *hedger {
+	return &hedger{
+		config:  config.withDefaults(),
+		samples: make([]time.Duration, 0, hedgeWindow),
+	}
+}
+
+// observe records the latency of a completed request on the route.
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < hedgeWindow {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % hedgeWindow
+}
+
+// delay returns how long to wait for the primary shore before hedging.
+func (h *hedger) delay() time.Duration {
+	h.mu.Lock()
+	if len(h.samples) < h.config.MinSamples {
+		h.mu.Unlock()
+		return h.config.MaxDelay
+	}
+	sorted := append([]time.Duration(nil), h.samples...)
+	h.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(float64(len(sorted))*h.config.Percentile/100+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+
+	d := sorted[idx]
+	if d < h.config.MinDelay {
+		d = h.config.MinDelay
+	}
+	if d > h.config.MaxDelay {
+		d = h.config.MaxDelay
+	}
+	return d
+}
+
+// hedgeable reports whether the request is safe to send twice.
+func hedgeable(req *http.Request) bool {
+	return (req.Method == http.MethodGet || req.Method == http.MethodHead) && req.ContentLength == 0
+}
+
+// crossingResult is the outcome of forwarding to one shore.
+type crossingResult struct {
+	shore    *Shore
+	resp     *http.Response
+	err      error
+	duration time.Duration
+}
+
+// settled reports whether the result ends the race. Errors and 5xx responses
+// give the other shore a chance to answer.
+func (c crossingResult) settled() bool {
+	return c.err == nil && c.resp.StatusCode < http.StatusInternalServerError
+}
+
+// forwardHedged forwards the request to the primary shore and, if the route
+// hedges and the primary is slower than the route's hedge delay, to a second
+// healthy shore as well. It returns the first settled response and the shore
+// that served it; the losing request is cancelled. Requests on routes without
+// hedging are forwarded as is.
+func (f *BoatFerry) forwardHedged(ctx context.Context, req *http.Request, r *route, primary *Shore, triedShores map[string]bool) (*http.Response, *Shore, time.Duration, error) {
+	if r == nil || r.hedge == nil || !hedgeable(req) {
+		start := time.Now()
+		resp, err := f.forwardRequest(ctx, req, primary)
+		return resp, primary, time.Since(start), err
+	}
+
+	// Buffered so the loser never blocks after the race is decided
+	results := make(chan crossingResult, 2)
+	launch := func(shore *Shore) context.CancelFunc {
+		shoreCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			start := time.Now()
+			resp, err := f.forwardRequest(shoreCtx, req, shore)
+			results <- crossingResult{shore: shore, resp: resp, err: err, duration: time.Since(start)}
+		}()
+		return cancel
+	}
+
+	cancels := map[string]context.CancelFunc{primary.ID: launch(primary)}
+	finish := func(res crossingResult) (*http.Response, *Shore, time.Duration, error) {
+		// Responses are fully buffered, so cancelling the winner is harmless
+		for _, cancel := range cancels {
+			cancel()
+		}
+		if res.settled() {
+			r.hedge.observe(res.duration)
+		}
+		return res.resp, res.shore, res.duration, res.err
+	}
+
+	delay := r.hedge.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case res := <-results:
+		return finish(res)
+	case <-ctx.Done():
+		return finish(<-results)
+	case <-timer.C:
+	}
+
+	second, err := f.retryWithFallback(ctx, req, r, triedShores)
+	if err != nil {
+		f.telemetry.RecordHedge(hedgeNoShore, delay)
+		return finish(<-results)
+	}
+	triedShores[second.ID] = true
+	cancels[second.ID] = launch(second)
+
+	first := <-results
+	if !first.settled() {
+		if first.resp != nil && first.resp.Body != nil {
+			first.resp.Body.Close()
+		}
+		first = <-results
+	}
+
+	outcome := hedgePrimaryWon
+	if first.shore.ID == second.ID {
+		outcome = hedgeHedgeWon
+	}
+	f.telemetry.RecordHedge(outcome, delay)
+	return finish(first)
+}
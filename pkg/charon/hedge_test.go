@@ -0,0 +1,92 @@
+package charon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedger_Delay(t *testing.T) {
+	h := newHedger(HedgeConfig{Enabled: true, Percentile: 90, MinDelay: 5 * time.Millisecond, MaxDelay: time.Second, MinSamples: 10})
+
+	// Too few samples: wait the full delay
+	h.observe(time.Millisecond)
+	assert.Equal(t, time.Second, h.delay())
+
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 90*time.Millisecond, h.delay())
+
+	// Clamped to the bounds
+	for i := 0; i < hedgeWindow; i++ {
+		h.observe(time.Microsecond)
+	}
+	assert.Equal(t, 5*time.Millisecond, h.delay())
+}
+
+func TestBoatFerry_HedgedRequests(t *testing.T) {
+	var slowCancelled int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+			atomic.AddInt32(&slowCancelled, 1)
+			return
+		}
+		_, _ = io.WriteString(w, "slow")
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "fast")
+	}))
+	defer fast.Close()
+
+	metrics := NewMockMetrics()
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	config.Metrics = metrics
+	config.Routes = []RouteConfig{{
+		PathPrefix: "/sandboxes",
+		Method:     http.MethodGet,
+		Hedge:      &HedgeConfig{Enabled: true, MaxDelay: 20 * time.Millisecond},
+	}}
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "slow", Address: slow.URL}))
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "fast", Address: fast.URL}))
+
+	// Round robin sends every other request to the slow shore first
+	for i := 0; i < 4; i++ {
+		start := time.Now()
+		resp, err := ferry.Cross(context.Background(), httptest.NewRequest(http.MethodGet, "/sandboxes", nil))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "fast", string(body))
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	}
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&slowCancelled) == 2 }, time.Second, 10*time.Millisecond,
+		"the losing request should be cancelled")
+	metrics.mu.Lock()
+	assert.Equal(t, 2.0, metrics.counters["charon_hedged_requests_total|result=hedge_won"])
+	metrics.mu.Unlock()
+
+	// Routes without hedging wait for the slow shore
+	atomic.StoreInt32(&slowCancelled, 0)
+	var sawSlow bool
+	for i := 0; i < 2; i++ {
+		resp, err := ferry.Cross(context.Background(), httptest.NewRequest(http.MethodPost, "/sandboxes", nil))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		sawSlow = sawSlow || string(body) == "slow"
+	}
+	assert.True(t, sawSlow)
+}
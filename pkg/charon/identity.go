@@ -0,0 +1,66 @@
+package charon
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+)
+
+// IdentityPropagator authenticates requests at the edge and forwards the
+// verified identity to shores in cerberus.IdentityHeader, so they can trust
+// it instead of validating the client's credentials again. The tenant
+// header is rewritten from the identity so tenant affinity and rate limits
+// use the verified tenant.
+type IdentityPropagator struct {
+	authenticator cerberus.Authenticator
+	extractor     cerberus.CredentialExtractor
+	signer        *cerberus.ProxyIdentitySigner
+}
+
+// NewIdentityPropagator creates an identity propagator.
+func NewIdentityPropagator(authenticator cerberus.Authenticator, extractor cerberus.CredentialExtractor, signer *cerberus.ProxyIdentitySigner) *IdentityPropagator {
+	return &IdentityPropagator{
+		authenticator: authenticator,
+		extractor:     extractor,
+		signer:        signer,
+	}
+}
+
+// Handler returns an HTTP handler that rejects unauthenticated requests and
+// injects the signed identity into the rest.
+func (p *IdentityPropagator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Never forward identity headers supplied by the client
+		r.Header.Del(cerberus.IdentityHeader)
+		r.Header.Del("X-Tenant-ID")
+		r.Header.Del("X-Identity-ID")
+
+		creds, err := p.extractor.Extract(r)
+		if err != nil {
+			cerberus.WriteAuthError(w, err)
+			return
+		}
+		identity, err := p.authenticator.Authenticate(r.Context(), creds)
+		if err != nil {
+			cerberus.WriteAuthError(w, err)
+			return
+		}
+
+		token, err := p.signer.Sign(r.Context(), identity)
+		if err != nil {
+			slog.Error("Failed to sign identity", "identity_id", identity.ID, "error", err)
+			http.Error(w, "Service Unavailable: identity signing failed", http.StatusServiceUnavailable)
+			return
+		}
+		r.Header.Set(cerberus.IdentityHeader, token)
+		r.Header.Set("X-Identity-ID", identity.ID)
+		ctx := context.WithValue(r.Context(), "identity_id", identity.ID)
+		if identity.TenantID != "" {
+			r.Header.Set("X-Tenant-ID", identity.TenantID)
+			ctx = context.WithValue(ctx, "tenant_id", identity.TenantID)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
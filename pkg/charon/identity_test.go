@@ -0,0 +1,58 @@
+package charon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+)
+
+type staticSecrets map[string]string
+
+func (s staticSecrets) Resolve(ctx context.Context, ref string) (string, error) {
+	return s[ref], nil
+}
+
+func TestIdentityPropagator(t *testing.T) {
+	secrets := staticSecrets{"key:charon": "charon-shared-secret-at-least-32-bytes"}
+	config := cerberus.ProxyIdentityConfig{KeyID: "charon"}
+	authenticator := cerberus.NewAPIKeyAuthenticator(map[string]*cerberus.Identity{
+		"alice-key": {ID: "alice", Type: cerberus.IdentityTypeUser, TenantID: "acme"},
+	})
+	propagator := NewIdentityPropagator(authenticator, cerberus.NewBearerTokenExtractor(), cerberus.NewProxyIdentitySigner(secrets, config))
+
+	var forwarded *http.Request
+	handler := propagator.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+	}))
+
+	// Client-supplied identity headers are dropped along with the request
+	req := httptest.NewRequest("GET", "/sandboxes", nil)
+	req.Header.Set(cerberus.IdentityHeader, "forged")
+	req.Header.Set("X-Tenant-ID", "other")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Nil(t, forwarded)
+
+	req = httptest.NewRequest("GET", "/sandboxes", nil)
+	req.Header.Set("Authorization", "Bearer alice-key")
+	req.Header.Set("X-Tenant-ID", "other")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.NotNil(t, forwarded)
+	assert.Equal(t, "acme", forwarded.Header.Get("X-Tenant-ID"))
+	assert.Equal(t, "acme", forwarded.Context().Value("tenant_id"))
+
+	// The forwarded identity is accepted by Olympus' trusted proxy authenticator
+	creds, err := cerberus.NewProxyIdentityExtractor().Extract(forwarded)
+	require.NoError(t, err)
+	identity, err := cerberus.NewTrustedProxyAuthenticator(secrets, config).Authenticate(context.Background(), creds)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.ID)
+	assert.Equal(t, "acme", identity.TenantID)
+}
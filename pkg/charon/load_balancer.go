@@ -7,8 +7,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"log/slog"
 	"math/rand"
 	"net/http"
+	"net/http/httptrace"
 	"net/http/httputil"
 	"net/url"
 	"sync"
@@ -29,23 +31,32 @@ type BoatFerry struct {
 	healthChecker *HealthChecker
 	rateLimiter   RateLimiter
 	breakers      map[string]CircuitBreakerInterface
+	routes        []*route
 
 	// Load balancing state
 	rrCounter      uint64
 	activeConns    map[string]*int32
 	reverseProxies map[string]*httputil.ReverseProxy
+	transports     map[string]*http.Transport
+	connStats      map[string]*connStats
 	hashRing       *ConsistentHashRing
+	affinity       *affinityCookies
 	telemetry      *Telemetry
 
 	mu sync.RWMutex
 }
 
-// TelemetryCircuitBreaker wraps a CircuitBreakerInterface to record state changes.
+// TelemetryCircuitBreaker wraps a CircuitBreakerInterface to record state
+// changes, and how long the breaker stays out of the closed state once it
+// opens.
 type TelemetryCircuitBreaker struct {
 	CircuitBreakerInterface
 	shoreID   string
 	telemetry *Telemetry
+
+	mu        sync.Mutex
 	lastState CircuitBreakerState
+	openedAt  time.Time
 }
 
 func (tcb *TelemetryCircuitBreaker) RecordSuccess() {
@@ -64,11 +75,25 @@ func (tcb *TelemetryCircuitBreaker) Reset() {
 }
 
 func (tcb *TelemetryCircuitBreaker) checkState() {
+	tcb.mu.Lock()
+	defer tcb.mu.Unlock()
+
 	currentState := tcb.CircuitBreakerInterface.State()
-	if currentState != tcb.lastState {
-		tcb.telemetry.RecordCircuitBreakerState(tcb.shoreID, currentState)
-		tcb.lastState = currentState
+	if currentState == tcb.lastState {
+		return
 	}
+	tcb.telemetry.RecordCircuitBreakerState(tcb.shoreID, currentState)
+
+	// Half-open counts as open: the shore is still being tested
+	switch {
+	case tcb.lastState == StateClosed:
+		tcb.openedAt = time.Now()
+		tcb.telemetry.RecordCircuitBreakerOpened(tcb.shoreID)
+	case currentState == StateClosed && !tcb.openedAt.IsZero():
+		tcb.telemetry.RecordCircuitBreakerClosed(tcb.shoreID, time.Since(tcb.openedAt))
+		tcb.openedAt = time.Time{}
+	}
+	tcb.lastState = currentState
 }
 
 // NewBoatFerry creates a new ferry with the given configuration.
@@ -84,26 +109,24 @@ func NewBoatFerry(config *FerryConfig) (*BoatFerry, error) {
 		breakers:       make(map[string]CircuitBreakerInterface),
 		activeConns:    make(map[string]*int32),
 		reverseProxies: make(map[string]*httputil.ReverseProxy),
+		transports:     make(map[string]*http.Transport),
+		connStats:      make(map[string]*connStats),
 		healthChecker:  NewHealthChecker(),
 		hashRing:       NewConsistentHashRing(150),
+		routes:         newRoutes(config.Routes),
 	}
 
-	// Initialize rate limiter
-	if config.RateLimiting.Enabled {
-		keyFunc := GetKeyFunc(config.RateLimiting.KeyFunc)
-		ferry.rateLimiter = NewTokenBucketLimiter(
-			config.RateLimiting.RequestsPerSecond,
-			config.RateLimiting.Burst,
-			keyFunc,
-		)
-	} else {
-		ferry.rateLimiter = NewNoOpLimiter()
+	if config.AffinityCookie.Enabled {
+		ferry.affinity = newAffinityCookies(config.AffinityCookie)
 	}
 
+	// Initialize rate limiter
+	ferry.rateLimiter = newRateLimiter(config.RateLimiting)
+
 	// Initialize telemetry
 	if config.Metrics != nil {
 		if metrics, ok := config.Metrics.(hermes.Metrics); ok {
-			ferry.telemetry = &Telemetry{metrics: metrics}
+			ferry.telemetry = NewTelemetry(metrics)
 		} else if metrics, ok := config.Metrics.(interface {
 			IncCounter(name string, value float64, labels ...interface{})
 			ObserveHistogram(name string, value float64, labels ...interface{})
@@ -111,7 +134,7 @@ func NewBoatFerry(config *FerryConfig) (*BoatFerry, error) {
 		}); ok {
 			// Convert to hermes.Metrics interface
 			// This is a bit hacky but avoids import cycles
-			ferry.telemetry = &Telemetry{metrics: &metricsAdapter{raw: metrics}}
+			ferry.telemetry = NewTelemetry(&metricsAdapter{raw: metrics})
 		}
 	}
 	if ferry.telemetry == nil {
@@ -124,6 +147,14 @@ func NewBoatFerry(config *FerryConfig) (*BoatFerry, error) {
 	return ferry, nil
 }
 
+// newRateLimiter creates the limiter for the rate limiting settings.
+func newRateLimiter(cfg RateLimitConfig) RateLimiter {
+	if !cfg.Enabled {
+		return NewNoOpLimiter()
+	}
+	return NewTokenBucketLimiter(cfg.RequestsPerSecond, cfg.Burst, GetKeyFunc(cfg.KeyFunc))
+}
+
 // RegisterShore adds a backend destination.
 func (f *BoatFerry) RegisterShore(shore *Shore) error {
 	if shore == nil {
@@ -137,7 +168,11 @@ func (f *BoatFerry) RegisterShore(shore *Shore) error {
 	if _, exists := f.shoreMap[shore.ID]; exists {
 		return ErrShoreAlreadyExists
 	}
+	return f.registerShoreLocked(shore)
+}
 
+// registerShoreLocked adds a shore not yet registered. Callers hold f.mu.
+func (f *BoatFerry) registerShoreLocked(shore *Shore) error {
 	// Set default health check if not provided
 	if shore.HealthCheck == nil {
 		shore.HealthCheck = DefaultHealthCheck()
@@ -148,41 +183,23 @@ func (f *BoatFerry) RegisterShore(shore *Shore) error {
 		shore.Weight = 1
 	}
 
-	// Parse shore address
-	targetURL, err := url.Parse(shore.Address)
+	proxy, transport, err := f.newShoreProxy(shore)
 	if err != nil {
-		return fmt.Errorf("invalid shore address: %w", err)
+		return err
 	}
 
-	// Create reverse proxy for this shore
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-	proxy.ErrorHandler = f.proxyErrorHandler
-
 	// Add to collections
 	f.shores = append(f.shores, shore)
 	f.shoreMap[shore.ID] = shore
 	f.reverseProxies[shore.ID] = proxy
-
-	// Initialize circuit breaker
-	if f.config.CircuitBreaker.Enabled {
-		cb := NewCircuitBreaker(
-			f.config.CircuitBreaker.Threshold,
-			f.config.CircuitBreaker.Timeout,
-			f.config.CircuitBreaker.HalfOpenRequests,
-		)
-		f.breakers[shore.ID] = &TelemetryCircuitBreaker{
-			CircuitBreakerInterface: cb,
-			shoreID:                 shore.ID,
-			telemetry:               f.telemetry,
-			lastState:               cb.State(),
-		}
-	} else {
-		cb := NewNoOpCircuitBreaker()
-		f.breakers[shore.ID] = &TelemetryCircuitBreaker{
-			CircuitBreakerInterface: cb,
-			shoreID:                 shore.ID,
-			telemetry:               f.telemetry,
-			lastState:               cb.State(),
+	f.transports[shore.ID] = transport
+	f.connStats[shore.ID] = &connStats{}
+
+	// Initialize circuit breakers, shore-wide and for routes with overrides
+	f.breakers[shore.ID] = f.newBreaker(f.config.CircuitBreaker, shore.ID)
+	for _, r := range f.routes {
+		if r.CircuitBreaker != nil {
+			r.breakers[shore.ID] = f.newBreaker(*r.CircuitBreaker, shore.ID)
 		}
 	}
 
@@ -199,6 +216,43 @@ func (f *BoatFerry) RegisterShore(shore *Shore) error {
 	return nil
 }
 
+// newShoreProxy creates the reverse proxy for a shore, with a dedicated
+// connection pool.
+func (f *BoatFerry) newShoreProxy(shore *Shore) (*httputil.ReverseProxy, *http.Transport, error) {
+	// Parse shore address
+	targetURL, err := url.Parse(shore.Address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid shore address: %w", err)
+	}
+
+	// Create a dedicated connection pool for this shore
+	transportCfg := f.config.Transport
+	if shore.Transport != nil {
+		transportCfg = *shore.Transport
+	}
+	transport := NewShoreTransport(transportCfg)
+
+	// Create reverse proxy for this shore
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	proxy.Transport = transport
+	proxy.ErrorHandler = f.proxyErrorHandler
+	return proxy, transport, nil
+}
+
+// newBreaker creates a telemetry-wrapped circuit breaker for a shore.
+func (f *BoatFerry) newBreaker(cfg CircuitBreakerConfig, shoreID string) CircuitBreakerInterface {
+	var cb CircuitBreakerInterface = NewNoOpCircuitBreaker()
+	if cfg.Enabled {
+		cb = NewCircuitBreaker(cfg.Threshold, cfg.Timeout, cfg.HalfOpenRequests)
+	}
+	return &TelemetryCircuitBreaker{
+		CircuitBreakerInterface: cb,
+		shoreID:                 shoreID,
+		telemetry:               f.telemetry,
+		lastState:               cb.State(),
+	}
+}
+
 // DeregisterShore removes a backend.
 func (f *BoatFerry) DeregisterShore(shoreID string) error {
 	f.mu.Lock()
@@ -207,18 +261,34 @@ func (f *BoatFerry) DeregisterShore(shoreID string) error {
 	if _, exists := f.shoreMap[shoreID]; !exists {
 		return ErrShoreNotFound
 	}
+	f.deregisterShoreLocked(shoreID)
+	return nil
+}
 
+// deregisterShoreLocked removes a registered shore. Crossings already on
+// their way to it finish on its proxy. Callers hold f.mu.
+func (f *BoatFerry) deregisterShoreLocked(shoreID string) {
 	// Remove from health checker
 	f.healthChecker.RemoveShore(shoreID)
 
 	// Remove from hash ring
 	f.hashRing.Remove(shoreID)
 
+	// Release pooled connections to the departing shore
+	if transport, ok := f.transports[shoreID]; ok {
+		transport.CloseIdleConnections()
+	}
+
 	// Remove from collections
 	delete(f.shoreMap, shoreID)
 	delete(f.breakers, shoreID)
+	for _, r := range f.routes {
+		delete(r.breakers, shoreID)
+	}
 	delete(f.activeConns, shoreID)
 	delete(f.reverseProxies, shoreID)
+	delete(f.transports, shoreID)
+	delete(f.connStats, shoreID)
 
 	// Remove from shores slice
 	for i, shore := range f.shores {
@@ -227,31 +297,39 @@ func (f *BoatFerry) DeregisterShore(shoreID string) error {
 			break
 		}
 	}
-
-	return nil
 }
 
 // Cross ferries a request to the appropriate backend.
 func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Response, error) {
+	// The crossing keeps the configuration it started with, even if the
+	// ferry is reloaded on the way
+	f.mu.RLock()
+	config, routes, limiter := f.config, f.routes, f.rateLimiter
+	f.mu.RUnlock()
+
+	// Per-route overrides take precedence over the ferry-wide settings
+	route := matchRoute(routes, req)
+	retry := retryConfig(config, route)
+
 	// Apply timeout
-	if f.config.CrossingTimeout > 0 {
+	if timeout := crossingTimeout(config, route); timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, f.config.CrossingTimeout)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
 	// Check rate limit (collecting the obol - payment for passage)
 	// Extract key based on the rate limiter's key function
 	key := ""
-	if tbl, ok := f.rateLimiter.(*TokenBucketLimiter); ok {
+	if tbl, ok := limiter.(*TokenBucketLimiter); ok {
 		key = tbl.keyFunc(ctx)
 	} else {
 		// For NoOpLimiter or other implementations, use a default key
 		key = "default"
 	}
 
-	if err := f.rateLimiter.Allow(ctx, key); err != nil {
-		f.telemetry.RecordRateLimitHit(key)
+	if err := limiter.Allow(ctx, key); err != nil {
+		f.telemetry.RecordRateLimitHit(key, route.class())
 		return nil, ToHTTPError(err)
 	}
 
@@ -267,8 +345,8 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 
 	// Initial attempt + retries
 	maxAttempts := 1
-	if f.config.Retry.MaxRetries > 0 {
-		maxAttempts += f.config.Retry.MaxRetries
+	if retry.MaxRetries > 0 {
+		maxAttempts += retry.MaxRetries
 	}
 
 	// Track shores we've already tried to avoid retrying the same failing shore
@@ -282,9 +360,9 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 		// If this is a retry (attempt > 0), we need to select a new shore if the previous one failed
 		if attempt > 0 {
 			// Calculate backoff
-			delay := f.config.Retry.InitialDelay * time.Duration(1<<uint(attempt-1))
-			if delay > f.config.Retry.MaxDelay {
-				delay = f.config.Retry.MaxDelay
+			delay := retry.InitialDelay * time.Duration(1<<uint(attempt-1))
+			if delay > retry.MaxDelay {
+				delay = retry.MaxDelay
 			}
 
 			select {
@@ -294,7 +372,7 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 			}
 
 			// Find a new healthy shore
-			nextShore, err := f.retryWithFallback(ctx, req, triedShores)
+			nextShore, err := f.retryWithFallback(ctx, req, route, triedShores)
 			if err != nil {
 				// No more healthy shores to try
 				if lastErr != nil {
@@ -307,7 +385,7 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 		}
 
 		// Check circuit breaker for the current shore
-		breaker := f.breakers[currentShore.ID]
+		breaker := f.breakerFor(route, currentShore.ID)
 		if !breaker.Allow() {
 			// This shouldn't happen for the first shore if we checked before loop,
 			// but could happen if state changed or for fallback shores.
@@ -316,21 +394,27 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 			continue
 		}
 
-		start := time.Now()
-		resp, err = f.forwardRequest(ctx, req, currentShore)
-		duration := time.Since(start)
+		// A hedged request may be answered by another shore
+		var servedBy *Shore
+		var duration time.Duration
+		resp, servedBy, duration, err = f.forwardHedged(ctx, req, route, currentShore, triedShores)
+		if servedBy != currentShore {
+			currentShore = servedBy
+			breaker = f.breakerFor(route, currentShore.ID)
+		}
 
 		if err != nil {
 			breaker.RecordFailure()
 			f.healthChecker.RecordRequest(currentShore.ID, false)
-			f.telemetry.RecordRequest(currentShore.ID, false, duration)
+			f.telemetry.RecordRequest(currentShore.ID, route.class(), false, duration)
+			logCrossingFailure(ctx, currentShore.ID, attempt, err)
 			lastErr = err
 			continue
 		}
 
 		// Check if status code warrants a retry
 		shouldRetry := false
-		for _, code := range f.config.Retry.RetryOn {
+		for _, code := range retry.RetryOn {
 			if resp.StatusCode == code {
 				shouldRetry = true
 				break
@@ -345,6 +429,7 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 			// For now, just mark breaker/health.
 
 			lastErr = fmt.Errorf("received retryable status code: %d", resp.StatusCode)
+			logCrossingFailure(ctx, currentShore.ID, attempt, lastErr)
 
 			// Close body before retrying
 			if resp.Body != nil {
@@ -356,7 +441,8 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 		// Success!
 		breaker.RecordSuccess()
 		f.healthChecker.RecordRequest(currentShore.ID, true)
-		f.telemetry.RecordRequest(currentShore.ID, true, duration)
+		f.telemetry.RecordRequest(currentShore.ID, route.class(), true, duration)
+		f.applyAffinityCookie(req, resp, currentShore)
 		return resp, nil
 	}
 
@@ -367,6 +453,18 @@ func (f *BoatFerry) Cross(ctx context.Context, req *http.Request) (*http.Respons
 	return nil, ToHTTPError(fmt.Errorf("request failed after %d attempts", maxAttempts))
 }
 
+// logCrossingFailure logs a failed attempt with the request's IDs, so it can
+// be matched with the access log and the shore's own logs.
+func logCrossingFailure(ctx context.Context, shoreID string, attempt int, err error) {
+	slog.Warn("Shore request failed",
+		"shore_id", shoreID,
+		"attempt", attempt+1,
+		"error", err,
+		"request_id", RequestIDFromContext(ctx),
+		"trace_id", TraceIDFromContext(ctx),
+	)
+}
+
 // selectShore chooses a backend based on the configured strategy.
 func (f *BoatFerry) selectShore(ctx context.Context, req *http.Request) (*Shore, error) {
 	f.mu.RLock()
@@ -461,6 +559,11 @@ func (f *BoatFerry) selectIPHash(shores []*Shore, req *http.Request) *Shore {
 
 // selectConsistentHash uses consistent hashing ring for sticky sessions.
 func (f *BoatFerry) selectConsistentHash(shores []*Shore, req *http.Request) *Shore {
+	// A valid affinity cookie overrides the hash key
+	if shore := f.pinnedShore(shores, req); shore != nil {
+		return shore
+	}
+
 	// Extract session key based on configuration
 	key := f.extractSessionKey(req)
 
@@ -535,16 +638,27 @@ func (f *BoatFerry) extractSessionKey(req *http.Request) string {
 
 // forwardRequest forwards the request to the selected shore.
 func (f *BoatFerry) forwardRequest(ctx context.Context, req *http.Request, shore *Shore) (*http.Response, error) {
+	// Get reverse proxy for this shore. A shore deregistered or replaced
+	// by a reload while the request is in flight keeps serving it.
+	f.mu.RLock()
+	proxy := f.reverseProxies[shore.ID]
+	stats := f.connStats[shore.ID]
+	active := f.activeConns[shore.ID]
+	f.mu.RUnlock()
+	if proxy == nil {
+		return nil, ErrShoreNotFound
+	}
+
 	// Increment active connections
-	newCount := atomic.AddInt32(f.activeConns[shore.ID], 1)
+	newCount := atomic.AddInt32(active, 1)
 	f.telemetry.RecordActiveConnections(shore.ID, int(newCount))
 	defer func() {
-		newCount := atomic.AddInt32(f.activeConns[shore.ID], -1)
+		newCount := atomic.AddInt32(active, -1)
 		f.telemetry.RecordActiveConnections(shore.ID, int(newCount))
 	}()
 
-	// Get reverse proxy for this shore
-	proxy := f.reverseProxies[shore.ID]
+	// Trace connection reuse so pool efficiency shows up in telemetry
+	ctx = httptrace.WithClientTrace(ctx, f.connTrace(shore.ID, stats))
 
 	// Create a response writer to capture the response
 	recorder := &responseRecorder{
@@ -575,7 +689,7 @@ func (f *BoatFerry) proxyErrorHandler(w http.ResponseWriter, r *http.Request, er
 }
 
 // retryWithFallback tries to find an alternative healthy shore.
-func (f *BoatFerry) retryWithFallback(ctx context.Context, req *http.Request, triedShores map[string]bool) (*Shore, error) {
+func (f *BoatFerry) retryWithFallback(ctx context.Context, req *http.Request, r *route, triedShores map[string]bool) (*Shore, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -587,7 +701,7 @@ func (f *BoatFerry) retryWithFallback(ctx context.Context, req *http.Request, tr
 		if !f.healthChecker.IsHealthy(shore.ID) {
 			continue
 		}
-		if !f.breakers[shore.ID].Allow() {
+		if !f.breakerForLocked(r, shore.ID).Allow() {
 			continue
 		}
 
@@ -612,6 +726,13 @@ func (f *BoatFerry) Health(ctx context.Context) (*FerryHealth, error) {
 			openBreakers++
 		}
 	}
+	for _, r := range f.routes {
+		for _, breaker := range r.breakers {
+			if breaker.State() == StateOpen {
+				openBreakers++
+			}
+		}
+	}
 
 	// Determine overall status
 	status := HealthStatusHealthy
@@ -628,9 +749,12 @@ func (f *BoatFerry) Health(ctx context.Context) (*FerryHealth, error) {
 		status = HealthStatusDegraded
 	}
 
-	// Add active connections to shore health
+	// Add active connections and pool reuse to shore health
 	for i := range shoreHealth {
 		shoreHealth[i].ActiveConns = int(atomic.LoadInt32(f.activeConns[shoreHealth[i].ShoreID]))
+		if stats, ok := f.connStats[shoreHealth[i].ShoreID]; ok {
+			shoreHealth[i].ConnReuse = stats.ratio()
+		}
 	}
 
 	return &FerryHealth{
@@ -648,6 +772,13 @@ func (f *BoatFerry) Start(ctx context.Context) {
 // Close gracefully shuts down the ferry.
 func (f *BoatFerry) Close() error {
 	f.healthChecker.Stop()
+
+	f.mu.RLock()
+	for _, transport := range f.transports {
+		transport.CloseIdleConnections()
+	}
+	f.mu.RUnlock()
+
 	return f.rateLimiter.Close()
 }
 
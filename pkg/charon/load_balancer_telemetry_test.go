@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -175,6 +176,11 @@ func TestBoatFerry_Telemetry_RateLimit(t *testing.T) {
 
 	assert.True(t, ok, "Rate limit hits should be recorded")
 	assert.Equal(t, 1.0, val)
+
+	metrics.mu.Lock()
+	val = metrics.counters["charon_rate_limited_requests_total|route_class=default"]
+	metrics.mu.Unlock()
+	assert.Equal(t, 1.0, val, "Rate limited requests should be counted by route class")
 }
 
 func TestBoatFerry_Telemetry_Request(t *testing.T) {
@@ -212,6 +218,57 @@ func TestBoatFerry_Telemetry_Request(t *testing.T) {
 
 	assert.NotEmpty(t, durations, "Request duration should be recorded")
 	assert.Greater(t, durations[0], 0.0)
+
+	metrics.mu.Lock()
+	latencies := metrics.histograms["charon_upstream_latency_seconds|shore_id=shore-1|route_class=default|status=success"]
+	metrics.mu.Unlock()
+	assert.Len(t, latencies, 1, "Upstream latency should be recorded by shore and route class")
+}
+
+func TestBoatFerry_Telemetry_RouteClassAndBreakerOpenTime(t *testing.T) {
+	metrics := NewMockMetrics()
+	config := DefaultFerryConfig()
+	config.Metrics = metrics
+	config.Retry.MaxRetries = 0
+	config.CircuitBreaker.Threshold = 1
+	config.CircuitBreaker.Timeout = 20 * time.Millisecond
+	config.CircuitBreaker.HalfOpenRequests = 1
+	config.Routes = []RouteConfig{{PathPrefix: "/sandboxes/", Class: "sandboxes"}}
+
+	ferry, err := NewBoatFerry(config)
+	assert.NoError(t, err)
+
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	assert.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-1", Address: server.URL}))
+
+	// A failure opens the breaker
+	_, err = ferry.Cross(context.Background(), httptest.NewRequest("GET", "/sandboxes/1", nil))
+	assert.Error(t, err)
+
+	// Once the breaker lets a request through again, a success closes it
+	time.Sleep(30 * time.Millisecond)
+	failing.Store(false)
+	_, err = ferry.Cross(context.Background(), httptest.NewRequest("GET", "/sandboxes/1", nil))
+	assert.NoError(t, err)
+
+	metrics.mu.Lock()
+	opens := metrics.counters["charon_circuit_breaker_opens_total|shore_id=shore-1"]
+	openSeconds := metrics.counters["charon_circuit_breaker_open_seconds_total|shore_id=shore-1"]
+	latencies := metrics.histograms["charon_upstream_latency_seconds|shore_id=shore-1|route_class=sandboxes|status=success"]
+	metrics.mu.Unlock()
+
+	assert.Equal(t, 1.0, opens)
+	assert.GreaterOrEqual(t, openSeconds, 0.02, "Open time should cover the breaker timeout")
+	assert.Len(t, latencies, 1)
 }
 
 func TestBoatFerry_Telemetry_ActiveConnections(t *testing.T) {
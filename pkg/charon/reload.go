@@ -0,0 +1,196 @@
+package charon
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Reload replaces the ferry configuration and shore set in one step, for
+// changing shores, rate limits or retry settings without a restart.
+// Crossings already under way finish with the settings and shore they
+// started with; new crossings see only the new configuration.
+//
+// Shores are matched by ID. Removed shores are deregistered and added ones
+// registered. Shores that remain keep their health-check state, active
+// connection counts and circuit breakers; their connection pool is only
+// replaced when the address or pool settings change. The consistent hash
+// ring is rebuilt from the new shore set. The rate limiter, circuit
+// breakers and affinity cookie keys are recreated only when their own
+// settings change, so unchanged limits and pinned clients carry over.
+//
+// An invalid configuration is rejected without changing the ferry.
+func (f *BoatFerry) Reload(config *FerryConfig, shores []*Shore) error {
+	err := f.reload(config, shores)
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	f.telemetry.RecordConfigReload(result, len(f.Shores()))
+	return err
+}
+
+func (f *BoatFerry) reload(config *FerryConfig, shores []*Shore) error {
+	if config == nil {
+		return ErrInvalidConfig
+	}
+	seen := make(map[string]bool, len(shores))
+	for _, shore := range shores {
+		if shore == nil || shore.ID == "" {
+			return fmt.Errorf("%w: shore without an ID", ErrInvalidConfig)
+		}
+		if seen[shore.ID] {
+			return fmt.Errorf("%w: shore %s listed twice", ErrInvalidConfig, shore.ID)
+		}
+		seen[shore.ID] = true
+		if _, err := url.Parse(shore.Address); err != nil {
+			return fmt.Errorf("invalid address for shore %s: %w", shore.ID, err)
+		}
+	}
+
+	f.mu.Lock()
+	old := f.config
+	if config.Metrics == nil {
+		// Telemetry is kept across reloads
+		config.Metrics = old.Metrics
+	}
+
+	// Unchanged rate limits keep their token buckets
+	var oldLimiter RateLimiter
+	if config.RateLimiting != old.RateLimiting {
+		oldLimiter = f.rateLimiter
+		f.rateLimiter = newRateLimiter(config.RateLimiting)
+	}
+	if config.AffinityCookie != old.AffinityCookie {
+		f.affinity = nil
+		if config.AffinityCookie.Enabled {
+			f.affinity = newAffinityCookies(config.AffinityCookie)
+		}
+	}
+
+	f.config = config
+	f.routes = f.carryRoutes(newRoutes(config.Routes))
+	breakerChanged := config.CircuitBreaker != old.CircuitBreaker
+	transportChanged := config.Transport != old.Transport
+
+	for _, shore := range append([]*Shore(nil), f.shores...) {
+		if !seen[shore.ID] {
+			f.deregisterShoreLocked(shore.ID)
+		}
+	}
+	var err error
+	for _, shore := range shores {
+		current, ok := f.shoreMap[shore.ID]
+		if !ok {
+			if err = f.registerShoreLocked(shore); err != nil {
+				break
+			}
+			continue
+		}
+		if err = f.updateShoreLocked(current, shore, transportChanged); err != nil {
+			break
+		}
+		if breakerChanged {
+			f.breakers[shore.ID] = f.newBreaker(config.CircuitBreaker, shore.ID)
+		}
+	}
+
+	// Order the shores as configured, and hash them afresh
+	ordered := make([]*Shore, 0, len(shores))
+	ring := NewConsistentHashRing(150)
+	for _, shore := range shores {
+		if registered, ok := f.shoreMap[shore.ID]; ok {
+			ordered = append(ordered, registered)
+			ring.Add(shore.ID)
+		}
+	}
+	f.shores = ordered
+	f.hashRing = ring
+	f.mu.Unlock()
+
+	if oldLimiter != nil {
+		oldLimiter.Close()
+	}
+	return err
+}
+
+// Shores returns the IDs of the registered shores.
+func (f *BoatFerry) Shores() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	ids := make([]string, 0, len(f.shores))
+	for _, shore := range f.shores {
+		ids = append(ids, shore.ID)
+	}
+	return ids
+}
+
+// updateShoreLocked replaces a registered shore's settings with next's,
+// keeping its health state, counters and breakers. Callers hold f.mu.
+func (f *BoatFerry) updateShoreLocked(current, next *Shore, transportChanged bool) error {
+	if next.HealthCheck == nil {
+		next.HealthCheck = DefaultHealthCheck()
+	}
+	if next.Weight == 0 {
+		next.Weight = 1
+	}
+
+	if transportChanged || next.Address != current.Address || !sameTransport(next.Transport, current.Transport) {
+		proxy, transport, err := f.newShoreProxy(next)
+		if err != nil {
+			return err
+		}
+		// Idle connections to the old pool are released; requests using
+		// it finish first
+		f.transports[next.ID].CloseIdleConnections()
+		f.reverseProxies[next.ID] = proxy
+		f.transports[next.ID] = transport
+	}
+
+	f.shoreMap[next.ID] = next
+	for i, shore := range f.shores {
+		if shore.ID == next.ID {
+			f.shores[i] = next
+		}
+	}
+	f.healthChecker.UpdateShore(next)
+	return nil
+}
+
+// carryRoutes gives routes the breakers and hedge latency history of the
+// current route for the same path and method, where their settings are
+// unchanged, and new breakers for every shore otherwise. Callers hold f.mu.
+func (f *BoatFerry) carryRoutes(routes []*route) []*route {
+	for _, r := range routes {
+		var prev *route
+		for _, p := range f.routes {
+			if p.PathPrefix == r.PathPrefix && p.Method == r.Method {
+				prev = p
+				break
+			}
+		}
+
+		if r.CircuitBreaker != nil {
+			if prev != nil && prev.CircuitBreaker != nil && *prev.CircuitBreaker == *r.CircuitBreaker {
+				for id, cb := range prev.breakers {
+					r.breakers[id] = cb
+				}
+			}
+			for _, shore := range f.shores {
+				if _, ok := r.breakers[shore.ID]; !ok {
+					r.breakers[shore.ID] = f.newBreaker(*r.CircuitBreaker, shore.ID)
+				}
+			}
+		}
+		if r.hedge != nil && prev != nil && prev.hedge != nil && *prev.Hedge == *r.Hedge {
+			r.hedge = prev.hedge
+		}
+	}
+	return routes
+}
+
+func sameTransport(a, b *TransportConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
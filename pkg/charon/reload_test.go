@@ -0,0 +1,151 @@
+package charon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedServer answers every request with its name.
+func namedServer(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func crossBody(t *testing.T, ferry *BoatFerry) string {
+	t.Helper()
+	resp, err := ferry.Cross(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestBoatFerry_ReloadShores(t *testing.T) {
+	a, b, c := namedServer(t, "a"), namedServer(t, "b"), namedServer(t, "c")
+
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "a", Address: a.URL}))
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "b", Address: b.URL}))
+
+	// b has failed enough to be unhealthy; its state survives the reload
+	ferry.healthChecker.mu.Lock()
+	ferry.healthChecker.shores["b"].status = HealthStatusUnhealthy
+	ferry.healthChecker.mu.Unlock()
+	breaker := ferry.breakers["a"]
+
+	next := DefaultFerryConfig()
+	next.RateLimiting.Enabled = false
+	next.Retry.MaxRetries = 0
+	require.NoError(t, ferry.Reload(next, []*Shore{
+		{ID: "b", Address: b.URL},
+		{ID: "c", Address: c.URL},
+		{ID: "a", Address: a.URL},
+	}))
+
+	assert.Equal(t, []string{"b", "c", "a"}, ferry.Shores())
+	assert.False(t, ferry.healthChecker.IsHealthy("b"))
+	assert.Same(t, breaker, ferry.breakers["a"])
+	assert.Equal(t, 0, ferry.config.Retry.MaxRetries)
+
+	// Round robin over the healthy shores only
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[crossBody(t, ferry)] = true
+	}
+	assert.Equal(t, map[string]bool{"a": true, "c": true}, seen)
+
+	// Dropping a shore and moving another
+	require.NoError(t, ferry.Reload(next, []*Shore{{ID: "a", Address: c.URL}}))
+	assert.Equal(t, []string{"a"}, ferry.Shores())
+	assert.Equal(t, "c", crossBody(t, ferry))
+	assert.Nil(t, ferry.healthChecker.GetShoreHealth("b"))
+	assert.Equal(t, "a", ferry.hashRing.Get("anything"))
+}
+
+func TestBoatFerry_ReloadRejectsInvalidConfig(t *testing.T) {
+	a := namedServer(t, "a")
+	ferry, err := NewBoatFerry(nil)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "a", Address: a.URL}))
+
+	assert.ErrorIs(t, ferry.Reload(nil, nil), ErrInvalidConfig)
+	assert.ErrorIs(t, ferry.Reload(DefaultFerryConfig(), []*Shore{{ID: "x"}, {ID: "x"}}), ErrInvalidConfig)
+	assert.Error(t, ferry.Reload(DefaultFerryConfig(), []*Shore{{ID: "y", Address: "http://[::1"}}))
+	assert.Equal(t, []string{"a"}, ferry.Shores())
+}
+
+func TestBoatFerry_ReloadRateLimiter(t *testing.T) {
+	ferry, err := NewBoatFerry(nil)
+	require.NoError(t, err)
+	limiter := ferry.rateLimiter
+
+	// Unchanged limits keep their token buckets
+	require.NoError(t, ferry.Reload(DefaultFerryConfig(), nil))
+	assert.Same(t, limiter, ferry.rateLimiter)
+
+	next := DefaultFerryConfig()
+	next.RateLimiting.RequestsPerSecond = 1
+	next.RateLimiting.Burst = 1
+	require.NoError(t, ferry.Reload(next, nil))
+	assert.NotSame(t, limiter, ferry.rateLimiter)
+	ctx := context.Background()
+	assert.NoError(t, ferry.rateLimiter.Allow(ctx, "tenant"))
+	assert.Error(t, ferry.rateLimiter.Allow(ctx, "tenant"))
+	require.NoError(t, ferry.Close())
+}
+
+func TestBoatFerry_ReloadKeepsInFlightCrossings(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "slow")
+	}))
+	defer slow.Close()
+	fast := namedServer(t, "fast")
+
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "slow", Address: slow.URL}))
+
+	done := make(chan string)
+	go func() {
+		resp, err := ferry.Cross(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		done <- string(body)
+	}()
+	<-started
+
+	// The crossing's shore is removed while it is in flight
+	require.NoError(t, ferry.Reload(config, []*Shore{{ID: "fast", Address: fast.URL}}))
+	assert.Equal(t, "fast", crossBody(t, ferry))
+
+	close(release)
+	select {
+	case body := <-done:
+		assert.Equal(t, "slow", body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight crossing did not finish")
+	}
+}
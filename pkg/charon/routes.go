@@ -0,0 +1,141 @@
+package charon
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RouteConfig overrides the ferry-wide crossing settings for requests that
+// match a path prefix and, optionally, a method. Unset fields inherit the
+// FerryConfig values.
+type RouteConfig struct {
+	PathPrefix string // e.g. "/sandboxes/"; empty matches every path
+	Method     string // e.g. "GET"; empty matches every method
+
+	// Class labels the route's latency and rate-limit metrics
+	// (route_class). Defaults to the path prefix.
+	Class string
+
+	// Timeout replaces CrossingTimeout. Negative disables the timeout,
+	// for streaming endpoints that stay open indefinitely.
+	Timeout time.Duration
+
+	// Retry replaces the ferry retry budget and retryable status codes.
+	// Use MaxRetries 0 to never retry the route.
+	Retry *RetryConfig
+
+	// CircuitBreaker gives the route its own breaker per shore, so failures
+	// on this route neither open nor are hidden by the shore-wide breaker.
+	CircuitBreaker *CircuitBreakerConfig
+
+	// Hedge sends slow idempotent requests to a second shore as well.
+	Hedge *HedgeConfig
+}
+
+// route is a configured override with its per-shore breakers.
+type route struct {
+	RouteConfig
+	breakers map[string]CircuitBreakerInterface
+	hedge    *hedger // nil unless hedging is enabled
+}
+
+// matches reports whether the request falls under the route.
+func (r *route) matches(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+	return strings.HasPrefix(req.URL.Path, r.PathPrefix)
+}
+
+// defaultRouteClass labels requests that match no route.
+const defaultRouteClass = "default"
+
+// class returns the route class the request's metrics are reported under.
+func (r *route) class() string {
+	switch {
+	case r == nil:
+		return defaultRouteClass
+	case r.Class != "":
+		return r.Class
+	case r.PathPrefix != "":
+		return r.PathPrefix
+	default:
+		return defaultRouteClass
+	}
+}
+
+// newRoutes builds the route table from the configuration.
+func newRoutes(configs []RouteConfig) []*route {
+	routes := make([]*route, 0, len(configs))
+	for _, cfg := range configs {
+		r := &route{
+			RouteConfig: cfg,
+			breakers:    make(map[string]CircuitBreakerInterface),
+		}
+		if cfg.Hedge != nil && cfg.Hedge.Enabled {
+			r.hedge = newHedger(*cfg.Hedge)
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// matchRoute returns the most specific route for the request: the longest
+// matching prefix, then a method-specific route over a wildcard one. Ties go
+// to the route declared first. Returns nil when no route matches.
+func (f *BoatFerry) matchRoute(req *http.Request) *route {
+	f.mu.RLock()
+	routes := f.routes
+	f.mu.RUnlock()
+	return matchRoute(routes, req)
+}
+
+// matchRoute returns the most specific of routes for the request.
+func matchRoute(routes []*route, req *http.Request) *route {
+	var best *route
+	for _, r := range routes {
+		if !r.matches(req) {
+			continue
+		}
+		if best == nil ||
+			len(r.PathPrefix) > len(best.PathPrefix) ||
+			(len(r.PathPrefix) == len(best.PathPrefix) && r.Method != "" && best.Method == "") {
+			best = r
+		}
+	}
+	return best
+}
+
+// crossingTimeout returns the timeout for a request on the route.
+func crossingTimeout(config *FerryConfig, r *route) time.Duration {
+	if r != nil && r.Timeout != 0 {
+		return r.Timeout
+	}
+	return config.CrossingTimeout
+}
+
+// retryConfig returns the retry settings for a request on the route.
+func retryConfig(config *FerryConfig, r *route) RetryConfig {
+	if r != nil && r.Retry != nil {
+		return *r.Retry
+	}
+	return config.Retry
+}
+
+// breakerFor returns the circuit breaker guarding the shore for the route.
+func (f *BoatFerry) breakerFor(r *route, shoreID string) CircuitBreakerInterface {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.breakerForLocked(r, shoreID)
+}
+
+// breakerForLocked is breakerFor for callers already holding f.mu.
+func (f *BoatFerry) breakerForLocked(r *route, shoreID string) CircuitBreakerInterface {
+	if r != nil && r.CircuitBreaker != nil {
+		if cb, ok := r.breakers[shoreID]; ok {
+			return cb
+		}
+	}
+	return f.breakers[shoreID]
+}
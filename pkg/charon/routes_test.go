@@ -0,0 +1,136 @@
+package charon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoatFerry_MatchRoute(t *testing.T) {
+	config := DefaultFerryConfig()
+	config.Routes = []RouteConfig{
+		{PathPrefix: "/sandboxes/"},
+		{PathPrefix: "/sandboxes/", Method: http.MethodPost},
+		{PathPrefix: "/sandboxes/logs"},
+	}
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+
+	match := func(method, path string) *RouteConfig {
+		r := ferry.matchRoute(httptest.NewRequest(method, path, nil))
+		if r == nil {
+			return nil
+		}
+		return &r.RouteConfig
+	}
+
+	assert.Nil(t, match(http.MethodGet, "/health"))
+	assert.Equal(t, &config.Routes[0], match(http.MethodGet, "/sandboxes/abc"))
+	assert.Equal(t, &config.Routes[1], match(http.MethodPost, "/sandboxes/abc"))
+	assert.Equal(t, &config.Routes[2], match(http.MethodPost, "/sandboxes/logs/abc"))
+}
+
+func TestBoatFerry_RouteRetryOverride(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	config.CircuitBreaker.Enabled = false
+	config.Retry.InitialDelay = time.Millisecond
+	config.Routes = []RouteConfig{
+		{PathPrefix: "/logs", Retry: &RetryConfig{}},
+		{PathPrefix: "/fast", Retry: &RetryConfig{MaxRetries: 1, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, RetryOn: []int{503}}},
+	}
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-1", Address: server.URL}))
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-2", Address: server.URL}))
+
+	cross := func(path string) int32 {
+		atomic.StoreInt32(&calls, 0)
+		resp, err := ferry.Cross(context.Background(), httptest.NewRequest(http.MethodGet, path, nil))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return atomic.LoadInt32(&calls)
+	}
+
+	// Never retried: the 503 is returned as is
+	assert.Equal(t, int32(1), cross("/logs/abc"))
+	// Route budget of one retry
+	assert.Equal(t, int32(2), cross("/fast"))
+	// Ferry-wide budget; only two shores to try
+	assert.Equal(t, int32(2), cross("/other"))
+}
+
+func TestBoatFerry_RouteTimeoutOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	config.Retry.MaxRetries = 0
+	config.CrossingTimeout = 50 * time.Millisecond
+	config.Routes = []RouteConfig{{PathPrefix: "/stream", Timeout: -1}}
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-1", Address: server.URL}))
+
+	resp, err := ferry.Cross(context.Background(), httptest.NewRequest(http.MethodGet, "/stream", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Other routes keep the ferry-wide timeout
+	_, err = ferry.Cross(context.Background(), httptest.NewRequest(http.MethodGet, "/short", nil))
+	assert.ErrorContains(t, err, "502")
+}
+
+func TestBoatFerry_RouteBreakerOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	config.Retry.MaxRetries = 0
+	config.Routes = []RouteConfig{{
+		PathPrefix:     "/fragile",
+		CircuitBreaker: &CircuitBreakerConfig{Enabled: true, Threshold: 1, Timeout: time.Minute, HalfOpenRequests: 1},
+	}}
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-1", Address: server.URL}))
+
+	_, err = ferry.Cross(context.Background(), httptest.NewRequest(http.MethodGet, "/fragile", nil))
+	require.Error(t, err)
+
+	// The route breaker opened after one failure; the shore breaker did not
+	route := ferry.matchRoute(httptest.NewRequest(http.MethodGet, "/fragile", nil))
+	assert.Equal(t, StateOpen, ferry.breakerFor(route, "shore-1").State())
+	assert.Equal(t, StateClosed, ferry.breakers["shore-1"].State())
+
+	health, err := ferry.Health(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, health.OpenBreakers)
+
+	require.NoError(t, ferry.DeregisterShore("shore-1"))
+	assert.Empty(t, route.breakers)
+}
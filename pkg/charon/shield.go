@@ -0,0 +1,538 @@
+package charon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ShieldConfig configures the abuse mitigation layer in front of the
+// ferry. Zero values disable the corresponding protection.
+type ShieldConfig struct {
+	// Maximum requests in flight per client IP. Long-lived requests such
+	// as log streams and exec sessions hold their slot until they end.
+	MaxConnsPerIP int
+
+	// Failed authentications (401 or 403 responses) after which a client
+	// IP is greylisted
+	GreylistThreshold int
+
+	// Delay of the first greylisted request, doubled by every further
+	// failure up to GreylistMaxDelay. Defaults to 1s and 30s.
+	GreylistDelay    time.Duration
+	GreylistMaxDelay time.Duration
+
+	// How long a client IP stays greylisted after its last failure.
+	// Defaults to 15m.
+	GreylistTTL time.Duration
+
+	// Take the client IP from the last X-Forwarded-For entry, for
+	// deployments behind an external load balancer
+	TrustForwardedFor bool
+}
+
+// Enabled reports whether any protection is configured. Block lists can
+// be loaded into any shield.
+func (c ShieldConfig) Enabled() bool {
+	return c.MaxConnsPerIP > 0 || c.GreylistThreshold > 0
+}
+
+// BlockList rejects client IPs by network or by autonomous system.
+type BlockList struct {
+	Prefixes []netip.Prefix
+	ASNs     []uint32
+}
+
+// ParseBlockList reads a block list with one entry per line: an IP
+// address, a CIDR, or an AS number such as AS64496. Blank lines and
+// comments starting with # are ignored.
+func ParseBlockList(r io.Reader) (*BlockList, error) {
+	list := &BlockList{}
+	err := scanEntries(r, func(line int, fields []string) error {
+		entry := fields[0]
+		if len(entry) > 2 && strings.EqualFold(entry[:2], "AS") {
+			asn, ok := parseASN(entry)
+			if !ok {
+				return fmt.Errorf("line %d: invalid AS number %q", line, entry)
+			}
+			list.ASNs = append(list.ASNs, asn)
+			return nil
+		}
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		list.Prefixes = append(list.Prefixes, prefix)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ASNTable maps client IPs to the autonomous system announcing them.
+type ASNTable struct {
+	prefixes prefixMap[uint32]
+}
+
+// ParseASNTable reads a table with one "<cidr> <asn>" entry per line, as
+// exported from a routing table or an IP-to-ASN database. The AS number
+// may carry an AS prefix. Blank lines and comments are ignored.
+func ParseASNTable(r io.Reader) (*ASNTable, error) {
+	table := &ASNTable{}
+	err := scanEntries(r, func(line int, fields []string) error {
+		if len(fields) < 2 {
+			return fmt.Errorf("line %d: expected <cidr> <asn>", line)
+		}
+		prefix, err := parsePrefix(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		asn, ok := parseASN(fields[1])
+		if !ok {
+			return fmt.Errorf("line %d: invalid AS number %q", line, fields[1])
+		}
+		table.prefixes.insert(prefix, asn)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// Lookup returns the AS number of the most specific prefix containing ip.
+func (t *ASNTable) Lookup(ip netip.Addr) (uint32, bool) {
+	return t.prefixes.lookup(ip)
+}
+
+// Shield protects shores from credential stuffing and scraping bursts. It
+// rejects clients on the block list, caps the requests each client IP has
+// in flight, and slows down client IPs that keep failing authentication.
+type Shield struct {
+	config  ShieldConfig
+	metrics hermes.Metrics
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	// Block list, swapped as a whole on reload
+	blockMu   sync.RWMutex
+	blocked   prefixMap[struct{}]
+	blockASNs map[uint32]bool
+	asns      *ASNTable
+
+	mu        sync.Mutex
+	inFlight  map[netip.Addr]int
+	offenders map[netip.Addr]*offender
+	lastSweep time.Time
+}
+
+// offender tracks the failed authentications of a client IP.
+type offender struct {
+	failures    int
+	lastFailure time.Time
+}
+
+// NewShield creates a shield. A nil metrics disables shield metrics.
+func NewShield(config ShieldConfig, metrics hermes.Metrics) *Shield {
+	if config.GreylistDelay <= 0 {
+		config.GreylistDelay = time.Second
+	}
+	if config.GreylistMaxDelay <= 0 {
+		config.GreylistMaxDelay = 30 * time.Second
+	}
+	if config.GreylistTTL <= 0 {
+		config.GreylistTTL = 15 * time.Minute
+	}
+	return &Shield{
+		config:    config,
+		metrics:   metrics,
+		now:       time.Now,
+		sleep:     sleepContext,
+		inFlight:  make(map[netip.Addr]int),
+		offenders: make(map[netip.Addr]*offender),
+	}
+}
+
+// SetBlockList replaces the block list. AS numbers are only matched once
+// an ASN table is set.
+func (s *Shield) SetBlockList(list *BlockList) {
+	var blocked prefixMap[struct{}]
+	asns := make(map[uint32]bool)
+	if list != nil {
+		for _, p := range list.Prefixes {
+			blocked.insert(p, struct{}{})
+		}
+		for _, asn := range list.ASNs {
+			asns[asn] = true
+		}
+	}
+	s.blockMu.Lock()
+	s.blocked = blocked
+	s.blockASNs = asns
+	s.blockMu.Unlock()
+}
+
+// SetASNTable replaces the table used to match blocked AS numbers.
+func (s *Shield) SetASNTable(table *ASNTable) {
+	s.blockMu.Lock()
+	s.asns = table
+	s.blockMu.Unlock()
+}
+
+// LoadFiles loads the block list, and the ASN table if asnPath is set,
+// replacing the current ones. Nothing is replaced if either fails to load.
+func (s *Shield) LoadFiles(blockListPath, asnPath string) error {
+	var list *BlockList
+	if blockListPath != "" {
+		f, err := os.Open(blockListPath)
+		if err != nil {
+			return fmt.Errorf("failed to open block list: %w", err)
+		}
+		list, err = ParseBlockList(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse block list: %w", err)
+		}
+	}
+	var table *ASNTable
+	if asnPath != "" {
+		f, err := os.Open(asnPath)
+		if err != nil {
+			return fmt.Errorf("failed to open ASN table: %w", err)
+		}
+		table, err = ParseASNTable(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse ASN table: %w", err)
+		}
+	}
+
+	s.SetBlockList(list)
+	if table != nil {
+		s.SetASNTable(table)
+	}
+	return nil
+}
+
+// Handler returns an HTTP handler that screens requests before passing
+// them to next.
+func (s *Shield) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, ok := s.clientIP(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if reason := s.blockReason(ip); reason != "" {
+			s.recordBlocked(reason)
+			httpErr := ToHTTPError(ErrClientBlocked)
+			http.Error(w, httpErr.Message, httpErr.HTTPStatusCode())
+			return
+		}
+
+		if !s.acquire(ip) {
+			s.recordBlocked("conn_limit")
+			httpErr := ToHTTPError(ErrTooManyConnections)
+			http.Error(w, httpErr.Message, httpErr.HTTPStatusCode())
+			return
+		}
+		defer s.release(ip)
+
+		if delay := s.greylistDelay(ip); delay > 0 {
+			if s.metrics != nil {
+				s.metrics.IncCounter("charon_shield_delayed_total", 1)
+				s.metrics.ObserveHistogram("charon_shield_delay_seconds", delay.Seconds())
+			}
+			if err := s.sleep(r.Context(), delay); err != nil {
+				// The client gave up
+				return
+			}
+		}
+
+		rw := &shieldResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+		if rw.status == http.StatusUnauthorized || rw.status == http.StatusForbidden {
+			s.recordFailure(ip)
+		}
+	})
+}
+
+// Greylisted returns the number of client IPs currently greylisted.
+func (s *Shield) Greylisted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.greylistedLocked(s.now())
+}
+
+// clientIP returns the IP a request came from.
+func (s *Shield) clientIP(r *http.Request) (netip.Addr, bool) {
+	if s.config.TrustForwardedFor {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			// Only the last entry was added by the trusted load balancer
+			entries := strings.Split(xff, ",")
+			if ip, err := netip.ParseAddr(strings.TrimSpace(entries[len(entries)-1])); err == nil {
+				return ip.Unmap(), true
+			}
+		}
+	}
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		host = h
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+// blockReason returns why ip is blocked, or "" if it is not.
+func (s *Shield) blockReason(ip netip.Addr) string {
+	s.blockMu.RLock()
+	defer s.blockMu.RUnlock()
+	if _, ok := s.blocked.lookup(ip); ok {
+		return "cidr"
+	}
+	if len(s.blockASNs) > 0 && s.asns != nil {
+		if asn, ok := s.asns.Lookup(ip); ok && s.blockASNs[asn] {
+			return "asn"
+		}
+	}
+	return ""
+}
+
+func (s *Shield) acquire(ip netip.Addr) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.MaxConnsPerIP > 0 && s.inFlight[ip] >= s.config.MaxConnsPerIP {
+		return false
+	}
+	s.inFlight[ip]++
+	return true
+}
+
+func (s *Shield) release(ip netip.Addr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[ip]--; s.inFlight[ip] <= 0 {
+		delete(s.inFlight, ip)
+	}
+}
+
+// greylistDelay returns how long to hold a request from ip.
+func (s *Shield) greylistDelay(ip netip.Addr) time.Duration {
+	if s.config.GreylistThreshold <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.offenders[ip]
+	if !ok {
+		return 0
+	}
+	if s.now().Sub(o.lastFailure) >= s.config.GreylistTTL {
+		delete(s.offenders, ip)
+		return 0
+	}
+	if o.failures < s.config.GreylistThreshold {
+		return 0
+	}
+	delay := s.config.GreylistDelay
+	for i := s.config.GreylistThreshold; i < o.failures && delay < s.config.GreylistMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.config.GreylistMaxDelay)
+}
+
+// recordFailure counts a failed authentication from ip.
+func (s *Shield) recordFailure(ip netip.Addr) {
+	if s.config.GreylistThreshold <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	o, ok := s.offenders[ip]
+	if !ok || now.Sub(o.lastFailure) >= s.config.GreylistTTL {
+		o = &offender{}
+		s.offenders[ip] = o
+	}
+	o.failures++
+	o.lastFailure = now
+
+	// Forget offenders that stopped failing, at most once per TTL
+	if now.Sub(s.lastSweep) >= s.config.GreylistTTL {
+		for addr, o := range s.offenders {
+			if now.Sub(o.lastFailure) >= s.config.GreylistTTL {
+				delete(s.offenders, addr)
+			}
+		}
+		s.lastSweep = now
+	}
+	if s.metrics != nil {
+		s.metrics.SetGauge("charon_shield_greylisted_ips", float64(s.greylistedLocked(now)))
+	}
+}
+
+func (s *Shield) greylistedLocked(now time.Time) int {
+	n := 0
+	for _, o := range s.offenders {
+		if o.failures >= s.config.GreylistThreshold && now.Sub(o.lastFailure) < s.config.GreylistTTL {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Shield) recordBlocked(reason string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.IncCounter("charon_shield_blocked_total", 1,
+		hermes.Label{Key: "reason", Value: reason},
+	)
+}
+
+// shieldResponseWriter records the status to detect failed
+// authentications, and keeps WebSocket upgrades working.
+type shieldResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *shieldResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *shieldResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *shieldResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *shieldResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// prefixMap finds the most specific prefix containing an address.
+type prefixMap[T any] struct {
+	entries map[netip.Prefix]T
+	bits    []int // Prefix lengths present, longest first
+}
+
+func (m *prefixMap[T]) insert(p netip.Prefix, v T) {
+	if m.entries == nil {
+		m.entries = make(map[netip.Prefix]T)
+	}
+	p = p.Masked()
+	m.entries[p] = v
+	for _, b := range m.bits {
+		if b == p.Bits() {
+			return
+		}
+	}
+	m.bits = append(m.bits, p.Bits())
+	sort.Sort(sort.Reverse(sort.IntSlice(m.bits)))
+}
+
+func (m *prefixMap[T]) lookup(ip netip.Addr) (T, bool) {
+	var zero T
+	for _, b := range m.bits {
+		if b > ip.BitLen() {
+			continue
+		}
+		p, err := ip.Prefix(b)
+		if err != nil {
+			continue
+		}
+		if v, ok := m.entries[p]; ok {
+			return v, true
+		}
+	}
+	return zero, false
+}
+
+// scanEntries calls fn with the fields of every line that is not blank or
+// a comment.
+func scanEntries(r io.Reader, fn func(line int, fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(line, fields); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parsePrefix parses a CIDR, or a single address as a full-length prefix.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if p.Addr().Is4In6() {
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		return p, nil
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	ip = ip.Unmap()
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+// parseASN parses an AS number with or without the AS prefix.
+func parseASN(s string) (uint32, bool) {
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
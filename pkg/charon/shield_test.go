@@ -0,0 +1,132 @@
+package charon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shieldRequest(handler http.Handler, remoteAddr string) int {
+	req := httptest.NewRequest("GET", "/sandboxes", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestShield_BlockList(t *testing.T) {
+	list, err := ParseBlockList(strings.NewReader(`
+# Scrapers
+203.0.113.0/24
+2001:db8::1
+AS64496   # Bulletproof hosting
+`))
+	require.NoError(t, err)
+	assert.Len(t, list.Prefixes, 2)
+	assert.Equal(t, []uint32{64496}, list.ASNs)
+
+	table, err := ParseASNTable(strings.NewReader("198.51.100.0/24 AS64496\n198.51.100.128/25 64497\n"))
+	require.NoError(t, err)
+	asn, ok := table.Lookup(netip.MustParseAddr("198.51.100.200"))
+	require.True(t, ok)
+	assert.Equal(t, uint32(64497), asn, "most specific prefix wins")
+
+	metrics := NewMockMetrics()
+	shield := NewShield(ShieldConfig{}, metrics)
+	shield.SetBlockList(list)
+	shield.SetASNTable(table)
+	handler := shield.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.Equal(t, http.StatusForbidden, shieldRequest(handler, "203.0.113.9:4000"))
+	assert.Equal(t, http.StatusForbidden, shieldRequest(handler, "[::ffff:203.0.113.9]:4000"))
+	assert.Equal(t, http.StatusForbidden, shieldRequest(handler, "[2001:db8::1]:4000"))
+	assert.Equal(t, http.StatusForbidden, shieldRequest(handler, "198.51.100.7:4000"))
+	assert.Equal(t, http.StatusOK, shieldRequest(handler, "198.51.100.200:4000"))
+	assert.Equal(t, http.StatusOK, shieldRequest(handler, "192.0.2.1:4000"))
+	assert.Equal(t, 3.0, metrics.counters["charon_shield_blocked_total|reason=cidr"])
+	assert.Equal(t, 1.0, metrics.counters["charon_shield_blocked_total|reason=asn"])
+
+	// Reloading replaces the list
+	shield.SetBlockList(&BlockList{})
+	assert.Equal(t, http.StatusOK, shieldRequest(handler, "203.0.113.9:4000"))
+
+	_, err = ParseBlockList(strings.NewReader("not-an-ip\n"))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestShield_ConnectionLimit(t *testing.T) {
+	shield := NewShield(ShieldConfig{MaxConnsPerIP: 1}, nil)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := shield.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan int)
+	go func() { done <- shieldRequest(handler, "192.0.2.1:4000") }()
+	<-entered
+
+	assert.Equal(t, http.StatusTooManyRequests, shieldRequest(handler, "192.0.2.1:4001"))
+	go func() { done <- shieldRequest(handler, "192.0.2.2:4000") }()
+	<-entered
+	release <- struct{}{}
+	release <- struct{}{}
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	// The slot is free again
+	go func() { done <- shieldRequest(handler, "192.0.2.1:4002") }()
+	<-entered
+	release <- struct{}{}
+	assert.Equal(t, http.StatusOK, <-done)
+}
+
+func TestShield_Greylist(t *testing.T) {
+	now := time.Now()
+	var delays []time.Duration
+	shield := NewShield(ShieldConfig{
+		GreylistThreshold: 3,
+		GreylistDelay:     time.Second,
+		GreylistMaxDelay:  5 * time.Second,
+		GreylistTTL:       time.Minute,
+	}, nil)
+	shield.now = func() time.Time { return now }
+	shield.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	handler := shield.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}))
+
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, http.StatusUnauthorized, shieldRequest(handler, "192.0.2.1:4000"))
+	}
+	// Delays start after the third failure and double up to the maximum
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, delays)
+	assert.Equal(t, 1, shield.Greylisted())
+
+	// Other clients are not slowed down
+	delays = nil
+	assert.Equal(t, http.StatusUnauthorized, shieldRequest(handler, "192.0.2.2:4000"))
+	assert.Empty(t, delays)
+	assert.Equal(t, http.StatusUnauthorized, shieldRequest(handler, "192.0.2.1:4000"))
+	assert.Equal(t, []time.Duration{5 * time.Second}, delays)
+
+	// The greylist expires a TTL after the last failure
+	now = now.Add(time.Minute)
+	delays = nil
+	assert.Equal(t, http.StatusUnauthorized, shieldRequest(handler, "192.0.2.1:4000"))
+	assert.Empty(t, delays)
+	assert.Equal(t, 0, shield.Greylisted())
+}
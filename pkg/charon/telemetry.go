@@ -1,11 +1,25 @@
 package charon
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
 
+// upstreamLatencyMetric is the latency of each shore by route class, with
+// buckets fine enough below a second to track latency SLOs.
+const upstreamLatencyMetric = "charon_upstream_latency_seconds"
+
+var upstreamLatencyBuckets = []float64{
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.15, 0.25, 0.35,
+	0.5, 0.75, 1, 1.5, 2.5, 5, 10, 30, 60,
+}
+
+// upstreamLatencyNativeFactor bounds the relative error of percentiles read
+// from the native histogram to about 5%.
+const upstreamLatencyNativeFactor = 1.1
+
 // Telemetry tracks and exports metrics for the Charon ferry.
 type Telemetry struct {
 	metrics hermes.Metrics
@@ -13,13 +27,19 @@ type Telemetry struct {
 
 // NewTelemetry creates a new telemetry exporter.
 func NewTelemetry(metrics hermes.Metrics) *Telemetry {
+	if shaper, ok := metrics.(hermes.HistogramShaper); ok {
+		shaper.ShapeHistogram(upstreamLatencyMetric, hermes.HistogramOpts{
+			Buckets:            upstreamLatencyBuckets,
+			NativeBucketFactor: upstreamLatencyNativeFactor,
+		})
+	}
 	return &Telemetry{
 		metrics: metrics,
 	}
 }
 
-// RecordRequest records a request to a backend shore.
-func (t *Telemetry) RecordRequest(shoreID string, success bool, duration time.Duration) {
+// RecordRequest records a request to a backend shore on a route class.
+func (t *Telemetry) RecordRequest(shoreID, routeClass string, success bool, duration time.Duration) {
 	if t.metrics == nil {
 		return
 	}
@@ -39,6 +59,13 @@ func (t *Telemetry) RecordRequest(shoreID string, success bool, duration time.Du
 	t.metrics.ObserveHistogram("charon_request_duration_seconds", duration.Seconds(),
 		hermes.Label{Key: "shore_id", Value: shoreID},
 	)
+
+	// Histogram: Latency by shore and route class, for SLO percentiles
+	t.metrics.ObserveHistogram(upstreamLatencyMetric, duration.Seconds(),
+		hermes.Label{Key: "shore_id", Value: shoreID},
+		hermes.Label{Key: "route_class", Value: routeClass},
+		hermes.Label{Key: "status", Value: status},
+	)
 }
 
 // RecordCircuitBreakerState records the state of a circuit breaker.
@@ -60,6 +87,30 @@ func (t *Telemetry) RecordCircuitBreakerState(shoreID string, state CircuitBreak
 	}
 }
 
+// RecordCircuitBreakerOpened records a circuit breaker opening after
+// serving normally.
+func (t *Telemetry) RecordCircuitBreakerOpened(shoreID string) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.IncCounter("charon_circuit_breaker_opens_total", 1,
+		hermes.Label{Key: "shore_id", Value: shoreID},
+	)
+}
+
+// RecordCircuitBreakerClosed records a circuit breaker closing again and
+// how long it kept the shore out of service.
+func (t *Telemetry) RecordCircuitBreakerClosed(shoreID string, openFor time.Duration) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.IncCounter("charon_circuit_breaker_open_seconds_total", openFor.Seconds(),
+		hermes.Label{Key: "shore_id", Value: shoreID},
+	)
+}
+
 // RecordActiveConnections records the current number of active connections.
 func (t *Telemetry) RecordActiveConnections(shoreID string, count int) {
 	if t.metrics == nil {
@@ -71,6 +122,50 @@ func (t *Telemetry) RecordActiveConnections(shoreID string, count int) {
 	)
 }
 
+// RecordConnection records an upstream connection acquisition and the
+// running connection reuse ratio for the shore.
+func (t *Telemetry) RecordConnection(shoreID string, reused bool, reuseRatio float64) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.IncCounter("charon_upstream_connections_total", 1,
+		hermes.Label{Key: "shore_id", Value: shoreID},
+		hermes.Label{Key: "reused", Value: strconv.FormatBool(reused)},
+	)
+
+	t.metrics.SetGauge("charon_connection_reuse_ratio", reuseRatio,
+		hermes.Label{Key: "shore_id", Value: shoreID},
+	)
+}
+
+// RecordAffinityCookie records an affinity cookie being issued, rotated or
+// cleared on a response served by the shore.
+func (t *Telemetry) RecordAffinityCookie(shoreID, action string) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.IncCounter("charon_affinity_cookies_total", 1,
+		hermes.Label{Key: "shore_id", Value: shoreID},
+		hermes.Label{Key: "action", Value: action},
+	)
+}
+
+// RecordHedge records the outcome of a hedged request and the delay after
+// which the second shore was tried.
+func (t *Telemetry) RecordHedge(result string, delay time.Duration) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.IncCounter("charon_hedged_requests_total", 1,
+		hermes.Label{Key: "result", Value: result},
+	)
+
+	t.metrics.ObserveHistogram("charon_hedge_delay_seconds", delay.Seconds())
+}
+
 // RecordHealthCheck records the result of a health check.
 func (t *Telemetry) RecordHealthCheck(shoreID string, success bool, latency time.Duration) {
 	if t.metrics == nil {
@@ -116,7 +211,7 @@ func (t *Telemetry) RecordShoreHealth(shoreID string, status HealthStatus) {
 }
 
 // RecordRateLimitHit records when a request is rate limited.
-func (t *Telemetry) RecordRateLimitHit(key string) {
+func (t *Telemetry) RecordRateLimitHit(key, routeClass string) {
 	if t.metrics == nil {
 		return
 	}
@@ -124,6 +219,23 @@ func (t *Telemetry) RecordRateLimitHit(key string) {
 	t.metrics.IncCounter("charon_rate_limit_hits_total", 1,
 		hermes.Label{Key: "key", Value: key},
 	)
+
+	t.metrics.IncCounter("charon_rate_limited_requests_total", 1,
+		hermes.Label{Key: "route_class", Value: routeClass},
+	)
+}
+
+// RecordConfigReload records a configuration reload and the number of
+// shores registered after it.
+func (t *Telemetry) RecordConfigReload(result string, shores int) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.IncCounter("charon_config_reloads_total", 1,
+		hermes.Label{Key: "result", Value: result},
+	)
+	t.metrics.SetGauge("charon_shores", float64(shores))
 }
 
 // NoOpTelemetry is a telemetry implementation that does nothing.
@@ -134,10 +246,14 @@ func NewNoOpTelemetry() *NoOpTelemetry {
 	return &NoOpTelemetry{}
 }
 
-func (t *NoOpTelemetry) RecordRequest(shoreID string, success bool, duration time.Duration)  {}
+func (t *NoOpTelemetry) RecordRequest(shoreID, routeClass string, success bool, duration time.Duration) {
+}
 func (t *NoOpTelemetry) RecordCircuitBreakerState(shoreID string, state CircuitBreakerState) {}
+func (t *NoOpTelemetry) RecordCircuitBreakerOpened(shoreID string)                           {}
+func (t *NoOpTelemetry) RecordCircuitBreakerClosed(shoreID string, openFor time.Duration)    {}
 func (t *NoOpTelemetry) RecordActiveConnections(shoreID string, count int)                   {}
+func (t *NoOpTelemetry) RecordConnection(shoreID string, reused bool, reuseRatio float64)    {}
 func (t *NoOpTelemetry) RecordHealthCheck(shoreID string, success bool, latency time.Duration) {
 }
 func (t *NoOpTelemetry) RecordShoreHealth(shoreID string, status HealthStatus) {}
-func (t *NoOpTelemetry) RecordRateLimitHit(key string)                         {}
+func (t *NoOpTelemetry) RecordRateLimitHit(key, routeClass string)             {}
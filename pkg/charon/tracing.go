@@ -0,0 +1,193 @@
+package charon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader carries the ID of a request across services.
+	RequestIDHeader = "X-Request-ID"
+	// TraceparentHeader and TracestateHeader carry W3C trace context.
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+
+	maxRequestIDLength = 128
+)
+
+// TraceContext is a parsed W3C traceparent header.
+type TraceContext struct {
+	TraceID  string // 32 lowercase hex characters
+	ParentID string // 16 lowercase hex characters: the span of the sender
+	Flags    string // 2 hex characters; "01" means sampled
+}
+
+// ParseTraceparent parses a version 00 traceparent header. Headers with a
+// higher version are accepted if they start with a valid version 00 prefix,
+// as the specification requires.
+func ParseTraceparent(header string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || !isLowerHex(parts[0]) || parts[0] == "ff" {
+		return TraceContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return TraceContext{}, false
+	}
+	tc := TraceContext{TraceID: parts[1], ParentID: parts[2], Flags: parts[3]}
+	if len(tc.TraceID) != 32 || !isLowerHex(tc.TraceID) || tc.TraceID == strings.Repeat("0", 32) {
+		return TraceContext{}, false
+	}
+	if len(tc.ParentID) != 16 || !isLowerHex(tc.ParentID) || tc.ParentID == strings.Repeat("0", 16) {
+		return TraceContext{}, false
+	}
+	if len(tc.Flags) != 2 || !isLowerHex(tc.Flags) {
+		return TraceContext{}, false
+	}
+	return tc, true
+}
+
+// String formats the trace context as a version 00 traceparent header.
+func (tc TraceContext) String() string {
+	return "00-" + tc.TraceID + "-" + tc.ParentID + "-" + tc.Flags
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether a client-supplied request ID can be kept:
+// short and printable ASCII, so it is safe in headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFromContext returns the request ID set by TraceMiddleware.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value("request_id").(string)
+	return id
+}
+
+// TraceIDFromContext returns the W3C trace ID set by TraceMiddleware.
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value("trace_id").(string)
+	return id
+}
+
+// TraceMiddleware makes every request traceable across services. It keeps
+// the client's X-Request-ID or generates one, continues the client's W3C
+// trace or starts a new one, and forwards both to shores with Charon's own
+// span as the parent. The request ID is returned to the client, and every
+// request is written to the access log with its IDs.
+type TraceMiddleware struct {
+	logger *slog.Logger
+}
+
+// NewTraceMiddleware creates a trace middleware. A nil logger disables
+// access logs.
+func NewTraceMiddleware(logger *slog.Logger) *TraceMiddleware {
+	return &TraceMiddleware{logger: logger}
+}
+
+// Handler returns an HTTP handler that traces requests before passing them
+// to next.
+func (m *TraceMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		tc, ok := ParseTraceparent(r.Header.Get(TraceparentHeader))
+		if !ok {
+			// tracestate is meaningless without a valid traceparent
+			r.Header.Del(TracestateHeader)
+			tc = TraceContext{TraceID: randomHex(16), Flags: "01"}
+		}
+		clientSpan := tc.ParentID
+		tc.ParentID = randomHex(8)
+
+		r.Header.Set(RequestIDHeader, requestID)
+		r.Header.Set(TraceparentHeader, tc.String())
+
+		ctx := context.WithValue(r.Context(), "request_id", requestID)
+		ctx = context.WithValue(ctx, "trace_id", tc.TraceID)
+		ctx = context.WithValue(ctx, "span_id", tc.ParentID)
+
+		rw := &tracedResponseWriter{ResponseWriter: w, requestID: requestID}
+		start := time.Now()
+		next.ServeHTTP(rw, r.WithContext(ctx))
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		if m.logger != nil {
+			m.logger.Info("Request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rw.status,
+				"duration", time.Since(start),
+				"remote_addr", r.RemoteAddr,
+				"request_id", requestID,
+				"trace_id", tc.TraceID,
+				"span_id", tc.ParentID,
+				"parent_span_id", clientSpan,
+			)
+		}
+	})
+}
+
+// tracedResponseWriter records the status for the access log and makes
+// sure the response carries Charon's request ID, even when a shore
+// returned its own.
+type tracedResponseWriter struct {
+	http.ResponseWriter
+	requestID string
+	status    int
+}
+
+func (w *tracedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.Header().Set(RequestIDHeader, w.requestID)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *tracedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *tracedResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,122 @@
+package charon
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", tc.ParentID)
+	assert.Equal(t, "01", tc.Flags)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.String())
+
+	// Future versions may append fields
+	_, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.True(t, ok)
+
+	for _, header := range []string{
+		"",
+		"garbage",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		_, ok := ParseTraceparent(header)
+		assert.False(t, ok, header)
+	}
+}
+
+func TestTraceMiddleware_Propagates(t *testing.T) {
+	var forwarded http.Header
+	shore := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+		w.Header().Set(RequestIDHeader, "shore-generated")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer shore.Close()
+
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-1", Address: shore.URL}))
+
+	var logs bytes.Buffer
+	handler := NewTraceMiddleware(slog.New(slog.NewJSONHandler(&logs, nil))).Handler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, err := ferry.Cross(r.Context(), r)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			for key, values := range resp.Header {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+			w.WriteHeader(resp.StatusCode)
+		}))
+
+	t.Run("continues client trace", func(t *testing.T) {
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/sandboxes", nil)
+		req.Header.Set(RequestIDHeader, "client-req-1")
+		req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req.Header.Set(TracestateHeader, "vendor=abc")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "client-req-1", forwarded.Get(RequestIDHeader))
+		tc, ok := ParseTraceparent(forwarded.Get(TraceparentHeader))
+		require.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+		assert.NotEqual(t, "00f067aa0ba902b7", tc.ParentID, "Charon forwards its own span")
+		assert.Equal(t, "01", tc.Flags)
+		assert.Equal(t, "vendor=abc", forwarded.Get(TracestateHeader))
+
+		assert.Equal(t, []string{"client-req-1"}, rec.Header().Values(RequestIDHeader))
+		assert.Contains(t, logs.String(), `"request_id":"client-req-1"`)
+		assert.Contains(t, logs.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+		assert.Contains(t, logs.String(), `"parent_span_id":"00f067aa0ba902b7"`)
+	})
+
+	t.Run("starts trace and request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/sandboxes", nil)
+		req.Header.Set(RequestIDHeader, strings.Repeat("x", maxRequestIDLength+1))
+		req.Header.Set(TraceparentHeader, "not-a-traceparent")
+		req.Header.Set(TracestateHeader, "vendor=abc")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		requestID := forwarded.Get(RequestIDHeader)
+		assert.Len(t, requestID, 36)
+		_, ok := ParseTraceparent(forwarded.Get(TraceparentHeader))
+		assert.True(t, ok)
+		assert.Empty(t, forwarded.Get(TracestateHeader))
+		assert.Equal(t, requestID, rec.Header().Get(RequestIDHeader))
+	})
+}
+
+func TestTraceMiddleware_RejectedRequestsCarryID(t *testing.T) {
+	handler := NewTraceMiddleware(nil).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, RequestIDFromContext(r.Context()))
+		assert.NotEmpty(t, TraceIDFromContext(r.Context()))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(RequestIDHeader))
+}
@@ -0,0 +1,126 @@
+package charon
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// TransportConfig tunes the upstream connection pool used to reach a shore.
+// Zero values fall back to the defaults from DefaultTransportConfig.
+type TransportConfig struct {
+	MaxIdleConns        int           // Idle connections kept across all hosts
+	MaxIdleConnsPerHost int           // Idle connections kept per shore
+	MaxConnsPerHost     int           // Hard cap on connections per shore (0 = unlimited)
+	IdleConnTimeout     time.Duration // How long an idle connection is kept in the pool
+	DialTimeout         time.Duration // TCP connect timeout
+	KeepAlive           time.Duration // TCP keep-alive probe interval
+	TLSHandshakeTimeout time.Duration // TLS handshake timeout
+	TLSSessionCacheSize int           // Client session cache size for TLS resumption (0 = disabled)
+	EnableHTTP2         bool          // Attempt HTTP/2 when the shore supports it
+	DisableKeepAlives   bool          // Force a new connection per request
+}
+
+// DefaultTransportConfig returns pool settings sized for proxying to a
+// small number of Olympus shores under sustained load.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        512,
+		MaxIdleConnsPerHost: 128,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         5 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSSessionCacheSize: 64,
+		EnableHTTP2:         true,
+	}
+}
+
+// withDefaults fills zero-valued fields from DefaultTransportConfig.
+func (c TransportConfig) withDefaults() TransportConfig {
+	def := DefaultTransportConfig()
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = def.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = def.MaxIdleConnsPerHost
+	}
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = def.IdleConnTimeout
+	}
+	if c.DialTimeout == 0 {
+		c.DialTimeout = def.DialTimeout
+	}
+	if c.KeepAlive == 0 {
+		c.KeepAlive = def.KeepAlive
+	}
+	if c.TLSHandshakeTimeout == 0 {
+		c.TLSHandshakeTimeout = def.TLSHandshakeTimeout
+	}
+	return c
+}
+
+// NewShoreTransport builds an http.Transport from the given configuration.
+func NewShoreTransport(cfg TransportConfig) *http.Transport {
+	cfg = cfg.withDefaults()
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	tlsConfig := &tls.Config{}
+	if cfg.TLSSessionCacheSize > 0 {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(cfg.TLSSessionCacheSize)
+	}
+
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   cfg.EnableHTTP2,
+		DisableKeepAlives:   cfg.DisableKeepAlives,
+	}
+}
+
+// connStats counts upstream connections handed out for a shore so the
+// reuse ratio can be reported.
+type connStats struct {
+	total  uint64
+	reused uint64
+}
+
+func (s *connStats) record(reused bool) {
+	atomic.AddUint64(&s.total, 1)
+	if reused {
+		atomic.AddUint64(&s.reused, 1)
+	}
+}
+
+// ratio returns the fraction of connections that were reused from the pool.
+func (s *connStats) ratio() float64 {
+	total := atomic.LoadUint64(&s.total)
+	if total == 0 {
+		return 0
+	}
+	return float64(atomic.LoadUint64(&s.reused)) / float64(total)
+}
+
+// connTrace returns a client trace that records whether each upstream
+// connection was freshly dialed or taken from the idle pool.
+func (f *BoatFerry) connTrace(shoreID string, stats *connStats) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			stats.record(info.Reused)
+			f.telemetry.RecordConnection(shoreID, info.Reused, stats.ratio())
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package charon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewShoreTransport_AppliesConfig(t *testing.T) {
+	transport := NewShoreTransport(TransportConfig{
+		MaxIdleConnsPerHost: 7,
+		MaxConnsPerHost:     20,
+		IdleConnTimeout:     15 * time.Second,
+		TLSSessionCacheSize: 8,
+		EnableHTTP2:         true,
+	})
+
+	assert.Equal(t, 7, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 20, transport.MaxConnsPerHost)
+	assert.Equal(t, 15*time.Second, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSClientConfig.ClientSessionCache)
+
+	// Unset fields fall back to defaults
+	def := DefaultTransportConfig()
+	assert.Equal(t, def.MaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, def.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+}
+
+func TestNewShoreTransport_SessionCacheDisabled(t *testing.T) {
+	transport := NewShoreTransport(TransportConfig{})
+	assert.Nil(t, transport.TLSClientConfig.ClientSessionCache)
+}
+
+func TestBoatFerry_ConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	metrics := NewMockMetrics()
+	config := DefaultFerryConfig()
+	config.RateLimiting.Enabled = false
+	config.Metrics = metrics
+
+	ferry, err := NewBoatFerry(config)
+	require.NoError(t, err)
+
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-1", Address: server.URL}))
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		resp, err := ferry.Cross(context.Background(), req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	health, err := ferry.Health(context.Background())
+	require.NoError(t, err)
+	require.Len(t, health.Shores, 1)
+	assert.InDelta(t, 0.8, health.Shores[0].ConnReuse, 0.001)
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	assert.Equal(t, 1.0, metrics.counters["charon_upstream_connections_total|shore_id=shore-1|reused=false"])
+	assert.Equal(t, 4.0, metrics.counters["charon_upstream_connections_total|shore_id=shore-1|reused=true"])
+}
+
+func TestBoatFerry_PerShoreTransportOverride(t *testing.T) {
+	ferry, err := NewBoatFerry(DefaultFerryConfig())
+	require.NoError(t, err)
+
+	require.NoError(t, ferry.RegisterShore(&Shore{
+		ID:        "shore-1",
+		Address:   "http://localhost:1",
+		Transport: &TransportConfig{MaxIdleConnsPerHost: 3, DisableKeepAlives: true},
+	}))
+	require.NoError(t, ferry.RegisterShore(&Shore{ID: "shore-2", Address: "http://localhost:2"}))
+
+	assert.Equal(t, 3, ferry.transports["shore-1"].MaxIdleConnsPerHost)
+	assert.True(t, ferry.transports["shore-1"].DisableKeepAlives)
+	assert.Equal(t, DefaultTransportConfig().MaxIdleConnsPerHost, ferry.transports["shore-2"].MaxIdleConnsPerHost)
+
+	require.NoError(t, ferry.DeregisterShore("shore-1"))
+	assert.NotContains(t, ferry.transports, "shore-1")
+	assert.NotContains(t, ferry.connStats, "shore-1")
+}
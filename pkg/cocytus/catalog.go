@@ -0,0 +1,251 @@
+package cocytus
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// DefaultSuppressThreshold is the number of identical failures after which a
+// fingerprint is treated as a poison pill.
+const DefaultSuppressThreshold = 3
+
+// FingerprintSummary groups the dead letters sharing a fingerprint.
+type FingerprintSummary struct {
+	Fingerprint string            `json:"fingerprint"`
+	Class       FailureClass      `json:"class"`
+	Template    domain.TemplateID `json:"template,omitempty"`
+	Reason      string            `json:"reason"` // First reason seen
+	Count       int64             `json:"count"`
+	FirstSeen   time.Time         `json:"first_seen"`
+	LastSeen    time.Time         `json:"last_seen"`
+	Suppressed  bool              `json:"suppressed"`
+}
+
+// Catalog counts dead letters by fingerprint.
+type Catalog interface {
+	// Observe records an annotated dead letter.
+	Observe(ctx context.Context, rec *Record) error
+	// Count returns how many times the fingerprint has been observed.
+	Count(ctx context.Context, fingerprint string) (int64, error)
+	// Summary returns all fingerprints in no particular order.
+	Summary(ctx context.Context) ([]FingerprintSummary, error)
+}
+
+// Suppressor is implemented by sinks that can tell whether a failure is a
+// known poison pill that should not be re-driven.
+type Suppressor interface {
+	Suppressed(ctx context.Context, fingerprint string) bool
+}
+
+// ClassifyingSink annotates dead letters, records them in a Catalog and
+// forwards them to the next sink.
+type ClassifyingSink struct {
+	next      Sink
+	catalog   Catalog
+	threshold int64
+}
+
+// NewClassifyingSink wraps next. Fingerprints seen at least threshold times
+// are suppressed; a threshold of 0 disables suppression.
+func NewClassifyingSink(next Sink, catalog Catalog, threshold int) *ClassifyingSink {
+	return &ClassifyingSink{
+		next:      next,
+		catalog:   catalog,
+		threshold: int64(threshold),
+	}
+}
+
+// Write annotates and catalogs the record, then forwards it.
+func (s *ClassifyingSink) Write(ctx context.Context, rec *Record) error {
+	Annotate(rec)
+	catalogErr := s.catalog.Observe(ctx, rec)
+	if s.next != nil {
+		if err := s.next.Write(ctx, rec); err != nil {
+			return err
+		}
+	}
+	return catalogErr
+}
+
+// Suppressed reports whether the fingerprint has reached the threshold.
+func (s *ClassifyingSink) Suppressed(ctx context.Context, fingerprint string) bool {
+	if s.threshold <= 0 || fingerprint == "" {
+		return false
+	}
+	count, err := s.catalog.Count(ctx, fingerprint)
+	return err == nil && count >= s.threshold
+}
+
+// Summarize returns the catalog summary, most frequent first, flagging
+// fingerprints that have reached threshold as suppressed.
+func Summarize(ctx context.Context, catalog Catalog, threshold int) ([]FingerprintSummary, error) {
+	summaries, err := catalog.Summary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		summaries[i].Suppressed = threshold > 0 && summaries[i].Count >= int64(threshold)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		return summaries[i].LastSeen.After(summaries[j].LastSeen)
+	})
+	return summaries, nil
+}
+
+// MemoryCatalog keeps fingerprints in process memory.
+type MemoryCatalog struct {
+	mu      sync.Mutex
+	entries map[string]*FingerprintSummary
+}
+
+func NewMemoryCatalog() *MemoryCatalog {
+	return &MemoryCatalog{entries: make(map[string]*FingerprintSummary)}
+}
+
+func (c *MemoryCatalog) Observe(ctx context.Context, rec *Record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := rec.CreatedAt
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+	entry, ok := c.entries[rec.Fingerprint]
+	if !ok {
+		entry = &FingerprintSummary{
+			Fingerprint: rec.Fingerprint,
+			Class:       rec.Class,
+			Template:    rec.Template,
+			Reason:      rec.Reason,
+			FirstSeen:   seen,
+		}
+		c.entries[rec.Fingerprint] = entry
+	}
+	entry.Count++
+	entry.LastSeen = seen
+	return nil
+}
+
+func (c *MemoryCatalog) Count(ctx context.Context, fingerprint string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[fingerprint]; ok {
+		return entry.Count, nil
+	}
+	return 0, nil
+}
+
+func (c *MemoryCatalog) Summary(ctx context.Context) ([]FingerprintSummary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summaries := make([]FingerprintSummary, 0, len(c.entries))
+	for _, entry := range c.entries {
+		summaries = append(summaries, *entry)
+	}
+	return summaries, nil
+}
+
+// RedisCatalog shares fingerprints between agents and Olympus. Each
+// fingerprint is a hash that expires after TTL without new failures.
+type RedisCatalog struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisCatalog stores fingerprints under prefix (default "tartarus:cocytus").
+func NewRedisCatalog(client *redis.Client, prefix string, ttl time.Duration) *RedisCatalog {
+	if prefix == "" {
+		prefix = "tartarus:cocytus"
+	}
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	return &RedisCatalog{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (c *RedisCatalog) key(fingerprint string) string {
+	return c.prefix + ":fp:" + fingerprint
+}
+
+func (c *RedisCatalog) indexKey() string {
+	return c.prefix + ":fingerprints"
+}
+
+func (c *RedisCatalog) Observe(ctx context.Context, rec *Record) error {
+	seen := rec.CreatedAt
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+	key := c.key(rec.Fingerprint)
+	ts := strconv.FormatInt(seen.UnixNano(), 10)
+
+	pipe := c.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, "count", 1)
+	pipe.HSetNX(ctx, key, "class", string(rec.Class))
+	pipe.HSetNX(ctx, key, "template", string(rec.Template))
+	pipe.HSetNX(ctx, key, "reason", rec.Reason)
+	pipe.HSetNX(ctx, key, "first_seen", ts)
+	pipe.HSet(ctx, key, "last_seen", ts)
+	pipe.Expire(ctx, key, c.ttl)
+	pipe.SAdd(ctx, c.indexKey(), rec.Fingerprint)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisCatalog) Count(ctx context.Context, fingerprint string) (int64, error) {
+	count, err := c.client.HGet(ctx, c.key(fingerprint), "count").Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return count, err
+}
+
+func (c *RedisCatalog) Summary(ctx context.Context) ([]FingerprintSummary, error) {
+	fingerprints, err := c.client.SMembers(ctx, c.indexKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]FingerprintSummary, 0, len(fingerprints))
+	for _, fp := range fingerprints {
+		fields, err := c.client.HGetAll(ctx, c.key(fp)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			// Expired; drop it from the index
+			c.client.SRem(ctx, c.indexKey(), fp)
+			continue
+		}
+		count, _ := strconv.ParseInt(fields["count"], 10, 64)
+		summaries = append(summaries, FingerprintSummary{
+			Fingerprint: fp,
+			Class:       FailureClass(fields["class"]),
+			Template:    domain.TemplateID(fields["template"]),
+			Reason:      fields["reason"],
+			Count:       count,
+			FirstSeen:   parseNanos(fields["first_seen"]),
+			LastSeen:    parseNanos(fields["last_seen"]),
+		})
+	}
+	return summaries, nil
+}
+
+func parseNanos(s string) time.Time {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,92 @@
+package cocytus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// FailureClass groups dead letters by their likely cause.
+type FailureClass string
+
+const (
+	ClassImagePull  FailureClass = "image_pull"
+	ClassJudgePanic FailureClass = "judge_panic"
+	ClassRuntimeOOM FailureClass = "runtime_oom"
+	ClassTimeout    FailureClass = "timeout"
+	ClassPoisonPill FailureClass = "poison_pill"
+	ClassUnknown    FailureClass = "unknown"
+)
+
+// classRules are checked in order; the first rule with a matching
+// substring wins. Reasons are lower-cased before matching.
+var classRules = []struct {
+	class    FailureClass
+	patterns []string
+}{
+	{ClassPoisonPill, []string{"poison_pill"}},
+	{ClassJudgePanic, []string{"judge panic", "panic:", "panicked"}},
+	{ClassRuntimeOOM, []string{"out of memory", "oom", "exit status 137", "signal: killed", "cannot allocate memory"}},
+	{ClassTimeout, []string{"deadline exceeded", "timeout", "timed out"}},
+	{ClassImagePull, []string{"pull", "manifest unknown", "failed to fetch image", "failed to get snapshot", "image not found", "unauthorized: authentication required"}},
+}
+
+// Classify infers the failure class from a dead-letter reason.
+func Classify(reason string) FailureClass {
+	lower := strings.ToLower(reason)
+	for _, rule := range classRules {
+		for _, p := range rule.patterns {
+			if strings.Contains(lower, p) {
+				return rule.class
+			}
+		}
+	}
+	return ClassUnknown
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	hexPattern    = regexp.MustCompile(`\b[0-9a-f]{8,}\b`)
+	numberPattern = regexp.MustCompile(`\d+`)
+	pathPattern   = regexp.MustCompile(`/(tmp|run|var/run)/[^\s:"']+`)
+)
+
+// NormalizeReason strips identifiers, paths and numbers from a reason so that
+// repeated failures with the same cause produce the same text.
+func NormalizeReason(reason string) string {
+	s := strings.ToLower(strings.TrimSpace(reason))
+	s = uuidPattern.ReplaceAllString(s, "<id>")
+	s = hexPattern.ReplaceAllString(s, "<hex>")
+	s = pathPattern.ReplaceAllString(s, "<path>")
+	s = numberPattern.ReplaceAllString(s, "<n>")
+	return s
+}
+
+// Fingerprint identifies identical failures of the same template.
+func Fingerprint(class FailureClass, template domain.TemplateID, reason string) string {
+	sum := sha256.Sum256([]byte(string(class) + "\x00" + string(template) + "\x00" + NormalizeReason(reason)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Annotate fills in the record's class, template and fingerprint if unset.
+// The template is read from the request payload when available.
+func Annotate(rec *Record) {
+	if rec.Template == "" && len(rec.Payload) > 0 {
+		var req struct {
+			Template domain.TemplateID `json:"template"`
+		}
+		if err := json.Unmarshal(rec.Payload, &req); err == nil {
+			rec.Template = req.Template
+		}
+	}
+	if rec.Class == "" {
+		rec.Class = Classify(rec.Reason)
+	}
+	if rec.Fingerprint == "" {
+		rec.Fingerprint = Fingerprint(rec.Class, rec.Template, rec.Reason)
+	}
+}
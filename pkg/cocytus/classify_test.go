@@ -0,0 +1,109 @@
+package cocytus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   FailureClass
+	}{
+		{"failed to get snapshot: manifest unknown", ClassImagePull},
+		{"judge panic: runtime error: index out of range", ClassJudgePanic},
+		{"firecracker exited: exit status 137", ClassRuntimeOOM},
+		{"Out Of Memory while booting", ClassRuntimeOOM},
+		{"context deadline exceeded", ClassTimeout},
+		{"poison_pill: exceeded max deliveries", ClassPoisonPill},
+		{"launch failed", ClassUnknown},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.reason); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprint_IgnoresVolatileDetails(t *testing.T) {
+	a := Fingerprint(ClassRuntimeOOM, "python", "vm 3f2a9c1e-1111-4222-8333-444455556666 killed after 512MB at /tmp/fc-123/sock")
+	b := Fingerprint(ClassRuntimeOOM, "python", "vm 9d8e7f6a-aaaa-4bbb-8ccc-ddddeeeeffff killed after 256MB at /tmp/fc-987/sock")
+	if a != b {
+		t.Errorf("expected identical fingerprints, got %s and %s", a, b)
+	}
+	if c := Fingerprint(ClassRuntimeOOM, "node", "vm killed"); c == a {
+		t.Error("expected different templates to produce different fingerprints")
+	}
+}
+
+func TestAnnotate_ReadsTemplateFromPayload(t *testing.T) {
+	rec := &Record{Reason: "image pull failed", Payload: []byte(`{"id":"r1","template":"python"}`)}
+	Annotate(rec)
+	if rec.Template != "python" || rec.Class != ClassImagePull || rec.Fingerprint == "" {
+		t.Errorf("unexpected annotation: %+v", rec)
+	}
+}
+
+func TestClassifyingSink_SuppressesAfterThreshold(t *testing.T) {
+	ctx := context.Background()
+	catalog := NewMemoryCatalog()
+	sink := NewClassifyingSink(nil, catalog, 2)
+
+	first := &Record{RequestID: "a", Reason: "launch failed: exit status 137"}
+	if err := sink.Write(ctx, first); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if sink.Suppressed(ctx, first.Fingerprint) {
+		t.Fatal("fingerprint suppressed below threshold")
+	}
+	sink.Write(ctx, &Record{RequestID: "b", Reason: "launch failed: exit status 137"})
+	if !sink.Suppressed(ctx, first.Fingerprint) {
+		t.Fatal("expected fingerprint to be suppressed at threshold")
+	}
+
+	if NewClassifyingSink(nil, catalog, 0).Suppressed(ctx, first.Fingerprint) {
+		t.Error("threshold 0 should disable suppression")
+	}
+}
+
+func TestSummarize_GroupsByFingerprint(t *testing.T) {
+	ctx := context.Background()
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	catalogs := map[string]Catalog{
+		"memory": NewMemoryCatalog(),
+		"redis":  NewRedisCatalog(rdb, "test", time.Hour),
+	}
+	for name, catalog := range catalogs {
+		t.Run(name, func(t *testing.T) {
+			sink := NewClassifyingSink(nil, catalog, 3)
+			for i := 0; i < 3; i++ {
+				sink.Write(ctx, &Record{Reason: "context deadline exceeded", Template: "python", CreatedAt: time.Now()})
+			}
+			sink.Write(ctx, &Record{Reason: "failed to get snapshot", Template: "node", CreatedAt: time.Now()})
+
+			summary, err := Summarize(ctx, catalog, 3)
+			if err != nil {
+				t.Fatalf("Summarize failed: %v", err)
+			}
+			if len(summary) != 2 {
+				t.Fatalf("expected 2 fingerprints, got %d", len(summary))
+			}
+			top := summary[0]
+			if top.Count != 3 || top.Class != ClassTimeout || top.Template != "python" || !top.Suppressed {
+				t.Errorf("unexpected top entry: %+v", top)
+			}
+			if summary[1].Count != 1 || summary[1].Class != ClassImagePull || summary[1].Suppressed {
+				t.Errorf("unexpected second entry: %+v", summary[1])
+			}
+			if top.FirstSeen.IsZero() || top.LastSeen.Before(top.FirstSeen) {
+				t.Errorf("unexpected timestamps: %+v", top)
+			}
+		})
+	}
+}
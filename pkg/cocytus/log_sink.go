@@ -23,6 +23,8 @@ func (s *LogSink) Write(ctx context.Context, rec *Record) error {
 		"run_id", rec.RunID,
 		"request_id", rec.RequestID,
 		"reason", rec.Reason,
+		"class", rec.Class,
+		"fingerprint", rec.Fingerprint,
 		"created_at", rec.CreatedAt,
 		"payload_size", len(rec.Payload),
 	)
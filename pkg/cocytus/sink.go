@@ -10,11 +10,14 @@ import (
 // Record captures failed runs and their lamentations.
 
 type Record struct {
-	RunID     domain.SandboxID `json:"run_id"`
-	RequestID domain.SandboxID `json:"request_id"`
-	Reason    string           `json:"reason"`
-	Payload   []byte           `json:"payload"`
-	CreatedAt time.Time        `json:"created_at"`
+	RunID       domain.SandboxID  `json:"run_id"`
+	RequestID   domain.SandboxID  `json:"request_id"`
+	Template    domain.TemplateID `json:"template,omitempty"`
+	Reason      string            `json:"reason"`
+	Class       FailureClass      `json:"class,omitempty"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	Payload     []byte            `json:"payload"`
+	CreatedAt   time.Time         `json:"created_at"`
 }
 
 // Sink is the interface for Cocytus.
@@ -12,17 +12,50 @@ type Config struct {
 	SnapshotPath string
 	LogLevel     string
 
-	SchedulerStrategy string
+	SchedulerStrategy      string
+	SchedulerAvoidPressure bool   // Skip nodes reporting disk/memory pressure
+	SchedulerLocalityMinMB int    // Input size from which jobs go to nodes holding their inputs (0 = off)
+	SpreadTopologyKey      string // Node label topology-spread spreads templates across
+	SpreadMaxSkew          int    // Most sandboxes of a template a domain may have above the emptiest
+	SchedulerTrace         string // Verbosity of the decision trace kept on each run: off, summary or full
 
 	RedisAddress string
 	RedisDB      int
 	RedisPass    string
 
+	// NATS server for the control plane; when set, commands go over NATS
+	// JetStream instead of Redis pub/sub
+	NATSURL string
+
+	// Hades federation: region name -> Redis address of that region's registry.
+	// When set, Region selects the local region.
+	FederatedRegions map[string]string
+	AllowCrossRegion bool
+
+	// Hades registry in PostgreSQL instead of Redis (empty = Redis or memory)
+	HadesPostgresDSN string
+
+	// Acheron
+	QueueMessageTTL      int      // Seconds a request may wait in the queue before it expires (0 = no default)
+	QueueArchive         bool     // Copy consumed payloads to Erebus for replay
+	QueueArchiveInterval int      // Seconds between archive batches
+	QueuePreemptBatch    bool     // Kill batch sandboxes to make room for high-priority requests
+	KafkaBrokers         []string // Kafka bootstrap brokers; when set, the queue lives in Kafka instead of Redis
+	KafkaTopic           string   // Base topic of the Kafka queue
+
 	S3Endpoint  string
 	S3Region    string
 	S3Bucket    string
 	S3AccessKey string
 	S3SecretKey string
+	StoreTiered bool // Write through to SnapshotPath and S3, read locally first
+
+	LayerZstd               bool // Cache pulled image layers zstd-compressed and recompress existing ones
+	LayerRecompressInterval int  // Seconds between passes recompressing gzip layers in the cache
+
+	OfflineMode          bool   // Agent: resolve images and snapshots from a seed bundle in SnapshotPath, never pulling
+	OfflineSeedPublicKey string // PEM ed25519 public key file the seed manifest must be signed with
+	OfflineVerifyDigests bool   // Hash every seed bundle object at startup, not only check it exists
 
 	AllowedNetworks []string
 
@@ -30,6 +63,11 @@ type Config struct {
 	EnableHypnos bool
 	// Thanatos (Graceful Termination) is always enabled
 
+	// External license API consulted by the entitlement judge (policy grants only when unset)
+	EntitlementAPIURL   string
+	EntitlementAPIToken string
+	EntitlementCacheTTL int // Seconds license API answers are cached
+
 	// Cerberus Auth Config
 	OIDCClientID   string
 	OIDCIssuerURL  string
@@ -39,6 +77,41 @@ type Config struct {
 	TLSClientAuth  string // "none", "request", "require", "verify-if-given", "require-verify"
 	TLSCAFile      string
 
+	// Rego authorization (replaces RBAC when OPABundle is set)
+	OPABundle       string // Bundle directory, .tar.gz file, or bundle server URL
+	OPAQuery        string // Decision to evaluate per request
+	OPABundleToken  string // Bearer token for the bundle server
+	OPAPollInterval int    // Seconds between checks of the bundle for changes (0 = never)
+
+	// Client certificate extensions copied into mTLS identity attributes (OID -> attribute name)
+	MTLSExtensionAttributes map[string]string
+
+	// Identities verified by Charon at the edge, forwarded as signed tokens
+	TrustedProxyKeyID string // Signing key shared with Charon's CHARON_IDENTITY_KEY_ID; empty disables
+
+	// Paths (or path.Match patterns) readable with GET without credentials, as the anonymous identity
+	AnonymousPaths []string
+
+	// OIDC browser login (/auth/login, /auth/callback, /auth/logout)
+	OIDCClientSecret       string
+	OIDCRedirectURL        string   // Enables the login endpoints when set
+	OIDCScopes             []string // nil = openid, profile, email
+	OIDCPostLogoutRedirect string
+	OIDCInsecureCookies    bool // Session cookies without the Secure flag (plain-HTTP development)
+
+	// Per-identity session limits (a session is one token used from one source IP)
+	SessionMaxPerIdentity int    // Concurrent sessions per identity (0 = unlimited)
+	TokenMaxPerIdentity   int    // Concurrent distinct tokens per identity (0 = unlimited)
+	SessionIdleTimeout    int    // Seconds after which an unused session ends
+	SessionLimitMode      string // "reject" new sessions or "evict" the oldest
+	SessionTracking       bool   // Record sessions for GET /sessions even without limits
+
+	// Terms of service gate (disabled when TermsVersion is empty)
+	TermsVersion       string
+	TermsURL           string
+	TermsIdentityTypes []string // Identity types that must accept (nil = user)
+	TermsDenyStatus    int      // 451 or 403
+
 	// Secrets Management
 	VaultAddress   string
 	VaultToken     string
@@ -51,8 +124,160 @@ type Config struct {
 	WasmEngine        string // "wazero" (future: "wasmtime", "wasmer")
 	GVisorRunscPath   string // Path to runsc binary
 
+	// Firecracker jailer; each VM runs chrooted as a UID/GID from
+	// [FirecrackerJailerUID, +FirecrackerJailerIDCount)
+	FirecrackerJailer        bool   // Run Firecracker VMMs under the jailer
+	FirecrackerJailerBinary  string // Path to the jailer binary
+	FirecrackerBinary        string // Path to the firecracker binary the jailer execs
+	FirecrackerChrootBase    string // Directory holding the VMs' chroots
+	FirecrackerJailerUID     int
+	FirecrackerJailerGID     int
+	FirecrackerJailerIDCount int    // UIDs/GIDs handed out one per VM (0 = all VMs share one)
+	FirecrackerJailerNetNS   string // Network namespace path the VMMs join (empty = the host's)
+	FirecrackerNewPIDNS      bool   // Start each VMM in a new PID namespace
+
+	// Guest agent, reached over vsock, for exec, readiness and guest metrics
+	GuestAgent             bool   // Start the guest agent in Firecracker VMs
+	GuestAgentPort         int    // vsock port it listens on
+	GuestAgentReadyTimeout int    // Seconds a launch waits for it to answer (0 = does not wait)
+	GuestAgentBinary       string // Host path of the binary Erebus installs in each rootfs
+
 	// Erebus Configuration
-	InitBinaryPath string // Path to the init binary for OCI images
+	InitBinaryPath  string            // Path to the init binary for OCI images
+	InitBinaryPaths map[string]string // Per-architecture init binaries (arch -> path)
+	InitSmokeTest   bool              // Exec-check assembled rootfs images
+
+	// Agent Heartbeat
+	HeartbeatInterval         int     // Seconds between agent heartbeats
+	HeartbeatIncludeSandboxes bool    // Report active sandboxes in heartbeats
+	HeartbeatConditions       bool    // Report node conditions in heartbeats
+	HeartbeatDiskPath         string  // Filesystem checked for disk pressure
+	DiskPressureThreshold     float64 // Used-disk percentage that signals pressure
+	MemoryPressureThreshold   float64 // Used-memory percentage that signals pressure
+
+	// Sandbox process limits, where the policy sets none
+	SandboxMaxPIDs      int    // Processes and threads per sandbox (0 = unlimited)
+	SandboxMaxOpenFiles int    // Open files per sandbox process (0 = runtime default)
+	SandboxCoreDumps    string // "disabled" or "enabled"
+
+	// Agent cgroup v2 slices
+	CgroupRoot            string // Parent cgroup for the agent and sandbox slices; empty disables
+	CgroupHostReserveCPU  int    // MilliCPU sandboxes may never use, kept for the host and agent
+	CgroupHostReserveMem  int    // MB sandboxes may never use, kept for the host and agent
+	CgroupAgentReserveMem int    // MB of memory guaranteed to the agent
+
+	// Hermes metrics cardinality guard
+	MetricsAllowedLabels []string // Label keys emitted verbatim (nil = hermes defaults)
+	MetricsHashedLabels  []string // Label keys whose values are hashed into buckets (nil = hermes defaults)
+	MetricsMaxSeries     int      // Series per metric before new ones are dropped (-1 = unlimited)
+
+	// Run results
+	ResultTailBytes int // Bytes of stdout/stderr kept in finished run records
+
+	// Erinyes integrity monitoring
+	IntegrityCheckInterval int // Seconds between guest file re-verifications when the policy sets none
+
+	// Thanatos crash bundles
+	CrashBundleTimeout int // Seconds a crash bundle may delay a Fury kill
+
+	// Lethe overlays
+	LetheBackend string // "file" copies of snapshot disks, or "overlayfs" over snapshot root directories
+
+	// Host hooks
+	HostHooksDir    string // Directory of hook executables policies may name; empty disables hooks
+	HostHookTimeout int    // Seconds a hook may run when the policy sets no timeout
+
+	// GPU inventory
+	GPUDiscovery  bool   // Report the node's GPUs and assign them to sandboxes
+	NvidiaSMIPath string // nvidia-smi binary the GPUs are read with
+	GPURefresh    int    // Seconds between GPU inventory probes
+
+	// Sandbox swap
+	SandboxSwapMB int // MB of swap the node offers sandboxes whose policy allows it (0 = none)
+
+	// Node taints
+	NodeTaints string // Comma-separated key[=value]:effect taints; only requests tolerating them are placed on the node
+
+	// Extra node labels reported in heartbeats (e.g. zone=us-east-1a), for selectors and topology spread
+	NodeLabels map[string]string
+
+	// Cocytus
+	DeadLetterSuppressThreshold int // Identical failures before re-drives are suppressed (0 = never)
+
+	// Node consolidation
+	ConsolidationEnabled              bool
+	ConsolidationDryRun               bool    // Log consolidation plans without draining nodes
+	ConsolidationUtilizationThreshold float64 // Node utilization (0-1) below which a node is drained
+	ConsolidationMinNodes             int     // Schedulable nodes always kept
+	ConsolidationMaxNodesPerCycle     int     // Nodes drained per scaler tick
+
+	// Scale to zero
+	ScaleToZeroEnabled     bool
+	ScaleToZeroIdleWindow  int    // Seconds without pending or active runs before every node is released
+	ScaleToZeroNodeGroup   string // Node group resized to zero and back (empty = none)
+	ScaleToZeroWakeNodes   int    // Nodes the node group is resized to by the first submission
+	ScaleToZeroWakeTimeout int    // Seconds parked submissions wait for a node before failing
+
+	// Warm pool
+	WarmPoolEnabled    bool
+	WarmPoolHeatLevels []string // Heat levels whose submissions claim pre-warmed sandboxes (nil = cold, warm, hot)
+	WarmPoolMaxPerNode int      // Pre-warmed sandboxes placed on one node at most (0 = no limit)
+
+	// Agent version skew
+	AgentMinVersion   string // Oldest supported agent version (empty = no floor)
+	AgentMaxMinorSkew int    // Minor versions an agent may trail the newest agent (0 = unlimited)
+
+	// Soft delete
+	DeleteRetention int // Seconds a deleted template or policy can be restored before it is purged
+
+	// Hibernation advisor
+	HypnosMemoryCostPerGBHour  float64 // Cost of 1 GiB of resident sandbox memory per hour
+	HypnosStorageCostPerGBHour float64 // Cost of 1 GiB of stored snapshot per hour
+	HypnosMaxWakeLatency       int     // Milliseconds of wake latency above which hibernation is discouraged (0 = no limit)
+
+	// Hibernation memory sharing (agent)
+	HypnosSharedImagesDir string // Node-local dir of memory images shared by identical wakes (empty = private copies)
+	HypnosSharedImageTTL  int    // Seconds an unused shared image is kept
+	HypnosKSM             bool   // Let KSM merge identical pages of sandbox memory
+
+	// Wake pre-warming (Olympus)
+	HypnosPreWake              bool    // Pre-wake hibernated sandboxes whose policy opts in
+	HypnosPreWakeLeadTime      int     // Seconds before a predicted access to wake
+	HypnosPreWakeHitWindow     int     // Seconds after the predicted time an access still counts as a hit
+	HypnosPreWakeMinConfidence float64 // Least confidence worth a speculative wake
+	HypnosPreWakeDailyBudget   int     // Speculative wakes allowed per rolling 24 hours
+
+	// Chargeback rate card for submit-time cost estimates (all rates 0 = no estimates)
+	ChargebackCurrency     string
+	ChargebackCPUCoreHour  float64 // Price of one core per hour
+	ChargebackMemoryGBHour float64 // Price of 1 GiB of memory per hour
+	ChargebackGPUHour      float64 // Price of one GPU per hour
+	ChargebackDefaultTTL   int     // Seconds of run time assumed for requests without a TTL
+
+	// Plugins
+	PluginsDir           string // Directory of judge/fury plugins loaded by Olympus (empty = disabled)
+	PluginReloadInterval int    // Seconds between checks for changed Wasm plugin modules
+
+	// Browser clients (CORS)
+	CORSAllowedOrigins   []string // Origins or patterns allowed to call the API (empty = no cross-origin access)
+	CORSAllowCredentials bool     // Allow cookies and Authorization on cross-origin requests
+	CORSAllowedHeaders   []string // Request headers browsers may send (nil = defaults)
+	CORSExposedHeaders   []string // Response headers scripts may read (nil = defaults)
+	CORSMaxAge           int      // Seconds browsers may cache preflight responses
+
+	// API versioning
+	APILegacySunset string // RFC 3339 time or date after which unversioned routes return 410 (empty = never)
+
+	// API usage analytics
+	UsageFlushInterval int // Seconds between writes of counted API calls to the usage store
+	UsageRetentionDays int // Days a day's API usage is kept in Redis
+
+	// gRPC API
+	GRPCPort        string // Port of the gRPC API (empty = disabled)
+	GRPCGatewayPort string // Port of the REST gateway to the gRPC API (empty = disabled)
+
+	// Nyx golden snapshots
+	GoldenSnapshots bool // Build, validate and publish a template's snapshot when it is registered
 }
 
 func Load() *Config {
@@ -62,17 +287,35 @@ func Load() *Config {
 		SnapshotPath: getEnv("SNAPSHOT_PATH", "/tmp/tartarus/snapshots"),
 		LogLevel:     getEnv("LOG_LEVEL", "INFO"),
 
-		SchedulerStrategy: getEnv("SCHEDULER_STRATEGY", "least-loaded"),
+		SchedulerStrategy:      getEnv("SCHEDULER_STRATEGY", "least-loaded"),
+		SchedulerAvoidPressure: GetEnvBool("SCHEDULER_AVOID_PRESSURE", true),
+		SchedulerLocalityMinMB: GetEnvInt("SCHEDULER_LOCALITY_MIN_MB", 256),
+		SpreadTopologyKey:      getEnv("SPREAD_TOPOLOGY_KEY", "zone"),
+		SpreadMaxSkew:          GetEnvInt("SPREAD_MAX_SKEW", 1),
+		SchedulerTrace:         getEnv("SCHEDULER_TRACE", "summary"),
 
 		RedisAddress: getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisDB:      GetEnvInt("REDIS_DB", 0),
 		RedisPass:    getEnv("REDIS_PASSWORD", ""),
 
+		FederatedRegions: GetEnvMap("HADES_REGIONS"),
+		AllowCrossRegion: GetEnvBool("HADES_ALLOW_CROSS_REGION", true),
+
+		HadesPostgresDSN: getEnv("HADES_POSTGRES_DSN", ""),
+
 		S3Endpoint:  getEnv("S3_ENDPOINT", ""),
 		S3Region:    getEnv("S3_REGION", "us-east-1"),
 		S3Bucket:    getEnv("S3_BUCKET", "tartarus-snapshots"),
 		S3AccessKey: getEnv("AWS_ACCESS_KEY_ID", ""),
 		S3SecretKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		StoreTiered: GetEnvBool("EREBUS_TIERED", false),
+
+		LayerZstd:               GetEnvBool("EREBUS_LAYER_ZSTD", false),
+		LayerRecompressInterval: GetEnvInt("EREBUS_RECOMPRESS_INTERVAL", 3600),
+
+		OfflineMode:          GetEnvBool("OFFLINE_MODE", false),
+		OfflineSeedPublicKey: getEnv("OFFLINE_SEED_PUBLIC_KEY", ""),
+		OfflineVerifyDigests: GetEnvBool("OFFLINE_VERIFY_DIGESTS", true),
 
 		AllowedNetworks: strings.Split(getEnv("ALLOWED_NETWORKS", "no-net,lockdown"), ","),
 
@@ -80,6 +323,10 @@ func Load() *Config {
 		EnableHypnos: GetEnvBool("ENABLE_HYPNOS", true),
 		// Thanatos is now always enabled - no feature flag needed
 
+		EntitlementAPIURL:   getEnv("ENTITLEMENT_API_URL", ""),
+		EntitlementAPIToken: getEnv("ENTITLEMENT_API_TOKEN", ""),
+		EntitlementCacheTTL: GetEnvInt("ENTITLEMENT_CACHE_TTL", 300),
+
 		// Cerberus Auth Config
 		OIDCClientID:   getEnv("OIDC_CLIENT_ID", ""),
 		OIDCIssuerURL:  getEnv("OIDC_ISSUER_URL", ""),
@@ -89,6 +336,37 @@ func Load() *Config {
 		TLSClientAuth:  getEnv("TLS_CLIENT_AUTH", "none"),
 		TLSCAFile:      getEnv("TLS_CA_FILE", ""),
 
+		OPABundle:       getEnv("OPA_BUNDLE", ""),
+		OPAQuery:        getEnv("OPA_QUERY", "data.tartarus.authz.allow"),
+		OPABundleToken:  getEnv("OPA_BUNDLE_TOKEN", ""),
+		OPAPollInterval: GetEnvInt("OPA_POLL_INTERVAL", 30),
+
+		MTLSExtensionAttributes: GetEnvMap("MTLS_EXTENSION_ATTRIBUTES"),
+
+		TrustedProxyKeyID: getEnv("TRUSTED_PROXY_KEY_ID", ""),
+
+		AnonymousPaths: GetEnvList("ANONYMOUS_PATHS"),
+
+		// OIDC browser login
+		OIDCClientSecret:       getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:        getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:             GetEnvList("OIDC_SCOPES"),
+		OIDCPostLogoutRedirect: getEnv("OIDC_POST_LOGOUT_REDIRECT", "/"),
+		OIDCInsecureCookies:    GetEnvBool("OIDC_INSECURE_COOKIES", false),
+
+		// Per-identity session limits
+		SessionMaxPerIdentity: GetEnvInt("SESSION_MAX_PER_IDENTITY", 0),
+		TokenMaxPerIdentity:   GetEnvInt("TOKEN_MAX_PER_IDENTITY", 0),
+		SessionIdleTimeout:    GetEnvInt("SESSION_IDLE_TIMEOUT", 3600),
+		SessionLimitMode:      getEnv("SESSION_LIMIT_MODE", "reject"),
+		SessionTracking:       GetEnvBool("SESSION_TRACKING", false),
+
+		// Terms of service gate
+		TermsVersion:       getEnv("TERMS_VERSION", ""),
+		TermsURL:           getEnv("TERMS_URL", ""),
+		TermsIdentityTypes: GetEnvList("TERMS_IDENTITY_TYPES"),
+		TermsDenyStatus:    GetEnvInt("TERMS_DENY_STATUS", 451),
+
 		// Secrets Management
 		VaultAddress:   getEnv("VAULT_ADDR", ""),
 		VaultToken:     getEnv("VAULT_TOKEN", ""),
@@ -101,11 +379,184 @@ func Load() *Config {
 		WasmEngine:        getEnv("WASM_ENGINE", "wazero"),
 		GVisorRunscPath:   getEnv("GVISOR_RUNSC_PATH", "/usr/local/bin/runsc"),
 
+		FirecrackerJailer:        GetEnvBool("FC_JAILER", false),
+		FirecrackerJailerBinary:  getEnv("FC_JAILER_BINARY", "/usr/local/bin/jailer"),
+		FirecrackerBinary:        getEnv("FC_BINARY", "/usr/local/bin/firecracker"),
+		FirecrackerChrootBase:    getEnv("FC_JAILER_CHROOT_BASE", "/srv/jailer"),
+		FirecrackerJailerUID:     GetEnvInt("FC_JAILER_UID", 100000),
+		FirecrackerJailerGID:     GetEnvInt("FC_JAILER_GID", 100000),
+		FirecrackerJailerIDCount: GetEnvInt("FC_JAILER_ID_COUNT", 1024),
+		FirecrackerJailerNetNS:   getEnv("FC_JAILER_NETNS", ""),
+		FirecrackerNewPIDNS:      GetEnvBool("FC_JAILER_NEW_PID_NS", true),
+
+		GuestAgent:             GetEnvBool("FC_GUEST_AGENT", false),
+		GuestAgentPort:         GetEnvInt("FC_GUEST_AGENT_PORT", 1024),
+		GuestAgentReadyTimeout: GetEnvInt("FC_GUEST_AGENT_READY_TIMEOUT", 10),
+		GuestAgentBinary:       getEnv("GUEST_AGENT_BINARY", ""),
+
 		// Erebus Configuration
-		InitBinaryPath: getEnv("INIT_BINARY_PATH", "init"),
+		InitBinaryPath:  getEnv("INIT_BINARY_PATH", "init"),
+		InitBinaryPaths: GetEnvMap("INIT_BINARY_PATHS"),
+		InitSmokeTest:   GetEnvBool("INIT_SMOKE_TEST", true),
+
+		// Agent Heartbeat
+		HeartbeatInterval:         GetEnvInt("HEARTBEAT_INTERVAL", 5),
+		HeartbeatIncludeSandboxes: GetEnvBool("HEARTBEAT_INCLUDE_SANDBOXES", true),
+		HeartbeatConditions:       GetEnvBool("HEARTBEAT_CONDITIONS", true),
+		HeartbeatDiskPath:         getEnv("HEARTBEAT_DISK_PATH", "/var/lib/tartarus"),
+		DiskPressureThreshold:     GetEnvFloat("DISK_PRESSURE_THRESHOLD", 90),
+		MemoryPressureThreshold:   GetEnvFloat("MEMORY_PRESSURE_THRESHOLD", 90),
+
+		// Sandbox process limits
+		SandboxMaxPIDs:      GetEnvInt("SANDBOX_MAX_PIDS", 1024),
+		SandboxMaxOpenFiles: GetEnvInt("SANDBOX_MAX_OPEN_FILES", 1024),
+		SandboxCoreDumps:    getEnv("SANDBOX_CORE_DUMPS", "disabled"),
+
+		// Agent cgroup v2 slices
+		CgroupRoot:            getEnv("CGROUP_ROOT", ""),
+		CgroupHostReserveCPU:  GetEnvInt("CGROUP_HOST_RESERVE_CPU", 1000),
+		CgroupHostReserveMem:  GetEnvInt("CGROUP_HOST_RESERVE_MEM_MB", 1024),
+		CgroupAgentReserveMem: GetEnvInt("CGROUP_AGENT_RESERVE_MEM_MB", 256),
+
+		// Hermes metrics cardinality guard
+		MetricsAllowedLabels: GetEnvList("METRICS_ALLOWED_LABELS"),
+		MetricsHashedLabels:  GetEnvList("METRICS_HASHED_LABELS"),
+		MetricsMaxSeries:     GetEnvInt("METRICS_MAX_SERIES", 1000),
+
+		// Run results
+		ResultTailBytes: GetEnvInt("RESULT_TAIL_BYTES", 4096),
+
+		// Erinyes integrity monitoring
+		IntegrityCheckInterval: GetEnvInt("INTEGRITY_CHECK_INTERVAL", 60),
+
+		// Thanatos crash bundles
+		CrashBundleTimeout: GetEnvInt("CRASH_BUNDLE_TIMEOUT", 60),
+
+		// Lethe overlays
+		LetheBackend: getEnv("LETHE_BACKEND", "file"),
+
+		// Host hooks
+		HostHooksDir:    getEnv("HOST_HOOKS_DIR", ""),
+		HostHookTimeout: GetEnvInt("HOST_HOOK_TIMEOUT", 30),
+
+		// GPU inventory
+		GPUDiscovery:  GetEnvBool("GPU_DISCOVERY", true),
+		NvidiaSMIPath: getEnv("NVIDIA_SMI_PATH", "nvidia-smi"),
+		GPURefresh:    GetEnvInt("GPU_REFRESH_INTERVAL", 60),
+
+		// Sandbox swap
+		SandboxSwapMB: GetEnvInt("SANDBOX_SWAP_MB", 0),
+
+		// Node taints
+		NodeTaints: getEnv("NODE_TAINTS", ""),
+
+		NodeLabels: GetEnvMap("NODE_LABELS"),
+
+		NATSURL: getEnv("NATS_URL", ""),
+
+		// Acheron
+		QueueMessageTTL:      GetEnvInt("ACHERON_MESSAGE_TTL", 0),
+		QueueArchive:         GetEnvBool("ACHERON_ARCHIVE", false),
+		QueueArchiveInterval: GetEnvInt("ACHERON_ARCHIVE_INTERVAL", 3600),
+		QueuePreemptBatch:    GetEnvBool("ACHERON_PREEMPT_BATCH", false),
+		KafkaBrokers:         GetEnvList("ACHERON_KAFKA_BROKERS"),
+		KafkaTopic:           getEnv("ACHERON_KAFKA_TOPIC", "tartarus.queue"),
+
+		// Cocytus
+		DeadLetterSuppressThreshold: GetEnvInt("COCYTUS_SUPPRESS_THRESHOLD", 3),
+
+		// Node consolidation
+		ConsolidationEnabled:              GetEnvBool("CONSOLIDATION_ENABLED", false),
+		ConsolidationDryRun:               GetEnvBool("CONSOLIDATION_DRY_RUN", true),
+		ConsolidationUtilizationThreshold: GetEnvFloat("CONSOLIDATION_UTILIZATION_THRESHOLD", 0.3),
+		ConsolidationMinNodes:             GetEnvInt("CONSOLIDATION_MIN_NODES", 1),
+		ConsolidationMaxNodesPerCycle:     GetEnvInt("CONSOLIDATION_MAX_NODES_PER_CYCLE", 1),
+
+		// Scale to zero
+		ScaleToZeroEnabled:     GetEnvBool("SCALE_TO_ZERO_ENABLED", false),
+		ScaleToZeroIdleWindow:  GetEnvInt("SCALE_TO_ZERO_IDLE_WINDOW", 900),
+		ScaleToZeroNodeGroup:   getEnv("SCALE_TO_ZERO_NODE_GROUP", ""),
+		ScaleToZeroWakeNodes:   GetEnvInt("SCALE_TO_ZERO_WAKE_NODES", 1),
+		ScaleToZeroWakeTimeout: GetEnvInt("SCALE_TO_ZERO_WAKE_TIMEOUT", 600),
+
+		// Warm pool
+		WarmPoolEnabled:    GetEnvBool("WARM_POOL_ENABLED", false),
+		WarmPoolHeatLevels: GetEnvList("WARM_POOL_HEAT_LEVELS"),
+		WarmPoolMaxPerNode: GetEnvInt("WARM_POOL_MAX_PER_NODE", 0),
+
+		// Agent version skew
+		AgentMinVersion:   getEnv("AGENT_MIN_VERSION", ""),
+		AgentMaxMinorSkew: GetEnvInt("AGENT_MAX_MINOR_SKEW", 2),
+
+		// Soft delete
+		DeleteRetention: GetEnvInt("DELETE_RETENTION", 604800),
+
+		// Hibernation advisor
+		HypnosMemoryCostPerGBHour:  GetEnvFloat("HYPNOS_MEMORY_COST_PER_GB_HOUR", 1),
+		HypnosStorageCostPerGBHour: GetEnvFloat("HYPNOS_STORAGE_COST_PER_GB_HOUR", 0.01),
+		HypnosMaxWakeLatency:       GetEnvInt("HYPNOS_MAX_WAKE_LATENCY", 0),
+
+		// Hibernation memory sharing
+		HypnosSharedImagesDir: getEnv("HYPNOS_SHARED_IMAGES_DIR", ""),
+		HypnosSharedImageTTL:  GetEnvInt("HYPNOS_SHARED_IMAGE_TTL", 600),
+		HypnosKSM:             GetEnvBool("HYPNOS_KSM", false),
+
+		// Wake pre-warming
+		HypnosPreWake:              GetEnvBool("HYPNOS_PREWAKE", false),
+		HypnosPreWakeLeadTime:      GetEnvInt("HYPNOS_PREWAKE_LEAD_TIME", 300),
+		HypnosPreWakeHitWindow:     GetEnvInt("HYPNOS_PREWAKE_HIT_WINDOW", 1800),
+		HypnosPreWakeMinConfidence: GetEnvFloat("HYPNOS_PREWAKE_MIN_CONFIDENCE", 0.6),
+		HypnosPreWakeDailyBudget:   GetEnvInt("HYPNOS_PREWAKE_DAILY_BUDGET", 50),
+
+		// Chargeback rate card
+		ChargebackCurrency:     getEnv("CHARGEBACK_CURRENCY", "USD"),
+		ChargebackCPUCoreHour:  GetEnvFloat("CHARGEBACK_CPU_CORE_HOUR", 0),
+		ChargebackMemoryGBHour: GetEnvFloat("CHARGEBACK_MEMORY_GB_HOUR", 0),
+		ChargebackGPUHour:      GetEnvFloat("CHARGEBACK_GPU_HOUR", 0),
+		ChargebackDefaultTTL:   GetEnvInt("CHARGEBACK_DEFAULT_TTL", 3600),
+
+		// Plugins
+		PluginsDir:           getEnv("PLUGINS_DIR", ""),
+		PluginReloadInterval: GetEnvInt("PLUGIN_RELOAD_INTERVAL", 10),
+
+		// Browser clients (CORS)
+		CORSAllowedOrigins:   corsOrigins(),
+		CORSAllowCredentials: GetEnvBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSAllowedHeaders:   GetEnvList("CORS_ALLOWED_HEADERS"),
+		CORSExposedHeaders:   GetEnvList("CORS_EXPOSED_HEADERS"),
+		CORSMaxAge:           GetEnvInt("CORS_MAX_AGE", 600),
+
+		// API versioning
+		APILegacySunset: getEnv("API_LEGACY_SUNSET", ""),
+
+		// API usage analytics
+		UsageFlushInterval: GetEnvInt("API_USAGE_FLUSH_INTERVAL", 60),
+		UsageRetentionDays: GetEnvInt("API_USAGE_RETENTION_DAYS", 400),
+
+		// gRPC API
+		GRPCPort:        getEnv("GRPC_PORT", ""),
+		GRPCGatewayPort: getEnv("GRPC_GATEWAY_PORT", ""),
+
+		// Nyx golden snapshots
+		GoldenSnapshots: GetEnvBool("NYX_GOLDEN_SNAPSHOTS", false),
 	}
 }
 
+// corsOrigins returns the allowed CORS origins for the environment:
+// CORS_ALLOWED_ORIGINS_<ENV> (e.g. CORS_ALLOWED_ORIGINS_STAGING) when set,
+// then CORS_ALLOWED_ORIGINS. Outside production, local dashboards are
+// allowed when neither is set.
+func corsOrigins() []string {
+	env := getEnv("TARTARUS_ENV", "development")
+	if origins := GetEnvList("CORS_ALLOWED_ORIGINS_" + strings.ToUpper(env)); origins != nil {
+		return origins
+	}
+	if _, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok || env == "production" {
+		return GetEnvList("CORS_ALLOWED_ORIGINS")
+	}
+	return []string{"http://localhost:*", "http://127.0.0.1:*"}
+}
+
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
@@ -122,6 +573,15 @@ func GetEnvInt(key string, fallback int) int {
 	return fallback
 }
 
+func GetEnvFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
 func GetEnvBool(key string, fallback bool) bool {
 	if value, ok := os.LookupEnv(key); ok {
 		lowerValue := strings.ToLower(value)
@@ -130,6 +590,41 @@ func GetEnvBool(key string, fallback bool) bool {
 	return fallback
 }
 
+// GetEnvMap parses a comma-separated list of key=value pairs
+// (e.g. "us-east=redis-a:6379,eu-west=redis-b:6379").
+// Returns nil when the variable is unset or empty.
+func GetEnvMap(key string) map[string]string {
+	value := getEnv(key, "")
+	if value == "" {
+		return nil
+	}
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || k == "" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// GetEnvList parses a comma-separated list, skipping empty entries.
+// Returns nil when the variable is unset or empty.
+func GetEnvList(key string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return nil
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 // GetEnv returns an environment variable or a fallback value (exported for external use).
 func GetEnv(key, fallback string) string {
 	return getEnv(key, fallback)
@@ -0,0 +1,65 @@
+package domain
+
+import "sort"
+
+// CPU architectures, named as in GOARCH and OCI image configs.
+const (
+	ArchAMD64 = "amd64"
+	ArchARM64 = "arm64"
+
+	// DefaultArch is assumed for nodes that do not advertise an architecture.
+	DefaultArch = ArchAMD64
+)
+
+// TemplateVariant holds the architecture-specific artifacts of a template.
+// Empty fields fall back to the template defaults.
+type TemplateVariant struct {
+	KernelImage string `json:"kernel_image,omitempty"`
+	BaseImage   string `json:"base_image,omitempty"`
+}
+
+// Architectures lists the architectures the template supports, sorted.
+// A template without variants is architecture-neutral and returns nil.
+func (t *TemplateSpec) Architectures() []string {
+	if len(t.Variants) == 0 {
+		return nil
+	}
+	arches := make([]string, 0, len(t.Variants))
+	for arch := range t.Variants {
+		arches = append(arches, arch)
+	}
+	sort.Strings(arches)
+	return arches
+}
+
+// SupportsArch reports whether the template can run on arch.
+func (t *TemplateSpec) SupportsArch(arch string) bool {
+	if len(t.Variants) == 0 {
+		return true
+	}
+	_, ok := t.Variants[arch]
+	return ok
+}
+
+// ForArch returns a copy of the template with the kernel and base image of
+// the arch variant applied.
+func (t *TemplateSpec) ForArch(arch string) *TemplateSpec {
+	out := *t
+	if v, ok := t.Variants[arch]; ok {
+		if v.KernelImage != "" {
+			out.KernelImage = v.KernelImage
+		}
+		if v.BaseImage != "" {
+			out.BaseImage = v.BaseImage
+		}
+	}
+	return &out
+}
+
+// NodeArch returns the architecture a node advertises through its labels.
+func NodeArch(node NodeInfo) string {
+	if arch := node.Labels[NodeLabelArch]; arch != "" {
+		return arch
+	}
+	return DefaultArch
+}
@@ -0,0 +1,18 @@
+package domain
+
+// CrashBundlePolicy asks for a crash bundle when the Furies kill a sandbox,
+// including when its TTL runs out: a final VM snapshot, the console log and
+// the listed guest files are stored in Erebus before the sandbox is
+// destroyed, and the bundle is linked from the run record. It comes from the
+// Themis policy and is copied onto the request by Olympus; submitters cannot
+// set it.
+type CrashBundlePolicy struct {
+	Snapshot     bool     `json:"snapshot"`                 // Include a memory and disk snapshot
+	Paths        []string `json:"paths,omitempty"`          // Absolute guest file paths to collect
+	MaxFileBytes int64    `json:"max_file_bytes,omitempty"` // Per-file size cap (agent default if zero)
+}
+
+// Enabled reports whether a crash bundle is collected.
+func (p *CrashBundlePolicy) Enabled() bool {
+	return p != nil
+}
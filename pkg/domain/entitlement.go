@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// PremiumTemplate marks a template sold separately: tenants may only submit
+// against it if they hold the entitlement SKU. It is set on template
+// policies; tenants are granted SKUs through their policy's Entitlements or
+// by the external license API.
+type PremiumTemplate struct {
+	SKU         string `json:"sku"`                    // Entitlement a tenant must hold
+	PurchaseURL string `json:"purchase_url,omitempty"` // Where tenants without it can buy it
+}
+
+// Validate checks that the SKU is named and the purchase URL is absolute.
+func (p *PremiumTemplate) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.SKU == "" {
+		return fmt.Errorf("premium template must name an entitlement sku")
+	}
+	if p.PurchaseURL != "" {
+		if u, err := url.Parse(p.PurchaseURL); err != nil || !u.IsAbs() {
+			return fmt.Errorf("premium purchase_url %q must be an absolute URL", p.PurchaseURL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// EscalationAction is a step the Furies take against a sandbox that keeps
+// violating its policy.
+type EscalationAction string
+
+const (
+	EscalationWarn      EscalationAction = "warn"      // Log and record the violation
+	EscalationThrottle  EscalationAction = "throttle"  // Cap the sandbox's CPU
+	EscalationHibernate EscalationAction = "hibernate" // Snapshot the sandbox through Hypnos and stop it
+	EscalationKill      EscalationAction = "kill"      // Terminate the sandbox
+)
+
+// EscalationActions lists the valid actions.
+var EscalationActions = []EscalationAction{EscalationWarn, EscalationThrottle, EscalationHibernate, EscalationKill}
+
+// EscalationPolicy replaces the Furies' immediate kill with a ladder of
+// steps taken one after the other while a sandbox keeps violating its
+// limits. A sandbox that reaches the last step stays there; a ladder that
+// does not end in kill or hibernate never terminates the sandbox. It comes
+// from the Themis policy and is copied onto the request by Olympus.
+type EscalationPolicy struct {
+	Steps []EscalationStep `json:"steps"`
+}
+
+// EscalationStep is one rung of the ladder. The step is taken once the
+// sandbox has been found in violation Threshold times since the previous
+// step, and no sooner than Dwell after it.
+type EscalationStep struct {
+	Action      EscalationAction `json:"action"`
+	Threshold   int              `json:"threshold,omitempty"`    // Violating polls before the step (default 1)
+	Dwell       time.Duration    `json:"dwell,omitempty"`        // Minimum time since the previous step
+	ThrottleCPU MilliCPU         `json:"throttle_cpu,omitempty"` // CPU cap of a throttle step (default half the requested CPU)
+}
+
+// Validate checks that every step has a known action and no negative
+// setting, and that nothing follows a step that stops the sandbox.
+func (p *EscalationPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("escalation ladder has no steps")
+	}
+	for i, step := range p.Steps {
+		if !slices.Contains(EscalationActions, step.Action) {
+			return fmt.Errorf("escalation step %d: unknown action %q", i, step.Action)
+		}
+		if step.Threshold < 0 || step.Dwell < 0 || step.ThrottleCPU < 0 {
+			return fmt.Errorf("escalation step %d: threshold, dwell and throttle_cpu must not be negative", i)
+		}
+		if (step.Action == EscalationKill || step.Action == EscalationHibernate) && i < len(p.Steps)-1 {
+			return fmt.Errorf("escalation step %d: %s must be the last step", i, step.Action)
+		}
+	}
+	return nil
+}
+
+// ViolationEvent is an entry of a run's violation timeline: a step of the
+// escalation ladder the Furies took, or tried to take.
+type ViolationEvent struct {
+	At         time.Time        `json:"at"`
+	Reason     string           `json:"reason"`          // Limit violated, e.g. "memory_exceeded"
+	Action     EscalationAction `json:"action"`          // Step taken
+	Step       int              `json:"step"`            // Index of the step in the ladder
+	Violations int              `json:"violations"`      // Violating polls since the previous step
+	Error      string           `json:"error,omitempty"` // Why the step could not be carried out
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ExecChannel is the first byte of every frame of an interactive exec
+// session, saying what the rest of the frame carries. The same frames are
+// relayed between the agent and Olympus, and between Olympus and clients.
+type ExecChannel byte
+
+const (
+	ExecStdin  ExecChannel = 0   // Input for the command
+	ExecStdout ExecChannel = 1   // Standard output of the command
+	ExecStderr ExecChannel = 2   // Standard error of the command
+	ExecStatus ExecChannel = 3   // JSON ExecResult, the last frame of a session
+	ExecReady  ExecChannel = 4   // The agent is ready for input
+	ExecClose  ExecChannel = 254 // Closes the command's input
+	ExecCancel ExecChannel = 255 // Ends the session, killing the command
+)
+
+// ErrInvalidExecFrame is returned for empty exec frames.
+var ErrInvalidExecFrame = errors.New("invalid exec frame")
+
+// ExecFrame prefixes data with its channel.
+func ExecFrame(ch ExecChannel, data []byte) []byte {
+	return append([]byte{byte(ch)}, data...)
+}
+
+// ParseExecFrame splits a frame into its channel and data.
+func ParseExecFrame(frame []byte) (ExecChannel, []byte, error) {
+	if len(frame) == 0 {
+		return 0, nil, ErrInvalidExecFrame
+	}
+	return ExecChannel(frame[0]), frame[1:], nil
+}
+
+// ExecResult is how an interactive exec session ended.
+type ExecResult struct {
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"` // Set when the command could not run or was killed
+}
+
+// StatusFrame encodes the result as the last frame of a session.
+func (r ExecResult) StatusFrame() []byte {
+	data, _ := json.Marshal(r)
+	return ExecFrame(ExecStatus, data)
+}
+
+// Err returns nil for a command that exited 0, an *ExecExitError for other
+// exit codes, and the error for commands that could not run.
+func (r ExecResult) Err() error {
+	switch {
+	case r.Error != "":
+		return errors.New(r.Error)
+	case r.ExitCode != 0:
+		return &ExecExitError{Code: r.ExitCode}
+	}
+	return nil
+}
+
+// ExecExitError is returned for commands that exited with a non-zero code.
+type ExecExitError struct {
+	Code int
+}
+
+func (e *ExecExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// ExecResultOf converts the error an exec returned into its result. Errors
+// with an ExitCode method, such as *exec.ExitError, give the exit code.
+func ExecResultOf(err error) ExecResult {
+	if err == nil {
+		return ExecResult{}
+	}
+	var exit interface{ ExitCode() int }
+	if errors.As(err, &exit) && exit.ExitCode() > 0 {
+		return ExecResult{ExitCode: exit.ExitCode()}
+	}
+	var exitErr *ExecExitError
+	if errors.As(err, &exitErr) {
+		return ExecResult{ExitCode: exitErr.Code}
+	}
+	return ExecResult{ExitCode: -1, Error: err.Error()}
+}
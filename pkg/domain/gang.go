@@ -0,0 +1,25 @@
+package domain
+
+import "errors"
+
+var ErrInvalidGang = errors.New("invalid gang")
+
+// MaxGangSize bounds the number of sandboxes in one gang.
+const MaxGangSize = 256
+
+// GangSpec marks a request as a member of a gang: sandboxes that are placed
+// together or not at all, such as the workers of a multi-node training job.
+// Olympus sets it; agents expose it to the sandbox as TARTARUS_GANG_*.
+type GangSpec struct {
+	ID      string      `json:"id"`
+	Size    int         `json:"size"`
+	Index   int         `json:"index"`   // Position of this member, from 0
+	Members []SandboxID `json:"members"` // All members, in index order
+}
+
+// RunGang records a run's gang. While the gang waits for capacity its
+// members' requests are kept, so Olympus can place them once it is there.
+type RunGang struct {
+	GangSpec
+	Request *SandboxRequest `json:"request,omitempty"`
+}
@@ -0,0 +1,66 @@
+package domain
+
+import "strings"
+
+// GPUDevice is a GPU on a node, as NVML reports it.
+type GPUDevice struct {
+	Index    int         `json:"index"`
+	UUID     string      `json:"uuid"`
+	Model    string      `json:"model"` // e.g. "NVIDIA A100-SXM4-80GB"
+	MemoryMB Megabytes   `json:"memory_mb"`
+	MIG      []MIGDevice `json:"mig,omitempty"`       // Partitions, when the GPU is in MIG mode
+	MIGMode  bool        `json:"mig_mode,omitempty"`  // Only the partitions are handed out
+	InUseBy  SandboxID   `json:"in_use_by,omitempty"` // Sandbox the agent assigned the whole GPU to
+}
+
+// MIGDevice is a Multi-Instance GPU partition, handed out as a GPU of its own.
+type MIGDevice struct {
+	UUID     string    `json:"uuid"`
+	Profile  string    `json:"profile"` // e.g. "1g.10gb"
+	MemoryMB Megabytes `json:"memory_mb"`
+	InUseBy  SandboxID `json:"in_use_by,omitempty"`
+}
+
+// GPUUnit is what a node hands out as one GPU: a whole device, or a MIG
+// partition of a device in MIG mode.
+type GPUUnit struct {
+	UUID     string
+	Model    string
+	Profile  string // MIG profile; empty for a whole device
+	MemoryMB Megabytes
+	InUseBy  SandboxID
+}
+
+// GPUUnits lists the units devices offer, in device order.
+func GPUUnits(devices []GPUDevice) []GPUUnit {
+	var units []GPUUnit
+	for _, d := range devices {
+		if !d.MIGMode {
+			units = append(units, GPUUnit{UUID: d.UUID, Model: d.Model, MemoryMB: d.MemoryMB, InUseBy: d.InUseBy})
+			continue
+		}
+		for _, m := range d.MIG {
+			units = append(units, GPUUnit{UUID: m.UUID, Model: d.Model, Profile: m.Profile, MemoryMB: m.MemoryMB, InUseBy: m.InUseBy})
+		}
+	}
+	return units
+}
+
+// Matches reports whether unit satisfies the request's Type. An empty Type
+// matches any unit. A MIG profile, optionally prefixed "mig-" (e.g.
+// "mig-1g.10gb"), matches partitions of that profile. Anything else matches
+// whole GPUs whose model contains it, case-insensitively, so it can name a
+// vendor ("nvidia") or a model ("a100", "h100-80gb").
+func (r GPURequest) Matches(unit GPUUnit) bool {
+	want := strings.ToLower(strings.TrimSpace(r.Type))
+	if want == "" {
+		return true
+	}
+	if profile, ok := strings.CutPrefix(want, "mig-"); ok {
+		return strings.EqualFold(unit.Profile, profile)
+	}
+	if unit.Profile != "" {
+		return strings.EqualFold(unit.Profile, want)
+	}
+	return strings.Contains(strings.ToLower(unit.Model), want)
+}
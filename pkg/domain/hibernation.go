@@ -0,0 +1,10 @@
+package domain
+
+// HibernationPolicy tunes how Hypnos treats a template's hibernated
+// sandboxes. It comes from the Themis policy.
+type HibernationPolicy struct {
+	// PreWake wakes hibernated sandboxes shortly before their predicted
+	// next use, learned from when they were woken before. Speculative wakes
+	// count against Olympus's pre-wake budget.
+	PreWake bool `json:"pre_wake,omitempty"`
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Host hook phases.
+const (
+	HookPreStart = "pre_start"
+	HookPostStop = "post_stop"
+)
+
+// HostHooks are executables the agent runs on the host around a sandbox's
+// life, e.g. to mount a dataset volume or fetch a license file before it
+// starts. Hooks are referenced by name: the agent only runs executables
+// from its own hooks directory, never code from the policy. They come from
+// the Themis policy and are copied onto the request by Olympus; submitters
+// cannot set them.
+type HostHooks struct {
+	PreStart []HostHook `json:"pre_start,omitempty"` // Run in order before launch; a failure aborts the launch
+	PostStop []HostHook `json:"post_stop,omitempty"` // Run in order once the sandbox is gone; failures are logged
+}
+
+// HostHook is one hook invocation.
+type HostHook struct {
+	Name    string            `json:"name"`              // Executable in the agent's hooks directory
+	Args    []string          `json:"args,omitempty"`    // Passed to the executable as is
+	Env     map[string]string `json:"env,omitempty"`     // Added to the hook's minimal environment
+	Timeout time.Duration     `json:"timeout,omitempty"` // Killed after this long (agent default if zero)
+}
+
+// Enabled reports whether any hook is declared.
+func (h *HostHooks) Enabled() bool {
+	return h != nil && len(h.PreStart)+len(h.PostStop) > 0
+}
+
+// Validate rejects hooks that could name a file outside the hooks directory
+// or override the variables the agent sets.
+func (h *HostHooks) Validate() error {
+	if h == nil {
+		return nil
+	}
+	for _, hook := range append(append([]HostHook(nil), h.PreStart...), h.PostStop...) {
+		if hook.Name == "" || hook.Name == "." || hook.Name == ".." || strings.ContainsAny(hook.Name, `/\`) {
+			return fmt.Errorf("hook name %q must be a file name in the agent's hooks directory", hook.Name)
+		}
+		if hook.Timeout < 0 {
+			return fmt.Errorf("hook %s: timeout must not be negative", hook.Name)
+		}
+		for k := range hook.Env {
+			if k == "" || strings.Contains(k, "=") || k == "PATH" || strings.HasPrefix(k, "TARTARUS_") {
+				return fmt.Errorf("hook %s: environment variable %q cannot be set", hook.Name, k)
+			}
+		}
+	}
+	return nil
+}
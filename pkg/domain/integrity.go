@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// IntegrityPolicy lists guest files whose hashes are recorded at launch and
+// re-verified while the sandbox runs. It comes from the Themis policy and is
+// copied onto the request by Olympus; submitters cannot set it.
+type IntegrityPolicy struct {
+	Paths    []string      `json:"paths"`              // Absolute guest file paths
+	Interval time.Duration `json:"interval,omitempty"` // Re-verification period (agent default if zero)
+}
+
+// Enabled reports whether any path is monitored.
+func (p *IntegrityPolicy) Enabled() bool {
+	return p != nil && len(p.Paths) > 0
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// InputArtifact is content a sandbox reads when it starts. Moirai prefers
+// nodes that already hold most of the bytes of a request's inputs.
+type InputArtifact struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest,omitempty"` // Content digest ("sha256:..."), matched against node caches
+	Ref    string `json:"ref,omitempty"`    // OCI image ref, for images known by name only
+	Bytes  int64  `json:"bytes,omitempty"`  // Size; for images, taken from node caches if unset
+}
+
+// CachedArtifact is an input artifact held in a node's local artifact cache.
+type CachedArtifact struct {
+	Digest   string    `json:"digest"`
+	Bytes    int64     `json:"bytes"`
+	CachedAt time.Time `json:"cached_at"`
+}
+
+// HeldBytes returns how many bytes of the input the node holds in its image
+// or artifact cache: all of them or none.
+func (n *NodeStatus) HeldBytes(input InputArtifact) int64 {
+	for _, img := range n.CachedImages {
+		if (input.Digest != "" && img.Digest == input.Digest) || (input.Digest == "" && input.Ref != "" && img.Ref == input.Ref) {
+			if input.Bytes > 0 {
+				return input.Bytes
+			}
+			return img.Bytes
+		}
+	}
+	if input.Digest == "" {
+		return 0
+	}
+	for _, artifact := range n.CachedArtifacts {
+		if artifact.Digest == input.Digest {
+			if input.Bytes > 0 {
+				return input.Bytes
+			}
+			return artifact.Bytes
+		}
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// MigrationPhase is the step a live migration between nodes has reached.
+type MigrationPhase string
+
+const (
+	// MigrationPhaseSnapshotting: the source node is snapshotting the
+	// sandbox and uploading it to Erebus.
+	MigrationPhaseSnapshotting MigrationPhase = "SNAPSHOTTING"
+	// MigrationPhaseRestoring: the snapshot is uploaded and the target node
+	// is restoring it.
+	MigrationPhaseRestoring MigrationPhase = "RESTORING"
+	MigrationPhaseCompleted MigrationPhase = "COMPLETED"
+	MigrationPhaseFailed    MigrationPhase = "FAILED"
+)
+
+// MigrationTargetStore is the target of a migration that parks a sandbox in
+// Erebus until a node is chosen to restore it on, as scaling the cluster to
+// zero does.
+const MigrationTargetStore NodeID = "@erebus"
+
+// RunMigration records a run's latest move from one node to another.
+type RunMigration struct {
+	Source      NodeID         `json:"source"`
+	Target      NodeID         `json:"target"`
+	Phase       MigrationPhase `json:"phase"`
+	SnapshotKey string         `json:"snapshot_key,omitempty"` // Erebus key the source handed the sandbox off under
+	Error       string         `json:"error,omitempty"`
+	StartedAt   time.Time      `json:"started_at"`
+	FinishedAt  time.Time      `json:"finished_at,omitempty"`
+}
+
+// InProgress reports whether the migration has yet to complete or fail.
+func (m *RunMigration) InProgress() bool {
+	return m != nil && m.Phase != MigrationPhaseCompleted && m.Phase != MigrationPhaseFailed
+}
+
+// Parked reports whether the sandbox is handed off to Erebus and waits for a
+// node to be restored on.
+func (m *RunMigration) Parked() bool {
+	return m != nil && m.Target == MigrationTargetStore && m.Phase == MigrationPhaseRestoring
+}
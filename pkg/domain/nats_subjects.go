@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// NATS control plane subjects, shared by Olympus and the agents. Commands
+// that must reach an agent even if it is briefly disconnected (kill,
+// hibernate, wake, snapshot, prefetch, restart) are published to the
+// NATSControlStream JetStream stream; commands that stream a response back
+// (logs, exec, list) are sent as requests the agent acknowledges.
+const (
+	NATSControlStream = "TARTARUS_CONTROL"
+	// NATSControlMaxAge is how long a command waits in the stream for an
+	// agent to come back. Older commands are stale and dropped.
+	NATSControlMaxAge = 10 * time.Minute
+
+	natsControlPrefix = "tartarus.control."
+)
+
+// NATSControlSubjects matches every node's control subject.
+const NATSControlSubjects = natsControlPrefix + "*"
+
+// NATSToken makes s usable as a single subject token. Node IDs are often
+// host names, and dots, spaces and wildcards would otherwise split or widen
+// the subject.
+func NATSToken(s string) string {
+	return strings.NewReplacer(".", "_", " ", "_", "*", "_", ">", "_").Replace(s)
+}
+
+// NATSControlSubject is the JetStream subject of a node's commands.
+func NATSControlSubject(nodeID NodeID) string {
+	return natsControlPrefix + NATSToken(string(nodeID))
+}
+
+// NATSRequestSubject is the subject a node's agent answers requests on.
+func NATSRequestSubject(nodeID NodeID) string {
+	return "tartarus.request." + NATSToken(string(nodeID))
+}
+
+// NATSLogsSubject carries a sandbox's log chunks.
+func NATSLogsSubject(sandboxID SandboxID) string {
+	return "tartarus.logs." + NATSToken(string(sandboxID))
+}
+
+// NATSExecSubject carries the output of an exec session.
+func NATSExecSubject(sandboxID SandboxID, requestID string) string {
+	return "tartarus.exec." + NATSToken(string(sandboxID)) + "." + NATSToken(requestID)
+}
+
+// NATSStdinSubject carries the input of an interactive exec session.
+func NATSStdinSubject(requestID string) string {
+	return "tartarus.stdin." + NATSToken(requestID)
+}
+
+// NATSResponseSubject carries an agent's answer to a list request.
+func NATSResponseSubject(requestID string) string {
+	return "tartarus.response." + NATSToken(requestID)
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "testing"
+
+func TestNATSSubjects(t *testing.T) {
+	for got, want := range map[string]string{
+		NATSControlSubject("node-1.example.com"): "tartarus.control.node-1_example_com",
+		NATSRequestSubject("node-1"):             "tartarus.request.node-1",
+		NATSLogsSubject("sbx *>1"):               "tartarus.logs.sbx___1",
+		NATSExecSubject("sbx.1", "req-1"):        "tartarus.exec.sbx_1.req-1",
+		NATSStdinSubject("req-1"):                "tartarus.stdin.req-1",
+		NATSResponseSubject("req-1"):             "tartarus.response.req-1",
+	} {
+		if got != want {
+			t.Errorf("got subject %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// NodeConditionType names an aspect of node health reported by the agent.
+type NodeConditionType string
+
+const (
+	NodeDiskPressure   NodeConditionType = "DiskPressure"   // True when the sandbox disk is nearly full
+	NodeMemoryPressure NodeConditionType = "MemoryPressure" // True when host memory is nearly exhausted
+	NodeKVMAvailable   NodeConditionType = "KVMAvailable"   // True when /dev/kvm is usable
+	NodeRuntimeHealthy NodeConditionType = "RuntimeHealthy" // True when the sandbox runtime responds
+)
+
+// NodeCondition is the observed state of one NodeConditionType.
+type NodeCondition struct {
+	Type    NodeConditionType `json:"type"`
+	Status  bool              `json:"status"`
+	Reason  string            `json:"reason,omitempty"`
+	Message string            `json:"message,omitempty"`
+	// LastTransition is when Status last changed
+	LastTransition time.Time `json:"last_transition,omitempty"`
+}
+
+// Condition returns the node's condition of the given type.
+func (s *NodeStatus) Condition(t NodeConditionType) (NodeCondition, bool) {
+	for _, c := range s.Conditions {
+		if c.Type == t {
+			return c, true
+		}
+	}
+	return NodeCondition{}, false
+}
+
+// UnderPressure reports whether the node reports disk or memory pressure.
+// Nodes that do not report conditions are not under pressure.
+func (s *NodeStatus) UnderPressure() bool {
+	for _, t := range []NodeConditionType{NodeDiskPressure, NodeMemoryPressure} {
+		if c, ok := s.Condition(t); ok && c.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// Draining reports whether the node has been cordoned and should not receive
+// new sandboxes.
+func (s *NodeStatus) Draining() bool {
+	return s.Labels[NodeLabelStatus] == NodeStatusDraining
+}
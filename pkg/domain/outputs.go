@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var ErrInvalidOutputPolicy = errors.New("invalid output policy")
+
+// OutputPolicy asks for the files a sandbox wrote to be kept: when the run
+// finishes, the agent packages what changed in its overlay's upper layer
+// into a tarball in Erebus before the overlay is destroyed, and links it
+// from the run record. It comes from the Themis policy and is copied onto
+// the request by Olympus; submitters cannot set it.
+type OutputPolicy struct {
+	Paths    []string `json:"paths,omitempty"`     // Guest directories whose changes are kept (all if empty)
+	Exclude  []string `json:"exclude,omitempty"`   // Guest directories whose changes are dropped
+	MaxBytes int64    `json:"max_bytes,omitempty"` // Cap on file bytes packaged (1 GiB if zero)
+}
+
+// Enabled reports whether outputs are extracted.
+func (p *OutputPolicy) Enabled() bool {
+	return p != nil
+}
+
+// Validate checks the paths are absolute and the cap is not negative.
+func (p *OutputPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, dir := range append(append([]string(nil), p.Paths...), p.Exclude...) {
+		if !path.IsAbs(dir) {
+			return fmt.Errorf("%w: path %q is not absolute", ErrInvalidOutputPolicy, dir)
+		}
+	}
+	if p.MaxBytes < 0 {
+		return fmt.Errorf("%w: max_bytes must not be negative", ErrInvalidOutputPolicy)
+	}
+	return nil
+}
+
+// Includes reports whether changes to the guest path are kept.
+func (p *OutputPolicy) Includes(guestPath string) bool {
+	return !p.Excludes(guestPath) && (len(p.Paths) == 0 || under(guestPath, p.Paths))
+}
+
+// Excludes reports whether the guest path falls under an excluded directory.
+func (p *OutputPolicy) Excludes(guestPath string) bool {
+	return under(guestPath, p.Exclude)
+}
+
+// under reports whether guestPath is one of dirs or inside one.
+func under(guestPath string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = path.Clean(dir)
+		if dir == "/" || guestPath == dir || strings.HasPrefix(guestPath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// RunOutput describes the tarball of files a run wrote.
+type RunOutput struct {
+	Key       string `json:"key"`                 // Erebus key of the gzipped tarball
+	Files     int    `json:"files"`               // Files and links written or changed
+	Deleted   int    `json:"deleted,omitempty"`   // Paths removed, as whiteouts in the tarball
+	Bytes     int64  `json:"bytes"`               // File bytes packaged
+	Truncated bool   `json:"truncated,omitempty"` // Files were left out to stay under the cap
+}
@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidPlacement is returned for a request whose node selector or
+// tolerations cannot be evaluated.
+var ErrInvalidPlacement = errors.New("invalid placement")
+
+// Node selector operators.
+const (
+	SelectorIn           = "In"
+	SelectorNotIn        = "NotIn"
+	SelectorExists       = "Exists"
+	SelectorDoesNotExist = "DoesNotExist"
+)
+
+// NodeSelectorRequirement is a condition on a node label that a request's
+// node must meet.
+type NodeSelectorRequirement struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator"`         // SelectorIn, SelectorNotIn, SelectorExists or SelectorDoesNotExist
+	Values   []string `json:"values,omitempty"` // For SelectorIn and SelectorNotIn
+}
+
+// Matches reports whether labels meet the requirement. NotIn matches nodes
+// without the label.
+func (r NodeSelectorRequirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectorIn:
+		return ok && slices.Contains(r.Values, value)
+	case SelectorNotIn:
+		return !ok || !slices.Contains(r.Values, value)
+	case SelectorExists:
+		return ok
+	case SelectorDoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// AntiAffinity keeps a sandbox off nodes that already run sandboxes like
+// it, so that noisy workloads of one tenant or template do not share a node.
+type AntiAffinity struct {
+	Tenant   bool `json:"tenant,omitempty"`   // No other sandbox of the same tenant (or submitter, without one)
+	Template bool `json:"template,omitempty"` // No other sandbox of the same template
+}
+
+// Conflicts reports whether run, active on a node, keeps req off it.
+func (a *AntiAffinity) Conflicts(req *SandboxRequest, run SandboxRun) bool {
+	if a == nil || run.ID == req.ID {
+		return false
+	}
+	if a.Template && run.Template == req.Template {
+		return true
+	}
+	if a.Tenant {
+		tenant := submitterTenant(req.Submitter)
+		return tenant != "" && submitterTenant(run.Submitter) == tenant
+	}
+	return false
+}
+
+// submitterTenant is the tenant of a submitter, or its ID without one.
+func submitterTenant(s *Submitter) string {
+	switch {
+	case s == nil:
+		return ""
+	case s.TenantID != "":
+		return "tenant:" + s.TenantID
+	default:
+		return "submitter:" + s.ID
+	}
+}
+
+// Taint effects.
+const (
+	// TaintNoSchedule places only requests that tolerate the taint on the node.
+	TaintNoSchedule = "NoSchedule"
+)
+
+// Toleration operators.
+const (
+	TolerationEqual  = "Equal"
+	TolerationExists = "Exists"
+)
+
+// Taint keeps requests that do not tolerate it off a node, e.g. to reserve
+// nodes for noisy workloads.
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"` // TaintNoSchedule
+}
+
+// Toleration lets a request be placed on nodes with matching taints.
+type Toleration struct {
+	Key      string `json:"key,omitempty"`      // Empty, with TolerationExists, tolerates every taint
+	Operator string `json:"operator,omitempty"` // TolerationEqual (default) or TolerationExists
+	Value    string `json:"value,omitempty"`
+	Effect   string `json:"effect,omitempty"` // Empty tolerates every effect
+}
+
+// Tolerates reports whether the toleration matches taint.
+func (t Toleration) Tolerates(taint Taint) bool {
+	if t.Effect != "" && t.Effect != taint.Effect {
+		return false
+	}
+	if t.Operator == TolerationExists {
+		return t.Key == "" || t.Key == taint.Key
+	}
+	return t.Key == taint.Key && t.Value == taint.Value
+}
+
+// Tolerated reports whether every taint is matched by one of tolerations.
+func Tolerated(taints []Taint, tolerations []Toleration) bool {
+	for _, taint := range taints {
+		if !slices.ContainsFunc(tolerations, func(t Toleration) bool { return t.Tolerates(taint) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate rejects a taint no scheduler can apply.
+func (t Taint) Validate() error {
+	if t.Key == "" {
+		return fmt.Errorf("taint key must not be empty")
+	}
+	if t.Effect != TaintNoSchedule {
+		return fmt.Errorf("taint %s: effect must be %q, got %q", t.Key, TaintNoSchedule, t.Effect)
+	}
+	return nil
+}
+
+// ParseTaints parses comma-separated taints of the form key[=value]:effect.
+func ParseTaints(s string) ([]Taint, error) {
+	var taints []Taint
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		spec, effect, ok := strings.Cut(field, ":")
+		if !ok {
+			return nil, fmt.Errorf("taint %q: missing effect", field)
+		}
+		key, value, _ := strings.Cut(spec, "=")
+		taint := Taint{Key: key, Value: value, Effect: effect}
+		if err := taint.Validate(); err != nil {
+			return nil, err
+		}
+		taints = append(taints, taint)
+	}
+	return taints, nil
+}
+
+// ValidatePlacement checks the request's node selector and tolerations.
+func (r *SandboxRequest) ValidatePlacement() error {
+	for _, s := range r.NodeSelector {
+		if s.Key == "" {
+			return fmt.Errorf("%w: node selector key must not be empty", ErrInvalidPlacement)
+		}
+		switch s.Operator {
+		case SelectorIn, SelectorNotIn:
+			if len(s.Values) == 0 {
+				return fmt.Errorf("%w: node selector %s: %s needs values", ErrInvalidPlacement, s.Key, s.Operator)
+			}
+		case SelectorExists, SelectorDoesNotExist:
+			if len(s.Values) > 0 {
+				return fmt.Errorf("%w: node selector %s: %s takes no values", ErrInvalidPlacement, s.Key, s.Operator)
+			}
+		default:
+			return fmt.Errorf("%w: node selector %s: unknown operator %q", ErrInvalidPlacement, s.Key, s.Operator)
+		}
+	}
+	for _, t := range r.Tolerations {
+		switch t.Operator {
+		case "", TolerationEqual:
+			if t.Key == "" {
+				return fmt.Errorf("%w: toleration key must not be empty with operator %s", ErrInvalidPlacement, TolerationEqual)
+			}
+		case TolerationExists:
+			if t.Value != "" {
+				return fmt.Errorf("%w: toleration %s: %s takes no value", ErrInvalidPlacement, t.Key, TolerationExists)
+			}
+		default:
+			return fmt.Errorf("%w: toleration %s: unknown operator %q", ErrInvalidPlacement, t.Key, t.Operator)
+		}
+		if t.Effect != "" && t.Effect != TaintNoSchedule {
+			return fmt.Errorf("%w: toleration %s: unknown effect %q", ErrInvalidPlacement, t.Key, t.Effect)
+		}
+	}
+	return nil
+}
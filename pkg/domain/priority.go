@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// Priority is the Acheron lane a request waits in. Agents drain the lanes in
+// the order of Priorities.
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"   // Latency-sensitive work, may preempt batch sandboxes
+	PriorityNormal Priority = "normal" // The default lane
+	PriorityBatch  Priority = "batch"  // Throughput work that waits for the other lanes
+)
+
+// Priorities lists the lanes from highest to lowest.
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityBatch}
+
+// Valid reports whether p names a lane.
+func (p Priority) Valid() bool {
+	switch p {
+	case PriorityHigh, PriorityNormal, PriorityBatch:
+		return true
+	}
+	return false
+}
+
+// ValidatePriority checks that the request's priority, if set, names a lane.
+func (r *SandboxRequest) ValidatePriority() error {
+	if r.Priority != "" && !r.Priority.Valid() {
+		return fmt.Errorf("%w: %q must be one of %v", ErrInvalidPriority, r.Priority, Priorities)
+	}
+	return nil
+}
+
+// Lane returns the lane the request waits in: its priority, or normal if it
+// has none.
+func (r *SandboxRequest) Lane() Priority {
+	if r.Priority.Valid() {
+		return r.Priority
+	}
+	return PriorityNormal
+}
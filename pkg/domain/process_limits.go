@@ -0,0 +1,52 @@
+package domain
+
+import "fmt"
+
+// Core dump policies.
+const (
+	CoreDumpsDisabled = "disabled"
+	CoreDumpsEnabled  = "enabled"
+)
+
+// ProcessLimits bounds the processes inside a sandbox, so a fork bomb or a
+// file descriptor leak stays contained. It comes from the Themis policy,
+// with the agent's defaults for fields the policy leaves unset; submitters
+// cannot set it.
+type ProcessLimits struct {
+	MaxPIDs      int64  `json:"max_pids,omitempty"`       // Processes and threads (0 = agent default)
+	MaxOpenFiles uint64 `json:"max_open_files,omitempty"` // Open files per process (0 = agent default)
+	CoreDumps    string `json:"core_dumps,omitempty"`     // CoreDumpsDisabled or CoreDumpsEnabled ("" = agent default)
+}
+
+// Override returns l with every set field of o applied on top.
+func (l ProcessLimits) Override(o *ProcessLimits) ProcessLimits {
+	if o == nil {
+		return l
+	}
+	if o.MaxPIDs != 0 {
+		l.MaxPIDs = o.MaxPIDs
+	}
+	if o.MaxOpenFiles != 0 {
+		l.MaxOpenFiles = o.MaxOpenFiles
+	}
+	if o.CoreDumps != "" {
+		l.CoreDumps = o.CoreDumps
+	}
+	return l
+}
+
+// Validate rejects limits no runtime can apply.
+func (l *ProcessLimits) Validate() error {
+	if l == nil {
+		return nil
+	}
+	if l.MaxPIDs < 0 {
+		return fmt.Errorf("max_pids must not be negative, got %d", l.MaxPIDs)
+	}
+	switch l.CoreDumps {
+	case "", CoreDumpsDisabled, CoreDumpsEnabled:
+	default:
+		return fmt.Errorf("core_dumps must be %q or %q, got %q", CoreDumpsDisabled, CoreDumpsEnabled, l.CoreDumps)
+	}
+	return nil
+}
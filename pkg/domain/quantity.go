@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// ErrInvalidQuantity is returned for resource quantities that cannot be parsed.
+var ErrInvalidQuantity = errors.New("invalid resource quantity")
+
+const (
+	cpuFormats = `millicores (1500), cores ("2", "0.5") or millicores with an m suffix ("1500m")`
+	memFormats = `megabytes (512) or a size with a binary (Ki, Mi, Gi, Ti) or decimal (k, M, G, T) suffix ("512Mi", "4Gi")`
+)
+
+// memSuffixes maps memory quantity suffixes to bytes, binary ones first so
+// "Mi" is not read as "M".
+var memSuffixes = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+	{"k", 1e3}, {"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+}
+
+// ParseMilliCPU parses a Kubernetes-style CPU quantity: "2" or "0.5" cores,
+// or "1500m" millicores.
+func ParseMilliCPU(s string) (MilliCPU, error) {
+	v := strings.TrimSpace(s)
+	scale := big.NewRat(1000, 1)
+	if strings.HasSuffix(v, "m") {
+		v = strings.TrimSuffix(v, "m")
+		scale = big.NewRat(1, 1)
+	}
+	n, ok := parseQuantity(v, scale)
+	if !ok {
+		return 0, fmt.Errorf("%w: cpu %q: use %s", ErrInvalidQuantity, s, cpuFormats)
+	}
+	if !n.IsInt() {
+		return 0, fmt.Errorf("%w: cpu %q is finer than 1m: use %s", ErrInvalidQuantity, s, cpuFormats)
+	}
+	return MilliCPU(n.Num().Int64()), nil
+}
+
+// ParseMegabytes parses a Kubernetes-style memory quantity such as "512Mi",
+// "4Gi" or "1G" into megabytes (MiB), rounding up. Unlike Kubernetes, a
+// suffix is required: a bare "512" is too easily meant as megabytes.
+func ParseMegabytes(s string) (Megabytes, error) {
+	v := strings.TrimSpace(s)
+	var bytes int64
+	for _, suf := range memSuffixes {
+		if strings.HasSuffix(v, suf.suffix) {
+			v = strings.TrimSuffix(v, suf.suffix)
+			bytes = suf.bytes
+			break
+		}
+	}
+	n, ok := parseQuantity(v, new(big.Rat).SetFrac64(bytes, 1<<20))
+	if !ok || bytes == 0 {
+		return 0, fmt.Errorf("%w: memory %q: use %s", ErrInvalidQuantity, s, memFormats)
+	}
+	// Round partial megabytes up
+	mb := new(big.Int).Quo(n.Num(), n.Denom())
+	if !n.IsInt() {
+		mb.Add(mb, big.NewInt(1))
+	}
+	return Megabytes(mb.Int64()), nil
+}
+
+// parseQuantity parses a non-negative decimal number and multiplies it by
+// scale, rejecting results that overflow int64.
+func parseQuantity(v string, scale *big.Rat) (*big.Rat, bool) {
+	if v == "" || v == "." || strings.Count(v, ".") > 1 || strings.Trim(v, "0123456789.") != "" {
+		return nil, false
+	}
+	n, ok := new(big.Rat).SetString(v)
+	if !ok {
+		return nil, false
+	}
+	n.Mul(n, scale)
+	limit := new(big.Rat).SetInt64(math.MaxInt64)
+	if n.Cmp(limit) > 0 {
+		return nil, false
+	}
+	return n, true
+}
+
+// FormatMilliCPU returns the canonical quantity for c: whole cores as a plain
+// number ("2"), anything else in millicores ("1500m").
+func FormatMilliCPU(c MilliCPU) string {
+	if c%1000 == 0 {
+		return fmt.Sprintf("%d", c/1000)
+	}
+	return fmt.Sprintf("%dm", c)
+}
+
+// FormatMegabytes returns the canonical quantity for m in the largest binary
+// unit that divides it exactly ("4Gi", "1536Mi").
+func FormatMegabytes(m Megabytes) string {
+	switch {
+	case m != 0 && m%(1<<20) == 0:
+		return fmt.Sprintf("%dTi", m>>20)
+	case m != 0 && m%(1<<10) == 0:
+		return fmt.Sprintf("%dGi", m>>10)
+	default:
+		return fmt.Sprintf("%dMi", m)
+	}
+}
+
+// resourceSpecFields has ResourceSpec's fields without its JSON methods.
+type resourceSpecFields ResourceSpec
+
+// UnmarshalJSON accepts cpu_milli and mem_mb either as integers in their
+// base units, as before, or as quantity strings ("2", "1500m", "4Gi").
+func (r *ResourceSpec) UnmarshalJSON(data []byte) error {
+	var wire struct {
+		resourceSpecFields
+		CPU json.RawMessage `json:"cpu_milli"`
+		Mem json.RawMessage `json:"mem_mb"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*r = ResourceSpec(wire.resourceSpecFields)
+
+	if quantity, isString, err := rawQuantity(wire.CPU, &r.CPU); err != nil {
+		return fmt.Errorf("%w: cpu_milli %s: use %s", ErrInvalidQuantity, wire.CPU, cpuFormats)
+	} else if isString {
+		if r.CPU, err = ParseMilliCPU(quantity); err != nil {
+			return err
+		}
+	}
+	if quantity, isString, err := rawQuantity(wire.Mem, &r.Mem); err != nil {
+		return fmt.Errorf("%w: mem_mb %s: use %s", ErrInvalidQuantity, wire.Mem, memFormats)
+	} else if isString {
+		if r.Mem, err = ParseMegabytes(quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes cpu_milli and mem_mb as canonical quantity strings.
+func (r ResourceSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		resourceSpecFields
+		CPU string `json:"cpu_milli"`
+		Mem string `json:"mem_mb"`
+	}{resourceSpecFields(r), FormatMilliCPU(r.CPU), FormatMegabytes(r.Mem)})
+}
+
+// rawQuantity decodes a JSON integer into number, or returns the string when
+// the value is a quantity string.
+func rawQuantity(raw json.RawMessage, number any) (string, bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", false, nil
+	}
+	if raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err == nil, err
+	}
+	return "", false, json.Unmarshal(raw, number)
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMilliCPU(t *testing.T) {
+	for in, want := range map[string]MilliCPU{
+		"2":     2000,
+		"0.5":   500,
+		"1500m": 1500,
+		"250m":  250,
+		" 1 ":   1000,
+	} {
+		got, err := ParseMilliCPU(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMilliCPU(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "m", "-1", "2Gi", "0.5m", "1e3", "0x10", "1.2.3"} {
+		if _, err := ParseMilliCPU(in); !errors.Is(err, ErrInvalidQuantity) {
+			t.Errorf("ParseMilliCPU(%q) = %v; want ErrInvalidQuantity", in, err)
+		}
+	}
+}
+
+func TestParseMegabytes(t *testing.T) {
+	for in, want := range map[string]Megabytes{
+		"512Mi":  512,
+		"4Gi":    4096,
+		"1.5Gi":  1536,
+		"1Ti":    1 << 20,
+		"2048Ki": 2,
+		"1Ki":    1, // Rounded up
+		"1G":     954,
+		"500M":   477,
+	} {
+		got, err := ParseMegabytes(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMegabytes(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "512", "Gi", "-1Gi", "4GB", "4gi"} {
+		if _, err := ParseMegabytes(in); !errors.Is(err, ErrInvalidQuantity) {
+			t.Errorf("ParseMegabytes(%q) = %v; want ErrInvalidQuantity", in, err)
+		}
+	}
+}
+
+func TestResourceSpec_JSON(t *testing.T) {
+	var spec ResourceSpec
+	if err := json.Unmarshal([]byte(`{"cpu_milli":"1500m","mem_mb":"4Gi","ttl":60000000000}`), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.CPU != 1500 || spec.Mem != 4096 || spec.TTL != time.Minute {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+
+	// Raw integers keep their base units
+	if err := json.Unmarshal([]byte(`{"cpu_milli":2000,"mem_mb":512,"profile":"small"}`), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.CPU != 2000 || spec.Mem != 512 || spec.Profile != "small" || spec.TTL != 0 {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+
+	// Written back out in canonical form, and read again unchanged
+	for _, tc := range []struct {
+		spec ResourceSpec
+		want string
+	}{
+		{ResourceSpec{CPU: 2000, Mem: 512}, `"cpu_milli":"2","mem_mb":"512Mi"`},
+		{ResourceSpec{CPU: 1500, Mem: 4096}, `"cpu_milli":"1500m","mem_mb":"4Gi"`},
+		{ResourceSpec{}, `"cpu_milli":"0","mem_mb":"0Mi"`},
+	} {
+		data, err := json.Marshal(tc.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), tc.want) {
+			t.Errorf("Marshal(%+v) = %s; want %s", tc.spec, data, tc.want)
+		}
+		var back ResourceSpec
+		if err := json.Unmarshal(data, &back); err != nil || back != tc.spec {
+			t.Errorf("round trip of %+v = %+v, %v", tc.spec, back, err)
+		}
+	}
+
+	for _, body := range []string{`{"cpu_milli":"two"}`, `{"mem_mb":"4GB"}`, `{"cpu_milli":1.5}`, `{"mem_mb":true}`} {
+		err := json.Unmarshal([]byte(body), &spec)
+		if !errors.Is(err, ErrInvalidQuantity) {
+			t.Errorf("Unmarshal(%s) = %v; want ErrInvalidQuantity", body, err)
+		}
+	}
+
+	// Nested in a request
+	var req SandboxRequest
+	if err := json.Unmarshal([]byte(`{"template":"python","resources":{"cpu_milli":"2","mem_mb":"1Gi"}}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Resources.CPU != 2000 || req.Resources.Mem != 1024 {
+		t.Errorf("unexpected resources %+v", req.Resources)
+	}
+}
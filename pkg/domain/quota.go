@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidQuota is returned for quotas with negative limits.
+var ErrInvalidQuota = errors.New("invalid quota")
+
+// ResourceQuota bounds what a tenant may hold at once. As a limit, zero
+// means unlimited; as usage, it is the amount currently held.
+type ResourceQuota struct {
+	CPU       MilliCPU  `json:"cpu_milli"`
+	Mem       Megabytes `json:"mem_mb"`
+	GPU       int       `json:"gpu"`
+	Sandboxes int       `json:"sandboxes"`  // Pending, scheduled and running sandboxes
+	StorageMB Megabytes `json:"storage_mb"` // Hibernation snapshots and exports in Erebus
+}
+
+// Override returns q with every non-zero field of o applied on top.
+func (q ResourceQuota) Override(o ResourceQuota) ResourceQuota {
+	if o.CPU != 0 {
+		q.CPU = o.CPU
+	}
+	if o.Mem != 0 {
+		q.Mem = o.Mem
+	}
+	if o.GPU != 0 {
+		q.GPU = o.GPU
+	}
+	if o.Sandboxes != 0 {
+		q.Sandboxes = o.Sandboxes
+	}
+	if o.StorageMB != 0 {
+		q.StorageMB = o.StorageMB
+	}
+	return q
+}
+
+// Validate checks no limit is negative. A nil quota is valid.
+func (q *ResourceQuota) Validate() error {
+	if q == nil {
+		return nil
+	}
+	if q.CPU < 0 || q.Mem < 0 || q.GPU < 0 || q.Sandboxes < 0 || q.StorageMB < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidQuota)
+	}
+	return nil
+}
+
+// Exceeded lists the limits of q that usage goes over, as "<limit> <usage>/<max>".
+func (q ResourceQuota) Exceeded(usage ResourceQuota) []string {
+	var over []string
+	check := func(name string, used, max int64) {
+		if max > 0 && used > max {
+			over = append(over, fmt.Sprintf("%s %d/%d", name, used, max))
+		}
+	}
+	check("cpu_milli", int64(usage.CPU), int64(q.CPU))
+	check("mem_mb", int64(usage.Mem), int64(q.Mem))
+	check("gpu", int64(usage.GPU), int64(q.GPU))
+	check("sandboxes", int64(usage.Sandboxes), int64(q.Sandboxes))
+	check("storage_mb", int64(usage.StorageMB), int64(q.StorageMB))
+	return over
+}
+
+// Active reports whether the run holds resources against a quota.
+func (r *SandboxRun) Active() bool {
+	switch r.Status {
+	case RunStatusPending, RunStatusScheduled, RunStatusRescheduling, RunStatusRunning:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// RunRescheduling records a request an agent turned away because its node
+// had less headroom than Olympus believed, e.g. when it placed on a lagging
+// heartbeat. While the run is RESCHEDULING the request is kept, so Olympus
+// can place it again.
+type RunRescheduling struct {
+	Request *SandboxRequest `json:"request,omitempty"` // Cleared once the request is placed again
+	From    NodeID          `json:"from"`              // Node that last turned the request away
+	Reason  string          `json:"reason"`
+	Count   int             `json:"count"` // Times the request was turned away
+	At      time.Time       `json:"at"`
+}
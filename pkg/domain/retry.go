@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// RetryClass groups run failures by cause, for choosing which are retried.
+type RetryClass string
+
+const (
+	RetryNodeLost   RetryClass = "node_lost"   // The node stopped sending heartbeats while the run was in flight
+	RetryImagePull  RetryClass = "image_pull"  // The image or snapshot could not be fetched
+	RetryScheduling RetryClass = "scheduling"  // Olympus could not schedule or enqueue the request
+	RetryOOM        RetryClass = "oom"         // The sandbox ran out of memory
+	RetryTimeout    RetryClass = "timeout"     // An operation timed out
+	RetryExitCode   RetryClass = "exit_code"   // The workload exited non-zero
+	RetryUnknown    RetryClass = "unknown"     // Any other failure
+	RetryPoisonPill RetryClass = "poison_pill" // A known poison pill; never retried
+)
+
+// DefaultRetryClasses are retried when a retry policy lists no classes: the
+// infrastructure failures a new attempt is likely to get past.
+var DefaultRetryClasses = []RetryClass{RetryNodeLost, RetryImagePull, RetryScheduling}
+
+// MaxRetryAttempts caps RetryPolicy.MaxAttempts.
+const MaxRetryAttempts = 10
+
+// RetryPolicy resubmits failed runs. Requests may set it, and Themis policies
+// set the default; a policy's MaxAttempts also caps what requests may ask for.
+type RetryPolicy struct {
+	MaxAttempts int           `json:"max_attempts"`          // Attempts including the first; 1 disables retries
+	Backoff     time.Duration `json:"backoff,omitempty"`     // Wait before the second attempt, doubled for each one after
+	MaxBackoff  time.Duration `json:"max_backoff,omitempty"` // Cap on the doubled wait (none if zero)
+	On          []RetryClass  `json:"on,omitempty"`          // Failure classes retried (DefaultRetryClasses if empty)
+}
+
+// Validate checks the attempt bounds, waits and classes.
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxAttempts < 1 || p.MaxAttempts > MaxRetryAttempts {
+		return fmt.Errorf("%w: max_attempts must be between 1 and %d", ErrInvalidRetryPolicy, MaxRetryAttempts)
+	}
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("%w: backoff must not be negative", ErrInvalidRetryPolicy)
+	}
+	for _, class := range p.On {
+		switch class {
+		case RetryNodeLost, RetryImagePull, RetryScheduling, RetryOOM, RetryTimeout, RetryExitCode, RetryUnknown:
+		default:
+			return fmt.Errorf("%w: unknown class %q", ErrInvalidRetryPolicy, class)
+		}
+	}
+	return nil
+}
+
+// Retries reports whether failures of class are retried.
+func (p *RetryPolicy) Retries(class RetryClass) bool {
+	if p == nil || class == RetryPoisonPill {
+		return false
+	}
+	if len(p.On) == 0 {
+		return slices.Contains(DefaultRetryClasses, class)
+	}
+	return slices.Contains(p.On, class)
+}
+
+// Delay returns the wait after the given failed attempt (1-based) before
+// the next one starts.
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay > 0; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Cap limits the policy to at most the attempts allowed by limit.
+func (p RetryPolicy) Cap(limit *RetryPolicy) RetryPolicy {
+	if limit != nil && p.MaxAttempts > limit.MaxAttempts {
+		p.MaxAttempts = limit.MaxAttempts
+	}
+	return p
+}
+
+// RunRetry tracks one attempt of a request with a retry policy. Attempts are
+// separate runs, linked to each other through PreviousID and NextID.
+type RunRetry struct {
+	Attempt    int             `json:"attempt"`               // 1 for the first run
+	Policy     RetryPolicy     `json:"policy"`                // Effective policy
+	OriginalID SandboxID       `json:"original_id"`           // First attempt
+	PreviousID SandboxID       `json:"previous_id,omitempty"` // Attempt this one retries
+	NextID     SandboxID       `json:"next_id,omitempty"`     // Attempt that retried this one
+	Class      RetryClass      `json:"class,omitempty"`       // Why this attempt failed
+	Stopped    string          `json:"stopped,omitempty"`     // Why no further attempt was made
+	Request    *SandboxRequest `json:"request,omitempty"`     // Resubmitted for the next attempt
+}
+
+// Pending reports whether the attempt may still be retried.
+func (r *RunRetry) Pending() bool {
+	return r != nil && r.NextID == "" && r.Stopped == ""
+}
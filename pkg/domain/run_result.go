@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// DefaultResultTailBytes is how much trailing output is kept per stream
+// when no limit is configured.
+const DefaultResultTailBytes = 4096
+
+// RunResult summarizes a finished sandbox so clients can read its outcome
+// from the run record without fetching logs. The exit code is kept on the
+// run itself.
+type RunResult struct {
+	StdoutTail      string        `json:"stdout_tail,omitempty"`
+	StderrTail      string        `json:"stderr_tail,omitempty"`
+	OutputTruncated bool          `json:"output_truncated,omitempty"` // A tail dropped earlier output
+	DurationMs      int64         `json:"duration_ms"`                // Wall-clock time from start to finish
+	PeakMemory      Megabytes     `json:"peak_memory_mb,omitempty"`
+	Intensity       *RunIntensity `json:"intensity,omitempty"` // Observed by Erinyes, if the sandbox was watched
+}
+
+// RunIntensity is the peak resource use Erinyes observed while a sandbox
+// ran, in the units Phlegethon classifies heat by.
+type RunIntensity struct {
+	PeakCPUCores    float64   `json:"peak_cpu_cores,omitempty"`
+	PeakMemory      Megabytes `json:"peak_memory_mb,omitempty"`
+	PeakNetworkMbps float64   `json:"peak_network_mbps,omitempty"` // Ingress plus egress
+}
+
+// WallClock returns the run's duration, or zero if it has not finished.
+func (r *SandboxRun) WallClock() time.Duration {
+	if r.StartedAt.IsZero() || r.FinishedAt.IsZero() || r.FinishedAt.Before(r.StartedAt) {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidRunWindow = errors.New("invalid run window")
+
+// RunWindow bounds when a sandbox may start and by when it must finish.
+// Zero times are unbounded. TimeZone is an IANA zone name used to render the
+// window back to the submitter; the bounds themselves are absolute instants.
+type RunWindow struct {
+	NotBefore time.Time `json:"not_before,omitempty"` // Earliest start
+	StartBy   time.Time `json:"start_by,omitempty"`   // Expire if not started by then
+	Deadline  time.Time `json:"deadline,omitempty"`   // Must complete by then
+	TimeZone  string    `json:"time_zone,omitempty"`  // e.g. "Europe/Berlin"
+}
+
+// Validate checks that the bounds are ordered and the time zone is known.
+func (w *RunWindow) Validate() error {
+	if w == nil {
+		return nil
+	}
+	if w.TimeZone != "" {
+		if _, err := time.LoadLocation(w.TimeZone); err != nil {
+			return fmt.Errorf("%w: unknown time zone %q", ErrInvalidRunWindow, w.TimeZone)
+		}
+	}
+	if !w.NotBefore.IsZero() && !w.StartBy.IsZero() && w.StartBy.Before(w.NotBefore) {
+		return fmt.Errorf("%w: start_by is before not_before", ErrInvalidRunWindow)
+	}
+	if !w.NotBefore.IsZero() && !w.Deadline.IsZero() && !w.Deadline.After(w.NotBefore) {
+		return fmt.Errorf("%w: deadline is not after not_before", ErrInvalidRunWindow)
+	}
+	if !w.StartBy.IsZero() && !w.Deadline.IsZero() && w.Deadline.Before(w.StartBy) {
+		return fmt.Errorf("%w: deadline is before start_by", ErrInvalidRunWindow)
+	}
+	return nil
+}
+
+// ApplyDefaults fills missing StartBy and Deadline bounds from the policy,
+// measured from the earliest start (NotBefore, or from when it is later).
+func (w *RunWindow) ApplyDefaults(p RunWindowPolicy, from time.Time) {
+	if w.NotBefore.After(from) {
+		from = w.NotBefore
+	}
+	if w.StartBy.IsZero() && p.MaxQueueTime > 0 {
+		w.StartBy = from.Add(p.MaxQueueTime)
+	}
+	if w.Deadline.IsZero() && p.MaxCompletionTime > 0 {
+		w.Deadline = from.Add(p.MaxCompletionTime)
+	}
+}
+
+// Delay returns how long to wait from now before the window opens.
+func (w *RunWindow) Delay(now time.Time) time.Duration {
+	if w == nil || w.NotBefore.IsZero() || !w.NotBefore.After(now) {
+		return 0
+	}
+	return w.NotBefore.Sub(now)
+}
+
+// StartExpired reports whether the latest start time has passed.
+func (w *RunWindow) StartExpired(now time.Time) bool {
+	return w != nil && !w.StartBy.IsZero() && now.After(w.StartBy)
+}
+
+// DeadlineExceeded reports whether the completion deadline has passed.
+func (w *RunWindow) DeadlineExceeded(now time.Time) bool {
+	return w != nil && !w.Deadline.IsZero() && now.After(w.Deadline)
+}
+
+// Localized returns a copy of the window with all bounds expressed in its
+// TimeZone. Unknown or empty zones leave the bounds unchanged.
+func (w *RunWindow) Localized() *RunWindow {
+	if w == nil {
+		return nil
+	}
+	out := *w
+	if w.TimeZone == "" {
+		return &out
+	}
+	loc, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return &out
+	}
+	for _, t := range []*time.Time{&out.NotBefore, &out.StartBy, &out.Deadline} {
+		if !t.IsZero() {
+			*t = t.In(loc)
+		}
+	}
+	return &out
+}
+
+// IsTerminal reports whether the status is final.
+func (s RunStatus) IsTerminal() bool {
+	switch s {
+	case RunStatusSucceeded, RunStatusFailed, RunStatusCanceled, RunStatusExpired, RunStatusDeadlineExceeded:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// SchedulingDecision records why Moirai placed a run where it did, or why
+// it could not place it, as of the last placement attempt.
+type SchedulingDecision struct {
+	NodeID    NodeID    `json:"node_id,omitempty"` // Chosen node; empty if placement failed
+	Error     string    `json:"error,omitempty"`
+	Verbosity string    `json:"verbosity"`
+	DecidedAt time.Time `json:"decided_at"`
+
+	// Evaluated counts the nodes considered; Excluded counts those a
+	// filter ruled out, by filter.
+	Evaluated int            `json:"evaluated"`
+	Excluded  map[string]int `json:"excluded,omitempty"`
+
+	// Candidates has an entry per node considered, in the order they were
+	// first considered. Only recorded at full verbosity.
+	Candidates []CandidateNode `json:"candidates,omitempty"`
+
+	// Notes are decisions about the node set as a whole, such as a region
+	// failover or a topology spread that could not be kept.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// CandidateNode is one node's outcome in a SchedulingDecision: excluded by
+// Filter, with the Reason, or eligible with the Score the scheduler ranked
+// it by.
+type CandidateNode struct {
+	NodeID NodeID  `json:"node_id"`
+	Filter string  `json:"filter,omitempty"`
+	Reason string  `json:"reason,omitempty"`
+	Scorer string  `json:"scorer,omitempty"`
+	Score  float64 `json:"score,omitempty"`
+	Chosen bool    `json:"chosen,omitempty"`
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var ErrInvalidScript = errors.New("invalid script run")
+
+// DefaultScriptDir is where a script run's artifact is unpacked when the
+// request does not say.
+const DefaultScriptDir = "/workspace"
+
+// ScriptSpec makes a request a script run: before launch the agent unpacks
+// the artifact, a gzipped tarball holding the script and its requirements,
+// into Dir in the sandbox's root filesystem. Olympus sets the command that
+// runs the entrypoint there.
+type ScriptSpec struct {
+	Artifact   string   `json:"artifact"`          // Digest ("sha256:...") of the tarball in Erebus
+	Entrypoint string   `json:"entrypoint"`        // Script path inside the tarball
+	Dir        string   `json:"dir,omitempty"`     // Guest directory the tarball is unpacked into (default DefaultScriptDir)
+	Outputs    []string `json:"outputs,omitempty"` // Guest paths kept when the run finishes, within the policy's outputs
+}
+
+// GuestDir returns the guest directory the artifact is unpacked into.
+func (s *ScriptSpec) GuestDir() string {
+	if s.Dir == "" {
+		return DefaultScriptDir
+	}
+	return path.Clean(s.Dir)
+}
+
+// Validate checks the artifact digest, that the entrypoint stays inside the
+// unpacked tarball, and that the directory and outputs are absolute.
+func (s *ScriptSpec) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if !strings.HasPrefix(s.Artifact, "sha256:") {
+		return fmt.Errorf("%w: artifact must be a sha256 digest", ErrInvalidScript)
+	}
+	entry := path.Clean(s.Entrypoint)
+	if s.Entrypoint == "" || path.IsAbs(entry) || entry == "." || entry == ".." || strings.HasPrefix(entry, "../") {
+		return fmt.Errorf("%w: entrypoint %q must be a path inside the artifact", ErrInvalidScript, s.Entrypoint)
+	}
+	if s.Dir != "" && (!path.IsAbs(s.Dir) || s.GuestDir() == "/") {
+		return fmt.Errorf("%w: dir %q must be an absolute path below /", ErrInvalidScript, s.Dir)
+	}
+	for _, out := range s.Outputs {
+		if !path.IsAbs(out) {
+			return fmt.Errorf("%w: output %q is not absolute", ErrInvalidScript, out)
+		}
+	}
+	return nil
+}
+
+// NarrowOutputs returns the output policy of a script run: the declared
+// outputs, each of which the policy must already extract, with the policy's
+// exclusions and cap.
+func (s *ScriptSpec) NarrowOutputs(policy *OutputPolicy) (*OutputPolicy, error) {
+	if len(s.Outputs) == 0 {
+		return policy, nil
+	}
+	if !policy.Enabled() {
+		return nil, fmt.Errorf("%w: outputs are not enabled by policy", ErrInvalidScript)
+	}
+	for _, out := range s.Outputs {
+		if !policy.Includes(path.Clean(out)) {
+			return nil, fmt.Errorf("%w: output %q is not extracted by policy", ErrInvalidScript, out)
+		}
+	}
+	return &OutputPolicy{Paths: s.Outputs, Exclude: policy.Exclude, MaxBytes: policy.MaxBytes}, nil
+}
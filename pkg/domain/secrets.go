@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSecretRef is returned for secret references that cannot be
+// injected into a sandbox.
+var ErrInvalidSecretRef = errors.New("invalid secret reference")
+
+// SecretRefSchemes are the secret stores a sandbox may reference, e.g.
+// "vault:secret/db:password". Other Cerberus references, such as API key
+// signing keys, are never injected.
+var SecretRefSchemes = []string{"env", "vault", "kms"}
+
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateSecrets checks that each secret is injected into a valid
+// environment variable not also set in Env, from a supported store.
+func (r *SandboxRequest) ValidateSecrets() error {
+	for _, name := range r.SecretNames() {
+		ref := r.Secrets[name]
+		if !envVarName.MatchString(name) {
+			return fmt.Errorf("%w: %q is not a valid environment variable name", ErrInvalidSecretRef, name)
+		}
+		if _, ok := r.Env[name]; ok {
+			return fmt.Errorf("%w: %s is also set in env", ErrInvalidSecretRef, name)
+		}
+		scheme, path, ok := strings.Cut(ref, ":")
+		if !ok || path == "" || !isSecretRefScheme(scheme) {
+			return fmt.Errorf("%w: %s: %q must be <store>:<path> with store one of %s",
+				ErrInvalidSecretRef, name, ref, strings.Join(SecretRefSchemes, ", "))
+		}
+	}
+	return nil
+}
+
+// SecretNames returns the environment variables receiving secrets, sorted.
+func (r *SandboxRequest) SecretNames() []string {
+	names := make([]string, 0, len(r.Secrets))
+	for name := range r.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isSecretRefScheme(scheme string) bool {
+	for _, s := range SecretRefSchemes {
+		if scheme == s {
+			return true
+		}
+	}
+	return false
+}
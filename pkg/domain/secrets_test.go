@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSandboxRequest_ValidateSecrets(t *testing.T) {
+	valid := &SandboxRequest{
+		Env:     map[string]string{"DEBUG": "true"},
+		Secrets: map[string]string{"API_KEY": "vault:secret/myapp:api_key", "DB_PASSWORD": "kms:/prod/db", "TOKEN": "env:CI_TOKEN"},
+	}
+	if err := valid.ValidateSecrets(); err != nil {
+		t.Errorf("ValidateSecrets: %v", err)
+	}
+
+	for name, secrets := range map[string]map[string]string{
+		"bad name":     {"1KEY": "vault:a:b"},
+		"also in env":  {"DEBUG": "vault:a:b"},
+		"no scheme":    {"API_KEY": "secret/myapp"},
+		"empty path":   {"API_KEY": "vault:"},
+		"signing keys": {"API_KEY": "key:default"},
+	} {
+		req := &SandboxRequest{Env: map[string]string{"DEBUG": "true"}, Secrets: secrets}
+		if err := req.ValidateSecrets(); !errors.Is(err, ErrInvalidSecretRef) {
+			t.Errorf("%s: got %v, want ErrInvalidSecretRef", name, err)
+		}
+	}
+}
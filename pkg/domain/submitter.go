@@ -0,0 +1,28 @@
+package domain
+
+// Submitter is the authenticated identity that submitted a sandbox request.
+// It is stamped by Olympus from the API credentials; clients cannot set it.
+type Submitter struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type,omitempty"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+// SubmittedBy reports whether the run was submitted by the given identity
+// and tenant. Empty arguments match anything.
+func (r *SandboxRun) SubmittedBy(id, tenantID string) bool {
+	if id == "" && tenantID == "" {
+		return true
+	}
+	if r.Submitter == nil {
+		return false
+	}
+	if id != "" && r.Submitter.ID != id {
+		return false
+	}
+	if tenantID != "" && r.Submitter.TenantID != tenantID {
+		return false
+	}
+	return true
+}
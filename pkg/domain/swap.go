@@ -0,0 +1,68 @@
+package domain
+
+import "fmt"
+
+// ZramCompressionRatio is the compression assumed for zram swap when
+// counting the guest memory it takes.
+const ZramCompressionRatio = 2
+
+// SwapPolicy lets part of a sandbox's memory live in swap, so memory-heavy
+// but latency-tolerant jobs take less of a node's RAM. It comes from the
+// Themis policy; submitters cannot set it.
+type SwapPolicy struct {
+	MaxMB      Megabytes `json:"max_mb"`               // Part of resources.mem_mb that may be swapped out
+	Swappiness *int      `json:"swappiness,omitempty"` // vm.swappiness in guests, memory.swappiness in cgroups (nil = kernel default)
+	Zram       bool      `json:"zram,omitempty"`       // microVMs swap to compressed guest memory instead of a swap disk
+}
+
+// Size returns the swap a sandbox with mem of memory gets: MaxMB, capped at
+// half of mem so the working set stays in RAM.
+func (p *SwapPolicy) Size(mem Megabytes) Megabytes {
+	if p == nil || p.MaxMB <= 0 {
+		return 0
+	}
+	return min(p.MaxMB, mem/2)
+}
+
+// Split returns how much of mem a sandbox takes from the node's RAM and
+// from its swap. Zram swap is compressed guest memory: it takes no swap from
+// the node, and its RAM is counted at ZramCompressionRatio.
+func (p *SwapPolicy) Split(mem Megabytes) (ram, swap Megabytes) {
+	size := p.Size(mem)
+	if size == 0 {
+		return mem, 0
+	}
+	if p.Zram {
+		return mem - size + size/ZramCompressionRatio, 0
+	}
+	return mem - size, size
+}
+
+// CgroupSplit is Split for sandboxes confined by a cgroup (gVisor,
+// containerd), where zram does not apply and all the memory stays in RAM.
+func (p *SwapPolicy) CgroupSplit(mem Megabytes) (ram, swap Megabytes) {
+	if p != nil && p.Zram {
+		return mem, 0
+	}
+	return p.Split(mem)
+}
+
+// Validate rejects swap settings no runtime can apply.
+func (p *SwapPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxMB < 0 {
+		return fmt.Errorf("swap max_mb must not be negative, got %d", p.MaxMB)
+	}
+	if p.Swappiness != nil && (*p.Swappiness < 0 || *p.Swappiness > 200) {
+		return fmt.Errorf("swappiness must be between 0 and 200, got %d", *p.Swappiness)
+	}
+	return nil
+}
+
+// MemoryNeeds returns the RAM and swap the request takes on a node that
+// offers swap.
+func (r *SandboxRequest) MemoryNeeds() (ram, swap Megabytes) {
+	return r.Swap.Split(r.Resources.Mem)
+}
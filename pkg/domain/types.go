@@ -23,6 +23,13 @@ const (
 	RunStatusSucceeded RunStatus = "SUCCEEDED"
 	RunStatusFailed    RunStatus = "FAILED"
 	RunStatusCanceled  RunStatus = "CANCELED"
+
+	// RunStatusExpired marks a request that was not started before its run window closed.
+	RunStatusExpired RunStatus = "EXPIRED"
+	// RunStatusDeadlineExceeded marks a run that did not complete before its deadline.
+	RunStatusDeadlineExceeded RunStatus = "DEADLINE_EXCEEDED"
+	// RunStatusRescheduling marks a request an agent handed back because its node had no room for it.
+	RunStatusRescheduling RunStatus = "RESCHEDULING"
 )
 
 // IsolationType defines the type of isolation/runtime to use for sandboxes.
@@ -63,53 +70,131 @@ type NetworkPolicyRef struct {
 // SandboxRequest is what Olympus enqueues into Acheron.
 
 type SandboxRequest struct {
-	ID         SandboxID         `json:"id"`
-	Template   TemplateID        `json:"template"`
-	NodeID     NodeID            `json:"node_id,omitempty"`    // Scheduled node
-	HeatLevel  string            `json:"heat_level,omitempty"` // Phlegethon heat classification
-	Command    []string          `json:"command"`
-	Args       []string          `json:"args"`
-	Env        map[string]string `json:"env"`
-	Resources  ResourceSpec      `json:"resources"`
-	NetworkRef NetworkPolicyRef  `json:"network"`
-	Retention  RetentionPolicy   `json:"retention,omitempty"`
-	Secrets    map[string]string `json:"secrets,omitempty"`  // key -> secret ref
-	Metadata   map[string]string `json:"metadata"`           // tenant, user, origin, etc.
-	Hardened   bool              `json:"hardened,omitempty"` // Use hardened kernel/runtime
-	CreatedAt  time.Time         `json:"created_at"`
+	ID          SandboxID          `json:"id"`
+	Template    TemplateID         `json:"template"`
+	NodeID      NodeID             `json:"node_id,omitempty"`    // Scheduled node
+	HeatLevel   string             `json:"heat_level,omitempty"` // Phlegethon heat classification
+	Priority    Priority           `json:"priority,omitempty"`   // Acheron lane; Olympus derives it from the heat level if empty
+	Command     []string           `json:"command"`
+	Args        []string           `json:"args"`
+	Env         map[string]string  `json:"env"`
+	Resources   ResourceSpec       `json:"resources"`
+	NetworkRef  NetworkPolicyRef   `json:"network"`
+	Retention   RetentionPolicy    `json:"retention,omitempty"`
+	Secrets     map[string]string  `json:"secrets,omitempty"`           // key -> secret ref
+	Metadata    map[string]string  `json:"metadata"`                    // tenant, user, origin, etc.
+	Hardened    bool               `json:"hardened,omitempty"`          // Use hardened kernel/runtime
+	Arch        string             `json:"arch,omitempty"`              // CPU architecture ("amd64", "arm64"); pinned or set at scheduling
+	Window      *RunWindow         `json:"window,omitempty"`            // When the sandbox may run
+	Submitter   *Submitter         `json:"submitter,omitempty"`         // Authenticated submitter, set by Olympus
+	ExpiresAt   time.Time          `json:"expires_at,omitempty"`        // Dropped from the queue if not dequeued by then
+	Wasm        *WasmCapabilities  `json:"wasm_capabilities,omitempty"` // Host functions granted by policy, set by Olympus
+	Integrity   *IntegrityPolicy   `json:"integrity,omitempty"`         // Guest files to monitor, set by Olympus
+	Limits      *ProcessLimits     `json:"limits,omitempty"`            // Process limits, set by Olympus and completed by the agent
+	Swap        *SwapPolicy        `json:"swap,omitempty"`              // Memory that may be swapped out, set by Olympus
+	CrashBundle *CrashBundlePolicy `json:"crash_bundle,omitempty"`      // Post-mortem collection on kill, set by Olympus
+	Escalation  *EscalationPolicy  `json:"escalation,omitempty"`        // Watchdog escalation ladder, set by Olympus
+	Hooks       *HostHooks         `json:"hooks,omitempty"`             // Host hooks around the sandbox, set by Olympus
+	Inputs      []InputArtifact    `json:"inputs,omitempty"`            // Artifacts and images read at start, for locality-aware scheduling
+	Retry       *RetryPolicy       `json:"retry,omitempty"`             // Resubmission of failed runs (policy default if nil)
+	Outputs     *OutputPolicy      `json:"outputs,omitempty"`           // Extraction of files the sandbox wrote, set by Olympus
+	Script      *ScriptSpec        `json:"script,omitempty"`            // Script artifact unpacked into the sandbox before launch
+	Gang        *GangSpec          `json:"gang,omitempty"`              // Gang the request is placed with, set by Olympus
+
+	// WarmInstance is the pre-warmed sandbox on the node the request was
+	// given to run in, set by Olympus
+	WarmInstance SandboxID `json:"warm_instance,omitempty"`
+
+	// Placement constraints
+	NodeSelector []NodeSelectorRequirement `json:"node_selector,omitempty"` // Node labels the node must match
+	AntiAffinity *AntiAffinity             `json:"anti_affinity,omitempty"` // Sandboxes the node must not run already
+	Tolerations  []Toleration              `json:"tolerations,omitempty"`   // Node taints the request accepts
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Expired reports whether the request outlived its queue TTL.
+func (r *SandboxRequest) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
 }
 
 // SandboxRun is the lifecycle instance of a request on a node.
 
 type SandboxRun struct {
-	ID          SandboxID         `json:"id"`
-	RequestID   SandboxID         `json:"request_id"`
-	NodeID      NodeID            `json:"node_id"`
-	Template    TemplateID        `json:"template"`
-	Status      RunStatus         `json:"status"`
-	ExitCode    *int              `json:"exit_code,omitempty"`
-	Error       string            `json:"error,omitempty"`
-	StartedAt   time.Time         `json:"started_at"`
-	FinishedAt  time.Time         `json:"finished_at"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at"`
-	MemoryUsage Megabytes         `json:"memory_usage,omitempty"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
+	ID           SandboxID           `json:"id"`
+	RequestID    SandboxID           `json:"request_id"`
+	NodeID       NodeID              `json:"node_id"`
+	Template     TemplateID          `json:"template"`
+	Status       RunStatus           `json:"status"`
+	ExitCode     *int                `json:"exit_code,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	StartedAt    time.Time           `json:"started_at"`
+	FinishedAt   time.Time           `json:"finished_at"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	MemoryUsage  Megabytes           `json:"memory_usage,omitempty"`
+	CPUUsageUsec uint64              `json:"cpu_usage_usec,omitempty"` // CPU time used so far
+	Result       *RunResult          `json:"result,omitempty"`
+	Window       *RunWindow          `json:"window,omitempty"`
+	Submitter    *Submitter          `json:"submitter,omitempty"`
+	Resources    *ResourceSpec       `json:"resources,omitempty"`
+	Tampered     []string            `json:"tampered,omitempty"`     // Guest paths changed since launch
+	CrashBundle  string              `json:"crash_bundle,omitempty"` // Erebus key of the crash bundle manifest, if one was taken
+	Violations   []ViolationEvent    `json:"violations,omitempty"`   // Escalation steps the Furies took
+	Retry        *RunRetry           `json:"retry,omitempty"`        // Attempt tracking, for requests with a retry policy
+	Output       *RunOutput          `json:"output,omitempty"`       // Files the sandbox wrote, if its policy asks for them
+	Gang         *RunGang            `json:"gang,omitempty"`         // Gang the run is placed with
+	Scheduling   *SchedulingDecision `json:"scheduling,omitempty"`   // Why the run was placed where it was
+	Migration    *RunMigration       `json:"migration,omitempty"`    // Latest move to another node, if any
+	Parked       *SandboxRequest     `json:"parked,omitempty"`       // Held while the cluster scales up from zero
+	Rescheduling *RunRescheduling    `json:"rescheduling,omitempty"` // Latest hand-back by an over-committed node, if any
+	Warm         *RunWarm            `json:"warm,omitempty"`         // Set for pre-warmed sandboxes
+	Metadata     map[string]string   `json:"metadata,omitempty"`
+	Revision     int64               `json:"revision,omitempty"` // Incremented by every write to Hades
+
+	// User-facing fields, changed through PATCH /sandboxes/{id}
+	DisplayName     string            `json:"display_name,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	ResourceVersion int64             `json:"resource_version,omitempty"` // Incremented by every patch
 }
 
 // Node & capacity
 
 type ResourceCapacity struct {
-	CPU MilliCPU  `json:"cpu_milli"`
-	Mem Megabytes `json:"mem_mb"`
-	GPU int       `json:"gpu"`
+	CPU  MilliCPU  `json:"cpu_milli"`
+	Mem  Megabytes `json:"mem_mb"`
+	GPU  int       `json:"gpu"`
+	Swap Megabytes `json:"swap_mb,omitempty"` // Swap offered to (or used by) sandboxes, apart from Mem
 }
 
+// Well-known node labels.
+
+const (
+	NodeLabelRegion = "region"
+	NodeLabelZone   = "zone"
+	NodeLabelArch   = "arch"
+
+	// NodeLabelRuntimes lists the isolation classes a node's agent can
+	// launch, comma-separated (e.g. "microvm,gvisor").
+	NodeLabelRuntimes = "runtimes"
+
+	// NodeLabelNodeGroup names the node group (e.g. "gpu") a node was
+	// provisioned in; seasons size node groups independently.
+	NodeLabelNodeGroup = "node_group"
+
+	// NodeLabelStatus carries scheduling state set by Olympus; it survives
+	// heartbeats. NodeStatusDraining cordons the node.
+	NodeLabelStatus    = "status"
+	NodeStatusDraining = "draining"
+)
+
 type NodeInfo struct {
 	ID       NodeID            `json:"id"`
 	Address  string            `json:"address"`
 	Labels   map[string]string `json:"labels"`
 	Capacity ResourceCapacity  `json:"capacity"`
+	GPUs     []GPUDevice       `json:"gpus,omitempty"`   // Device inventory; Capacity.GPU counts its units
+	Taints   []Taint           `json:"taints,omitempty"` // Only requests tolerating these are placed on the node
 }
 
 type NodeStatus struct {
@@ -117,6 +202,50 @@ type NodeStatus struct {
 	Allocated       ResourceCapacity `json:"allocated"`
 	Heartbeat       time.Time        `json:"heartbeat"`
 	ActiveSandboxes []SandboxRun     `json:"active_sandboxes"`
+	Conditions      []NodeCondition  `json:"conditions,omitempty"`
+	AgentVersion    string           `json:"agent_version,omitempty"`
+	AgentBuild      *AgentBuild      `json:"agent_build,omitempty"`
+	AgentStartedAt  time.Time        `json:"agent_started_at,omitempty"`
+	CachedImages    []CachedImage    `json:"cached_images,omitempty"`
+	CachedArtifacts []CachedArtifact `json:"cached_artifacts,omitempty"`
+	CgroupSlices    []CgroupSlice    `json:"cgroup_slices,omitempty"`
+	MemorySharing   *MemorySharing   `json:"memory_sharing,omitempty"`
+}
+
+// AgentBuild identifies the agent binary running on a node.
+type AgentBuild struct {
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version,omitempty"`
+}
+
+// CachedImage is an OCI image assembled in a node's local image cache.
+type CachedImage struct {
+	Ref      string    `json:"ref"`
+	Digest   string    `json:"digest"`
+	Bytes    int64     `json:"bytes,omitempty"` // Compressed size of the image's layers
+	PulledAt time.Time `json:"pulled_at"`
+}
+
+// CgroupSlice is the resource usage of one of the cgroup v2 slices the
+// agent runs itself and its sandbox processes in.
+type CgroupSlice struct {
+	Name             string `json:"name"`
+	MemoryBytes      uint64 `json:"memory_bytes"`
+	CPUUsageUsec     uint64 `json:"cpu_usage_usec"`
+	CPUThrottledUsec uint64 `json:"cpu_throttled_usec,omitempty"`
+	Pids             uint64 `json:"pids"`
+}
+
+// MemorySharing is how much of the memory of a node's woken sandboxes is
+// shared between them rather than private to each.
+type MemorySharing struct {
+	Images         int    `json:"images"`                     // Memory images on the node
+	SharedBytes    uint64 `json:"shared_bytes"`               // Image bytes mapped by more than one sandbox
+	PrivateBytes   uint64 `json:"private_bytes"`              // Image bytes mapped by a single sandbox
+	SavedBytes     uint64 `json:"saved_bytes"`                // Bytes private copies of shared images would take
+	KSMSharedBytes uint64 `json:"ksm_shared_bytes,omitempty"` // Pages KSM merged, kept once
+	KSMSavedBytes  uint64 `json:"ksm_saved_bytes,omitempty"`  // Duplicate pages KSM freed
 }
 
 // Template & snapshot references
@@ -130,6 +259,12 @@ type TemplateSpec struct {
 	Resources     ResourceSpec      `json:"resources"`
 	DefaultEnv    map[string]string `json:"default_env"`
 	WarmupCommand []string          `json:"warmup_command,omitempty"`
+
+	// Variants overrides kernel and rootfs per CPU architecture. When set, the
+	// template only runs on the listed architectures.
+	Variants map[string]TemplateVariant `json:"variants,omitempty"`
+
+	DeletedAt time.Time `json:"deleted_at,omitempty"` // Set while the template is soft-deleted
 }
 
 type SnapshotRef struct {
@@ -139,6 +274,19 @@ type SnapshotRef struct {
 	Path      string     `json:"path"`
 }
 
+// SnapshotRecord is a snapshot's entry in the snapshot catalog, with where it
+// came from and how often it has been restored.
+type SnapshotRecord struct {
+	ID             SnapshotID `json:"id"`
+	Template       TemplateID `json:"template"`
+	SandboxID      SandboxID  `json:"sandbox_id,omitempty"` // Sandbox the snapshot was taken of; empty for template snapshots
+	NodeID         NodeID     `json:"node_id,omitempty"`    // Node that took the snapshot
+	SizeBytes      int64      `json:"size_bytes"`           // Memory and disk image size
+	CreatedAt      time.Time  `json:"created_at"`
+	LastRestoredAt time.Time  `json:"last_restored_at,omitempty"`
+	RestoreCount   int        `json:"restore_count"`
+}
+
 // Policies
 
 type RetentionPolicy struct {
@@ -146,12 +294,39 @@ type RetentionPolicy struct {
 	KeepOutputs bool          `json:"keep_outputs"`
 }
 
+// RunWindowPolicy provides relative run window defaults for requests that do not
+// carry explicit bounds.
+type RunWindowPolicy struct {
+	MaxQueueTime      time.Duration `json:"max_queue_time,omitempty"`      // start_by = earliest start + this
+	MaxCompletionTime time.Duration `json:"max_completion_time,omitempty"` // deadline = earliest start + this
+}
+
+// SandboxPolicy limits sandboxes at one level of the Themis hierarchy:
+// global defaults (no tenant or template), a tenant (TenantID only) or a
+// template (TemplateID only). More specific levels override less specific
+// ones field by field.
 type SandboxPolicy struct {
-	ID            PolicyID          `json:"id"`
-	TemplateID    TemplateID        `json:"template_id"`
-	Resources     ResourceSpec      `json:"resources"`
-	NetworkPolicy NetworkPolicyRef  `json:"network"`
-	Retention     RetentionPolicy   `json:"retention"`
-	Tags          map[string]string `json:"tags"`
-	Version       int64             `json:"version"`
+	ID            PolicyID           `json:"id"`
+	TenantID      string             `json:"tenant_id,omitempty"`
+	TemplateID    TemplateID         `json:"template_id"`
+	Resources     ResourceSpec       `json:"resources"`
+	NetworkPolicy NetworkPolicyRef   `json:"network"`
+	Retention     RetentionPolicy    `json:"retention"`
+	RunWindow     RunWindowPolicy    `json:"run_window,omitempty"`
+	Wasm          *WasmCapabilities  `json:"wasm_capabilities,omitempty"` // Host functions granted to Wasm sandboxes
+	Quota         *ResourceQuota     `json:"quota,omitempty"`             // Per-tenant limits on held resources
+	Integrity     *IntegrityPolicy   `json:"integrity,omitempty"`         // Guest files monitored for tampering
+	Limits        *ProcessLimits     `json:"limits,omitempty"`            // PID, open-file and core dump limits
+	Swap          *SwapPolicy        `json:"swap,omitempty"`              // Swap and zram for memory-heavy, latency-tolerant sandboxes
+	CrashBundle   *CrashBundlePolicy `json:"crash_bundle,omitempty"`      // Post-mortem collection when the Furies kill a sandbox
+	Escalation    *EscalationPolicy  `json:"escalation,omitempty"`        // Steps before the Furies kill a sandbox in violation
+	Hooks         *HostHooks         `json:"hooks,omitempty"`             // Host scripts run before launch and after exit
+	Retry         *RetryPolicy       `json:"retry,omitempty"`             // Default retry policy, and cap on requests' attempts
+	Outputs       *OutputPolicy      `json:"outputs,omitempty"`           // Files the sandbox wrote, kept after it finishes
+	Hibernation   *HibernationPolicy `json:"hibernation,omitempty"`       // Pre-waking of hibernated sandboxes
+	Premium       *PremiumTemplate   `json:"premium,omitempty"`           // Entitlement tenants must hold to use the template
+	Entitlements  []string           `json:"entitlements,omitempty"`      // Entitlement SKUs granted to the tenant
+	Tags          map[string]string  `json:"tags"`
+	Version       int64              `json:"version"`
+	DeletedAt     time.Time          `json:"deleted_at,omitempty"` // Set while the policy is soft-deleted
 }
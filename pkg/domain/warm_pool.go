@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// MetadataWarm marks a request, and its run, as a pre-warmed sandbox kept
+// for later requests of its template to claim.
+const MetadataWarm = "warm"
+
+// WarmState is where a pre-warmed sandbox is in its life.
+type WarmState string
+
+const (
+	WarmReady   WarmState = "READY"   // Paused and waiting to be claimed
+	WarmClaimed WarmState = "CLAIMED" // Handed to a request, which runs in it
+)
+
+// RunWarm records a pre-warmed sandbox an agent keeps paused for a request
+// of its template to claim.
+type RunWarm struct {
+	State     WarmState `json:"state"`
+	Network   string    `json:"network"`              // Network policy the sandbox is attached with
+	ClaimedBy SandboxID `json:"claimed_by,omitempty"` // Request running in the sandbox, once claimed
+	ReadyAt   time.Time `json:"ready_at"`
+	ClaimedAt time.Time `json:"claimed_at,omitempty"`
+}
+
+// Warm reports whether the request pre-warms a sandbox rather than runs a
+// workload.
+func (r *SandboxRequest) Warm() bool {
+	return r.Metadata[MetadataWarm] == "true"
+}
+
+// CanClaim reports whether the request can run in the sandbox pre-warmed
+// for warm: the sandbox was booted for the same template and network with
+// at least the resources the request asks for, and the request needs
+// nothing set up before boot, such as secrets, host hooks, staged inputs, a
+// script or GPUs.
+func (r *SandboxRequest) CanClaim(warm *SandboxRequest) bool {
+	switch {
+	case r.Warm() || !warm.Warm():
+		return false
+	case r.Template != warm.Template || r.NetworkRef.ID != warm.NetworkRef.ID:
+		return false
+	case r.Resources.CPU > warm.Resources.CPU || r.Resources.Mem > warm.Resources.Mem || r.Resources.GPU.Count > 0:
+		return false
+	case len(r.Command) == 0 || r.Hardened != warm.Hardened:
+		return false
+	case len(r.Secrets) > 0 || r.Hooks != nil || len(r.Inputs) > 0 || r.Script != nil || r.Gang != nil:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package domain
+
+// DefaultMaxArtifactBytes caps the total output a Wasm sandbox may write
+// through the artifact host functions when the grant sets no limit.
+const DefaultMaxArtifactBytes int64 = 16 << 20
+
+// WasmCapabilities grants a Wasm sandbox access to Tartarus host functions.
+// Everything not granted is denied. Grants come from the Themis policy and
+// are copied onto the request by Olympus; submitters cannot set them.
+type WasmCapabilities struct {
+	ReadArtifacts    []string `json:"read_artifacts,omitempty"`     // Input artifact names the module may read ("*" = any)
+	WriteArtifacts   []string `json:"write_artifacts,omitempty"`    // Output artifact names the module may write ("*" = any)
+	MaxArtifactBytes int64    `json:"max_artifact_bytes,omitempty"` // Total bytes the module may write (default DefaultMaxArtifactBytes)
+	EmitMetrics      bool     `json:"emit_metrics,omitempty"`       // Allow user metrics
+	EmitLogs         bool     `json:"emit_logs,omitempty"`          // Allow structured log lines
+}
+
+// CanRead reports whether the input artifact name is granted.
+func (c *WasmCapabilities) CanRead(name string) bool {
+	return c != nil && grants(c.ReadArtifacts, name)
+}
+
+// CanWrite reports whether the output artifact name is granted.
+func (c *WasmCapabilities) CanWrite(name string) bool {
+	return c != nil && grants(c.WriteArtifacts, name)
+}
+
+// WriteLimit returns the total number of bytes the module may write.
+func (c *WasmCapabilities) WriteLimit() int64 {
+	if c == nil || c.MaxArtifactBytes <= 0 {
+		return DefaultMaxArtifactBytes
+	}
+	return c.MaxArtifactBytes
+}
+
+func grants(list []string, name string) bool {
+	for _, g := range list {
+		if g == "*" || g == name {
+			return true
+		}
+	}
+	return false
+}
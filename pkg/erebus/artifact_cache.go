@@ -0,0 +1,111 @@
+package erebus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ArtifactKey is the Store key of an input artifact with the given digest.
+func ArtifactKey(digest string) string {
+	return "artifacts/" + digestHex(digest)
+}
+
+// ArtifactCache keeps input artifacts on a node, one file per sha256 digest.
+// Agents report its contents in their heartbeats so Moirai can send jobs to
+// the nodes that already hold their inputs.
+type ArtifactCache struct {
+	Store Store
+	Dir   string
+}
+
+// NewArtifactCache creates an artifact cache rooted at dir that fetches
+// missing artifacts from store.
+func NewArtifactCache(store Store, dir string) (*ArtifactCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating artifact cache directory: %w", err)
+	}
+	return &ArtifactCache{Store: store, Dir: dir}, nil
+}
+
+// Fetch copies the artifact from the Store unless it is already cached, and
+// verifies its digest.
+func (c *ArtifactCache) Fetch(ctx context.Context, digest string) (*domain.CachedArtifact, error) {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("unsupported artifact digest %q", digest)
+	}
+	if cached, err := c.stat(digest); err == nil {
+		return cached, nil
+	}
+
+	r, err := c.Store.Get(ctx, ArtifactKey(digest))
+	if err != nil {
+		return nil, fmt.Errorf("fetching artifact %s: %w", digest, err)
+	}
+	defer r.Close()
+
+	tmp, err := os.CreateTemp(c.Dir, "tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		return nil, fmt.Errorf("fetching artifact %s: %w", digest, err)
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != digest {
+		return nil, fmt.Errorf("artifact %s has digest %s", digest, got)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), c.Path(digest)); err != nil {
+		return nil, fmt.Errorf("committing cached artifact: %w", err)
+	}
+	return c.stat(digest)
+}
+
+// List returns the cached artifacts, most recently cached first.
+func (c *ArtifactCache) List() ([]domain.CachedArtifact, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := make([]domain.CachedArtifact, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), "tmp-") {
+			continue
+		}
+		cached, err := c.stat("sha256:" + e.Name())
+		if err != nil {
+			continue
+		}
+		artifacts = append(artifacts, *cached)
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].CachedAt.After(artifacts[j].CachedAt) })
+	return artifacts, nil
+}
+
+// Path returns the cache file of an artifact digest ("sha256:...").
+func (c *ArtifactCache) Path(digest string) string {
+	return filepath.Join(c.Dir, filepath.Base(digestHex(digest)))
+}
+
+func (c *ArtifactCache) stat(digest string) (*domain.CachedArtifact, error) {
+	info, err := os.Stat(c.Path(digest))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.CachedArtifact{Digest: digest, Bytes: info.Size(), CachedAt: info.ModTime()}, nil
+}
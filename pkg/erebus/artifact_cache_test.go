@@ -0,0 +1,61 @@
+package erebus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactCache_Fetch(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	data := "training data"
+	sum := sha256.Sum256([]byte(data))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	require.NoError(t, store.Put(ctx, ArtifactKey(digest), strings.NewReader(data)))
+
+	cache, err := NewArtifactCache(store, filepath.Join(t.TempDir(), "artifacts"))
+	require.NoError(t, err)
+
+	cached, err := cache.Fetch(ctx, digest)
+	require.NoError(t, err)
+	assert.Equal(t, digest, cached.Digest)
+	assert.Equal(t, int64(len(data)), cached.Bytes)
+	assert.FileExists(t, cache.Path(digest))
+
+	// Cached artifacts are served without the Store
+	require.NoError(t, store.Delete(ctx, ArtifactKey(digest)))
+	_, err = cache.Fetch(ctx, digest)
+	require.NoError(t, err)
+
+	artifacts, err := cache.List()
+	require.NoError(t, err)
+	require.Len(t, artifacts, 1)
+	assert.Equal(t, digest, artifacts[0].Digest)
+}
+
+func TestArtifactCache_RejectsDigestMismatch(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	digest := "sha256:" + strings.Repeat("0", 64)
+	require.NoError(t, store.Put(ctx, ArtifactKey(digest), strings.NewReader("tampered")))
+
+	cache, err := NewArtifactCache(store, t.TempDir())
+	require.NoError(t, err)
+
+	_, err = cache.Fetch(ctx, digest)
+	assert.Error(t, err)
+	artifacts, err := cache.List()
+	require.NoError(t, err)
+	assert.Empty(t, artifacts, "a mismatched artifact is not cached")
+}
@@ -0,0 +1,164 @@
+package erebus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+const imageMetadataFile = "image.json"
+
+// ImageCache pre-pulls and assembles OCI images into a node-local directory,
+// one subdirectory per manifest digest. The layers land in the builder's
+// Store, so a later template build for the same image is a cache hit.
+type ImageCache struct {
+	Builder *OCIBuilder
+	Dir     string
+
+	// Now is the clock used to stamp pulls (time.Now if nil).
+	Now func() time.Time
+
+	// Prefetches are serialized; an image is assembled at most once.
+	mu sync.Mutex
+}
+
+// NewImageCache creates an image cache rooted at dir.
+func NewImageCache(builder *OCIBuilder, dir string) (*ImageCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating image cache directory: %w", err)
+	}
+	return &ImageCache{Builder: builder, Dir: dir}, nil
+}
+
+// Prefetch pulls and assembles the image unless its digest is already
+// cached. A ref that moved to a new digest is assembled again.
+func (c *ImageCache) Prefetch(ctx context.Context, ref string) (*domain.CachedImage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	img, err := c.Builder.Pull(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("resolving digest of %s: %w", ref, err)
+	}
+
+	dir := c.Path(digest.String())
+	if cached, err := readCachedImage(dir); err == nil {
+		if cached.Ref == ref {
+			return cached, nil
+		}
+		// Same content under another ref; record the new name
+		cached.Ref = ref
+		return cached, writeCachedImage(dir, cached)
+	}
+
+	// Assemble next to the final location and rename, so a crash never
+	// leaves a half-extracted image that looks cached
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, err
+	}
+	if err := c.Builder.AssembleImage(ctx, img, filepath.Join(tmp, "rootfs")); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+
+	cached := &domain.CachedImage{Ref: ref, Digest: digest.String(), Bytes: layerBytes(img), PulledAt: c.now()}
+	if err := writeCachedImage(tmp, cached); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		os.RemoveAll(tmp)
+		return nil, fmt.Errorf("committing cached image: %w", err)
+	}
+	return cached, nil
+}
+
+// List returns the cached images, most recently pulled first.
+func (c *ImageCache) List() ([]domain.CachedImage, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]domain.CachedImage, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() || filepath.Ext(e.Name()) == ".tmp" {
+			continue
+		}
+		cached, err := readCachedImage(filepath.Join(c.Dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		images = append(images, *cached)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].PulledAt.After(images[j].PulledAt) })
+	return images, nil
+}
+
+// Path returns the cache directory of an image digest ("sha256:...").
+// The assembled root filesystem is in its rootfs subdirectory.
+func (c *ImageCache) Path(digest string) string {
+	return filepath.Join(c.Dir, filepath.Base(digestHex(digest)))
+}
+
+func (c *ImageCache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// layerBytes returns the compressed size of the image's layers, or zero if
+// the manifest cannot be read.
+func layerBytes(img v1.Image) int64 {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, layer := range manifest.Layers {
+		total += layer.Size
+	}
+	return total
+}
+
+func digestHex(digest string) string {
+	if _, hex, ok := strings.Cut(digest, ":"); ok {
+		return hex
+	}
+	return digest
+}
+
+func readCachedImage(dir string) (*domain.CachedImage, error) {
+	data, err := os.ReadFile(filepath.Join(dir, imageMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var cached domain.CachedImage
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+func writeCachedImage(dir string, cached *domain.CachedImage) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, imageMetadataFile), data, 0644)
+}
@@ -0,0 +1,81 @@
+package erebus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageCache_Prefetch(t *testing.T) {
+	img, err := random.Image(64, 2)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	scanner := new(TestMockScanner)
+	scanner.On("Scan", mock.Anything, mock.Anything).Return(nil)
+
+	builder := NewOCIBuilder(store, nil)
+	builder.Scanner = scanner
+	pulls := 0
+	builder.Fetcher = func(ctx context.Context, ref string) (v1.Image, error) {
+		pulls++
+		return img, nil
+	}
+
+	cache, err := NewImageCache(builder, filepath.Join(t.TempDir(), "images"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	cached, err := cache.Prefetch(ctx, "registry.example.com/app:v1")
+	require.NoError(t, err)
+	assert.Equal(t, digest.String(), cached.Digest)
+	assert.Positive(t, cached.Bytes, "layer sizes are recorded for locality-aware scheduling")
+	assert.DirExists(t, filepath.Join(cache.Path(cached.Digest), "rootfs"))
+
+	// Layers are in the Store for later template builds
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	for _, l := range layers {
+		d, err := l.Digest()
+		require.NoError(t, err)
+		exists, err := store.Exists(ctx, "layers/"+d.Hex)
+		require.NoError(t, err)
+		assert.True(t, exists)
+	}
+
+	// The same digest under another ref is not assembled again
+	_, err = cache.Prefetch(ctx, "registry.example.com/app:latest")
+	require.NoError(t, err)
+	scanner.AssertNumberOfCalls(t, "Scan", 1)
+	assert.Equal(t, 2, pulls)
+
+	images, err := cache.List()
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, "registry.example.com/app:latest", images[0].Ref)
+	assert.Equal(t, digest.String(), images[0].Digest)
+}
+
+func TestImageCache_ListSkipsIncomplete(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewImageCache(NewOCIBuilder(nil, nil), dir)
+	require.NoError(t, err)
+
+	// An interrupted prefetch leaves only a .tmp directory
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "abc.tmp", "rootfs"), 0755))
+
+	images, err := cache.List()
+	require.NoError(t, err)
+	assert.Empty(t, images)
+}
@@ -0,0 +1,234 @@
+package erebus
+
+import (
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// ErrInitMismatch is returned when the injected init cannot run in the target image.
+var ErrInitMismatch = errors.New("init binary does not match image")
+
+// Libc identifies the C library an image or binary is built against.
+type Libc string
+
+const (
+	LibcStatic Libc = "static"
+	LibcGlibc  Libc = "glibc"
+	LibcMusl   Libc = "musl"
+)
+
+// elfMachines maps OCI/GOARCH architecture names to ELF machine types.
+var elfMachines = map[string]elf.Machine{
+	"amd64":   elf.EM_X86_64,
+	"arm64":   elf.EM_AARCH64,
+	"386":     elf.EM_386,
+	"arm":     elf.EM_ARM,
+	"riscv64": elf.EM_RISCV,
+	"ppc64le": elf.EM_PPC64,
+	"s390x":   elf.EM_S390,
+}
+
+// qemuArch maps OCI architecture names to qemu-user binary suffixes.
+var qemuArch = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"386":     "i386",
+	"arm":     "arm",
+	"riscv64": "riscv64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// ImageArch returns the architecture declared in the image config.
+func ImageArch(img v1.Image) (string, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return "", fmt.Errorf("reading image config: %w", err)
+	}
+	return cfg.Architecture, nil
+}
+
+// initCandidates lists the init binaries to try for the given architecture,
+// most specific first.
+func (b *OCIBuilder) initCandidates(arch string) []string {
+	candidates := []string{}
+
+	// 1. Per-architecture binaries
+	if arch != "" {
+		if path, ok := b.InitPaths[arch]; ok {
+			candidates = append(candidates, path)
+		}
+		if b.InitPath != "" {
+			candidates = append(candidates, b.InitPath+"-"+arch)
+		}
+		if exe, err := os.Executable(); err == nil {
+			candidates = append(candidates, filepath.Join(filepath.Dir(exe), "tartarus-init-"+arch))
+		}
+		candidates = append(candidates, "/usr/local/bin/tartarus-init-"+arch)
+		candidates = append(candidates, filepath.Join("/opt/tartarus/bin", arch, "init"))
+	}
+
+	// 2. Configured path (or default "init")
+	if b.InitPath != "" {
+		candidates = append(candidates, b.InitPath)
+	} else {
+		candidates = append(candidates, "init")
+	}
+
+	// 3. Path relative to the current executable
+	if exe, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(exe), "init"))
+		candidates = append(candidates, filepath.Join(filepath.Dir(exe), "tartarus-init"))
+	}
+
+	// 4. Common system paths
+	candidates = append(candidates, "/usr/local/bin/tartarus-init")
+	candidates = append(candidates, "/opt/tartarus/bin/init")
+
+	// 5. LookPath
+	if path, err := exec.LookPath("tartarus-init"); err == nil {
+		candidates = append(candidates, path)
+	}
+
+	return candidates
+}
+
+// VerifyInit checks that the ELF binary at path targets arch and that, if it
+// is dynamically linked, the rootfs provides the matching libc.
+func VerifyInit(path, arch, rootfs string) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %s is not an ELF executable: %v", ErrInitMismatch, path, err)
+	}
+	defer f.Close()
+
+	if want, ok := elfMachines[arch]; ok && f.Machine != want {
+		return fmt.Errorf("%w: init is %s, image is %s", ErrInitMismatch, f.Machine, arch)
+	}
+
+	binLibc := binaryLibc(f)
+	if binLibc == LibcStatic {
+		return nil
+	}
+	if imgLibc := RootfsLibc(rootfs); imgLibc != binLibc {
+		return fmt.Errorf("%w: init needs %s, image provides %s", ErrInitMismatch, binLibc, imgLibc)
+	}
+	return nil
+}
+
+// binaryLibc infers the libc an ELF binary needs from its interpreter.
+func binaryLibc(f *elf.File) Libc {
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		buf := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(buf, 0); err != nil {
+			return LibcGlibc
+		}
+		if strings.Contains(string(buf), "musl") {
+			return LibcMusl
+		}
+		return LibcGlibc
+	}
+	return LibcStatic
+}
+
+// RootfsLibc detects the dynamic loader shipped in an extracted rootfs.
+// Images without a loader (distroless static, scratch) report LibcStatic.
+func RootfsLibc(rootfs string) Libc {
+	if matches, _ := filepath.Glob(filepath.Join(rootfs, "lib", "ld-musl-*.so.1")); len(matches) > 0 {
+		return LibcMusl
+	}
+	for _, pattern := range []string{"lib/ld-linux*.so*", "lib64/ld-linux*.so*", "lib/*/ld-linux*.so*", "usr/lib/ld-linux*.so*"} {
+		if matches, _ := filepath.Glob(filepath.Join(rootfs, pattern)); len(matches) > 0 {
+			return LibcGlibc
+		}
+	}
+	return LibcStatic
+}
+
+// SmokeTest checks that the assembled rootfs can execute binaries for its
+// architecture. Native images are exercised through chroot (requires root);
+// foreign images through qemu-user when it is installed. The check is skipped
+// when neither is possible or the image has no shell.
+func (b *OCIBuilder) SmokeTest(ctx context.Context, rootfs, arch string) error {
+	if _, err := os.Stat(filepath.Join(rootfs, "bin", "sh")); err != nil {
+		b.logSmokeSkip(ctx, rootfs, "no /bin/sh in image")
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	switch {
+	case arch == "" || arch == runtime.GOARCH:
+		if os.Geteuid() != 0 {
+			b.logSmokeSkip(ctx, rootfs, "chroot requires root")
+			return nil
+		}
+		cmd = exec.CommandContext(ctx, "chroot", rootfs, "/bin/sh", "-c", "true")
+	default:
+		qemu, err := exec.LookPath("qemu-" + qemuArch[arch])
+		if err != nil {
+			qemu, err = exec.LookPath("qemu-" + qemuArch[arch] + "-static")
+		}
+		if err != nil {
+			b.logSmokeSkip(ctx, rootfs, "qemu-user not installed for "+arch)
+			return nil
+		}
+		cmd = exec.CommandContext(ctx, qemu, "-L", rootfs, filepath.Join(rootfs, "bin", "sh"), "-c", "true")
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("rootfs smoke test failed: %w, output: %s", err, string(output))
+	}
+	if b.Logger != nil {
+		b.Logger.Info(ctx, "Rootfs smoke test passed", map[string]any{"rootfs": rootfs, "arch": arch})
+	}
+	return nil
+}
+
+func (b *OCIBuilder) logSmokeSkip(ctx context.Context, rootfs, reason string) {
+	if b.Logger != nil {
+		b.Logger.Info(ctx, "Skipping rootfs smoke test", map[string]any{"rootfs": rootfs, "reason": reason})
+	}
+}
+
+// GuestAgentRootfsPath is where InjectGuestAgent installs the guest agent.
+const GuestAgentRootfsPath = "/sbin/tartarus-guest-agent"
+
+// InjectGuestAgent installs the configured guest agent binary in the rootfs,
+// after checking it can run there. It does nothing without GuestAgentPath.
+func (b *OCIBuilder) InjectGuestAgent(ctx context.Context, outputDir, arch string) error {
+	if b.GuestAgentPath == "" {
+		return nil
+	}
+	if arch != "" {
+		if err := VerifyInit(b.GuestAgentPath, arch, outputDir); err != nil {
+			return err
+		}
+	}
+	dest := filepath.Join(outputDir, GuestAgentRootfsPath)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(b.GuestAgentPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dest, data, 0755); err != nil {
+		return err
+	}
+	if b.Logger != nil {
+		b.Logger.Info(ctx, "Installed guest agent", map[string]any{"path": b.GuestAgentPath, "arch": arch})
+	}
+	return nil
+}
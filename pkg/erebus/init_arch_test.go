@@ -0,0 +1,149 @@
+package erebus
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeELF writes a minimal ELF64 executable header for machine. A non-empty
+// interp adds a PT_INTERP segment, making the binary dynamically linked.
+func writeELF(t *testing.T, path string, machine elf.Machine, interp string) {
+	t.Helper()
+
+	hdr := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Ehsize:    64,
+		Phentsize: 56,
+		Shentsize: 64,
+	}
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	if interp != "" {
+		hdr.Phoff = 64
+		hdr.Phnum = 1
+	}
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, hdr))
+	if interp != "" {
+		data := append([]byte(interp), 0)
+		prog := elf.Prog64{
+			Type:   uint32(elf.PT_INTERP),
+			Flags:  uint32(elf.PF_R),
+			Off:    64 + 56,
+			Filesz: uint64(len(data)),
+			Memsz:  uint64(len(data)),
+			Align:  1,
+		}
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, prog))
+		buf.Write(data)
+	}
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0755))
+}
+
+func makeRootfs(t *testing.T, loader string) string {
+	t.Helper()
+	rootfs := t.TempDir()
+	if loader != "" {
+		path := filepath.Join(rootfs, loader)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0755))
+	}
+	return rootfs
+}
+
+func TestVerifyInit(t *testing.T) {
+	dir := t.TempDir()
+	staticArm := filepath.Join(dir, "init-arm64")
+	writeELF(t, staticArm, elf.EM_AARCH64, "")
+	muslAmd := filepath.Join(dir, "init-musl")
+	writeELF(t, muslAmd, elf.EM_X86_64, "/lib/ld-musl-x86_64.so.1")
+	script := filepath.Join(dir, "init.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"), 0755))
+
+	glibcRoot := makeRootfs(t, "lib64/ld-linux-x86-64.so.2")
+	muslRoot := makeRootfs(t, "lib/ld-musl-x86_64.so.1")
+
+	tests := []struct {
+		name    string
+		path    string
+		arch    string
+		rootfs  string
+		wantErr bool
+	}{
+		{"StaticMatchingArch", staticArm, "arm64", glibcRoot, false},
+		{"StaticWrongArch", staticArm, "amd64", glibcRoot, true},
+		{"MuslOnMusl", muslAmd, "amd64", muslRoot, false},
+		{"MuslOnGlibc", muslAmd, "amd64", glibcRoot, true},
+		{"NotELF", script, "amd64", glibcRoot, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyInit(tt.path, tt.arch, tt.rootfs)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInitMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRootfsLibc(t *testing.T) {
+	assert.Equal(t, LibcMusl, RootfsLibc(makeRootfs(t, "lib/ld-musl-aarch64.so.1")))
+	assert.Equal(t, LibcGlibc, RootfsLibc(makeRootfs(t, "lib/aarch64-linux-gnu/ld-linux-aarch64.so.1")))
+	assert.Equal(t, LibcStatic, RootfsLibc(makeRootfs(t, "")))
+}
+
+func TestOCIBuilder_InjectInitForArch_SelectsPerArchBinary(t *testing.T) {
+	dir := t.TempDir()
+	amdInit := filepath.Join(dir, "init-amd64")
+	armInit := filepath.Join(dir, "init-arm64")
+	writeELF(t, amdInit, elf.EM_X86_64, "")
+	writeELF(t, armInit, elf.EM_AARCH64, "")
+
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	builder := NewOCIBuilder(store, nil)
+	builder.InitPath = amdInit
+	builder.InitPaths = map[string]string{"arm64": armInit}
+
+	outputDir := t.TempDir()
+	require.NoError(t, builder.InjectInitForArch(context.Background(), outputDir, "arm64"))
+
+	f, err := elf.Open(filepath.Join(outputDir, "init"))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, elf.EM_AARCH64, f.Machine)
+}
+
+func TestOCIBuilder_InjectInitForArch_RejectsMismatch(t *testing.T) {
+	dir := t.TempDir()
+	amdInit := filepath.Join(dir, "init")
+	writeELF(t, amdInit, elf.EM_X86_64, "")
+
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	builder := NewOCIBuilder(store, nil)
+	builder.InitPath = amdInit
+
+	outputDir := t.TempDir()
+	err = builder.InjectInitForArch(context.Background(), outputDir, "arm64")
+	assert.ErrorIs(t, err, ErrInitMismatch)
+	assert.NoFileExists(t, filepath.Join(outputDir, "init"))
+}
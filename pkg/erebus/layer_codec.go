@@ -0,0 +1,106 @@
+package erebus
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs cached layers are compressed with. Layers are pulled gzip-compressed
+// and may be recompressed to zstd, which decompresses several times faster;
+// either way they stay keyed by the digest they were pulled with.
+const (
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+)
+
+// ErrUnknownLayerCodec is returned when a cached layer is neither gzip- nor
+// zstd-compressed.
+var ErrUnknownLayerCodec = errors.New("unknown layer compression")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// layerCodec returns the codec of a cached layer from its first bytes.
+func layerCodec(header []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(header, zstdMagic):
+		return CodecZstd, nil
+	case bytes.HasPrefix(header, gzipMagic):
+		return CodecGzip, nil
+	}
+	return "", ErrUnknownLayerCodec
+}
+
+// decompressLayer returns the tar stream of a cached layer, whichever codec
+// it is stored with, and the codec.
+func decompressLayer(r io.Reader) (io.ReadCloser, string, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(zstdMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", fmt.Errorf("reading layer header: %w", err)
+	}
+	codec, err := layerCodec(header)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if codec == CodecZstd {
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("creating zstd reader: %w", err)
+		}
+		return zr.IOReadCloser(), codec, nil
+	}
+	gr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating gzip reader: %w", err)
+	}
+	return gr, codec, nil
+}
+
+// compressZstd streams r zstd-compressed. Closing the returned reader closes
+// r; a read error from r is returned by the reader, so a failed source is
+// never mistaken for a complete one.
+func compressZstd(r io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer r.Close()
+		enc, err := zstd.NewWriter(pw)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(enc, r); err != nil {
+			enc.Close()
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(enc.Close())
+	}()
+	return pr
+}
+
+// timedReader counts the bytes read through it and the time spent reading
+// them, so decompression can be timed apart from what consumes its output.
+type timedReader struct {
+	r       io.Reader
+	n       int64
+	elapsed time.Duration
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(p)
+	t.elapsed += time.Since(start)
+	t.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,156 @@
+package erebus
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// codecMetrics records the codec label of decompression observations and
+// the results of recompressions.
+type codecMetrics struct {
+	decompressed []string
+	results      map[string]int
+}
+
+func (m *codecMetrics) IncCounter(name string, value float64, labels ...hermes.Label) {
+	if name != "erebus_layers_recompressed_total" {
+		return
+	}
+	if m.results == nil {
+		m.results = make(map[string]int)
+	}
+	m.results[labels[0].Value]++
+}
+
+func (m *codecMetrics) ObserveHistogram(name string, value float64, labels ...hermes.Label) {
+	if name == "erebus_layer_decompress_seconds" {
+		m.decompressed = append(m.decompressed, labels[0].Value)
+	}
+}
+
+func (m *codecMetrics) SetGauge(name string, value float64, labels ...hermes.Label) {}
+
+// fileLayer returns a gzip layer holding a single file.
+func fileLayer(t *testing.T, name, content string) v1.Layer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	require.NoError(t, err)
+	return layer
+}
+
+func storedCodec(t *testing.T, store Store, key string) string {
+	t.Helper()
+	rc, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer rc.Close()
+	header := make([]byte, len(zstdMagic))
+	_, err = io.ReadFull(rc, header)
+	require.NoError(t, err)
+	codec, err := layerCodec(header)
+	require.NoError(t, err)
+	return codec
+}
+
+func newCodecBuilder(t *testing.T, store Store, img v1.Image) (*OCIBuilder, *codecMetrics) {
+	t.Helper()
+	metrics := &codecMetrics{}
+	builder := NewOCIBuilder(store, hermes.NewNoopLogger())
+	builder.Scanner = nil
+	builder.InitPath = filepath.Join(t.TempDir(), "init")
+	require.NoError(t, os.WriteFile(builder.InitPath, []byte("#!/bin/sh\n"), 0755))
+	builder.Metrics = metrics
+	builder.Fetcher = func(ctx context.Context, ref string) (v1.Image, error) {
+		return img, nil
+	}
+	return builder, metrics
+}
+
+func TestOCIBuilder_Assemble_Zstd(t *testing.T) {
+	layer := fileLayer(t, "hello.txt", "hello zstd")
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	digest, err := layer.Digest()
+	require.NoError(t, err)
+
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	builder, metrics := newCodecBuilder(t, store, img)
+	builder.CompressZstd = true
+
+	outputDir := t.TempDir()
+	require.NoError(t, builder.Assemble(context.Background(), "fake.registry/repo:tag", outputDir))
+
+	// Cached zstd-compressed under the digest it was pulled with
+	assert.Equal(t, CodecZstd, storedCodec(t, store, LayerArtifact(digest.String())))
+	data, err := os.ReadFile(filepath.Join(outputDir, "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello zstd", string(data))
+	assert.Equal(t, []string{CodecZstd}, metrics.decompressed)
+}
+
+func TestLayerRecompressor(t *testing.T) {
+	ctx := context.Background()
+	layer := fileLayer(t, "hello.txt", "hello gzip")
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	digest, err := layer.Digest()
+	require.NoError(t, err)
+	key := LayerArtifact(digest.String())
+
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	builder, metrics := newCodecBuilder(t, store, img)
+	require.NoError(t, builder.Assemble(ctx, "fake.registry/repo:tag", t.TempDir()))
+	assert.Equal(t, CodecGzip, storedCodec(t, store, key))
+	require.NoError(t, store.Put(ctx, "layers/corrupt", bytes.NewReader([]byte{0x1f, 0x8b, 0x08, 0x00, 0x01})))
+	require.NoError(t, store.Put(ctx, "snapshots/tpl/snap.mem", bytes.NewReader([]byte("memory"))))
+
+	recompressor := NewLayerRecompressor(store, hermes.NewNoopLogger(), metrics)
+	n, err := recompressor.RecompressAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, map[string]int{"ok": 1, "error": 1}, metrics.results)
+	assert.Equal(t, CodecZstd, storedCodec(t, store, key))
+
+	// A corrupt layer is kept as it was
+	rc, err := store.Get(ctx, "layers/corrupt")
+	require.NoError(t, err)
+	corrupt, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, []byte{0x1f, 0x8b, 0x08, 0x00, 0x01}, corrupt)
+
+	// Already zstd layers are left alone
+	n, err = recompressor.RecompressAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// The recompressed layer is read from the cache transparently
+	outputDir := t.TempDir()
+	require.NoError(t, builder.Assemble(ctx, "fake.registry/repo:tag", outputDir))
+	data, err := os.ReadFile(filepath.Join(outputDir, "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello gzip", string(data))
+	assert.Equal(t, []string{CodecGzip, CodecZstd}, metrics.decompressed)
+}
@@ -0,0 +1,137 @@
+package erebus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// DefaultRecompressInterval is how often LayerRecompressor looks for layers
+// to recompress.
+const DefaultRecompressInterval = time.Hour
+
+// LayerRecompressor migrates the layer cache to zstd in the background: it
+// rewrites each gzip-compressed layer zstd-compressed under the same key,
+// one layer at a time. The gzip stream is checked as it is read, so a
+// corrupt layer fails to recompress and is kept as it is. Readers opened
+// before a layer is rewritten keep reading the old copy.
+//
+// Layers are counted in erebus_layers_recompressed_total{result}.
+type LayerRecompressor struct {
+	Store    Store // Must implement Lister
+	Interval time.Duration
+	Logger   hermes.Logger
+	Metrics  hermes.Metrics // Optional
+}
+
+// NewLayerRecompressor creates a recompressor of the layers in store.
+func NewLayerRecompressor(store Store, logger hermes.Logger, metrics hermes.Metrics) *LayerRecompressor {
+	return &LayerRecompressor{
+		Store:    store,
+		Interval: DefaultRecompressInterval,
+		Logger:   logger,
+		Metrics:  metrics,
+	}
+}
+
+// Run recompresses the cache at once, then every Interval, until ctx is
+// done. New layers keep arriving gzip-compressed from builders that do not
+// compress them to zstd themselves.
+func (r *LayerRecompressor) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultRecompressInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RecompressAll(ctx); err != nil && ctx.Err() == nil {
+			r.Logger.Error(ctx, "Failed to recompress layer cache", map[string]any{"error": err})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecompressAll recompresses every gzip-compressed layer in the Store and
+// returns how many it recompressed. A layer that fails is logged and left
+// for the next pass.
+func (r *LayerRecompressor) RecompressAll(ctx context.Context) (int, error) {
+	lister, ok := r.Store.(Lister)
+	if !ok {
+		return 0, fmt.Errorf("store does not list objects")
+	}
+	keys, err := lister.List(ctx, "layers/")
+	if err != nil {
+		return 0, fmt.Errorf("listing layers: %w", err)
+	}
+
+	recompressed := 0
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return recompressed, err
+		}
+		ok, err := r.Recompress(ctx, key)
+		if err != nil {
+			r.observe("error")
+			r.Logger.Error(ctx, "Failed to recompress layer", map[string]any{"key": key, "error": err})
+			continue
+		}
+		if ok {
+			r.observe("ok")
+			recompressed++
+		}
+	}
+	if recompressed > 0 {
+		r.Logger.Info(ctx, "Recompressed cached layers to zstd", map[string]any{"count": recompressed, "layers": len(keys)})
+	}
+	return recompressed, nil
+}
+
+// Recompress rewrites the layer under key zstd-compressed. It reports false
+// if the layer already is.
+func (r *LayerRecompressor) Recompress(ctx context.Context, key string) (bool, error) {
+	rc, err := r.Store.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	read := &countingReader{r: rc}
+	tarReader, codec, err := decompressLayer(read)
+	if err != nil {
+		return false, fmt.Errorf("layer %s: %w", key, err)
+	}
+	if codec == CodecZstd {
+		tarReader.Close()
+		return false, nil
+	}
+
+	zstdReader := compressZstd(tarReader)
+	defer zstdReader.Close()
+	written := &countingReader{r: zstdReader}
+	start := time.Now()
+	if err := r.Store.Put(ctx, key, written); err != nil {
+		return false, fmt.Errorf("writing recompressed layer %s: %w", key, err)
+	}
+	r.Logger.Info(ctx, "Recompressed layer", map[string]any{
+		"key":        key,
+		"gzip_bytes": read.n,
+		"zstd_bytes": written.n,
+		"duration":   time.Since(start).String(),
+	})
+	return true, nil
+}
+
+func (r *LayerRecompressor) observe(result string) {
+	if r.Metrics == nil {
+		return
+	}
+	r.Metrics.IncCounter("erebus_layers_recompressed_total", 1, hermes.Label{Key: "result", Value: result})
+}
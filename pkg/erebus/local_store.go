@@ -2,9 +2,12 @@ package erebus
 
 import (
 	"context"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type LocalStore struct {
@@ -65,3 +68,50 @@ func (s *LocalStore) Delete(ctx context.Context, key string) error {
 	path := filepath.Join(s.BasePath, key)
 	return os.Remove(path)
 }
+
+// Size walks the files under the prefix. A missing prefix has size zero.
+func (s *LocalStore) Size(ctx context.Context, prefix string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(filepath.Join(s.BasePath, prefix), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return total, err
+}
+
+// List walks the files under the prefix, skipping writes in progress. A
+// missing prefix has no keys.
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(filepath.Join(s.BasePath, prefix), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), "tmp-") {
+			return nil
+		}
+		key, err := filepath.Rel(s.BasePath, path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(key))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return keys, err
+}
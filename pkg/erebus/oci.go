@@ -2,7 +2,6 @@ package erebus
 
 import (
 	"archive/tar"
-	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
@@ -33,6 +32,32 @@ type OCIBuilder struct {
 	Fetcher  ImageFetcher
 	Scanner  Scanner
 	InitPath string
+
+	// InitPaths selects an init binary per image architecture ("amd64", "arm64").
+	// InitPath is used when the architecture has no entry.
+	InitPaths map[string]string
+
+	// GuestAgentPath, if set, is the guest agent binary installed in every
+	// assembled rootfs at GuestAgentRootfsPath, for runtimes that exec and
+	// health-check sandboxes through it.
+	GuestAgentPath string
+
+	// ValidateRootFS runs a post-assembly exec smoke test of the rootfs.
+	ValidateRootFS bool
+
+	// Refs, if set, records that each assembled image owns its cached
+	// layers, so the layers are kept while anything uses the image.
+	Refs *RefCounter
+
+	// CompressZstd caches pulled layers zstd-compressed instead of as
+	// pulled, still keyed by their original digest. Layers cached either
+	// way are read transparently.
+	CompressZstd bool
+
+	// Metrics, if set, times layer decompression in
+	// erebus_layer_decompress_seconds{codec} and
+	// erebus_layer_decompress_bytes_per_second{codec}.
+	Metrics hermes.Metrics
 }
 
 // NewOCIBuilder creates a new OCIBuilder.
@@ -67,7 +92,12 @@ func (b *OCIBuilder) Assemble(ctx context.Context, ref string, outputDir string)
 	if err != nil {
 		return err
 	}
+	return b.AssembleImage(ctx, img, outputDir)
+}
 
+// AssembleImage extracts the layers of an already pulled image to the output
+// directory, caching them in the Store, and injects the init binary.
+func (b *OCIBuilder) AssembleImage(ctx context.Context, img v1.Image, outputDir string) error {
 	// Ensure output directory exists
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("creating output directory: %w", err)
@@ -126,9 +156,18 @@ func (b *OCIBuilder) Assemble(ctx context.Context, ref string, outputDir string)
 
 			if !exists {
 				if b.Logger != nil {
-					b.Logger.Info(ctx, "Cache miss for layer, downloading", map[string]any{"digest": digest.String()})
+					b.Logger.Info(ctx, "Cache miss for layer, downloading", map[string]any{"digest": digest.String(), "zstd": b.CompressZstd})
+				}
+				var compressed io.ReadCloser
+				if b.CompressZstd {
+					var uncompressed io.ReadCloser
+					uncompressed, err = layer.Uncompressed()
+					if err == nil {
+						compressed = compressZstd(uncompressed)
+					}
+				} else {
+					compressed, err = layer.Compressed()
 				}
-				compressed, err := layer.Compressed()
 				if err != nil {
 					layerReady[i] <- err
 					return err
@@ -177,22 +216,24 @@ func (b *OCIBuilder) Assemble(ctx context.Context, ref string, outputDir string)
 			}
 
 			// Decompress
-			gzipReader, err := gzip.NewReader(rc)
+			tarReader, codec, err := decompressLayer(rc)
 			if err != nil {
 				rc.Close()
-				return fmt.Errorf("creating gzip reader: %w", err)
+				return fmt.Errorf("decompressing layer %s: %w", digest, err)
 			}
 
-			err = untar(gzipReader, outputDir)
-			gzipReader.Close()
+			timed := &timedReader{r: tarReader}
+			err = untar(timed, outputDir)
+			tarReader.Close()
 			rc.Close()
 
 			if err != nil {
 				return fmt.Errorf("extracting layer %s: %w", digest, err)
 			}
+			b.observeDecompress(codec, timed)
 
 			if b.Logger != nil {
-				b.Logger.Info(ctx, "Extracted layer", map[string]any{"index": i, "digest": digest.String()})
+				b.Logger.Info(ctx, "Extracted layer", map[string]any{"index": i, "digest": digest.String(), "codec": codec})
 			}
 		}
 		return nil
@@ -211,10 +252,30 @@ func (b *OCIBuilder) Assemble(ctx context.Context, ref string, outputDir string)
 		return extractErr
 	}
 
+	if b.Refs != nil {
+		if err := b.acquireLayers(ctx, img, layers); err != nil {
+			return err
+		}
+	}
+
+	arch, err := ImageArch(img)
+	if err != nil {
+		return err
+	}
+
 	// Inject Init
-	if err := b.InjectInit(ctx, outputDir); err != nil {
+	if err := b.InjectInitForArch(ctx, outputDir, arch); err != nil {
 		return fmt.Errorf("injecting init: %w", err)
 	}
+	if err := b.InjectGuestAgent(ctx, outputDir, arch); err != nil {
+		return fmt.Errorf("injecting guest agent: %w", err)
+	}
+
+	if b.ValidateRootFS {
+		if err := b.SmokeTest(ctx, outputDir, arch); err != nil {
+			return err
+		}
+	}
 
 	// Scan the extracted directory
 	if b.Scanner != nil {
@@ -226,39 +287,54 @@ func (b *OCIBuilder) Assemble(ctx context.Context, ref string, outputDir string)
 	return nil
 }
 
-// InjectInit injects the init binary into the rootfs.
-func (b *OCIBuilder) InjectInit(ctx context.Context, outputDir string) error {
-	// List of potential paths to check
-	candidates := []string{}
-
-	// 1. Configured path (or default "init")
-	if b.InitPath != "" {
-		candidates = append(candidates, b.InitPath)
-	} else {
-		candidates = append(candidates, "init")
+// acquireLayers records the image as the owner of its layers.
+func (b *OCIBuilder) acquireLayers(ctx context.Context, img v1.Image, layers []v1.Layer) error {
+	digest, err := img.Digest()
+	if err != nil {
+		return fmt.Errorf("getting image digest: %w", err)
+	}
+	artifacts := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		layerDigest, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, LayerArtifact(layerDigest.String()))
 	}
+	if err := b.Refs.Acquire(ctx, ImageArtifact(digest.String()), artifacts...); err != nil {
+		return fmt.Errorf("recording layer references: %w", err)
+	}
+	return nil
+}
 
-	// 2. Path relative to the current executable
-	if exe, err := os.Executable(); err == nil {
-		candidates = append(candidates, filepath.Join(filepath.Dir(exe), "init"))
-		candidates = append(candidates, filepath.Join(filepath.Dir(exe), "tartarus-init"))
+// observeDecompress records how long decompressing a layer took, and at
+// what rate of uncompressed bytes.
+func (b *OCIBuilder) observeDecompress(codec string, r *timedReader) {
+	if b.Metrics == nil || r.elapsed <= 0 {
+		return
 	}
+	label := hermes.Label{Key: "codec", Value: codec}
+	b.Metrics.ObserveHistogram("erebus_layer_decompress_seconds", r.elapsed.Seconds(), label)
+	b.Metrics.ObserveHistogram("erebus_layer_decompress_bytes_per_second", float64(r.n)/r.elapsed.Seconds(), label)
+}
 
-	// 3. Common system paths
-	candidates = append(candidates, "/usr/local/bin/tartarus-init")
-	candidates = append(candidates, "/opt/tartarus/bin/init")
+// InjectInit injects the init binary into the rootfs without checking it
+// against the image architecture.
+func (b *OCIBuilder) InjectInit(ctx context.Context, outputDir string) error {
+	return b.InjectInitForArch(ctx, outputDir, "")
+}
 
-	// 4. LookPath
-	if path, err := exec.LookPath("tartarus-init"); err == nil {
-		candidates = append(candidates, path)
-	}
+// InjectInitForArch injects the init binary built for arch into the rootfs
+// and verifies that it matches the image architecture and libc.
+func (b *OCIBuilder) InjectInitForArch(ctx context.Context, outputDir, arch string) error {
+	candidates := b.initCandidates(arch)
 
 	var foundPath string
 	for _, path := range candidates {
 		if _, err := os.Stat(path); err == nil {
 			foundPath = path
 			if b.Logger != nil {
-				b.Logger.Info(ctx, "Found init binary", map[string]any{"path": path})
+				b.Logger.Info(ctx, "Found init binary", map[string]any{"path": path, "arch": arch})
 			}
 			break
 		}
@@ -271,6 +347,12 @@ func (b *OCIBuilder) InjectInit(ctx context.Context, outputDir string) error {
 		return nil
 	}
 
+	if arch != "" {
+		if err := VerifyInit(foundPath, arch, outputDir); err != nil {
+			return err
+		}
+	}
+
 	dest := filepath.Join(outputDir, "init")
 	srcFile, err := os.Open(foundPath)
 	if err != nil {
@@ -0,0 +1,296 @@
+package erebus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ErrArtifactInUse is returned when deleting an artifact something still references.
+var ErrArtifactInUse = errors.New("artifact is in use")
+
+// Artifacts are named by their Store key, or key prefix for those stored as
+// several objects. An artifact can own others: a template owns its
+// snapshots, a snapshot the image it was built from, and an image its
+// layers. Artifacts are only deleted once nothing owns them.
+
+// TemplateArtifact names a template. Templates are not stored in Erebus; they
+// only own other artifacts.
+func TemplateArtifact(id domain.TemplateID) string {
+	return "templates/" + string(id)
+}
+
+// ImageArtifact names an assembled image by manifest digest ("sha256:...").
+// Assembled root filesystems are node-local; in Erebus an image only owns
+// its layers.
+func ImageArtifact(digest string) string {
+	return "images/" + digestHex(digest)
+}
+
+// LayerArtifact names a cached image layer by digest.
+func LayerArtifact(digest string) string {
+	return "layers/" + digestHex(digest)
+}
+
+// SnapshotArtifact names a snapshot, stored as its .mem, .disk and .json objects.
+func SnapshotArtifact(tplID domain.TemplateID, snapID domain.SnapshotID) string {
+	return fmt.Sprintf("snapshots/%s/%s", tplID, snapID)
+}
+
+// snapshotObjectExts are the objects a snapshot is stored as.
+var snapshotObjectExts = []string{".mem", ".disk", ".json"}
+
+// artifactObjects returns the Store keys an artifact is stored under.
+func artifactObjects(artifact string) []string {
+	switch {
+	case strings.HasPrefix(artifact, "layers/"):
+		return []string{artifact}
+	case strings.HasPrefix(artifact, "snapshots/"):
+		keys := make([]string, len(snapshotObjectExts))
+		for i, ext := range snapshotObjectExts {
+			keys[i] = artifact + ext
+		}
+		return keys
+	}
+	return nil
+}
+
+// objectArtifact returns the artifact a Store key belongs to.
+func objectArtifact(key string) string {
+	if strings.HasPrefix(key, "snapshots/") {
+		for _, ext := range snapshotObjectExts {
+			if strings.HasSuffix(key, ext) {
+				return strings.TrimSuffix(key, ext)
+			}
+		}
+	}
+	return key
+}
+
+// RefCounter records which artifacts own which, in the Store itself under
+// refs/, so the references survive restarts and are shared by every process
+// using the Store. Updates are serialized within a process only; processes
+// sharing a Store must not update references to the same artifact at once.
+type RefCounter struct {
+	store Store
+
+	mu sync.Mutex
+}
+
+// NewRefCounter creates a reference counter persisted in store.
+func NewRefCounter(store Store) *RefCounter {
+	return &RefCounter{store: store}
+}
+
+func ownersKey(artifact string) string { return path.Join("refs/owners", artifact+".json") }
+func heldKey(owner string) string      { return path.Join("refs/held", owner+".json") }
+
+// Acquire records that owner references the artifacts.
+func (r *RefCounter) Acquire(ctx context.Context, owner string, artifacts ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, artifact := range artifacts {
+		if err := r.update(ctx, ownersKey(artifact), func(set map[string]bool) { set[owner] = true }); err != nil {
+			return err
+		}
+	}
+	return r.update(ctx, heldKey(owner), func(set map[string]bool) {
+		for _, artifact := range artifacts {
+			set[artifact] = true
+		}
+	})
+}
+
+// Release drops owner's references to the artifacts. Artifacts left without
+// owners are not deleted; see Collect.
+func (r *RefCounter) Release(ctx context.Context, owner string, artifacts ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.release(ctx, owner, artifacts)
+}
+
+func (r *RefCounter) release(ctx context.Context, owner string, artifacts []string) error {
+	for _, artifact := range artifacts {
+		if err := r.update(ctx, ownersKey(artifact), func(set map[string]bool) { delete(set, owner) }); err != nil {
+			return err
+		}
+	}
+	return r.update(ctx, heldKey(owner), func(set map[string]bool) {
+		for _, artifact := range artifacts {
+			delete(set, artifact)
+		}
+	})
+}
+
+// Owners returns the artifacts referencing artifact, sorted.
+func (r *RefCounter) Owners(ctx context.Context, artifact string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.read(ctx, ownersKey(artifact))
+}
+
+// Held returns the artifacts owner references, sorted.
+func (r *RefCounter) Held(ctx context.Context, owner string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.read(ctx, heldKey(owner))
+}
+
+// Collect deletes artifact if nothing owns it, then releases everything it
+// owned and collects those in turn. It returns the artifacts deleted; an
+// artifact that is still owned is left alone and nothing is returned.
+func (r *RefCounter) Collect(ctx context.Context, artifact string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted []string
+	pending := []string{artifact}
+	for len(pending) > 0 {
+		next := pending[0]
+		pending = pending[1:]
+
+		owners, err := r.read(ctx, ownersKey(next))
+		if err != nil {
+			return deleted, err
+		}
+		if len(owners) > 0 {
+			continue
+		}
+
+		for _, key := range artifactObjects(next) {
+			if err := r.store.Delete(ctx, key); err != nil {
+				if exists, existsErr := r.store.Exists(ctx, key); existsErr != nil || exists {
+					return deleted, fmt.Errorf("failed to delete %s: %w", key, err)
+				}
+			}
+		}
+		held, err := r.read(ctx, heldKey(next))
+		if err != nil {
+			return deleted, err
+		}
+		if err := r.release(ctx, next, held); err != nil {
+			return deleted, err
+		}
+		_ = r.store.Delete(ctx, ownersKey(next))
+		_ = r.store.Delete(ctx, heldKey(next))
+		deleted = append(deleted, next)
+		pending = append(pending, held...)
+	}
+	return deleted, nil
+}
+
+// CheckDelete returns ErrArtifactInUse if the artifact stored under key is
+// still owned.
+func (r *RefCounter) CheckDelete(ctx context.Context, key string) error {
+	artifact := objectArtifact(key)
+	owners, err := r.Owners(ctx, artifact)
+	if err != nil {
+		return err
+	}
+	if len(owners) > 0 {
+		return fmt.Errorf("%w: %s is referenced by %s", ErrArtifactInUse, artifact, strings.Join(owners, ", "))
+	}
+	return nil
+}
+
+// read loads a reference set; a missing set is empty.
+func (r *RefCounter) read(ctx context.Context, key string) ([]string, error) {
+	exists, err := r.store.Exists(ctx, key)
+	if err != nil || !exists {
+		return nil, err
+	}
+	rc, err := r.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	var refs []string
+	if err := json.Unmarshal(data, &refs); err != nil {
+		return nil, fmt.Errorf("corrupt reference set %s: %w", key, err)
+	}
+	return refs, nil
+}
+
+func (r *RefCounter) update(ctx context.Context, key string, change func(map[string]bool)) error {
+	refs, err := r.read(ctx, key)
+	if err != nil {
+		return err
+	}
+	set := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		set[ref] = true
+	}
+	change(set)
+
+	if len(set) == 0 {
+		if len(refs) > 0 {
+			return r.store.Delete(ctx, key)
+		}
+		return nil
+	}
+	refs = refs[:0]
+	for ref := range set {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	return r.store.Put(ctx, key, bytes.NewReader(data))
+}
+
+// GuardedStore refuses to delete objects of artifacts that are still owned,
+// so retention and cleanup code built on a plain Store cannot remove
+// anything in use.
+type GuardedStore struct {
+	Store
+	Refs *RefCounter
+}
+
+// NewGuardedStore wraps store, consulting refs before every delete.
+func NewGuardedStore(store Store, refs *RefCounter) *GuardedStore {
+	return &GuardedStore{Store: store, Refs: refs}
+}
+
+// Delete deletes key unless its artifact is still owned.
+func (s *GuardedStore) Delete(ctx context.Context, key string) error {
+	if !strings.HasPrefix(key, "refs/") {
+		if err := s.Refs.CheckDelete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+// Size reports the size under prefix if the wrapped store supports it.
+func (s *GuardedStore) Size(ctx context.Context, prefix string) (int64, error) {
+	sizer, ok := s.Store.(Sizer)
+	if !ok {
+		return 0, fmt.Errorf("store does not report sizes")
+	}
+	return sizer.Size(ctx, prefix)
+}
+
+// List lists the keys under prefix if the wrapped store supports it.
+func (s *GuardedStore) List(ctx context.Context, prefix string) ([]string, error) {
+	lister, ok := s.Store.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("store does not list objects")
+	}
+	return lister.List(ctx, prefix)
+}
@@ -0,0 +1,120 @@
+package erebus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func putSnapshot(t *testing.T, store Store, artifact string) {
+	t.Helper()
+	for _, key := range artifactObjects(artifact) {
+		require.NoError(t, store.Put(context.Background(), key, bytes.NewReader([]byte("data"))))
+	}
+}
+
+func TestRefCounter_SharedLayersSurviveTemplateDeletion(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	refs := NewRefCounter(store)
+
+	// Two images share a base layer; a snapshot of each template uses one
+	base, app, other := LayerArtifact("sha256:base"), LayerArtifact("sha256:app"), LayerArtifact("sha256:other")
+	for _, layer := range []string{base, app, other} {
+		require.NoError(t, store.Put(ctx, layer, bytes.NewReader([]byte("layer"))))
+	}
+	require.NoError(t, refs.Acquire(ctx, ImageArtifact("sha256:one"), base, app))
+	require.NoError(t, refs.Acquire(ctx, ImageArtifact("sha256:two"), base, other))
+
+	snapA, snapB := SnapshotArtifact("tpl-a", "s1"), SnapshotArtifact("tpl-b", "s1")
+	putSnapshot(t, store, snapA)
+	putSnapshot(t, store, snapB)
+	require.NoError(t, refs.Acquire(ctx, snapA, ImageArtifact("sha256:one")))
+	require.NoError(t, refs.Acquire(ctx, snapB, ImageArtifact("sha256:two")))
+	require.NoError(t, refs.Acquire(ctx, TemplateArtifact("tpl-a"), snapA))
+	require.NoError(t, refs.Acquire(ctx, TemplateArtifact("tpl-b"), snapB))
+
+	deleted, err := refs.Collect(ctx, TemplateArtifact("tpl-a"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{TemplateArtifact("tpl-a"), snapA, ImageArtifact("sha256:one"), app}, deleted)
+
+	exists := func(key string) bool {
+		ok, err := store.Exists(ctx, key)
+		require.NoError(t, err)
+		return ok
+	}
+	assert.False(t, exists(snapA+".mem"))
+	assert.False(t, exists(app))
+	assert.True(t, exists(base), "layer shared with tpl-b must be kept")
+	assert.True(t, exists(other))
+	assert.True(t, exists(snapB+".disk"))
+
+	owners, err := refs.Owners(ctx, base)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ImageArtifact("sha256:two")}, owners)
+
+	// Still-owned artifacts are never collected
+	deleted, err = refs.Collect(ctx, snapB)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+	assert.True(t, exists(snapB+".mem"))
+
+	// Removing the last template frees everything
+	_, err = refs.Collect(ctx, TemplateArtifact("tpl-b"))
+	require.NoError(t, err)
+	assert.False(t, exists(base))
+	assert.False(t, exists(snapB+".json"))
+	assert.False(t, exists(ownersKey(base)))
+}
+
+func TestGuardedStore_RefusesInUseDeletes(t *testing.T) {
+	ctx := context.Background()
+	inner, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	refs := NewRefCounter(inner)
+	store := NewGuardedStore(inner, refs)
+
+	snap := SnapshotArtifact("tpl", "s1")
+	putSnapshot(t, store, snap)
+	require.NoError(t, refs.Acquire(ctx, TemplateArtifact("tpl"), snap))
+
+	err = store.Delete(ctx, snap+".disk")
+	assert.ErrorIs(t, err, ErrArtifactInUse)
+
+	require.NoError(t, refs.Release(ctx, TemplateArtifact("tpl"), snap))
+	assert.NoError(t, store.Delete(ctx, snap+".disk"))
+}
+
+func TestOCIBuilder_AcquiresLayers(t *testing.T) {
+	img, err := random.Image(64, 2)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	scanner := new(TestMockScanner)
+	scanner.On("Scan", mock.Anything, mock.Anything).Return(nil)
+
+	builder := NewOCIBuilder(store, nil)
+	builder.Scanner = scanner
+	builder.Refs = NewRefCounter(store)
+	builder.Fetcher = func(ctx context.Context, ref string) (v1.Image, error) { return img, nil }
+
+	ctx := context.Background()
+	require.NoError(t, builder.Assemble(ctx, "registry.example.com/app:v1", t.TempDir()))
+
+	held, err := builder.Refs.Held(ctx, ImageArtifact(digest.String()))
+	require.NoError(t, err)
+	assert.Len(t, held, 2)
+
+	err = NewGuardedStore(store, builder.Refs).Delete(ctx, held[0])
+	assert.ErrorIs(t, err, ErrArtifactInUse)
+}
@@ -19,7 +19,7 @@ import (
 type S3Store struct {
 	client     *s3.Client
 	bucket     string
-	localCache string
+	localCache string // Directory downloads are cached in; empty streams them
 	uploader   *manager.Uploader
 	downloader *manager.Downloader
 }
@@ -53,8 +53,10 @@ func NewS3Store(ctx context.Context, endpoint, region, bucket, accessKey, secret
 	})
 
 	// Ensure local cache directory exists
-	if err := os.MkdirAll(localCache, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create local cache dir: %w", err)
+	if localCache != "" {
+		if err := os.MkdirAll(localCache, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create local cache dir: %w", err)
+		}
 	}
 
 	return &S3Store{
@@ -80,6 +82,21 @@ func (s *S3Store) Put(ctx context.Context, key string, r io.Reader) error {
 }
 
 func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if s.localCache == "" {
+		out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			var nsk *types.NoSuchKey
+			if errors.As(err, &nsk) {
+				return nil, os.ErrNotExist
+			}
+			return nil, fmt.Errorf("failed to download from s3: %w", err)
+		}
+		return out.Body, nil
+	}
+
 	localPath := filepath.Join(s.localCache, key)
 
 	// Check if exists locally
@@ -148,8 +165,47 @@ func (s *S3Store) Delete(ctx context.Context, key string) error {
 	}
 
 	// Also try to delete from local cache if present
-	localPath := filepath.Join(s.localCache, key)
-	_ = os.Remove(localPath)
+	if s.localCache != "" {
+		_ = os.Remove(filepath.Join(s.localCache, key))
+	}
 
 	return nil
 }
+
+// Size sums the objects listed under the prefix.
+func (s *S3Store) Size(ctx context.Context, prefix string) (int64, error) {
+	var total int64
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list s3 objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			total += aws.ToInt64(obj.Size)
+		}
+	}
+	return total, nil
+}
+
+// List lists the keys of the objects under the prefix.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3 objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
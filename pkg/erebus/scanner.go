@@ -47,6 +47,10 @@ type TrivyScanner struct {
 	Severities     []Severity // List of severities to scan for
 	ExitCodeOnFail bool       // Whether to use --exit-code flag
 	OutputFormat   string     // Output format: json, table, etc.
+
+	// Offline scans with the vulnerability database already on the node,
+	// without updating it, for nodes without internet access.
+	Offline bool
 }
 
 func NewTrivyScanner() *TrivyScanner {
@@ -82,8 +86,8 @@ func (s *TrivyScanner) Scan(ctx context.Context, path string) error {
 		"--format", s.OutputFormat,
 		"--no-progress",
 		"--quiet",
-		path,
 	}
+	args = append(append(args, s.offlineArgs()...), path)
 
 	if s.ExitCodeOnFail {
 		args = append([]string{"fs", "--exit-code", "1"}, args[1:]...)
@@ -125,8 +129,8 @@ func (s *TrivyScanner) ScanWithResults(ctx context.Context, path string) ([]Scan
 		"--format", "json",
 		"--no-progress",
 		"--quiet",
-		path,
 	}
+	args = append(append(args, s.offlineArgs()...), path)
 
 	cmd := exec.CommandContext(ctx, s.BinaryPath, args...)
 	output, err := cmd.CombinedOutput()
@@ -148,6 +152,13 @@ func (s *TrivyScanner) ScanWithResults(ctx context.Context, path string) ([]Scan
 	return results.Results, nil
 }
 
+func (s *TrivyScanner) offlineArgs() []string {
+	if !s.Offline {
+		return nil
+	}
+	return []string{"--skip-db-update", "--skip-java-db-update", "--offline-scan"}
+}
+
 func severitiesList(severities []Severity) []string {
 	result := make([]string, len(severities))
 	for i, sev := range severities {
@@ -0,0 +1,369 @@
+package erebus
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Offline nodes resolve images and snapshots from a seed bundle copied into
+// their local store instead of pulling them. The bundle is described by a
+// manifest signed with an ed25519 key:
+//
+//	seed/manifest.json        the SeedManifest
+//	seed/manifest.json.sig    ed25519 signature of manifest.json
+//	images/<hex>/manifest.json  OCI manifest of each image, by digest
+//	images/<hex>/config.json    its config blob
+//	layers/<hex>              its layers, as cached by OCIBuilder
+//	snapshots/...             snapshots, as stored by Nyx
+//
+// Every object but the manifest and its signature is listed in the
+// manifest with its SHA-256 digest.
+const (
+	SeedManifestKey  = "seed/manifest.json"
+	SeedSignatureKey = "seed/manifest.json.sig"
+)
+
+var (
+	// ErrNotSeeded is returned in offline mode for an image or object the
+	// seed bundle does not contain, where online it would have been pulled.
+	ErrNotSeeded = errors.New("not in offline seed bundle")
+
+	// ErrSeedInvalid is returned for a seed bundle whose manifest is
+	// malformed, unsigned or not signed by the trusted key.
+	ErrSeedInvalid = errors.New("invalid seed bundle")
+)
+
+// SeedManifest lists the contents of a seed bundle.
+type SeedManifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Images maps the image refs templates name to manifest digests.
+	Images []SeedImage `json:"images"`
+
+	// Objects are the store objects the bundle contains.
+	Objects []SeedObject `json:"objects"`
+}
+
+// SeedImage names a bundled image.
+type SeedImage struct {
+	Ref    string `json:"ref"`
+	Digest string `json:"digest"` // "sha256:..." of the OCI manifest
+}
+
+// SeedObject is a bundled store object.
+type SeedObject struct {
+	Key    string `json:"key"`
+	Digest string `json:"digest"` // "sha256:..." of the object's content
+}
+
+// SeedBundle is a verified seed bundle in a store.
+type SeedBundle struct {
+	Store    Store
+	Manifest SeedManifest
+
+	images  map[string]string // ref -> manifest digest
+	objects map[string]string // key -> digest
+}
+
+// ParseSeedPublicKey parses a PEM-encoded ed25519 public key, as written by
+// "openssl pkey -pubout".
+func ParseSeedPublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("seed public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing seed public key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("seed public key is %T, not ed25519", key)
+	}
+	return edKey, nil
+}
+
+// LoadSeedBundle reads the seed manifest from store and verifies its
+// signature against publicKey. The objects it lists are not checked; see
+// Validate.
+func LoadSeedBundle(ctx context.Context, store Store, publicKey ed25519.PublicKey) (*SeedBundle, error) {
+	data, err := readAll(ctx, store, SeedManifestKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %v", ErrSeedInvalid, SeedManifestKey, err)
+	}
+	sig, err := readAll(ctx, store, SeedSignatureKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %v", ErrSeedInvalid, SeedSignatureKey, err)
+	}
+	if !ed25519.Verify(publicKey, data, decodeSignature(sig)) {
+		return nil, fmt.Errorf("%w: manifest signature does not verify", ErrSeedInvalid)
+	}
+
+	bundle := &SeedBundle{
+		Store:   store,
+		images:  make(map[string]string),
+		objects: make(map[string]string),
+	}
+	if err := json.Unmarshal(data, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: parsing manifest: %v", ErrSeedInvalid, err)
+	}
+	for _, img := range bundle.Manifest.Images {
+		if img.Ref == "" || !strings.HasPrefix(img.Digest, "sha256:") {
+			return nil, fmt.Errorf("%w: image %q has no sha256 digest", ErrSeedInvalid, img.Ref)
+		}
+		bundle.images[img.Ref] = img.Digest
+	}
+	for _, obj := range bundle.Manifest.Objects {
+		if obj.Key == "" || !strings.HasPrefix(obj.Digest, "sha256:") {
+			return nil, fmt.Errorf("%w: object %q has no sha256 digest", ErrSeedInvalid, obj.Key)
+		}
+		bundle.objects[obj.Key] = obj.Digest
+	}
+	return bundle, nil
+}
+
+// Validate checks that every image the manifest names is complete and
+// every object it lists is in the store, reporting all missing objects at
+// once. With verifyDigests the objects are also read and hashed, which
+// takes a while for large snapshots.
+func (b *SeedBundle) Validate(ctx context.Context, verifyDigests bool) error {
+	var problems []string
+
+	// Each image's manifest, config and layers must be bundled objects
+	for _, img := range b.Manifest.Images {
+		manifest, err := b.imageManifest(ctx, img.Digest)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("image %s: %v", img.Ref, err))
+			continue
+		}
+		keys := []string{imageConfigKey(img.Digest)}
+		for _, layer := range manifest.Layers {
+			keys = append(keys, LayerArtifact(layer.Digest.String()))
+		}
+		for _, key := range keys {
+			if _, ok := b.objects[key]; !ok {
+				problems = append(problems, fmt.Sprintf("image %s: %s is not listed in the manifest", img.Ref, key))
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := b.checkObject(ctx, key, verifyDigests); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %d problem(s):\n  %s", ErrSeedInvalid, len(problems), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// checkObject checks that a listed object exists and, with verify, that
+// its content matches its digest.
+func (b *SeedBundle) checkObject(ctx context.Context, key string, verify bool) error {
+	if !verify {
+		exists, err := b.Store.Exists(ctx, key)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		if !exists {
+			return fmt.Errorf("%s: missing", key)
+		}
+		return nil
+	}
+
+	rc, err := b.Store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("%s: missing: %v", key, err)
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return fmt.Errorf("%s: reading: %v", key, err)
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != b.objects[key] {
+		return fmt.Errorf("%s: digest %s, manifest lists %s", key, got, b.objects[key])
+	}
+	return nil
+}
+
+// Has reports whether the bundle lists a store object.
+func (b *SeedBundle) Has(key string) bool {
+	_, ok := b.objects[key]
+	return ok
+}
+
+// Fetcher returns an ImageFetcher serving the bundled images and failing
+// fast with ErrNotSeeded for any other ref, for OCIBuilder.Fetcher on
+// nodes without registry access. Refs match as bundled, or by digest
+// ("name@sha256:...").
+func (b *SeedBundle) Fetcher() ImageFetcher {
+	return func(ctx context.Context, ref string) (v1.Image, error) {
+		digest, ok := b.images[ref]
+		if !ok {
+			if _, d, found := strings.Cut(ref, "@"); found && b.bundlesImage(d) {
+				digest, ok = d, true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("image %s: %w (offline mode does not pull from registries)", ref, ErrNotSeeded)
+		}
+		return b.Image(ctx, digest)
+	}
+}
+
+func (b *SeedBundle) bundlesImage(digest string) bool {
+	for _, d := range b.images {
+		if d == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// Image returns a bundled image by manifest digest.
+func (b *SeedBundle) Image(ctx context.Context, digest string) (v1.Image, error) {
+	raw, err := b.readObject(ctx, imageManifestKey(digest))
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := v1.ParseManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest of %s: %w", digest, err)
+	}
+	config, err := b.readObject(ctx, imageConfigKey(digest))
+	if err != nil {
+		return nil, err
+	}
+	return partial.CompressedToImage(&seedImage{bundle: b, ctx: ctx, rawManifest: raw, manifest: manifest, config: config})
+}
+
+func (b *SeedBundle) imageManifest(ctx context.Context, digest string) (*v1.Manifest, error) {
+	raw, err := b.readObject(ctx, imageManifestKey(digest))
+	if err != nil {
+		return nil, err
+	}
+	return v1.ParseManifest(bytes.NewReader(raw))
+}
+
+// readObject reads a bundled object, which must be listed in the manifest.
+func (b *SeedBundle) readObject(ctx context.Context, key string) ([]byte, error) {
+	if !b.Has(key) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotSeeded)
+	}
+	data, err := readAll(ctx, b.Store, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s is listed in the seed manifest but unreadable: %w", key, err)
+	}
+	return data, nil
+}
+
+// decodeSignature accepts a raw ed25519 signature, as written by
+// "openssl pkeyutl -sign -rawin", or its base64 encoding.
+func decodeSignature(sig []byte) []byte {
+	if len(sig) == ed25519.SignatureSize {
+		return sig
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+	if err != nil {
+		return sig
+	}
+	return decoded
+}
+
+func imageManifestKey(digest string) string {
+	return path.Join(ImageArtifact(digest), "manifest.json")
+}
+
+func imageConfigKey(digest string) string {
+	return path.Join(ImageArtifact(digest), "config.json")
+}
+
+func readAll(ctx context.Context, store Store, key string) ([]byte, error) {
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// seedImage is a bundled image, read from the store on demand.
+type seedImage struct {
+	bundle      *SeedBundle
+	ctx         context.Context
+	rawManifest []byte
+	manifest    *v1.Manifest
+	config      []byte
+}
+
+func (i *seedImage) RawConfigFile() ([]byte, error) { return i.config, nil }
+func (i *seedImage) RawManifest() ([]byte, error)   { return i.rawManifest, nil }
+
+func (i *seedImage) MediaType() (types.MediaType, error) {
+	if i.manifest.MediaType != "" {
+		return i.manifest.MediaType, nil
+	}
+	return types.OCIManifestSchema1, nil
+}
+
+func (i *seedImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if h == i.manifest.Config.Digest {
+		return &seedLayer{image: i, desc: i.manifest.Config}, nil
+	}
+	for _, desc := range i.manifest.Layers {
+		if desc.Digest == h {
+			return &seedLayer{image: i, desc: desc}, nil
+		}
+	}
+	return nil, fmt.Errorf("layer %s is not in image manifest", h)
+}
+
+// seedLayer is a bundled layer, cached under LayerArtifact(digest).
+type seedLayer struct {
+	image *seedImage
+	desc  v1.Descriptor
+}
+
+func (l *seedLayer) Digest() (v1.Hash, error)            { return l.desc.Digest, nil }
+func (l *seedLayer) Size() (int64, error)                { return l.desc.Size, nil }
+func (l *seedLayer) MediaType() (types.MediaType, error) { return l.desc.MediaType, nil }
+
+func (l *seedLayer) Compressed() (io.ReadCloser, error) {
+	if l.desc.Digest == l.image.manifest.Config.Digest {
+		return io.NopCloser(bytes.NewReader(l.image.config)), nil
+	}
+	key := LayerArtifact(l.desc.Digest.String())
+	if !l.image.bundle.Has(key) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotSeeded)
+	}
+	return l.image.bundle.Store.Get(l.image.ctx, key)
+}
@@ -0,0 +1,172 @@
+package erebus
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// seedStore writes img and a snapshot into a local store as a seed bundle
+// signed with key.
+func seedStore(t *testing.T, img v1.Image, ref string, key ed25519.PrivateKey) *LocalStore {
+	t.Helper()
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	manifest := SeedManifest{Version: 1, CreatedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}
+	put := func(key string, data []byte) {
+		require.NoError(t, store.Put(ctx, key, bytes.NewReader(data)))
+		sum := sha256.Sum256(data)
+		manifest.Objects = append(manifest.Objects, SeedObject{Key: key, Digest: "sha256:" + hex.EncodeToString(sum[:])})
+	}
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	rawManifest, err := img.RawManifest()
+	require.NoError(t, err)
+	put(imageManifestKey(digest.String()), rawManifest)
+	rawConfig, err := img.RawConfigFile()
+	require.NoError(t, err)
+	put(imageConfigKey(digest.String()), rawConfig)
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	for _, layer := range layers {
+		d, err := layer.Digest()
+		require.NoError(t, err)
+		rc, err := layer.Compressed()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		put(LayerArtifact(d.String()), data)
+	}
+	put("snapshots/python/snap-1.mem", []byte("memory"))
+	manifest.Images = []SeedImage{{Ref: ref, Digest: digest.String()}}
+
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, SeedManifestKey, bytes.NewReader(data)))
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n"
+	require.NoError(t, store.Put(ctx, SeedSignatureKey, strings.NewReader(sig)))
+	return store
+}
+
+func TestSeedBundle_Offline(t *testing.T) {
+	ctx := context.Background()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	img, err := random.Image(64, 2)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	store := seedStore(t, img, "registry.example.com/python:3.12", private)
+	seed, err := LoadSeedBundle(ctx, store, public)
+	require.NoError(t, err)
+	require.NoError(t, seed.Validate(ctx, true))
+	assert.True(t, seed.Has("snapshots/python/snap-1.mem"))
+
+	scanner := new(TestMockScanner)
+	scanner.On("Scan", mock.Anything, mock.Anything).Return(nil)
+	builder := NewOCIBuilder(store, nil)
+	builder.Scanner = scanner
+	builder.Fetcher = seed.Fetcher()
+
+	// Bundled images assemble without a registry, by ref or digest
+	cache, err := NewImageCache(builder, filepath.Join(t.TempDir(), "images"))
+	require.NoError(t, err)
+	cached, err := cache.Prefetch(ctx, "registry.example.com/python:3.12")
+	require.NoError(t, err)
+	assert.Equal(t, digest.String(), cached.Digest)
+	byDigest, err := builder.Pull(ctx, "registry.example.com/python@"+digest.String())
+	require.NoError(t, err)
+	got, err := byDigest.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, digest, got)
+
+	// Anything else fails at once
+	_, err = builder.Pull(ctx, "docker.io/library/alpine:3")
+	assert.ErrorIs(t, err, ErrNotSeeded)
+	assert.Contains(t, err.Error(), "docker.io/library/alpine:3")
+}
+
+func TestSeedBundle_RejectsUntrustedManifest(t *testing.T) {
+	ctx := context.Background()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	store := seedStore(t, img, "app:v1", private)
+
+	_, err = LoadSeedBundle(ctx, store, other)
+	assert.ErrorIs(t, err, ErrSeedInvalid)
+
+	// No bundle at all
+	empty, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	_, err = LoadSeedBundle(ctx, empty, other)
+	assert.ErrorIs(t, err, ErrSeedInvalid)
+}
+
+func TestSeedBundle_ValidateReportsMissingObjects(t *testing.T) {
+	ctx := context.Background()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	img, err := random.Image(64, 2)
+	require.NoError(t, err)
+	store := seedStore(t, img, "app:v1", private)
+	seed, err := LoadSeedBundle(ctx, store, public)
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	d, err := layers[1].Digest()
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(ctx, LayerArtifact(d.String())))
+	require.NoError(t, store.Put(ctx, "snapshots/python/snap-1.mem", strings.NewReader("tampered")))
+
+	// Without hashing, only the missing layer is found
+	err = seed.Validate(ctx, false)
+	assert.ErrorIs(t, err, ErrSeedInvalid)
+	assert.Contains(t, err.Error(), LayerArtifact(d.String())+": missing")
+	assert.NotContains(t, err.Error(), "snap-1.mem")
+
+	err = seed.Validate(ctx, true)
+	assert.Contains(t, err.Error(), "2 problem(s)")
+	assert.Contains(t, err.Error(), "snapshots/python/snap-1.mem: digest")
+}
+
+func TestParseSeedPublicKey(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	parsed, err := ParseSeedPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.Equal(t, public, parsed)
+
+	_, err = ParseSeedPublicKey([]byte("not a key"))
+	assert.Error(t, err)
+}
@@ -13,3 +13,15 @@ type Store interface {
 	Exists(ctx context.Context, key string) (bool, error)
 	Delete(ctx context.Context, key string) error
 }
+
+// Sizer is implemented by stores that can report the total size in bytes of
+// the objects under a key prefix.
+type Sizer interface {
+	Size(ctx context.Context, prefix string) (int64, error)
+}
+
+// Lister is implemented by stores that can list the keys of the objects
+// under a key prefix.
+type Lister interface {
+	List(ctx context.Context, prefix string) ([]string, error)
+}
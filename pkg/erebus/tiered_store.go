@@ -0,0 +1,215 @@
+package erebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ErrPartialWrite is returned by TieredStore.Put when an object reached the
+// local tier but not the remote one. The local copy is kept, so this node
+// can still read it, but the object is not durable.
+var ErrPartialWrite = errors.New("object written to the local tier only")
+
+// Tier names used in TieredStore metrics.
+const (
+	TierLocal  = "local"
+	TierRemote = "remote"
+)
+
+// TieredStore combines a fast local store with a durable remote one, such
+// as a LocalStore and an S3Store. Writes go through to both tiers. Reads
+// are served locally when possible; a local miss is read from the remote
+// tier and copied to the local one, so the next read is a hit.
+//
+// Each tier's requests are counted in erebus_tier_requests_total{tier, op,
+// result} and timed in erebus_tier_latency_seconds{tier, op}.
+type TieredStore struct {
+	Local  Store
+	Remote Store
+
+	metrics hermes.Metrics
+	logger  hermes.Logger
+}
+
+func NewTieredStore(local, remote Store, metrics hermes.Metrics, logger hermes.Logger) *TieredStore {
+	return &TieredStore{
+		Local:   local,
+		Remote:  remote,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// Put writes the object locally, then uploads the local copy. If the local
+// write fails before reading anything, the object is written to the remote
+// tier alone. If the upload fails, the local copy is kept and the error
+// wraps ErrPartialWrite.
+func (s *TieredStore) Put(ctx context.Context, key string, r io.Reader) error {
+	cr := &countingReader{r: r}
+	start := time.Now()
+	err := s.Local.Put(ctx, key, cr)
+	s.observe(TierLocal, "put", start, err)
+	if err != nil {
+		if cr.n > 0 {
+			return fmt.Errorf("failed to write %s to the local tier: %w", key, err)
+		}
+		s.logger.Error(ctx, "Local tier write failed, writing to the remote tier only", map[string]any{"key": key, "error": err})
+		start = time.Now()
+		err = s.Remote.Put(ctx, key, r)
+		s.observe(TierRemote, "put", start, err)
+		return err
+	}
+
+	local, err := s.Local.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to read back %s from the local tier: %w", key, err)
+	}
+	defer local.Close()
+	start = time.Now()
+	err = s.Remote.Put(ctx, key, local)
+	s.observe(TierRemote, "put", start, err)
+	if err != nil {
+		s.logger.Error(ctx, "Remote tier write failed", map[string]any{"key": key, "error": err})
+		return fmt.Errorf("%w: %s: %v", ErrPartialWrite, key, err)
+	}
+	return nil
+}
+
+// Get reads the object locally, falling back to the remote tier and
+// copying what it reads there to the local one.
+func (s *TieredStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := s.Local.Get(ctx, key)
+	s.observe(TierLocal, "get", start, err)
+	if err == nil {
+		return rc, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		s.logger.Error(ctx, "Local tier read failed, reading from the remote tier", map[string]any{"key": key, "error": err})
+	}
+
+	start = time.Now()
+	remote, err := s.Remote.Get(ctx, key)
+	s.observe(TierRemote, "get", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-populate the local tier. If that fails the remote stream has been
+	// consumed, so it is opened again and returned directly.
+	start = time.Now()
+	err = s.Local.Put(ctx, key, remote)
+	remote.Close()
+	s.observe(TierLocal, "repopulate", start, err)
+	if err == nil {
+		if rc, err := s.Local.Get(ctx, key); err == nil {
+			return rc, nil
+		}
+	} else {
+		s.logger.Error(ctx, "Failed to re-populate the local tier", map[string]any{"key": key, "error": err})
+	}
+	return s.Remote.Get(ctx, key)
+}
+
+// Exists checks the local tier, then the remote one.
+func (s *TieredStore) Exists(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	ok, err := s.Local.Exists(ctx, key)
+	s.observe(TierLocal, "exists", start, existsErr(ok, err))
+	if err == nil && ok {
+		return true, nil
+	}
+	start = time.Now()
+	ok, err = s.Remote.Exists(ctx, key)
+	s.observe(TierRemote, "exists", start, existsErr(ok, err))
+	return ok, err
+}
+
+// existsErr reports a missing object as fs.ErrNotExist, for metrics.
+func existsErr(ok bool, err error) error {
+	if err == nil && !ok {
+		return fs.ErrNotExist
+	}
+	return err
+}
+
+// Delete deletes the object from both tiers. Only a remote failure is an
+// error; an object missing locally is not.
+func (s *TieredStore) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.Remote.Delete(ctx, key)
+	s.observe(TierRemote, "delete", start, err)
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+	err = s.Local.Delete(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	s.observe(TierLocal, "delete", start, err)
+	if err != nil {
+		s.logger.Error(ctx, "Failed to delete from the local tier", map[string]any{"key": key, "error": err})
+	}
+	return nil
+}
+
+// Size reports the size under prefix from the remote tier, which holds
+// every object, or the local one if only it supports sizes.
+func (s *TieredStore) Size(ctx context.Context, prefix string) (int64, error) {
+	for _, tier := range []Store{s.Remote, s.Local} {
+		if sizer, ok := tier.(Sizer); ok {
+			return sizer.Size(ctx, prefix)
+		}
+	}
+	return 0, fmt.Errorf("store does not report sizes")
+}
+
+// List lists the keys under prefix from the remote tier, which holds every
+// object, or the local one if only it supports listing.
+func (s *TieredStore) List(ctx context.Context, prefix string) ([]string, error) {
+	for _, tier := range []Store{s.Remote, s.Local} {
+		if lister, ok := tier.(Lister); ok {
+			return lister.List(ctx, prefix)
+		}
+	}
+	return nil, fmt.Errorf("store does not list objects")
+}
+
+func (s *TieredStore) observe(tier, op string, start time.Time, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		result = "miss"
+	case err != nil:
+		result = "error"
+	case op == "get" || op == "exists":
+		result = "hit"
+	}
+	s.metrics.IncCounter("erebus_tier_requests_total", 1,
+		hermes.Label{Key: "tier", Value: tier},
+		hermes.Label{Key: "op", Value: op},
+		hermes.Label{Key: "result", Value: result})
+	s.metrics.ObserveHistogram("erebus_tier_latency_seconds", time.Since(start).Seconds(),
+		hermes.Label{Key: "tier", Value: tier},
+		hermes.Label{Key: "op", Value: op})
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,141 @@
+package erebus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// tierMetrics counts erebus_tier_requests_total by "tier/op/result".
+type tierMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (m *tierMetrics) IncCounter(name string, value float64, labels ...hermes.Label) {
+	if name != "erebus_tier_requests_total" {
+		return
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Value
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]int)
+	}
+	m.counts[strings.Join(parts, "/")]++
+}
+
+func (m *tierMetrics) ObserveHistogram(name string, value float64, labels ...hermes.Label) {}
+func (m *tierMetrics) SetGauge(name string, value float64, labels ...hermes.Label)         {}
+
+func (m *tierMetrics) count(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[key]
+}
+
+// brokenStore fails every write.
+type brokenStore struct {
+	Store
+}
+
+func (s *brokenStore) Put(ctx context.Context, key string, r io.Reader) error {
+	return errors.New("disk full")
+}
+
+func newTieredStore(t *testing.T, local, remote Store) (*TieredStore, *tierMetrics) {
+	t.Helper()
+	logger := &MockLogger{}
+	logger.On("Error", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	metrics := &tierMetrics{}
+	return NewTieredStore(local, remote, metrics, logger), metrics
+}
+
+func readKey(t *testing.T, store Store, key string) string {
+	t.Helper()
+	rc, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestTieredStore_WriteThroughAndReadFallback(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	remote, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	store, metrics := newTieredStore(t, local, remote)
+
+	require.NoError(t, store.Put(ctx, "snapshots/tpl/s1.mem", bytes.NewReader([]byte("memory"))))
+	assert.Equal(t, "memory", readKey(t, local, "snapshots/tpl/s1.mem"))
+	assert.Equal(t, "memory", readKey(t, remote, "snapshots/tpl/s1.mem"))
+
+	// A local hit never reaches the remote tier
+	assert.Equal(t, "memory", readKey(t, store, "snapshots/tpl/s1.mem"))
+	assert.Equal(t, 1, metrics.count("local/get/hit"))
+	assert.Zero(t, metrics.count("remote/get/hit"))
+
+	// Another node's cache is cold: read remotely, then served locally
+	require.NoError(t, local.Delete(ctx, "snapshots/tpl/s1.mem"))
+	assert.Equal(t, "memory", readKey(t, store, "snapshots/tpl/s1.mem"))
+	assert.Equal(t, 1, metrics.count("local/get/miss"))
+	assert.Equal(t, 1, metrics.count("remote/get/hit"))
+	assert.Equal(t, 1, metrics.count("local/repopulate/ok"))
+	assert.Equal(t, "memory", readKey(t, local, "snapshots/tpl/s1.mem"))
+
+	// Missing everywhere
+	_, err = store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	ok, err := store.Exists(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Deletes reach both tiers
+	require.NoError(t, store.Delete(ctx, "snapshots/tpl/s1.mem"))
+	for _, tier := range []Store{local, remote} {
+		ok, err := tier.Exists(ctx, "snapshots/tpl/s1.mem")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+}
+
+func TestTieredStore_PartialFailures(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	remote, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	// The upload fails: the local copy is kept, but the write is not durable
+	store, metrics := newTieredStore(t, local, &brokenStore{Store: remote})
+	err = store.Put(ctx, "layers/abc", bytes.NewReader([]byte("layer")))
+	assert.ErrorIs(t, err, ErrPartialWrite)
+	assert.Equal(t, "layer", readKey(t, store, "layers/abc"))
+	assert.Equal(t, 1, metrics.count("remote/put/error"))
+
+	// The local disk fails before reading: the object still reaches S3
+	store, metrics = newTieredStore(t, &brokenStore{Store: local}, remote)
+	require.NoError(t, store.Put(ctx, "layers/def", bytes.NewReader([]byte("layer"))))
+	assert.Equal(t, "layer", readKey(t, remote, "layers/def"))
+	assert.Equal(t, 1, metrics.count("local/put/error"))
+
+	// Re-populating fails: the object is streamed from the remote tier
+	assert.Equal(t, "layer", readKey(t, store, "layers/def"))
+	assert.Equal(t, 1, metrics.count("local/repopulate/error"))
+}
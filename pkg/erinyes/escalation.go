@@ -0,0 +1,135 @@
+package erinyes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// Throttler is implemented by runtimes that can cap the CPU of a running
+// sandbox.
+type Throttler interface {
+	Throttle(ctx context.Context, id domain.SandboxID, cpu domain.MilliCPU) error
+}
+
+// minThrottleCPU is the lowest CPU cap a throttle step sets.
+const minThrottleCPU domain.MilliCPU = 100
+
+// immediateKill is the ladder of policies without one: the first violation
+// kills the sandbox.
+var immediateKill = &domain.EscalationPolicy{Steps: []domain.EscalationStep{{Action: domain.EscalationKill}}}
+
+// escalation is how far a watched sandbox has climbed its ladder.
+type escalation struct {
+	step       int       // Next step to take
+	violations int       // Violating polls since the previous step
+	since      time.Time // When the previous step was taken, or the first violation was found
+}
+
+// escalate takes the next step of the sandbox's ladder once the violation
+// has been seen often and long enough, and records it through
+// ViolationHook.
+func (p *PollFury) escalate(ctx context.Context, run *domain.SandboxRun, policy *PolicySnapshot, reason string, fields map[string]any) {
+	ladder := policy.Escalation
+	if ladder == nil || len(ladder.Steps) == 0 {
+		ladder = immediateKill
+	}
+	now := time.Now()
+
+	p.mu.Lock()
+	e, ok := p.escalations[run.ID]
+	if !ok {
+		e = &escalation{since: now}
+		p.escalations[run.ID] = e
+	}
+	e.violations++
+	if e.step >= len(ladder.Steps) {
+		// Top of a ladder that does not stop the sandbox
+		p.mu.Unlock()
+		return
+	}
+	step := ladder.Steps[e.step]
+	if e.violations < max(step.Threshold, 1) || now.Sub(e.since) < step.Dwell {
+		p.mu.Unlock()
+		return
+	}
+	event := domain.ViolationEvent{At: now, Reason: reason, Action: step.Action, Step: e.step, Violations: e.violations}
+	e.step++
+	e.violations = 0
+	e.since = now
+	p.mu.Unlock()
+
+	fields["reason"] = reason
+	fields["action"] = step.Action
+	p.Metrics.IncCounter("erinyes_escalation_total", 1,
+		hermes.Label{Key: "action", Value: string(step.Action)},
+		hermes.Label{Key: "reason", Value: reason},
+	)
+
+	switch step.Action {
+	case domain.EscalationWarn:
+		p.Logger.Error(ctx, "Policy violation detected", fields)
+		p.recordViolation(ctx, run.ID, event)
+
+	case domain.EscalationThrottle:
+		p.Logger.Error(ctx, "Policy violation detected, throttling sandbox", fields)
+		if err := p.throttle(ctx, run, step); err != nil {
+			p.Logger.Error(ctx, "Failed to throttle sandbox", map[string]any{"sandbox_id": run.ID, "error": err.Error()})
+			event.Error = err.Error()
+		}
+		p.recordViolation(ctx, run.ID, event)
+
+	case domain.EscalationHibernate:
+		// Recorded first: hibernation stops the sandbox, after which the
+		// agent writes its final run
+		p.Logger.Error(ctx, "Policy violation detected, hibernating sandbox", fields)
+		p.recordViolation(ctx, run.ID, event)
+		err := errors.New("hibernation is not available")
+		if p.HibernateHook != nil {
+			err = p.HibernateHook(ctx, run.ID, reason)
+		}
+		if err == nil {
+			p.stopWatching(run.ID)
+			return
+		}
+		// A sandbox the ladder meant to stop is not left running
+		p.Logger.Error(ctx, "Failed to hibernate sandbox, killing it", map[string]any{"sandbox_id": run.ID, "error": err.Error()})
+		p.recordViolation(ctx, run.ID, domain.ViolationEvent{
+			At:     time.Now(),
+			Reason: reason,
+			Action: domain.EscalationKill,
+			Step:   event.Step,
+			Error:  fmt.Sprintf("hibernate failed: %v", err),
+		})
+		p.killForViolation(ctx, run.ID, reason, fields)
+
+	default:
+		p.recordViolation(ctx, run.ID, event)
+		p.killForViolation(ctx, run.ID, reason, fields)
+	}
+}
+
+// throttle caps the sandbox's CPU at the step's limit, or half what it
+// asked for.
+func (p *PollFury) throttle(ctx context.Context, run *domain.SandboxRun, step domain.EscalationStep) error {
+	throttler, ok := p.Runtime.(Throttler)
+	if !ok {
+		return errors.New("runtime cannot throttle sandboxes")
+	}
+	cpu := step.ThrottleCPU
+	if cpu == 0 && run.Resources != nil {
+		cpu = run.Resources.CPU / 2
+	}
+	cpu = max(cpu, minThrottleCPU)
+	return throttler.Throttle(ctx, run.ID, cpu)
+}
+
+func (p *PollFury) recordViolation(ctx context.Context, id domain.SandboxID, event domain.ViolationEvent) {
+	if p.ViolationHook != nil {
+		p.ViolationHook(ctx, id, event)
+	}
+}
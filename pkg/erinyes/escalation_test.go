@@ -0,0 +1,181 @@
+package erinyes
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// throttlingRuntime records the CPU caps set on sandboxes.
+type throttlingRuntime struct {
+	*tartarus.MockRuntime
+	mu   sync.Mutex
+	caps map[domain.SandboxID]domain.MilliCPU
+}
+
+func (r *throttlingRuntime) Throttle(ctx context.Context, id domain.SandboxID, cpu domain.MilliCPU) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.caps[id] = cpu
+	return nil
+}
+
+func collectViolations(fury *PollFury) (func() []domain.ViolationEvent, chan struct{}) {
+	var mu sync.Mutex
+	var events []domain.ViolationEvent
+	killed := make(chan struct{})
+	fury.ViolationHook = func(ctx context.Context, id domain.SandboxID, event domain.ViolationEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	fury.BeforeKillHook = func(ctx context.Context, id domain.SandboxID, reason string) {
+		close(killed)
+	}
+	return func() []domain.ViolationEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]domain.ViolationEvent(nil), events...)
+	}, killed
+}
+
+func TestPollFury_EscalationLadder(t *testing.T) {
+	runtime := &throttlingRuntime{MockRuntime: tartarus.NewMockRuntime(slog.Default()), caps: map[domain.SandboxID]domain.MilliCPU{}}
+	fury := NewPollFury(runtime, hermes.NewSlogAdapter(), hermes.NewNoopMetrics(), &MockNetworkStatsProvider{}, 5*time.Millisecond)
+	ctx := context.Background()
+	events, killed := collectViolations(fury)
+
+	req := &domain.SandboxRequest{
+		ID:        "test-ladder",
+		Template:  "test-template",
+		Resources: domain.ResourceSpec{CPU: 1000, Mem: 64},
+	}
+	run, err := runtime.Launch(ctx, req, tartarus.VMConfig{CPUs: 1, MemoryMB: 64})
+	if err != nil {
+		t.Fatalf("Failed to launch sandbox: %v", err)
+	}
+	// Set by the agent
+	run.Resources = &req.Resources
+
+	policy := &PolicySnapshot{
+		MaxRuntime:   time.Millisecond,
+		KillOnBreach: true,
+		Escalation: &domain.EscalationPolicy{Steps: []domain.EscalationStep{
+			{Action: domain.EscalationWarn},
+			{Action: domain.EscalationThrottle, Threshold: 2},
+			{Action: domain.EscalationKill, Dwell: 20 * time.Millisecond},
+		}},
+	}
+	if err := fury.Arm(ctx, run, policy); err != nil {
+		t.Fatalf("Failed to arm fury: %v", err)
+	}
+
+	select {
+	case <-killed:
+	case <-time.After(time.Second):
+		t.Fatal("Sandbox was not killed at the top of the ladder")
+	}
+
+	got := events()
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 violation events, got %+v", got)
+	}
+	for i, action := range []domain.EscalationAction{domain.EscalationWarn, domain.EscalationThrottle, domain.EscalationKill} {
+		if got[i].Action != action || got[i].Step != i || got[i].Reason != "runtime_exceeded" {
+			t.Errorf("Event %d: expected %s at step %d, got %+v", i, action, i, got[i])
+		}
+	}
+	if got[1].Violations != 2 {
+		t.Errorf("Expected throttle after 2 violations, got %d", got[1].Violations)
+	}
+	if d := got[2].At.Sub(got[1].At); d < 20*time.Millisecond {
+		t.Errorf("Expected kill no sooner than the dwell, got %v", d)
+	}
+
+	runtime.mu.Lock()
+	defer runtime.mu.Unlock()
+	if cpu := runtime.caps[run.ID]; cpu != 500 {
+		t.Errorf("Expected CPU throttled to half the request (500m), got %d", cpu)
+	}
+}
+
+func TestPollFury_EscalationHibernateFallsBackToKill(t *testing.T) {
+	runtime := tartarus.NewMockRuntime(slog.Default())
+	fury := NewPollFury(runtime, hermes.NewSlogAdapter(), hermes.NewNoopMetrics(), &MockNetworkStatsProvider{}, 5*time.Millisecond)
+	ctx := context.Background()
+	events, killed := collectViolations(fury)
+
+	run, err := runtime.Launch(ctx, &domain.SandboxRequest{ID: "test-hibernate", Template: "test-template"}, tartarus.VMConfig{CPUs: 1, MemoryMB: 64})
+	if err != nil {
+		t.Fatalf("Failed to launch sandbox: %v", err)
+	}
+
+	policy := &PolicySnapshot{
+		MaxRuntime:   time.Millisecond,
+		KillOnBreach: true,
+		Escalation:   &domain.EscalationPolicy{Steps: []domain.EscalationStep{{Action: domain.EscalationHibernate}}},
+	}
+	if err := fury.Arm(ctx, run, policy); err != nil {
+		t.Fatalf("Failed to arm fury: %v", err)
+	}
+
+	select {
+	case <-killed:
+	case <-time.After(time.Second):
+		t.Fatal("Sandbox was not killed after hibernation failed")
+	}
+
+	got := events()
+	if len(got) != 2 || got[0].Action != domain.EscalationHibernate || got[1].Action != domain.EscalationKill || got[1].Error == "" {
+		t.Errorf("Expected hibernate then kill with an error, got %+v", got)
+	}
+}
+
+func TestPollFury_EscalationHibernate(t *testing.T) {
+	runtime := tartarus.NewMockRuntime(slog.Default())
+	fury := NewPollFury(runtime, hermes.NewSlogAdapter(), hermes.NewNoopMetrics(), &MockNetworkStatsProvider{}, 5*time.Millisecond)
+	ctx := context.Background()
+	events, _ := collectViolations(fury)
+
+	hibernated := make(chan domain.SandboxID, 1)
+	fury.HibernateHook = func(ctx context.Context, id domain.SandboxID, reason string) error {
+		hibernated <- id
+		return nil
+	}
+
+	run, err := runtime.Launch(ctx, &domain.SandboxRequest{ID: "test-hibernate-ok", Template: "test-template"}, tartarus.VMConfig{CPUs: 1, MemoryMB: 64})
+	if err != nil {
+		t.Fatalf("Failed to launch sandbox: %v", err)
+	}
+
+	policy := &PolicySnapshot{
+		MaxRuntime:   time.Millisecond,
+		KillOnBreach: true,
+		Escalation:   &domain.EscalationPolicy{Steps: []domain.EscalationStep{{Action: domain.EscalationHibernate}}},
+	}
+	if err := fury.Arm(ctx, run, policy); err != nil {
+		t.Fatalf("Failed to arm fury: %v", err)
+	}
+
+	select {
+	case id := <-hibernated:
+		if id != run.ID {
+			t.Errorf("Expected %s hibernated, got %s", run.ID, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("HibernateHook was not called")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := events(); len(got) != 1 || got[0].Action != domain.EscalationHibernate {
+		t.Errorf("Expected a single hibernate event, got %+v", got)
+	}
+	if _, err := runtime.Inspect(ctx, run.ID); err != nil {
+		t.Errorf("Expected sandbox left to Hypnos, not killed: %v", err)
+	}
+}
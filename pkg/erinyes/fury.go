@@ -11,12 +11,14 @@ import (
 
 type PolicySnapshot struct {
 	MaxRuntime             time.Duration
+	Deadline               time.Time // Absolute completion deadline (zero = none)
 	MaxCPU                 domain.MilliCPU
 	MaxMemory              domain.Megabytes
 	MaxNetworkEgressBytes  int64
 	MaxNetworkIngressBytes int64
 	MaxBannedIPAttempts    int
 	KillOnBreach           bool
+	Escalation             *domain.EscalationPolicy // Steps before the kill (nil = kill on the first violation)
 }
 
 // Fury watches a running sandbox and enforces runtime policy.
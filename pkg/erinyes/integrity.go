@@ -0,0 +1,213 @@
+package erinyes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// IntegrityMonitor detects tampering inside long-running sandboxes. At
+// launch it records SHA-256 hashes of the policy's guest paths through the
+// guest agent, then re-hashes them periodically. Each path that changes,
+// disappears or appears is reported once: logged, emitted as an audit
+// record and passed to OnTamper.
+type IntegrityMonitor struct {
+	Runtime  tartarus.SandboxRuntime
+	Audit    judges.AuditSink
+	Logger   hermes.Logger
+	Metrics  hermes.Metrics
+	Interval time.Duration // Used when the policy sets none
+
+	// OnTamper is called with the paths that changed since the last check.
+	OnTamper func(ctx context.Context, id domain.SandboxID, paths []string)
+
+	mu     sync.Mutex
+	active map[domain.SandboxID]context.CancelFunc
+}
+
+// NewIntegrityMonitor creates a new IntegrityMonitor.
+func NewIntegrityMonitor(runtime tartarus.SandboxRuntime, audit judges.AuditSink, logger hermes.Logger, metrics hermes.Metrics, interval time.Duration) *IntegrityMonitor {
+	return &IntegrityMonitor{
+		Runtime:  runtime,
+		Audit:    audit,
+		Logger:   logger,
+		Metrics:  metrics,
+		Interval: interval,
+		active:   make(map[domain.SandboxID]context.CancelFunc),
+	}
+}
+
+// Watch records the baseline hashes and starts re-verifying them. It returns
+// an error if the baseline cannot be taken; the sandbox keeps running
+// unmonitored in that case.
+func (m *IntegrityMonitor) Watch(ctx context.Context, run *domain.SandboxRun, policy *domain.IntegrityPolicy) error {
+	if !policy.Enabled() {
+		return nil
+	}
+
+	baseline, err := m.hash(ctx, run.ID, policy.Paths)
+	if err != nil {
+		m.Metrics.IncCounter("erinyes_integrity_check_failures_total", 1)
+		return fmt.Errorf("failed to record integrity baseline: %w", err)
+	}
+
+	interval := policy.Interval
+	if interval <= 0 {
+		interval = m.Interval
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	m.mu.Lock()
+	if prev, ok := m.active[run.ID]; ok {
+		prev()
+	}
+	m.active[run.ID] = cancel
+	m.mu.Unlock()
+
+	m.Logger.Info(ctx, "Integrity baseline recorded", map[string]any{
+		"sandbox_id": run.ID,
+		"paths":      len(baseline),
+	})
+	go m.watch(watchCtx, run, policy.Paths, baseline, interval)
+	return nil
+}
+
+// Stop ends monitoring of the sandbox. Safe to call multiple times.
+func (m *IntegrityMonitor) Stop(id domain.SandboxID) {
+	m.mu.Lock()
+	cancel, exists := m.active[id]
+	delete(m.active, id)
+	m.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+}
+
+func (m *IntegrityMonitor) watch(ctx context.Context, run *domain.SandboxRun, paths []string, baseline map[string]string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reported := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.verify(ctx, run, paths, baseline, reported)
+		}
+	}
+}
+
+// verify re-hashes the paths and reports those that differ from the baseline
+// and have not been reported yet.
+func (m *IntegrityMonitor) verify(ctx context.Context, run *domain.SandboxRun, paths []string, baseline map[string]string, reported map[string]bool) {
+	current, err := m.hash(ctx, run.ID, paths)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.Logger.Error(ctx, "Integrity check failed", map[string]any{
+				"sandbox_id": run.ID,
+				"error":      err.Error(),
+			})
+			m.Metrics.IncCounter("erinyes_integrity_check_failures_total", 1)
+		}
+		return
+	}
+
+	var changed []string
+	for _, path := range paths {
+		if reported[path] || current[path] == baseline[path] {
+			continue
+		}
+		reported[path] = true
+		changed = append(changed, path)
+		m.record(ctx, run, path, baseline[path], current[path])
+	}
+
+	if len(changed) > 0 && m.OnTamper != nil {
+		m.OnTamper(ctx, run.ID, changed)
+	}
+}
+
+// record logs and audits a single changed path. Empty hashes mean the file
+// did not exist.
+func (m *IntegrityMonitor) record(ctx context.Context, run *domain.SandboxRun, path, expected, actual string) {
+	m.Logger.Error(ctx, "Integrity violation detected", map[string]any{
+		"sandbox_id": run.ID,
+		"path":       path,
+		"expected":   expected,
+		"actual":     actual,
+	})
+	m.Metrics.IncCounter("erinyes_integrity_violations_total", 1)
+
+	if m.Audit == nil {
+		return
+	}
+	record := &judges.AuditRecord{
+		AuditID:    uuid.New().String(),
+		Timestamp:  time.Now().UTC(),
+		SandboxID:  run.ID,
+		TemplateID: run.Template,
+		Event:      "sandbox_integrity_violation",
+		Metadata: map[string]string{
+			"path":     path,
+			"expected": expected,
+			"actual":   actual,
+		},
+	}
+	if run.Submitter != nil {
+		record.IdentityID = run.Submitter.ID
+		record.IdentityType = run.Submitter.Type
+		record.TenantID = run.Submitter.TenantID
+	}
+	if err := m.Audit.Emit(ctx, record); err != nil {
+		m.Logger.Error(ctx, "Failed to emit integrity audit record", map[string]any{
+			"sandbox_id": run.ID,
+			"error":      err.Error(),
+		})
+	}
+}
+
+// hash runs sha256sum in the guest and returns the hash of each path that
+// exists. sha256sum exits non-zero when a path is missing, so an error only
+// counts when nothing was hashed.
+func (m *IntegrityMonitor) hash(ctx context.Context, id domain.SandboxID, paths []string) (map[string]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := append([]string{"sha256sum", "--"}, paths...)
+	execErr := m.Runtime.Exec(ctx, id, cmd, &stdout, &stderr)
+
+	sums := parseSHA256Sums(stdout.Bytes())
+	if execErr != nil && len(sums) == 0 {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", execErr, msg)
+		}
+		return nil, execErr
+	}
+	return sums, nil
+}
+
+// parseSHA256Sums parses "<hex>  <path>" lines as printed by sha256sum.
+func parseSHA256Sums(out []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		sum, path, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || len(sum) != 64 {
+			continue
+		}
+		// Binary mode marks the path with '*'
+		path = strings.TrimPrefix(strings.TrimPrefix(path, " "), "*")
+		sums[path] = sum
+	}
+	return sums
+}
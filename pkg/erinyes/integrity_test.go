@@ -0,0 +1,146 @@
+package erinyes
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// guestFiles answers sha256sum like a guest agent would.
+type guestFiles struct {
+	tartarus.SandboxRuntime
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func (g *guestFiles) set(path, content string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if content == "" {
+		delete(g.files, path)
+		return
+	}
+	g.files[path] = content
+}
+
+func (g *guestFiles) Exec(ctx context.Context, id domain.SandboxID, cmd []string, stdout, stderr io.Writer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var missing bool
+	for _, path := range cmd[2:] {
+		content, ok := g.files[path]
+		if !ok {
+			fmt.Fprintf(stderr, "sha256sum: %s: No such file or directory\n", path)
+			missing = true
+			continue
+		}
+		fmt.Fprintf(stdout, "%x  %s\n", sha256.Sum256([]byte(content)), path)
+	}
+	if missing {
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+type recordingSink struct {
+	mu      sync.Mutex
+	records []*judges.AuditRecord
+}
+
+func (s *recordingSink) Emit(ctx context.Context, record *judges.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *recordingSink) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+func TestIntegrityMonitor_DetectsChanges(t *testing.T) {
+	guest := &guestFiles{files: map[string]string{
+		"/etc/passwd": "root:x:0:0",
+		"/bin/worker": "v1",
+	}}
+	sink := &recordingSink{}
+	monitor := NewIntegrityMonitor(guest, sink, hermes.NewSlogAdapter(), hermes.NewNoopMetrics(), time.Hour)
+
+	var mu sync.Mutex
+	var tampered []string
+	monitor.OnTamper = func(ctx context.Context, id domain.SandboxID, paths []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		tampered = append(tampered, paths...)
+	}
+
+	ctx := context.Background()
+	run := &domain.SandboxRun{ID: "sbx-1", Template: "tpl", Submitter: &domain.Submitter{ID: "alice", TenantID: "acme"}}
+	policy := &domain.IntegrityPolicy{
+		Paths:    []string{"/etc/passwd", "/bin/worker", "/tmp/dropper"},
+		Interval: 10 * time.Millisecond,
+	}
+	if err := monitor.Watch(ctx, run, policy); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer monitor.Stop(run.ID)
+
+	// Unchanged files are not reported
+	time.Sleep(30 * time.Millisecond)
+	if n := sink.len(); n != 0 {
+		t.Fatalf("expected no violations, got %d", n)
+	}
+
+	guest.set("/bin/worker", "v2")
+	guest.set("/etc/passwd", "")
+	guest.set("/tmp/dropper", "payload")
+	time.Sleep(50 * time.Millisecond)
+
+	// Each path is reported once even though it stays changed
+	if n := sink.len(); n != 3 {
+		t.Fatalf("expected 3 audit records, got %d", n)
+	}
+	mu.Lock()
+	got := append([]string(nil), tampered...)
+	mu.Unlock()
+	if len(got) != 3 || got[0] != "/etc/passwd" || got[1] != "/bin/worker" || got[2] != "/tmp/dropper" {
+		t.Errorf("unexpected tampered paths: %v", got)
+	}
+
+	sink.mu.Lock()
+	record := sink.records[0]
+	sink.mu.Unlock()
+	if record.Event != "sandbox_integrity_violation" || record.SandboxID != "sbx-1" || record.TenantID != "acme" {
+		t.Errorf("unexpected audit record: %+v", record)
+	}
+	if record.Metadata["path"] != "/etc/passwd" || record.Metadata["expected"] == "" || record.Metadata["actual"] != "" {
+		t.Errorf("unexpected audit metadata: %v", record.Metadata)
+	}
+}
+
+func TestIntegrityMonitor_BaselineFailure(t *testing.T) {
+	guest := &guestFiles{files: map[string]string{}}
+	monitor := NewIntegrityMonitor(guest, nil, hermes.NewSlogAdapter(), hermes.NewNoopMetrics(), time.Hour)
+
+	err := monitor.Watch(context.Background(), &domain.SandboxRun{ID: "sbx-1"}, &domain.IntegrityPolicy{Paths: []string{"/missing"}})
+	if err == nil {
+		t.Fatal("expected baseline error when nothing can be hashed")
+	}
+
+	// Disabled policies are a no-op
+	if err := monitor.Watch(context.Background(), &domain.SandboxRun{ID: "sbx-2"}, nil); err != nil {
+		t.Errorf("expected nil policy to be ignored, got %v", err)
+	}
+}
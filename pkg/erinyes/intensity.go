@@ -0,0 +1,101 @@
+package erinyes
+
+import (
+	"context"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// CPUUsageReporter is implemented by runtimes that can report the
+// cumulative CPU time a sandbox has used.
+type CPUUsageReporter interface {
+	CPUUsage(ctx context.Context, id domain.SandboxID) (time.Duration, error)
+}
+
+// IntensityObserver is implemented by furies that record how intensely the
+// sandboxes they watch actually ran.
+type IntensityObserver interface {
+	// TakeIntensity returns what was observed for a run and forgets it.
+	TakeIntensity(id domain.SandboxID) (*domain.RunIntensity, bool)
+}
+
+// intensitySample tracks the peaks of one watched sandbox. Rates are
+// computed from the cumulative counters of the previous poll.
+type intensitySample struct {
+	peak     domain.RunIntensity
+	cpuAt    time.Time
+	cpu      time.Duration
+	netAt    time.Time
+	netBytes int64
+}
+
+// observeUsage records a memory reading and, if the runtime reports it,
+// the CPU rate since the previous poll.
+func (p *PollFury) observeUsage(ctx context.Context, id domain.SandboxID, memory domain.Megabytes) {
+	var cpu time.Duration
+	reporter, hasCPU := p.Runtime.(CPUUsageReporter)
+	if hasCPU {
+		var err error
+		if cpu, err = reporter.CPUUsage(ctx, id); err != nil {
+			hasCPU = false
+		}
+	}
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.sample(id)
+	if memory > s.peak.PeakMemory {
+		s.peak.PeakMemory = memory
+	}
+	if hasCPU {
+		if !s.cpuAt.IsZero() && now.After(s.cpuAt) && cpu >= s.cpu {
+			if cores := float64(cpu-s.cpu) / float64(now.Sub(s.cpuAt)); cores > s.peak.PeakCPUCores {
+				s.peak.PeakCPUCores = cores
+			}
+		}
+		s.cpu = cpu
+		s.cpuAt = now
+	}
+}
+
+// observeNetwork records the network rate since the previous poll from the
+// cumulative bytes on the sandbox's TAP device.
+func (p *PollFury) observeNetwork(id domain.SandboxID, bytes int64) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.sample(id)
+	if !s.netAt.IsZero() && now.After(s.netAt) && bytes >= s.netBytes {
+		if mbps := float64(bytes-s.netBytes) * 8 / 1e6 / now.Sub(s.netAt).Seconds(); mbps > s.peak.PeakNetworkMbps {
+			s.peak.PeakNetworkMbps = mbps
+		}
+	}
+	s.netBytes = bytes
+	s.netAt = now
+}
+
+// sample returns the sample for a run, creating it; p.mu must be held.
+func (p *PollFury) sample(id domain.SandboxID) *intensitySample {
+	s, ok := p.observed[id]
+	if !ok {
+		s = &intensitySample{}
+		p.observed[id] = s
+	}
+	return s
+}
+
+// TakeIntensity returns the peaks observed while a run was watched and
+// forgets them. The agent collects them once the sandbox has exited.
+func (p *PollFury) TakeIntensity(id domain.SandboxID) (*domain.RunIntensity, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.observed[id]
+	if !ok {
+		return nil, false
+	}
+	delete(p.observed, id)
+	peak := s.peak
+	return &peak, true
+}
@@ -24,8 +24,23 @@ type PollFury struct {
 	// If it returns false, proceed with force kill.
 	GracefulKillHook func(ctx context.Context, id domain.SandboxID, reason string) bool
 
-	mu     sync.Mutex
-	active map[domain.SandboxID]context.CancelFunc
+	// BeforeKillHook, if set, is called once a sandbox has been found in
+	// breach and before it is terminated, while its guest still runs. It is
+	// used to collect crash bundles.
+	BeforeKillHook func(ctx context.Context, id domain.SandboxID, reason string)
+
+	// HibernateHook, if set, carries out the hibernate step of escalation
+	// ladders. Without it, or if it fails, the sandbox is killed instead.
+	HibernateHook func(ctx context.Context, id domain.SandboxID, reason string) error
+
+	// ViolationHook, if set, is called with every escalation step taken,
+	// so it can be added to the run's violation timeline.
+	ViolationHook func(ctx context.Context, id domain.SandboxID, event domain.ViolationEvent)
+
+	mu          sync.Mutex
+	active      map[domain.SandboxID]context.CancelFunc
+	observed    map[domain.SandboxID]*intensitySample // Until taken by TakeIntensity
+	escalations map[domain.SandboxID]*escalation
 }
 
 // NewPollFury creates a new PollFury instance.
@@ -37,6 +52,8 @@ func NewPollFury(runtime tartarus.SandboxRuntime, logger hermes.Logger, metrics
 		NetworkStats: networkStats,
 		Interval:     interval,
 		active:       make(map[domain.SandboxID]context.CancelFunc),
+		observed:     make(map[domain.SandboxID]*intensitySample),
+		escalations:  make(map[domain.SandboxID]*escalation),
 	}
 }
 
@@ -69,6 +86,7 @@ func (p *PollFury) Disarm(ctx context.Context, runID domain.SandboxID) error {
 	if exists {
 		delete(p.active, runID)
 	}
+	delete(p.escalations, runID)
 	p.mu.Unlock()
 
 	if exists {
@@ -93,7 +111,8 @@ func (p *PollFury) watch(ctx context.Context, run *domain.SandboxRun, policy *Po
 	}
 }
 
-// checkAndEnforce inspects the sandbox and enforces policy limits.
+// checkAndEnforce inspects the sandbox and escalates the first policy
+// limit it finds violated.
 func (p *PollFury) checkAndEnforce(ctx context.Context, run *domain.SandboxRun, policy *PolicySnapshot) {
 	// Inspect the current state
 	currentRun, err := p.Runtime.Inspect(ctx, run.ID)
@@ -110,12 +129,13 @@ func (p *PollFury) checkAndEnforce(ctx context.Context, run *domain.SandboxRun,
 		p.stopWatching(run.ID)
 		return
 	}
+	p.observeUsage(ctx, run.ID, currentRun.MemoryUsage)
 
 	// Check runtime limit
 	if policy.MaxRuntime > 0 {
 		elapsed := time.Since(currentRun.StartedAt)
 		if elapsed > policy.MaxRuntime {
-			p.killForViolation(ctx, run.ID, "runtime_exceeded", map[string]any{
+			p.escalate(ctx, run, policy, "runtime_exceeded", map[string]any{
 				"sandbox_id":  run.ID,
 				"elapsed":     elapsed.String(),
 				"max_runtime": policy.MaxRuntime.String(),
@@ -124,9 +144,18 @@ func (p *PollFury) checkAndEnforce(ctx context.Context, run *domain.SandboxRun,
 		}
 	}
 
+	// Check run window deadline
+	if !policy.Deadline.IsZero() && time.Now().After(policy.Deadline) {
+		p.escalate(ctx, run, policy, "deadline_exceeded", map[string]any{
+			"sandbox_id": run.ID,
+			"deadline":   policy.Deadline,
+		})
+		return
+	}
+
 	// Check memory limit
 	if policy.MaxMemory > 0 && currentRun.MemoryUsage > policy.MaxMemory {
-		p.killForViolation(ctx, run.ID, "memory_exceeded", map[string]any{
+		p.escalate(ctx, run, policy, "memory_exceeded", map[string]any{
 			"sandbox_id":   run.ID,
 			"memory_usage": currentRun.MemoryUsage,
 			"max_memory":   policy.MaxMemory,
@@ -155,9 +184,11 @@ func (p *PollFury) checkAndEnforce(ctx context.Context, run *domain.SandboxRun,
 				"error":      err.Error(),
 			})
 		} else {
+			p.observeNetwork(run.ID, rx+tx)
+
 			// Host RX = VM Egress
 			if policy.MaxNetworkEgressBytes > 0 && rx > policy.MaxNetworkEgressBytes {
-				p.killForViolation(ctx, run.ID, "network_egress_exceeded", map[string]any{
+				p.escalate(ctx, run, policy, "network_egress_exceeded", map[string]any{
 					"sandbox_id": run.ID,
 					"egress":     rx,
 					"max_egress": policy.MaxNetworkEgressBytes,
@@ -166,7 +197,7 @@ func (p *PollFury) checkAndEnforce(ctx context.Context, run *domain.SandboxRun,
 			}
 			// Host TX = VM Ingress
 			if policy.MaxNetworkIngressBytes > 0 && tx > policy.MaxNetworkIngressBytes {
-				p.killForViolation(ctx, run.ID, "network_ingress_exceeded", map[string]any{
+				p.escalate(ctx, run, policy, "network_ingress_exceeded", map[string]any{
 					"sandbox_id":  run.ID,
 					"ingress":     tx,
 					"max_ingress": policy.MaxNetworkIngressBytes,
@@ -185,7 +216,7 @@ func (p *PollFury) checkAndEnforce(ctx context.Context, run *domain.SandboxRun,
 					"error":      err.Error(),
 				})
 			} else if drops > policy.MaxBannedIPAttempts {
-				p.killForViolation(ctx, run.ID, "banned_ip_attempts_exceeded", map[string]any{
+				p.escalate(ctx, run, policy, "banned_ip_attempts_exceeded", map[string]any{
 					"sandbox_id":   run.ID,
 					"drops":        drops,
 					"max_attempts": policy.MaxBannedIPAttempts,
@@ -196,12 +227,17 @@ func (p *PollFury) checkAndEnforce(ctx context.Context, run *domain.SandboxRun,
 	}
 }
 
-// killForViolation kills a sandbox for policy violation.
+// killForViolation kills a sandbox for policy violation, the last step of
+// most escalation ladders.
 func (p *PollFury) killForViolation(ctx context.Context, runID domain.SandboxID, reason string, fields map[string]any) {
 	// Log the violation
 	fields["reason"] = reason
 	p.Logger.Error(ctx, "Policy violation detected", fields)
 
+	if p.BeforeKillHook != nil {
+		p.BeforeKillHook(ctx, runID, reason)
+	}
+
 	// Try graceful termination first if hook is configured
 	if p.GracefulKillHook != nil {
 		p.Logger.Info(ctx, "Attempting graceful termination before kill", map[string]any{
@@ -252,6 +288,7 @@ func (p *PollFury) stopWatching(runID domain.SandboxID) {
 	if exists {
 		delete(p.active, runID)
 	}
+	delete(p.escalations, runID)
 	p.mu.Unlock()
 
 	if exists {
@@ -261,7 +298,5 @@ func (p *PollFury) stopWatching(runID domain.SandboxID) {
 
 // isFinished checks if a run status represents a finished state.
 func isFinished(status domain.RunStatus) bool {
-	return status == domain.RunStatusSucceeded ||
-		status == domain.RunStatusFailed ||
-		status == domain.RunStatusCanceled
+	return status.IsTerminal()
 }
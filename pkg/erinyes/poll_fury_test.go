@@ -102,6 +102,44 @@ func TestPollFury_MemoryEnforcement(t *testing.T) {
 	}
 }
 
+func TestPollFury_BeforeKillHookSeesRunningSandbox(t *testing.T) {
+	runtime := tartarus.NewMockRuntime(slog.Default())
+	fury := NewPollFury(runtime, hermes.NewSlogAdapter(), hermes.NewNoopMetrics(), &MockNetworkStatsProvider{}, 10*time.Millisecond)
+	ctx := context.Background()
+
+	run, err := runtime.Launch(ctx, &domain.SandboxRequest{ID: "test-ttl", Template: "test-template"}, tartarus.VMConfig{CPUs: 1, MemoryMB: 64})
+	if err != nil {
+		t.Fatalf("Failed to launch sandbox: %v", err)
+	}
+
+	type call struct {
+		reason  string
+		running bool
+	}
+	calls := make(chan call, 1)
+	fury.BeforeKillHook = func(ctx context.Context, id domain.SandboxID, reason string) {
+		_, err := runtime.Inspect(ctx, id)
+		calls <- call{reason: reason, running: err == nil}
+	}
+
+	if err := fury.Arm(ctx, run, &PolicySnapshot{MaxRuntime: time.Millisecond, KillOnBreach: true}); err != nil {
+		t.Fatalf("Failed to arm fury: %v", err)
+	}
+
+	select {
+	case c := <-calls:
+		if c.reason != "runtime_exceeded" || !c.running {
+			t.Errorf("Expected hook before the TTL kill, got %+v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("BeforeKillHook was not called")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := runtime.Inspect(ctx, run.ID); err == nil {
+		t.Error("Expected sandbox to be killed after the hook")
+	}
+}
+
 func TestPollFury_NetworkEnforcement(t *testing.T) {
 	// Setup
 	logger := hermes.NewSlogAdapter()
@@ -194,3 +232,37 @@ func TestPollFury_NetworkEnforcement(t *testing.T) {
 		t.Error("Expected error (sandbox killed due to banned IP attempts), got nil")
 	}
 }
+
+func TestPollFury_ObservesIntensity(t *testing.T) {
+	runtime := tartarus.NewMockRuntime(slog.Default())
+	networkStats := &MockNetworkStatsProvider{}
+	fury := NewPollFury(runtime, hermes.NewSlogAdapter(), hermes.NewNoopMetrics(), networkStats, time.Hour)
+	ctx := context.Background()
+
+	req := &domain.SandboxRequest{ID: "test-intensity", Template: "test-template"}
+	run, err := runtime.Launch(ctx, req, tartarus.VMConfig{CPUs: 1, MemoryMB: 100, TapDevice: "tap-test"})
+	if err != nil {
+		t.Fatalf("Failed to launch sandbox: %v", err)
+	}
+	policy := &PolicySnapshot{KillOnBreach: true}
+
+	fury.checkAndEnforce(ctx, run, policy)
+	time.Sleep(20 * time.Millisecond)
+	networkStats.RxBytes = 10_000_000 // 80Mb well within a second
+	fury.checkAndEnforce(ctx, run, policy)
+
+	intensity, ok := fury.TakeIntensity(run.ID)
+	if !ok {
+		t.Fatal("Expected an observed intensity")
+	}
+	// MockRuntime reports half the allocated memory in use
+	if intensity.PeakMemory != 50 {
+		t.Errorf("Expected peak memory 50MB, got %d", intensity.PeakMemory)
+	}
+	if intensity.PeakNetworkMbps < 50 {
+		t.Errorf("Expected a network burst above 50Mbps, got %.1f", intensity.PeakNetworkMbps)
+	}
+	if _, ok := fury.TakeIntensity(run.ID); ok {
+		t.Error("Expected the intensity to be forgotten once taken")
+	}
+}
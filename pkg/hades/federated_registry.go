@@ -0,0 +1,246 @@
+package hades
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ErrUnknownRegion is returned when a write targets a region with no registry.
+var ErrUnknownRegion = errors.New("unknown region")
+
+// FederatedRegistry aggregates several regional registries (typically one
+// Redis cluster per region) behind a single Registry. Reads fan out across
+// all regions; writes are routed to the region that owns the node.
+//
+// Nodes are attributed to a region through the domain.NodeLabelRegion label.
+// Nodes returned from a regional registry without that label are stamped with
+// the region they were read from so the scheduler can reason about locality.
+type FederatedRegistry struct {
+	local   string
+	regions map[string]Registry
+
+	mu         sync.RWMutex
+	nodeRegion map[domain.NodeID]string
+}
+
+// NewFederatedRegistry creates a federated registry. localRegion names the
+// region this control plane runs in; it must be present in regions and is
+// used for writes whose owner cannot be determined.
+func NewFederatedRegistry(localRegion string, regions map[string]Registry) (*FederatedRegistry, error) {
+	if _, ok := regions[localRegion]; !ok {
+		return nil, fmt.Errorf("%w: local region %q has no registry", ErrUnknownRegion, localRegion)
+	}
+	return &FederatedRegistry{
+		local:      localRegion,
+		regions:    regions,
+		nodeRegion: make(map[domain.NodeID]string),
+	}, nil
+}
+
+// LocalRegion returns the region this registry treats as home.
+func (r *FederatedRegistry) LocalRegion() string {
+	return r.local
+}
+
+// Regions returns the configured region names, local region first.
+func (r *FederatedRegistry) Regions() []string {
+	names := make([]string, 0, len(r.regions))
+	for name := range r.regions {
+		if name != r.local {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{r.local}, names...)
+}
+
+// Region returns the registry for a single region.
+func (r *FederatedRegistry) Region(name string) (Registry, bool) {
+	reg, ok := r.regions[name]
+	return reg, ok
+}
+
+func (r *FederatedRegistry) ListNodes(ctx context.Context) ([]domain.NodeStatus, error) {
+	var all []domain.NodeStatus
+	var errs []error
+
+	for _, name := range r.Regions() {
+		nodes, err := r.regions[name].ListNodes(ctx)
+		if err != nil {
+			// A single unreachable region must not blind the scheduler to
+			// the others; report only if every region failed.
+			errs = append(errs, fmt.Errorf("region %s: %w", name, err))
+			continue
+		}
+		for _, node := range nodes {
+			all = append(all, r.stampRegion(name, node))
+		}
+	}
+
+	if len(errs) == len(r.regions) {
+		return nil, errors.Join(errs...)
+	}
+	return all, nil
+}
+
+func (r *FederatedRegistry) GetNode(ctx context.Context, id domain.NodeID) (*domain.NodeStatus, error) {
+	if name, ok := r.ownerOf(id); ok {
+		node, err := r.regions[name].GetNode(ctx, id)
+		if err == nil {
+			stamped := r.stampRegion(name, *node)
+			return &stamped, nil
+		}
+		if !errors.Is(err, ErrNodeNotFound) {
+			return nil, err
+		}
+	}
+
+	for _, name := range r.Regions() {
+		node, err := r.regions[name].GetNode(ctx, id)
+		if err != nil {
+			continue
+		}
+		stamped := r.stampRegion(name, *node)
+		return &stamped, nil
+	}
+	return nil, ErrNodeNotFound
+}
+
+func (r *FederatedRegistry) UpdateHeartbeat(ctx context.Context, payload HeartbeatPayload) error {
+	name := r.local
+	if region := payload.Node.Labels[domain.NodeLabelRegion]; region != "" {
+		name = region
+	}
+	reg, ok := r.regions[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRegion, name)
+	}
+	if err := reg.UpdateHeartbeat(ctx, payload); err != nil {
+		return err
+	}
+	r.remember(payload.Node.ID, name)
+	return nil
+}
+
+func (r *FederatedRegistry) MarkDraining(ctx context.Context, id domain.NodeID) error {
+	if _, err := r.GetNode(ctx, id); err != nil {
+		return err
+	}
+	name, _ := r.ownerOf(id)
+	return r.regions[name].MarkDraining(ctx, id)
+}
+
+func (r *FederatedRegistry) MarkSchedulable(ctx context.Context, id domain.NodeID) error {
+	if _, err := r.GetNode(ctx, id); err != nil {
+		return err
+	}
+	name, _ := r.ownerOf(id)
+	return r.regions[name].MarkSchedulable(ctx, id)
+}
+
+// UpdateRun writes the run to the region owning its node. Runs that have not
+// been placed yet are written to the local region; once placed remotely the
+// newer remote copy shadows the local PENDING record on reads.
+func (r *FederatedRegistry) UpdateRun(ctx context.Context, run domain.SandboxRun) error {
+	return r.runRegion(run).UpdateRun(ctx, run)
+}
+
+// CompareAndSwapRun swaps the run in the region UpdateRun writes it to.
+func (r *FederatedRegistry) CompareAndSwapRun(ctx context.Context, run domain.SandboxRun) error {
+	swapper, ok := r.runRegion(run).(RunSwapper)
+	if !ok {
+		return fmt.Errorf("registry of the run's region does not support compare-and-swap")
+	}
+	return swapper.CompareAndSwapRun(ctx, run)
+}
+
+// runRegion returns the registry of the region owning the run's node, or
+// the local one.
+func (r *FederatedRegistry) runRegion(run domain.SandboxRun) Registry {
+	name := r.local
+	if run.NodeID != "" {
+		if owner, ok := r.ownerOf(run.NodeID); ok {
+			name = owner
+		}
+	}
+	return r.regions[name]
+}
+
+// GetRun looks the run up in the local region first, then in the others.
+// When a run exists in several regions, the copy with the latest update wins.
+func (r *FederatedRegistry) GetRun(ctx context.Context, id domain.SandboxID) (*domain.SandboxRun, error) {
+	var found *domain.SandboxRun
+	for _, name := range r.Regions() {
+		run, err := r.regions[name].GetRun(ctx, id)
+		if err != nil {
+			continue
+		}
+		if found == nil || run.UpdatedAt.After(found.UpdatedAt) {
+			found = run
+		}
+	}
+	if found == nil {
+		return nil, ErrRunNotFound
+	}
+	return found, nil
+}
+
+func (r *FederatedRegistry) ListRuns(ctx context.Context) ([]domain.SandboxRun, error) {
+	latest := make(map[domain.SandboxID]domain.SandboxRun)
+	var errs []error
+
+	for _, name := range r.Regions() {
+		runs, err := r.regions[name].ListRuns(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("region %s: %w", name, err))
+			continue
+		}
+		for _, run := range runs {
+			if prev, ok := latest[run.ID]; !ok || run.UpdatedAt.After(prev.UpdatedAt) {
+				latest[run.ID] = run
+			}
+		}
+	}
+
+	if len(errs) == len(r.regions) {
+		return nil, errors.Join(errs...)
+	}
+
+	list := make([]domain.SandboxRun, 0, len(latest))
+	for _, run := range latest {
+		list = append(list, run)
+	}
+	return list, nil
+}
+
+// stampRegion ensures the node carries its region label and records the owner.
+func (r *FederatedRegistry) stampRegion(region string, node domain.NodeStatus) domain.NodeStatus {
+	if node.Labels[domain.NodeLabelRegion] == "" {
+		labels := make(map[string]string, len(node.Labels)+1)
+		for k, v := range node.Labels {
+			labels[k] = v
+		}
+		labels[domain.NodeLabelRegion] = region
+		node.Labels = labels
+	}
+	r.remember(node.ID, region)
+	return node
+}
+
+func (r *FederatedRegistry) remember(id domain.NodeID, region string) {
+	r.mu.Lock()
+	r.nodeRegion[id] = region
+	r.mu.Unlock()
+}
+
+func (r *FederatedRegistry) ownerOf(id domain.NodeID) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.nodeRegion[id]
+	return name, ok
+}
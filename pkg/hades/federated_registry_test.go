@@ -0,0 +1,146 @@
+package hades_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+func newFederation(t *testing.T) (*hades.FederatedRegistry, *hades.MemoryRegistry, *hades.MemoryRegistry) {
+	t.Helper()
+	east := hades.NewMemoryRegistry()
+	west := hades.NewMemoryRegistry()
+	fed, err := hades.NewFederatedRegistry("us-east", map[string]hades.Registry{
+		"us-east": east,
+		"eu-west": west,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create federated registry: %v", err)
+	}
+	return fed, east, west
+}
+
+func TestFederatedRegistry_RoutesHeartbeatsByRegion(t *testing.T) {
+	fed, east, west := newFederation(t)
+	ctx := context.Background()
+
+	for _, hb := range []hades.HeartbeatPayload{
+		{Node: domain.NodeInfo{ID: "east-1", Labels: map[string]string{domain.NodeLabelRegion: "us-east"}}, Time: time.Now()},
+		{Node: domain.NodeInfo{ID: "west-1", Labels: map[string]string{domain.NodeLabelRegion: "eu-west"}}, Time: time.Now()},
+		{Node: domain.NodeInfo{ID: "unlabeled"}, Time: time.Now()},
+	} {
+		if err := fed.UpdateHeartbeat(ctx, hb); err != nil {
+			t.Fatalf("UpdateHeartbeat(%s) failed: %v", hb.Node.ID, err)
+		}
+	}
+
+	if _, err := west.GetNode(ctx, "west-1"); err != nil {
+		t.Errorf("Expected west-1 in eu-west registry: %v", err)
+	}
+	if _, err := east.GetNode(ctx, "west-1"); err == nil {
+		t.Error("Did not expect west-1 in us-east registry")
+	}
+	if _, err := east.GetNode(ctx, "unlabeled"); err != nil {
+		t.Errorf("Expected unlabeled node in local registry: %v", err)
+	}
+
+	nodes, err := fed.ListNodes(ctx)
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("Expected 3 nodes across regions, got %d", len(nodes))
+	}
+	for _, n := range nodes {
+		if n.Labels[domain.NodeLabelRegion] == "" {
+			t.Errorf("Node %s missing region label", n.ID)
+		}
+	}
+
+	err = fed.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "mars-1", Labels: map[string]string{domain.NodeLabelRegion: "mars"}},
+		Time: time.Now(),
+	})
+	if !errors.Is(err, hades.ErrUnknownRegion) {
+		t.Errorf("Expected ErrUnknownRegion, got %v", err)
+	}
+}
+
+func TestFederatedRegistry_RunsFollowNodeRegion(t *testing.T) {
+	fed, east, west := newFederation(t)
+	ctx := context.Background()
+
+	fed.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "west-1", Labels: map[string]string{domain.NodeLabelRegion: "eu-west"}},
+		Time: time.Now(),
+	})
+
+	// Pending run lands in the local region
+	pending := domain.SandboxRun{ID: "run-1", Status: domain.RunStatusPending, UpdatedAt: time.Now()}
+	if err := fed.UpdateRun(ctx, pending); err != nil {
+		t.Fatalf("UpdateRun failed: %v", err)
+	}
+	if _, err := east.GetRun(ctx, "run-1"); err != nil {
+		t.Fatalf("Expected pending run in local region: %v", err)
+	}
+
+	// Once scheduled on a remote node it is written to that region
+	scheduled := pending
+	scheduled.NodeID = "west-1"
+	scheduled.Status = domain.RunStatusScheduled
+	scheduled.UpdatedAt = pending.UpdatedAt.Add(time.Second)
+	if err := fed.UpdateRun(ctx, scheduled); err != nil {
+		t.Fatalf("UpdateRun failed: %v", err)
+	}
+	if _, err := west.GetRun(ctx, "run-1"); err != nil {
+		t.Fatalf("Expected scheduled run in eu-west region: %v", err)
+	}
+
+	got, err := fed.GetRun(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("GetRun failed: %v", err)
+	}
+	if got.Status != domain.RunStatusScheduled {
+		t.Errorf("Expected newest copy (SCHEDULED), got %s", got.Status)
+	}
+
+	runs, err := fed.ListRuns(ctx)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != domain.RunStatusScheduled {
+		t.Errorf("Expected a single deduplicated SCHEDULED run, got %+v", runs)
+	}
+}
+
+func TestFederatedRegistry_MarkDrainingRoutesToOwner(t *testing.T) {
+	fed, _, west := newFederation(t)
+	ctx := context.Background()
+
+	fed.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "west-1", Labels: map[string]string{domain.NodeLabelRegion: "eu-west"}},
+		Time: time.Now(),
+	})
+
+	if err := fed.MarkDraining(ctx, "west-1"); err != nil {
+		t.Fatalf("MarkDraining failed: %v", err)
+	}
+	node, err := west.GetNode(ctx, "west-1")
+	if err != nil {
+		t.Fatalf("GetNode failed: %v", err)
+	}
+	if node.Labels["status"] != "draining" {
+		t.Errorf("Expected node to be draining in its own region")
+	}
+}
+
+func TestNewFederatedRegistry_RequiresLocalRegion(t *testing.T) {
+	_, err := hades.NewFederatedRegistry("ap-south", map[string]hades.Registry{"us-east": hades.NewMemoryRegistry()})
+	if !errors.Is(err, hades.ErrUnknownRegion) {
+		t.Errorf("Expected ErrUnknownRegion, got %v", err)
+	}
+}
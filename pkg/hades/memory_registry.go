@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
 
 const (
@@ -15,8 +16,14 @@ const (
 )
 
 type MemoryRegistry struct {
-	nodes sync.Map // map[domain.NodeID]domain.NodeStatus
-	runs  sync.Map // map[domain.SandboxID]domain.SandboxRun
+	nodes sync.Map   // map[domain.NodeID]domain.NodeStatus
+	runMu sync.Mutex // Serializes run writes so revisions are compared atomically
+	runs  sync.Map   // map[domain.SandboxID]domain.SandboxRun
+
+	snapMu    sync.Mutex // Serializes snapshot catalog updates
+	snapshots sync.Map   // map[domain.SnapshotID]domain.SnapshotRecord
+
+	conflicts conflictCounter
 }
 
 func NewMemoryRegistry() *MemoryRegistry {
@@ -67,8 +74,19 @@ func (r *MemoryRegistry) UpdateHeartbeat(ctx context.Context, payload HeartbeatP
 		NodeInfo:        payload.Node,
 		Allocated:       payload.Load,
 		ActiveSandboxes: payload.ActiveSandboxes,
+		Conditions:      payload.Conditions,
+		AgentVersion:    payload.AgentVersion,
+		AgentBuild:      payload.AgentBuild,
+		AgentStartedAt:  payload.AgentStartedAt,
+		CachedImages:    payload.CachedImages,
+		CachedArtifacts: payload.CachedArtifacts,
+		CgroupSlices:    payload.CgroupSlices,
+		MemorySharing:   payload.MemorySharing,
 		Heartbeat:       payload.Time,
 	}
+	if prev, ok := r.nodes.Load(status.ID); ok {
+		keepDraining(&status, prev.(domain.NodeStatus))
+	}
 
 	r.nodes.Store(status.ID, status)
 	return nil
@@ -77,24 +95,71 @@ func (r *MemoryRegistry) UpdateHeartbeat(ctx context.Context, payload HeartbeatP
 func (r *MemoryRegistry) MarkDraining(ctx context.Context, id domain.NodeID) error {
 	val, ok := r.nodes.Load(id)
 	if !ok {
-		return errors.New("node not found")
+		return ErrNodeNotFound
 	}
 	status := val.(domain.NodeStatus)
 
-	// Initialize Labels map if needed
-	if status.Labels == nil {
-		status.Labels = make(map[string]string)
+	// Copy the labels so the map shared with the heartbeat payload is untouched
+	labels := make(map[string]string, len(status.Labels)+1)
+	for k, v := range status.Labels {
+		labels[k] = v
+	}
+	labels[domain.NodeLabelStatus] = domain.NodeStatusDraining
+	status.Labels = labels
+	r.nodes.Store(id, status)
+	return nil
+}
+
+func (r *MemoryRegistry) MarkSchedulable(ctx context.Context, id domain.NodeID) error {
+	val, ok := r.nodes.Load(id)
+	if !ok {
+		return ErrNodeNotFound
 	}
-	status.Labels["status"] = "draining"
+	status := val.(domain.NodeStatus)
+	status.Labels = withoutDraining(status.Labels)
 	r.nodes.Store(id, status)
 	return nil
 }
 
+// WithMetrics makes the registry count resolved run conflicts in
+// hades_run_conflicts_total.
+func (r *MemoryRegistry) WithMetrics(metrics hermes.Metrics) *MemoryRegistry {
+	r.conflicts.metrics = metrics
+	return r
+}
+
 func (r *MemoryRegistry) UpdateRun(ctx context.Context, run domain.SandboxRun) error {
-	r.runs.Store(run.ID, run)
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	resolved, write, resolution := resolveRun(r.storedRun(run.ID), run)
+	r.conflicts.record(resolution)
+	if write {
+		r.runs.Store(run.ID, resolved)
+	}
+	return nil
+}
+
+func (r *MemoryRegistry) CompareAndSwapRun(ctx context.Context, run domain.SandboxRun) error {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	swapped, err := swapRun(r.storedRun(run.ID), run)
+	if err != nil {
+		r.conflicts.record(ConflictRejected)
+		return err
+	}
+	r.runs.Store(run.ID, swapped)
 	return nil
 }
 
+func (r *MemoryRegistry) storedRun(id domain.SandboxID) *domain.SandboxRun {
+	val, ok := r.runs.Load(id)
+	if !ok {
+		return nil
+	}
+	run := val.(domain.SandboxRun)
+	return &run
+}
+
 func (r *MemoryRegistry) GetRun(ctx context.Context, id domain.SandboxID) (*domain.SandboxRun, error) {
 	val, ok := r.runs.Load(id)
 	if !ok {
@@ -2,11 +2,13 @@ package hades_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
 
 func TestMemoryRegistry_NodeExpiration(t *testing.T) {
@@ -110,3 +112,202 @@ func TestMemoryRegistry_NodeTTL(t *testing.T) {
 
 	t.Logf("✓ GetNode correctly rejects expired nodes")
 }
+
+func TestMemoryRegistry_StoresConditions(t *testing.T) {
+	registry := hades.NewMemoryRegistry()
+	ctx := context.Background()
+
+	err := registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "node-1"},
+		Conditions: []domain.NodeCondition{
+			{Type: domain.NodeDiskPressure, Status: true, Reason: "ThresholdExceeded"},
+			{Type: domain.NodeKVMAvailable, Status: true},
+		},
+		AgentVersion: "v1.2.3",
+		AgentBuild:   &domain.AgentBuild{Commit: "abc1234"},
+		Time:         time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to update heartbeat: %v", err)
+	}
+
+	node, err := registry.GetNode(ctx, "node-1")
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if node.AgentVersion != "v1.2.3" {
+		t.Errorf("Expected agent version v1.2.3, got %q", node.AgentVersion)
+	}
+	if node.AgentBuild == nil || node.AgentBuild.Commit != "abc1234" {
+		t.Errorf("Expected agent commit abc1234, got %+v", node.AgentBuild)
+	}
+	if c, ok := node.Condition(domain.NodeDiskPressure); !ok || !c.Status {
+		t.Errorf("Expected DiskPressure=true, got %+v (found=%v)", c, ok)
+	}
+	if !node.UnderPressure() {
+		t.Error("Expected node to be under pressure")
+	}
+}
+
+func TestMemoryRegistry_DrainingSurvivesHeartbeat(t *testing.T) {
+	registry := hades.NewMemoryRegistry()
+	ctx := context.Background()
+
+	payload := hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "node-1", Labels: map[string]string{"region": "us-west"}},
+		Time: time.Now(),
+	}
+	if err := registry.UpdateHeartbeat(ctx, payload); err != nil {
+		t.Fatalf("Failed to update heartbeat: %v", err)
+	}
+	if err := registry.MarkDraining(ctx, "node-1"); err != nil {
+		t.Fatalf("Failed to mark draining: %v", err)
+	}
+
+	// The agent keeps reporting its own labels only
+	payload.Time = time.Now()
+	if err := registry.UpdateHeartbeat(ctx, payload); err != nil {
+		t.Fatalf("Failed to update heartbeat: %v", err)
+	}
+
+	node, err := registry.GetNode(ctx, "node-1")
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if !node.Draining() {
+		t.Error("Expected node to stay draining after heartbeat")
+	}
+	if node.Labels["region"] != "us-west" {
+		t.Errorf("Expected agent labels to be kept, got %v", node.Labels)
+	}
+	if _, ok := payload.Node.Labels[domain.NodeLabelStatus]; ok {
+		t.Error("Heartbeat payload labels must not be modified")
+	}
+}
+
+func TestMemoryRegistry_MarkSchedulable(t *testing.T) {
+	registry := hades.NewMemoryRegistry()
+	ctx := context.Background()
+
+	payload := hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "node-1", Labels: map[string]string{"region": "us-west"}},
+		Time: time.Now(),
+	}
+	if err := registry.UpdateHeartbeat(ctx, payload); err != nil {
+		t.Fatalf("Failed to update heartbeat: %v", err)
+	}
+	if err := registry.MarkDraining(ctx, "node-1"); err != nil {
+		t.Fatalf("Failed to mark draining: %v", err)
+	}
+	if err := registry.MarkSchedulable(ctx, "node-1"); err != nil {
+		t.Fatalf("Failed to mark schedulable: %v", err)
+	}
+
+	// The cordon no longer survives heartbeats
+	payload.Time = time.Now()
+	if err := registry.UpdateHeartbeat(ctx, payload); err != nil {
+		t.Fatalf("Failed to update heartbeat: %v", err)
+	}
+	node, err := registry.GetNode(ctx, "node-1")
+	if err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if node.Draining() {
+		t.Error("Expected node to be schedulable")
+	}
+	if node.Labels["region"] != "us-west" {
+		t.Errorf("Expected agent labels to be kept, got %v", node.Labels)
+	}
+
+	if err := registry.MarkSchedulable(ctx, "missing"); !errors.Is(err, hades.ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+// conflictMetrics counts hades_run_conflicts_total by resolution.
+type conflictMetrics struct {
+	hermes.Metrics
+	conflicts map[string]float64
+}
+
+func (m *conflictMetrics) IncCounter(name string, value float64, labels ...hermes.Label) {
+	if name == "hades_run_conflicts_total" {
+		m.conflicts[labels[0].Value] += value
+	}
+}
+
+func TestMemoryRegistry_RunConflicts(t *testing.T) {
+	ctx := context.Background()
+	metrics := &conflictMetrics{conflicts: make(map[string]float64)}
+	registry := hades.NewMemoryRegistry().WithMetrics(metrics)
+
+	// The agent and Olympus both start from the scheduled run
+	if err := registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-1", Status: domain.RunStatusScheduled}); err != nil {
+		t.Fatal(err)
+	}
+	read, _ := registry.GetRun(ctx, "sb-1")
+	if read.Revision != 1 {
+		t.Fatalf("expected revision 1, got %d", read.Revision)
+	}
+
+	canceled := *read
+	canceled.Status = domain.RunStatusCanceled
+	canceled.Error = "canceled by user"
+	if err := registry.UpdateRun(ctx, canceled); err != nil {
+		t.Fatal(err)
+	}
+
+	// The agent reports RUNNING late: the final state wins
+	running := *read
+	running.Status = domain.RunStatusRunning
+	if err := registry.UpdateRun(ctx, running); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := registry.GetRun(ctx, "sb-1")
+	if got.Status != domain.RunStatusCanceled || got.Revision != 2 {
+		t.Errorf("expected the canceled run at revision 2 to stand, got %s at %d", got.Status, got.Revision)
+	}
+
+	// A stale final state keeps the first outcome but applies other fields
+	failed := *read
+	failed.Status = domain.RunStatusFailed
+	failed.Error = "killed"
+	failed.CrashBundle = "bundles/sb-1"
+	if err := registry.UpdateRun(ctx, failed); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = registry.GetRun(ctx, "sb-1")
+	if got.Status != domain.RunStatusCanceled || got.Error != "canceled by user" || got.CrashBundle != "bundles/sb-1" || got.Revision != 3 {
+		t.Errorf("unexpected merged run %+v", got)
+	}
+
+	// A writer that read the finished run may reopen it
+	reopened := *got
+	reopened.Status = domain.RunStatusPending
+	if err := registry.UpdateRun(ctx, reopened); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = registry.GetRun(ctx, "sb-1")
+	if got.Status != domain.RunStatusPending {
+		t.Errorf("expected an up-to-date write to apply, got %s", got.Status)
+	}
+
+	// Compare-and-swap refuses stale revisions
+	stale := *read
+	if err := registry.CompareAndSwapRun(ctx, stale); !errors.Is(err, hades.ErrRunConflict) {
+		t.Errorf("expected ErrRunConflict, got %v", err)
+	}
+	if err := registry.CompareAndSwapRun(ctx, *got); err != nil {
+		t.Errorf("expected the swap to apply, got %v", err)
+	}
+	if err := registry.CompareAndSwapRun(ctx, domain.SandboxRun{ID: "sb-2"}); err != nil {
+		t.Errorf("expected a new run to be created, got %v", err)
+	}
+
+	want := map[string]float64{hades.ConflictDropped: 1, hades.ConflictStatusKept: 1, hades.ConflictRejected: 1}
+	for resolution, n := range want {
+		if metrics.conflicts[resolution] != n {
+			t.Errorf("expected %v %s conflicts, got %v", n, resolution, metrics.conflicts)
+		}
+	}
+}
@@ -0,0 +1,553 @@
+package hades
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// postgresMigrationLock is the advisory lock that serializes schema
+// migrations between Olympus replicas starting together.
+const postgresMigrationLock = 0x6861646573 // "hades"
+
+// postgresMigrations are applied in order, each once. Released migrations
+// must never change; append new ones instead.
+var postgresMigrations = []string{
+	// 1: nodes, runs and the snapshot catalog
+	`CREATE TABLE hades_nodes (
+		id        TEXT PRIMARY KEY,
+		status    JSONB NOT NULL,
+		draining  BOOLEAN NOT NULL DEFAULT FALSE,
+		heartbeat TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX hades_nodes_heartbeat_idx ON hades_nodes (heartbeat);
+
+	CREATE TABLE hades_runs (
+		id           TEXT PRIMARY KEY,
+		node_id      TEXT NOT NULL,
+		template     TEXT NOT NULL,
+		status       TEXT NOT NULL,
+		submitter_id TEXT NOT NULL,
+		tenant_id    TEXT NOT NULL,
+		exit_code    INTEGER,
+		created_at   TIMESTAMPTZ NOT NULL,
+		updated_at   TIMESTAMPTZ NOT NULL,
+		finished_at  TIMESTAMPTZ,
+		run          JSONB NOT NULL
+	);
+	CREATE INDEX hades_runs_status_idx ON hades_runs (status, created_at);
+	CREATE INDEX hades_runs_node_idx ON hades_runs (node_id, created_at);
+	CREATE INDEX hades_runs_template_idx ON hades_runs (template, created_at);
+	CREATE INDEX hades_runs_submitter_idx ON hades_runs (submitter_id, created_at);
+	CREATE INDEX hades_runs_tenant_idx ON hades_runs (tenant_id, created_at);
+	CREATE INDEX hades_runs_created_idx ON hades_runs (created_at);
+
+	CREATE TABLE hades_snapshots (
+		id       TEXT PRIMARY KEY,
+		template TEXT NOT NULL,
+		record   JSONB NOT NULL
+	);
+	CREATE INDEX hades_snapshots_template_idx ON hades_snapshots (template);`,
+}
+
+// PostgresRegistry keeps nodes, runs and the snapshot catalog in
+// PostgreSQL. Unlike RedisRegistry, runs are kept until deleted, for
+// sandbox history and SQL reporting; run columns other than the JSON
+// document exist for indexed queries. Nodes whose heartbeat is older than
+// NodeTTL are hidden but kept.
+type PostgresRegistry struct {
+	db        *sql.DB
+	now       func() time.Time
+	conflicts conflictCounter
+}
+
+// NewPostgresRegistry connects to the database at dsn, a PostgreSQL URL or
+// key=value connection string, and migrates its schema.
+func NewPostgresRegistry(dsn string) (*PostgresRegistry, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	r, err := NewPostgresRegistryFromDB(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewPostgresRegistryFromDB uses an open database, such as a pool shared
+// with other components, and migrates its schema.
+func NewPostgresRegistryFromDB(ctx context.Context, db *sql.DB) (*PostgresRegistry, error) {
+	r := &PostgresRegistry{db: db, now: time.Now}
+	if err := r.Migrate(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Close closes the database connections.
+func (r *PostgresRegistry) Close() error {
+	return r.db.Close()
+}
+
+// Migrate applies the schema migrations the database does not have yet.
+func (r *PostgresRegistry) Migrate(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS hades_schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM hades_schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(postgresMigrations) {
+		return fmt.Errorf("schema version %d is newer than this build supports (%d)", version, len(postgresMigrations))
+	}
+
+	for i := version; i < len(postgresMigrations); i++ {
+		if _, err := tx.ExecContext(ctx, postgresMigrations[i]); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO hades_schema_migrations (version, applied_at) VALUES ($1, $2)`, i+1, r.now()); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRegistry) ListNodes(ctx context.Context) ([]domain.NodeStatus, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT status, draining FROM hades_nodes WHERE heartbeat >= $1 ORDER BY id`,
+		r.now().Add(-NodeTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []domain.NodeStatus
+	for rows.Next() {
+		status, err := scanNode(rows)
+		if err != nil {
+			// Skip corrupt entries, like RedisRegistry
+			continue
+		}
+		nodes = append(nodes, *status)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return nodes, nil
+}
+
+func (r *PostgresRegistry) GetNode(ctx context.Context, id domain.NodeID) (*domain.NodeStatus, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT status, draining FROM hades_nodes WHERE id = $1 AND heartbeat >= $2`,
+		string(id), r.now().Add(-NodeTTL))
+	status, err := scanNode(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNodeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node: %w", err)
+	}
+	return status, nil
+}
+
+func (r *PostgresRegistry) UpdateHeartbeat(ctx context.Context, payload HeartbeatPayload) error {
+	status := domain.NodeStatus{
+		NodeInfo:        payload.Node,
+		Allocated:       payload.Load,
+		ActiveSandboxes: payload.ActiveSandboxes,
+		Conditions:      payload.Conditions,
+		AgentVersion:    payload.AgentVersion,
+		AgentBuild:      payload.AgentBuild,
+		AgentStartedAt:  payload.AgentStartedAt,
+		CachedImages:    payload.CachedImages,
+		CachedArtifacts: payload.CachedArtifacts,
+		CgroupSlices:    payload.CgroupSlices,
+		MemorySharing:   payload.MemorySharing,
+		Heartbeat:       payload.Time,
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal node status: %w", err)
+	}
+
+	// A cordon outlives heartbeats, but not the node expiring
+	_, err = r.db.ExecContext(ctx, `INSERT INTO hades_nodes (id, status, draining, heartbeat)
+		VALUES ($1, $2, FALSE, $3)
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status,
+			draining = hades_nodes.draining AND hades_nodes.heartbeat >= $4,
+			heartbeat = EXCLUDED.heartbeat`,
+		string(status.ID), data, status.Heartbeat, r.now().Add(-NodeTTL))
+	if err != nil {
+		return fmt.Errorf("failed to update heartbeat: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRegistry) MarkDraining(ctx context.Context, id domain.NodeID) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE hades_nodes SET draining = TRUE WHERE id = $1 AND heartbeat >= $2`,
+		string(id), r.now().Add(-NodeTTL))
+	if err != nil {
+		return fmt.Errorf("failed to mark draining: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNodeNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRegistry) MarkSchedulable(ctx context.Context, id domain.NodeID) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE hades_nodes SET draining = FALSE WHERE id = $1 AND heartbeat >= $2`,
+		string(id), r.now().Add(-NodeTTL))
+	if err != nil {
+		return fmt.Errorf("failed to mark schedulable: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNodeNotFound
+	}
+	return nil
+}
+
+// WithMetrics makes the registry count resolved run conflicts in
+// hades_run_conflicts_total.
+func (r *PostgresRegistry) WithMetrics(metrics hermes.Metrics) *PostgresRegistry {
+	r.conflicts.metrics = metrics
+	return r
+}
+
+func (r *PostgresRegistry) UpdateRun(ctx context.Context, run domain.SandboxRun) error {
+	return r.writeRun(ctx, run, func(stored *domain.SandboxRun) (domain.SandboxRun, bool, error) {
+		resolved, write, resolution := resolveRun(stored, run)
+		r.conflicts.record(resolution)
+		return resolved, write, nil
+	})
+}
+
+func (r *PostgresRegistry) CompareAndSwapRun(ctx context.Context, run domain.SandboxRun) error {
+	return r.writeRun(ctx, run, func(stored *domain.SandboxRun) (domain.SandboxRun, bool, error) {
+		swapped, err := swapRun(stored, run)
+		if err != nil {
+			r.conflicts.record(ConflictRejected)
+			return run, false, err
+		}
+		return swapped, true, nil
+	})
+}
+
+// writeRun rewrites the run with its row locked, so revisions are compared
+// against the run as stored when the write lands.
+func (r *PostgresRegistry) writeRun(ctx context.Context, run domain.SandboxRun, update func(stored *domain.SandboxRun) (domain.SandboxRun, bool, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update run: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the row, or the id if the row does not exist yet
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, string(run.ID)); err != nil {
+		return fmt.Errorf("failed to update run: %w", err)
+	}
+
+	var stored *domain.SandboxRun
+	var data []byte
+	err = tx.QueryRowContext(ctx, `SELECT run FROM hades_runs WHERE id = $1`, string(run.ID)).Scan(&data)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("failed to update run: %w", err)
+	default:
+		stored = &domain.SandboxRun{}
+		if err := json.Unmarshal(data, stored); err != nil {
+			return fmt.Errorf("failed to unmarshal run: %w", err)
+		}
+	}
+
+	run, write, err := update(stored)
+	if err != nil || !write {
+		return err
+	}
+	if data, err = json.Marshal(run); err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	var submitterID, tenantID string
+	if run.Submitter != nil {
+		submitterID, tenantID = run.Submitter.ID, run.Submitter.TenantID
+	}
+	createdAt := run.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = r.now()
+	}
+	updatedAt := run.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = r.now()
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO hades_runs
+		(id, node_id, template, status, submitter_id, tenant_id, exit_code, created_at, updated_at, finished_at, run)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			node_id = EXCLUDED.node_id,
+			template = EXCLUDED.template,
+			status = EXCLUDED.status,
+			submitter_id = EXCLUDED.submitter_id,
+			tenant_id = EXCLUDED.tenant_id,
+			exit_code = EXCLUDED.exit_code,
+			created_at = LEAST(hades_runs.created_at, EXCLUDED.created_at),
+			updated_at = EXCLUDED.updated_at,
+			finished_at = EXCLUDED.finished_at,
+			run = EXCLUDED.run`,
+		string(run.ID), string(run.NodeID), string(run.Template), string(run.Status),
+		submitterID, tenantID, nullInt(run.ExitCode), createdAt, updatedAt, nullTime(run.FinishedAt), data)
+	if err != nil {
+		return fmt.Errorf("failed to update run: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update run: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRegistry) GetRun(ctx context.Context, id domain.SandboxID) (*domain.SandboxRun, error) {
+	var data []byte
+	err := r.db.QueryRowContext(ctx, `SELECT run FROM hades_runs WHERE id = $1`, string(id)).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run: %w", err)
+	}
+
+	var run domain.SandboxRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run: %w", err)
+	}
+	return &run, nil
+}
+
+func (r *PostgresRegistry) ListRuns(ctx context.Context) ([]domain.SandboxRun, error) {
+	return r.QueryRuns(ctx, RunQuery{})
+}
+
+// QueryRuns returns the runs matching q, oldest first, using the run
+// indexes.
+func (r *PostgresRegistry) QueryRuns(ctx context.Context, q RunQuery) ([]domain.SandboxRun, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, strings.Replace(cond, "?", "$"+strconv.Itoa(len(args)), 1))
+	}
+	if q.Status != "" {
+		add("status = ?", string(q.Status))
+	}
+	if q.NodeID != "" {
+		add("node_id = ?", string(q.NodeID))
+	}
+	if q.Template != "" {
+		add("template = ?", string(q.Template))
+	}
+	if q.SubmitterID != "" {
+		add("submitter_id = ?", q.SubmitterID)
+	}
+	if q.TenantID != "" {
+		add("tenant_id = ?", q.TenantID)
+	}
+	if !q.Since.IsZero() {
+		add("created_at >= ?", q.Since)
+	}
+	if !q.Until.IsZero() {
+		add("created_at < ?", q.Until)
+	}
+
+	query := `SELECT run FROM hades_runs`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at, id`
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []domain.SandboxRun
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		var run domain.SandboxRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *PostgresRegistry) RecordSnapshot(ctx context.Context, rec domain.SnapshotRecord) error {
+	return r.updateSnapshot(ctx, rec.ID, func(prev *domain.SnapshotRecord) domain.SnapshotRecord {
+		return mergeSnapshot(prev, rec)
+	})
+}
+
+func (r *PostgresRegistry) RecordRestore(ctx context.Context, rec domain.SnapshotRecord, at time.Time) error {
+	return r.updateSnapshot(ctx, rec.ID, func(prev *domain.SnapshotRecord) domain.SnapshotRecord {
+		return restoreSnapshot(prev, rec, at)
+	})
+}
+
+// updateSnapshot rewrites the snapshot's entry with its row locked, so
+// concurrent restores are all counted.
+func (r *PostgresRegistry) updateSnapshot(ctx context.Context, id domain.SnapshotID, update func(prev *domain.SnapshotRecord) domain.SnapshotRecord) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the row, or the id if the row does not exist yet
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, string(id)); err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	}
+
+	var prev *domain.SnapshotRecord
+	var data []byte
+	err = tx.QueryRowContext(ctx, `SELECT record FROM hades_snapshots WHERE id = $1`, string(id)).Scan(&data)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	default:
+		prev = &domain.SnapshotRecord{}
+		if err := json.Unmarshal(data, prev); err != nil {
+			return fmt.Errorf("failed to unmarshal snapshot: %w", err)
+		}
+	}
+
+	rec := update(prev)
+	if data, err = json.Marshal(rec); err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO hades_snapshots (id, template, record) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET template = EXCLUDED.template, record = EXCLUDED.record`,
+		string(rec.ID), string(rec.Template), data); err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRegistry) DeleteSnapshotRecord(ctx context.Context, id domain.SnapshotID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM hades_snapshots WHERE id = $1`, string(id)); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRegistry) ListSnapshotRecords(ctx context.Context) ([]domain.SnapshotRecord, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT record FROM hades_snapshots ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var list []domain.SnapshotRecord
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		var rec domain.SnapshotRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			continue
+		}
+		list = append(list, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	return list, nil
+}
+
+// scanNode reads a node row, adding the draining label of a cordoned node.
+func scanNode(row interface{ Scan(dest ...any) error }) (*domain.NodeStatus, error) {
+	var data []byte
+	var draining bool
+	if err := row.Scan(&data, &draining); err != nil {
+		return nil, err
+	}
+	var status domain.NodeStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node status: %w", err)
+	}
+	if draining {
+		keepDraining(&status, domain.NodeStatus{NodeInfo: domain.NodeInfo{
+			Labels: map[string]string{domain.NodeLabelStatus: domain.NodeStatusDraining},
+		}})
+	}
+	return &status, nil
+}
+
+func nullInt(v *int) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*v), Valid: true}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
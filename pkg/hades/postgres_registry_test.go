@@ -0,0 +1,212 @@
+package hades_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+// newMockPostgres returns a registry on a mock database whose schema is at
+// version 1.
+func newMockPostgres(t *testing.T) (*hades.PostgresRegistry, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS hades_schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	mock.ExpectCommit()
+
+	registry, err := hades.NewPostgresRegistryFromDB(context.Background(), db)
+	require.NoError(t, err)
+	return registry, mock
+}
+
+func TestPostgresRegistry_Migrate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// A new database gets every migration
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS hades_schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+	mock.ExpectExec("CREATE TABLE hades_nodes").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO hades_schema_migrations").WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err = hades.NewPostgresRegistryFromDB(context.Background(), db)
+	require.NoError(t, err)
+
+	// A schema from a newer build is refused
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS hades_schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(99))
+	mock.ExpectRollback()
+
+	_, err = hades.NewPostgresRegistryFromDB(context.Background(), db)
+	assert.ErrorContains(t, err, "newer than this build")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRegistry_Nodes(t *testing.T) {
+	registry, mock := newMockPostgres(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectExec("INSERT INTO hades_nodes").
+		WithArgs("node-1", sqlmock.AnyArg(), now, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "node-1", Labels: map[string]string{"region": "eu"}},
+		Time: now,
+	}))
+
+	status, err := json.Marshal(domain.NodeStatus{
+		NodeInfo:  domain.NodeInfo{ID: "node-1", Labels: map[string]string{"region": "eu"}},
+		Heartbeat: now,
+	})
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT status, draining FROM hades_nodes WHERE id").
+		WithArgs("node-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "draining"}).AddRow(status, true))
+	node, err := registry.GetNode(ctx, "node-1")
+	require.NoError(t, err)
+	assert.True(t, node.Draining())
+	assert.Equal(t, "eu", node.Labels["region"])
+
+	mock.ExpectQuery("SELECT status, draining FROM hades_nodes WHERE id").
+		WithArgs("gone", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "draining"}))
+	_, err = registry.GetNode(ctx, "gone")
+	assert.ErrorIs(t, err, hades.ErrNodeNotFound)
+
+	mock.ExpectExec("UPDATE hades_nodes SET draining = TRUE").
+		WithArgs("gone", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, registry.MarkDraining(ctx, "gone"), hades.ErrNodeNotFound)
+
+	mock.ExpectExec("UPDATE hades_nodes SET draining = FALSE").
+		WithArgs("node-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, registry.MarkSchedulable(ctx, "node-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRegistry_Runs(t *testing.T) {
+	registry, mock := newMockPostgres(t)
+	ctx := context.Background()
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	code := 0
+	run := domain.SandboxRun{
+		ID:        "sb-1",
+		NodeID:    "node-1",
+		Template:  "python",
+		Status:    domain.RunStatusSucceeded,
+		ExitCode:  &code,
+		CreatedAt: created,
+		UpdatedAt: created,
+		Submitter: &domain.Submitter{ID: "alice", TenantID: "acme"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext($1))")).WithArgs("sb-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT run FROM hades_runs WHERE id = $1")).WithArgs("sb-1").
+		WillReturnRows(sqlmock.NewRows([]string{"run"}))
+	mock.ExpectExec("INSERT INTO hades_runs").
+		WithArgs("sb-1", "node-1", "python", "SUCCEEDED", "alice", "acme", int64(0), created, created, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, registry.UpdateRun(ctx, run))
+
+	run.Revision = 1
+	data, err := json.Marshal(run)
+	require.NoError(t, err)
+
+	// A stale write cannot reopen the finished run
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext($1))")).WithArgs("sb-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT run FROM hades_runs WHERE id = $1")).WithArgs("sb-1").
+		WillReturnRows(sqlmock.NewRows([]string{"run"}).AddRow(data))
+	mock.ExpectRollback()
+	stale := run
+	stale.Revision = 0
+	stale.Status = domain.RunStatusRunning
+	require.NoError(t, registry.UpdateRun(ctx, stale))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT run FROM hades_runs WHERE id = $1")).
+		WithArgs("sb-1").
+		WillReturnRows(sqlmock.NewRows([]string{"run"}).AddRow(data))
+	got, err := registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.Submitter.ID)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT run FROM hades_runs WHERE id = $1")).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"run"}))
+	_, err = registry.GetRun(ctx, "missing")
+	assert.ErrorIs(t, err, hades.ErrRunNotFound)
+
+	// Queries filter on the indexed columns
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT run FROM hades_runs WHERE status = $1 AND tenant_id = $2 AND created_at >= $3 ORDER BY created_at, id LIMIT $4")).
+		WithArgs("SUCCEEDED", "acme", created, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"run"}).AddRow(data))
+	runs, err := registry.QueryRuns(ctx, hades.RunQuery{
+		Status:   domain.RunStatusSucceeded,
+		TenantID: "acme",
+		Since:    created,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, domain.SandboxID("sb-1"), runs[0].ID)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT run FROM hades_runs ORDER BY created_at, id")).
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"run"}).AddRow(data).AddRow([]byte("not json")))
+	runs, err = registry.ListRuns(ctx)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRegistry_RecordRestore(t *testing.T) {
+	registry, mock := newMockPostgres(t)
+	ctx := context.Background()
+	at := time.Now()
+	prev, err := json.Marshal(domain.SnapshotRecord{ID: "snap-1", Template: "python", RestoreCount: 2})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext($1))")).WithArgs("snap-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT record FROM hades_snapshots").WithArgs("snap-1").
+		WillReturnRows(sqlmock.NewRows([]string{"record"}).AddRow(prev))
+	mock.ExpectExec("INSERT INTO hades_snapshots").
+		WithArgs("snap-1", "python", restoreCount(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, registry.RecordRestore(ctx, domain.SnapshotRecord{ID: "snap-1", Template: "python"}, at))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// restoreCount matches a snapshot record argument with the given count.
+type restoreCount int
+
+func (c restoreCount) Match(v driver.Value) bool {
+	var rec domain.SnapshotRecord
+	data, ok := v.([]byte)
+	return ok && json.Unmarshal(data, &rec) == nil && rec.RestoreCount == int(c)
+}
@@ -9,10 +9,26 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
 
+// RedisRunTTL is how long a run is kept in Redis after its last update.
+const RedisRunTTL = 24 * time.Hour
+
+// redisRunRetries bounds optimistic-lock retries when the agent and
+// Olympus write a run concurrently.
+const redisRunRetries = 5
+
+// RedisRunKey is the Redis key a run is stored under. Writers that persist
+// runs alongside other data in one transaction, such as the Acheron outbox,
+// use it to stay compatible with RedisRegistry.
+func RedisRunKey(id domain.SandboxID) string {
+	return fmt.Sprintf("tartarus:run:%s", id)
+}
+
 type RedisRegistry struct {
-	client *redis.Client
+	client    *redis.Client
+	conflicts conflictCounter
 }
 
 func NewRedisRegistry(addr string, db int, password string) (*RedisRegistry, error) {
@@ -84,15 +100,30 @@ func (r *RedisRegistry) UpdateHeartbeat(ctx context.Context, payload HeartbeatPa
 		NodeInfo:        payload.Node,
 		Allocated:       payload.Load,
 		ActiveSandboxes: payload.ActiveSandboxes,
+		Conditions:      payload.Conditions,
+		AgentVersion:    payload.AgentVersion,
+		AgentBuild:      payload.AgentBuild,
+		AgentStartedAt:  payload.AgentStartedAt,
+		CachedImages:    payload.CachedImages,
+		CachedArtifacts: payload.CachedArtifacts,
+		CgroupSlices:    payload.CgroupSlices,
+		MemorySharing:   payload.MemorySharing,
 		Heartbeat:       payload.Time,
 	}
 
+	key := fmt.Sprintf("tartarus:node:%s", status.ID)
+	if val, err := r.client.Get(ctx, key).Result(); err == nil {
+		var prev domain.NodeStatus
+		if json.Unmarshal([]byte(val), &prev) == nil {
+			keepDraining(&status, prev)
+		}
+	}
+
 	data, err := json.Marshal(status)
 	if err != nil {
 		return fmt.Errorf("failed to marshal node status: %w", err)
 	}
 
-	key := fmt.Sprintf("tartarus:node:%s", status.ID)
 	// Set with TTL
 	if err := r.client.Set(ctx, key, data, NodeTTL).Err(); err != nil {
 		return fmt.Errorf("failed to update heartbeat: %w", err)
@@ -128,7 +159,7 @@ func (r *RedisRegistry) MarkDraining(ctx context.Context, id domain.NodeID) erro
 		if status.Labels == nil {
 			status.Labels = make(map[string]string)
 		}
-		status.Labels["status"] = "draining"
+		status.Labels[domain.NodeLabelStatus] = domain.NodeStatusDraining
 
 		data, err := json.Marshal(status)
 		if err != nil {
@@ -150,23 +181,120 @@ func (r *RedisRegistry) MarkDraining(ctx context.Context, id domain.NodeID) erro
 	return nil
 }
 
-func (r *RedisRegistry) UpdateRun(ctx context.Context, run domain.SandboxRun) error {
-	data, err := json.Marshal(run)
+func (r *RedisRegistry) MarkSchedulable(ctx context.Context, id domain.NodeID) error {
+	key := fmt.Sprintf("tartarus:node:%s", id)
+
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return ErrNodeNotFound
+			}
+			return err
+		}
+
+		var status domain.NodeStatus
+		if err := json.Unmarshal([]byte(val), &status); err != nil {
+			return err
+		}
+		status.Labels = withoutDraining(status.Labels)
+
+		data, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, redis.KeepTTL)
+			return nil
+		})
+		return err
+	}, key)
+
 	if err != nil {
-		return fmt.Errorf("failed to marshal run: %w", err)
+		return fmt.Errorf("failed to mark schedulable: %w", err)
 	}
 
-	key := fmt.Sprintf("tartarus:run:%s", run.ID)
-	// Store run indefinitely (or with long TTL)
-	if err := r.client.Set(ctx, key, data, 24*time.Hour).Err(); err != nil {
-		return fmt.Errorf("failed to update run: %w", err)
+	return nil
+}
+
+// WithMetrics makes the registry count resolved run conflicts in
+// hades_run_conflicts_total.
+func (r *RedisRegistry) WithMetrics(metrics hermes.Metrics) *RedisRegistry {
+	r.conflicts.metrics = metrics
+	return r
+}
+
+func (r *RedisRegistry) UpdateRun(ctx context.Context, run domain.SandboxRun) error {
+	return r.writeRun(ctx, run, func(stored *domain.SandboxRun) (domain.SandboxRun, bool, error) {
+		resolved, write, resolution := resolveRun(stored, run)
+		r.conflicts.record(resolution)
+		return resolved, write, nil
+	})
+}
+
+func (r *RedisRegistry) CompareAndSwapRun(ctx context.Context, run domain.SandboxRun) error {
+	return r.writeRun(ctx, run, func(stored *domain.SandboxRun) (domain.SandboxRun, bool, error) {
+		swapped, err := swapRun(stored, run)
+		if err != nil {
+			r.conflicts.record(ConflictRejected)
+			return run, false, err
+		}
+		return swapped, true, nil
+	})
+}
+
+// writeRun rewrites the run's key under an optimistic lock, so revisions
+// are compared against the run as stored when the write lands.
+func (r *RedisRegistry) writeRun(ctx context.Context, run domain.SandboxRun, update func(stored *domain.SandboxRun) (domain.SandboxRun, bool, error)) error {
+	key := RedisRunKey(run.ID)
+	txf := func(tx *redis.Tx) error {
+		var stored *domain.SandboxRun
+		val, err := tx.Get(ctx, key).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+		case err != nil:
+			return err
+		default:
+			stored = &domain.SandboxRun{}
+			if err := json.Unmarshal([]byte(val), stored); err != nil {
+				return fmt.Errorf("failed to unmarshal run: %w", err)
+			}
+		}
+
+		resolved, write, err := update(stored)
+		if err != nil || !write {
+			return err
+		}
+		data, err := json.Marshal(resolved)
+		if err != nil {
+			return fmt.Errorf("failed to marshal run: %w", err)
+		}
+		// Store run indefinitely (or with long TTL)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, RedisRunTTL)
+			return nil
+		})
+		return err
 	}
 
-	return nil
+	for i := 0; i < redisRunRetries; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if errors.Is(err, ErrRunConflict) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update run: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to update run: too many concurrent updates")
 }
 
 func (r *RedisRegistry) GetRun(ctx context.Context, id domain.SandboxID) (*domain.SandboxRun, error) {
-	key := fmt.Sprintf("tartarus:run:%s", id)
+	key := RedisRunKey(id)
 	val, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
@@ -20,18 +20,82 @@ type Registry interface {
 	GetNode(ctx context.Context, id domain.NodeID) (*domain.NodeStatus, error)
 	UpdateHeartbeat(ctx context.Context, payload HeartbeatPayload) error
 	MarkDraining(ctx context.Context, id domain.NodeID) error
+	// MarkSchedulable lifts a cordon set by MarkDraining.
+	MarkSchedulable(ctx context.Context, id domain.NodeID) error
 
-	// Run persistence
+	// Run persistence. UpdateRun advances the run's revision; a write from
+	// a stale revision cannot reopen a finished run (see resolveRun).
 	UpdateRun(ctx context.Context, run domain.SandboxRun) error
 	GetRun(ctx context.Context, id domain.SandboxID) (*domain.SandboxRun, error)
 	ListRuns(ctx context.Context) ([]domain.SandboxRun, error)
 }
 
+// RunQuery selects runs by their indexed fields. Zero fields match
+// anything.
+type RunQuery struct {
+	Status      domain.RunStatus
+	NodeID      domain.NodeID
+	Template    domain.TemplateID
+	SubmitterID string
+	TenantID    string
+	Since       time.Time // Created at or after
+	Until       time.Time // Created before
+	Limit       int       // 0 = no limit
+}
+
+// RunQuerier is implemented by registries that select runs without
+// listing them all.
+type RunQuerier interface {
+	QueryRuns(ctx context.Context, q RunQuery) ([]domain.SandboxRun, error)
+}
+
+// RunSwapper is implemented by registries that write a run only if it is
+// still at the revision it was read at.
+type RunSwapper interface {
+	// CompareAndSwapRun writes run if the stored run is at run.Revision, 0
+	// if there is none, and fails with ErrRunConflict otherwise.
+	CompareAndSwapRun(ctx context.Context, run domain.SandboxRun) error
+}
+
 // HeartbeatPayload is what Hecatoncheir agents send periodically.
 
 type HeartbeatPayload struct {
 	Node            domain.NodeInfo         `json:"node"`
 	Load            domain.ResourceCapacity `json:"load"`
 	ActiveSandboxes []domain.SandboxRun     `json:"active_sandboxes"`
+	Conditions      []domain.NodeCondition  `json:"conditions,omitempty"`
+	AgentVersion    string                  `json:"agent_version,omitempty"`
+	AgentBuild      *domain.AgentBuild      `json:"agent_build,omitempty"`
+	AgentStartedAt  time.Time               `json:"agent_started_at,omitempty"`
+	CachedImages    []domain.CachedImage    `json:"cached_images,omitempty"`
+	CachedArtifacts []domain.CachedArtifact `json:"cached_artifacts,omitempty"`
+	CgroupSlices    []domain.CgroupSlice    `json:"cgroup_slices,omitempty"`
+	MemorySharing   *domain.MemorySharing   `json:"memory_sharing,omitempty"`
 	Time            time.Time               `json:"time"`
 }
+
+// withoutDraining returns labels without the cordon, leaving labels itself
+// untouched.
+func withoutDraining(labels map[string]string) map[string]string {
+	kept := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k != domain.NodeLabelStatus || v != domain.NodeStatusDraining {
+			kept[k] = v
+		}
+	}
+	return kept
+}
+
+// keepDraining carries a cordon set by MarkDraining over to a status rebuilt
+// from a heartbeat, which only holds the labels the agent reports.
+func keepDraining(status *domain.NodeStatus, prev domain.NodeStatus) {
+	if !prev.Draining() {
+		return
+	}
+	labels := make(map[string]string, len(status.Labels)+1)
+	for k, v := range status.Labels {
+		labels[k] = v
+	}
+	labels[domain.NodeLabelStatus] = domain.NodeStatusDraining
+	status.Labels = labels
+}
@@ -0,0 +1,77 @@
+package hades
+
+import (
+	"errors"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ErrRunConflict is returned by CompareAndSwapRun when the run was written
+// since it was read.
+var ErrRunConflict = errors.New("run was updated since it was read")
+
+// Resolutions of stale run writes, as counted in hades_run_conflicts_total.
+const (
+	ConflictDropped    = "dropped"     // A stale write would have reopened a finished run
+	ConflictStatusKept = "status_kept" // A stale write finished the run differently; the first final state stands
+	ConflictRejected   = "rejected"    // A compare-and-swap found a newer revision
+)
+
+// resolveRun decides what writing run over stored, nil if there is none,
+// leaves in the registry. A write carrying the stored revision applies as
+// is. A stale one, from a writer that has not seen the latest revision,
+// applies too unless the run has finished in the meantime: final states
+// win, so a stale write that would reopen the run is dropped, and one that
+// finishes it differently keeps the stored outcome but applies its other
+// fields. It returns the run to store, with its revision advanced, whether
+// to store it, and the conflict resolution if the rules changed the write.
+func resolveRun(stored *domain.SandboxRun, run domain.SandboxRun) (domain.SandboxRun, bool, string) {
+	if stored == nil {
+		run.Revision = 1
+		return run, true, ""
+	}
+	stale := run.Revision != stored.Revision
+	run.Revision = stored.Revision + 1
+	if !stale || !stored.Status.IsTerminal() {
+		return run, true, ""
+	}
+	if !run.Status.IsTerminal() {
+		return *stored, false, ConflictDropped
+	}
+	if run.Status != stored.Status {
+		run.Status = stored.Status
+		run.ExitCode = stored.ExitCode
+		run.Error = stored.Error
+		run.FinishedAt = stored.FinishedAt
+		return run, true, ConflictStatusKept
+	}
+	return run, true, ""
+}
+
+// swapRun checks a compare-and-swap of run over stored and returns the run
+// to store with its revision advanced.
+func swapRun(stored *domain.SandboxRun, run domain.SandboxRun) (domain.SandboxRun, error) {
+	var revision int64
+	if stored != nil {
+		revision = stored.Revision
+	}
+	if run.Revision != revision {
+		return run, ErrRunConflict
+	}
+	run.Revision++
+	return run, nil
+}
+
+// conflictCounter counts resolved run conflicts. The zero value counts
+// nothing.
+type conflictCounter struct {
+	metrics hermes.Metrics
+}
+
+func (c *conflictCounter) record(resolution string) {
+	if resolution == "" || c.metrics == nil {
+		return
+	}
+	c.metrics.IncCounter("hades_run_conflicts_total", 1, hermes.Label{Key: "resolution", Value: resolution})
+}
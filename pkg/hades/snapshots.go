@@ -0,0 +1,253 @@
+package hades
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+var (
+	// ErrSnapshotNotFound is returned for snapshots missing from the catalog.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrNoSnapshotCatalog is returned when the registry keeps no snapshot catalog.
+	ErrNoSnapshotCatalog = errors.New("registry has no snapshot catalog")
+)
+
+// SnapshotCatalog is implemented by registries that keep snapshot metadata,
+// so snapshots can be searched across templates and nodes.
+type SnapshotCatalog interface {
+	// RecordSnapshot adds or replaces a snapshot's catalog entry. Restore
+	// statistics of an existing entry are kept.
+	RecordSnapshot(ctx context.Context, rec domain.SnapshotRecord) error
+
+	// RecordRestore counts a restore of the snapshot at the given time,
+	// adding rec to the catalog first if the snapshot is not in it yet.
+	RecordRestore(ctx context.Context, rec domain.SnapshotRecord, at time.Time) error
+
+	// DeleteSnapshotRecord removes the snapshot's entry.
+	DeleteSnapshotRecord(ctx context.Context, id domain.SnapshotID) error
+
+	// ListSnapshotRecords returns every catalog entry.
+	ListSnapshotRecords(ctx context.Context) ([]domain.SnapshotRecord, error)
+}
+
+// mergeSnapshot applies a recorded snapshot over an existing entry.
+func mergeSnapshot(prev *domain.SnapshotRecord, rec domain.SnapshotRecord) domain.SnapshotRecord {
+	if prev != nil {
+		rec.RestoreCount = prev.RestoreCount
+		rec.LastRestoredAt = prev.LastRestoredAt
+	}
+	return rec
+}
+
+// restoreSnapshot counts a restore on the entry, creating it from rec if needed.
+func restoreSnapshot(prev *domain.SnapshotRecord, rec domain.SnapshotRecord, at time.Time) domain.SnapshotRecord {
+	if prev != nil {
+		rec = *prev
+	}
+	rec.RestoreCount++
+	if at.After(rec.LastRestoredAt) {
+		rec.LastRestoredAt = at
+	}
+	return rec
+}
+
+func (r *MemoryRegistry) RecordSnapshot(ctx context.Context, rec domain.SnapshotRecord) error {
+	r.snapMu.Lock()
+	defer r.snapMu.Unlock()
+
+	r.snapshots.Store(rec.ID, mergeSnapshot(r.loadSnapshot(rec.ID), rec))
+	return nil
+}
+
+func (r *MemoryRegistry) RecordRestore(ctx context.Context, rec domain.SnapshotRecord, at time.Time) error {
+	r.snapMu.Lock()
+	defer r.snapMu.Unlock()
+
+	r.snapshots.Store(rec.ID, restoreSnapshot(r.loadSnapshot(rec.ID), rec, at))
+	return nil
+}
+
+func (r *MemoryRegistry) DeleteSnapshotRecord(ctx context.Context, id domain.SnapshotID) error {
+	r.snapshots.Delete(id)
+	return nil
+}
+
+func (r *MemoryRegistry) ListSnapshotRecords(ctx context.Context) ([]domain.SnapshotRecord, error) {
+	var list []domain.SnapshotRecord
+	r.snapshots.Range(func(key, value any) bool {
+		list = append(list, value.(domain.SnapshotRecord))
+		return true
+	})
+	return list, nil
+}
+
+func (r *MemoryRegistry) loadSnapshot(id domain.SnapshotID) *domain.SnapshotRecord {
+	val, ok := r.snapshots.Load(id)
+	if !ok {
+		return nil
+	}
+	rec := val.(domain.SnapshotRecord)
+	return &rec
+}
+
+// redisSnapshotRetries bounds optimistic-lock retries when agents restore
+// the same snapshot concurrently.
+const redisSnapshotRetries = 5
+
+func snapshotKey(id domain.SnapshotID) string {
+	return fmt.Sprintf("tartarus:snapshot:%s", id)
+}
+
+func (r *RedisRegistry) RecordSnapshot(ctx context.Context, rec domain.SnapshotRecord) error {
+	return r.updateSnapshot(ctx, rec.ID, func(prev *domain.SnapshotRecord) domain.SnapshotRecord {
+		return mergeSnapshot(prev, rec)
+	})
+}
+
+func (r *RedisRegistry) RecordRestore(ctx context.Context, rec domain.SnapshotRecord, at time.Time) error {
+	return r.updateSnapshot(ctx, rec.ID, func(prev *domain.SnapshotRecord) domain.SnapshotRecord {
+		return restoreSnapshot(prev, rec, at)
+	})
+}
+
+// updateSnapshot rewrites the snapshot's entry under WATCH.
+func (r *RedisRegistry) updateSnapshot(ctx context.Context, id domain.SnapshotID, update func(prev *domain.SnapshotRecord) domain.SnapshotRecord) error {
+	key := snapshotKey(id)
+	txf := func(tx *redis.Tx) error {
+		var prev *domain.SnapshotRecord
+		val, err := tx.Get(ctx, key).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+		case err != nil:
+			return err
+		default:
+			prev = &domain.SnapshotRecord{}
+			if err := json.Unmarshal([]byte(val), prev); err != nil {
+				return fmt.Errorf("failed to unmarshal snapshot: %w", err)
+			}
+		}
+
+		data, err := json.Marshal(update(prev))
+		if err != nil {
+			return fmt.Errorf("failed to marshal snapshot: %w", err)
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < redisSnapshotRetries; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update snapshot: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to update snapshot: too many concurrent updates")
+}
+
+func (r *RedisRegistry) DeleteSnapshotRecord(ctx context.Context, id domain.SnapshotID) error {
+	if err := r.client.Del(ctx, snapshotKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisRegistry) ListSnapshotRecords(ctx context.Context) ([]domain.SnapshotRecord, error) {
+	var list []domain.SnapshotRecord
+	iter := r.client.Scan(ctx, 0, "tartarus:snapshot:*", 0).Iterator()
+
+	for iter.Next(ctx) {
+		key := iter.Val()
+		val, err := r.client.Get(ctx, key).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue // Deleted during iteration
+			}
+			return nil, fmt.Errorf("failed to get snapshot key %s: %w", key, err)
+		}
+
+		var rec domain.SnapshotRecord
+		if err := json.Unmarshal([]byte(val), &rec); err != nil {
+			continue
+		}
+		list = append(list, rec)
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan snapshots: %w", err)
+	}
+
+	return list, nil
+}
+
+// RecordSnapshot records the snapshot in the local region's catalog.
+func (r *FederatedRegistry) RecordSnapshot(ctx context.Context, rec domain.SnapshotRecord) error {
+	catalog, ok := r.regions[r.local].(SnapshotCatalog)
+	if !ok {
+		return ErrNoSnapshotCatalog
+	}
+	return catalog.RecordSnapshot(ctx, rec)
+}
+
+// RecordRestore counts the restore in the local region's catalog.
+func (r *FederatedRegistry) RecordRestore(ctx context.Context, rec domain.SnapshotRecord, at time.Time) error {
+	catalog, ok := r.regions[r.local].(SnapshotCatalog)
+	if !ok {
+		return ErrNoSnapshotCatalog
+	}
+	return catalog.RecordRestore(ctx, rec, at)
+}
+
+// DeleteSnapshotRecord removes the snapshot from every region's catalog.
+func (r *FederatedRegistry) DeleteSnapshotRecord(ctx context.Context, id domain.SnapshotID) error {
+	var errs []error
+	for _, name := range r.Regions() {
+		if catalog, ok := r.regions[name].(SnapshotCatalog); ok {
+			if err := catalog.DeleteSnapshotRecord(ctx, id); err != nil {
+				errs = append(errs, fmt.Errorf("region %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListSnapshotRecords merges the catalogs of all regions.
+func (r *FederatedRegistry) ListSnapshotRecords(ctx context.Context) ([]domain.SnapshotRecord, error) {
+	var list []domain.SnapshotRecord
+	var errs []error
+	catalogs := 0
+
+	for _, name := range r.Regions() {
+		catalog, ok := r.regions[name].(SnapshotCatalog)
+		if !ok {
+			continue
+		}
+		catalogs++
+		recs, err := catalog.ListSnapshotRecords(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("region %s: %w", name, err))
+			continue
+		}
+		list = append(list, recs...)
+	}
+
+	if catalogs == 0 {
+		return nil, ErrNoSnapshotCatalog
+	}
+	if len(errs) == catalogs {
+		return nil, errors.Join(errs...)
+	}
+	return list, nil
+}
@@ -0,0 +1,92 @@
+package hades_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+func testSnapshotCatalog(t *testing.T, catalog hades.SnapshotCatalog) {
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	rec := domain.SnapshotRecord{ID: "snap-1", Template: "python", SandboxID: "sb-1", NodeID: "node-1", SizeBytes: 1024, CreatedAt: created}
+
+	if err := catalog.RecordSnapshot(ctx, rec); err != nil {
+		t.Fatalf("RecordSnapshot: %v", err)
+	}
+	restoredAt := time.Now().Truncate(time.Second)
+	for i := 0; i < 2; i++ {
+		if err := catalog.RecordRestore(ctx, domain.SnapshotRecord{ID: "snap-1"}, restoredAt); err != nil {
+			t.Fatalf("RecordRestore: %v", err)
+		}
+	}
+	// Restores of uncatalogued snapshots add them
+	if err := catalog.RecordRestore(ctx, domain.SnapshotRecord{ID: "tpl-snap", Template: "node"}, restoredAt); err != nil {
+		t.Fatalf("RecordRestore: %v", err)
+	}
+	// Re-recording keeps the restore statistics
+	rec.SizeBytes = 2048
+	if err := catalog.RecordSnapshot(ctx, rec); err != nil {
+		t.Fatalf("RecordSnapshot: %v", err)
+	}
+
+	records, err := catalog.ListSnapshotRecords(ctx)
+	if err != nil {
+		t.Fatalf("ListSnapshotRecords: %v", err)
+	}
+	byID := make(map[domain.SnapshotID]domain.SnapshotRecord)
+	for _, r := range records {
+		byID[r.ID] = r
+	}
+	if got := byID["snap-1"]; got.RestoreCount != 2 || !got.LastRestoredAt.Equal(restoredAt) || got.SizeBytes != 2048 || got.SandboxID != "sb-1" {
+		t.Errorf("unexpected record %+v", got)
+	}
+	if got := byID["tpl-snap"]; got.RestoreCount != 1 || got.Template != "node" {
+		t.Errorf("unexpected record %+v", got)
+	}
+
+	if err := catalog.DeleteSnapshotRecord(ctx, "snap-1"); err != nil {
+		t.Fatalf("DeleteSnapshotRecord: %v", err)
+	}
+	if records, _ := catalog.ListSnapshotRecords(ctx); len(records) != 1 {
+		t.Errorf("expected 1 record after delete, got %d", len(records))
+	}
+}
+
+func TestMemoryRegistry_SnapshotCatalog(t *testing.T) {
+	testSnapshotCatalog(t, hades.NewMemoryRegistry())
+}
+
+func TestRedisRegistry_SnapshotCatalog(t *testing.T) {
+	s := miniredis.RunT(t)
+	registry, err := hades.NewRedisRegistry(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("NewRedisRegistry: %v", err)
+	}
+	testSnapshotCatalog(t, registry)
+}
+
+func TestFederatedRegistry_SnapshotCatalog(t *testing.T) {
+	federation, east, west := newFederation(t)
+	testSnapshotCatalog(t, federation)
+
+	// Catalogs of other regions are searched too
+	ctx := context.Background()
+	if err := west.RecordSnapshot(ctx, domain.SnapshotRecord{ID: "west-snap"}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := federation.ListSnapshotRecords(ctx)
+	if err != nil {
+		t.Fatalf("ListSnapshotRecords: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("expected records from both regions, got %+v", records)
+	}
+	if local, _ := east.ListSnapshotRecords(ctx); len(local) != 1 {
+		t.Errorf("expected writes to go to the local region, got %+v", local)
+	}
+}
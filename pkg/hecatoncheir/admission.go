@@ -0,0 +1,94 @@
+package hecatoncheir
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// admitHeadroom reports whether the node has room for the request next to
+// the sandboxes it already runs. Olympus places on heartbeats that may lag
+// behind, so a node can be sent more than it holds; such a request is handed
+// back for Olympus to place again instead of failing its sandbox. Zero
+// capacities are not enforced.
+func (a *Agent) admitHeadroom(ctx context.Context, req *domain.SandboxRequest, receipt string) bool {
+	if a.Capacity.CPU == 0 && a.Capacity.Mem == 0 {
+		return true
+	}
+	allocated, err := a.Runtime.Allocation(ctx)
+	if err != nil {
+		// Olympus found room for it; without a reading, trust that
+		a.Logger.Error(ctx, "Failed to get allocation for admission", map[string]any{"id": req.ID, "error": err})
+		return true
+	}
+	allocated.CPU += req.Resources.CPU
+	allocated.Mem += req.Resources.Mem
+	if a.fits(allocated) {
+		return true
+	}
+
+	reason := fmt.Sprintf("node %s over-committed: %dm CPU and %d MB allocated with the request, capacity %dm CPU and %d MB",
+		a.NodeID, allocated.CPU, allocated.Mem, a.Capacity.CPU, a.Capacity.Mem)
+	a.Logger.Info(ctx, "Node has no room for request, handing it back", map[string]any{"id": req.ID, "reason": reason})
+	a.reschedule(ctx, req, receipt, reason)
+	return false
+}
+
+// reschedule hands a request back to Olympus: its run is marked RESCHEDULING
+// with the request, which Olympus places again, on another node if one has
+// room. If the run cannot be recorded, the request goes back to this node's
+// queue and waits for room here. Gang members are handled as by requeue.
+func (a *Agent) reschedule(ctx context.Context, req *domain.SandboxRequest, receipt, reason string) {
+	if req.Gang != nil {
+		a.requeue(ctx, req, receipt, reason)
+		return
+	}
+
+	run, err := a.Registry.GetRun(ctx, req.ID)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to get run to reschedule, requeueing", map[string]any{"id": req.ID, "error": err})
+		a.requeueOverCommitted(ctx, req, receipt)
+		return
+	}
+	handedBack := *req
+	handedBack.NodeID = ""
+	handedBack.WarmInstance = ""
+	count := 1
+	if run.Rescheduling != nil {
+		count = run.Rescheduling.Count + 1
+	}
+	now := time.Now()
+	rescheduled := *run
+	rescheduled.Status = domain.RunStatusRescheduling
+	rescheduled.NodeID = ""
+	rescheduled.Rescheduling = &domain.RunRescheduling{
+		Request: &handedBack,
+		From:    a.NodeID,
+		Reason:  reason,
+		Count:   count,
+		At:      now,
+	}
+	rescheduled.UpdatedAt = now
+	if err := a.Registry.UpdateRun(ctx, rescheduled); err != nil {
+		a.Logger.Error(ctx, "Failed to mark run rescheduling, requeueing", map[string]any{"id": req.ID, "error": err})
+		a.requeueOverCommitted(ctx, req, receipt)
+		return
+	}
+	if err := a.Queue.Ack(ctx, receipt); err != nil {
+		a.Logger.Error(ctx, "Failed to ack rescheduled request", map[string]any{"id": req.ID, "error": err})
+	}
+	a.Metrics.IncCounter("agent_jobs_rescheduled_total", 1)
+}
+
+// requeueOverCommitted puts a request the node has no room for back on its
+// queue.
+func (a *Agent) requeueOverCommitted(ctx context.Context, req *domain.SandboxRequest, receipt string) {
+	if err := a.Queue.Nack(ctx, receipt, "over-committed"); err != nil {
+		a.Logger.Error(ctx, "Failed to requeue request", map[string]any{"id": req.ID, "error": err})
+		return
+	}
+	a.Metrics.IncCounter("agent_jobs_requeued_total", 1, hermes.Label{Key: "reason", Value: "over_committed"})
+}
@@ -0,0 +1,92 @@
+package hecatoncheir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+func TestAgent_AdmitHeadroom(t *testing.T) {
+	ctx := context.Background()
+	queue := acheron.NewMemoryQueue()
+	registry := hades.NewMemoryRegistry()
+	runtime := &allocRuntime{allocated: domain.ResourceCapacity{CPU: 3000, Mem: 4096}}
+	agent := &Agent{
+		NodeID:   "node-1",
+		Runtime:  runtime,
+		Queue:    queue,
+		Registry: registry,
+		Logger:   hermes.NewSlogAdapter(),
+		Metrics:  hermes.NewNoopMetrics(),
+		Capacity: domain.ResourceCapacity{CPU: 4000, Mem: 8192},
+	}
+	dequeue := func(req *domain.SandboxRequest) string {
+		t.Helper()
+		if err := queue.Enqueue(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		_, receipt, err := queue.Dequeue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return receipt
+	}
+
+	// A request that fits is launched
+	small := &domain.SandboxRequest{ID: "small", NodeID: "node-1", Resources: domain.ResourceSpec{CPU: 1000, Mem: 1024}}
+	receipt := dequeue(small)
+	if !agent.admitHeadroom(ctx, small, receipt) {
+		t.Fatal("expected a request that fits to be admitted")
+	}
+	_ = queue.Ack(ctx, receipt)
+
+	// One that does not is handed back to Olympus
+	big := &domain.SandboxRequest{ID: "big", Template: "base", NodeID: "node-1", Resources: domain.ResourceSpec{CPU: 2000, Mem: 1024}}
+	if err := registry.UpdateRun(ctx, domain.SandboxRun{ID: "big", NodeID: "node-1", Status: domain.RunStatusScheduled, Labels: map[string]string{"team": "ml"}}); err != nil {
+		t.Fatal(err)
+	}
+	if agent.admitHeadroom(ctx, big, dequeue(big)) {
+		t.Fatal("expected an over-committing request to be turned away")
+	}
+	if n := queue.Len(ctx); n != 0 {
+		t.Errorf("expected the request to leave the node's queue, %d left", n)
+	}
+	run, err := registry.GetRun(ctx, "big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != domain.RunStatusRescheduling || run.NodeID != "" || run.Labels["team"] != "ml" {
+		t.Fatalf("expected the run to be rescheduling off the node, got %s on %q", run.Status, run.NodeID)
+	}
+	if r := run.Rescheduling; r == nil || r.From != "node-1" || r.Count != 1 || r.Request == nil || r.Request.NodeID != "" || r.Request.Template != "base" {
+		t.Fatalf("unexpected rescheduling record %+v", run.Rescheduling)
+	}
+
+	// Turned away again, the count goes up
+	if agent.admitHeadroom(ctx, big, dequeue(big)) {
+		t.Fatal("expected the request to be turned away again")
+	}
+	run, _ = registry.GetRun(ctx, "big")
+	if run.Rescheduling.Count != 2 {
+		t.Errorf("expected a count of 2, got %d", run.Rescheduling.Count)
+	}
+
+	// Without a run to record it on, the request waits in the queue
+	orphan := &domain.SandboxRequest{ID: "orphan", Resources: domain.ResourceSpec{CPU: 2000}}
+	if agent.admitHeadroom(ctx, orphan, dequeue(orphan)) {
+		t.Fatal("expected the request to be turned away")
+	}
+	if n := queue.Len(ctx); n != 1 {
+		t.Errorf("expected the request back in the queue, got %d", n)
+	}
+
+	// Nodes without a capacity admit everything
+	agent.Capacity = domain.ResourceCapacity{}
+	if !agent.admitHeadroom(ctx, big, "") {
+		t.Error("expected no check without a capacity")
+	}
+}
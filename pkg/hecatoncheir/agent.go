@@ -7,6 +7,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"strings"
@@ -20,6 +22,7 @@ import (
 	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
 	"github.com/tartarus-sandbox/tartarus/pkg/cocytus"
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
 	"github.com/tartarus-sandbox/tartarus/pkg/erinyes"
 	"github.com/tartarus-sandbox/tartarus/pkg/hades"
 	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
@@ -41,6 +44,7 @@ type Agent struct {
 	Styx       styx.Gateway
 	Judges     *judges.Chain
 	Furies     erinyes.Fury
+	Integrity  *erinyes.IntegrityMonitor // Optional; watches guest files named by the policy
 	Hypnos     *hypnos.Manager
 	Thanatos   *thanatos.Handler
 	Queue      acheron.Queue
@@ -48,8 +52,57 @@ type Agent struct {
 	DeadLetter cocytus.Sink
 	Control    ControlListener
 	Secrets    cerberus.SecretProvider
+	Audit      judges.AuditSink // Optional; records secrets injected into sandboxes
+	Images     *erebus.ImageCache
+	Artifacts  *erebus.ArtifactCache // Optional; holds input artifacts for locality-aware scheduling
+	Outputs    erebus.Store          // Optional; receives the files sandboxes wrote, for policies that ask
 	Metrics    hermes.Metrics
 	Logger     hermes.Logger
+
+	// ProcessLimits are the PID, open-file and core dump limits applied
+	// where the policy sets none. Zero fields leave the runtime's defaults.
+	ProcessLimits domain.ProcessLimits
+
+	// CrashBundles, if set, collects crash bundles for policies that ask for
+	// them; see CaptureCrashBundle.
+	CrashBundles *thanatos.CrashBundler
+
+	// Hooks, if set, runs the host hooks policies declare. Requests with
+	// hooks fail to launch without it.
+	Hooks *HookRunner
+
+	// GPUs, if set, assigns the node's GPUs to the sandboxes that ask for
+	// them and reports them in heartbeats.
+	GPUs *GPUInventory
+
+	// Jailer, if set, runs Firecracker VMMs under the jailer; see
+	// tartarus.JailerConfig.
+	Jailer *tartarus.JailerConfig
+
+	// ResultTailBytes limits the console tail stored in run results
+	// (domain.DefaultResultTailBytes if zero).
+	ResultTailBytes int
+
+	// Restart is called once the agent has drained for a RESTART command.
+	// It should stop the process so that its supervisor starts the new
+	// binary. Without it RESTART is ignored.
+	Restart func()
+
+	// Capacity is the CPU, memory and swap the node offers sandboxes. A
+	// request that would not fit is handed back to Olympus to place again,
+	// see admitHeadroom. With PreemptBatch set, a high-priority request that would not fit next to
+	// the running sandboxes kills batch sandboxes to make room; their
+	// requests are queued again.
+	Capacity     domain.ResourceCapacity
+	PreemptBatch bool
+
+	restarting atomic.Bool
+
+	batchMu sync.Mutex
+	batch   map[domain.SandboxID]*batchRun // Running batch sandboxes, for preemption
+
+	migrating sync.Map // Sandboxes being handed off to another node
+	warm      sync.Map // Pre-warmed sandboxes kept paused, by ID
 }
 
 // Run starts the main loop: consume from Acheron, execute, enforce, report.
@@ -77,6 +130,12 @@ func (a *Agent) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
+			// Leave queued requests to the restarted agent
+			if a.restarting.Load() {
+				time.Sleep(1 * time.Second)
+				continue
+			}
+
 			// Dequeue
 			req, receipt, err := a.Queue.Dequeue(ctx)
 			if err != nil {
@@ -88,23 +147,40 @@ func (a *Agent) Run(ctx context.Context) error {
 			a.Logger.Info(ctx, "Received request", map[string]any{"id": req.ID})
 			a.Metrics.IncCounter("agent_jobs_dequeued_total", 1)
 
+			// 0. Enforce Run Window
+			if !a.admitWindow(ctx, req, receipt) {
+				continue
+			}
+
+			// 0.4 Run in the pre-warmed sandbox Olympus gave the request
+			if a.claimWarm(ctx, req, receipt) {
+				continue
+			}
+
+			// 0.5 Make room for high-priority requests, or hand the request
+			// back if Olympus over-committed the node
+			if a.preemptFor(ctx, req) == 0 && !a.admitHeadroom(ctx, req, receipt) {
+				continue
+			}
+
 			// 1. Get Snapshot (Nyx)
 			snap, err := a.Nyx.GetSnapshot(ctx, req.Template)
 			if err != nil {
 				a.Logger.Error(ctx, "Failed to get snapshot", map[string]any{"error": err})
 				// If we can't get snapshot, it's likely a permanent error or configuration issue.
-				// We should Nack (maybe with delay) or just Ack and fail.
-				// For now, let's Nack to retry.
-				a.Queue.Nack(ctx, receipt, "failed to get snapshot")
-				a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "snapshot_fetch_failed"})
+				// Nack to retry unless Cocytus has seen this failure too often.
+				a.deadLetter(ctx, req, receipt, "failed to get snapshot: "+err.Error(), "failed to get snapshot", "snapshot_fetch_failed")
 				continue
 			}
 
+			// 1.5 Stage input artifacts (Erebus)
+			a.stageInputs(ctx, req)
+
 			// 2. Create Overlay (Lethe)
 			overlay, err := a.Lethe.Create(ctx, snap)
 			if err != nil {
 				a.Logger.Error(ctx, "Failed to create overlay", map[string]any{"error": err})
-				a.Queue.Nack(ctx, receipt, "failed to create overlay")
+				a.requeue(ctx, req, receipt, "failed to create overlay")
 				a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "overlay_creation_failed"})
 				continue
 			}
@@ -117,32 +193,57 @@ func (a *Agent) Run(ctx context.Context) error {
 			if err != nil {
 				a.Logger.Error(ctx, "Failed to attach network", map[string]any{"error": err})
 				a.Lethe.Destroy(ctx, overlay)
-				a.Queue.Nack(ctx, receipt, "failed to attach network")
+				a.requeue(ctx, req, receipt, "failed to attach network")
 				a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "network_attach_failed"})
 				continue
 			}
 
-			// 3.5 Resolve Secrets (Cerberus)
-			if len(req.Secrets) > 0 && a.Secrets != nil {
-				if req.Env == nil {
-					req.Env = make(map[string]string)
-				}
-				for key, ref := range req.Secrets {
-					val, err := a.Secrets.Resolve(ctx, ref)
-					if err != nil {
-						a.Logger.Error(ctx, "Failed to resolve secret", map[string]any{"key": key, "ref": ref, "error": err})
-						// Fail the job if secret resolution fails? Yes, security critical.
-						a.Lethe.Destroy(ctx, overlay)
-						a.Styx.Detach(ctx, req.ID)
-						a.Queue.Nack(ctx, receipt, fmt.Sprintf("failed to resolve secret %s", key))
-						a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "secret_resolution_failed"})
-						continue
-					}
-					req.Env[key] = val
-				}
+			// 3.5 Resolve Secrets (Cerberus) into a copy of the request
+			launchReq, err := a.injectSecrets(ctx, req)
+			if err != nil {
+				// Security critical: never launch without the requested secrets
+				a.Lethe.Destroy(ctx, overlay)
+				a.Styx.Detach(ctx, req.ID)
+				a.requeue(ctx, req, receipt, "failed to resolve secrets")
+				a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "secret_resolution_failed"})
+				continue
+			}
+			launchReq = a.withProcessLimits(launchReq)
+			launchReq = a.withSwap(ctx, launchReq)
+			launchReq = withGangEnv(launchReq)
+
+			// 3.6 Host hooks, e.g. mounting a dataset the sandbox needs
+			if err := a.runPreStartHooks(ctx, req, overlay.MountPath); err != nil {
+				a.Lethe.Destroy(ctx, overlay)
+				a.Styx.Detach(ctx, req.ID)
+				a.deadLetter(ctx, req, receipt, err.Error(), "pre-start hook failed", "pre_start_hook_failed")
+				continue
 			}
 
-			// 4. Launch (Runtime)
+			// 3.7 Unpack a script run's artifact into the sandbox (Erebus)
+			if err := a.unpackScript(ctx, req, overlay); err != nil {
+				a.runPostStopHooks(ctx, req, overlay.MountPath)
+				a.Styx.Detach(ctx, req.ID)
+				a.Lethe.Destroy(ctx, overlay)
+				a.deadLetter(ctx, req, receipt, err.Error(), "failed to unpack script", "script_unpack_failed")
+				continue
+			}
+
+			// 3.8 Assign GPUs of the requested type
+			launchReq, err = a.assignGPUs(ctx, launchReq)
+			if err != nil {
+				a.Logger.Error(ctx, "Failed to assign GPUs", map[string]any{"id": req.ID, "error": err})
+				a.runPostStopHooks(ctx, req, overlay.MountPath)
+				a.Styx.Detach(ctx, req.ID)
+				a.Lethe.Destroy(ctx, overlay)
+				a.requeue(ctx, req, receipt, "failed to assign GPUs")
+				a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "gpu_unavailable"})
+				continue
+			}
+
+			// 4. Launch (Runtime), with the part of the memory that may be
+			// swapped out taken from swap instead of RAM
+			ram, swap := launchReq.MemoryNeeds()
 			vmCfg := tartarus.VMConfig{
 				Snapshot: domain.SnapshotRef{
 					ID:       snap.ID,
@@ -155,114 +256,326 @@ func (a *Agent) Run(ctx context.Context) error {
 				Gateway:   gateway,
 				CIDR:      cidr,
 				CPUs:      int(req.Resources.CPU),
-				MemoryMB:  int(req.Resources.Mem),
+				MemoryMB:  int(ram),
+				SwapMB:    int(swap),
+				Jailer:    a.Jailer,
 			}
 
-			run, err := a.Runtime.Launch(ctx, req, vmCfg)
+			run, err := a.Runtime.Launch(ctx, launchReq, vmCfg)
 			if err != nil {
 				a.Logger.Error(ctx, "Failed to launch", map[string]any{"error": err})
 
-				// Report to Cocytus
-				go func() {
-					payload, _ := json.Marshal(req)
-					rec := &cocytus.Record{
-						RequestID: req.ID,
-						Reason:    err.Error(),
-						Payload:   payload,
-						CreatedAt: time.Now(),
-					}
-					// Use a detached context with timeout to avoid blocking
-					rctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-					defer cancel()
-
-					if wErr := a.DeadLetter.Write(rctx, rec); wErr != nil {
-						a.Logger.Error(context.Background(), "Failed to write to dead letter sink", map[string]any{"error": wErr})
-					}
-				}()
-
 				// Cleanup
+				a.releaseGPUs(req.ID)
+				a.runPostStopHooks(ctx, req, overlay.MountPath)
 				a.Styx.Detach(ctx, req.ID)
 				a.Lethe.Destroy(ctx, overlay)
 
-				// Nack or Ack? If launch failed, it might be transient.
-				a.Queue.Nack(ctx, receipt, "failed to launch")
-				a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "launch_failed"})
+				// Launch failures may be transient; report to Cocytus and retry
+				// unless the failure is a known poison pill.
+				a.deadLetter(ctx, req, receipt, err.Error(), "failed to launch", "launch_failed")
 				continue
 			}
 
 			a.Logger.Info(ctx, "Sandbox launched", map[string]any{"run_id": run.ID})
 			a.Metrics.IncCounter("agent_jobs_launched_total", 1)
-			if !req.CreatedAt.IsZero() {
-				latency := time.Since(req.CreatedAt).Seconds()
-				a.Metrics.ObserveHistogram("agent_launch_latency_seconds", latency)
-			}
+			a.recordRestore(ctx, snap)
 
-			// Update Run Status to Running
-			if err := a.Registry.UpdateRun(ctx, *run); err != nil {
-				a.Logger.Error(ctx, "Failed to update run status", map[string]any{"run_id": run.ID, "error": err})
+			// Keep a pre-warming request's sandbox paused for a later claim
+			if a.poolWarm(ctx, req, run, overlay, receipt) {
+				continue
 			}
+			a.supervise(ctx, req, run, overlay, req.ID, receipt)
+		}
+	}
+}
+
+// supervise records a launched sandbox RUNNING, arms its watchdog and, once
+// it exits, records how it ended, cleans up after it and acks its request.
+// netID is the ID its network was attached under.
+func (a *Agent) supervise(ctx context.Context, req *domain.SandboxRequest, run *domain.SandboxRun, overlay *lethe.Overlay, netID domain.SandboxID, receipt string) {
+	a.trackBatch(req, run.StartedAt)
+	if !req.CreatedAt.IsZero() {
+		latency := time.Since(req.CreatedAt).Seconds()
+		a.Metrics.ObserveHistogram("agent_launch_latency_seconds", latency)
+	}
+
+	// Update Run Status to Running
+	run.Window = req.Window
+	run.Submitter = req.Submitter
+	run.Resources = &req.Resources
+	a.keepUserFields(ctx, run)
+	if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+		a.Logger.Error(ctx, "Failed to update run status", map[string]any{"run_id": run.ID, "error": err})
+	}
+
+	// Arm Watchdog (Erinyes)
+	policy := &erinyes.PolicySnapshot{
+		MaxRuntime:   req.Resources.TTL,
+		KillOnBreach: true,
+		Escalation:   req.Escalation,
+	}
+	if req.Window != nil {
+		policy.Deadline = req.Window.Deadline
+	}
+	if err := a.Furies.Arm(ctx, run, policy); err != nil {
+		a.Logger.Error(ctx, "Failed to arm watchdog", map[string]any{"run_id": run.ID, "error": err})
+	}
+	monitored := a.Integrity != nil && req.Integrity.Enabled()
+	if monitored {
+		if err := a.Integrity.Watch(ctx, run, req.Integrity); err != nil {
+			a.Logger.Error(ctx, "Failed to start integrity monitor", map[string]any{"run_id": run.ID, "error": err})
+			monitored = false
+		}
+	}
+
+	// 5. Wait & Cleanup
+	go func(runID, reqID, netID domain.SandboxID, ov *lethe.Overlay, receipt string, window *domain.RunWindow, submitter *domain.Submitter, startedAt time.Time, monitored bool, req *domain.SandboxRequest) {
+		// Wait for completion
+		if err := a.Runtime.Wait(context.Background(), runID); err != nil {
+			a.Logger.Error(context.Background(), "Wait failed", map[string]any{"run_id": runID, "error": err})
+		}
+
+		a.Logger.Info(context.Background(), "Sandbox exited", map[string]any{"run_id": runID})
+		// A sandbox handed off to another node goes on running there
+		_, moved := a.migrating.LoadAndDelete(runID)
+		preempted := a.untrackBatch(runID)
+		a.releaseGPUs(runID)
 
-			// Arm Watchdog (Erinyes)
-			policy := &erinyes.PolicySnapshot{
-				MaxRuntime:   req.Resources.TTL,
-				KillOnBreach: true,
+		// Disarm Watchdog
+		if err := a.Furies.Disarm(context.Background(), runID); err != nil {
+			a.Logger.Error(context.Background(), "Failed to disarm watchdog", map[string]any{"run_id": runID, "error": err})
+		}
+		if monitored {
+			a.Integrity.Stop(runID)
+		}
+
+		// Inspect to get final status and exit code
+		finalRun, err := a.Runtime.Inspect(context.Background(), runID)
+		if moved {
+			a.attachIntensity(&domain.SandboxRun{ID: runID})
+		} else if err == nil {
+			finalRun.Window = window
+			finalRun.Submitter = submitter
+			// Keep the tamper flags, crash bundle and violation timeline recorded while the sandbox ran
+			if prev, err := a.Registry.GetRun(context.Background(), runID); err == nil {
+				if monitored {
+					finalRun.Tampered = prev.Tampered
+				}
+				finalRun.CrashBundle = prev.CrashBundle
+				finalRun.Violations = prev.Violations
+			}
+			if finalRun.Status != domain.RunStatusSucceeded && window.DeadlineExceeded(time.Now()) {
+				finalRun.Status = domain.RunStatusDeadlineExceeded
+			}
+			a.captureResult(context.Background(), finalRun, startedAt)
+			a.attachIntensity(finalRun)
+			a.keepUserFields(context.Background(), finalRun)
+			if preempted {
+				markPreempted(finalRun)
 			}
-			if err := a.Furies.Arm(ctx, run, policy); err != nil {
-				a.Logger.Error(ctx, "Failed to arm watchdog", map[string]any{"run_id": run.ID, "error": err})
+			// Update Run Status to Succeeded/Failed
+			if err := a.Registry.UpdateRun(context.Background(), *finalRun); err != nil {
+				a.Logger.Error(context.Background(), "Failed to update final run status", map[string]any{"run_id": runID, "error": err})
 			}
+		} else {
+			a.Logger.Error(context.Background(), "Failed to inspect final run", map[string]any{"run_id": runID, "error": err})
+			// Nothing to attach them to; drop the watchdog's observations
+			a.attachIntensity(&domain.SandboxRun{ID: runID})
+		}
 
-			// 5. Wait & Cleanup
-			go func(runID domain.SandboxID, reqID domain.SandboxID, ov *lethe.Overlay, receipt string) {
-				// Wait for completion
-				if err := a.Runtime.Wait(context.Background(), runID); err != nil {
-					a.Logger.Error(context.Background(), "Wait failed", map[string]any{"run_id": runID, "error": err})
-				}
+		a.runPostStopHooks(context.Background(), req, ov.MountPath)
 
-				a.Logger.Info(context.Background(), "Sandbox exited", map[string]any{"run_id": runID})
+		// Cleanup Network
+		if err := a.Styx.Detach(context.Background(), netID); err != nil {
+			a.Logger.Error(context.Background(), "Failed to detach network", map[string]any{"req_id": reqID, "error": err})
+		}
 
-				// Disarm Watchdog
-				if err := a.Furies.Disarm(context.Background(), runID); err != nil {
-					a.Logger.Error(context.Background(), "Failed to disarm watchdog", map[string]any{"run_id": runID, "error": err})
-				}
+		// Keep what the sandbox wrote before the overlay goes
+		if !moved {
+			a.captureOutputs(context.Background(), req, ov)
+		}
 
-				// Inspect to get final status and exit code
-				finalRun, err := a.Runtime.Inspect(context.Background(), runID)
-				if err == nil {
-					// Update Run Status to Succeeded/Failed
-					if err := a.Registry.UpdateRun(context.Background(), *finalRun); err != nil {
-						a.Logger.Error(context.Background(), "Failed to update final run status", map[string]any{"run_id": runID, "error": err})
-					}
-				} else {
-					a.Logger.Error(context.Background(), "Failed to inspect final run", map[string]any{"run_id": runID, "error": err})
-				}
+		// Cleanup Overlay
+		if err := a.Lethe.Destroy(context.Background(), ov); err != nil {
+			a.Logger.Error(context.Background(), "Failed to destroy overlay", map[string]any{"overlay_id": ov.ID, "error": err})
+		}
 
-				// Cleanup Network
-				if err := a.Styx.Detach(context.Background(), reqID); err != nil {
-					a.Logger.Error(context.Background(), "Failed to detach network", map[string]any{"req_id": reqID, "error": err})
-				}
+		// Ack the job, or queue a preempted one again
+		if preempted {
+			a.requeuePreempted(context.Background(), reqID, receipt)
+		} else if err := a.Queue.Ack(context.Background(), receipt); err != nil {
+			a.Logger.Error(context.Background(), "Failed to ack job", map[string]any{"req_id": reqID, "error": err})
+		}
+		// We can't easily access 'a.Metrics' here if it's not thread-safe or if we are in a closure?
+		// 'a' is available.
+		// But we are in a goroutine.
+		// Assuming Metrics is thread-safe (SlogAdapter is).
+		// We should emit success/failure based on exit code?
+		// But we don't have exit code easily here unless we check finalRun.
+		// Let's just emit "finished".
+		// Actually, we can check if finalRun.ExitCode == 0
+		// But finalRun might be nil if Inspect failed.
+		// Let's just emit "job_finished".
+	}(run.ID, req.ID, netID, overlay, receipt, req.Window, req.Submitter, run.StartedAt, monitored, req)
+}
 
-				// Cleanup Overlay
-				if err := a.Lethe.Destroy(context.Background(), ov); err != nil {
-					a.Logger.Error(context.Background(), "Failed to destroy overlay", map[string]any{"overlay_id": ov.ID, "error": err})
-				}
+// MarkExpired records a request that Acheron dropped because its queue TTL
+// passed. It is installed as the queue's acheron.ExpiryHandler.
+func (a *Agent) MarkExpired(ctx context.Context, req *domain.SandboxRequest) {
+	a.Logger.Info(ctx, "Queue TTL expired before start", map[string]any{"id": req.ID, "expires_at": req.ExpiresAt})
+	a.recordExpired(ctx, req, "queue TTL expired before the sandbox could start")
+	a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "queue_ttl_expired"})
+}
 
-				// Ack the job
-				if err := a.Queue.Ack(context.Background(), receipt); err != nil {
-					a.Logger.Error(context.Background(), "Failed to ack job", map[string]any{"req_id": reqID, "error": err})
-				}
-				// We can't easily access 'a.Metrics' here if it's not thread-safe or if we are in a closure?
-				// 'a' is available.
-				// But we are in a goroutine.
-				// Assuming Metrics is thread-safe (SlogAdapter is).
-				// We should emit success/failure based on exit code?
-				// But we don't have exit code easily here unless we check finalRun.
-				// Let's just emit "finished".
-				// Actually, we can check if finalRun.ExitCode == 0
-				// But finalRun might be nil if Inspect failed.
-				// Let's just emit "job_finished".
-			}(run.ID, req.ID, overlay, receipt)
+// FlagTampered records guest paths that changed since launch on the run in
+// Hades. It is installed as the integrity monitor's OnTamper hook.
+func (a *Agent) FlagTampered(ctx context.Context, id domain.SandboxID, paths []string) {
+	run, err := a.Registry.GetRun(ctx, id)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to load run to flag tampering", map[string]any{"run_id": id, "error": err})
+		return
+	}
+	run.Tampered = append(run.Tampered, paths...)
+	run.UpdatedAt = time.Now()
+	if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+		a.Logger.Error(ctx, "Failed to flag tampered run", map[string]any{"run_id": id, "error": err})
+	}
+}
+
+// keepUserFields carries the user-facing fields edited through Olympus, and
+// the retry, gang and migration tracking, over from the run in Hades to a run rebuilt from the
+// runtime, which does not know them.
+func (a *Agent) keepUserFields(ctx context.Context, run *domain.SandboxRun) {
+	prev, err := a.Registry.GetRun(ctx, run.ID)
+	if err != nil {
+		return
+	}
+	run.DisplayName = prev.DisplayName
+	run.Labels = prev.Labels
+	run.ResourceVersion = prev.ResourceVersion
+	run.Retry = prev.Retry
+	run.Gang = prev.Gang
+	run.Migration = prev.Migration
+	run.Rescheduling = prev.Rescheduling
+	for k, v := range prev.Metadata {
+		if _, ok := run.Metadata[k]; ok {
+			continue
+		}
+		if run.Metadata == nil {
+			run.Metadata = make(map[string]string)
+		}
+		run.Metadata[k] = v
+	}
+}
+
+// recordExpired marks the request's run EXPIRED in Hades.
+func (a *Agent) recordExpired(ctx context.Context, req *domain.SandboxRequest, reason string) {
+	expired := domain.SandboxRun{
+		ID:        req.ID,
+		RequestID: req.ID,
+		Template:  req.Template,
+		NodeID:    a.NodeID,
+		Status:    domain.RunStatusExpired,
+		Error:     reason,
+		Window:    req.Window,
+		Submitter: req.Submitter,
+		CreatedAt: req.CreatedAt,
+		UpdatedAt: time.Now(),
+	}
+	if err := a.Registry.UpdateRun(ctx, expired); err != nil {
+		a.Logger.Error(ctx, "Failed to mark run expired", map[string]any{"id": req.ID, "error": err})
+	}
+}
+
+// admitWindow checks the request against its run window. Requests whose start
+// window has closed are marked expired and acknowledged; requests delivered
+// before their window opens are handed back to the queue. It reports whether
+// the request may be launched now.
+func (a *Agent) admitWindow(ctx context.Context, req *domain.SandboxRequest, receipt string) bool {
+	now := time.Now()
+
+	if req.Window.StartExpired(now) {
+		a.Logger.Info(ctx, "Run window expired before start", map[string]any{"id": req.ID, "start_by": req.Window.StartBy})
+		a.recordExpired(ctx, req, "run window expired before the sandbox could start")
+		if err := a.Queue.Ack(ctx, receipt); err != nil {
+			a.Logger.Error(ctx, "Failed to ack expired job", map[string]any{"id": req.ID, "error": err})
+		}
+		a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "run_window_expired"})
+		return false
+	}
+
+	if delay := req.Window.Delay(now); delay > 0 {
+		if delayed, ok := a.Queue.(acheron.DelayedEnqueuer); ok {
+			if err := delayed.EnqueueAt(ctx, req, req.Window.NotBefore); err == nil {
+				a.Queue.Ack(ctx, receipt)
+				return false
+			}
 		}
+		a.Queue.Nack(ctx, receipt, "run window not open yet")
+		return false
 	}
+
+	return true
+}
+
+// deadLetter reports a failed request to Cocytus and hands it back to the
+// queue (see requeue). If the failure's fingerprint is a known poison pill the request is
+// acknowledged and its run marked failed instead of being re-driven.
+func (a *Agent) deadLetter(ctx context.Context, req *domain.SandboxRequest, receipt, reason, nackReason, metricReason string) {
+	payload, _ := json.Marshal(req)
+	rec := &cocytus.Record{
+		RequestID: req.ID,
+		Template:  req.Template,
+		Reason:    reason,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	cocytus.Annotate(rec)
+
+	suppressed := false
+	if s, ok := a.DeadLetter.(cocytus.Suppressor); ok {
+		suppressed = s.Suppressed(ctx, rec.Fingerprint)
+	}
+
+	go func() {
+		// Use a detached context with timeout to avoid blocking
+		rctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if wErr := a.DeadLetter.Write(rctx, rec); wErr != nil {
+			a.Logger.Error(context.Background(), "Failed to write to dead letter sink", map[string]any{"error": wErr})
+		}
+	}()
+
+	if !suppressed {
+		a.requeue(ctx, req, receipt, nackReason)
+		a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: metricReason})
+		return
+	}
+
+	a.Logger.Info(ctx, "Suppressing re-drive of poison pill", map[string]any{"id": req.ID, "fingerprint": rec.Fingerprint, "class": rec.Class})
+	now := time.Now()
+	failed := domain.SandboxRun{
+		ID:        req.ID,
+		RequestID: req.ID,
+		Template:  req.Template,
+		NodeID:    a.NodeID,
+		Status:    domain.RunStatusFailed,
+		Error:     fmt.Sprintf("%s (poison pill %s, not retried)", reason, rec.Fingerprint),
+		Window:    req.Window,
+		Submitter: req.Submitter,
+		CreatedAt: req.CreatedAt,
+		UpdatedAt: now,
+	}
+	a.keepUserFields(ctx, &failed)
+	if err := a.Registry.UpdateRun(ctx, failed); err != nil {
+		a.Logger.Error(ctx, "Failed to mark run failed", map[string]any{"id": req.ID, "error": err})
+	}
+	if err := a.Queue.Ack(ctx, receipt); err != nil {
+		a.Logger.Error(ctx, "Failed to ack poison pill", map[string]any{"id": req.ID, "error": err})
+	}
+	a.Metrics.IncCounter("agent_jobs_failed_total", 1, hermes.Label{Key: "reason", Value: "poison_pill_suppressed"})
 }
 
 // Reconcile cleans up zombie processes and network interfaces from previous runs.
@@ -324,6 +637,9 @@ func (a *Agent) controlLoop(ctx context.Context, ch <-chan ControlMessage) {
 
 		switch msg.Type {
 		case ControlMessageKill:
+			if a.releaseWarm(ctx, msg.SandboxID) {
+				continue
+			}
 			if err := a.Runtime.Kill(ctx, msg.SandboxID); err != nil {
 				a.Logger.Error(ctx, "Failed to kill sandbox", map[string]any{"sandbox_id": msg.SandboxID, "error": err})
 			} else {
@@ -389,6 +705,28 @@ func (a *Agent) controlLoop(ctx context.Context, ch <-chan ControlMessage) {
 			go a.handleExecInteractive(ctx, msg)
 		case ControlMessageListSandboxes:
 			go a.handleListSandboxes(ctx, msg)
+		case ControlMessagePrefetchImage:
+			// The image ref takes the place of the sandbox ID
+			go a.handlePrefetchImage(ctx, string(msg.SandboxID))
+		case ControlMessageRestart:
+			// The drain timeout takes the place of the sandbox ID
+			timeout, err := time.ParseDuration(string(msg.SandboxID))
+			if err != nil {
+				timeout = DefaultRestartDrainTimeout
+			}
+			go a.handleRestart(ctx, timeout)
+		case ControlMessageMigrateOut:
+			if len(msg.Args) < 1 {
+				a.Logger.Error(ctx, "Migration requested without a target node", map[string]any{"sandbox_id": msg.SandboxID})
+				continue
+			}
+			go a.handleMigrateOut(ctx, msg.SandboxID, domain.NodeID(msg.Args[0]))
+		case ControlMessageMigrateIn:
+			if len(msg.Args) < 1 {
+				a.Logger.Error(ctx, "Restore requested without a snapshot key", map[string]any{"sandbox_id": msg.SandboxID})
+				continue
+			}
+			go a.handleMigrateIn(ctx, msg.SandboxID, msg.Args[0])
 		}
 	}
 }
@@ -427,6 +765,10 @@ func (a *Agent) handleExec(ctx context.Context, msg ControlMessage) {
 	}
 }
 
+// handleExecInteractive runs one interactive exec session, exchanging
+// framed messages (see domain.ExecChannel) with Olympus: it reports ready
+// once subscribed to stdin, streams stdout and stderr apart, and ends with
+// the command's status. A cancel frame kills the command.
 func (a *Agent) handleExecInteractive(ctx context.Context, msg ControlMessage) {
 	if len(msg.Args) < 2 {
 		a.Logger.Error(ctx, "Exec interactive requested without requestID or command", nil)
@@ -437,6 +779,9 @@ func (a *Agent) handleExecInteractive(ctx context.Context, msg ControlMessage) {
 
 	a.Logger.Info(ctx, "Exec interactive requested", map[string]any{"sandbox_id": msg.SandboxID, "request_id": requestID, "cmd": cmd})
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Subscribe to stdin
 	stdinCh, err := a.Control.SubscribeStdin(ctx, requestID)
 	if err != nil {
@@ -444,39 +789,75 @@ func (a *Agent) handleExecInteractive(ctx context.Context, msg ControlMessage) {
 		return
 	}
 
+	publish := func(frame []byte) {
+		if err := a.Control.PublishExecOutput(ctx, msg.SandboxID, requestID, frame); err != nil {
+			a.Logger.Error(ctx, "Failed to publish exec output", map[string]any{"sandbox_id": msg.SandboxID, "error": err})
+		}
+	}
+
 	// Create stdin pipe
 	stdinR, stdinW := io.Pipe()
 	go func() {
 		defer stdinW.Close()
 		for chunk := range stdinCh {
-			if _, err := stdinW.Write(chunk); err != nil {
-				return
+			channel, data, err := domain.ParseExecFrame(chunk)
+			if err != nil {
+				continue
 			}
-		}
-	}()
-
-	// Create stdout/stderr pipe
-	r, w := io.Pipe()
-	go func() {
-		defer r.Close()
-		buf := make([]byte, 4096)
-		for {
-			n, err := r.Read(buf)
-			if n > 0 {
-				if pErr := a.Control.PublishExecOutput(ctx, msg.SandboxID, requestID, buf[:n]); pErr != nil {
-					a.Logger.Error(ctx, "Failed to publish exec output", map[string]any{"sandbox_id": msg.SandboxID, "error": pErr})
+			switch channel {
+			case domain.ExecStdin:
+				if _, err := stdinW.Write(data); err != nil {
+					return
 				}
-			}
-			if err != nil {
+			case domain.ExecClose:
+				return
+			case domain.ExecCancel:
+				cancel()
 				return
 			}
 		}
 	}()
 
-	defer w.Close()
-	if err := a.Runtime.ExecInteractive(ctx, msg.SandboxID, cmd, stdinR, w, w); err != nil {
+	// Create stdout and stderr pipes, published as frames of their channel
+	var pumps sync.WaitGroup
+	pump := func(channel domain.ExecChannel) *io.PipeWriter {
+		r, w := io.Pipe()
+		pumps.Add(1)
+		go func() {
+			defer pumps.Done()
+			defer r.Close()
+			buf := make([]byte, 4096)
+			for {
+				n, err := r.Read(buf)
+				if n > 0 {
+					publish(domain.ExecFrame(channel, buf[:n]))
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		return w
+	}
+	stdout := pump(domain.ExecStdout)
+	stderr := pump(domain.ExecStderr)
+
+	publish(domain.ExecFrame(domain.ExecReady, nil))
+	err = a.Runtime.ExecInteractive(ctx, msg.SandboxID, cmd, stdinR, stdout, stderr)
+	stdinR.Close()
+	stdout.Close()
+	stderr.Close()
+	pumps.Wait()
+
+	result := domain.ExecResultOf(err)
+	if result.Error != "" {
 		a.Logger.Error(ctx, "Failed to exec interactive command", map[string]any{"sandbox_id": msg.SandboxID, "error": err})
-		fmt.Fprintf(w, "Error executing command: %v\n", err)
+	}
+	// The status is published even when the session was cancelled
+	publishCtx, cancelPublish := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelPublish()
+	if err := a.Control.PublishExecOutput(publishCtx, msg.SandboxID, requestID, result.StatusFrame()); err != nil {
+		a.Logger.Error(ctx, "Failed to publish exec status", map[string]any{"sandbox_id": msg.SandboxID, "error": err})
 	}
 }
 
@@ -499,6 +880,26 @@ func (a *Agent) handleListSandboxes(ctx context.Context, msg ControlMessage) {
 	}
 }
 
+func (a *Agent) handlePrefetchImage(ctx context.Context, ref string) {
+	if a.Images == nil {
+		a.Logger.Info(ctx, "Image prefetch requested but the image cache is disabled", map[string]any{"ref": ref})
+		return
+	}
+
+	a.Logger.Info(ctx, "Prefetching image", map[string]any{"ref": ref})
+	start := time.Now()
+	cached, err := a.Images.Prefetch(ctx, ref)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to prefetch image", map[string]any{"ref": ref, "error": err})
+		a.Metrics.IncCounter("agent_image_prefetch_total", 1, hermes.Label{Key: "result", Value: "error"})
+		return
+	}
+
+	a.Logger.Info(ctx, "Image prefetched", map[string]any{"ref": ref, "digest": cached.Digest, "duration": time.Since(start)})
+	a.Metrics.IncCounter("agent_image_prefetch_total", 1, hermes.Label{Key: "result", Value: "success"})
+	a.Metrics.ObserveHistogram("agent_image_prefetch_duration_seconds", time.Since(start).Seconds())
+}
+
 func (a *Agent) streamLogs(ctx context.Context, id domain.SandboxID, follow bool) {
 	// Create a pipe to read logs from runtime and write to Redis
 	r, w := io.Pipe()
@@ -559,10 +960,12 @@ func (a *Agent) handleSnapshot(ctx context.Context, id domain.SandboxID) {
 
 	// 4. Save to Nyx
 	snapID := domain.SnapshotID(uuid.New().String())
-	if _, err := a.Nyx.SaveSnapshot(ctx, req.Template, snapID, memPath, diskPath); err != nil {
+	snap, err := a.Nyx.SaveSnapshot(ctx, req.Template, snapID, memPath, diskPath)
+	if err != nil {
 		a.Logger.Error(ctx, "Failed to save snapshot to Nyx", map[string]any{"sandbox_id": id, "error": err})
 		return
 	}
+	a.recordSnapshot(ctx, snap, id)
 
 	a.Logger.Info(ctx, "Snapshot created successfully", map[string]any{
 		"sandbox_id":  id,
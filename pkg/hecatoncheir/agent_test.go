@@ -136,6 +136,10 @@ func (m *mockRegistry) UpdateRun(ctx context.Context, run domain.SandboxRun) err
 	return nil
 }
 
+func (m *mockRegistry) GetRun(ctx context.Context, id domain.SandboxID) (*domain.SandboxRun, error) {
+	return nil, hades.ErrRunNotFound
+}
+
 type mockFury struct {
 	erinyes.Fury
 }
@@ -0,0 +1,203 @@
+package hecatoncheir
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// Cgroup slices the agent creates under CgroupConfig.Root. The sandbox
+// runtimes share the sandboxes parent, which carries the host reserve.
+const (
+	SliceAgent       = "agent"
+	SliceFirecracker = "firecracker"
+	SliceGVisor      = "gvisor"
+
+	sandboxesSlice = "sandboxes"
+
+	// agentCPUWeight is the highest cgroup v2 weight, so under CPU
+	// contention the agent's heartbeat and queue loops always run first.
+	agentCPUWeight = 10000
+	cpuPeriodUsec  = 100000
+)
+
+// CgroupConfig places the agent and the sandbox processes it starts in
+// dedicated cgroup v2 slices.
+type CgroupConfig struct {
+	Root            string           // Parent cgroup directory, e.g. /sys/fs/cgroup/tartarus
+	HostReserveCPU  domain.MilliCPU  // CPU sandboxes may never use, kept for the host and agent
+	HostReserveMem  domain.Megabytes // Memory sandboxes may never use, kept for the host and agent
+	AgentReserveMem domain.Megabytes // Memory guaranteed to the agent slice (memory.min)
+}
+
+// CgroupSlices manages the agent's cgroup v2 slices:
+//
+//	<root>/agent                  the agent itself
+//	<root>/sandboxes/firecracker  firecracker VMMs
+//	<root>/sandboxes/gvisor/<id>  runsc sandboxes, one cgroup each
+//
+// The sandboxes slice is capped at the node's capacity less the host
+// reserve, so sandbox load cannot starve the agent.
+type CgroupSlices struct {
+	config   CgroupConfig
+	capacity domain.ResourceCapacity
+}
+
+// NewCgroupSlices creates the slice manager for a node with the given capacity.
+func NewCgroupSlices(config CgroupConfig, capacity domain.ResourceCapacity) *CgroupSlices {
+	return &CgroupSlices{config: config, capacity: capacity}
+}
+
+// Path returns the cgroup directory of a slice.
+func (c *CgroupSlices) Path(slice string) string {
+	if slice == SliceAgent {
+		return filepath.Join(c.config.Root, SliceAgent)
+	}
+	return filepath.Join(c.config.Root, sandboxesSlice, slice)
+}
+
+// Setup creates the slices, applies the reserves, and moves the process
+// pid (the agent) into the agent slice.
+func (c *CgroupSlices) Setup(pid int) error {
+	sandboxes := filepath.Join(c.config.Root, sandboxesSlice)
+	for _, dir := range []string{c.Path(SliceAgent), c.Path(SliceFirecracker), c.Path(SliceGVisor)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating cgroup %s: %w", dir, err)
+		}
+	}
+
+	// Controllers must be enabled top-down; the parent may already have
+	// them, or be managed by someone else, so its failure is not fatal.
+	// The gVisor slice only holds per-sandbox cgroups, which need the
+	// controllers for their usage to be read.
+	_ = writeCgroupFile(filepath.Dir(c.config.Root), "cgroup.subtree_control", "+cpu +memory +pids")
+	for _, dir := range []string{c.config.Root, sandboxes, c.Path(SliceGVisor)} {
+		if err := writeCgroupFile(dir, "cgroup.subtree_control", "+cpu +memory +pids"); err != nil {
+			return err
+		}
+	}
+
+	if err := writeCgroupFile(c.Path(SliceAgent), "cpu.weight", strconv.Itoa(agentCPUWeight)); err != nil {
+		return err
+	}
+	if c.config.AgentReserveMem > 0 {
+		if err := writeCgroupFile(c.Path(SliceAgent), "memory.min", strconv.FormatUint(megabytesToBytes(c.config.AgentReserveMem), 10)); err != nil {
+			return err
+		}
+	}
+	if err := writeCgroupFile(sandboxes, "cpu.max", c.sandboxCPUMax()); err != nil {
+		return err
+	}
+	if err := writeCgroupFile(sandboxes, "memory.max", c.sandboxMemoryMax()); err != nil {
+		return err
+	}
+
+	return writeCgroupFile(c.Path(SliceAgent), "cgroup.procs", strconv.Itoa(pid))
+}
+
+// sandboxCPUMax is the cpu.max of the sandboxes slice: the node's CPUs less
+// the host reserve, or "max" if nothing is reserved.
+func (c *CgroupSlices) sandboxCPUMax() string {
+	if c.config.HostReserveCPU <= 0 || c.capacity.CPU <= 0 {
+		return "max"
+	}
+	available := c.capacity.CPU - c.config.HostReserveCPU
+	if available < 100 {
+		available = 100 // Sandboxes always get a tenth of a CPU
+	}
+	return fmt.Sprintf("%d %d", int64(available)*cpuPeriodUsec/1000, cpuPeriodUsec)
+}
+
+// sandboxMemoryMax is the memory.max of the sandboxes slice: the node's
+// memory less the host reserve, or "max" if nothing is reserved.
+func (c *CgroupSlices) sandboxMemoryMax() string {
+	if c.config.HostReserveMem <= 0 || c.capacity.Mem <= c.config.HostReserveMem {
+		return "max"
+	}
+	return strconv.FormatUint(megabytesToBytes(c.capacity.Mem-c.config.HostReserveMem), 10)
+}
+
+// Usage reads the current usage of every slice. Slices that cannot be
+// read are skipped.
+func (c *CgroupSlices) Usage() []domain.CgroupSlice {
+	var usage []domain.CgroupSlice
+	for _, name := range []string{SliceAgent, SliceFirecracker, SliceGVisor} {
+		slice, err := readSliceUsage(name, c.Path(name))
+		if err != nil {
+			continue
+		}
+		usage = append(usage, slice)
+	}
+	return usage
+}
+
+// ReportSliceMetrics publishes slice usage as gauges.
+func ReportSliceMetrics(metrics hermes.Metrics, usage []domain.CgroupSlice) {
+	for _, s := range usage {
+		label := hermes.Label{Key: "slice", Value: s.Name}
+		metrics.SetGauge("agent_cgroup_memory_bytes", float64(s.MemoryBytes), label)
+		metrics.SetGauge("agent_cgroup_cpu_usage_seconds", float64(s.CPUUsageUsec)/1e6, label)
+		metrics.SetGauge("agent_cgroup_cpu_throttled_seconds", float64(s.CPUThrottledUsec)/1e6, label)
+		metrics.SetGauge("agent_cgroup_pids", float64(s.Pids), label)
+	}
+}
+
+func readSliceUsage(name, dir string) (domain.CgroupSlice, error) {
+	slice := domain.CgroupSlice{Name: name}
+	var err error
+	if slice.MemoryBytes, err = readCgroupUint(dir, "memory.current"); err != nil {
+		return slice, err
+	}
+	if slice.Pids, err = readCgroupUint(dir, "pids.current"); err != nil {
+		return slice, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return slice, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "usage_usec":
+			slice.CPUUsageUsec = n
+		case "throttled_usec":
+			slice.CPUThrottledUsec = n
+		}
+	}
+	return slice, scanner.Err()
+}
+
+func readCgroupUint(dir, file string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+		return fmt.Errorf("writing %s to %s: %w", value, filepath.Join(dir, file), err)
+	}
+	return nil
+}
+
+func megabytesToBytes(mb domain.Megabytes) uint64 {
+	return uint64(mb) * 1024 * 1024
+}
@@ -0,0 +1,95 @@
+package hecatoncheir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func readCgroupFile(t *testing.T, dir, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		t.Fatalf("reading %s: %v", file, err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestCgroupSlices_Setup(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "tartarus")
+	slices := NewCgroupSlices(CgroupConfig{
+		Root:            root,
+		HostReserveCPU:  1000,
+		HostReserveMem:  1024,
+		AgentReserveMem: 256,
+	}, domain.ResourceCapacity{CPU: 4000, Mem: 8192})
+
+	if err := slices.Setup(4242); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	sandboxes := filepath.Join(root, "sandboxes")
+	if got := readCgroupFile(t, sandboxes, "cpu.max"); got != "300000 100000" {
+		t.Errorf("sandboxes cpu.max = %q, want 3 CPUs", got)
+	}
+	if got := readCgroupFile(t, sandboxes, "memory.max"); got != "7516192768" {
+		t.Errorf("sandboxes memory.max = %q, want 7168 MB", got)
+	}
+	if got := readCgroupFile(t, slices.Path(SliceAgent), "memory.min"); got != "268435456" {
+		t.Errorf("agent memory.min = %q, want 256 MB", got)
+	}
+	if got := readCgroupFile(t, slices.Path(SliceAgent), "cgroup.procs"); got != "4242" {
+		t.Errorf("agent cgroup.procs = %q, want the agent pid", got)
+	}
+	if got := slices.Path(SliceGVisor); got != filepath.Join(sandboxes, "gvisor") {
+		t.Errorf("gvisor slice at %s", got)
+	}
+	// runsc sandboxes get their own cgroups under the gVisor slice
+	if got := readCgroupFile(t, slices.Path(SliceGVisor), "cgroup.subtree_control"); got != "+cpu +memory +pids" {
+		t.Errorf("gvisor cgroup.subtree_control = %q, want the controllers enabled", got)
+	}
+}
+
+func TestCgroupSlices_NoReserve(t *testing.T) {
+	slices := NewCgroupSlices(CgroupConfig{Root: t.TempDir(), HostReserveMem: 16384}, domain.ResourceCapacity{CPU: 4000, Mem: 8192})
+	if got := slices.sandboxCPUMax(); got != "max" {
+		t.Errorf("cpu.max without a CPU reserve = %q, want max", got)
+	}
+	// A reserve larger than the node leaves sandboxes unlimited rather than starved
+	if got := slices.sandboxMemoryMax(); got != "max" {
+		t.Errorf("memory.max with an oversized reserve = %q, want max", got)
+	}
+}
+
+func TestCgroupSlices_Usage(t *testing.T) {
+	root := t.TempDir()
+	slices := NewCgroupSlices(CgroupConfig{Root: root}, domain.ResourceCapacity{})
+
+	dir := slices.Path(SliceFirecracker)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"memory.current": "1048576\n",
+		"pids.current":   "3\n",
+		"cpu.stat":       "usage_usec 2500000\nuser_usec 2000000\nnr_throttled 4\nthrottled_usec 125000\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Slices that do not exist are skipped
+	usage := slices.Usage()
+	if len(usage) != 1 {
+		t.Fatalf("expected usage for 1 slice, got %d", len(usage))
+	}
+	want := domain.CgroupSlice{Name: SliceFirecracker, MemoryBytes: 1048576, CPUUsageUsec: 2500000, CPUThrottledUsec: 125000, Pids: 3}
+	if usage[0] != want {
+		t.Errorf("usage = %+v, want %+v", usage[0], want)
+	}
+}
@@ -0,0 +1,140 @@
+package hecatoncheir
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// HeartbeatConfig controls how often the agent heartbeats and what it reports.
+type HeartbeatConfig struct {
+	Interval          time.Duration
+	IncludeSandboxes  bool // Report active sandboxes
+	IncludeConditions bool // Report node conditions
+
+	DiskPath              string  // Filesystem checked for disk pressure
+	DiskPressurePercent   float64 // Used-space percentage that counts as pressure
+	MemoryPressurePercent float64 // Used-memory percentage that counts as pressure
+	KVMDevice             string  // Device checked for KVM availability
+}
+
+// DefaultHeartbeatConfig returns the settings used when none are configured.
+func DefaultHeartbeatConfig() HeartbeatConfig {
+	return HeartbeatConfig{
+		Interval:              5 * time.Second,
+		IncludeSandboxes:      true,
+		IncludeConditions:     true,
+		DiskPath:              "/var/lib/tartarus",
+		DiskPressurePercent:   90,
+		MemoryPressurePercent: 90,
+		KVMDevice:             "/dev/kvm",
+	}
+}
+
+// NodeConditions probes the host and tracks when each condition last changed.
+type NodeConditions struct {
+	config HeartbeatConfig
+
+	mu   sync.Mutex
+	last map[domain.NodeConditionType]domain.NodeCondition
+
+	// Probes, replaceable in tests
+	diskUsage func(path string) (float64, error)
+	memUsage  func() (float64, error)
+	kvmCheck  func(device string) error
+	now       func() time.Time
+}
+
+// NewNodeConditions creates a prober for the given heartbeat settings.
+func NewNodeConditions(config HeartbeatConfig) *NodeConditions {
+	return &NodeConditions{
+		config:    config,
+		last:      make(map[domain.NodeConditionType]domain.NodeCondition),
+		diskUsage: diskUsedPercent,
+		memUsage:  memUsedPercent,
+		kvmCheck:  checkKVM,
+		now:       time.Now,
+	}
+}
+
+// Collect probes every condition. runtimeErr is the result of the latest
+// runtime call and determines RuntimeHealthy.
+func (n *NodeConditions) Collect(runtimeErr error) []domain.NodeCondition {
+	conditions := []domain.NodeCondition{
+		n.pressure(domain.NodeDiskPressure, func() (float64, error) { return n.diskUsage(n.config.DiskPath) }, n.config.DiskPressurePercent),
+		n.pressure(domain.NodeMemoryPressure, n.memUsage, n.config.MemoryPressurePercent),
+		n.kvm(),
+		n.runtime(runtimeErr),
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := n.now()
+	for i, c := range conditions {
+		prev, seen := n.last[c.Type]
+		if seen && prev.Status == c.Status {
+			c.LastTransition = prev.LastTransition
+		} else {
+			c.LastTransition = now
+		}
+		conditions[i] = c
+		n.last[c.Type] = c
+	}
+	return conditions
+}
+
+func (n *NodeConditions) pressure(t domain.NodeConditionType, usage func() (float64, error), threshold float64) domain.NodeCondition {
+	used, err := usage()
+	if err != nil {
+		return domain.NodeCondition{Type: t, Status: false, Reason: "ProbeFailed", Message: err.Error()}
+	}
+	message := fmt.Sprintf("%.1f%% used, threshold %.1f%%", used, threshold)
+	if threshold > 0 && used >= threshold {
+		return domain.NodeCondition{Type: t, Status: true, Reason: "ThresholdExceeded", Message: message}
+	}
+	return domain.NodeCondition{Type: t, Status: false, Reason: "WithinThreshold", Message: message}
+}
+
+func (n *NodeConditions) kvm() domain.NodeCondition {
+	if err := n.kvmCheck(n.config.KVMDevice); err != nil {
+		return domain.NodeCondition{Type: domain.NodeKVMAvailable, Status: false, Reason: "DeviceUnavailable", Message: err.Error()}
+	}
+	return domain.NodeCondition{Type: domain.NodeKVMAvailable, Status: true, Reason: "DeviceReady"}
+}
+
+func (n *NodeConditions) runtime(runtimeErr error) domain.NodeCondition {
+	if runtimeErr != nil {
+		return domain.NodeCondition{Type: domain.NodeRuntimeHealthy, Status: false, Reason: "RuntimeError", Message: runtimeErr.Error()}
+	}
+	return domain.NodeCondition{Type: domain.NodeRuntimeHealthy, Status: true, Reason: "RuntimeResponding"}
+}
+
+func diskUsedPercent(path string) (float64, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, err
+	}
+	return usage.UsedPercent, nil
+}
+
+func memUsedPercent() (float64, error) {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, err
+	}
+	return vm.UsedPercent, nil
+}
+
+// checkKVM verifies the KVM device exists and can be opened for read/write.
+func checkKVM(device string) error {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
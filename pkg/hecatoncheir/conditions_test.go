@@ -0,0 +1,77 @@
+package hecatoncheir
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func newTestConditions(disk, memory float64, kvmErr error) *NodeConditions {
+	n := NewNodeConditions(DefaultHeartbeatConfig())
+	n.diskUsage = func(string) (float64, error) { return disk, nil }
+	n.memUsage = func() (float64, error) { return memory, nil }
+	n.kvmCheck = func(string) error { return kvmErr }
+	return n
+}
+
+func conditionStatus(t *testing.T, conditions []domain.NodeCondition, typ domain.NodeConditionType) domain.NodeCondition {
+	t.Helper()
+	for _, c := range conditions {
+		if c.Type == typ {
+			return c
+		}
+	}
+	t.Fatalf("condition %s not reported", typ)
+	return domain.NodeCondition{}
+}
+
+func TestNodeConditions_Collect(t *testing.T) {
+	n := newTestConditions(95, 40, errors.New("no such device"))
+	conditions := n.Collect(errors.New("runtime unreachable"))
+
+	if c := conditionStatus(t, conditions, domain.NodeDiskPressure); !c.Status {
+		t.Errorf("expected disk pressure at 95%%, got %+v", c)
+	}
+	if c := conditionStatus(t, conditions, domain.NodeMemoryPressure); c.Status {
+		t.Errorf("expected no memory pressure at 40%%, got %+v", c)
+	}
+	if c := conditionStatus(t, conditions, domain.NodeKVMAvailable); c.Status {
+		t.Errorf("expected KVM unavailable, got %+v", c)
+	}
+	if c := conditionStatus(t, conditions, domain.NodeRuntimeHealthy); c.Status {
+		t.Errorf("expected runtime unhealthy, got %+v", c)
+	}
+}
+
+func TestNodeConditions_ProbeFailureIsNotPressure(t *testing.T) {
+	n := newTestConditions(0, 0, nil)
+	n.diskUsage = func(string) (float64, error) { return 0, errors.New("stat failed") }
+
+	c := conditionStatus(t, n.Collect(nil), domain.NodeDiskPressure)
+	if c.Status || c.Reason != "ProbeFailed" {
+		t.Errorf("expected ProbeFailed without pressure, got %+v", c)
+	}
+}
+
+func TestNodeConditions_LastTransition(t *testing.T) {
+	n := newTestConditions(50, 50, nil)
+	clock := time.Unix(1000, 0)
+	n.now = func() time.Time { return clock }
+
+	first := conditionStatus(t, n.Collect(nil), domain.NodeDiskPressure)
+
+	clock = clock.Add(time.Minute)
+	unchanged := conditionStatus(t, n.Collect(nil), domain.NodeDiskPressure)
+	if !unchanged.LastTransition.Equal(first.LastTransition) {
+		t.Errorf("transition time moved without a status change: %v -> %v", first.LastTransition, unchanged.LastTransition)
+	}
+
+	clock = clock.Add(time.Minute)
+	n.diskUsage = func(string) (float64, error) { return 99, nil }
+	changed := conditionStatus(t, n.Collect(nil), domain.NodeDiskPressure)
+	if !changed.LastTransition.Equal(clock) {
+		t.Errorf("expected transition at %v, got %v", clock, changed.LastTransition)
+	}
+}
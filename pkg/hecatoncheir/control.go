@@ -23,6 +23,10 @@ const (
 	ControlMessageExec            ControlMessageType = "EXEC"
 	ControlMessageExecInteractive ControlMessageType = "EXEC_INTERACTIVE"
 	ControlMessageListSandboxes   ControlMessageType = "LIST_SANDBOXES"
+	ControlMessagePrefetchImage   ControlMessageType = "PREFETCH_IMAGE"
+	ControlMessageRestart         ControlMessageType = "RESTART"
+	ControlMessageMigrateOut      ControlMessageType = "MIGRATE_OUT"
+	ControlMessageMigrateIn       ControlMessageType = "MIGRATE_IN"
 )
 
 // ControlMessage is a command sent to the agent.
@@ -85,24 +89,11 @@ func (r *RedisControlListener) Listen(ctx context.Context) (<-chan ControlMessag
 				if !ok {
 					return
 				}
-				// Parse message: "TYPE SANDBOX_ID [ARGS...]"
-				parts := strings.Split(msg.Payload, " ")
-				if len(parts) < 2 {
+				cm, ok := parseControlMessage(msg.Payload)
+				if !ok {
 					continue
 				}
-
-				cmdType := ControlMessageType(parts[0])
-				sandboxID := domain.SandboxID(parts[1])
-				var args []string
-				if len(parts) > 2 {
-					args = parts[2:]
-				}
-
-				ch <- ControlMessage{
-					Type:      cmdType,
-					SandboxID: sandboxID,
-					Args:      args,
-				}
+				ch <- cm
 			}
 		}
 	}()
@@ -110,6 +101,24 @@ func (r *RedisControlListener) Listen(ctx context.Context) (<-chan ControlMessag
 	return ch, nil
 }
 
+// parseControlMessage parses a message of the form "TYPE SANDBOX_ID [ARGS...]".
+func parseControlMessage(payload string) (ControlMessage, bool) {
+	parts := strings.Split(payload, " ")
+	if len(parts) < 2 {
+		return ControlMessage{}, false
+	}
+
+	var args []string
+	if len(parts) > 2 {
+		args = parts[2:]
+	}
+	return ControlMessage{
+		Type:      ControlMessageType(parts[0]),
+		SandboxID: domain.SandboxID(parts[1]),
+		Args:      args,
+	}, true
+}
+
 // PublishLogs publishes log chunks to the sandbox's log topic.
 func (r *RedisControlListener) PublishLogs(ctx context.Context, sandboxID domain.SandboxID, logs []byte) error {
 	topic := fmt.Sprintf("tartarus:logs:%s", sandboxID)
@@ -0,0 +1,43 @@
+package hecatoncheir
+
+import (
+	"context"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// CaptureCrashBundle collects a crash bundle from a sandbox the Furies are
+// about to kill, if its policy asks for one, and links it from the run
+// record. It is meant as the Furies' BeforeKillHook; the kill goes ahead
+// whether or not collection succeeds.
+func (a *Agent) CaptureCrashBundle(ctx context.Context, id domain.SandboxID, reason string) {
+	if a.CrashBundles == nil {
+		return
+	}
+	_, req, err := a.Runtime.GetConfig(ctx, id)
+	if err != nil || !req.CrashBundle.Enabled() {
+		return
+	}
+
+	a.Logger.Info(ctx, "Collecting crash bundle", map[string]any{"sandbox_id": id, "reason": reason})
+	key, bundle, err := a.CrashBundles.Collect(ctx, id, req.Template, reason, req.CrashBundle)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to collect crash bundle", map[string]any{"sandbox_id": id, "error": err})
+		return
+	}
+	if len(bundle.Errors) > 0 {
+		a.Logger.Error(ctx, "Crash bundle is incomplete", map[string]any{"sandbox_id": id, "key": key, "errors": bundle.Errors})
+	}
+
+	run, err := a.Registry.GetRun(ctx, id)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to link crash bundle to run", map[string]any{"sandbox_id": id, "key": key, "error": err})
+		return
+	}
+	run.CrashBundle = key
+	run.UpdatedAt = time.Now()
+	if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+		a.Logger.Error(ctx, "Failed to link crash bundle to run", map[string]any{"sandbox_id": id, "key": key, "error": err})
+	}
+}
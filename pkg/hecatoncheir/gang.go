@@ -0,0 +1,68 @@
+package hecatoncheir
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// withGangEnv tells a gang member about its gang through TARTARUS_GANG_ID,
+// TARTARUS_GANG_SIZE, TARTARUS_GANG_INDEX and TARTARUS_GANG_MEMBERS (the
+// comma-separated member IDs in index order), e.g. to pick a rank and find
+// its peers. The request is copied if it changes.
+func withGangEnv(req *domain.SandboxRequest) *domain.SandboxRequest {
+	if req.Gang == nil {
+		return req
+	}
+	members := make([]string, len(req.Gang.Members))
+	for i, id := range req.Gang.Members {
+		members[i] = string(id)
+	}
+
+	launch := *req
+	launch.Env = make(map[string]string, len(req.Env)+4)
+	for k, v := range req.Env {
+		launch.Env[k] = v
+	}
+	launch.Env["TARTARUS_GANG_ID"] = req.Gang.ID
+	launch.Env["TARTARUS_GANG_SIZE"] = strconv.Itoa(req.Gang.Size)
+	launch.Env["TARTARUS_GANG_INDEX"] = strconv.Itoa(req.Gang.Index)
+	launch.Env["TARTARUS_GANG_MEMBERS"] = strings.Join(members, ",")
+	return &launch
+}
+
+// requeue hands a request that could not be launched back to the queue.
+// A gang member is not: started late it would miss its gang, so its run is
+// marked failed instead and Olympus rolls back the rest of the gang.
+func (a *Agent) requeue(ctx context.Context, req *domain.SandboxRequest, receipt, reason string) {
+	if req.Gang == nil {
+		a.Queue.Nack(ctx, receipt, reason)
+		return
+	}
+
+	a.Logger.Info(ctx, "Failing gang member instead of re-driving it", map[string]any{"id": req.ID, "gang_id": req.Gang.ID, "reason": reason})
+	now := time.Now()
+	failed := domain.SandboxRun{
+		ID:        req.ID,
+		RequestID: req.ID,
+		Template:  req.Template,
+		NodeID:    a.NodeID,
+		Status:    domain.RunStatusFailed,
+		Error:     fmt.Sprintf("%s (gang %s member, not retried)", reason, req.Gang.ID),
+		Submitter: req.Submitter,
+		CreatedAt: req.CreatedAt,
+		UpdatedAt: now,
+	}
+	a.keepUserFields(ctx, &failed)
+	if err := a.Registry.UpdateRun(ctx, failed); err != nil {
+		a.Logger.Error(ctx, "Failed to mark gang member failed", map[string]any{"id": req.ID, "error": err})
+	}
+	if err := a.Queue.Ack(ctx, receipt); err != nil {
+		a.Logger.Error(ctx, "Failed to ack gang member", map[string]any{"id": req.ID, "error": err})
+	}
+	a.Metrics.IncCounter("agent_gang_launch_failures_total", 1)
+}
@@ -0,0 +1,111 @@
+package hecatoncheir
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cocytus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+func gangRequest(id domain.SandboxID) *domain.SandboxRequest {
+	return &domain.SandboxRequest{
+		ID:         id,
+		Template:   "base",
+		Env:        map[string]string{"EPOCHS": "3"},
+		Resources:  domain.ResourceSpec{CPU: 1, Mem: 128},
+		NetworkRef: domain.NetworkPolicyRef{ID: "net-1"},
+		Gang: &domain.GangSpec{
+			ID:      "gang-1",
+			Size:    2,
+			Index:   1,
+			Members: []domain.SandboxID{"worker-0", id},
+		},
+	}
+}
+
+func TestAgent_Run_GangEnv(t *testing.T) {
+	req := gangRequest("worker-1")
+
+	var mu sync.Mutex
+	var env map[string]string
+	agent := &Agent{
+		Queue: &mockQueue{req: req},
+		Nyx:   &mockNyx{},
+		Lethe: &mockLethe{},
+		Styx:  &mockStyx{},
+		Runtime: &mockRuntime{LaunchFunc: func(ctx context.Context, req *domain.SandboxRequest, cfg tartarus.VMConfig) (*domain.SandboxRun, error) {
+			mu.Lock()
+			env = req.Env
+			mu.Unlock()
+			return &domain.SandboxRun{ID: req.ID, Status: domain.RunStatusRunning}, nil
+		}},
+		Registry: &mockRegistry{},
+		Furies:   &mockFury{},
+		Logger:   &mockLogger{},
+		Metrics:  &mockMetrics{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	agent.Run(ctx)
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]string{
+		"EPOCHS":                "3",
+		"TARTARUS_GANG_ID":      "gang-1",
+		"TARTARUS_GANG_SIZE":    "2",
+		"TARTARUS_GANG_INDEX":   "1",
+		"TARTARUS_GANG_MEMBERS": "worker-0,worker-1",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+	// The queued request is left as submitted
+	if _, ok := req.Env["TARTARUS_GANG_ID"]; ok {
+		t.Error("gang env leaked into the queued request")
+	}
+}
+
+func TestAgent_Run_GangMemberLaunchFailure(t *testing.T) {
+	// A member is not re-driven alone; its run fails so the gang is rolled back
+	queue := &ackQueue{mockQueue: mockQueue{req: gangRequest("req-fail")}}
+	registry := &runRecorder{}
+	agent := &Agent{
+		Queue:      queue,
+		Nyx:        &mockNyx{},
+		Lethe:      &mockLethe{},
+		Styx:       &mockStyx{},
+		Runtime:    &mockRuntime{},
+		Registry:   registry,
+		Furies:     &mockFury{},
+		DeadLetter: cocytus.NewClassifyingSink(nil, cocytus.NewMemoryCatalog(), 1),
+		Logger:     &mockLogger{},
+		Metrics:    &mockMetrics{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	agent.Run(ctx)
+	cancel()
+
+	queue.mu.Lock()
+	acked, nacked := queue.acked, queue.nacked
+	queue.mu.Unlock()
+	registry.mu.Lock()
+	runs := registry.runs
+	registry.mu.Unlock()
+
+	if !acked || nacked {
+		t.Errorf("expected ack without re-drive, got acked=%v nacked=%v", acked, nacked)
+	}
+	if len(runs) != 1 || runs[0].Status != domain.RunStatusFailed || !strings.Contains(runs[0].Error, "gang-1") {
+		t.Errorf("expected run marked FAILED for the gang, got %+v", runs)
+	}
+}
@@ -0,0 +1,300 @@
+package hecatoncheir
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ErrNoFreeGPU is returned when a node has fewer free GPUs of the requested
+// type than a sandbox asks for.
+var ErrNoFreeGPU = errors.New("not enough free GPUs")
+
+// GPUProber lists the GPUs of the node.
+type GPUProber interface {
+	ProbeGPUs(ctx context.Context) ([]domain.GPUDevice, error)
+}
+
+// NvidiaSMIProber reads the NVML device inventory through nvidia-smi, which
+// ships with the driver, so the agent needs neither cgo nor the NVML
+// headers. A host without nvidia-smi has no GPUs.
+type NvidiaSMIProber struct {
+	Path string // nvidia-smi binary (default "nvidia-smi")
+}
+
+// ProbeGPUs implements GPUProber.
+func (p *NvidiaSMIProber) ProbeGPUs(ctx context.Context) ([]domain.GPUDevice, error) {
+	path := p.Path
+	if path == "" {
+		path = "nvidia-smi"
+	}
+	query, err := exec.CommandContext(ctx, path,
+		"--query-gpu=index,uuid,name,memory.total,mig.mode.current",
+		"--format=csv,noheader,nounits").Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi query failed: %w", err)
+	}
+	devices, err := parseGPUQuery(string(query))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range devices {
+		if d.MIGMode {
+			list, err := exec.CommandContext(ctx, path, "-L").Output()
+			if err != nil {
+				return nil, fmt.Errorf("nvidia-smi -L failed: %w", err)
+			}
+			addMIGDevices(devices, string(list))
+			break
+		}
+	}
+	return devices, nil
+}
+
+// parseGPUQuery parses the output of nvidia-smi --query-gpu=index,uuid,
+// name,memory.total,mig.mode.current --format=csv,noheader,nounits.
+func parseGPUQuery(out string) ([]domain.GPUDevice, error) {
+	var devices []domain.GPUDevice
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected nvidia-smi output %q", line)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("bad GPU index %q: %w", fields[0], err)
+		}
+		memory, _ := strconv.ParseInt(fields[3], 10, 64)
+		devices = append(devices, domain.GPUDevice{
+			Index:    index,
+			UUID:     fields[1],
+			Model:    fields[2],
+			MemoryMB: domain.Megabytes(memory),
+			MIGMode:  strings.EqualFold(fields[4], "Enabled"),
+		})
+	}
+	return devices, nil
+}
+
+var (
+	gpuListLine   = regexp.MustCompile(`^GPU (\d+): `)
+	migListLine   = regexp.MustCompile(`^\s+MIG\s+(\S+)\s+Device\s+\d+:\s+\(UUID:\s+([^)\s]+)\)`)
+	migProfileMem = regexp.MustCompile(`(\d+)gb`)
+)
+
+// addMIGDevices adds the MIG partitions listed by nvidia-smi -L to the
+// devices in MIG mode they belong to.
+func addMIGDevices(devices []domain.GPUDevice, list string) {
+	byIndex := make(map[int]*domain.GPUDevice, len(devices))
+	for i := range devices {
+		byIndex[devices[i].Index] = &devices[i]
+	}
+
+	var current *domain.GPUDevice
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := gpuListLine.FindStringSubmatch(line); m != nil {
+			index, _ := strconv.Atoi(m[1])
+			current = byIndex[index]
+			continue
+		}
+		m := migListLine.FindStringSubmatch(line)
+		if m == nil || current == nil || !current.MIGMode {
+			continue
+		}
+		mig := domain.MIGDevice{UUID: m[2], Profile: m[1]}
+		if gb := migProfileMem.FindStringSubmatch(m[1]); gb != nil {
+			n, _ := strconv.ParseInt(gb[1], 10, 64)
+			mig.MemoryMB = domain.Megabytes(n * 1024)
+		}
+		current.MIG = append(current.MIG, mig)
+	}
+}
+
+// gpuRetainGrace is how long a sandbox keeps its GPUs without being listed
+// by the runtime, which it may not be until its launch completes.
+const gpuRetainGrace = 2 * time.Minute
+
+type gpuAssignment struct {
+	id domain.SandboxID
+	at time.Time
+}
+
+// GPUInventory tracks the node's GPUs and which sandbox each is assigned
+// to. Devices are probed again once Refresh has passed, e.g. to see MIG
+// partitions an operator created. Assignments are kept in memory, so they
+// start empty when the agent restarts.
+type GPUInventory struct {
+	Prober  GPUProber
+	Refresh time.Duration
+
+	mu       sync.Mutex
+	devices  []domain.GPUDevice
+	probedAt time.Time
+	assigned map[string]gpuAssignment // By unit UUID
+	now      func() time.Time
+}
+
+// NewGPUInventory creates an inventory that probes GPUs with prober.
+func NewGPUInventory(prober GPUProber, refresh time.Duration) *GPUInventory {
+	return &GPUInventory{
+		Prober:   prober,
+		Refresh:  refresh,
+		assigned: make(map[string]gpuAssignment),
+		now:      time.Now,
+	}
+}
+
+// Devices returns the node's GPUs with the sandboxes they are assigned to,
+// probing them again if they are stale. If probing fails the last devices
+// seen are returned with the error.
+func (g *GPUInventory) Devices(ctx context.Context) ([]domain.GPUDevice, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	err := g.refresh(ctx)
+	return g.annotated(), err
+}
+
+// refresh probes the devices if they are stale. g.mu must be held.
+func (g *GPUInventory) refresh(ctx context.Context) error {
+	if !g.probedAt.IsZero() && g.now().Sub(g.probedAt) < g.Refresh {
+		return nil
+	}
+	devices, err := g.Prober.ProbeGPUs(ctx)
+	if err != nil {
+		return err
+	}
+	g.devices = devices
+	g.probedAt = g.now()
+	return nil
+}
+
+// annotated copies the devices with their assignments. g.mu must be held.
+func (g *GPUInventory) annotated() []domain.GPUDevice {
+	out := make([]domain.GPUDevice, len(g.devices))
+	for i, d := range g.devices {
+		d.InUseBy = g.assigned[d.UUID].id
+		if len(d.MIG) > 0 {
+			d.MIG = append([]domain.MIGDevice(nil), d.MIG...)
+			for j := range d.MIG {
+				d.MIG[j].InUseBy = g.assigned[d.MIG[j].UUID].id
+			}
+		}
+		out[i] = d
+	}
+	return out
+}
+
+// Units returns the number of GPUs the node hands out and how many of them
+// are assigned.
+func (g *GPUInventory) Units() (total, inUse int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(domain.GPUUnits(g.devices)), len(g.assigned)
+}
+
+// Assign assigns free GPUs matching want to a sandbox and returns their
+// UUIDs, or ErrNoFreeGPU if there are not enough.
+func (g *GPUInventory) Assign(ctx context.Context, id domain.SandboxID, want domain.GPURequest) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.refresh(ctx); err != nil && g.probedAt.IsZero() {
+		return nil, err
+	}
+
+	var picked []string
+	for _, unit := range domain.GPUUnits(g.annotated()) {
+		if len(picked) == want.Count {
+			break
+		}
+		if unit.InUseBy == "" && want.Matches(unit) {
+			picked = append(picked, unit.UUID)
+		}
+	}
+	if len(picked) < want.Count {
+		return nil, fmt.Errorf("%w: %d of type %q requested, %d free", ErrNoFreeGPU, want.Count, want.Type, len(picked))
+	}
+	now := g.now()
+	for _, uuid := range picked {
+		g.assigned[uuid] = gpuAssignment{id: id, at: now}
+	}
+	return picked, nil
+}
+
+// Release frees the GPUs assigned to a sandbox.
+func (g *GPUInventory) Release(id domain.SandboxID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for uuid, a := range g.assigned {
+		if a.id == id {
+			delete(g.assigned, uuid)
+		}
+	}
+}
+
+// Retain frees the GPUs of sandboxes that are not in active, such as
+// sandboxes whose exit the agent missed. GPUs assigned in the last two
+// minutes are kept, as their sandbox may still be launching.
+func (g *GPUInventory) Retain(active []domain.SandboxRun) {
+	keep := make(map[domain.SandboxID]bool, len(active))
+	for _, run := range active {
+		keep[run.ID] = true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	for uuid, a := range g.assigned {
+		if !keep[a.id] && now.Sub(a.at) > gpuRetainGrace {
+			delete(g.assigned, uuid)
+		}
+	}
+}
+
+// assignGPUs assigns GPUs to a request that asks for them and exposes them
+// to the sandbox as NVIDIA_VISIBLE_DEVICES. The request is copied if it
+// changes. Without an inventory requests are launched as they are.
+func (a *Agent) assignGPUs(ctx context.Context, req *domain.SandboxRequest) (*domain.SandboxRequest, error) {
+	if a.GPUs == nil || req.Resources.GPU.Count <= 0 {
+		return req, nil
+	}
+	uuids, err := a.GPUs.Assign(ctx, req.ID, req.Resources.GPU)
+	if err != nil {
+		return nil, err
+	}
+
+	launch := *req
+	launch.Env = make(map[string]string, len(req.Env)+1)
+	for k, v := range req.Env {
+		launch.Env[k] = v
+	}
+	launch.Env["NVIDIA_VISIBLE_DEVICES"] = strings.Join(uuids, ",")
+	a.Logger.Info(ctx, "Assigned GPUs", map[string]any{"id": req.ID, "gpus": uuids})
+	return &launch, nil
+}
+
+// releaseGPUs frees the GPUs assigned to a sandbox, if any.
+func (a *Agent) releaseGPUs(id domain.SandboxID) {
+	if a.GPUs != nil {
+		a.GPUs.Release(id)
+	}
+}
@@ -0,0 +1,156 @@
+package hecatoncheir
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+type staticGPUProber struct {
+	devices []domain.GPUDevice
+	probes  int
+}
+
+func (p *staticGPUProber) ProbeGPUs(ctx context.Context) ([]domain.GPUDevice, error) {
+	p.probes++
+	return p.devices, nil
+}
+
+const gpuQueryOutput = `0, GPU-5d5ba0d6-1111, NVIDIA A100-SXM4-40GB, 40960, Enabled
+1, GPU-7c1e0f2a-2222, NVIDIA A100-SXM4-40GB, 40960, Disabled
+`
+
+const gpuListOutput = `GPU 0: NVIDIA A100-SXM4-40GB (UUID: GPU-5d5ba0d6-1111)
+  MIG 3g.20gb     Device  0: (UUID: MIG-aaaa)
+  MIG 1g.5gb      Device  1: (UUID: MIG-bbbb)
+GPU 1: NVIDIA A100-SXM4-40GB (UUID: GPU-7c1e0f2a-2222)
+`
+
+func TestParseNvidiaSMI(t *testing.T) {
+	devices, err := parseGPUQuery(gpuQueryOutput)
+	if err != nil {
+		t.Fatalf("parseGPUQuery failed: %v", err)
+	}
+	addMIGDevices(devices, gpuListOutput)
+
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got %+v", devices)
+	}
+	if d := devices[1]; d.UUID != "GPU-7c1e0f2a-2222" || d.Model != "NVIDIA A100-SXM4-40GB" || d.MemoryMB != 40960 || d.MIGMode {
+		t.Errorf("unexpected device: %+v", d)
+	}
+	mig := devices[0].MIG
+	if !devices[0].MIGMode || len(mig) != 2 || mig[0].Profile != "3g.20gb" || mig[0].UUID != "MIG-aaaa" || mig[0].MemoryMB != 20480 {
+		t.Errorf("unexpected MIG partitions: %+v", mig)
+	}
+
+	units := domain.GPUUnits(devices)
+	if len(units) != 3 {
+		t.Errorf("expected 2 partitions and 1 whole GPU, got %+v", units)
+	}
+
+	if _, err := parseGPUQuery("garbage"); err == nil {
+		t.Error("expected malformed output to be rejected")
+	}
+}
+
+func TestGPUInventory_AssignAndRelease(t *testing.T) {
+	prober := &staticGPUProber{devices: []domain.GPUDevice{
+		{UUID: "GPU-1", Model: "NVIDIA A100"},
+		{UUID: "GPU-2", Model: "NVIDIA A100"},
+		{UUID: "GPU-3", Model: "Tesla T4"},
+	}}
+	inv := NewGPUInventory(prober, time.Minute)
+	now := time.Now()
+	inv.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	got, err := inv.Assign(ctx, "sb-1", domain.GPURequest{Count: 2, Type: "a100"})
+	if err != nil || len(got) != 2 || got[0] != "GPU-1" || got[1] != "GPU-2" {
+		t.Fatalf("expected both A100s, got %v, %v", got, err)
+	}
+	if _, err := inv.Assign(ctx, "sb-2", domain.GPURequest{Count: 1, Type: "a100"}); !errors.Is(err, ErrNoFreeGPU) {
+		t.Errorf("expected ErrNoFreeGPU, got %v", err)
+	}
+	if total, inUse := inv.Units(); total != 3 || inUse != 2 {
+		t.Errorf("expected 2 of 3 units in use, got %d of %d", inUse, total)
+	}
+	devices, _ := inv.Devices(ctx)
+	if devices[0].InUseBy != "sb-1" || devices[2].InUseBy != "" {
+		t.Errorf("unexpected assignments: %+v", devices)
+	}
+	if prober.probes != 1 {
+		t.Errorf("expected devices to be probed once within the refresh interval, got %d", prober.probes)
+	}
+
+	// Sandboxes the runtime no longer lists lose their GPUs, once past the
+	// launch grace period
+	inv.Retain(nil)
+	if _, inUse := inv.Units(); inUse != 2 {
+		t.Error("GPUs of a launching sandbox were released")
+	}
+	now = now.Add(gpuRetainGrace + time.Second)
+	inv.Retain([]domain.SandboxRun{{ID: "sb-9"}})
+	if _, inUse := inv.Units(); inUse != 0 {
+		t.Errorf("expected GPUs of the vanished sandbox to be released, %d in use", inUse)
+	}
+
+	if _, err := inv.Assign(ctx, "sb-2", domain.GPURequest{Count: 1, Type: "a100"}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	inv.Release("sb-2")
+	if _, inUse := inv.Units(); inUse != 0 {
+		t.Errorf("expected Release to free the GPU, %d in use", inUse)
+	}
+}
+
+func TestAgent_Run_AssignsGPUs(t *testing.T) {
+	req := &domain.SandboxRequest{
+		ID:         "gpu-1",
+		Template:   "base",
+		Resources:  domain.ResourceSpec{CPU: 1, Mem: 128, GPU: domain.GPURequest{Count: 1, Type: "h100"}},
+		NetworkRef: domain.NetworkPolicyRef{ID: "net-1"},
+	}
+	inv := NewGPUInventory(&staticGPUProber{devices: []domain.GPUDevice{
+		{UUID: "GPU-t4", Model: "Tesla T4"},
+		{UUID: "GPU-h100", Model: "NVIDIA H100 80GB HBM3"},
+	}}, time.Minute)
+
+	var mu sync.Mutex
+	var visible string
+	agent := &Agent{
+		Queue: &mockQueue{req: req},
+		Nyx:   &mockNyx{},
+		Lethe: &mockLethe{},
+		Styx:  &mockStyx{},
+		Runtime: &mockRuntime{LaunchFunc: func(ctx context.Context, req *domain.SandboxRequest, cfg tartarus.VMConfig) (*domain.SandboxRun, error) {
+			mu.Lock()
+			visible = req.Env["NVIDIA_VISIBLE_DEVICES"]
+			mu.Unlock()
+			return &domain.SandboxRun{ID: req.ID, Status: domain.RunStatusRunning}, nil
+		}},
+		Registry: &mockRegistry{},
+		Furies:   &mockFury{},
+		GPUs:     inv,
+		Logger:   &mockLogger{},
+		Metrics:  &mockMetrics{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	agent.Run(ctx)
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if visible != "GPU-h100" {
+		t.Errorf("NVIDIA_VISIBLE_DEVICES = %q, want GPU-h100", visible)
+	}
+	if req.Env["NVIDIA_VISIBLE_DEVICES"] != "" {
+		t.Error("GPU assignment leaked into the queued request")
+	}
+}
@@ -0,0 +1,262 @@
+package hecatoncheir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// DefaultHookTimeout bounds a host hook whose policy sets no timeout.
+const DefaultHookTimeout = 30 * time.Second
+
+// hookOutputBytes is how much of a hook's output is kept for logs and errors.
+const hookOutputBytes = 4096
+
+// hookPath is the only PATH host hooks see.
+const hookPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// ErrHooksDisabled is returned for requests with host hooks on an agent
+// without a hooks directory.
+var ErrHooksDisabled = errors.New("host hooks are not enabled on this node")
+
+// HookEnv describes the sandbox a hook runs for. It is passed to hooks as
+// TARTARUS_* environment variables.
+type HookEnv struct {
+	SandboxID domain.SandboxID
+	Template  domain.TemplateID
+	NodeID    domain.NodeID
+	Overlay   string // Host path of the sandbox's writable overlay, if any
+}
+
+// HookRunner runs the host hooks declared by policies. Hooks are resolved
+// in Dir only, and run without stdin in a fresh working directory, in their
+// own process group, with a minimal environment: PATH, the hook's own
+// variables and TARTARUS_SANDBOX_ID, TARTARUS_TEMPLATE_ID, TARTARUS_NODE_ID,
+// TARTARUS_HOOK_PHASE and TARTARUS_OVERLAY. The whole group is killed when
+// the hook times out.
+type HookRunner struct {
+	Dir     string        // Directory holding the hook executables
+	Timeout time.Duration // Per hook when the policy sets none (DefaultHookTimeout if zero)
+
+	metrics hermes.Metrics
+	logger  hermes.Logger
+}
+
+func NewHookRunner(dir string, timeout time.Duration, metrics hermes.Metrics, logger hermes.Logger) *HookRunner {
+	return &HookRunner{
+		Dir:     dir,
+		Timeout: timeout,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// HookError is a hook that failed, with the tail of its output.
+type HookError struct {
+	Phase  string
+	Name   string
+	Output string
+	Err    error
+}
+
+func (e *HookError) Error() string {
+	msg := fmt.Sprintf("%s hook %s failed: %v", e.Phase, e.Name, e.Err)
+	if e.Output != "" {
+		msg += ": " + e.Output
+	}
+	return msg
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs hooks in order. In the pre-start phase it stops at the first
+// failure; in the post-stop phase every hook runs and the failures are
+// joined.
+func (r *HookRunner) Run(ctx context.Context, phase string, hooks []domain.HostHook, env HookEnv) error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := r.run(ctx, phase, hook, env); err != nil {
+			if phase == domain.HookPreStart {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *HookRunner) run(ctx context.Context, phase string, hook domain.HostHook, env HookEnv) error {
+	start := time.Now()
+	output, err := r.exec(ctx, phase, hook, env)
+	result := "ok"
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		result = "timeout"
+	case err != nil:
+		result = "error"
+	}
+	r.metrics.IncCounter("agent_hook_runs_total", 1,
+		hermes.Label{Key: "phase", Value: phase},
+		hermes.Label{Key: "result", Value: result})
+	r.metrics.ObserveHistogram("agent_hook_duration_seconds", time.Since(start).Seconds(),
+		hermes.Label{Key: "phase", Value: phase})
+
+	fields := map[string]any{
+		"sandbox_id": env.SandboxID,
+		"phase":      phase,
+		"hook":       hook.Name,
+		"duration":   time.Since(start).String(),
+		"output":     output,
+	}
+	if err != nil {
+		fields["error"] = err
+		r.logger.Error(ctx, "Host hook failed", fields)
+		return &HookError{Phase: phase, Name: hook.Name, Output: output, Err: err}
+	}
+	r.logger.Info(ctx, "Host hook finished", fields)
+	return nil
+}
+
+func (r *HookRunner) exec(ctx context.Context, phase string, hook domain.HostHook, env HookEnv) (string, error) {
+	path, err := r.resolve(hook.Name)
+	if err != nil {
+		return "", err
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = r.Timeout
+	}
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	workDir, err := os.MkdirTemp("", "tartarus-hook-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create hook working directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	output := &tailBuffer{max: hookOutputBytes}
+	cmd := exec.CommandContext(ctx, path, hook.Args...)
+	cmd.Dir = workDir
+	cmd.Env = hookEnviron(phase, hook, env)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// Children the hook started go down with it
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+	return strings.TrimSpace(output.String()), err
+}
+
+// resolve returns the executable for name, which must be a regular file
+// in Dir once symlinks are followed.
+func (r *HookRunner) resolve(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid hook name %q", name)
+	}
+	dir, err := filepath.EvalSymlinks(r.Dir)
+	if err != nil {
+		return "", fmt.Errorf("hooks directory: %w", err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+	if err != nil {
+		return "", fmt.Errorf("hook %s not installed on this node: %w", name, err)
+	}
+	if filepath.Dir(path) != dir {
+		return "", fmt.Errorf("hook %s resolves outside the hooks directory", name)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return "", fmt.Errorf("hook %s is not an executable file", name)
+	}
+	return path, nil
+}
+
+func hookEnviron(phase string, hook domain.HostHook, env HookEnv) []string {
+	environ := make([]string, 0, len(hook.Env)+6)
+	for k, v := range hook.Env {
+		environ = append(environ, k+"="+v)
+	}
+	sort.Strings(environ)
+	return append(environ,
+		"PATH="+hookPath,
+		"TARTARUS_SANDBOX_ID="+string(env.SandboxID),
+		"TARTARUS_TEMPLATE_ID="+string(env.Template),
+		"TARTARUS_NODE_ID="+string(env.NodeID),
+		"TARTARUS_HOOK_PHASE="+phase,
+		"TARTARUS_OVERLAY="+env.Overlay,
+	)
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
+
+// runPreStartHooks runs the request's pre-start hooks. If one fails, the
+// post-stop hooks run so they can undo what the others set up.
+func (a *Agent) runPreStartHooks(ctx context.Context, req *domain.SandboxRequest, overlay string) error {
+	if !req.Hooks.Enabled() {
+		return nil
+	}
+	if a.Hooks == nil {
+		return ErrHooksDisabled
+	}
+	err := a.Hooks.Run(ctx, domain.HookPreStart, req.Hooks.PreStart, a.hookEnv(req, overlay))
+	if err != nil {
+		a.runPostStopHooks(ctx, req, overlay)
+	}
+	return err
+}
+
+// runPostStopHooks runs the request's post-stop hooks. Failures are logged
+// by the runner and do not affect the run.
+func (a *Agent) runPostStopHooks(ctx context.Context, req *domain.SandboxRequest, overlay string) {
+	if a.Hooks == nil || !req.Hooks.Enabled() || len(req.Hooks.PostStop) == 0 {
+		return
+	}
+	a.Hooks.Run(ctx, domain.HookPostStop, req.Hooks.PostStop, a.hookEnv(req, overlay))
+}
+
+func (a *Agent) hookEnv(req *domain.SandboxRequest, overlay string) HookEnv {
+	return HookEnv{SandboxID: req.ID, Template: req.Template, NodeID: a.NodeID, Overlay: overlay}
+}
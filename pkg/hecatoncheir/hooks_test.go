@@ -0,0 +1,155 @@
+package hecatoncheir
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+func writeHook(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHookRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	writeHook(t, dir, "record", `echo "$TARTARUS_HOOK_PHASE $TARTARUS_SANDBOX_ID $DATASET $1 $HOME" >> `+out+"\n")
+	writeHook(t, dir, "fail", "echo license server unreachable >&2\nexit 3\n")
+	writeHook(t, dir, "hang", "sleep 30\n")
+	if err := os.Symlink("/bin/sh", filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewHookRunner(dir, time.Second, hermes.NewNoopMetrics(), &mockLogger{})
+	ctx := context.Background()
+	env := HookEnv{SandboxID: "sbx-1", Template: "trainer", NodeID: "node-1"}
+
+	// Hooks see only their own variables and the sandbox's
+	t.Setenv("HOME", "/root")
+	hooks := []domain.HostHook{{Name: "record", Args: []string{"imagenet"}, Env: map[string]string{"DATASET": "ds"}}}
+	if err := runner.Run(ctx, domain.HookPreStart, hooks, env); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "pre_start sbx-1 ds imagenet" {
+		t.Errorf("Unexpected hook output %q", got)
+	}
+
+	// A pre-start failure stops the remaining hooks and carries the output
+	err = runner.Run(ctx, domain.HookPreStart, []domain.HostHook{{Name: "fail"}, {Name: "record"}}, env)
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("Expected a HookError, got %v", err)
+	}
+	if hookErr.Name != "fail" || hookErr.Output != "license server unreachable" {
+		t.Errorf("Unexpected hook error %+v", hookErr)
+	}
+	if data, _ := os.ReadFile(out); strings.Count(string(data), "\n") != 1 {
+		t.Error("Expected the hooks after a pre-start failure not to run")
+	}
+
+	// Post-stop hooks all run
+	if err := runner.Run(ctx, domain.HookPostStop, []domain.HostHook{{Name: "fail"}, {Name: "record"}}, env); err == nil {
+		t.Error("Expected the post-stop failure to be reported")
+	}
+	if data, _ := os.ReadFile(out); strings.Count(string(data), "\n") != 2 {
+		t.Error("Expected the hooks after a post-stop failure to run")
+	}
+
+	// Hooks are killed at their timeout
+	start := time.Now()
+	err = runner.Run(ctx, domain.HookPreStart, []domain.HostHook{{Name: "hang", Timeout: 100 * time.Millisecond}}, env)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Expected the hook to be killed at its timeout")
+	}
+
+	// Only executables in the hooks directory run
+	for _, name := range []string{"escape", "missing", "../fail"} {
+		if err := runner.Run(ctx, domain.HookPreStart, []domain.HostHook{{Name: name}}, env); err == nil {
+			t.Errorf("Expected hook %s to be refused", name)
+		}
+	}
+}
+
+func TestHostHooks_Validate(t *testing.T) {
+	valid := &domain.HostHooks{PreStart: []domain.HostHook{{Name: "mount-dataset", Env: map[string]string{"DATASET": "x"}}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid hooks, got %v", err)
+	}
+	for _, hooks := range []*domain.HostHooks{
+		{PreStart: []domain.HostHook{{Name: "../bin/sh"}}},
+		{PostStop: []domain.HostHook{{Name: ""}}},
+		{PreStart: []domain.HostHook{{Name: "x", Env: map[string]string{"TARTARUS_SANDBOX_ID": "other"}}}},
+		{PreStart: []domain.HostHook{{Name: "x", Timeout: -time.Second}}},
+	} {
+		if err := hooks.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", hooks)
+		}
+	}
+}
+
+func TestAgent_Run_PreStartHookAbortsLaunch(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(t.TempDir(), "undone")
+	writeHook(t, dir, "fetch-license", "exit 1\n")
+	writeHook(t, dir, "cleanup", "touch "+out+"\n")
+
+	req := &domain.SandboxRequest{
+		ID:         "req-hooked",
+		Template:   "base",
+		Resources:  domain.ResourceSpec{CPU: 1, Mem: 128},
+		NetworkRef: domain.NetworkPolicyRef{ID: "net-1"},
+		Hooks: &domain.HostHooks{
+			PreStart: []domain.HostHook{{Name: "fetch-license"}},
+			PostStop: []domain.HostHook{{Name: "cleanup"}},
+		},
+	}
+	sink := &mockSink{}
+	agent := &Agent{
+		Queue:      &mockQueue{req: req},
+		Nyx:        &mockNyx{},
+		Lethe:      &mockLethe{},
+		Styx:       &mockStyx{},
+		Runtime:    &mockRuntime{},
+		Registry:   &mockRegistry{},
+		Furies:     &mockFury{},
+		DeadLetter: sink,
+		Hooks:      NewHookRunner(dir, time.Second, hermes.NewNoopMetrics(), &mockLogger{}),
+		Logger:     &mockLogger{},
+		Metrics:    &mockMetrics{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	agent.Run(ctx)
+
+	for i := 0; i < 10 && sink.written == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sink.written == nil {
+		t.Fatal("Expected the aborted launch to be dead-lettered")
+	}
+	// The runtime's own launch failure would read "launch failed"
+	if !strings.HasPrefix(sink.written.Reason, "pre_start hook fetch-license failed") {
+		t.Errorf("Expected the hook failure as reason, got %q", sink.written.Reason)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Error("Expected the post-stop hooks to run after the aborted launch")
+	}
+}
@@ -0,0 +1,34 @@
+package hecatoncheir
+
+import (
+	"context"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// stageInputs pulls the request's input artifacts into the node's artifact
+// cache, so the next request reading them is scheduled here and finds them
+// local. Staging is best effort and never holds up the launch.
+func (a *Agent) stageInputs(ctx context.Context, req *domain.SandboxRequest) {
+	if a.Artifacts == nil {
+		return
+	}
+	for _, input := range req.Inputs {
+		if input.Digest == "" || input.Ref != "" {
+			// Images are cached by the image cache
+			continue
+		}
+		if _, err := a.Artifacts.Fetch(ctx, input.Digest); err != nil {
+			a.Logger.Error(ctx, "Failed to stage input artifact", map[string]any{
+				"sandbox_id": req.ID,
+				"input":      input.Name,
+				"digest":     input.Digest,
+				"error":      err,
+			})
+			a.Metrics.IncCounter("agent_input_staging_total", 1, hermes.Label{Key: "result", Value: "error"})
+			continue
+		}
+		a.Metrics.IncCounter("agent_input_staging_total", 1, hermes.Label{Key: "result", Value: "success"})
+	}
+}
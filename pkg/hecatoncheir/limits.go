@@ -0,0 +1,15 @@
+package hecatoncheir
+
+import "github.com/tartarus-sandbox/tartarus/pkg/domain"
+
+// withProcessLimits returns the request to launch with the policy's process
+// limits completed by the node's defaults. The queued request is not changed.
+func (a *Agent) withProcessLimits(req *domain.SandboxRequest) *domain.SandboxRequest {
+	limits := a.ProcessLimits.Override(req.Limits)
+	if limits == (domain.ProcessLimits{}) {
+		return req
+	}
+	launch := *req
+	launch.Limits = &limits
+	return &launch
+}
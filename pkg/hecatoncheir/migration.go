@@ -0,0 +1,170 @@
+package hecatoncheir
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/hypnos"
+	"github.com/tartarus-sandbox/tartarus/pkg/styx"
+)
+
+// handleMigrateOut snapshots a sandbox for a live migration to target and
+// hands it off in Erebus, for the target's agent to restore. Olympus sends
+// the target its restore command once the run's migration reaches
+// RESTORING.
+func (a *Agent) handleMigrateOut(ctx context.Context, id domain.SandboxID, target domain.NodeID) {
+	if a.Hypnos == nil {
+		a.failMigration(ctx, id, "hibernation is disabled on the source node")
+		return
+	}
+	start := time.Now()
+	a.Logger.Info(ctx, "Migrating sandbox", map[string]any{"sandbox_id": id, "target": target})
+
+	// The sandbox exits here once it sleeps; its run is not over
+	a.migrating.Store(id, true)
+	if _, err := a.Hypnos.Sleep(ctx, id, &hypnos.SleepOptions{GracefulShutdown: true, WithRootFS: true}); err != nil {
+		a.migrating.Delete(id)
+		a.failMigration(ctx, id, "failed to snapshot sandbox: "+err.Error())
+		return
+	}
+	key, err := a.Hypnos.HandOff(ctx, id)
+	if err != nil {
+		// It stays asleep here and can still be woken on this node
+		a.failMigration(ctx, id, "failed to hand sandbox off: "+err.Error())
+		return
+	}
+
+	a.updateMigration(ctx, id, func(run *domain.SandboxRun) {
+		run.Migration.Phase = domain.MigrationPhaseRestoring
+		run.Migration.SnapshotKey = key
+	})
+	a.Logger.Info(ctx, "Sandbox handed off", map[string]any{"sandbox_id": id, "target": target, "key": key})
+	a.Metrics.ObserveHistogram("agent_migration_handoff_seconds", time.Since(start).Seconds())
+}
+
+// handleMigrateIn restores a sandbox another node handed off under key: it
+// attaches the sandbox to this node's network, wakes it and records this
+// node as the run's in Hades.
+func (a *Agent) handleMigrateIn(ctx context.Context, id domain.SandboxID, key string) {
+	if a.Hypnos == nil {
+		a.failMigration(ctx, id, "hibernation is disabled on the target node")
+		return
+	}
+	start := time.Now()
+
+	record, err := a.Hypnos.Adopt(ctx, key, func(rec *hypnos.SleepRecord) error {
+		tapName, ip, gateway, cidr, err := a.Styx.Attach(ctx, id, &styx.Contract{ID: rec.Request.NetworkRef.ID})
+		if err != nil {
+			return fmt.Errorf("failed to attach network: %w", err)
+		}
+		if ip != rec.Config.IP {
+			a.Logger.Info(ctx, "Migrated sandbox has a new address", map[string]any{"sandbox_id": id, "previous": rec.Config.IP, "ip": ip})
+		}
+		rec.Config.TapDevice = tapName
+		rec.Config.IP = ip
+		rec.Config.Gateway = gateway
+		rec.Config.CIDR = cidr
+		rec.Config.Jailer = a.Jailer
+		return nil
+	})
+	if err != nil {
+		a.failMigration(ctx, id, "failed to adopt sandbox: "+err.Error())
+		return
+	}
+
+	if _, err := a.Hypnos.Wake(ctx, id); err != nil {
+		a.Hypnos.Forget(id)
+		a.Styx.Detach(ctx, id)
+		a.failMigration(ctx, id, "failed to restore sandbox: "+err.Error())
+		return
+	}
+
+	a.updateMigration(ctx, id, func(run *domain.SandboxRun) {
+		run.NodeID = a.NodeID
+		run.Status = domain.RunStatusRunning
+		run.Migration.Phase = domain.MigrationPhaseCompleted
+		run.Migration.FinishedAt = time.Now()
+	})
+	a.Logger.Info(ctx, "Sandbox restored", map[string]any{"sandbox_id": id, "key": key})
+	a.Metrics.IncCounter("agent_migrations_total", 1, hermes.Label{Key: "result", Value: "restored"})
+	a.Metrics.ObserveHistogram("agent_migration_restore_seconds", time.Since(start).Seconds())
+
+	go a.superviseMigrated(id, record)
+}
+
+// superviseMigrated records the final status of a sandbox restored from
+// another node and releases what it held here once it exits.
+func (a *Agent) superviseMigrated(id domain.SandboxID, record *hypnos.SleepRecord) {
+	ctx := context.Background()
+	if err := a.Runtime.Wait(ctx, id); err != nil {
+		a.Logger.Error(ctx, "Wait failed", map[string]any{"run_id": id, "error": err})
+	}
+	a.Logger.Info(ctx, "Sandbox exited", map[string]any{"run_id": id})
+
+	if _, moved := a.migrating.LoadAndDelete(id); !moved {
+		if final, err := a.Runtime.Inspect(ctx, id); err == nil {
+			if run, err := a.Registry.GetRun(ctx, id); err == nil {
+				run.Status = final.Status
+				run.ExitCode = final.ExitCode
+				run.Error = final.Error
+				run.FinishedAt = final.FinishedAt
+				run.UpdatedAt = time.Now()
+				if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+					a.Logger.Error(ctx, "Failed to update final run status", map[string]any{"run_id": id, "error": err})
+				}
+			}
+		} else {
+			a.Logger.Error(ctx, "Failed to inspect final run", map[string]any{"run_id": id, "error": err})
+		}
+	}
+
+	if err := a.Styx.Detach(ctx, id); err != nil {
+		a.Logger.Error(ctx, "Failed to detach network", map[string]any{"req_id": id, "error": err})
+	}
+	if record.RootFSKey != "" {
+		os.Remove(record.Config.OverlayFS)
+	}
+}
+
+// failMigration marks the run's migration failed. If the sandbox is gone
+// from this node without having been handed off, the run failed with it.
+func (a *Agent) failMigration(ctx context.Context, id domain.SandboxID, reason string) {
+	a.Logger.Error(ctx, "Migration failed", map[string]any{"sandbox_id": id, "error": reason})
+	a.Metrics.IncCounter("agent_migrations_total", 1, hermes.Label{Key: "result", Value: "failed"})
+	a.updateMigration(ctx, id, func(run *domain.SandboxRun) {
+		run.Migration.Phase = domain.MigrationPhaseFailed
+		run.Migration.Error = reason
+		run.Migration.FinishedAt = time.Now()
+		if run.NodeID != a.NodeID || a.Hypnos == nil || a.Hypnos.IsSleeping(id) {
+			return
+		}
+		if _, err := a.Runtime.Inspect(ctx, id); err != nil {
+			run.Status = domain.RunStatusFailed
+			run.Error = "lost during migration: " + reason
+			run.FinishedAt = time.Now()
+		}
+	})
+}
+
+// updateMigration applies fn to the run's migration record in Hades.
+func (a *Agent) updateMigration(ctx context.Context, id domain.SandboxID, fn func(*domain.SandboxRun)) {
+	run, err := a.Registry.GetRun(ctx, id)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to load run to record migration", map[string]any{"sandbox_id": id, "error": err})
+		return
+	}
+	migration := domain.RunMigration{}
+	if run.Migration != nil {
+		migration = *run.Migration
+	}
+	run.Migration = &migration
+	fn(run)
+	run.UpdatedAt = time.Now()
+	if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+		a.Logger.Error(ctx, "Failed to record migration", map[string]any{"sandbox_id": id, "error": err})
+	}
+}
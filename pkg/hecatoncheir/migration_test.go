@@ -0,0 +1,92 @@
+package hecatoncheir
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/hypnos"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+func newMigrationAgent(t *testing.T, node domain.NodeID, store erebus.Store, registry hades.Registry) (*Agent, *tartarus.MockRuntime) {
+	t.Helper()
+	runtime := tartarus.NewMockRuntime(slog.Default())
+	runtime.StartDuration = time.Millisecond
+	return &Agent{
+		NodeID:   node,
+		Runtime:  runtime,
+		Styx:     &mockStyx{},
+		Hypnos:   hypnos.NewManager(runtime, store, t.TempDir()),
+		Registry: registry,
+		Metrics:  hermes.NewNoopMetrics(),
+		Logger:   hermes.NewSlogAdapter(),
+	}, runtime
+}
+
+func TestAgent_Migration(t *testing.T) {
+	ctx := context.Background()
+	store, err := erebus.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	registry := hades.NewMemoryRegistry()
+	source, sourceRuntime := newMigrationAgent(t, "node-a", store, registry)
+	target, targetRuntime := newMigrationAgent(t, "node-b", store, registry)
+
+	req := &domain.SandboxRequest{ID: "sb-1", Template: "tpl", Resources: domain.ResourceSpec{CPU: 1000, Mem: 128}}
+	_, err = sourceRuntime.Launch(ctx, req, tartarus.VMConfig{TapDevice: "tap-a", CPUs: 1, MemoryMB: 128})
+	require.NoError(t, err)
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{
+		ID: "sb-1", NodeID: "node-a", Status: domain.RunStatusRunning,
+		Migration: &domain.RunMigration{Source: "node-a", Target: "node-b", Phase: domain.MigrationPhaseSnapshotting},
+	}))
+
+	source.handleMigrateOut(ctx, "sb-1", "node-b")
+	run, err := registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	require.Equal(t, domain.MigrationPhaseRestoring, run.Migration.Phase, run.Migration.Error)
+	assert.NotEmpty(t, run.Migration.SnapshotKey)
+	_, err = sourceRuntime.Inspect(ctx, "sb-1")
+	assert.Error(t, err, "the sandbox left the source")
+	assert.False(t, source.Hypnos.IsSleeping("sb-1"))
+
+	target.handleMigrateIn(ctx, "sb-1", run.Migration.SnapshotKey)
+	run, err = registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	require.Equal(t, domain.MigrationPhaseCompleted, run.Migration.Phase, run.Migration.Error)
+	assert.Equal(t, domain.NodeID("node-b"), run.NodeID)
+	assert.Equal(t, domain.RunStatusRunning, run.Status)
+
+	// Restored on the target with the target's network
+	cfg, restored, err := targetRuntime.GetConfig(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, "tap0", cfg.TapDevice)
+	assert.Equal(t, req.Template, restored.Template)
+}
+
+func TestAgent_MigrationRestoreFailure(t *testing.T) {
+	ctx := context.Background()
+	store, err := erebus.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	registry := hades.NewMemoryRegistry()
+	target, _ := newMigrationAgent(t, "node-b", store, registry)
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{
+		ID: "sb-1", NodeID: "node-a", Status: domain.RunStatusRunning,
+		Migration: &domain.RunMigration{Source: "node-a", Target: "node-b", Phase: domain.MigrationPhaseRestoring, SnapshotKey: "sleep/sb-1/1"},
+	}))
+
+	target.handleMigrateIn(ctx, "sb-1", "sleep/sb-1/1")
+	run, err := registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.MigrationPhaseFailed, run.Migration.Phase)
+	assert.Contains(t, run.Migration.Error, "failed to adopt sandbox")
+	// Nothing ran here, so the run is left to the source
+	assert.Equal(t, domain.NodeID("node-a"), run.NodeID)
+	assert.Equal(t, domain.RunStatusRunning, run.Status)
+}
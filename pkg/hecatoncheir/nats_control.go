@@ -0,0 +1,180 @@
+package hecatoncheir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// natsConsumerInactivity is how long the durable consumer of a node that
+// stopped listening is kept before the server removes it.
+const natsConsumerInactivity = 24 * time.Hour
+
+// NATSControlListener implements ControlListener using NATS. Commands are
+// read from a durable JetStream consumer on the node's control subject, so
+// those sent while the agent reconnects are not lost. Log, exec and list
+// requests arrive on the node's request subject and are acknowledged once
+// accepted, and their output is published on core NATS subjects.
+type NATSControlListener struct {
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	nodeID domain.NodeID
+}
+
+// NewNATSControlListener creates a new NATSControlListener.
+func NewNATSControlListener(nc *nats.Conn, nodeID domain.NodeID) (*NATSControlListener, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+	return &NATSControlListener{
+		nc:     nc,
+		js:     js,
+		nodeID: nodeID,
+	}, nil
+}
+
+// Listen consumes the node's commands and requests and returns a channel of
+// messages. The channel is closed when ctx is cancelled.
+func (n *NATSControlListener) Listen(ctx context.Context) (<-chan ControlMessage, error) {
+	// The agent may start before Olympus, so either side creates the stream
+	if _, err := n.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     domain.NATSControlStream,
+		Subjects: []string{domain.NATSControlSubjects},
+		MaxAge:   domain.NATSControlMaxAge,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create control stream: %w", err)
+	}
+	consumer, err := n.js.CreateOrUpdateConsumer(ctx, domain.NATSControlStream, jetstream.ConsumerConfig{
+		Durable:       "agent-" + domain.NATSToken(string(n.nodeID)),
+		FilterSubject: domain.NATSControlSubject(n.nodeID),
+		// A new node has nothing to catch up on; a returning one resumes
+		// where it stopped
+		DeliverPolicy:     jetstream.DeliverNewPolicy,
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		InactiveThreshold: natsConsumerInactivity,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control consumer: %w", err)
+	}
+
+	ch := make(chan ControlMessage)
+	var mu sync.Mutex
+	closed := false
+	send := func(msg ControlMessage) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return false
+		}
+		select {
+		case ch <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Commands are acknowledged on receipt: one that fails is not retried,
+	// as with Redis, and a RESTART is not replayed by the restarted agent
+	cc, err := consumer.Consume(func(m jetstream.Msg) {
+		m.Ack()
+		if msg, ok := parseControlMessage(string(m.Data())); ok {
+			send(msg)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume control subject: %w", err)
+	}
+
+	sub, err := n.nc.Subscribe(domain.NATSRequestSubject(n.nodeID), func(m *nats.Msg) {
+		msg, ok := parseControlMessage(string(m.Data))
+		if !ok {
+			m.Respond([]byte("invalid request"))
+			return
+		}
+		if send(msg) {
+			m.Respond([]byte("OK"))
+		}
+	})
+	if err != nil {
+		cc.Stop()
+		return nil, fmt.Errorf("failed to subscribe to request subject: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.Unsubscribe()
+		cc.Stop()
+		<-cc.Closed()
+
+		mu.Lock()
+		closed = true
+		close(ch)
+		mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+// PublishLogs publishes log chunks to the sandbox's log subject.
+func (n *NATSControlListener) PublishLogs(ctx context.Context, sandboxID domain.SandboxID, logs []byte) error {
+	return n.nc.Publish(domain.NATSLogsSubject(sandboxID), logs)
+}
+
+// PublishSandboxes publishes the list of sandboxes to a response subject.
+func (n *NATSControlListener) PublishSandboxes(ctx context.Context, requestID string, sandboxes []domain.SandboxRun) error {
+	payload, err := json.Marshal(sandboxes)
+	if err != nil {
+		return err
+	}
+	return n.nc.Publish(domain.NATSResponseSubject(requestID), payload)
+}
+
+// PublishExecOutput publishes exec output to the session's subject.
+func (n *NATSControlListener) PublishExecOutput(ctx context.Context, sandboxID domain.SandboxID, requestID string, output []byte) error {
+	return n.nc.Publish(domain.NATSExecSubject(sandboxID, requestID), output)
+}
+
+// SubscribeStdin subscribes to the stdin subject for a request. It returns
+// once the server has the subscription, so input sent after the agent
+// reports it is ready is not missed.
+func (n *NATSControlListener) SubscribeStdin(ctx context.Context, requestID string) (<-chan []byte, error) {
+	msgs := make(chan *nats.Msg, 64)
+	sub, err := n.nc.ChanSubscribe(domain.NATSStdinSubject(requestID), msgs)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.nc.FlushWithContext(ctx); err != nil {
+		sub.Unsubscribe()
+		return nil, err
+	}
+
+	ch := make(chan []byte)
+
+	go func() {
+		defer close(ch)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-msgs:
+				select {
+				case ch <- msg.Data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,80 @@
+package hecatoncheir
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func connectNATS(t *testing.T) *nats.Conn {
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	nc, err := nats.Connect(url, nats.Timeout(time.Second))
+	if err != nil {
+		t.Skip("NATS not available")
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func TestNATSControlListener(t *testing.T) {
+	nc := connectNATS(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	nodeID := domain.NodeID("test-node." + time.Now().Format("150405.000"))
+	listener, err := NewNATSControlListener(nc, nodeID)
+	require.NoError(t, err)
+	ch, err := listener.Listen(ctx)
+	require.NoError(t, err)
+
+	// Commands arrive through the JetStream consumer
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.Publish(ctx, domain.NATSControlSubject(nodeID), []byte("KILL sbx-1"))
+	require.NoError(t, err)
+
+	select {
+	case msg := <-ch:
+		assert.Equal(t, ControlMessageKill, msg.Type)
+		assert.Equal(t, domain.SandboxID("sbx-1"), msg.SandboxID)
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for command")
+	}
+
+	// Requests are acknowledged once accepted
+	replies := make(chan *nats.Msg, 1)
+	go func() {
+		reply, err := nc.Request(domain.NATSRequestSubject(nodeID), []byte("LOGS sbx-1 true"), 5*time.Second)
+		if err == nil {
+			replies <- reply
+		}
+	}()
+
+	select {
+	case msg := <-ch:
+		assert.Equal(t, ControlMessageLogs, msg.Type)
+		assert.Equal(t, []string{"true"}, msg.Args)
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for request")
+	}
+	select {
+	case reply := <-replies:
+		assert.Equal(t, "OK", string(reply.Data))
+	case <-ctx.Done():
+		t.Fatal("Request was not acknowledged")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,80 @@
+package hecatoncheir
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/lethe"
+)
+
+// OutputKey is the Erebus key of the tarball of files a run wrote.
+func OutputKey(id domain.SandboxID) string {
+	return fmt.Sprintf("outputs/%s.tar.gz", id)
+}
+
+// captureOutputs packages the files the sandbox wrote to its overlay into
+// Erebus and links the tarball from the run, if the request's policy asks
+// for them. It must run before the overlay is destroyed.
+func (a *Agent) captureOutputs(ctx context.Context, req *domain.SandboxRequest, ov *lethe.Overlay) {
+	if !req.Outputs.Enabled() {
+		return
+	}
+	differ, ok := a.Lethe.(lethe.Differ)
+	if !ok || a.Outputs == nil {
+		a.Logger.Error(ctx, "Outputs requested but this node cannot extract them", map[string]any{"sandbox_id": req.ID})
+		a.Metrics.IncCounter("agent_outputs_total", 1, hermes.Label{Key: "result", Value: "unsupported"})
+		return
+	}
+
+	key := OutputKey(req.ID)
+	pr, pw := io.Pipe()
+	var output *domain.RunOutput
+	var diffErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		output, diffErr = differ.Diff(ctx, ov, pw, req.Outputs)
+		pw.CloseWithError(diffErr)
+	}()
+	err := a.Outputs.Put(ctx, key, pr)
+	pr.CloseWithError(err)
+	<-done
+	if err == nil {
+		err = diffErr
+	}
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to extract sandbox outputs", map[string]any{"sandbox_id": req.ID, "error": err})
+		a.Metrics.IncCounter("agent_outputs_total", 1, hermes.Label{Key: "result", Value: "failed"})
+		return
+	}
+	output.Key = key
+
+	result := "complete"
+	if output.Truncated {
+		result = "truncated"
+	}
+	a.Logger.Info(ctx, "Extracted sandbox outputs", map[string]any{
+		"sandbox_id": req.ID,
+		"key":        key,
+		"files":      output.Files,
+		"deleted":    output.Deleted,
+		"bytes":      output.Bytes,
+		"truncated":  output.Truncated,
+	})
+	a.Metrics.IncCounter("agent_outputs_total", 1, hermes.Label{Key: "result", Value: result})
+
+	run, err := a.Registry.GetRun(ctx, req.ID)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to link outputs to run", map[string]any{"sandbox_id": req.ID, "key": key, "error": err})
+		return
+	}
+	run.Output = output
+	run.UpdatedAt = time.Now()
+	if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+		a.Logger.Error(ctx, "Failed to link outputs to run", map[string]any{"sandbox_id": req.ID, "key": key, "error": err})
+	}
+}
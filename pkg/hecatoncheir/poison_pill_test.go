@@ -0,0 +1,104 @@
+package hecatoncheir
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cocytus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+type ackQueue struct {
+	mockQueue
+	mu     sync.Mutex
+	acked  bool
+	nacked bool
+}
+
+func (q *ackQueue) Ack(ctx context.Context, receipt string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked = true
+	return nil
+}
+
+func (q *ackQueue) Nack(ctx context.Context, receipt string, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nacked = true
+	return nil
+}
+
+type runRecorder struct {
+	mockRegistry
+	mu   sync.Mutex
+	runs []domain.SandboxRun
+}
+
+func (r *runRecorder) UpdateRun(ctx context.Context, run domain.SandboxRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func TestAgent_Run_PoisonPill(t *testing.T) {
+	for _, known := range []bool{false, true} {
+		req := &domain.SandboxRequest{
+			ID:         "req-fail",
+			Template:   "base",
+			Resources:  domain.ResourceSpec{CPU: 1, Mem: 128},
+			NetworkRef: domain.NetworkPolicyRef{ID: "net-1"},
+		}
+
+		catalog := cocytus.NewMemoryCatalog()
+		sink := cocytus.NewClassifyingSink(nil, catalog, 1)
+		if known {
+			// The same launch failure was already dead-lettered once
+			prior := &cocytus.Record{RequestID: "earlier", Template: "base", Reason: "launch failed"}
+			cocytus.Annotate(prior)
+			catalog.Observe(context.Background(), prior)
+		}
+
+		queue := &ackQueue{mockQueue: mockQueue{req: req}}
+		registry := &runRecorder{}
+		agent := &Agent{
+			Queue:      queue,
+			Nyx:        &mockNyx{},
+			Lethe:      &mockLethe{},
+			Styx:       &mockStyx{},
+			Runtime:    &mockRuntime{},
+			Registry:   registry,
+			Furies:     &mockFury{},
+			DeadLetter: sink,
+			Logger:     &mockLogger{},
+			Metrics:    &mockMetrics{},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		agent.Run(ctx)
+		cancel()
+
+		queue.mu.Lock()
+		acked, nacked := queue.acked, queue.nacked
+		queue.mu.Unlock()
+		registry.mu.Lock()
+		runs := registry.runs
+		registry.mu.Unlock()
+
+		if !known {
+			if !nacked || acked {
+				t.Errorf("first failure: expected re-drive (nack), got acked=%v nacked=%v", acked, nacked)
+			}
+			continue
+		}
+		if !acked || nacked {
+			t.Errorf("known poison pill: expected ack without re-drive, got acked=%v nacked=%v", acked, nacked)
+		}
+		if len(runs) != 1 || runs[0].Status != domain.RunStatusFailed {
+			t.Errorf("expected run marked FAILED, got %+v", runs)
+		}
+	}
+}
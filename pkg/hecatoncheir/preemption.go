@@ -0,0 +1,127 @@
+package hecatoncheir
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// preemptedError is recorded on batch runs stopped for a high-priority
+// request while they wait in the queue again.
+const preemptedError = "preempted by a high-priority sandbox, queued again"
+
+// batchRun is a running batch sandbox that may be preempted.
+type batchRun struct {
+	resources domain.ResourceSpec
+	startedAt time.Time
+	preempted bool
+}
+
+// trackBatch records a launched sandbox if it came from the batch lane.
+func (a *Agent) trackBatch(req *domain.SandboxRequest, startedAt time.Time) {
+	if !a.PreemptBatch || req.Lane() != domain.PriorityBatch {
+		return
+	}
+	a.batchMu.Lock()
+	defer a.batchMu.Unlock()
+	if a.batch == nil {
+		a.batch = make(map[domain.SandboxID]*batchRun)
+	}
+	a.batch[req.ID] = &batchRun{resources: req.Resources, startedAt: startedAt}
+}
+
+// untrackBatch forgets an exited sandbox and reports whether it exited
+// because it was preempted.
+func (a *Agent) untrackBatch(id domain.SandboxID) bool {
+	a.batchMu.Lock()
+	defer a.batchMu.Unlock()
+	run, ok := a.batch[id]
+	delete(a.batch, id)
+	return ok && run.preempted
+}
+
+// preemptFor makes room for a high-priority request that would not fit in
+// the node's capacity next to the sandboxes already running, by killing
+// batch sandboxes, the most recently started first since they lose the
+// least work. Their requests go back to the batch lane once they exit. It
+// returns the number of sandboxes preempted; if killing every batch
+// sandbox would not make room, none is killed.
+func (a *Agent) preemptFor(ctx context.Context, req *domain.SandboxRequest) int {
+	if !a.PreemptBatch || req.Lane() != domain.PriorityHigh {
+		return 0
+	}
+	allocated, err := a.Runtime.Allocation(ctx)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to get allocation for preemption", map[string]any{"id": req.ID, "error": err})
+		return 0
+	}
+	allocated.CPU += req.Resources.CPU
+	allocated.Mem += req.Resources.Mem
+	if a.fits(allocated) {
+		return 0
+	}
+
+	a.batchMu.Lock()
+	var victims []domain.SandboxID
+	for id, run := range a.batch {
+		if !run.preempted {
+			victims = append(victims, id)
+		}
+	}
+	sort.Slice(victims, func(i, j int) bool {
+		return a.batch[victims[i]].startedAt.After(a.batch[victims[j]].startedAt)
+	})
+	n := 0
+	for n < len(victims) && !a.fits(allocated) {
+		run := a.batch[victims[n]]
+		allocated.CPU -= run.resources.CPU
+		allocated.Mem -= run.resources.Mem
+		n++
+	}
+	victims = victims[:n]
+	if !a.fits(allocated) {
+		victims = nil
+	}
+	for _, id := range victims {
+		a.batch[id].preempted = true
+	}
+	a.batchMu.Unlock()
+
+	for _, id := range victims {
+		a.Logger.Info(ctx, "Preempting batch sandbox", map[string]any{"id": id, "for": req.ID})
+		if err := a.Runtime.Kill(ctx, id); err != nil {
+			a.Logger.Error(ctx, "Failed to preempt batch sandbox", map[string]any{"id": id, "error": err})
+			continue
+		}
+		a.Metrics.IncCounter("agent_preemptions_total", 1)
+	}
+	return len(victims)
+}
+
+// fits reports whether the allocation is within the node's capacity. Zero
+// capacities are not enforced.
+func (a *Agent) fits(allocated domain.ResourceCapacity) bool {
+	return (a.Capacity.CPU == 0 || allocated.CPU <= a.Capacity.CPU) &&
+		(a.Capacity.Mem == 0 || allocated.Mem <= a.Capacity.Mem)
+}
+
+// markPreempted turns the final state of a preempted run back into a
+// scheduled one, as its request is queued again.
+func markPreempted(run *domain.SandboxRun) {
+	run.Status = domain.RunStatusScheduled
+	run.Error = preemptedError
+	run.ExitCode = nil
+	run.FinishedAt = time.Time{}
+}
+
+// requeuePreempted hands a preempted batch request back to its lane.
+func (a *Agent) requeuePreempted(ctx context.Context, id domain.SandboxID, receipt string) {
+	if err := a.Queue.Nack(ctx, receipt, "preempted"); err != nil {
+		a.Logger.Error(ctx, "Failed to requeue preempted sandbox", map[string]any{"id": id, "error": err})
+		return
+	}
+	a.Metrics.IncCounter("agent_jobs_requeued_total", 1, hermes.Label{Key: "reason", Value: "preempted"})
+}
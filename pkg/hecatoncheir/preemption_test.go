@@ -0,0 +1,87 @@
+package hecatoncheir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// allocRuntime reports a fixed allocation and records kills.
+type allocRuntime struct {
+	tartarus.SandboxRuntime
+	allocated domain.ResourceCapacity
+	killed    []domain.SandboxID
+}
+
+func (r *allocRuntime) Allocation(ctx context.Context) (domain.ResourceCapacity, error) {
+	return r.allocated, nil
+}
+
+func (r *allocRuntime) Kill(ctx context.Context, id domain.SandboxID) error {
+	r.killed = append(r.killed, id)
+	return nil
+}
+
+func TestAgent_PreemptFor(t *testing.T) {
+	ctx := context.Background()
+	runtime := &allocRuntime{allocated: domain.ResourceCapacity{CPU: 4000, Mem: 4096}}
+	agent := &Agent{
+		Runtime:      runtime,
+		Logger:       hermes.NewSlogAdapter(),
+		Metrics:      hermes.NewNoopMetrics(),
+		Capacity:     domain.ResourceCapacity{CPU: 4000, Mem: 8192},
+		PreemptBatch: true,
+	}
+	start := time.Now()
+	for i, id := range []domain.SandboxID{"batch-old", "batch-new"} {
+		agent.trackBatch(&domain.SandboxRequest{
+			ID:        id,
+			Priority:  domain.PriorityBatch,
+			Resources: domain.ResourceSpec{CPU: 1000, Mem: 1024},
+		}, start.Add(time.Duration(i)*time.Minute))
+	}
+	agent.trackBatch(&domain.SandboxRequest{ID: "normal", Resources: domain.ResourceSpec{CPU: 2000}}, start)
+
+	// Normal requests never preempt
+	if n := agent.preemptFor(ctx, &domain.SandboxRequest{ID: "n", Resources: domain.ResourceSpec{CPU: 1000}}); n != 0 {
+		t.Fatalf("expected no preemption for a normal request, got %d", n)
+	}
+
+	// The most recently started batch sandbox makes room
+	high := &domain.SandboxRequest{ID: "h", Priority: domain.PriorityHigh, Resources: domain.ResourceSpec{CPU: 1000, Mem: 512}}
+	if n := agent.preemptFor(ctx, high); n != 1 {
+		t.Fatalf("expected 1 preemption, got %d", n)
+	}
+	if len(runtime.killed) != 1 || runtime.killed[0] != "batch-new" {
+		t.Fatalf("expected batch-new to be killed, got %v", runtime.killed)
+	}
+	if !agent.untrackBatch("batch-new") {
+		t.Error("expected batch-new to be reported preempted")
+	}
+	if agent.untrackBatch("normal") {
+		t.Error("normal sandboxes are not tracked for preemption")
+	}
+
+	// Nothing is killed if the batch sandboxes cannot make room
+	runtime.killed = nil
+	huge := &domain.SandboxRequest{ID: "h2", Priority: domain.PriorityHigh, Resources: domain.ResourceSpec{CPU: 4000}}
+	if n := agent.preemptFor(ctx, huge); n != 0 || len(runtime.killed) != 0 {
+		t.Fatalf("expected no preemption, got %d killed %v", n, runtime.killed)
+	}
+
+	// A request that fits needs no room
+	runtime.allocated = domain.ResourceCapacity{CPU: 1000}
+	if n := agent.preemptFor(ctx, high); n != 0 {
+		t.Fatalf("expected no preemption when the request fits, got %d", n)
+	}
+
+	run := &domain.SandboxRun{ID: "batch-new", Status: domain.RunStatusFailed}
+	markPreempted(run)
+	if run.Status != domain.RunStatusScheduled || run.Error != preemptedError {
+		t.Errorf("expected a preempted run to be scheduled again, got %s %q", run.Status, run.Error)
+	}
+}
@@ -0,0 +1,72 @@
+package hecatoncheir
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultRestartDrainTimeout bounds how long a RESTART waits for running
+// sandboxes when the command carries no timeout.
+const DefaultRestartDrainTimeout = 10 * time.Minute
+
+// restartPollInterval is how often a draining agent checks for sandboxes.
+var restartPollInterval = 1 * time.Second
+
+// Restarting reports whether the agent is draining for a restart.
+func (a *Agent) Restarting() bool {
+	return a.restarting.Load()
+}
+
+// handleRestart drains the agent for an upgrade: it stops taking requests
+// from the queue, waits up to timeout for running sandboxes to finish and
+// then calls Restart. Requests stay queued for the restarted agent; sandboxes
+// still running at the timeout are left to the shutdown path.
+func (a *Agent) handleRestart(ctx context.Context, timeout time.Duration) {
+	if a.Restart == nil {
+		a.Logger.Info(ctx, "Restart requested but no restart hook is installed", nil)
+		return
+	}
+	if !a.restarting.CompareAndSwap(false, true) {
+		a.Logger.Info(ctx, "Restart already in progress", nil)
+		return
+	}
+
+	a.Logger.Info(ctx, "Draining for restart", map[string]any{"timeout": timeout.String(), "version": Version})
+	a.Metrics.IncCounter("agent_restarts_total", 1)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		active, err := a.activeSandboxes(ctx)
+		if err != nil {
+			a.Logger.Error(ctx, "Failed to list sandboxes while draining", map[string]any{"error": err})
+		} else if active == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			a.Logger.Info(ctx, "Drain timeout reached, restarting with sandboxes still running", map[string]any{"active": active})
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(restartPollInterval):
+		}
+	}
+
+	a.Logger.Info(ctx, "Drained, restarting agent", nil)
+	a.Restart()
+}
+
+func (a *Agent) activeSandboxes(ctx context.Context) (int, error) {
+	runs, err := a.Runtime.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	active := 0
+	for _, run := range runs {
+		if !run.Status.IsTerminal() {
+			active++
+		}
+	}
+	return active, nil
+}
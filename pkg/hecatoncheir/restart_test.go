@@ -0,0 +1,97 @@
+package hecatoncheir
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// listRuntime reports a fixed set of runs that tests can finish.
+type listRuntime struct {
+	tartarus.SandboxRuntime
+	mu   sync.Mutex
+	runs []domain.SandboxRun
+}
+
+func (r *listRuntime) List(ctx context.Context) ([]domain.SandboxRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.SandboxRun(nil), r.runs...), nil
+}
+
+func (r *listRuntime) finishAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.runs {
+		r.runs[i].Status = domain.RunStatusSucceeded
+	}
+}
+
+func TestAgent_Restart_WaitsForSandboxes(t *testing.T) {
+	restartPollInterval = 10 * time.Millisecond
+	defer func() { restartPollInterval = time.Second }()
+
+	runtime := &listRuntime{runs: []domain.SandboxRun{{ID: "sbx-1", Status: domain.RunStatusRunning}}}
+	restarted := make(chan struct{}, 1)
+	agent := &Agent{
+		Runtime: runtime,
+		Logger:  hermes.NewSlogAdapter(),
+		Metrics: hermes.NewNoopMetrics(),
+		Restart: func() { restarted <- struct{}{} },
+	}
+
+	ch := make(chan ControlMessage, 1)
+	ch <- ControlMessage{Type: ControlMessageRestart, SandboxID: "1m0s"}
+	close(ch)
+	agent.controlLoop(context.Background(), ch)
+
+	// Draining stops dequeuing but waits for the running sandbox
+	time.Sleep(50 * time.Millisecond)
+	if !agent.Restarting() {
+		t.Fatal("expected agent to be draining")
+	}
+	select {
+	case <-restarted:
+		t.Fatal("restarted while a sandbox was still running")
+	default:
+	}
+
+	runtime.finishAll()
+	select {
+	case <-restarted:
+	case <-time.After(time.Second):
+		t.Fatal("expected restart once sandboxes finished")
+	}
+}
+
+func TestAgent_Restart_DrainTimeout(t *testing.T) {
+	restartPollInterval = 10 * time.Millisecond
+	defer func() { restartPollInterval = time.Second }()
+
+	runtime := &listRuntime{runs: []domain.SandboxRun{{ID: "sbx-1", Status: domain.RunStatusRunning}}}
+	restarted := make(chan struct{}, 2)
+	agent := &Agent{
+		Runtime: runtime,
+		Logger:  hermes.NewSlogAdapter(),
+		Metrics: hermes.NewNoopMetrics(),
+		Restart: func() { restarted <- struct{}{} },
+	}
+
+	agent.handleRestart(context.Background(), 30*time.Millisecond)
+	select {
+	case <-restarted:
+	default:
+		t.Fatal("expected restart after the drain timeout")
+	}
+
+	// A second RESTART while draining is ignored
+	agent.handleRestart(context.Background(), 0)
+	if len(restarted) != 0 {
+		t.Error("expected repeated restart to be ignored")
+	}
+}
@@ -0,0 +1,56 @@
+package hecatoncheir
+
+import (
+	"context"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erinyes"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// captureResult fills in run.Result for runtimes that do not report one
+// themselves. Such runtimes have a single console, so its tail is reported
+// as stdout.
+func (a *Agent) captureResult(ctx context.Context, run *domain.SandboxRun, startedAt time.Time) {
+	if run.StartedAt.IsZero() {
+		run.StartedAt = startedAt
+	}
+	if run.FinishedAt.IsZero() {
+		run.FinishedAt = time.Now()
+	}
+	if run.Result != nil {
+		return
+	}
+
+	tail := tartarus.NewTailBuffer(a.ResultTailBytes)
+	lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := a.Runtime.StreamLogs(lctx, run.ID, tail, false); err != nil {
+		a.Logger.Error(ctx, "Failed to capture output tail", map[string]any{"run_id": run.ID, "error": err})
+	}
+
+	run.Result = &domain.RunResult{
+		StdoutTail:      tail.String(),
+		OutputTruncated: tail.Truncated(),
+		DurationMs:      run.WallClock().Milliseconds(),
+		PeakMemory:      run.MemoryUsage,
+	}
+}
+
+// attachIntensity adds what the watchdog observed while the sandbox ran to
+// its result, so Olympus can feed it back to Phlegethon.
+func (a *Agent) attachIntensity(run *domain.SandboxRun) {
+	observer, ok := a.Furies.(erinyes.IntensityObserver)
+	if !ok {
+		return
+	}
+	intensity, ok := observer.TakeIntensity(run.ID)
+	if !ok || run.Result == nil {
+		return
+	}
+	if intensity.PeakMemory < run.Result.PeakMemory {
+		intensity.PeakMemory = run.Result.PeakMemory
+	}
+	run.Result.Intensity = intensity
+}
@@ -0,0 +1,58 @@
+package hecatoncheir
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+type consoleRuntime struct {
+	mockRuntime
+	console string
+}
+
+func (r *consoleRuntime) StreamLogs(ctx context.Context, id domain.SandboxID, w io.Writer, follow bool) error {
+	_, err := io.WriteString(w, r.console)
+	return err
+}
+
+func TestAgent_CaptureResult(t *testing.T) {
+	agent := &Agent{
+		Runtime:         &consoleRuntime{console: strings.Repeat("x", 100) + "done\n"},
+		Logger:          &mockLogger{},
+		ResultTailBytes: 16,
+	}
+
+	started := time.Now().Add(-3 * time.Second)
+	run := &domain.SandboxRun{ID: "sbx-1", Status: domain.RunStatusSucceeded, MemoryUsage: 64}
+	agent.captureResult(context.Background(), run, started)
+
+	if run.Result == nil {
+		t.Fatal("expected result")
+	}
+	if got := run.Result.StdoutTail; len(got) != 16 || !strings.HasSuffix(got, "done\n") {
+		t.Errorf("unexpected stdout tail %q", got)
+	}
+	if !run.Result.OutputTruncated {
+		t.Error("expected truncated output")
+	}
+	if run.Result.DurationMs < 3000 {
+		t.Errorf("expected duration of at least 3s, got %dms", run.Result.DurationMs)
+	}
+	if run.Result.PeakMemory != 64 {
+		t.Errorf("expected peak memory 64, got %d", run.Result.PeakMemory)
+	}
+
+	t.Run("KeepsRuntimeResult", func(t *testing.T) {
+		own := &domain.RunResult{StderrTail: "boom"}
+		run := &domain.SandboxRun{ID: "sbx-2", Result: own}
+		agent.captureResult(context.Background(), run, started)
+		if run.Result != own {
+			t.Error("runtime-reported result was replaced")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package hecatoncheir
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/lethe"
+)
+
+var errScriptUnsupported = errors.New("script runs need an artifact cache and a directory overlay (LETHE_BACKEND=overlayfs)")
+
+// unpackScript unpacks a script run's artifact into the sandbox's root
+// filesystem before launch. Only directories and regular files are written,
+// and every path is resolved inside the overlay, so an image whose target
+// directory is a symlink cannot redirect the write to the host.
+func (a *Agent) unpackScript(ctx context.Context, req *domain.SandboxRequest, ov *lethe.Overlay) error {
+	if req.Script == nil {
+		return nil
+	}
+	err := a.unpackScriptArtifact(ctx, req.Script, ov)
+	result := "success"
+	if err != nil {
+		result = "error"
+		a.Logger.Error(ctx, "Failed to unpack script artifact", map[string]any{
+			"sandbox_id": req.ID,
+			"artifact":   req.Script.Artifact,
+			"error":      err,
+		})
+	}
+	a.Metrics.IncCounter("agent_script_unpack_total", 1, hermes.Label{Key: "result", Value: result})
+	return err
+}
+
+func (a *Agent) unpackScriptArtifact(ctx context.Context, script *domain.ScriptSpec, ov *lethe.Overlay) error {
+	if a.Artifacts == nil {
+		return errScriptUnsupported
+	}
+	if info, err := os.Stat(ov.MountPath); err != nil || !info.IsDir() {
+		return errScriptUnsupported
+	}
+	if _, err := a.Artifacts.Fetch(ctx, script.Artifact); err != nil {
+		return err
+	}
+	f, err := os.Open(a.Artifacts.Path(script.Artifact))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	root, err := os.OpenRoot(ov.MountPath)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return untarScript(root, strings.TrimPrefix(script.GuestDir(), "/"), f)
+}
+
+// untarScript extracts a gzipped tarball into dir under root.
+func untarScript(root *os.Root, dir string, r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("script artifact is not a gzipped tarball: %w", err)
+	}
+	defer zr.Close()
+
+	if err := root.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading script artifact: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("illegal path in script artifact: %s", hdr.Name)
+		}
+		target := path.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := root.MkdirAll(path.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := root.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			// Links and devices are skipped
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package hecatoncheir
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func scriptTarball(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+	zw.Close()
+	return &buf
+}
+
+func TestUntarScript(t *testing.T) {
+	dir := t.TempDir()
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	err = untarScript(root, "workspace", scriptTarball(t, map[string]string{
+		"main.py":          "print('hi')",
+		"lib/util.py":      "X = 1",
+		"requirements.txt": "numpy",
+	}))
+	if err != nil {
+		t.Fatalf("untarScript: %v", err)
+	}
+	for name, want := range map[string]string{"main.py": "print('hi')", "lib/util.py": "X = 1"} {
+		data, err := os.ReadFile(filepath.Join(dir, "workspace", name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s: got %q, %v", name, data, err)
+		}
+	}
+
+	if err := untarScript(root, "workspace", scriptTarball(t, map[string]string{"../../evil": "x"})); err == nil {
+		t.Error("expected an error for a path leaving the directory")
+	}
+}
+
+func TestUntarScript_SymlinkedDirStaysInRoot(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	// An image whose workspace points elsewhere on the host
+	if err := os.Symlink(outside, filepath.Join(dir, "workspace")); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	if err := untarScript(root, "workspace", scriptTarball(t, map[string]string{"main.py": "x"})); err == nil {
+		t.Error("expected an error unpacking through a symlink leaving the root")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "main.py")); !os.IsNotExist(err) {
+		t.Errorf("script written outside the overlay: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package hecatoncheir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+)
+
+var errNoSecretProvider = errors.New("no secret provider configured")
+
+// injectSecrets returns the request to launch: a copy of req whose
+// environment also holds the resolved secrets. Resolved values only ever
+// reach the runtime; req itself, which may be dead-lettered or re-queued,
+// keeps the references alone. Each injection is audited by reference, never
+// by value.
+func (a *Agent) injectSecrets(ctx context.Context, req *domain.SandboxRequest) (*domain.SandboxRequest, error) {
+	if len(req.Secrets) == 0 {
+		return req, nil
+	}
+	if a.Secrets == nil {
+		return nil, errNoSecretProvider
+	}
+
+	launch := *req
+	launch.Env = make(map[string]string, len(req.Env)+len(req.Secrets))
+	for k, v := range req.Env {
+		launch.Env[k] = v
+	}
+	refs := make(map[string]string, len(req.Secrets))
+	for _, name := range req.SecretNames() {
+		ref := req.Secrets[name]
+		val, err := a.Secrets.Resolve(ctx, ref)
+		if err != nil {
+			a.Logger.Error(ctx, "Failed to resolve secret", map[string]any{"sandbox_id": req.ID, "key": name, "ref": ref, "error": err})
+			return nil, fmt.Errorf("failed to resolve secret %s: %w", name, err)
+		}
+		launch.Env[name] = val
+		refs[name] = ref
+	}
+
+	a.auditSecrets(ctx, req, refs)
+	a.Metrics.IncCounter("agent_secrets_injected_total", float64(len(refs)))
+	return &launch, nil
+}
+
+// auditSecrets records which secret references were injected into which
+// environment variables of the sandbox.
+func (a *Agent) auditSecrets(ctx context.Context, req *domain.SandboxRequest, refs map[string]string) {
+	if a.Audit == nil {
+		return
+	}
+	record := &judges.AuditRecord{
+		AuditID:         uuid.New().String(),
+		Timestamp:       time.Now().UTC(),
+		SandboxID:       req.ID,
+		TemplateID:      req.Template,
+		Event:           "sandbox_secrets_injected",
+		ComplianceLevel: req.Metadata["compliance_level"],
+		RetentionPolicy: req.Retention,
+		Metadata:        refs,
+	}
+	if s := req.Submitter; s != nil {
+		record.IdentityID = s.ID
+		record.IdentityType = s.Type
+		record.TenantID = s.TenantID
+		record.IdentityRoles = s.Roles
+	}
+	if err := a.Audit.Emit(ctx, record); err != nil {
+		a.Logger.Error(ctx, "Failed to audit secret injection", map[string]any{"sandbox_id": req.ID, "error": err})
+	}
+}
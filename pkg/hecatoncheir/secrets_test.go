@@ -0,0 +1,125 @@
+package hecatoncheir
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+type recordingAuditSink struct {
+	records []*judges.AuditRecord
+}
+
+func (s *recordingAuditSink) Emit(ctx context.Context, record *judges.AuditRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestAgent_InjectSecrets(t *testing.T) {
+	req := &domain.SandboxRequest{
+		ID:        "sb-1",
+		Template:  "base",
+		Env:       map[string]string{"DEBUG": "true"},
+		Secrets:   map[string]string{"API_KEY": "vault:secret/myapp:api_key"},
+		Submitter: &domain.Submitter{ID: "alice", TenantID: "acme"},
+	}
+	audit := &recordingAuditSink{}
+	agent := &Agent{
+		Secrets: &mockSecretProvider{secrets: map[string]string{"vault:secret/myapp:api_key": "s3cr3t"}},
+		Audit:   audit,
+		Logger:  &mockLogger{},
+		Metrics: &mockMetrics{},
+	}
+
+	launch, err := agent.injectSecrets(context.Background(), req)
+	if err != nil {
+		t.Fatalf("injectSecrets: %v", err)
+	}
+	if launch.Env["API_KEY"] != "s3cr3t" || launch.Env["DEBUG"] != "true" {
+		t.Errorf("unexpected launch env %v", launch.Env)
+	}
+	// The original request, which may be dead-lettered, never holds the value
+	if _, ok := req.Env["API_KEY"]; ok {
+		t.Error("secret value leaked into the queued request")
+	}
+
+	if len(audit.records) != 1 {
+		t.Fatalf("expected 1 audit record, got %d", len(audit.records))
+	}
+	record := audit.records[0]
+	if record.Event != "sandbox_secrets_injected" || record.SandboxID != "sb-1" || record.IdentityID != "alice" {
+		t.Errorf("unexpected audit record %+v", record)
+	}
+	if record.Metadata["API_KEY"] != "vault:secret/myapp:api_key" {
+		t.Errorf("expected the secret reference to be audited, got %v", record.Metadata)
+	}
+	for _, v := range record.Metadata {
+		if v == "s3cr3t" {
+			t.Error("secret value leaked into the audit record")
+		}
+	}
+}
+
+func TestAgent_InjectSecrets_Failure(t *testing.T) {
+	req := &domain.SandboxRequest{ID: "sb-1", Secrets: map[string]string{"API_KEY": "vault:missing:key"}}
+	audit := &recordingAuditSink{}
+	agent := &Agent{Audit: audit, Logger: &mockLogger{}, Metrics: &mockMetrics{}}
+
+	if _, err := agent.injectSecrets(context.Background(), req); err == nil {
+		t.Error("expected an error without a secret provider")
+	}
+	agent.Secrets = &mockSecretProvider{}
+	if _, err := agent.injectSecrets(context.Background(), req); err == nil {
+		t.Error("expected an error for an unresolvable secret")
+	}
+	if len(audit.records) != 0 {
+		t.Errorf("expected no audit records, got %d", len(audit.records))
+	}
+
+	// Requests without secrets launch as they are
+	plain := &domain.SandboxRequest{ID: "sb-2"}
+	if launch, err := agent.injectSecrets(context.Background(), plain); err != nil || launch != plain {
+		t.Errorf("injectSecrets(plain) = %v, %v", launch, err)
+	}
+}
+
+func TestAgent_Run_SecretResolutionFailure(t *testing.T) {
+	req := &domain.SandboxRequest{
+		ID:         "req-secrets",
+		Template:   "base",
+		NetworkRef: domain.NetworkPolicyRef{ID: "net-1"},
+		Secrets:    map[string]string{"API_KEY": "vault:missing:key"},
+	}
+	launched := false
+	runtime := &mockRuntime{
+		LaunchFunc: func(ctx context.Context, r *domain.SandboxRequest, cfg tartarus.VMConfig) (*domain.SandboxRun, error) {
+			launched = true
+			return &domain.SandboxRun{ID: r.ID, Status: domain.RunStatusRunning}, nil
+		},
+	}
+	agent := &Agent{
+		Queue:      &mockQueue{req: req},
+		Nyx:        &mockNyx{},
+		Lethe:      &mockLethe{},
+		Styx:       &mockStyx{},
+		Runtime:    runtime,
+		Registry:   &mockRegistry{},
+		Furies:     &mockFury{},
+		DeadLetter: &mockSink{},
+		Logger:     &mockLogger{},
+		Metrics:    &mockMetrics{},
+		Secrets:    &mockSecretProvider{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	agent.Run(ctx)
+
+	if launched {
+		t.Error("sandbox launched without its secrets")
+	}
+}
@@ -0,0 +1,58 @@
+package hecatoncheir
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/nyx"
+)
+
+// snapshotRecord describes a snapshot held on this node for the catalog.
+func (a *Agent) snapshotRecord(snap *nyx.Snapshot, sandboxID domain.SandboxID) domain.SnapshotRecord {
+	return domain.SnapshotRecord{
+		ID:        snap.ID,
+		Template:  snap.Template,
+		SandboxID: sandboxID,
+		NodeID:    a.NodeID,
+		SizeBytes: snapshotSize(snap.Path),
+		CreatedAt: snap.CreatedAt,
+	}
+}
+
+// recordSnapshot adds a snapshot taken of a sandbox to the catalog, if the
+// registry keeps one.
+func (a *Agent) recordSnapshot(ctx context.Context, snap *nyx.Snapshot, sandboxID domain.SandboxID) {
+	catalog, ok := a.Registry.(hades.SnapshotCatalog)
+	if !ok {
+		return
+	}
+	if err := catalog.RecordSnapshot(ctx, a.snapshotRecord(snap, sandboxID)); err != nil {
+		a.Logger.Error(ctx, "Failed to record snapshot in catalog", map[string]any{"snapshot_id": snap.ID, "error": err})
+	}
+}
+
+// recordRestore counts a sandbox launched from the snapshot in the catalog.
+func (a *Agent) recordRestore(ctx context.Context, snap *nyx.Snapshot) {
+	catalog, ok := a.Registry.(hades.SnapshotCatalog)
+	if !ok {
+		return
+	}
+	if err := catalog.RecordRestore(ctx, a.snapshotRecord(snap, ""), time.Now()); err != nil {
+		a.Logger.Error(ctx, "Failed to record snapshot restore", map[string]any{"snapshot_id": snap.ID, "error": err})
+	}
+}
+
+// snapshotSize returns the size of the memory and disk images of the
+// snapshot stored at path.
+func snapshotSize(path string) int64 {
+	var size int64
+	for _, ext := range []string{".mem", ".disk"} {
+		if info, err := os.Stat(path + ext); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
@@ -0,0 +1,21 @@
+package hecatoncheir
+
+import (
+	"context"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// withSwap returns the request to launch with the swap its policy allows.
+// A node that offers no swap (Capacity.Swap) launches swap-disk sandboxes
+// with all their memory in RAM, as Moirai counted them; zram needs no swap
+// from the node. The queued request is not changed.
+func (a *Agent) withSwap(ctx context.Context, req *domain.SandboxRequest) *domain.SandboxRequest {
+	if req.Swap == nil || req.Swap.Zram || a.Capacity.Swap > 0 {
+		return req
+	}
+	a.Logger.Info(ctx, "Node offers no swap, launching with memory in RAM", map[string]any{"id": req.ID})
+	launch := *req
+	launch.Swap = nil
+	return &launch
+}
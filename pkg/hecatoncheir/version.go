@@ -0,0 +1,38 @@
+package hecatoncheir
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// Version is the agent build version reported in heartbeats. It is set at
+// link time with -ldflags "-X github.com/tartarus-sandbox/tartarus/pkg/hecatoncheir.Version=v1.2.3".
+var Version = "dev"
+
+// Commit and BuildDate describe the build and are set at link time like
+// Version. Commit falls back to the VCS revision recorded by the Go toolchain.
+var (
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Build returns the build information reported alongside Version.
+func Build() *domain.AgentBuild {
+	build := &domain.AgentBuild{
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	if build.Commit == "" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range info.Settings {
+				if s.Key == "vcs.revision" {
+					build.Commit = s.Value
+				}
+			}
+		}
+	}
+	return build
+}
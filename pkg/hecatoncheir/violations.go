@@ -0,0 +1,37 @@
+package hecatoncheir
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+var errHypnosDisabled = errors.New("hypnos is not enabled on this node")
+
+// RecordViolation appends an escalation step the Furies took to the run's
+// violation timeline. It is meant as the Furies' ViolationHook.
+func (a *Agent) RecordViolation(ctx context.Context, id domain.SandboxID, event domain.ViolationEvent) {
+	run, err := a.Registry.GetRun(ctx, id)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to record violation", map[string]any{"sandbox_id": id, "error": err})
+		return
+	}
+	run.Violations = append(run.Violations, event)
+	run.UpdatedAt = time.Now()
+	if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+		a.Logger.Error(ctx, "Failed to record violation", map[string]any{"sandbox_id": id, "error": err})
+	}
+}
+
+// HibernateForViolation hibernates a sandbox through Hypnos. It is meant as
+// the Furies' HibernateHook.
+func (a *Agent) HibernateForViolation(ctx context.Context, id domain.SandboxID, reason string) error {
+	if a.Hypnos == nil {
+		return errHypnosDisabled
+	}
+	a.Logger.Info(ctx, "Hibernating sandbox", map[string]any{"sandbox_id": id, "reason": reason})
+	_, err := a.Hypnos.Sleep(ctx, id, nil)
+	return err
+}
@@ -0,0 +1,175 @@
+package hecatoncheir
+
+import (
+	"context"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/lethe"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// warmInstance is a pre-warmed sandbox the agent keeps paused until a
+// request claims it.
+type warmInstance struct {
+	req     *domain.SandboxRequest
+	overlay *lethe.Overlay
+	readyAt time.Time
+}
+
+// poolWarm keeps the sandbox of a pre-warming request paused, booted and
+// attached to its network, for a later request of its template to claim.
+// Its run stays RUNNING with Warm set and its request is acked. It reports
+// whether the sandbox was pooled; if the runtime cannot hand sandboxes
+// over, the sandbox runs like any other.
+func (a *Agent) poolWarm(ctx context.Context, req *domain.SandboxRequest, run *domain.SandboxRun, overlay *lethe.Overlay, receipt string) bool {
+	if !req.Warm() {
+		return false
+	}
+	if _, ok := a.Runtime.(tartarus.Claimer); !ok {
+		return false
+	}
+	if err := a.Runtime.Pause(ctx, run.ID); err != nil {
+		a.Logger.Error(ctx, "Failed to pause pre-warmed sandbox, running it instead", map[string]any{"run_id": run.ID, "error": err})
+		return false
+	}
+
+	now := time.Now()
+	a.warm.Store(run.ID, &warmInstance{req: req, overlay: overlay, readyAt: now})
+	run.Submitter = req.Submitter
+	run.Resources = &req.Resources
+	run.Warm = &domain.RunWarm{State: domain.WarmReady, Network: req.NetworkRef.ID, ReadyAt: now}
+	a.keepUserFields(ctx, run)
+	if err := a.Registry.UpdateRun(ctx, *run); err != nil {
+		a.Logger.Error(ctx, "Failed to record pre-warmed sandbox", map[string]any{"run_id": run.ID, "error": err})
+	}
+	if err := a.Queue.Ack(ctx, receipt); err != nil {
+		a.Logger.Error(ctx, "Failed to ack pre-warming request", map[string]any{"req_id": req.ID, "error": err})
+	}
+
+	a.Logger.Info(ctx, "Sandbox pre-warmed", map[string]any{"run_id": run.ID, "template": req.Template})
+	a.Metrics.IncCounter("agent_warm_pooled_total", 1, hermes.Label{Key: "template", Value: string(req.Template)})
+	a.Metrics.SetGauge("agent_warm_pool_size", float64(a.warmCount()))
+	return true
+}
+
+// claimWarm runs a request in the pre-warmed sandbox Olympus gave it, which
+// skips the snapshot, overlay, network and boot. It reports whether it
+// did; if the sandbox is gone or cannot take the request, the request is
+// launched as usual.
+func (a *Agent) claimWarm(ctx context.Context, req *domain.SandboxRequest, receipt string) bool {
+	if req.WarmInstance == "" {
+		return false
+	}
+	v, ok := a.warm.LoadAndDelete(req.WarmInstance)
+	if !ok {
+		a.Logger.Info(ctx, "Pre-warmed sandbox is gone, launching cold", map[string]any{"id": req.ID, "warm_id": req.WarmInstance})
+		a.Metrics.IncCounter("agent_warm_claims_total", 1, hermes.Label{Key: "result", Value: "missed"})
+		return false
+	}
+	w := v.(*warmInstance)
+	if !req.CanClaim(w.req) {
+		a.warm.Store(req.WarmInstance, w)
+		a.Logger.Info(ctx, "Request cannot run in its pre-warmed sandbox, launching cold", map[string]any{"id": req.ID, "warm_id": req.WarmInstance})
+		a.Metrics.IncCounter("agent_warm_claims_total", 1, hermes.Label{Key: "result", Value: "mismatched"})
+		return false
+	}
+
+	claimer := a.Runtime.(tartarus.Claimer)
+	run, err := claimer.Claim(ctx, req.WarmInstance, req)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to claim pre-warmed sandbox, launching cold", map[string]any{"id": req.ID, "warm_id": req.WarmInstance, "error": err})
+		a.retireWarm(ctx, req.WarmInstance, w, domain.RunStatusFailed, "failed to claim: "+err.Error())
+		a.Metrics.IncCounter("agent_warm_claims_total", 1, hermes.Label{Key: "result", Value: "failed"})
+		return false
+	}
+	a.recordClaimed(ctx, req.WarmInstance, req.ID)
+
+	a.Logger.Info(ctx, "Sandbox launched in pre-warmed sandbox", map[string]any{"run_id": run.ID, "warm_id": req.WarmInstance})
+	a.Metrics.IncCounter("agent_warm_claims_total", 1, hermes.Label{Key: "result", Value: "claimed"})
+	a.Metrics.ObserveHistogram("agent_warm_idle_seconds", time.Since(w.readyAt).Seconds())
+	a.Metrics.IncCounter("agent_jobs_launched_total", 1)
+	a.Metrics.SetGauge("agent_warm_pool_size", float64(a.warmCount()))
+	a.supervise(ctx, req, run, w.overlay, req.WarmInstance, receipt)
+	return true
+}
+
+// recordClaimed marks the run of a claimed pre-warmed sandbox SUCCEEDED,
+// with the request now running in it.
+func (a *Agent) recordClaimed(ctx context.Context, warmID, claimedBy domain.SandboxID) {
+	run, err := a.Registry.GetRun(ctx, warmID)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to get claimed pre-warmed run", map[string]any{"run_id": warmID, "error": err})
+		return
+	}
+	now := time.Now()
+	claimed := *run
+	warm := domain.RunWarm{}
+	if run.Warm != nil {
+		warm = *run.Warm
+	}
+	warm.State = domain.WarmClaimed
+	warm.ClaimedBy = claimedBy
+	warm.ClaimedAt = now
+	claimed.Warm = &warm
+	claimed.Status = domain.RunStatusSucceeded
+	claimed.FinishedAt = now
+	claimed.UpdatedAt = now
+	if err := a.Registry.UpdateRun(ctx, claimed); err != nil {
+		a.Logger.Error(ctx, "Failed to record claimed pre-warmed run", map[string]any{"run_id": warmID, "error": err})
+	}
+}
+
+// releaseWarm tears down a pre-warmed sandbox Olympus asked to kill, e.g.
+// when the season's warm-pool target shrinks. It reports whether id was a
+// pre-warmed sandbox of this node.
+func (a *Agent) releaseWarm(ctx context.Context, id domain.SandboxID) bool {
+	v, ok := a.warm.LoadAndDelete(id)
+	if !ok {
+		return false
+	}
+	a.retireWarm(ctx, id, v.(*warmInstance), domain.RunStatusCanceled, "released from the warm pool")
+	a.Logger.Info(ctx, "Released pre-warmed sandbox", map[string]any{"sandbox_id": id})
+	return true
+}
+
+// retireWarm kills a pre-warmed sandbox taken out of the pool, frees its
+// network and overlay and records its run with status.
+func (a *Agent) retireWarm(ctx context.Context, id domain.SandboxID, w *warmInstance, status domain.RunStatus, reason string) {
+	if err := a.Runtime.Kill(ctx, id); err != nil {
+		a.Logger.Error(ctx, "Failed to kill pre-warmed sandbox", map[string]any{"sandbox_id": id, "error": err})
+	}
+	if err := a.Styx.Detach(ctx, id); err != nil {
+		a.Logger.Error(ctx, "Failed to detach network", map[string]any{"req_id": id, "error": err})
+	}
+	if err := a.Lethe.Destroy(ctx, w.overlay); err != nil {
+		a.Logger.Error(ctx, "Failed to destroy overlay", map[string]any{"overlay_id": w.overlay.ID, "error": err})
+	}
+	a.Metrics.SetGauge("agent_warm_pool_size", float64(a.warmCount()))
+
+	run, err := a.Registry.GetRun(ctx, id)
+	if err != nil {
+		a.Logger.Error(ctx, "Failed to get pre-warmed run", map[string]any{"run_id": id, "error": err})
+		return
+	}
+	now := time.Now()
+	retired := *run
+	retired.Status = status
+	retired.Error = reason
+	retired.FinishedAt = now
+	retired.UpdatedAt = now
+	if err := a.Registry.UpdateRun(ctx, retired); err != nil {
+		a.Logger.Error(ctx, "Failed to record retired pre-warmed run", map[string]any{"run_id": id, "error": err})
+	}
+}
+
+// warmCount is the number of pre-warmed sandboxes the agent keeps.
+func (a *Agent) warmCount() int {
+	n := 0
+	a.warm.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
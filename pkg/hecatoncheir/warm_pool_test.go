@@ -0,0 +1,170 @@
+package hecatoncheir
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/lethe"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+type detachRecorder struct {
+	mockStyx
+	mu       sync.Mutex
+	detached []domain.SandboxID
+}
+
+func (d *detachRecorder) Detach(ctx context.Context, id domain.SandboxID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.detached = append(d.detached, id)
+	return nil
+}
+
+func (d *detachRecorder) has(id domain.SandboxID) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, detached := range d.detached {
+		if detached == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAgent_WarmPool(t *testing.T) {
+	ctx := context.Background()
+	queue := acheron.NewMemoryQueue()
+	registry := hades.NewMemoryRegistry()
+	runtime := tartarus.NewMockRuntime(slog.Default())
+	runtime.SetStartDuration(time.Millisecond)
+	network := &detachRecorder{}
+	agent := &Agent{
+		NodeID:   "node-1",
+		Runtime:  runtime,
+		Lethe:    &mockLethe{},
+		Styx:     network,
+		Furies:   &mockFury{},
+		Queue:    queue,
+		Registry: registry,
+		Logger:   hermes.NewSlogAdapter(),
+		Metrics:  hermes.NewNoopMetrics(),
+	}
+	submit := func(req *domain.SandboxRequest) string {
+		t.Helper()
+		if err := registry.UpdateRun(ctx, domain.SandboxRun{ID: req.ID, NodeID: "node-1", Template: req.Template, Status: domain.RunStatusScheduled, Metadata: req.Metadata}); err != nil {
+			t.Fatal(err)
+		}
+		if err := queue.Enqueue(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		_, receipt, err := queue.Dequeue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return receipt
+	}
+	prewarm := func(id domain.SandboxID) {
+		t.Helper()
+		req := &domain.SandboxRequest{
+			ID:         id,
+			Template:   "tpl",
+			Command:    []string{"/bin/sh", "-c", "sleep 3600"},
+			Resources:  domain.ResourceSpec{CPU: 1000, Mem: 512},
+			NetworkRef: domain.NetworkPolicyRef{ID: "lockdown-no-net"},
+			Metadata:   map[string]string{domain.MetadataWarm: "true"},
+		}
+		receipt := submit(req)
+		run, err := runtime.Launch(ctx, req, tartarus.VMConfig{CPUs: 1, MemoryMB: 512})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !agent.poolWarm(ctx, req, run, &lethe.Overlay{ID: "ov-" + string(id)}, receipt) {
+			t.Fatalf("expected %s to be pooled", id)
+		}
+	}
+
+	// A pre-warming request's sandbox is kept paused and its request acked
+	prewarm("warm-1")
+	if n := queue.Len(ctx); n != 0 {
+		t.Errorf("expected the pre-warming request to be acked, %d left", n)
+	}
+	run, err := registry.GetRun(ctx, "warm-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != domain.RunStatusRunning || run.Warm == nil || run.Warm.State != domain.WarmReady || run.Metadata[domain.MetadataWarm] != "true" {
+		t.Fatalf("expected a ready pre-warmed run, got %s with %+v", run.Status, run.Warm)
+	}
+
+	// Other requests are not pooled
+	if agent.poolWarm(ctx, &domain.SandboxRequest{ID: "plain"}, &domain.SandboxRun{ID: "plain"}, nil, "") {
+		t.Error("expected a plain request not to be pooled")
+	}
+
+	// A request given the sandbox runs in it
+	req := &domain.SandboxRequest{
+		ID:           "req-1",
+		Template:     "tpl",
+		Command:      []string{"echo", "hi"},
+		Resources:    domain.ResourceSpec{CPU: 500, Mem: 256},
+		NetworkRef:   domain.NetworkPolicyRef{ID: "lockdown-no-net"},
+		WarmInstance: "warm-1",
+	}
+	if !agent.claimWarm(ctx, req, submit(req)) {
+		t.Fatal("expected the request to claim the pre-warmed sandbox")
+	}
+	if _, err := runtime.Inspect(ctx, "req-1"); err != nil {
+		t.Errorf("expected the runtime to track the sandbox as the request: %v", err)
+	}
+	run, _ = registry.GetRun(ctx, "warm-1")
+	if run.Status != domain.RunStatusSucceeded || run.Warm.State != domain.WarmClaimed || run.Warm.ClaimedBy != "req-1" {
+		t.Fatalf("expected the pre-warmed run to be claimed by req-1, got %s with %+v", run.Status, run.Warm)
+	}
+	run, _ = registry.GetRun(ctx, "req-1")
+	if run.Status != domain.RunStatusRunning {
+		t.Errorf("expected the request to run, got %s", run.Status)
+	}
+
+	// A second request for the same sandbox launches cold
+	again := *req
+	again.ID = "req-2"
+	if agent.claimWarm(ctx, &again, "") {
+		t.Error("expected a claimed sandbox not to be claimed again")
+	}
+
+	// Once the command exits the sandbox is cleaned up under its original network
+	if err := runtime.Shutdown(ctx, "req-1"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !network.has("warm-1") || queue.Len(ctx) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the claimed sandbox to be cleaned up and its request acked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Olympus shrinking the pool releases a sandbox
+	prewarm("warm-2")
+	if !agent.releaseWarm(ctx, "warm-2") {
+		t.Fatal("expected warm-2 to be released")
+	}
+	if _, err := runtime.Inspect(ctx, "warm-2"); err == nil {
+		t.Error("expected the released sandbox to be killed")
+	}
+	run, _ = registry.GetRun(ctx, "warm-2")
+	if run.Status != domain.RunStatusCanceled {
+		t.Errorf("expected the released run to be canceled, got %s", run.Status)
+	}
+	if agent.releaseWarm(ctx, "req-1") {
+		t.Error("expected only pre-warmed sandboxes to be released")
+	}
+}
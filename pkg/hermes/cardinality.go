@@ -0,0 +1,198 @@
+package hermes
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultAllowedLabels are the label keys with bounded value sets used
+// across Tartarus. Any other key is stripped by the CardinalityGuard.
+var DefaultAllowedLabels = []string{
+	"action", "class", "config", "heat_level", "image", "node_group",
+	"operation", "phase", "queue", "reason", "region", "resource_type",
+	"result", "reused", "route", "route_class", "runtime", "scenario",
+	"season", "season_id", "season_name", "selected_runtime", "shore_id",
+	"slice", "source", "span", "state", "status", "tag", "template", "topic",
+	"type", "user_metric", "version",
+}
+
+// DefaultHashedLabels are label keys whose values are unbounded (sandbox IDs,
+// client keys) and are folded into a fixed number of hash buckets.
+var DefaultHashedLabels = []string{"sandbox_id", "key"}
+
+const (
+	defaultHashBuckets        = 32
+	defaultMaxSeriesPerMetric = 1000
+
+	// DroppedSeriesMetric counts labels and series discarded by the guard.
+	DroppedSeriesMetric = "hermes_dropped_series_total"
+
+	dropReasonLabel       = "label_not_allowed"
+	dropReasonSeriesLimit = "series_limit"
+)
+
+// CardinalityConfig configures a CardinalityGuard.
+type CardinalityConfig struct {
+	AllowedLabels      []string // Label keys passed through verbatim (default DefaultAllowedLabels)
+	HashedLabels       []string // Label keys whose values are hashed into buckets (default DefaultHashedLabels)
+	HashBuckets        int      // Number of buckets for hashed values (default 32)
+	MaxSeriesPerMetric int      // New series beyond this are dropped (default 1000, negative = unlimited)
+}
+
+// CardinalityGuard wraps a Metrics implementation and keeps the number of
+// series it creates bounded. Labels outside the allow-list are stripped,
+// hashed labels are bucketed, and once a metric reaches MaxSeriesPerMetric
+// further series are dropped. Drops are reported on the wrapped Metrics as
+// hermes_dropped_series_total{metric, reason}.
+type CardinalityGuard struct {
+	next    Metrics
+	allowed map[string]bool
+	hashed  map[string]bool
+	buckets uint32
+	max     int
+
+	mu      sync.Mutex
+	series  map[string]map[string]struct{}
+	dropped map[string]int64
+}
+
+// NewCardinalityGuard wraps next with the given limits.
+func NewCardinalityGuard(next Metrics, cfg CardinalityConfig) *CardinalityGuard {
+	if cfg.AllowedLabels == nil {
+		cfg.AllowedLabels = DefaultAllowedLabels
+	}
+	if cfg.HashedLabels == nil {
+		cfg.HashedLabels = DefaultHashedLabels
+	}
+	if cfg.HashBuckets <= 0 {
+		cfg.HashBuckets = defaultHashBuckets
+	}
+	if cfg.MaxSeriesPerMetric == 0 {
+		cfg.MaxSeriesPerMetric = defaultMaxSeriesPerMetric
+	}
+
+	g := &CardinalityGuard{
+		next:    next,
+		allowed: make(map[string]bool, len(cfg.AllowedLabels)),
+		hashed:  make(map[string]bool, len(cfg.HashedLabels)),
+		buckets: uint32(cfg.HashBuckets),
+		max:     cfg.MaxSeriesPerMetric,
+		series:  make(map[string]map[string]struct{}),
+		dropped: make(map[string]int64),
+	}
+	for _, k := range cfg.AllowedLabels {
+		g.allowed[k] = true
+	}
+	for _, k := range cfg.HashedLabels {
+		g.hashed[k] = true
+	}
+	return g
+}
+
+func (g *CardinalityGuard) IncCounter(name string, value float64, labels ...Label) {
+	if labels, ok := g.admit(name, labels); ok {
+		g.next.IncCounter(name, value, labels...)
+	}
+}
+
+func (g *CardinalityGuard) ObserveHistogram(name string, value float64, labels ...Label) {
+	if labels, ok := g.admit(name, labels); ok {
+		g.next.ObserveHistogram(name, value, labels...)
+	}
+}
+
+func (g *CardinalityGuard) SetGauge(name string, value float64, labels ...Label) {
+	if labels, ok := g.admit(name, labels); ok {
+		g.next.SetGauge(name, value, labels...)
+	}
+}
+
+// ShapeHistogram passes the shape on to the wrapped Metrics, if it takes one.
+func (g *CardinalityGuard) ShapeHistogram(name string, opts HistogramOpts) {
+	if shaper, ok := g.next.(HistogramShaper); ok {
+		shaper.ShapeHistogram(name, opts)
+	}
+}
+
+// DroppedSeries returns the number of drops per "metric/reason".
+func (g *CardinalityGuard) DroppedSeries() map[string]int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make(map[string]int64, len(g.dropped))
+	for k, v := range g.dropped {
+		out[k] = v
+	}
+	return out
+}
+
+// admit rewrites labels and reports whether the resulting series may be
+// emitted.
+func (g *CardinalityGuard) admit(name string, labels []Label) ([]Label, bool) {
+	if name == DroppedSeriesMetric {
+		return labels, true
+	}
+
+	kept := make([]Label, 0, len(labels))
+	stripped := false
+	for _, l := range labels {
+		switch {
+		case g.hashed[l.Key]:
+			kept = append(kept, Label{Key: l.Key, Value: g.bucket(l.Value)})
+		case g.allowed[l.Key]:
+			kept = append(kept, l)
+		default:
+			stripped = true
+		}
+	}
+	if stripped {
+		g.drop(name, dropReasonLabel)
+	}
+
+	if g.max < 0 {
+		return kept, true
+	}
+
+	id := seriesID(kept)
+	g.mu.Lock()
+	known, ok := g.series[name]
+	if !ok {
+		known = make(map[string]struct{})
+		g.series[name] = known
+	}
+	_, exists := known[id]
+	admitted := exists || len(known) < g.max
+	if admitted && !exists {
+		known[id] = struct{}{}
+	}
+	g.mu.Unlock()
+
+	if !admitted {
+		g.drop(name, dropReasonSeriesLimit)
+	}
+	return kept, admitted
+}
+
+func (g *CardinalityGuard) bucket(value string) string {
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return fmt.Sprintf("bucket-%02d", h.Sum32()%g.buckets)
+}
+
+func (g *CardinalityGuard) drop(name, reason string) {
+	g.mu.Lock()
+	g.dropped[name+"/"+reason]++
+	g.mu.Unlock()
+	g.next.IncCounter(DroppedSeriesMetric, 1, Label{Key: "metric", Value: name}, Label{Key: "reason", Value: reason})
+}
+
+func seriesID(labels []Label) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\x00")
+}
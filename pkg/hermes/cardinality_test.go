@@ -0,0 +1,92 @@
+package hermes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedCall struct {
+	name   string
+	labels []Label
+}
+
+type recordingMetrics struct {
+	calls []recordedCall
+}
+
+func (m *recordingMetrics) IncCounter(name string, value float64, labels ...Label) {
+	m.calls = append(m.calls, recordedCall{name, labels})
+}
+
+func (m *recordingMetrics) ObserveHistogram(name string, value float64, labels ...Label) {
+	m.calls = append(m.calls, recordedCall{name, labels})
+}
+
+func (m *recordingMetrics) SetGauge(name string, value float64, labels ...Label) {
+	m.calls = append(m.calls, recordedCall{name, labels})
+}
+
+func (m *recordingMetrics) named(name string) []recordedCall {
+	var out []recordedCall
+	for _, c := range m.calls {
+		if c.name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func TestCardinalityGuard_StripsAndHashesLabels(t *testing.T) {
+	rec := &recordingMetrics{}
+	g := NewCardinalityGuard(rec, CardinalityConfig{HashBuckets: 4})
+
+	g.ObserveHistogram("wake_seconds", 1,
+		Label{Key: "phase", Value: "wake"},
+		Label{Key: "iteration", Value: "17"},
+		Label{Key: "sandbox_id", Value: "sbx-123"},
+	)
+
+	calls := rec.named("wake_seconds")
+	require.Len(t, calls, 1)
+	require.Len(t, calls[0].labels, 2)
+	assert.Equal(t, Label{Key: "phase", Value: "wake"}, calls[0].labels[0])
+	assert.Equal(t, "sandbox_id", calls[0].labels[1].Key)
+	assert.Regexp(t, `^bucket-0[0-3]$`, calls[0].labels[1].Value)
+
+	// Hashing is stable
+	g.ObserveHistogram("wake_seconds", 1, Label{Key: "phase", Value: "wake"}, Label{Key: "sandbox_id", Value: "sbx-123"})
+	assert.Equal(t, calls[0].labels[1], rec.named("wake_seconds")[1].labels[1])
+
+	assert.Equal(t, int64(1), g.DroppedSeries()["wake_seconds/label_not_allowed"])
+	dropped := rec.named(DroppedSeriesMetric)
+	require.Len(t, dropped, 1)
+	assert.Equal(t, []Label{{Key: "metric", Value: "wake_seconds"}, {Key: "reason", Value: "label_not_allowed"}}, dropped[0].labels)
+}
+
+func TestCardinalityGuard_SeriesLimit(t *testing.T) {
+	rec := &recordingMetrics{}
+	g := NewCardinalityGuard(rec, CardinalityConfig{AllowedLabels: []string{"image"}, MaxSeriesPerMetric: 3})
+
+	for i := 0; i < 5; i++ {
+		g.IncCounter("pulls_total", 1, Label{Key: "image", Value: fmt.Sprintf("img-%d", i)})
+	}
+	// Existing series keep being recorded
+	g.IncCounter("pulls_total", 1, Label{Key: "image", Value: "img-0"})
+
+	assert.Len(t, rec.named("pulls_total"), 4)
+	assert.Equal(t, int64(2), g.DroppedSeries()["pulls_total/series_limit"])
+	assert.Len(t, rec.named(DroppedSeriesMetric), 2)
+
+	t.Run("Unlimited", func(t *testing.T) {
+		rec := &recordingMetrics{}
+		g := NewCardinalityGuard(rec, CardinalityConfig{AllowedLabels: []string{"image"}, MaxSeriesPerMetric: -1})
+		for i := 0; i < 5; i++ {
+			g.IncCounter("pulls_total", 1, Label{Key: "image", Value: fmt.Sprintf("img-%d", i)})
+		}
+		assert.Len(t, rec.named("pulls_total"), 5)
+		assert.Empty(t, g.DroppedSeries())
+	})
+}
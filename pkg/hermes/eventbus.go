@@ -0,0 +1,262 @@
+package hermes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxDeliveries is how many times a failing handler is given an event
+// before it is dropped.
+const DefaultMaxDeliveries = 3
+
+// ErrEventBusClosed is returned by a closed event bus.
+var ErrEventBusClosed = errors.New("event bus closed")
+
+// Topic names a stream of events.
+type Topic string
+
+// Event is a message on the event bus. Data carries the JSON payload; use a
+// TypedTopic to encode and decode it.
+type Event struct {
+	ID         string            `json:"id"`
+	Topic      Topic             `json:"topic"`
+	Type       string            `json:"type"`              // What happened, e.g. "sandbox.killed"
+	Subject    string            `json:"subject,omitempty"` // What it happened to, e.g. a sandbox ID
+	Time       time.Time         `json:"time"`
+	Data       json.RawMessage   `json:"data,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// EventHandler processes one event. Returning an error has the event
+// redelivered, up to the bus's maximum number of deliveries.
+type EventHandler func(ctx context.Context, ev Event) error
+
+// Subscription is a registered handler. Close stops delivery to it.
+type Subscription interface {
+	Close() error
+}
+
+// EventBus is the internal publish/subscribe transport shared by control
+// plane notifications, webhooks, audit alerts and lifecycle events.
+//
+// Subscribers in the same consumer group share the events of a topic, each
+// event going to one of them; every group receives every event. An empty
+// group subscribes on its own, so it receives every event published while
+// it is subscribed. Delivery is at least once, so handlers must tolerate
+// duplicates.
+type EventBus interface {
+	Publish(ctx context.Context, ev Event) error
+	Subscribe(ctx context.Context, topic Topic, group string, handler EventHandler) (Subscription, error)
+	Close() error
+}
+
+// TypedTopic is a topic whose event data is always a T.
+type TypedTopic[T any] struct {
+	Name Topic
+}
+
+// NewTypedTopic returns the typed topic called name.
+func NewTypedTopic[T any](name Topic) TypedTopic[T] {
+	return TypedTopic[T]{Name: name}
+}
+
+// Publish encodes payload and publishes it as an event of the given type.
+func (t TypedTopic[T]) Publish(ctx context.Context, bus EventBus, eventType, subject string, payload T) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", t.Name, err)
+	}
+	return bus.Publish(ctx, Event{Topic: t.Name, Type: eventType, Subject: subject, Data: data})
+}
+
+// Subscribe registers handler for the topic's events, decoding their data.
+// Events whose data cannot be decoded are dropped without redelivery.
+func (t TypedTopic[T]) Subscribe(ctx context.Context, bus EventBus, group string, handler func(ctx context.Context, ev Event, payload T) error) (Subscription, error) {
+	return bus.Subscribe(ctx, t.Name, group, func(ctx context.Context, ev Event) error {
+		var payload T
+		if len(ev.Data) > 0 {
+			if err := json.Unmarshal(ev.Data, &payload); err != nil {
+				return nil
+			}
+		}
+		return handler(ctx, ev, payload)
+	})
+}
+
+// stamp fills in the ID and time of an event about to be published.
+func stamp(ev *Event) error {
+	if ev.Topic == "" {
+		return errors.New("event has no topic")
+	}
+	if ev.ID == "" {
+		ev.ID = uuid.New().String()
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	return nil
+}
+
+// deliver runs handler until it succeeds or maxDeliveries attempts failed,
+// and records the outcome.
+func deliver(ctx context.Context, metrics Metrics, maxDeliveries int, handler EventHandler, ev Event) bool {
+	for attempt := 1; attempt <= maxDeliveries; attempt++ {
+		if err := handler(ctx, ev); err == nil {
+			metrics.IncCounter("hermes_events_delivered_total", 1, Label{Key: "topic", Value: string(ev.Topic)}, Label{Key: "result", Value: "success"})
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+	metrics.IncCounter("hermes_events_delivered_total", 1, Label{Key: "topic", Value: string(ev.Topic)}, Label{Key: "result", Value: "dropped"})
+	return false
+}
+
+// MemoryEventBus is an in-process EventBus. Events are only delivered to
+// groups subscribed when they are published, and are lost on restart.
+type MemoryEventBus struct {
+	// MaxDeliveries bounds handler attempts per event (DefaultMaxDeliveries if zero).
+	MaxDeliveries int
+	// Buffer is the number of undelivered events a group holds before
+	// Publish blocks (1024 if zero).
+	Buffer int
+
+	metrics Metrics
+	mu      sync.Mutex
+	groups  map[Topic]map[string]*memoryGroup
+	closed  bool
+	done    chan struct{}
+}
+
+type memoryGroup struct {
+	events  chan Event
+	members int
+}
+
+// subscription is a handler loop that runs until cancelled.
+type subscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (s *subscription) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// NewMemoryEventBus creates an in-process event bus.
+func NewMemoryEventBus(metrics Metrics) *MemoryEventBus {
+	if metrics == nil {
+		metrics = NewNoopMetrics()
+	}
+	return &MemoryEventBus{
+		metrics: metrics,
+		groups:  make(map[Topic]map[string]*memoryGroup),
+		done:    make(chan struct{}),
+	}
+}
+
+// Publish hands the event to every group subscribed to its topic.
+func (b *MemoryEventBus) Publish(ctx context.Context, ev Event) error {
+	if err := stamp(&ev); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrEventBusClosed
+	}
+	targets := make([]*memoryGroup, 0, len(b.groups[ev.Topic]))
+	for _, g := range b.groups[ev.Topic] {
+		targets = append(targets, g)
+	}
+	b.mu.Unlock()
+
+	for _, g := range targets {
+		select {
+		case g.events <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.done:
+			return ErrEventBusClosed
+		}
+	}
+	b.metrics.IncCounter("hermes_events_published_total", 1, Label{Key: "topic", Value: string(ev.Topic)})
+	return nil
+}
+
+// Subscribe starts delivering the topic's events to handler until the
+// subscription is closed or ctx is cancelled.
+func (b *MemoryEventBus) Subscribe(ctx context.Context, topic Topic, group string, handler EventHandler) (Subscription, error) {
+	if group == "" {
+		group = "private-" + uuid.New().String()
+	}
+	buffer := b.Buffer
+	if buffer <= 0 {
+		buffer = 1024
+	}
+	maxDeliveries := b.MaxDeliveries
+	if maxDeliveries <= 0 {
+		maxDeliveries = DefaultMaxDeliveries
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrEventBusClosed
+	}
+	if b.groups[topic] == nil {
+		b.groups[topic] = make(map[string]*memoryGroup)
+	}
+	g := b.groups[topic][group]
+	if g == nil {
+		g = &memoryGroup{events: make(chan Event, buffer)}
+		b.groups[topic][group] = g
+	}
+	g.members++
+	b.mu.Unlock()
+
+	subCtx, cancel := context.WithCancel(ctx)
+	sub := &subscription{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(sub.done)
+		defer func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if g.members--; g.members == 0 {
+				delete(b.groups[topic], group)
+			}
+		}()
+		for {
+			select {
+			case <-subCtx.Done():
+				return
+			case <-b.done:
+				return
+			case ev := <-g.events:
+				deliver(subCtx, b.metrics, maxDeliveries, handler, ev)
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Close stops accepting events and ends every subscription.
+func (b *MemoryEventBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package hermes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultEventStreamPrefix prefixes the Redis stream of each topic.
+const DefaultEventStreamPrefix = "tartarus:events:"
+
+// RedisEventBus is an EventBus on Redis streams, one stream per topic.
+// Consumer groups map to Redis consumer groups, so events published while a
+// group has no running subscriber wait for it, and events left unacknowledged
+// by a subscriber that died are claimed by another member of its group.
+type RedisEventBus struct {
+	StreamPrefix  string        // Stream key prefix (DefaultEventStreamPrefix if empty)
+	MaxLen        int64         // Approximate number of events kept per topic (10000 if zero)
+	MaxDeliveries int           // Handler attempts per event (DefaultMaxDeliveries if zero)
+	ClaimIdle     time.Duration // Age at which another consumer's pending events are claimed (1m if zero)
+	Block         time.Duration // Longest wait for new events per read (1s if zero)
+
+	client   *redis.Client
+	consumer string
+	metrics  Metrics
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewRedisEventBus connects to Redis. consumer names this process within
+// consumer groups, e.g. its hostname.
+func NewRedisEventBus(addr string, db int, password string, consumer string, metrics Metrics) (*RedisEventBus, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		DB:       db,
+		Password: password,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	if metrics == nil {
+		metrics = NewNoopMetrics()
+	}
+	if consumer == "" {
+		consumer = "consumer"
+	}
+	busCtx, busCancel := context.WithCancel(context.Background())
+	return &RedisEventBus{
+		StreamPrefix:  DefaultEventStreamPrefix,
+		MaxLen:        10000,
+		MaxDeliveries: DefaultMaxDeliveries,
+		ClaimIdle:     time.Minute,
+		Block:         time.Second,
+		client:        client,
+		consumer:      consumer,
+		metrics:       metrics,
+		ctx:           busCtx,
+		cancel:        busCancel,
+	}, nil
+}
+
+func (b *RedisEventBus) streamKey(topic Topic) string {
+	prefix := b.StreamPrefix
+	if prefix == "" {
+		prefix = DefaultEventStreamPrefix
+	}
+	return prefix + string(topic)
+}
+
+// Publish appends the event to its topic's stream.
+func (b *RedisEventBus) Publish(ctx context.Context, ev Event) error {
+	if b.ctx.Err() != nil {
+		return ErrEventBusClosed
+	}
+	if err := stamp(&ev); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	maxLen := b.MaxLen
+	if maxLen <= 0 {
+		maxLen = 10000
+	}
+	if err := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.streamKey(ev.Topic),
+		MaxLen: maxLen,
+		Approx: true,
+		Values: map[string]any{"event": data},
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	b.metrics.IncCounter("hermes_events_published_total", 1, Label{Key: "topic", Value: string(ev.Topic)})
+	return nil
+}
+
+// Subscribe starts delivering the topic's events to handler until the
+// subscription is closed, ctx is cancelled or the bus is closed. A new
+// group starts with the events published after it was created.
+func (b *RedisEventBus) Subscribe(ctx context.Context, topic Topic, group string, handler EventHandler) (Subscription, error) {
+	if b.ctx.Err() != nil {
+		return nil, ErrEventBusClosed
+	}
+	key := b.streamKey(topic)
+
+	var loop func(ctx context.Context)
+	if group == "" {
+		// Start after the newest event, so nothing published from here on is missed
+		last := "0-0"
+		msgs, err := b.client.XRevRangeN(ctx, key, "+", "-", 1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream %s: %w", key, err)
+		}
+		if len(msgs) > 0 {
+			last = msgs[0].ID
+		}
+		loop = func(ctx context.Context) { b.readPrivate(ctx, key, last, handler) }
+	} else {
+		err := b.client.XGroupCreateMkStream(ctx, key, group, "$").Err()
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+			return nil, fmt.Errorf("failed to create consumer group %s: %w", group, err)
+		}
+		consumer := b.consumer + "-" + uuid.New().String()[:8]
+		loop = func(ctx context.Context) { b.readGroup(ctx, key, group, consumer, handler) }
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(b.ctx, cancel)
+	sub := &subscription{cancel: cancel, done: make(chan struct{})}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer close(sub.done)
+		defer stop()
+		loop(subCtx)
+	}()
+	return sub, nil
+}
+
+// readPrivate delivers every event after last.
+func (b *RedisEventBus) readPrivate(ctx context.Context, key, last string, handler EventHandler) {
+	for ctx.Err() == nil {
+		res, err := b.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{key, last},
+			Count:   16,
+			Block:   b.block(),
+		}).Result()
+		if err != nil {
+			b.backoff(ctx, err)
+			continue
+		}
+		for _, stream := range res {
+			for _, msg := range stream.Messages {
+				last = msg.ID
+				if ev, ok := b.decode(msg); ok {
+					deliver(ctx, b.metrics, b.maxDeliveries(), handler, ev)
+				}
+			}
+		}
+	}
+}
+
+// readGroup delivers the group's share of events, first taking over events
+// another consumer of the group left pending for longer than ClaimIdle.
+func (b *RedisEventBus) readGroup(ctx context.Context, key, group, consumer string, handler EventHandler) {
+	claimIdle := b.ClaimIdle
+	if claimIdle <= 0 {
+		claimIdle = time.Minute
+	}
+	handle := func(msgs []redis.XMessage) {
+		for _, msg := range msgs {
+			ev, ok := b.decode(msg)
+			if ok {
+				deliver(ctx, b.metrics, b.maxDeliveries(), handler, ev)
+			}
+			if ctx.Err() != nil {
+				// Left pending for the group to claim
+				return
+			}
+			if err := b.client.XAck(ctx, key, group, msg.ID).Err(); err != nil {
+				b.backoff(ctx, err)
+			}
+		}
+	}
+
+	for ctx.Err() == nil {
+		claimed, _, err := b.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   key,
+			Group:    group,
+			Consumer: consumer,
+			MinIdle:  claimIdle,
+			Start:    "0-0",
+			Count:    16,
+		}).Result()
+		if err == nil {
+			handle(claimed)
+		}
+
+		res, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  []string{key, ">"},
+			Count:    16,
+			Block:    b.block(),
+		}).Result()
+		if err != nil {
+			b.backoff(ctx, err)
+			continue
+		}
+		for _, stream := range res {
+			handle(stream.Messages)
+		}
+	}
+}
+
+// decode parses a stream entry. Entries that are not events are dropped.
+func (b *RedisEventBus) decode(msg redis.XMessage) (Event, bool) {
+	var ev Event
+	data, ok := msg.Values["event"].(string)
+	if !ok || json.Unmarshal([]byte(data), &ev) != nil {
+		b.metrics.IncCounter("hermes_events_delivered_total", 1, Label{Key: "topic", Value: "unknown"}, Label{Key: "result", Value: "invalid"})
+		return Event{}, false
+	}
+	return ev, true
+}
+
+// backoff waits before retrying a failed read, unless it only timed out.
+func (b *RedisEventBus) backoff(ctx context.Context, err error) {
+	if err == redis.Nil || ctx.Err() != nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+	}
+}
+
+func (b *RedisEventBus) block() time.Duration {
+	if b.Block <= 0 {
+		return time.Second
+	}
+	return b.Block
+}
+
+func (b *RedisEventBus) maxDeliveries() int {
+	if b.MaxDeliveries <= 0 {
+		return DefaultMaxDeliveries
+	}
+	return b.MaxDeliveries
+}
+
+// Close ends every subscription and disconnects from Redis.
+func (b *RedisEventBus) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	return b.client.Close()
+}
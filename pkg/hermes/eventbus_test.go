@@ -0,0 +1,182 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector records the subjects of the events it handles.
+type collector struct {
+	mu       sync.Mutex
+	subjects []string
+}
+
+func (c *collector) handle(ctx context.Context, ev Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subjects = append(c.subjects, ev.Subject)
+	return nil
+}
+
+func (c *collector) got() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.subjects...)
+}
+
+func newRedisBus(t *testing.T) (*RedisEventBus, *miniredis.Miniredis) {
+	s := miniredis.RunT(t)
+	bus, err := NewRedisEventBus(s.Addr(), 0, "", "test", nil)
+	require.NoError(t, err)
+	bus.Block = 10 * time.Millisecond
+	t.Cleanup(func() { bus.Close() })
+	return bus, s
+}
+
+func eventBuses(t *testing.T) map[string]EventBus {
+	memory := NewMemoryEventBus(nil)
+	t.Cleanup(func() { memory.Close() })
+	redisBus, _ := newRedisBus(t)
+	return map[string]EventBus{"memory": memory, "redis": redisBus}
+}
+
+func TestEventBus_GroupsShareAndFanOut(t *testing.T) {
+	for name, bus := range eventBuses(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var workerA, workerB, audit, private collector
+			for _, s := range []struct {
+				group string
+				c     *collector
+			}{{"webhooks", &workerA}, {"webhooks", &workerB}, {"audit", &audit}, {"", &private}} {
+				sub, err := bus.Subscribe(ctx, "lifecycle", s.group, s.c.handle)
+				require.NoError(t, err)
+				defer sub.Close()
+			}
+
+			want := []string{"sb-1", "sb-2", "sb-3", "sb-4"}
+			for _, subject := range want {
+				require.NoError(t, bus.Publish(ctx, Event{Topic: "lifecycle", Type: "sandbox.started", Subject: subject}))
+			}
+			require.NoError(t, bus.Publish(ctx, Event{Topic: "other", Subject: "ignored"}))
+
+			assert.Eventually(t, func() bool {
+				return len(audit.got()) == 4 && len(private.got()) == 4 && len(workerA.got())+len(workerB.got()) == 4
+			}, 2*time.Second, 10*time.Millisecond)
+			assert.Equal(t, want, audit.got())
+			assert.Equal(t, want, private.got())
+			assert.ElementsMatch(t, want, append(workerA.got(), workerB.got()...), "each event goes to one group member")
+		})
+	}
+}
+
+func TestEventBus_RedeliversThenDrops(t *testing.T) {
+	for name, bus := range eventBuses(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var mu sync.Mutex
+			attempts := map[string]int{}
+			sub, err := bus.Subscribe(ctx, "alerts", "pager", func(ctx context.Context, ev Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts[ev.Subject]++
+				if ev.Subject == "poison" || attempts[ev.Subject] < 2 {
+					return errors.New("transient")
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			defer sub.Close()
+
+			require.NoError(t, bus.Publish(ctx, Event{Topic: "alerts", Subject: "poison"}))
+			require.NoError(t, bus.Publish(ctx, Event{Topic: "alerts", Subject: "flaky"}))
+
+			assert.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return attempts["flaky"] == 2
+			}, 2*time.Second, 10*time.Millisecond)
+			mu.Lock()
+			assert.Equal(t, DefaultMaxDeliveries, attempts["poison"])
+			mu.Unlock()
+		})
+	}
+}
+
+func TestTypedTopic(t *testing.T) {
+	type alert struct {
+		Severity string `json:"severity"`
+	}
+	topic := NewTypedTopic[alert]("alerts")
+	bus := NewMemoryEventBus(nil)
+	defer bus.Close()
+
+	got := make(chan alert, 1)
+	sub, err := topic.Subscribe(context.Background(), bus, "", func(ctx context.Context, ev Event, payload alert) error {
+		assert.Equal(t, "audit.chain_broken", ev.Type)
+		assert.NotEmpty(t, ev.ID)
+		got <- payload
+		return nil
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	require.NoError(t, topic.Publish(context.Background(), bus, "audit.chain_broken", "", alert{Severity: "critical"}))
+	select {
+	case a := <-got:
+		assert.Equal(t, "critical", a.Severity)
+	case <-time.After(time.Second):
+		t.Fatal("typed event not delivered")
+	}
+}
+
+func TestRedisEventBus_GroupsSurviveRestarts(t *testing.T) {
+	bus, s := newRedisBus(t)
+	ctx := context.Background()
+
+	var first collector
+	sub, err := bus.Subscribe(ctx, "lifecycle", "webhooks", first.handle)
+	require.NoError(t, err)
+	require.NoError(t, sub.Close())
+
+	// Read but never acknowledged by a consumer that then died
+	require.NoError(t, bus.Publish(ctx, Event{Topic: "lifecycle", Subject: "sb-1"}))
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+	_, err = client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "webhooks", Consumer: "dead", Streams: []string{DefaultEventStreamPrefix + "lifecycle", ">"}, Count: 1,
+	}).Result()
+	require.NoError(t, err)
+
+	// Published while the group has no subscriber
+	require.NoError(t, bus.Publish(ctx, Event{Topic: "lifecycle", Subject: "sb-2"}))
+
+	bus.ClaimIdle = time.Millisecond
+	var second collector
+	sub, err = bus.Subscribe(ctx, "lifecycle", "webhooks", second.handle)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	assert.Eventually(t, func() bool { return len(second.got()) == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"sb-1", "sb-2"}, second.got())
+	assert.Empty(t, first.got())
+}
+
+func TestEventBus_ClosedBusRejects(t *testing.T) {
+	for name, bus := range eventBuses(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, bus.Close())
+			assert.ErrorIs(t, bus.Publish(context.Background(), Event{Topic: "lifecycle"}), ErrEventBusClosed)
+			_, err := bus.Subscribe(context.Background(), "lifecycle", "", func(ctx context.Context, ev Event) error { return nil })
+			assert.ErrorIs(t, err, ErrEventBusClosed)
+		})
+	}
+}
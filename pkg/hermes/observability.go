@@ -13,6 +13,22 @@ type Metrics interface {
 	SetGauge(name string, value float64, labels ...Label)
 }
 
+// HistogramOpts shapes a histogram.
+type HistogramOpts struct {
+	Buckets []float64 // Upper bounds of the classic buckets (backend default if nil)
+
+	// NativeBucketFactor above 1 also exposes a native histogram, whose
+	// buckets grow by at most this factor, to scrapers that negotiate it.
+	// Percentiles then no longer depend on where the classic bounds fall.
+	NativeBucketFactor float64
+}
+
+// HistogramShaper is implemented by Metrics whose histograms can be shaped.
+// A shape only applies to a histogram not yet observed.
+type HistogramShaper interface {
+	ShapeHistogram(name string, opts HistogramOpts)
+}
+
 type Logger interface {
 	Info(ctx context.Context, msg string, fields map[string]any)
 	Error(ctx context.Context, msg string, fields map[string]any)
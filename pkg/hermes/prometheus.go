@@ -2,6 +2,7 @@ package hermes
 
 import (
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -11,6 +12,7 @@ type PrometheusMetrics struct {
 	counters   map[string]*prometheus.CounterVec
 	histograms map[string]*prometheus.HistogramVec
 	gauges     map[string]*prometheus.GaugeVec
+	shapes     map[string]HistogramOpts
 	mu         sync.RWMutex
 }
 
@@ -20,9 +22,25 @@ func NewPrometheusMetrics() *PrometheusMetrics {
 		counters:   make(map[string]*prometheus.CounterVec),
 		histograms: make(map[string]*prometheus.HistogramVec),
 		gauges:     make(map[string]*prometheus.GaugeVec),
+		shapes:     make(map[string]HistogramOpts),
 	}
 }
 
+// Native histograms keep at most this many buckets, widening them when
+// observations spread further, and start over at most this often.
+const (
+	nativeHistogramMaxBuckets       = 160
+	nativeHistogramMinResetDuration = time.Hour
+)
+
+// ShapeHistogram sets the buckets of a histogram before its first
+// observation. Histograms already observed keep their buckets.
+func (m *PrometheusMetrics) ShapeHistogram(name string, opts HistogramOpts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shapes[name] = opts
+}
+
 func (m *PrometheusMetrics) getLabels(labels []Label) ([]string, []string) {
 	keys := make([]string, len(labels))
 	values := make([]string, len(labels))
@@ -68,10 +86,19 @@ func (m *PrometheusMetrics) ObserveHistogram(name string, value float64, labels
 		vec, ok = m.histograms[name]
 		if !ok {
 			keys, _ := m.getLabels(labels)
-			vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			opts := prometheus.HistogramOpts{
 				Name: name,
 				Help: name,
-			}, keys)
+			}
+			if shape, ok := m.shapes[name]; ok {
+				opts.Buckets = shape.Buckets
+				if shape.NativeBucketFactor > 1 {
+					opts.NativeHistogramBucketFactor = shape.NativeBucketFactor
+					opts.NativeHistogramMaxBucketNumber = nativeHistogramMaxBuckets
+					opts.NativeHistogramMinResetDuration = nativeHistogramMinResetDuration
+				}
+			}
+			vec = prometheus.NewHistogramVec(opts, keys)
 			prometheus.MustRegister(vec)
 			m.histograms[name] = vec
 		}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,3 +30,32 @@ func TestPrometheusMetrics(t *testing.T) {
 	assert.Contains(t, m.histograms, "test_histogram")
 	assert.Contains(t, m.gauges, "test_gauge")
 }
+
+func TestPrometheusMetrics_ShapeHistogram(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = registry
+
+	// Shapes pass through the cardinality guard
+	m := NewCardinalityGuard(NewPrometheusMetrics(), CardinalityConfig{})
+	m.ShapeHistogram("shaped_seconds", HistogramOpts{Buckets: []float64{0.01, 0.1, 1}, NativeBucketFactor: 1.1})
+	m.ObserveHistogram("shaped_seconds", 0.05, Label{Key: "shore_id", Value: "a"})
+	m.ObserveHistogram("plain_seconds", 0.05)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	histograms := map[string]*dto.Histogram{}
+	for _, f := range families {
+		histograms[f.GetName()] = f.GetMetric()[0].GetHistogram()
+	}
+
+	shaped := histograms["shaped_seconds"]
+	if assert.NotNil(t, shaped) {
+		assert.Len(t, shaped.GetBucket(), 3)
+		assert.NotNil(t, shaped.Schema, "native histogram not enabled")
+	}
+	plain := histograms["plain_seconds"]
+	if assert.NotNil(t, plain) {
+		assert.Len(t, plain.GetBucket(), len(prometheus.DefBuckets))
+		assert.Nil(t, plain.Schema)
+	}
+}
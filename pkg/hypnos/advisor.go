@@ -0,0 +1,180 @@
+package hypnos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// AdvisorConfig holds the cost model used to weigh hibernation. Costs are in
+// arbitrary units; only their ratio matters.
+type AdvisorConfig struct {
+	MemoryCostPerGBHour  float64       // Cost of keeping 1 GiB of guest memory resident for an hour (default 1)
+	StorageCostPerGBHour float64       // Cost of storing 1 GiB of compressed snapshot for an hour (default 0.01)
+	SnapshotMBps         float64       // Snapshot, compress and upload throughput (default 200)
+	RestoreMBps          float64       // Download, decompress and restore throughput (default 400)
+	WakeOverhead         time.Duration // Fixed cost of relaunching the VM (default 500ms)
+	CompressionRatio     float64       // Expected compressed/uncompressed snapshot size (default 0.5)
+	CycleCost            float64       // CPU, I/O and API overhead of one sleep/wake cycle (default 0.005, 18s of 1 GiB)
+	MaxWakeLatency       time.Duration // Never recommend hibernation when waking would take longer (0 = no limit)
+	DefaultExpectedIdle  time.Duration // Idle time assumed when none is known (default 1h)
+}
+
+func (c AdvisorConfig) withDefaults() AdvisorConfig {
+	if c.MemoryCostPerGBHour <= 0 {
+		c.MemoryCostPerGBHour = 1
+	}
+	if c.StorageCostPerGBHour <= 0 {
+		c.StorageCostPerGBHour = 0.01
+	}
+	if c.SnapshotMBps <= 0 {
+		c.SnapshotMBps = 200
+	}
+	if c.RestoreMBps <= 0 {
+		c.RestoreMBps = 400
+	}
+	if c.WakeOverhead <= 0 {
+		c.WakeOverhead = 500 * time.Millisecond
+	}
+	if c.CompressionRatio <= 0 || c.CompressionRatio > 1 {
+		c.CompressionRatio = 0.5
+	}
+	if c.CycleCost <= 0 {
+		c.CycleCost = 0.005
+	}
+	if c.DefaultExpectedIdle <= 0 {
+		c.DefaultExpectedIdle = time.Hour
+	}
+	return c
+}
+
+// SandboxProfile describes a sandbox being considered for hibernation.
+type SandboxProfile struct {
+	SandboxID    domain.SandboxID
+	MemoryMB     domain.Megabytes // Guest memory, captured in the memory snapshot
+	DiskMB       domain.Megabytes // Writable disk captured alongside it
+	ExpectedIdle time.Duration    // How long the sandbox is expected to stay idle (0 = unknown)
+	Remaining    time.Duration    // Time until the sandbox's TTL or deadline ends it (0 = unbounded)
+}
+
+// Advice is the advisor's verdict on hibernating one sandbox.
+type Advice struct {
+	SandboxID    domain.SandboxID `json:"sandbox_id"`
+	Hibernate    bool             `json:"hibernate"`
+	Reason       string           `json:"reason"`
+	SnapshotMB   domain.Megabytes `json:"snapshot_mb"`   // Uncompressed memory and disk state
+	SleepTime    time.Duration    `json:"sleep_time"`    // Estimated time to hibernate
+	WakeLatency  time.Duration    `json:"wake_latency"`  // Estimated time to wake
+	BreakEven    time.Duration    `json:"break_even"`    // Idle time after which hibernation pays off (0 = never)
+	ExpectedIdle time.Duration    `json:"expected_idle"` // Idle time the verdict assumes
+	NetSavings   float64          `json:"net_savings"`   // Expected saving over ExpectedIdle; negative when hibernation costs more
+}
+
+// Advisor estimates whether hibernating a sandbox saves more than it costs.
+// Hibernation frees the sandbox's memory but costs the time its memory stays
+// committed while the snapshot is taken and restored, a fixed overhead per
+// cycle, and snapshot storage for as long as it sleeps. Tiny or short-lived
+// sandboxes rarely break even.
+type Advisor struct {
+	config AdvisorConfig
+}
+
+// NewAdvisor creates an advisor; zero config fields take their defaults.
+func NewAdvisor(config AdvisorConfig) *Advisor {
+	return &Advisor{config: config.withDefaults()}
+}
+
+// Advise returns the hibernation verdict for the sandbox.
+func (a *Advisor) Advise(p SandboxProfile) Advice {
+	c := a.config
+	snapshotMB := p.MemoryMB + p.DiskMB
+	sleepTime := throughputTime(float64(snapshotMB), c.SnapshotMBps)
+	wakeLatency := c.WakeOverhead + throughputTime(float64(snapshotMB), c.RestoreMBps)
+
+	memoryRate := float64(p.MemoryMB) / 1024 * c.MemoryCostPerGBHour                        // Saved per idle hour
+	storageRate := float64(snapshotMB) * c.CompressionRatio / 1024 * c.StorageCostPerGBHour // Paid per idle hour
+	transitionCost := c.CycleCost + memoryRate*(sleepTime+wakeLatency).Hours()
+
+	expectedIdle := p.ExpectedIdle
+	if expectedIdle <= 0 {
+		expectedIdle = c.DefaultExpectedIdle
+	}
+	if p.Remaining > 0 && p.Remaining < expectedIdle {
+		expectedIdle = p.Remaining
+	}
+
+	advice := Advice{
+		SandboxID:    p.SandboxID,
+		SnapshotMB:   snapshotMB,
+		SleepTime:    sleepTime,
+		WakeLatency:  wakeLatency,
+		ExpectedIdle: expectedIdle,
+		NetSavings:   (memoryRate-storageRate)*expectedIdle.Hours() - transitionCost,
+	}
+	if memoryRate > storageRate {
+		advice.BreakEven = time.Duration(transitionCost / (memoryRate - storageRate) * float64(time.Hour))
+	}
+
+	switch {
+	case c.MaxWakeLatency > 0 && wakeLatency > c.MaxWakeLatency:
+		advice.Reason = fmt.Sprintf("wake latency %s exceeds the %s limit", round(wakeLatency), c.MaxWakeLatency)
+	case advice.BreakEven == 0:
+		advice.Reason = "snapshot storage costs as much as the memory it frees"
+	case expectedIdle <= advice.BreakEven && p.Remaining > 0 && p.Remaining == expectedIdle:
+		advice.Reason = fmt.Sprintf("sandbox ends in %s, before hibernation breaks even after %s", round(p.Remaining), round(advice.BreakEven))
+	case expectedIdle <= advice.BreakEven:
+		advice.Reason = fmt.Sprintf("expected idle time %s is below the %s break-even", round(expectedIdle), round(advice.BreakEven))
+	default:
+		advice.Hibernate = true
+		advice.Reason = fmt.Sprintf("expected idle time %s exceeds the %s break-even", round(expectedIdle), round(advice.BreakEven))
+	}
+	return advice
+}
+
+// AdviseIdle adapts Advise to idle detectors that only know a sandbox's
+// memory, how long it has been idle and how long it has left. A sandbox is
+// expected to stay idle about as long as it already has been.
+func (a *Advisor) AdviseIdle(id domain.SandboxID, memoryMB domain.Megabytes, idleFor, remaining time.Duration) (bool, string) {
+	advice := a.Advise(SandboxProfile{SandboxID: id, MemoryMB: memoryMB, ExpectedIdle: idleFor, Remaining: remaining})
+	return advice.Hibernate, advice.Reason
+}
+
+// ProfileRun builds a profile for a running sandbox from its run record.
+// expectedIdle may be zero when unknown.
+func ProfileRun(run *domain.SandboxRun, expectedIdle time.Duration, now time.Time) SandboxProfile {
+	p := SandboxProfile{SandboxID: run.ID, ExpectedIdle: expectedIdle}
+	if run.Resources != nil {
+		p.MemoryMB = run.Resources.Mem
+	}
+	if p.MemoryMB == 0 {
+		p.MemoryMB = run.MemoryUsage
+	}
+
+	var end time.Time
+	if run.Resources != nil && run.Resources.TTL > 0 && !run.StartedAt.IsZero() {
+		end = run.StartedAt.Add(run.Resources.TTL)
+	}
+	if run.Window != nil && !run.Window.Deadline.IsZero() && (end.IsZero() || run.Window.Deadline.Before(end)) {
+		end = run.Window.Deadline
+	}
+	if !end.IsZero() {
+		// A sandbox already past its end is about to be reaped
+		p.Remaining = end.Sub(now)
+		if p.Remaining <= 0 {
+			p.Remaining = time.Nanosecond
+		}
+	}
+	return p
+}
+
+func throughputTime(mb, mbps float64) time.Duration {
+	return time.Duration(mb / mbps * float64(time.Second))
+}
+
+func round(d time.Duration) time.Duration {
+	if d >= time.Minute {
+		return d.Round(time.Second)
+	}
+	return d.Round(time.Millisecond)
+}
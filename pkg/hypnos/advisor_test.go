@@ -0,0 +1,78 @@
+package hypnos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func TestAdvisor_Advise(t *testing.T) {
+	advisor := NewAdvisor(AdvisorConfig{})
+
+	// A tiny sandbox idle for a minute does not pay back the cycle overhead
+	tiny := advisor.Advise(SandboxProfile{SandboxID: "tiny", MemoryMB: 128, ExpectedIdle: time.Minute})
+	assert.False(t, tiny.Hibernate, tiny.Reason)
+	assert.Greater(t, tiny.BreakEven, time.Minute)
+	assert.Negative(t, tiny.NetSavings)
+
+	// A large sandbox idle for an hour does
+	large := advisor.Advise(SandboxProfile{SandboxID: "large", MemoryMB: 4096, DiskMB: 1024, ExpectedIdle: time.Hour})
+	assert.True(t, large.Hibernate, large.Reason)
+	assert.Less(t, large.BreakEven, tiny.BreakEven)
+	assert.Positive(t, large.NetSavings)
+	assert.Equal(t, domain.Megabytes(5120), large.SnapshotMB)
+	assert.Greater(t, large.WakeLatency, 500*time.Millisecond)
+
+	// Unknown idle time assumes the default
+	assert.Equal(t, time.Hour, advisor.Advise(SandboxProfile{MemoryMB: 1024}).ExpectedIdle)
+}
+
+func TestAdvisor_RemainingCapsIdle(t *testing.T) {
+	advisor := NewAdvisor(AdvisorConfig{})
+
+	advice := advisor.Advise(SandboxProfile{MemoryMB: 4096, ExpectedIdle: time.Hour, Remaining: 10 * time.Second})
+	assert.False(t, advice.Hibernate)
+	assert.Equal(t, 10*time.Second, advice.ExpectedIdle)
+	assert.Contains(t, advice.Reason, "sandbox ends in 10s")
+}
+
+func TestAdvisor_MaxWakeLatency(t *testing.T) {
+	advisor := NewAdvisor(AdvisorConfig{MaxWakeLatency: time.Second})
+
+	advice := advisor.Advise(SandboxProfile{MemoryMB: 8192, ExpectedIdle: 24 * time.Hour})
+	assert.False(t, advice.Hibernate)
+	assert.Contains(t, advice.Reason, "wake latency")
+
+	ok, _ := advisor.AdviseIdle("small", 128, 24*time.Hour, 0)
+	assert.True(t, ok)
+}
+
+func TestAdvisor_StorageAsCostlyAsMemory(t *testing.T) {
+	advisor := NewAdvisor(AdvisorConfig{MemoryCostPerGBHour: 0.01, StorageCostPerGBHour: 1})
+
+	advice := advisor.Advise(SandboxProfile{MemoryMB: 1024, ExpectedIdle: 24 * time.Hour})
+	assert.False(t, advice.Hibernate)
+	assert.Zero(t, advice.BreakEven)
+}
+
+func TestProfileRun(t *testing.T) {
+	now := time.Now()
+	run := &domain.SandboxRun{
+		ID:        "sb-1",
+		StartedAt: now.Add(-10 * time.Minute),
+		Resources: &domain.ResourceSpec{Mem: 2048, TTL: 30 * time.Minute},
+	}
+
+	p := ProfileRun(run, time.Hour, now)
+	require.Equal(t, domain.SandboxID("sb-1"), p.SandboxID)
+	assert.Equal(t, domain.Megabytes(2048), p.MemoryMB)
+	assert.Equal(t, 20*time.Minute, p.Remaining)
+
+	// Reported usage stands in when no resources were requested
+	p = ProfileRun(&domain.SandboxRun{ID: "sb-2", MemoryUsage: 300}, 0, now)
+	assert.Equal(t, domain.Megabytes(300), p.MemoryMB)
+	assert.Zero(t, p.Remaining)
+}
@@ -0,0 +1,39 @@
+package hypnos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// KSMSysfsDir is where the kernel exposes kernel same-page merging.
+const KSMSysfsDir = "/sys/kernel/mm/ksm"
+
+// KSMStats returns the bytes of the pages KSM merged, which it keeps once,
+// and the bytes of the duplicates it freed.
+func KSMStats() (shared, saved uint64, err error) {
+	return ksmStats(KSMSysfsDir)
+}
+
+func ksmStats(dir string) (shared, saved uint64, err error) {
+	read := func(name string) (uint64, error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return 0, err
+		}
+		pages, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		return pages * uint64(os.Getpagesize()), nil
+	}
+	if shared, err = read("pages_shared"); err != nil {
+		return 0, 0, err
+	}
+	if saved, err = read("pages_sharing"); err != nil {
+		return 0, 0, err
+	}
+	return shared, saved, nil
+}
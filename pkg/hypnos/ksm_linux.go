@@ -0,0 +1,36 @@
+//go:build linux
+// +build linux
+
+package hypnos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// prSetMemoryMerge is PR_SET_MEMORY_MERGE (Linux 6.4).
+const prSetMemoryMerge = 67
+
+// EnableKSM marks the agent's memory as mergeable and starts the KSM daemon.
+// VMMs the agent starts inherit the setting (Linux 6.7), so KSM merges the
+// identical pages of their guests, such as pages guests of one template
+// wrote alike after waking from different images.
+func EnableKSM() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetMemoryMerge, 1, 0); errno != 0 {
+		return fmt.Errorf("failed to enable memory merging: %w", errno)
+	}
+	run := filepath.Join(KSMSysfsDir, "run")
+	data, err := os.ReadFile(run)
+	if err != nil {
+		return fmt.Errorf("failed to read KSM state: %w", err)
+	}
+	if strings.TrimSpace(string(data)) != "1" {
+		if err := os.WriteFile(run, []byte("1"), 0644); err != nil {
+			return fmt.Errorf("failed to start KSM: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package hypnos
+
+import "errors"
+
+// EnableKSM is only supported on Linux.
+func EnableKSM() error {
+	return errors.New("KSM is only supported on Linux")
+}
@@ -3,6 +3,8 @@ package hypnos
 import (
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -36,6 +38,11 @@ type Manager struct {
 	StagingDir string
 	Hooks      *LifecycleHooks
 	Metrics    hermes.Metrics
+	// Shared, if set, holds woken sandboxes' memory images, one per
+	// identical image, instead of a private copy per wake.
+	Shared *SharedImages
+	// KSM reports kernel same-page merging in MemorySharing; see EnableKSM.
+	KSM bool
 
 	mu       sync.Mutex
 	sleeping map[domain.SandboxID]*SleepRecord
@@ -46,6 +53,9 @@ type Manager struct {
 type SleepOptions struct {
 	// GracefulShutdown requests a clean shutdown after the snapshot is captured.
 	GracefulShutdown bool
+	// WithRootFS uploads the sandbox's root filesystem with the snapshot,
+	// so that it can be woken on a node that does not have it.
+	WithRootFS bool
 }
 
 // SleepRecord tracks a hibernated sandbox.
@@ -56,6 +66,8 @@ type SleepRecord struct {
 	Config           tartarus.VMConfig
 	Request          domain.SandboxRequest
 	CompressionRatio float64 // Ratio of compressed to uncompressed size
+	MemoryDigest     string  // sha256 of the uncompressed memory image
+	RootFSKey        string  // Erebus key of the root filesystem, if it was uploaded
 }
 
 // NewManager constructs a Hypnos manager.
@@ -138,18 +150,34 @@ func (m *Manager) Sleep(ctx context.Context, id domain.SandboxID, opts *SleepOpt
 	}
 	snapshotSpan()
 
+	// The overlay goes with the VM, so keep a copy while it is paused
+	rootfsPath := snapshotBase + ".rootfs"
+	withRootFS := opts.WithRootFS && cfg.OverlayFS != ""
+	if withRootFS {
+		if err := copyFile(cfg.OverlayFS, rootfsPath); err != nil {
+			_ = m.Runtime.Resume(ctx, id)
+			if m.Metrics != nil {
+				m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "copy_rootfs"})
+			}
+			return nil, fmt.Errorf("failed to copy root filesystem: %w", err)
+		}
+	}
+
 	if opts.GracefulShutdown {
 		_ = m.Runtime.Shutdown(ctx, id)
 	}
 	// Ensure runtime state is cleared so we can re-launch on wake.
 	_ = m.Runtime.Kill(ctx, id)
+	if m.Shared != nil {
+		m.Shared.Release(id)
+	}
 
 	keyBase := fmt.Sprintf("sleep/%s/%d", id, m.now().UnixNano())
 
 	// Compress and upload memory snapshot
 	memCompressedPath := memPath + ".gz"
 	compressSpan := m.trace(ctx, "Sleep.Compress")
-	compressionRatio, err := m.compressFile(memPath, memCompressedPath)
+	compressionRatio, memDigest, err := m.compressFile(memPath, memCompressedPath)
 	if err != nil {
 		if m.Metrics != nil {
 			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "compress_memory"})
@@ -175,6 +203,16 @@ func (m *Manager) Sleep(ctx context.Context, id domain.SandboxID, opts *SleepOpt
 		}
 		return nil, err
 	}
+	var rootfsKey string
+	if withRootFS {
+		rootfsKey = keyBase + ".rootfs"
+		if err := m.copyToStore(ctx, rootfsKey, rootfsPath); err != nil {
+			if m.Metrics != nil {
+				m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "upload_rootfs"})
+			}
+			return nil, err
+		}
+	}
 	uploadSpan()
 
 	record := &SleepRecord{
@@ -184,6 +222,8 @@ func (m *Manager) Sleep(ctx context.Context, id domain.SandboxID, opts *SleepOpt
 		Config:           cfg,
 		Request:          *req,
 		CompressionRatio: compressionRatio,
+		MemoryDigest:     memDigest,
+		RootFSKey:        rootfsKey,
 	}
 
 	m.mu.Lock()
@@ -246,28 +286,49 @@ func (m *Manager) Wake(ctx context.Context, id domain.SandboxID) (*domain.Sandbo
 	memCompressedPath := memPath + ".gz"
 	diskPath := snapshotBase + ".disk"
 
-	// Download and decompress memory snapshot
+	// Download and decompress memory snapshot, into the shared image of its
+	// digest if it has one and the node does not have it yet
 	downloadSpan := m.trace(ctx, "Wake.Download")
-	if err := m.copyFromStore(ctx, record.SnapshotKey+".mem.gz", memCompressedPath); err != nil {
-		if m.Metrics != nil {
-			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "download_memory"})
+	shared := m.Shared != nil && record.MemoryDigest != ""
+	if shared {
+		imagePath, err := m.Shared.Acquire(id, record.MemoryDigest, func(path string) error {
+			return m.fetchMemory(ctx, record, memCompressedPath, path)
+		})
+		if err != nil {
+			return nil, err
 		}
-		return nil, err
-	}
-
-	if err := m.decompressFile(memCompressedPath, memPath); err != nil {
-		if m.Metrics != nil {
-			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "decompress_memory"})
+		if err := os.Symlink(imagePath, memPath); err != nil {
+			m.Shared.Release(id)
+			if m.Metrics != nil {
+				m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "link_memory"})
+			}
+			return nil, fmt.Errorf("failed to link shared memory image: %w", err)
 		}
-		return nil, fmt.Errorf("failed to decompress memory snapshot: %w", err)
+	} else if err := m.fetchMemory(ctx, record, memCompressedPath, memPath); err != nil {
+		return nil, err
 	}
 
 	if err := m.copyFromStore(ctx, record.SnapshotKey+".disk", diskPath); err != nil {
+		if shared {
+			m.Shared.Release(id)
+		}
 		if m.Metrics != nil {
 			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "download_disk"})
 		}
 		return nil, err
 	}
+	// The snapshot names the root filesystem's path, so it goes back there
+	if record.RootFSKey != "" {
+		if err := m.copyFromStore(ctx, record.RootFSKey, record.Config.OverlayFS); err != nil {
+			if shared {
+				m.Shared.Release(id)
+			}
+			if m.Metrics != nil {
+				m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "download_rootfs"})
+			}
+			return nil, err
+		}
+	}
 	downloadSpan()
 
 	cfg := record.Config
@@ -277,6 +338,9 @@ func (m *Manager) Wake(ctx context.Context, id domain.SandboxID) (*domain.Sandbo
 	launchSpan := m.trace(ctx, "Wake.Launch")
 	run, err := m.Runtime.Launch(ctx, &req, cfg)
 	if err != nil {
+		if shared {
+			m.Shared.Release(id)
+		}
 		if m.Metrics != nil {
 			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "wake_launch"})
 		}
@@ -330,6 +394,45 @@ func (m *Manager) IsSleeping(id domain.SandboxID) bool {
 	return ok
 }
 
+// MemorySharing reports how much memory woken sandboxes share on this node,
+// or nil if neither shared images nor KSM are enabled. Images of sandboxes
+// the runtime no longer has are released first.
+func (m *Manager) MemorySharing(ctx context.Context) *domain.MemorySharing {
+	if m.Shared == nil && !m.KSM {
+		return nil
+	}
+	var usage domain.MemorySharing
+	if m.Shared != nil {
+		if runs, err := m.Runtime.List(ctx); err == nil {
+			live := make(map[domain.SandboxID]bool, len(runs))
+			for _, run := range runs {
+				live[run.ID] = true
+			}
+			m.Shared.Prune(live)
+		}
+		usage = m.Shared.Usage()
+	}
+	if m.KSM {
+		if shared, saved, err := KSMStats(); err == nil {
+			usage.KSMSharedBytes = shared
+			usage.KSMSavedBytes = saved
+		}
+	}
+	return &usage
+}
+
+// ReportSharingMetrics exports a node's memory sharing as gauges.
+func ReportSharingMetrics(metrics hermes.Metrics, usage *domain.MemorySharing) {
+	if usage == nil {
+		return
+	}
+	metrics.SetGauge("hypnos_shared_images", float64(usage.Images))
+	metrics.SetGauge("hypnos_memory_bytes", float64(usage.SharedBytes), hermes.Label{Key: "kind", Value: "shared"})
+	metrics.SetGauge("hypnos_memory_bytes", float64(usage.PrivateBytes), hermes.Label{Key: "kind", Value: "private"})
+	metrics.SetGauge("hypnos_memory_saved_bytes", float64(usage.SavedBytes), hermes.Label{Key: "source", Value: "shared_images"})
+	metrics.SetGauge("hypnos_memory_saved_bytes", float64(usage.KSMSavedBytes), hermes.Label{Key: "source", Value: "ksm"})
+}
+
 func (m *Manager) getRecord(id domain.SandboxID) (*SleepRecord, bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -337,6 +440,33 @@ func (m *Manager) getRecord(id domain.SandboxID) (*SleepRecord, bool) {
 	return rec, ok
 }
 
+// fetchMemory downloads a record's compressed memory image to
+// compressedPath and decompresses it to path, checking its digest.
+func (m *Manager) fetchMemory(ctx context.Context, record *SleepRecord, compressedPath, path string) error {
+	if err := m.copyFromStore(ctx, record.SnapshotKey+".mem.gz", compressedPath); err != nil {
+		if m.Metrics != nil {
+			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "download_memory"})
+		}
+		return err
+	}
+	defer os.Remove(compressedPath)
+
+	digest, err := m.decompressFile(compressedPath, path)
+	if err != nil {
+		if m.Metrics != nil {
+			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "decompress_memory"})
+		}
+		return fmt.Errorf("failed to decompress memory snapshot: %w", err)
+	}
+	if record.MemoryDigest != "" && digest != record.MemoryDigest {
+		if m.Metrics != nil {
+			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "verify_memory"})
+		}
+		return fmt.Errorf("memory snapshot digest %s does not match %s", digest, record.MemoryDigest)
+	}
+	return nil
+}
+
 func (m *Manager) copyToStore(ctx context.Context, key, path string) error {
 	f, err := os.Open(path)
 	if err != nil {
@@ -378,41 +508,61 @@ func (m *Manager) copyFromStore(ctx context.Context, key, path string) error {
 	return nil
 }
 
-// compressFile compresses src to dst using gzip and returns the compression ratio.
-func (m *Manager) compressFile(src, dst string) (float64, error) {
+// copyFile copies the regular file src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// compressFile compresses src to dst using gzip and returns the compression
+// ratio and the digest of src.
+func (m *Manager) compressFile(src, dst string) (float64, string, error) {
 	srcFile, err := os.Open(src)
 	if err != nil {
-		return 0, fmt.Errorf("failed to open source file: %w", err)
+		return 0, "", fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer srcFile.Close()
 
 	srcInfo, err := srcFile.Stat()
 	if err != nil {
-		return 0, fmt.Errorf("failed to stat source file: %w", err)
+		return 0, "", fmt.Errorf("failed to stat source file: %w", err)
 	}
 	originalSize := srcInfo.Size()
 
 	dstFile, err := os.Create(dst)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create destination file: %w", err)
+		return 0, "", fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer dstFile.Close()
 
 	gzWriter := gzip.NewWriter(dstFile)
 	defer gzWriter.Close()
 
-	written, err := io.Copy(gzWriter, srcFile)
-	if err != nil {
-		return 0, fmt.Errorf("failed to compress file: %w", err)
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(gzWriter, hash), srcFile); err != nil {
+		return 0, "", fmt.Errorf("failed to compress file: %w", err)
 	}
 
 	if err := gzWriter.Close(); err != nil {
-		return 0, fmt.Errorf("failed to finalize compression: %w", err)
+		return 0, "", fmt.Errorf("failed to finalize compression: %w", err)
 	}
 
 	dstInfo, err := dstFile.Stat()
 	if err != nil {
-		return 0, fmt.Errorf("failed to stat compressed file: %w", err)
+		return 0, "", fmt.Errorf("failed to stat compressed file: %w", err)
 	}
 	compressedSize := dstInfo.Size()
 
@@ -421,33 +571,34 @@ func (m *Manager) compressFile(src, dst string) (float64, error) {
 		ratio = 0
 	}
 
-	_ = written // Silence unused variable
-	return ratio, nil
+	return ratio, "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// decompressFile decompresses src (gzipped) to dst.
-func (m *Manager) decompressFile(src, dst string) error {
+// decompressFile decompresses src (gzipped) to dst and returns the digest
+// of dst.
+func (m *Manager) decompressFile(src, dst string) (string, error) {
 	srcFile, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("failed to open compressed file: %w", err)
+		return "", fmt.Errorf("failed to open compressed file: %w", err)
 	}
 	defer srcFile.Close()
 
 	gzReader, err := gzip.NewReader(srcFile)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return "", fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 	defer gzReader.Close()
 
 	dstFile, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+		return "", fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer dstFile.Close()
 
-	if _, err := io.Copy(dstFile, gzReader); err != nil {
-		return fmt.Errorf("failed to decompress file: %w", err)
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dstFile, hash), gzReader); err != nil {
+		return "", fmt.Errorf("failed to decompress file: %w", err)
 	}
 
-	return nil
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
 }
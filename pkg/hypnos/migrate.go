@@ -0,0 +1,86 @@
+package hypnos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// Live migration moves a sandbox between nodes through a shared store: the
+// source sleeps it (with SleepOptions.WithRootFS) and hands its record off,
+// and the target adopts the record and wakes it.
+
+// HandOff stores the record of a sleeping sandbox next to its snapshot and
+// forgets it on this node. It returns the key another node's manager adopts
+// the sandbox by.
+func (m *Manager) HandOff(ctx context.Context, id domain.SandboxID) (string, error) {
+	record, ok := m.getRecord(id)
+	if !ok {
+		return "", fmt.Errorf("sandbox %s is not sleeping", id)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode sleep record: %w", err)
+	}
+	if err := m.Store.Put(ctx, record.SnapshotKey+".json", bytes.NewReader(data)); err != nil {
+		if m.Metrics != nil {
+			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "hand_off"})
+		}
+		return "", fmt.Errorf("failed to store sleep record: %w", err)
+	}
+
+	m.mu.Lock()
+	delete(m.sleeping, id)
+	m.mu.Unlock()
+
+	if m.Metrics != nil {
+		m.Metrics.IncCounter("hypnos_handoffs_total", 1)
+	}
+	return record.SnapshotKey, nil
+}
+
+// Adopt loads a record handed off by another node and tracks the sandbox as
+// sleeping here, so that Wake restores it. retarget, if set, first adapts
+// the record's VM config to this node (its TAP device, addresses and
+// jailer); if it fails the sandbox is not adopted.
+func (m *Manager) Adopt(ctx context.Context, key string, retarget func(*SleepRecord) error) (*SleepRecord, error) {
+	reader, err := m.Store.Get(ctx, key+".json")
+	if err != nil {
+		if m.Metrics != nil {
+			m.Metrics.IncCounter("hypnos_errors_total", 1, hermes.Label{Key: "phase", Value: "adopt"})
+		}
+		return nil, fmt.Errorf("failed to fetch sleep record %s: %w", key, err)
+	}
+	defer reader.Close()
+
+	var record SleepRecord
+	if err := json.NewDecoder(reader).Decode(&record); err != nil {
+		return nil, fmt.Errorf("failed to decode sleep record %s: %w", key, err)
+	}
+	if record.SnapshotKey != key {
+		return nil, fmt.Errorf("sleep record %s names snapshot %s", key, record.SnapshotKey)
+	}
+	if retarget != nil {
+		if err := retarget(&record); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	m.sleeping[record.SandboxID] = &record
+	m.mu.Unlock()
+	return &record, nil
+}
+
+// Forget stops tracking a sleeping sandbox without waking it, e.g. after an
+// adopted sandbox failed to wake. Its snapshot stays in the store.
+func (m *Manager) Forget(id domain.SandboxID) {
+	m.mu.Lock()
+	delete(m.sleeping, id)
+	m.mu.Unlock()
+}
@@ -0,0 +1,75 @@
+package hypnos
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+func TestHandOffAndAdopt(t *testing.T) {
+	ctx := context.Background()
+	store, err := erebus.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	sourceRuntime := tartarus.NewMockRuntime(slog.Default())
+	targetRuntime := tartarus.NewMockRuntime(slog.Default())
+	source := NewManager(sourceRuntime, store, t.TempDir())
+	target := NewManager(targetRuntime, store, t.TempDir())
+
+	rootfs := filepath.Join(t.TempDir(), "source", "ov-1.ext4")
+	require.NoError(t, os.MkdirAll(filepath.Dir(rootfs), 0755))
+	require.NoError(t, os.WriteFile(rootfs, []byte("written by the guest"), 0644))
+	req := &domain.SandboxRequest{ID: "sandbox-1", Template: "tpl-1", Resources: domain.ResourceSpec{CPU: 1, Mem: 128}}
+	_, err = sourceRuntime.Launch(ctx, req, tartarus.VMConfig{OverlayFS: rootfs, TapDevice: "tap-source", CPUs: 1, MemoryMB: 128})
+	require.NoError(t, err)
+
+	record, err := source.Sleep(ctx, req.ID, &SleepOptions{WithRootFS: true})
+	require.NoError(t, err)
+	assert.Equal(t, record.SnapshotKey+".rootfs", record.RootFSKey)
+	key, err := source.HandOff(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, record.SnapshotKey, key)
+	assert.False(t, source.IsSleeping(req.ID))
+	require.NoError(t, os.Remove(rootfs))
+
+	// A failed retarget leaves the sandbox unadopted
+	_, err = target.Adopt(ctx, key, func(*SleepRecord) error { return errors.New("no addresses left") })
+	assert.ErrorContains(t, err, "no addresses left")
+	assert.False(t, target.IsSleeping(req.ID))
+
+	ip := netip.MustParseAddr("10.0.0.9")
+	adopted, err := target.Adopt(ctx, key, func(rec *SleepRecord) error {
+		assert.Equal(t, req.ID, rec.Request.ID)
+		rec.Config.TapDevice = "tap-target"
+		rec.Config.IP = ip
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "tap-target", adopted.Config.TapDevice)
+	assert.True(t, target.IsSleeping(req.ID))
+
+	run, err := target.Wake(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, req.ID, run.ID)
+	cfg, _, err := targetRuntime.GetConfig(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "tap-target", cfg.TapDevice)
+	assert.Equal(t, ip, cfg.IP)
+
+	// The root filesystem is back where the snapshot expects it
+	data, err := os.ReadFile(rootfs)
+	require.NoError(t, err)
+	assert.Equal(t, "written by the guest", string(data))
+
+	_, err = target.Adopt(ctx, "sleep/missing/1", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,390 @@
+package hypnos
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// PreWakeConfig tunes speculative wakes of hibernated sandboxes ahead of
+// their predicted use.
+type PreWakeConfig struct {
+	LeadTime      time.Duration // How long before the predicted access to wake (default 5m)
+	HitWindow     time.Duration // An access this long after the predicted time still counts as a hit (default 30m)
+	Tolerance     time.Duration // Accesses this close to a time of day count as the same habit (default 15m)
+	History       time.Duration // How far back accesses are remembered (default 28 days)
+	MinDays       int           // Comparable days observed before predicting (default 3)
+	MinConfidence float64       // Least confidence worth a speculative wake (default 0.6)
+	DailyBudget   int           // Speculative wakes allowed per rolling 24 hours (default 50)
+}
+
+func (c PreWakeConfig) withDefaults() PreWakeConfig {
+	if c.LeadTime <= 0 {
+		c.LeadTime = 5 * time.Minute
+	}
+	if c.HitWindow <= 0 {
+		c.HitWindow = 30 * time.Minute
+	}
+	if c.Tolerance <= 0 {
+		c.Tolerance = 15 * time.Minute
+	}
+	if c.History <= 0 {
+		c.History = 28 * 24 * time.Hour
+	}
+	if c.MinDays <= 0 {
+		c.MinDays = 3
+	}
+	if c.MinConfidence <= 0 {
+		c.MinConfidence = 0.6
+	}
+	if c.DailyBudget <= 0 {
+		c.DailyBudget = 50
+	}
+	return c
+}
+
+// DemandForecast weighs a predicted access by the demand expected across
+// the fleet at that time. persephone.Forecast implements it.
+type DemandForecast interface {
+	// DemandFactor returns 1 for average demand, more for busier times.
+	DemandFactor(t time.Time) float64
+}
+
+// AccessPrediction is when a sandbox is next expected to be used.
+type AccessPrediction struct {
+	At         time.Time
+	Confidence float64 // Share of comparable days the sandbox was used then, weighted by the forecast
+}
+
+// WakePatterns remembers when each sandbox was woken on demand and predicts
+// its next use from the times of day it recurs at. Weekdays and weekends
+// are compared separately, as are individual days of the week, so both a
+// notebook opened every weekday at 9:00 and a report run every Monday are
+// recognized.
+type WakePatterns struct {
+	config PreWakeConfig
+
+	mu       sync.Mutex
+	accesses map[domain.SandboxID][]time.Time
+}
+
+// NewWakePatterns creates an empty access history.
+func NewWakePatterns(cfg PreWakeConfig) *WakePatterns {
+	return &WakePatterns{
+		config:   cfg.withDefaults(),
+		accesses: make(map[domain.SandboxID][]time.Time),
+	}
+}
+
+// Record notes that the sandbox was used at t.
+func (p *WakePatterns) Record(id domain.SandboxID, t time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cutoff := t.Add(-p.config.History)
+	kept := p.accesses[id][:0]
+	for _, at := range p.accesses[id] {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	p.accesses[id] = append(kept, t)
+}
+
+// Forget drops the sandbox's history.
+func (p *WakePatterns) Forget(id domain.SandboxID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.accesses, id)
+}
+
+// Predict returns the likeliest access to the sandbox after now and no later
+// than now+horizon. forecast may be nil.
+func (p *WakePatterns) Predict(id domain.SandboxID, now time.Time, horizon time.Duration, forecast DemandForecast) (AccessPrediction, bool) {
+	p.mu.Lock()
+	history := append([]time.Time(nil), p.accesses[id]...)
+	p.mu.Unlock()
+	if len(history) == 0 {
+		return AccessPrediction{}, false
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Before(history[j]) })
+
+	var best AccessPrediction
+	seen := map[time.Duration]bool{}
+	for _, at := range history {
+		tod := timeOfDay(at).Truncate(time.Minute)
+		if seen[tod] {
+			continue
+		}
+		seen[tod] = true
+
+		next := nextAt(now, tod)
+		if next.Sub(now) > horizon {
+			continue
+		}
+		confidence := p.confidence(history, next, tod)
+		if forecast != nil {
+			confidence *= forecast.DemandFactor(next)
+		}
+		confidence = min(confidence, 1)
+		if confidence > best.Confidence || (confidence == best.Confidence && next.Before(best.At)) {
+			best = AccessPrediction{At: next, Confidence: confidence}
+		}
+	}
+	return best, best.Confidence > 0
+}
+
+// confidence is the share of days comparable to target's on which the
+// sandbox was used near tod: the better of days of the same kind (weekday or
+// weekend) and the same day of the week, each counted only once MinDays of
+// them have been observed.
+func (p *WakePatterns) confidence(history []time.Time, target time.Time, tod time.Duration) float64 {
+	used := map[time.Time]bool{}
+	for _, at := range history {
+		if absDuration(timeOfDay(at)-tod) <= p.config.Tolerance {
+			used[startOfDay(at)] = true
+		}
+	}
+
+	var kindDays, kindUsed, weekdayDays, weekdayUsed int
+	targetDay := startOfDay(target)
+	for day := startOfDay(history[0]); day.Before(targetDay); day = day.AddDate(0, 0, 1) {
+		if isWeekend(day) == isWeekend(targetDay) {
+			kindDays++
+			if used[day] {
+				kindUsed++
+			}
+		}
+		if day.Weekday() == targetDay.Weekday() {
+			weekdayDays++
+			if used[day] {
+				weekdayUsed++
+			}
+		}
+	}
+
+	var confidence float64
+	if kindDays >= p.config.MinDays {
+		confidence = float64(kindUsed) / float64(kindDays)
+	}
+	if weekdayDays >= p.config.MinDays {
+		confidence = max(confidence, float64(weekdayUsed)/float64(weekdayDays))
+	}
+	return confidence
+}
+
+// preWake is a speculative wake awaiting the access it anticipated.
+type preWake struct {
+	WokenAt     time.Time
+	PredictedAt time.Time
+}
+
+// PreWaker wakes hibernated sandboxes that opted in shortly before their
+// predicted use, within a budget of speculative wakes, and measures how
+// often the prediction was right: a pre-woken sandbox accessed within the
+// hit window of its predicted time is a hit, one left unused is a miss.
+type PreWaker struct {
+	Patterns *WakePatterns
+	Metrics  hermes.Metrics
+	Config   PreWakeConfig
+
+	mu       sync.Mutex
+	sleeping map[domain.SandboxID]bool // Hibernated sandboxes that opted in
+	pending  map[domain.SandboxID]preWake
+	spent    []time.Time // Speculative wakes in the last 24 hours
+	now      func() time.Time
+}
+
+// NewPreWaker creates a pre-waker with its own access history.
+func NewPreWaker(cfg PreWakeConfig, metrics hermes.Metrics) *PreWaker {
+	cfg = cfg.withDefaults()
+	if metrics == nil {
+		metrics = hermes.NewNoopMetrics()
+	}
+	return &PreWaker{
+		Patterns: NewWakePatterns(cfg),
+		Metrics:  metrics,
+		Config:   cfg,
+		sleeping: make(map[domain.SandboxID]bool),
+		pending:  make(map[domain.SandboxID]preWake),
+		now:      time.Now,
+	}
+}
+
+// Slept records that the sandbox was hibernated; only sandboxes whose
+// policy opts in are pre-woken.
+func (w *PreWaker) Slept(id domain.SandboxID, optIn bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.pending, id)
+	if optIn {
+		w.sleeping[id] = true
+	} else {
+		delete(w.sleeping, id)
+	}
+}
+
+// Accessed records a use of the sandbox, such as a wake on demand. It
+// reports whether the sandbox had been pre-woken and is therefore already
+// awake.
+func (w *PreWaker) Accessed(id domain.SandboxID) bool {
+	now := w.now()
+	w.Patterns.Record(id, now)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.sleeping, id)
+	wake, ok := w.pending[id]
+	if !ok {
+		return false
+	}
+	delete(w.pending, id)
+	if now.After(wake.PredictedAt.Add(w.Config.HitWindow)) {
+		w.Metrics.IncCounter("hypnos_prewake_outcomes_total", 1, hermes.Label{Key: "result", Value: "late"})
+	} else {
+		w.Metrics.IncCounter("hypnos_prewake_outcomes_total", 1, hermes.Label{Key: "result", Value: "hit"})
+		w.Metrics.ObserveHistogram("hypnos_prewake_lead_seconds", now.Sub(wake.WokenAt).Seconds())
+	}
+	return true
+}
+
+// Forget stops tracking a sandbox that no longer exists.
+func (w *PreWaker) Forget(id domain.SandboxID) {
+	w.Patterns.Forget(id)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.sleeping, id)
+	delete(w.pending, id)
+}
+
+// Run makes one pass: pre-wakes that went unused past their hit window
+// are counted as misses, then sleeping sandboxes predicted to be used
+// within the lead time are woken through wake while the budget lasts.
+// forecast may be nil. It returns the sandboxes woken.
+func (w *PreWaker) Run(ctx context.Context, forecast DemandForecast, wake func(context.Context, domain.SandboxID) error) []domain.SandboxID {
+	now := w.now()
+
+	w.mu.Lock()
+	for id, pw := range w.pending {
+		if now.After(pw.PredictedAt.Add(w.Config.HitWindow)) {
+			delete(w.pending, id)
+			w.Metrics.IncCounter("hypnos_prewake_outcomes_total", 1, hermes.Label{Key: "result", Value: "miss"})
+		}
+	}
+	cutoff := now.Add(-24 * time.Hour)
+	spent := w.spent[:0]
+	for _, at := range w.spent {
+		if at.After(cutoff) {
+			spent = append(spent, at)
+		}
+	}
+	w.spent = spent
+	candidates := make([]domain.SandboxID, 0, len(w.sleeping))
+	for id := range w.sleeping {
+		candidates = append(candidates, id)
+	}
+	w.mu.Unlock()
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	type due struct {
+		id         domain.SandboxID
+		prediction AccessPrediction
+	}
+	var queue []due
+	for _, id := range candidates {
+		prediction, ok := w.Patterns.Predict(id, now, w.Config.LeadTime, forecast)
+		if !ok || prediction.Confidence < w.Config.MinConfidence {
+			continue
+		}
+		queue = append(queue, due{id, prediction})
+	}
+	// The most certain predictions get the budget first
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].prediction.Confidence > queue[j].prediction.Confidence
+	})
+
+	var woken []domain.SandboxID
+	for _, d := range queue {
+		w.mu.Lock()
+		if len(w.spent) >= w.Config.DailyBudget {
+			w.mu.Unlock()
+			w.Metrics.IncCounter("hypnos_prewake_skipped_total", 1, hermes.Label{Key: "reason", Value: "budget"})
+			continue
+		}
+		w.mu.Unlock()
+
+		if err := wake(ctx, d.id); err != nil {
+			w.Metrics.IncCounter("hypnos_prewake_skipped_total", 1, hermes.Label{Key: "reason", Value: "wake_failed"})
+			continue
+		}
+
+		w.mu.Lock()
+		w.spent = append(w.spent, now)
+		delete(w.sleeping, d.id)
+		w.pending[d.id] = preWake{WokenAt: now, PredictedAt: d.prediction.At}
+		w.mu.Unlock()
+		w.Metrics.IncCounter("hypnos_prewakes_total", 1)
+		woken = append(woken, d.id)
+	}
+
+	w.mu.Lock()
+	w.Metrics.SetGauge("hypnos_prewake_budget_remaining", float64(w.Config.DailyBudget-len(w.spent)))
+	w.mu.Unlock()
+	return woken
+}
+
+// Start runs Run every interval until ctx is cancelled. forecast is called
+// on each pass and may return nil.
+func (w *PreWaker) Start(ctx context.Context, interval time.Duration, forecast func(context.Context) DemandForecast, wake func(context.Context, domain.SandboxID) error) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var fc DemandForecast
+			if forecast != nil {
+				fc = forecast(ctx)
+			}
+			w.Run(ctx, fc, wake)
+		}
+	}
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	return t.Sub(startOfDay(t))
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// nextAt returns the first time after now at time of day tod.
+func nextAt(now time.Time, tod time.Duration) time.Time {
+	next := startOfDay(now).Add(tod)
+	if !next.After(now) {
+		next = startOfDay(now).AddDate(0, 0, 1).Add(tod)
+	}
+	return next
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,136 @@
+package hypnos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// outcomeMetrics counts pre-wake outcomes by result.
+type outcomeMetrics struct {
+	outcomes map[string]int
+	gauge    float64
+}
+
+func (m *outcomeMetrics) IncCounter(name string, value float64, labels ...hermes.Label) {
+	if name != "hypnos_prewake_outcomes_total" {
+		return
+	}
+	if m.outcomes == nil {
+		m.outcomes = make(map[string]int)
+	}
+	m.outcomes[labels[0].Value] += int(value)
+}
+func (m *outcomeMetrics) ObserveHistogram(name string, value float64, labels ...hermes.Label) {}
+func (m *outcomeMetrics) SetGauge(name string, value float64, labels ...hermes.Label) {
+	m.gauge = value
+}
+
+type fixedForecast float64
+
+func (f fixedForecast) DemandFactor(time.Time) float64 { return float64(f) }
+
+// weekdayMornings records a use at 9:00 on each weekday of two weeks
+// starting Monday 6 January 2025.
+func weekdayMornings(p *WakePatterns, id domain.SandboxID) {
+	for day := 0; day < 12; day++ {
+		at := time.Date(2025, 1, 6+day, 9, 0, 0, 0, time.UTC)
+		if !isWeekend(at) {
+			p.Record(id, at)
+		}
+	}
+}
+
+func TestWakePatterns_Predict(t *testing.T) {
+	patterns := NewWakePatterns(PreWakeConfig{})
+	weekdayMornings(patterns, "notebook")
+
+	// Monday 20 January at 8:56: the 9:00 habit is due
+	monday := time.Date(2025, 1, 20, 8, 56, 0, 0, time.UTC)
+	prediction, ok := patterns.Predict("notebook", monday, 5*time.Minute, nil)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC), prediction.At)
+	assert.Equal(t, 1.0, prediction.Confidence)
+
+	// Not yet within the horizon
+	_, ok = patterns.Predict("notebook", monday.Add(-time.Hour), 5*time.Minute, nil)
+	assert.False(t, ok)
+
+	// Weekends were never used
+	saturday := time.Date(2025, 1, 18, 8, 56, 0, 0, time.UTC)
+	_, ok = patterns.Predict("notebook", saturday, 5*time.Minute, nil)
+	assert.False(t, ok)
+
+	// A quiet forecast lowers the confidence
+	prediction, ok = patterns.Predict("notebook", monday, 5*time.Minute, fixedForecast(0.5))
+	require.True(t, ok)
+	assert.Equal(t, 0.5, prediction.Confidence)
+
+	// Too few days observed
+	fresh := NewWakePatterns(PreWakeConfig{})
+	fresh.Record("new", time.Date(2025, 1, 17, 9, 0, 0, 0, time.UTC))
+	_, ok = fresh.Predict("new", monday, 5*time.Minute, nil)
+	assert.False(t, ok)
+}
+
+func TestWakePatterns_WeeklyHabit(t *testing.T) {
+	patterns := NewWakePatterns(PreWakeConfig{})
+	// Every Monday at 14:30 for three weeks
+	for week := 0; week < 3; week++ {
+		patterns.Record("report", time.Date(2025, 1, 6+7*week, 14, 30, 0, 0, time.UTC))
+	}
+
+	prediction, ok := patterns.Predict("report", time.Date(2025, 1, 27, 14, 27, 0, 0, time.UTC), 5*time.Minute, nil)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, prediction.Confidence)
+
+	// Tuesdays share the weekday class but not the habit
+	prediction, _ = patterns.Predict("report", time.Date(2025, 1, 28, 14, 27, 0, 0, time.UTC), 5*time.Minute, nil)
+	assert.Less(t, prediction.Confidence, 0.6)
+}
+
+func TestPreWaker_Run(t *testing.T) {
+	ctx := context.Background()
+	metrics := &outcomeMetrics{}
+	waker := NewPreWaker(PreWakeConfig{DailyBudget: 2}, metrics)
+	now := time.Date(2025, 1, 20, 8, 56, 0, 0, time.UTC)
+	waker.now = func() time.Time { return now }
+
+	for _, id := range []domain.SandboxID{"a", "b", "c", "opted-out"} {
+		weekdayMornings(waker.Patterns, id)
+		waker.Slept(id, id != "opted-out")
+	}
+
+	var woken []domain.SandboxID
+	wake := func(ctx context.Context, id domain.SandboxID) error {
+		woken = append(woken, id)
+		return nil
+	}
+
+	// The budget allows two of the three opted-in sandboxes
+	assert.Equal(t, []domain.SandboxID{"a", "b"}, waker.Run(ctx, nil, wake))
+	assert.Equal(t, []domain.SandboxID{"a", "b"}, woken)
+	assert.Equal(t, 0.0, metrics.gauge)
+
+	// a is used as predicted and is already awake
+	now = now.Add(6 * time.Minute)
+	assert.True(t, waker.Accessed("a"))
+	assert.False(t, waker.Accessed("opted-out"))
+
+	// b is never used
+	now = now.Add(time.Hour)
+	waker.Run(ctx, nil, wake)
+	assert.Equal(t, map[string]int{"hit": 1, "miss": 1}, metrics.outcomes)
+	assert.False(t, waker.Accessed("b"))
+
+	// The budget frees up a day later
+	now = now.Add(24 * time.Hour)
+	waker.Slept("a", true)
+	waker.Run(ctx, nil, wake)
+	assert.Equal(t, 2.0, metrics.gauge)
+}
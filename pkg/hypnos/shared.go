@@ -0,0 +1,202 @@
+package hypnos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// DefaultSharedImageTTL is how long an image no sandbox maps is kept for the
+// next wake.
+const DefaultSharedImageTTL = 10 * time.Minute
+
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// SharedImages is a node-local store of decompressed memory images, keyed by
+// digest. Sandboxes woken from identical images map the same file, so the
+// kernel keeps one copy of the pages they have not written in its page
+// cache; the runtime maps images privately, so written pages are copied.
+//
+// Removing an image only stops later wakes from sharing it: sandboxes that
+// map it keep the file's pages until they exit.
+type SharedImages struct {
+	Dir string
+	TTL time.Duration
+
+	mu     sync.Mutex
+	images map[string]*sharedImage
+	users  map[domain.SandboxID]string // Digest of the image each woken sandbox maps
+	now    func() time.Time
+}
+
+type sharedImage struct {
+	path     string
+	size     int64
+	users    int
+	lastUsed time.Time
+	ready    chan struct{} // Closed once the image is filled
+	err      error
+}
+
+// NewSharedImages creates a store in dir. Images left in dir by a previous
+// agent are removed, as nothing tracks their users.
+func NewSharedImages(dir string, ttl time.Duration) (*SharedImages, error) {
+	if ttl <= 0 {
+		ttl = DefaultSharedImageTTL
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create shared image dir: %w", err)
+	}
+	stale, err := filepath.Glob(filepath.Join(dir, "*.mem*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range stale {
+		os.Remove(path)
+	}
+	return &SharedImages{
+		Dir:    dir,
+		TTL:    ttl,
+		images: make(map[string]*sharedImage),
+		users:  make(map[domain.SandboxID]string),
+		now:    time.Now,
+	}, nil
+}
+
+// Acquire returns the path of the image with the given digest for sandbox
+// id to map, calling fill to write the image if the node does not have it.
+// Concurrent wakes of the same image wait for a single fill. A sandbox maps
+// one image at a time; acquiring another releases the previous one.
+func (s *SharedImages) Acquire(id domain.SandboxID, digest string, fill func(path string) error) (string, error) {
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid memory image digest %q", digest)
+	}
+
+	s.mu.Lock()
+	img, ok := s.images[digest]
+	if !ok {
+		img = &sharedImage{
+			path:  filepath.Join(s.Dir, strings.TrimPrefix(digest, "sha256:")+".mem"),
+			ready: make(chan struct{}),
+		}
+		s.images[digest] = img
+		s.mu.Unlock()
+
+		img.err = img.fill(fill)
+
+		s.mu.Lock()
+		img.lastUsed = s.now()
+		if img.err != nil {
+			delete(s.images, digest)
+		}
+		close(img.ready)
+	} else {
+		s.mu.Unlock()
+		<-img.ready
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+
+	if img.err != nil {
+		return "", img.err
+	}
+	s.release(id)
+	img.users++
+	img.lastUsed = s.now()
+	s.users[id] = digest
+	return img.path, nil
+}
+
+func (img *sharedImage) fill(fill func(path string) error) error {
+	tmp := img.path + ".tmp"
+	if err := fill(tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to stat memory image: %w", err)
+	}
+	if err := os.Rename(tmp, img.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to finalize memory image: %w", err)
+	}
+	img.size = info.Size()
+	return nil
+}
+
+// Release records that sandbox id no longer maps its image.
+func (s *SharedImages) Release(id domain.SandboxID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.release(id)
+}
+
+func (s *SharedImages) release(id domain.SandboxID) {
+	digest, ok := s.users[id]
+	if !ok {
+		return
+	}
+	delete(s.users, id)
+	if img, ok := s.images[digest]; ok {
+		img.users--
+		img.lastUsed = s.now()
+	}
+}
+
+// Prune releases the images of sandboxes that are no longer in live and
+// removes images no sandbox has mapped for the TTL.
+func (s *SharedImages) Prune(live map[domain.SandboxID]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.users {
+		if !live[id] {
+			s.release(id)
+		}
+	}
+	now := s.now()
+	for digest, img := range s.images {
+		select {
+		case <-img.ready:
+		default:
+			continue // Still filling
+		}
+		if img.users == 0 && now.Sub(img.lastUsed) >= s.TTL {
+			os.Remove(img.path)
+			delete(s.images, digest)
+		}
+	}
+}
+
+// Usage reports the images' bytes by how many sandboxes map them.
+func (s *SharedImages) Usage() domain.MemorySharing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var usage domain.MemorySharing
+	for _, img := range s.images {
+		select {
+		case <-img.ready:
+		default:
+			continue
+		}
+		usage.Images++
+		size := uint64(img.size)
+		switch {
+		case img.users > 1:
+			usage.SharedBytes += size
+			usage.SavedBytes += size * uint64(img.users-1)
+		case img.users == 1:
+			usage.PrivateBytes += size
+		}
+	}
+	return usage
+}
@@ -0,0 +1,148 @@
+package hypnos
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+const testDigest = "sha256:" + "ab01ab01ab01ab01ab01ab01ab01ab01ab01ab01ab01ab01ab01ab01ab01ab01"
+
+func TestSharedImages_AcquireFillsOnce(t *testing.T) {
+	shared, err := NewSharedImages(t.TempDir(), time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+	shared.now = func() time.Time { return now }
+
+	var fills atomic.Int32
+	fill := func(path string) error {
+		fills.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return os.WriteFile(path, []byte("memory"), 0644)
+	}
+
+	var wg sync.WaitGroup
+	paths := make([]string, 3)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			paths[i], err = shared.Acquire(domain.SandboxID(rune('a'+i)), testDigest, fill)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), fills.Load())
+	assert.Equal(t, paths[0], paths[1])
+	assert.Equal(t, paths[0], paths[2])
+	assert.Equal(t, domain.MemorySharing{Images: 1, SharedBytes: 6, SavedBytes: 12}, shared.Usage())
+
+	// Sandboxes that are gone release their image
+	shared.Prune(map[domain.SandboxID]bool{"a": true})
+	assert.Equal(t, domain.MemorySharing{Images: 1, PrivateBytes: 6}, shared.Usage())
+
+	// Unused images are kept for the TTL
+	shared.Release("a")
+	shared.Prune(nil)
+	assert.FileExists(t, paths[0])
+	now = now.Add(time.Minute)
+	shared.Prune(nil)
+	assert.NoFileExists(t, paths[0])
+	assert.Equal(t, domain.MemorySharing{}, shared.Usage())
+}
+
+func TestSharedImages_FillError(t *testing.T) {
+	shared, err := NewSharedImages(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	_, err = shared.Acquire("a", "sha256:../../etc", nil)
+	assert.ErrorContains(t, err, "invalid memory image digest")
+
+	_, err = shared.Acquire("a", testDigest, func(path string) error { return os.ErrNotExist })
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// A failed fill is retried by the next wake
+	path, err := shared.Acquire("a", testDigest, func(path string) error {
+		return os.WriteFile(path, []byte("memory"), 0644)
+	})
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+	assert.Equal(t, 1, shared.Usage().Images)
+}
+
+func TestSleepAndWake_SharedImage(t *testing.T) {
+	ctx := context.Background()
+	runtime := tartarus.NewMockRuntime(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})))
+	store, err := erebus.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	manager := NewManager(runtime, store, t.TempDir())
+	manager.Shared, err = NewSharedImages(t.TempDir(), time.Minute)
+	require.NoError(t, err)
+
+	var records []*SleepRecord
+	for _, id := range []domain.SandboxID{"sb-1", "sb-2"} {
+		req := &domain.SandboxRequest{ID: id, Template: "tpl-1"}
+		_, err := runtime.Launch(ctx, req, tartarus.VMConfig{CPUs: 1, MemoryMB: 128})
+		require.NoError(t, err)
+		record, err := manager.Sleep(ctx, id, nil)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(record.MemoryDigest, "sha256:"))
+		records = append(records, record)
+	}
+
+	// Both sandboxes slept with the same memory image
+	records[1].SnapshotKey = records[0].SnapshotKey
+	records[1].MemoryDigest = records[0].MemoryDigest
+
+	for _, record := range records {
+		_, err := manager.Wake(ctx, record.SandboxID)
+		require.NoError(t, err)
+	}
+	images, err := filepath.Glob(filepath.Join(manager.Shared.Dir, "*.mem"))
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	info, err := os.Stat(images[0])
+	require.NoError(t, err)
+	size := uint64(info.Size())
+	assert.Equal(t, &domain.MemorySharing{Images: 1, SharedBytes: size, SavedBytes: size}, manager.MemorySharing(ctx))
+
+	require.NoError(t, runtime.Kill(ctx, "sb-2"))
+	assert.Equal(t, &domain.MemorySharing{Images: 1, PrivateBytes: size}, manager.MemorySharing(ctx))
+
+	// An image that does not match its digest is refused
+	_, err = manager.Sleep(ctx, "sb-1", nil)
+	require.NoError(t, err)
+	record, _ := manager.getRecord("sb-1")
+	record.MemoryDigest = testDigest
+	_, err = manager.Wake(ctx, "sb-1")
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestKSMStats(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages_shared"), []byte("2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages_sharing"), []byte("10\n"), 0644))
+
+	shared, saved, err := ksmStats(dir)
+	require.NoError(t, err)
+	page := uint64(os.Getpagesize())
+	assert.Equal(t, 2*page, shared)
+	assert.Equal(t, 10*page, saved)
+
+	_, _, err = ksmStats(t.TempDir())
+	assert.Error(t, err)
+}
@@ -63,11 +63,17 @@ func (j *AeacusJudge) PreAdmit(ctx context.Context, req *domain.SandboxRequest)
 		Metadata:        req.Metadata,
 	}
 
-	// Capture identity if available
-	if identity, ok := cerberus.GetIdentity(ctx); ok {
+	// Capture identity: prefer the submitter stamped by Olympus, else the request context
+	if sub := req.Submitter; sub != nil {
+		auditRecord.IdentityID = sub.ID
+		auditRecord.IdentityType = sub.Type
+		auditRecord.TenantID = sub.TenantID
+		auditRecord.IdentityRoles = sub.Roles
+	} else if identity, ok := cerberus.GetIdentity(ctx); ok {
 		auditRecord.IdentityID = identity.ID
 		auditRecord.IdentityType = string(identity.Type)
 		auditRecord.TenantID = identity.TenantID
+		auditRecord.IdentityRoles = identity.Roles
 	}
 
 	if err := j.sink.Emit(ctx, auditRecord); err != nil {
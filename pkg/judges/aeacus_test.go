@@ -103,4 +103,31 @@ func TestAeacusJudge_PreAdmit(t *testing.T) {
 			t.Errorf("expected retention 24h, got %v", req.Retention.MaxAge)
 		}
 	})
+	t.Run("RecordsSubmitter", func(t *testing.T) {
+		mockSink := NewMockAuditSink()
+		judge := NewAeacusJudge(logger, mockSink)
+
+		req := &domain.SandboxRequest{
+			ID:       "test-sandbox-submitter",
+			Template: "test-template",
+			Submitter: &domain.Submitter{
+				ID:       "alice",
+				Type:     "user",
+				TenantID: "acme",
+				Roles:    []string{"developer"},
+			},
+		}
+
+		if _, err := judge.PreAdmit(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		auditRecord := mockSink.LastRecord()
+		if auditRecord.IdentityID != "alice" || auditRecord.TenantID != "acme" {
+			t.Errorf("expected submitter alice/acme, got %s/%s", auditRecord.IdentityID, auditRecord.TenantID)
+		}
+		if len(auditRecord.IdentityRoles) != 1 || auditRecord.IdentityRoles[0] != "developer" {
+			t.Errorf("expected roles [developer], got %v", auditRecord.IdentityRoles)
+		}
+	})
 }
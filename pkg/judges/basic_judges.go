@@ -27,8 +27,8 @@ func NewResourceJudge(policyRepo themis.Repository, logger hermes.Logger) *Resou
 
 // PreAdmit validates a sandbox request's resource requirements against policy.
 func (j *ResourceJudge) PreAdmit(ctx context.Context, req *domain.SandboxRequest) (Verdict, error) {
-	// Load policy for the request's template
-	policy, err := j.policyRepo.GetPolicy(ctx, req.Template)
+	// Load the effective policy for the request's tenant and template
+	effective, err := themis.ResolveRequest(ctx, j.policyRepo, req)
 	if err != nil {
 		j.logger.Error(ctx, "Failed to load policy for resource validation", map[string]any{
 			"template": req.Template,
@@ -36,6 +36,7 @@ func (j *ResourceJudge) PreAdmit(ctx context.Context, req *domain.SandboxRequest
 		})
 		return VerdictReject, fmt.Errorf("failed to load policy: %w", err)
 	}
+	policy := effective.Policy
 
 	// Validate CPU
 	if req.Resources.CPU > policy.Resources.CPU {
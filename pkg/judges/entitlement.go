@@ -0,0 +1,201 @@
+package judges
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+// Request metadata written by the entitlement judge on rejection.
+const (
+	MetadataRejectCode  = "reject_code"  // Machine-readable reason a judge rejected the request
+	MetadataPurchaseURL = "purchase_url" // Where the missing entitlement can be bought
+
+	// RejectCodePurchaseRequired rejects requests for premium templates
+	// from tenants without the entitlement.
+	RejectCodePurchaseRequired = "purchase_required"
+)
+
+// LicenseChecker answers whether a tenant holds an entitlement, e.g. by
+// asking a billing system.
+type LicenseChecker interface {
+	Entitled(ctx context.Context, tenantID, sku string) (bool, error)
+}
+
+// EntitlementJudge rejects requests for premium templates from tenants that
+// have not bought them. Templates are premium when their effective policy
+// sets premium; tenants hold the SKUs their effective policy grants in
+// entitlements, and any the license checker confirms.
+type EntitlementJudge struct {
+	policyRepo themis.Repository
+	license    LicenseChecker
+	logger     hermes.Logger
+}
+
+// NewEntitlementJudge creates a new entitlement judge. license may be nil to
+// rely on policy grants only.
+func NewEntitlementJudge(policyRepo themis.Repository, license LicenseChecker, logger hermes.Logger) *EntitlementJudge {
+	return &EntitlementJudge{
+		policyRepo: policyRepo,
+		license:    license,
+		logger:     logger,
+	}
+}
+
+// PreAdmit checks the submitter's tenant holds the template's entitlement.
+func (j *EntitlementJudge) PreAdmit(ctx context.Context, req *domain.SandboxRequest) (Verdict, error) {
+	effective, err := themis.ResolveRequest(ctx, j.policyRepo, req)
+	if err != nil {
+		j.logger.Error(ctx, "Failed to load policy for entitlement check", map[string]any{
+			"template": req.Template,
+			"error":    err,
+		})
+		return VerdictReject, fmt.Errorf("failed to load policy: %w", err)
+	}
+	premium := effective.Policy.Premium
+	if premium == nil {
+		return VerdictAccept, nil
+	}
+
+	var tenantID string
+	if req.Submitter != nil {
+		tenantID = req.Submitter.TenantID
+	}
+	if slices.Contains(effective.Policy.Entitlements, premium.SKU) {
+		return VerdictAccept, nil
+	}
+	if j.license != nil && tenantID != "" {
+		entitled, err := j.license.Entitled(ctx, tenantID, premium.SKU)
+		if err != nil {
+			j.logger.Error(ctx, "Failed to check entitlement with license API", map[string]any{
+				"tenant_id": tenantID,
+				"sku":       premium.SKU,
+				"error":     err,
+			})
+			return VerdictReject, fmt.Errorf("failed to check entitlement: %w", err)
+		}
+		if entitled {
+			return VerdictAccept, nil
+		}
+	}
+
+	reason := fmt.Sprintf("template %s requires the %s entitlement", req.Template, premium.SKU)
+	j.logger.Info(ctx, "Request rejected: entitlement missing", map[string]any{
+		"sandbox_id": req.ID,
+		"template":   req.Template,
+		"tenant_id":  tenantID,
+		"sku":        premium.SKU,
+	})
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]string)
+	}
+	req.Metadata[MetadataRejectReason] = reason
+	req.Metadata[MetadataRejectCode] = RejectCodePurchaseRequired
+	if premium.PurchaseURL != "" {
+		req.Metadata[MetadataPurchaseURL] = premium.PurchaseURL
+	}
+	return VerdictReject, nil
+}
+
+// HTTPLicenseChecker asks an external license API whether a tenant holds an
+// entitlement:
+//
+//	GET <URL>?tenant=<tenant>&sku=<sku>  ->  {"entitled": true}
+//
+// Answers are cached for TTL. When the API fails, an expired answer is used
+// if there is one, so a license API outage does not lock tenants out.
+type HTTPLicenseChecker struct {
+	URL    string
+	Token  string // Sent as a bearer token if set
+	TTL    time.Duration
+	Client *http.Client
+
+	mu    sync.Mutex
+	cache map[licenseKey]licenseAnswer
+}
+
+type licenseKey struct{ tenantID, sku string }
+
+type licenseAnswer struct {
+	entitled bool
+	expires  time.Time
+}
+
+// NewHTTPLicenseChecker creates a checker for the license API at url.
+func NewHTTPLicenseChecker(url, token string, ttl time.Duration) *HTTPLicenseChecker {
+	return &HTTPLicenseChecker{
+		URL:    url,
+		Token:  token,
+		TTL:    ttl,
+		Client: &http.Client{Timeout: 5 * time.Second},
+		cache:  make(map[licenseKey]licenseAnswer),
+	}
+}
+
+// Entitled returns the cached answer or asks the license API.
+func (c *HTTPLicenseChecker) Entitled(ctx context.Context, tenantID, sku string) (bool, error) {
+	key := licenseKey{tenantID, sku}
+	c.mu.Lock()
+	cached, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.entitled, nil
+	}
+
+	entitled, err := c.fetch(ctx, tenantID, sku)
+	if err != nil {
+		if ok {
+			return cached.entitled, nil
+		}
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = licenseAnswer{entitled: entitled, expires: time.Now().Add(c.TTL)}
+	c.mu.Unlock()
+	return entitled, nil
+}
+
+func (c *HTTPLicenseChecker) fetch(ctx context.Context, tenantID, sku string) (bool, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return false, err
+	}
+	q := u.Query()
+	q.Set("tenant", tenantID)
+	q.Set("sku", sku)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("license API returned %s", resp.Status)
+	}
+
+	var answer struct {
+		Entitled bool `json:"entitled"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return false, fmt.Errorf("invalid license API response: %w", err)
+	}
+	return answer.Entitled, nil
+}
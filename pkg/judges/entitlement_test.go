@@ -0,0 +1,112 @@
+package judges
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+type staticLicenses map[string]bool
+
+func (s staticLicenses) Entitled(_ context.Context, tenantID, sku string) (bool, error) {
+	return s[tenantID+"/"+sku], nil
+}
+
+func TestEntitlementJudge_PreAdmit(t *testing.T) {
+	ctx := context.Background()
+	repo := themis.NewMemoryRepo()
+	require.NoError(t, repo.UpsertPolicy(ctx, &domain.SandboxPolicy{
+		ID:         "pol-gpu",
+		TemplateID: "gpu-notebook",
+		Premium:    &domain.PremiumTemplate{SKU: "gpu-pro", PurchaseURL: "https://example.com/buy/gpu-pro"},
+	}))
+	require.NoError(t, repo.UpsertPolicy(ctx, &domain.SandboxPolicy{
+		ID:           "pol-acme",
+		TenantID:     "acme",
+		Entitlements: []string{"gpu-pro"},
+	}))
+
+	judge := NewEntitlementJudge(repo, staticLicenses{"globex/gpu-pro": true}, hermes.NewSlogAdapter())
+
+	tests := []struct {
+		name     string
+		template domain.TemplateID
+		tenant   string
+		want     Verdict
+	}{
+		{"free template", "python", "initech", VerdictAccept},
+		{"granted by tenant policy", "gpu-notebook", "acme", VerdictAccept},
+		{"granted by license API", "gpu-notebook", "globex", VerdictAccept},
+		{"not entitled", "gpu-notebook", "initech", VerdictReject},
+		{"anonymous", "gpu-notebook", "", VerdictReject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.SandboxRequest{ID: "sbx", Template: tt.template}
+			if tt.tenant != "" {
+				req.Submitter = &domain.Submitter{TenantID: tt.tenant}
+			}
+			got, err := judge.PreAdmit(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			if tt.want == VerdictReject {
+				assert.Equal(t, RejectCodePurchaseRequired, req.Metadata[MetadataRejectCode])
+				assert.Equal(t, "https://example.com/buy/gpu-pro", req.Metadata[MetadataPurchaseURL])
+				assert.Contains(t, req.Metadata[MetadataRejectReason], "gpu-pro")
+			}
+		})
+	}
+}
+
+func TestHTTPLicenseChecker_Caches(t *testing.T) {
+	var calls atomic.Int32
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if down.Load() || r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		entitled := r.URL.Query().Get("tenant") == "acme" && r.URL.Query().Get("sku") == "gpu-pro"
+		json.NewEncoder(w).Encode(map[string]bool{"entitled": entitled})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	checker := NewHTTPLicenseChecker(srv.URL, "s3cr3t", time.Hour)
+
+	ok, err := checker.Entitled(ctx, "acme", "gpu-pro")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = checker.Entitled(ctx, "initech", "gpu-pro")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Fresh answers come from the cache
+	ok, err = checker.Entitled(ctx, "acme", "gpu-pro")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Expired answers are kept while the API is down
+	checker.TTL = 0
+	checker.cache[licenseKey{"acme", "gpu-pro"}] = licenseAnswer{entitled: true}
+	down.Store(true)
+	ok, err = checker.Entitled(ctx, "acme", "gpu-pro")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// With nothing cached the error is returned
+	_, err = checker.Entitled(ctx, "globex", "gpu-pro")
+	assert.Error(t, err)
+}
@@ -0,0 +1,250 @@
+package judges
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// Request metadata read and written by the runtime compatibility judge.
+const (
+	MetadataIsolationType    = "isolation_type"    // Isolation class the agent must use
+	MetadataPreferredRuntime = "preferred_runtime" // Isolation class hint; may be rewritten
+	MetadataRuntimeVerdict   = "runtime_verdict"   // Why the judge chose the isolation class
+	MetadataRejectReason     = "reject_reason"     // Why a judge rejected the request
+)
+
+// TemplateSource looks up templates by ID.
+type TemplateSource interface {
+	GetTemplate(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, error)
+}
+
+// NodeSource lists registered nodes.
+type NodeSource interface {
+	ListNodes(ctx context.Context) ([]domain.NodeStatus, error)
+}
+
+// compatOrder is the order in which isolation classes are tried when the
+// judge picks one.
+var compatOrder = []domain.IsolationType{domain.IsolationMicroVM, domain.IsolationGVisor, domain.IsolationWASM}
+
+// RuntimeCompatJudge rejects workloads the target isolation class cannot
+// run, so they fail at submission with a reason instead of at launch. A
+// class pinned with isolation_type is never changed; a preferred_runtime
+// hint or automatic selection is rewritten to a class that fits.
+type RuntimeCompatJudge struct {
+	templates TemplateSource
+	nodes     NodeSource
+	logger    hermes.Logger
+}
+
+// NewRuntimeCompatJudge creates a new runtime compatibility judge. templates
+// and nodes may be nil: Wasm modules are then recognised by the
+// wasm_module metadata only, and node capabilities are not checked.
+func NewRuntimeCompatJudge(templates TemplateSource, nodes NodeSource, logger hermes.Logger) *RuntimeCompatJudge {
+	return &RuntimeCompatJudge{
+		templates: templates,
+		nodes:     nodes,
+		logger:    logger,
+	}
+}
+
+// workloadNeeds is what a request asks of its isolation class.
+type workloadNeeds struct {
+	wasmModule bool // Ships a Wasm module rather than an OCI image or rootfs
+	gpu        int
+	privileged bool // Kernel modules, devices or a privileged guest
+	ports      bool // Exposes ports to the network
+}
+
+// PreAdmit checks the request's isolation class against its needs and the
+// classes and GPUs available on registered nodes.
+func (j *RuntimeCompatJudge) PreAdmit(ctx context.Context, req *domain.SandboxRequest) (Verdict, error) {
+	needs, err := j.needs(ctx, req)
+	if err != nil {
+		return VerdictReject, err
+	}
+	available, maxGPU, err := j.capabilities(ctx)
+	if err != nil {
+		return VerdictReject, err
+	}
+
+	if needs.gpu > 0 && maxGPU >= 0 && needs.gpu > maxGPU {
+		return j.reject(ctx, req, fmt.Sprintf("%d GPUs requested but no node has more than %d", needs.gpu, maxGPU))
+	}
+
+	pinned := domain.IsolationType(req.Metadata[MetadataIsolationType])
+	if pinned != "" && pinned != domain.IsolationAuto {
+		if why := needs.unsuitable(pinned); why != "" {
+			return j.reject(ctx, req, fmt.Sprintf("isolation %s cannot run this workload: %s", pinned, why))
+		}
+		if available != nil && !available[pinned] {
+			return j.reject(ctx, req, fmt.Sprintf("no node offers isolation %s", pinned))
+		}
+		return VerdictAccept, nil
+	}
+
+	preferred := domain.IsolationType(req.Metadata[MetadataPreferredRuntime])
+	if preferred == domain.IsolationAuto {
+		preferred = ""
+	}
+	if preferred != "" && needs.unsuitable(preferred) == "" && (available == nil || available[preferred]) {
+		return VerdictAccept, nil
+	}
+	// Automatic selection only needs help when the workload rules classes out
+	if preferred == "" && needs == (workloadNeeds{}) {
+		return VerdictAccept, nil
+	}
+
+	var reasons []string
+	for _, class := range compatOrder {
+		why := needs.unsuitable(class)
+		if why == "" && available != nil && !available[class] {
+			why = "not offered by any node"
+		}
+		if why != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", class, why))
+			continue
+		}
+
+		verdict := fmt.Sprintf("selected %s", class)
+		if preferred != "" {
+			verdict = fmt.Sprintf("selected %s instead of preferred %s: %s", class, preferred, unavailableReason(needs, preferred, available))
+		}
+		if req.Metadata == nil {
+			req.Metadata = make(map[string]string)
+		}
+		req.Metadata[MetadataIsolationType] = string(class)
+		req.Metadata[MetadataRuntimeVerdict] = verdict
+		j.logger.Info(ctx, "Request isolation rewritten for compatibility", map[string]any{
+			"sandbox_id": req.ID,
+			"template":   req.Template,
+			"isolation":  class,
+			"verdict":    verdict,
+		})
+		return VerdictAccept, nil
+	}
+	return j.reject(ctx, req, "no isolation class can run this workload ("+strings.Join(reasons, "; ")+")")
+}
+
+func unavailableReason(needs workloadNeeds, class domain.IsolationType, available map[domain.IsolationType]bool) string {
+	if why := needs.unsuitable(class); why != "" {
+		return why
+	}
+	if available != nil && !available[class] {
+		return "not offered by any node"
+	}
+	return ""
+}
+
+func (j *RuntimeCompatJudge) reject(ctx context.Context, req *domain.SandboxRequest, reason string) (Verdict, error) {
+	j.logger.Info(ctx, "Request rejected: incompatible runtime", map[string]any{
+		"sandbox_id": req.ID,
+		"template":   req.Template,
+		"reason":     reason,
+	})
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]string)
+	}
+	req.Metadata[MetadataRejectReason] = reason
+	return VerdictReject, nil
+}
+
+// unsuitable returns why class cannot run the workload, or "" if it can.
+func (n workloadNeeds) unsuitable(class domain.IsolationType) string {
+	switch class {
+	case domain.IsolationWASM:
+		switch {
+		case !n.wasmModule:
+			return "the workload is not a Wasm module"
+		case n.gpu > 0:
+			return "Wasm sandboxes have no GPU access"
+		case n.privileged:
+			return "Wasm sandboxes cannot run privileged workloads"
+		case n.ports:
+			return "Wasm sandboxes cannot expose ports"
+		}
+	case domain.IsolationGVisor:
+		switch {
+		case n.wasmModule:
+			return "a Wasm module needs the wasm runtime"
+		case n.gpu > 0:
+			return "gVisor sandboxes have no GPU passthrough"
+		case n.privileged:
+			return "gVisor does not load kernel modules or pass through devices"
+		}
+	case domain.IsolationMicroVM:
+		if n.wasmModule {
+			return "a Wasm module needs the wasm runtime"
+		}
+	default:
+		return fmt.Sprintf("unknown isolation class %q", class)
+	}
+	return ""
+}
+
+// needs derives the workload's requirements from the request and its template.
+func (j *RuntimeCompatJudge) needs(ctx context.Context, req *domain.SandboxRequest) (workloadNeeds, error) {
+	md := req.Metadata
+	needs := workloadNeeds{
+		wasmModule: md["wasm_module"] != "",
+		gpu:        req.Resources.GPU.Count,
+		privileged: md["privileged"] == "true" || md["kernel_modules"] != "" || md["devices"] != "",
+		ports:      md["ports"] != "",
+	}
+	if !needs.wasmModule && j.templates != nil && req.Template != "" {
+		tpl, err := j.templates.GetTemplate(ctx, req.Template)
+		if err != nil {
+			j.logger.Error(ctx, "Failed to load template for runtime compatibility", map[string]any{
+				"template": req.Template,
+				"error":    err,
+			})
+			return needs, fmt.Errorf("failed to load template: %w", err)
+		}
+		needs.wasmModule = strings.EqualFold(filepath.Ext(tpl.BaseImage), ".wasm")
+	}
+	return needs, nil
+}
+
+// capabilities returns the isolation classes offered by registered nodes and
+// the most GPUs on any one node. available is nil, and maxGPU -1, when
+// nodes are unknown; nodes that do not advertise their runtimes are assumed
+// to offer every class.
+func (j *RuntimeCompatJudge) capabilities(ctx context.Context) (map[domain.IsolationType]bool, int, error) {
+	if j.nodes == nil {
+		return nil, -1, nil
+	}
+	nodes, err := j.nodes.ListNodes(ctx)
+	if err != nil {
+		j.logger.Error(ctx, "Failed to list nodes for runtime compatibility", map[string]any{
+			"error": err,
+		})
+		return nil, -1, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return nil, -1, nil
+	}
+
+	available := make(map[domain.IsolationType]bool)
+	maxGPU := 0
+	for _, node := range nodes {
+		if node.Capacity.GPU > maxGPU {
+			maxGPU = node.Capacity.GPU
+		}
+		runtimes := node.Labels[domain.NodeLabelRuntimes]
+		if runtimes == "" {
+			for _, class := range compatOrder {
+				available[class] = true
+			}
+			continue
+		}
+		for _, class := range strings.Split(runtimes, ",") {
+			available[domain.IsolationType(strings.TrimSpace(class))] = true
+		}
+	}
+	return available, maxGPU, nil
+}
@@ -0,0 +1,166 @@
+package judges
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+type fakeTemplates map[domain.TemplateID]*domain.TemplateSpec
+
+func (f fakeTemplates) GetTemplate(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, error) {
+	if tpl, ok := f[id]; ok {
+		return tpl, nil
+	}
+	return nil, errors.New("template not found")
+}
+
+type fakeNodes []domain.NodeStatus
+
+func (f fakeNodes) ListNodes(ctx context.Context) ([]domain.NodeStatus, error) {
+	return f, nil
+}
+
+func node(runtimes string, gpu int) domain.NodeStatus {
+	return domain.NodeStatus{NodeInfo: domain.NodeInfo{
+		Labels:   map[string]string{domain.NodeLabelRuntimes: runtimes},
+		Capacity: domain.ResourceCapacity{GPU: gpu},
+	}}
+}
+
+func TestRuntimeCompatJudge_PreAdmit(t *testing.T) {
+	templates := fakeTemplates{
+		"python": {ID: "python", BaseImage: "python:3.11"},
+		"fn":     {ID: "fn", BaseImage: "registry/fn/handler.wasm"},
+	}
+	allNodes := fakeNodes{node("microvm,gvisor,wasm", 2)}
+
+	tests := []struct {
+		name          string
+		nodes         NodeSource
+		req           *domain.SandboxRequest
+		want          Verdict
+		wantIsolation string
+		wantReason    string
+	}{
+		{
+			name:  "Accept plain OCI workload",
+			nodes: allNodes,
+			req:   &domain.SandboxRequest{Template: "python"},
+			want:  VerdictAccept,
+		},
+		{
+			name:  "Accept compatible pinned class",
+			nodes: allNodes,
+			req: &domain.SandboxRequest{Template: "python",
+				Metadata: map[string]string{MetadataIsolationType: "gvisor"}},
+			want:          VerdictAccept,
+			wantIsolation: "gvisor",
+		},
+		{
+			name:  "Reject GPU workload pinned to gVisor",
+			nodes: allNodes,
+			req: &domain.SandboxRequest{Template: "python",
+				Resources: domain.ResourceSpec{GPU: domain.GPURequest{Count: 1}},
+				Metadata:  map[string]string{MetadataIsolationType: "gvisor"}},
+			want:          VerdictReject,
+			wantIsolation: "gvisor",
+			wantReason:    "isolation gvisor cannot run this workload: gVisor sandboxes have no GPU passthrough",
+		},
+		{
+			name:  "Reject OCI image pinned to wasm",
+			nodes: allNodes,
+			req: &domain.SandboxRequest{Template: "python",
+				Metadata: map[string]string{MetadataIsolationType: "wasm"}},
+			want:          VerdictReject,
+			wantIsolation: "wasm",
+			wantReason:    "isolation wasm cannot run this workload: the workload is not a Wasm module",
+		},
+		{
+			name:  "Rewrite preferred wasm for privileged workload",
+			nodes: allNodes,
+			req: &domain.SandboxRequest{Template: "python",
+				Metadata: map[string]string{MetadataPreferredRuntime: "wasm", "privileged": "true"}},
+			want:          VerdictAccept,
+			wantIsolation: "microvm",
+		},
+		{
+			name:          "Select wasm for Wasm module template",
+			nodes:         allNodes,
+			req:           &domain.SandboxRequest{Template: "fn"},
+			want:          VerdictAccept,
+			wantIsolation: "wasm",
+		},
+		{
+			name:       "Reject Wasm module exposing ports",
+			nodes:      allNodes,
+			req:        &domain.SandboxRequest{Template: "fn", Metadata: map[string]string{"ports": "8080"}},
+			want:       VerdictReject,
+			wantReason: "no isolation class can run this workload",
+		},
+		{
+			name:          "Select gVisor when nodes lack microVMs",
+			nodes:         fakeNodes{node("gvisor", 0)},
+			req:           &domain.SandboxRequest{Template: "python", Metadata: map[string]string{"ports": "8080"}},
+			want:          VerdictAccept,
+			wantIsolation: "gvisor",
+		},
+		{
+			name:          "Reject pinned class no node offers",
+			nodes:         fakeNodes{node("microvm", 0)},
+			req:           &domain.SandboxRequest{Template: "python", Metadata: map[string]string{MetadataIsolationType: "gvisor"}},
+			want:          VerdictReject,
+			wantIsolation: "gvisor",
+			wantReason:    "no node offers isolation gvisor",
+		},
+		{
+			name:  "Reject more GPUs than any node has",
+			nodes: allNodes,
+			req: &domain.SandboxRequest{Template: "python",
+				Resources: domain.ResourceSpec{GPU: domain.GPURequest{Count: 4}}},
+			want:       VerdictReject,
+			wantReason: "4 GPUs requested but no node has more than 2",
+		},
+		{
+			name:  "Skip node checks without nodes",
+			nodes: nil,
+			req: &domain.SandboxRequest{Template: "python",
+				Resources: domain.ResourceSpec{GPU: domain.GPURequest{Count: 4}}},
+			want:          VerdictAccept,
+			wantIsolation: "microvm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			judge := NewRuntimeCompatJudge(templates, tt.nodes, hermes.NewSlogAdapter())
+			got, err := judge.PreAdmit(context.Background(), tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantIsolation, tt.req.Metadata[MetadataIsolationType])
+			if tt.wantReason != "" {
+				assert.Contains(t, tt.req.Metadata[MetadataRejectReason], tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRuntimeCompatJudge_RecordsRewriteVerdict(t *testing.T) {
+	judge := NewRuntimeCompatJudge(nil, nil, hermes.NewSlogAdapter())
+	req := &domain.SandboxRequest{
+		Resources: domain.ResourceSpec{GPU: domain.GPURequest{Count: 1}},
+		Metadata:  map[string]string{MetadataPreferredRuntime: "gvisor"},
+	}
+
+	got, err := judge.PreAdmit(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, VerdictAccept, got)
+	assert.Equal(t, "microvm", req.Metadata[MetadataIsolationType])
+	assert.Equal(t, "selected microvm instead of preferred gvisor: gVisor sandboxes have no GPU passthrough",
+		req.Metadata[MetadataRuntimeVerdict])
+}
@@ -22,6 +22,7 @@ type AuditRecord struct {
 	IdentityID      string                 `json:"identity_id,omitempty"`
 	IdentityType    string                 `json:"identity_type,omitempty"`
 	TenantID        string                 `json:"tenant_id,omitempty"`
+	IdentityRoles   []string               `json:"identity_roles,omitempty"`
 }
 
 // AuditSink is the interface for audit record emission.
@@ -45,7 +46,7 @@ func NewLogAuditSink(logger hermes.Logger) *LogAuditSink {
 
 // Emit writes the audit record as a structured log entry.
 func (s *LogAuditSink) Emit(ctx context.Context, record *AuditRecord) error {
-	s.logger.Info(ctx, "Aeacus: Audit Record", map[string]any{
+	fields := map[string]any{
 		"event":            record.Event,
 		"sandbox_id":       record.SandboxID,
 		"audit_id":         record.AuditID,
@@ -53,7 +54,14 @@ func (s *LogAuditSink) Emit(ctx context.Context, record *AuditRecord) error {
 		"compliance_level": record.ComplianceLevel,
 		"retention_policy": record.RetentionPolicy,
 		"timestamp":        record.Timestamp.Format(time.RFC3339),
-	})
+		"identity_id":      record.IdentityID,
+		"identity_type":    record.IdentityType,
+		"tenant_id":        record.TenantID,
+	}
+	if len(record.Metadata) > 0 {
+		fields["metadata"] = record.Metadata
+	}
+	s.logger.Info(ctx, "Aeacus: Audit Record", fields)
 	return nil
 }
 
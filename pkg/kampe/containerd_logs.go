@@ -0,0 +1,214 @@
+package kampe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ContainerdLogConfig controls where containerd task output is kept.
+// containerd has no log store of its own: task stdio is attached to FIFOs
+// that the adapter copies into one log file per sandbox.
+type ContainerdLogConfig struct {
+	Dir      string // Directory of per-sandbox log files (default /var/lib/tartarus/containerd/logs)
+	MaxBytes int64  // Size at which a sandbox's log is rotated (default 10 MiB)
+	MaxFiles int    // Rotated files kept per sandbox, besides the live one (default 3)
+}
+
+func (c ContainerdLogConfig) withDefaults() ContainerdLogConfig {
+	if c.Dir == "" {
+		c.Dir = "/var/lib/tartarus/containerd/logs"
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = 10 << 20
+	}
+	if c.MaxFiles <= 0 {
+		c.MaxFiles = 3
+	}
+	return c
+}
+
+// rotatingLog is a log file that is rotated once it reaches maxBytes:
+// path.1 is the most recent rotated file, path.<maxFiles> the oldest kept.
+type rotatingLog struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newRotatingLog(path string, maxBytes int64, maxFiles int) (*rotatingLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log dir: %w", err)
+	}
+	l := &rotatingLog{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *rotatingLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would take the file past maxBytes.
+// Writes are never split, so a line is never torn across files.
+func (l *rotatingLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return 0, os.ErrClosed
+	}
+	if l.size > 0 && l.size+int64(len(p)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+func (l *rotatingLog) rotate() error {
+	l.file.Close()
+	l.file = nil
+	os.Remove(rotatedLogPath(l.path, l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(rotatedLogPath(l.path, i), rotatedLogPath(l.path, i+1))
+	}
+	if err := os.Rename(l.path, rotatedLogPath(l.path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return l.open()
+}
+
+func (l *rotatingLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Remove closes the log and deletes it along with its rotated files.
+func (l *rotatingLog) Remove() {
+	l.Close()
+	os.Remove(l.path)
+	for i := 1; i <= l.maxFiles; i++ {
+		os.Remove(rotatedLogPath(l.path, i))
+	}
+}
+
+func rotatedLogPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// streamRotatingLog copies a rotating log to w, oldest rotated file first.
+// In follow mode it keeps reading the live file, reopening it when it is
+// rotated, until exited reports true and everything has been read.
+func streamRotatingLog(ctx context.Context, path string, maxFiles int, w io.Writer, follow bool, exited func() bool) error {
+	for i := maxFiles; i >= 1; i-- {
+		if err := copyLogFile(rotatedLogPath(path, i), w); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer func() { file.Close() }()
+
+	if !follow {
+		_, err = io.Copy(w, file)
+		return err
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		// Check for exit before draining, so output written just before
+		// the task exited is never missed
+		done := exited()
+		if _, err := io.Copy(w, file); err != nil {
+			return err
+		}
+
+		// Rotated: finish the old file, then continue with the new one
+		if rotated, err := logRotated(file, path); err != nil {
+			return err
+		} else if rotated {
+			next, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to reopen log file: %w", err)
+			}
+			if _, err := io.Copy(w, file); err != nil {
+				next.Close()
+				return err
+			}
+			file.Close()
+			file = next
+			continue
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// logRotated reports whether path no longer names the open file.
+func logRotated(file *os.File, path string) (bool, error) {
+	current, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil // Between the rename and the reopen
+	}
+	if err != nil {
+		return false, err
+	}
+	open, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(open, current), nil
+}
+
+func copyLogFile(path string, w io.Writer) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,97 @@
+package kampe
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sb-1.log")
+	log, err := newRotatingLog(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		_, err := log.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, log.Close())
+
+	// Each write went past 10 bytes, so only the last two rotations are kept
+	read := func(p string) string {
+		data, _ := os.ReadFile(p)
+		return string(data)
+	}
+	assert.Equal(t, "line-4\n", read(path))
+	assert.Equal(t, "line-3\n", read(path+".1"))
+	assert.Equal(t, "line-2\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3")
+
+	var out bytes.Buffer
+	require.NoError(t, streamRotatingLog(context.Background(), path, 2, &out, false, nil))
+	assert.Equal(t, "line-2\nline-3\nline-4\n", out.String())
+
+	log.Remove()
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, path+".1")
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStreamRotatingLog_Follow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sb-1.log")
+	log, err := newRotatingLog(path, 16, 3)
+	require.NoError(t, err)
+
+	var exited atomic.Bool
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- streamRotatingLog(context.Background(), path, 3, out, true, exited.Load)
+	}()
+
+	// Output written while following, across rotations, arrives in order
+	var want strings.Builder
+	for i := 0; i < 6; i++ {
+		line := strings.Repeat(string(rune('a'+i)), 9) + "\n"
+		want.WriteString(line)
+		_, err := log.Write([]byte(line))
+		require.NoError(t, err)
+		time.Sleep(150 * time.Millisecond)
+	}
+	log.Close()
+	exited.Store(true)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("follow did not stop after the task exited")
+	}
+	assert.Equal(t, want.String(), out.String())
+}
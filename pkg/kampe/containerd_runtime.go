@@ -35,6 +35,8 @@ type containerdState struct {
 	StartedAt   time.Time
 	ExitCode    *int
 	ExitChannel <-chan containerd.ExitStatus
+	Log         *rotatingLog
+	CgroupDir   string // The task's cgroup v2 directory, if known
 	mu          sync.Mutex
 }
 
@@ -44,6 +46,9 @@ type ContainerdAdapter struct {
 	socketPath string
 	namespace  string
 	containers sync.Map // SandboxID -> *containerdState
+
+	// Logs configures the per-sandbox log files task output is captured to
+	Logs ContainerdLogConfig
 }
 
 // NewContainerdAdapter creates a new containerd adapter
@@ -117,9 +122,9 @@ func (c *ContainerdAdapter) Launch(ctx context.Context, req *domain.SandboxReque
 	}
 
 	// Add resource limits
-	if req.Resources.Mem > 0 {
-		memLimit := int64(req.Resources.Mem) * 1024 * 1024 // MB to bytes
-		specOpts = append(specOpts, oci.WithMemoryLimit(uint64(memLimit)))
+	if memory := tartarus.MemoryLimits(req); memory != nil {
+		// Memory, and memory.swap.max if the policy allows swap
+		specOpts = append(specOpts, withMemory(memory))
 	}
 	if req.Resources.CPU > 0 {
 		// CPU quota in microseconds per 100ms period
@@ -143,9 +148,18 @@ func (c *ContainerdAdapter) Launch(ctx context.Context, req *domain.SandboxReque
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
 
+	// Capture stdout and stderr through FIFOs into the sandbox's log file
+	logs := c.Logs.withDefaults()
+	logFile, err := newRotatingLog(filepath.Join(logs.Dir, containerID+".log"), logs.MaxBytes, logs.MaxFiles)
+	if err != nil {
+		container.Delete(ctx, containerd.WithSnapshotCleanup)
+		return nil, err
+	}
+
 	// Create task (the running process)
-	task, err := container.NewTask(ctx, cio.NewCreator(cio.WithStdio))
+	task, err := container.NewTask(ctx, cio.NewCreator(cio.WithStreams(nil, logFile, logFile)))
 	if err != nil {
+		logFile.Remove()
 		container.Delete(ctx, containerd.WithSnapshotCleanup)
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
@@ -154,6 +168,7 @@ func (c *ContainerdAdapter) Launch(ctx context.Context, req *domain.SandboxReque
 	exitStatusC, err := task.Wait(ctx)
 	if err != nil {
 		task.Delete(ctx)
+		logFile.Remove()
 		container.Delete(ctx, containerd.WithSnapshotCleanup)
 		return nil, fmt.Errorf("failed to wait on task: %w", err)
 	}
@@ -161,6 +176,7 @@ func (c *ContainerdAdapter) Launch(ctx context.Context, req *domain.SandboxReque
 	// Start the task
 	if err := task.Start(ctx); err != nil {
 		task.Delete(ctx)
+		logFile.Remove()
 		container.Delete(ctx, containerd.WithSnapshotCleanup)
 		return nil, fmt.Errorf("failed to start task: %w", err)
 	}
@@ -173,12 +189,20 @@ func (c *ContainerdAdapter) Launch(ctx context.Context, req *domain.SandboxReque
 		Config:      cfg,
 		StartedAt:   time.Now(),
 		ExitChannel: exitStatusC,
+		Log:         logFile,
+	}
+	// containerd places the task in the spec's cgroup, /<namespace>/<id> by default
+	if spec, err := container.Spec(ctx); err == nil && spec.Linux != nil {
+		state.CgroupDir, _ = tartarus.CgroupDir(spec.Linux.CgroupsPath)
 	}
 	c.containers.Store(req.ID, state)
 
 	// Start background goroutine to capture exit status
 	go func() {
 		exitStatus := <-exitStatusC
+		// Let the FIFOs drain so the log is complete once the exit is seen
+		task.IO().Wait()
+		logFile.Close()
 		state.mu.Lock()
 		code := int(exitStatus.ExitCode())
 		state.ExitCode = &code
@@ -232,14 +256,21 @@ func (c *ContainerdAdapter) Inspect(ctx context.Context, id domain.SandboxID) (*
 		}
 	}
 
+	var usage tartarus.ResourceUsage
+	if state.CgroupDir != "" && status == domain.RunStatusRunning {
+		usage, _ = tartarus.CgroupUsage(state.CgroupDir)
+	}
+
 	return &domain.SandboxRun{
-		ID:         id,
-		RequestID:  state.Request.ID,
-		Status:     status,
-		ExitCode:   exitCode,
-		StartedAt:  state.StartedAt,
-		FinishedAt: finishedAt,
-		UpdatedAt:  time.Now(),
+		ID:           id,
+		RequestID:    state.Request.ID,
+		Status:       status,
+		ExitCode:     exitCode,
+		StartedAt:    state.StartedAt,
+		FinishedAt:   finishedAt,
+		UpdatedAt:    time.Now(),
+		MemoryUsage:  usage.Memory,
+		CPUUsageUsec: usage.CPUUsec,
 	}, nil
 }
 
@@ -282,6 +313,7 @@ func (c *ContainerdAdapter) Kill(ctx context.Context, id domain.SandboxID) error
 	if err := state.Container.Delete(ctx, containerd.WithSnapshotCleanup); err != nil {
 		return fmt.Errorf("failed to delete container: %w", err)
 	}
+	state.Log.Remove()
 
 	c.containers.Delete(id)
 	return nil
@@ -383,36 +415,62 @@ func (c *ContainerdAdapter) GetConfig(ctx context.Context, id domain.SandboxID)
 	return state.Config, state.Request, nil
 }
 
-// StreamLogs streams container logs
+// StreamLogs streams the sandbox's captured stdout and stderr, including
+// rotated output still kept
 func (c *ContainerdAdapter) StreamLogs(ctx context.Context, id domain.SandboxID, w io.Writer, follow bool) error {
-	// containerd doesn't have built-in log storage like Docker
-	// Logs would need to be captured from the task's stdio
-	return fmt.Errorf("log streaming requires custom log driver configuration")
+	state, err := c.getState(id)
+	if err != nil {
+		return err
+	}
+
+	exited := func() bool {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		return state.ExitCode != nil
+	}
+	return streamRotatingLog(ctx, state.Log.path, state.Log.maxFiles, w, follow, exited)
 }
 
 // Allocation returns the total resources allocated to running containers
 func (c *ContainerdAdapter) Allocation(ctx context.Context) (domain.ResourceCapacity, error) {
 	var cpu domain.MilliCPU
-	var mem domain.Megabytes
+	var mem, swap domain.Megabytes
 
 	c.containers.Range(func(key, value any) bool {
 		state := value.(*containerdState)
 		state.mu.Lock()
 		if state.ExitCode == nil {
+			ram, sw := state.Request.Swap.CgroupSplit(state.Request.Resources.Mem)
 			cpu += state.Request.Resources.CPU
-			mem += state.Request.Resources.Mem
+			mem += ram
+			swap += sw
 		}
 		state.mu.Unlock()
 		return true
 	})
 
 	return domain.ResourceCapacity{
-		CPU: cpu,
-		Mem: mem,
-		GPU: 0,
+		CPU:  cpu,
+		Mem:  mem,
+		GPU:  0,
+		Swap: swap,
 	}, nil
 }
 
+// withMemory sets the container's memory limits.
+func withMemory(memory *specs.LinuxMemory) oci.SpecOpts {
+	return func(_ context.Context, _ oci.Client, _ *containers.Container, s *oci.Spec) error {
+		if s.Linux == nil {
+			s.Linux = &specs.Linux{}
+		}
+		if s.Linux.Resources == nil {
+			s.Linux.Resources = &specs.LinuxResources{}
+		}
+		s.Linux.Resources.Memory = memory
+		return nil
+	}
+}
+
 // Wait blocks until the sandbox exits
 func (c *ContainerdAdapter) Wait(ctx context.Context, id domain.SandboxID) error {
 	state, err := c.getState(id)
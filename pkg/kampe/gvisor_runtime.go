@@ -28,6 +28,7 @@ type gvisorState struct {
 	ExitCode    *int
 	Cmd         *exec.Cmd
 	ConsoleFile *os.File
+	CgroupDir   string // The sandbox's cgroup v2 directory, if known
 	mu          sync.Mutex
 }
 
@@ -113,12 +114,7 @@ func (g *GVisorAdapter) createOCISpec(req *domain.SandboxRequest, cfg tartarus.V
 	}
 
 	// Set resource limits
-	if req.Resources.Mem > 0 {
-		memLimit := int64(req.Resources.Mem) * 1024 * 1024
-		spec.Linux.Resources.Memory = &specs.LinuxMemory{
-			Limit: &memLimit,
-		}
-	}
+	spec.Linux.Resources.Memory = tartarus.MemoryLimits(req)
 	if req.Resources.CPU > 0 {
 		quota := int64(req.Resources.CPU) * 100 // MilliCPU to quota
 		period := uint64(100000)
@@ -164,6 +160,10 @@ func (g *GVisorAdapter) Launch(ctx context.Context, req *domain.SandboxRequest,
 	// Create OCI spec
 	spec := g.createOCISpec(req, cfg)
 	spec.Root.Path = rootfsPath
+	// runsc creates the sandbox's cgroup, where its limits and usage live,
+	// at the same path containerd would use
+	spec.Linux.CgroupsPath = "/" + defaultNamespace + "/" + sandboxID
+	cgroupDir, _ := tartarus.CgroupDir(spec.Linux.CgroupsPath)
 
 	// Write config.json
 	configPath := filepath.Join(bundlePath, "config.json")
@@ -213,6 +213,7 @@ func (g *GVisorAdapter) Launch(ctx context.Context, req *domain.SandboxRequest,
 		StartedAt:   time.Now(),
 		Cmd:         cmd,
 		ConsoleFile: consoleFile,
+		CgroupDir:   cgroupDir,
 	}
 	g.containers.Store(req.ID, state)
 
@@ -271,14 +272,21 @@ func (g *GVisorAdapter) Inspect(ctx context.Context, id domain.SandboxID) (*doma
 		finishedAt = time.Now()
 	}
 
+	var usage tartarus.ResourceUsage
+	if state.CgroupDir != "" && status == domain.RunStatusRunning {
+		usage, _ = tartarus.CgroupUsage(state.CgroupDir)
+	}
+
 	return &domain.SandboxRun{
-		ID:         id,
-		RequestID:  state.Request.ID,
-		Status:     status,
-		ExitCode:   exitCode,
-		StartedAt:  state.StartedAt,
-		FinishedAt: finishedAt,
-		UpdatedAt:  time.Now(),
+		ID:           id,
+		RequestID:    state.Request.ID,
+		Status:       status,
+		ExitCode:     exitCode,
+		StartedAt:    state.StartedAt,
+		FinishedAt:   finishedAt,
+		UpdatedAt:    time.Now(),
+		MemoryUsage:  usage.Memory,
+		CPUUsageUsec: usage.CPUUsec,
 	}, nil
 }
 
@@ -480,23 +488,26 @@ func (g *GVisorAdapter) StreamLogs(ctx context.Context, id domain.SandboxID, w i
 // Allocation returns the total resources allocated
 func (g *GVisorAdapter) Allocation(ctx context.Context) (domain.ResourceCapacity, error) {
 	var cpu domain.MilliCPU
-	var mem domain.Megabytes
+	var mem, swap domain.Megabytes
 
 	g.containers.Range(func(key, value any) bool {
 		state := value.(*gvisorState)
 		state.mu.Lock()
 		if state.ExitCode == nil {
+			ram, sw := state.Request.Swap.CgroupSplit(state.Request.Resources.Mem)
 			cpu += state.Request.Resources.CPU
-			mem += state.Request.Resources.Mem
+			mem += ram
+			swap += sw
 		}
 		state.mu.Unlock()
 		return true
 	})
 
 	return domain.ResourceCapacity{
-		CPU: cpu,
-		Mem: mem,
-		GPU: 0,
+		CPU:  cpu,
+		Mem:  mem,
+		GPU:  0,
+		Swap: swap,
 	}, nil
 }
 
@@ -12,9 +12,11 @@ func TestAdaptersImplementInterface(t *testing.T) {
 	var _ LegacyRuntime = &DockerAdapter{}
 	var _ LegacyRuntime = &ContainerdAdapter{}
 	var _ LegacyRuntime = &GVisorAdapter{}
+	var _ LegacyRuntime = &PodmanAdapter{}
 	var _ tartarus.SandboxRuntime = &DockerAdapter{}
 	var _ tartarus.SandboxRuntime = &ContainerdAdapter{}
 	var _ tartarus.SandboxRuntime = &GVisorAdapter{}
+	var _ tartarus.SandboxRuntime = &PodmanAdapter{}
 }
 
 func TestDockerAdapter_MigrateToMicroVM(t *testing.T) {
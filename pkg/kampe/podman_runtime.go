@@ -0,0 +1,964 @@
+package kampe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// podmanAPIPrefix is the libpod REST API version spoken; Podman 4 and 5
+// both serve it.
+const podmanAPIPrefix = "/v4.0.0/libpod"
+
+// podmanState tracks a running Podman container's state
+type podmanState struct {
+	ContainerID string
+	Request     *domain.SandboxRequest
+	Config      tartarus.VMConfig
+	StartedAt   time.Time
+	ExitCode    *int
+	mu          sync.Mutex
+}
+
+// PodmanAdapter wraps Podman through its REST socket with full
+// SandboxRuntime implementation. No daemon runs as root: with a rootless
+// socket, containers run as the socket's user.
+type PodmanAdapter struct {
+	client     *http.Client
+	socketPath string
+	rootless   bool
+	containers sync.Map // SandboxID -> *podmanState
+}
+
+// NewPodmanAdapter creates a new Podman adapter connected to the specified
+// socket. An empty path uses CONTAINER_HOST, then the rootless socket of
+// the current user, or the system socket when running as root.
+func NewPodmanAdapter(socketPath string) (*PodmanAdapter, error) {
+	if socketPath == "" {
+		socketPath = podmanSocketPath()
+	}
+
+	p := &PodmanAdapter{
+		socketPath: socketPath,
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+
+	// Verify connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var info struct {
+		Host struct {
+			Security struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/info", nil, nil, &info); err != nil {
+		return nil, fmt.Errorf("failed to connect to podman at %s: %w", socketPath, err)
+	}
+	p.rootless = info.Host.Security.Rootless
+
+	return p, nil
+}
+
+// podmanSocketPath returns the default Podman socket for this process.
+func podmanSocketPath() string {
+	if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+	if os.Geteuid() == 0 {
+		return "/run/podman/podman.sock"
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Geteuid())
+	}
+	return filepath.Join(runtimeDir, "podman", "podman.sock")
+}
+
+// Rootless reports whether the Podman service runs without root.
+func (p *PodmanAdapter) Rootless() bool {
+	return p.rootless
+}
+
+// podmanError is an error response of the Podman API.
+type podmanError struct {
+	Status  int
+	Message string
+}
+
+func (e *podmanError) Error() string {
+	return fmt.Sprintf("podman: %s (HTTP %d)", e.Message, e.Status)
+}
+
+func isPodmanNotFound(err error) bool {
+	var perr *podmanError
+	return errors.As(err, &perr) && perr.Status == http.StatusNotFound
+}
+
+// request sends an API request and returns the response of a 2xx or 304
+// status; other statuses are returned as a *podmanError.
+func (p *PodmanAdapter) request(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	u := "http://podman" + podmanAPIPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotModified {
+		defer resp.Body.Close()
+		return nil, readPodmanError(resp)
+	}
+	return resp, nil
+}
+
+func readPodmanError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+		Cause   string `json:"cause"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, &body); err != nil || body.Message == "" {
+		body.Message = strings.TrimSpace(string(data))
+	}
+	return &podmanError{Status: resp.StatusCode, Message: body.Message}
+}
+
+// call sends an API request and decodes its JSON response into out, if set.
+func (p *PodmanAdapter) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := p.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// hijack sends an API request and returns the raw connection, for streams
+// that carry stdin as well as output.
+func (p *PodmanAdapter) hijack(ctx context.Context, path string, body any) (net.Conn, *bufio.Reader, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", p.socketPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://podman"+podmanAPIPrefix+path, bytes.NewReader(data))
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusSwitchingProtocols {
+		defer conn.Close()
+		return nil, nil, readPodmanError(resp)
+	}
+	return conn, br, nil
+}
+
+// getState retrieves the state for a sandbox
+func (p *PodmanAdapter) getState(id domain.SandboxID) (*podmanState, error) {
+	val, ok := p.containers.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("sandbox not found: %s", id)
+	}
+	return val.(*podmanState), nil
+}
+
+// podmanSpec is the subset of the libpod SpecGenerator the adapter sets.
+type podmanSpec struct {
+	Name           string            `json:"name"`
+	Image          string            `json:"image"`
+	Command        []string          `json:"command,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	WorkDir        string            `json:"work_dir,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	ResourceLimits *podmanLimits     `json:"resource_limits,omitempty"`
+	Mounts         []podmanMount     `json:"mounts,omitempty"`
+}
+
+type podmanLimits struct {
+	Memory *podmanMemory `json:"memory,omitempty"`
+	CPU    *podmanCPU    `json:"cpu,omitempty"`
+}
+
+type podmanMemory struct {
+	Limit int64 `json:"limit"`
+}
+
+type podmanCPU struct {
+	Quota  int64  `json:"quota"`
+	Period uint64 `json:"period"`
+}
+
+type podmanMount struct {
+	Destination string   `json:"destination"`
+	Source      string   `json:"source"`
+	Type        string   `json:"type"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// cpuPeriod is the CFS period CPU quotas are expressed in, in microseconds.
+const cpuPeriod = 100000
+
+// Launch creates and starts a Podman container
+func (p *PodmanAdapter) Launch(ctx context.Context, req *domain.SandboxRequest, cfg tartarus.VMConfig) (*domain.SandboxRun, error) {
+	spec := podmanSpec{
+		Name:    fmt.Sprintf("tartarus-%s", req.ID),
+		Image:   string(req.Template), // Use template as image name
+		Command: append(append([]string(nil), req.Command...), req.Args...),
+		Env:     req.Env,
+		WorkDir: "/",
+		Labels: map[string]string{
+			"tartarus.sandbox.id": string(req.ID),
+		},
+	}
+
+	limits := &podmanLimits{}
+	if req.Resources.Mem > 0 {
+		limits.Memory = &podmanMemory{Limit: int64(req.Resources.Mem) * 1024 * 1024} // MB to bytes
+	}
+	if req.Resources.CPU > 0 {
+		limits.CPU = &podmanCPU{Quota: int64(req.Resources.CPU) * cpuPeriod / 1000, Period: cpuPeriod} // MilliCPU to quota
+	}
+	if limits.Memory != nil || limits.CPU != nil {
+		spec.ResourceLimits = limits
+	}
+
+	// Create volume mounts if overlay is specified
+	if cfg.OverlayFS != "" {
+		spec.Mounts = append(spec.Mounts, podmanMount{
+			Destination: "/overlay",
+			Source:      cfg.OverlayFS,
+			Type:        "bind",
+			Options:     []string{"rw"},
+		})
+	}
+
+	// Ensure image exists
+	if err := p.ensureImage(ctx, spec.Image); err != nil {
+		return nil, fmt.Errorf("failed to ensure image: %w", err)
+	}
+
+	// Create the container
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := p.call(ctx, http.MethodPost, "/containers/create", nil, spec, &created); err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+
+	// Start the container
+	if err := p.call(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil, nil, nil); err != nil {
+		// Clean up on failure
+		_ = p.remove(context.Background(), created.ID)
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	// Store state
+	state := &podmanState{
+		ContainerID: created.ID,
+		Request:     req,
+		Config:      cfg,
+		StartedAt:   time.Now(),
+	}
+	p.containers.Store(req.ID, state)
+
+	return &domain.SandboxRun{
+		ID:        req.ID,
+		RequestID: req.ID,
+		Status:    domain.RunStatusRunning,
+		StartedAt: state.StartedAt,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// stringList decodes a JSON string or array of strings; Podman 4 reports
+// an entrypoint as a string and Podman 5 as an array.
+type stringList []string
+
+func (s *stringList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*s = nil
+	if str != "" {
+		*s = strings.Fields(str)
+	}
+	return nil
+}
+
+// podmanInspect is the subset of a libpod container inspection the adapter reads.
+type podmanInspect struct {
+	ID        string `json:"Id"`
+	ImageName string `json:"ImageName"`
+	Pod       string `json:"Pod"`
+	State     struct {
+		Running    bool      `json:"Running"`
+		ExitCode   int       `json:"ExitCode"`
+		FinishedAt time.Time `json:"FinishedAt"`
+	} `json:"State"`
+	Config struct {
+		Env        []string   `json:"Env"`
+		Cmd        []string   `json:"Cmd"`
+		Entrypoint stringList `json:"Entrypoint"`
+		WorkingDir string     `json:"WorkingDir"`
+		User       string     `json:"User"`
+	} `json:"Config"`
+	HostConfig struct {
+		NetworkMode  string `json:"NetworkMode"`
+		Privileged   bool   `json:"Privileged"`
+		UsernsMode   string `json:"UsernsMode"`
+		Devices      []any  `json:"Devices"`
+		PortBindings map[string][]struct {
+			HostPort string `json:"HostPort"`
+		} `json:"PortBindings"`
+	} `json:"HostConfig"`
+	Mounts []struct {
+		Type        string `json:"Type"`
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+	} `json:"Mounts"`
+}
+
+func (p *PodmanAdapter) inspect(ctx context.Context, containerID string) (*podmanInspect, error) {
+	var info podmanInspect
+	if err := p.call(ctx, http.MethodGet, "/containers/"+containerID+"/json", nil, nil, &info); err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return &info, nil
+}
+
+// Inspect returns the current state of a sandbox
+func (p *PodmanAdapter) Inspect(ctx context.Context, id domain.SandboxID) (*domain.SandboxRun, error) {
+	state, err := p.getState(id)
+	if err != nil {
+		return nil, err
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	info, err := p.inspect(ctx, state.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := domain.RunStatusRunning
+	var exitCode *int
+	var finishedAt time.Time
+
+	if !info.State.Running {
+		code := info.State.ExitCode
+		exitCode = &code
+		state.ExitCode = exitCode
+		if code == 0 {
+			status = domain.RunStatusSucceeded
+		} else {
+			status = domain.RunStatusFailed
+		}
+		finishedAt = info.State.FinishedAt
+	}
+
+	// Memory usage from a single stats sample
+	var memUsage domain.Megabytes
+	var stats struct {
+		Stats []struct {
+			MemUsage uint64 `json:"MemUsage"`
+		} `json:"Stats"`
+	}
+	query := url.Values{"containers": {state.ContainerID}, "stream": {"false"}}
+	if info.State.Running && p.call(ctx, http.MethodGet, "/containers/stats", query, nil, &stats) == nil && len(stats.Stats) > 0 {
+		memUsage = domain.Megabytes(stats.Stats[0].MemUsage / (1024 * 1024))
+	}
+
+	return &domain.SandboxRun{
+		ID:          id,
+		RequestID:   state.Request.ID,
+		Status:      status,
+		ExitCode:    exitCode,
+		StartedAt:   state.StartedAt,
+		FinishedAt:  finishedAt,
+		UpdatedAt:   time.Now(),
+		MemoryUsage: memUsage,
+	}, nil
+}
+
+// List returns all active sandboxes
+func (p *PodmanAdapter) List(ctx context.Context) ([]domain.SandboxRun, error) {
+	var runs []domain.SandboxRun
+
+	p.containers.Range(func(key, value any) bool {
+		id := key.(domain.SandboxID)
+		run, err := p.Inspect(ctx, id)
+		if err == nil {
+			runs = append(runs, *run)
+		}
+		return true
+	})
+
+	return runs, nil
+}
+
+// Kill terminates a sandbox forcefully
+func (p *PodmanAdapter) Kill(ctx context.Context, id domain.SandboxID) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return nil // Already gone
+	}
+
+	// Stop the container; errors are ignored as removal forces it anyway
+	_ = p.call(ctx, http.MethodPost, "/containers/"+state.ContainerID+"/stop", url.Values{"timeout": {"0"}}, nil, nil)
+
+	if err := p.remove(ctx, state.ContainerID); err != nil {
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+
+	p.containers.Delete(id)
+	return nil
+}
+
+func (p *PodmanAdapter) remove(ctx context.Context, containerID string) error {
+	err := p.call(ctx, http.MethodDelete, "/containers/"+containerID, url.Values{"force": {"true"}}, nil, nil)
+	if isPodmanNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Pause pauses a running sandbox
+func (p *PodmanAdapter) Pause(ctx context.Context, id domain.SandboxID) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+
+	if err := p.call(ctx, http.MethodPost, "/containers/"+state.ContainerID+"/pause", nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to pause container: %w", err)
+	}
+
+	return nil
+}
+
+// Resume unpauses a paused sandbox
+func (p *PodmanAdapter) Resume(ctx context.Context, id domain.SandboxID) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+
+	if err := p.call(ctx, http.MethodPost, "/containers/"+state.ContainerID+"/unpause", nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to unpause container: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSnapshot exports a checkpoint of the container to memPath, keeping
+// it running. Checkpoints need CRIU and rootful Podman; the archive holds
+// the root filesystem changes too, so diskPath is not written.
+func (p *PodmanAdapter) CreateSnapshot(ctx context.Context, id domain.SandboxID, memPath, diskPath string) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+	if p.rootless {
+		return fmt.Errorf("failed to create checkpoint: rootless podman cannot checkpoint containers")
+	}
+
+	query := url.Values{"export": {"true"}, "leaveRunning": {"true"}}
+	resp, err := p.request(ctx, http.MethodPost, "/containers/"+state.ContainerID+"/checkpoint", query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint (requires CRIU): %w", err)
+	}
+	defer resp.Body.Close()
+
+	f, err := os.Create(memPath)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return f.Close()
+}
+
+// Shutdown gracefully stops the sandbox
+func (p *PodmanAdapter) Shutdown(ctx context.Context, id domain.SandboxID) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+
+	// 30 second graceful shutdown
+	if err := p.call(ctx, http.MethodPost, "/containers/"+state.ContainerID+"/stop", url.Values{"timeout": {"30"}}, nil, nil); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+
+	return nil
+}
+
+// GetConfig returns the VM config and original request
+func (p *PodmanAdapter) GetConfig(ctx context.Context, id domain.SandboxID) (tartarus.VMConfig, *domain.SandboxRequest, error) {
+	state, err := p.getState(id)
+	if err != nil {
+		return tartarus.VMConfig{}, nil, err
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.Config, state.Request, nil
+}
+
+// StreamLogs streams container logs to the writer, stdout and stderr
+// interleaved as written
+func (p *PodmanAdapter) StreamLogs(ctx context.Context, id domain.SandboxID, w io.Writer, follow bool) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}, "follow": {strconv.FormatBool(follow)}}
+	resp, err := p.request(ctx, http.MethodGet, "/containers/"+state.ContainerID+"/logs", query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Without a TTY, logs are multiplexed with an 8-byte header per frame
+	_, err = stdcopy.StdCopy(w, w, resp.Body)
+	return err
+}
+
+// Allocation returns the total resources allocated to running containers
+func (p *PodmanAdapter) Allocation(ctx context.Context) (domain.ResourceCapacity, error) {
+	var cpu domain.MilliCPU
+	var mem domain.Megabytes
+
+	p.containers.Range(func(key, value any) bool {
+		state := value.(*podmanState)
+		state.mu.Lock()
+		if state.ExitCode == nil {
+			cpu += state.Request.Resources.CPU
+			mem += state.Request.Resources.Mem
+		}
+		state.mu.Unlock()
+		return true
+	})
+
+	return domain.ResourceCapacity{
+		CPU: cpu,
+		Mem: mem,
+		GPU: 0,
+	}, nil
+}
+
+// Wait blocks until the sandbox exits
+func (p *PodmanAdapter) Wait(ctx context.Context, id domain.SandboxID) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+
+	var code int
+	query := url.Values{"condition": {"exited", "stopped"}}
+	if err := p.call(ctx, http.MethodPost, "/containers/"+state.ContainerID+"/wait", query, nil, &code); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	state.mu.Lock()
+	state.ExitCode = &code
+	state.mu.Unlock()
+	return nil
+}
+
+// podmanExec is the body of an exec session create request.
+type podmanExec struct {
+	Cmd          []string `json:"Cmd"`
+	AttachStdin  bool     `json:"AttachStdin"`
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+	Tty          bool     `json:"Tty"`
+}
+
+func (p *PodmanAdapter) createExec(ctx context.Context, containerID string, exec podmanExec) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := p.call(ctx, http.MethodPost, "/containers/"+containerID+"/exec", nil, exec, &created); err != nil {
+		return "", fmt.Errorf("failed to create exec: %w", err)
+	}
+	return created.ID, nil
+}
+
+// execExitCode returns the exit code of a finished exec session as an
+// error, or nil for zero.
+func (p *PodmanAdapter) execExitCode(ctx context.Context, execID string) error {
+	var info struct {
+		ExitCode int  `json:"ExitCode"`
+		Running  bool `json:"Running"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/exec/"+execID+"/json", nil, nil, &info); err != nil {
+		return fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if !info.Running && info.ExitCode != 0 {
+		return fmt.Errorf("command exited with code %d", info.ExitCode)
+	}
+	return nil
+}
+
+// Exec executes a command in the sandbox
+func (p *PodmanAdapter) Exec(ctx context.Context, id domain.SandboxID, cmd []string, stdout, stderr io.Writer) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+
+	execID, err := p.createExec(ctx, state.ContainerID, podmanExec{Cmd: cmd, AttachStdout: true, AttachStderr: true})
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.request(ctx, http.MethodPost, "/exec/"+execID+"/start", nil, map[string]bool{"Detach": false, "Tty": false})
+	if err != nil {
+		return fmt.Errorf("failed to start exec: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	if _, err := stdcopy.StdCopy(stdout, stderr, resp.Body); err != nil {
+		return fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	return p.execExitCode(ctx, execID)
+}
+
+// ExecInteractive executes an interactive command with stdin support
+func (p *PodmanAdapter) ExecInteractive(ctx context.Context, id domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	state, err := p.getState(id)
+	if err != nil {
+		return err
+	}
+
+	execID, err := p.createExec(ctx, state.ContainerID, podmanExec{
+		Cmd:          cmd,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+	})
+	if err != nil {
+		return err
+	}
+
+	conn, reader, err := p.hijack(ctx, "/exec/"+execID+"/start", map[string]bool{"Detach": false, "Tty": true})
+	if err != nil {
+		return fmt.Errorf("failed to attach exec: %w", err)
+	}
+	defer conn.Close()
+
+	// Handle I/O; a TTY session is a single raw stream
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(conn, stdin)
+			if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+				_ = cw.CloseWrite()
+			}
+		}()
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	_, _ = io.Copy(stdout, reader)
+
+	return p.execExitCode(ctx, execID)
+}
+
+// Migration helpers
+
+// CanMigrate checks if a container can be migrated to microVM
+func (p *PodmanAdapter) CanMigrate(ctx context.Context, containerID string) (bool, error) {
+	info, err := p.inspect(ctx, containerID)
+	if err != nil {
+		return false, err
+	}
+
+	// Check for features that are hard to migrate
+	if info.HostConfig.NetworkMode == "host" {
+		return false, nil // Host network not supported in microVM
+	}
+
+	if len(info.HostConfig.Devices) > 0 {
+		return false, nil // Direct device access not supported
+	}
+
+	return true, nil
+}
+
+// MigrateToMicroVM analyzes a container and creates a migration plan
+func (p *PodmanAdapter) MigrateToMicroVM(ctx context.Context, containerID string) (*MigrationPlan, error) {
+	info, err := p.inspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MigrationPlan{
+		ContainerID:    containerID,
+		TargetTemplate: "microvm-podman-compatible",
+		RiskLevel:      RiskLevelLow,
+	}
+	raise := func(level RiskLevel) {
+		if level == RiskLevelHigh || plan.RiskLevel == RiskLevelLow {
+			plan.RiskLevel = level
+		}
+	}
+
+	// Analyze container configuration
+	if info.HostConfig.NetworkMode == "host" {
+		raise(RiskLevelHigh)
+		plan.RequiredChanges = append(plan.RequiredChanges, MigrationChange{
+			Type:        ChangeTypeNetwork,
+			Description: "Host network mode not supported in microVM",
+			Required:    true,
+			AutoFix:     false,
+		})
+	}
+
+	if len(info.HostConfig.Devices) > 0 {
+		raise(RiskLevelHigh)
+		plan.RequiredChanges = append(plan.RequiredChanges, MigrationChange{
+			Type:        ChangeTypeResources,
+			Description: fmt.Sprintf("%d device mounts not supported", len(info.HostConfig.Devices)),
+			Required:    true,
+			AutoFix:     false,
+		})
+	}
+
+	if info.HostConfig.Privileged {
+		raise(RiskLevelMedium)
+		plan.RequiredChanges = append(plan.RequiredChanges, MigrationChange{
+			Type:        ChangeTypeResources,
+			Description: "Privileged mode will be disabled (microVM provides isolation)",
+			Required:    false,
+			AutoFix:     true,
+		})
+	}
+
+	// Containers in a pod share its network namespace and reach each
+	// other on localhost, which separate microVMs cannot
+	if info.Pod != "" {
+		raise(RiskLevelMedium)
+		plan.RequiredChanges = append(plan.RequiredChanges, MigrationChange{
+			Type:        ChangeTypeNetwork,
+			Description: fmt.Sprintf("Container is in pod %s; its containers share localhost and must be migrated together or addressed over the network", info.Pod),
+			Required:    true,
+			AutoFix:     false,
+		})
+	}
+
+	// Rootless containers write volume files as subordinate host UIDs
+	userns := info.HostConfig.UsernsMode != "" && info.HostConfig.UsernsMode != "host"
+	if (p.rootless || userns) && len(info.Mounts) > 0 {
+		plan.RequiredChanges = append(plan.RequiredChanges, MigrationChange{
+			Type:        ChangeTypeFilesystem,
+			Description: "Volume files are owned by subordinate UIDs of the rootless user namespace; fix ownership before mounting them in the microVM",
+			Required:    false,
+			AutoFix:     false,
+		})
+	}
+
+	// Estimate downtime based on image size
+	var image struct {
+		Size int64 `json:"Size"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/images/"+info.ImageName+"/json", nil, nil, &image); err == nil {
+		sizeMB := image.Size / (1024 * 1024)
+		plan.EstimatedDowntime = time.Duration(sizeMB/100) * time.Second // ~100MB/s conversion
+	}
+	if plan.EstimatedDowntime < 5*time.Second {
+		plan.EstimatedDowntime = 5 * time.Second
+	}
+
+	plan.Recommendations = []string{
+		"Test the migrated workload in staging before production",
+		"Verify all environment variables are correctly passed",
+	}
+
+	if len(info.Mounts) > 0 {
+		plan.Recommendations = append(plan.Recommendations,
+			fmt.Sprintf("Review %d volume mounts for overlay filesystem compatibility", len(info.Mounts)))
+	}
+	if len(info.HostConfig.PortBindings) > 0 {
+		plan.Recommendations = append(plan.Recommendations,
+			fmt.Sprintf("Expose %d published ports through the microVM network instead of rootless port forwarding", len(info.HostConfig.PortBindings)))
+	}
+
+	return plan, nil
+}
+
+// ExportState exports the container state for migration
+func (p *PodmanAdapter) ExportState(ctx context.Context, containerID string) (*ContainerState, error) {
+	info, err := p.inspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &ContainerState{
+		ID:    containerID,
+		Image: info.ImageName,
+		Config: ContainerConfig{
+			Entrypoint: info.Config.Entrypoint,
+			Cmd:        info.Config.Cmd,
+			WorkingDir: info.Config.WorkingDir,
+			User:       info.Config.User,
+			Env:        info.Config.Env,
+		},
+		Environment: make(map[string]string),
+	}
+
+	// Parse environment variables
+	for _, e := range info.Config.Env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			state.Environment[parts[0]] = parts[1]
+		}
+	}
+
+	// Parse volumes
+	for _, m := range info.Mounts {
+		state.Config.Volumes = append(state.Config.Volumes, m.Destination)
+	}
+
+	// Parse port mappings ("8080/tcp")
+	for port, bindings := range info.HostConfig.PortBindings {
+		number, proto, _ := strings.Cut(port, "/")
+		containerPort, _ := strconv.Atoi(number)
+		if proto == "" {
+			proto = "tcp"
+		}
+		for _, binding := range bindings {
+			hostPort, _ := strconv.Atoi(binding.HostPort)
+			state.Config.Ports = append(state.Config.Ports, PortMapping{
+				ContainerPort: containerPort,
+				HostPort:      hostPort,
+				Protocol:      proto,
+			})
+		}
+	}
+
+	return state, nil
+}
+
+func (p *PodmanAdapter) ensureImage(ctx context.Context, imageName string) error {
+	err := p.call(ctx, http.MethodGet, "/images/"+imageName+"/exists", nil, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !isPodmanNotFound(err) {
+		return fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	// Image not found, pull it
+	resp, err := p.request(ctx, http.MethodPost, "/images/pull", url.Values{"reference": {imageName}, "quiet": {"true"}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The pull reports progress, and failures, as a stream of JSON objects
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var report struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&report); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read pull progress: %w", err)
+		}
+		if report.Error != "" {
+			return fmt.Errorf("failed to pull image: %s", report.Error)
+		}
+	}
+}
+
+// Ensure PodmanAdapter implements LegacyRuntime
+var _ LegacyRuntime = (*PodmanAdapter)(nil)
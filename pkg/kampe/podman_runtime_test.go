@@ -0,0 +1,294 @@
+package kampe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// fakePodman serves the parts of the libpod API the adapter uses.
+type fakePodman struct {
+	mu         sync.Mutex
+	rootless   bool
+	images     map[string]bool
+	pulled     []string
+	created    []podmanSpec
+	containers map[string]map[string]any // ID -> inspect JSON
+	execs      map[string]podmanExec
+	exitCode   int
+}
+
+func startFakePodman(t *testing.T, f *fakePodman) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "podman")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(f)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return socket
+}
+
+func (f *fakePodman) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, podmanAPIPrefix)
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"message": "no such object", "response": 404})
+	}
+
+	switch {
+	case path == "/info":
+		json.NewEncoder(w).Encode(map[string]any{"host": map[string]any{"security": map[string]any{"rootless": f.rootless}}})
+
+	case parts[0] == "images" && path == "/images/pull":
+		ref := r.URL.Query().Get("reference")
+		f.pulled = append(f.pulled, ref)
+		f.images[ref] = true
+		io.WriteString(w, `{"stream":"Pulling"}`+"\n"+`{"id":"abc"}`+"\n")
+	case parts[0] == "images" && strings.HasSuffix(path, "/exists"):
+		if !f.images[strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/exists")] {
+			notFound()
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case parts[0] == "images" && strings.HasSuffix(path, "/json"):
+		json.NewEncoder(w).Encode(map[string]any{"Size": 1200 * 1024 * 1024})
+
+	case path == "/containers/create":
+		var spec podmanSpec
+		json.NewDecoder(r.Body).Decode(&spec)
+		f.created = append(f.created, spec)
+		id := "c" + spec.Name
+		f.containers[id] = map[string]any{
+			"Id": id, "ImageName": spec.Image,
+			"State":  map[string]any{"Running": true},
+			"Config": map[string]any{"Cmd": spec.Command},
+		}
+		json.NewEncoder(w).Encode(map[string]string{"Id": id})
+	case path == "/containers/stats":
+		json.NewEncoder(w).Encode(map[string]any{"Stats": []map[string]any{{"MemUsage": 64 * 1024 * 1024}}})
+	case parts[0] == "containers" && len(parts) >= 2:
+		c, ok := f.containers[parts[1]]
+		if !ok {
+			notFound()
+			return
+		}
+		action := ""
+		if len(parts) > 2 {
+			action = parts[2]
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.containers, parts[1])
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, "[]")
+		case action == "json":
+			json.NewEncoder(w).Encode(c)
+		case action == "start", action == "stop", action == "pause", action == "unpause":
+			w.WriteHeader(http.StatusNoContent)
+		case action == "wait":
+			c["State"] = map[string]any{"Running": false, "ExitCode": f.exitCode, "FinishedAt": "2025-01-01T00:00:00Z"}
+			json.NewEncoder(w).Encode(f.exitCode)
+		case action == "logs":
+			stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte("hello\n"))
+			stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("warning\n"))
+		case action == "exec":
+			var exec podmanExec
+			json.NewDecoder(r.Body).Decode(&exec)
+			id := "e" + string(rune('0'+len(f.execs)))
+			f.execs[id] = exec
+			json.NewEncoder(w).Encode(map[string]string{"Id": id})
+		default:
+			notFound()
+		}
+
+	case parts[0] == "exec" && len(parts) == 3:
+		exec, ok := f.execs[parts[1]]
+		if !ok {
+			notFound()
+			return
+		}
+		switch parts[2] {
+		case "json":
+			code := 0
+			if exec.Cmd[0] == "false" {
+				code = 1
+			}
+			json.NewEncoder(w).Encode(map[string]any{"ExitCode": code, "Running": false})
+		case "start":
+			if !exec.Tty {
+				stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte(strings.Join(exec.Cmd, " ")))
+				stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("err"))
+				return
+			}
+			// Interactive sessions echo stdin on the hijacked connection
+			io.Copy(io.Discard, r.Body)
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\n\r\n")
+			buf.Flush()
+			io.Copy(conn, buf)
+		}
+
+	default:
+		notFound()
+	}
+}
+
+func newFakePodman() *fakePodman {
+	return &fakePodman{
+		rootless:   true,
+		images:     map[string]bool{},
+		containers: map[string]map[string]any{},
+		execs:      map[string]podmanExec{},
+	}
+}
+
+func TestPodmanAdapter_Lifecycle(t *testing.T) {
+	fake := newFakePodman()
+	fake.exitCode = 3
+	adapter, err := NewPodmanAdapter(startFakePodman(t, fake))
+	require.NoError(t, err)
+	assert.True(t, adapter.Rootless())
+	ctx := context.Background()
+
+	req := &domain.SandboxRequest{
+		ID:        "sb-1",
+		Template:  "docker.io/library/python:3.12",
+		Command:   []string{"python"},
+		Args:      []string{"-c", "print(1)"},
+		Resources: domain.ResourceSpec{CPU: 500, Mem: 256},
+	}
+	run, err := adapter.Launch(ctx, req, tartarus.VMConfig{OverlayFS: "/var/lib/overlay/sb-1"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusRunning, run.Status)
+
+	// The missing image was pulled and the container created with limits
+	assert.Equal(t, []string{"docker.io/library/python:3.12"}, fake.pulled)
+	require.Len(t, fake.created, 1)
+	spec := fake.created[0]
+	assert.Equal(t, "tartarus-sb-1", spec.Name)
+	assert.Equal(t, []string{"python", "-c", "print(1)"}, spec.Command)
+	assert.Equal(t, int64(256*1024*1024), spec.ResourceLimits.Memory.Limit)
+	assert.Equal(t, int64(50000), spec.ResourceLimits.CPU.Quota)
+	assert.Equal(t, "/var/lib/overlay/sb-1", spec.Mounts[0].Source)
+
+	inspected, err := adapter.Inspect(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Megabytes(64), inspected.MemoryUsage)
+
+	// Exec output is demultiplexed, and a failing command is an error
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, adapter.Exec(ctx, "sb-1", []string{"echo", "hi"}, &stdout, &stderr))
+	assert.Equal(t, "echo hi", stdout.String())
+	assert.Equal(t, "err", stderr.String())
+	assert.ErrorContains(t, adapter.Exec(ctx, "sb-1", []string{"false"}, nil, nil), "exited with code 1")
+
+	var echoed bytes.Buffer
+	require.NoError(t, adapter.ExecInteractive(ctx, "sb-1", []string{"sh"}, strings.NewReader("ls\n"), &echoed, nil))
+	assert.Equal(t, "ls\n", echoed.String())
+
+	var logs bytes.Buffer
+	require.NoError(t, adapter.StreamLogs(ctx, "sb-1", &logs, false))
+	assert.Equal(t, "hello\nwarning\n", logs.String())
+
+	alloc, err := adapter.Allocation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MilliCPU(500), alloc.CPU)
+
+	require.NoError(t, adapter.Wait(ctx, "sb-1"))
+	inspected, err = adapter.Inspect(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusFailed, inspected.Status)
+	assert.Equal(t, 3, *inspected.ExitCode)
+
+	// Rootless Podman cannot checkpoint
+	assert.ErrorContains(t, adapter.CreateSnapshot(ctx, "sb-1", filepath.Join(t.TempDir(), "mem"), ""), "rootless")
+
+	require.NoError(t, adapter.Kill(ctx, "sb-1"))
+	assert.Empty(t, fake.containers)
+	_, err = adapter.Inspect(ctx, "sb-1")
+	assert.Error(t, err)
+}
+
+func TestPodmanAdapter_MigrateToMicroVM(t *testing.T) {
+	fake := newFakePodman()
+	fake.containers["web"] = map[string]any{
+		"Id": "web", "ImageName": "quay.io/acme/web:1", "Pod": "frontend",
+		"Config": map[string]any{
+			"Entrypoint": "/usr/bin/web --serve", // Podman 4 reports a string
+			"Cmd":        []string{"--port", "8080"},
+			"Env":        []string{"MODE=prod"},
+		},
+		"HostConfig": map[string]any{
+			"Privileged":   true,
+			"PortBindings": map[string]any{"8080/tcp": []map[string]string{{"HostPort": "18080"}}},
+		},
+		"Mounts": []map[string]string{{"Type": "volume", "Destination": "/data"}},
+	}
+	fake.containers["host-net"] = map[string]any{
+		"Id": "host-net", "HostConfig": map[string]any{"NetworkMode": "host"},
+	}
+	adapter, err := NewPodmanAdapter(startFakePodman(t, fake))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	plan, err := adapter.MigrateToMicroVM(ctx, "web")
+	require.NoError(t, err)
+	assert.Equal(t, "microvm-podman-compatible", plan.TargetTemplate)
+	assert.Equal(t, RiskLevelMedium, plan.RiskLevel)
+	var types []ChangeType
+	for _, change := range plan.RequiredChanges {
+		types = append(types, change.Type)
+	}
+	// Privileged, pod membership and rootless volume ownership
+	assert.Equal(t, []ChangeType{ChangeTypeResources, ChangeTypeNetwork, ChangeTypeFilesystem}, types)
+	assert.Contains(t, plan.RequiredChanges[1].Description, "frontend")
+	assert.Equal(t, 12*time.Second, plan.EstimatedDowntime)
+
+	ok, err := adapter.CanMigrate(ctx, "host-net")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	plan, err = adapter.MigrateToMicroVM(ctx, "host-net")
+	require.NoError(t, err)
+	assert.Equal(t, RiskLevelHigh, plan.RiskLevel)
+
+	state, err := adapter.ExportState(ctx, "web")
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/acme/web:1", state.Image)
+	assert.Equal(t, []string{"/usr/bin/web", "--serve"}, state.Config.Entrypoint)
+	assert.Equal(t, map[string]string{"MODE": "prod"}, state.Environment)
+	assert.Equal(t, []string{"/data"}, state.Config.Volumes)
+	assert.Equal(t, []PortMapping{{ContainerPort: 8080, HostPort: 18080, Protocol: "tcp"}}, state.Config.Ports)
+
+	_, err = adapter.MigrateToMicroVM(ctx, "missing")
+	assert.True(t, isPodmanNotFound(err))
+}
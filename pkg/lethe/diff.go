@@ -0,0 +1,159 @@
+package lethe
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// DefaultDiffMaxBytes caps the file bytes packaged when the policy sets no cap.
+const DefaultDiffMaxBytes = 1 << 30
+
+// Whiteout markers in diff tarballs, as in OCI image layers.
+const (
+	whiteoutPrefix = ".wh."         // ".wh.<name>" records that <name> was deleted
+	whiteoutOpaque = ".wh..wh..opq" // Records that a directory was replaced, hiding the base's contents
+)
+
+// WriteDiff packages the overlayfs upper layer at upperDir as a gzipped
+// tarball in OCI layer format: files, links and directories the sandbox
+// created or changed (copied up), and whiteouts for what it deleted. Paths
+// outside the policy are left out, and files that would take the total past
+// the cap are skipped and the result marked truncated.
+func WriteDiff(ctx context.Context, upperDir string, w io.Writer, policy *domain.OutputPolicy) (*domain.RunOutput, error) {
+	if policy == nil {
+		policy = &domain.OutputPolicy{}
+	}
+	maxBytes := policy.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = DefaultDiffMaxBytes
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	out := &domain.RunOutput{}
+
+	err := filepath.WalkDir(upperDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(upperDir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		guestPath := "/" + rel
+		if !policy.Includes(guestPath) {
+			if d.IsDir() && !leadsTo(guestPath, policy) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if isWhiteout(info) {
+			out.Deleted++
+			return tw.WriteHeader(&tar.Header{
+				Name:     path.Join(path.Dir(rel), whiteoutPrefix+path.Base(rel)),
+				Typeflag: tar.TypeReg,
+				Mode:     0o644,
+				ModTime:  info.ModTime(),
+			})
+		}
+
+		var link string
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if out.Bytes+info.Size() > maxBytes {
+				out.Truncated = true
+				return nil
+			}
+		case info.IsDir():
+		default:
+			// Devices, sockets and pipes are not outputs
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			if isOpaque(file) {
+				return tw.WriteHeader(&tar.Header{
+					Name:     path.Join(rel, whiteoutOpaque),
+					Typeflag: tar.TypeReg,
+					Mode:     0o644,
+					ModTime:  info.ModTime(),
+				})
+			}
+			return nil
+		case info.Mode().IsRegular():
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.CopyN(tw, f, info.Size()); err != nil {
+				return err
+			}
+			out.Bytes += info.Size()
+		}
+		out.Files++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay upper layer: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// leadsTo reports whether a directory left out by the policy may still hold
+// paths it keeps.
+func leadsTo(dir string, policy *domain.OutputPolicy) bool {
+	if policy.Excludes(dir) {
+		return false
+	}
+	for _, p := range policy.Paths {
+		if strings.HasPrefix(path.Clean(p), dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package lethe
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// readTarball returns the tarball's entries by name, with file contents.
+func readTarball(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("not gzipped: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	entries := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("bad tarball: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		if hdr.Typeflag == tar.TypeSymlink {
+			content = []byte("-> " + hdr.Linkname)
+		}
+		entries[hdr.Name] = string(content)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	upper := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		p := filepath.Join(upper, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("out/model.bin", "weights")
+	write("out/metrics.json", `{"loss": 0.1}`)
+	write("out/cache/tmp.bin", "scratch")
+	write("etc/hosts", "127.0.0.1 sandbox")
+	if err := os.Symlink("model.bin", filepath.Join(upper, "out/latest")); err != nil {
+		t.Fatal(err)
+	}
+	// overlayfs records deleted files as 0:0 character devices
+	whiteouts := syscall.Mknod(filepath.Join(upper, "out/stale.bin"), syscall.S_IFCHR, 0) == nil
+
+	var buf bytes.Buffer
+	out, err := WriteDiff(context.Background(), upper, &buf, &domain.OutputPolicy{
+		Paths:   []string{"/out"},
+		Exclude: []string{"/out/cache"},
+	})
+	if err != nil {
+		t.Fatalf("WriteDiff: %v", err)
+	}
+	entries := readTarball(t, buf.Bytes())
+
+	if entries["out/model.bin"] != "weights" || entries["out/metrics.json"] != `{"loss": 0.1}` {
+		t.Errorf("missing changed files, got %v", entries)
+	}
+	if entries["out/latest"] != "-> model.bin" {
+		t.Errorf("expected symlink, got %q", entries["out/latest"])
+	}
+	for _, name := range []string{"etc/hosts", "out/cache/tmp.bin"} {
+		if _, ok := entries[name]; ok {
+			t.Errorf("expected %s to be filtered out", name)
+		}
+	}
+	if out.Files != 3 || out.Bytes != int64(len("weights")+len(`{"loss": 0.1}`)) || out.Truncated {
+		t.Errorf("unexpected summary %+v", out)
+	}
+	if whiteouts {
+		if _, ok := entries["out/.wh.stale.bin"]; !ok || out.Deleted != 1 {
+			t.Errorf("expected whiteout for deleted file, got %v (%+v)", entries, out)
+		}
+	}
+
+	// Files past the cap are skipped
+	buf.Reset()
+	out, err = WriteDiff(context.Background(), upper, &buf, &domain.OutputPolicy{Paths: []string{"/out"}, MaxBytes: 10})
+	if err != nil {
+		t.Fatalf("WriteDiff: %v", err)
+	}
+	if !out.Truncated || out.Bytes > 10 {
+		t.Errorf("expected truncated output under the cap, got %+v", out)
+	}
+}
@@ -0,0 +1,125 @@
+//go:build linux
+
+package lethe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/google/uuid"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/nyx"
+)
+
+// OverlayFSPool implements Pool with overlayfs mounts over a snapshot's
+// root filesystem directory (<snapshot path>.rootfs), for runtimes that take
+// a directory as the sandbox root. Files the sandbox changes are copied up
+// into a per-overlay upper directory, which Diff reads out.
+type OverlayFSPool struct {
+	BaseDir string
+	Logger  hermes.Logger
+}
+
+// NewOverlayFSPool creates a new overlayfs-based overlay pool.
+func NewOverlayFSPool(baseDir string, logger hermes.Logger) (*OverlayFSPool, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to ensure base dir exists: %w", err)
+	}
+	return &OverlayFSPool{
+		BaseDir: baseDir,
+		Logger:  logger,
+	}, nil
+}
+
+// Create mounts a new overlay on the snapshot's root filesystem.
+func (p *OverlayFSPool) Create(ctx context.Context, snapshot *nyx.Snapshot) (*Overlay, error) {
+	id := uuid.New().String()
+	dir := filepath.Join(p.BaseDir, id)
+	lower := snapshot.Path + ".rootfs"
+	upper := filepath.Join(dir, "upper")
+	work := filepath.Join(dir, "work")
+	merged := filepath.Join(dir, "merged")
+
+	if p.Logger != nil {
+		p.Logger.Info(ctx, "Creating overlay", map[string]any{
+			"overlay_id":  id,
+			"snapshot_id": snapshot.ID,
+			"lower_dir":   lower,
+			"mount_path":  merged,
+		})
+	}
+
+	for _, d := range []string{upper, work, merged} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to create overlay dir: %w", err)
+		}
+	}
+	opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", lower, upper, work)
+	if err := syscall.Mount("overlay", merged, "overlay", 0, opts); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to mount overlay: %w", err)
+	}
+
+	return &Overlay{
+		ID:              id,
+		MountPath:       merged,
+		BackingSnapshot: snapshot.ID,
+		UpperDir:        upper,
+	}, nil
+}
+
+// Destroy unmounts the overlay and removes its directories.
+func (p *OverlayFSPool) Destroy(ctx context.Context, overlay *Overlay) error {
+	if p.Logger != nil {
+		p.Logger.Info(ctx, "Destroying overlay", map[string]any{
+			"overlay_id": overlay.ID,
+			"mount_path": overlay.MountPath,
+		})
+	}
+
+	if err := syscall.Unmount(overlay.MountPath, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL && err != syscall.ENOENT {
+		return fmt.Errorf("failed to unmount overlay: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Dir(overlay.MountPath)); err != nil {
+		return fmt.Errorf("failed to remove overlay dir: %w", err)
+	}
+
+	return nil
+}
+
+// Diff implements Differ by packaging the overlay's upper directory.
+func (p *OverlayFSPool) Diff(ctx context.Context, overlay *Overlay, w io.Writer, policy *domain.OutputPolicy) (*domain.RunOutput, error) {
+	if overlay.UpperDir == "" {
+		return nil, fmt.Errorf("overlay %s has no upper layer", overlay.ID)
+	}
+	return WriteDiff(ctx, overlay.UpperDir, w, policy)
+}
+
+// isWhiteout reports whether an upper layer entry marks a deleted path:
+// overlayfs records deletions as 0:0 character devices.
+func isWhiteout(info fs.FileInfo) bool {
+	if info.Mode()&fs.ModeCharDevice == 0 {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Rdev == 0
+}
+
+// isOpaque reports whether an upper layer directory replaced the lower one,
+// hiding its contents.
+func isOpaque(dir string) bool {
+	buf := make([]byte, 1)
+	for _, attr := range []string{"trusted.overlay.opaque", "user.overlay.opaque"} {
+		if n, err := syscall.Getxattr(dir, attr, buf); err == nil && n == 1 && buf[0] == 'y' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+
+package lethe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/nyx"
+)
+
+// OverlayFSPool requires Linux overlayfs.
+type OverlayFSPool struct {
+	BaseDir string
+	Logger  hermes.Logger
+}
+
+// NewOverlayFSPool fails: overlayfs is only available on Linux.
+func NewOverlayFSPool(baseDir string, logger hermes.Logger) (*OverlayFSPool, error) {
+	return nil, fmt.Errorf("overlayfs overlays are only supported on Linux")
+}
+
+func (p *OverlayFSPool) Create(ctx context.Context, snapshot *nyx.Snapshot) (*Overlay, error) {
+	return nil, fmt.Errorf("overlayfs overlays are only supported on Linux")
+}
+
+func (p *OverlayFSPool) Destroy(ctx context.Context, overlay *Overlay) error {
+	return nil
+}
+
+func (p *OverlayFSPool) Diff(ctx context.Context, overlay *Overlay, w io.Writer, policy *domain.OutputPolicy) (*domain.RunOutput, error) {
+	return nil, fmt.Errorf("overlayfs overlays are only supported on Linux")
+}
+
+func isWhiteout(info fs.FileInfo) bool { return false }
+
+func isOpaque(dir string) bool { return false }
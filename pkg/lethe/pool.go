@@ -2,6 +2,7 @@ package lethe
 
 import (
 	"context"
+	"io"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 	"github.com/tartarus-sandbox/tartarus/pkg/nyx"
@@ -13,6 +14,7 @@ type Overlay struct {
 	ID              string            `json:"id"`
 	MountPath       string            `json:"mount_path"`
 	BackingSnapshot domain.SnapshotID `json:"backing_snapshot"`
+	UpperDir        string            `json:"upper_dir,omitempty"` // Writable layer holding only what the sandbox changed, if the pool keeps one
 }
 
 // Pool is Lethe: creates and forgets overlays.
@@ -21,3 +23,11 @@ type Pool interface {
 	Create(ctx context.Context, snapshot *nyx.Snapshot) (*Overlay, error)
 	Destroy(ctx context.Context, overlay *Overlay) error
 }
+
+// Differ is implemented by pools whose overlays keep the sandbox's writes in
+// a separate upper layer, so they can be read out without the base.
+type Differ interface {
+	// Diff writes the overlay's changes filtered by policy to w as a
+	// gzipped tarball. The returned summary has no key.
+	Diff(ctx context.Context, overlay *Overlay, w io.Writer, policy *domain.OutputPolicy) (*domain.RunOutput, error)
+}
@@ -12,8 +12,12 @@ const (
 )
 
 // CheckAffinity returns true if the node satisfies all affinity and anti-affinity rules
-// defined in the request metadata.
+// defined in the request metadata, the request's node selector and sandbox
+// anti-affinity, and tolerates the node's taints.
 func CheckAffinity(req *domain.SandboxRequest, node domain.NodeStatus) bool {
+	if !CheckPlacement(req, node) {
+		return false
+	}
 	if req.Metadata == nil {
 		return true
 	}
@@ -42,6 +46,29 @@ func CheckAffinity(req *domain.SandboxRequest, node domain.NodeStatus) bool {
 	return true
 }
 
+// CheckPlacement returns true if the node matches the request's node
+// selector, its taints are all tolerated, and it runs no sandbox the
+// request's anti-affinity keeps it away from. Sandboxes count if they are in
+// the node's ActiveSandboxes with their template and submitter.
+func CheckPlacement(req *domain.SandboxRequest, node domain.NodeStatus) bool {
+	for _, s := range req.NodeSelector {
+		if !s.Matches(node.Labels) {
+			return false
+		}
+	}
+	if !domain.Tolerated(node.Taints, req.Tolerations) {
+		return false
+	}
+	if req.AntiAffinity != nil {
+		for _, run := range node.ActiveSandboxes {
+			if run.Active() && req.AntiAffinity.Conflicts(req, run) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // IsQuarantineRequest checks if the request requires quarantine isolation.
 // A request is considered quarantined if it has metadata["quarantine"]="true".
 func IsQuarantineRequest(req *domain.SandboxRequest) bool {
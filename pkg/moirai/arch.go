@@ -0,0 +1,31 @@
+package moirai
+
+import (
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// CheckArch returns true if the node can run the request's pinned architecture.
+// Requests without an architecture run anywhere.
+func CheckArch(req *domain.SandboxRequest, node domain.NodeStatus) bool {
+	return req.Arch == "" || domain.NodeArch(node.NodeInfo) == req.Arch
+}
+
+// FilterArchNodes returns the nodes whose architecture is in arches.
+// An empty arches list keeps every node.
+func FilterArchNodes(nodes []domain.NodeStatus, arches []string) []domain.NodeStatus {
+	if len(arches) == 0 {
+		return nodes
+	}
+	allowed := make(map[string]bool, len(arches))
+	for _, arch := range arches {
+		allowed[arch] = true
+	}
+
+	var filtered []domain.NodeStatus
+	for _, node := range nodes {
+		if allowed[domain.NodeArch(node.NodeInfo)] {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,91 @@
+package moirai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func archNode(id, arch string, freeMem domain.Megabytes) domain.NodeStatus {
+	labels := map[string]string{}
+	if arch != "" {
+		labels[domain.NodeLabelArch] = arch
+	}
+	return domain.NodeStatus{
+		NodeInfo: domain.NodeInfo{
+			ID:       domain.NodeID(id),
+			Labels:   labels,
+			Capacity: domain.ResourceCapacity{Mem: 8192},
+		},
+		Allocated: domain.ResourceCapacity{Mem: 8192 - freeMem},
+		Heartbeat: time.Now(),
+	}
+}
+
+func TestCheckArch(t *testing.T) {
+	arm := archNode("arm", domain.ArchARM64, 4096)
+	unlabeled := archNode("old", "", 4096)
+
+	if !moirai.CheckArch(&domain.SandboxRequest{}, arm) {
+		t.Error("request without arch should match any node")
+	}
+	if !moirai.CheckArch(&domain.SandboxRequest{Arch: domain.ArchARM64}, arm) {
+		t.Error("arm64 request should match arm64 node")
+	}
+	if moirai.CheckArch(&domain.SandboxRequest{Arch: domain.ArchARM64}, unlabeled) {
+		t.Error("arm64 request should not match unlabeled (amd64) node")
+	}
+	if !moirai.CheckArch(&domain.SandboxRequest{Arch: domain.ArchAMD64}, unlabeled) {
+		t.Error("amd64 request should match unlabeled node")
+	}
+}
+
+func TestSchedulers_FilterByArch(t *testing.T) {
+	logger := &mockLogger{}
+	nodes := []domain.NodeStatus{
+		archNode("amd-big", domain.ArchAMD64, 6000),
+		archNode("arm-small", domain.ArchARM64, 2048),
+	}
+	req := &domain.SandboxRequest{ID: "req", Arch: domain.ArchARM64, Resources: domain.ResourceSpec{Mem: 1024}}
+
+	schedulers := map[string]moirai.Scheduler{
+		"least-loaded": moirai.NewLeastLoadedScheduler(logger),
+		"bin-packing":  moirai.NewBinPackingScheduler(logger),
+	}
+	for name, s := range schedulers {
+		t.Run(name, func(t *testing.T) {
+			nodeID, err := s.ChooseNode(context.Background(), req, nodes)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if nodeID != "arm-small" {
+				t.Errorf("expected arm-small, got %s", nodeID)
+			}
+
+			_, err = s.ChooseNode(context.Background(), req, nodes[:1])
+			if !errors.Is(err, moirai.ErrNoCapacity) {
+				t.Errorf("expected ErrNoCapacity without arm64 nodes, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFilterArchNodes(t *testing.T) {
+	nodes := []domain.NodeStatus{
+		archNode("amd", domain.ArchAMD64, 4096),
+		archNode("arm", domain.ArchARM64, 4096),
+		archNode("old", "", 4096),
+	}
+
+	if got := moirai.FilterArchNodes(nodes, nil); len(got) != 3 {
+		t.Errorf("expected all nodes without filter, got %d", len(got))
+	}
+	got := moirai.FilterArchNodes(nodes, []string{domain.ArchAMD64})
+	if len(got) != 2 || got[0].ID != "amd" || got[1].ID != "old" {
+		t.Errorf("expected amd and old, got %v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package moirai
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// BatchScheduler places the requests of a batch against one listing of the
+// nodes. The listing only reflects sandboxes that were running when it was
+// taken, so each placement is counted against its node and later requests
+// of the batch see the capacity earlier ones took.
+type BatchScheduler struct {
+	Scheduler Scheduler
+	placed    map[domain.NodeID]domain.ResourceCapacity
+	gpus      map[domain.NodeID][]*domain.SandboxRequest // Placements that take GPUs, in order
+	runs      map[domain.NodeID][]domain.SandboxRun      // Placements, as scheduled runs for anti-affinity
+}
+
+func NewBatchScheduler(scheduler Scheduler) *BatchScheduler {
+	return &BatchScheduler{
+		Scheduler: scheduler,
+		placed:    make(map[domain.NodeID]domain.ResourceCapacity),
+		gpus:      make(map[domain.NodeID][]*domain.SandboxRequest),
+		runs:      make(map[domain.NodeID][]domain.SandboxRun),
+	}
+}
+
+// ChooseNode chooses a node for req among nodes, with the batch's earlier
+// placements added to their allocations, and counts req against it. The
+// nodes are not modified.
+func (b *BatchScheduler) ChooseNode(ctx context.Context, req *domain.SandboxRequest, nodes []domain.NodeStatus) (domain.NodeID, error) {
+	adjusted := nodes
+	if len(b.placed) > 0 {
+		adjusted = make([]domain.NodeStatus, len(nodes))
+		for i, node := range nodes {
+			if p, ok := b.placed[node.ID]; ok {
+				node.Allocated.CPU += p.CPU
+				node.Allocated.Mem += p.Mem
+				node.Allocated.GPU += p.GPU
+				node.Allocated.Swap += p.Swap
+			}
+			for _, placed := range b.gpus[node.ID] {
+				node.GPUs = reserveGPUs(node.GPUs, placed)
+			}
+			if runs := b.runs[node.ID]; len(runs) > 0 {
+				node.ActiveSandboxes = append(append([]domain.SandboxRun(nil), node.ActiveSandboxes...), runs...)
+			}
+			adjusted[i] = node
+		}
+	}
+
+	nodeID, err := b.Scheduler.ChooseNode(ctx, req, adjusted)
+	if err != nil {
+		return "", err
+	}
+	placed := addCapacity(b.placed[nodeID], req.Resources, 1)
+	for _, node := range nodes {
+		if node.ID == nodeID {
+			ram, swap := MemoryOn(req, node)
+			placed.Mem += ram - req.Resources.Mem
+			placed.Swap += swap
+			break
+		}
+	}
+	b.placed[nodeID] = placed
+	if req.Resources.GPU.Count > 0 {
+		b.gpus[nodeID] = append(b.gpus[nodeID], req)
+	}
+	// Later requests' anti-affinity sees this one on its node
+	b.runs[nodeID] = append(b.runs[nodeID], domain.SandboxRun{
+		ID:        req.ID,
+		NodeID:    nodeID,
+		Template:  req.Template,
+		Status:    domain.RunStatusScheduled,
+		Submitter: req.Submitter,
+	})
+	return nodeID, nil
+}
+
+// PackingOrder returns the indexes of reqs largest first, by memory, then
+// CPU, then GPUs, keeping submission order among equals. Placing a batch in
+// this order (first-fit decreasing) fills nodes with the large requests
+// while they still have room and leaves the gaps to the small ones, instead
+// of scattering small requests until no node fits a large one.
+func PackingOrder(reqs []*domain.SandboxRequest) []int {
+	order := make([]int, len(reqs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := reqs[order[i]].Resources, reqs[order[j]].Resources
+		if a.Mem != b.Mem {
+			return a.Mem > b.Mem
+		}
+		if a.CPU != b.CPU {
+			return a.CPU > b.CPU
+		}
+		return a.GPU.Count > b.GPU.Count
+	})
+	return order
+}
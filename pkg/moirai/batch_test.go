@@ -0,0 +1,95 @@
+package moirai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func batchNodes() []domain.NodeStatus {
+	return []domain.NodeStatus{
+		{
+			NodeInfo:  domain.NodeInfo{ID: "node-a", Capacity: domain.ResourceCapacity{Mem: 4096}},
+			Allocated: domain.ResourceCapacity{Mem: 1024}, // 3072 MB free
+			Heartbeat: time.Now(),
+		},
+		{
+			NodeInfo:  domain.NodeInfo{ID: "node-b", Capacity: domain.ResourceCapacity{Mem: 4096}},
+			Heartbeat: time.Now(),
+		},
+	}
+}
+
+func batchRequests(mems ...domain.Megabytes) []*domain.SandboxRequest {
+	reqs := make([]*domain.SandboxRequest, len(mems))
+	for i, mem := range mems {
+		reqs[i] = &domain.SandboxRequest{ID: domain.SandboxID(string(rune('a' + i))), Resources: domain.ResourceSpec{Mem: mem}}
+	}
+	return reqs
+}
+
+func TestBatchScheduler_CountsPlacements(t *testing.T) {
+	ctx := context.Background()
+	nodes := batchNodes()
+	batch := moirai.NewBatchScheduler(moirai.NewBinPackingScheduler(&mockLogger{}))
+
+	reqs := batchRequests(3072, 3072, 1024)
+	var placed []domain.NodeID
+	for _, req := range reqs {
+		nodeID, err := batch.ChooseNode(ctx, req, nodes)
+		if err != nil {
+			t.Fatalf("ChooseNode(%s): %v", req.ID, err)
+		}
+		placed = append(placed, nodeID)
+	}
+	want := []domain.NodeID{"node-a", "node-b", "node-b"}
+	for i := range want {
+		if placed[i] != want[i] {
+			t.Errorf("request %d placed on %s, want %s", i, placed[i], want[i])
+		}
+	}
+
+	// Both nodes are now full, although the listing does not show it
+	if _, err := batch.ChooseNode(ctx, batchRequests(1024)[0], nodes); !errors.Is(err, moirai.ErrNoCapacity) {
+		t.Errorf("expected ErrNoCapacity, got %v", err)
+	}
+	if nodes[0].Allocated.Mem != 1024 || nodes[1].Allocated.Mem != 0 {
+		t.Errorf("nodes were modified: %+v %+v", nodes[0].Allocated, nodes[1].Allocated)
+	}
+}
+
+func TestPackingOrder(t *testing.T) {
+	ctx := context.Background()
+	reqs := batchRequests(1024, 3072, 3072)
+
+	order := moirai.PackingOrder(reqs)
+	want := []int{1, 2, 0}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+
+	// In submission order the small request takes the tight node and the
+	// second large one fits nowhere; largest first, everything fits
+	place := func(order []int) int {
+		batch := moirai.NewBatchScheduler(moirai.NewBinPackingScheduler(&mockLogger{}))
+		placed := 0
+		for _, i := range order {
+			if _, err := batch.ChooseNode(ctx, reqs[i], batchNodes()); err == nil {
+				placed++
+			}
+		}
+		return placed
+	}
+	if got := place([]int{0, 1, 2}); got != 2 {
+		t.Errorf("submission order placed %d, want 2", got)
+	}
+	if got := place(order); got != 3 {
+		t.Errorf("packing order placed %d, want 3", got)
+	}
+}
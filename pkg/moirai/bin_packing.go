@@ -2,6 +2,7 @@ package moirai
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"time"
 
@@ -28,12 +29,14 @@ func (s *BinPackingScheduler) ChooseNode(ctx context.Context, req *domain.Sandbo
 	var candidates []candidate
 
 	now := time.Now()
+	trace := TraceFrom(ctx)
 
 	// Filter for quarantine requirements first
 	nodesToConsider := nodes
 	isQuarantine := IsQuarantineRequest(req)
 	if isQuarantine {
 		nodesToConsider = FilterTyphonNodes(nodes)
+		trace.ExcludeAll(nodes, nodesToConsider, FilterQuarantine, "not a Typhon node")
 		if len(nodesToConsider) == 0 {
 			s.Logger.Error(ctx, "No Typhon nodes available for quarantine workload", map[string]any{
 				"sandbox_id": req.ID,
@@ -47,7 +50,9 @@ func (s *BinPackingScheduler) ChooseNode(ctx context.Context, req *domain.Sandbo
 	}
 
 	// Filter for Phlegethon resource classes
+	considered := nodesToConsider
 	nodesToConsider = FilterPhlegethonNodes(nodesToConsider, req.HeatLevel)
+	trace.ExcludeAll(considered, nodesToConsider, FilterHeatClass, fmt.Sprintf("does not fit heat level %s", req.HeatLevel))
 	if len(nodesToConsider) == 0 {
 		s.Logger.Error(ctx, "No nodes available for Phlegethon resource class", map[string]any{
 			"sandbox_id": req.ID,
@@ -57,22 +62,20 @@ func (s *BinPackingScheduler) ChooseNode(ctx context.Context, req *domain.Sandbo
 	}
 
 	for _, node := range nodesToConsider {
-		// 1. Filter Unhealthy Nodes (Heartbeat > 10s ago)
-		if now.Sub(node.Heartbeat) > 10*time.Second {
+		// 1. Filter unhealthy nodes (heartbeat > 10s ago), then by capacity,
+		// affinity, architecture, GPU inventory and swap
+		if filter, reason := checkNode(req, node, now); filter != "" {
+			trace.Exclude(node.ID, filter, reason)
 			continue
 		}
 
-		// 2. Filter by Capacity
+		// 2. Score by free memory
 		freeMem := node.Capacity.Mem - node.Allocated.Mem
-		if freeMem >= req.Resources.Mem {
-			// 3. Filter by Affinity
-			if CheckAffinity(req, node) {
-				candidates = append(candidates, candidate{
-					node:    node,
-					freeMem: freeMem,
-				})
-			}
-		}
+		trace.Score(node.ID, "free_mem_mb", float64(freeMem))
+		candidates = append(candidates, candidate{
+			node:    node,
+			freeMem: freeMem,
+		})
 	}
 
 	if len(candidates) == 0 {
@@ -0,0 +1,60 @@
+package moirai
+
+import (
+	"context"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ConditionAwareScheduler hides nodes reporting disk or memory pressure from
+// the inner scheduler. Nodes that do not report conditions are kept.
+type ConditionAwareScheduler struct {
+	Inner  Scheduler
+	Logger hermes.Logger
+}
+
+func NewConditionAwareScheduler(inner Scheduler, logger hermes.Logger) *ConditionAwareScheduler {
+	return &ConditionAwareScheduler{
+		Inner:  inner,
+		Logger: logger,
+	}
+}
+
+func (s *ConditionAwareScheduler) ChooseNode(ctx context.Context, req *domain.SandboxRequest, nodes []domain.NodeStatus) (domain.NodeID, error) {
+	healthy := FilterPressuredNodes(nodes)
+	TraceFrom(ctx).ExcludeAll(nodes, healthy, FilterPressure, "reporting disk or memory pressure")
+	if skipped := len(nodes) - len(healthy); skipped > 0 {
+		s.Logger.Info(ctx, "Skipping nodes under resource pressure", map[string]any{
+			"sandbox_id": req.ID,
+			"skipped":    skipped,
+			"remaining":  len(healthy),
+		})
+	}
+	if len(healthy) == 0 {
+		return "", ErrNoCapacity
+	}
+	return s.Inner.ChooseNode(ctx, req, healthy)
+}
+
+// FilterPressuredNodes returns the nodes not reporting pressure conditions.
+func FilterPressuredNodes(nodes []domain.NodeStatus) []domain.NodeStatus {
+	var filtered []domain.NodeStatus
+	for _, node := range nodes {
+		if !node.UnderPressure() {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
+// FilterDrainingNodes returns the nodes that have not been cordoned.
+func FilterDrainingNodes(nodes []domain.NodeStatus) []domain.NodeStatus {
+	var filtered []domain.NodeStatus
+	for _, node := range nodes {
+		if !node.Draining() {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
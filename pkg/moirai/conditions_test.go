@@ -0,0 +1,35 @@
+package moirai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func TestConditionAwareScheduler(t *testing.T) {
+	logger := &mockLogger{}
+	s := moirai.NewConditionAwareScheduler(moirai.NewLeastLoadedScheduler(logger), logger)
+	req := &domain.SandboxRequest{ID: "req", Resources: domain.ResourceSpec{Mem: 1024}}
+
+	pressured := archNode("pressured-big", "", 6000)
+	pressured.Conditions = []domain.NodeCondition{{Type: domain.NodeMemoryPressure, Status: true}}
+	calm := archNode("calm-small", "", 2048)
+	calm.Conditions = []domain.NodeCondition{{Type: domain.NodeMemoryPressure, Status: false}}
+	legacy := archNode("legacy", "", 1500)
+
+	nodeID, err := s.ChooseNode(context.Background(), req, []domain.NodeStatus{pressured, calm, legacy})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nodeID != "calm-small" {
+		t.Errorf("expected calm-small, got %s", nodeID)
+	}
+
+	_, err = s.ChooseNode(context.Background(), req, []domain.NodeStatus{pressured})
+	if !errors.Is(err, moirai.ErrNoCapacity) {
+		t.Errorf("expected ErrNoCapacity when every node is under pressure, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package moirai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ChooseGang places the members of a gang: either every member gets a node
+// or none does. candidates[i] are the nodes member i may be placed on. The
+// members are placed against the one listing, each seeing the capacity the
+// others took, so the result is a placement that fits as a whole: those with
+// the fewest candidate nodes go first, so the others do not take the only
+// room they have, then the largest. The returned nodes are in the order of
+// reqs. If some member does not fit, the error wraps that member's
+// scheduling error (usually ErrNoCapacity) and nothing is placed.
+func ChooseGang(ctx context.Context, scheduler Scheduler, reqs []*domain.SandboxRequest, candidates [][]domain.NodeStatus) ([]domain.NodeID, error) {
+	if len(candidates) != len(reqs) {
+		return nil, fmt.Errorf("%d candidate lists for %d gang members", len(candidates), len(reqs))
+	}
+	batch := NewBatchScheduler(scheduler)
+	placement := make([]domain.NodeID, len(reqs))
+	order := PackingOrder(reqs)
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(candidates[order[i]]) < len(candidates[order[j]])
+	})
+	for _, i := range order {
+		nodeID, err := batch.ChooseNode(ctx, reqs[i], candidates[i])
+		if err != nil {
+			return nil, fmt.Errorf("gang member %d (%s): %w", i, reqs[i].ID, err)
+		}
+		placement[i] = nodeID
+	}
+	return placement, nil
+}
@@ -0,0 +1,58 @@
+package moirai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func gangCandidates(n int) [][]domain.NodeStatus {
+	candidates := make([][]domain.NodeStatus, n)
+	for i := range candidates {
+		candidates[i] = batchNodes()
+	}
+	return candidates
+}
+
+func TestChooseGang(t *testing.T) {
+	ctx := context.Background()
+	scheduler := moirai.NewBinPackingScheduler(&mockLogger{})
+
+	reqs := batchRequests(1024, 3072, 3072)
+	placement, err := moirai.ChooseGang(ctx, scheduler, reqs, gangCandidates(len(reqs)))
+	if err != nil {
+		t.Fatalf("ChooseGang: %v", err)
+	}
+	want := []domain.NodeID{"node-b", "node-a", "node-b"}
+	for i := range want {
+		if placement[i] != want[i] {
+			t.Errorf("member %d placed on %s, want %s", i, placement[i], want[i])
+		}
+	}
+}
+
+func TestChooseGang_AllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	scheduler := moirai.NewBinPackingScheduler(&mockLogger{})
+
+	// The first three fit, the fourth does not: no member is placed
+	reqs := batchRequests(1024, 3072, 3072, 1024)
+	placement, err := moirai.ChooseGang(ctx, scheduler, reqs, gangCandidates(len(reqs)))
+	if !errors.Is(err, moirai.ErrNoCapacity) {
+		t.Fatalf("expected ErrNoCapacity, got %v", err)
+	}
+	if placement != nil {
+		t.Errorf("expected no placement, got %v", placement)
+	}
+
+	// A member restricted to the tight node takes its room from the others
+	reqs = batchRequests(3072, 3072)
+	candidates := gangCandidates(len(reqs))
+	candidates[1] = candidates[1][:1]
+	if _, err := moirai.ChooseGang(ctx, scheduler, reqs, candidates); err != nil {
+		t.Fatalf("ChooseGang: %v", err)
+	}
+}
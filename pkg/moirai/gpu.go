@@ -0,0 +1,60 @@
+package moirai
+
+import "github.com/tartarus-sandbox/tartarus/pkg/domain"
+
+// CheckGPU reports whether node has the GPUs req asks for free. Nodes that
+// report their devices are matched by type against the units no sandbox
+// uses. For nodes that do not, only an untyped request can be matched, by
+// count against capacity.
+func CheckGPU(req *domain.SandboxRequest, node domain.NodeStatus) bool {
+	want := req.Resources.GPU
+	if want.Count <= 0 {
+		return true
+	}
+	if len(node.GPUs) == 0 {
+		return want.Type == "" && node.Capacity.GPU-node.Allocated.GPU >= want.Count
+	}
+	return len(freeGPUs(node.GPUs, want)) >= want.Count
+}
+
+// freeGPUs returns the UUIDs of the unused units of devices that match want.
+func freeGPUs(devices []domain.GPUDevice, want domain.GPURequest) []string {
+	var free []string
+	for _, unit := range domain.GPUUnits(devices) {
+		if unit.InUseBy == "" && want.Matches(unit) {
+			free = append(free, unit.UUID)
+		}
+	}
+	return free
+}
+
+// reserveGPUs returns a copy of devices with the units req would be given
+// marked in use by it, so that later placements against the same listing do
+// not count them as free.
+func reserveGPUs(devices []domain.GPUDevice, req *domain.SandboxRequest) []domain.GPUDevice {
+	free := freeGPUs(devices, req.Resources.GPU)
+	if len(free) > req.Resources.GPU.Count {
+		free = free[:req.Resources.GPU.Count]
+	}
+	take := make(map[string]bool, len(free))
+	for _, id := range free {
+		take[id] = true
+	}
+
+	out := make([]domain.GPUDevice, len(devices))
+	for i, d := range devices {
+		if take[d.UUID] {
+			d.InUseBy = req.ID
+		}
+		if len(d.MIG) > 0 {
+			d.MIG = append([]domain.MIGDevice(nil), d.MIG...)
+			for j := range d.MIG {
+				if take[d.MIG[j].UUID] {
+					d.MIG[j].InUseBy = req.ID
+				}
+			}
+		}
+		out[i] = d
+	}
+	return out
+}
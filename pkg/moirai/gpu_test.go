@@ -0,0 +1,121 @@
+package moirai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func gpuNode(id string, freeMem domain.Megabytes, gpus ...domain.GPUDevice) domain.NodeStatus {
+	return domain.NodeStatus{
+		NodeInfo: domain.NodeInfo{
+			ID:       domain.NodeID(id),
+			Capacity: domain.ResourceCapacity{Mem: 65536, GPU: len(domain.GPUUnits(gpus))},
+			GPUs:     gpus,
+		},
+		Allocated: domain.ResourceCapacity{Mem: 65536 - freeMem},
+		Heartbeat: time.Now(),
+	}
+}
+
+func gpuRequest(id string, count int, typ string) *domain.SandboxRequest {
+	return &domain.SandboxRequest{
+		ID:        domain.SandboxID(id),
+		Resources: domain.ResourceSpec{Mem: 1024, GPU: domain.GPURequest{Count: count, Type: typ}},
+	}
+}
+
+func TestCheckGPU(t *testing.T) {
+	a100 := domain.GPUDevice{UUID: "GPU-a", Model: "NVIDIA A100-SXM4-80GB", MemoryMB: 81920}
+	busy := domain.GPUDevice{UUID: "GPU-b", Model: "NVIDIA A100-SXM4-80GB", MemoryMB: 81920, InUseBy: "sb-1"}
+	mig := domain.GPUDevice{UUID: "GPU-c", Model: "NVIDIA A100-SXM4-40GB", MIGMode: true, MIG: []domain.MIGDevice{
+		{UUID: "MIG-1", Profile: "1g.5gb", MemoryMB: 5120},
+		{UUID: "MIG-2", Profile: "3g.20gb", MemoryMB: 20480},
+	}}
+	node := gpuNode("gpu", 32768, a100, busy, mig)
+
+	tests := []struct {
+		name string
+		req  *domain.SandboxRequest
+		want bool
+	}{
+		{"no GPU", gpuRequest("r", 0, ""), true},
+		{"any free unit", gpuRequest("r", 3, ""), true},
+		{"more than free", gpuRequest("r", 4, ""), false},
+		{"vendor", gpuRequest("r", 1, "nvidia"), true},
+		{"model, one free", gpuRequest("r", 1, "a100-sxm4-80gb"), true},
+		{"model, busy", gpuRequest("r", 2, "A100-SXM4-80GB"), false},
+		{"model of MIG device is not a whole GPU", gpuRequest("r", 1, "a100-sxm4-40gb"), false},
+		{"MIG profile", gpuRequest("r", 1, "mig-1g.5gb"), true},
+		{"bare MIG profile", gpuRequest("r", 1, "3g.20gb"), true},
+		{"missing MIG profile", gpuRequest("r", 1, "mig-7g.40gb"), false},
+		{"unknown model", gpuRequest("r", 1, "h100"), false},
+	}
+	for _, tt := range tests {
+		if got := moirai.CheckGPU(tt.req, node); got != tt.want {
+			t.Errorf("%s: CheckGPU = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Nodes without an inventory match untyped requests by count
+	legacy := gpuNode("legacy", 32768)
+	legacy.Capacity.GPU = 2
+	if !moirai.CheckGPU(gpuRequest("r", 2, ""), legacy) {
+		t.Error("expected untyped request to fit legacy node")
+	}
+	if moirai.CheckGPU(gpuRequest("r", 1, "a100"), legacy) {
+		t.Error("typed request cannot be matched without an inventory")
+	}
+}
+
+func TestSchedulers_GPUInventory(t *testing.T) {
+	h100 := domain.GPUDevice{UUID: "GPU-h", Model: "NVIDIA H100 80GB HBM3"}
+	t4 := domain.GPUDevice{UUID: "GPU-t", Model: "Tesla T4"}
+	nodes := []domain.NodeStatus{
+		gpuNode("t4", 60000, t4),
+		gpuNode("h100", 1024, h100),
+		gpuNode("cpu", 65536),
+	}
+
+	for _, scheduler := range []moirai.Scheduler{
+		moirai.NewLeastLoadedScheduler(hermes.NewNoopLogger()),
+		moirai.NewBinPackingScheduler(hermes.NewNoopLogger()),
+	} {
+		nodeID, err := scheduler.ChooseNode(context.Background(), gpuRequest("r", 1, "h100"), nodes)
+		if err != nil || nodeID != "h100" {
+			t.Errorf("%T: expected h100 node, got %q, %v", scheduler, nodeID, err)
+		}
+		if _, err := scheduler.ChooseNode(context.Background(), gpuRequest("r", 2, "h100"), nodes); !errors.Is(err, moirai.ErrNoCapacity) {
+			t.Errorf("%T: expected ErrNoCapacity for two H100s, got %v", scheduler, err)
+		}
+	}
+}
+
+func TestBatchScheduler_ReservesGPUs(t *testing.T) {
+	nodes := []domain.NodeStatus{
+		gpuNode("a", 65536, domain.GPUDevice{UUID: "GPU-1", Model: "NVIDIA A100"}),
+		gpuNode("b", 1024, domain.GPUDevice{UUID: "GPU-2", Model: "NVIDIA A100"}),
+	}
+	batch := moirai.NewBatchScheduler(moirai.NewLeastLoadedScheduler(hermes.NewNoopLogger()))
+
+	first, err := batch.ChooseNode(context.Background(), gpuRequest("r1", 1, "a100"), nodes)
+	if err != nil || first != "a" {
+		t.Fatalf("expected node a, got %q, %v", first, err)
+	}
+	// Node a has more memory free but its only GPU is taken by r1
+	second, err := batch.ChooseNode(context.Background(), gpuRequest("r2", 1, "a100"), nodes)
+	if err != nil || second != "b" {
+		t.Fatalf("expected node b, got %q, %v", second, err)
+	}
+	if _, err := batch.ChooseNode(context.Background(), gpuRequest("r3", 1, "a100"), nodes); !errors.Is(err, moirai.ErrNoCapacity) {
+		t.Errorf("expected ErrNoCapacity once both GPUs are taken, got %v", err)
+	}
+	if nodes[0].GPUs[0].InUseBy != "" {
+		t.Error("the listing must not be modified")
+	}
+}
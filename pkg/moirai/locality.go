@@ -0,0 +1,108 @@
+package moirai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// DefaultLocalityMinBytes is the input size from which requests are steered
+// towards the nodes holding their inputs.
+const DefaultLocalityMinBytes int64 = 256 << 20
+
+// LocalityAwareScheduler sends requests with large inputs to the nodes that
+// already hold most of their bytes, according to the image and artifact
+// caches the nodes report. It offers the inner scheduler the nodes holding
+// the most bytes first, and only falls back to nodes holding fewer when those
+// cannot host the request. Requests whose inputs total less than MinBytes
+// are passed through unchanged.
+type LocalityAwareScheduler struct {
+	Inner    Scheduler
+	MinBytes int64
+	Logger   hermes.Logger
+}
+
+func NewLocalityAwareScheduler(inner Scheduler, minBytes int64, logger hermes.Logger) *LocalityAwareScheduler {
+	if minBytes <= 0 {
+		minBytes = DefaultLocalityMinBytes
+	}
+	return &LocalityAwareScheduler{
+		Inner:    inner,
+		MinBytes: minBytes,
+		Logger:   logger,
+	}
+}
+
+func (s *LocalityAwareScheduler) ChooseNode(ctx context.Context, req *domain.SandboxRequest, nodes []domain.NodeStatus) (domain.NodeID, error) {
+	tiers, total := LocalityTiers(req, nodes)
+	if total < s.MinBytes || len(tiers) < 2 {
+		return s.Inner.ChooseNode(ctx, req, nodes)
+	}
+
+	trace := TraceFrom(ctx)
+	var err error
+	for i, tier := range tiers {
+		var nodeID domain.NodeID
+		nodeID, err = s.Inner.ChooseNode(ctx, req, tier.Nodes)
+		if err == nil {
+			trace.Note("placed in the locality tier holding %d of %d input bytes", tier.HeldBytes, total)
+			for _, rest := range tiers[i+1:] {
+				for _, node := range rest.Nodes {
+					trace.Exclude(node.ID, FilterLocality, fmt.Sprintf("holds %d of %d input bytes", rest.HeldBytes, total))
+				}
+			}
+			s.Logger.Info(ctx, "Scheduled sandbox near its inputs", map[string]any{
+				"sandbox_id":  req.ID,
+				"node_id":     nodeID,
+				"local_bytes": tier.HeldBytes,
+				"input_bytes": total,
+			})
+			return nodeID, nil
+		}
+	}
+	return "", err
+}
+
+// LocalityTier is a group of nodes holding the same number of a request's
+// input bytes.
+type LocalityTier struct {
+	HeldBytes int64
+	Nodes     []domain.NodeStatus
+}
+
+// LocalityTiers groups nodes by how many of the request's input bytes they
+// hold, most first, and returns the total size of the inputs. Images given
+// by ref without a size count as large as the largest copy any node reports.
+func LocalityTiers(req *domain.SandboxRequest, nodes []domain.NodeStatus) ([]LocalityTier, int64) {
+	inputs := make([]domain.InputArtifact, len(req.Inputs))
+	copy(inputs, req.Inputs)
+
+	var total int64
+	for i := range inputs {
+		if inputs[i].Bytes <= 0 {
+			for _, node := range nodes {
+				inputs[i].Bytes = max(inputs[i].Bytes, node.HeldBytes(inputs[i]))
+			}
+		}
+		total += inputs[i].Bytes
+	}
+
+	byHeld := make(map[int64][]domain.NodeStatus)
+	for _, node := range nodes {
+		var held int64
+		for _, input := range inputs {
+			held += node.HeldBytes(input)
+		}
+		byHeld[held] = append(byHeld[held], node)
+	}
+
+	tiers := make([]LocalityTier, 0, len(byHeld))
+	for held, members := range byHeld {
+		tiers = append(tiers, LocalityTier{HeldBytes: held, Nodes: members})
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].HeldBytes > tiers[j].HeldBytes })
+	return tiers, total
+}
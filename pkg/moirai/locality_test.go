@@ -0,0 +1,79 @@
+package moirai_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+const gib = 1 << 30
+
+func TestLocalityAwareScheduler(t *testing.T) {
+	logger := &mockLogger{}
+	s := moirai.NewLocalityAwareScheduler(moirai.NewLeastLoadedScheduler(logger), 256<<20, logger)
+
+	dataset := domain.InputArtifact{Name: "dataset", Digest: "sha256:data", Bytes: 4 * gib}
+	image := domain.InputArtifact{Name: "image", Ref: "registry.example.com/etl:v1"}
+
+	empty := archNode("empty-big", "", 6000)
+	imageOnly := archNode("image-only", "", 4000)
+	imageOnly.CachedImages = []domain.CachedImage{{Ref: image.Ref, Digest: "sha256:img", Bytes: gib}}
+	dataOnly := archNode("data-only", "", 2048)
+	dataOnly.CachedArtifacts = []domain.CachedArtifact{{Digest: dataset.Digest, Bytes: dataset.Bytes}}
+	full := archNode("full", "", 0)
+	full.CachedImages = imageOnly.CachedImages
+	full.CachedArtifacts = dataOnly.CachedArtifacts
+	nodes := []domain.NodeStatus{empty, imageOnly, dataOnly, full}
+
+	t.Run("Prefers nodes holding the most input bytes", func(t *testing.T) {
+		req := &domain.SandboxRequest{ID: "req", Resources: domain.ResourceSpec{Mem: 1024}, Inputs: []domain.InputArtifact{dataset, image}}
+		nodeID, err := s.ChooseNode(context.Background(), req, nodes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// "full" holds everything but has no room left
+		if nodeID != "data-only" {
+			t.Errorf("expected data-only, got %s", nodeID)
+		}
+	})
+
+	t.Run("Small inputs are scheduled as usual", func(t *testing.T) {
+		req := &domain.SandboxRequest{ID: "req", Resources: domain.ResourceSpec{Mem: 1024},
+			Inputs: []domain.InputArtifact{{Name: "config", Digest: dataset.Digest, Bytes: 1 << 20}}}
+		nodeID, err := s.ChooseNode(context.Background(), req, nodes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if nodeID != "empty-big" {
+			t.Errorf("expected empty-big, got %s", nodeID)
+		}
+	})
+
+	t.Run("Falls back to nodes without the inputs", func(t *testing.T) {
+		req := &domain.SandboxRequest{ID: "req", Resources: domain.ResourceSpec{Mem: 5000}, Inputs: []domain.InputArtifact{dataset}}
+		nodeID, err := s.ChooseNode(context.Background(), req, nodes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if nodeID != "empty-big" {
+			t.Errorf("expected empty-big, got %s", nodeID)
+		}
+	})
+}
+
+func TestLocalityTiers_ImageSizeFromCaches(t *testing.T) {
+	cached := archNode("cached", "", 4096)
+	cached.CachedImages = []domain.CachedImage{{Ref: "registry.example.com/etl:v1", Digest: "sha256:img", Bytes: 2 * gib}}
+	other := archNode("other", "", 4096)
+
+	req := &domain.SandboxRequest{Inputs: []domain.InputArtifact{{Name: "image", Ref: "registry.example.com/etl:v1"}}}
+	tiers, total := moirai.LocalityTiers(req, []domain.NodeStatus{other, cached})
+	if total != 2*gib {
+		t.Errorf("expected the image size from the node caches, got %d", total)
+	}
+	if len(tiers) != 2 || tiers[0].Nodes[0].ID != "cached" || tiers[0].HeldBytes != 2*gib || tiers[1].HeldBytes != 0 {
+		t.Errorf("unexpected tiers: %+v", tiers)
+	}
+}
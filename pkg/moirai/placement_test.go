@@ -0,0 +1,143 @@
+package moirai_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func TestCheckPlacement_NodeSelector(t *testing.T) {
+	node := domain.NodeStatus{NodeInfo: domain.NodeInfo{Labels: map[string]string{"disk": "ssd", "zone": "a"}}}
+
+	tests := []struct {
+		name     string
+		selector domain.NodeSelectorRequirement
+		want     bool
+	}{
+		{"in", domain.NodeSelectorRequirement{Key: "disk", Operator: domain.SelectorIn, Values: []string{"ssd", "nvme"}}, true},
+		{"in, other value", domain.NodeSelectorRequirement{Key: "disk", Operator: domain.SelectorIn, Values: []string{"hdd"}}, false},
+		{"in, missing label", domain.NodeSelectorRequirement{Key: "gpu", Operator: domain.SelectorIn, Values: []string{"a100"}}, false},
+		{"not in", domain.NodeSelectorRequirement{Key: "zone", Operator: domain.SelectorNotIn, Values: []string{"a"}}, false},
+		{"not in, missing label", domain.NodeSelectorRequirement{Key: "gpu", Operator: domain.SelectorNotIn, Values: []string{"a100"}}, true},
+		{"exists", domain.NodeSelectorRequirement{Key: "zone", Operator: domain.SelectorExists}, true},
+		{"does not exist", domain.NodeSelectorRequirement{Key: "zone", Operator: domain.SelectorDoesNotExist}, false},
+	}
+	for _, tt := range tests {
+		req := &domain.SandboxRequest{NodeSelector: []domain.NodeSelectorRequirement{tt.selector}}
+		if got := moirai.CheckAffinity(req, node); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCheckPlacement_Taints(t *testing.T) {
+	node := domain.NodeStatus{NodeInfo: domain.NodeInfo{
+		Taints: []domain.Taint{{Key: "workload", Value: "noisy", Effect: domain.TaintNoSchedule}},
+	}}
+
+	tests := []struct {
+		name        string
+		tolerations []domain.Toleration
+		want        bool
+	}{
+		{"none", nil, false},
+		{"equal", []domain.Toleration{{Key: "workload", Value: "noisy"}}, true},
+		{"equal, other value", []domain.Toleration{{Key: "workload", Value: "batch"}}, false},
+		{"exists", []domain.Toleration{{Key: "workload", Operator: domain.TolerationExists}}, true},
+		{"exists, any key", []domain.Toleration{{Operator: domain.TolerationExists, Effect: domain.TaintNoSchedule}}, true},
+	}
+	for _, tt := range tests {
+		req := &domain.SandboxRequest{Tolerations: tt.tolerations}
+		if got := moirai.CheckAffinity(req, node); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	// Tolerations do not keep requests off untainted nodes
+	req := &domain.SandboxRequest{Tolerations: []domain.Toleration{{Key: "workload", Value: "noisy"}}}
+	if !moirai.CheckAffinity(req, domain.NodeStatus{}) {
+		t.Error("expected a tolerating request to be placed on an untainted node")
+	}
+}
+
+func TestCheckPlacement_AntiAffinity(t *testing.T) {
+	acme := &domain.Submitter{ID: "alice", TenantID: "acme"}
+	node := domain.NodeStatus{ActiveSandboxes: []domain.SandboxRun{
+		{ID: "r1", Template: "builder", Status: domain.RunStatusRunning, Submitter: acme},
+		{ID: "r2", Template: "crawler", Status: domain.RunStatusSucceeded, Submitter: &domain.Submitter{ID: "bob", TenantID: "globex"}},
+	}}
+
+	req := &domain.SandboxRequest{ID: "new", Template: "builder", AntiAffinity: &domain.AntiAffinity{Template: true}}
+	if moirai.CheckAffinity(req, node) {
+		t.Error("expected the node running the same template to be rejected")
+	}
+	req = &domain.SandboxRequest{ID: "new", Template: "crawler", AntiAffinity: &domain.AntiAffinity{Template: true}}
+	if !moirai.CheckAffinity(req, node) {
+		t.Error("expected finished sandboxes not to count")
+	}
+	req = &domain.SandboxRequest{ID: "new", Template: "linter", Submitter: &domain.Submitter{ID: "carol", TenantID: "acme"}, AntiAffinity: &domain.AntiAffinity{Tenant: true}}
+	if moirai.CheckAffinity(req, node) {
+		t.Error("expected the node running the same tenant to be rejected")
+	}
+	req.AntiAffinity = nil
+	if !moirai.CheckAffinity(req, node) {
+		t.Error("expected the node to be accepted without anti-affinity")
+	}
+}
+
+func TestBatchScheduler_AntiAffinity(t *testing.T) {
+	now := time.Now()
+	nodes := []domain.NodeStatus{
+		{NodeInfo: domain.NodeInfo{ID: "n1", Capacity: domain.ResourceCapacity{CPU: 8000, Mem: 16384}}, Heartbeat: now},
+		{NodeInfo: domain.NodeInfo{ID: "n2", Capacity: domain.ResourceCapacity{CPU: 8000, Mem: 16384}}, Heartbeat: now},
+	}
+	batch := moirai.NewBatchScheduler(moirai.NewBinPackingScheduler(hermes.NewNoopLogger()))
+
+	// Each request of the batch avoids the nodes of the earlier ones
+	placed := map[domain.NodeID]bool{}
+	for _, id := range []domain.SandboxID{"a", "b"} {
+		req := &domain.SandboxRequest{
+			ID:           id,
+			Template:     "crawler",
+			Resources:    domain.ResourceSpec{CPU: 1000, Mem: 1024},
+			AntiAffinity: &domain.AntiAffinity{Template: true},
+		}
+		node, err := batch.ChooseNode(context.Background(), req, nodes)
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if placed[node] {
+			t.Fatalf("%s: placed on %s with an earlier request of its template", id, node)
+		}
+		placed[node] = true
+	}
+
+	req := &domain.SandboxRequest{ID: "c", Template: "crawler", Resources: domain.ResourceSpec{CPU: 1000, Mem: 1024}, AntiAffinity: &domain.AntiAffinity{Template: true}}
+	if _, err := batch.ChooseNode(context.Background(), req, nodes); err == nil {
+		t.Error("expected the third request to find no node")
+	}
+}
+
+func TestParseTaints(t *testing.T) {
+	taints, err := domain.ParseTaints("workload=noisy:NoSchedule, dedicated:NoSchedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.Taint{
+		{Key: "workload", Value: "noisy", Effect: domain.TaintNoSchedule},
+		{Key: "dedicated", Effect: domain.TaintNoSchedule},
+	}
+	if len(taints) != len(want) || taints[0] != want[0] || taints[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, taints)
+	}
+	if _, err := domain.ParseTaints("workload=noisy"); err == nil {
+		t.Error("expected a taint without effect to be rejected")
+	}
+	if _, err := domain.ParseTaints("workload:PreferNoSchedule"); err == nil {
+		t.Error("expected an unknown effect to be rejected")
+	}
+}
@@ -0,0 +1,67 @@
+package moirai
+
+import (
+	"context"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// RegionAwareScheduler prefers nodes in the local region and only considers
+// other regions when the local region cannot host the request.
+// Nodes without a region label are treated as local.
+type RegionAwareScheduler struct {
+	Inner            Scheduler
+	LocalRegion      string
+	AllowCrossRegion bool
+	Logger           hermes.Logger
+}
+
+func NewRegionAwareScheduler(inner Scheduler, localRegion string, allowCrossRegion bool, logger hermes.Logger) *RegionAwareScheduler {
+	return &RegionAwareScheduler{
+		Inner:            inner,
+		LocalRegion:      localRegion,
+		AllowCrossRegion: allowCrossRegion,
+		Logger:           logger,
+	}
+}
+
+func (s *RegionAwareScheduler) ChooseNode(ctx context.Context, req *domain.SandboxRequest, nodes []domain.NodeStatus) (domain.NodeID, error) {
+	local, remote := PartitionByRegion(nodes, s.LocalRegion)
+	trace := TraceFrom(ctx)
+
+	nodeID, err := s.Inner.ChooseNode(ctx, req, local)
+	if err == nil {
+		trace.ExcludeAll(nodes, local, FilterRegion, "local region "+s.LocalRegion+" preferred")
+		return nodeID, nil
+	}
+
+	if !s.AllowCrossRegion || len(remote) == 0 {
+		trace.ExcludeAll(nodes, local, FilterRegion, "outside local region "+s.LocalRegion)
+		return "", err
+	}
+	trace.Note("local region %s cannot host the sandbox (%v), failing over to %d remote nodes", s.LocalRegion, err, len(remote))
+
+	s.Logger.Info(ctx, "Local region cannot host sandbox, failing over to remote regions", map[string]any{
+		"sandbox_id":   req.ID,
+		"local_region": s.LocalRegion,
+		"local_error":  err.Error(),
+		"remote_nodes": len(remote),
+	})
+
+	return s.Inner.ChooseNode(ctx, req, remote)
+}
+
+// PartitionByRegion splits nodes into those in (or unattributed to) the given
+// region and those in other regions.
+func PartitionByRegion(nodes []domain.NodeStatus, region string) (local, remote []domain.NodeStatus) {
+	for _, node := range nodes {
+		nodeRegion := node.Labels[domain.NodeLabelRegion]
+		if nodeRegion == "" || nodeRegion == region {
+			local = append(local, node)
+		} else {
+			remote = append(remote, node)
+		}
+	}
+	return local, remote
+}
@@ -0,0 +1,83 @@
+package moirai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func regionNode(id, region string, freeMem domain.Megabytes) domain.NodeStatus {
+	return domain.NodeStatus{
+		NodeInfo: domain.NodeInfo{
+			ID:       domain.NodeID(id),
+			Labels:   map[string]string{domain.NodeLabelRegion: region},
+			Capacity: domain.ResourceCapacity{Mem: 8192},
+		},
+		Allocated: domain.ResourceCapacity{Mem: 8192 - freeMem},
+		Heartbeat: time.Now(),
+	}
+}
+
+func TestRegionAwareScheduler(t *testing.T) {
+	logger := &mockLogger{}
+	inner := moirai.NewLeastLoadedScheduler(logger)
+	req := &domain.SandboxRequest{ID: "req", Resources: domain.ResourceSpec{Mem: 1024}}
+
+	t.Run("Prefers local region", func(t *testing.T) {
+		nodes := []domain.NodeStatus{
+			regionNode("remote-big", "eu-west", 6000),
+			regionNode("local-small", "us-east", 2048),
+		}
+		s := moirai.NewRegionAwareScheduler(inner, "us-east", true, logger)
+		nodeID, err := s.ChooseNode(context.Background(), req, nodes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if nodeID != "local-small" {
+			t.Errorf("expected local-small, got %s", nodeID)
+		}
+	})
+
+	t.Run("Fails over to remote region", func(t *testing.T) {
+		nodes := []domain.NodeStatus{
+			regionNode("remote", "eu-west", 6000),
+			regionNode("local-full", "us-east", 0),
+		}
+		s := moirai.NewRegionAwareScheduler(inner, "us-east", true, logger)
+		nodeID, err := s.ChooseNode(context.Background(), req, nodes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if nodeID != "remote" {
+			t.Errorf("expected remote, got %s", nodeID)
+		}
+	})
+
+	t.Run("Cross-region disabled", func(t *testing.T) {
+		nodes := []domain.NodeStatus{
+			regionNode("remote", "eu-west", 6000),
+			regionNode("local-full", "us-east", 0),
+		}
+		s := moirai.NewRegionAwareScheduler(inner, "us-east", false, logger)
+		_, err := s.ChooseNode(context.Background(), req, nodes)
+		if !errors.Is(err, moirai.ErrNoCapacity) {
+			t.Errorf("expected ErrNoCapacity, got %v", err)
+		}
+	})
+
+	t.Run("Unlabeled nodes are local", func(t *testing.T) {
+		unlabeled := regionNode("unlabeled", "", 4096)
+		unlabeled.Labels = nil
+		local, remote := moirai.PartitionByRegion([]domain.NodeStatus{unlabeled, regionNode("remote", "eu-west", 1)}, "us-east")
+		if len(local) != 1 || local[0].ID != "unlabeled" {
+			t.Errorf("expected unlabeled node to be local, got %v", local)
+		}
+		if len(remote) != 1 {
+			t.Errorf("expected one remote node, got %d", len(remote))
+		}
+	})
+}
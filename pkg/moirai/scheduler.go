@@ -24,6 +24,8 @@ func NewScheduler(strategy string, logger hermes.Logger) Scheduler {
 		return NewBinPackingScheduler(logger)
 	case "least-loaded":
 		return NewLeastLoadedScheduler(logger)
+	case StrategyTopologySpread:
+		return NewTopologySpreadScheduler(NewLeastLoadedScheduler(logger), DefaultTopologyKey, 1, logger, nil)
 	default:
 		logger.Info(context.Background(), "Unknown scheduler strategy, defaulting to least-loaded", map[string]any{"strategy": strategy})
 		return NewLeastLoadedScheduler(logger)
@@ -0,0 +1,424 @@
+package moirai
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// MaxSimulatedRequests bounds the size of a simulated workload.
+const MaxSimulatedRequests = 5000
+
+// defaultSimulatedDuration is how long a simulated sandbox holds its node
+// when the request sets neither a duration nor a TTL.
+const defaultSimulatedDuration = 5 * time.Minute
+
+var ErrInvalidWorkload = errors.New("invalid simulation workload")
+
+// SimulatedRequest is one request shape of a what-if workload.
+type SimulatedRequest struct {
+	Template  domain.TemplateID   `json:"template,omitempty"`
+	HeatLevel string              `json:"heat_level,omitempty"`
+	Resources domain.ResourceSpec `json:"resources"`
+	Duration  time.Duration       `json:"duration,omitempty"` // How long the sandbox holds its node (default: resources.ttl, then 5m)
+	Count     int                 `json:"count,omitempty"`    // Copies to submit (default 1); the weight in a rate profile
+	At        time.Duration       `json:"at,omitempty"`       // Arrival offset from the start of the simulation
+}
+
+// RateProfile submits requests at a steady rate over a window, cycling
+// through the mix in proportion to each shape's count.
+type RateProfile struct {
+	PerMinute float64            `json:"per_minute"`
+	Window    time.Duration      `json:"window"`
+	Mix       []SimulatedRequest `json:"mix"`
+}
+
+// Workload is the hypothetical demand to simulate: explicit requests, a
+// rate profile, or both.
+type Workload struct {
+	Requests []SimulatedRequest `json:"requests,omitempty"`
+	Rate     *RateProfile       `json:"rate,omitempty"`
+	Horizon  time.Duration      `json:"horizon,omitempty"` // Stop after this much simulated time (default: until the queue settles)
+}
+
+// SimulationResult is the predicted outcome of a workload.
+type SimulationResult struct {
+	Requests   int                       `json:"requests"`
+	Placed     int                       `json:"placed"`
+	Nodes      map[domain.NodeID]int     `json:"nodes"` // Node -> requests placed on it
+	Placements []SimulatedPlacement      `json:"placements"`
+	Wait       WaitStats                 `json:"wait"`
+	Classes    map[string]*ClassForecast `json:"classes"` // Keyed by heat level
+}
+
+// SimulatedPlacement is where and when one request was placed. Requests
+// that were never placed have no node.
+type SimulatedPlacement struct {
+	Index     int               `json:"index"`
+	Template  domain.TemplateID `json:"template,omitempty"`
+	HeatLevel string            `json:"heat_level,omitempty"`
+	NodeID    domain.NodeID     `json:"node_id,omitempty"`
+	Arrival   time.Duration     `json:"arrival"`
+	Wait      time.Duration     `json:"wait"`
+}
+
+// WaitStats summarizes the queue wait of placed requests.
+type WaitStats struct {
+	Immediate int           `json:"immediate"` // Placed on arrival
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+}
+
+// ClassForecast is the outcome for one heat class. The shortfall is the
+// largest amount of resources waiting in the queue at any one time: the
+// extra capacity the class needed to place everything on arrival.
+type ClassForecast struct {
+	Requests      int              `json:"requests"`
+	Placed        int              `json:"placed"`
+	Unplaced      int              `json:"unplaced"`      // Still queued when the simulation ended
+	Unschedulable int              `json:"unschedulable"` // Fit no node even before the workload started
+	Wait          WaitStats        `json:"wait"`
+	PeakQueued    int              `json:"peak_queued"`
+	ShortfallCPU  domain.MilliCPU  `json:"shortfall_cpu_milli"`
+	ShortfallMem  domain.Megabytes `json:"shortfall_mem_mb"`
+	ShortfallGPU  int              `json:"shortfall_gpu"`
+}
+
+// Expand turns the workload into individual requests ordered by arrival.
+func (w *Workload) Expand() ([]SimulatedRequest, error) {
+	var out []SimulatedRequest
+	add := func(r SimulatedRequest) error {
+		if len(out) >= MaxSimulatedRequests {
+			return fmt.Errorf("%w: more than %d requests", ErrInvalidWorkload, MaxSimulatedRequests)
+		}
+		r.Count = 1
+		out = append(out, r)
+		return nil
+	}
+
+	for _, r := range w.Requests {
+		if r.Count < 0 || r.At < 0 {
+			return nil, fmt.Errorf("%w: negative count or arrival", ErrInvalidWorkload)
+		}
+		for i := 0; i < max(r.Count, 1); i++ {
+			if err := add(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if p := w.Rate; p != nil {
+		if p.PerMinute <= 0 || p.Window <= 0 || len(p.Mix) == 0 {
+			return nil, fmt.Errorf("%w: rate profile needs per_minute, window and mix", ErrInvalidWorkload)
+		}
+		// Weighted round robin over the mix
+		var cycle []SimulatedRequest
+		for _, r := range p.Mix {
+			if r.Count < 0 {
+				return nil, fmt.Errorf("%w: negative count", ErrInvalidWorkload)
+			}
+			for i := 0; i < max(r.Count, 1); i++ {
+				cycle = append(cycle, r)
+			}
+		}
+		interval := time.Duration(float64(time.Minute) / p.PerMinute)
+		if interval <= 0 {
+			return nil, fmt.Errorf("%w: rate too high", ErrInvalidWorkload)
+		}
+		for i, at := 0, time.Duration(0); at < p.Window; i, at = i+1, at+interval {
+			r := cycle[i%len(cycle)]
+			r.At = at
+			if err := add(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: no requests", ErrInvalidWorkload)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At < out[j].At })
+	return out, nil
+}
+
+// Simulate replays the workload against copies of the nodes using the
+// scheduler's real placement logic. Simulated time advances from arrival to
+// arrival and release to release; requests that do not fit wait in a FIFO
+// queue until a sandbox finishes. Allocations already on the nodes are
+// treated as permanent. The nodes are not modified.
+//
+// The scheduler is called for every placement attempt, so it should be
+// built with a logger that discards its output.
+func Simulate(ctx context.Context, scheduler Scheduler, nodes []domain.NodeStatus, workload *Workload) (*SimulationResult, error) {
+	requests, err := workload.Expand()
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := append([]domain.NodeStatus(nil), nodes...)
+	state := append([]domain.NodeStatus(nil), nodes...)
+	index := make(map[domain.NodeID]int, len(state))
+	for i, n := range state {
+		index[n.ID] = i
+	}
+
+	sim := &simulation{
+		result: &SimulationResult{
+			Requests:   len(requests),
+			Nodes:      make(map[domain.NodeID]int),
+			Placements: make([]SimulatedPlacement, len(requests)),
+			Classes:    make(map[string]*ClassForecast),
+		},
+		queued: make(map[string]*domain.ResourceCapacity),
+		waits:  make(map[string][]time.Duration),
+	}
+	for i, r := range requests {
+		sim.result.Placements[i] = SimulatedPlacement{Index: i, Template: r.Template, HeatLevel: r.HeatLevel, Arrival: r.At}
+		sim.class(r.HeatLevel).Requests++
+	}
+
+	place := func(i int, now time.Duration) (bool, error) {
+		r := requests[i]
+		req := simulatedSandboxRequest(i, r)
+		nodeID, err := scheduler.ChooseNode(ctx, req, state)
+		if err != nil {
+			if errors.Is(err, ErrNoCapacity) || errors.Is(err, ErrNoTyphonNodes) {
+				return false, nil
+			}
+			return false, err
+		}
+		n, ok := index[nodeID]
+		if !ok {
+			return false, fmt.Errorf("scheduler chose unknown node %s", nodeID)
+		}
+		state[n].Allocated = addCapacity(state[n].Allocated, r.Resources, 1)
+		heap.Push(&sim.running, simulatedRelease{at: now + simulatedDuration(r), node: n, req: i})
+
+		wait := now - r.At
+		p := &sim.result.Placements[i]
+		p.NodeID, p.Wait = nodeID, wait
+		sim.result.Nodes[nodeID]++
+		sim.result.Placed++
+		sim.class(r.HeatLevel).Placed++
+		sim.waits[r.HeatLevel] = append(sim.waits[r.HeatLevel], wait)
+		return true, nil
+	}
+
+	// Whether each request shape fits the nodes before the workload starts
+	type fitKey struct {
+		template  domain.TemplateID
+		heatLevel string
+		resources domain.ResourceSpec
+	}
+	schedulable := make(map[fitKey]bool)
+
+	var queue []int
+	next := 0
+	for next < len(requests) || (len(queue) > 0 && sim.running.Len() > 0) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Advance to the next arrival or release
+		now := time.Duration(-1)
+		if next < len(requests) {
+			now = requests[next].At
+		}
+		if sim.running.Len() > 0 && (now < 0 || sim.running[0].at < now) {
+			now = sim.running[0].at
+		}
+		if workload.Horizon > 0 && now > workload.Horizon {
+			break
+		}
+
+		released := false
+		for sim.running.Len() > 0 && sim.running[0].at <= now {
+			rel := heap.Pop(&sim.running).(simulatedRelease)
+			state[rel.node].Allocated = addCapacity(state[rel.node].Allocated, requests[rel.req].Resources, -1)
+			released = true
+		}
+
+		firstNew := len(queue)
+		for next < len(requests) && requests[next].At <= now {
+			r := requests[next]
+			key := fitKey{r.Template, r.HeatLevel, r.Resources}
+			ok, seen := schedulable[key]
+			if !seen {
+				if ok, err = fits(ctx, scheduler, simulatedSandboxRequest(next, r), baseline); err != nil {
+					return nil, err
+				}
+				schedulable[key] = ok
+			}
+			if ok {
+				queue = append(queue, next)
+			} else {
+				sim.class(r.HeatLevel).Unschedulable++
+			}
+			next++
+		}
+
+		// Queued requests only fit again once something was released
+		attempt := queue[firstNew:]
+		if released {
+			attempt = queue
+		}
+
+		placed := make(map[int]bool)
+		for _, i := range attempt {
+			ok, err := place(i, now)
+			if err != nil {
+				return nil, err
+			}
+			placed[i] = ok
+		}
+		remaining := queue[:0]
+		for _, i := range queue {
+			if !placed[i] {
+				remaining = append(remaining, i)
+			}
+		}
+		queue = remaining
+		sim.observeQueue(requests, queue)
+	}
+
+	// Requests still queued or not yet arrived at the horizon
+	for _, i := range queue {
+		sim.class(requests[i].HeatLevel).Unplaced++
+	}
+	for _, r := range requests[next:] {
+		sim.class(r.HeatLevel).Unplaced++
+	}
+
+	var all []time.Duration
+	for level, waits := range sim.waits {
+		sim.class(level).Wait = waitStats(waits)
+		all = append(all, waits...)
+	}
+	sim.result.Wait = waitStats(all)
+	return sim.result, nil
+}
+
+type simulation struct {
+	result  *SimulationResult
+	running releaseHeap
+	queued  map[string]*domain.ResourceCapacity // Per-class scratch space for observeQueue
+	waits   map[string][]time.Duration
+}
+
+func (s *simulation) class(level string) *ClassForecast {
+	c, ok := s.result.Classes[level]
+	if !ok {
+		c = &ClassForecast{}
+		s.result.Classes[level] = c
+	}
+	return c
+}
+
+// observeQueue records the per-class peaks of the queue.
+func (s *simulation) observeQueue(requests []SimulatedRequest, queue []int) {
+	counts := make(map[string]int)
+	for _, q := range s.queued {
+		*q = domain.ResourceCapacity{}
+	}
+	for _, i := range queue {
+		r := requests[i]
+		q, ok := s.queued[r.HeatLevel]
+		if !ok {
+			q = &domain.ResourceCapacity{}
+			s.queued[r.HeatLevel] = q
+		}
+		*q = addCapacity(*q, r.Resources, 1)
+		counts[r.HeatLevel]++
+	}
+	for level, q := range s.queued {
+		c := s.class(level)
+		c.PeakQueued = max(c.PeakQueued, counts[level])
+		c.ShortfallCPU = max(c.ShortfallCPU, q.CPU)
+		c.ShortfallMem = max(c.ShortfallMem, q.Mem)
+		c.ShortfallGPU = max(c.ShortfallGPU, q.GPU)
+	}
+}
+
+// fits reports whether the request could be placed on the nodes at all.
+func fits(ctx context.Context, scheduler Scheduler, req *domain.SandboxRequest, nodes []domain.NodeStatus) (bool, error) {
+	_, err := scheduler.ChooseNode(ctx, req, nodes)
+	if errors.Is(err, ErrNoCapacity) || errors.Is(err, ErrNoTyphonNodes) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func simulatedSandboxRequest(i int, r SimulatedRequest) *domain.SandboxRequest {
+	return &domain.SandboxRequest{
+		ID:        domain.SandboxID(fmt.Sprintf("sim-%d", i)),
+		Template:  r.Template,
+		HeatLevel: r.HeatLevel,
+		Resources: r.Resources,
+	}
+}
+
+func simulatedDuration(r SimulatedRequest) time.Duration {
+	switch {
+	case r.Duration > 0:
+		return r.Duration
+	case r.Resources.TTL > 0:
+		return r.Resources.TTL
+	default:
+		return defaultSimulatedDuration
+	}
+}
+
+func addCapacity(c domain.ResourceCapacity, r domain.ResourceSpec, sign int) domain.ResourceCapacity {
+	c.CPU += domain.MilliCPU(sign) * r.CPU
+	c.Mem += domain.Megabytes(sign) * r.Mem
+	c.GPU += sign * r.GPU.Count
+	return c
+}
+
+func waitStats(waits []time.Duration) WaitStats {
+	if len(waits) == 0 {
+		return WaitStats{}
+	}
+	sorted := append([]time.Duration(nil), waits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats := WaitStats{Max: sorted[len(sorted)-1]}
+	for _, w := range sorted {
+		if w == 0 {
+			stats.Immediate++
+		}
+	}
+	percentile := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	stats.P50, stats.P90, stats.P99 = percentile(0.50), percentile(0.90), percentile(0.99)
+	return stats
+}
+
+// simulatedRelease frees a placed request's resources at a point in
+// simulated time.
+type simulatedRelease struct {
+	at   time.Duration
+	node int
+	req  int
+}
+
+type releaseHeap []simulatedRelease
+
+func (h releaseHeap) Len() int           { return len(h) }
+func (h releaseHeap) Less(i, j int) bool { return h[i].at < h[j].at }
+func (h releaseHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *releaseHeap) Push(x any)        { *h = append(*h, x.(simulatedRelease)) }
+func (h *releaseHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
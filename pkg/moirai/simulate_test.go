@@ -0,0 +1,129 @@
+package moirai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+func simNode(id domain.NodeID, mem domain.Megabytes) domain.NodeStatus {
+	return domain.NodeStatus{
+		NodeInfo:  domain.NodeInfo{ID: id, Capacity: domain.ResourceCapacity{CPU: 8000, Mem: mem}},
+		Heartbeat: time.Now(),
+	}
+}
+
+func TestSimulate_QueuesUntilRelease(t *testing.T) {
+	nodes := []domain.NodeStatus{simNode("node-1", 2048), simNode("node-2", 1024)}
+	workload := &Workload{Requests: []SimulatedRequest{
+		{HeatLevel: "warm", Resources: domain.ResourceSpec{CPU: 1000, Mem: 1024}, Duration: time.Minute, Count: 4},
+	}}
+
+	result, err := Simulate(context.Background(), NewLeastLoadedScheduler(hermes.NewNoopLogger()), nodes, workload)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+
+	// Three fit immediately, the fourth waits for the first release
+	if result.Requests != 4 || result.Placed != 4 {
+		t.Fatalf("expected 4 of 4 placed, got %d of %d", result.Placed, result.Requests)
+	}
+	if result.Nodes["node-1"] != 3 || result.Nodes["node-2"] != 1 {
+		t.Errorf("unexpected node placements %v", result.Nodes)
+	}
+	if result.Wait.Immediate != 3 || result.Wait.Max != time.Minute {
+		t.Errorf("unexpected wait stats %+v", result.Wait)
+	}
+	if result.Placements[3].Wait != time.Minute || result.Placements[3].NodeID == "" {
+		t.Errorf("unexpected placement of queued request %+v", result.Placements[3])
+	}
+
+	warm := result.Classes["warm"]
+	if warm == nil || warm.PeakQueued != 1 || warm.ShortfallMem != 1024 || warm.ShortfallCPU != 1000 || warm.Unplaced != 0 {
+		t.Errorf("unexpected class forecast %+v", warm)
+	}
+
+	// The real nodes are untouched
+	if nodes[0].Allocated.Mem != 0 || nodes[1].Allocated.Mem != 0 {
+		t.Errorf("simulation modified the input nodes: %+v", nodes)
+	}
+}
+
+func TestSimulate_HeatClassesAndHorizon(t *testing.T) {
+	gpuNode := simNode("gpu-1", 4096)
+	gpuNode.Capacity.GPU = 1
+	gpuNode.Labels = map[string]string{PoolLabel: "inferno"}
+	nodes := []domain.NodeStatus{simNode("node-1", 1024), gpuNode}
+
+	workload := &Workload{
+		Rate: &RateProfile{
+			PerMinute: 2,
+			Window:    10 * time.Minute,
+			Mix: []SimulatedRequest{
+				{HeatLevel: "cold", Resources: domain.ResourceSpec{Mem: 512}, Duration: 5 * time.Minute},
+			},
+		},
+		Requests: []SimulatedRequest{
+			// Larger than any node that may host it
+			{HeatLevel: "cold", Resources: domain.ResourceSpec{Mem: 8192}},
+		},
+		Horizon: 15 * time.Minute,
+	}
+
+	result, err := Simulate(context.Background(), NewLeastLoadedScheduler(hermes.NewNoopLogger()), nodes, workload)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+
+	cold := result.Classes["cold"]
+	if cold.Requests != 21 || cold.Unschedulable != 1 {
+		t.Fatalf("unexpected cold forecast %+v", cold)
+	}
+	if cold.Placed+cold.Unplaced+cold.Unschedulable != cold.Requests {
+		t.Errorf("requests unaccounted for: %+v", cold)
+	}
+	if cold.Unplaced == 0 || cold.ShortfallMem == 0 {
+		t.Errorf("expected the cold class to fall short, got %+v", cold)
+	}
+	if result.Nodes["gpu-1"] != 0 {
+		t.Errorf("cold requests must not land in the inferno pool, got %v", result.Nodes)
+	}
+}
+
+func TestWorkload_Expand(t *testing.T) {
+	w := &Workload{Rate: &RateProfile{
+		PerMinute: 3,
+		Window:    time.Minute,
+		Mix: []SimulatedRequest{
+			{Template: "a", Count: 2},
+			{Template: "b"},
+		},
+	}}
+	requests, err := w.Expand()
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	if requests[0].Template != "a" || requests[1].Template != "a" || requests[2].Template != "b" {
+		t.Errorf("unexpected mix order %+v", requests)
+	}
+	if requests[1].At != 20*time.Second || requests[2].At != 40*time.Second {
+		t.Errorf("unexpected arrivals %v, %v", requests[1].At, requests[2].At)
+	}
+
+	for _, bad := range []*Workload{
+		{},
+		{Rate: &RateProfile{PerMinute: 1, Window: time.Minute}},
+		{Requests: []SimulatedRequest{{Count: MaxSimulatedRequests + 1}}},
+	} {
+		if _, err := bad.Expand(); !errors.Is(err, ErrInvalidWorkload) {
+			t.Errorf("expected ErrInvalidWorkload for %+v, got %v", bad, err)
+		}
+	}
+}
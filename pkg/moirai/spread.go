@@ -0,0 +1,156 @@
+package moirai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// StrategyTopologySpread is the strategy name of TopologySpreadScheduler.
+const StrategyTopologySpread = "topology-spread"
+
+// DefaultTopologyKey is the node label topology-spread reads domains from.
+const DefaultTopologyKey = domain.NodeLabelZone
+
+// TopologySpreadScheduler spreads sandboxes of the same template across the
+// domains (zones) named by a node label, for resilience. A domain is
+// eligible while placing there leaves it at most MaxSkew sandboxes of the
+// template above the emptiest domain; the inner scheduler picks among the
+// nodes of eligible domains. If none has capacity, the request is placed
+// anywhere rather than waits, and the violation is counted.
+//
+// Sandboxes count if they are in the nodes' ActiveSandboxes with their
+// template. Requests without a template are not spread, and nodes without
+// the label only take requests that cannot be spread.
+type TopologySpreadScheduler struct {
+	Inner       Scheduler
+	TopologyKey string
+	MaxSkew     int
+	Logger      hermes.Logger
+	Metrics     hermes.Metrics // Optional
+}
+
+// NewTopologySpreadScheduler spreads by the node label topologyKey
+// (DefaultTopologyKey if empty) with maxSkew (1 if below 1), choosing nodes
+// within domains with inner.
+func NewTopologySpreadScheduler(inner Scheduler, topologyKey string, maxSkew int, logger hermes.Logger, metrics hermes.Metrics) *TopologySpreadScheduler {
+	if topologyKey == "" {
+		topologyKey = DefaultTopologyKey
+	}
+	if maxSkew < 1 {
+		maxSkew = 1
+	}
+	return &TopologySpreadScheduler{
+		Inner:       inner,
+		TopologyKey: topologyKey,
+		MaxSkew:     maxSkew,
+		Logger:      logger,
+		Metrics:     metrics,
+	}
+}
+
+func (s *TopologySpreadScheduler) ChooseNode(ctx context.Context, req *domain.SandboxRequest, nodes []domain.NodeStatus) (domain.NodeID, error) {
+	if req.Template == "" {
+		return s.Inner.ChooseNode(ctx, req, nodes)
+	}
+
+	counts := SpreadCounts(req.Template, s.TopologyKey, nodes)
+	eligible := s.eligibleDomains(counts)
+	var spread []domain.NodeStatus
+	for _, node := range nodes {
+		if eligible[node.Labels[s.TopologyKey]] {
+			spread = append(spread, node)
+		}
+	}
+	trace := TraceFrom(ctx)
+	if len(spread) > 0 {
+		nodeID, err := s.Inner.ChooseNode(ctx, req, spread)
+		if !errors.Is(err, ErrNoCapacity) {
+			if err == nil {
+				trace.ExcludeAll(nodes, spread, FilterSpread, fmt.Sprintf("%s domain would exceed max skew %d", s.TopologyKey, s.MaxSkew))
+			}
+			return nodeID, err
+		}
+	}
+
+	// No eligible domain can take the request: place it anywhere
+	nodeID, err := s.Inner.ChooseNode(ctx, req, nodes)
+	if err != nil || len(counts) == 0 {
+		return nodeID, err
+	}
+	zone := ""
+	for _, node := range nodes {
+		if node.ID == nodeID {
+			zone = node.Labels[s.TopologyKey]
+			break
+		}
+	}
+	trace.Note("no %s domain within max skew %d could host the sandbox; placed in %q", s.TopologyKey, s.MaxSkew, zone)
+	s.Logger.Info(ctx, "Placed sandbox outside its topology spread", map[string]any{
+		"sandbox_id":   req.ID,
+		"template":     req.Template,
+		"node_id":      nodeID,
+		"topology_key": s.TopologyKey,
+		"domain":       zone,
+		"max_skew":     s.MaxSkew,
+	})
+	if s.Metrics != nil {
+		s.Metrics.IncCounter("moirai_spread_violations_total", 1,
+			hermes.Label{Key: "topology_key", Value: s.TopologyKey},
+			hermes.Label{Key: "domain", Value: zone},
+		)
+	}
+	return nodeID, nil
+}
+
+// eligibleDomains returns the domains placing one more sandbox in keeps
+// within MaxSkew of the emptiest.
+func (s *TopologySpreadScheduler) eligibleDomains(counts map[string]int) map[string]bool {
+	if len(counts) == 0 {
+		return nil
+	}
+	least := -1
+	for _, n := range counts {
+		if least < 0 || n < least {
+			least = n
+		}
+	}
+	eligible := make(map[string]bool, len(counts))
+	for d, n := range counts {
+		if n+1-least <= s.MaxSkew {
+			eligible[d] = true
+		}
+	}
+	return eligible
+}
+
+// SpreadCounts returns, for each domain of topologyKey with a healthy node,
+// the active sandboxes of template on its nodes. Domains without a healthy
+// node are left out, so that they do not hold the others back.
+func SpreadCounts(template domain.TemplateID, topologyKey string, nodes []domain.NodeStatus) map[string]int {
+	now := time.Now()
+	all := make(map[string]int)
+	counts := make(map[string]int)
+	for _, node := range nodes {
+		zone := node.Labels[topologyKey]
+		if zone == "" {
+			continue
+		}
+		if now.Sub(node.Heartbeat) <= 10*time.Second {
+			counts[zone] = 0
+		}
+		for _, run := range node.ActiveSandboxes {
+			if run.Active() && run.Template == template {
+				all[zone]++
+			}
+		}
+	}
+	for zone := range counts {
+		counts[zone] = all[zone]
+	}
+	return counts
+}
@@ -0,0 +1,122 @@
+package moirai_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+type countingMetrics struct {
+	counters map[string]float64
+}
+
+func (m *countingMetrics) IncCounter(name string, value float64, labels ...hermes.Label) {
+	if m.counters == nil {
+		m.counters = make(map[string]float64)
+	}
+	m.counters[name] += value
+}
+func (m *countingMetrics) ObserveHistogram(name string, value float64, labels ...hermes.Label) {}
+func (m *countingMetrics) SetGauge(name string, value float64, labels ...hermes.Label)         {}
+
+func zoneNodes(now time.Time) []domain.NodeStatus {
+	node := func(id, zone string, mem domain.Megabytes) domain.NodeStatus {
+		return domain.NodeStatus{
+			NodeInfo:  domain.NodeInfo{ID: domain.NodeID(id), Labels: map[string]string{"zone": zone}, Capacity: domain.ResourceCapacity{CPU: 8000, Mem: mem}},
+			Heartbeat: now,
+		}
+	}
+	return []domain.NodeStatus{
+		node("a-1", "a", 32768), // Emptiest, so least-loaded alone would always pick it
+		node("b-1", "b", 16384),
+		node("c-1", "c", 8192),
+	}
+}
+
+func TestTopologySpreadScheduler_Spreads(t *testing.T) {
+	logger := hermes.NewNoopLogger()
+	metrics := &countingMetrics{}
+	spread := moirai.NewTopologySpreadScheduler(moirai.NewLeastLoadedScheduler(logger), "zone", 1, logger, metrics)
+	batch := moirai.NewBatchScheduler(spread)
+	nodes := zoneNodes(time.Now())
+
+	zones := map[domain.NodeID]int{}
+	for _, id := range []domain.SandboxID{"s1", "s2", "s3", "s4"} {
+		req := &domain.SandboxRequest{ID: id, Template: "web", Resources: domain.ResourceSpec{CPU: 500, Mem: 512}}
+		node, err := batch.ChooseNode(context.Background(), req, nodes)
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		zones[node]++
+	}
+	if zones["a-1"] != 2 || zones["b-1"] != 1 || zones["c-1"] != 1 {
+		t.Errorf("expected 2/1/1 across zones, got %v", zones)
+	}
+	if metrics.counters["moirai_spread_violations_total"] != 0 {
+		t.Errorf("expected no violations, got %v", metrics.counters)
+	}
+
+	// Other templates are not held back by web
+	req := &domain.SandboxRequest{ID: "db", Template: "db", Resources: domain.ResourceSpec{CPU: 500, Mem: 512}}
+	if node, err := batch.ChooseNode(context.Background(), req, nodes); err != nil || node != "a-1" {
+		t.Errorf("expected the least loaded node for another template, got %q, %v", node, err)
+	}
+}
+
+func TestTopologySpreadScheduler_MaxSkew(t *testing.T) {
+	logger := hermes.NewNoopLogger()
+	nodes := zoneNodes(time.Now())
+	nodes[0].ActiveSandboxes = []domain.SandboxRun{
+		{ID: "r1", Template: "web", Status: domain.RunStatusRunning},
+		{ID: "r2", Template: "web", Status: domain.RunStatusRunning},
+	}
+	req := &domain.SandboxRequest{ID: "s", Template: "web", Resources: domain.ResourceSpec{CPU: 500, Mem: 512}}
+
+	// Zone a is 2 ahead: a skew of 1 sends the request elsewhere, 3 allows it
+	strict := moirai.NewTopologySpreadScheduler(moirai.NewLeastLoadedScheduler(logger), "zone", 1, logger, nil)
+	if node, err := strict.ChooseNode(context.Background(), req, nodes); err != nil || node == "a-1" {
+		t.Errorf("expected a node outside zone a, got %q, %v", node, err)
+	}
+	loose := moirai.NewTopologySpreadScheduler(moirai.NewLeastLoadedScheduler(logger), "zone", 3, logger, nil)
+	if node, err := loose.ChooseNode(context.Background(), req, nodes); err != nil || node != "a-1" {
+		t.Errorf("expected zone a within a skew of 3, got %q, %v", node, err)
+	}
+}
+
+func TestTopologySpreadScheduler_Violation(t *testing.T) {
+	logger := hermes.NewNoopLogger()
+	metrics := &countingMetrics{}
+	spread := moirai.NewTopologySpreadScheduler(moirai.NewLeastLoadedScheduler(logger), "zone", 1, logger, metrics)
+	nodes := zoneNodes(time.Now())
+	nodes[0].ActiveSandboxes = []domain.SandboxRun{{ID: "r1", Template: "web", Status: domain.RunStatusRunning}}
+
+	// Only zone a has room: the request is placed there anyway
+	req := &domain.SandboxRequest{ID: "s", Template: "web", Resources: domain.ResourceSpec{CPU: 500, Mem: 20000}}
+	node, err := spread.ChooseNode(context.Background(), req, nodes)
+	if err != nil || node != "a-1" {
+		t.Fatalf("expected zone a, got %q, %v", node, err)
+	}
+	if metrics.counters["moirai_spread_violations_total"] != 1 {
+		t.Errorf("expected one violation, got %v", metrics.counters)
+	}
+}
+
+func TestSpreadCounts(t *testing.T) {
+	now := time.Now()
+	nodes := zoneNodes(now)
+	nodes[1].ActiveSandboxes = []domain.SandboxRun{
+		{ID: "r1", Template: "web", Status: domain.RunStatusRunning},
+		{ID: "r2", Template: "web", Status: domain.RunStatusSucceeded},
+	}
+	nodes[2].Heartbeat = now.Add(-time.Minute)
+	nodes = append(nodes, domain.NodeStatus{NodeInfo: domain.NodeInfo{ID: "x"}, Heartbeat: now})
+
+	counts := moirai.SpreadCounts("web", "zone", nodes)
+	if len(counts) != 2 || counts["a"] != 0 || counts["b"] != 1 {
+		t.Errorf("expected a=0 b=1 without stale or unlabeled nodes, got %v", counts)
+	}
+}
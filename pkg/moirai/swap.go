@@ -0,0 +1,19 @@
+package moirai
+
+import "github.com/tartarus-sandbox/tartarus/pkg/domain"
+
+// MemoryOn returns the RAM and swap req takes on node. A node that offers no
+// swap launches swap-disk sandboxes with all their memory in RAM, as its
+// agent does; zram needs no swap from the node.
+func MemoryOn(req *domain.SandboxRequest, node domain.NodeStatus) (ram, swap domain.Megabytes) {
+	if req.Swap != nil && !req.Swap.Zram && node.Capacity.Swap == 0 {
+		return req.Resources.Mem, 0
+	}
+	return req.MemoryNeeds()
+}
+
+// CheckSwap reports whether node has the swap req would use on it free.
+func CheckSwap(req *domain.SandboxRequest, node domain.NodeStatus) bool {
+	_, swap := MemoryOn(req, node)
+	return swap == 0 || node.Capacity.Swap-node.Allocated.Swap >= swap
+}
@@ -0,0 +1,79 @@
+package moirai_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func TestMemoryOn(t *testing.T) {
+	req := &domain.SandboxRequest{
+		Resources: domain.ResourceSpec{Mem: 8192},
+		Swap:      &domain.SwapPolicy{MaxMB: 3072},
+	}
+	withSwap := domain.NodeStatus{NodeInfo: domain.NodeInfo{Capacity: domain.ResourceCapacity{Mem: 16384, Swap: 4096}}}
+	noSwap := domain.NodeStatus{NodeInfo: domain.NodeInfo{Capacity: domain.ResourceCapacity{Mem: 16384}}}
+
+	if ram, swap := moirai.MemoryOn(req, withSwap); ram != 5120 || swap != 3072 {
+		t.Errorf("expected 5120 MB of RAM and 3072 MB of swap, got %d and %d", ram, swap)
+	}
+	if ram, swap := moirai.MemoryOn(req, noSwap); ram != 8192 || swap != 0 {
+		t.Errorf("expected all memory in RAM on a node without swap, got %d and %d", ram, swap)
+	}
+	if !moirai.CheckSwap(req, noSwap) {
+		t.Error("a node without swap should take the request in RAM")
+	}
+	withSwap.Allocated.Swap = 2048
+	if moirai.CheckSwap(req, withSwap) {
+		t.Error("expected a node with 2048 MB of swap free to be rejected")
+	}
+
+	// Swap is capped at half the memory; zram is counted as compressed RAM
+	req.Swap = &domain.SwapPolicy{MaxMB: 6144, Zram: true}
+	if ram, swap := moirai.MemoryOn(req, noSwap); ram != 6144 || swap != 0 {
+		t.Errorf("expected 6144 MB of RAM for zram, got %d and %d", ram, swap)
+	}
+}
+
+func TestSchedulers_Swap(t *testing.T) {
+	now := time.Now()
+	req := &domain.SandboxRequest{
+		ID:        "batch",
+		Resources: domain.ResourceSpec{CPU: 1000, Mem: 8192},
+		Swap:      &domain.SwapPolicy{MaxMB: 4096},
+	}
+	nodes := []domain.NodeStatus{
+		// Fits the RAM part only, with swap to spare
+		{NodeInfo: domain.NodeInfo{ID: "swap", Capacity: domain.ResourceCapacity{CPU: 4000, Mem: 6144, Swap: 8192}}, Heartbeat: now},
+		// Fits neither the request in RAM nor its swap
+		{NodeInfo: domain.NodeInfo{ID: "small", Capacity: domain.ResourceCapacity{CPU: 4000, Mem: 6144}}, Heartbeat: now},
+	}
+	logger := hermes.NewNoopLogger()
+	for name, s := range map[string]moirai.Scheduler{
+		"least-loaded": moirai.NewLeastLoadedScheduler(logger),
+		"bin-packing":  moirai.NewBinPackingScheduler(logger),
+	} {
+		node, err := s.ChooseNode(context.Background(), req, nodes)
+		if err != nil || node != "swap" {
+			t.Errorf("%s: expected the node with swap, got %q, %v", name, node, err)
+		}
+	}
+
+	// The batch counts swap against the node: the second request's swap no
+	// longer fits
+	batch := moirai.NewBatchScheduler(moirai.NewLeastLoadedScheduler(logger))
+	if node, err := batch.ChooseNode(context.Background(), req, nodes[:1]); err != nil || node != "swap" {
+		t.Fatalf("expected the first request on the swap node, got %q, %v", node, err)
+	}
+	nodes[0].Capacity.Mem = 12288
+	if _, err := batch.ChooseNode(context.Background(), req, nodes[:1]); err != nil {
+		t.Fatalf("expected the second request to fit, got %v", err)
+	}
+	if _, err := batch.ChooseNode(context.Background(), req, nodes[:1]); err == nil {
+		t.Error("expected the third request to run out of swap")
+	}
+}
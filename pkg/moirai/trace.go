@@ -0,0 +1,202 @@
+package moirai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// Verbosities of a scheduling trace.
+const (
+	TraceOff     = "off"     // No trace is recorded
+	TraceSummary = "summary" // Counts of excluded nodes by filter, and notes
+	TraceFull    = "full"    // Also every node's outcome, with its reason or score
+)
+
+// Filters that exclude nodes, as named in a trace.
+const (
+	FilterQuarantine = "quarantine"
+	FilterHeatClass  = "heat_class"
+	FilterHeartbeat  = "heartbeat"
+	FilterCapacity   = "capacity"
+	FilterPlacement  = "placement"
+	FilterAffinity   = "affinity"
+	FilterArch       = "arch"
+	FilterGPU        = "gpu"
+	FilterSwap       = "swap"
+	FilterPressure   = "pressure"
+	FilterDraining   = "draining"
+	FilterSpread     = "spread"
+	FilterRegion     = "region"
+	FilterLocality   = "locality"
+	FilterWarmPool   = "warm_pool"
+)
+
+// ParseTraceVerbosity checks a trace verbosity, defaulting to TraceSummary.
+func ParseTraceVerbosity(s string) (string, error) {
+	switch s {
+	case "":
+		return TraceSummary, nil
+	case TraceOff, TraceSummary, TraceFull:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown scheduling trace verbosity %q (want %s, %s or %s)", s, TraceOff, TraceSummary, TraceFull)
+}
+
+// Trace records why each node was excluded or how it scored while a
+// scheduler chose a node for one request. Schedulers find it on the context
+// and record into it as they go; wrappers that pass subsets of the nodes to
+// their inner scheduler may have a node evaluated more than once, and the
+// last evaluation stands. A nil *Trace records nothing.
+type Trace struct {
+	verbosity string
+
+	mu    sync.Mutex
+	order []domain.NodeID
+	nodes map[domain.NodeID]domain.CandidateNode
+	notes []string
+}
+
+// NewTrace returns a trace at verbosity, or nil if it is TraceOff.
+func NewTrace(verbosity string) *Trace {
+	if verbosity == TraceOff {
+		return nil
+	}
+	return &Trace{
+		verbosity: verbosity,
+		nodes:     make(map[domain.NodeID]domain.CandidateNode),
+	}
+}
+
+type traceKey struct{}
+
+// WithTrace returns ctx carrying t for the schedulers it is passed to.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// TraceFrom returns the trace on ctx, nil if there is none.
+func TraceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+func (t *Trace) set(c domain.CandidateNode) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.nodes[c.NodeID]; !ok {
+		t.order = append(t.order, c.NodeID)
+	}
+	t.nodes[c.NodeID] = c
+}
+
+// Exclude records that filter ruled node out, for reason.
+func (t *Trace) Exclude(node domain.NodeID, filter, reason string) {
+	if t == nil {
+		return
+	}
+	t.set(domain.CandidateNode{NodeID: node, Filter: filter, Reason: reason})
+}
+
+// ExcludeAll records filter ruling out the nodes of all not in kept.
+func (t *Trace) ExcludeAll(all, kept []domain.NodeStatus, filter, reason string) {
+	if t == nil || len(all) == len(kept) {
+		return
+	}
+	keep := make(map[domain.NodeID]bool, len(kept))
+	for _, node := range kept {
+		keep[node.ID] = true
+	}
+	for _, node := range all {
+		if !keep[node.ID] {
+			t.Exclude(node.ID, filter, reason)
+		}
+	}
+}
+
+// Score records that node passed every filter and scorer ranked it score.
+func (t *Trace) Score(node domain.NodeID, scorer string, score float64) {
+	if t == nil {
+		return
+	}
+	t.set(domain.CandidateNode{NodeID: node, Scorer: scorer, Score: score})
+}
+
+// Note records a decision about the node set as a whole.
+func (t *Trace) Note(format string, args ...any) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.notes = append(t.notes, fmt.Sprintf(format, args...))
+}
+
+// Decision returns the trace as the decision to record on the run: chosen
+// if placement succeeded, err if it failed.
+func (t *Trace) Decision(chosen domain.NodeID, err error) *domain.SchedulingDecision {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d := &domain.SchedulingDecision{
+		NodeID:    chosen,
+		Verbosity: t.verbosity,
+		DecidedAt: time.Now(),
+		Evaluated: len(t.order),
+		Notes:     append([]string(nil), t.notes...),
+	}
+	if err != nil {
+		d.NodeID = ""
+		d.Error = err.Error()
+	}
+	for _, id := range t.order {
+		c := t.nodes[id]
+		if c.Filter != "" {
+			if d.Excluded == nil {
+				d.Excluded = make(map[string]int)
+			}
+			d.Excluded[c.Filter]++
+		}
+		if t.verbosity == TraceFull {
+			c.Chosen = err == nil && id == chosen
+			d.Candidates = append(d.Candidates, c)
+		}
+	}
+	return d
+}
+
+// checkNode applies the per-node filters the built-in schedulers share. It
+// returns the filter that excludes node for req and why, or "" if none does.
+func checkNode(req *domain.SandboxRequest, node domain.NodeStatus, now time.Time) (string, string) {
+	if age := now.Sub(node.Heartbeat); age > 10*time.Second {
+		return FilterHeartbeat, fmt.Sprintf("last heartbeat %s ago", age.Round(time.Second))
+	}
+	freeMem := node.Capacity.Mem - node.Allocated.Mem
+	if ram, _ := MemoryOn(req, node); freeMem < ram {
+		return FilterCapacity, fmt.Sprintf("%d MB free, %d MB needed", freeMem, ram)
+	}
+	if !CheckPlacement(req, node) {
+		return FilterPlacement, "node selector, taints or anti-affinity not satisfied"
+	}
+	if !CheckAffinity(req, node) {
+		return FilterAffinity, "affinity metadata not satisfied"
+	}
+	if !CheckArch(req, node) {
+		return FilterArch, fmt.Sprintf("node is %s, %s requested", domain.NodeArch(node.NodeInfo), req.Arch)
+	}
+	if !CheckGPU(req, node) {
+		return FilterGPU, "not enough free matching GPUs"
+	}
+	if !CheckSwap(req, node) {
+		return FilterSwap, "not enough free swap"
+	}
+	return "", ""
+}
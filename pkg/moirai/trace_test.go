@@ -0,0 +1,106 @@
+package moirai_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+func TestTrace_LeastLoaded(t *testing.T) {
+	stale := regionNode("stale", "us-east", 6000)
+	stale.Heartbeat = time.Now().Add(-time.Minute)
+	pressured := regionNode("pressured", "us-east", 6000)
+	pressured.Conditions = []domain.NodeCondition{{Type: domain.NodeMemoryPressure, Status: true}}
+	nodes := []domain.NodeStatus{
+		regionNode("full", "us-east", 512),
+		regionNode("small", "us-east", 2048),
+		stale,
+		pressured,
+		regionNode("big", "us-east", 4096),
+	}
+	req := &domain.SandboxRequest{ID: "req", Resources: domain.ResourceSpec{Mem: 1024}}
+
+	trace := moirai.NewTrace(moirai.TraceFull)
+	s := moirai.NewConditionAwareScheduler(moirai.NewLeastLoadedScheduler(&mockLogger{}), &mockLogger{})
+	nodeID, err := s.ChooseNode(moirai.WithTrace(context.Background(), trace), req, nodes)
+	if err != nil || nodeID != "big" {
+		t.Fatalf("expected big, got %s, %v", nodeID, err)
+	}
+
+	d := trace.Decision(nodeID, err)
+	if d.NodeID != "big" || d.Evaluated != 5 {
+		t.Errorf("unexpected decision %+v", d)
+	}
+	want := map[string]int{moirai.FilterCapacity: 1, moirai.FilterHeartbeat: 1, moirai.FilterPressure: 1}
+	for filter, n := range want {
+		if d.Excluded[filter] != n {
+			t.Errorf("expected %d excluded by %s, got %v", n, filter, d.Excluded)
+		}
+	}
+	byNode := make(map[domain.NodeID]domain.CandidateNode)
+	for _, c := range d.Candidates {
+		byNode[c.NodeID] = c
+	}
+	if c := byNode["full"]; c.Reason != "512 MB free, 1024 MB needed" {
+		t.Errorf("unexpected capacity reason %q", c.Reason)
+	}
+	if c := byNode["small"]; c.Filter != "" || c.Score != 2048 || c.Chosen {
+		t.Errorf("unexpected entry for small: %+v", c)
+	}
+	if c := byNode["big"]; c.Score != 4096 || !c.Chosen {
+		t.Errorf("unexpected entry for big: %+v", c)
+	}
+
+	// Summaries keep the counts but not the entries
+	summary := moirai.NewTrace(moirai.TraceSummary)
+	_, _ = s.ChooseNode(moirai.WithTrace(context.Background(), summary), req, nodes)
+	if d := summary.Decision("big", nil); len(d.Candidates) != 0 || d.Excluded[moirai.FilterPressure] != 1 {
+		t.Errorf("unexpected summary %+v", d)
+	}
+}
+
+func TestTrace_RegionFailover(t *testing.T) {
+	logger := &mockLogger{}
+	nodes := []domain.NodeStatus{
+		regionNode("remote", "eu-west", 6000),
+		regionNode("local-full", "us-east", 0),
+	}
+	req := &domain.SandboxRequest{ID: "req", Resources: domain.ResourceSpec{Mem: 1024}}
+
+	trace := moirai.NewTrace(moirai.TraceFull)
+	s := moirai.NewRegionAwareScheduler(moirai.NewLeastLoadedScheduler(logger), "us-east", true, logger)
+	nodeID, err := s.ChooseNode(moirai.WithTrace(context.Background(), trace), req, nodes)
+	if err != nil || nodeID != "remote" {
+		t.Fatalf("expected remote, got %s, %v", nodeID, err)
+	}
+	d := trace.Decision(nodeID, err)
+	if len(d.Notes) != 1 || d.Excluded[moirai.FilterCapacity] != 1 {
+		t.Errorf("unexpected decision %+v", d)
+	}
+
+	// Without failover, the remote node is reported as outside the region
+	trace = moirai.NewTrace(moirai.TraceFull)
+	s = moirai.NewRegionAwareScheduler(moirai.NewLeastLoadedScheduler(logger), "us-east", false, logger)
+	_, err = s.ChooseNode(moirai.WithTrace(context.Background(), trace), req, nodes)
+	d = trace.Decision("", err)
+	if d.Error == "" || d.Excluded[moirai.FilterRegion] != 1 {
+		t.Errorf("unexpected decision %+v", d)
+	}
+}
+
+func TestTrace_Off(t *testing.T) {
+	if moirai.NewTrace(moirai.TraceOff) != nil {
+		t.Error("expected no trace when off")
+	}
+	var trace *moirai.Trace
+	trace.Exclude("node", moirai.FilterCapacity, "full")
+	if trace.Decision("node", nil) != nil {
+		t.Error("expected no decision from a nil trace")
+	}
+	if _, err := moirai.ParseTraceVerbosity("verbose"); err == nil {
+		t.Error("expected an unknown verbosity to be rejected")
+	}
+}
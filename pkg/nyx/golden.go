@@ -0,0 +1,196 @@
+package nyx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// GoldenBuilder builds snapshots that are validated before being served.
+// LocalManager implements it.
+type GoldenBuilder interface {
+	// Build boots the template, warms it up, snapshots it and checks that
+	// the snapshot restores. The snapshot is persisted but not served.
+	Build(ctx context.Context, tpl *domain.TemplateSpec) (*Snapshot, error)
+
+	// Publish makes a built snapshot the one served for its template.
+	Publish(ctx context.Context, snap *Snapshot) error
+}
+
+// GoldenSnapshot is one published version of a template's golden snapshot.
+type GoldenSnapshot struct {
+	Template    domain.TemplateID `json:"template"`
+	Version     int               `json:"version"`
+	SnapshotID  domain.SnapshotID `json:"snapshot_id"`
+	Fingerprint string            `json:"fingerprint"` // TemplateFingerprint of the spec it was built from
+	BaseImage   string            `json:"base_image"`
+	Tags        []string          `json:"tags"` // "v<version>", plus "latest" on the newest
+	PublishedAt time.Time         `json:"published_at"`
+}
+
+// TemplateFingerprint hashes the parts of a template that change what its
+// snapshot contains, so a snapshot is only rebuilt when one of them changes.
+func TemplateFingerprint(tpl *domain.TemplateSpec) string {
+	data, _ := json.Marshal(struct {
+		BaseImage     string
+		KernelImage   string
+		WarmupCommand []string
+		Resources     domain.ResourceSpec
+		Variants      map[string]domain.TemplateVariant
+	}{tpl.BaseImage, tpl.KernelImage, tpl.WarmupCommand, tpl.Resources, tpl.Variants})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// GoldenPipeline builds, validates and publishes golden snapshots for
+// templates, and keeps their version history in Erebus.
+type GoldenPipeline struct {
+	Builder GoldenBuilder
+	Store   erebus.Store
+	Logger  hermes.Logger
+	Metrics hermes.Metrics
+
+	mu       sync.Mutex // Serializes version history updates
+	inflight sync.Map   // domain.TemplateID -> struct{}, for Trigger
+}
+
+func NewGoldenPipeline(builder GoldenBuilder, store erebus.Store, logger hermes.Logger, metrics hermes.Metrics) *GoldenPipeline {
+	return &GoldenPipeline{
+		Builder: builder,
+		Store:   store,
+		Logger:  logger,
+		Metrics: metrics,
+	}
+}
+
+func goldenKey(tplID domain.TemplateID) string {
+	return fmt.Sprintf("snapshots/%s/golden.json", tplID)
+}
+
+// Versions returns the published golden snapshots of a template, newest first.
+func (p *GoldenPipeline) Versions(ctx context.Context, tplID domain.TemplateID) ([]GoldenSnapshot, error) {
+	key := goldenKey(tplID)
+	exists, err := p.Store.Exists(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check golden history: %w", err)
+	}
+	if !exists {
+		return []GoldenSnapshot{}, nil
+	}
+
+	r, err := p.Store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden history: %w", err)
+	}
+	defer r.Close()
+
+	var versions []GoldenSnapshot
+	if err := json.NewDecoder(r).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("failed to decode golden history: %w", err)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+	return versions, nil
+}
+
+// Run builds and publishes a new golden snapshot for the template. Unless
+// force is set, it does nothing and returns the current version when that was
+// built from an identical spec. A snapshot that fails validation is never
+// published, so the previous version keeps being served.
+func (p *GoldenPipeline) Run(ctx context.Context, tpl *domain.TemplateSpec, force bool) (*GoldenSnapshot, error) {
+	fingerprint := TemplateFingerprint(tpl)
+	if !force {
+		versions, err := p.Versions(ctx, tpl.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) > 0 && versions[0].Fingerprint == fingerprint {
+			p.Metrics.IncCounter("nyx_golden_builds_total", 1, hermes.Label{Key: "result", Value: "unchanged"})
+			return &versions[0], nil
+		}
+	}
+
+	start := time.Now()
+	p.Logger.Info(ctx, "Building golden snapshot", map[string]any{"template": tpl.ID})
+	snap, err := p.Builder.Build(ctx, tpl)
+	if err != nil {
+		p.Metrics.IncCounter("nyx_golden_builds_total", 1, hermes.Label{Key: "result", Value: "failed"})
+		return nil, fmt.Errorf("failed to build golden snapshot: %w", err)
+	}
+	p.Metrics.ObserveHistogram("nyx_golden_build_seconds", time.Since(start).Seconds())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	versions, err := p.Versions(ctx, tpl.ID)
+	if err != nil {
+		return nil, err
+	}
+	golden := GoldenSnapshot{
+		Template:    tpl.ID,
+		Version:     1,
+		SnapshotID:  snap.ID,
+		Fingerprint: fingerprint,
+		BaseImage:   tpl.BaseImage,
+		PublishedAt: time.Now(),
+	}
+	if len(versions) > 0 {
+		golden.Version = versions[0].Version + 1
+	}
+	golden.Tags = []string{fmt.Sprintf("v%d", golden.Version), "latest"}
+	for i := range versions {
+		versions[i].Tags = []string{fmt.Sprintf("v%d", versions[i].Version)}
+	}
+	versions = append([]GoldenSnapshot{golden}, versions...)
+
+	// Record the version before serving it, so a served snapshot is always
+	// in the history
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode golden history: %w", err)
+	}
+	if err := p.Store.Put(ctx, goldenKey(tpl.ID), bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to write golden history: %w", err)
+	}
+	if err := p.Builder.Publish(ctx, snap); err != nil {
+		p.Metrics.IncCounter("nyx_golden_builds_total", 1, hermes.Label{Key: "result", Value: "failed"})
+		return nil, fmt.Errorf("failed to publish golden snapshot: %w", err)
+	}
+
+	p.Metrics.IncCounter("nyx_golden_builds_total", 1, hermes.Label{Key: "result", Value: "published"})
+	p.Logger.Info(ctx, "Published golden snapshot", map[string]any{
+		"template": tpl.ID,
+		"version":  golden.Version,
+		"snapshot": snap.ID,
+	})
+	return &golden, nil
+}
+
+// Trigger runs the pipeline for a template in the background. It returns
+// false without doing anything if a build for the template is in progress.
+func (p *GoldenPipeline) Trigger(ctx context.Context, tpl *domain.TemplateSpec, force bool) bool {
+	if _, busy := p.inflight.LoadOrStore(tpl.ID, struct{}{}); busy {
+		return false
+	}
+	// The build outlives the request or registration that triggered it
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer p.inflight.Delete(tpl.ID)
+		if _, err := p.Run(ctx, tpl, force); err != nil {
+			p.Logger.Error(ctx, "Golden snapshot pipeline failed", map[string]any{
+				"template": tpl.ID,
+				"error":    err,
+			})
+		}
+	}()
+	return true
+}
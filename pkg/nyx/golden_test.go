@@ -0,0 +1,130 @@
+package nyx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+type fakeGoldenBuilder struct {
+	builds    int
+	published []*Snapshot
+	buildErr  error
+}
+
+func (b *fakeGoldenBuilder) Build(ctx context.Context, tpl *domain.TemplateSpec) (*Snapshot, error) {
+	if b.buildErr != nil {
+		return nil, b.buildErr
+	}
+	b.builds++
+	return &Snapshot{
+		ID:        domain.SnapshotID(fmt.Sprintf("snap-%d", b.builds)),
+		Template:  tpl.ID,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (b *fakeGoldenBuilder) Publish(ctx context.Context, snap *Snapshot) error {
+	b.published = append(b.published, snap)
+	return nil
+}
+
+func TestGoldenPipeline_Run(t *testing.T) {
+	ctx := context.Background()
+	store, err := erebus.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := &fakeGoldenBuilder{}
+	p := NewGoldenPipeline(builder, store, hermes.NewNoopLogger(), hermes.NewNoopMetrics())
+
+	tpl := &domain.TemplateSpec{
+		ID:            "python-ds",
+		BaseImage:     "python-ds:1",
+		WarmupCommand: []string{"python3", "-c", "import numpy"},
+	}
+
+	golden, err := p.Run(ctx, tpl, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if golden.Version != 1 || golden.SnapshotID != "snap-1" {
+		t.Fatalf("unexpected first version: %+v", golden)
+	}
+
+	// Unchanged spec: nothing is rebuilt
+	golden, err = p.Run(ctx, tpl, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if builder.builds != 1 || golden.Version != 1 {
+		t.Fatalf("unchanged template was rebuilt: builds=%d version=%d", builder.builds, golden.Version)
+	}
+
+	// Image update: a new version is published and tagged latest
+	updated := *tpl
+	updated.BaseImage = "python-ds:2"
+	golden, err = p.Run(ctx, &updated, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if golden.Version != 2 || len(builder.published) != 2 {
+		t.Fatalf("image update not published: version=%d published=%d", golden.Version, len(builder.published))
+	}
+
+	versions, err := p.Versions(ctx, tpl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+	if versions[0].Version != 2 || len(versions[0].Tags) != 2 || versions[0].Tags[1] != "latest" {
+		t.Errorf("newest version not tagged latest: %+v", versions[0])
+	}
+	if len(versions[1].Tags) != 1 || versions[1].Tags[0] != "v1" {
+		t.Errorf("older version kept latest tag: %+v", versions[1])
+	}
+
+	// Forced rebuild of an unchanged spec
+	if _, err := p.Run(ctx, &updated, true); err != nil {
+		t.Fatal(err)
+	}
+	if builder.builds != 3 {
+		t.Errorf("forced run did not rebuild: builds=%d", builder.builds)
+	}
+}
+
+func TestGoldenPipeline_FailedBuildKeepsPrevious(t *testing.T) {
+	ctx := context.Background()
+	store, err := erebus.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := &fakeGoldenBuilder{}
+	p := NewGoldenPipeline(builder, store, hermes.NewNoopLogger(), hermes.NewNoopMetrics())
+
+	tpl := &domain.TemplateSpec{ID: "tpl-1", BaseImage: "img:1"}
+	if _, err := p.Run(ctx, tpl, false); err != nil {
+		t.Fatal(err)
+	}
+
+	builder.buildErr = errors.New("snapshot failed to restore")
+	if _, err := p.Run(ctx, &domain.TemplateSpec{ID: "tpl-1", BaseImage: "img:2"}, false); err == nil {
+		t.Fatal("expected build error")
+	}
+
+	versions, err := p.Versions(ctx, tpl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].SnapshotID != "snap-1" || len(builder.published) != 1 {
+		t.Errorf("failed build changed published snapshots: %+v", versions)
+	}
+}
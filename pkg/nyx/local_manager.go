@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +31,14 @@ type LocalManager struct {
 	SnapshotDir string
 	Logger      hermes.Logger
 
+	// Arch selects the template kernel/rootfs variant snapshots are built from.
+	Arch string
+
+	// Refs, if set, records that templates own their snapshots and
+	// snapshots the image they were built from. Deleting a snapshot then
+	// only removes what nothing else still references.
+	Refs *erebus.RefCounter
+
 	mu         sync.Mutex
 	byTemplate map[domain.TemplateID][]*Snapshot
 	group      singleflight.Group
@@ -37,6 +46,9 @@ type LocalManager struct {
 	// vmLauncher is the function used to create a paused VM.
 	// It is exposed for testing purposes.
 	vmLauncher func(ctx context.Context, tpl *domain.TemplateSpec, rootfsPath, socketPath string) (SnapshotMachine, error)
+
+	// vmRestorer resumes a VM from snapshot files to validate them.
+	vmRestorer func(ctx context.Context, tpl *domain.TemplateSpec, rootfsPath, memPath, statePath, socketPath string) (SnapshotMachine, error)
 }
 
 // SnapshotMachine is an interface that abstracts firecracker.Machine for snapshotting.
@@ -55,9 +67,11 @@ func NewLocalManager(store erebus.Store, ociBuilder *erebus.OCIBuilder, snapshot
 		OCIBuilder:  ociBuilder,
 		SnapshotDir: snapshotDir,
 		Logger:      logger,
+		Arch:        runtime.GOARCH,
 		byTemplate:  make(map[domain.TemplateID][]*Snapshot),
 	}
 	lm.vmLauncher = lm.createPausedVM
+	lm.vmRestorer = lm.restoreVM
 	return lm, nil
 }
 
@@ -71,7 +85,49 @@ func (m *LocalManager) Prepare(ctx context.Context, tpl *domain.TemplateSpec) (*
 		return snaps[len(snaps)-1], nil
 	}
 
-	m.Logger.Info(ctx, "Preparing new snapshot for template", map[string]any{"template_id": tpl.ID})
+	snap, err := m.build(ctx, tpl, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.publish(ctx, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Build boots the template, runs its WarmupCommand, snapshots it and checks
+// that the snapshot restores before persisting it. The snapshot is not
+// served until it is published.
+func (m *LocalManager) Build(ctx context.Context, tpl *domain.TemplateSpec) (*Snapshot, error) {
+	return m.build(ctx, tpl, true)
+}
+
+// Publish makes a built snapshot the one served for its template.
+func (m *LocalManager) Publish(ctx context.Context, snap *Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.publish(ctx, snap)
+}
+
+// publish updates the 'latest' pointer and the cache; m.mu must be held.
+func (m *LocalManager) publish(ctx context.Context, snap *Snapshot) error {
+	latestKey := fmt.Sprintf("snapshots/%s/latest", snap.Template)
+	if err := m.Store.Put(ctx, latestKey, bytes.NewReader([]byte(snap.ID))); err != nil {
+		return fmt.Errorf("failed to update latest pointer: %w", err)
+	}
+	m.byTemplate[snap.Template] = append(m.byTemplate[snap.Template], snap)
+	return nil
+}
+
+// build creates and persists a snapshot of the template, optionally
+// restoring it once to validate it.
+func (m *LocalManager) build(ctx context.Context, tpl *domain.TemplateSpec, validate bool) (*Snapshot, error) {
+	if !tpl.SupportsArch(m.Arch) {
+		return nil, fmt.Errorf("template %s has no variant for %s (supports %v)", tpl.ID, m.Arch, tpl.Architectures())
+	}
+	tpl = tpl.ForArch(m.Arch)
+
+	m.Logger.Info(ctx, "Preparing new snapshot for template", map[string]any{"template_id": tpl.ID, "arch": m.Arch})
 
 	// Create a new snapshot
 	snapID := domain.SnapshotID(uuid.New().String())
@@ -91,6 +147,7 @@ func (m *LocalManager) Prepare(ctx context.Context, tpl *domain.TemplateSpec) (*
 
 	// Determine rootfs path
 	rootfsPath := tpl.BaseImage
+	var imageDigest string
 	if strings.Contains(tpl.BaseImage, ":") || strings.Contains(tpl.BaseImage, "/") {
 		// Assume OCI ref
 		if m.OCIBuilder == nil {
@@ -104,9 +161,18 @@ func (m *LocalManager) Prepare(ctx context.Context, tpl *domain.TemplateSpec) (*
 		}
 		defer os.RemoveAll(extractDir)
 
-		if err := m.OCIBuilder.Assemble(ctx, tpl.BaseImage, extractDir); err != nil {
+		img, err := m.OCIBuilder.Pull(ctx, tpl.BaseImage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assemble OCI image: %w", err)
+		}
+		if err := m.OCIBuilder.AssembleImage(ctx, img, extractDir); err != nil {
 			return nil, fmt.Errorf("failed to assemble OCI image: %w", err)
 		}
+		digest, err := img.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OCI image digest: %w", err)
+		}
+		imageDigest = digest.String()
 
 		// Build rootfs image
 		ociRootfs := filepath.Join(socketDir, "rootfs.img")
@@ -134,10 +200,19 @@ func (m *LocalManager) Prepare(ctx context.Context, tpl *domain.TemplateSpec) (*
 		return nil, fmt.Errorf("failed to create snapshot: %w", err)
 	}
 
+	if validate {
+		// The restored VM takes over the rootfs, so the original must go first
+		machine.StopVMM()
+		restored, err := m.vmRestorer(ctx, tpl, rootfsPath, memFile, diskFile, filepath.Join(socketDir, "restore.sock"))
+		if err != nil {
+			return nil, fmt.Errorf("snapshot failed to restore: %w", err)
+		}
+		restored.StopVMM()
+	}
+
 	// Persist to Erebus
 	memKey := fmt.Sprintf("snapshots/%s/%s.mem", tpl.ID, snapID)
 	diskKey := fmt.Sprintf("snapshots/%s/%s.disk", tpl.ID, snapID)
-	latestKey := fmt.Sprintf("snapshots/%s/latest", tpl.ID)
 
 	if err := m.uploadFile(ctx, memKey, memFile); err != nil {
 		return nil, err
@@ -172,6 +247,9 @@ func (m *LocalManager) Prepare(ctx context.Context, tpl *domain.TemplateSpec) (*
 			"mem_size_mb":  fmt.Sprintf("%d", memSz),
 		},
 	}
+	if imageDigest != "" {
+		snap.Metadata["image_digest"] = imageDigest
+	}
 
 	// Persist Metadata to Erebus
 	jsonKey := fmt.Sprintf("snapshots/%s/%s.json", tpl.ID, snapID)
@@ -183,9 +261,8 @@ func (m *LocalManager) Prepare(ctx context.Context, tpl *domain.TemplateSpec) (*
 	if err := m.Store.Put(ctx, jsonKey, bytes.NewReader(jsonBytes)); err != nil {
 		return nil, fmt.Errorf("failed to upload snapshot metadata: %w", err)
 	}
-	// Update 'latest' pointer
-	if err := m.Store.Put(ctx, latestKey, bytes.NewReader([]byte(snapID))); err != nil {
-		return nil, fmt.Errorf("failed to update latest pointer: %w", err)
+	if err := m.acquireSnapshot(ctx, tpl.ID, snapID, imageDigest); err != nil {
+		return nil, err
 	}
 
 	// Move files to local cache (SnapshotDir) so they are available for immediate use
@@ -214,9 +291,6 @@ func (m *LocalManager) Prepare(ctx context.Context, tpl *domain.TemplateSpec) (*
 		return nil, fmt.Errorf("failed to cache json file: %w", err)
 	}
 
-	// Update cache
-	m.byTemplate[tpl.ID] = append(m.byTemplate[tpl.ID], snap)
-
 	return snap, nil
 }
 
@@ -371,18 +445,22 @@ func (m *LocalManager) Invalidate(ctx context.Context, tplID domain.TemplateID)
 		snapID := snap.ID
 
 		// Delete from Erebus store
-		memKey := fmt.Sprintf("snapshots/%s/%s.mem", tplID, snapID)
-		diskKey := fmt.Sprintf("snapshots/%s/%s.disk", tplID, snapID)
-		jsonKey := fmt.Sprintf("snapshots/%s/%s.json", tplID, snapID)
-
-		if err := m.Store.Delete(ctx, memKey); err != nil {
-			m.Logger.Info(ctx, "Failed to delete mem file from store during invalidation", map[string]any{"key": memKey, "error": err.Error()})
-		}
-		if err := m.Store.Delete(ctx, diskKey); err != nil {
-			m.Logger.Info(ctx, "Failed to delete disk file from store during invalidation", map[string]any{"key": diskKey, "error": err.Error()})
-		}
-		if err := m.Store.Delete(ctx, jsonKey); err != nil {
-			m.Logger.Info(ctx, "Failed to delete json file from store during invalidation", map[string]any{"key": jsonKey, "error": err.Error()})
+		if m.Refs != nil {
+			m.releaseSnapshot(ctx, tplID, snapID)
+		} else {
+			memKey := fmt.Sprintf("snapshots/%s/%s.mem", tplID, snapID)
+			diskKey := fmt.Sprintf("snapshots/%s/%s.disk", tplID, snapID)
+			jsonKey := fmt.Sprintf("snapshots/%s/%s.json", tplID, snapID)
+
+			if err := m.Store.Delete(ctx, memKey); err != nil {
+				m.Logger.Info(ctx, "Failed to delete mem file from store during invalidation", map[string]any{"key": memKey, "error": err.Error()})
+			}
+			if err := m.Store.Delete(ctx, diskKey); err != nil {
+				m.Logger.Info(ctx, "Failed to delete disk file from store during invalidation", map[string]any{"key": diskKey, "error": err.Error()})
+			}
+			if err := m.Store.Delete(ctx, jsonKey); err != nil {
+				m.Logger.Info(ctx, "Failed to delete json file from store during invalidation", map[string]any{"key": jsonKey, "error": err.Error()})
+			}
 		}
 
 		// Delete from local cache
@@ -468,6 +546,9 @@ func (m *LocalManager) SaveSnapshot(ctx context.Context, tplID domain.TemplateID
 	if err := m.Store.Put(ctx, jsonKey, bytes.NewReader(jsonBytes)); err != nil {
 		return nil, fmt.Errorf("failed to upload snapshot metadata: %w", err)
 	}
+	if err := m.acquireSnapshot(ctx, tplID, snapID, ""); err != nil {
+		return nil, err
+	}
 
 	// Cache JSON locally
 	finalJsonPath := filepath.Join(finalDir, string(snapID)+".json")
@@ -497,19 +578,23 @@ func (m *LocalManager) DeleteSnapshot(ctx context.Context, tplID domain.Template
 	}
 
 	// Remove from store (Erebus)
-	memKey := fmt.Sprintf("snapshots/%s/%s.mem", tplID, snapID)
-	diskKey := fmt.Sprintf("snapshots/%s/%s.disk", tplID, snapID)
-	jsonKey := fmt.Sprintf("snapshots/%s/%s.json", tplID, snapID)
+	if m.Refs != nil {
+		m.releaseSnapshot(ctx, tplID, snapID)
+	} else {
+		memKey := fmt.Sprintf("snapshots/%s/%s.mem", tplID, snapID)
+		diskKey := fmt.Sprintf("snapshots/%s/%s.disk", tplID, snapID)
+		jsonKey := fmt.Sprintf("snapshots/%s/%s.json", tplID, snapID)
 
-	// We try to delete all components, logging errors but not stopping
-	if err := m.Store.Delete(ctx, memKey); err != nil {
-		m.Logger.Info(ctx, "Failed to delete mem file from store", map[string]any{"key": memKey, "error": err.Error()})
-	}
-	if err := m.Store.Delete(ctx, diskKey); err != nil {
-		m.Logger.Info(ctx, "Failed to delete disk file from store", map[string]any{"key": diskKey, "error": err.Error()})
-	}
-	if err := m.Store.Delete(ctx, jsonKey); err != nil {
-		m.Logger.Info(ctx, "Failed to delete json file from store", map[string]any{"key": jsonKey, "error": err.Error()})
+		// We try to delete all components, logging errors but not stopping
+		if err := m.Store.Delete(ctx, memKey); err != nil {
+			m.Logger.Info(ctx, "Failed to delete mem file from store", map[string]any{"key": memKey, "error": err.Error()})
+		}
+		if err := m.Store.Delete(ctx, diskKey); err != nil {
+			m.Logger.Info(ctx, "Failed to delete disk file from store", map[string]any{"key": diskKey, "error": err.Error()})
+		}
+		if err := m.Store.Delete(ctx, jsonKey); err != nil {
+			m.Logger.Info(ctx, "Failed to delete json file from store", map[string]any{"key": jsonKey, "error": err.Error()})
+		}
 	}
 
 	// Also remove from local cache dir if present
@@ -521,6 +606,43 @@ func (m *LocalManager) DeleteSnapshot(ctx context.Context, tplID domain.Template
 	return nil
 }
 
+// acquireSnapshot records that the template owns the snapshot and, when
+// built from an OCI image, that the snapshot owns the image.
+func (m *LocalManager) acquireSnapshot(ctx context.Context, tplID domain.TemplateID, snapID domain.SnapshotID, imageDigest string) error {
+	if m.Refs == nil {
+		return nil
+	}
+	snapArtifact := erebus.SnapshotArtifact(tplID, snapID)
+	if imageDigest != "" {
+		if err := m.Refs.Acquire(ctx, snapArtifact, erebus.ImageArtifact(imageDigest)); err != nil {
+			return fmt.Errorf("failed to record snapshot image reference: %w", err)
+		}
+	}
+	if err := m.Refs.Acquire(ctx, erebus.TemplateArtifact(tplID), snapArtifact); err != nil {
+		return fmt.Errorf("failed to record template snapshot reference: %w", err)
+	}
+	return nil
+}
+
+// releaseSnapshot drops the template's reference to the snapshot and
+// collects it, along with its image and layers, unless something else
+// still references them.
+func (m *LocalManager) releaseSnapshot(ctx context.Context, tplID domain.TemplateID, snapID domain.SnapshotID) {
+	snapArtifact := erebus.SnapshotArtifact(tplID, snapID)
+	if err := m.Refs.Release(ctx, erebus.TemplateArtifact(tplID), snapArtifact); err != nil {
+		m.Logger.Info(ctx, "Failed to release snapshot reference", map[string]any{"artifact": snapArtifact, "error": err.Error()})
+		return
+	}
+	deleted, err := m.Refs.Collect(ctx, snapArtifact)
+	if err != nil {
+		m.Logger.Info(ctx, "Failed to collect snapshot from store", map[string]any{"artifact": snapArtifact, "error": err.Error()})
+		return
+	}
+	if len(deleted) == 0 {
+		m.Logger.Info(ctx, "Snapshot still referenced, keeping it in store", map[string]any{"artifact": snapArtifact})
+	}
+}
+
 func (m *LocalManager) uploadFile(ctx context.Context, key string, path string) error {
 	f, err := os.Open(path)
 	if err != nil {
@@ -661,6 +783,41 @@ func (m *LocalManager) createPausedVM(ctx context.Context, tpl *domain.TemplateS
 	return machine, nil
 }
 
+// restoreVM resumes a VM from snapshot files, the way the runtime does when
+// a sandbox is launched from a snapshot.
+func (m *LocalManager) restoreVM(ctx context.Context, tpl *domain.TemplateSpec, rootfsPath, memPath, statePath, socketPath string) (SnapshotMachine, error) {
+	fcCfg := firecracker.Config{
+		SocketPath: socketPath,
+		Drives: []models.Drive{
+			{
+				DriveID:      firecracker.String("rootfs"),
+				PathOnHost:   firecracker.String(rootfsPath),
+				IsRootDevice: firecracker.Bool(true),
+				IsReadOnly:   firecracker.Bool(false),
+			},
+		},
+		Snapshot: firecracker.SnapshotConfig{
+			MemFilePath:  memPath,
+			SnapshotPath: statePath,
+			ResumeVM:     true,
+		},
+	}
+
+	cmd := firecracker.VMCommandBuilder{}.
+		WithSocketPath(socketPath).
+		Build(ctx)
+
+	machine, err := firecracker.NewMachine(ctx, fcCfg, firecracker.WithProcessRunner(cmd))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create machine: %w", err)
+	}
+	if err := machine.Start(ctx); err != nil {
+		machine.StopVMM()
+		return nil, fmt.Errorf("failed to resume machine: %w", err)
+	}
+	return machine, nil
+}
+
 func copyFile(src, dst string) error {
 	in, err := os.Open(src)
 	if err != nil {
@@ -13,6 +13,7 @@ import (
 )
 
 type LocalManager struct {
+	Refs *erebus.RefCounter
 }
 
 func NewLocalManager(store erebus.Store, ociBuilder *erebus.OCIBuilder, snapshotDir string, logger hermes.Logger) (*LocalManager, error) {
@@ -42,3 +43,11 @@ func (m *LocalManager) SaveSnapshot(ctx context.Context, tplID domain.TemplateID
 func (m *LocalManager) DeleteSnapshot(ctx context.Context, tplID domain.TemplateID, snapID domain.SnapshotID) error {
 	return fmt.Errorf("Nyx LocalManager not supported on non-Linux platforms")
 }
+
+func (m *LocalManager) Build(ctx context.Context, tpl *domain.TemplateSpec) (*Snapshot, error) {
+	return nil, fmt.Errorf("Nyx LocalManager not supported on non-Linux platforms")
+}
+
+func (m *LocalManager) Publish(ctx context.Context, snap *Snapshot) error {
+	return fmt.Errorf("Nyx LocalManager not supported on non-Linux platforms")
+}
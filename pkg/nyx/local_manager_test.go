@@ -446,3 +446,51 @@ func TestLocalManager_DeleteSnapshot(t *testing.T) {
 		t.Error("Expected mem file to be deleted from store")
 	}
 }
+
+func TestLocalManager_Build_RejectsUnrestorableSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := erebus.NewLocalStore(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := NewLocalManager(store, nil, filepath.Join(tmpDir, "snapshots"), hermes.NewNoopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.vmLauncher = func(ctx context.Context, tpl *domain.TemplateSpec, rootfsPath, socketPath string) (SnapshotMachine, error) {
+		return &MockSnapshotMachine{}, nil
+	}
+	mgr.vmRestorer = func(ctx context.Context, tpl *domain.TemplateSpec, rootfsPath, memPath, statePath, socketPath string) (SnapshotMachine, error) {
+		return nil, fmt.Errorf("bad snapshot")
+	}
+
+	tpl := &domain.TemplateSpec{ID: "tpl-1", BaseImage: "base.img", KernelImage: "kernel"}
+	if _, err := mgr.Build(context.Background(), tpl); err == nil {
+		t.Fatal("expected Build to fail validation")
+	}
+	if _, err := mgr.GetSnapshot(context.Background(), tpl.ID); err == nil {
+		t.Error("unrestorable snapshot was published")
+	}
+
+	// A snapshot that restores is built, but only served once published
+	mgr.vmRestorer = func(ctx context.Context, tpl *domain.TemplateSpec, rootfsPath, memPath, statePath, socketPath string) (SnapshotMachine, error) {
+		return &MockSnapshotMachine{}, nil
+	}
+	snap, err := mgr.Build(context.Background(), tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.GetSnapshot(context.Background(), tpl.ID); err == nil {
+		t.Error("snapshot served before it was published")
+	}
+	if err := mgr.Publish(context.Background(), snap); err != nil {
+		t.Fatal(err)
+	}
+	got, err := mgr.GetSnapshot(context.Background(), tpl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != snap.ID {
+		t.Errorf("expected published snapshot %s, got %s", snap.ID, got.ID)
+	}
+}
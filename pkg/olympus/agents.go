@@ -0,0 +1,234 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// VersionWindow is the range of agent versions Olympus supports.
+type VersionWindow struct {
+	MinVersion   string // Oldest supported version ("" = no floor)
+	MaxMinorSkew int    // Minor versions an agent may trail the newest agent (0 = unlimited)
+}
+
+// AgentVersionReport summarizes the agent versions running across the fleet.
+type AgentVersionReport struct {
+	Newest       string             `json:"newest,omitempty"`
+	MinVersion   string             `json:"min_version,omitempty"`
+	MaxMinorSkew int                `json:"max_minor_skew,omitempty"`
+	Versions     map[string]int     `json:"versions"` // Version -> number of agents
+	Agents       []AgentVersionInfo `json:"agents"`
+	Warnings     []string           `json:"warnings,omitempty"`
+}
+
+// AgentVersionInfo is the version of a single agent and whether it is
+// inside the supported window.
+type AgentVersionInfo struct {
+	NodeID    domain.NodeID      `json:"node_id"`
+	Version   string             `json:"version"`
+	Build     *domain.AgentBuild `json:"build,omitempty"`
+	StartedAt time.Time          `json:"started_at,omitempty"`
+	Supported bool               `json:"supported"`
+	Reason    string             `json:"reason,omitempty"` // Why the agent is unsupported
+}
+
+// RolloutOptions control a rolling agent restart.
+type RolloutOptions struct {
+	DrainTimeout time.Duration // How long each agent waits for its sandboxes (default 10m)
+	StartTimeout time.Duration // How long to wait for the agent to come back after draining
+	PollInterval time.Duration // How often Hades is checked for the new agent
+}
+
+const (
+	defaultRolloutDrainTimeout = 10 * time.Minute
+	defaultRolloutStartTimeout = 5 * time.Minute
+	defaultRolloutPollInterval = 2 * time.Second
+)
+
+// AgentVersions reports the version of every agent and warns about agents
+// outside the supported window: older than MinVersion, on a different major
+// version than the newest agent, or more than MaxMinorSkew minor versions
+// behind it. Agents are sorted by node ID.
+func (m *Manager) AgentVersions(ctx context.Context) (*AgentVersionReport, error) {
+	nodes, err := m.Hades.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	window := m.AgentVersionWindow
+	report := &AgentVersionReport{
+		MinVersion:   window.MinVersion,
+		MaxMinorSkew: window.MaxMinorSkew,
+		Versions:     make(map[string]int),
+		Agents:       make([]AgentVersionInfo, 0, len(nodes)),
+	}
+
+	var newest agentVersion
+	for _, n := range nodes {
+		if v, ok := parseAgentVersion(n.AgentVersion); ok && (report.Newest == "" || newest.less(v)) {
+			newest, report.Newest = v, n.AgentVersion
+		}
+	}
+	floor, hasFloor := parseAgentVersion(window.MinVersion)
+
+	for _, n := range nodes {
+		info := AgentVersionInfo{
+			NodeID:    n.ID,
+			Version:   n.AgentVersion,
+			Build:     n.AgentBuild,
+			StartedAt: n.AgentStartedAt,
+			Supported: true,
+		}
+		report.Versions[n.AgentVersion]++
+
+		v, ok := parseAgentVersion(n.AgentVersion)
+		switch {
+		case !ok:
+			info.Reason = fmt.Sprintf("unparseable version %q", n.AgentVersion)
+		case hasFloor && v.less(floor):
+			info.Reason = fmt.Sprintf("older than minimum supported %s", window.MinVersion)
+		case v.major != newest.major:
+			info.Reason = fmt.Sprintf("major version differs from newest %s", report.Newest)
+		case window.MaxMinorSkew > 0 && newest.minor-v.minor > window.MaxMinorSkew:
+			info.Reason = fmt.Sprintf("%d minor versions behind newest %s (max %d)", newest.minor-v.minor, report.Newest, window.MaxMinorSkew)
+		}
+		if info.Reason != "" {
+			info.Supported = false
+			report.Warnings = append(report.Warnings, fmt.Sprintf("node %s runs %s: %s", n.ID, n.AgentVersion, info.Reason))
+		}
+		report.Agents = append(report.Agents, info)
+	}
+
+	m.Metrics.SetGauge("agent_version_unsupported", float64(len(report.Warnings)))
+	return report, nil
+}
+
+// RestartAgents drains and restarts agents one at a time so the fleet can be
+// upgraded without losing capacity all at once. With no nodes given, every
+// node is restarted. The rollout runs in the background; it waits for each
+// agent to report a new start time before moving on and stops at the first
+// agent that does not come back. Returns the nodes in rollout order.
+func (m *Manager) RestartAgents(ctx context.Context, nodes []domain.NodeID, opts RolloutOptions) ([]domain.NodeID, error) {
+	all, err := m.Hades.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	targets := nodes
+	if len(targets) == 0 {
+		for _, n := range all {
+			targets = append(targets, n.ID)
+		}
+		sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+	} else {
+		known := make(map[domain.NodeID]bool, len(all))
+		for _, n := range all {
+			known[n.ID] = true
+		}
+		for _, id := range targets {
+			if !known[id] {
+				return nil, fmt.Errorf("%w: %s", hades.ErrNodeNotFound, id)
+			}
+		}
+	}
+
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = defaultRolloutDrainTimeout
+	}
+	if opts.StartTimeout <= 0 {
+		opts.StartTimeout = defaultRolloutStartTimeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultRolloutPollInterval
+	}
+
+	m.Logger.Info(ctx, "Agent rollout started", map[string]any{"nodes": len(targets), "drain_timeout": opts.DrainTimeout.String()})
+	go m.rollout(context.WithoutCancel(ctx), targets, opts)
+	return targets, nil
+}
+
+func (m *Manager) rollout(ctx context.Context, nodes []domain.NodeID, opts RolloutOptions) {
+	for i, id := range nodes {
+		issued := time.Now()
+		if err := m.Control.RestartAgent(ctx, id, opts.DrainTimeout); err != nil {
+			m.Logger.Error(ctx, "Failed to send restart command, stopping rollout", map[string]any{"node_id": id, "error": err})
+			m.Metrics.IncCounter("agent_rollout_restarts_total", 1, hermes.Label{Key: "result", Value: "error"})
+			return
+		}
+
+		if !m.awaitAgentStart(ctx, id, issued, issued.Add(opts.DrainTimeout+opts.StartTimeout), opts.PollInterval) {
+			m.Logger.Error(ctx, "Agent did not come back, stopping rollout", map[string]any{
+				"node_id":   id,
+				"remaining": len(nodes) - i - 1,
+			})
+			m.Metrics.IncCounter("agent_rollout_restarts_total", 1, hermes.Label{Key: "result", Value: "timeout"})
+			return
+		}
+		m.Metrics.IncCounter("agent_rollout_restarts_total", 1, hermes.Label{Key: "result", Value: "success"})
+	}
+	m.Logger.Info(ctx, "Agent rollout complete", map[string]any{"nodes": len(nodes)})
+}
+
+// awaitAgentStart polls Hades until the node's agent reports a start time
+// after issued.
+func (m *Manager) awaitAgentStart(ctx context.Context, id domain.NodeID, issued, deadline time.Time, interval time.Duration) bool {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		if node, err := m.Hades.GetNode(ctx, id); err == nil && node.AgentStartedAt.After(issued) {
+			m.Logger.Info(ctx, "Agent restarted", map[string]any{"node_id": id, "version": node.AgentVersion})
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+	}
+}
+
+// agentVersion is a parsed "vMAJOR.MINOR.PATCH" version; pre-release and
+// build suffixes are ignored.
+type agentVersion struct{ major, minor, patch int }
+
+func parseAgentVersion(s string) (agentVersion, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return agentVersion{}, false
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return agentVersion{}, false
+		}
+		nums[i] = n
+	}
+	return agentVersion{nums[0], nums[1], nums[2]}, true
+}
+
+func (v agentVersion) less(o agentVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	if v.minor != o.minor {
+		return v.minor < o.minor
+	}
+	return v.patch < o.patch
+}
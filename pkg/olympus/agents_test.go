@@ -0,0 +1,138 @@
+package olympus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestManager_AgentVersions(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	versions := map[domain.NodeID]string{
+		"node-a": "v1.4.0",
+		"node-b": "v1.3.2",
+		"node-c": "v1.1.0",
+		"node-d": "v0.9.0",
+		"node-e": "dev",
+		"node-f": "v1.4.0-rc.1",
+	}
+	for id, v := range versions {
+		require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+			Node:         domain.NodeInfo{ID: id},
+			AgentVersion: v,
+			AgentBuild:   &domain.AgentBuild{Commit: "abc"},
+			Time:         time.Now(),
+		}))
+	}
+
+	manager := &olympus.Manager{
+		Hades:              registry,
+		Metrics:            hermes.NewNoopMetrics(),
+		Logger:             &mockLogger{},
+		AgentVersionWindow: olympus.VersionWindow{MinVersion: "v1.0.0", MaxMinorSkew: 2},
+	}
+	report, err := manager.AgentVersions(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1.4.0", report.Newest)
+	assert.Equal(t, 1, report.Versions["v1.4.0"])
+	assert.Equal(t, 1, report.Versions["v1.4.0-rc.1"])
+	require.Len(t, report.Agents, 6)
+	assert.Equal(t, domain.NodeID("node-a"), report.Agents[0].NodeID)
+	assert.Equal(t, "abc", report.Agents[0].Build.Commit)
+
+	supported := make(map[domain.NodeID]bool)
+	for _, a := range report.Agents {
+		supported[a.NodeID] = a.Supported
+	}
+	assert.Equal(t, map[domain.NodeID]bool{
+		"node-a": true,
+		"node-b": true,
+		"node-c": false, // 3 minor versions behind
+		"node-d": false, // Below the minimum
+		"node-e": false, // Unparseable
+		"node-f": true,
+	}, supported)
+	assert.Len(t, report.Warnings, 3)
+	assert.Contains(t, report.Warnings[0], "node-c")
+}
+
+// restartingControl simulates agents that come back after a restart, except
+// for those in stuck.
+type restartingControl struct {
+	olympus.NoopControlPlane
+	registry *hades.MemoryRegistry
+	stuck    map[domain.NodeID]bool
+
+	mu        sync.Mutex
+	restarted []domain.NodeID
+}
+
+func (c *restartingControl) RestartAgent(ctx context.Context, nodeID domain.NodeID, drainTimeout time.Duration) error {
+	c.mu.Lock()
+	c.restarted = append(c.restarted, nodeID)
+	c.mu.Unlock()
+	if c.stuck[nodeID] {
+		return nil
+	}
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		now := time.Now()
+		c.registry.UpdateHeartbeat(context.Background(), hades.HeartbeatPayload{
+			Node:           domain.NodeInfo{ID: nodeID},
+			AgentVersion:   "v1.5.0",
+			AgentStartedAt: now,
+			Time:           now,
+		})
+	}()
+	return nil
+}
+
+func (c *restartingControl) nodes() []domain.NodeID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]domain.NodeID(nil), c.restarted...)
+}
+
+func TestManager_RestartAgents_Rolling(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	for _, id := range []domain.NodeID{"node-c", "node-a", "node-b"} {
+		require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+			Node:           domain.NodeInfo{ID: id},
+			AgentVersion:   "v1.4.0",
+			AgentStartedAt: time.Now().Add(-time.Hour),
+			Time:           time.Now(),
+		}))
+	}
+	opts := olympus.RolloutOptions{DrainTimeout: 20 * time.Millisecond, StartTimeout: 50 * time.Millisecond, PollInterval: 5 * time.Millisecond}
+
+	control := &restartingControl{registry: registry}
+	manager := &olympus.Manager{Hades: registry, Control: control, Metrics: hermes.NewNoopMetrics(), Logger: &mockLogger{}}
+
+	order, err := manager.RestartAgents(ctx, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.NodeID{"node-a", "node-b", "node-c"}, order)
+	assert.Eventually(t, func() bool { return len(control.nodes()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, order, control.nodes())
+
+	// The rollout stops at an agent that does not come back
+	stuck := &restartingControl{registry: registry, stuck: map[domain.NodeID]bool{"node-a": true}}
+	manager.Control = stuck
+	_, err = manager.RestartAgents(ctx, []domain.NodeID{"node-a", "node-b"}, opts)
+	require.NoError(t, err)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, []domain.NodeID{"node-a"}, stuck.nodes())
+
+	_, err = manager.RestartAgents(ctx, []domain.NodeID{"node-x"}, opts)
+	assert.ErrorIs(t, err, hades.ErrNodeNotFound)
+}
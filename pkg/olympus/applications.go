@@ -0,0 +1,452 @@
+package olympus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+var (
+	ErrApplicationNotFound     = errors.New("application not found")
+	ErrInvalidApplication      = errors.New("invalid application")
+	ErrApplicationConflict     = errors.New("application conflicts with another application")
+	ErrApplicationsUnavailable = errors.New("application store not configured")
+)
+
+var applicationName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Application bundles what a workload needs into one document: its
+// template, the template's policy, the network its sandboxes join and the
+// schedule they may run on. Applying it creates or updates every part.
+type Application struct {
+	Name     string                   `json:"name"`
+	Template domain.TemplateSpec      `json:"template"`
+	Policy   *domain.SandboxPolicy    `json:"policy,omitempty"`   // Template-level policy; its scope and version are managed by the application
+	Network  *domain.NetworkPolicyRef `json:"network,omitempty"`  // Overrides policy.network
+	Schedule *domain.RunWindowPolicy  `json:"schedule,omitempty"` // Overrides policy.run_window
+}
+
+// ApplicationRecord is an application as last applied.
+type ApplicationRecord struct {
+	Application
+	Generation int64     `json:"generation"` // Incremented by every apply that changed a part
+	AppliedAt  time.Time `json:"applied_at"`
+	AppliedBy  string    `json:"applied_by,omitempty"`
+}
+
+// Application parts, as named in ApplicationChange.
+const (
+	ApplicationPartTemplate = "template"
+	ApplicationPartPolicy   = "policy"
+)
+
+// Actions in ApplicationChange.
+const (
+	ApplicationCreate    = "create"
+	ApplicationUpdate    = "update"
+	ApplicationUnchanged = "unchanged"
+)
+
+// ApplicationChange is what applying an application does to one part.
+type ApplicationChange struct {
+	Part   string   `json:"part"`
+	ID     string   `json:"id"`
+	Action string   `json:"action"`
+	Fields []string `json:"fields,omitempty"` // Top-level fields that change on update
+}
+
+// ApplyResult is the outcome of applying an application, or of a dry run.
+type ApplyResult struct {
+	Application *ApplicationRecord  `json:"application"`
+	Changes     []ApplicationChange `json:"changes"`
+	Applied     bool                `json:"applied"` // False for dry runs and applies that changed nothing
+}
+
+// ApplicationStatus is the deployed state of an application: the record as
+// applied, the parts as they are now, parts edited outside the application
+// since, and its sandboxes by status.
+type ApplicationStatus struct {
+	*ApplicationRecord
+	LiveTemplate *domain.TemplateSpec     `json:"live_template,omitempty"`
+	LivePolicy   *domain.SandboxPolicy    `json:"live_policy,omitempty"`
+	Drift        []ApplicationChange      `json:"drift,omitempty"` // What applying the record again would change
+	Sandboxes    map[domain.RunStatus]int `json:"sandboxes"`
+}
+
+// ApplicationStore keeps applied applications.
+type ApplicationStore interface {
+	GetApplication(ctx context.Context, name string) (*ApplicationRecord, error)
+	ListApplications(ctx context.Context) ([]*ApplicationRecord, error)
+	PutApplication(ctx context.Context, rec *ApplicationRecord) error
+}
+
+// MemoryApplicationStore is an in-memory ApplicationStore.
+type MemoryApplicationStore struct {
+	mu   sync.RWMutex
+	apps map[string]*ApplicationRecord
+}
+
+func NewMemoryApplicationStore() *MemoryApplicationStore {
+	return &MemoryApplicationStore{apps: make(map[string]*ApplicationRecord)}
+}
+
+func (s *MemoryApplicationStore) GetApplication(ctx context.Context, name string) (*ApplicationRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.apps[name]
+	if !ok {
+		return nil, ErrApplicationNotFound
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+// ListApplications returns the applications ordered by name.
+func (s *MemoryApplicationStore) ListApplications(ctx context.Context) ([]*ApplicationRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*ApplicationRecord, 0, len(s.apps))
+	for _, rec := range s.apps {
+		copied := *rec
+		list = append(list, &copied)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (s *MemoryApplicationStore) PutApplication(ctx context.Context, rec *ApplicationRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *rec
+	s.apps[rec.Name] = &copied
+	return nil
+}
+
+// desiredPolicy is the template-level policy the application asks for.
+func (app *Application) desiredPolicy() *domain.SandboxPolicy {
+	policy := &domain.SandboxPolicy{}
+	if app.Policy != nil {
+		copied := *app.Policy
+		policy = &copied
+	}
+	policy.ID = domain.PolicyID("app-" + app.Name)
+	policy.TenantID = ""
+	policy.TemplateID = app.Template.ID
+	tags := map[string]string{"application": app.Name}
+	for k, v := range policy.Tags {
+		if k != "application" {
+			tags[k] = v
+		}
+	}
+	policy.Tags = tags
+	if app.Network != nil {
+		policy.NetworkPolicy = *app.Network
+	}
+	if app.Schedule != nil {
+		policy.RunWindow = *app.Schedule
+	}
+	return policy
+}
+
+// validate checks the application and its policy before anything is
+// written.
+func (app *Application) validate() error {
+	if !applicationName.MatchString(app.Name) {
+		return fmt.Errorf("%w: name %q must be lowercase letters, digits and dashes", ErrInvalidApplication, app.Name)
+	}
+	if app.Template.ID == "" {
+		return fmt.Errorf("%w: template.id is required", ErrInvalidApplication)
+	}
+	if app.Template.BaseImage == "" && len(app.Template.Variants) == 0 {
+		return fmt.Errorf("%w: template.base_image is required", ErrInvalidApplication)
+	}
+	if err := themis.ValidatePolicy(app.desiredPolicy()); err != nil {
+		return fmt.Errorf("%w: policy: %v", ErrInvalidApplication, err)
+	}
+	return nil
+}
+
+// ApplyApplication creates or updates the application's template and
+// policy, then records the application. Parts are written only if they
+// change. If writing a part fails, the parts already written are put back as
+// they were. With dryRun set nothing is written and the result lists the
+// changes an apply would make.
+func (m *Manager) ApplyApplication(ctx context.Context, app *Application, dryRun bool) (*ApplyResult, error) {
+	if m.Applications == nil {
+		return nil, ErrApplicationsUnavailable
+	}
+	if err := app.validate(); err != nil {
+		return nil, err
+	}
+
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	if err := m.checkApplicationOwnership(ctx, app); err != nil {
+		return nil, err
+	}
+	prev, err := m.Applications.GetApplication(ctx, app.Name)
+	if err != nil && !errors.Is(err, ErrApplicationNotFound) {
+		return nil, err
+	}
+
+	liveTemplate, livePolicy, err := m.applicationParts(ctx, app.Template.ID)
+	if err != nil {
+		return nil, err
+	}
+	desiredPolicy := app.desiredPolicy()
+	changes := applicationChanges(app, desiredPolicy, liveTemplate, livePolicy)
+
+	rec := &ApplicationRecord{Application: *app, AppliedAt: time.Now()}
+	if sub := submitterFromContext(ctx); sub != nil {
+		rec.AppliedBy = sub.ID
+	}
+	if prev != nil {
+		rec.Generation = prev.Generation
+	}
+	changed := applicationChanged(changes) || prev == nil || !reflect.DeepEqual(prev.Application, *app)
+	if dryRun || !changed {
+		if prev != nil && !changed {
+			rec = prev
+		}
+		return &ApplyResult{Application: rec, Changes: changes}, nil
+	}
+	rec.Generation++
+
+	if err := m.applyApplicationParts(ctx, rec, changes, desiredPolicy, liveTemplate, livePolicy); err != nil {
+		m.Metrics.IncCounter("olympus_application_applies_total", 1, hermes.Label{Key: "result", Value: "failed"})
+		return nil, err
+	}
+	m.Logger.Info(ctx, "Application applied", map[string]any{
+		"application": app.Name,
+		"generation":  rec.Generation,
+		"template":    app.Template.ID,
+	})
+	m.Metrics.IncCounter("olympus_application_applies_total", 1, hermes.Label{Key: "result", Value: "applied"})
+	return &ApplyResult{Application: rec, Changes: changes, Applied: true}, nil
+}
+
+// applyApplicationParts writes the changed parts and the record, rolling back
+// the parts on failure.
+func (m *Manager) applyApplicationParts(ctx context.Context, rec *ApplicationRecord, changes []ApplicationChange, desiredPolicy *domain.SandboxPolicy, liveTemplate *domain.TemplateSpec, livePolicy *domain.SandboxPolicy) error {
+	var undo []func()
+	rollback := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Action == ApplicationUnchanged:
+		case change.Part == ApplicationPartTemplate:
+			tpl := rec.Template
+			if err := m.Templates.RegisterTemplate(ctx, &tpl); err != nil {
+				rollback()
+				return fmt.Errorf("failed to write template: %w", err)
+			}
+			undo = append(undo, func() { m.restoreTemplate(ctx, tpl.ID, liveTemplate) })
+		case change.Part == ApplicationPartPolicy:
+			if livePolicy != nil {
+				desiredPolicy.Version = livePolicy.Version
+			}
+			if err := m.Policies.UpsertPolicy(ctx, desiredPolicy); err != nil {
+				rollback()
+				return fmt.Errorf("failed to write policy: %w", err)
+			}
+			undo = append(undo, func() { m.restorePolicy(ctx, desiredPolicy, livePolicy) })
+		}
+	}
+
+	if err := m.Applications.PutApplication(ctx, rec); err != nil {
+		rollback()
+		return fmt.Errorf("failed to record application: %w", err)
+	}
+	return nil
+}
+
+// restoreTemplate puts a template back as it was before an apply, removing
+// it if the apply created it.
+func (m *Manager) restoreTemplate(ctx context.Context, id domain.TemplateID, prev *domain.TemplateSpec) {
+	var err error
+	if prev != nil {
+		err = m.Templates.RegisterTemplate(ctx, prev)
+	} else if err = m.Templates.DeleteTemplate(ctx, id, time.Now()); err == nil {
+		err = m.Templates.PurgeTemplate(ctx, id)
+	}
+	if err != nil {
+		m.Logger.Error(ctx, "Failed to roll back application template", map[string]any{"template": id, "error": err})
+	}
+}
+
+// restorePolicy puts a policy back as it was before an apply, removing it
+// if the apply created it.
+func (m *Manager) restorePolicy(ctx context.Context, written, prev *domain.SandboxPolicy) {
+	scope := themis.ScopeOf(written)
+	var err error
+	if prev != nil {
+		restored := *prev
+		restored.Version = written.Version
+		err = m.Policies.UpsertPolicy(ctx, &restored)
+	} else if err = m.Policies.DeletePolicy(ctx, scope, time.Now()); err == nil {
+		err = m.Policies.PurgePolicy(ctx, scope)
+	}
+	if err != nil {
+		m.Logger.Error(ctx, "Failed to roll back application policy", map[string]any{"template": written.TemplateID, "error": err})
+	}
+}
+
+// checkApplicationOwnership refuses to take over a template another
+// application manages.
+func (m *Manager) checkApplicationOwnership(ctx context.Context, app *Application) error {
+	apps, err := m.Applications.ListApplications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range apps {
+		if other.Name != app.Name && other.Template.ID == app.Template.ID {
+			return fmt.Errorf("%w: template %s belongs to application %s", ErrApplicationConflict, app.Template.ID, other.Name)
+		}
+	}
+	return nil
+}
+
+// applicationParts returns the template and its template-level policy as
+// they are now, nil for those that do not exist.
+func (m *Manager) applicationParts(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, *domain.SandboxPolicy, error) {
+	tpl, err := m.Templates.GetTemplate(ctx, id)
+	if errors.Is(err, ErrTemplateNotFound) {
+		tpl = nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	policy, err := m.Policies.GetScopedPolicy(ctx, themis.TemplateScope(id))
+	if errors.Is(err, themis.ErrPolicyNotFound) {
+		policy = nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to get policy: %w", err)
+	}
+	return tpl, policy, nil
+}
+
+// applicationChanges compares the parts an application asks for with the
+// live ones.
+func applicationChanges(app *Application, desiredPolicy *domain.SandboxPolicy, liveTemplate *domain.TemplateSpec, livePolicy *domain.SandboxPolicy) []ApplicationChange {
+	templateChange := ApplicationChange{Part: ApplicationPartTemplate, ID: string(app.Template.ID), Action: ApplicationCreate}
+	if liveTemplate != nil {
+		templateChange.Fields = changedFields(liveTemplate, &app.Template, "deleted_at")
+		templateChange.Action = updateAction(templateChange.Fields)
+	}
+	policyChange := ApplicationChange{Part: ApplicationPartPolicy, ID: string(desiredPolicy.ID), Action: ApplicationCreate}
+	if livePolicy != nil {
+		// The application takes over the template's existing policy
+		desiredPolicy.ID = livePolicy.ID
+		policyChange.ID = string(livePolicy.ID)
+		policyChange.Fields = changedFields(livePolicy, desiredPolicy, "version", "deleted_at")
+		policyChange.Action = updateAction(policyChange.Fields)
+	}
+	return []ApplicationChange{templateChange, policyChange}
+}
+
+func updateAction(fields []string) string {
+	if len(fields) == 0 {
+		return ApplicationUnchanged
+	}
+	return ApplicationUpdate
+}
+
+func applicationChanged(changes []ApplicationChange) bool {
+	for _, change := range changes {
+		if change.Action != ApplicationUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFields returns the top-level JSON fields that differ between two
+// values of the same type, sorted, leaving out ignored ones.
+func changedFields(live, desired any, ignore ...string) []string {
+	liveFields, desiredFields := jsonFields(live), jsonFields(desired)
+	for _, name := range ignore {
+		delete(liveFields, name)
+		delete(desiredFields, name)
+	}
+	var fields []string
+	for name, value := range desiredFields {
+		if string(liveFields[name]) != string(value) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range liveFields {
+		if _, ok := desiredFields[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func jsonFields(v any) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	data, err := json.Marshal(v)
+	if err == nil {
+		_ = json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+// GetApplication returns the deployed state of an application.
+func (m *Manager) GetApplication(ctx context.Context, name string) (*ApplicationStatus, error) {
+	if m.Applications == nil {
+		return nil, ErrApplicationsUnavailable
+	}
+	rec, err := m.Applications.GetApplication(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	liveTemplate, livePolicy, err := m.applicationParts(ctx, rec.Template.ID)
+	if err != nil {
+		return nil, err
+	}
+	status := &ApplicationStatus{
+		ApplicationRecord: rec,
+		LiveTemplate:      liveTemplate,
+		LivePolicy:        livePolicy,
+		Sandboxes:         make(map[domain.RunStatus]int),
+	}
+	for _, change := range applicationChanges(&rec.Application, rec.desiredPolicy(), liveTemplate, livePolicy) {
+		if change.Action != ApplicationUnchanged {
+			status.Drift = append(status.Drift, change)
+		}
+	}
+
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	for _, run := range runs {
+		if run.Template == rec.Template.ID {
+			status.Sandboxes[run.Status]++
+		}
+	}
+	return status, nil
+}
+
+// ListApplications returns the applied applications ordered by name.
+func (m *Manager) ListApplications(ctx context.Context) ([]*ApplicationRecord, error) {
+	if m.Applications == nil {
+		return nil, ErrApplicationsUnavailable
+	}
+	return m.Applications.ListApplications(ctx)
+}
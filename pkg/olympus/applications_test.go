@@ -0,0 +1,191 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+// failingPolicyRepo refuses policy writes.
+type failingPolicyRepo struct {
+	themis.Repository
+}
+
+func (r failingPolicyRepo) UpsertPolicy(ctx context.Context, p *domain.SandboxPolicy) error {
+	return errors.New("policy store unavailable")
+}
+
+func newApplicationManager() *olympus.Manager {
+	return &olympus.Manager{
+		Hades:        hades.NewMemoryRegistry(),
+		Templates:    olympus.NewMemoryTemplateManager(),
+		Policies:     themis.NewMemoryRepo(),
+		Applications: olympus.NewMemoryApplicationStore(),
+		Metrics:      hermes.NewNoopMetrics(),
+		Logger:       &mockLogger{},
+	}
+}
+
+func webApplication() *olympus.Application {
+	return &olympus.Application{
+		Name:     "web",
+		Template: domain.TemplateSpec{ID: "web", BaseImage: "python:3.12", Resources: domain.ResourceSpec{CPU: 500, Mem: 256}},
+		Policy:   &domain.SandboxPolicy{Resources: domain.ResourceSpec{CPU: 1000, Mem: 512}},
+		Network:  &domain.NetworkPolicyRef{ID: "net-web", Name: "web"},
+		Schedule: &domain.RunWindowPolicy{MaxQueueTime: time.Hour},
+	}
+}
+
+func actions(changes []olympus.ApplicationChange) map[string]string {
+	got := make(map[string]string)
+	for _, change := range changes {
+		got[change.Part] = change.Action
+	}
+	return got
+}
+
+func TestManager_ApplyApplication(t *testing.T) {
+	ctx := context.Background()
+	manager := newApplicationManager()
+
+	// A dry run reports the parts it would create and writes nothing
+	result, err := manager.ApplyApplication(ctx, webApplication(), true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if result.Applied || actions(result.Changes)["template"] != olympus.ApplicationCreate || actions(result.Changes)["policy"] != olympus.ApplicationCreate {
+		t.Fatalf("unexpected dry run result %+v", result)
+	}
+	if _, err := manager.Templates.GetTemplate(ctx, "web"); !errors.Is(err, olympus.ErrTemplateNotFound) {
+		t.Fatalf("expected dry run to write nothing, got %v", err)
+	}
+
+	result, err = manager.ApplyApplication(ctx, webApplication(), false)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if !result.Applied || result.Application.Generation != 1 {
+		t.Fatalf("expected generation 1 to be applied, got %+v", result)
+	}
+	policy, err := manager.Policies.GetScopedPolicy(ctx, themis.TemplateScope("web"))
+	if err != nil {
+		t.Fatalf("expected template policy: %v", err)
+	}
+	if policy.NetworkPolicy.ID != "net-web" || policy.RunWindow.MaxQueueTime != time.Hour || policy.Resources.CPU != 1000 || policy.Tags["application"] != "web" {
+		t.Errorf("policy does not carry the application's parts: %+v", policy)
+	}
+
+	// Applying the same document again changes nothing
+	result, err = manager.ApplyApplication(ctx, webApplication(), false)
+	if err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+	if result.Applied || result.Application.Generation != 1 || actions(result.Changes)["policy"] != olympus.ApplicationUnchanged {
+		t.Errorf("expected an unchanged re-apply, got %+v", result)
+	}
+
+	// An update lists the fields it changes
+	app := webApplication()
+	app.Template.BaseImage = "python:3.13"
+	app.Schedule.MaxQueueTime = 2 * time.Hour
+	result, err = manager.ApplyApplication(ctx, app, false)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if result.Application.Generation != 2 {
+		t.Errorf("expected generation 2, got %d", result.Application.Generation)
+	}
+	for _, change := range result.Changes {
+		want := map[string][]string{"template": {"base_image"}, "policy": {"run_window"}}[change.Part]
+		if change.Action != olympus.ApplicationUpdate || !reflect.DeepEqual(change.Fields, want) {
+			t.Errorf("unexpected %s change %+v", change.Part, change)
+		}
+	}
+
+	// Another application may not take over the template
+	other := webApplication()
+	other.Name = "web-copy"
+	if _, err := manager.ApplyApplication(ctx, other, false); !errors.Is(err, olympus.ErrApplicationConflict) {
+		t.Errorf("expected ErrApplicationConflict, got %v", err)
+	}
+
+	invalid := webApplication()
+	invalid.Name = "Web App"
+	if _, err := manager.ApplyApplication(ctx, invalid, false); !errors.Is(err, olympus.ErrInvalidApplication) {
+		t.Errorf("expected ErrInvalidApplication, got %v", err)
+	}
+}
+
+func TestManager_ApplyApplication_Rollback(t *testing.T) {
+	ctx := context.Background()
+	manager := newApplicationManager()
+	manager.Policies = failingPolicyRepo{Repository: manager.Policies}
+
+	if _, err := manager.ApplyApplication(ctx, webApplication(), false); err == nil {
+		t.Fatal("expected the apply to fail")
+	}
+	if _, err := manager.Templates.GetTemplate(ctx, "web"); !errors.Is(err, olympus.ErrTemplateNotFound) {
+		t.Errorf("expected the created template to be rolled back, got %v", err)
+	}
+	if _, err := manager.GetApplication(ctx, "web"); !errors.Is(err, olympus.ErrApplicationNotFound) {
+		t.Errorf("expected no application record, got %v", err)
+	}
+}
+
+func TestManager_GetApplication(t *testing.T) {
+	ctx := context.Background()
+	manager := newApplicationManager()
+	if _, err := manager.ApplyApplication(ctx, webApplication(), false); err != nil {
+		t.Fatal(err)
+	}
+	for id, status := range map[domain.SandboxID]domain.RunStatus{
+		"r1": domain.RunStatusRunning,
+		"r2": domain.RunStatusRunning,
+		"r3": domain.RunStatusSucceeded,
+	} {
+		if err := manager.Hades.UpdateRun(ctx, domain.SandboxRun{ID: id, Template: "web", Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The template is edited outside the application
+	tpl, err := manager.Templates.GetTemplate(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := *tpl
+	edited.BaseImage = "python:edited"
+	if err := manager.Templates.RegisterTemplate(ctx, &edited); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := manager.GetApplication(ctx, "web")
+	if err != nil {
+		t.Fatalf("GetApplication: %v", err)
+	}
+	if status.Sandboxes[domain.RunStatusRunning] != 2 || status.Sandboxes[domain.RunStatusSucceeded] != 1 {
+		t.Errorf("unexpected sandbox counts %v", status.Sandboxes)
+	}
+	if len(status.Drift) != 1 || status.Drift[0].Part != "template" || !reflect.DeepEqual(status.Drift[0].Fields, []string{"base_image"}) {
+		t.Errorf("expected template drift on base_image, got %+v", status.Drift)
+	}
+	if status.LivePolicy == nil || status.LivePolicy.TemplateID != "web" {
+		t.Errorf("expected the live policy, got %+v", status.LivePolicy)
+	}
+
+	if _, err := manager.GetApplication(ctx, "missing"); !errors.Is(err, olympus.ErrApplicationNotFound) {
+		t.Errorf("expected ErrApplicationNotFound, got %v", err)
+	}
+	apps, err := manager.ListApplications(ctx)
+	if err != nil || len(apps) != 1 || apps[0].Name != "web" {
+		t.Errorf("unexpected applications %v %v", apps, err)
+	}
+}
@@ -0,0 +1,106 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+func newArchManager(t *testing.T, tmpl *domain.TemplateSpec) *olympus.Manager {
+	t.Helper()
+	ctx := context.Background()
+
+	registry := hades.NewMemoryRegistry()
+	policyRepo := themis.NewMemoryRepo()
+	templateMgr := olympus.NewMemoryTemplateManager()
+	logger := &mockLogger{}
+
+	// The amd64 node has more free capacity, so it wins unless filtered out
+	for _, n := range []struct {
+		id   domain.NodeID
+		arch string
+		mem  domain.Megabytes
+	}{
+		{"amd-node", domain.ArchAMD64, 32768},
+		{"arm-node", domain.ArchARM64, 8192},
+	} {
+		registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+			Node: domain.NodeInfo{
+				ID:       n.id,
+				Labels:   map[string]string{domain.NodeLabelArch: n.arch},
+				Capacity: domain.ResourceCapacity{CPU: 8000, Mem: n.mem},
+			},
+			Time: time.Now(),
+		})
+	}
+	templateMgr.RegisterTemplate(ctx, tmpl)
+	policyRepo.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "pol", TemplateID: tmpl.ID})
+
+	return &olympus.Manager{
+		Queue:     acheron.NewMemoryQueue(),
+		Hades:     registry,
+		Policies:  policyRepo,
+		Templates: templateMgr,
+		Judges:    &judges.Chain{},
+		Scheduler: moirai.NewLeastLoadedScheduler(logger),
+		Control:   &olympus.NoopControlPlane{},
+		Metrics:   hermes.NewNoopMetrics(),
+		Logger:    logger,
+	}
+}
+
+func TestSubmit_ArchUnsupported(t *testing.T) {
+	manager := newArchManager(t, &domain.TemplateSpec{
+		ID: "tpl",
+		Variants: map[string]domain.TemplateVariant{
+			domain.ArchAMD64: {KernelImage: "/kernels/vmlinux-amd64"},
+		},
+	})
+
+	err := manager.Submit(context.Background(), &domain.SandboxRequest{Template: "tpl", Arch: domain.ArchARM64})
+	if !errors.Is(err, olympus.ErrUnsupportedArch) {
+		t.Fatalf("expected ErrUnsupportedArch, got %v", err)
+	}
+}
+
+func TestSubmit_ArchFiltersNodes(t *testing.T) {
+	manager := newArchManager(t, &domain.TemplateSpec{
+		ID: "tpl",
+		Variants: map[string]domain.TemplateVariant{
+			domain.ArchARM64: {KernelImage: "/kernels/vmlinux-arm64"},
+		},
+	})
+
+	req := &domain.SandboxRequest{Template: "tpl"}
+	if err := manager.Submit(context.Background(), req); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if req.NodeID != "arm-node" {
+		t.Errorf("expected arm-node, got %s", req.NodeID)
+	}
+	if req.Arch != domain.ArchARM64 {
+		t.Errorf("expected request arch to be resolved to arm64, got %q", req.Arch)
+	}
+}
+
+func TestSubmit_ArchPinned(t *testing.T) {
+	manager := newArchManager(t, &domain.TemplateSpec{ID: "tpl"})
+
+	req := &domain.SandboxRequest{Template: "tpl", Arch: domain.ArchARM64}
+	if err := manager.Submit(context.Background(), req); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if req.NodeID != "arm-node" {
+		t.Errorf("expected arm-node, got %s", req.NodeID)
+	}
+}
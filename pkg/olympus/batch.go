@@ -0,0 +1,101 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+// MaxBatchSize bounds the number of requests in one batch submission.
+const MaxBatchSize = 1000
+
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// BatchResult is the outcome of one request of a batch.
+type BatchResult struct {
+	ID  domain.SandboxID
+	Err error // Nil if the request was accepted
+}
+
+// SubmitBatch submits reqs in one pass. Each request is validated, checked
+// against quota and judged as by Submit; the accepted ones are then placed
+// largest first against a single listing of the nodes, so the batch packs
+// onto as few nodes as the scheduler allows, and enqueued. A request that
+// fails does not affect the others: the results are in the order of reqs.
+// The error is only set for a batch that is empty or too large.
+func (m *Manager) SubmitBatch(ctx context.Context, reqs []*domain.SandboxRequest) ([]BatchResult, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%w: no requests", ErrInvalidBatch)
+	}
+	if len(reqs) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: %d requests, at most %d allowed", ErrInvalidBatch, len(reqs), MaxBatchSize)
+	}
+
+	start := time.Now()
+	defer func() {
+		m.Metrics.ObserveHistogram("sandbox_batch_submission_duration_seconds", time.Since(start).Seconds())
+	}()
+	m.Metrics.IncCounter("sandbox_batch_submissions_total", 1)
+
+	// Record who submitted the requests; never trust a client-supplied value
+	submitter := submitterFromContext(ctx)
+
+	results := make([]BatchResult, len(reqs))
+	admitted := make([]*admission, len(reqs))
+	var accepted []*admission
+	// With an outbox, runs are only recorded once placed, so the quota of
+	// later requests must count the earlier ones
+	var held domain.ResourceQuota
+	for i, req := range reqs {
+		req.Submitter = submitter
+		a, err := m.admit(ctx, req, nil, held)
+		results[i] = BatchResult{ID: req.ID, Err: err}
+		if err != nil {
+			continue
+		}
+		admitted[i] = a
+		accepted = append(accepted, a)
+		if m.Outbox != nil {
+			held.Sandboxes++
+			held.CPU += req.Resources.CPU
+			held.Mem += req.Resources.Mem
+			held.GPU += req.Resources.GPU.Count
+		}
+	}
+	if len(accepted) == 0 {
+		return results, nil
+	}
+
+	nodes, err := m.listNodes(ctx, accepted...)
+	if err != nil {
+		for i, a := range admitted {
+			if a != nil {
+				results[i].Err = err
+			}
+		}
+		return results, nil
+	}
+
+	scheduler := moirai.NewBatchScheduler(m.Scheduler)
+	placed := 0
+	for _, i := range moirai.PackingOrder(reqs) {
+		if admitted[i] == nil {
+			continue
+		}
+		results[i].Err = m.place(ctx, admitted[i], scheduler, nodes)
+		if results[i].Err == nil {
+			placed++
+		}
+	}
+
+	m.Logger.Info(ctx, "Batch submitted", map[string]any{
+		"requests": len(reqs),
+		"accepted": placed,
+		"duration": time.Since(start).String(),
+	})
+	return results, nil
+}
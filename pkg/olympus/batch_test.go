@@ -0,0 +1,99 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestSubmitBatch_PacksLargestFirst(t *testing.T) {
+	ctx := context.Background()
+	manager := newArchManager(t, &domain.TemplateSpec{
+		ID: "tpl",
+		Variants: map[string]domain.TemplateVariant{
+			domain.ArchAMD64: {KernelImage: "/kernels/vmlinux-amd64"},
+			domain.ArchARM64: {KernelImage: "/kernels/vmlinux-arm64"},
+		},
+	})
+	manager.Scheduler = moirai.NewBinPackingScheduler(&mockLogger{})
+
+	reqs := []*domain.SandboxRequest{
+		{Template: "tpl", Resources: domain.ResourceSpec{CPU: 500, Mem: 1024}},
+		{Template: "tpl", Resources: domain.ResourceSpec{CPU: 500, Mem: 8192}},
+		{Template: "missing", Resources: domain.ResourceSpec{CPU: 500, Mem: 1024}},
+		{Template: "tpl", Resources: domain.ResourceSpec{CPU: 500, Mem: 1024}},
+	}
+	results, err := manager.SubmitBatch(ctx, reqs)
+	if err != nil {
+		t.Fatalf("SubmitBatch: %v", err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("got %d results, want %d", len(results), len(reqs))
+	}
+	for i, res := range results {
+		if res.ID == "" || res.ID != reqs[i].ID {
+			t.Errorf("result %d has ID %q, request has %q", i, res.ID, reqs[i].ID)
+		}
+		if (res.Err != nil) != (i == 2) {
+			t.Errorf("result %d: unexpected error %v", i, res.Err)
+		}
+	}
+
+	// The large request was placed first and took the node it fills; the
+	// small ones would have fragmented it in submission order
+	want := map[int]domain.NodeID{0: "amd-node", 1: "arm-node", 3: "amd-node"}
+	for i, nodeID := range want {
+		run, err := manager.Hades.GetRun(ctx, reqs[i].ID)
+		if err != nil {
+			t.Fatalf("GetRun(%d): %v", i, err)
+		}
+		if run.NodeID != nodeID || run.Status != domain.RunStatusScheduled {
+			t.Errorf("request %d: %s on %q, want SCHEDULED on %s", i, run.Status, run.NodeID, nodeID)
+		}
+	}
+	if n := manager.Queue.Len(ctx); n != 3 {
+		t.Errorf("queue holds %d requests, want 3", n)
+	}
+}
+
+func TestSubmitBatch_QuotaCountsBatch(t *testing.T) {
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	manager.Outbox = acheron.NewMemoryOutbox(registry)
+	ctx := withIdentity("alice", "acme")
+
+	if _, err := manager.SetQuota(ctx, "acme", domain.ResourceQuota{Sandboxes: 2}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+
+	var reqs []*domain.SandboxRequest
+	for range 3 {
+		reqs = append(reqs, &domain.SandboxRequest{Template: "tpl", Resources: domain.ResourceSpec{CPU: 100, Mem: 128}})
+	}
+	results, err := manager.SubmitBatch(ctx, reqs)
+	if err != nil {
+		t.Fatalf("SubmitBatch: %v", err)
+	}
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatalf("first two requests rejected: %v, %v", results[0].Err, results[1].Err)
+	}
+	if !errors.Is(results[2].Err, olympus.ErrQuotaExceeded) {
+		t.Fatalf("expected the third request to exceed the quota, got %v", results[2].Err)
+	}
+}
+
+func TestSubmitBatch_Invalid(t *testing.T) {
+	manager, _, _ := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	if _, err := manager.SubmitBatch(context.Background(), nil); !errors.Is(err, olympus.ErrInvalidBatch) {
+		t.Errorf("expected ErrInvalidBatch for an empty batch, got %v", err)
+	}
+	reqs := make([]*domain.SandboxRequest, olympus.MaxBatchSize+1)
+	if _, err := manager.SubmitBatch(context.Background(), reqs); !errors.Is(err, olympus.ErrInvalidBatch) {
+		t.Errorf("expected ErrInvalidBatch for an oversized batch, got %v", err)
+	}
+}
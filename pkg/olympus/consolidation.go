@@ -0,0 +1,312 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// DoNotDisruptKey is the run metadata key that exempts a sandbox, and the
+// node it runs on, from consolidation.
+const DoNotDisruptKey = "tartarus.io/do-not-disrupt"
+
+// NodeProvisioner is the cloud provisioner driver that removes agent nodes.
+type NodeProvisioner interface {
+	TerminateNode(ctx context.Context, id domain.NodeID) error
+}
+
+// ConsolidationConfig configures hibernate-based node consolidation.
+type ConsolidationConfig struct {
+	Enabled              bool
+	DryRun               bool    // Log the plan without acting on it
+	UtilizationThreshold float64 // Nodes below this CPU and memory utilization are candidates (default 0.3)
+	MinNodes             int     // Never consolidate below this many schedulable nodes (default 1)
+	MaxNodesPerCycle     int     // Nodes drained per run (default 1)
+}
+
+func (c ConsolidationConfig) withDefaults() ConsolidationConfig {
+	if c.UtilizationThreshold <= 0 {
+		c.UtilizationThreshold = 0.3
+	}
+	if c.MinNodes <= 0 {
+		c.MinNodes = 1
+	}
+	if c.MaxNodesPerCycle <= 0 {
+		c.MaxNodesPerCycle = 1
+	}
+	return c
+}
+
+// NodeConsolidation is the planned action for one node.
+type NodeConsolidation struct {
+	NodeID      domain.NodeID                      `json:"node_id"`
+	Action      string                             `json:"action"` // "drain", "terminate" or "skip"
+	Utilization float64                            `json:"utilization"`
+	Sandboxes   []domain.SandboxID                 `json:"sandboxes,omitempty"`
+	Placement   map[domain.SandboxID]domain.NodeID `json:"placement,omitempty"` // Where each sandbox fits once woken
+	Reason      string                             `json:"reason,omitempty"`
+}
+
+// ConsolidationPlan lists the actions for one consolidation run.
+type ConsolidationPlan struct {
+	DryRun bool                `json:"dry_run"`
+	Nodes  []NodeConsolidation `json:"nodes"`
+}
+
+// Consolidator bin-packs load off under-utilized nodes. A drained node is
+// cordoned and its sandboxes hibernated through Hypnos, whose snapshots are
+// stored in Erebus; once the node reports no active sandboxes it is handed to
+// the provisioner for termination.
+type Consolidator struct {
+	Hades       hades.Registry
+	Manager     *Manager
+	Provisioner NodeProvisioner // Optional; without it drained nodes are only flagged
+	Logger      hermes.Logger
+	Metrics     hermes.Metrics
+	Config      ConsolidationConfig
+
+	mu      sync.Mutex
+	flagged map[domain.NodeID]time.Time // Nodes drained by this consolidator
+}
+
+func NewConsolidator(h hades.Registry, m *Manager, p NodeProvisioner, l hermes.Logger, met hermes.Metrics, cfg ConsolidationConfig) *Consolidator {
+	return &Consolidator{
+		Hades:       h,
+		Manager:     m,
+		Provisioner: p,
+		Logger:      l,
+		Metrics:     met,
+		Config:      cfg.withDefaults(),
+		flagged:     make(map[domain.NodeID]time.Time),
+	}
+}
+
+// Plan computes the consolidation actions without executing them.
+func (c *Consolidator) Plan(ctx context.Context) (*ConsolidationPlan, error) {
+	nodes, err := c.Hades.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	plan := &ConsolidationPlan{DryRun: c.Config.DryRun}
+
+	// Drained nodes that have emptied out are ready for removal
+	c.mu.Lock()
+	for _, node := range nodes {
+		if _, ok := c.flagged[node.ID]; ok && node.Draining() && nodeEmpty(node) {
+			plan.Nodes = append(plan.Nodes, NodeConsolidation{NodeID: node.ID, Action: "terminate"})
+		}
+	}
+	c.mu.Unlock()
+
+	var schedulable []domain.NodeStatus
+	for _, node := range nodes {
+		if !node.Draining() {
+			schedulable = append(schedulable, node)
+		}
+	}
+
+	candidates := make([]domain.NodeStatus, 0, len(schedulable))
+	for _, node := range schedulable {
+		if nodeUtilization(node) < c.Config.UtilizationThreshold {
+			candidates = append(candidates, node)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return nodeUtilization(candidates[i]) < nodeUtilization(candidates[j])
+	})
+
+	// Free capacity of every schedulable node, reduced as sandboxes are placed
+	free := make(map[domain.NodeID]domain.ResourceCapacity, len(schedulable))
+	for _, node := range schedulable {
+		free[node.ID] = domain.ResourceCapacity{
+			CPU: node.Capacity.CPU - node.Allocated.CPU,
+			Mem: node.Capacity.Mem - node.Allocated.Mem,
+		}
+	}
+
+	removing := map[domain.NodeID]bool{}
+	for _, node := range candidates {
+		if len(removing) >= c.Config.MaxNodesPerCycle {
+			break
+		}
+		entry := NodeConsolidation{NodeID: node.ID, Utilization: nodeUtilization(node)}
+		for _, run := range node.ActiveSandboxes {
+			entry.Sandboxes = append(entry.Sandboxes, run.ID)
+		}
+
+		switch {
+		case len(schedulable)-len(removing)-1 < c.Config.MinNodes:
+			entry.Action, entry.Reason = "skip", fmt.Sprintf("would leave fewer than %d schedulable nodes", c.Config.MinNodes)
+		case len(node.ActiveSandboxes) == 0 && !nodeEmpty(node):
+			entry.Action, entry.Reason = "skip", "node has allocated resources but reports no sandboxes"
+		case doNotDisrupt(node):
+			entry.Action, entry.Reason = "skip", "runs a sandbox marked "+DoNotDisruptKey
+		default:
+			placement, ok := binPack(node, schedulable, removing, free)
+			if !ok {
+				entry.Action, entry.Reason = "skip", "remaining nodes lack capacity for its sandboxes"
+				break
+			}
+			entry.Action, entry.Placement = "drain", placement
+			removing[node.ID] = true
+		}
+		plan.Nodes = append(plan.Nodes, entry)
+	}
+
+	return plan, nil
+}
+
+// Run plans and, unless in dry-run mode, executes one consolidation pass.
+func (c *Consolidator) Run(ctx context.Context) (*ConsolidationPlan, error) {
+	plan, err := c.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range plan.Nodes {
+		fields := map[string]any{
+			"node_id":     entry.NodeID,
+			"action":      entry.Action,
+			"utilization": entry.Utilization,
+			"sandboxes":   len(entry.Sandboxes),
+			"dry_run":     plan.DryRun,
+		}
+		if entry.Reason != "" {
+			fields["reason"] = entry.Reason
+		}
+		c.Logger.Info(ctx, "Consolidation plan", fields)
+		if plan.DryRun || entry.Action == "skip" {
+			continue
+		}
+
+		switch entry.Action {
+		case "drain":
+			c.drain(ctx, entry)
+		case "terminate":
+			c.terminate(ctx, entry.NodeID)
+		}
+	}
+	return plan, nil
+}
+
+// drain cordons the node and hibernates its sandboxes.
+func (c *Consolidator) drain(ctx context.Context, entry NodeConsolidation) {
+	if err := c.Hades.MarkDraining(ctx, entry.NodeID); err != nil {
+		c.Logger.Error(ctx, "Failed to cordon node for consolidation", map[string]any{"node_id": entry.NodeID, "error": err})
+		return
+	}
+	c.mu.Lock()
+	c.flagged[entry.NodeID] = time.Now()
+	c.mu.Unlock()
+	c.Metrics.IncCounter("scaler_consolidation_actions_total", 1, hermes.Label{Key: "action", Value: "drain"})
+
+	for _, id := range entry.Sandboxes {
+		if _, err := c.Manager.HibernateSandbox(ctx, id, 0); err != nil {
+			c.Logger.Error(ctx, "Failed to hibernate sandbox for consolidation", map[string]any{"node_id": entry.NodeID, "sandbox_id": id, "error": err})
+			continue
+		}
+		c.Metrics.IncCounter("scaler_consolidation_actions_total", 1, hermes.Label{Key: "action", Value: "hibernate"})
+	}
+}
+
+// terminate hands an empty, drained node to the provisioner.
+func (c *Consolidator) terminate(ctx context.Context, id domain.NodeID) {
+	if c.Provisioner == nil {
+		c.Logger.Info(ctx, "Node drained and flagged for removal; no provisioner configured", map[string]any{"node_id": id})
+		c.Metrics.IncCounter("scaler_consolidation_actions_total", 1, hermes.Label{Key: "action", Value: "flagged"})
+		return
+	}
+	if err := c.Provisioner.TerminateNode(ctx, id); err != nil {
+		c.Logger.Error(ctx, "Failed to terminate consolidated node", map[string]any{"node_id": id, "error": err})
+		return
+	}
+	c.mu.Lock()
+	delete(c.flagged, id)
+	c.mu.Unlock()
+	c.Metrics.IncCounter("scaler_consolidation_actions_total", 1, hermes.Label{Key: "action", Value: "terminate"})
+}
+
+// nodeUtilization is the higher of the node's CPU and memory utilization.
+func nodeUtilization(node domain.NodeStatus) float64 {
+	var cpu, mem float64
+	if node.Capacity.CPU > 0 {
+		cpu = float64(node.Allocated.CPU) / float64(node.Capacity.CPU)
+	}
+	if node.Capacity.Mem > 0 {
+		mem = float64(node.Allocated.Mem) / float64(node.Capacity.Mem)
+	}
+	if cpu > mem {
+		return cpu
+	}
+	return mem
+}
+
+// nodeEmpty reports whether the node runs nothing. Heartbeats may omit the
+// sandbox list, so the allocation must be zero as well.
+func nodeEmpty(node domain.NodeStatus) bool {
+	return len(node.ActiveSandboxes) == 0 && node.Allocated.CPU == 0 && node.Allocated.Mem == 0
+}
+
+func doNotDisrupt(node domain.NodeStatus) bool {
+	for _, run := range node.ActiveSandboxes {
+		if run.Metadata[DoNotDisruptKey] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// binPack places the node's sandboxes first-fit on the other schedulable
+// nodes. Runs do not carry their resource requests, so each sandbox is sized
+// as an even share of the node's allocation. free is updated only when every
+// sandbox fits.
+func binPack(node domain.NodeStatus, schedulable []domain.NodeStatus, removing map[domain.NodeID]bool, free map[domain.NodeID]domain.ResourceCapacity) (map[domain.SandboxID]domain.NodeID, bool) {
+	placement := make(map[domain.SandboxID]domain.NodeID, len(node.ActiveSandboxes))
+	if len(node.ActiveSandboxes) == 0 {
+		return placement, true
+	}
+
+	n := int64(len(node.ActiveSandboxes))
+	share := domain.ResourceCapacity{
+		CPU: domain.MilliCPU(int64(node.Allocated.CPU) / n),
+		Mem: domain.Megabytes(int64(node.Allocated.Mem) / n),
+	}
+
+	trial := make(map[domain.NodeID]domain.ResourceCapacity, len(free))
+	for id, f := range free {
+		trial[id] = f
+	}
+	for _, run := range node.ActiveSandboxes {
+		placed := false
+		for _, target := range schedulable {
+			if target.ID == node.ID || removing[target.ID] {
+				continue
+			}
+			f := trial[target.ID]
+			if f.CPU >= share.CPU && f.Mem >= share.Mem {
+				f.CPU -= share.CPU
+				f.Mem -= share.Mem
+				trial[target.ID] = f
+				placement[run.ID] = target.ID
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			return nil, false
+		}
+	}
+
+	for id, f := range trial {
+		free[id] = f
+	}
+	return placement, true
+}
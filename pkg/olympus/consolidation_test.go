@@ -0,0 +1,167 @@
+package olympus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+type hibernateRecorder struct {
+	olympus.NoopControlPlane
+	mu         sync.Mutex
+	hibernated []domain.SandboxID
+}
+
+func (c *hibernateRecorder) Hibernate(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hibernated = append(c.hibernated, sandboxID)
+	return nil
+}
+
+type fakeProvisioner struct {
+	terminated []domain.NodeID
+}
+
+func (p *fakeProvisioner) TerminateNode(ctx context.Context, id domain.NodeID) error {
+	p.terminated = append(p.terminated, id)
+	return nil
+}
+
+// heartbeat registers a node with the given allocation and sandboxes.
+func heartbeat(t *testing.T, registry hades.Registry, id domain.NodeID, cpu domain.MilliCPU, mem domain.Megabytes, runs ...domain.SandboxRun) {
+	t.Helper()
+	for i := range runs {
+		runs[i].NodeID = id
+		runs[i].Status = domain.RunStatusRunning
+		require.NoError(t, registry.UpdateRun(context.Background(), runs[i]))
+	}
+	require.NoError(t, registry.UpdateHeartbeat(context.Background(), hades.HeartbeatPayload{
+		Node: domain.NodeInfo{
+			ID:       id,
+			Capacity: domain.ResourceCapacity{CPU: 8000, Mem: 16384},
+		},
+		Load:            domain.ResourceCapacity{CPU: cpu, Mem: mem},
+		ActiveSandboxes: runs,
+		Time:            time.Now(),
+	}))
+}
+
+func newConsolidator(registry hades.Registry, control olympus.ControlPlane, p olympus.NodeProvisioner, cfg olympus.ConsolidationConfig) *olympus.Consolidator {
+	logger := &mockLogger{}
+	manager := &olympus.Manager{
+		Hades:   registry,
+		Control: control,
+		Metrics: hermes.NewNoopMetrics(),
+		Logger:  logger,
+	}
+	return olympus.NewConsolidator(registry, manager, p, logger, hermes.NewNoopMetrics(), cfg)
+}
+
+func TestConsolidator_DryRun(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	heartbeat(t, registry, "busy", 6000, 12000)
+	heartbeat(t, registry, "idle", 1000, 1024, domain.SandboxRun{ID: "sbx-1"})
+
+	control := &hibernateRecorder{}
+	c := newConsolidator(registry, control, nil, olympus.ConsolidationConfig{Enabled: true, DryRun: true})
+
+	plan, err := c.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, plan.Nodes, 1)
+	assert.True(t, plan.DryRun)
+	assert.Equal(t, domain.NodeID("idle"), plan.Nodes[0].NodeID)
+	assert.Equal(t, "drain", plan.Nodes[0].Action)
+	assert.Equal(t, domain.NodeID("busy"), plan.Nodes[0].Placement["sbx-1"])
+
+	// Nothing was touched
+	node, err := registry.GetNode(ctx, "idle")
+	require.NoError(t, err)
+	assert.False(t, node.Draining())
+	assert.Empty(t, control.hibernated)
+}
+
+func TestConsolidator_DrainThenTerminate(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	heartbeat(t, registry, "busy", 4000, 8000)
+	heartbeat(t, registry, "idle", 1000, 1024, domain.SandboxRun{ID: "sbx-1"}, domain.SandboxRun{ID: "sbx-2"})
+
+	control := &hibernateRecorder{}
+	provisioner := &fakeProvisioner{}
+	c := newConsolidator(registry, control, provisioner, olympus.ConsolidationConfig{Enabled: true})
+
+	// First pass cordons the node and hibernates its sandboxes
+	_, err := c.Run(ctx)
+	require.NoError(t, err)
+	node, err := registry.GetNode(ctx, "idle")
+	require.NoError(t, err)
+	assert.True(t, node.Draining())
+	assert.ElementsMatch(t, []domain.SandboxID{"sbx-1", "sbx-2"}, control.hibernated)
+	assert.Empty(t, provisioner.terminated)
+
+	// Not terminated while sandboxes are still reported
+	_, err = c.Run(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, provisioner.terminated)
+
+	// Once the agent reports the node empty it is handed to the provisioner
+	heartbeat(t, registry, "idle", 0, 0)
+	_, err = c.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.NodeID{"idle"}, provisioner.terminated)
+}
+
+func TestConsolidator_SafetyChecks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("MinNodes", func(t *testing.T) {
+		registry := hades.NewMemoryRegistry()
+		heartbeat(t, registry, "a", 1000, 1024)
+		heartbeat(t, registry, "b", 1000, 1024)
+
+		c := newConsolidator(registry, &hibernateRecorder{}, nil, olympus.ConsolidationConfig{Enabled: true, MinNodes: 2})
+		plan, err := c.Plan(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, plan.Nodes)
+		assert.Equal(t, "skip", plan.Nodes[0].Action)
+	})
+
+	t.Run("DoNotDisrupt", func(t *testing.T) {
+		registry := hades.NewMemoryRegistry()
+		heartbeat(t, registry, "busy", 4000, 8000)
+		heartbeat(t, registry, "idle", 500, 512, domain.SandboxRun{
+			ID:       "sbx-1",
+			Metadata: map[string]string{olympus.DoNotDisruptKey: "true"},
+		})
+
+		c := newConsolidator(registry, &hibernateRecorder{}, nil, olympus.ConsolidationConfig{Enabled: true})
+		plan, err := c.Plan(ctx)
+		require.NoError(t, err)
+		require.Len(t, plan.Nodes, 1)
+		assert.Equal(t, "skip", plan.Nodes[0].Action)
+		assert.Contains(t, plan.Nodes[0].Reason, olympus.DoNotDisruptKey)
+	})
+
+	t.Run("InsufficientCapacity", func(t *testing.T) {
+		registry := hades.NewMemoryRegistry()
+		heartbeat(t, registry, "full", 7800, 16000)
+		heartbeat(t, registry, "idle", 2000, 1024, domain.SandboxRun{ID: "sbx-1"})
+
+		c := newConsolidator(registry, &hibernateRecorder{}, nil, olympus.ConsolidationConfig{Enabled: true})
+		plan, err := c.Plan(ctx)
+		require.NoError(t, err)
+		require.Len(t, plan.Nodes, 1)
+		assert.Equal(t, domain.NodeID("idle"), plan.Nodes[0].NodeID)
+		assert.Equal(t, "skip", plan.Nodes[0].Action)
+	})
+}
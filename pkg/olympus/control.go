@@ -3,6 +3,7 @@ package olympus
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
@@ -17,6 +18,13 @@ type ControlPlane interface {
 	Exec(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, cmd []string, stdout, stderr io.Writer) error
 	ExecInteractive(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error
 	ListSandboxes(ctx context.Context, nodeID domain.NodeID) ([]domain.SandboxRun, error)
+	PrefetchImage(ctx context.Context, nodeID domain.NodeID, ref string) error
+	RestartAgent(ctx context.Context, nodeID domain.NodeID, drainTimeout time.Duration) error
+	// MigrateOut asks the node running a sandbox to snapshot it and hand it
+	// off for target; MigrateIn asks target to restore the snapshot handed
+	// off under key.
+	MigrateOut(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, target domain.NodeID) error
+	MigrateIn(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, key string) error
 }
 
 // NoopControlPlane for when Redis is not available
@@ -53,3 +61,19 @@ func (n *NoopControlPlane) ExecInteractive(ctx context.Context, nodeID domain.No
 func (n *NoopControlPlane) ListSandboxes(ctx context.Context, nodeID domain.NodeID) ([]domain.SandboxRun, error) {
 	return nil, nil
 }
+
+func (n *NoopControlPlane) PrefetchImage(ctx context.Context, nodeID domain.NodeID, ref string) error {
+	return nil
+}
+
+func (n *NoopControlPlane) RestartAgent(ctx context.Context, nodeID domain.NodeID, drainTimeout time.Duration) error {
+	return nil
+}
+
+func (n *NoopControlPlane) MigrateOut(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, target domain.NodeID) error {
+	return nil
+}
+
+func (n *NoopControlPlane) MigrateIn(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, key string) error {
+	return nil
+}
@@ -0,0 +1,171 @@
+package olympus
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for CORSConfig fields left empty.
+var (
+	DefaultCORSMethods = []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	}
+	DefaultCORSHeaders = []string{
+		"API-Version", "Authorization", "Content-Type", "If-Match", "X-Requested-With",
+	}
+	DefaultCORSExposedHeaders = []string{"API-Version", "Deprecation", "ETag", "Link", "Location", "Sunset"}
+)
+
+// CORSConfig controls which browser origins may call the API.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins or path.Match patterns such as
+	// "https://*.example.com" or "http://localhost:*"; "*" allows any origin.
+	// Empty disables cross-origin access.
+	AllowedOrigins []string
+	AllowedMethods []string // Default DefaultCORSMethods
+	AllowedHeaders []string // Request headers a browser may send (default DefaultCORSHeaders)
+	ExposedHeaders []string // Response headers scripts may read (default DefaultCORSExposedHeaders)
+
+	// AllowCredentials lets browsers send session cookies. It cannot be
+	// combined with a "*" origin.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// Validate rejects configurations browsers would refuse or that would
+// expose credentials to every site.
+func (c CORSConfig) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" && c.AllowCredentials {
+			return errors.New("CORS credentials cannot be allowed for every origin")
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return errors.New("invalid CORS origin pattern " + strconv.Quote(origin))
+		}
+	}
+	return nil
+}
+
+// AllowsOrigin reports whether a browser on origin may call the API.
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if ok, _ := path.Match(allowed, origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin is a websocket.Upgrader CheckOrigin that applies the CORS
+// origins to WebSocket handshakes, which browsers do not preflight.
+// Requests without an Origin header, from non-browser clients, and
+// same-origin requests are always allowed.
+func (c CORSConfig) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return c.AllowsOrigin(origin)
+}
+
+// CORSMiddleware answers preflight requests and adds CORS headers to
+// responses for allowed origins. It must wrap authentication, since
+// browsers send preflights without credentials. Responses to disallowed
+// origins carry no CORS headers, so browsers block them.
+func CORSMiddleware(cfg CORSConfig, next http.Handler) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		return next
+	}
+	methods := strings.Join(orDefault(cfg.AllowedMethods, DefaultCORSMethods), ", ")
+	headers := orDefault(cfg.AllowedHeaders, DefaultCORSHeaders)
+	exposed := strings.Join(orDefault(cfg.ExposedHeaders, DefaultCORSExposedHeaders), ", ")
+	wildcard := false
+	for _, origin := range cfg.AllowedOrigins {
+		wildcard = wildcard || origin == "*"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !cfg.AllowsOrigin(origin) {
+			if preflight {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if preflight {
+			for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+				if h = strings.TrimSpace(h); h != "" && !containsFold(headers, h) {
+					http.Error(w, "Header not allowed: "+h, http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		if wildcard && !cfg.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", exposed)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", methods)
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		if cfg.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func orDefault(values, fallback []string) []string {
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
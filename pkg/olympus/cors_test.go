@@ -0,0 +1,158 @@
+package olympus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://dashboard.example.com", "http://localhost:*"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	// Stands in for authentication, which preflights must never reach
+	handler := CORSMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name            string
+		method          string
+		origin          string
+		headers         map[string]string
+		wantStatus      int
+		wantAllowOrigin string
+		wantMaxAge      string
+	}{
+		{
+			name:            "Preflight from allowed origin",
+			method:          http.MethodOptions,
+			origin:          "https://dashboard.example.com",
+			headers:         map[string]string{"Access-Control-Request-Method": "PATCH", "Access-Control-Request-Headers": "authorization, if-match"},
+			wantStatus:      http.StatusNoContent,
+			wantAllowOrigin: "https://dashboard.example.com",
+			wantMaxAge:      "600",
+		},
+		{
+			name:            "Preflight from origin pattern",
+			method:          http.MethodOptions,
+			origin:          "http://localhost:3000",
+			headers:         map[string]string{"Access-Control-Request-Method": "GET"},
+			wantStatus:      http.StatusNoContent,
+			wantAllowOrigin: "http://localhost:3000",
+			wantMaxAge:      "600",
+		},
+		{
+			name:       "Preflight from unknown origin",
+			method:     http.MethodOptions,
+			origin:     "https://evil.example.com",
+			headers:    map[string]string{"Access-Control-Request-Method": "GET"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Preflight with header not allowed",
+			method:     http.MethodOptions,
+			origin:     "https://dashboard.example.com",
+			headers:    map[string]string{"Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Custom"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:            "Request from allowed origin",
+			method:          http.MethodGet,
+			origin:          "https://dashboard.example.com",
+			headers:         map[string]string{"Authorization": "Bearer key"},
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "https://dashboard.example.com",
+		},
+		{
+			name:       "Request from unknown origin",
+			method:     http.MethodGet,
+			origin:     "https://evil.example.com",
+			headers:    map[string]string{"Authorization": "Bearer key"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Request without origin",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Authorization": "Bearer key"},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/sandboxes", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := rr.Header().Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Access-Control-Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+			if tt.wantAllowOrigin == "" {
+				return
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+			}
+			if tt.method != http.MethodOptions && rr.Header().Get("Access-Control-Expose-Headers") != strings.Join(DefaultCORSExposedHeaders, ", ") {
+				t.Errorf("expected ETag to be exposed, got %q", rr.Header().Get("Access-Control-Expose-Headers"))
+			}
+		})
+	}
+}
+
+func TestCORSConfig_Validate(t *testing.T) {
+	if err := (CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Error("expected credentials for every origin to be rejected")
+	}
+	if err := (CORSConfig{AllowedOrigins: []string{"https://[bad"}}).Validate(); err == nil {
+		t.Error("expected a malformed pattern to be rejected")
+	}
+	if err := (CORSConfig{AllowedOrigins: []string{"*"}}).Validate(); err != nil {
+		t.Errorf("expected any origin without credentials to be valid, got %v", err)
+	}
+}
+
+func TestCORSConfig_CheckOrigin(t *testing.T) {
+	cfg := CORSConfig{AllowedOrigins: []string{"https://*.example.com"}}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},                              // Not a browser
+		{"https://olympus.internal:8080", true}, // Same origin
+		{"https://dashboard.example.com", true}, // Allowed pattern
+		{"https://example.com.evil.io", false},  // Pattern does not match
+		{"http://dashboard.example.com", false}, // Wrong scheme
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://olympus.internal:8080/sandboxes/exec/sock/abc", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := cfg.CheckOrigin(req); got != tt.want {
+			t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package olympus
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// DefaultEstimateTTL is the run time assumed for requests without a TTL.
+const DefaultEstimateTTL = time.Hour
+
+// RateCard prices sandbox resources for chargeback.
+type RateCard struct {
+	Currency     string
+	CPUCoreHour  float64 // Per core (1000 millicores) per hour
+	MemoryGBHour float64 // Per GiB of memory per hour
+	GPUHour      float64 // Per GPU per hour
+
+	// DefaultTTL is the run time assumed for requests without a TTL
+	// (DefaultEstimateTTL if zero).
+	DefaultTTL time.Duration
+}
+
+// CostEstimate is the expected cost of a run that lasts its whole TTL.
+type CostEstimate struct {
+	Currency string  `json:"currency"`
+	Total    float64 `json:"total"`
+	CPU      float64 `json:"cpu"`
+	Memory   float64 `json:"memory"`
+	GPU      float64 `json:"gpu,omitempty"`
+	Hours    float64 `json:"hours"`
+	// TTLAssumed is set when the request has no TTL and the rate card's
+	// default run time was used, so the run may cost more.
+	TTLAssumed bool `json:"ttl_assumed,omitempty"`
+}
+
+// Estimate prices the resources for their TTL.
+func (c RateCard) Estimate(res domain.ResourceSpec) *CostEstimate {
+	ttl, assumed := res.TTL, false
+	if ttl <= 0 {
+		ttl, assumed = c.DefaultTTL, true
+		if ttl <= 0 {
+			ttl = DefaultEstimateTTL
+		}
+	}
+	hours := ttl.Hours()
+
+	est := &CostEstimate{
+		Currency:   c.Currency,
+		CPU:        roundCost(float64(res.CPU) / 1000 * hours * c.CPUCoreHour),
+		Memory:     roundCost(float64(res.Mem) / 1024 * hours * c.MemoryGBHour),
+		GPU:        roundCost(float64(res.GPU.Count) * hours * c.GPUHour),
+		Hours:      hours,
+		TTLAssumed: assumed,
+	}
+	est.Total = roundCost(est.CPU + est.Memory + est.GPU)
+	return est
+}
+
+// roundCost rounds to a hundredth of a cent.
+func roundCost(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// EstimateCost prices the request with the manager's rate card, or returns
+// nil if no rate card is configured.
+func (m *Manager) EstimateCost(req *domain.SandboxRequest) *CostEstimate {
+	if m.RateCard == nil {
+		return nil
+	}
+	return m.RateCard.Estimate(req.Resources)
+}
+
+// DryRun validates the request as Submit would, without running judges,
+// persisting, scheduling or enqueueing it, and returns its cost estimate.
+func (m *Manager) DryRun(ctx context.Context, req *domain.SandboxRequest) (*CostEstimate, error) {
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+	req.Submitter = submitterFromContext(ctx)
+	if _, _, err := m.prepare(ctx, req); err != nil {
+		return nil, err
+	}
+	return m.EstimateCost(req), nil
+}
@@ -0,0 +1,51 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestRateCard_Estimate(t *testing.T) {
+	card := olympus.RateCard{Currency: "USD", CPUCoreHour: 0.04, MemoryGBHour: 0.005, GPUHour: 2.5}
+
+	est := card.Estimate(domain.ResourceSpec{CPU: 2000, Mem: 4096, GPU: domain.GPURequest{Count: 1}, TTL: 30 * time.Minute})
+	want := &olympus.CostEstimate{Currency: "USD", CPU: 0.04, Memory: 0.01, GPU: 1.25, Total: 1.3, Hours: 0.5}
+	if *est != *want {
+		t.Errorf("expected %+v, got %+v", want, est)
+	}
+
+	// Without a TTL the default run time is assumed and flagged
+	est = card.Estimate(domain.ResourceSpec{CPU: 1000})
+	if !est.TTLAssumed || est.Hours != 1 || est.Total != 0.04 {
+		t.Errorf("expected one assumed hour, got %+v", est)
+	}
+}
+
+func TestManager_DryRun(t *testing.T) {
+	manager := newArchManager(t, &domain.TemplateSpec{ID: "tpl"})
+	manager.RateCard = &olympus.RateCard{Currency: "EUR", CPUCoreHour: 0.1}
+	ctx := context.Background()
+
+	req := &domain.SandboxRequest{Template: "tpl", Resources: domain.ResourceSpec{CPU: 4000, TTL: 2 * time.Hour}}
+	est, err := manager.DryRun(ctx, req)
+	if err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	if est.Currency != "EUR" || est.Total != 0.8 {
+		t.Errorf("unexpected estimate %+v", est)
+	}
+
+	// Nothing is recorded or queued
+	runs, _ := manager.Hades.ListRuns(ctx)
+	if len(runs) != 0 || req.ID != "" {
+		t.Errorf("dry run persisted %d runs (id %q)", len(runs), req.ID)
+	}
+
+	if _, err := manager.DryRun(ctx, &domain.SandboxRequest{Template: "missing"}); err == nil {
+		t.Error("expected an unknown template to fail the dry run")
+	}
+}
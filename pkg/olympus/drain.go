@@ -0,0 +1,305 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+var (
+	ErrDrainInProgress = errors.New("node is already being drained")
+	ErrDrainNotFound   = errors.New("node has not been drained")
+	ErrInvalidDrain    = errors.New("invalid drain")
+)
+
+// DrainMode is how a drain evicts a node's sandboxes.
+type DrainMode string
+
+const (
+	DrainHibernate DrainMode = "hibernate" // Snapshot through Hypnos; kill if the hibernate command fails
+	DrainKill      DrainMode = "kill"
+)
+
+// DrainPhase is the state of a node drain.
+type DrainPhase string
+
+const (
+	DrainRunning   DrainPhase = "DRAINING"
+	DrainCompleted DrainPhase = "COMPLETED"
+	DrainTimedOut  DrainPhase = "TIMED_OUT"
+	DrainCanceled  DrainPhase = "CANCELED" // The node was uncordoned
+)
+
+// DrainOptions control a node drain.
+type DrainOptions struct {
+	Mode         DrainMode     // How sandboxes are evicted (default DrainHibernate)
+	Timeout      time.Duration // How long to wait for the node to empty (default 10m)
+	PollInterval time.Duration // How often Hades is checked for the node's sandboxes
+}
+
+const (
+	defaultDrainTimeout      = 10 * time.Minute
+	defaultDrainPollInterval = 2 * time.Second
+)
+
+// SandboxEviction is the progress of one sandbox off a draining node.
+type SandboxEviction struct {
+	SandboxID domain.SandboxID `json:"sandbox_id"`
+	Action    DrainMode        `json:"action"` // The command sent
+	Done      bool             `json:"done"`   // Gone from the node's heartbeat
+	Error     string           `json:"error,omitempty"`
+}
+
+// NodeDrain reports the progress of evacuating a node.
+type NodeDrain struct {
+	NodeID     domain.NodeID     `json:"node_id"`
+	Mode       DrainMode         `json:"mode"`
+	Phase      DrainPhase        `json:"phase"`
+	Sandboxes  []SandboxEviction `json:"sandboxes"`
+	Remaining  int               `json:"remaining"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at,omitempty"`
+}
+
+func (d *NodeDrain) clone() *NodeDrain {
+	c := *d
+	c.Sandboxes = append([]SandboxEviction(nil), d.Sandboxes...)
+	return &c
+}
+
+// CordonNode stops new sandboxes from being placed on the node. Its
+// sandboxes keep running.
+func (m *Manager) CordonNode(ctx context.Context, id domain.NodeID) error {
+	if err := m.Hades.MarkDraining(ctx, id); err != nil {
+		return err
+	}
+	m.Logger.Info(ctx, "Node cordoned", map[string]any{"node_id": id})
+	m.Metrics.IncCounter("node_cordon_total", 1, hermes.Label{Key: "action", Value: "cordon"})
+	return nil
+}
+
+// UncordonNode makes the node schedulable again and cancels its drain, if
+// one is running. Sandboxes already evicted stay where they went.
+func (m *Manager) UncordonNode(ctx context.Context, id domain.NodeID) error {
+	if err := m.Hades.MarkSchedulable(ctx, id); err != nil {
+		return err
+	}
+	m.drainMu.Lock()
+	if drain, ok := m.drains[id]; ok && drain.Phase == DrainRunning {
+		drain.Phase = DrainCanceled
+		drain.FinishedAt = time.Now()
+	}
+	m.drainMu.Unlock()
+	m.Logger.Info(ctx, "Node uncordoned", map[string]any{"node_id": id})
+	m.Metrics.IncCounter("node_cordon_total", 1, hermes.Label{Key: "action", Value: "uncordon"})
+	return nil
+}
+
+// DrainNode cordons the node and evicts its sandboxes through the control
+// plane, hibernating or killing each. The drain goes on in the background
+// until the node's heartbeat reports no sandboxes or the timeout passes;
+// NodeDrainStatus reports its progress.
+func (m *Manager) DrainNode(ctx context.Context, id domain.NodeID, opts DrainOptions) (*NodeDrain, error) {
+	switch opts.Mode {
+	case "":
+		opts.Mode = DrainHibernate
+	case DrainHibernate, DrainKill:
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidDrain, opts.Mode)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultDrainTimeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultDrainPollInterval
+	}
+
+	node, err := m.Hades.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	m.drainMu.Lock()
+	if prev, ok := m.drains[id]; ok && prev.Phase == DrainRunning {
+		m.drainMu.Unlock()
+		return nil, ErrDrainInProgress
+	}
+	drain := &NodeDrain{NodeID: id, Mode: opts.Mode, Phase: DrainRunning, Sandboxes: []SandboxEviction{}, StartedAt: time.Now()}
+	if m.drains == nil {
+		m.drains = make(map[domain.NodeID]*NodeDrain)
+	}
+	m.drains[id] = drain
+	m.drainMu.Unlock()
+
+	if err := m.CordonNode(ctx, id); err != nil {
+		m.drainMu.Lock()
+		delete(m.drains, id)
+		m.drainMu.Unlock()
+		return nil, err
+	}
+
+	// The sandboxes the agent reports, or the runs Hades places on the node
+	// if its heartbeats omit them
+	sandboxes := make(map[domain.SandboxID]bool)
+	for _, run := range node.ActiveSandboxes {
+		sandboxes[run.ID] = true
+	}
+	if len(sandboxes) == 0 && !nodeEmpty(*node) {
+		runs, err := m.Hades.ListRuns(ctx)
+		if err != nil {
+			m.Logger.Error(ctx, "Failed to list runs for drain", map[string]any{"node_id": id, "error": err})
+		}
+		for _, run := range runs {
+			if run.NodeID == id && run.Status == domain.RunStatusRunning {
+				sandboxes[run.ID] = true
+			}
+		}
+	}
+	ids := make([]domain.SandboxID, 0, len(sandboxes))
+	for sandboxID := range sandboxes {
+		ids = append(ids, sandboxID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	evictions := make([]SandboxEviction, 0, len(ids))
+	for _, sandboxID := range ids {
+		evictions = append(evictions, m.evict(ctx, sandboxID, opts.Mode))
+	}
+	m.drainMu.Lock()
+	drain.Sandboxes = evictions
+	drain.Remaining = len(evictions)
+	started := drain.clone()
+	m.drainMu.Unlock()
+
+	m.Logger.Info(ctx, "Node drain started", map[string]any{"node_id": id, "mode": opts.Mode, "sandboxes": len(ids)})
+	m.Metrics.IncCounter("node_drains_total", 1, hermes.Label{Key: "result", Value: "started"})
+	if m.checkDrain(ctx, drain) {
+		return m.NodeDrainStatus(id)
+	}
+	go m.watchDrain(context.WithoutCancel(ctx), drain, opts)
+	return started, nil
+}
+
+// NodeDrainStatus returns the progress of the node's latest drain.
+func (m *Manager) NodeDrainStatus(id domain.NodeID) (*NodeDrain, error) {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	drain, ok := m.drains[id]
+	if !ok {
+		return nil, ErrDrainNotFound
+	}
+	return drain.clone(), nil
+}
+
+// evict sends the drain's command for one sandbox. A sandbox that cannot
+// be hibernated is killed instead.
+func (m *Manager) evict(ctx context.Context, id domain.SandboxID, mode DrainMode) SandboxEviction {
+	eviction := SandboxEviction{SandboxID: id, Action: mode}
+	if mode == DrainHibernate {
+		_, err := m.HibernateSandbox(ctx, id, 0)
+		if err == nil {
+			return eviction
+		}
+		m.Logger.Error(ctx, "Failed to hibernate sandbox for drain, killing it", map[string]any{"sandbox_id": id, "error": err})
+		eviction.Action = DrainKill
+	}
+	if err := m.KillSandbox(ctx, id); err != nil {
+		eviction.Error = err.Error()
+	}
+	return eviction
+}
+
+// watchDrain polls Hades until the drain completes, is canceled or times
+// out.
+func (m *Manager) watchDrain(ctx context.Context, drain *NodeDrain, opts DrainOptions) {
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if m.checkDrain(ctx, drain) {
+			return
+		}
+		if time.Now().After(deadline) {
+			m.drainMu.Lock()
+			if drain.Phase == DrainRunning {
+				drain.Phase = DrainTimedOut
+				drain.FinishedAt = time.Now()
+			}
+			remaining := drain.Remaining
+			m.drainMu.Unlock()
+			m.Logger.Error(ctx, "Node drain timed out", map[string]any{"node_id": drain.NodeID, "remaining": remaining})
+			m.Metrics.IncCounter("node_drains_total", 1, hermes.Label{Key: "result", Value: "timeout"})
+			return
+		}
+	}
+}
+
+// checkDrain marks the sandboxes gone from the node's heartbeat as done,
+// evicts sandboxes that appeared on the node since the drain began and
+// reports whether the drain is over.
+func (m *Manager) checkDrain(ctx context.Context, drain *NodeDrain) bool {
+	// A node gone from Hades took its sandboxes with it
+	active := make(map[domain.SandboxID]bool)
+	empty := true
+	if node, err := m.Hades.GetNode(ctx, drain.NodeID); err == nil {
+		for _, run := range node.ActiveSandboxes {
+			active[run.ID] = true
+		}
+		empty = nodeEmpty(*node)
+	}
+
+	m.drainMu.Lock()
+	if drain.Phase != DrainRunning {
+		m.drainMu.Unlock()
+		return true
+	}
+	known := make(map[domain.SandboxID]bool, len(drain.Sandboxes))
+	for _, eviction := range drain.Sandboxes {
+		known[eviction.SandboxID] = true
+	}
+	m.drainMu.Unlock()
+
+	var late []domain.SandboxID
+	for id := range active {
+		if !known[id] {
+			late = append(late, id)
+		}
+	}
+	sort.Slice(late, func(i, j int) bool { return late[i] < late[j] })
+	evictions := make([]SandboxEviction, 0, len(late))
+	for _, id := range late {
+		evictions = append(evictions, m.evict(ctx, id, drain.Mode))
+	}
+
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if drain.Phase != DrainRunning {
+		return true
+	}
+	drain.Sandboxes = append(drain.Sandboxes, evictions...)
+	drain.Remaining = 0
+	for i := range drain.Sandboxes {
+		// Without a sandbox list in the heartbeat only an empty node tells
+		drain.Sandboxes[i].Done = !active[drain.Sandboxes[i].SandboxID] && (len(active) > 0 || empty)
+		if !drain.Sandboxes[i].Done {
+			drain.Remaining++
+		}
+	}
+	if drain.Remaining > 0 || !empty {
+		return false
+	}
+	drain.Phase = DrainCompleted
+	drain.FinishedAt = time.Now()
+	m.Logger.Info(ctx, "Node drained", map[string]any{"node_id": drain.NodeID, "sandboxes": len(drain.Sandboxes)})
+	m.Metrics.IncCounter("node_drains_total", 1, hermes.Label{Key: "result", Value: "completed"})
+	return true
+}
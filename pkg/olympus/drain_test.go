@@ -0,0 +1,150 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+// evictionRecorder records the commands a drain sends; sandboxes in
+// noHibernate cannot be hibernated.
+type evictionRecorder struct {
+	olympus.NoopControlPlane
+	noHibernate map[domain.SandboxID]bool
+	mu          sync.Mutex
+	commands    []string
+}
+
+func (c *evictionRecorder) Hibernate(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	if c.noHibernate[sandboxID] {
+		return errors.New("hibernation disabled")
+	}
+	c.record("hibernate " + string(sandboxID))
+	return nil
+}
+
+func (c *evictionRecorder) Kill(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	c.record("kill " + string(sandboxID))
+	return nil
+}
+
+func (c *evictionRecorder) record(cmd string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, cmd)
+}
+
+func (c *evictionRecorder) Commands() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.commands...)
+}
+
+func newDrainManager(control olympus.ControlPlane) (*olympus.Manager, *hades.MemoryRegistry) {
+	registry := hades.NewMemoryRegistry()
+	return &olympus.Manager{
+		Hades:   registry,
+		Control: control,
+		Metrics: hermes.NewNoopMetrics(),
+		Logger:  &mockLogger{},
+	}, registry
+}
+
+func awaitDrain(t *testing.T, manager *olympus.Manager, id domain.NodeID, done func(*olympus.NodeDrain) bool) *olympus.NodeDrain {
+	t.Helper()
+	var drain *olympus.NodeDrain
+	require.Eventually(t, func() bool {
+		var err error
+		drain, err = manager.NodeDrainStatus(id)
+		return err == nil && done(drain)
+	}, 5*time.Second, 10*time.Millisecond)
+	return drain
+}
+
+func TestManager_DrainNode(t *testing.T) {
+	ctx := context.Background()
+	control := &evictionRecorder{noHibernate: map[domain.SandboxID]bool{"sb-2": true}}
+	manager, registry := newDrainManager(control)
+	heartbeat(t, registry, "node-1", 2000, 1024, domain.SandboxRun{ID: "sb-1"}, domain.SandboxRun{ID: "sb-2"})
+
+	drain, err := manager.DrainNode(ctx, "node-1", olympus.DrainOptions{PollInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, olympus.DrainRunning, drain.Phase)
+	assert.Equal(t, olympus.DrainHibernate, drain.Mode)
+	assert.Equal(t, 2, drain.Remaining)
+	require.Len(t, drain.Sandboxes, 2)
+	assert.Equal(t, olympus.DrainHibernate, drain.Sandboxes[0].Action)
+	assert.Equal(t, olympus.DrainKill, drain.Sandboxes[1].Action, "falls back to a kill")
+	node, err := registry.GetNode(ctx, "node-1")
+	require.NoError(t, err)
+	assert.True(t, node.Draining())
+
+	_, err = manager.DrainNode(ctx, "node-1", olympus.DrainOptions{})
+	assert.ErrorIs(t, err, olympus.ErrDrainInProgress)
+
+	// Progress follows the heartbeats; a sandbox that turns up is evicted too
+	heartbeat(t, registry, "node-1", 2000, 1024, domain.SandboxRun{ID: "sb-1"}, domain.SandboxRun{ID: "sb-3"})
+	drain = awaitDrain(t, manager, "node-1", func(d *olympus.NodeDrain) bool { return len(d.Sandboxes) == 3 })
+	assert.True(t, drain.Sandboxes[1].Done)
+	assert.Equal(t, 2, drain.Remaining)
+
+	heartbeat(t, registry, "node-1", 0, 0)
+	drain = awaitDrain(t, manager, "node-1", func(d *olympus.NodeDrain) bool { return d.Phase != olympus.DrainRunning })
+	assert.Equal(t, olympus.DrainCompleted, drain.Phase)
+	assert.Zero(t, drain.Remaining)
+	assert.False(t, drain.FinishedAt.IsZero())
+	assert.Equal(t, []string{"hibernate sb-1", "kill sb-2", "hibernate sb-3"}, control.Commands())
+}
+
+func TestManager_UncordonCancelsDrain(t *testing.T) {
+	ctx := context.Background()
+	control := &evictionRecorder{}
+	manager, registry := newDrainManager(control)
+	heartbeat(t, registry, "node-1", 1000, 512, domain.SandboxRun{ID: "sb-1"})
+
+	_, err := manager.DrainNode(ctx, "node-1", olympus.DrainOptions{Mode: olympus.DrainKill, PollInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kill sb-1"}, control.Commands())
+
+	require.NoError(t, manager.UncordonNode(ctx, "node-1"))
+	node, err := registry.GetNode(ctx, "node-1")
+	require.NoError(t, err)
+	assert.False(t, node.Draining())
+	drain, err := manager.NodeDrainStatus("node-1")
+	require.NoError(t, err)
+	assert.Equal(t, olympus.DrainCanceled, drain.Phase)
+
+	// A new drain may start once the last one is over
+	_, err = manager.DrainNode(ctx, "node-1", olympus.DrainOptions{Mode: olympus.DrainKill, PollInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+}
+
+func TestManager_DrainNodeValidation(t *testing.T) {
+	ctx := context.Background()
+	manager, registry := newDrainManager(&evictionRecorder{})
+	heartbeat(t, registry, "idle", 0, 0)
+
+	_, err := manager.DrainNode(ctx, "missing", olympus.DrainOptions{})
+	assert.ErrorIs(t, err, hades.ErrNodeNotFound)
+	_, err = manager.DrainNode(ctx, "idle", olympus.DrainOptions{Mode: "evaporate"})
+	assert.ErrorIs(t, err, olympus.ErrInvalidDrain)
+	_, err = manager.NodeDrainStatus("idle")
+	assert.ErrorIs(t, err, olympus.ErrDrainNotFound)
+	assert.ErrorIs(t, manager.CordonNode(ctx, "missing"), hades.ErrNodeNotFound)
+	assert.ErrorIs(t, manager.UncordonNode(ctx, "missing"), hades.ErrNodeNotFound)
+
+	// An empty node is drained at once
+	drain, err := manager.DrainNode(ctx, "idle", olympus.DrainOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, olympus.DrainCompleted, drain.Phase)
+	assert.Empty(t, drain.Sandboxes)
+}
@@ -0,0 +1,53 @@
+package olympus
+
+import (
+	"context"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// Sandbox lifecycle event types.
+const (
+	EventSandboxSubmitted     = "sandbox.submitted"
+	EventSandboxKillRequested = "sandbox.kill_requested"
+	EventSandboxCancelled     = "sandbox.cancelled"
+	EventSandboxBoosted       = "sandbox.boosted"
+	EventSandboxRetried       = "sandbox.retried"
+)
+
+// SandboxEvent is the payload of a sandbox lifecycle event.
+type SandboxEvent struct {
+	SandboxID domain.SandboxID  `json:"sandbox_id"`
+	Template  domain.TemplateID `json:"template"`
+	NodeID    domain.NodeID     `json:"node_id,omitempty"`
+	Status    domain.RunStatus  `json:"status"`
+	TenantID  string            `json:"tenant_id,omitempty"`
+}
+
+// SandboxLifecycle is the topic Olympus publishes sandbox lifecycle events to.
+var SandboxLifecycle = hermes.NewTypedTopic[SandboxEvent]("sandbox.lifecycle")
+
+// publishSandboxEvent publishes a lifecycle event for run if an event bus is
+// configured. Publishing is best-effort and never fails the operation.
+func (m *Manager) publishSandboxEvent(ctx context.Context, eventType string, run domain.SandboxRun) {
+	if m.Events == nil {
+		return
+	}
+	event := SandboxEvent{
+		SandboxID: run.ID,
+		Template:  run.Template,
+		NodeID:    run.NodeID,
+		Status:    run.Status,
+	}
+	if run.Submitter != nil {
+		event.TenantID = run.Submitter.TenantID
+	}
+	if err := SandboxLifecycle.Publish(ctx, m.Events, eventType, string(run.ID), event); err != nil {
+		m.Logger.Error(ctx, "Failed to publish sandbox event", map[string]any{
+			"sandbox_id": run.ID,
+			"type":       eventType,
+			"error":      err,
+		})
+	}
+}
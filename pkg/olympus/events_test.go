@@ -0,0 +1,53 @@
+package olympus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+func TestManager_PublishesLifecycleEvents(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{
+		ID:        "sb-1",
+		Template:  "python",
+		NodeID:    "node-1",
+		Status:    domain.RunStatusRunning,
+		Submitter: &domain.Submitter{ID: "alice", TenantID: "acme"},
+	}))
+
+	bus := hermes.NewMemoryEventBus(nil)
+	defer bus.Close()
+	events := make(chan SandboxEvent, 1)
+	sub, err := SandboxLifecycle.Subscribe(ctx, bus, "", func(ctx context.Context, ev hermes.Event, payload SandboxEvent) error {
+		assert.Equal(t, EventSandboxKillRequested, ev.Type)
+		assert.Equal(t, "sb-1", ev.Subject)
+		events <- payload
+		return nil
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	m := &Manager{
+		Hades:   registry,
+		Control: &killRecordingControl{},
+		Events:  bus,
+		Metrics: hermes.NewNoopMetrics(),
+		Logger:  hermes.NewNoopLogger(),
+	}
+	require.NoError(t, m.KillSandbox(ctx, "sb-1"))
+
+	select {
+	case ev := <-events:
+		assert.Equal(t, SandboxEvent{SandboxID: "sb-1", Template: "python", NodeID: "node-1", Status: domain.RunStatusRunning, TenantID: "acme"}, ev)
+	case <-time.After(time.Second):
+		t.Fatal("no lifecycle event published")
+	}
+}
@@ -0,0 +1,135 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ExecHandlers serves interactive exec sessions over WebSocket
+type ExecHandlers struct {
+	manager  *Manager
+	upgrader websocket.Upgrader
+}
+
+// NewExecHandlers creates exec handlers accepting WebSocket connections from
+// the origins checkOrigin allows
+func NewExecHandlers(manager *Manager, checkOrigin func(r *http.Request) bool) *ExecHandlers {
+	return &ExecHandlers{
+		manager:  manager,
+		upgrader: websocket.Upgrader{CheckOrigin: checkOrigin},
+	}
+}
+
+// HandleExecSession upgrades the request to a WebSocket and runs the command
+// given by the repeated cmd query parameter (default sh) in the sandbox,
+// like kubectl exec -it. Every binary message is one frame whose first byte
+// is its domain.ExecChannel: clients send stdin and close frames, and receive
+// stdout and stderr frames, then a status frame with the exit code before
+// the server closes the connection. Closing the connection early kills the
+// command.
+func (h *ExecHandlers) HandleExecSession(w http.ResponseWriter, r *http.Request, id domain.SandboxID) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cmd := r.URL.Query()["cmd"]
+	if len(cmd) == 0 {
+		cmd = []string{"sh"}
+	}
+
+	// Refuse before upgrading, so clients get a plain HTTP status
+	run, err := h.manager.Hades.GetRun(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Sandbox not found", http.StatusNotFound)
+		return
+	}
+	if run.Status != domain.RunStatusRunning {
+		http.Error(w, ErrSandboxNotRunning.Error()+": "+string(run.Status), http.StatusConflict)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	out := &execFrameWriter{conn: conn}
+	stdinR, stdinW := io.Pipe()
+	go func() {
+		defer stdinW.Close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				// The client went away: kill the command
+				cancel()
+				return
+			}
+			channel, data, err := domain.ParseExecFrame(message)
+			if err != nil {
+				continue
+			}
+			switch channel {
+			case domain.ExecStdin:
+				// Input after the command closed its stdin is dropped
+				stdinW.Write(data)
+			case domain.ExecClose:
+				stdinW.Close()
+			case domain.ExecCancel:
+				cancel()
+				return
+			}
+		}
+	}()
+
+	err = h.manager.ExecInteractive(ctx, id, cmd, stdinR, out.channel(domain.ExecStdout), out.channel(domain.ExecStderr))
+	stdinR.Close()
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return
+	}
+
+	out.write(domain.ExecResultOf(err).StatusFrame())
+	out.mu.Lock()
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	out.mu.Unlock()
+}
+
+// execFrameWriter sends frames over a WebSocket, one writer at a time.
+type execFrameWriter struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (f *execFrameWriter) write(frame []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conn.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+// channel returns a writer sending each write as a frame of channel.
+func (f *execFrameWriter) channel(channel domain.ExecChannel) io.Writer {
+	return execChannelWriter{f, channel}
+}
+
+type execChannelWriter struct {
+	frames  *execFrameWriter
+	channel domain.ExecChannel
+}
+
+func (w execChannelWriter) Write(p []byte) (int, error) {
+	if err := w.frames.write(domain.ExecFrame(w.channel, p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
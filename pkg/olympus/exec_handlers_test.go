@@ -0,0 +1,100 @@
+package olympus_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+// echoControl runs every command as cat, writing the command to stderr
+// first, and exits 7.
+type echoControl struct {
+	olympus.NoopControlPlane
+}
+
+func (c *echoControl) ExecInteractive(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	io.WriteString(stderr, strings.Join(cmd, " "))
+	if _, err := io.Copy(stdout, stdin); err != nil {
+		return err
+	}
+	return &domain.ExecExitError{Code: 7}
+}
+
+func TestExecHandlers_HandleExecSession(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-1", NodeID: "node-1", Status: domain.RunStatusRunning}))
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-2", NodeID: "node-1", Status: domain.RunStatusSucceeded}))
+
+	manager := &olympus.Manager{
+		Hades:   registry,
+		Control: &echoControl{},
+		Metrics: hermes.NewNoopMetrics(),
+		Logger:  &mockLogger{},
+	}
+	handlers := olympus.NewExecHandlers(manager, func(r *http.Request) bool { return true })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/sandboxes/"), "/exec")
+		handlers.HandleExecSession(w, r, domain.SandboxID(id))
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	t.Run("Session", func(t *testing.T) {
+		c, _, err := websocket.DefaultDialer.Dial(wsURL+"/sandboxes/sb-1/exec?cmd=cat&cmd=-u", nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		require.NoError(t, c.WriteMessage(websocket.BinaryMessage, domain.ExecFrame(domain.ExecStdin, []byte("hello"))))
+		require.NoError(t, c.WriteMessage(websocket.BinaryMessage, domain.ExecFrame(domain.ExecClose, nil)))
+
+		var stdout, stderr string
+		var result domain.ExecResult
+		for {
+			_, message, err := c.ReadMessage()
+			require.NoError(t, err)
+			channel, data, err := domain.ParseExecFrame(message)
+			require.NoError(t, err)
+			if channel == domain.ExecStatus {
+				require.NoError(t, json.Unmarshal(data, &result))
+				break
+			}
+			switch channel {
+			case domain.ExecStdout:
+				stdout += string(data)
+			case domain.ExecStderr:
+				stderr += string(data)
+			}
+		}
+		assert.Equal(t, "hello", stdout)
+		assert.Equal(t, "cat -u", stderr)
+		assert.Equal(t, domain.ExecResult{ExitCode: 7}, result)
+
+		_, _, err = c.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+	})
+
+	t.Run("NotRunning", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+"/sandboxes/sb-2/exec", nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+"/sandboxes/missing/exec", nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
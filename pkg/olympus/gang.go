@@ -0,0 +1,354 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+// GangSubmission is the outcome of an accepted gang.
+type GangSubmission struct {
+	ID      string
+	Members []domain.SandboxID // In the order of the submitted requests
+	Queued  bool               // No room for the whole gang yet; ScheduleGangs places it once there is
+}
+
+// SubmitGang submits reqs as a gang: sandboxes that are placed together or
+// not at all. Every member is validated, checked against quota and judged
+// as by Submit; if any is rejected the whole gang is, with an error naming
+// the member. The members are then placed atomically against one listing
+// of the nodes. If they do not all fit, none is enqueued: the gang waits,
+// its runs PENDING, until ScheduleGangs finds room for all of them.
+//
+// Each member carries a domain.GangSpec to its agent. A member that fails to
+// launch is not retried alone; ScheduleGangs rolls back the rest of its gang.
+func (m *Manager) SubmitGang(ctx context.Context, reqs []*domain.SandboxRequest) (*GangSubmission, error) {
+	if err := validateGang(reqs); err != nil {
+		m.Metrics.IncCounter("sandbox_gang_submissions_total", 1, hermes.Label{Key: "result", Value: "invalid"})
+		return nil, err
+	}
+
+	gang := domain.GangSpec{
+		ID:      uuid.New().String(),
+		Size:    len(reqs),
+		Members: make([]domain.SandboxID, len(reqs)),
+	}
+	for i, req := range reqs {
+		if req.ID == "" {
+			req.ID = domain.SandboxID(uuid.New().String())
+		}
+		gang.Members[i] = req.ID
+	}
+
+	// Record who submitted the requests; never trust a client-supplied value
+	submitter := submitterFromContext(ctx)
+
+	admitted := make([]*admission, 0, len(reqs))
+	// With an outbox, runs are only recorded once placed, so the quota of
+	// later members must count the earlier ones
+	var held domain.ResourceQuota
+	for i, req := range reqs {
+		spec := gang
+		spec.Index = i
+		req.Gang = &spec
+		req.Submitter = submitter
+
+		a, err := m.admit(ctx, req, nil, held)
+		if err != nil {
+			m.rollbackGang(ctx, admittedRuns(admitted), fmt.Sprintf("member %s was rejected", req.ID))
+			m.Metrics.IncCounter("sandbox_gang_submissions_total", 1, hermes.Label{Key: "result", Value: "rejected"})
+			return nil, fmt.Errorf("gang member %d: %w", i, err)
+		}
+		// A member retried alone could never start with its gang
+		a.run.Retry = nil
+		a.run.Gang = &domain.RunGang{GangSpec: spec}
+		admitted = append(admitted, a)
+		if m.Outbox != nil {
+			held.Sandboxes++
+			held.CPU += req.Resources.CPU
+			held.Mem += req.Resources.Mem
+			held.GPU += req.Resources.GPU.Count
+		}
+	}
+
+	nodes, err := m.listNodes(ctx, admitted...)
+	if err != nil {
+		m.Metrics.IncCounter("sandbox_gang_submissions_total", 1, hermes.Label{Key: "result", Value: "error"})
+		return nil, err
+	}
+
+	submission := &GangSubmission{ID: gang.ID, Members: gang.Members}
+	err = m.placeGang(ctx, admitted, nodes)
+	switch {
+	case err == nil:
+		m.Metrics.IncCounter("sandbox_gang_submissions_total", 1, hermes.Label{Key: "result", Value: "placed"})
+	case errors.Is(err, moirai.ErrNoCapacity):
+		if err := m.holdGang(ctx, admitted); err != nil {
+			m.Metrics.IncCounter("sandbox_gang_submissions_total", 1, hermes.Label{Key: "result", Value: "error"})
+			return nil, err
+		}
+		submission.Queued = true
+		m.Metrics.IncCounter("sandbox_gang_submissions_total", 1, hermes.Label{Key: "result", Value: "queued"})
+	default:
+		m.Metrics.IncCounter("sandbox_gang_submissions_total", 1, hermes.Label{Key: "result", Value: "error"})
+		return nil, err
+	}
+
+	m.Logger.Info(ctx, "Gang submitted", map[string]any{
+		"gang_id": gang.ID,
+		"size":    gang.Size,
+		"queued":  submission.Queued,
+	})
+	return submission, nil
+}
+
+// validateGang checks the size of a gang and that its members can start
+// together.
+func validateGang(reqs []*domain.SandboxRequest) error {
+	if len(reqs) == 0 {
+		return fmt.Errorf("%w: no requests", domain.ErrInvalidGang)
+	}
+	if len(reqs) > domain.MaxGangSize {
+		return fmt.Errorf("%w: %d requests, at most %d allowed", domain.ErrInvalidGang, len(reqs), domain.MaxGangSize)
+	}
+	seen := make(map[domain.SandboxID]bool, len(reqs))
+	for i, req := range reqs {
+		switch {
+		case req == nil:
+			return fmt.Errorf("%w: member %d is null", domain.ErrInvalidGang, i)
+		case req.Window != nil:
+			return fmt.Errorf("%w: member %d has a run window; gang members start together", domain.ErrInvalidGang, i)
+		case req.ID != "" && seen[req.ID]:
+			return fmt.Errorf("%w: duplicate id %s", domain.ErrInvalidGang, req.ID)
+		}
+		seen[req.ID] = true
+	}
+	return nil
+}
+
+// placeGang places the admitted members of a gang on nodes, all or none, and
+// enqueues them. If a member cannot be enqueued, the members already
+// enqueued are rolled back.
+func (m *Manager) placeGang(ctx context.Context, admitted []*admission, nodes []domain.NodeStatus) error {
+	reqs := make([]*domain.SandboxRequest, len(admitted))
+	candidates := make([][]domain.NodeStatus, len(admitted))
+	for i, a := range admitted {
+		reqs[i] = a.req
+		candidates[i] = candidateNodes(ctx, a, nodes)
+	}
+	placement, err := moirai.ChooseGang(ctx, m.Scheduler, reqs, candidates)
+	if err != nil {
+		return err
+	}
+
+	for i, a := range admitted {
+		a.run.Gang.Request = nil
+		if err := m.dispatch(ctx, a, placement[i], candidates[i]); err != nil {
+			rest := append(admittedRuns(admitted[:i]), admittedRuns(admitted[i+1:])...)
+			m.rollbackGang(ctx, rest, fmt.Sprintf("member %s could not be enqueued", a.req.ID))
+			return err
+		}
+	}
+	return nil
+}
+
+// holdGang records the runs of a gang there is no room for yet as PENDING,
+// with the requests ScheduleGangs needs to place them later.
+func (m *Manager) holdGang(ctx context.Context, admitted []*admission) error {
+	for i, a := range admitted {
+		a.run.Gang.Request = cloneRequest(a.req)
+		a.run.UpdatedAt = time.Now()
+		if err := m.Hades.UpdateRun(ctx, a.run); err != nil {
+			m.rollbackGang(ctx, admittedRuns(admitted[:i]), fmt.Sprintf("member %s could not be queued", a.req.ID))
+			return fmt.Errorf("failed to persist run state: %w", err)
+		}
+	}
+	m.Logger.Info(ctx, "Gang waiting for capacity", map[string]any{
+		"gang_id": admitted[0].run.Gang.ID,
+		"size":    len(admitted),
+	})
+	return nil
+}
+
+func admittedRuns(admitted []*admission) []domain.SandboxRun {
+	runs := make([]domain.SandboxRun, len(admitted))
+	for i, a := range admitted {
+		runs[i] = a.run
+	}
+	return runs
+}
+
+// rollbackGang cancels the members of a gang that have not finished, since
+// they cannot run without the rest: queued requests are withdrawn and
+// running sandboxes killed. cause says what broke the gang.
+func (m *Manager) rollbackGang(ctx context.Context, members []domain.SandboxRun, cause string) {
+	now := time.Now()
+	reorderer, _ := m.Queue.(acheron.Reorderer)
+	cancelled := 0
+	for _, run := range members {
+		if run.Status.IsTerminal() {
+			continue
+		}
+		switch {
+		case run.Status == domain.RunStatusRunning:
+			if err := m.Control.Kill(ctx, run.NodeID, run.ID); err != nil {
+				m.Logger.Error(ctx, "Failed to kill gang member", map[string]any{"sandbox_id": run.ID, "node_id": run.NodeID, "error": err})
+			}
+		case run.NodeID != "" && reorderer != nil:
+			// An agent that already received it reports it RUNNING, and the
+			// next sweep kills it
+			_ = reorderer.Cancel(ctx, &domain.SandboxRequest{ID: run.ID, NodeID: run.NodeID})
+			if m.Outbox != nil {
+				_ = m.Outbox.MarkDelivered(ctx, run.ID)
+			}
+		}
+
+		run.Status = domain.RunStatusCanceled
+		run.Error = "gang rolled back: " + cause
+		if run.Gang != nil {
+			run.Error = fmt.Sprintf("gang %s rolled back: %s", run.Gang.ID, cause)
+			run.Gang.Request = nil
+		}
+		run.FinishedAt = now
+		run.UpdatedAt = now
+		if err := m.Hades.UpdateRun(ctx, run); err != nil {
+			m.Logger.Error(ctx, "Failed to cancel gang member", map[string]any{"sandbox_id": run.ID, "error": err})
+			continue
+		}
+		cancelled++
+		m.Logger.Info(ctx, "Gang member cancelled", map[string]any{"sandbox_id": run.ID, "cause": cause})
+		m.publishSandboxEvent(ctx, EventSandboxCancelled, run)
+	}
+	if cancelled > 0 {
+		m.Metrics.IncCounter("sandbox_gang_rollbacks_total", 1)
+	}
+}
+
+// ScheduleGangs places the gangs waiting for capacity whose members all fit
+// now, and rolls back gangs a member of which finished without starting,
+// e.g. because its launch failed. It returns the number of gangs it placed.
+func (m *Manager) ScheduleGangs(ctx context.Context) (int, error) {
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list runs: %w", err)
+	}
+	gangs := make(map[string][]domain.SandboxRun)
+	for _, run := range runs {
+		if run.Gang != nil {
+			gangs[run.Gang.ID] = append(gangs[run.Gang.ID], run)
+		}
+	}
+
+	var nodes []domain.NodeStatus
+	placed, waiting := 0, 0
+	for id, members := range gangs {
+		sort.Slice(members, func(i, j int) bool { return members[i].Gang.Index < members[j].Gang.Index })
+
+		if cause := brokenGang(members); cause != "" {
+			if active(members) {
+				m.rollbackGang(ctx, members, cause)
+			}
+			continue
+		}
+		if !queuedGang(members) {
+			continue
+		}
+
+		if nodes == nil {
+			if nodes, err = m.Hades.ListNodes(ctx); err != nil {
+				return placed, fmt.Errorf("failed to list nodes: %w", err)
+			}
+		}
+		err := m.placeQueuedGang(ctx, members, nodes)
+		switch {
+		case err == nil:
+			placed++
+			m.Logger.Info(ctx, "Placed waiting gang", map[string]any{"gang_id": id, "size": len(members)})
+		case errors.Is(err, moirai.ErrNoCapacity):
+			waiting++
+		default:
+			m.Logger.Error(ctx, "Failed to place waiting gang", map[string]any{"gang_id": id, "error": err})
+		}
+	}
+	m.Metrics.SetGauge("sandbox_gangs_waiting", float64(waiting))
+	return placed, nil
+}
+
+// brokenGang returns why a gang cannot run, or "" if it still can: some
+// member finished without having started.
+func brokenGang(members []domain.SandboxRun) string {
+	for _, run := range members {
+		if run.Status.IsTerminal() && run.StartedAt.IsZero() {
+			return fmt.Sprintf("member %s ended %s before starting", run.ID, run.Status)
+		}
+	}
+	return ""
+}
+
+func active(members []domain.SandboxRun) bool {
+	for _, run := range members {
+		if !run.Status.IsTerminal() {
+			return true
+		}
+	}
+	return false
+}
+
+// queuedGang reports whether all members of a gang are recorded and waiting
+// for capacity.
+func queuedGang(members []domain.SandboxRun) bool {
+	if len(members) != members[0].Gang.Size {
+		return false
+	}
+	for _, run := range members {
+		if run.Status != domain.RunStatusPending || run.Gang.Request == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// placeQueuedGang places a gang that was waiting for capacity. If a member's
+// template is gone, the gang fails.
+func (m *Manager) placeQueuedGang(ctx context.Context, members []domain.SandboxRun, nodes []domain.NodeStatus) error {
+	admitted := make([]*admission, len(members))
+	for i, run := range members {
+		req := cloneRequest(run.Gang.Request)
+		if req == nil {
+			return fmt.Errorf("request of member %s was not recorded", run.ID)
+		}
+		tmpl, err := m.Templates.GetTemplate(ctx, req.Template)
+		if err != nil {
+			rest := append(members[:i:i], members[i+1:]...)
+			m.rollbackGang(ctx, rest, fmt.Sprintf("template %s of member %s is gone", req.Template, run.ID))
+			return m.schedulingFailed(ctx, &admission{req: req, run: run}, fmt.Errorf("invalid template: %w", err))
+		}
+		admitted[i] = &admission{req: req, tmpl: tmpl, run: run}
+	}
+	return m.placeGang(ctx, admitted, nodes)
+}
+
+// RunGangScheduler periodically calls ScheduleGangs until ctx is cancelled.
+func (m *Manager) RunGangScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.ScheduleGangs(ctx); err != nil {
+				m.Logger.Error(ctx, "Gang scheduler failed", map[string]any{"error": err})
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+func gangRequests(mems ...domain.Megabytes) []*domain.SandboxRequest {
+	reqs := make([]*domain.SandboxRequest, len(mems))
+	for i, mem := range mems {
+		reqs[i] = &domain.SandboxRequest{Template: "tpl", Resources: domain.ResourceSpec{CPU: 1000, Mem: mem}}
+	}
+	return reqs
+}
+
+func TestSubmitGang_PlacesAllMembers(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	reqs := gangRequests(4096, 4096, 4096)
+	gang, err := manager.SubmitGang(ctx, reqs)
+	if err != nil {
+		t.Fatalf("SubmitGang: %v", err)
+	}
+	if gang.Queued || len(gang.Members) != 3 {
+		t.Fatalf("unexpected submission: %+v", gang)
+	}
+	for i, id := range gang.Members {
+		run, err := registry.GetRun(ctx, id)
+		if err != nil {
+			t.Fatalf("GetRun(%s): %v", id, err)
+		}
+		if run.Status != domain.RunStatusScheduled || run.NodeID != "node-1" {
+			t.Errorf("member %d: %s on %q, want SCHEDULED on node-1", i, run.Status, run.NodeID)
+		}
+		if run.Gang == nil || run.Gang.ID != gang.ID || run.Gang.Index != i || run.Gang.Request != nil {
+			t.Errorf("member %d: unexpected gang %+v", i, run.Gang)
+		}
+	}
+
+	// Agents receive the gang with each member
+	req, _, err := queue.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if req.Gang == nil || req.Gang.ID != gang.ID || req.Gang.Size != 3 || len(req.Gang.Members) != 3 {
+		t.Errorf("unexpected gang on queued request: %+v", req.Gang)
+	}
+}
+
+func TestSubmitGang_WaitsForCapacity(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	// node-1 fits two of the three members: none may start
+	gang, err := manager.SubmitGang(ctx, gangRequests(8192, 8192, 8192))
+	if err != nil {
+		t.Fatalf("SubmitGang: %v", err)
+	}
+	if !gang.Queued {
+		t.Fatal("expected the gang to wait for capacity")
+	}
+	if n := queue.Len(ctx); n != 0 {
+		t.Fatalf("queue holds %d requests, want 0", n)
+	}
+	for _, id := range gang.Members {
+		run, _ := registry.GetRun(ctx, id)
+		if run.Status != domain.RunStatusPending || run.Gang.Request == nil {
+			t.Fatalf("member %s: %s, want PENDING with its request kept", id, run.Status)
+		}
+	}
+
+	if placed, err := manager.ScheduleGangs(ctx); err != nil || placed != 0 {
+		t.Fatalf("ScheduleGangs placed %d (%v), want 0", placed, err)
+	}
+
+	registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "node-2", Capacity: domain.ResourceCapacity{CPU: 8000, Mem: 16384}},
+		Time: time.Now(),
+	})
+	if placed, err := manager.ScheduleGangs(ctx); err != nil || placed != 1 {
+		t.Fatalf("ScheduleGangs placed %d (%v), want 1", placed, err)
+	}
+	if n := queue.Len(ctx); n != 3 {
+		t.Errorf("queue holds %d requests, want 3", n)
+	}
+	for _, id := range gang.Members {
+		run, _ := registry.GetRun(ctx, id)
+		if run.Status != domain.RunStatusScheduled || run.Gang.Request != nil {
+			t.Errorf("member %s: %s, want SCHEDULED", id, run.Status)
+		}
+	}
+}
+
+func TestScheduleGangs_RollsBackPartialLaunch(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	gang, err := manager.SubmitGang(ctx, gangRequests(1024, 1024, 1024))
+	if err != nil {
+		t.Fatalf("SubmitGang: %v", err)
+	}
+
+	// Member 0 failed to launch, member 1 started, member 2 is still queued
+	now := time.Now()
+	failed, _ := registry.GetRun(ctx, gang.Members[0])
+	failed.Status = domain.RunStatusFailed
+	failed.Error = "failed to launch"
+	registry.UpdateRun(ctx, *failed)
+	running, _ := registry.GetRun(ctx, gang.Members[1])
+	running.Status = domain.RunStatusRunning
+	running.StartedAt = now
+	registry.UpdateRun(ctx, *running)
+	for range 2 {
+		_, receipt, _ := queue.Dequeue(ctx)
+		queue.Ack(ctx, receipt)
+	}
+
+	if _, err := manager.ScheduleGangs(ctx); err != nil {
+		t.Fatalf("ScheduleGangs: %v", err)
+	}
+	for _, id := range gang.Members[1:] {
+		run, _ := registry.GetRun(ctx, id)
+		if run.Status != domain.RunStatusCanceled || !strings.Contains(run.Error, gang.ID) {
+			t.Errorf("member %s: %s %q, want CANCELED by the gang", id, run.Status, run.Error)
+		}
+	}
+	if n := queue.Len(ctx); n != 0 {
+		t.Errorf("queue holds %d requests, want the last member withdrawn", n)
+	}
+}
+
+func TestSubmitGang_MemberRejected(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	reqs := gangRequests(1024, 1024)
+	reqs[1].Template = "missing"
+	if _, err := manager.SubmitGang(ctx, reqs); err == nil || !strings.Contains(err.Error(), "gang member 1") {
+		t.Fatalf("expected member 1 to be rejected, got %v", err)
+	}
+	run, err := registry.GetRun(ctx, reqs[0].ID)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if run.Status != domain.RunStatusCanceled {
+		t.Errorf("admitted member is %s, want CANCELED", run.Status)
+	}
+	if n := queue.Len(ctx); n != 0 {
+		t.Errorf("queue holds %d requests, want 0", n)
+	}
+
+	windowed := gangRequests(1024)
+	windowed[0].Window = &domain.RunWindow{NotBefore: time.Now().Add(time.Hour)}
+	if _, err := manager.SubmitGang(ctx, windowed); !errors.Is(err, domain.ErrInvalidGang) {
+		t.Errorf("expected ErrInvalidGang for a member with a run window, got %v", err)
+	}
+}
+
+func TestScheduleGangs_CancelWaitingMember(t *testing.T) {
+	ctx := context.Background()
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	gang, err := manager.SubmitGang(ctx, gangRequests(8192, 8192, 8192))
+	if err != nil || !gang.Queued {
+		t.Fatalf("SubmitGang: %+v, %v", gang, err)
+	}
+	if _, err := manager.CancelQueued(ctx, gang.Members[0], "not needed"); err != nil {
+		t.Fatalf("CancelQueued: %v", err)
+	}
+	if _, err := manager.ScheduleGangs(ctx); err != nil {
+		t.Fatalf("ScheduleGangs: %v", err)
+	}
+	for _, id := range gang.Members {
+		run, _ := registry.GetRun(ctx, id)
+		if run.Status != domain.RunStatusCanceled || run.Gang.Request != nil {
+			t.Errorf("member %s: %s, want CANCELED", id, run.Status)
+		}
+	}
+}
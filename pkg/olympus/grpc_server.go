@@ -0,0 +1,461 @@
+package olympus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	olympusv1 "github.com/tartarus-sandbox/tartarus/api/olympus/v1"
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServer serves the Olympus gRPC API from the manager, with the same
+// semantics as the HTTP handlers.
+type GRPCServer struct {
+	olympusv1.UnimplementedOlympusServer
+	manager *Manager
+}
+
+// NewGRPCServer creates the gRPC API of manager.
+func NewGRPCServer(manager *Manager) *GRPCServer {
+	return &GRPCServer{manager: manager}
+}
+
+func (s *GRPCServer) Submit(ctx context.Context, in *olympusv1.SubmitRequest) (*olympusv1.SubmitResponse, error) {
+	req := submitRequestFromProto(in)
+	if err := s.manager.Submit(ctx, req); err != nil {
+		return nil, s.error(ctx, "Failed to submit request", err)
+	}
+	return &olympusv1.SubmitResponse{Id: string(req.ID), Status: "accepted"}, nil
+}
+
+func (s *GRPCServer) Get(ctx context.Context, in *olympusv1.GetRequest) (*olympusv1.Sandbox, error) {
+	run, err := s.manager.Hades.GetRun(ctx, domain.SandboxID(in.GetId()))
+	if err != nil {
+		return nil, s.error(ctx, "Failed to get sandbox", err)
+	}
+	return sandboxToProto(run), nil
+}
+
+func (s *GRPCServer) List(ctx context.Context, in *olympusv1.ListRequest) (*olympusv1.ListResponse, error) {
+	runs, err := s.manager.ListSandboxesFiltered(ctx, RunFilter{SubmitterID: in.GetSubmitter(), TenantID: in.GetTenant()})
+	if err != nil {
+		return nil, s.error(ctx, "Failed to list sandboxes", err)
+	}
+	out := &olympusv1.ListResponse{Sandboxes: make([]*olympusv1.Sandbox, 0, len(runs))}
+	for i := range runs {
+		out.Sandboxes = append(out.Sandboxes, sandboxToProto(&runs[i]))
+	}
+	return out, nil
+}
+
+func (s *GRPCServer) Kill(ctx context.Context, in *olympusv1.KillRequest) (*olympusv1.KillResponse, error) {
+	if err := s.manager.KillSandbox(ctx, domain.SandboxID(in.GetId())); err != nil {
+		return nil, s.error(ctx, "Failed to kill sandbox", err)
+	}
+	return &olympusv1.KillResponse{Id: in.GetId(), Status: "killed"}, nil
+}
+
+func (s *GRPCServer) Snapshot(ctx context.Context, in *olympusv1.SnapshotRequest) (*olympusv1.SnapshotResponse, error) {
+	if err := s.manager.CreateSnapshot(ctx, domain.SandboxID(in.GetId())); err != nil {
+		return nil, s.error(ctx, "Failed to create snapshot", err)
+	}
+	return &olympusv1.SnapshotResponse{Id: in.GetId(), Status: "snapshot_requested"}, nil
+}
+
+func (s *GRPCServer) StreamLogs(in *olympusv1.StreamLogsRequest, stream olympusv1.Olympus_StreamLogsServer) error {
+	ctx := stream.Context()
+	w := writerFunc(func(p []byte) error {
+		return stream.Send(&olympusv1.LogChunk{Data: p})
+	})
+	if err := s.manager.StreamLogs(ctx, domain.SandboxID(in.GetId()), w, in.GetFollow()); err != nil {
+		return s.error(ctx, "Failed to stream logs", err)
+	}
+	return nil
+}
+
+func (s *GRPCServer) Exec(ctx context.Context, in *olympusv1.ExecRequest) (*olympusv1.ExecResponse, error) {
+	if err := s.manager.Exec(ctx, domain.SandboxID(in.GetId()), in.GetCmd()); err != nil {
+		return nil, s.error(ctx, "Failed to exec", err)
+	}
+	return &olympusv1.ExecResponse{}, nil
+}
+
+func (s *GRPCServer) ExecInteractive(stream olympusv1.Olympus_ExecInteractiveServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	start := first.GetStart()
+	if start == nil {
+		return status.Error(codes.InvalidArgument, "first message must be start")
+	}
+	cmd := start.GetCmd()
+	if len(cmd) == 0 {
+		cmd = []string{"sh"}
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var sendMu sync.Mutex
+	send := func(out *olympusv1.ExecOutput) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(out)
+	}
+
+	stdinR, stdinW := io.Pipe()
+	go func() {
+		defer stdinW.Close()
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				// Half-closing the call only closes the command's input
+				if err != io.EOF {
+					cancel()
+				}
+				return
+			}
+			switch input := in.Input.(type) {
+			case *olympusv1.ExecInput_Stdin:
+				// Input after the command closed its stdin is dropped
+				stdinW.Write(input.Stdin)
+			case *olympusv1.ExecInput_CloseStdin:
+				if input.CloseStdin {
+					stdinW.Close()
+				}
+			}
+		}
+	}()
+
+	stdout := writerFunc(func(p []byte) error {
+		return send(&olympusv1.ExecOutput{Output: &olympusv1.ExecOutput_Stdout{Stdout: p}})
+	})
+	stderr := writerFunc(func(p []byte) error {
+		return send(&olympusv1.ExecOutput{Output: &olympusv1.ExecOutput_Stderr{Stderr: p}})
+	})
+	err = s.manager.ExecInteractive(ctx, domain.SandboxID(start.GetId()), cmd, stdinR, stdout, stderr)
+	stdinR.Close()
+	if err := stream.Context().Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if errors.Is(err, ErrSandboxNotFound) || errors.Is(err, ErrSandboxNotRunning) {
+		return s.error(ctx, "Failed to exec", err)
+	}
+
+	result := domain.ExecResultOf(err)
+	return send(&olympusv1.ExecOutput{Output: &olympusv1.ExecOutput_Exit{Exit: &olympusv1.ExecExit{
+		ExitCode: int32(result.ExitCode),
+		Error:    result.Error,
+	}}})
+}
+
+// error converts a manager error to a gRPC status, logging unexpected ones.
+func (s *GRPCServer) error(ctx context.Context, msg string, err error) error {
+	code := grpcCode(err)
+	if code == codes.Internal {
+		s.manager.Logger.Error(ctx, msg, map[string]any{"error": err})
+		return status.Error(codes.Internal, "internal error")
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcCode maps errors to the gRPC equivalents of the HTTP API's statuses.
+func grpcCode(err error) codes.Code {
+	var purchase *PurchaseRequiredError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, ErrSandboxNotFound), errors.Is(err, hades.ErrRunNotFound):
+		return codes.NotFound
+	case errors.As(err, &purchase), errors.Is(err, ErrPolicyRejected):
+		return codes.PermissionDenied
+	case errors.Is(err, ErrQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, domain.ErrInvalidRunWindow), errors.Is(err, domain.ErrInvalidSecretRef),
+		errors.Is(err, domain.ErrInvalidRetryPolicy), errors.Is(err, domain.ErrInvalidPriority),
+		errors.Is(err, domain.ErrInvalidPlacement), errors.Is(err, domain.ErrInvalidScript), errors.Is(err, ErrUnsupportedArch):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrRunWindowExpired), errors.Is(err, ErrSandboxNotRunning):
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
+
+func submitRequestFromProto(in *olympusv1.SubmitRequest) *domain.SandboxRequest {
+	req := &domain.SandboxRequest{
+		ID:       domain.SandboxID(in.GetId()),
+		Template: domain.TemplateID(in.GetTemplate()),
+		Command:  in.GetCommand(),
+		Args:     in.GetArgs(),
+		Env:      in.GetEnv(),
+		Metadata: in.GetMetadata(),
+		Secrets:  in.GetSecrets(),
+		Hardened: in.GetHardened(),
+		Arch:     in.GetArch(),
+	}
+	if r := in.GetResources(); r != nil {
+		req.Resources = domain.ResourceSpec{
+			CPU:     domain.MilliCPU(r.GetCpuMilli()),
+			Mem:     domain.Megabytes(r.GetMemMb()),
+			GPU:     domain.GPURequest{Count: int(r.GetGpu().GetCount()), Type: r.GetGpu().GetType()},
+			TTL:     r.GetTtl().AsDuration(),
+			Profile: r.GetProfile(),
+		}
+	}
+	return req
+}
+
+func sandboxToProto(run *domain.SandboxRun) *olympusv1.Sandbox {
+	out := &olympusv1.Sandbox{
+		Id:              string(run.ID),
+		RequestId:       string(run.RequestID),
+		NodeId:          string(run.NodeID),
+		Template:        string(run.Template),
+		Status:          string(run.Status),
+		Error:           run.Error,
+		StartedAt:       timestampToProto(run.StartedAt),
+		FinishedAt:      timestampToProto(run.FinishedAt),
+		CreatedAt:       timestampToProto(run.CreatedAt),
+		UpdatedAt:       timestampToProto(run.UpdatedAt),
+		Metadata:        run.Metadata,
+		DisplayName:     run.DisplayName,
+		Labels:          run.Labels,
+		ResourceVersion: run.ResourceVersion,
+	}
+	if run.ExitCode != nil {
+		code := int32(*run.ExitCode)
+		out.ExitCode = &code
+	}
+	if r := run.Resources; r != nil {
+		out.Resources = &olympusv1.Resources{
+			CpuMilli: int64(r.CPU),
+			MemMb:    int64(r.Mem),
+			Gpu:      &olympusv1.GPU{Count: int32(r.GPU.Count), Type: r.GPU.Type},
+			Ttl:      durationpb.New(r.TTL),
+			Profile:  r.Profile,
+		}
+	}
+	return out
+}
+
+// timestampToProto leaves unset times unset.
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// writerFunc adapts a send function to an io.Writer.
+type writerFunc func(p []byte) error
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	if err := f(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// GRPCAuthInterceptors authorize every call with the HTTP middleware that
+// guards the HTTP API, such as the Cerberus middleware's Wrap, as the HTTP
+// request the call corresponds to. Identities, RBAC rules, session limits
+// and audit are therefore shared by both APIs. Streaming calls are
+// authorized on their first message.
+func GRPCAuthInterceptors(auth func(http.Handler) http.Handler) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	authorize := func(ctx context.Context, req any) (context.Context, error) {
+		method, path, err := grpcRoute(req)
+		if err != nil {
+			return nil, err
+		}
+		r, err := http.NewRequestWithContext(ctx, method, path, nil)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		for key, values := range md {
+			if strings.HasPrefix(key, ":") {
+				continue
+			}
+			for _, v := range values {
+				r.Header.Add(key, v)
+			}
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			r.RemoteAddr = p.Addr.String()
+			if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+				r.TLS = &info.State
+			}
+		}
+
+		var authorized context.Context
+		rec := &grpcAuthRecorder{header: http.Header{}}
+		auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorized = r.Context()
+		})).ServeHTTP(rec, r)
+		if authorized == nil {
+			return nil, rec.refusal()
+		}
+		return authorized, nil
+	}
+
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &authorizedStream{ServerStream: ss, authorize: authorize})
+	}
+	return unary, stream
+}
+
+// grpcRoute returns the HTTP request a call is authorized as.
+func grpcRoute(req any) (method, path string, err error) {
+	sandbox := func(id string, suffix string) string {
+		return "/sandboxes/" + url.PathEscape(id) + suffix
+	}
+	switch req := req.(type) {
+	case *olympusv1.SubmitRequest:
+		return http.MethodPost, "/submit", nil
+	case *olympusv1.GetRequest:
+		return http.MethodGet, sandbox(req.GetId(), ""), nil
+	case *olympusv1.ListRequest:
+		return http.MethodGet, "/sandboxes", nil
+	case *olympusv1.KillRequest:
+		return http.MethodDelete, sandbox(req.GetId(), ""), nil
+	case *olympusv1.SnapshotRequest:
+		return http.MethodPost, sandbox(req.GetId(), "/snapshot"), nil
+	case *olympusv1.StreamLogsRequest:
+		return http.MethodGet, "/sandboxes/logs/" + url.PathEscape(req.GetId()), nil
+	case *olympusv1.ExecRequest:
+		return http.MethodPost, sandbox(req.GetId(), "/exec"), nil
+	case *olympusv1.ExecInput:
+		if req.GetStart() == nil {
+			return "", "", status.Error(codes.InvalidArgument, "first message must be start")
+		}
+		return http.MethodGet, sandbox(req.GetStart().GetId(), "/exec"), nil
+	}
+	return "", "", status.Error(codes.PermissionDenied, "unknown method")
+}
+
+// authorizedStream authorizes a streaming call on its first message and
+// carries the authorized context from then on.
+type authorizedStream struct {
+	grpc.ServerStream
+	authorize func(ctx context.Context, req any) (context.Context, error)
+	ctx       context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return s.ServerStream.Context()
+}
+
+func (s *authorizedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.ctx == nil {
+		ctx, err := s.authorize(s.ServerStream.Context(), m)
+		if err != nil {
+			return err
+		}
+		s.ctx = ctx
+	}
+	return nil
+}
+
+func (s *authorizedStream) SendMsg(m any) error {
+	if s.ctx == nil {
+		return status.Error(codes.PermissionDenied, "call not authorized")
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// grpcAuthRecorder keeps the response of a request the HTTP middleware
+// refused.
+type grpcAuthRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *grpcAuthRecorder) Header() http.Header { return r.header }
+
+func (r *grpcAuthRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *grpcAuthRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+// refusal returns the status of the refused call. Cerberus refusals carry
+// their code as the reason of an ErrorInfo detail.
+func (r *grpcAuthRecorder) refusal() error {
+	code := grpcCodeFromHTTP(r.status)
+	var resp cerberus.AuthErrorResponse
+	if r.header.Get("Content-Type") != "application/json" || json.Unmarshal(r.body.Bytes(), &resp) != nil || resp.Code == "" {
+		return status.Error(code, strings.TrimSpace(r.body.String()))
+	}
+	st := status.New(code, resp.Error)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(resp.Code), Domain: cerberus.Realm}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// grpcCodeFromHTTP maps the statuses the HTTP middleware refuses requests
+// with to gRPC codes.
+func grpcCodeFromHTTP(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden, http.StatusUnavailableForLegalReasons:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
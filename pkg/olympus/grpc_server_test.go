@@ -0,0 +1,154 @@
+package olympus_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	olympusv1 "github.com/tartarus-sandbox/tartarus/api/olympus/v1"
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves manager over an in-memory listener, authorizing calls
+// with auth.
+func dialGRPC(t *testing.T, manager *olympus.Manager, auth func(http.Handler) http.Handler) olympusv1.OlympusClient {
+	lis := bufconn.Listen(1 << 20)
+	unary, stream := olympus.GRPCAuthInterceptors(auth)
+	server := grpc.NewServer(grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	olympusv1.RegisterOlympusServer(server, olympus.NewGRPCServer(manager))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return olympusv1.NewOlympusClient(conn)
+}
+
+func TestGRPCServer_AuthErrorCode(t *testing.T) {
+	gateway := cerberus.NewGateway(cerberus.NewSimpleAPIKeyAuthenticator("key"), cerberus.NewAllowAllAuthorizer(), cerberus.NewNoopAuditor())
+	middleware := cerberus.NewHTTPMiddleware(gateway, cerberus.NewBearerTokenExtractor(), cerberus.NewDefaultResourceMapper())
+	manager := &olympus.Manager{Hades: hades.NewMemoryRegistry(), Metrics: hermes.NewNoopMetrics(), Logger: &mockLogger{}}
+	client := dialGRPC(t, manager, middleware.Wrap)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err := client.Get(ctx, &olympusv1.GetRequest{Id: "sb-1"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.Unauthenticated, st.Code())
+	assert.Equal(t, "invalid API key", st.Message())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, string(cerberus.AuthCodeInvalidCredentials), info.Reason)
+}
+
+func TestGRPCServer(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	code := 0
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-1", NodeID: "node-1", Status: domain.RunStatusRunning}))
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-2", NodeID: "node-1", Status: domain.RunStatusSucceeded, ExitCode: &code}))
+
+	manager := &olympus.Manager{
+		Hades:   registry,
+		Control: &echoControl{},
+		Metrics: hermes.NewNoopMetrics(),
+		Logger:  &mockLogger{},
+	}
+
+	// Only requests with a token pass, as with the HTTP API
+	var routes []string
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			routes = append(routes, r.Method+" "+r.URL.Path)
+			next.ServeHTTP(w, r)
+		})
+	}
+	client := dialGRPC(t, manager, auth)
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer token")
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		_, err := client.Get(ctx, &olympusv1.GetRequest{Id: "sb-1"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		stream, err := client.ExecInteractive(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&olympusv1.ExecInput{Input: &olympusv1.ExecInput_Start{Start: &olympusv1.ExecStart{Id: "sb-1"}}}))
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("GetAndList", func(t *testing.T) {
+		routes = nil
+		sb, err := client.Get(authed, &olympusv1.GetRequest{Id: "sb-2"})
+		require.NoError(t, err)
+		assert.Equal(t, string(domain.RunStatusSucceeded), sb.Status)
+		require.NotNil(t, sb.ExitCode)
+		assert.Equal(t, int32(0), *sb.ExitCode)
+
+		_, err = client.Get(authed, &olympusv1.GetRequest{Id: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		list, err := client.List(authed, &olympusv1.ListRequest{})
+		require.NoError(t, err)
+		assert.Len(t, list.Sandboxes, 2)
+		assert.Equal(t, []string{"GET /sandboxes/sb-2", "GET /sandboxes/missing", "GET /sandboxes"}, routes)
+	})
+
+	t.Run("KillNotFound", func(t *testing.T) {
+		_, err := client.Kill(authed, &olympusv1.KillRequest{Id: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("ExecInteractive", func(t *testing.T) {
+		stream, err := client.ExecInteractive(authed)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&olympusv1.ExecInput{Input: &olympusv1.ExecInput_Start{Start: &olympusv1.ExecStart{Id: "sb-1", Cmd: []string{"cat", "-u"}}}}))
+		require.NoError(t, stream.Send(&olympusv1.ExecInput{Input: &olympusv1.ExecInput_Stdin{Stdin: []byte("hello")}}))
+		require.NoError(t, stream.CloseSend())
+
+		var stdout, stderr string
+		var exit *olympusv1.ExecExit
+		for exit == nil {
+			out, err := stream.Recv()
+			require.NoError(t, err)
+			stdout += string(out.GetStdout())
+			stderr += string(out.GetStderr())
+			exit = out.GetExit()
+		}
+		assert.Equal(t, "hello", stdout)
+		assert.Equal(t, "cat -u", stderr)
+		assert.Equal(t, int32(7), exit.ExitCode)
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("ExecInteractiveNotRunning", func(t *testing.T) {
+		stream, err := client.ExecInteractive(authed)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&olympusv1.ExecInput{Input: &olympusv1.ExecInput_Start{Start: &olympusv1.ExecStart{Id: "sb-2"}}}))
+		_, err = stream.Recv()
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
@@ -0,0 +1,98 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/phlegethon"
+)
+
+// HeatFeedback feeds the intensity Erinyes observed on finished sandboxes
+// back to Phlegethon, so each template's heat converges on how its
+// sandboxes actually behave.
+type HeatFeedback struct {
+	Hades      hades.Registry
+	Phlegethon *phlegethon.HeatClassifier
+	Logger     hermes.Logger
+	Metrics    hermes.Metrics
+	Interval   time.Duration // How often finished runs are collected (default 1m)
+
+	seen map[domain.SandboxID]time.Time // Observed runs by finish time, until they age out
+}
+
+func NewHeatFeedback(h hades.Registry, p *phlegethon.HeatClassifier, l hermes.Logger, m hermes.Metrics) *HeatFeedback {
+	return &HeatFeedback{
+		Hades:      h,
+		Phlegethon: p,
+		Logger:     l,
+		Metrics:    m,
+		Interval:   time.Minute,
+		seen:       make(map[domain.SandboxID]time.Time),
+	}
+}
+
+// Run collects observations every Interval until ctx is cancelled.
+func (f *HeatFeedback) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := f.Collect(ctx); err != nil {
+				f.Logger.Error(ctx, "Heat feedback failed", map[string]any{"error": err})
+			}
+		}
+	}
+}
+
+// Collect feeds every finished run with an observed intensity that has not
+// been fed yet to Phlegethon, and returns how many were fed. Observations
+// are timestamped with the run's finish time so they decay from then.
+func (f *HeatFeedback) Collect(ctx context.Context) (int, error) {
+	runs, err := f.Hades.ListRuns(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	now := time.Now()
+	maxAge := f.Phlegethon.Feedback.MaxAge
+	for id, finished := range f.seen {
+		if maxAge > 0 && now.Sub(finished) > maxAge {
+			delete(f.seen, id)
+		}
+	}
+
+	fed := 0
+	for _, run := range runs {
+		if !run.Status.IsTerminal() || run.Result == nil || run.Result.Intensity == nil || run.Template == "" {
+			continue
+		}
+		if _, ok := f.seen[run.ID]; ok {
+			continue
+		}
+		if maxAge > 0 && now.Sub(run.FinishedAt) > maxAge {
+			continue
+		}
+		f.seen[run.ID] = run.FinishedAt
+
+		obs := phlegethon.HeatObservation{
+			TemplateID:      string(run.Template),
+			ActualDuration:  run.WallClock(),
+			PeakCPU:         run.Result.Intensity.PeakCPUCores,
+			PeakMemory:      int64(run.Result.Intensity.PeakMemory),
+			PeakNetworkMbps: run.Result.Intensity.PeakNetworkMbps,
+			Timestamp:       run.FinishedAt,
+		}
+		f.Phlegethon.Observe(obs)
+		f.Metrics.IncCounter("phlegethon_observations_total", 1, hermes.Label{Key: "heat_level", Value: string(phlegethon.ObservedLevel(obs))})
+		fed++
+	}
+	return fed, nil
+}
@@ -0,0 +1,58 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/phlegethon"
+)
+
+func TestHeatFeedback_Collect(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{
+			ID:         domain.SandboxID(fmt.Sprintf("etl-%d", i)),
+			Template:   "etl",
+			Status:     domain.RunStatusSucceeded,
+			StartedAt:  now.Add(-time.Minute),
+			FinishedAt: now,
+			Result:     &domain.RunResult{Intensity: &domain.RunIntensity{PeakCPUCores: 3.5, PeakMemory: 6000}},
+		}))
+	}
+	// Still running, and finished without an observation
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "etl-running", Template: "etl", Status: domain.RunStatusRunning}))
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "etl-unwatched", Template: "etl", Status: domain.RunStatusFailed, FinishedAt: now, Result: &domain.RunResult{}}))
+
+	classifier := phlegethon.NewHeatClassifier()
+	classifier.Feedback.MinWeight = 2
+	classifier.AddHint("etl", phlegethon.HeatCold)
+	feedback := NewHeatFeedback(registry, classifier, hermes.NewSlogAdapter(), hermes.NewNoopMetrics())
+
+	fed, err := feedback.Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, fed)
+
+	// Runs are only fed once
+	fed, err = feedback.Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, fed)
+
+	level, source := classifier.Classify(&phlegethon.SandboxRequest{TemplateID: "etl"})
+	assert.Equal(t, phlegethon.HeatHot, level)
+	assert.Equal(t, "observed", source)
+
+	report := classifier.HeatReport(time.Now())
+	require.Len(t, report, 1)
+	assert.Equal(t, phlegethon.HeatCold, report[0].Configured)
+	assert.Equal(t, phlegethon.HeatHot, report[0].Observed)
+	assert.Equal(t, 3, report[0].Observations)
+}
@@ -0,0 +1,117 @@
+package olympus
+
+import (
+	"context"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/hypnos"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+// AdviseHibernation estimates whether hibernating the sandbox would save more
+// than it costs, without hibernating it. expectedIdle may be zero when unknown.
+func (m *Manager) AdviseHibernation(ctx context.Context, id domain.SandboxID, expectedIdle time.Duration) (*hypnos.Advice, error) {
+	run, err := m.Hades.GetRun(ctx, id)
+	if err != nil {
+		return nil, ErrSandboxNotFound
+	}
+	return m.adviseRun(run, expectedIdle), nil
+}
+
+// adviseRun applies the advisor to a run and records the verdict.
+func (m *Manager) adviseRun(run *domain.SandboxRun, expectedIdle time.Duration) *hypnos.Advice {
+	advisor := m.Advisor
+	if advisor == nil {
+		advisor = hypnos.NewAdvisor(hypnos.AdvisorConfig{})
+	}
+	advice := advisor.Advise(hypnos.ProfileRun(run, expectedIdle, time.Now()))
+
+	result := "hibernate"
+	if !advice.Hibernate {
+		result = "keep"
+	}
+	m.Metrics.IncCounter("sandbox_hibernate_advice_total", 1, hermes.Label{Key: "result", Value: result})
+	return &advice
+}
+
+// trackPreWake hands a hibernated run to the pre-waker, which wakes it ahead
+// of its predicted use if its policy opts in.
+func (m *Manager) trackPreWake(ctx context.Context, run *domain.SandboxRun) {
+	if m.PreWaker == nil {
+		return
+	}
+	optIn := false
+	if m.Policies != nil {
+		var tenantID string
+		if run.Submitter != nil {
+			tenantID = run.Submitter.TenantID
+		}
+		effective, err := themis.Resolve(ctx, m.Policies, tenantID, run.Template)
+		if err != nil {
+			m.Logger.Error(ctx, "Failed to load policy for pre-wake", map[string]any{
+				"sandbox_id": run.ID,
+				"template":   run.Template,
+				"error":      err,
+			})
+		} else {
+			optIn = effective.Policy.Hibernation != nil && effective.Policy.Hibernation.PreWake
+		}
+	}
+	m.PreWaker.Slept(run.ID, optIn)
+}
+
+// PreWake makes one pre-wake pass: hibernated sandboxes predicted to be used
+// within the pre-waker's lead time are woken, weighted by the Persephone
+// forecast if there is one. It returns the sandboxes woken.
+func (m *Manager) PreWake(ctx context.Context) []domain.SandboxID {
+	if m.PreWaker == nil {
+		return nil
+	}
+	return m.PreWaker.Run(ctx, m.demandForecast(ctx), m.preWakeSandbox)
+}
+
+// RunPreWaker makes a pre-wake pass every interval until ctx is cancelled.
+func (m *Manager) RunPreWaker(ctx context.Context, interval time.Duration) {
+	if m.PreWaker == nil {
+		return
+	}
+	m.PreWaker.Start(ctx, interval, m.demandForecast, m.preWakeSandbox)
+}
+
+// demandForecast covers the pre-waker's lead time, or is nil without a
+// Persephone scaler.
+func (m *Manager) demandForecast(ctx context.Context) hypnos.DemandForecast {
+	if m.Seasons == nil {
+		return nil
+	}
+	forecast, err := m.Seasons.Forecast(ctx, m.PreWaker.Config.LeadTime+m.PreWaker.Config.HitWindow)
+	if err != nil || forecast == nil {
+		return nil
+	}
+	return forecast
+}
+
+// preWakeSandbox sends a speculative wake command; unlike WakeSandbox it is
+// not a use of the sandbox.
+func (m *Manager) preWakeSandbox(ctx context.Context, id domain.SandboxID) error {
+	run, err := m.Hades.GetRun(ctx, id)
+	if err != nil {
+		m.PreWaker.Forget(id)
+		return ErrSandboxNotFound
+	}
+	if err := m.Control.Wake(ctx, run.NodeID, id); err != nil {
+		m.Logger.Error(ctx, "Failed to send pre-wake command", map[string]any{
+			"sandbox_id": id,
+			"node_id":    run.NodeID,
+			"error":      err,
+		})
+		return err
+	}
+	m.Logger.Info(ctx, "Pre-wake command sent", map[string]any{
+		"sandbox_id": id,
+		"node_id":    run.NodeID,
+	})
+	return nil
+}
@@ -0,0 +1,107 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/hypnos"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+func TestManager_HibernationAdvice(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	now := time.Now()
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{
+		ID: "sb-tiny", NodeID: "node-1", Status: domain.RunStatusRunning, StartedAt: now,
+		Resources: &domain.ResourceSpec{Mem: 128, TTL: 2 * time.Minute},
+	}))
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{
+		ID: "sb-large", NodeID: "node-1", Status: domain.RunStatusRunning, StartedAt: now,
+		Resources: &domain.ResourceSpec{Mem: 8192},
+	}))
+
+	control := &hibernateRecorder{}
+	manager := &olympus.Manager{Hades: registry, Control: control, Logger: &mockLogger{}, Metrics: hermes.NewNoopMetrics()}
+
+	// Advice alone hibernates nothing
+	advice, err := manager.AdviseHibernation(ctx, "sb-tiny", time.Hour)
+	require.NoError(t, err)
+	assert.False(t, advice.Hibernate, advice.Reason)
+	assert.LessOrEqual(t, advice.ExpectedIdle, 2*time.Minute)
+	assert.Empty(t, control.hibernated)
+
+	// Hibernating against the advice is allowed but annotated
+	advice, err = manager.HibernateSandbox(ctx, "sb-tiny", 0)
+	require.NoError(t, err)
+	assert.False(t, advice.Hibernate)
+
+	advice, err = manager.HibernateSandbox(ctx, "sb-large", 2*time.Hour)
+	require.NoError(t, err)
+	assert.True(t, advice.Hibernate, advice.Reason)
+	assert.Equal(t, []domain.SandboxID{"sb-tiny", "sb-large"}, control.hibernated)
+
+	_, err = manager.AdviseHibernation(ctx, "sb-missing", 0)
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotFound)
+}
+
+type wakeRecorder struct {
+	hibernateRecorder
+	woken []domain.SandboxID
+}
+
+func (c *wakeRecorder) Wake(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.woken = append(c.woken, sandboxID)
+	return nil
+}
+
+func TestManager_PreWake(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	policies := themis.NewMemoryRepo()
+	require.NoError(t, policies.UpsertPolicy(ctx, &domain.SandboxPolicy{
+		ID: "notebooks", TemplateID: "notebook", Hibernation: &domain.HibernationPolicy{PreWake: true},
+	}))
+	require.NoError(t, policies.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "batch", TemplateID: "batch"}))
+
+	preWaker := hypnos.NewPreWaker(hypnos.PreWakeConfig{}, nil)
+	now := time.Now()
+	for _, run := range []domain.SandboxRun{
+		{ID: "sb-notebook", NodeID: "node-1", Template: "notebook", Status: domain.RunStatusRunning},
+		{ID: "sb-batch", NodeID: "node-1", Template: "batch", Status: domain.RunStatusRunning},
+	} {
+		require.NoError(t, registry.UpdateRun(ctx, run))
+		// Used every day two minutes from now
+		for day := 1; day <= 14; day++ {
+			preWaker.Patterns.Record(run.ID, now.AddDate(0, 0, -day).Add(2*time.Minute))
+		}
+	}
+
+	control := &wakeRecorder{}
+	manager := &olympus.Manager{
+		Hades: registry, Policies: policies, Control: control, PreWaker: preWaker,
+		Logger: &mockLogger{}, Metrics: hermes.NewNoopMetrics(),
+	}
+	for _, id := range []domain.SandboxID{"sb-notebook", "sb-batch"} {
+		_, err := manager.HibernateSandbox(ctx, id, 0)
+		require.NoError(t, err)
+	}
+
+	// Only the sandbox whose policy opts in is pre-woken
+	assert.Equal(t, []domain.SandboxID{"sb-notebook"}, manager.PreWake(ctx))
+	assert.Equal(t, []domain.SandboxID{"sb-notebook"}, control.woken)
+
+	// Waking it on demand finds it already awake
+	require.NoError(t, manager.WakeSandbox(ctx, "sb-notebook"))
+	require.NoError(t, manager.WakeSandbox(ctx, "sb-batch"))
+	assert.Equal(t, []domain.SandboxID{"sb-notebook", "sb-batch"}, control.woken)
+}
@@ -0,0 +1,135 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+var ErrInvalidImageRef = errors.New("invalid image reference")
+
+// ImageCacheEntry is an image digest and the nodes that have it cached.
+type ImageCacheEntry struct {
+	Ref    string          `json:"ref"`
+	Digest string          `json:"digest"`
+	Bytes  int64           `json:"bytes,omitempty"` // Compressed size of the image's layers
+	Nodes  []domain.NodeID `json:"nodes"`
+}
+
+// PrefetchImage instructs agents to pre-pull and assemble an image so that
+// launches on those nodes skip the pull. With no nodes given, every node
+// that is not draining is targeted. Returns the nodes that were instructed.
+func (m *Manager) PrefetchImage(ctx context.Context, ref string, nodes []domain.NodeID) ([]domain.NodeID, error) {
+	if _, err := name.ParseReference(ref); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImageRef, err)
+	}
+
+	all, err := m.Hades.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var targets []domain.NodeID
+	if len(nodes) == 0 {
+		for _, n := range all {
+			if !n.Draining() {
+				targets = append(targets, n.ID)
+			}
+		}
+	} else {
+		known := make(map[domain.NodeID]bool, len(all))
+		for _, n := range all {
+			known[n.ID] = true
+		}
+		for _, id := range nodes {
+			if !known[id] {
+				return nil, fmt.Errorf("%w: %s", hades.ErrNodeNotFound, id)
+			}
+		}
+		targets = nodes
+	}
+
+	sent := make([]domain.NodeID, 0, len(targets))
+	for _, id := range targets {
+		if err := m.Control.PrefetchImage(ctx, id, ref); err != nil {
+			m.Logger.Error(ctx, "Failed to send prefetch command", map[string]any{
+				"ref":     ref,
+				"node_id": id,
+				"error":   err,
+			})
+			continue
+		}
+		sent = append(sent, id)
+	}
+
+	m.Logger.Info(ctx, "Image prefetch requested", map[string]any{"ref": ref, "nodes": len(sent)})
+	m.Metrics.IncCounter("image_prefetch_requests_total", 1)
+	if len(sent) == 0 && len(targets) > 0 {
+		return nil, fmt.Errorf("failed to instruct any of %d nodes", len(targets))
+	}
+	return sent, nil
+}
+
+// ImageCache reports which nodes have which images cached, as advertised in
+// their heartbeats. Entries are sorted by ref, then digest.
+func (m *Manager) ImageCache(ctx context.Context) ([]ImageCacheEntry, error) {
+	nodes, err := m.Hades.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	type key struct{ ref, digest string }
+	index := make(map[key]*ImageCacheEntry)
+	for _, n := range nodes {
+		for _, img := range n.CachedImages {
+			k := key{img.Ref, img.Digest}
+			entry, ok := index[k]
+			if !ok {
+				entry = &ImageCacheEntry{Ref: img.Ref, Digest: img.Digest}
+				index[k] = entry
+			}
+			entry.Bytes = max(entry.Bytes, img.Bytes)
+			entry.Nodes = append(entry.Nodes, n.ID)
+		}
+	}
+
+	entries := make([]ImageCacheEntry, 0, len(index))
+	for _, entry := range index {
+		sort.Slice(entry.Nodes, func(i, j int) bool { return entry.Nodes[i] < entry.Nodes[j] })
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Ref != entries[j].Ref {
+			return entries[i].Ref < entries[j].Ref
+		}
+		return entries[i].Digest < entries[j].Digest
+	})
+	return entries, nil
+}
+
+// withImageInput adds the template's OCI image to the request's inputs, so
+// nodes that have it cached count as holding its bytes. Templates built from
+// disk images are left out.
+func withImageInput(req *domain.SandboxRequest, tmpl *domain.TemplateSpec) {
+	ref := tmpl.BaseImage
+	if variant, ok := tmpl.Variants[req.Arch]; ok && variant.BaseImage != "" {
+		ref = variant.BaseImage
+	}
+	if ref == "" {
+		return
+	}
+	if _, err := name.ParseReference(ref, name.StrictValidation); err != nil {
+		return
+	}
+	for _, input := range req.Inputs {
+		if input.Ref == ref {
+			return
+		}
+	}
+	req.Inputs = append(req.Inputs, domain.InputArtifact{Name: "image", Ref: ref})
+}
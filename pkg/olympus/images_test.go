@@ -0,0 +1,81 @@
+package olympus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+type prefetchRecorder struct {
+	olympus.NoopControlPlane
+	mu    sync.Mutex
+	nodes []domain.NodeID
+}
+
+func (c *prefetchRecorder) PrefetchImage(ctx context.Context, nodeID domain.NodeID, ref string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes = append(c.nodes, nodeID)
+	return nil
+}
+
+func TestManager_PrefetchImage(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	for _, id := range []domain.NodeID{"node-a", "node-b", "node-c"} {
+		require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{Node: domain.NodeInfo{ID: id}, Time: time.Now()}))
+	}
+	require.NoError(t, registry.MarkDraining(ctx, "node-c"))
+
+	control := &prefetchRecorder{}
+	manager := &olympus.Manager{Hades: registry, Control: control, Metrics: hermes.NewNoopMetrics(), Logger: &mockLogger{}}
+
+	// All nodes that are not draining
+	sent, err := manager.PrefetchImage(ctx, "registry.example.com/app:v1", nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []domain.NodeID{"node-a", "node-b"}, sent)
+
+	// Selected nodes
+	control.nodes = nil
+	sent, err = manager.PrefetchImage(ctx, "registry.example.com/app:v1", []domain.NodeID{"node-b"})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.NodeID{"node-b"}, sent)
+	assert.Equal(t, []domain.NodeID{"node-b"}, control.nodes)
+
+	_, err = manager.PrefetchImage(ctx, "registry.example.com/app:v1", []domain.NodeID{"node-x"})
+	assert.ErrorIs(t, err, hades.ErrNodeNotFound)
+
+	_, err = manager.PrefetchImage(ctx, "not a ref", nil)
+	assert.ErrorIs(t, err, olympus.ErrInvalidImageRef)
+}
+
+func TestManager_ImageCache(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	v1 := domain.CachedImage{Ref: "app:v1", Digest: "sha256:aaa"}
+	v2 := domain.CachedImage{Ref: "app:v2", Digest: "sha256:bbb", Bytes: 4096}
+	cached := map[domain.NodeID][]domain.CachedImage{
+		"node-b": {v1},
+		"node-a": {v1, v2},
+		"node-c": nil,
+	}
+	for id, images := range cached {
+		require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{Node: domain.NodeInfo{ID: id}, CachedImages: images, Time: time.Now()}))
+	}
+
+	manager := &olympus.Manager{Hades: registry, Metrics: hermes.NewNoopMetrics(), Logger: &mockLogger{}}
+	entries, err := manager.ImageCache(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []olympus.ImageCacheEntry{
+		{Ref: "app:v1", Digest: "sha256:aaa", Nodes: []domain.NodeID{"node-a", "node-b"}},
+		{Ref: "app:v2", Digest: "sha256:bbb", Bytes: 4096, Nodes: []domain.NodeID{"node-a"}},
+	}, entries)
+}
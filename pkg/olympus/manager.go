@@ -5,78 +5,160 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
 	"github.com/tartarus-sandbox/tartarus/pkg/hades"
 	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/hypnos"
 	"github.com/tartarus-sandbox/tartarus/pkg/judges"
 	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
 	"github.com/tartarus-sandbox/tartarus/pkg/nyx"
+	"github.com/tartarus-sandbox/tartarus/pkg/persephone"
 	"github.com/tartarus-sandbox/tartarus/pkg/phlegethon"
 	"github.com/tartarus-sandbox/tartarus/pkg/themis"
 )
 
 var ErrPolicyRejected = errors.New("request rejected by policy enforcement")
 var ErrSandboxNotFound = errors.New("sandbox not found")
+var ErrRunWindowExpired = errors.New("run window expired before the sandbox could start")
+var ErrUnsupportedArch = errors.New("template does not support the requested architecture")
+var ErrSandboxNotRunning = errors.New("sandbox is not running")
+
+// PurchaseRequiredError rejects a request for a premium template the
+// submitter's tenant holds no entitlement for. It wraps ErrPolicyRejected.
+type PurchaseRequiredError struct {
+	Reason      string
+	PurchaseURL string // Where the entitlement can be bought, if known
+}
+
+func (e *PurchaseRequiredError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPolicyRejected, e.Reason)
+}
+
+func (e *PurchaseRequiredError) Unwrap() error { return ErrPolicyRejected }
 
 // Manager is Olympus: front-door for users, back-door to Hades and Acheron.
 
 type Manager struct {
-	Queue      acheron.Queue
-	Hades      hades.Registry
-	Policies   themis.Repository
-	Templates  TemplateManager
-	Nyx        nyx.Manager
-	Judges     *judges.Chain
-	Scheduler  moirai.Scheduler
-	Phlegethon *phlegethon.HeatClassifier
-	Control    ControlPlane
-	Metrics    hermes.Metrics
-	Logger     hermes.Logger
+	Queue        acheron.Queue
+	Outbox       acheron.Outbox // Optional; persists the scheduled run and enqueue intent atomically
+	Hades        hades.Registry
+	Policies     themis.Repository
+	Templates    TemplateManager
+	Nyx          nyx.Manager
+	Judges       *judges.Chain
+	Scheduler    moirai.Scheduler
+	Simulator    moirai.Scheduler // Optional; scheduler for what-if simulations, built without logging (Scheduler if nil)
+	Phlegethon   *phlegethon.HeatClassifier
+	Control      ControlPlane
+	Store        erebus.Store              // Optional; used for storage usage and the queue archive
+	Refs         *erebus.RefCounter        // Optional; purging a template collects the artifacts only it referenced
+	Advisor      *hypnos.Advisor           // Optional; hibernation cost model (defaults if nil)
+	PreWaker     *hypnos.PreWaker          // Optional; wakes opted-in hibernated sandboxes before their predicted use
+	Seasons      persephone.SeasonalScaler // Optional; its demand forecast weighs pre-wakes
+	Audit        judges.AuditSink          // Optional; records changes to sandboxes
+	Events       hermes.EventBus           // Optional; receives sandbox lifecycle events
+	RateCard     *RateCard                 // Optional; prices runs for cost estimates
+	Applications ApplicationStore          // Optional; declarative application bundles
+	ScaleToZero  *ScaleToZero              // Optional; parks submissions while the cluster has no nodes
+	WarmPool     *WarmPool                 // Optional; hands submissions sandboxes pre-warmed for their template
+	Metrics      hermes.Metrics
+	Logger       hermes.Logger
+
+	// SpreadRuns gives the scheduler every node's runs from Hades, with
+	// their templates, for the topology-spread strategy
+	SpreadRuns bool
+
+	// SchedulingTrace is the verbosity of the decision trace recorded on
+	// each run placed (moirai.TraceSummary if empty)
+	SchedulingTrace string
+
+	// AgentVersionWindow bounds the agent versions reported as supported
+	AgentVersionWindow VersionWindow
+
+	// DeleteRetention is the restore window for deleted templates and
+	// policies (DefaultDeleteRetention if zero)
+	DeleteRetention time.Duration
+
+	// MigrationTimeout bounds a live migration between nodes
+	// (DefaultMigrationTimeout if zero)
+	MigrationTimeout time.Duration
+
+	// patchMu serializes sandbox patches so version checks are atomic
+	patchMu sync.Mutex
+	// applyMu serializes application applies so their parts change together
+	applyMu sync.Mutex
+
+	// drains holds the latest drain of each node, guarded by drainMu
+	drainMu sync.Mutex
+	drains  map[domain.NodeID]*NodeDrain
 }
 
-// Submit enqueues a new sandbox request after validation and policy checks.
-
-func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error {
-	// 1) Assign ID if missing
-	if req.ID == "" {
-		req.ID = domain.SandboxID(uuid.New().String())
-	}
-	if req.CreatedAt.IsZero() {
-		req.CreatedAt = time.Now()
+// heatClassificationRequest maps a request to the shape Phlegethon classifies.
+func heatClassificationRequest(req *domain.SandboxRequest) *phlegethon.SandboxRequest {
+	phlegReq := &phlegethon.SandboxRequest{
+		TemplateID:  string(req.Template),
+		MaxDuration: req.Resources.TTL,
+		CPUCores:    int(req.Resources.CPU / 1000), // Convert milliCPU to cores
+		MemoryMB:    int(req.Resources.Mem),
 	}
 
-	start := time.Now()
-	defer func() {
-		m.Metrics.ObserveHistogram("sandbox_submission_duration_seconds", time.Since(start).Seconds())
-	}()
+	// Check for explicit heat hint in metadata
+	if req.Metadata != nil {
+		if heatHint := req.Metadata["heat_hint"]; heatHint != "" {
+			phlegReq.HeatHint = phlegethon.HeatLevel(heatHint)
+		}
+	}
+	return phlegReq
+}
 
-	m.Metrics.IncCounter("sandbox_submissions_total", 1)
+// priorityForHeat is the Acheron lane of a request that sets no priority:
+// quick cold tasks are usually waited on, inferno workloads run for long and
+// can wait themselves.
+func priorityForHeat(heat phlegethon.HeatLevel) domain.Priority {
+	switch heat {
+	case phlegethon.HeatCold:
+		return domain.PriorityHigh
+	case phlegethon.HeatInferno:
+		return domain.PriorityBatch
+	default:
+		return domain.PriorityNormal
+	}
+}
 
+// prepare validates the request's template, architecture, run window and
+// secret references against the effective policy, and copies the
+// policy-derived settings onto it. On failure it returns the reason recorded
+// in sandbox_submission_failures_total.
+func (m *Manager) prepare(ctx context.Context, req *domain.SandboxRequest) (*domain.TemplateSpec, string, error) {
 	// 2) Validate Template
-	_, err := m.Templates.GetTemplate(ctx, req.Template)
+	tmpl, err := m.Templates.GetTemplate(ctx, req.Template)
 	if err != nil {
 		m.Logger.Error(ctx, "Template not found", map[string]any{
 			"template": req.Template,
 			"error":    err,
 		})
-		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "invalid_template"})
-		return fmt.Errorf("invalid template: %w", err)
+		return nil, "invalid_template", fmt.Errorf("invalid template: %w", err)
+	}
+	if req.Arch != "" && !tmpl.SupportsArch(req.Arch) {
+		return nil, "unsupported_arch", fmt.Errorf("%w: %s supports %v, requested %s", ErrUnsupportedArch, req.Template, tmpl.Architectures(), req.Arch)
 	}
 
-	// 3) Load policy from Themis
-	policy, err := m.Policies.GetPolicy(ctx, req.Template)
+	// 3) Resolve the effective policy (global → tenant → template) from Themis
+	effective, err := themis.ResolveRequest(ctx, m.Policies, req)
 	if err != nil {
 		m.Logger.Error(ctx, "Failed to load policy", map[string]any{
 			"template": req.Template,
 			"error":    err,
 		})
-		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "policy_load_failed"})
-		return err
+		return nil, "policy_load_failed", err
 	}
+	policy := effective.Policy
 
 	m.Logger.Info(ctx, "Loaded policy for request", map[string]any{
 		"sandbox_id": req.ID,
@@ -84,6 +166,137 @@ func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error
 		"policy_id":  policy.ID,
 	})
 
+	// 3b) Resolve the run window from the request and policy defaults
+	if err := req.Window.Validate(); err != nil {
+		return nil, "invalid_run_window", err
+	}
+	if err := req.ValidateSecrets(); err != nil {
+		return nil, "invalid_secret_ref", err
+	}
+	if err := req.ValidatePriority(); err != nil {
+		return nil, "invalid_priority", err
+	}
+	if err := req.ValidatePlacement(); err != nil {
+		return nil, "invalid_placement", err
+	}
+	if policy.RunWindow.MaxQueueTime > 0 || policy.RunWindow.MaxCompletionTime > 0 {
+		if req.Window == nil {
+			req.Window = &domain.RunWindow{}
+		}
+		req.Window.ApplyDefaults(policy.RunWindow, req.CreatedAt)
+	}
+
+	// 3c) Wasm host function grants, integrity monitoring, process limits,
+	// swap, crash bundles, watchdog escalation, host hooks and output
+	// extraction come only from the policy
+	req.Wasm = policy.Wasm
+	req.Integrity = policy.Integrity
+	req.Limits = policy.Limits
+	req.Swap = policy.Swap
+	req.CrashBundle = policy.CrashBundle
+	req.Escalation = policy.Escalation
+	req.Hooks = policy.Hooks
+	req.Outputs = policy.Outputs
+
+	// 3d) Retries: the request's policy, capped at the policy's attempts, or
+	// the policy's default
+	if req.Retry != nil {
+		if err := req.Retry.Validate(); err != nil {
+			return nil, "invalid_retry_policy", err
+		}
+		retry := req.Retry.Cap(policy.Retry)
+		req.Retry = &retry
+	} else {
+		req.Retry = policy.Retry
+	}
+
+	// 3e) A script run keeps only the outputs it declares, within the
+	// policy's
+	if err := req.Script.Validate(); err != nil {
+		return nil, "invalid_script", err
+	}
+	if req.Script != nil {
+		outputs, err := req.Script.NarrowOutputs(policy.Outputs)
+		if err != nil {
+			return nil, "invalid_script", err
+		}
+		req.Outputs = outputs
+	}
+
+	return tmpl, "", nil
+}
+
+// Submit enqueues a new sandbox request after validation and policy checks.
+
+func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error {
+	// Record who submitted the request; never trust a client-supplied value
+	req.Submitter = submitterFromContext(ctx)
+	return m.submit(ctx, req, nil)
+}
+
+// submit validates, schedules and enqueues a request. prev is the failed
+// attempt the request retries, if any.
+func (m *Manager) submit(ctx context.Context, req *domain.SandboxRequest, prev *domain.SandboxRun) error {
+	start := time.Now()
+	defer func() {
+		m.Metrics.ObserveHistogram("sandbox_submission_duration_seconds", time.Since(start).Seconds())
+	}()
+
+	a, err := m.admit(ctx, req, prev, domain.ResourceQuota{})
+	if err != nil {
+		return err
+	}
+	nodes, err := m.listNodes(ctx, a)
+	if err != nil {
+		return err
+	}
+	return m.place(ctx, a, m.Scheduler, nodes)
+}
+
+// admission is a request that passed validation, quota and the judges and
+// waits to be placed.
+type admission struct {
+	req  *domain.SandboxRequest
+	tmpl *domain.TemplateSpec
+	run  domain.SandboxRun
+}
+
+// admit validates and judges a request, persists its pending run and
+// classifies its heat. held is usage the submitter's quota must also cover
+// that is not yet recorded in Hades.
+func (m *Manager) admit(ctx context.Context, req *domain.SandboxRequest, prev *domain.SandboxRun, held domain.ResourceQuota) (*admission, error) {
+	// 1) Assign ID if missing
+	if req.ID == "" {
+		req.ID = domain.SandboxID(uuid.New().String())
+	}
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+
+	m.Metrics.IncCounter("sandbox_submissions_total", 1)
+
+	// 2-3c) Validate against the template and the effective policy
+	tmpl, reason, err := m.prepare(ctx, req)
+	if err != nil {
+		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: reason})
+		return nil, err
+	}
+	// 3e) Reject requests that would take the tenant past its quota
+	if err := m.checkQuota(ctx, req, held); err != nil {
+		reason := "quota_check_failed"
+		if errors.Is(err, ErrQuotaExceeded) {
+			reason = "quota_exceeded"
+			m.Logger.Info(ctx, "Request rejected: quota exceeded", map[string]any{
+				"sandbox_id": req.ID,
+				"error":      err,
+			})
+		}
+		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: reason})
+		return nil, err
+	}
+	// Kept before judges and scheduling change it, for resubmission
+	retry := newRunRetry(req, prev)
+
 	// 4) Run PreJudges
 	verdict, err := m.Judges.RunPre(ctx, req)
 	if err != nil {
@@ -92,7 +305,7 @@ func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error
 			"error":      err,
 		})
 		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "judge_error"})
-		return err
+		return nil, err
 	}
 
 	// 5) Verdict Handling
@@ -103,7 +316,16 @@ func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error
 			"verdict":    verdict,
 		})
 		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "rejected"})
-		return ErrPolicyRejected
+		if req.Metadata[judges.MetadataRejectCode] == judges.RejectCodePurchaseRequired {
+			return nil, &PurchaseRequiredError{
+				Reason:      req.Metadata[judges.MetadataRejectReason],
+				PurchaseURL: req.Metadata[judges.MetadataPurchaseURL],
+			}
+		}
+		if reason := req.Metadata[judges.MetadataRejectReason]; reason != "" {
+			return nil, fmt.Errorf("%w: %s", ErrPolicyRejected, reason)
+		}
+		return nil, ErrPolicyRejected
 	case judges.VerdictQuarantine:
 		m.Logger.Info(ctx, "Request quarantined by policy enforcement", map[string]any{
 			"sandbox_id": req.ID,
@@ -118,50 +340,60 @@ func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error
 			"sandbox_id": req.ID,
 		})
 	default:
-		return fmt.Errorf("unknown verdict: %v", verdict)
+		return nil, fmt.Errorf("unknown verdict: %v", verdict)
 	}
 
 	// 6) Persistence
+	resources := req.Resources
 	initialRun := domain.SandboxRun{
 		ID:        req.ID,
 		RequestID: req.ID,
 		Template:  req.Template,
 		Status:    domain.RunStatusPending,
+		Window:    req.Window,
+		Submitter: req.Submitter,
+		Resources: &resources,
+		Retry:     retry,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
-	if err := m.Hades.UpdateRun(ctx, initialRun); err != nil {
-		m.Logger.Error(ctx, "Failed to persist initial run state", map[string]any{
-			"sandbox_id": req.ID,
-			"error":      err,
-		})
-		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "persistence_failed"})
-		return fmt.Errorf("failed to persist run state: %w", err)
+	// Pre-warmed sandboxes are counted and claimed by their run
+	if req.Warm() {
+		initialRun.Metadata = map[string]string{domain.MetadataWarm: "true"}
+	}
+	if req.Window.StartExpired(time.Now()) {
+		initialRun.Status = domain.RunStatusExpired
+		initialRun.Error = ErrRunWindowExpired.Error()
+		_ = m.Hades.UpdateRun(ctx, initialRun)
+		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "run_window_expired"})
+		return nil, ErrRunWindowExpired
+	}
+	// With an outbox the run is first written together with its enqueue
+	// intent, so no crash can leave it PENDING without a way to launch
+	if m.Outbox == nil {
+		if err := m.Hades.UpdateRun(ctx, initialRun); err != nil {
+			m.Logger.Error(ctx, "Failed to persist initial run state", map[string]any{
+				"sandbox_id": req.ID,
+				"error":      err,
+			})
+			m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "persistence_failed"})
+			return nil, fmt.Errorf("failed to persist run state: %w", err)
+		}
 	}
 
 	// 7) Heat Classification
 	if m.Phlegethon != nil {
-		// Map domain.SandboxRequest to phlegethon.SandboxRequest
-		phlegReq := &phlegethon.SandboxRequest{
-			TemplateID:  string(req.Template),
-			MaxDuration: req.Resources.TTL,
-			CPUCores:    int(req.Resources.CPU / 1000), // Convert milliCPU to cores
-			MemoryMB:    int(req.Resources.Mem),
-		}
-
-		// Check for explicit heat hint in metadata
-		if req.Metadata != nil {
-			if heatHint := req.Metadata["heat_hint"]; heatHint != "" {
-				phlegReq.HeatHint = phlegethon.HeatLevel(heatHint)
-			}
-		}
-
+		phlegReq := heatClassificationRequest(req)
 		heatLevel, source := m.Phlegethon.Classify(phlegReq)
 		req.HeatLevel = string(heatLevel)
+		if req.Priority == "" {
+			req.Priority = priorityForHeat(heatLevel)
+		}
 
 		m.Logger.Info(ctx, "Classified workload heat", map[string]any{
 			"sandbox_id": req.ID,
 			"heat_level": heatLevel,
+			"priority":   req.Priority,
 			"source":     source,
 			"cpu_cores":  phlegReq.CPUCores,
 			"memory_mb":  phlegReq.MemoryMB,
@@ -174,43 +406,134 @@ func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error
 		)
 	}
 
-	// 8) Scheduling
+	return &admission{req: req, tmpl: tmpl, run: initialRun}, nil
+}
+
+// listNodes lists the nodes to schedule on. If that fails, the admitted
+// runs are marked failed.
+func (m *Manager) listNodes(ctx context.Context, admitted ...*admission) ([]domain.NodeStatus, error) {
 	nodes, err := m.Hades.ListNodes(ctx)
-	if err != nil {
+	if err == nil {
+		return m.withActiveRuns(ctx, nodes, admitted), nil
+	}
+	for _, a := range admitted {
 		m.Logger.Error(ctx, "Failed to list nodes for scheduling", map[string]any{
-			"sandbox_id": req.ID,
+			"sandbox_id": a.req.ID,
 			"error":      err,
 		})
 		// Mark as failed
-		initialRun.Status = domain.RunStatusFailed
-		initialRun.Error = fmt.Sprintf("failed to list nodes: %v", err)
-		initialRun.UpdatedAt = time.Now()
-		_ = m.Hades.UpdateRun(ctx, initialRun)
+		a.run.Status = domain.RunStatusFailed
+		a.run.Error = fmt.Sprintf("failed to list nodes: %v", err)
+		a.run.UpdatedAt = time.Now()
+		_ = m.Hades.UpdateRun(ctx, a.run)
 		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "node_listing_failed"})
-		return fmt.Errorf("failed to list nodes: %w", err)
 	}
+	return nil, fmt.Errorf("failed to list nodes: %w", err)
+}
+
+// place schedules an admitted request on one of nodes and enqueues it.
+func (m *Manager) place(ctx context.Context, a *admission, scheduler moirai.Scheduler, nodes []domain.NodeStatus) error {
+	// 8) Scheduling
+	trace := m.newTrace()
+	ctx = moirai.WithTrace(ctx, trace)
+	all := nodes
+	nodes = candidateNodes(ctx, a, nodes)
+
+	// Start in a sandbox pre-warmed for the template if one is ready
+	if nodeID, ok := m.WarmPool.claim(ctx, a, nodes); ok {
+		trace.Note("claimed pre-warmed sandbox %s", a.req.WarmInstance)
+		a.run.Scheduling = trace.Decision(nodeID, nil)
+		if err := m.dispatch(ctx, a, nodeID, nodes); err != nil {
+			m.WarmPool.unclaim(ctx, a.req)
+			return err
+		}
+		return nil
+	}
+	nodes = m.WarmPool.spread(ctx, a, nodes)
 
-	nodeID, err := m.Scheduler.ChooseNode(ctx, req, nodes)
+	nodeID, err := scheduler.ChooseNode(ctx, a.req, nodes)
+	a.run.Scheduling = trace.Decision(nodeID, err)
 	if err != nil {
-		m.Logger.Error(ctx, "Failed to schedule sandbox", map[string]any{
-			"sandbox_id": req.ID,
-			"error":      err,
-		})
-		// Mark as failed
-		initialRun.Status = domain.RunStatusFailed
-		initialRun.Error = fmt.Sprintf("failed to schedule: %v", err)
-		initialRun.UpdatedAt = time.Now()
-		_ = m.Hades.UpdateRun(ctx, initialRun)
-		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "scheduling_failed"})
-		return fmt.Errorf("failed to schedule sandbox: %w", err)
+		// Without nodes, wait for the cluster to scale up from zero
+		if m.ScaleToZero.park(ctx, a, all) {
+			return nil
+		}
+		return m.schedulingFailed(ctx, a, err)
+	}
+	return m.dispatch(ctx, a, nodeID, nodes)
+}
+
+// newTrace returns a scheduling trace at the configured verbosity.
+func (m *Manager) newTrace() *moirai.Trace {
+	if m.SchedulingTrace == "" {
+		return moirai.NewTrace(moirai.TraceSummary)
+	}
+	return moirai.NewTrace(m.SchedulingTrace)
+}
+
+// candidateNodes returns the nodes an admitted request may be placed on,
+// recording the others in the trace on ctx.
+func candidateNodes(ctx context.Context, a *admission, nodes []domain.NodeStatus) []domain.NodeStatus {
+	trace := moirai.TraceFrom(ctx)
+
+	// Cordoned nodes take no new sandboxes
+	all := nodes
+	nodes = moirai.FilterDrainingNodes(nodes)
+	trace.ExcludeAll(all, nodes, moirai.FilterDraining, "cordoned")
+
+	// Only consider nodes the template has a kernel/rootfs variant for
+	if a.req.Arch == "" {
+		undrained := nodes
+		nodes = moirai.FilterArchNodes(nodes, a.tmpl.Architectures())
+		trace.ExcludeAll(undrained, nodes, moirai.FilterArch, fmt.Sprintf("template %s has no variant for the node's architecture", a.tmpl.ID))
 	}
+
+	// The template image counts towards the inputs held by each node
+	withImageInput(a.req, a.tmpl)
+	return nodes
+}
+
+// schedulingFailed marks the run of a request no node was found for failed.
+func (m *Manager) schedulingFailed(ctx context.Context, a *admission, err error) error {
+	m.Logger.Error(ctx, "Failed to schedule sandbox", map[string]any{
+		"sandbox_id": a.req.ID,
+		"error":      err,
+	})
+	// Mark as failed
+	a.run.Status = domain.RunStatusFailed
+	a.run.Error = fmt.Sprintf("failed to schedule: %v", err)
+	a.run.UpdatedAt = time.Now()
+	_ = m.Hades.UpdateRun(ctx, a.run)
+	m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "scheduling_failed"})
+	return fmt.Errorf("failed to schedule sandbox: %w", err)
+}
+
+// dispatch records that an admitted request was scheduled on nodeID, one of
+// nodes, and enqueues it for that node's agent.
+func (m *Manager) dispatch(ctx context.Context, a *admission, nodeID domain.NodeID, nodes []domain.NodeStatus) error {
+	req, initialRun := a.req, &a.run
 	req.NodeID = nodeID
+	if req.Arch == "" {
+		for _, node := range nodes {
+			if node.ID == nodeID {
+				req.Arch = domain.NodeArch(node.NodeInfo)
+				break
+			}
+		}
+	}
 
 	// Update run with scheduled node
 	initialRun.NodeID = nodeID
 	initialRun.Status = domain.RunStatusScheduled
 	initialRun.UpdatedAt = time.Now()
-	if err := m.Hades.UpdateRun(ctx, initialRun); err != nil {
+	if m.Outbox != nil {
+		if err := m.commitAndEnqueue(ctx, req, *initialRun); err != nil {
+			return err
+		}
+		m.publishSandboxEvent(ctx, EventSandboxSubmitted, *initialRun)
+		return nil
+	}
+	if err := m.Hades.UpdateRun(ctx, *initialRun); err != nil {
 		m.Logger.Error(ctx, "Failed to update run state to SCHEDULED", map[string]any{
 			"sandbox_id": req.ID,
 			"error":      err,
@@ -225,8 +548,8 @@ func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error
 		"node_id":    nodeID,
 	})
 
-	// 8) Enqueue into Acheron
-	if err := m.Queue.Enqueue(ctx, req); err != nil {
+	// 8) Enqueue into Acheron, holding the request back until its window opens
+	if err := m.enqueue(ctx, req); err != nil {
 		m.Logger.Error(ctx, "Failed to enqueue request", map[string]any{
 			"sandbox_id": req.ID,
 			"error":      err,
@@ -235,17 +558,93 @@ func (m *Manager) Submit(ctx context.Context, req *domain.SandboxRequest) error
 		initialRun.Status = domain.RunStatusFailed
 		initialRun.Error = fmt.Sprintf("failed to enqueue: %v", err)
 		initialRun.UpdatedAt = time.Now()
-		_ = m.Hades.UpdateRun(ctx, initialRun)
+		_ = m.Hades.UpdateRun(ctx, *initialRun)
 		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "enqueue_failed"})
 		return err
 	}
 
+	m.Logger.Info(ctx, "Request successfully enqueued", map[string]any{
+		"sandbox_id": req.ID,
+	})
+	m.publishSandboxEvent(ctx, EventSandboxSubmitted, *initialRun)
+	return nil
+}
+
+// commitAndEnqueue persists the scheduled run together with the intent to
+// enqueue its request, then delivers the request. Once committed the
+// submission stands: if the enqueue fails, the OutboxRelay retries it.
+func (m *Manager) commitAndEnqueue(ctx context.Context, req *domain.SandboxRequest, run domain.SandboxRun) error {
+	entry := acheron.OutboxEntry{Request: req, CreatedAt: time.Now()}
+	if req.Window != nil {
+		entry.NotBefore = req.Window.NotBefore
+	}
+	if err := m.Outbox.Commit(ctx, run, entry); err != nil {
+		m.Logger.Error(ctx, "Failed to commit scheduled run", map[string]any{
+			"sandbox_id": req.ID,
+			"error":      err,
+		})
+		// Mark as failed
+		run.Status = domain.RunStatusFailed
+		run.Error = fmt.Sprintf("failed to commit: %v", err)
+		run.UpdatedAt = time.Now()
+		_ = m.Hades.UpdateRun(ctx, run)
+		m.Metrics.IncCounter("sandbox_submission_failures_total", 1, hermes.Label{Key: "reason", Value: "persistence_failed"})
+		return fmt.Errorf("failed to persist run state: %w", err)
+	}
+
+	m.Logger.Info(ctx, "Scheduled sandbox", map[string]any{
+		"sandbox_id": req.ID,
+		"node_id":    run.NodeID,
+	})
+
+	if err := m.enqueue(ctx, req); err != nil {
+		m.Logger.Error(ctx, "Failed to enqueue request, leaving it to the outbox relay", map[string]any{
+			"sandbox_id": req.ID,
+			"error":      err,
+		})
+		m.Metrics.IncCounter("sandbox_outbox_deferred_total", 1)
+		return nil
+	}
+	if err := m.Outbox.MarkDelivered(ctx, req.ID); err != nil {
+		// The relay will enqueue the request a second time
+		m.Logger.Error(ctx, "Failed to mark outbox entry delivered", map[string]any{
+			"sandbox_id": req.ID,
+			"error":      err,
+		})
+	}
+
 	m.Logger.Info(ctx, "Request successfully enqueued", map[string]any{
 		"sandbox_id": req.ID,
 	})
 	return nil
 }
 
+// enqueue places the request on the queue, delaying its visibility until the
+// run window opens when the queue supports it.
+func (m *Manager) enqueue(ctx context.Context, req *domain.SandboxRequest) error {
+	delay := req.Window.Delay(time.Now())
+	if delay <= 0 {
+		return m.Queue.Enqueue(ctx, req)
+	}
+
+	delayed, ok := m.Queue.(acheron.DelayedEnqueuer)
+	if !ok {
+		// The agent re-checks the window on dequeue, so an early delivery is
+		// still held back; only the queue slot is occupied sooner.
+		m.Logger.Info(ctx, "Queue does not support delayed enqueue, enqueueing immediately", map[string]any{
+			"sandbox_id": req.ID,
+			"not_before": req.Window.NotBefore,
+		})
+		return m.Queue.Enqueue(ctx, req)
+	}
+
+	m.Logger.Info(ctx, "Delaying request until run window opens", map[string]any{
+		"sandbox_id": req.ID,
+		"not_before": req.Window.NotBefore,
+	})
+	return delayed.EnqueueAt(ctx, req, req.Window.NotBefore)
+}
+
 // ListSandboxes returns all sandboxes across all nodes.
 func (m *Manager) ListSandboxes(ctx context.Context) ([]domain.SandboxRun, error) {
 	return m.Hades.ListRuns(ctx)
@@ -272,17 +671,24 @@ func (m *Manager) KillSandbox(ctx context.Context, id domain.SandboxID) error {
 		"sandbox_id": id,
 		"node_id":    run.NodeID,
 	})
+	if m.PreWaker != nil {
+		m.PreWaker.Forget(id)
+	}
+	m.publishSandboxEvent(ctx, EventSandboxKillRequested, *run)
 	return nil
 }
 
 // HibernateSandbox sends a hibernate command to the node running the sandbox.
-func (m *Manager) HibernateSandbox(ctx context.Context, id domain.SandboxID) error {
+// The returned advice annotates the decision: it is logged and reported but
+// does not prevent the hibernation. expectedIdle may be zero when unknown.
+func (m *Manager) HibernateSandbox(ctx context.Context, id domain.SandboxID, expectedIdle time.Duration) (*hypnos.Advice, error) {
 	// Find which node is running this sandbox
 	run, err := m.Hades.GetRun(ctx, id)
 	if err != nil {
 		m.Metrics.IncCounter("sandbox_hibernate_failures_total", 1, hermes.Label{Key: "reason", Value: "not_found"})
-		return ErrSandboxNotFound
+		return nil, ErrSandboxNotFound
 	}
+	advice := m.adviseRun(run, expectedIdle)
 
 	if err := m.Control.Hibernate(ctx, run.NodeID, id); err != nil {
 		m.Logger.Error(ctx, "Failed to send hibernate command", map[string]any{
@@ -291,15 +697,18 @@ func (m *Manager) HibernateSandbox(ctx context.Context, id domain.SandboxID) err
 			"error":      err,
 		})
 		m.Metrics.IncCounter("sandbox_hibernate_failures_total", 1, hermes.Label{Key: "reason", Value: "control_error"})
-		return err
+		return nil, err
 	}
 
 	m.Logger.Info(ctx, "Hibernate command sent", map[string]any{
-		"sandbox_id": id,
-		"node_id":    run.NodeID,
+		"sandbox_id":       id,
+		"node_id":          run.NodeID,
+		"advice_hibernate": advice.Hibernate,
+		"advice_reason":    advice.Reason,
 	})
 	m.Metrics.IncCounter("sandbox_hibernate_requests_total", 1)
-	return nil
+	m.trackPreWake(ctx, run)
+	return advice, nil
 }
 
 // WakeSandbox sends a wake command to the node that hibernated the sandbox.
@@ -311,6 +720,15 @@ func (m *Manager) WakeSandbox(ctx context.Context, id domain.SandboxID) error {
 		return ErrSandboxNotFound
 	}
 
+	// A wake is a use of the sandbox; if it was pre-woken it is already up
+	if m.PreWaker != nil && m.PreWaker.Accessed(id) {
+		m.Logger.Info(ctx, "Sandbox already pre-woken", map[string]any{
+			"sandbox_id": id,
+			"node_id":    run.NodeID,
+		})
+		return nil
+	}
+
 	if err := m.Control.Wake(ctx, run.NodeID, id); err != nil {
 		m.Logger.Error(ctx, "Failed to send wake command", map[string]any{
 			"sandbox_id": id,
@@ -392,7 +810,20 @@ func (m *Manager) DeleteSnapshot(ctx context.Context, id domain.SandboxID, snapI
 		return ErrSandboxNotFound
 	}
 
-	return m.Nyx.DeleteSnapshot(ctx, run.Template, snapID)
+	if err := m.Nyx.DeleteSnapshot(ctx, run.Template, snapID); err != nil {
+		return err
+	}
+
+	// Keep the catalog in step with the store
+	if catalog, ok := m.Hades.(hades.SnapshotCatalog); ok {
+		if err := catalog.DeleteSnapshotRecord(ctx, snapID); err != nil {
+			m.Logger.Error(ctx, "Failed to remove snapshot from catalog", map[string]any{
+				"snapshot_id": snapID,
+				"error":       err,
+			})
+		}
+	}
+	return nil
 }
 
 // Exec executes a command in the sandbox.
@@ -421,14 +852,21 @@ func (m *Manager) Exec(ctx context.Context, id domain.SandboxID, cmd []string) e
 }
 
 // ExecInteractive executes a command in the sandbox with interactive streams.
+// It returns once the command exits, with a *domain.ExecExitError for
+// non-zero exit codes.
 func (m *Manager) ExecInteractive(ctx context.Context, id domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	// Find which node is running this sandbox
 	run, err := m.Hades.GetRun(ctx, id)
 	if err != nil {
 		return ErrSandboxNotFound
 	}
+	if run.Status != domain.RunStatusRunning {
+		return fmt.Errorf("%w: %s", ErrSandboxNotRunning, run.Status)
+	}
 
-	if err := m.Control.ExecInteractive(ctx, run.NodeID, id, cmd, stdin, stdout, stderr); err != nil {
+	err = m.Control.ExecInteractive(ctx, run.NodeID, id, cmd, stdin, stdout, stderr)
+	var exitErr *domain.ExecExitError
+	if err != nil && !errors.As(err, &exitErr) {
 		m.Logger.Error(ctx, "Failed to send exec interactive command", map[string]any{
 			"sandbox_id": id,
 			"node_id":    run.NodeID,
@@ -437,12 +875,13 @@ func (m *Manager) ExecInteractive(ctx context.Context, id domain.SandboxID, cmd
 		return err
 	}
 
-	m.Logger.Info(ctx, "Exec interactive command sent", map[string]any{
+	m.Logger.Info(ctx, "Exec interactive session ended", map[string]any{
 		"sandbox_id": id,
 		"node_id":    run.NodeID,
 		"command":    cmd,
+		"exit_code":  domain.ExecResultOf(err).ExitCode,
 	})
-	return nil
+	return err
 }
 
 // Reconcile rebuilds in-memory state by querying all nodes for running sandboxes.
@@ -473,6 +912,18 @@ func (m *Manager) Reconcile(ctx context.Context) error {
 			// Agent returns SandboxRun, which has NodeID.
 			// But let's enforce it matches the node we queried.
 			run.NodeID = node.ID
+			// Agents do not track run windows, submitters or retries; keep the ones recorded at submit.
+			if existing, err := m.Hades.GetRun(ctx, run.ID); err == nil && existing != nil {
+				if run.Window == nil {
+					run.Window = existing.Window
+				}
+				if run.Submitter == nil {
+					run.Submitter = existing.Submitter
+				}
+				if run.Retry == nil {
+					run.Retry = existing.Retry
+				}
+			}
 			// Status should be RUNNING if it's in the list?
 			// Runtime.List returns current state.
 
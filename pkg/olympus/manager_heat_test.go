@@ -2,6 +2,7 @@ package olympus_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -199,6 +200,38 @@ func TestManagerHeatClassification(t *testing.T) {
 		}
 	})
 
+	t.Run("PriorityFromHeat", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			priority domain.Priority
+			hint     phlegethon.HeatLevel
+			want     domain.Priority
+		}{
+			{"cold is high", "", phlegethon.HeatCold, domain.PriorityHigh},
+			{"warm is normal", "", phlegethon.HeatWarm, domain.PriorityNormal},
+			{"inferno is batch", "", phlegethon.HeatInferno, domain.PriorityBatch},
+			{"explicit priority wins", domain.PriorityBatch, phlegethon.HeatCold, domain.PriorityBatch},
+		}
+		for _, tc := range cases {
+			req := &domain.SandboxRequest{
+				Template: "test-template",
+				Priority: tc.priority,
+				Metadata: map[string]string{"heat_hint": string(tc.hint)},
+			}
+			if err := manager.Submit(context.Background(), req); err != nil {
+				t.Fatalf("%s: unexpected error: %v", tc.name, err)
+			}
+			if req.Priority != tc.want {
+				t.Errorf("%s: expected priority %s, got %s", tc.name, tc.want, req.Priority)
+			}
+		}
+
+		err := manager.Submit(context.Background(), &domain.SandboxRequest{Template: "test-template", Priority: "urgent"})
+		if !errors.Is(err, domain.ErrInvalidPriority) {
+			t.Errorf("expected ErrInvalidPriority, got %v", err)
+		}
+	})
+
 	t.Run("NilPhlegethonDoesNotCrash", func(t *testing.T) {
 		// Create manager without Phlegethon
 		managerNoHeat := &olympus.Manager{
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
 )
 
 type MemoryScheduler struct {
@@ -36,8 +37,9 @@ func (s *MemoryScheduler) ChooseNode(ctx context.Context, req *domain.SandboxReq
 		freeMem := node.Capacity.Mem - node.Allocated.Mem
 		freeCPU := node.Capacity.CPU - node.Allocated.CPU
 
-		// Check if node has sufficient resources
-		if freeMem >= req.Resources.Mem && freeCPU >= req.Resources.CPU {
+		// Check if node has sufficient resources, GPUs and swap included
+		ram, _ := moirai.MemoryOn(req, node)
+		if freeMem >= ram && freeCPU >= req.Resources.CPU && moirai.CheckAffinity(req, node) && moirai.CheckGPU(req, node) && moirai.CheckSwap(req, node) {
 			eligible = append(eligible, candidate{
 				nodeID:  node.ID,
 				freeMem: freeMem,
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
 )
 
 // AuthMiddleware enforces API key authentication.
@@ -26,14 +28,14 @@ func AuthMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Unauthorized: Missing Authorization header", http.StatusUnauthorized)
+			cerberus.WriteAuthError(w, cerberus.NewAuthenticationError("missing Authorization header", nil).WithCode(cerberus.AuthCodeMissingCredentials))
 			return
 		}
 
 		// Expect "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			http.Error(w, "Unauthorized: Invalid Authorization header format", http.StatusUnauthorized)
+			cerberus.WriteAuthError(w, cerberus.NewAuthenticationError("invalid Authorization header format", nil).WithCode(cerberus.AuthCodeMalformedCredentials))
 			return
 		}
 
@@ -41,7 +43,7 @@ func AuthMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 
 		// ConstantTimeCompare returns 1 if the two slices are equal, 0 otherwise.
 		if subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
-			http.Error(w, "Unauthorized: Invalid API Key", http.StatusUnauthorized)
+			cerberus.WriteAuthError(w, cerberus.NewAuthenticationError("invalid API key", nil))
 			return
 		}
 
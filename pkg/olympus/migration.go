@@ -0,0 +1,190 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+var (
+	ErrInvalidMigration    = errors.New("invalid migration")
+	ErrMigrationInProgress = errors.New("sandbox is already migrating")
+)
+
+// DefaultMigrationTimeout bounds a migration from the first command to the
+// sandbox running on the target.
+const DefaultMigrationTimeout = 10 * time.Minute
+
+// MigrateSandbox moves a running sandbox to the target node. The source
+// agent snapshots it through Hypnos and hands it off in Erebus, then the
+// target agent restores it, attaches its network and records itself as the
+// run's node in Hades. The migration goes on in the background once
+// started; its progress is the run's Migration field.
+func (m *Manager) MigrateSandbox(ctx context.Context, id domain.SandboxID, target domain.NodeID) (*domain.RunMigration, error) {
+	run, err := m.Hades.GetRun(ctx, id)
+	if err != nil {
+		m.Metrics.IncCounter("sandbox_migration_failures_total", 1, hermes.Label{Key: "reason", Value: "not_found"})
+		return nil, ErrSandboxNotFound
+	}
+	if run.Status != domain.RunStatusRunning {
+		return nil, ErrSandboxNotRunning
+	}
+	if run.Migration.InProgress() {
+		return nil, ErrMigrationInProgress
+	}
+	if target == "" {
+		return nil, fmt.Errorf("%w: target node is required", ErrInvalidMigration)
+	}
+	if target == run.NodeID {
+		return nil, fmt.Errorf("%w: sandbox already runs on %s", ErrInvalidMigration, target)
+	}
+	node, err := m.Hades.GetNode(ctx, target)
+	if errors.Is(err, hades.ErrNodeNotFound) {
+		return nil, fmt.Errorf("%w: node %s not found", ErrInvalidMigration, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if node.Labels[domain.NodeLabelStatus] == domain.NodeStatusDraining {
+		return nil, fmt.Errorf("%w: node %s is draining", ErrInvalidMigration, target)
+	}
+
+	migration := domain.RunMigration{
+		Source:    run.NodeID,
+		Target:    target,
+		Phase:     domain.MigrationPhaseSnapshotting,
+		StartedAt: time.Now(),
+	}
+	recorded := migration
+	run.Migration = &recorded
+	run.UpdatedAt = migration.StartedAt
+	if err := m.Hades.UpdateRun(ctx, *run); err != nil {
+		return nil, fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	if err := m.Control.MigrateOut(ctx, migration.Source, id, target); err != nil {
+		m.Logger.Error(ctx, "Failed to send migrate command", map[string]any{
+			"sandbox_id": id,
+			"node_id":    migration.Source,
+			"error":      err,
+		})
+		m.failMigration(ctx, id, "failed to reach source node: "+err.Error())
+		m.Metrics.IncCounter("sandbox_migration_failures_total", 1, hermes.Label{Key: "reason", Value: "control_error"})
+		return nil, err
+	}
+
+	m.Logger.Info(ctx, "Migration started", map[string]any{
+		"sandbox_id": id,
+		"source":     migration.Source,
+		"target":     target,
+	})
+	m.Metrics.IncCounter("sandbox_migration_requests_total", 1)
+	go m.driveMigration(id, migration)
+	return &migration, nil
+}
+
+// driveMigration tells the target to restore the sandbox once the source
+// has handed it off, and fails the migration if a step does not finish in
+// time.
+func (m *Manager) driveMigration(id domain.SandboxID, migration domain.RunMigration) {
+	timeout := m.MigrationTimeout
+	if timeout <= 0 {
+		timeout = DefaultMigrationTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := "failed"
+	defer func() {
+		m.Metrics.IncCounter("sandbox_migrations_total", 1, hermes.Label{Key: "result", Value: result})
+		m.Metrics.ObserveHistogram("sandbox_migration_duration_seconds", time.Since(migration.StartedAt).Seconds())
+	}()
+
+	handedOff, err := m.awaitMigration(ctx, id, domain.MigrationPhaseRestoring)
+	if err != nil {
+		m.failMigration(ctx, id, "source did not hand the sandbox off: "+err.Error())
+		return
+	}
+
+	if err := m.Control.MigrateIn(ctx, migration.Target, id, handedOff.SnapshotKey); err != nil {
+		m.Logger.Error(ctx, "Failed to send restore command", map[string]any{
+			"sandbox_id": id,
+			"node_id":    migration.Target,
+			"error":      err,
+		})
+		m.failMigration(ctx, id, "failed to reach target node: "+err.Error())
+		return
+	}
+
+	if _, err := m.awaitMigration(ctx, id, domain.MigrationPhaseCompleted); err != nil {
+		m.failMigration(ctx, id, "target did not restore the sandbox: "+err.Error())
+		return
+	}
+	result = "completed"
+	m.Logger.Info(ctx, "Migration completed", map[string]any{
+		"sandbox_id": id,
+		"source":     migration.Source,
+		"target":     migration.Target,
+	})
+}
+
+// awaitMigration polls Hades until the run's migration reaches phase. It
+// fails if the migration fails instead, or ctx ends first.
+func (m *Manager) awaitMigration(ctx context.Context, id domain.SandboxID, phase domain.MigrationPhase) (*domain.RunMigration, error) {
+	interval := waitPollMin
+	for {
+		// Other errors may pass; the deadline bounds them
+		run, err := m.Hades.GetRun(ctx, id)
+		if errors.Is(err, hades.ErrRunNotFound) {
+			return nil, ErrSandboxNotFound
+		}
+		if err == nil && run.Migration != nil {
+			switch migration := *run.Migration; migration.Phase {
+			case phase:
+				return &migration, nil
+			case domain.MigrationPhaseFailed:
+				return nil, errors.New(run.Migration.Error)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for phase %s", phase)
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, waitPollMax)
+	}
+}
+
+// failMigration marks the run's migration failed, unless an agent already
+// did.
+func (m *Manager) failMigration(ctx context.Context, id domain.SandboxID, reason string) {
+	// The migration's own context may be what ran out
+	ctx = context.WithoutCancel(ctx)
+	run, err := m.Hades.GetRun(ctx, id)
+	if err != nil || run.Migration == nil {
+		return
+	}
+	if run.Migration.Phase != domain.MigrationPhaseFailed {
+		failed := *run.Migration
+		failed.Phase = domain.MigrationPhaseFailed
+		failed.Error = reason
+		failed.FinishedAt = time.Now()
+		run.Migration = &failed
+		run.UpdatedAt = failed.FinishedAt
+		if err := m.Hades.UpdateRun(ctx, *run); err != nil {
+			m.Logger.Error(ctx, "Failed to record failed migration", map[string]any{"sandbox_id": id, "error": err})
+		}
+	}
+	m.Logger.Error(ctx, "Migration failed", map[string]any{
+		"sandbox_id": id,
+		"source":     run.Migration.Source,
+		"target":     run.Migration.Target,
+		"error":      run.Migration.Error,
+	})
+}
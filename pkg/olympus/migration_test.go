@@ -0,0 +1,150 @@
+package olympus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+// migratingControl plays both agents of a migration against the registry.
+type migratingControl struct {
+	olympus.NoopControlPlane
+	registry *hades.MemoryRegistry
+	failOut  string // Error the source reports, if any
+	mu       sync.Mutex
+	commands []string
+}
+
+func (c *migratingControl) update(id domain.SandboxID, fn func(*domain.SandboxRun)) {
+	ctx := context.Background()
+	run, _ := c.registry.GetRun(ctx, id)
+	migration := *run.Migration
+	run.Migration = &migration
+	fn(run)
+	c.registry.UpdateRun(ctx, *run)
+}
+
+func (c *migratingControl) MigrateOut(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, target domain.NodeID) error {
+	c.mu.Lock()
+	c.commands = append(c.commands, "out "+string(nodeID)+" "+string(target))
+	c.mu.Unlock()
+	go c.update(sandboxID, func(run *domain.SandboxRun) {
+		if c.failOut != "" {
+			run.Migration.Phase = domain.MigrationPhaseFailed
+			run.Migration.Error = c.failOut
+			return
+		}
+		run.Migration.Phase = domain.MigrationPhaseRestoring
+		run.Migration.SnapshotKey = "sleep/" + string(sandboxID) + "/1"
+	})
+	return nil
+}
+
+func (c *migratingControl) MigrateIn(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, key string) error {
+	c.mu.Lock()
+	c.commands = append(c.commands, "in "+string(nodeID)+" "+key)
+	c.mu.Unlock()
+	go c.update(sandboxID, func(run *domain.SandboxRun) {
+		run.NodeID = nodeID
+		run.Migration.Phase = domain.MigrationPhaseCompleted
+	})
+	return nil
+}
+
+func (c *migratingControl) Commands() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.commands...)
+}
+
+func newMigrationManager(t *testing.T) (*olympus.Manager, *hades.MemoryRegistry, *migratingControl) {
+	t.Helper()
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	for _, node := range []domain.NodeInfo{
+		{ID: "node-a"},
+		{ID: "node-b"},
+		{ID: "node-c", Labels: map[string]string{domain.NodeLabelStatus: domain.NodeStatusDraining}},
+	} {
+		require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{Node: node, Time: time.Now()}))
+	}
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-1", NodeID: "node-a", Status: domain.RunStatusRunning}))
+	control := &migratingControl{registry: registry}
+	return &olympus.Manager{
+		Hades:   registry,
+		Control: control,
+		Metrics: hermes.NewNoopMetrics(),
+		Logger:  &mockLogger{},
+	}, registry, control
+}
+
+func awaitPhase(t *testing.T, registry *hades.MemoryRegistry, id domain.SandboxID, phase domain.MigrationPhase) *domain.SandboxRun {
+	t.Helper()
+	var run *domain.SandboxRun
+	require.Eventually(t, func() bool {
+		run, _ = registry.GetRun(context.Background(), id)
+		return run.Migration != nil && run.Migration.Phase == phase
+	}, 5*time.Second, 20*time.Millisecond)
+	return run
+}
+
+func TestManager_MigrateSandbox(t *testing.T) {
+	ctx := context.Background()
+	manager, registry, control := newMigrationManager(t)
+
+	migration, err := manager.MigrateSandbox(ctx, "sb-1", "node-b")
+	require.NoError(t, err)
+	assert.Equal(t, domain.NodeID("node-a"), migration.Source)
+	assert.Equal(t, domain.MigrationPhaseSnapshotting, migration.Phase)
+
+	// One at a time
+	_, err = manager.MigrateSandbox(ctx, "sb-1", "node-b")
+	assert.ErrorIs(t, err, olympus.ErrMigrationInProgress)
+
+	run := awaitPhase(t, registry, "sb-1", domain.MigrationPhaseCompleted)
+	assert.Equal(t, domain.NodeID("node-b"), run.NodeID)
+	assert.Equal(t, []string{"out node-a node-b", "in node-b sleep/sb-1/1"}, control.Commands())
+}
+
+func TestManager_MigrateSandboxFailure(t *testing.T) {
+	ctx := context.Background()
+	manager, registry, control := newMigrationManager(t)
+	control.failOut = "failed to create snapshot"
+
+	_, err := manager.MigrateSandbox(ctx, "sb-1", "node-b")
+	require.NoError(t, err)
+	run := awaitPhase(t, registry, "sb-1", domain.MigrationPhaseFailed)
+	assert.Equal(t, "failed to create snapshot", run.Migration.Error)
+	assert.Equal(t, domain.NodeID("node-a"), run.NodeID)
+
+	// The target is never asked to restore
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"out node-a node-b"}, control.Commands())
+}
+
+func TestManager_MigrateSandboxValidation(t *testing.T) {
+	ctx := context.Background()
+	manager, registry, _ := newMigrationManager(t)
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "done", NodeID: "node-a", Status: domain.RunStatusSucceeded}))
+
+	_, err := manager.MigrateSandbox(ctx, "missing", "node-b")
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotFound)
+	_, err = manager.MigrateSandbox(ctx, "done", "node-b")
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotRunning)
+	for _, target := range []domain.NodeID{"", "node-a", "node-x", "node-c"} {
+		_, err = manager.MigrateSandbox(ctx, "sb-1", target)
+		assert.ErrorIs(t, err, olympus.ErrInvalidMigration, target)
+	}
+
+	run, err := registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Nil(t, run.Migration)
+}
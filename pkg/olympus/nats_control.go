@@ -0,0 +1,289 @@
+package olympus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// natsRequestTimeout bounds how long an agent may take to accept a request.
+const natsRequestTimeout = 5 * time.Second
+
+// NATSControlPlane implements ControlPlane over NATS. Kill, hibernate, wake,
+// migrate, snapshot, prefetch and restart commands are published to the node's
+// subject of a JetStream stream, so they reach an agent that reconnects
+// within domain.NATSControlMaxAge. Logs, exec and list are requests the
+// agent must acknowledge, so they fail at once when no agent listens on the
+// node instead of waiting for output that never comes.
+type NATSControlPlane struct {
+	nc *nats.Conn
+	js jetstream.JetStream
+}
+
+// NewNATSControlPlane creates the control stream if needed and returns a
+// control plane using it.
+func NewNATSControlPlane(ctx context.Context, nc *nats.Conn) (*NATSControlPlane, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     domain.NATSControlStream,
+		Subjects: []string{domain.NATSControlSubjects},
+		MaxAge:   domain.NATSControlMaxAge,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create control stream: %w", err)
+	}
+	return &NATSControlPlane{nc: nc, js: js}, nil
+}
+
+// command publishes msg to the node's control subject and waits for the
+// stream to store it.
+func (n *NATSControlPlane) command(ctx context.Context, nodeID domain.NodeID, msg string) error {
+	if _, err := n.js.Publish(ctx, domain.NATSControlSubject(nodeID), []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send command to node %s: %w", nodeID, err)
+	}
+	return nil
+}
+
+// request sends msg to the node's agent and waits for it to accept it.
+func (n *NATSControlPlane) request(ctx context.Context, nodeID domain.NodeID, msg string) error {
+	reqCtx, cancel := context.WithTimeout(ctx, natsRequestTimeout)
+	defer cancel()
+	reply, err := n.nc.RequestWithContext(reqCtx, domain.NATSRequestSubject(nodeID), []byte(msg))
+	if errors.Is(err, nats.ErrNoResponders) {
+		return fmt.Errorf("no agent is listening on node %s", nodeID)
+	}
+	if err != nil {
+		return fmt.Errorf("request to node %s failed: %w", nodeID, err)
+	}
+	if string(reply.Data) != "OK" {
+		return fmt.Errorf("agent on node %s rejected the request: %s", nodeID, reply.Data)
+	}
+	return nil
+}
+
+// subscribe subscribes to subject and waits for the server to register the
+// subscription, so nothing the agent publishes in response is missed.
+func (n *NATSControlPlane) subscribe(ctx context.Context, subject string) (*nats.Subscription, chan *nats.Msg, error) {
+	ch := make(chan *nats.Msg, 256)
+	sub, err := n.nc.ChanSubscribe(subject, ch)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := n.nc.FlushWithContext(ctx); err != nil {
+		sub.Unsubscribe()
+		return nil, nil, err
+	}
+	return sub, ch, nil
+}
+
+func (n *NATSControlPlane) Kill(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("KILL %s", sandboxID))
+}
+
+func (n *NATSControlPlane) StreamLogs(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, w io.Writer, follow bool) error {
+	// 1. Subscribe to logs FIRST to avoid race condition
+	sub, ch, err := n.subscribe(ctx, domain.NATSLogsSubject(sandboxID))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to logs: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	// 2. Ask the agent to start streaming
+	if err := n.request(ctx, nodeID, fmt.Sprintf("LOGS %s %v", sandboxID, follow)); err != nil {
+		return fmt.Errorf("failed to trigger log streaming: %w", err)
+	}
+
+	// 3. Stream logs to writer, for 5 minutes at most
+	streamCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	for {
+		select {
+		case <-streamCtx.Done():
+			if ctx.Err() == nil {
+				return fmt.Errorf("log streaming timeout after 5 minutes")
+			}
+			return ctx.Err()
+		case msg := <-ch:
+			if _, err := w.Write(msg.Data); err != nil {
+				return fmt.Errorf("failed to write logs: %w", err)
+			}
+		}
+	}
+}
+
+func (n *NATSControlPlane) Hibernate(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("HIBERNATE %s", sandboxID))
+}
+
+func (n *NATSControlPlane) Wake(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("WAKE %s", sandboxID))
+}
+
+func (n *NATSControlPlane) MigrateOut(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, target domain.NodeID) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("MIGRATE_OUT %s %s", sandboxID, target))
+}
+
+func (n *NATSControlPlane) MigrateIn(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, key string) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("MIGRATE_IN %s %s", sandboxID, key))
+}
+
+func (n *NATSControlPlane) Snapshot(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("SNAPSHOT %s", sandboxID))
+}
+
+func (n *NATSControlPlane) Exec(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, cmd []string, stdout, stderr io.Writer) error {
+	requestID := uuid.New().String()
+	sub, ch, err := n.subscribe(ctx, domain.NATSExecSubject(sandboxID, requestID))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to exec output: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	msg := strings.Join(append([]string{"EXEC", string(sandboxID), requestID}, cmd...), " ")
+	if err := n.request(ctx, nodeID, msg); err != nil {
+		return fmt.Errorf("failed to send exec command: %w", err)
+	}
+
+	// Output is not split into stdout and stderr by this protocol
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-ch:
+			if _, err := stdout.Write(msg.Data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ExecInteractive runs cmd on the agent like RedisControlPlane.ExecInteractive,
+// relaying frames over NATS subjects.
+func (n *NATSControlPlane) ExecInteractive(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	requestID := uuid.New().String()
+	stdinSubject := domain.NATSStdinSubject(requestID)
+	sub, ch, err := n.subscribe(ctx, domain.NATSExecSubject(sandboxID, requestID))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to exec output: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	msg := strings.Join(append([]string{"EXEC_INTERACTIVE", string(sandboxID), requestID}, cmd...), " ")
+	if err := n.request(ctx, nodeID, msg); err != nil {
+		return fmt.Errorf("failed to send exec command: %w", err)
+	}
+
+	// The command is killed however the session ends early. The agent may
+	// already be gone, so this is best effort.
+	done := false
+	defer func() {
+		if !done {
+			n.nc.Publish(stdinSubject, domain.ExecFrame(domain.ExecCancel, nil))
+		}
+	}()
+
+	ready := time.NewTimer(execReadyTimeout)
+	defer ready.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ready.C:
+			return fmt.Errorf("agent on node %s did not start the exec session within %s", nodeID, execReadyTimeout)
+		case msg := <-ch:
+			channel, data, err := domain.ParseExecFrame(msg.Data)
+			if err != nil {
+				continue
+			}
+			switch channel {
+			case domain.ExecReady:
+				ready.Stop()
+				if stdin != nil {
+					go n.forwardStdin(stdinSubject, stdin)
+				}
+			case domain.ExecStdout:
+				if _, err := stdout.Write(data); err != nil {
+					return err
+				}
+			case domain.ExecStderr:
+				if _, err := stderr.Write(data); err != nil {
+					return err
+				}
+			case domain.ExecStatus:
+				done = true
+				var result domain.ExecResult
+				if err := json.Unmarshal(data, &result); err != nil {
+					return fmt.Errorf("invalid exec status: %w", err)
+				}
+				return result.Err()
+			}
+		}
+	}
+}
+
+// forwardStdin publishes stdin as frames until EOF, then closes the
+// command's input.
+func (n *NATSControlPlane) forwardStdin(subject string, stdin io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		read, err := stdin.Read(buf)
+		if read > 0 {
+			if err := n.nc.Publish(subject, domain.ExecFrame(domain.ExecStdin, buf[:read])); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				n.nc.Publish(subject, domain.ExecFrame(domain.ExecClose, nil))
+			}
+			return
+		}
+	}
+}
+
+func (n *NATSControlPlane) ListSandboxes(ctx context.Context, nodeID domain.NodeID) ([]domain.SandboxRun, error) {
+	requestID := uuid.New().String()
+	sub, ch, err := n.subscribe(ctx, domain.NATSResponseSubject(requestID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to response subject: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := n.request(ctx, nodeID, fmt.Sprintf("LIST_SANDBOXES %s", requestID)); err != nil {
+		return nil, fmt.Errorf("failed to send list request: %w", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	select {
+	case <-timeoutCtx.Done():
+		return nil, fmt.Errorf("timeout waiting for agent response")
+	case msg := <-ch:
+		var runs []domain.SandboxRun
+		if err := json.Unmarshal(msg.Data, &runs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		return runs, nil
+	}
+}
+
+func (n *NATSControlPlane) PrefetchImage(ctx context.Context, nodeID domain.NodeID, ref string) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("PREFETCH_IMAGE %s", ref))
+}
+
+func (n *NATSControlPlane) RestartAgent(ctx context.Context, nodeID domain.NodeID, drainTimeout time.Duration) error {
+	return n.command(ctx, nodeID, fmt.Sprintf("RESTART %s", drainTimeout))
+}
@@ -0,0 +1,82 @@
+package olympus
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func connectNATS(t *testing.T) *nats.Conn {
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	nc, err := nats.Connect(url, nats.Timeout(time.Second))
+	if err != nil {
+		t.Skip("NATS not available")
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func TestNATSControlPlane_Kill(t *testing.T) {
+	nc := connectNATS(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	control, err := NewNATSControlPlane(ctx, nc)
+	require.NoError(t, err)
+
+	nodeID := domain.NodeID("test-node-" + time.Now().Format("150405.000"))
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	consumer, err := js.CreateOrUpdateConsumer(ctx, domain.NATSControlStream, jetstream.ConsumerConfig{
+		FilterSubject: domain.NATSControlSubject(nodeID),
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, control.Kill(ctx, nodeID, "sbx-1"))
+
+	msg, err := consumer.Next(jetstream.FetchMaxWait(5 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, "KILL sbx-1", string(msg.Data()))
+}
+
+func TestNATSControlPlane_StreamLogs(t *testing.T) {
+	nc := connectNATS(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	control, err := NewNATSControlPlane(ctx, nc)
+	require.NoError(t, err)
+
+	// No agent listening
+	err = control.StreamLogs(ctx, "missing-node", "sbx-1", &bytes.Buffer{}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no agent is listening")
+
+	// An agent that accepts the request and sends a chunk
+	sub, err := nc.Subscribe(domain.NATSRequestSubject("test-node"), func(m *nats.Msg) {
+		assert.Equal(t, "LOGS sbx-1 false", string(m.Data))
+		m.Respond([]byte("OK"))
+		nc.Publish(domain.NATSLogsSubject("sbx-1"), []byte("hello"))
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	streamCtx, stop := context.WithTimeout(ctx, time.Second)
+	defer stop()
+	var buf bytes.Buffer
+	err = control.StreamLogs(streamCtx, "test-node", "sbx-1", &buf, false)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "hello", buf.String())
+}
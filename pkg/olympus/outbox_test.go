@@ -0,0 +1,81 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// flakyQueue fails every enqueue while down.
+type flakyQueue struct {
+	*acheron.MemoryQueue
+	down bool
+}
+
+func (q *flakyQueue) Enqueue(ctx context.Context, req *domain.SandboxRequest) error {
+	if q.down {
+		return errors.New("queue unavailable")
+	}
+	return q.MemoryQueue.Enqueue(ctx, req)
+}
+
+func TestSubmit_OutboxSurvivesEnqueueFailure(t *testing.T) {
+	ctx := context.Background()
+	manager, memQueue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	queue := &flakyQueue{MemoryQueue: memQueue, down: true}
+	outbox := acheron.NewMemoryOutbox(registry)
+	manager.Queue = queue
+	manager.Outbox = outbox
+
+	req := &domain.SandboxRequest{Template: "tpl"}
+	if err := manager.Submit(ctx, req); err != nil {
+		t.Fatalf("submission committed to the outbox must succeed, got %v", err)
+	}
+
+	run, err := registry.GetRun(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("run not persisted: %v", err)
+	}
+	if run.Status != domain.RunStatusScheduled || run.NodeID != "node-1" {
+		t.Errorf("expected run SCHEDULED on node-1, got %s on %q", run.Status, run.NodeID)
+	}
+
+	// Once the queue is back the relay delivers the request exactly once
+	queue.down = false
+	relay := acheron.NewOutboxRelay(outbox, queue, &mockLogger{}, hermes.NewNoopMetrics())
+	relay.MinAge = 0
+	delivered, err := relay.Drain(ctx)
+	if err != nil || delivered != 1 {
+		t.Fatalf("expected 1 delivery, got %d (%v)", delivered, err)
+	}
+	if delivered, _ := relay.Drain(ctx); delivered != 0 {
+		t.Errorf("expected the entry to be delivered once, got %d more", delivered)
+	}
+	dequeued, _, err := queue.Dequeue(ctx)
+	if err != nil || dequeued.ID != req.ID {
+		t.Fatalf("expected %s on the queue, got %v (%v)", req.ID, dequeued, err)
+	}
+}
+
+func TestSubmit_OutboxDeliversInline(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	outbox := acheron.NewMemoryOutbox(registry)
+	manager.Outbox = outbox
+
+	if err := manager.Submit(ctx, &domain.SandboxRequest{Template: "tpl"}); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if n := queue.Len(ctx); n != 1 {
+		t.Errorf("expected the request on the queue, got %d", n)
+	}
+	pending, err := outbox.Pending(ctx, time.Now().Add(time.Second), 10)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("expected no pending outbox entries, got %d (%v)", len(pending), err)
+	}
+}
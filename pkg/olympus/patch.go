@@ -0,0 +1,221 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+)
+
+var (
+	ErrVersionConflict = errors.New("sandbox was modified since the given version")
+	ErrInvalidPatch    = errors.New("invalid sandbox patch")
+)
+
+// Limits on the user-facing fields of a sandbox.
+const (
+	MaxDisplayNameLength = 128
+	MaxLabelKeyLength    = 63
+	MaxLabelValueLength  = 256
+)
+
+// SandboxPatch is a JSON merge patch of a sandbox's user-facing fields.
+// Omitted fields are unchanged; in Labels and Metadata a null value removes
+// the key.
+type SandboxPatch struct {
+	DisplayName *string            `json:"display_name,omitempty"`
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Metadata    map[string]*string `json:"metadata,omitempty"`
+}
+
+// systemMetadata are run metadata keys maintained by the runtimes.
+var systemMetadata = map[string]bool{"runtime_type": true}
+
+// RunETag returns the ETag of a run's user-facing fields.
+func RunETag(run *domain.SandboxRun) string {
+	return strconv.Quote(strconv.FormatInt(run.ResourceVersion, 10))
+}
+
+// ParseETag returns the resource version in an If-Match header value. "*"
+// and the empty string match any version and return 0.
+func ParseETag(value string) (int64, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+	version, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("%w: malformed ETag %s", ErrInvalidPatch, value)
+	}
+	return version, nil
+}
+
+// patchRetries bounds how often a patch is re-applied when the run is
+// written by someone else between reading and writing it.
+const patchRetries = 3
+
+// PatchSandbox applies patch to the sandbox's run in Hades. If version is
+// non-zero the patch only applies while the run is still at that resource
+// version; otherwise it fails with ErrVersionConflict, so concurrent
+// editors cannot overwrite each other. Changes are audited.
+func (m *Manager) PatchSandbox(ctx context.Context, id domain.SandboxID, patch SandboxPatch, version int64) (*domain.SandboxRun, error) {
+	if err := patch.validate(); err != nil {
+		return nil, err
+	}
+
+	m.patchMu.Lock()
+	defer m.patchMu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		run, err := m.Hades.GetRun(ctx, id)
+		if errors.Is(err, hades.ErrRunNotFound) {
+			return nil, ErrSandboxNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && version != run.ResourceVersion {
+			m.Metrics.IncCounter("sandbox_patch_total", 1, hermes.Label{Key: "result", Value: "conflict"})
+			return nil, fmt.Errorf("%w: at version %d, not %d", ErrVersionConflict, run.ResourceVersion, version)
+		}
+
+		changes := patch.apply(run)
+		if len(changes) == 0 {
+			m.Metrics.IncCounter("sandbox_patch_total", 1, hermes.Label{Key: "result", Value: "unchanged"})
+			return run, nil
+		}
+		run.ResourceVersion++
+		run.UpdatedAt = time.Now()
+		// The agent may write the run meanwhile; re-read and patch again
+		// rather than overwrite its update
+		err = m.swapRun(ctx, *run)
+		if errors.Is(err, hades.ErrRunConflict) && attempt < patchRetries {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update sandbox: %w", err)
+		}
+		run.Revision++ // As stored
+
+		m.Metrics.IncCounter("sandbox_patch_total", 1, hermes.Label{Key: "result", Value: "updated"})
+		m.Logger.Info(ctx, "Sandbox updated", map[string]any{"sandbox_id": id, "resource_version": run.ResourceVersion, "changes": len(changes)})
+		m.auditPatch(ctx, run, changes)
+		return run, nil
+	}
+}
+
+// swapRun writes a run read from Hades only if it has not been written
+// since, where the registry supports compare-and-swap.
+func (m *Manager) swapRun(ctx context.Context, run domain.SandboxRun) error {
+	if swapper, ok := m.Hades.(hades.RunSwapper); ok {
+		return swapper.CompareAndSwapRun(ctx, run)
+	}
+	return m.Hades.UpdateRun(ctx, run)
+}
+
+func (p SandboxPatch) validate() error {
+	if p.DisplayName != nil && len(*p.DisplayName) > MaxDisplayNameLength {
+		return fmt.Errorf("%w: display_name longer than %d characters", ErrInvalidPatch, MaxDisplayNameLength)
+	}
+	for k, v := range p.Labels {
+		if k == "" || len(k) > MaxLabelKeyLength {
+			return fmt.Errorf("%w: label key %q must be 1-%d characters", ErrInvalidPatch, k, MaxLabelKeyLength)
+		}
+		if v != nil && len(*v) > MaxLabelValueLength {
+			return fmt.Errorf("%w: label %s longer than %d characters", ErrInvalidPatch, k, MaxLabelValueLength)
+		}
+	}
+	for k := range p.Metadata {
+		if k == "" {
+			return fmt.Errorf("%w: empty metadata key", ErrInvalidPatch)
+		}
+		if systemMetadata[k] {
+			return fmt.Errorf("%w: metadata %s is set by the runtime", ErrInvalidPatch, k)
+		}
+	}
+	return nil
+}
+
+// apply changes run and returns what changed, as audit metadata: the new
+// value of each changed field, or "" if it was removed.
+func (p SandboxPatch) apply(run *domain.SandboxRun) map[string]string {
+	changes := make(map[string]string)
+	if p.DisplayName != nil && *p.DisplayName != run.DisplayName {
+		run.DisplayName = *p.DisplayName
+		changes["display_name"] = run.DisplayName
+	}
+	run.Labels = mergeStrings(run.Labels, p.Labels, "label:", changes)
+	run.Metadata = mergeStrings(run.Metadata, p.Metadata, "metadata:", changes)
+	return changes
+}
+
+// mergeStrings applies a merge patch to m, recording changed keys under prefix.
+func mergeStrings(m map[string]string, patch map[string]*string, prefix string, changes map[string]string) map[string]string {
+	for k, v := range patch {
+		old, had := m[k]
+		switch {
+		case v == nil && had:
+			delete(m, k)
+			changes[prefix+k] = ""
+		case v != nil && (!had || old != *v):
+			if m == nil {
+				m = make(map[string]string)
+			}
+			m[k] = *v
+			changes[prefix+k] = *v
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *Manager) auditPatch(ctx context.Context, run *domain.SandboxRun, changes map[string]string) {
+	if m.Audit == nil {
+		return
+	}
+	fields := make([]string, 0, len(changes))
+	for k := range changes {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	changes["changed"] = strings.Join(fields, ",")
+	changes["resource_version"] = strconv.FormatInt(run.ResourceVersion, 10)
+
+	m.audit(ctx, run, "sandbox_updated", changes)
+}
+
+// audit records a change to a sandbox, attributed to the caller.
+func (m *Manager) audit(ctx context.Context, run *domain.SandboxRun, event string, metadata map[string]string) {
+	if m.Audit == nil {
+		return
+	}
+	record := &judges.AuditRecord{
+		AuditID:    uuid.New().String(),
+		Timestamp:  time.Now().UTC(),
+		SandboxID:  run.ID,
+		TemplateID: run.Template,
+		Event:      event,
+		Metadata:   metadata,
+	}
+	// The caller, not the submitter, is accountable for the change
+	if s := submitterFromContext(ctx); s != nil {
+		record.IdentityID = s.ID
+		record.IdentityType = s.Type
+		record.TenantID = s.TenantID
+		record.IdentityRoles = s.Roles
+	}
+	if err := m.Audit.Emit(ctx, record); err != nil {
+		m.Logger.Error(ctx, "Failed to audit sandbox change", map[string]any{"sandbox_id": run.ID, "event": event, "error": err})
+	}
+}
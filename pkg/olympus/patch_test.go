@@ -0,0 +1,91 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+type recordingAuditSink struct {
+	records []*judges.AuditRecord
+}
+
+func (s *recordingAuditSink) Emit(ctx context.Context, record *judges.AuditRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestManager_PatchSandbox(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	audit := &recordingAuditSink{}
+	manager := &olympus.Manager{Hades: registry, Audit: audit, Logger: &mockLogger{}, Metrics: hermes.NewNoopMetrics()}
+
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{
+		ID:       "sb-1",
+		Status:   domain.RunStatusRunning,
+		Metadata: map[string]string{"runtime_type": "microvm", "team": "ml"},
+	}))
+
+	run, err := manager.PatchSandbox(ctx, "sb-1", olympus.SandboxPatch{
+		DisplayName: strPtr("training job"),
+		Labels:      map[string]*string{"env": strPtr("prod")},
+		Metadata:    map[string]*string{"team": nil, olympus.DoNotDisruptKey: strPtr("true")},
+	}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), run.ResourceVersion)
+	assert.Equal(t, `"1"`, olympus.RunETag(run))
+
+	// Changes are persisted in Hades
+	stored, err := registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, "training job", stored.DisplayName)
+	assert.Equal(t, map[string]string{"env": "prod"}, stored.Labels)
+	assert.Equal(t, map[string]string{"runtime_type": "microvm", olympus.DoNotDisruptKey: "true"}, stored.Metadata)
+
+	require.Len(t, audit.records, 1)
+	assert.Equal(t, "sandbox_updated", audit.records[0].Event)
+	assert.Equal(t, "prod", audit.records[0].Metadata["label:env"])
+	assert.Equal(t, "", audit.records[0].Metadata["metadata:team"])
+
+	// A patch based on a stale version is rejected
+	_, err = manager.PatchSandbox(ctx, "sb-1", olympus.SandboxPatch{Labels: map[string]*string{"env": strPtr("dev")}}, 1)
+	require.NoError(t, err)
+	_, err = manager.PatchSandbox(ctx, "sb-1", olympus.SandboxPatch{Labels: map[string]*string{"env": strPtr("staging")}}, 1)
+	assert.ErrorIs(t, err, olympus.ErrVersionConflict)
+	stored, err = registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, "dev", stored.Labels["env"])
+	assert.Equal(t, int64(2), stored.ResourceVersion)
+
+	// No-op patches do not bump the version
+	run, err = manager.PatchSandbox(ctx, "sb-1", olympus.SandboxPatch{Labels: map[string]*string{"env": strPtr("dev")}}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), run.ResourceVersion)
+	assert.Len(t, audit.records, 2)
+
+	_, err = manager.PatchSandbox(ctx, "sb-1", olympus.SandboxPatch{Metadata: map[string]*string{"runtime_type": strPtr("wasm")}}, 0)
+	assert.ErrorIs(t, err, olympus.ErrInvalidPatch)
+
+	_, err = manager.PatchSandbox(ctx, "sb-missing", olympus.SandboxPatch{DisplayName: strPtr("x")}, 0)
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotFound)
+}
+
+func TestParseETag(t *testing.T) {
+	for value, want := range map[string]int64{"": 0, "*": 0, `"7"`: 7, `W/"3"`: 3} {
+		got, err := olympus.ParseETag(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	_, err := olympus.ParseETag(`"abc"`)
+	assert.ErrorIs(t, err, olympus.ErrInvalidPatch)
+}
@@ -2,6 +2,8 @@ package olympus
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -28,6 +30,44 @@ type SeasonRequest struct {
 	MaxNodes    int                       `json:"max_nodes"`
 	TargetUtil  float64                   `json:"target_utilization"`
 	Prewarming  persephone.PrewarmConfig  `json:"prewarming"`
+	WarmPools   map[string]int            `json:"warm_pools"`  // Template ID -> warm sandboxes
+	NodeGroups  map[string]int            `json:"node_groups"` // Node group -> nodes
+}
+
+// season validates the request and converts it to a season.
+func (req SeasonRequest) season() (*persephone.Season, error) {
+	for name, size := range req.WarmPools {
+		if size < 0 {
+			return nil, fmt.Errorf("warm_pools[%s] must not be negative", name)
+		}
+	}
+	for name, size := range req.NodeGroups {
+		if size < 0 {
+			return nil, fmt.Errorf("node_groups[%s] must not be negative", name)
+		}
+	}
+
+	return &persephone.Season{
+		ID:                req.ID,
+		Name:              req.Name,
+		Description:       req.Description,
+		Schedule:          req.Schedule,
+		MinNodes:          req.MinNodes,
+		MaxNodes:          req.MaxNodes,
+		TargetUtilization: req.TargetUtil,
+		Prewarming:        req.Prewarming,
+		WarmPools:         req.WarmPools,
+		NodeGroups:        req.NodeGroups,
+	}, nil
+}
+
+// SimulationRequest asks how a proposed season would have served the demand
+// of the last Days days
+type SimulationRequest struct {
+	Season           SeasonRequest `json:"season"`
+	Days             int           `json:"days"`               // Defaults to 7
+	SandboxesPerNode int           `json:"sandboxes_per_node"` // Defaults to 1
+	CostPerNodeHour  float64       `json:"cost_per_node_hour"`
 }
 
 // ForecastRequest represents a forecast query
@@ -54,15 +94,10 @@ func (h *PersephoneHandlers) HandleCreateSeason(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	season := &persephone.Season{
-		ID:                req.ID,
-		Name:              req.Name,
-		Description:       req.Description,
-		Schedule:          req.Schedule,
-		MinNodes:          req.MinNodes,
-		MaxNodes:          req.MaxNodes,
-		TargetUtilization: req.TargetUtil,
-		Prewarming:        req.Prewarming,
+	season, err := req.season()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	if err := h.scaler.Persephone.DefineSeason(r.Context(), season); err != nil {
@@ -80,6 +115,49 @@ func (h *PersephoneHandlers) HandleCreateSeason(w http.ResponseWriter, r *http.R
 	})
 }
 
+// HandleSimulateSeason projects queue waits, node hours and cost for a
+// proposed season over recorded demand, against the active season. Live
+// capacity is not touched.
+func (h *PersephoneHandlers) HandleSimulateSeason(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	season, err := req.Season.season()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 7
+	}
+	if req.Days < 0 || req.Days > maxSimulationDays {
+		http.Error(w, fmt.Sprintf("days must be between 1 and %d", maxSimulationDays), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.scaler.Simulate(r.Context(), season, time.Duration(req.Days)*24*time.Hour, persephone.SimulationOptions{
+		SandboxesPerNode: req.SandboxesPerNode,
+		CostPerNodeHour:  req.CostPerNodeHour,
+	})
+	switch {
+	case errors.Is(err, persephone.ErrNoHistory):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrSimulationUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
 // HandleListSeasons returns all defined seasons
 func (h *PersephoneHandlers) HandleListSeasons(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -190,3 +268,20 @@ func (h *PersephoneHandlers) HandleGetRecommendations(w http.ResponseWriter, r *
 
 	json.NewEncoder(w).Encode(recommendation)
 }
+
+// HandleGetTargets returns the active season's per-template warm-pool and
+// node-group targets against what is currently running
+func (h *PersephoneHandlers) HandleGetTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	targets, err := h.scaler.Targets(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(targets)
+}
@@ -0,0 +1,77 @@
+package olympus
+
+import (
+	"context"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+// withActiveRuns adds the scheduled and running sandboxes Hades holds to the
+// nodes' active sandboxes, if the scheduler spreads by template or any
+// admitted request has anti-affinity.
+// Heartbeats list sandboxes as the runtime sees them, without template or
+// submitter, and miss those scheduled but not started yet. If the runs
+// cannot be listed, the nodes are scheduled on as they are.
+func (m *Manager) withActiveRuns(ctx context.Context, nodes []domain.NodeStatus, admitted []*admission) []domain.NodeStatus {
+	needed := m.SpreadRuns
+	for _, a := range admitted {
+		if a.req.AntiAffinity != nil {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return nodes
+	}
+
+	runs, err := m.activeRuns(ctx)
+	if err != nil {
+		m.Logger.Error(ctx, "Failed to list runs for anti-affinity", map[string]any{"error": err})
+		return nodes
+	}
+	byNode := make(map[domain.NodeID][]domain.SandboxRun)
+	for _, run := range runs {
+		if run.NodeID != "" && run.Active() {
+			byNode[run.NodeID] = append(byNode[run.NodeID], run)
+		}
+	}
+
+	// Hades' copy of a run replaces the heartbeat's, so it counts once
+	out := make([]domain.NodeStatus, len(nodes))
+	for i, node := range nodes {
+		if placed := byNode[node.ID]; len(placed) > 0 {
+			known := make(map[domain.SandboxID]bool, len(placed))
+			for _, run := range placed {
+				known[run.ID] = true
+			}
+			var active []domain.SandboxRun
+			for _, run := range node.ActiveSandboxes {
+				if !known[run.ID] {
+					active = append(active, run)
+				}
+			}
+			node.ActiveSandboxes = append(active, placed...)
+		}
+		out[i] = node
+	}
+	return out
+}
+
+// activeRuns returns the runs placed on a node and not finished, or all runs
+// if the registry cannot select them.
+func (m *Manager) activeRuns(ctx context.Context) ([]domain.SandboxRun, error) {
+	querier, ok := m.Hades.(hades.RunQuerier)
+	if !ok {
+		return m.Hades.ListRuns(ctx)
+	}
+	var runs []domain.SandboxRun
+	for _, status := range []domain.RunStatus{domain.RunStatusScheduled, domain.RunStatusRunning} {
+		found, err := querier.QueryRuns(ctx, hades.RunQuery{Status: status})
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, found...)
+	}
+	return runs, nil
+}
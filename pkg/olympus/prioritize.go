@@ -0,0 +1,127 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ErrSandboxNotQueued is returned when cancelling or boosting a sandbox that
+// is no longer waiting in Acheron.
+var ErrSandboxNotQueued = errors.New("sandbox is not queued")
+
+// CancelQueued withdraws a pending sandbox's request from the queue before
+// an agent receives it and marks the run CANCELED. It fails with
+// ErrSandboxNotQueued once the request has been delivered, in which case
+// the sandbox must be killed instead. reason is recorded in the audit log.
+func (m *Manager) CancelQueued(ctx context.Context, id domain.SandboxID, reason string) (*domain.SandboxRun, error) {
+	m.patchMu.Lock()
+	defer m.patchMu.Unlock()
+
+	run, err := m.reorder(ctx, id, "cancel", acheron.Reorderer.Cancel)
+	if err != nil {
+		return nil, err
+	}
+	// The relay must not enqueue the request again
+	if m.Outbox != nil {
+		if err := m.Outbox.MarkDelivered(ctx, id); err != nil {
+			m.Logger.Error(ctx, "Failed to clear outbox entry of cancelled sandbox", map[string]any{"sandbox_id": id, "error": err})
+		}
+	}
+
+	now := time.Now()
+	run.Status = domain.RunStatusCanceled
+	run.Error = "cancelled while queued"
+	run.FinishedAt = now
+	run.UpdatedAt = now
+	if err := m.Hades.UpdateRun(ctx, *run); err != nil {
+		return nil, fmt.Errorf("failed to update sandbox: %w", err)
+	}
+
+	m.Logger.Info(ctx, "Queued sandbox cancelled", map[string]any{"sandbox_id": id, "node_id": run.NodeID})
+	m.audit(ctx, run, "sandbox_queue_cancelled", reorderMetadata(run, reason))
+	m.publishSandboxEvent(ctx, EventSandboxCancelled, *run)
+	return run, nil
+}
+
+// BoostQueued moves a pending sandbox's request ahead of every request on
+// its node's queue that has not been boosted. reason is recorded in the
+// audit log.
+func (m *Manager) BoostQueued(ctx context.Context, id domain.SandboxID, reason string) (*domain.SandboxRun, error) {
+	run, err := m.reorder(ctx, id, "boost", acheron.Reorderer.Boost)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Logger.Info(ctx, "Queued sandbox boosted", map[string]any{"sandbox_id": id, "node_id": run.NodeID})
+	m.audit(ctx, run, "sandbox_queue_boosted", reorderMetadata(run, reason))
+	m.publishSandboxEvent(ctx, EventSandboxBoosted, *run)
+	return run, nil
+}
+
+// reorder applies op to the queued request of a pending run, counting the
+// outcome in sandbox_queue_<name>_total.
+func (m *Manager) reorder(ctx context.Context, id domain.SandboxID, name string, op func(acheron.Reorderer, context.Context, *domain.SandboxRequest) error) (*domain.SandboxRun, error) {
+	result := "error"
+	defer func() {
+		m.Metrics.IncCounter("sandbox_queue_"+name+"_total", 1, hermes.Label{Key: "result", Value: result})
+	}()
+
+	run, err := m.Hades.GetRun(ctx, id)
+	if errors.Is(err, hades.ErrRunNotFound) {
+		result = "not_found"
+		return nil, ErrSandboxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != domain.RunStatusPending && run.Status != domain.RunStatusScheduled {
+		result = "not_queued"
+		return nil, fmt.Errorf("%w: %s is %s", ErrSandboxNotQueued, id, run.Status)
+	}
+
+	// A gang waiting for capacity has nothing in the queue yet; cancelling
+	// a member leaves ScheduleGangs to roll back the rest
+	if run.Gang != nil && run.Gang.Request != nil {
+		if name != "cancel" {
+			result = "not_queued"
+			return nil, fmt.Errorf("%w: %s waits for room for its gang", ErrSandboxNotQueued, id)
+		}
+		run.Gang.Request = nil
+		result = "ok"
+		return run, nil
+	}
+
+	reorderer, ok := m.Queue.(acheron.Reorderer)
+	if !ok {
+		result = "unsupported"
+		return nil, acheron.ErrReorderUnsupported
+	}
+	err = op(reorderer, ctx, &domain.SandboxRequest{ID: run.ID, NodeID: run.NodeID})
+	switch {
+	case errors.Is(err, acheron.ErrNotQueued):
+		result = "not_queued"
+		return nil, fmt.Errorf("%w: %s has been delivered to %s", ErrSandboxNotQueued, id, run.NodeID)
+	case errors.Is(err, acheron.ErrReorderUnsupported):
+		result = "unsupported"
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("failed to %s queued request: %w", name, err)
+	}
+	result = "ok"
+	return run, nil
+}
+
+func reorderMetadata(run *domain.SandboxRun, reason string) map[string]string {
+	metadata := map[string]string{"node_id": string(run.NodeID)}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	return metadata
+}
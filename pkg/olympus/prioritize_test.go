@@ -0,0 +1,67 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestManager_CancelAndBoostQueued(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	queue := acheron.NewMemoryQueue()
+	audit := &recordingAuditSink{}
+	manager := &olympus.Manager{
+		Queue:   acheron.NewExpiringQueue(queue, 0, hermes.NewNoopMetrics()),
+		Hades:   registry,
+		Audit:   audit,
+		Logger:  &mockLogger{},
+		Metrics: hermes.NewNoopMetrics(),
+	}
+
+	for _, id := range []domain.SandboxID{"sb-1", "sb-2", "sb-3"} {
+		require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: id, NodeID: "node-1", Status: domain.RunStatusScheduled}))
+		require.NoError(t, manager.Queue.Enqueue(ctx, &domain.SandboxRequest{ID: id, NodeID: "node-1"}))
+	}
+
+	run, err := manager.CancelQueued(ctx, "sb-1", "submitted by mistake")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusCanceled, run.Status)
+	stored, err := registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusCanceled, stored.Status)
+	assert.False(t, stored.FinishedAt.IsZero())
+
+	_, err = manager.BoostQueued(ctx, "sb-3", "")
+	require.NoError(t, err)
+
+	require.Len(t, audit.records, 2)
+	assert.Equal(t, "sandbox_queue_cancelled", audit.records[0].Event)
+	assert.Equal(t, "submitted by mistake", audit.records[0].Metadata["reason"])
+	assert.Equal(t, "sandbox_queue_boosted", audit.records[1].Event)
+	assert.Equal(t, "node-1", audit.records[1].Metadata["node_id"])
+
+	// The cancelled request is gone and the boosted one comes first
+	req, _, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SandboxID("sb-3"), req.ID)
+	req, _, err = queue.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SandboxID("sb-2"), req.ID)
+
+	// Delivered, finished and unknown sandboxes cannot be reordered
+	_, err = manager.CancelQueued(ctx, "sb-2", "")
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotQueued)
+	_, err = manager.BoostQueued(ctx, "sb-1", "")
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotQueued)
+	_, err = manager.CancelQueued(ctx, "sb-missing", "")
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotFound)
+	assert.Len(t, audit.records, 2)
+}
@@ -0,0 +1,232 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+var (
+	// ErrQuotaExceeded is returned by Submit when the sandbox would take its
+	// tenant past a quota limit.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrQuotaNotFound is returned when removing the quota of a tenant that
+	// has none.
+	ErrQuotaNotFound = errors.New("quota not found")
+)
+
+// sandboxStoragePrefixes are the Erebus key prefixes that hold a sandbox's
+// stored state: Hypnos hibernation snapshots and Thanatos exports.
+var sandboxStoragePrefixes = []string{"sleep/%s/", "exports/%s/"}
+
+// QuotaStatus is the configured quota and live usage of a tenant.
+type QuotaStatus struct {
+	TenantID    string               `json:"tenant_id,omitempty"`
+	SubmitterID string               `json:"submitter_id,omitempty"` // Set when the caller has no tenant
+	Limits      domain.ResourceQuota `json:"limits"`                 // Zero means unlimited
+	Usage       domain.ResourceQuota `json:"usage"`
+}
+
+// newQuotaStatus starts the status of the submitter's tenant, or of the
+// submitter alone when it has no tenant.
+func newQuotaStatus(sub *domain.Submitter) *QuotaStatus {
+	status := &QuotaStatus{}
+	if sub != nil {
+		if sub.TenantID != "" {
+			status.TenantID = sub.TenantID
+		} else {
+			status.SubmitterID = sub.ID
+		}
+	}
+	return status
+}
+
+// Quota reports the quota of the authenticated caller's tenant, resolved
+// from the global and tenant policies, and what the tenant holds now.
+// Callers without a tenant see the usage of their own sandboxes. Storage
+// usage is only reported when the Store implements erebus.Sizer.
+func (m *Manager) Quota(ctx context.Context) (*QuotaStatus, error) {
+	return m.quotaStatus(ctx, newQuotaStatus(submitterFromContext(ctx)))
+}
+
+// TenantQuota reports the quota and usage of a tenant, as Quota does for the
+// caller's own.
+func (m *Manager) TenantQuota(ctx context.Context, tenantID string) (*QuotaStatus, error) {
+	return m.quotaStatus(ctx, &QuotaStatus{TenantID: tenantID})
+}
+
+// ListQuotas reports the quota and usage of every tenant whose policy sets a
+// quota, ordered by tenant.
+func (m *Manager) ListQuotas(ctx context.Context) ([]*QuotaStatus, error) {
+	policies, err := m.Policies.ListPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tenants []string
+	for _, p := range policies {
+		if themis.ScopeOf(p).Level() == themis.LevelTenant && p.Quota != nil {
+			tenants = append(tenants, p.TenantID)
+		}
+	}
+	sort.Strings(tenants)
+
+	out := make([]*QuotaStatus, 0, len(tenants))
+	for _, tenantID := range tenants {
+		status, err := m.TenantQuota(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, status)
+	}
+	return out, nil
+}
+
+// SetQuota sets the quota of a tenant's policy, creating the policy if the
+// tenant has none. The policy repository keeps it, in Redis when configured.
+func (m *Manager) SetQuota(ctx context.Context, tenantID string, quota domain.ResourceQuota) (*domain.SandboxPolicy, error) {
+	if tenantID == "" {
+		return nil, fmt.Errorf("%w: tenant is required", domain.ErrInvalidQuota)
+	}
+	policy := &domain.SandboxPolicy{ID: domain.PolicyID("tenant-" + tenantID), TenantID: tenantID}
+	existing, err := m.Policies.GetScopedPolicy(ctx, themis.TenantScope(tenantID))
+	switch {
+	case err == nil:
+		copied := *existing
+		policy = &copied
+	case !errors.Is(err, themis.ErrPolicyNotFound):
+		return nil, err
+	}
+	policy.Quota = &quota
+	if err := m.Policies.UpsertPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeleteQuota removes the quota from a tenant's policy, leaving the global
+// quota to apply. The rest of the policy is kept.
+func (m *Manager) DeleteQuota(ctx context.Context, tenantID string) error {
+	existing, err := m.Policies.GetScopedPolicy(ctx, themis.TenantScope(tenantID))
+	if errors.Is(err, themis.ErrPolicyNotFound) {
+		return ErrQuotaNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Quota == nil {
+		return ErrQuotaNotFound
+	}
+	policy := *existing
+	policy.Quota = nil
+	return m.Policies.UpsertPolicy(ctx, &policy)
+}
+
+// checkQuota rejects a request that would take its submitter's tenant past
+// the sandbox, CPU, memory or GPU limits of its quota, on top of held
+// usage not yet recorded in Hades. Storage is not checked, as a new sandbox
+// holds none.
+func (m *Manager) checkQuota(ctx context.Context, req *domain.SandboxRequest, held domain.ResourceQuota) error {
+	status := newQuotaStatus(req.Submitter)
+	limits, err := m.quotaLimits(ctx, status.TenantID)
+	if err != nil {
+		return err
+	}
+	if limits == (domain.ResourceQuota{}) {
+		return nil
+	}
+	limits.StorageMB = 0
+
+	usage, err := m.quotaUsage(ctx, status, false)
+	if err != nil {
+		return err
+	}
+	usage.Sandboxes += held.Sandboxes + 1
+	usage.CPU += held.CPU + req.Resources.CPU
+	usage.Mem += held.Mem + req.Resources.Mem
+	usage.GPU += held.GPU + req.Resources.GPU.Count
+
+	over := limits.Exceeded(usage)
+	if len(over) == 0 {
+		return nil
+	}
+	owner := "tenant " + status.TenantID
+	switch {
+	case status.SubmitterID != "":
+		owner = "submitter " + status.SubmitterID
+	case status.TenantID == "":
+		owner = "unauthenticated submissions"
+	}
+	return fmt.Errorf("%w: %s would hold %s", ErrQuotaExceeded, owner, strings.Join(over, ", "))
+}
+
+// quotaStatus fills in the limits and usage of status.
+func (m *Manager) quotaStatus(ctx context.Context, status *QuotaStatus) (*QuotaStatus, error) {
+	limits, err := m.quotaLimits(ctx, status.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	status.Limits = limits
+
+	usage, err := m.quotaUsage(ctx, status, true)
+	if err != nil {
+		return nil, err
+	}
+	status.Usage = usage
+	return status, nil
+}
+
+// quotaLimits resolves a tenant's quota from the global and tenant policies.
+func (m *Manager) quotaLimits(ctx context.Context, tenantID string) (domain.ResourceQuota, error) {
+	effective, err := themis.Resolve(ctx, m.Policies, tenantID, "")
+	if err != nil {
+		return domain.ResourceQuota{}, err
+	}
+	if effective.Policy.Quota == nil {
+		return domain.ResourceQuota{}, nil
+	}
+	return *effective.Policy.Quota, nil
+}
+
+// quotaUsage sums what the tenant (or submitter) of status holds now.
+// Storage is measured only when storage is set.
+func (m *Manager) quotaUsage(ctx context.Context, status *QuotaStatus, storage bool) (domain.ResourceQuota, error) {
+	var usage domain.ResourceQuota
+	runs, err := m.ListSandboxesFiltered(ctx, RunFilter{TenantID: status.TenantID, SubmitterID: status.SubmitterID})
+	if err != nil {
+		return usage, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	var sizer erebus.Sizer
+	if storage {
+		sizer, _ = m.Store.(erebus.Sizer)
+	}
+	var storageBytes int64
+	for _, run := range runs {
+		if run.Active() {
+			usage.Sandboxes++
+			if run.Resources != nil {
+				usage.CPU += run.Resources.CPU
+				usage.Mem += run.Resources.Mem
+				usage.GPU += run.Resources.GPU.Count
+			}
+		}
+		if sizer == nil {
+			continue
+		}
+		for _, prefix := range sandboxStoragePrefixes {
+			n, err := sizer.Size(ctx, fmt.Sprintf(prefix, run.ID))
+			if err != nil {
+				return usage, fmt.Errorf("failed to size storage of %s: %w", run.ID, err)
+			}
+			storageBytes += n
+		}
+	}
+	usage.StorageMB = domain.Megabytes((storageBytes + 1<<20 - 1) >> 20)
+	return usage, nil
+}
@@ -0,0 +1,157 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+func TestManager_Quota(t *testing.T) {
+	ctx := context.Background()
+
+	policies := themis.NewMemoryRepo()
+	if err := policies.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "global", Quota: &domain.ResourceQuota{Sandboxes: 10, StorageMB: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := policies.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "tenant-acme", TenantID: "acme", Quota: &domain.ResourceQuota{CPU: 4000}}); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := hades.NewMemoryRegistry()
+	runs := []domain.SandboxRun{
+		{ID: "a1", Status: domain.RunStatusRunning, Submitter: &domain.Submitter{ID: "alice", TenantID: "acme"}, Resources: &domain.ResourceSpec{CPU: 1000, Mem: 512, GPU: domain.GPURequest{Count: 1}}},
+		{ID: "a2", Status: domain.RunStatusPending, Submitter: &domain.Submitter{ID: "bob", TenantID: "acme"}, Resources: &domain.ResourceSpec{CPU: 500, Mem: 256}},
+		{ID: "a3", Status: domain.RunStatusSucceeded, Submitter: &domain.Submitter{ID: "alice", TenantID: "acme"}, Resources: &domain.ResourceSpec{CPU: 8000}},
+		{ID: "o1", Status: domain.RunStatusRunning, Submitter: &domain.Submitter{ID: "eve", TenantID: "other"}, Resources: &domain.ResourceSpec{CPU: 8000}},
+	}
+	for _, run := range runs {
+		if err := registry.UpdateRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A finished run still holds its hibernation snapshot
+	store, err := erebus.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "sleep/a3/1.disk", strings.NewReader(strings.Repeat("x", 3<<20))); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "sleep/o1/1.disk", strings.NewReader("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &olympus.Manager{Hades: registry, Policies: policies, Store: store, Metrics: hermes.NewNoopMetrics(), Logger: &mockLogger{}}
+
+	status, err := manager.Quota(withIdentity("alice", "acme"))
+	if err != nil {
+		t.Fatalf("Quota: %v", err)
+	}
+	if status.TenantID != "acme" {
+		t.Errorf("expected tenant acme, got %q", status.TenantID)
+	}
+	wantLimits := domain.ResourceQuota{CPU: 4000, Sandboxes: 10, StorageMB: 100}
+	if status.Limits != wantLimits {
+		t.Errorf("expected limits %+v, got %+v", wantLimits, status.Limits)
+	}
+	wantUsage := domain.ResourceQuota{CPU: 1500, Mem: 768, GPU: 1, Sandboxes: 2, StorageMB: 3}
+	if status.Usage != wantUsage {
+		t.Errorf("expected usage %+v, got %+v", wantUsage, status.Usage)
+	}
+
+	// Without a tenant only the caller's own sandboxes count
+	status, err = manager.Quota(withIdentity("eve", ""))
+	if err != nil {
+		t.Fatalf("Quota: %v", err)
+	}
+	if status.SubmitterID != "eve" || status.Usage.Sandboxes != 1 || status.Limits.CPU != 0 {
+		t.Errorf("unexpected status for caller without tenant: %+v", status)
+	}
+}
+
+func TestSubmit_QuotaExceeded(t *testing.T) {
+	manager, _, _ := newRunWindowManager(t, domain.RunWindowPolicy{})
+	ctx := withIdentity("alice", "acme")
+
+	if _, err := manager.SetQuota(ctx, "acme", domain.ResourceQuota{Sandboxes: 2, CPU: 1500}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+
+	submit := func(ctx context.Context, cpu domain.MilliCPU) error {
+		return manager.Submit(ctx, &domain.SandboxRequest{Template: "tpl", Resources: domain.ResourceSpec{CPU: cpu, Mem: 128}})
+	}
+	if err := submit(ctx, 1000); err != nil {
+		t.Fatalf("first submit: %v", err)
+	}
+	if err := submit(ctx, 1000); !errors.Is(err, olympus.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded for CPU, got %v", err)
+	}
+	if err := submit(ctx, 500); err != nil {
+		t.Fatalf("second submit: %v", err)
+	}
+	err := submit(ctx, 0)
+	if !errors.Is(err, olympus.ErrQuotaExceeded) || !strings.Contains(err.Error(), "sandboxes 3/2") {
+		t.Fatalf("expected sandbox limit to be exceeded, got %v", err)
+	}
+
+	// Other tenants are not affected
+	if err := submit(withIdentity("eve", "other"), 1000); err != nil {
+		t.Fatalf("submit for other tenant: %v", err)
+	}
+
+	// Removing the quota lifts the limits
+	if err := manager.DeleteQuota(ctx, "acme"); err != nil {
+		t.Fatalf("DeleteQuota: %v", err)
+	}
+	if err := submit(ctx, 1000); err != nil {
+		t.Fatalf("submit without quota: %v", err)
+	}
+	if err := manager.DeleteQuota(ctx, "acme"); !errors.Is(err, olympus.ErrQuotaNotFound) {
+		t.Fatalf("expected ErrQuotaNotFound, got %v", err)
+	}
+}
+
+func TestManager_SetQuota(t *testing.T) {
+	ctx := context.Background()
+	policies := themis.NewMemoryRepo()
+	if err := policies.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "tenant-acme", TenantID: "acme", Entitlements: []string{"gpu-pro"}}); err != nil {
+		t.Fatal(err)
+	}
+	manager := &olympus.Manager{Hades: hades.NewMemoryRegistry(), Policies: policies, Metrics: hermes.NewNoopMetrics(), Logger: &mockLogger{}}
+
+	// The existing tenant policy is kept; a tenant without one gets a policy
+	if _, err := manager.SetQuota(ctx, "acme", domain.ResourceQuota{GPU: 2}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+	if _, err := manager.SetQuota(ctx, "globex", domain.ResourceQuota{Sandboxes: 5}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+	if _, err := manager.SetQuota(ctx, "initech", domain.ResourceQuota{GPU: -1}); !errors.Is(err, domain.ErrInvalidQuota) {
+		t.Fatalf("expected ErrInvalidQuota, got %v", err)
+	}
+
+	acme, err := policies.GetScopedPolicy(ctx, themis.TenantScope("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acme.Entitlements) != 1 || acme.Quota == nil || acme.Quota.GPU != 2 {
+		t.Errorf("unexpected acme policy: %+v", acme)
+	}
+
+	quotas, err := manager.ListQuotas(ctx)
+	if err != nil {
+		t.Fatalf("ListQuotas: %v", err)
+	}
+	if len(quotas) != 2 || quotas[0].TenantID != "acme" || quotas[1].TenantID != "globex" || quotas[1].Limits.Sandboxes != 5 {
+		t.Errorf("unexpected quotas: %+v", quotas)
+	}
+}
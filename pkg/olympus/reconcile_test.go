@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 	"github.com/tartarus-sandbox/tartarus/pkg/hades"
@@ -42,7 +43,8 @@ func (m *ReconcileMockHades) GetRun(ctx context.Context, id domain.SandboxID) (*
 func (m *ReconcileMockHades) ListRuns(ctx context.Context) ([]domain.SandboxRun, error) {
 	return nil, nil
 }
-func (m *ReconcileMockHades) MarkDraining(ctx context.Context, id domain.NodeID) error { return nil }
+func (m *ReconcileMockHades) MarkDraining(ctx context.Context, id domain.NodeID) error    { return nil }
+func (m *ReconcileMockHades) MarkSchedulable(ctx context.Context, id domain.NodeID) error { return nil }
 
 // We need the exact signature for UpdateHeartbeat.
 // It uses hades.HeartbeatPayload.
@@ -85,6 +87,21 @@ func (m *ReconcileMockControlPlane) Exec(ctx context.Context, nodeID domain.Node
 func (m *ReconcileMockControlPlane) ExecInteractive(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	return nil
 }
+func (m *ReconcileMockControlPlane) PrefetchImage(ctx context.Context, nodeID domain.NodeID, ref string) error {
+	return nil
+}
+
+func (m *ReconcileMockControlPlane) RestartAgent(ctx context.Context, nodeID domain.NodeID, drainTimeout time.Duration) error {
+	return nil
+}
+
+func (m *ReconcileMockControlPlane) MigrateOut(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, target domain.NodeID) error {
+	return nil
+}
+
+func (m *ReconcileMockControlPlane) MigrateIn(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, key string) error {
+	return nil
+}
 
 func TestReconcile(t *testing.T) {
 	// Setup
@@ -88,6 +88,18 @@ func (r *RedisControlPlane) Wake(ctx context.Context, nodeID domain.NodeID, sand
 	return r.client.Publish(ctx, topic, msg).Err()
 }
 
+func (r *RedisControlPlane) MigrateOut(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, target domain.NodeID) error {
+	topic := fmt.Sprintf("tartarus:control:%s", nodeID)
+	msg := fmt.Sprintf("MIGRATE_OUT %s %s", sandboxID, target)
+	return r.client.Publish(ctx, topic, msg).Err()
+}
+
+func (r *RedisControlPlane) MigrateIn(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, key string) error {
+	topic := fmt.Sprintf("tartarus:control:%s", nodeID)
+	msg := fmt.Sprintf("MIGRATE_IN %s %s", sandboxID, key)
+	return r.client.Publish(ctx, topic, msg).Err()
+}
+
 func (r *RedisControlPlane) Snapshot(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
 	topic := fmt.Sprintf("tartarus:control:%s", nodeID)
 	msg := fmt.Sprintf("SNAPSHOT %s", sandboxID)
@@ -138,6 +150,12 @@ func (r *RedisControlPlane) Exec(ctx context.Context, nodeID domain.NodeID, sand
 	}
 }
 
+// ExecInteractive runs cmd on the agent and relays the session as framed
+// messages (see domain.ExecChannel): output frames are demultiplexed to
+// stdout and stderr, and stdin is forwarded once the agent is ready for it,
+// then closed on EOF. It returns when the agent reports the command's exit,
+// with a *domain.ExecExitError for non-zero exit codes. Cancelling ctx kills
+// the command.
 func (r *RedisControlPlane) ExecInteractive(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	requestID := uuid.New().String()
 	responseTopic := fmt.Sprintf("tartarus:exec:%s:%s", sandboxID, requestID)
@@ -163,39 +181,82 @@ func (r *RedisControlPlane) ExecInteractive(ctx context.Context, nodeID domain.N
 		return fmt.Errorf("failed to send exec command: %w", err)
 	}
 
-	// 3. Stream stdin in background
-	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := stdin.Read(buf)
-			if n > 0 {
-				if err := r.client.Publish(ctx, stdinTopic, buf[:n]).Err(); err != nil {
-					// Failed to publish stdin, maybe context canceled
-					return
-				}
-			}
-			if err != nil {
-				// EOF or error
-				return
-			}
+	// The command is killed however the session ends early. The agent may
+	// already be gone, so this is best effort.
+	done := false
+	defer func() {
+		if !done {
+			cancelCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			r.client.Publish(cancelCtx, stdinTopic, domain.ExecFrame(domain.ExecCancel, nil))
 		}
 	}()
 
-	// 4. Stream output
+	// 3. Stream output, and stdin once the agent has subscribed to it
 	ch := pubsub.Channel()
+	ready := time.NewTimer(execReadyTimeout)
+	defer ready.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-ready.C:
+			return fmt.Errorf("agent on node %s did not start the exec session within %s", nodeID, execReadyTimeout)
 		case msg, ok := <-ch:
 			if !ok {
 				return nil
 			}
-			// Write to stdout (we don't distinguish stdout/stderr in this simple protocol yet)
-			if _, err := stdout.Write([]byte(msg.Payload)); err != nil {
-				return err
+			channel, data, err := domain.ParseExecFrame([]byte(msg.Payload))
+			if err != nil {
+				continue
+			}
+			switch channel {
+			case domain.ExecReady:
+				ready.Stop()
+				if stdin != nil {
+					go r.forwardStdin(ctx, stdinTopic, stdin)
+				}
+			case domain.ExecStdout:
+				if _, err := stdout.Write(data); err != nil {
+					return err
+				}
+			case domain.ExecStderr:
+				if _, err := stderr.Write(data); err != nil {
+					return err
+				}
+			case domain.ExecStatus:
+				done = true
+				var result domain.ExecResult
+				if err := json.Unmarshal(data, &result); err != nil {
+					return fmt.Errorf("invalid exec status: %w", err)
+				}
+				return result.Err()
+			}
+		}
+	}
+}
+
+// execReadyTimeout bounds how long an agent may take to start an exec
+// session.
+const execReadyTimeout = 10 * time.Second
+
+// forwardStdin publishes stdin as frames until EOF, then closes the
+// command's input.
+func (r *RedisControlPlane) forwardStdin(ctx context.Context, topic string, stdin io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			if err := r.client.Publish(ctx, topic, domain.ExecFrame(domain.ExecStdin, buf[:n])).Err(); err != nil {
+				return
 			}
 		}
+		if err != nil {
+			if err == io.EOF {
+				r.client.Publish(ctx, topic, domain.ExecFrame(domain.ExecClose, nil))
+			}
+			return
+		}
 	}
 }
 
@@ -235,3 +296,15 @@ func (r *RedisControlPlane) ListSandboxes(ctx context.Context, nodeID domain.Nod
 		return runs, nil
 	}
 }
+
+func (r *RedisControlPlane) PrefetchImage(ctx context.Context, nodeID domain.NodeID, ref string) error {
+	topic := fmt.Sprintf("tartarus:control:%s", nodeID)
+	msg := fmt.Sprintf("PREFETCH_IMAGE %s", ref)
+	return r.client.Publish(ctx, topic, msg).Err()
+}
+
+func (r *RedisControlPlane) RestartAgent(ctx context.Context, nodeID domain.NodeID, drainTimeout time.Duration) error {
+	topic := fmt.Sprintf("tartarus:control:%s", nodeID)
+	msg := fmt.Sprintf("RESTART %s", drainTimeout)
+	return r.client.Publish(ctx, topic, msg).Err()
+}
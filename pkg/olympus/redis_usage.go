@@ -0,0 +1,125 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultUsageRetention is how long RedisUsageStore keeps a day's usage.
+const DefaultUsageRetention = 400 * 24 * time.Hour
+
+// usageFieldSep separates the parts of a usage hash field.
+const usageFieldSep = "\x1f"
+
+// RedisUsageStore keeps API usage in Redis so every Olympus replica adds to
+// the same totals. Each day is a hash from identity, tenant, method, route
+// and counter to its count, expiring after Retention.
+type RedisUsageStore struct {
+	client    *redis.Client
+	Retention time.Duration
+}
+
+// NewRedisUsageStore creates a Redis-backed usage store.
+func NewRedisUsageStore(addr string, db int, password string) (*RedisUsageStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisUsageStore{client: client, Retention: DefaultUsageRetention}, nil
+}
+
+func usageKey(day string) string {
+	return "tartarus:usage:" + day
+}
+
+func usageField(key UsageKey, counter string) string {
+	return strings.Join([]string{key.Identity, key.TenantID, key.Method, key.Route, counter}, usageFieldSep)
+}
+
+// AddUsage implements UsageStore.
+func (s *RedisUsageStore) AddUsage(ctx context.Context, counts map[UsageKey]UsageCounts) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		days := make(map[string]bool)
+		for key, c := range counts {
+			hash := usageKey(key.Day)
+			pipe.HIncrBy(ctx, hash, usageField(key, "calls"), c.Calls)
+			if c.ClientErrors > 0 {
+				pipe.HIncrBy(ctx, hash, usageField(key, "client_errors"), c.ClientErrors)
+			}
+			if c.ServerErrors > 0 {
+				pipe.HIncrBy(ctx, hash, usageField(key, "server_errors"), c.ServerErrors)
+			}
+			days[key.Day] = true
+		}
+		for day := range days {
+			pipe.Expire(ctx, usageKey(day), s.Retention)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add usage: %w", err)
+	}
+	return nil
+}
+
+// ListUsage implements UsageStore.
+func (s *RedisUsageStore) ListUsage(ctx context.Context, from, to time.Time) ([]UsageRow, error) {
+	days := usageDays(from, to)
+	cmds := make([]*redis.MapStringStringCmd, len(days))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, day := range days {
+			cmds[i] = pipe.HGetAll(ctx, usageKey(day))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	var rows []UsageRow
+	for i, cmd := range cmds {
+		counts := make(map[UsageKey]*UsageCounts)
+		for field, val := range cmd.Val() {
+			parts := strings.Split(field, usageFieldSep)
+			if len(parts) != 5 {
+				continue
+			}
+			n, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				continue
+			}
+			key := UsageKey{Day: days[i], Identity: parts[0], TenantID: parts[1], Method: parts[2], Route: parts[3]}
+			c, ok := counts[key]
+			if !ok {
+				c = &UsageCounts{}
+				counts[key] = c
+			}
+			switch parts[4] {
+			case "calls":
+				c.Calls = n
+			case "client_errors":
+				c.ClientErrors = n
+			case "server_errors":
+				c.ServerErrors = n
+			}
+		}
+		for key, c := range counts {
+			rows = append(rows, UsageRow{UsageKey: key, UsageCounts: *c})
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,58 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ErrArchiveUnavailable is returned when Olympus has no store to read the
+// queue archive from.
+var ErrArchiveUnavailable = errors.New("queue archive not available")
+
+// MetadataReplayOf records on a replayed request the ID it was replayed from.
+const MetadataReplayOf = "replay_of"
+
+// ArchivedRequest returns the latest archived delivery of a request, as
+// written by the agents' queue archivers.
+func (m *Manager) ArchivedRequest(ctx context.Context, id domain.SandboxID) (*acheron.ArchivedPayload, error) {
+	if m.Store == nil {
+		return nil, ErrArchiveUnavailable
+	}
+	return acheron.LoadArchived(ctx, m.Store, id)
+}
+
+// ReplayArchived submits an archived request again under a fresh ID. The
+// payload is re-validated and re-scheduled like a new submission, and the
+// current caller becomes its submitter. Its run window and queue TTL, which
+// have usually passed, are dropped.
+func (m *Manager) ReplayArchived(ctx context.Context, id domain.SandboxID) (*domain.SandboxRequest, error) {
+	archived, err := m.ArchivedRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := archived.Request
+	req.ID = ""
+	req.NodeID = ""
+	req.CreatedAt = time.Time{}
+	req.ExpiresAt = time.Time{}
+	req.Window = nil
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]string)
+	}
+	req.Metadata[MetadataReplayOf] = string(id)
+
+	if err := m.Submit(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to replay %s: %w", id, err)
+	}
+	m.Logger.Info(ctx, "Replayed archived request", map[string]any{
+		"sandbox_id": req.ID,
+		"replay_of":  id,
+	})
+	return req, nil
+}
@@ -0,0 +1,73 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestManager_ReplayArchived(t *testing.T) {
+	manager := newArchManager(t, &domain.TemplateSpec{ID: "tpl"})
+	ctx := context.Background()
+
+	if _, err := manager.ReplayArchived(ctx, "sb-1"); !errors.Is(err, olympus.ErrArchiveUnavailable) {
+		t.Fatalf("expected ErrArchiveUnavailable without a store, got %v", err)
+	}
+
+	store, err := erebus.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.Store = store
+
+	// An agent dequeues the request, fails it, and archives the delivery
+	queue := acheron.NewMemoryQueue()
+	queue.Enqueue(ctx, &domain.SandboxRequest{
+		ID:        "sb-1",
+		Template:  "tpl",
+		NodeID:    "amd-node",
+		Resources: domain.ResourceSpec{CPU: 1000, Mem: 512},
+		Env:       map[string]string{"SEED": "42"},
+	})
+	archiver := acheron.NewArchivingQueue(queue, store, "amd-node", hermes.NewNoopMetrics(), &mockLogger{})
+	_, receipt, err := archiver.Dequeue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiver.Nack(ctx, receipt, "boot failed")
+	if err := archiver.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	archived, err := manager.ArchivedRequest(ctx, "sb-1")
+	if err != nil {
+		t.Fatalf("ArchivedRequest: %v", err)
+	}
+	if archived.Outcome != acheron.ArchiveNacked || archived.Reason != "boot failed" {
+		t.Errorf("unexpected archived delivery %+v", archived)
+	}
+
+	replayed, err := manager.ReplayArchived(ctx, "sb-1")
+	if err != nil {
+		t.Fatalf("ReplayArchived: %v", err)
+	}
+	if replayed.ID == "" || replayed.ID == "sb-1" {
+		t.Errorf("expected a fresh ID, got %q", replayed.ID)
+	}
+	if replayed.Metadata[olympus.MetadataReplayOf] != "sb-1" || replayed.Env["SEED"] != "42" {
+		t.Errorf("unexpected replayed request %+v", replayed)
+	}
+	if _, err := manager.Hades.GetRun(ctx, replayed.ID); err != nil {
+		t.Errorf("expected the replay to be recorded: %v", err)
+	}
+
+	if _, err := manager.ReplayArchived(ctx, "sb-unknown"); !errors.Is(err, acheron.ErrNotArchived) {
+		t.Errorf("expected ErrNotArchived, got %v", err)
+	}
+}
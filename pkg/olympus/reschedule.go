@@ -0,0 +1,115 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+// rescheduleHoldOff is how long the node that turned a request away is
+// passed over, so its heartbeat can catch up with what it runs.
+const rescheduleHoldOff = 30 * time.Second
+
+// RescheduleRuns places again the requests agents handed back because their
+// node had no room for them. A request no node has room for stays
+// RESCHEDULING until the next call. It returns the number of requests
+// placed.
+func (m *Manager) RescheduleRuns(ctx context.Context) (int, error) {
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	var nodes []domain.NodeStatus
+	now := time.Now()
+	placed, waiting := 0, 0
+	for _, run := range runs {
+		if run.Status != domain.RunStatusRescheduling || run.Rescheduling == nil {
+			continue
+		}
+		if nodes == nil {
+			if nodes, err = m.Hades.ListNodes(ctx); err != nil {
+				return placed, fmt.Errorf("failed to list nodes: %w", err)
+			}
+		}
+		err := m.reschedule(ctx, run, nodes, now)
+		switch {
+		case err == nil:
+			placed++
+			m.Metrics.IncCounter("sandbox_reschedules_total", 1, hermes.Label{Key: "result", Value: "placed"})
+		case errors.Is(err, moirai.ErrNoCapacity):
+			waiting++
+		default:
+			m.Logger.Error(ctx, "Failed to reschedule sandbox", map[string]any{"sandbox_id": run.ID, "error": err})
+			m.Metrics.IncCounter("sandbox_reschedules_total", 1, hermes.Label{Key: "result", Value: "failed"})
+		}
+	}
+	m.Metrics.SetGauge("sandbox_rescheduling", float64(waiting))
+	return placed, nil
+}
+
+// reschedule places a handed-back request, passing over the node that
+// turned it away while it is held off. If its template is gone, the run
+// fails.
+func (m *Manager) reschedule(ctx context.Context, run domain.SandboxRun, nodes []domain.NodeStatus, now time.Time) error {
+	rescheduling := *run.Rescheduling
+	req := cloneRequest(rescheduling.Request)
+	rescheduling.Request = nil
+	run.Rescheduling = &rescheduling
+	if req == nil {
+		return m.schedulingFailed(ctx, &admission{req: &domain.SandboxRequest{ID: run.ID}, run: run}, errors.New("handed-back request was not recorded"))
+	}
+	tmpl, err := m.Templates.GetTemplate(ctx, req.Template)
+	if err != nil {
+		return m.schedulingFailed(ctx, &admission{req: req, run: run}, fmt.Errorf("invalid template: %w", err))
+	}
+
+	if now.Before(rescheduling.At.Add(rescheduleHoldOff)) {
+		others := make([]domain.NodeStatus, 0, len(nodes))
+		for _, node := range nodes {
+			if node.ID != rescheduling.From {
+				others = append(others, node)
+			}
+		}
+		nodes = others
+	}
+
+	a := &admission{req: req, tmpl: tmpl, run: run}
+	trace := m.newTrace()
+	ctx = moirai.WithTrace(ctx, trace)
+	nodes = candidateNodes(ctx, a, m.withActiveRuns(ctx, nodes, []*admission{a}))
+	nodeID, err := m.Scheduler.ChooseNode(ctx, req, nodes)
+	if err != nil {
+		return err
+	}
+	a.run.Scheduling = trace.Decision(nodeID, nil)
+	m.Logger.Info(ctx, "Rescheduling sandbox turned away by its node", map[string]any{
+		"sandbox_id": run.ID,
+		"from":       rescheduling.From,
+		"node_id":    nodeID,
+		"count":      rescheduling.Count,
+	})
+	return m.dispatch(ctx, a, nodeID, nodes)
+}
+
+// RunRescheduler periodically calls RescheduleRuns until ctx is cancelled.
+func (m *Manager) RunRescheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.RescheduleRuns(ctx); err != nil {
+				m.Logger.Error(ctx, "Rescheduler failed", map[string]any{"error": err})
+			}
+		}
+	}
+}
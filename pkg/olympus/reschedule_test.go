@@ -0,0 +1,94 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+// handBack marks a run the way an over-committed agent does.
+func handBack(t *testing.T, registry *hades.MemoryRegistry, id domain.SandboxID, from domain.NodeID, at time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	run, err := registry.GetRun(ctx, id)
+	require.NoError(t, err)
+	run.Status = domain.RunStatusRescheduling
+	run.NodeID = ""
+	run.Rescheduling = &domain.RunRescheduling{
+		Request: &domain.SandboxRequest{ID: id, Template: run.Template, Resources: domain.ResourceSpec{CPU: 1000, Mem: 512}},
+		From:    from,
+		Reason:  "over-committed",
+		Count:   1,
+		At:      at,
+	}
+	require.NoError(t, registry.UpdateRun(ctx, *run))
+}
+
+func TestManager_RescheduleRuns(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	req := &domain.SandboxRequest{Template: "tpl", Resources: domain.ResourceSpec{CPU: 1000, Mem: 512}}
+	require.NoError(t, manager.Submit(ctx, req))
+	_, receipt, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NoError(t, queue.Ack(ctx, receipt))
+	handBack(t, registry, req.ID, "node-1", time.Now())
+
+	// The node that turned the request away is passed over for a while
+	placed, err := manager.RescheduleRuns(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, placed)
+	run, err := registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusRescheduling, run.Status)
+	require.NotNil(t, run.Rescheduling.Request)
+
+	// Another node with room takes it
+	heartbeat(t, registry, "node-2", 0, 0)
+	placed, err = manager.RescheduleRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, placed)
+	run, err = registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusScheduled, run.Status)
+	assert.Equal(t, domain.NodeID("node-2"), run.NodeID)
+	require.NotNil(t, run.Rescheduling)
+	assert.Nil(t, run.Rescheduling.Request)
+	assert.Equal(t, 1, run.Rescheduling.Count)
+	queued, _, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, req.ID, queued.ID)
+	assert.Equal(t, domain.NodeID("node-2"), queued.NodeID)
+
+	// Once held off long enough, the node may take it back
+	require.NoError(t, registry.MarkDraining(ctx, "node-2"))
+	handBack(t, registry, req.ID, "node-1", time.Now().Add(-time.Minute))
+	placed, err = manager.RescheduleRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, placed)
+	run, err = registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.NodeID("node-1"), run.NodeID)
+}
+
+func TestManager_RescheduleRunsTemplateGone(t *testing.T) {
+	ctx := context.Background()
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	require.NoError(t, registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-1", Template: "gone", Status: domain.RunStatusPending}))
+	handBack(t, registry, "sb-1", "node-1", time.Time{})
+
+	placed, err := manager.RescheduleRuns(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, placed)
+	run, err := registry.GetRun(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusFailed, run.Status)
+	assert.Contains(t, run.Error, "invalid template")
+	assert.Nil(t, run.Rescheduling.Request)
+}
@@ -0,0 +1,286 @@
+package olympus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/cocytus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// nodeLostError starts the error of runs whose node disappeared mid-flight.
+const nodeLostError = "node lost"
+
+// schedulingErrors start the errors Submit records on runs it could not
+// schedule or enqueue.
+var schedulingErrors = []string{"failed to list nodes", "failed to schedule", "failed to enqueue", "failed to commit"}
+
+// newRunRetry starts attempt tracking for a request with a retry policy. The
+// request is copied so it can be submitted again as it was.
+func newRunRetry(req *domain.SandboxRequest, prev *domain.SandboxRun) *domain.RunRetry {
+	if prev == nil && (req.Retry == nil || req.Retry.MaxAttempts <= 1) {
+		return nil
+	}
+	retry := &domain.RunRetry{
+		Attempt:    1,
+		OriginalID: req.ID,
+		Request:    cloneRequest(req),
+	}
+	if req.Retry != nil {
+		retry.Policy = *req.Retry
+	}
+	if prev != nil && prev.Retry != nil {
+		retry.Attempt = prev.Retry.Attempt + 1
+		retry.OriginalID = prev.Retry.OriginalID
+		retry.PreviousID = prev.ID
+	}
+	return retry
+}
+
+// cloneRequest deep-copies a request.
+func cloneRequest(req *domain.SandboxRequest) *domain.SandboxRequest {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil
+	}
+	var clone domain.SandboxRequest
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil
+	}
+	return &clone
+}
+
+// retryID names an attempt after the first.
+func retryID(original domain.SandboxID, attempt int) domain.SandboxID {
+	return domain.SandboxID(fmt.Sprintf("%s-attempt-%d", original, attempt))
+}
+
+// classifyFailure infers why a failed run failed.
+func classifyFailure(run domain.SandboxRun) domain.RetryClass {
+	if strings.HasPrefix(run.Error, nodeLostError) {
+		return domain.RetryNodeLost
+	}
+	if strings.Contains(run.Error, "poison pill") {
+		return domain.RetryPoisonPill
+	}
+	for _, prefix := range schedulingErrors {
+		if strings.HasPrefix(run.Error, prefix) {
+			return domain.RetryScheduling
+		}
+	}
+	if run.Error != "" {
+		switch cocytus.Classify(run.Error) {
+		case cocytus.ClassImagePull:
+			return domain.RetryImagePull
+		case cocytus.ClassRuntimeOOM:
+			return domain.RetryOOM
+		case cocytus.ClassTimeout:
+			return domain.RetryTimeout
+		case cocytus.ClassPoisonPill:
+			return domain.RetryPoisonPill
+		}
+	}
+	if run.ExitCode != nil && *run.ExitCode != 0 {
+		return domain.RetryExitCode
+	}
+	return domain.RetryUnknown
+}
+
+// stopReason returns why a failed attempt is not retried, or "" if it is.
+func stopReason(run domain.SandboxRun, class domain.RetryClass) string {
+	retry := run.Retry
+	switch {
+	case !retry.Policy.Retries(class):
+		return fmt.Sprintf("%s failures are not retried", class)
+	case retry.Attempt >= retry.Policy.MaxAttempts:
+		return fmt.Sprintf("gave up after %d attempts", retry.Attempt)
+	case retry.Request == nil:
+		return "request was not recorded"
+	case class == domain.RetryScheduling && retry.Attempt == 1:
+		// Submit returned the error, so the submitter knows it failed
+		return "submission failed"
+	}
+	return ""
+}
+
+// RetryFailedRuns resubmits failed runs whose retry policy covers their
+// failure, once their backoff has passed. Runs still scheduled or running on
+// a node that stopped sending heartbeats are first marked failed as lost.
+// Each attempt is a new run, linked to the one it retries. It returns the
+// number of runs it resubmitted.
+func (m *Manager) RetryFailedRuns(ctx context.Context) (int, error) {
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list runs: %w", err)
+	}
+	nodes, err := m.Hades.ListNodes(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	live := make(map[domain.NodeID]bool, len(nodes))
+	for _, node := range nodes {
+		live[node.ID] = true
+	}
+
+	now := time.Now()
+	retried := 0
+
+	for _, run := range runs {
+		if !run.Retry.Pending() {
+			continue
+		}
+		if run.Status == domain.RunStatusScheduled || run.Status == domain.RunStatusRunning {
+			if run.NodeID == "" || live[run.NodeID] {
+				continue
+			}
+			if err := m.markNodeLost(ctx, &run, now); err != nil {
+				m.Logger.Error(ctx, "Failed to mark run on lost node failed", map[string]any{
+					"sandbox_id": run.ID,
+					"node_id":    run.NodeID,
+					"error":      err,
+				})
+				continue
+			}
+		}
+		if run.Status != domain.RunStatusFailed {
+			continue
+		}
+
+		class := classifyFailure(run)
+		if reason := stopReason(run, class); reason != "" {
+			run.Retry.Class = class
+			run.Retry.Stopped = reason
+			m.finishAttempt(ctx, run, "stopped")
+			continue
+		}
+
+		failedAt := run.FinishedAt
+		if failedAt.IsZero() {
+			failedAt = run.UpdatedAt
+		}
+		if now.Before(failedAt.Add(run.Retry.Policy.Delay(run.Retry.Attempt))) {
+			continue
+		}
+
+		if m.resubmit(ctx, run, class) {
+			retried++
+		}
+	}
+
+	return retried, nil
+}
+
+// markNodeLost fails a run whose node is gone. A request still queued for
+// the node is withdrawn, so it cannot start there too if the node returns.
+func (m *Manager) markNodeLost(ctx context.Context, run *domain.SandboxRun, now time.Time) error {
+	if run.Status == domain.RunStatusScheduled && run.Retry.Request != nil {
+		if reorderer, ok := m.Queue.(acheron.Reorderer); ok {
+			queued := *run.Retry.Request
+			queued.ID = run.ID
+			queued.NodeID = run.NodeID
+			_ = reorderer.Cancel(ctx, &queued)
+		}
+	}
+
+	run.Status = domain.RunStatusFailed
+	run.Error = fmt.Sprintf("%s: %s stopped sending heartbeats", nodeLostError, run.NodeID)
+	run.FinishedAt = now
+	run.UpdatedAt = now
+	if err := m.Hades.UpdateRun(ctx, *run); err != nil {
+		return err
+	}
+	m.Logger.Info(ctx, "Marked run on lost node failed", map[string]any{
+		"sandbox_id": run.ID,
+		"node_id":    run.NodeID,
+	})
+	m.Metrics.IncCounter("sandbox_node_lost_total", 1)
+	return nil
+}
+
+// resubmit submits the next attempt of a failed run and links the two. It
+// reports whether a new attempt was submitted.
+func (m *Manager) resubmit(ctx context.Context, run domain.SandboxRun, class domain.RetryClass) bool {
+	next := cloneRequest(run.Retry.Request)
+	next.ID = retryID(run.Retry.OriginalID, run.Retry.Attempt+1)
+	next.NodeID = ""
+	next.HeatLevel = ""
+	next.CreatedAt = time.Now()
+
+	// A sweep that submitted the attempt but failed to link it leaves its run behind
+	submitted := false
+	if existing, err := m.Hades.GetRun(ctx, next.ID); err != nil || existing == nil {
+		if err := m.submit(ctx, next, &run); err != nil {
+			m.Logger.Error(ctx, "Failed to resubmit failed run", map[string]any{
+				"sandbox_id": run.ID,
+				"attempt":    run.Retry.Attempt + 1,
+				"error":      err,
+			})
+			// Attempts that got as far as a run record are retried in turn
+			if existing, getErr := m.Hades.GetRun(ctx, next.ID); getErr != nil || existing == nil {
+				run.Retry.Class = class
+				run.Retry.Stopped = fmt.Sprintf("resubmission failed: %v", err)
+				m.finishAttempt(ctx, run, "failed")
+				return false
+			}
+		} else {
+			submitted = true
+		}
+	}
+
+	run.Retry.Class = class
+	run.Retry.NextID = next.ID
+	m.finishAttempt(ctx, run, "retried")
+	m.audit(ctx, &run, "sandbox_retried", map[string]string{
+		"class":   string(class),
+		"attempt": strconv.Itoa(run.Retry.Attempt + 1),
+		"next_id": string(next.ID),
+	})
+	m.publishSandboxEvent(ctx, EventSandboxRetried, run)
+	return submitted
+}
+
+// finishAttempt records what became of a failed attempt.
+func (m *Manager) finishAttempt(ctx context.Context, run domain.SandboxRun, result string) {
+	run.UpdatedAt = time.Now()
+	if err := m.Hades.UpdateRun(ctx, run); err != nil {
+		m.Logger.Error(ctx, "Failed to record retry of run", map[string]any{
+			"sandbox_id": run.ID,
+			"error":      err,
+		})
+		return
+	}
+	m.Logger.Info(ctx, "Handled failed run", map[string]any{
+		"sandbox_id": run.ID,
+		"attempt":    run.Retry.Attempt,
+		"class":      run.Retry.Class,
+		"next_id":    run.Retry.NextID,
+		"stopped":    run.Retry.Stopped,
+	})
+	m.Metrics.IncCounter("sandbox_retries_total", 1,
+		hermes.Label{Key: "class", Value: string(run.Retry.Class)},
+		hermes.Label{Key: "result", Value: result},
+	)
+}
+
+// RunRetrier periodically calls RetryFailedRuns until ctx is cancelled.
+func (m *Manager) RunRetrier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.RetryFailedRuns(ctx); err != nil {
+				m.Logger.Error(ctx, "Run retrier failed", map[string]any{"error": err})
+			}
+		}
+	}
+}
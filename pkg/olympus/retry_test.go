@@ -0,0 +1,233 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+func newRetryManager(t *testing.T, retry *domain.RetryPolicy) (*olympus.Manager, *acheron.MemoryQueue, *hades.MemoryRegistry) {
+	t.Helper()
+	ctx := context.Background()
+
+	queue := acheron.NewMemoryQueue()
+	registry := hades.NewMemoryRegistry()
+	policyRepo := themis.NewMemoryRepo()
+	templateMgr := olympus.NewMemoryTemplateManager()
+	logger := &mockLogger{}
+
+	require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{ID: "node-1", Capacity: domain.ResourceCapacity{CPU: 8000, Mem: 16384}},
+		Time: time.Now(),
+	}))
+	require.NoError(t, templateMgr.RegisterTemplate(ctx, &domain.TemplateSpec{ID: "tpl", Name: "tpl"}))
+	require.NoError(t, policyRepo.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "pol", TemplateID: "tpl", Retry: retry}))
+
+	return &olympus.Manager{
+		Queue:     queue,
+		Hades:     registry,
+		Policies:  policyRepo,
+		Templates: templateMgr,
+		Judges:    &judges.Chain{},
+		Scheduler: moirai.NewLeastLoadedScheduler(logger),
+		Control:   &olympus.NoopControlPlane{},
+		Audit:     &recordingAuditSink{},
+		Metrics:   hermes.NewNoopMetrics(),
+		Logger:    logger,
+	}, queue, registry
+}
+
+// failRun marks a run failed as an agent would.
+func failRun(t *testing.T, registry *hades.MemoryRegistry, id domain.SandboxID, reason string) {
+	t.Helper()
+	run, err := registry.GetRun(context.Background(), id)
+	require.NoError(t, err)
+	run.Status = domain.RunStatusFailed
+	run.Error = reason
+	run.FinishedAt = time.Now()
+	require.NoError(t, registry.UpdateRun(context.Background(), *run))
+}
+
+func TestRetryFailedRuns_ResubmitsAndLinksAttempts(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRetryManager(t, nil)
+
+	req := &domain.SandboxRequest{
+		Template: "tpl",
+		Command:  []string{"train"},
+		Env:      map[string]string{"EPOCHS": "3"},
+		Retry:    &domain.RetryPolicy{MaxAttempts: 3},
+	}
+	require.NoError(t, manager.Submit(ctx, req))
+	_, _, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+
+	first, err := registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	require.NotNil(t, first.Retry)
+	assert.Equal(t, 1, first.Retry.Attempt)
+	assert.Equal(t, req.ID, first.Retry.OriginalID)
+
+	// Runs that have not failed are left alone
+	retried, err := manager.RetryFailedRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, retried)
+
+	failRun(t, registry, req.ID, "failed to get snapshot: image pull failed")
+	retried, err = manager.RetryFailedRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried)
+
+	first, err = registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	secondID := domain.SandboxID(string(req.ID) + "-attempt-2")
+	assert.Equal(t, secondID, first.Retry.NextID)
+	assert.Equal(t, domain.RetryImagePull, first.Retry.Class)
+
+	second, err := registry.GetRun(ctx, secondID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusScheduled, second.Status)
+	assert.Equal(t, 2, second.Retry.Attempt)
+	assert.Equal(t, req.ID, second.Retry.PreviousID)
+	assert.Equal(t, req.ID, second.Retry.OriginalID)
+
+	resubmitted, _, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, secondID, resubmitted.ID)
+	assert.Equal(t, []string{"train"}, resubmitted.Command)
+	assert.Equal(t, "3", resubmitted.Env["EPOCHS"])
+
+	// The linked attempt is not retried twice
+	retried, err = manager.RetryFailedRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, retried)
+}
+
+func TestRetryFailedRuns_StopsWhenNotCovered(t *testing.T) {
+	ctx := context.Background()
+	// The policy caps requests at two attempts
+	manager, queue, registry := newRetryManager(t, &domain.RetryPolicy{MaxAttempts: 2})
+
+	exitOne := 1
+	tests := []struct {
+		name    string
+		reason  string
+		exit    *int
+		stopped string
+	}{
+		{"workload failure", "", &exitOne, "exit_code failures are not retried"},
+		{"poison pill", "failed to launch (poison pill abc123, not retried)", nil, "poison_pill failures are not retried"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.SandboxRequest{Template: "tpl", Retry: &domain.RetryPolicy{MaxAttempts: 5}}
+			require.NoError(t, manager.Submit(ctx, req))
+			_, _, _ = queue.Dequeue(ctx)
+
+			run, err := registry.GetRun(ctx, req.ID)
+			require.NoError(t, err)
+			assert.Equal(t, 2, run.Retry.Policy.MaxAttempts)
+			run.Status = domain.RunStatusFailed
+			run.Error = tt.reason
+			run.ExitCode = tt.exit
+			require.NoError(t, registry.UpdateRun(ctx, *run))
+
+			retried, err := manager.RetryFailedRuns(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 0, retried)
+			run, err = registry.GetRun(ctx, req.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.stopped, run.Retry.Stopped)
+			assert.Empty(t, run.Retry.NextID)
+		})
+	}
+
+	// The second attempt is the last
+	req := &domain.SandboxRequest{Template: "tpl"}
+	require.NoError(t, manager.Submit(ctx, req))
+	failRun(t, registry, req.ID, "failed to pull image")
+	retried, err := manager.RetryFailedRuns(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, retried)
+
+	second := domain.SandboxID(string(req.ID) + "-attempt-2")
+	failRun(t, registry, second, "failed to pull image")
+	retried, err = manager.RetryFailedRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, retried)
+	run, err := registry.GetRun(ctx, second)
+	require.NoError(t, err)
+	assert.Equal(t, "gave up after 2 attempts", run.Retry.Stopped)
+}
+
+func TestRetryFailedRuns_Backoff(t *testing.T) {
+	ctx := context.Background()
+	manager, _, registry := newRetryManager(t, nil)
+
+	req := &domain.SandboxRequest{
+		Template: "tpl",
+		Retry:    &domain.RetryPolicy{MaxAttempts: 2, Backoff: time.Hour},
+	}
+	require.NoError(t, manager.Submit(ctx, req))
+	failRun(t, registry, req.ID, "failed to pull image")
+
+	retried, err := manager.RetryFailedRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, retried)
+
+	run, err := registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.True(t, run.Retry.Pending())
+}
+
+func TestRetryFailedRuns_NodeLost(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRetryManager(t, &domain.RetryPolicy{MaxAttempts: 3})
+
+	req := &domain.SandboxRequest{Template: "tpl"}
+	require.NoError(t, manager.Submit(ctx, req))
+	_, _, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+
+	// The agent started the sandbox on a node that has since gone away
+	run, err := registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	run.Status = domain.RunStatusRunning
+	run.NodeID = "node-gone"
+	require.NoError(t, registry.UpdateRun(ctx, *run))
+
+	retried, err := manager.RetryFailedRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried)
+
+	run, err = registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusFailed, run.Status)
+	assert.Contains(t, run.Error, "node lost")
+	assert.Equal(t, domain.RetryNodeLost, run.Retry.Class)
+
+	next, _, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, domain.NodeID("node-1"), next.NodeID)
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := &domain.RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.Delay(1))
+	assert.Equal(t, 2*time.Second, p.Delay(2))
+	assert.Equal(t, 4*time.Second, p.Delay(3))
+	assert.Equal(t, 5*time.Second, p.Delay(4))
+	assert.Error(t, (&domain.RetryPolicy{MaxAttempts: 0}).Validate())
+	assert.Error(t, (&domain.RetryPolicy{MaxAttempts: 2, On: []domain.RetryClass{"cosmic_rays"}}).Validate())
+}
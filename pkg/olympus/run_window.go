@@ -0,0 +1,89 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// ReapExpiredRuns enforces run windows on runs that are still in flight.
+// Runs that never started before their StartBy are marked EXPIRED; running
+// sandboxes past their Deadline are killed and marked DEADLINE_EXCEEDED.
+// It returns the number of runs it transitioned.
+func (m *Manager) ReapExpiredRuns(ctx context.Context) (int, error) {
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	now := time.Now()
+	reaped := 0
+
+	for _, run := range runs {
+		if run.Window == nil || run.Status.IsTerminal() {
+			continue
+		}
+
+		switch {
+		case (run.Status == domain.RunStatusPending || run.Status == domain.RunStatusScheduled || run.Status == domain.RunStatusRescheduling) && run.Window.StartExpired(now):
+			run.Status = domain.RunStatusExpired
+			run.Error = ErrRunWindowExpired.Error()
+
+		case run.Status == domain.RunStatusRunning && run.Window.DeadlineExceeded(now):
+			if m.Control != nil && run.NodeID != "" {
+				if err := m.Control.Kill(ctx, run.NodeID, run.ID); err != nil {
+					m.Logger.Error(ctx, "Failed to kill sandbox past deadline", map[string]any{
+						"sandbox_id": run.ID,
+						"node_id":    run.NodeID,
+						"error":      err,
+					})
+					continue
+				}
+			}
+			run.Status = domain.RunStatusDeadlineExceeded
+			run.Error = fmt.Sprintf("deadline %s exceeded", run.Window.Deadline.Format(time.RFC3339))
+
+		default:
+			continue
+		}
+
+		run.UpdatedAt = now
+		if err := m.Hades.UpdateRun(ctx, run); err != nil {
+			m.Logger.Error(ctx, "Failed to persist reaped run", map[string]any{
+				"sandbox_id": run.ID,
+				"status":     run.Status,
+				"error":      err,
+			})
+			continue
+		}
+
+		m.Logger.Info(ctx, "Run window enforced", map[string]any{
+			"sandbox_id": run.ID,
+			"status":     run.Status,
+		})
+		m.Metrics.IncCounter("sandbox_run_window_reaped_total", 1, hermes.Label{Key: "status", Value: string(run.Status)})
+		reaped++
+	}
+
+	return reaped, nil
+}
+
+// RunReaper periodically calls ReapExpiredRuns until ctx is cancelled.
+func (m *Manager) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.ReapExpiredRuns(ctx); err != nil {
+				m.Logger.Error(ctx, "Run window reaper failed", map[string]any{"error": err})
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/acheron"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/judges"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+func newRunWindowManager(t *testing.T, policy domain.RunWindowPolicy) (*olympus.Manager, *acheron.MemoryQueue, *hades.MemoryRegistry) {
+	t.Helper()
+	ctx := context.Background()
+
+	queue := acheron.NewMemoryQueue()
+	registry := hades.NewMemoryRegistry()
+	policyRepo := themis.NewMemoryRepo()
+	templateMgr := olympus.NewMemoryTemplateManager()
+	logger := &mockLogger{}
+
+	registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+		Node: domain.NodeInfo{
+			ID:       "node-1",
+			Capacity: domain.ResourceCapacity{CPU: 8000, Mem: 16384},
+		},
+		Time: time.Now(),
+	})
+	templateMgr.RegisterTemplate(ctx, &domain.TemplateSpec{ID: "tpl", Name: "tpl"})
+	policyRepo.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "pol", TemplateID: "tpl", RunWindow: policy})
+
+	return &olympus.Manager{
+		Queue:     queue,
+		Hades:     registry,
+		Policies:  policyRepo,
+		Templates: templateMgr,
+		Judges:    &judges.Chain{},
+		Scheduler: moirai.NewLeastLoadedScheduler(logger),
+		Control:   &olympus.NoopControlPlane{},
+		Metrics:   hermes.NewNoopMetrics(),
+		Logger:    logger,
+	}, queue, registry
+}
+
+func TestSubmit_RunWindowInvalid(t *testing.T) {
+	manager, _, _ := newRunWindowManager(t, domain.RunWindowPolicy{})
+	now := time.Now()
+
+	err := manager.Submit(context.Background(), &domain.SandboxRequest{
+		Template: "tpl",
+		Window:   &domain.RunWindow{NotBefore: now.Add(time.Hour), StartBy: now},
+	})
+	if !errors.Is(err, domain.ErrInvalidRunWindow) {
+		t.Fatalf("expected ErrInvalidRunWindow, got %v", err)
+	}
+}
+
+func TestSubmit_RunWindowExpired(t *testing.T) {
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	req := &domain.SandboxRequest{
+		Template: "tpl",
+		Window:   &domain.RunWindow{StartBy: time.Now().Add(-time.Minute)},
+	}
+	err := manager.Submit(context.Background(), req)
+	if !errors.Is(err, olympus.ErrRunWindowExpired) {
+		t.Fatalf("expected ErrRunWindowExpired, got %v", err)
+	}
+
+	run, err := registry.GetRun(context.Background(), req.ID)
+	if err != nil {
+		t.Fatalf("run not persisted: %v", err)
+	}
+	if run.Status != domain.RunStatusExpired {
+		t.Errorf("expected status %s, got %s", domain.RunStatusExpired, run.Status)
+	}
+	if n := queue.Len(context.Background()); n != 0 {
+		t.Errorf("expected empty queue, got %d", n)
+	}
+}
+
+func TestSubmit_RunWindowDelaysEnqueue(t *testing.T) {
+	manager, queue, _ := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	req := &domain.SandboxRequest{
+		Template: "tpl",
+		Window:   &domain.RunWindow{NotBefore: time.Now().Add(100 * time.Millisecond)},
+	}
+	if err := manager.Submit(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := queue.Len(context.Background()); n != 0 {
+		t.Fatalf("expected request to be held back, queue has %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	got, _, err := queue.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("delayed request never became visible: %v", err)
+	}
+	if got.ID != req.ID {
+		t.Errorf("expected %s, got %s", req.ID, got.ID)
+	}
+}
+
+func TestSubmit_RunWindowPolicyDefaults(t *testing.T) {
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{
+		MaxQueueTime:      time.Minute,
+		MaxCompletionTime: time.Hour,
+	})
+
+	created := time.Now()
+	req := &domain.SandboxRequest{Template: "tpl", CreatedAt: created}
+	if err := manager.Submit(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	run, err := registry.GetRun(context.Background(), req.ID)
+	if err != nil {
+		t.Fatalf("run not persisted: %v", err)
+	}
+	if run.Window == nil {
+		t.Fatal("expected policy window to be applied")
+	}
+	if !run.Window.StartBy.Equal(created.Add(time.Minute)) {
+		t.Errorf("unexpected start_by %v", run.Window.StartBy)
+	}
+	if !run.Window.Deadline.Equal(created.Add(time.Hour)) {
+		t.Errorf("unexpected deadline %v", run.Window.Deadline)
+	}
+}
+
+func TestReapExpiredRuns(t *testing.T) {
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	runs := []domain.SandboxRun{
+		{ID: "queued-late", Status: domain.RunStatusScheduled, NodeID: "node-1", Window: &domain.RunWindow{StartBy: past}},
+		{ID: "running-late", Status: domain.RunStatusRunning, NodeID: "node-1", Window: &domain.RunWindow{Deadline: past}},
+		{ID: "running-ok", Status: domain.RunStatusRunning, NodeID: "node-1", Window: &domain.RunWindow{Deadline: future}},
+		{ID: "no-window", Status: domain.RunStatusPending},
+		{ID: "done", Status: domain.RunStatusSucceeded, Window: &domain.RunWindow{Deadline: past}},
+	}
+	for _, run := range runs {
+		registry.UpdateRun(ctx, run)
+	}
+
+	reaped, err := manager.ReapExpiredRuns(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reaped != 2 {
+		t.Errorf("expected 2 reaped runs, got %d", reaped)
+	}
+
+	want := map[domain.SandboxID]domain.RunStatus{
+		"queued-late":  domain.RunStatusExpired,
+		"running-late": domain.RunStatusDeadlineExceeded,
+		"running-ok":   domain.RunStatusRunning,
+		"no-window":    domain.RunStatusPending,
+		"done":         domain.RunStatusSucceeded,
+	}
+	for id, status := range want {
+		run, err := registry.GetRun(ctx, id)
+		if err != nil {
+			t.Fatalf("GetRun(%s): %v", id, err)
+		}
+		if run.Status != status {
+			t.Errorf("%s: expected %s, got %s", id, status, run.Status)
+		}
+	}
+}
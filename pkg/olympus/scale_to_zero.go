@@ -0,0 +1,517 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+// ClusterPhase is where the cluster is in scaling to and from zero nodes.
+type ClusterPhase string
+
+const (
+	ClusterActive   ClusterPhase = "ACTIVE"   // Nodes serve work as usual
+	ClusterDraining ClusterPhase = "DRAINING" // Sandboxes are handed off and nodes released
+	ClusterZero     ClusterPhase = "ZERO"     // No nodes; submissions wake the cluster
+	ClusterWaking   ClusterPhase = "WAKING"   // Nodes are provisioned for parked submissions
+)
+
+// ScaleToZeroConfig configures releasing every node of an idle cluster.
+type ScaleToZeroConfig struct {
+	Enabled     bool
+	IdleWindow  time.Duration // Time without activity before scaling to zero (default 15m)
+	NodeGroup   string        // Node group resized to zero and back, if any
+	WakeNodes   int           // Nodes the node group is resized to on wake (default 1)
+	WakeTimeout time.Duration // Time parked submissions wait for a node before failing (default 10m)
+}
+
+func (c ScaleToZeroConfig) withDefaults() ScaleToZeroConfig {
+	if c.IdleWindow <= 0 {
+		c.IdleWindow = 15 * time.Minute
+	}
+	if c.WakeNodes <= 0 {
+		c.WakeNodes = 1
+	}
+	if c.WakeTimeout <= 0 {
+		c.WakeTimeout = 10 * time.Minute
+	}
+	return c
+}
+
+// ScaleToZeroStatus reports the coordinator's phase.
+type ScaleToZeroStatus struct {
+	Phase     ClusterPhase `json:"phase"`
+	Since     time.Time    `json:"since"`                // When the phase began
+	IdleSince time.Time    `json:"idle_since,omitempty"` // Start of the current idle stretch, while active
+	Parked    int          `json:"parked"`               // Submissions waiting for a node
+	HandedOff int          `json:"handed_off"`           // Sandboxes waiting in Erebus for a node
+}
+
+// ScaleToZero releases every node once no run has been pending or active
+// for the idle window: the agents hand their sandboxes off to Erebus
+// through Hypnos, the nodes are cordoned and, once empty, terminated or
+// their node group resized to zero. While the cluster is at zero,
+// submissions are parked as PENDING runs and the first one provisions
+// nodes; as soon as one is schedulable the parked submissions are placed
+// and the handed-off sandboxes restored on it.
+type ScaleToZero struct {
+	Hades       hades.Registry
+	Manager     *Manager
+	Provisioner NodeProvisioner // Optional; terminates drained nodes
+	NodeGroups  NodeGroupScaler // Optional; resizes Config.NodeGroup to zero and back
+	Logger      hermes.Logger
+	Metrics     hermes.Metrics
+	Config      ScaleToZeroConfig
+
+	mu        sync.Mutex
+	phase     ClusterPhase
+	since     time.Time
+	idleSince time.Time
+	cpu       map[domain.SandboxID]uint64 // CPU time of each sandbox at the last tick
+	drained   map[domain.NodeID]bool      // Nodes cordoned here and not yet terminated
+	now       func() time.Time
+}
+
+func NewScaleToZero(h hades.Registry, m *Manager, p NodeProvisioner, g NodeGroupScaler, l hermes.Logger, met hermes.Metrics, cfg ScaleToZeroConfig) *ScaleToZero {
+	return &ScaleToZero{
+		Hades:       h,
+		Manager:     m,
+		Provisioner: p,
+		NodeGroups:  g,
+		Logger:      l,
+		Metrics:     met,
+		Config:      cfg.withDefaults(),
+		phase:       ClusterActive,
+		since:       time.Now(),
+		cpu:         make(map[domain.SandboxID]uint64),
+		drained:     make(map[domain.NodeID]bool),
+		now:         time.Now,
+	}
+}
+
+// Status returns the current phase and what waits on a node.
+func (s *ScaleToZero) Status(ctx context.Context) (*ScaleToZeroStatus, error) {
+	runs, err := s.Hades.ListRuns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	s.mu.Lock()
+	status := &ScaleToZeroStatus{Phase: s.phase, Since: s.since}
+	if s.phase == ClusterActive {
+		status.IdleSince = s.idleSince
+	}
+	s.mu.Unlock()
+	for _, run := range runs {
+		switch {
+		case parkedRun(run):
+			status.Parked++
+		case run.Migration.Parked():
+			status.HandedOff++
+		}
+	}
+	return status, nil
+}
+
+// Active reports whether the cluster serves work as usual. Warm pools and
+// node-group targets are left alone while it is not.
+func (s *ScaleToZero) Active() bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.phase == ClusterActive
+}
+
+// Run advances the coordinator by one step. The scaler calls it every tick.
+func (s *ScaleToZero) Run(ctx context.Context) error {
+	nodes, err := s.Hades.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	runs, err := s.Hades.ListRuns(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	s.mu.Lock()
+	phase := s.phase
+	s.mu.Unlock()
+
+	if phase != ClusterDraining {
+		s.terminateDrained(ctx, nodes)
+	}
+	switch phase {
+	case ClusterActive:
+		s.watchIdle(ctx, nodes, runs)
+	case ClusterDraining:
+		s.release(ctx, nodes)
+	case ClusterZero:
+		// A node that joined on its own takes work again
+		if len(moirai.FilterDrainingNodes(nodes)) > 0 {
+			s.transition(ctx, ClusterActive)
+		}
+	case ClusterWaking:
+		s.resume(ctx, nodes, runs)
+	}
+	s.Metrics.SetGauge("scale_to_zero_parked", float64(countRuns(runs, parkedRun)))
+	return nil
+}
+
+// watchIdle starts draining the cluster once it has been idle for the idle
+// window.
+func (s *ScaleToZero) watchIdle(ctx context.Context, nodes []domain.NodeStatus, runs []domain.SandboxRun) {
+	now := s.now()
+	busy := s.busy(ctx, nodes, runs)
+
+	s.mu.Lock()
+	if busy || s.idleSince.IsZero() {
+		s.idleSince = now
+	}
+	idleFor := now.Sub(s.idleSince)
+	s.mu.Unlock()
+	if busy || idleFor < s.Config.IdleWindow {
+		return
+	}
+
+	s.Logger.Info(ctx, "Cluster idle; scaling to zero", map[string]any{"idle_for": idleFor.String(), "nodes": len(nodes)})
+	s.transition(ctx, ClusterDraining)
+	for _, node := range nodes {
+		s.cordon(ctx, node.ID)
+	}
+	for _, run := range runs {
+		if run.Status == domain.RunStatusRunning && !run.Migration.InProgress() {
+			s.handOff(ctx, run)
+		}
+	}
+}
+
+// busy reports whether anything is queued, pending or active. A running
+// sandbox is active when its CPU time advanced since the last tick; warm
+// pool sandboxes never are, and sandboxes marked do-not-disrupt always are.
+func (s *ScaleToZero) busy(ctx context.Context, nodes []domain.NodeStatus, runs []domain.SandboxRun) bool {
+	if s.Manager.Queue != nil && s.Manager.Queue.Len(ctx) > 0 {
+		return true
+	}
+	for _, run := range runs {
+		if run.Status == domain.RunStatusPending || run.Status == domain.RunStatusScheduled || run.Status == domain.RunStatusRescheduling {
+			return true
+		}
+		if run.Migration.InProgress() && !run.Migration.Parked() {
+			return true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[domain.SandboxID]uint64)
+	active := false
+	for _, node := range nodes {
+		for _, run := range node.ActiveSandboxes {
+			seen[run.ID] = run.CPUUsageUsec
+			prev, known := s.cpu[run.ID]
+			switch {
+			case run.Metadata[DoNotDisruptKey] == "true":
+				active = true
+			case run.Metadata["warm"] == "true":
+			case !known || run.CPUUsageUsec > prev:
+				active = true
+			}
+		}
+	}
+	s.cpu = seen
+	return active
+}
+
+// handOff has the run's agent snapshot its sandbox and hand it off to
+// Erebus, to be restored on whichever node runs it after the wake.
+func (s *ScaleToZero) handOff(ctx context.Context, run domain.SandboxRun) {
+	run.Migration = &domain.RunMigration{
+		Source:    run.NodeID,
+		Target:    domain.MigrationTargetStore,
+		Phase:     domain.MigrationPhaseSnapshotting,
+		StartedAt: s.now(),
+	}
+	run.UpdatedAt = run.Migration.StartedAt
+	if err := s.Hades.UpdateRun(ctx, run); err != nil {
+		s.Logger.Error(ctx, "Failed to record hand-off", map[string]any{"sandbox_id": run.ID, "error": err})
+		return
+	}
+	if err := s.Manager.Control.MigrateOut(ctx, run.NodeID, run.ID, domain.MigrationTargetStore); err != nil {
+		s.Logger.Error(ctx, "Failed to hand sandbox off", map[string]any{"sandbox_id": run.ID, "node_id": run.NodeID, "error": err})
+		s.Manager.failMigration(ctx, run.ID, "failed to reach node: "+err.Error())
+		return
+	}
+	s.Metrics.IncCounter("scale_to_zero_actions_total", 1, hermes.Label{Key: "action", Value: "hand_off"})
+}
+
+// release cordons nodes that joined since the drain began and terminates
+// the drained nodes that have emptied out. Once every node is empty the
+// node group is scaled to zero and the cluster is at zero.
+func (s *ScaleToZero) release(ctx context.Context, nodes []domain.NodeStatus) {
+	remaining := 0
+	for _, node := range nodes {
+		if !node.Draining() {
+			s.cordon(ctx, node.ID)
+			remaining++
+		} else if !nodeEmpty(node) {
+			remaining++
+		}
+	}
+	s.terminateDrained(ctx, nodes)
+	if remaining > 0 {
+		return
+	}
+
+	if s.NodeGroups != nil && s.Config.NodeGroup != "" {
+		if err := s.NodeGroups.ScaleNodeGroup(ctx, s.Config.NodeGroup, 0); err != nil {
+			s.Logger.Error(ctx, "Failed to scale node group to zero", map[string]any{"group": s.Config.NodeGroup, "error": err})
+			return
+		}
+	} else if s.Provisioner == nil {
+		s.Logger.Info(ctx, "Nodes drained and flagged for removal; no provisioner configured", map[string]any{"nodes": len(nodes)})
+	}
+	s.transition(ctx, ClusterZero)
+}
+
+// cordon stops work being placed on the node and remembers it for
+// termination.
+func (s *ScaleToZero) cordon(ctx context.Context, id domain.NodeID) {
+	if err := s.Hades.MarkDraining(ctx, id); err != nil {
+		s.Logger.Error(ctx, "Failed to cordon node for scale to zero", map[string]any{"node_id": id, "error": err})
+		return
+	}
+	s.mu.Lock()
+	s.drained[id] = true
+	s.mu.Unlock()
+}
+
+// terminateDrained hands the nodes this coordinator cordoned to the
+// provisioner once they are empty, including after a wake interrupted the
+// drain.
+func (s *ScaleToZero) terminateDrained(ctx context.Context, nodes []domain.NodeStatus) {
+	if s.Provisioner == nil {
+		return
+	}
+	for _, node := range nodes {
+		s.mu.Lock()
+		drained := s.drained[node.ID]
+		s.mu.Unlock()
+		if !drained || !node.Draining() || !nodeEmpty(node) {
+			continue
+		}
+		if err := s.Provisioner.TerminateNode(ctx, node.ID); err != nil {
+			s.Logger.Error(ctx, "Failed to terminate node", map[string]any{"node_id": node.ID, "error": err})
+			continue
+		}
+		s.mu.Lock()
+		delete(s.drained, node.ID)
+		s.mu.Unlock()
+		s.Metrics.IncCounter("scale_to_zero_actions_total", 1, hermes.Label{Key: "action", Value: "terminate"})
+	}
+}
+
+// park holds an admitted request that could not be placed because the
+// cluster is scaling to or from zero, and starts the wake. It reports
+// whether the request was parked; otherwise its scheduling failed.
+func (s *ScaleToZero) park(ctx context.Context, a *admission, nodes []domain.NodeStatus) bool {
+	if s == nil || !s.Config.Enabled {
+		return false
+	}
+	s.mu.Lock()
+	phase := s.phase
+	s.mu.Unlock()
+	if phase == ClusterActive && len(moirai.FilterDrainingNodes(nodes)) > 0 {
+		return false
+	}
+
+	a.req.NodeID = ""
+	a.run.Status = domain.RunStatusPending
+	a.run.Parked = cloneRequest(a.req)
+	a.run.UpdatedAt = s.now()
+	if err := s.Hades.UpdateRun(ctx, a.run); err != nil {
+		s.Logger.Error(ctx, "Failed to park submission", map[string]any{"sandbox_id": a.req.ID, "error": err})
+		return false
+	}
+	s.Logger.Info(ctx, "Submission parked until the cluster wakes", map[string]any{"sandbox_id": a.req.ID, "phase": phase})
+	s.wake(ctx)
+	return true
+}
+
+// wake provisions nodes unless the cluster is already waking.
+func (s *ScaleToZero) wake(ctx context.Context) {
+	s.mu.Lock()
+	if s.phase == ClusterWaking {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.transition(ctx, ClusterWaking)
+
+	if s.NodeGroups == nil || s.Config.NodeGroup == "" {
+		s.Logger.Info(ctx, "Waiting for a node to join; no node group configured", nil)
+		return
+	}
+	if err := s.NodeGroups.ScaleNodeGroup(ctx, s.Config.NodeGroup, s.Config.WakeNodes); err != nil {
+		s.Logger.Error(ctx, "Failed to scale node group up", map[string]any{"group": s.Config.NodeGroup, "error": err})
+		return
+	}
+	s.Metrics.IncCounter("scale_to_zero_actions_total", 1, hermes.Label{Key: "action", Value: "provision"})
+}
+
+// resume places the parked submissions and restores the handed-off
+// sandboxes once a node is schedulable. Parked submissions fail when no
+// node is up by the wake timeout.
+func (s *ScaleToZero) resume(ctx context.Context, nodes []domain.NodeStatus, runs []domain.SandboxRun) {
+	s.mu.Lock()
+	waiting := s.now().Sub(s.since)
+	s.mu.Unlock()
+
+	schedulable := moirai.FilterDrainingNodes(nodes)
+	if len(schedulable) == 0 {
+		if waiting >= s.Config.WakeTimeout {
+			s.failParked(ctx, runs, errors.New("no node joined before the wake timeout"))
+			s.transition(ctx, ClusterZero)
+		}
+		return
+	}
+
+	left := 0
+	for _, run := range runs {
+		switch {
+		case parkedRun(run):
+			if !s.placeParked(ctx, run) {
+				left++
+			}
+		case run.Migration.Parked():
+			if !s.restore(ctx, run, schedulable) {
+				left++
+			}
+		}
+	}
+	if left == 0 {
+		s.mu.Lock()
+		s.idleSince = time.Time{}
+		s.mu.Unlock()
+		s.transition(ctx, ClusterActive)
+		return
+	}
+	if waiting >= s.Config.WakeTimeout {
+		// Nodes are up but lack room; further submissions are scheduled as usual
+		s.transition(ctx, ClusterActive)
+		s.failParked(ctx, runs, moirai.ErrNoCapacity)
+	}
+}
+
+// placeParked schedules a parked submission. It reports whether it left
+// the parked state.
+func (s *ScaleToZero) placeParked(ctx context.Context, run domain.SandboxRun) bool {
+	req := cloneRequest(run.Parked)
+	run.Parked = nil
+	if req == nil {
+		s.Manager.schedulingFailed(ctx, &admission{req: &domain.SandboxRequest{ID: run.ID}, run: run}, errors.New("parked request was not recorded"))
+		return true
+	}
+	tmpl, err := s.Manager.Templates.GetTemplate(ctx, req.Template)
+	if err != nil {
+		s.Manager.schedulingFailed(ctx, &admission{req: req, run: run}, fmt.Errorf("invalid template: %w", err))
+		return true
+	}
+	a := &admission{req: req, tmpl: tmpl, run: run}
+	nodes, err := s.Manager.listNodes(ctx)
+	if err != nil {
+		s.Logger.Error(ctx, "Failed to list nodes for parked submission", map[string]any{"sandbox_id": run.ID, "error": err})
+		return false
+	}
+	if err := s.Manager.place(ctx, a, s.Manager.Scheduler, nodes); err != nil {
+		s.Logger.Error(ctx, "Failed to place parked submission", map[string]any{"sandbox_id": run.ID, "error": err})
+		return true
+	}
+	if a.run.Parked != nil {
+		return false
+	}
+	s.Metrics.IncCounter("scale_to_zero_actions_total", 1, hermes.Label{Key: "action", Value: "replay"})
+	return true
+}
+
+// restore picks a node for a handed-off sandbox and has its agent restore
+// it. It reports whether the restore was started.
+func (s *ScaleToZero) restore(ctx context.Context, run domain.SandboxRun, nodes []domain.NodeStatus) bool {
+	req := &domain.SandboxRequest{ID: run.ID, Template: run.Template}
+	if run.Resources != nil {
+		req.Resources = *run.Resources
+	}
+	target, err := s.Manager.Scheduler.ChooseNode(ctx, req, nodes)
+	if err != nil {
+		s.Logger.Error(ctx, "No node to restore handed-off sandbox on", map[string]any{"sandbox_id": run.ID, "error": err})
+		return false
+	}
+
+	migration := *run.Migration
+	migration.Target = target
+	run.Migration = &migration
+	run.UpdatedAt = s.now()
+	if err := s.Hades.UpdateRun(ctx, run); err != nil {
+		s.Logger.Error(ctx, "Failed to record restore target", map[string]any{"sandbox_id": run.ID, "error": err})
+		return false
+	}
+	if err := s.Manager.Control.MigrateIn(ctx, target, run.ID, migration.SnapshotKey); err != nil {
+		s.Logger.Error(ctx, "Failed to send restore command", map[string]any{"sandbox_id": run.ID, "node_id": target, "error": err})
+		migration.Target = domain.MigrationTargetStore
+		run.UpdatedAt = s.now()
+		_ = s.Hades.UpdateRun(ctx, run)
+		return false
+	}
+	s.Metrics.IncCounter("scale_to_zero_actions_total", 1, hermes.Label{Key: "action", Value: "restore"})
+	return true
+}
+
+// failParked fails every parked submission with cause.
+func (s *ScaleToZero) failParked(ctx context.Context, runs []domain.SandboxRun, cause error) {
+	for _, run := range runs {
+		if !parkedRun(run) {
+			continue
+		}
+		req := run.Parked
+		run.Parked = nil
+		s.Manager.schedulingFailed(ctx, &admission{req: req, run: run}, cause)
+	}
+}
+
+func (s *ScaleToZero) transition(ctx context.Context, phase ClusterPhase) {
+	s.mu.Lock()
+	prev := s.phase
+	s.phase = phase
+	s.since = s.now()
+	s.mu.Unlock()
+	if prev == phase {
+		return
+	}
+	s.Logger.Info(ctx, "Cluster phase changed", map[string]any{"from": prev, "to": phase})
+	s.Metrics.IncCounter("scale_to_zero_transitions_total", 1, hermes.Label{Key: "phase", Value: string(phase)})
+}
+
+// parkedRun reports whether the run is a submission parked while the
+// cluster scales up.
+func parkedRun(run domain.SandboxRun) bool {
+	return run.Status == domain.RunStatusPending && run.Parked != nil
+}
+
+func countRuns(runs []domain.SandboxRun, match func(domain.SandboxRun) bool) int {
+	n := 0
+	for _, run := range runs {
+		if match(run) {
+			n++
+		}
+	}
+	return n
+}
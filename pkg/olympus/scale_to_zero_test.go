@@ -0,0 +1,155 @@
+package olympus_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+type groupRecorder struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (g *groupRecorder) ScaleNodeGroup(ctx context.Context, group string, size int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sizes = append(g.sizes, size)
+	return nil
+}
+
+func phaseOf(t *testing.T, s *olympus.ScaleToZero) olympus.ClusterPhase {
+	t.Helper()
+	status, err := s.Status(context.Background())
+	require.NoError(t, err)
+	return status.Phase
+}
+
+func TestScaleToZero_DrainAndWake(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	control := &migratingControl{registry: registry}
+	manager.Control = control
+	heartbeat(t, registry, "node-1", 1000, 512, domain.SandboxRun{ID: "sb-1", Template: "tpl", CPUUsageUsec: 100})
+
+	provisioner := &fakeProvisioner{}
+	groups := &groupRecorder{}
+	s := olympus.NewScaleToZero(registry, manager, provisioner, groups, &mockLogger{}, hermes.NewNoopMetrics(), olympus.ScaleToZeroConfig{
+		Enabled:    true,
+		IdleWindow: time.Millisecond,
+		NodeGroup:  "workers",
+		WakeNodes:  2,
+	})
+	manager.ScaleToZero = s
+
+	// First seen, the sandbox counts as active
+	require.NoError(t, s.Run(ctx))
+	assert.Equal(t, olympus.ClusterActive, phaseOf(t, s))
+
+	// Its CPU time stood still for the idle window
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, s.Run(ctx))
+	assert.Equal(t, olympus.ClusterDraining, phaseOf(t, s))
+	node, err := registry.GetNode(ctx, "node-1")
+	require.NoError(t, err)
+	assert.True(t, node.Draining())
+	awaitPhase(t, registry, "sb-1", domain.MigrationPhaseRestoring)
+
+	// The node is released once its agent reports it empty
+	heartbeat(t, registry, "node-1", 0, 0)
+	require.NoError(t, s.Run(ctx))
+	assert.Equal(t, []domain.NodeID{"node-1"}, provisioner.terminated)
+	assert.Equal(t, []int{0}, groups.sizes)
+	status, err := s.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, olympus.ClusterZero, status.Phase)
+	assert.Equal(t, 1, status.HandedOff)
+
+	// A submission is parked and wakes the cluster
+	req := &domain.SandboxRequest{Template: "tpl"}
+	require.NoError(t, manager.Submit(ctx, req))
+	run, err := registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusPending, run.Status)
+	require.NotNil(t, run.Parked)
+	assert.Equal(t, olympus.ClusterWaking, phaseOf(t, s))
+	assert.Equal(t, []int{0, 2}, groups.sizes)
+	assert.Equal(t, 0, queue.Len(ctx))
+
+	// Nothing moves until a node is schedulable
+	require.NoError(t, s.Run(ctx))
+	assert.Equal(t, olympus.ClusterWaking, phaseOf(t, s))
+
+	heartbeat(t, registry, "node-2", 0, 0)
+	require.NoError(t, s.Run(ctx))
+	assert.Equal(t, olympus.ClusterActive, phaseOf(t, s))
+	run, err = registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusScheduled, run.Status)
+	assert.Equal(t, domain.NodeID("node-2"), run.NodeID)
+	assert.Nil(t, run.Parked)
+	assert.Equal(t, 1, queue.Len(ctx))
+
+	restored := awaitPhase(t, registry, "sb-1", domain.MigrationPhaseCompleted)
+	assert.Equal(t, domain.NodeID("node-2"), restored.NodeID)
+	assert.Equal(t, []string{"out node-1 @erebus", "in node-2 sleep/sb-1/1"}, control.Commands())
+}
+
+func TestScaleToZero_StaysUpWhileBusy(t *testing.T) {
+	ctx := context.Background()
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	s := olympus.NewScaleToZero(registry, manager, nil, nil, &mockLogger{}, hermes.NewNoopMetrics(), olympus.ScaleToZeroConfig{
+		Enabled:    true,
+		IdleWindow: time.Millisecond,
+	})
+
+	for i := uint64(1); i <= 3; i++ {
+		heartbeat(t, registry, "node-1", 1000, 512,
+			domain.SandboxRun{ID: "busy", CPUUsageUsec: i * 100},
+			domain.SandboxRun{ID: "warm", Metadata: map[string]string{"warm": "true"}})
+		require.NoError(t, s.Run(ctx))
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, olympus.ClusterActive, phaseOf(t, s))
+
+	// Do-not-disrupt sandboxes keep the cluster up even when idle
+	heartbeat(t, registry, "node-1", 1000, 512,
+		domain.SandboxRun{ID: "pinned", Metadata: map[string]string{olympus.DoNotDisruptKey: "true"}})
+	require.NoError(t, s.Run(ctx))
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, s.Run(ctx))
+	assert.Equal(t, olympus.ClusterActive, phaseOf(t, s))
+}
+
+func TestScaleToZero_WakeTimeout(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	require.NoError(t, registry.MarkDraining(ctx, "node-1"))
+	s := olympus.NewScaleToZero(registry, manager, nil, nil, &mockLogger{}, hermes.NewNoopMetrics(), olympus.ScaleToZeroConfig{
+		Enabled:     true,
+		WakeTimeout: time.Millisecond,
+	})
+	manager.ScaleToZero = s
+
+	// With no schedulable node the first submission wakes the cluster
+	req := &domain.SandboxRequest{Template: "tpl"}
+	require.NoError(t, manager.Submit(ctx, req))
+	assert.Equal(t, olympus.ClusterWaking, phaseOf(t, s))
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, s.Run(ctx))
+	assert.Equal(t, olympus.ClusterZero, phaseOf(t, s))
+	run, err := registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStatusFailed, run.Status)
+	assert.Contains(t, run.Error, "wake timeout")
+	assert.Nil(t, run.Parked)
+	assert.Equal(t, 0, queue.Len(ctx))
+}
@@ -18,6 +18,9 @@ type Scaler struct {
 	Manager           *Manager
 	Logger            hermes.Logger
 	Metrics           hermes.Metrics
+	Consolidator      *Consolidator   // Optional; runs every tick when enabled
+	ScaleToZero       *ScaleToZero    // Optional; runs every tick when enabled
+	NodeGroups        NodeGroupScaler // Optional; without it node-group targets are only reported
 	seasonActivator   *persephone.SeasonActivator
 	capacityOptimizer *persephone.CapacityOptimizer
 }
@@ -79,7 +82,7 @@ func (s *Scaler) tick(ctx context.Context) error {
 		switch run.Status {
 		case domain.RunStatusRunning, domain.RunStatusScheduled:
 			activeCount++
-		case domain.RunStatusPending:
+		case domain.RunStatusPending, domain.RunStatusRescheduling:
 			launchCount++ // Jobs waiting to be launched
 		case domain.RunStatusFailed:
 			errorCount++
@@ -138,6 +141,18 @@ func (s *Scaler) tick(ctx context.Context) error {
 		s.Logger.Error(ctx, "Failed to update Persephone model", map[string]any{"error": err})
 	}
 
+	// Node consolidation runs regardless of the active season
+	if s.Consolidator != nil && s.Consolidator.Config.Enabled {
+		if _, err := s.Consolidator.Run(ctx); err != nil {
+			s.Logger.Error(ctx, "Node consolidation failed", map[string]any{"error": err})
+		}
+	}
+	if s.ScaleToZero != nil && s.ScaleToZero.Config.Enabled {
+		if err := s.ScaleToZero.Run(ctx); err != nil {
+			s.Logger.Error(ctx, "Scale to zero failed", map[string]any{"error": err})
+		}
+	}
+
 	// 3. Auto Season Activation
 	if s.seasonActivator != nil {
 		season, err := s.seasonActivator.EvaluateSeasons(ctx, time.Now())
@@ -183,37 +198,16 @@ func (s *Scaler) tick(ctx context.Context) error {
 		}
 	}
 
-	// 6. Pre-warming Logic
-	if season.Prewarming.PoolSize > 0 && len(season.Prewarming.Templates) > 0 {
-		for _, tplID := range season.Prewarming.Templates {
-			if err := s.ensureWarmPool(ctx, domain.TemplateID(tplID), season.Prewarming.PoolSize); err != nil {
-				s.Logger.Error(ctx, "Failed to ensure warm pool", map[string]any{
-					"template": tplID,
-					"error":    err,
-				})
-			}
-		}
+	// 6. Per-template warm pools and node groups, unless scaled to zero
+	if s.ScaleToZero == nil || !s.ScaleToZero.Config.Enabled || s.ScaleToZero.Active() {
+		s.reconcileTargets(ctx, season, runs, nodes)
 	}
 
 	return nil
 }
 
-func (s *Scaler) ensureWarmPool(ctx context.Context, tplID domain.TemplateID, targetSize int) error {
-	// Count existing warm sandboxes for this template
-	runs, err := s.Hades.ListRuns(ctx)
-	if err != nil {
-		return err
-	}
-
-	warmCount := 0
-	for _, run := range runs {
-		if run.Template == tplID && run.Status == domain.RunStatusRunning {
-			if val, ok := run.Metadata["warm"]; ok && val == "true" {
-				warmCount++
-			}
-		}
-	}
-
+// ensureWarmPool submits pre-warmed sandboxes until warmCount reaches targetSize.
+func (s *Scaler) ensureWarmPool(ctx context.Context, tplID domain.TemplateID, targetSize, warmCount int) error {
 	// If we have enough, do nothing
 	if warmCount >= targetSize {
 		return nil
@@ -76,6 +76,11 @@ func (m *MockHades) MarkDraining(ctx context.Context, id domain.NodeID) error {
 	return args.Error(0)
 }
 
+func (m *MockHades) MarkSchedulable(ctx context.Context, id domain.NodeID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (m *MockHades) UpdateRun(ctx context.Context, run domain.SandboxRun) error {
 	args := m.Called(ctx, run)
 	return args.Error(0)
@@ -298,3 +303,22 @@ func (m *MockTemplateManager) ListTemplates(ctx context.Context) ([]*domain.Temp
 	args := m.Called(ctx)
 	return args.Get(0).([]*domain.TemplateSpec), args.Error(1)
 }
+func (m *MockTemplateManager) DeleteTemplate(ctx context.Context, id domain.TemplateID, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+func (m *MockTemplateManager) RestoreTemplate(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TemplateSpec), args.Error(1)
+}
+func (m *MockTemplateManager) PurgeTemplate(ctx context.Context, id domain.TemplateID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockTemplateManager) ListDeletedTemplates(ctx context.Context) ([]*domain.TemplateSpec, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.TemplateSpec), args.Error(1)
+}
@@ -0,0 +1,29 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+// ErrNoSchedulingDecision is returned for a run placed without a scheduling
+// trace, such as a gang member or a run placed with tracing off.
+var ErrNoSchedulingDecision = errors.New("no scheduling decision recorded")
+
+// SchedulingDecision returns why the sandbox id was placed where it was, or
+// why it could not be, as of its last placement attempt.
+func (m *Manager) SchedulingDecision(ctx context.Context, id domain.SandboxID) (*domain.SchedulingDecision, error) {
+	run, err := m.Hades.GetRun(ctx, id)
+	if errors.Is(err, hades.ErrRunNotFound) {
+		return nil, ErrSandboxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if run.Scheduling == nil {
+		return nil, ErrNoSchedulingDecision
+	}
+	return run.Scheduling, nil
+}
@@ -0,0 +1,61 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestManager_SchedulingDecision(t *testing.T) {
+	ctx := context.Background()
+	manager := newArchManager(t, &domain.TemplateSpec{
+		ID: "tpl",
+		Variants: map[string]domain.TemplateVariant{
+			domain.ArchARM64: {KernelImage: "/kernels/vmlinux-arm64"},
+		},
+	})
+	manager.SchedulingTrace = moirai.TraceFull
+
+	req := &domain.SandboxRequest{ID: "sb-1", Template: "tpl"}
+	require.NoError(t, manager.Submit(ctx, req))
+
+	decision, err := manager.SchedulingDecision(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.NodeID("arm-node"), decision.NodeID)
+	assert.Equal(t, moirai.TraceFull, decision.Verbosity)
+	assert.Equal(t, map[string]int{moirai.FilterArch: 1}, decision.Excluded)
+	assert.Equal(t, []domain.CandidateNode{
+		{NodeID: "amd-node", Filter: moirai.FilterArch, Reason: "template tpl has no variant for the node's architecture"},
+		{NodeID: "arm-node", Scorer: "free_mem_mb", Score: 8192, Chosen: true},
+	}, decision.Candidates)
+
+	// A request no node can take records why on its failed run
+	big := &domain.SandboxRequest{ID: "sb-2", Template: "tpl", Resources: domain.ResourceSpec{Mem: 65536}}
+	require.Error(t, manager.Submit(ctx, big))
+	decision, err = manager.SchedulingDecision(ctx, "sb-2")
+	require.NoError(t, err)
+	assert.Empty(t, decision.NodeID)
+	assert.Contains(t, decision.Error, moirai.ErrNoCapacity.Error())
+	assert.Equal(t, map[string]int{moirai.FilterArch: 1, moirai.FilterCapacity: 1}, decision.Excluded)
+
+	// Summary traces keep the counts only
+	manager.SchedulingTrace = moirai.TraceSummary
+	require.NoError(t, manager.Submit(ctx, &domain.SandboxRequest{ID: "sb-3", Template: "tpl"}))
+	decision, err = manager.SchedulingDecision(ctx, "sb-3")
+	require.NoError(t, err)
+	assert.Equal(t, 2, decision.Evaluated)
+	assert.Empty(t, decision.Candidates)
+
+	manager.SchedulingTrace = moirai.TraceOff
+	require.NoError(t, manager.Submit(ctx, &domain.SandboxRequest{ID: "sb-4", Template: "tpl"}))
+	_, err = manager.SchedulingDecision(ctx, "sb-4")
+	assert.ErrorIs(t, err, olympus.ErrNoSchedulingDecision)
+
+	_, err = manager.SchedulingDecision(ctx, "sb-missing")
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotFound)
+}
@@ -0,0 +1,171 @@
+package olympus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+)
+
+// MaxArtifactBytes caps the size of an uploaded artifact.
+const MaxArtifactBytes = 256 << 20
+
+// ScriptRunRequest is a script run as clients submit it: the script and its
+// requirements were uploaded as an artifact, and Olympus picks the template
+// and command that run it.
+type ScriptRunRequest struct {
+	Artifact   string              `json:"artifact"`           // Digest returned by the artifact upload
+	Entrypoint string              `json:"entrypoint"`         // Script path inside the artifact
+	Template   domain.TemplateID   `json:"template,omitempty"` // Defaults to the template for the entrypoint's language
+	Args       []string            `json:"args,omitempty"`
+	Env        map[string]string   `json:"env,omitempty"`
+	Resources  domain.ResourceSpec `json:"resources,omitempty"` // Unset fields default to the template's
+	Outputs    []string            `json:"outputs,omitempty"`   // Guest paths to keep, within the policy's outputs
+	Metadata   map[string]string   `json:"metadata,omitempty"`
+}
+
+// scriptLanguage is how scripts with a file extension are run.
+type scriptLanguage struct {
+	Template    domain.TemplateID
+	Interpreter []string
+	Setup       string // Shell commands run in the script directory first
+}
+
+var scriptLanguages = map[string]scriptLanguage{
+	".py": {
+		Template:    "python-ds",
+		Interpreter: []string{"python3"},
+		Setup:       "if [ -f requirements.txt ]; then python3 -m pip install -q -r requirements.txt; fi",
+	},
+	".r":  {Template: "r-analytics", Interpreter: []string{"Rscript"}},
+	".jl": {Template: "julia-sci", Interpreter: []string{"julia"}},
+	".sh": {Template: "hello-world", Interpreter: []string{"/bin/sh"}},
+}
+
+// ScriptRequest turns a script run into the sandbox request that runs it.
+// Resources left unset are taken from the template.
+func (m *Manager) ScriptRequest(ctx context.Context, sr *ScriptRunRequest) (*domain.SandboxRequest, error) {
+	lang, ok := scriptLanguages[strings.ToLower(path.Ext(sr.Entrypoint))]
+	if !ok {
+		return nil, fmt.Errorf("%w: no interpreter for %q", domain.ErrInvalidScript, sr.Entrypoint)
+	}
+	template := sr.Template
+	if template == "" {
+		template = lang.Template
+	}
+	tmpl, err := m.Templates.GetTemplate(ctx, template)
+	if err != nil {
+		return nil, fmt.Errorf("%w: template %s: %v", domain.ErrInvalidScript, template, err)
+	}
+	resources := sr.Resources
+	if resources.CPU == 0 {
+		resources.CPU = tmpl.Resources.CPU
+	}
+	if resources.Mem == 0 {
+		resources.Mem = tmpl.Resources.Mem
+	}
+	if resources.TTL == 0 {
+		resources.TTL = tmpl.Resources.TTL
+	}
+
+	script := &domain.ScriptSpec{
+		Artifact:   sr.Artifact,
+		Entrypoint: sr.Entrypoint,
+		Outputs:    sr.Outputs,
+	}
+	if err := script.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The directory and script are passed as arguments rather than spliced
+	// into the shell command, so no quoting is needed
+	shell := `cd "$0" && `
+	if lang.Setup != "" {
+		shell += lang.Setup + " && "
+	}
+	shell += `exec "$@"`
+	command := append([]string{"/bin/sh", "-c", shell, script.GuestDir()}, lang.Interpreter...)
+
+	return &domain.SandboxRequest{
+		ID:        domain.SandboxID(uuid.New().String()),
+		Template:  template,
+		Command:   append(command, script.Entrypoint),
+		Args:      sr.Args,
+		Env:       sr.Env,
+		Resources: resources,
+		Metadata:  sr.Metadata,
+		Script:    script,
+		Inputs:    []domain.InputArtifact{{Name: "script", Digest: sr.Artifact}},
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// ScriptResult is the outcome of a script run in one object.
+type ScriptResult struct {
+	ID              domain.SandboxID  `json:"id"`
+	Status          domain.RunStatus  `json:"status"`
+	ExitCode        *int              `json:"exit_code,omitempty"`
+	Error           string            `json:"error,omitempty"`
+	Stdout          string            `json:"stdout,omitempty"` // Tail of standard output
+	Stderr          string            `json:"stderr,omitempty"` // Tail of standard error
+	OutputTruncated bool              `json:"output_truncated,omitempty"`
+	DurationMs      int64             `json:"duration_ms,omitempty"`
+	Output          *domain.RunOutput `json:"output,omitempty"` // Tarball of the declared outputs
+}
+
+// NewScriptResult summarizes a script run's record.
+func NewScriptResult(run *domain.SandboxRun) *ScriptResult {
+	res := &ScriptResult{
+		ID:       run.ID,
+		Status:   run.Status,
+		ExitCode: run.ExitCode,
+		Error:    run.Error,
+		Output:   run.Output,
+	}
+	if run.Result != nil {
+		res.Stdout = run.Result.StdoutTail
+		res.Stderr = run.Result.StderrTail
+		res.OutputTruncated = run.Result.OutputTruncated
+		res.DurationMs = run.Result.DurationMs
+	}
+	return res
+}
+
+// UploadArtifact stores r in Erebus under its content digest, where agents
+// fetch input artifacts from, and returns the digest and size.
+func UploadArtifact(ctx context.Context, store erebus.Store, r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp("", "artifact-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return "", 0, err
+	}
+	digest := "sha256:" + hex.EncodeToString(h.Sum(nil))
+
+	key := erebus.ArtifactKey(digest)
+	if exists, err := store.Exists(ctx, key); err == nil && exists {
+		return digest, n, nil
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	if err := store.Put(ctx, key, tmp); err != nil {
+		return "", 0, fmt.Errorf("storing artifact: %w", err)
+	}
+	return digest, n, nil
+}
@@ -0,0 +1,121 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+const testDigest = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func TestScriptRequest(t *testing.T) {
+	manager := newArchManager(t, &domain.TemplateSpec{
+		ID:        "python-ds",
+		Resources: domain.ResourceSpec{CPU: 2000, Mem: 2048},
+	})
+	ctx := context.Background()
+
+	req, err := manager.ScriptRequest(ctx, &olympus.ScriptRunRequest{
+		Artifact:   testDigest,
+		Entrypoint: "jobs/train.py",
+		Args:       []string{"--epochs", "3"},
+		Resources:  domain.ResourceSpec{Mem: 512},
+	})
+	if err != nil {
+		t.Fatalf("ScriptRequest: %v", err)
+	}
+	if req.Template != "python-ds" {
+		t.Errorf("expected the Python template, got %s", req.Template)
+	}
+	if req.Resources.CPU != 2000 || req.Resources.Mem != 512 {
+		t.Errorf("expected template CPU and requested memory, got %+v", req.Resources)
+	}
+	cmd := strings.Join(req.Command, " ")
+	if !strings.Contains(cmd, "requirements.txt") || !strings.HasSuffix(cmd, "/workspace python3 jobs/train.py") {
+		t.Errorf("unexpected command %q", cmd)
+	}
+	if req.Script == nil || req.Script.Artifact != testDigest || len(req.Inputs) != 1 || req.Inputs[0].Digest != testDigest {
+		t.Errorf("expected the artifact as script and input, got %+v %+v", req.Script, req.Inputs)
+	}
+
+	if _, err := manager.ScriptRequest(ctx, &olympus.ScriptRunRequest{Artifact: testDigest, Entrypoint: "main.exe"}); !errors.Is(err, domain.ErrInvalidScript) {
+		t.Errorf("expected ErrInvalidScript for an unknown language, got %v", err)
+	}
+	if _, err := manager.ScriptRequest(ctx, &olympus.ScriptRunRequest{Artifact: testDigest, Entrypoint: "../train.py"}); !errors.Is(err, domain.ErrInvalidScript) {
+		t.Errorf("expected ErrInvalidScript for an entrypoint outside the artifact, got %v", err)
+	}
+}
+
+func TestSubmit_ScriptOutputs(t *testing.T) {
+	manager := newArchManager(t, &domain.TemplateSpec{ID: "python-ds"})
+	ctx := context.Background()
+
+	newReq := func() *domain.SandboxRequest {
+		req, err := manager.ScriptRequest(ctx, &olympus.ScriptRunRequest{
+			Artifact:   testDigest,
+			Entrypoint: "train.py",
+			Outputs:    []string{"/workspace/out"},
+		})
+		if err != nil {
+			t.Fatalf("ScriptRequest: %v", err)
+		}
+		return req
+	}
+
+	// The policy extracts no outputs
+	if err := manager.Submit(ctx, newReq()); !errors.Is(err, domain.ErrInvalidScript) {
+		t.Fatalf("expected ErrInvalidScript without policy outputs, got %v", err)
+	}
+
+	if err := manager.Policies.UpsertPolicy(ctx, &domain.SandboxPolicy{
+		ID:         "pol",
+		TemplateID: "python-ds",
+		Version:    1,
+		Outputs:    &domain.OutputPolicy{Paths: []string{"/workspace"}, Exclude: []string{"/workspace/cache"}, MaxBytes: 1 << 20},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	req := newReq()
+	if err := manager.Submit(ctx, req); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if req.Outputs == nil || len(req.Outputs.Paths) != 1 || req.Outputs.Paths[0] != "/workspace/out" || req.Outputs.MaxBytes != 1<<20 || len(req.Outputs.Exclude) != 1 {
+		t.Errorf("expected outputs narrowed to the declared path, got %+v", req.Outputs)
+	}
+
+	req = newReq()
+	req.Script.Outputs = []string{"/etc"}
+	if err := manager.Submit(ctx, req); !errors.Is(err, domain.ErrInvalidScript) {
+		t.Errorf("expected ErrInvalidScript for an output the policy does not extract, got %v", err)
+	}
+}
+
+func TestUploadArtifact(t *testing.T) {
+	store, err := erebus.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	digest, size, err := olympus.UploadArtifact(ctx, store, strings.NewReader("foo"))
+	if err != nil {
+		t.Fatalf("UploadArtifact: %v", err)
+	}
+	if digest != testDigest || size != 3 {
+		t.Errorf("got %s (%d bytes)", digest, size)
+	}
+	r, err := store.Get(ctx, erebus.ArtifactKey(digest))
+	if err != nil {
+		t.Fatalf("artifact not stored: %v", err)
+	}
+	defer r.Close()
+	if data, _ := io.ReadAll(r); string(data) != "foo" {
+		t.Errorf("stored %q", data)
+	}
+}
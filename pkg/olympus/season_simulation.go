@@ -0,0 +1,47 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/persephone"
+)
+
+// ErrSimulationUnsupported is returned when the seasonal scaler cannot
+// return the demand history to simulate against.
+var ErrSimulationUnsupported = errors.New("seasonal scaler does not keep usage history")
+
+// maxSimulationDays bounds how much history one simulation replays.
+const maxSimulationDays = 90
+
+// Simulate replays the demand recorded over the last window against a
+// proposed season and against the active season, for comparison before the
+// proposed season is activated. Nothing is defined, activated or scaled.
+func (s *Scaler) Simulate(ctx context.Context, proposed *persephone.Season, window time.Duration, opts persephone.SimulationOptions) (*persephone.SimulationReport, error) {
+	reader, ok := s.Persephone.(persephone.HistoryReader)
+	if !ok {
+		return nil, ErrSimulationUnsupported
+	}
+	history, err := reader.History(ctx, time.Now().Add(-window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage history: %w", err)
+	}
+	current, err := s.Persephone.CurrentSeason(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current season: %w", err)
+	}
+
+	report, err := persephone.Simulate(history, current, proposed, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.Info(ctx, "Simulated season", map[string]any{
+		"season_id":        proposed.ID,
+		"records":          report.Records,
+		"node_hours_delta": report.NodeHoursDelta,
+		"cost_delta":       report.CostDelta,
+	})
+	return report, nil
+}
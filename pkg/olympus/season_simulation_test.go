@@ -0,0 +1,60 @@
+package olympus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/persephone"
+)
+
+func TestHandleSimulateSeason(t *testing.T) {
+	ctx := context.Background()
+	seasons := persephone.NewBasicSeasonalScaler()
+	require.NoError(t, seasons.DefineSeason(ctx, persephone.SeasonWinter))
+	require.NoError(t, seasons.ApplySeason(ctx, persephone.SeasonWinter.ID))
+
+	now := time.Now()
+	var history []*persephone.UsageRecord
+	for i := 48; i > 0; i-- {
+		history = append(history, &persephone.UsageRecord{Timestamp: now.Add(30*time.Minute - time.Duration(i)*time.Hour), ActiveVMs: 4})
+	}
+	require.NoError(t, seasons.Learn(ctx, history))
+
+	scaler := NewScaler(seasons, nil, nil, hermes.NewSlogAdapter(), hermes.NewNoopMetrics())
+	handlers := NewPersephoneHandlers(scaler)
+
+	body := `{"season": {"id": "lean", "min_nodes": 1, "max_nodes": 10, "target_utilization": 1}, "days": 1, "cost_per_node_hour": 2}`
+	rr := httptest.NewRecorder()
+	handlers.HandleSimulateSeason(rr, httptest.NewRequest(http.MethodPost, "/persephone/simulate", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var report persephone.SimulationReport
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, 24, report.Records)
+	require.NotNil(t, report.Baseline)
+	assert.Equal(t, "winter", report.Baseline.SeasonID)
+	assert.Equal(t, "lean", report.Proposed.SeasonID)
+	assert.Less(t, report.CostDelta, 0.0, "a leaner season should cost less than winter's 8 nodes")
+
+	// The live season is unchanged
+	current, _ := seasons.CurrentSeason(ctx)
+	assert.Equal(t, "winter", current.ID)
+
+	rr = httptest.NewRecorder()
+	handlers.HandleSimulateSeason(rr, httptest.NewRequest(http.MethodPost, "/persephone/simulate", strings.NewReader(`{"season": {"id": "x"}, "days": 365}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestScaler_Simulate_Unsupported(t *testing.T) {
+	scaler := NewScaler(new(MockSeasonalScaler), nil, nil, hermes.NewSlogAdapter(), hermes.NewNoopMetrics())
+	_, err := scaler.Simulate(context.Background(), persephone.SeasonSummer, time.Hour, persephone.SimulationOptions{})
+	assert.ErrorIs(t, err, ErrSimulationUnsupported)
+}
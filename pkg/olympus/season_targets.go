@@ -0,0 +1,196 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/persephone"
+)
+
+// NodeGroupScaler is the cloud provisioner driver that resizes node groups.
+type NodeGroupScaler interface {
+	ScaleNodeGroup(ctx context.Context, group string, size int) error
+}
+
+// Kinds of seasonal capacity target.
+const (
+	TargetWarmPool  = "warm_pool"
+	TargetNodeGroup = "node_group"
+)
+
+// CapacityTarget is one of the active season's capacity targets and what
+// is currently running against it.
+type CapacityTarget struct {
+	Kind   string `json:"kind"` // TargetWarmPool or TargetNodeGroup
+	Name   string `json:"name"` // Template ID or node group
+	Target int    `json:"target"`
+	Actual int    `json:"actual"`
+}
+
+// SeasonTargets are the active season's capacity targets.
+type SeasonTargets struct {
+	SeasonID string           `json:"season_id,omitempty"`
+	Targets  []CapacityTarget `json:"targets"`
+	At       time.Time        `json:"at"`
+}
+
+// Targets returns the active season's warm-pool and node-group targets
+// against what is currently running. Without an active season there are
+// no targets and nothing is reconciled.
+func (s *Scaler) Targets(ctx context.Context) (*SeasonTargets, error) {
+	season, err := s.Persephone.CurrentSeason(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current season: %w", err)
+	}
+	runs, err := s.Hades.ListRuns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	nodes, err := s.Hades.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	targets := &SeasonTargets{Targets: computeTargets(season, warmRuns(runs), nodes), At: time.Now()}
+	if season != nil {
+		targets.SeasonID = season.ID
+	}
+	return targets, nil
+}
+
+// computeTargets lists warm-pool targets, including templates that still
+// have warm sandboxes but are no longer targeted, then node-group targets.
+func computeTargets(season *persephone.Season, warm map[domain.TemplateID][]domain.SandboxRun, nodes []domain.NodeStatus) []CapacityTarget {
+	if season == nil {
+		return []CapacityTarget{}
+	}
+	pools := make(map[string]int)
+	for tpl := range warm {
+		pools[string(tpl)] = 0
+	}
+	for tpl, size := range season.WarmPoolTargets() {
+		pools[tpl] = size
+	}
+	groups := season.NodeGroups
+
+	targets := make([]CapacityTarget, 0, len(pools)+len(groups))
+	for tpl, size := range pools {
+		targets = append(targets, CapacityTarget{Kind: TargetWarmPool, Name: tpl, Target: size, Actual: len(warm[domain.TemplateID(tpl)])})
+	}
+	for group, size := range groups {
+		targets = append(targets, CapacityTarget{Kind: TargetNodeGroup, Name: group, Target: size, Actual: nodeGroupSize(nodes, group)})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Kind != targets[j].Kind {
+			return targets[i].Kind == TargetWarmPool
+		}
+		return targets[i].Name < targets[j].Name
+	})
+	return targets
+}
+
+// warmRuns groups the live pre-warmed sandboxes by template. Pending and
+// scheduled ones count so a pool is not refilled while it is still booting.
+func warmRuns(runs []domain.SandboxRun) map[domain.TemplateID][]domain.SandboxRun {
+	warm := make(map[domain.TemplateID][]domain.SandboxRun)
+	for _, run := range runs {
+		if run.Metadata["warm"] != "true" {
+			continue
+		}
+		// A claimed sandbox runs a request and leaves the pool
+		if run.Warm != nil && run.Warm.State == domain.WarmClaimed {
+			continue
+		}
+		switch run.Status {
+		case domain.RunStatusPending, domain.RunStatusScheduled, domain.RunStatusRunning:
+			warm[run.Template] = append(warm[run.Template], run)
+		}
+	}
+	return warm
+}
+
+// nodeGroupSize counts the schedulable nodes in a node group.
+func nodeGroupSize(nodes []domain.NodeStatus, group string) int {
+	size := 0
+	for _, node := range nodes {
+		if node.Labels[domain.NodeLabelNodeGroup] == group && node.Labels[domain.NodeLabelStatus] != domain.NodeStatusDraining {
+			size++
+		}
+	}
+	return size
+}
+
+// reconcileTargets moves every warm pool and node group towards the active
+// season's target and publishes targets and actuals as gauges.
+func (s *Scaler) reconcileTargets(ctx context.Context, season *persephone.Season, runs []domain.SandboxRun, nodes []domain.NodeStatus) {
+	warm := warmRuns(runs)
+	for _, target := range computeTargets(season, warm, nodes) {
+		switch target.Kind {
+		case TargetWarmPool:
+			label := hermes.Label{Key: "template", Value: target.Name}
+			s.Metrics.SetGauge("persephone_warm_pool_target", float64(target.Target), label)
+			s.Metrics.SetGauge("persephone_warm_pool_actual", float64(target.Actual), label)
+			if err := s.reconcileWarmPool(ctx, domain.TemplateID(target.Name), target.Target, warm[domain.TemplateID(target.Name)]); err != nil {
+				s.Logger.Error(ctx, "Failed to reconcile warm pool", map[string]any{
+					"template": target.Name,
+					"error":    err,
+				})
+			}
+		case TargetNodeGroup:
+			label := hermes.Label{Key: "node_group", Value: target.Name}
+			s.Metrics.SetGauge("persephone_node_group_target", float64(target.Target), label)
+			s.Metrics.SetGauge("persephone_node_group_actual", float64(target.Actual), label)
+			if s.NodeGroups == nil || target.Actual == target.Target {
+				continue
+			}
+			s.Logger.Info(ctx, "Resizing node group", map[string]any{
+				"node_group": target.Name,
+				"current":    target.Actual,
+				"target":     target.Target,
+			})
+			if err := s.NodeGroups.ScaleNodeGroup(ctx, target.Name, target.Target); err != nil {
+				s.Logger.Error(ctx, "Failed to resize node group", map[string]any{
+					"node_group": target.Name,
+					"error":      err,
+				})
+			}
+		}
+	}
+}
+
+// reconcileWarmPool pre-warms sandboxes up to the target, or kills the
+// excess once the target drops. Only sandboxes placed on a node can be
+// killed; pending ones are left to start and are trimmed on a later tick.
+func (s *Scaler) reconcileWarmPool(ctx context.Context, tplID domain.TemplateID, target int, warm []domain.SandboxRun) error {
+	if len(warm) < target {
+		return s.ensureWarmPool(ctx, tplID, target, len(warm))
+	}
+	excess := len(warm) - target
+	if excess == 0 || s.Manager == nil || s.Manager.Control == nil {
+		return nil
+	}
+
+	s.Logger.Info(ctx, "Shrinking warm pool", map[string]any{
+		"template": tplID,
+		"excess":   excess,
+		"current":  len(warm),
+		"target":   target,
+	})
+	for _, run := range warm {
+		if excess == 0 {
+			break
+		}
+		if run.NodeID == "" {
+			continue
+		}
+		if err := s.Manager.KillSandbox(ctx, run.ID); err != nil {
+			return err
+		}
+		excess--
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package olympus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/persephone"
+)
+
+type killRecordingControl struct {
+	NoopControlPlane
+	killed []domain.SandboxID
+}
+
+func (c *killRecordingControl) Kill(ctx context.Context, nodeID domain.NodeID, sandboxID domain.SandboxID) error {
+	c.killed = append(c.killed, sandboxID)
+	return nil
+}
+
+type recordingNodeGroups struct {
+	sizes map[string]int
+}
+
+func (r *recordingNodeGroups) ScaleNodeGroup(ctx context.Context, group string, size int) error {
+	r.sizes[group] = size
+	return nil
+}
+
+func warmRun(id domain.SandboxID, tpl domain.TemplateID, status domain.RunStatus, node domain.NodeID) domain.SandboxRun {
+	return domain.SandboxRun{ID: id, Template: tpl, Status: status, NodeID: node, Metadata: map[string]string{"warm": "true", "type": "prewarm"}}
+}
+
+func TestScaler_SeasonTargets(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	runs := []domain.SandboxRun{
+		warmRun("ds-1", "python-ds", domain.RunStatusRunning, "node-1"),
+		warmRun("ds-2", "python-ds", domain.RunStatusRunning, "node-1"),
+		warmRun("ds-3", "python-ds", domain.RunStatusPending, ""),
+		warmRun("old-1", "node-16", domain.RunStatusRunning, "node-1"),
+		{ID: "user-1", Template: "python-ds", Status: domain.RunStatusRunning, NodeID: "node-1"},
+	}
+	for _, run := range runs {
+		require.NoError(t, registry.UpdateRun(ctx, run))
+	}
+	for id, labels := range map[domain.NodeID]map[string]string{
+		"gpu-1":  {domain.NodeLabelNodeGroup: "gpu"},
+		"gpu-2":  {domain.NodeLabelNodeGroup: "gpu", domain.NodeLabelStatus: domain.NodeStatusDraining},
+		"node-1": {},
+	} {
+		require.NoError(t, registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{Node: domain.NodeInfo{ID: id, Labels: labels}, Time: time.Now()}))
+	}
+
+	season := &persephone.Season{
+		ID:         "winter",
+		Prewarming: persephone.PrewarmConfig{Templates: []string{"python-ds"}, PoolSize: 5},
+		WarmPools:  map[string]int{"python-ds": 1},
+		NodeGroups: map[string]int{"gpu": 2},
+	}
+	mockPersephone := new(MockSeasonalScaler)
+	mockPersephone.On("CurrentSeason", mock.Anything).Return(season, nil)
+
+	control := &killRecordingControl{}
+	logger := hermes.NewSlogAdapter()
+	manager := &Manager{Hades: registry, Control: control, Logger: logger, Metrics: hermes.NewNoopMetrics()}
+	scaler := NewScaler(mockPersephone, registry, manager, logger, hermes.NewNoopMetrics())
+	nodeGroups := &recordingNodeGroups{sizes: make(map[string]int)}
+	scaler.NodeGroups = nodeGroups
+
+	targets, err := scaler.Targets(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "winter", targets.SeasonID)
+	// WarmPools overrides the uniform pool size; templates no longer
+	// targeted drain to zero; draining nodes do not count
+	assert.Equal(t, []CapacityTarget{
+		{Kind: TargetWarmPool, Name: "node-16", Target: 0, Actual: 1},
+		{Kind: TargetWarmPool, Name: "python-ds", Target: 1, Actual: 3},
+		{Kind: TargetNodeGroup, Name: "gpu", Target: 2, Actual: 1},
+	}, targets.Targets)
+
+	nodes, err := registry.ListNodes(ctx)
+	require.NoError(t, err)
+	scaler.reconcileTargets(ctx, season, runs, nodes)
+
+	// Pending warm sandboxes are not killed, so python-ds keeps one extra until it starts
+	assert.ElementsMatch(t, []domain.SandboxID{"ds-1", "ds-2", "old-1"}, control.killed)
+	assert.Equal(t, map[string]int{"gpu": 2}, nodeGroups.sizes)
+}
+
+func TestScaler_SeasonTargets_NoSeason(t *testing.T) {
+	registry := hades.NewMemoryRegistry()
+	mockPersephone := new(MockSeasonalScaler)
+	mockPersephone.On("CurrentSeason", mock.Anything).Return(nil, nil)
+	scaler := NewScaler(mockPersephone, registry, nil, hermes.NewSlogAdapter(), hermes.NewNoopMetrics())
+
+	targets, err := scaler.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, targets.SeasonID)
+	assert.Empty(t, targets.Targets)
+}
@@ -0,0 +1,69 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+)
+
+// Simulate predicts how the scheduler would place a hypothetical workload on
+// the cluster as it is now. Cordoned nodes are left out and requests
+// without a heat level are classified the way submissions are. Nothing is
+// scheduled or persisted.
+func (m *Manager) Simulate(ctx context.Context, workload *moirai.Workload) (*moirai.SimulationResult, error) {
+	nodes, err := m.Hades.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodes = moirai.FilterDrainingNodes(nodes)
+
+	classified := *workload
+	classified.Requests = m.classifySimulated(workload.Requests)
+	if workload.Rate != nil {
+		rate := *workload.Rate
+		rate.Mix = m.classifySimulated(rate.Mix)
+		classified.Rate = &rate
+	}
+
+	scheduler := m.Simulator
+	if scheduler == nil {
+		scheduler = m.Scheduler
+	}
+
+	start := time.Now()
+	result, err := moirai.Simulate(ctx, scheduler, nodes, &classified)
+	if err != nil {
+		m.Metrics.IncCounter("scheduler_simulations_total", 1, hermes.Label{Key: "result", Value: "error"})
+		return nil, err
+	}
+	m.Metrics.IncCounter("scheduler_simulations_total", 1, hermes.Label{Key: "result", Value: "success"})
+	m.Logger.Info(ctx, "Scheduling simulation complete", map[string]any{
+		"requests": result.Requests,
+		"placed":   result.Placed,
+		"nodes":    len(nodes),
+		"duration": time.Since(start).String(),
+	})
+	return result, nil
+}
+
+func (m *Manager) classifySimulated(requests []moirai.SimulatedRequest) []moirai.SimulatedRequest {
+	if m.Phlegethon == nil || len(requests) == 0 {
+		return requests
+	}
+	out := make([]moirai.SimulatedRequest, len(requests))
+	for i, r := range requests {
+		if r.HeatLevel == "" {
+			level, _ := m.Phlegethon.Classify(heatClassificationRequest(&domain.SandboxRequest{
+				Template:  r.Template,
+				Resources: r.Resources,
+			}))
+			r.HeatLevel = string(level)
+		}
+		out[i] = r
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/phlegethon"
+)
+
+func TestManager_Simulate(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	for _, id := range []domain.NodeID{"node-1", "node-2"} {
+		if err := registry.UpdateHeartbeat(ctx, hades.HeartbeatPayload{
+			Node: domain.NodeInfo{ID: id, Capacity: domain.ResourceCapacity{CPU: 4000, Mem: 4096}},
+			Time: time.Now(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Cordoned nodes take no simulated sandboxes either
+	if err := registry.MarkDraining(ctx, "node-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &olympus.Manager{
+		Hades:      registry,
+		Scheduler:  moirai.NewLeastLoadedScheduler(hermes.NewNoopLogger()),
+		Phlegethon: phlegethon.NewHeatClassifier(),
+		Metrics:    hermes.NewNoopMetrics(),
+		Logger:     &mockLogger{},
+	}
+
+	result, err := manager.Simulate(ctx, &moirai.Workload{Requests: []moirai.SimulatedRequest{
+		{Template: "etl", Resources: domain.ResourceSpec{CPU: 4000, Mem: 1024, TTL: time.Hour}, Count: 2},
+		{Template: "lint", HeatLevel: "cold", Resources: domain.ResourceSpec{CPU: 500, Mem: 128}},
+	}})
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+
+	if result.Placed != 1 || result.Nodes["node-1"] != 1 || result.Nodes["node-2"] != 0 {
+		t.Errorf("expected one request on node-1, got %d placed: %v", result.Placed, result.Nodes)
+	}
+	// Requests without a heat level are classified like submissions; the
+	// inferno class needs GPU nodes the cluster does not have
+	if c := result.Classes[string(phlegethon.HeatInferno)]; c == nil || c.Requests != 2 || c.Unschedulable != 2 {
+		t.Errorf("expected 2 unschedulable inferno requests, got %+v", c)
+	}
+	if c := result.Classes["cold"]; c == nil || c.Requests != 1 {
+		t.Errorf("expected the explicit heat level to be kept, got %+v", result.Classes)
+	}
+
+	// Nothing was scheduled for real
+	if runs, _ := registry.ListRuns(ctx); len(runs) != 0 {
+		t.Errorf("expected no runs, got %d", len(runs))
+	}
+}
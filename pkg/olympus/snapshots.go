@@ -0,0 +1,99 @@
+package olympus
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+// SnapshotFilter narrows a snapshot catalog search. Zero fields match all.
+type SnapshotFilter struct {
+	Template domain.TemplateID
+	NodeID   domain.NodeID
+	MinAge   time.Duration
+	MaxAge   time.Duration
+	MinSize  int64
+	MaxSize  int64
+	Orphaned *bool // Only snapshots whose sandbox is (or is not) gone
+}
+
+// CatalogSnapshot is a catalog entry as returned by the snapshot catalog API.
+type CatalogSnapshot struct {
+	domain.SnapshotRecord
+	// Orphaned is set for snapshots of sandboxes that no longer exist:
+	// the run is unknown or has finished.
+	Orphaned bool `json:"orphaned"`
+}
+
+// SearchSnapshots returns the catalog entries matching the filter, newest
+// first.
+func (m *Manager) SearchSnapshots(ctx context.Context, filter SnapshotFilter) ([]CatalogSnapshot, error) {
+	catalog, ok := m.Hades.(hades.SnapshotCatalog)
+	if !ok {
+		return nil, hades.ErrNoSnapshotCatalog
+	}
+	records, err := catalog.ListSnapshotRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[domain.SandboxID]bool, len(runs))
+	for _, run := range runs {
+		if !run.Status.IsTerminal() {
+			live[run.ID] = true
+		}
+	}
+
+	now := time.Now()
+	result := make([]CatalogSnapshot, 0, len(records))
+	for _, rec := range records {
+		snap := CatalogSnapshot{
+			SnapshotRecord: rec,
+			Orphaned:       rec.SandboxID != "" && !live[rec.SandboxID],
+		}
+		if filter.matches(snap, now) {
+			result = append(result, snap)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (f SnapshotFilter) matches(s CatalogSnapshot, now time.Time) bool {
+	if f.Template != "" && s.Template != f.Template {
+		return false
+	}
+	if f.NodeID != "" && s.NodeID != f.NodeID {
+		return false
+	}
+	age := now.Sub(s.CreatedAt)
+	if f.MinAge > 0 && age < f.MinAge {
+		return false
+	}
+	if f.MaxAge > 0 && age > f.MaxAge {
+		return false
+	}
+	if f.MinSize > 0 && s.SizeBytes < f.MinSize {
+		return false
+	}
+	if f.MaxSize > 0 && s.SizeBytes > f.MaxSize {
+		return false
+	}
+	if f.Orphaned != nil && s.Orphaned != *f.Orphaned {
+		return false
+	}
+	return true
+}
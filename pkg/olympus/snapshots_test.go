@@ -0,0 +1,70 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestManager_SearchSnapshots(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	now := time.Now()
+
+	registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-live", Status: domain.RunStatusRunning})
+	registry.UpdateRun(ctx, domain.SandboxRun{ID: "sb-done", Status: domain.RunStatusSucceeded})
+	for _, rec := range []domain.SnapshotRecord{
+		{ID: "live", Template: "python", SandboxID: "sb-live", NodeID: "node-1", SizeBytes: 100, CreatedAt: now.Add(-time.Hour)},
+		{ID: "done", Template: "python", SandboxID: "sb-done", NodeID: "node-2", SizeBytes: 300, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "gone", Template: "node", SandboxID: "sb-gone", NodeID: "node-1", SizeBytes: 200, CreatedAt: now.Add(-2 * time.Hour)},
+		// Template snapshots belong to no sandbox and are never orphaned
+		{ID: "template", Template: "node", NodeID: "node-2", SizeBytes: 50, CreatedAt: now.Add(-72 * time.Hour)},
+	} {
+		if err := registry.RecordSnapshot(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := &olympus.Manager{Hades: registry, Logger: &mockLogger{}}
+	ids := func(filter olympus.SnapshotFilter) []domain.SnapshotID {
+		t.Helper()
+		snaps, err := manager.SearchSnapshots(ctx, filter)
+		if err != nil {
+			t.Fatalf("SearchSnapshots: %v", err)
+		}
+		var ids []domain.SnapshotID
+		for _, s := range snaps {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+	orphaned := true
+
+	for name, tc := range map[string]struct {
+		filter olympus.SnapshotFilter
+		want   []domain.SnapshotID
+	}{
+		"all, newest first": {olympus.SnapshotFilter{}, []domain.SnapshotID{"live", "gone", "done", "template"}},
+		"template":          {olympus.SnapshotFilter{Template: "python"}, []domain.SnapshotID{"live", "done"}},
+		"node":              {olympus.SnapshotFilter{NodeID: "node-1"}, []domain.SnapshotID{"live", "gone"}},
+		"age":               {olympus.SnapshotFilter{MinAge: 90 * time.Minute, MaxAge: 50 * time.Hour}, []domain.SnapshotID{"gone", "done"}},
+		"size":              {olympus.SnapshotFilter{MinSize: 100, MaxSize: 200}, []domain.SnapshotID{"live", "gone"}},
+		"orphaned":          {olympus.SnapshotFilter{Orphaned: &orphaned}, []domain.SnapshotID{"gone", "done"}},
+	} {
+		got := ids(tc.filter)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: got %v, want %v", name, got, tc.want)
+				break
+			}
+		}
+	}
+}
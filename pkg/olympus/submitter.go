@@ -0,0 +1,52 @@
+package olympus
+
+import (
+	"context"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+)
+
+// RunFilter narrows a sandbox listing to one submitter and/or tenant.
+type RunFilter struct {
+	SubmitterID string
+	TenantID    string
+}
+
+// submitterFromContext converts the identity injected by the Cerberus
+// middleware into a domain.Submitter. It returns nil for unauthenticated calls.
+func submitterFromContext(ctx context.Context) *domain.Submitter {
+	identity, ok := cerberus.GetIdentity(ctx)
+	if !ok || identity == nil {
+		return nil
+	}
+	return &domain.Submitter{
+		ID:       identity.ID,
+		Type:     string(identity.Type),
+		TenantID: identity.TenantID,
+		Roles:    append([]string(nil), identity.Roles...),
+	}
+}
+
+// ListSandboxesFiltered returns the runs matching filter.
+func (m *Manager) ListSandboxesFiltered(ctx context.Context, filter RunFilter) ([]domain.SandboxRun, error) {
+	if querier, ok := m.Hades.(hades.RunQuerier); ok && filter != (RunFilter{}) {
+		return querier.QueryRuns(ctx, hades.RunQuery{SubmitterID: filter.SubmitterID, TenantID: filter.TenantID})
+	}
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filter == (RunFilter{}) {
+		return runs, nil
+	}
+
+	filtered := make([]domain.SandboxRun, 0, len(runs))
+	for _, run := range runs {
+		if run.SubmittedBy(filter.SubmitterID, filter.TenantID) {
+			filtered = append(filtered, run)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,90 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func withIdentity(id, tenant string, roles ...string) context.Context {
+	return context.WithValue(context.Background(), cerberus.IdentityContextKey, &cerberus.Identity{
+		ID:       id,
+		Type:     cerberus.IdentityTypeUser,
+		TenantID: tenant,
+		Roles:    roles,
+	})
+}
+
+func TestSubmit_RecordsSubmitter(t *testing.T) {
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	req := &domain.SandboxRequest{Template: "tpl"}
+	if err := manager.Submit(withIdentity("alice", "acme", "developer"), req); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	run, err := registry.GetRun(context.Background(), req.ID)
+	if err != nil {
+		t.Fatalf("run not persisted: %v", err)
+	}
+	if run.Submitter == nil {
+		t.Fatal("expected submitter on run record")
+	}
+	if run.Submitter.ID != "alice" || run.Submitter.TenantID != "acme" || run.Submitter.Type != "user" {
+		t.Errorf("unexpected submitter: %+v", run.Submitter)
+	}
+	if len(run.Submitter.Roles) != 1 || run.Submitter.Roles[0] != "developer" {
+		t.Errorf("expected roles [developer], got %v", run.Submitter.Roles)
+	}
+}
+
+func TestSubmit_IgnoresClientSubmitter(t *testing.T) {
+	manager, _, _ := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	req := &domain.SandboxRequest{Template: "tpl", Submitter: &domain.Submitter{ID: "admin"}}
+	if err := manager.Submit(context.Background(), req); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if req.Submitter != nil {
+		t.Errorf("expected client-supplied submitter to be dropped, got %+v", req.Submitter)
+	}
+}
+
+func TestListSandboxesFiltered(t *testing.T) {
+	manager, _, _ := newRunWindowManager(t, domain.RunWindowPolicy{})
+
+	for _, who := range []struct{ id, tenant string }{
+		{"alice", "acme"},
+		{"bob", "acme"},
+		{"carol", "globex"},
+	} {
+		if err := manager.Submit(withIdentity(who.id, who.tenant), &domain.SandboxRequest{Template: "tpl"}); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter olympus.RunFilter
+		want   int
+	}{
+		{"All", olympus.RunFilter{}, 3},
+		{"BySubmitter", olympus.RunFilter{SubmitterID: "alice"}, 1},
+		{"ByTenant", olympus.RunFilter{TenantID: "acme"}, 2},
+		{"BySubmitterAndTenant", olympus.RunFilter{SubmitterID: "carol", TenantID: "acme"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := manager.ListSandboxesFiltered(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("ListSandboxesFiltered: %v", err)
+			}
+			if len(runs) != tt.want {
+				t.Errorf("expected %d runs, got %d", tt.want, len(runs))
+			}
+		})
+	}
+}
@@ -4,28 +4,48 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
 
-var ErrTemplateNotFound = errors.New("template not found")
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateExists   = errors.New("template already exists")
+)
 
 // TemplateManager manages the lifecycle and retrieval of sandbox templates.
 type TemplateManager interface {
 	GetTemplate(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, error)
 	ListTemplates(ctx context.Context) ([]*domain.TemplateSpec, error)
 	RegisterTemplate(ctx context.Context, tpl *domain.TemplateSpec) error
+
+	// DeleteTemplate moves a template to the trash, stamping DeletedAt.
+	// Deleted templates are hidden from GetTemplate and ListTemplates.
+	DeleteTemplate(ctx context.Context, id domain.TemplateID, at time.Time) error
+	// RestoreTemplate moves a deleted template back, or returns
+	// ErrTemplateExists if the ID has since been registered again.
+	RestoreTemplate(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, error)
+	// PurgeTemplate removes a deleted template for good.
+	PurgeTemplate(ctx context.Context, id domain.TemplateID) error
+	ListDeletedTemplates(ctx context.Context) ([]*domain.TemplateSpec, error)
 }
 
 // MemoryTemplateManager is an in-memory implementation of TemplateManager.
 type MemoryTemplateManager struct {
 	mu        sync.RWMutex
 	templates map[domain.TemplateID]*domain.TemplateSpec
+	deleted   map[domain.TemplateID]*domain.TemplateSpec
+
+	// OnRegister, if set, is called after a template is registered,
+	// outside the lock.
+	OnRegister func(ctx context.Context, tpl *domain.TemplateSpec)
 }
 
 func NewMemoryTemplateManager() *MemoryTemplateManager {
 	return &MemoryTemplateManager{
 		templates: make(map[domain.TemplateID]*domain.TemplateSpec),
+		deleted:   make(map[domain.TemplateID]*domain.TemplateSpec),
 	}
 }
 
@@ -51,9 +71,67 @@ func (m *MemoryTemplateManager) ListTemplates(ctx context.Context) ([]*domain.Te
 }
 
 func (m *MemoryTemplateManager) RegisterTemplate(ctx context.Context, tpl *domain.TemplateSpec) error {
+	m.mu.Lock()
+	m.templates[tpl.ID] = tpl
+	m.mu.Unlock()
+
+	if m.OnRegister != nil {
+		m.OnRegister(ctx, tpl)
+	}
+	return nil
+}
+
+func (m *MemoryTemplateManager) DeleteTemplate(ctx context.Context, id domain.TemplateID, at time.Time) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.templates[tpl.ID] = tpl
+	tpl, ok := m.templates[id]
+	if !ok {
+		return ErrTemplateNotFound
+	}
+	deleted := *tpl
+	deleted.DeletedAt = at
+	m.deleted[id] = &deleted
+	delete(m.templates, id)
+	return nil
+}
+
+func (m *MemoryTemplateManager) RestoreTemplate(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted, ok := m.deleted[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	if _, taken := m.templates[id]; taken {
+		return nil, ErrTemplateExists
+	}
+	restored := *deleted
+	restored.DeletedAt = time.Time{}
+	m.templates[id] = &restored
+	delete(m.deleted, id)
+	return &restored, nil
+}
+
+func (m *MemoryTemplateManager) PurgeTemplate(ctx context.Context, id domain.TemplateID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.deleted[id]; !ok {
+		return ErrTemplateNotFound
+	}
+	delete(m.deleted, id)
 	return nil
 }
+
+func (m *MemoryTemplateManager) ListDeletedTemplates(ctx context.Context) ([]*domain.TemplateSpec, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]*domain.TemplateSpec, 0, len(m.deleted))
+	for _, tpl := range m.deleted {
+		list = append(list, tpl)
+	}
+	return list, nil
+}
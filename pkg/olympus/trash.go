@@ -0,0 +1,245 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+var (
+	ErrTemplateInUse        = errors.New("template is in use")
+	ErrPolicyInUse          = errors.New("policy is in use")
+	ErrRestoreWindowExpired = errors.New("restore window expired")
+)
+
+// DefaultDeleteRetention is how long deleted templates and policies can be
+// restored before they are purged.
+const DefaultDeleteRetention = 7 * 24 * time.Hour
+
+func (m *Manager) deleteRetention() time.Duration {
+	if m.DeleteRetention > 0 {
+		return m.DeleteRetention
+	}
+	return DefaultDeleteRetention
+}
+
+// DeleteTemplate soft-deletes a template. It is refused while queued runs
+// or template policies reference the template. Returns the time until
+// which the template can be restored.
+func (m *Manager) DeleteTemplate(ctx context.Context, id domain.TemplateID) (time.Time, error) {
+	if _, err := m.Templates.GetTemplate(ctx, id); err != nil {
+		return time.Time{}, err
+	}
+
+	queued, err := m.countQueued(ctx, func(run *domain.SandboxRun) bool { return run.Template == id })
+	if err != nil {
+		return time.Time{}, err
+	}
+	policies, err := m.Policies.ListPolicies(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to list policies: %w", err)
+	}
+	var referencing int
+	for _, p := range policies {
+		if p.TemplateID == id {
+			referencing++
+		}
+	}
+	if queued > 0 || referencing > 0 {
+		m.Metrics.IncCounter("olympus_deletions_refused_total", 1, hermes.Label{Key: "type", Value: "template"})
+		return time.Time{}, fmt.Errorf("%w: %s is referenced by %d queued runs and %d policies", ErrTemplateInUse, id, queued, referencing)
+	}
+
+	now := time.Now()
+	if err := m.Templates.DeleteTemplate(ctx, id, now); err != nil {
+		return time.Time{}, err
+	}
+	m.Logger.Info(ctx, "Template deleted", map[string]any{"template": id})
+	m.Metrics.IncCounter("olympus_deletions_total", 1, hermes.Label{Key: "type", Value: "template"})
+	return now.Add(m.deleteRetention()), nil
+}
+
+// RestoreTemplate brings back a template deleted within the restore window.
+func (m *Manager) RestoreTemplate(ctx context.Context, id domain.TemplateID) (*domain.TemplateSpec, error) {
+	deleted, err := m.Templates.ListDeletedTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tpl := range deleted {
+		if tpl.ID != id {
+			continue
+		}
+		if m.expired(tpl.DeletedAt, time.Now()) {
+			return nil, fmt.Errorf("%w: %s was deleted at %s", ErrRestoreWindowExpired, id, tpl.DeletedAt.Format(time.RFC3339))
+		}
+		restored, err := m.Templates.RestoreTemplate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		m.Logger.Info(ctx, "Template restored", map[string]any{"template": id})
+		return restored, nil
+	}
+	return nil, ErrTemplateNotFound
+}
+
+// DeletePolicy soft-deletes the policy at scope. It is refused while queued
+// runs fall under the scope: any run for the global policy, the tenant's
+// runs for a tenant policy and the template's runs for a template policy.
+// Returns the time until which the policy can be restored.
+func (m *Manager) DeletePolicy(ctx context.Context, scope themis.Scope) (time.Time, error) {
+	if err := scope.Validate(); err != nil {
+		return time.Time{}, err
+	}
+	if _, err := m.Policies.GetScopedPolicy(ctx, scope); err != nil {
+		return time.Time{}, err
+	}
+
+	queued, err := m.countQueued(ctx, func(run *domain.SandboxRun) bool {
+		switch scope.Level() {
+		case themis.LevelTemplate:
+			return run.Template == scope.TemplateID
+		case themis.LevelTenant:
+			return run.Submitter != nil && run.Submitter.TenantID == scope.TenantID
+		default:
+			return true
+		}
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if queued > 0 {
+		m.Metrics.IncCounter("olympus_deletions_refused_total", 1, hermes.Label{Key: "type", Value: "policy"})
+		return time.Time{}, fmt.Errorf("%w: %s policy applies to %d queued runs", ErrPolicyInUse, scope.Level(), queued)
+	}
+
+	now := time.Now()
+	if err := m.Policies.DeletePolicy(ctx, scope, now); err != nil {
+		return time.Time{}, err
+	}
+	m.Logger.Info(ctx, "Policy deleted", map[string]any{"tenant": scope.TenantID, "template": scope.TemplateID})
+	m.Metrics.IncCounter("olympus_deletions_total", 1, hermes.Label{Key: "type", Value: "policy"})
+	return now.Add(m.deleteRetention()), nil
+}
+
+// RestorePolicy brings back a policy deleted within the restore window.
+func (m *Manager) RestorePolicy(ctx context.Context, scope themis.Scope) (*domain.SandboxPolicy, error) {
+	deleted, err := m.Policies.ListDeletedPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range deleted {
+		if themis.ScopeOf(p) != scope {
+			continue
+		}
+		if m.expired(p.DeletedAt, time.Now()) {
+			return nil, fmt.Errorf("%w: %s policy was deleted at %s", ErrRestoreWindowExpired, scope.Level(), p.DeletedAt.Format(time.RFC3339))
+		}
+		restored, err := m.Policies.RestorePolicy(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
+		m.Logger.Info(ctx, "Policy restored", map[string]any{"tenant": scope.TenantID, "template": scope.TemplateID})
+		return restored, nil
+	}
+	return nil, themis.ErrPolicyNotFound
+}
+
+// PurgeDeleted removes templates and policies whose restore window has
+// passed. Returns the number purged.
+func (m *Manager) PurgeDeleted(ctx context.Context, now time.Time) (int, error) {
+	purged := 0
+
+	templates, err := m.Templates.ListDeletedTemplates(ctx)
+	if err != nil {
+		return purged, fmt.Errorf("failed to list deleted templates: %w", err)
+	}
+	for _, tpl := range templates {
+		if !m.expired(tpl.DeletedAt, now) {
+			continue
+		}
+		if err := m.Templates.PurgeTemplate(ctx, tpl.ID); err != nil && !errors.Is(err, ErrTemplateNotFound) {
+			return purged, fmt.Errorf("failed to purge template %s: %w", tpl.ID, err)
+		}
+		m.collectTemplateArtifacts(ctx, tpl.ID)
+		m.Metrics.IncCounter("olympus_purged_total", 1, hermes.Label{Key: "type", Value: "template"})
+		purged++
+	}
+
+	policies, err := m.Policies.ListDeletedPolicies(ctx)
+	if err != nil {
+		return purged, fmt.Errorf("failed to list deleted policies: %w", err)
+	}
+	for _, p := range policies {
+		if !m.expired(p.DeletedAt, now) {
+			continue
+		}
+		if err := m.Policies.PurgePolicy(ctx, themis.ScopeOf(p)); err != nil && !errors.Is(err, themis.ErrPolicyNotFound) {
+			return purged, fmt.Errorf("failed to purge policy %s: %w", p.ID, err)
+		}
+		m.Metrics.IncCounter("olympus_purged_total", 1, hermes.Label{Key: "type", Value: "policy"})
+		purged++
+	}
+
+	if purged > 0 {
+		m.Logger.Info(ctx, "Purged deleted templates and policies", map[string]any{"count": purged})
+	}
+	return purged, nil
+}
+
+// collectTemplateArtifacts drops a purged template's references and deletes
+// the snapshots, images and layers nothing else references.
+func (m *Manager) collectTemplateArtifacts(ctx context.Context, id domain.TemplateID) {
+	if m.Refs == nil {
+		return
+	}
+	deleted, err := m.Refs.Collect(ctx, erebus.TemplateArtifact(id))
+	if err != nil {
+		m.Logger.Error(ctx, "Failed to collect template artifacts", map[string]any{"template": id, "error": err})
+		return
+	}
+	if len(deleted) > 1 {
+		m.Logger.Info(ctx, "Collected template artifacts", map[string]any{"template": id, "deleted": deleted[1:]})
+	}
+}
+
+// RunPurger calls PurgeDeleted every interval until ctx is done.
+func (m *Manager) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := m.PurgeDeleted(ctx, now); err != nil {
+				m.Logger.Error(ctx, "Purge of deleted templates and policies failed", map[string]any{"error": err})
+			}
+		}
+	}
+}
+
+func (m *Manager) expired(deletedAt, now time.Time) bool {
+	return now.Sub(deletedAt) > m.deleteRetention()
+}
+
+// countQueued counts runs waiting to launch that match.
+func (m *Manager) countQueued(ctx context.Context, match func(*domain.SandboxRun) bool) (int, error) {
+	runs, err := m.Hades.ListRuns(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list runs: %w", err)
+	}
+	n := 0
+	for i := range runs {
+		run := &runs[i]
+		if (run.Status == domain.RunStatusPending || run.Status == domain.RunStatusScheduled || run.Status == domain.RunStatusRescheduling) && match(run) {
+			n++
+		}
+	}
+	return n, nil
+}
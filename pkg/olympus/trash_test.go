@@ -0,0 +1,177 @@
+package olympus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+	"github.com/tartarus-sandbox/tartarus/pkg/themis"
+)
+
+func newTrashManager(t *testing.T) (*olympus.Manager, *hades.MemoryRegistry) {
+	t.Helper()
+	ctx := context.Background()
+
+	templates := olympus.NewMemoryTemplateManager()
+	for _, id := range []domain.TemplateID{"python", "node"} {
+		if err := templates.RegisterTemplate(ctx, &domain.TemplateSpec{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policies := themis.NewMemoryRepo()
+	if err := policies.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "tpl-node", TemplateID: "node"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := policies.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "tenant-acme", TenantID: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := hades.NewMemoryRegistry()
+	manager := &olympus.Manager{
+		Hades:     registry,
+		Templates: templates,
+		Policies:  policies,
+		Metrics:   hermes.NewNoopMetrics(),
+		Logger:    &mockLogger{},
+	}
+	return manager, registry
+}
+
+func TestManager_DeleteTemplate_Protection(t *testing.T) {
+	ctx := context.Background()
+	manager, registry := newTrashManager(t)
+
+	// Referenced by a template policy
+	if _, err := manager.DeleteTemplate(ctx, "node"); !errors.Is(err, olympus.ErrTemplateInUse) {
+		t.Errorf("expected ErrTemplateInUse for template with policy, got %v", err)
+	}
+
+	// Referenced by a queued run; finished runs do not count
+	if err := registry.UpdateRun(ctx, domain.SandboxRun{ID: "r1", Template: "python", Status: domain.RunStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.UpdateRun(ctx, domain.SandboxRun{ID: "r2", Template: "python", Status: domain.RunStatusSucceeded}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.DeleteTemplate(ctx, "python"); !errors.Is(err, olympus.ErrTemplateInUse) {
+		t.Errorf("expected ErrTemplateInUse for template with queued run, got %v", err)
+	}
+
+	if err := registry.UpdateRun(ctx, domain.SandboxRun{ID: "r1", Template: "python", Status: domain.RunStatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	restoreBy, err := manager.DeleteTemplate(ctx, "python")
+	if err != nil {
+		t.Fatalf("DeleteTemplate: %v", err)
+	}
+	if d := time.Until(restoreBy); d < olympus.DefaultDeleteRetention-time.Minute || d > olympus.DefaultDeleteRetention {
+		t.Errorf("unexpected restore deadline %v", restoreBy)
+	}
+	if _, err := manager.Templates.GetTemplate(ctx, "python"); !errors.Is(err, olympus.ErrTemplateNotFound) {
+		t.Errorf("expected deleted template to be hidden, got %v", err)
+	}
+
+	if _, err := manager.DeleteTemplate(ctx, "missing"); !errors.Is(err, olympus.ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestManager_RestoreTemplate(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := newTrashManager(t)
+	manager.DeleteRetention = time.Hour
+
+	if _, err := manager.DeleteTemplate(ctx, "python"); err != nil {
+		t.Fatalf("DeleteTemplate: %v", err)
+	}
+	tpl, err := manager.RestoreTemplate(ctx, "python")
+	if err != nil {
+		t.Fatalf("RestoreTemplate: %v", err)
+	}
+	if tpl.ID != "python" || !tpl.DeletedAt.IsZero() {
+		t.Errorf("unexpected restored template %+v", tpl)
+	}
+	if _, err := manager.Templates.GetTemplate(ctx, "python"); err != nil {
+		t.Errorf("expected restored template to be served, got %v", err)
+	}
+
+	// Outside the window the template can no longer be restored
+	if err := manager.Templates.DeleteTemplate(ctx, "python", time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.RestoreTemplate(ctx, "python"); !errors.Is(err, olympus.ErrRestoreWindowExpired) {
+		t.Errorf("expected ErrRestoreWindowExpired, got %v", err)
+	}
+	if _, err := manager.RestoreTemplate(ctx, "node"); !errors.Is(err, olympus.ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound for live template, got %v", err)
+	}
+}
+
+func TestManager_DeletePolicy_Protection(t *testing.T) {
+	ctx := context.Background()
+	manager, registry := newTrashManager(t)
+
+	if err := registry.UpdateRun(ctx, domain.SandboxRun{ID: "r1", Template: "python", Status: domain.RunStatusScheduled, Submitter: &domain.Submitter{ID: "alice", TenantID: "acme"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.DeletePolicy(ctx, themis.TenantScope("acme")); !errors.Is(err, olympus.ErrPolicyInUse) {
+		t.Errorf("expected ErrPolicyInUse for tenant with queued run, got %v", err)
+	}
+	if _, err := manager.DeletePolicy(ctx, themis.TenantScope("other")); !errors.Is(err, themis.ErrPolicyNotFound) {
+		t.Errorf("expected ErrPolicyNotFound, got %v", err)
+	}
+
+	// The node template has no queued runs
+	if _, err := manager.DeletePolicy(ctx, themis.TemplateScope("node")); err != nil {
+		t.Fatalf("DeletePolicy: %v", err)
+	}
+	if _, err := manager.Policies.GetScopedPolicy(ctx, themis.TemplateScope("node")); !errors.Is(err, themis.ErrPolicyNotFound) {
+		t.Errorf("expected deleted policy to stop applying, got %v", err)
+	}
+
+	policy, err := manager.RestorePolicy(ctx, themis.TemplateScope("node"))
+	if err != nil {
+		t.Fatalf("RestorePolicy: %v", err)
+	}
+	if policy.ID != "tpl-node" {
+		t.Errorf("unexpected restored policy %+v", policy)
+	}
+}
+
+func TestManager_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := newTrashManager(t)
+	manager.DeleteRetention = time.Hour
+
+	if _, err := manager.DeleteTemplate(ctx, "python"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.DeletePolicy(ctx, themis.TemplateScope("node")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is purged inside the window
+	if n, err := manager.PurgeDeleted(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("expected nothing purged, got %d, %v", n, err)
+	}
+
+	n, err := manager.PurgeDeleted(ctx, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeleted: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 purged, got %d", n)
+	}
+	if tpls, _ := manager.Templates.ListDeletedTemplates(ctx); len(tpls) != 0 {
+		t.Errorf("expected no deleted templates left, got %d", len(tpls))
+	}
+	if _, err := manager.RestorePolicy(ctx, themis.TemplateScope("node")); !errors.Is(err, themis.ErrPolicyNotFound) {
+		t.Errorf("expected purged policy to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// MaxUsageReportDays bounds the range of one usage report.
+const MaxUsageReportDays = 366
+
+// anonymousCaller is the identity usage of unauthenticated calls is kept under.
+const anonymousCaller = "anonymous"
+
+// ErrInvalidUsageQuery is returned for a usage report with a bad range or
+// grouping.
+var ErrInvalidUsageQuery = errors.New("invalid usage query")
+
+// UsageKey identifies the calls one identity made to one endpoint on one day.
+type UsageKey struct {
+	Day      string `json:"day,omitempty"` // UTC date, 2006-01-02
+	Identity string `json:"identity,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Route    string `json:"route,omitempty"` // Mux pattern, a bounded set
+}
+
+// UsageCounts are the calls counted under a UsageKey.
+type UsageCounts struct {
+	Calls        int64 `json:"calls"`
+	ClientErrors int64 `json:"client_errors"` // 4xx responses
+	ServerErrors int64 `json:"server_errors"` // 5xx responses
+}
+
+func (c *UsageCounts) add(o UsageCounts) {
+	c.Calls += o.Calls
+	c.ClientErrors += o.ClientErrors
+	c.ServerErrors += o.ServerErrors
+}
+
+// UsageRow is one row of a usage report.
+type UsageRow struct {
+	UsageKey
+	UsageCounts
+	ErrorRate  float64 `json:"error_rate"`           // Share of calls answered with 4xx or 5xx
+	Identities int     `json:"identities,omitempty"` // Distinct callers, when grouped by route
+}
+
+// UsageStore keeps daily API usage counts.
+type UsageStore interface {
+	// AddUsage adds counts to the totals kept for their keys.
+	AddUsage(ctx context.Context, counts map[UsageKey]UsageCounts) error
+	// ListUsage returns the totals of the days from through to, inclusive.
+	ListUsage(ctx context.Context, from, to time.Time) ([]UsageRow, error)
+}
+
+// UsageQuery selects and groups the rows of a usage report.
+type UsageQuery struct {
+	From, To time.Time // Days, inclusive (default: the last 30 days)
+	Identity string
+	TenantID string
+	Route    string
+	// GroupBy collapses the rows: "route" sums each endpoint over days and
+	// callers, "identity" sums each caller over days and endpoints. Empty
+	// keeps one row per day, caller and endpoint.
+	GroupBy string
+}
+
+// UsageTracker counts API calls by caller and endpoint. Counts are kept in
+// memory and added to the Store by Flush, so recording a call never waits on
+// the Store.
+type UsageTracker struct {
+	Store   UsageStore
+	Routes  *http.ServeMux // Its patterns name the endpoints
+	Metrics hermes.Metrics
+	Logger  hermes.Logger
+
+	mu      sync.Mutex
+	pending map[UsageKey]UsageCounts
+	now     func() time.Time
+}
+
+// NewUsageTracker creates a tracker that keeps its counts in store.
+func NewUsageTracker(store UsageStore, routes *http.ServeMux, metrics hermes.Metrics, logger hermes.Logger) *UsageTracker {
+	return &UsageTracker{
+		Store:   store,
+		Routes:  routes,
+		Metrics: metrics,
+		Logger:  logger,
+		pending: make(map[UsageKey]UsageCounts),
+		now:     time.Now,
+	}
+}
+
+// Middleware counts each request next serves under the identity the Cerberus
+// middleware authenticated, so it must be wrapped by it.
+func (t *UsageTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &usageResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		key := UsageKey{
+			Day:      t.now().UTC().Format(time.DateOnly),
+			Identity: anonymousCaller,
+			Method:   r.Method,
+			Route:    routeOf(t.Routes, r),
+		}
+		if identity, ok := cerberus.GetIdentity(r.Context()); ok && identity != nil {
+			key.Identity = identity.ID
+			key.TenantID = identity.TenantID
+		}
+		t.record(key, sw.status)
+	})
+}
+
+func (t *UsageTracker) record(key UsageKey, status int) {
+	counts := UsageCounts{Calls: 1}
+	switch {
+	case status >= 500:
+		counts.ServerErrors = 1
+	case status >= 400:
+		counts.ClientErrors = 1
+	}
+
+	t.mu.Lock()
+	c := t.pending[key]
+	c.add(counts)
+	t.pending[key] = c
+	t.mu.Unlock()
+}
+
+// Flush adds the counts recorded since the last flush to the Store. If the
+// Store fails they are kept for the next flush.
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[UsageKey]UsageCounts)
+	t.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := t.Store.AddUsage(ctx, pending); err != nil {
+		t.mu.Lock()
+		for key, counts := range pending {
+			c := t.pending[key]
+			c.add(counts)
+			t.pending[key] = c
+		}
+		t.mu.Unlock()
+		t.Metrics.IncCounter("api_usage_flush_failures_total", 1)
+		return fmt.Errorf("failed to store usage: %w", err)
+	}
+	return nil
+}
+
+// Run flushes the recorded counts every interval until ctx is cancelled, and
+// once more when it is.
+func (t *UsageTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := t.Flush(flushCtx); err != nil {
+				t.Logger.Error(flushCtx, "Failed to flush API usage", map[string]any{"error": err})
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.Logger.Error(ctx, "Failed to flush API usage", map[string]any{"error": err})
+			}
+		}
+	}
+}
+
+// Report returns the usage q selects, busiest first. Counts this replica has
+// not flushed yet are flushed first.
+func (t *UsageTracker) Report(ctx context.Context, q UsageQuery) ([]UsageRow, error) {
+	if q.To.IsZero() {
+		q.To = t.now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.AddDate(0, 0, -29)
+	}
+	from, to := usageDay(q.From), usageDay(q.To)
+	switch {
+	case to.Before(from):
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidUsageQuery)
+	case to.Sub(from) >= MaxUsageReportDays*24*time.Hour:
+		return nil, fmt.Errorf("%w: at most %d days per report", ErrInvalidUsageQuery, MaxUsageReportDays)
+	case q.GroupBy != "" && q.GroupBy != "route" && q.GroupBy != "identity":
+		return nil, fmt.Errorf("%w: group_by must be route or identity", ErrInvalidUsageQuery)
+	}
+
+	if err := t.Flush(ctx); err != nil {
+		t.Logger.Error(ctx, "Failed to flush API usage", map[string]any{"error": err})
+	}
+	rows, err := t.Store.ListUsage(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[UsageKey]*UsageRow)
+	callers := make(map[UsageKey]map[string]bool)
+	var keys []UsageKey
+	for _, row := range rows {
+		if (q.Identity != "" && row.Identity != q.Identity) ||
+			(q.TenantID != "" && row.TenantID != q.TenantID) ||
+			(q.Route != "" && row.Route != q.Route) {
+			continue
+		}
+		key := row.UsageKey
+		switch q.GroupBy {
+		case "route":
+			key = UsageKey{Method: row.Method, Route: row.Route}
+		case "identity":
+			key = UsageKey{Identity: row.Identity, TenantID: row.TenantID}
+		}
+		g, ok := grouped[key]
+		if !ok {
+			g = &UsageRow{UsageKey: key}
+			grouped[key] = g
+			callers[key] = make(map[string]bool)
+			keys = append(keys, key)
+		}
+		g.add(row.UsageCounts)
+		callers[key][row.Identity] = true
+	}
+
+	out := make([]UsageRow, 0, len(keys))
+	for _, key := range keys {
+		row := grouped[key]
+		if row.Calls > 0 {
+			row.ErrorRate = float64(row.ClientErrors+row.ServerErrors) / float64(row.Calls)
+		}
+		if q.GroupBy == "route" {
+			row.Identities = len(callers[key])
+		}
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Calls != out[j].Calls {
+			return out[i].Calls > out[j].Calls
+		}
+		a, b := out[i].UsageKey, out[j].UsageKey
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Identity != b.Identity {
+			return a.Identity < b.Identity
+		}
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		return a.Method < b.Method
+	})
+	return out, nil
+}
+
+// usageDay truncates t to its UTC day.
+func usageDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// usageDays lists the days from through to, inclusive, as 2006-01-02.
+func usageDays(from, to time.Time) []string {
+	var days []string
+	for day := usageDay(from); !day.After(usageDay(to)); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(time.DateOnly))
+	}
+	return days
+}
+
+// usageResponseWriter records the status a handler answered with.
+type usageResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *usageResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *usageResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *usageResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to upgrade
+// exec sessions to WebSocket.
+func (w *usageResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MemoryUsageStore keeps API usage in memory, for a single replica.
+type MemoryUsageStore struct {
+	mu     sync.RWMutex
+	counts map[UsageKey]UsageCounts
+}
+
+// NewMemoryUsageStore creates an empty in-memory usage store.
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{counts: make(map[UsageKey]UsageCounts)}
+}
+
+// AddUsage implements UsageStore.
+func (s *MemoryUsageStore) AddUsage(ctx context.Context, counts map[UsageKey]UsageCounts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, c := range counts {
+		total := s.counts[key]
+		total.add(c)
+		s.counts[key] = total
+	}
+	return nil
+}
+
+// ListUsage implements UsageStore.
+func (s *MemoryUsageStore) ListUsage(ctx context.Context, from, to time.Time) ([]UsageRow, error) {
+	days := make(map[string]bool)
+	for _, day := range usageDays(from, to) {
+		days[day] = true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var rows []UsageRow
+	for key, c := range s.counts {
+		if days[key.Day] {
+			rows = append(rows, UsageRow{UsageKey: key, UsageCounts: c})
+		}
+	}
+	return rows, nil
+}
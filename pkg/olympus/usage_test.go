@@ -0,0 +1,137 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+type failingUsageStore struct {
+	*MemoryUsageStore
+	fail bool
+}
+
+func (s *failingUsageStore) AddUsage(ctx context.Context, counts map[UsageKey]UsageCounts) error {
+	if s.fail {
+		return errors.New("store down")
+	}
+	return s.MemoryUsageStore.AddUsage(ctx, counts)
+}
+
+func TestUsageTracker_Report(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sandboxes", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/sandboxes/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	tracker := NewUsageTracker(NewMemoryUsageStore(), mux, hermes.NewNoopMetrics(), hermes.NewNoopLogger())
+	tracker.now = func() time.Time { return time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC) }
+	handler := tracker.Middleware(mux)
+
+	call := func(identity *cerberus.Identity, method, path string) {
+		req := httptest.NewRequest(method, path, nil)
+		if identity != nil {
+			req = req.WithContext(context.WithValue(req.Context(), cerberus.IdentityContextKey, identity))
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	alice := &cerberus.Identity{ID: "alice", TenantID: "acme"}
+	bob := &cerberus.Identity{ID: "bob", TenantID: "globex"}
+	call(alice, http.MethodGet, "/sandboxes")
+	call(alice, http.MethodGet, "/sandboxes")
+	call(alice, http.MethodGet, "/sandboxes/sb-1")
+	call(bob, http.MethodGet, "/sandboxes")
+	call(bob, http.MethodGet, "/templates")
+	call(nil, http.MethodGet, "/sandboxes")
+
+	ctx := context.Background()
+	rows, err := tracker.Report(ctx, UsageQuery{})
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d: %+v", len(rows), rows)
+	}
+	if top := rows[0]; top.Identity != "alice" || top.Route != "/sandboxes" || top.Calls != 2 || top.Day != "2026-10-17" {
+		t.Errorf("expected alice's listing first, got %+v", top)
+	}
+
+	// Per endpoint, with the number of callers, to find unused ones
+	rows, err = tracker.Report(ctx, UsageQuery{GroupBy: "route"})
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 routes, got %+v", rows)
+	}
+	if top := rows[0]; top.Route != "/sandboxes" || top.Calls != 4 || top.Identities != 3 || top.Day != "" {
+		t.Errorf("unexpected route totals: %+v", top)
+	}
+
+	// Per caller, with its error rate
+	rows, err = tracker.Report(ctx, UsageQuery{GroupBy: "identity", Identity: "bob"})
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Calls != 2 || rows[0].ServerErrors != 1 || rows[0].ErrorRate != 0.5 || rows[0].TenantID != "globex" {
+		t.Errorf("unexpected totals for bob: %+v", rows)
+	}
+
+	rows, _ = tracker.Report(ctx, UsageQuery{Identity: anonymousCaller})
+	if len(rows) != 1 || rows[0].Calls != 1 {
+		t.Errorf("expected one anonymous call, got %+v", rows)
+	}
+
+	// Days outside the range are not reported
+	rows, _ = tracker.Report(ctx, UsageQuery{
+		From: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC),
+	})
+	if len(rows) != 0 {
+		t.Errorf("expected no usage in September, got %+v", rows)
+	}
+}
+
+func TestUsageTracker_ReportValidation(t *testing.T) {
+	tracker := NewUsageTracker(NewMemoryUsageStore(), nil, hermes.NewNoopMetrics(), hermes.NewNoopLogger())
+	now := time.Now()
+	for name, q := range map[string]UsageQuery{
+		"reversed":  {From: now, To: now.AddDate(0, 0, -1)},
+		"too long":  {From: now.AddDate(-2, 0, 0), To: now},
+		"bad group": {GroupBy: "tenant"},
+	} {
+		if _, err := tracker.Report(context.Background(), q); !errors.Is(err, ErrInvalidUsageQuery) {
+			t.Errorf("%s: expected ErrInvalidUsageQuery, got %v", name, err)
+		}
+	}
+}
+
+func TestUsageTracker_FlushKeepsCountsOnFailure(t *testing.T) {
+	store := &failingUsageStore{MemoryUsageStore: NewMemoryUsageStore(), fail: true}
+	tracker := NewUsageTracker(store, nil, hermes.NewNoopMetrics(), hermes.NewNoopLogger())
+	tracker.record(UsageKey{Day: "2026-10-17", Identity: "alice", Method: http.MethodGet, Route: "/sandboxes"}, http.StatusOK)
+
+	if err := tracker.Flush(context.Background()); err == nil {
+		t.Fatal("expected flush to fail")
+	}
+	tracker.record(UsageKey{Day: "2026-10-17", Identity: "alice", Method: http.MethodGet, Route: "/sandboxes"}, http.StatusBadRequest)
+
+	store.fail = false
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	day := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	rows, _ := store.ListUsage(context.Background(), day, day)
+	if len(rows) != 1 || rows[0].Calls != 2 || rows[0].ClientErrors != 1 {
+		t.Errorf("expected both calls to be kept, got %+v", rows)
+	}
+}
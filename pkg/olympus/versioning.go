@@ -0,0 +1,161 @@
+package olympus
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+const (
+	// APIVersionHeader selects the API version for unversioned routes and
+	// reports the version that served a response.
+	APIVersionHeader = "API-Version"
+
+	// CurrentAPIVersion is the newest API version.
+	CurrentAPIVersion = "v1"
+
+	// legacyVersion is the metric label for unversioned routes used without
+	// an API-Version header.
+	legacyVersion = "legacy"
+)
+
+// SupportedAPIVersions lists the versions served under /<version>/.
+var SupportedAPIVersions = []string{"v1"}
+
+// LegacyRoutesDeprecatedAt is when the unversioned routes were deprecated
+// in favour of /v1.
+var LegacyRoutesDeprecatedAt = time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+
+// DefaultUnversionedPrefixes are paths outside the versioned API.
+var DefaultUnversionedPrefixes = []string{"/metrics", "/auth/"}
+
+// VersioningConfig controls API version routing.
+type VersioningConfig struct {
+	// DeprecatedAt is sent in the Deprecation header of unversioned routes
+	// (default LegacyRoutesDeprecatedAt).
+	DeprecatedAt time.Time
+	// Sunset, if set, is when unversioned routes stop being served. It is
+	// sent in the Sunset header, and unversioned requests get 410 Gone after it.
+	Sunset time.Time
+	// Unversioned are path prefixes served as-is, with no version handling
+	// (default DefaultUnversionedPrefixes).
+	Unversioned []string
+	// Routes, if set, is the mux the API is served from. Its patterns label
+	// the per-version request metrics.
+	Routes *http.ServeMux
+}
+
+// APIVersioning serves the API under /<version>/ and keeps the unversioned
+// routes as deprecated aliases of the current version. Versioned requests
+// have the prefix stripped before they reach next, so handlers and
+// authorization see the same paths for both.
+//
+// Unversioned requests can opt into a version with the API-Version header;
+// without it they get Deprecation, Sunset and successor Link headers.
+// Every request is counted in api_requests_total by version and route, to
+// track migration off the legacy routes.
+func APIVersioning(cfg VersioningConfig, metrics hermes.Metrics, next http.Handler) http.Handler {
+	if cfg.DeprecatedAt.IsZero() {
+		cfg.DeprecatedAt = LegacyRoutesDeprecatedAt
+	}
+	if cfg.Unversioned == nil {
+		cfg.Unversioned = DefaultUnversionedPrefixes
+	}
+	deprecation := fmt.Sprintf("@%d", cfg.DeprecatedAt.Unix())
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range cfg.Unversioned {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		requested := r.Header.Get(APIVersionHeader)
+		if requested != "" && !slices.Contains(SupportedAPIVersions, requested) {
+			http.Error(w, unsupportedVersion(requested), http.StatusBadRequest)
+			return
+		}
+
+		version, rest, versioned := splitVersion(r.URL.Path)
+		if versioned {
+			if !slices.Contains(SupportedAPIVersions, version) {
+				http.Error(w, unsupportedVersion(version), http.StatusNotFound)
+				return
+			}
+			if requested != "" && requested != version {
+				http.Error(w, fmt.Sprintf("%s header %s conflicts with path version %s", APIVersionHeader, requested, version), http.StatusBadRequest)
+				return
+			}
+			r = withPath(r, rest)
+		} else if requested != "" {
+			version = requested
+		} else {
+			version = legacyVersion
+			successor := fmt.Sprintf("</%s%s>; rel=\"successor-version\"", CurrentAPIVersion, r.URL.Path)
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Add("Link", successor)
+			if !cfg.Sunset.IsZero() {
+				w.Header().Set("Sunset", cfg.Sunset.UTC().Format(http.TimeFormat))
+				if !time.Now().Before(cfg.Sunset) {
+					metrics.IncCounter("api_requests_total", 1,
+						hermes.Label{Key: "version", Value: version},
+						hermes.Label{Key: "route", Value: "gone"},
+					)
+					http.Error(w, fmt.Sprintf("Unversioned routes were retired; use /%s%s", CurrentAPIVersion, r.URL.Path), http.StatusGone)
+					return
+				}
+			}
+		}
+
+		served := version
+		if version == legacyVersion {
+			served = CurrentAPIVersion
+		}
+		w.Header().Set(APIVersionHeader, served)
+
+		metrics.IncCounter("api_requests_total", 1,
+			hermes.Label{Key: "version", Value: version},
+			hermes.Label{Key: "route", Value: routeOf(cfg.Routes, r)},
+		)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// splitVersion splits "/v1/sandboxes" into "v1" and "/sandboxes".
+func splitVersion(path string) (version, rest string, ok bool) {
+	trimmed := strings.TrimPrefix(path, "/")
+	version, rest, _ = strings.Cut(trimmed, "/")
+	if len(version) < 2 || version[0] != 'v' || strings.Trim(version[1:], "0123456789") != "" {
+		return "", path, false
+	}
+	return version, "/" + rest, true
+}
+
+// withPath returns a shallow copy of r for a different path.
+func withPath(r *http.Request, path string) *http.Request {
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = path
+	r2.URL.RawPath = ""
+	r2.RequestURI = r2.URL.RequestURI()
+	return r2
+}
+
+// routeOf returns the mux pattern a request is served by, a bounded label.
+func routeOf(mux *http.ServeMux, r *http.Request) string {
+	if mux == nil {
+		return ""
+	}
+	if _, pattern := mux.Handler(r); pattern != "" {
+		return pattern
+	}
+	return "unmatched"
+}
+
+func unsupportedVersion(version string) string {
+	return fmt.Sprintf("Unsupported API version %q; supported: %s", version, strings.Join(SupportedAPIVersions, ", "))
+}
@@ -0,0 +1,116 @@
+package olympus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+type versionCounter struct {
+	hermes.NoopMetrics
+	counts map[string]int
+}
+
+func (m *versionCounter) IncCounter(name string, value float64, labels ...hermes.Label) {
+	key := name
+	for _, l := range labels {
+		key += " " + l.Key + "=" + l.Value
+	}
+	m.counts[key]++
+}
+
+func TestAPIVersioning(t *testing.T) {
+	mux := http.NewServeMux()
+	var gotPath string
+	mux.HandleFunc("/sandboxes/", func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {})
+	metrics := &versionCounter{counts: make(map[string]int)}
+	handler := APIVersioning(VersioningConfig{Routes: mux}, metrics, mux)
+
+	serve := func(path, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if version != "" {
+			req.Header.Set(APIVersionHeader, version)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Versioned routes reach the handler without their prefix
+	rr := serve("/v1/sandboxes/sb-1", "")
+	if rr.Code != http.StatusOK || gotPath != "/sandboxes/sb-1" {
+		t.Fatalf("expected /v1 to be stripped, got %d %q", rr.Code, gotPath)
+	}
+	if rr.Header().Get(APIVersionHeader) != "v1" || rr.Header().Get("Deprecation") != "" {
+		t.Errorf("unexpected versioned headers: %v", rr.Header())
+	}
+
+	// Legacy aliases still work but are marked deprecated
+	rr = serve("/sandboxes/sb-1", "")
+	if rr.Code != http.StatusOK || gotPath != "/sandboxes/sb-1" {
+		t.Fatalf("expected legacy route to be served, got %d %q", rr.Code, gotPath)
+	}
+	if rr.Header().Get("Deprecation") == "" || rr.Header().Get("Link") != `</v1/sandboxes/sb-1>; rel="successor-version"` {
+		t.Errorf("expected deprecation headers, got %v", rr.Header())
+	}
+	if rr.Header().Get("Sunset") != "" {
+		t.Errorf("expected no Sunset without one configured, got %q", rr.Header().Get("Sunset"))
+	}
+
+	// The version header opts unversioned routes into a version
+	rr = serve("/sandboxes/sb-1", "v1")
+	if rr.Code != http.StatusOK || rr.Header().Get("Deprecation") != "" {
+		t.Errorf("expected negotiated request without deprecation, got %d %v", rr.Code, rr.Header())
+	}
+
+	for path, version := range map[string]string{"/v9/sandboxes/sb-1": "", "/sandboxes/sb-1": "v9"} {
+		if rr := serve(path, version); rr.Code == http.StatusOK {
+			t.Errorf("expected unsupported version to be rejected for %s %s", path, version)
+		}
+	}
+
+	// Paths outside the API are not versioned or counted
+	if rr := serve("/metrics", ""); rr.Header().Get("Deprecation") != "" {
+		t.Errorf("expected /metrics to be unversioned, got %v", rr.Header())
+	}
+
+	want := map[string]int{
+		"api_requests_total version=v1 route=/sandboxes/":     2,
+		"api_requests_total version=legacy route=/sandboxes/": 1,
+	}
+	for key, n := range want {
+		if metrics.counts[key] != n {
+			t.Errorf("%s = %d, want %d (all: %v)", key, metrics.counts[key], n, metrics.counts)
+		}
+	}
+}
+
+func TestAPIVersioning_Sunset(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(sunset time.Time, path string) *httptest.ResponseRecorder {
+		handler := APIVersioning(VersioningConfig{Sunset: sunset}, hermes.NewNoopMetrics(), next)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	future := time.Now().Add(24 * time.Hour)
+	rr := serve(future, "/templates")
+	if rr.Code != http.StatusOK || rr.Header().Get("Sunset") != future.UTC().Format(http.TimeFormat) {
+		t.Errorf("expected Sunset header before the sunset, got %d %v", rr.Code, rr.Header())
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if rr := serve(past, "/templates"); rr.Code != http.StatusGone {
+		t.Errorf("expected 410 after the sunset, got %d", rr.Code)
+	}
+	if rr := serve(past, "/v1/templates"); rr.Code != http.StatusOK {
+		t.Errorf("expected /v1 to outlive the sunset, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package olympus
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// MaxWaitTimeout caps how long a single wait holds a client connection.
+const MaxWaitTimeout = 5 * time.Minute
+
+// Hades has no change feed, so waits poll it: quickly at first, for short
+// runs, then backing off so long waits cost little.
+const (
+	waitPollMin = 100 * time.Millisecond
+	waitPollMax = 2 * time.Second
+)
+
+// WaitRun blocks until the sandbox's run reaches a terminal state or timeout
+// elapses, and returns the latest run. done reports whether the run is
+// terminal; it is false when the timeout elapsed first.
+func (m *Manager) WaitRun(ctx context.Context, id domain.SandboxID, timeout time.Duration) (run *domain.SandboxRun, done bool, err error) {
+	if timeout <= 0 || timeout > MaxWaitTimeout {
+		timeout = MaxWaitTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	interval := waitPollMin
+	for {
+		run, err = m.Hades.GetRun(ctx, id)
+		if errors.Is(err, hades.ErrRunNotFound) {
+			return nil, false, ErrSandboxNotFound
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if run.Status.IsTerminal() {
+			m.Metrics.IncCounter("sandbox_wait_total", 1, hermes.Label{Key: "result", Value: "completed"})
+			return run, true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return run, false, ctx.Err()
+		case <-deadline.C:
+			m.Metrics.IncCounter("sandbox_wait_total", 1, hermes.Label{Key: "result", Value: "timeout"})
+			return run, false, nil
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, waitPollMax)
+	}
+}
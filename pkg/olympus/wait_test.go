@@ -0,0 +1,50 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+func TestManager_WaitRun(t *testing.T) {
+	ctx := context.Background()
+	registry := hades.NewMemoryRegistry()
+	manager := &olympus.Manager{Hades: registry, Logger: &mockLogger{}, Metrics: hermes.NewNoopMetrics()}
+
+	run := domain.SandboxRun{ID: "sb-1", NodeID: "node-1", Status: domain.RunStatusRunning}
+	require.NoError(t, registry.UpdateRun(ctx, run))
+
+	// Times out while the run is still going
+	got, done, err := manager.WaitRun(ctx, "sb-1", 150*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, domain.RunStatusRunning, got.Status)
+
+	// Returns as soon as the run finishes
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		run.Status = domain.RunStatusSucceeded
+		_ = registry.UpdateRun(ctx, run)
+	}()
+	start := time.Now()
+	got, done, err = manager.WaitRun(ctx, "sb-1", 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, domain.RunStatusSucceeded, got.Status)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// Terminal runs return immediately
+	_, done, err = manager.WaitRun(ctx, "sb-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	_, _, err = manager.WaitRun(ctx, "sb-missing", time.Second)
+	assert.ErrorIs(t, err, olympus.ErrSandboxNotFound)
+}
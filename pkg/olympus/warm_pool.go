@@ -0,0 +1,206 @@
+package olympus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hades"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/moirai"
+	"github.com/tartarus-sandbox/tartarus/pkg/phlegethon"
+)
+
+// WarmPoolConfig configures handing submissions sandboxes pre-warmed for
+// their template.
+type WarmPoolConfig struct {
+	Enabled    bool
+	HeatLevels []string // Heat levels whose requests may claim a pre-warmed sandbox (default cold, warm and hot)
+	MaxPerNode int      // Pre-warmed sandboxes placed on one node at most (0 = no limit)
+}
+
+func (c WarmPoolConfig) withDefaults() WarmPoolConfig {
+	if len(c.HeatLevels) == 0 {
+		c.HeatLevels = []string{string(phlegethon.HeatCold), string(phlegethon.HeatWarm), string(phlegethon.HeatHot)}
+	}
+	return c
+}
+
+// WarmPool hands a submission a sandbox pre-warmed for its template, which
+// the agent keeps booted and paused, so it starts in milliseconds instead
+// of after rootfs assembly and boot. The active season's warm-pool targets
+// size each template's pool (see Scaler); agents pause the pre-warmed
+// sandboxes and run the requests that claim them. Requests that cannot run
+// in one, such as those with secrets or host hooks, are placed as usual.
+type WarmPool struct {
+	Hades   hades.Registry
+	Manager *Manager
+	Logger  hermes.Logger
+	Metrics hermes.Metrics
+	Config  WarmPoolConfig
+
+	mu sync.Mutex // Serializes claims, so each sandbox goes to one request
+}
+
+func NewWarmPool(h hades.Registry, m *Manager, l hermes.Logger, met hermes.Metrics, cfg WarmPoolConfig) *WarmPool {
+	return &WarmPool{
+		Hades:   h,
+		Manager: m,
+		Logger:  l,
+		Metrics: met,
+		Config:  cfg.withDefaults(),
+	}
+}
+
+// claim gives an admitted request the longest-ready sandbox pre-warmed for
+// its template on one of nodes, marking it CLAIMED, and returns its node.
+// It reports false if the request is not of a heat level that claims, or
+// no sandbox it can run in is ready there.
+func (p *WarmPool) claim(ctx context.Context, a *admission, nodes []domain.NodeStatus) (domain.NodeID, bool) {
+	// Left over from an earlier attempt of the request, if any
+	a.req.WarmInstance = ""
+	if p == nil || !p.Config.Enabled || a.req.Warm() || !p.claims(a.req.HeatLevel) {
+		return "", false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ready, err := p.ready(ctx, a.req.Template)
+	if err != nil {
+		p.Logger.Error(ctx, "Failed to list pre-warmed sandboxes", map[string]any{"template": a.req.Template, "error": err})
+		return "", false
+	}
+	candidate := make(map[domain.NodeID]bool, len(nodes))
+	for _, node := range nodes {
+		candidate[node.ID] = true
+	}
+	label := hermes.Label{Key: "template", Value: string(a.req.Template)}
+	for _, run := range ready {
+		if !candidate[run.NodeID] || !a.req.CanClaim(warmRequest(run)) {
+			continue
+		}
+		now := time.Now()
+		warm := *run.Warm
+		warm.State = domain.WarmClaimed
+		warm.ClaimedBy = a.req.ID
+		warm.ClaimedAt = now
+		run.Warm = &warm
+		run.UpdatedAt = now
+		if err := p.Manager.swapRun(ctx, run); err != nil {
+			// Claimed by another replica, or released meanwhile
+			continue
+		}
+		a.req.WarmInstance = run.ID
+		p.Logger.Info(ctx, "Claimed pre-warmed sandbox", map[string]any{
+			"sandbox_id": a.req.ID,
+			"warm_id":    run.ID,
+			"node_id":    run.NodeID,
+		})
+		p.Metrics.IncCounter("warm_pool_claims_total", 1, label, hermes.Label{Key: "result", Value: "claimed"})
+		return run.NodeID, true
+	}
+	p.Metrics.IncCounter("warm_pool_claims_total", 1, label, hermes.Label{Key: "result", Value: "missed"})
+	return "", false
+}
+
+// unclaim returns the sandbox claimed for a request that could not be
+// dispatched to the pool.
+func (p *WarmPool) unclaim(ctx context.Context, req *domain.SandboxRequest) {
+	if p == nil || req.WarmInstance == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	run, err := p.Hades.GetRun(ctx, req.WarmInstance)
+	if err != nil || run.Warm == nil || run.Warm.ClaimedBy != req.ID {
+		return
+	}
+	returned := *run
+	returned.Warm = &domain.RunWarm{State: domain.WarmReady, Network: run.Warm.Network, ReadyAt: run.Warm.ReadyAt}
+	returned.UpdatedAt = time.Now()
+	if err := p.Manager.swapRun(ctx, returned); err != nil {
+		p.Logger.Error(ctx, "Failed to return pre-warmed sandbox to the pool", map[string]any{"warm_id": run.ID, "error": err})
+	}
+}
+
+// spread keeps a pre-warming request off the nodes that already hold
+// MaxPerNode pre-warmed sandboxes, recording them in the trace on ctx.
+func (p *WarmPool) spread(ctx context.Context, a *admission, nodes []domain.NodeStatus) []domain.NodeStatus {
+	if p == nil || !p.Config.Enabled || p.Config.MaxPerNode <= 0 || !a.req.Warm() {
+		return nodes
+	}
+	runs, err := p.Manager.activeRuns(ctx)
+	if err != nil {
+		p.Logger.Error(ctx, "Failed to count pre-warmed sandboxes", map[string]any{"error": err})
+		return nodes
+	}
+	held := make(map[domain.NodeID]int)
+	for _, run := range warmRuns(runs) {
+		for _, r := range run {
+			held[r.NodeID]++
+		}
+	}
+	kept := make([]domain.NodeStatus, 0, len(nodes))
+	for _, node := range nodes {
+		if held[node.ID] < p.Config.MaxPerNode {
+			kept = append(kept, node)
+		}
+	}
+	moirai.TraceFrom(ctx).ExcludeAll(nodes, kept, moirai.FilterWarmPool, fmt.Sprintf("node holds %d pre-warmed sandboxes", p.Config.MaxPerNode))
+	return kept
+}
+
+// claims reports whether requests of the heat level claim pre-warmed
+// sandboxes. Unclassified requests do.
+func (p *WarmPool) claims(heat string) bool {
+	if heat == "" {
+		return true
+	}
+	for _, level := range p.Config.HeatLevels {
+		if level == heat {
+			return true
+		}
+	}
+	return false
+}
+
+// ready returns the template's pre-warmed sandboxes waiting to be claimed,
+// the longest-ready first.
+func (p *WarmPool) ready(ctx context.Context, tpl domain.TemplateID) ([]domain.SandboxRun, error) {
+	var runs []domain.SandboxRun
+	var err error
+	if querier, ok := p.Hades.(hades.RunQuerier); ok {
+		runs, err = querier.QueryRuns(ctx, hades.RunQuery{Status: domain.RunStatusRunning, Template: tpl})
+	} else {
+		runs, err = p.Hades.ListRuns(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ready []domain.SandboxRun
+	for _, run := range runs {
+		if run.Template != tpl || run.Status != domain.RunStatusRunning || run.Warm == nil || run.Warm.State != domain.WarmReady || run.Resources == nil {
+			continue
+		}
+		ready = append(ready, run)
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].Warm.ReadyAt.Before(ready[j].Warm.ReadyAt) })
+	return ready, nil
+}
+
+// warmRequest is what a pre-warmed sandbox was booted for, as far as its
+// run records it.
+func warmRequest(run domain.SandboxRun) *domain.SandboxRequest {
+	return &domain.SandboxRequest{
+		ID:         run.ID,
+		Template:   run.Template,
+		Resources:  *run.Resources,
+		NetworkRef: domain.NetworkPolicyRef{ID: run.Warm.Network},
+		Metadata:   map[string]string{domain.MetadataWarm: "true"},
+	}
+}
@@ -0,0 +1,111 @@
+package olympus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/olympus"
+)
+
+// readyWarm is a sandbox an agent pre-warmed for tpl and keeps paused.
+func readyWarm(id domain.SandboxID, readyAt time.Time) domain.SandboxRun {
+	return domain.SandboxRun{
+		ID:        id,
+		Template:  "tpl",
+		Resources: &domain.ResourceSpec{CPU: 1000, Mem: 512},
+		Metadata:  map[string]string{domain.MetadataWarm: "true"},
+		Warm:      &domain.RunWarm{State: domain.WarmReady, Network: "lockdown-no-net", ReadyAt: readyAt},
+	}
+}
+
+func TestWarmPool_Claim(t *testing.T) {
+	ctx := context.Background()
+	manager, queue, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	manager.WarmPool = olympus.NewWarmPool(registry, manager, &mockLogger{}, hermes.NewNoopMetrics(), olympus.WarmPoolConfig{Enabled: true})
+	now := time.Now()
+	heartbeat(t, registry, "node-2", 0, 0, readyWarm("warm-new", now), readyWarm("warm-old", now.Add(-time.Minute)))
+
+	submit := func(req *domain.SandboxRequest) *domain.SandboxRequest {
+		t.Helper()
+		require.NoError(t, manager.Submit(ctx, req))
+		queued, receipt, err := queue.Dequeue(ctx)
+		require.NoError(t, err)
+		require.NoError(t, queue.Ack(ctx, receipt))
+		return queued
+	}
+	request := func(cpu domain.MilliCPU) *domain.SandboxRequest {
+		return &domain.SandboxRequest{
+			Template:   "tpl",
+			Command:    []string{"echo", "hi"},
+			Resources:  domain.ResourceSpec{CPU: cpu, Mem: 256},
+			NetworkRef: domain.NetworkPolicyRef{ID: "lockdown-no-net"},
+		}
+	}
+
+	// The longest-ready sandbox goes to the first request, on its node
+	queued := submit(request(500))
+	assert.Equal(t, domain.SandboxID("warm-old"), queued.WarmInstance)
+	assert.Equal(t, domain.NodeID("node-2"), queued.NodeID)
+	warm, err := registry.GetRun(ctx, "warm-old")
+	require.NoError(t, err)
+	assert.Equal(t, domain.WarmClaimed, warm.Warm.State)
+	assert.Equal(t, queued.ID, warm.Warm.ClaimedBy)
+	run, err := registry.GetRun(ctx, queued.ID)
+	require.NoError(t, err)
+	require.NotNil(t, run.Scheduling)
+	assert.Contains(t, run.Scheduling.Notes, "claimed pre-warmed sandbox warm-old")
+
+	// A request that needs more than the sandbox was booted with starts cold
+	queued = submit(request(2000))
+	assert.Empty(t, queued.WarmInstance)
+
+	// The next one takes the other sandbox, then the pool is empty
+	queued = submit(request(1000))
+	assert.Equal(t, domain.SandboxID("warm-new"), queued.WarmInstance)
+	queued = submit(request(1000))
+	assert.Empty(t, queued.WarmInstance)
+
+	// Requests of heat levels that do not claim start cold
+	heartbeat(t, registry, "node-2", 0, 0, readyWarm("warm-3", now))
+	manager.WarmPool.Config.HeatLevels = []string{"hot"}
+	req := request(500)
+	req.HeatLevel = "cold"
+	queued = submit(req)
+	assert.Empty(t, queued.WarmInstance)
+}
+
+func TestWarmPool_MaxPerNode(t *testing.T) {
+	ctx := context.Background()
+	manager, _, registry := newRunWindowManager(t, domain.RunWindowPolicy{})
+	manager.WarmPool = olympus.NewWarmPool(registry, manager, &mockLogger{}, hermes.NewNoopMetrics(), olympus.WarmPoolConfig{Enabled: true, MaxPerNode: 1})
+	heartbeat(t, registry, "node-2", 0, 0, readyWarm("warm-1", time.Now()))
+
+	prewarm := func() *domain.SandboxRequest {
+		return &domain.SandboxRequest{
+			Template:  "tpl",
+			Command:   []string{"/bin/sh", "-c", "sleep 3600"},
+			Resources: domain.ResourceSpec{CPU: 1000, Mem: 512},
+			Metadata:  map[string]string{domain.MetadataWarm: "true"},
+		}
+	}
+
+	// Pre-warmed sandboxes go to nodes that hold fewer than the cap
+	req := prewarm()
+	require.NoError(t, manager.Submit(ctx, req))
+	run, err := registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.NodeID("node-1"), run.NodeID)
+
+	// Once every node holds its share, no node takes more
+	req = prewarm()
+	require.Error(t, manager.Submit(ctx, req))
+	run, err = registry.GetRun(ctx, req.ID)
+	require.NoError(t, err)
+	require.NotNil(t, run.Scheduling)
+	assert.Equal(t, 2, run.Scheduling.Excluded["warm_pool"])
+}
@@ -351,3 +351,29 @@ func (f *HybridForecaster) Forecast(history []*UsageRecord, startTime time.Time,
 		Confidence:  analysis.Confidence,
 	}
 }
+
+// DemandFactor is the demand predicted at t relative to the forecast's mean,
+// pulled towards 1 as the prediction's confidence drops and clamped to
+// [0.5, 1.5]. It returns 1 when the forecast does not cover t.
+func (f *Forecast) DemandFactor(t time.Time) float64 {
+	if f == nil || len(f.Predictions) == 0 {
+		return 1
+	}
+
+	var total float64
+	var at *Prediction
+	for i := range f.Predictions {
+		p := &f.Predictions[i]
+		total += float64(p.PredictedDemand)
+		if !p.Time.After(t) {
+			at = p
+		}
+	}
+	mean := total / float64(len(f.Predictions))
+	if at == nil || mean <= 0 {
+		return 1
+	}
+
+	factor := 1 + at.Confidence*(float64(at.PredictedDemand)/mean-1)
+	return math.Max(0.5, math.Min(factor, 1.5))
+}
@@ -292,3 +292,22 @@ func TestPatternDetector_DynamicConfidence(t *testing.T) {
 	assert.Greater(t, stableConf, noisyConf, "Stable history should produce higher confidence than noisy history")
 	assert.Greater(t, stableConf, 0.8, "Stable history confidence should be high")
 }
+
+func TestForecast_DemandFactor(t *testing.T) {
+	start := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	forecast := &Forecast{Predictions: []Prediction{
+		{Time: start, PredictedDemand: 10, Confidence: 0.9},
+		{Time: start.Add(time.Hour), PredictedDemand: 30, Confidence: 0.9},
+		{Time: start.Add(2 * time.Hour), PredictedDemand: 20, Confidence: 0.5},
+	}}
+
+	// Mean demand is 20: the busy hour weighs more, the quiet hour less
+	assert.InDelta(t, 1.45, forecast.DemandFactor(start.Add(90*time.Minute)), 0.001)
+	assert.InDelta(t, 0.55, forecast.DemandFactor(start.Add(10*time.Minute)), 0.001)
+	assert.Equal(t, 1.0, forecast.DemandFactor(start.Add(2*time.Hour)))
+
+	// Times the forecast does not cover are neutral
+	assert.Equal(t, 1.0, forecast.DemandFactor(start.Add(-time.Minute)))
+	var none *Forecast
+	assert.Equal(t, 1.0, none.DemandFactor(start))
+}
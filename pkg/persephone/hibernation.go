@@ -67,6 +67,15 @@ type SandboxActivity struct {
 	ID           domain.SandboxID
 	LastActivity time.Time
 	IsIdle       bool
+	MemoryMB     domain.Megabytes // Guest memory freed by hibernation (0 = unknown)
+	Remaining    time.Duration    // Time until the sandbox's TTL ends it (0 = unbounded)
+}
+
+// HibernationAdvisor weighs the cost of hibernating an idle sandbox against
+// the memory it frees. hypnos.Advisor implements it.
+type HibernationAdvisor interface {
+	// AdviseIdle reports whether hibernating the sandbox pays off, and why.
+	AdviseIdle(id domain.SandboxID, memoryMB domain.Megabytes, idleFor, remaining time.Duration) (bool, string)
 }
 
 // HibernationController manages sandbox hibernation cycles
@@ -76,6 +85,7 @@ type HibernationController struct {
 	scaler    SeasonalScaler
 	scheduler *CronScheduler
 	metrics   hermes.Metrics
+	advisor   HibernationAdvisor
 
 	mu            sync.RWMutex
 	currentConfig *HibernationConfig
@@ -105,6 +115,14 @@ func NewHibernationController(
 	}
 }
 
+// SetAdvisor makes idle hibernation skip sandboxes the advisor expects to
+// cost more to hibernate than they save.
+func (c *HibernationController) SetAdvisor(advisor HibernationAdvisor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advisor = advisor
+}
+
 // Start begins the hibernation control loop
 func (c *HibernationController) Start(ctx context.Context, checkInterval time.Duration) error {
 	c.mu.Lock()
@@ -229,6 +247,9 @@ func (c *HibernationController) hibernateIdle(ctx context.Context, idleThreshold
 		// Check if sandbox has been idle long enough
 		idleDuration := now.Sub(activity.LastActivity)
 		if activity.IsIdle && idleDuration >= idleThreshold {
+			if !c.worthHibernating(activity, idleDuration) {
+				continue
+			}
 			if _, err := c.hypnos.Sleep(ctx, activity.ID, nil); err != nil {
 				// Log error but continue with other sandboxes
 				if c.metrics != nil {
@@ -275,6 +296,9 @@ func (c *HibernationController) hibernateForSchedule(ctx context.Context, config
 		// (even briefly idle) unless they're in the warm pool
 		idleDuration := now.Sub(activity.LastActivity)
 		if activity.IsIdle && idleDuration > 10*time.Second {
+			if !c.worthHibernating(activity, idleDuration) {
+				continue
+			}
 			if _, err := c.hypnos.Sleep(ctx, activity.ID, nil); err != nil {
 				continue
 			}
@@ -289,6 +313,27 @@ func (c *HibernationController) hibernateForSchedule(ctx context.Context, config
 	}
 }
 
+// worthHibernating consults the advisor, if any, and records its verdict.
+func (c *HibernationController) worthHibernating(activity SandboxActivity, idleFor time.Duration) bool {
+	c.mu.RLock()
+	advisor := c.advisor
+	c.mu.RUnlock()
+	if advisor == nil || activity.MemoryMB == 0 {
+		return true
+	}
+
+	hibernate, _ := advisor.AdviseIdle(activity.ID, activity.MemoryMB, idleFor, activity.Remaining)
+	if c.metrics != nil {
+		result := "hibernate"
+		if !hibernate {
+			result = "keep"
+		}
+		c.metrics.IncCounter("persephone_hibernation_advice_total", 1,
+			hermes.Label{Key: "result", Value: result})
+	}
+	return hibernate
+}
+
 // WakeForDemand wakes hibernating sandboxes to meet demand
 func (c *HibernationController) WakeForDemand(ctx context.Context, count int) (int, error) {
 	if c.hypnos == nil || count <= 0 {
@@ -252,3 +252,49 @@ func TestHibernationConfig_Disabled(t *testing.T) {
 	ctx := context.Background()
 	controller.evaluate(ctx) // Should not panic
 }
+
+// mockAdvisor recommends hibernating sandboxes with at least minMemory
+type mockAdvisor struct {
+	minMemory domain.Megabytes
+}
+
+func (m *mockAdvisor) AdviseIdle(id domain.SandboxID, memoryMB domain.Megabytes, idleFor, remaining time.Duration) (bool, string) {
+	if memoryMB < m.minMemory {
+		return false, "too small"
+	}
+	return true, "large enough"
+}
+
+func TestHibernationController_Advisor(t *testing.T) {
+	hypnos := newMockHypnosManager()
+	now := time.Now()
+
+	lister := &mockSandboxLister{
+		activities: []SandboxActivity{
+			{ID: "large", LastActivity: now.Add(-30 * time.Minute), IsIdle: true, MemoryMB: 4096},
+			{ID: "tiny", LastActivity: now.Add(-30 * time.Minute), IsIdle: true, MemoryMB: 64},
+			{ID: "unknown", LastActivity: now.Add(-30 * time.Minute), IsIdle: true}, // No memory reported
+			{ID: "busy-1", LastActivity: now, IsIdle: false, MemoryMB: 4096},
+			{ID: "busy-2", LastActivity: now, IsIdle: false, MemoryMB: 4096},
+			{ID: "busy-3", LastActivity: now, IsIdle: false, MemoryMB: 4096},
+		},
+	}
+
+	controller := NewHibernationController(hypnos, lister, nil, nil, nil)
+	controller.now = func() time.Time { return now }
+	controller.SetAdvisor(&mockAdvisor{minMemory: 512})
+
+	hibernated, err := controller.HibernateIdle(context.Background(), 10*time.Minute)
+	if err != nil {
+		t.Fatalf("HibernateIdle failed: %v", err)
+	}
+	if hibernated != 2 {
+		t.Errorf("Expected 2 hibernated, got %d", hibernated)
+	}
+	if hypnos.IsSleeping("tiny") {
+		t.Error("tiny should not be hibernated against the advice")
+	}
+	if !hypnos.IsSleeping("large") || !hypnos.IsSleeping("unknown") {
+		t.Error("large and unknown should be sleeping")
+	}
+}
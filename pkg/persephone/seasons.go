@@ -45,6 +45,11 @@ type Season struct {
 	// Pre-warming configuration
 	Prewarming PrewarmConfig
 
+	// Per-template and per-node-group capacity targets, e.g. 20 warm
+	// python-ds sandboxes and 2 GPU nodes for the winter season
+	WarmPools  map[string]int // Template ID -> warm sandboxes to keep
+	NodeGroups map[string]int // Node group -> nodes to run
+
 	// Resource class distribution
 	ResourceMix map[string]float64
 
@@ -55,6 +60,21 @@ type Season struct {
 	Hibernation HibernationConfig
 }
 
+// WarmPoolTargets returns the warm sandboxes to keep per template: the
+// uniform Prewarming pool size for its templates, overridden by WarmPools.
+func (s *Season) WarmPoolTargets() map[string]int {
+	targets := make(map[string]int, len(s.Prewarming.Templates)+len(s.WarmPools))
+	if s.Prewarming.PoolSize > 0 {
+		for _, tpl := range s.Prewarming.Templates {
+			targets[tpl] = s.Prewarming.PoolSize
+		}
+	}
+	for tpl, size := range s.WarmPools {
+		targets[tpl] = size
+	}
+	return targets
+}
+
 type SeasonSchedule struct {
 	// Cron-style schedules
 	StartCron string // e.g., "0 8 * * MON-FRI" (8am weekdays)
@@ -339,6 +359,24 @@ func (s *BasicSeasonalScaler) RecommendCapacity(ctx context.Context, targetUtil
 	}, nil
 }
 
+// History implements HistoryReader, reading from storage when configured
+// as it holds more than the in-memory history.
+func (s *BasicSeasonalScaler) History(ctx context.Context, since time.Time) ([]*UsageRecord, error) {
+	if s.store != nil {
+		return s.store.Load(ctx, since, time.Now())
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var records []*UsageRecord
+	for _, r := range s.history {
+		if !r.Timestamp.Before(since) {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
 // LoadHistory restores historical data from storage
 func (s *BasicSeasonalScaler) LoadHistory(ctx context.Context, days int) error {
 	if s.store == nil {
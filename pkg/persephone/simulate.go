@@ -0,0 +1,190 @@
+package persephone
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrNoHistory is returned when there is no recorded demand to simulate
+// against.
+var ErrNoHistory = errors.New("no usage history to simulate against")
+
+// defaultTargetUtilization is used for seasons that set none, as for
+// capacity recommendations.
+const defaultTargetUtilization = 0.7
+
+// HistoryReader is implemented by seasonal scalers that can return the
+// demand they learned from.
+type HistoryReader interface {
+	// History returns the usage records since the given time, oldest first.
+	History(ctx context.Context, since time.Time) ([]*UsageRecord, error)
+}
+
+// SimulationOptions parameterise the capacity model of a simulation.
+type SimulationOptions struct {
+	// SandboxesPerNode is how many sandboxes a node holds at full
+	// utilization. Defaults to 1, as capacity recommendations assume.
+	SandboxesPerNode int
+	// CostPerNodeHour prices node hours for seasons whose budget sets no
+	// cost.
+	CostPerNodeHour float64
+}
+
+// SeasonProjection is how a season would have served recorded demand.
+type SeasonProjection struct {
+	SeasonID            string  `json:"season_id,omitempty"`
+	NodeHours           float64 `json:"node_hours"`
+	Cost                float64 `json:"cost"`
+	PeakNodes           int     `json:"peak_nodes"`
+	MaxQueueDepth       int     `json:"max_queue_depth"`
+	AvgQueueWaitSeconds float64 `json:"avg_queue_wait_seconds"` // Over sandboxes that queued
+	MaxQueueWaitSeconds float64 `json:"max_queue_wait_seconds"`
+	ShortfallHours      float64 `json:"shortfall_hours"` // Time with demand above capacity
+}
+
+// SimulationReport compares a proposed season with a baseline over the same
+// recorded demand.
+type SimulationReport struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Records  int               `json:"records"`
+	Baseline *SeasonProjection `json:"baseline,omitempty"` // Nil without a baseline season
+	Proposed SeasonProjection  `json:"proposed"`
+
+	// Proposed minus baseline; zero without a baseline
+	NodeHoursDelta           float64 `json:"node_hours_delta"`
+	CostDelta                float64 `json:"cost_delta"`
+	AvgQueueWaitDeltaSeconds float64 `json:"avg_queue_wait_delta_seconds"`
+}
+
+// Simulate replays recorded demand against the proposed season, and against
+// the baseline season if given, as if each had been active throughout.
+// Nothing is scaled.
+//
+// Demand at each record is its active sandboxes plus its queue depth. Nodes
+// follow the demand of the previous record, as the scaler reacts a tick
+// late, divided by the season's target utilization and clamped to its node
+// bounds. Seasons that pre-warm also cover the demand recorded within their
+// lead time, as a perfect forecast would, so the projection is the best
+// pre-warming can do. Sandboxes beyond capacity queue until demand next fits.
+func Simulate(history []*UsageRecord, baseline, proposed *Season, opts SimulationOptions) (*SimulationReport, error) {
+	if len(history) == 0 {
+		return nil, ErrNoHistory
+	}
+	records := make([]*UsageRecord, len(history))
+	copy(records, history)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	if opts.SandboxesPerNode <= 0 {
+		opts.SandboxesPerNode = 1
+	}
+
+	report := &SimulationReport{
+		From:     records[0].Timestamp,
+		To:       records[len(records)-1].Timestamp,
+		Records:  len(records),
+		Proposed: project(records, proposed, opts),
+	}
+	if baseline != nil {
+		b := project(records, baseline, opts)
+		report.Baseline = &b
+		report.NodeHoursDelta = report.Proposed.NodeHours - b.NodeHours
+		report.CostDelta = report.Proposed.Cost - b.Cost
+		report.AvgQueueWaitDeltaSeconds = report.Proposed.AvgQueueWaitSeconds - b.AvgQueueWaitSeconds
+	}
+	return report, nil
+}
+
+// project simulates one season over records sorted oldest first.
+func project(records []*UsageRecord, season *Season, opts SimulationOptions) SeasonProjection {
+	out := SeasonProjection{SeasonID: season.ID}
+	targetUtil := season.TargetUtilization
+	if targetUtil <= 0 {
+		targetUtil = defaultTargetUtilization
+	}
+	costPerNodeHour := season.Budget.CostPerNodeHour
+	if costPerNodeHour <= 0 {
+		costPerNodeHour = opts.CostPerNodeHour
+	}
+
+	n := len(records)
+	demand := make([]int, n)
+	for i, r := range records {
+		demand[i] = r.ActiveVMs + r.QueueDepth
+	}
+	steps := stepDurations(records)
+
+	capacity := make([]int, n)
+	for i := range records {
+		want := demand[0]
+		if i > 0 {
+			want = demand[i-1]
+		}
+		if lead := season.Prewarming.LeadTime; lead > 0 {
+			horizon := records[i].Timestamp.Add(lead)
+			for j := i; j < n && !records[j].Timestamp.After(horizon); j++ {
+				want = max(want, demand[j])
+			}
+		}
+		nodes := int(math.Ceil(float64(want) / (targetUtil * float64(opts.SandboxesPerNode))))
+		nodes = max(nodes, season.MinNodes)
+		if season.MaxNodes > 0 {
+			nodes = min(nodes, season.MaxNodes)
+		}
+		capacity[i] = nodes * opts.SandboxesPerNode
+
+		hours := steps[i].Hours()
+		out.NodeHours += float64(nodes) * hours
+		out.PeakNodes = max(out.PeakNodes, nodes)
+	}
+	out.Cost = out.NodeHours * costPerNodeHour
+
+	// Sandboxes queued at a record wait until the first later record whose
+	// demand fits, or the end of the history
+	drained := make([]time.Time, n)
+	end := records[n-1].Timestamp.Add(steps[n-1])
+	for i := n - 1; i >= 0; i-- {
+		drained[i] = end
+		if demand[i] <= capacity[i] {
+			end = records[i].Timestamp
+		}
+	}
+	var queued int
+	var waited float64
+	for i := range records {
+		depth := demand[i] - capacity[i]
+		if depth <= 0 {
+			continue
+		}
+		out.MaxQueueDepth = max(out.MaxQueueDepth, depth)
+		out.ShortfallHours += steps[i].Hours()
+
+		wait := drained[i].Sub(records[i].Timestamp).Seconds()
+		queued += depth
+		waited += float64(depth) * wait
+		out.MaxQueueWaitSeconds = math.Max(out.MaxQueueWaitSeconds, wait)
+	}
+	if queued > 0 {
+		out.AvgQueueWaitSeconds = waited / float64(queued)
+	}
+	return out
+}
+
+// stepDurations returns how long each record stands for: until the next
+// record, and for the last one as long as the one before it. A lone record
+// stands for one scaler tick.
+func stepDurations(records []*UsageRecord) []time.Duration {
+	steps := make([]time.Duration, len(records))
+	for i := 0; i+1 < len(records); i++ {
+		steps[i] = records[i+1].Timestamp.Sub(records[i].Timestamp)
+	}
+	last := len(records) - 1
+	if last > 0 {
+		steps[last] = steps[last-1]
+	} else {
+		steps[last] = time.Minute
+	}
+	return steps
+}
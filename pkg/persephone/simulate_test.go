@@ -0,0 +1,104 @@
+package persephone
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// spikeHistory records hourly demand of 2 sandboxes with a two-hour spike
+// to 10.
+func spikeHistory(start time.Time) []*UsageRecord {
+	var history []*UsageRecord
+	for i, demand := range []int{2, 2, 10, 10, 2, 2} {
+		history = append(history, &UsageRecord{
+			Timestamp:  start.Add(time.Duration(i) * time.Hour),
+			ActiveVMs:  min(demand, 4),
+			QueueDepth: max(demand-4, 0),
+		})
+	}
+	return history
+}
+
+func TestSimulate(t *testing.T) {
+	history := spikeHistory(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	reactive := &Season{ID: "reactive", MinNodes: 1, MaxNodes: 20, TargetUtilization: 1}
+	prewarmed := &Season{ID: "prewarmed", MinNodes: 1, MaxNodes: 20, TargetUtilization: 1,
+		Prewarming: PrewarmConfig{LeadTime: time.Hour}}
+
+	report, err := Simulate(history, reactive, prewarmed, SimulationOptions{CostPerNodeHour: 0.5})
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if report.Records != 6 || !report.From.Equal(history[0].Timestamp) || !report.To.Equal(history[5].Timestamp) {
+		t.Errorf("unexpected range: %+v", report)
+	}
+
+	// The reactive season scales up an hour into the spike
+	base := report.Baseline
+	if base == nil {
+		t.Fatal("expected a baseline projection")
+	}
+	if base.NodeHours != 28 || base.Cost != 14 || base.PeakNodes != 10 {
+		t.Errorf("unexpected baseline capacity: %+v", base)
+	}
+	if base.MaxQueueDepth != 8 || base.AvgQueueWaitSeconds != 3600 || base.MaxQueueWaitSeconds != 3600 || base.ShortfallHours != 1 {
+		t.Errorf("unexpected baseline queueing: %+v", base)
+	}
+
+	// Pre-warming an hour ahead absorbs the spike at the cost of 16 node hours
+	if report.Proposed.NodeHours != 44 || report.Proposed.MaxQueueDepth != 0 || report.Proposed.AvgQueueWaitSeconds != 0 {
+		t.Errorf("unexpected proposed projection: %+v", report.Proposed)
+	}
+	if report.NodeHoursDelta != 16 || report.CostDelta != 8 || report.AvgQueueWaitDeltaSeconds != -3600 {
+		t.Errorf("unexpected deltas: %+v", report)
+	}
+}
+
+func TestSimulate_MaxNodes(t *testing.T) {
+	history := spikeHistory(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	capped := &Season{ID: "capped", MaxNodes: 5, TargetUtilization: 1}
+
+	report, err := Simulate(history, nil, capped, SimulationOptions{})
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if report.Baseline != nil || report.NodeHoursDelta != 0 {
+		t.Errorf("expected no baseline, got %+v", report)
+	}
+
+	// 8 sandboxes wait two hours and 5 one hour for the spike to pass
+	p := report.Proposed
+	if p.PeakNodes != 5 || p.MaxQueueDepth != 8 || p.MaxQueueWaitSeconds != 7200 || p.ShortfallHours != 2 {
+		t.Errorf("unexpected projection: %+v", p)
+	}
+	if want := (8*7200.0 + 5*3600.0) / 13; math.Abs(p.AvgQueueWaitSeconds-want) > 1e-9 {
+		t.Errorf("expected average wait %f, got %f", want, p.AvgQueueWaitSeconds)
+	}
+}
+
+func TestSimulate_NoHistory(t *testing.T) {
+	if _, err := Simulate(nil, nil, SeasonSummer, SimulationOptions{}); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("expected ErrNoHistory, got %v", err)
+	}
+}
+
+func TestBasicSeasonalScaler_History(t *testing.T) {
+	ctx := context.Background()
+	scaler := NewBasicSeasonalScaler()
+	now := time.Now()
+	scaler.Learn(ctx, []*UsageRecord{
+		{Timestamp: now.Add(-48 * time.Hour), ActiveVMs: 1},
+		{Timestamp: now.Add(-time.Hour), ActiveVMs: 2},
+	})
+
+	records, err := scaler.History(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(records) != 1 || records[0].ActiveVMs != 2 {
+		t.Errorf("expected the last day's record, got %+v", records)
+	}
+}
@@ -0,0 +1,156 @@
+package phlegethon
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// FeedbackConfig controls how observed behavior feeds back into
+// classification.
+type FeedbackConfig struct {
+	// HalfLife is how long until an observation counts half as much
+	HalfLife time.Duration
+	// MaxAge drops observations older than this
+	MaxAge time.Duration
+	// MinWeight is the decayed weight of evidence needed before observed
+	// heat overrides template hints and heuristics
+	MinWeight float64
+	// MaxObservations bounds the history kept per template
+	MaxObservations int
+}
+
+// DefaultFeedbackConfig returns the feedback settings used when none are configured.
+func DefaultFeedbackConfig() FeedbackConfig {
+	return FeedbackConfig{
+		HalfLife:        6 * time.Hour,
+		MaxAge:          7 * 24 * time.Hour,
+		MinWeight:       3,
+		MaxObservations: 100,
+	}
+}
+
+// heatOrder ranks heat levels for averaging.
+var heatOrder = []HeatLevel{HeatCold, HeatWarm, HeatHot, HeatInferno}
+
+func heatRank(level HeatLevel) int {
+	for i, l := range heatOrder {
+		if l == level {
+			return i
+		}
+	}
+	return 0
+}
+
+// ObservedLevel maps what a single sandbox actually used onto the coolest
+// default resource class it fits in; the most intense dimension wins.
+func ObservedLevel(obs HeatObservation) HeatLevel {
+	for _, level := range heatOrder[:len(heatOrder)-1] {
+		class := DefaultResourceClasses[level]
+		if obs.ActualDuration <= class.MaxDuration &&
+			obs.PeakCPU <= float64(class.CPUCores) &&
+			obs.PeakMemory <= int64(class.MemoryMB) &&
+			obs.PeakNetworkMbps <= float64(class.NetworkBurst) {
+			return level
+		}
+	}
+	return HeatInferno
+}
+
+// Observe records what a sandbox of a template actually used. Observations
+// are decayed by age, so the template's observed heat tracks recent behavior.
+func (c *HeatClassifier) Observe(obs HeatObservation) {
+	if obs.TemplateID == "" {
+		return
+	}
+	if obs.Timestamp.IsZero() {
+		obs.Timestamp = time.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	history := append(c.history[obs.TemplateID], obs)
+	if max := c.Feedback.MaxObservations; max > 0 && len(history) > max {
+		history = history[len(history)-max:]
+	}
+	c.history[obs.TemplateID] = history
+}
+
+// ObservedHeat returns the template's decay-weighted observed heat and the
+// weight of evidence behind it. ok is false once all observations are stale.
+func (c *HeatClassifier) ObservedHeat(templateID string, now time.Time) (level HeatLevel, weight float64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.observedHeat(templateID, now)
+}
+
+// observedHeat prunes stale observations and averages the rest; c.mu must be held.
+func (c *HeatClassifier) observedHeat(templateID string, now time.Time) (HeatLevel, float64, bool) {
+	history := c.history[templateID]
+	fresh := history[:0]
+	var sum, weight float64
+	for _, obs := range history {
+		age := now.Sub(obs.Timestamp)
+		if c.Feedback.MaxAge > 0 && age > c.Feedback.MaxAge {
+			continue
+		}
+		fresh = append(fresh, obs)
+		w := 1.0
+		if c.Feedback.HalfLife > 0 && age > 0 {
+			w = math.Exp2(-float64(age) / float64(c.Feedback.HalfLife))
+		}
+		sum += w * float64(heatRank(ObservedLevel(obs)))
+		weight += w
+	}
+
+	if len(fresh) == 0 {
+		delete(c.history, templateID)
+		return "", 0, false
+	}
+	c.history[templateID] = fresh
+	return heatOrder[int(math.Round(sum/weight))], weight, true
+}
+
+// TemplateHeat compares a template's configured heat with what was observed.
+type TemplateHeat struct {
+	TemplateID   string    `json:"template_id"`
+	Configured   HeatLevel `json:"configured,omitempty"` // Template hint, if any
+	Observed     HeatLevel `json:"observed,omitempty"`
+	Effective    HeatLevel `json:"effective,omitempty"` // Used for requests without an explicit hint
+	Weight       float64   `json:"weight"`              // Decayed weight of the observations
+	Observations int       `json:"observations"`
+	LastObserved time.Time `json:"last_observed,omitempty"`
+}
+
+// HeatReport lists observed against configured heat for every template
+// with a hint or fresh observations.
+func (c *HeatClassifier) HeatReport(now time.Time) []TemplateHeat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := make(map[string]bool)
+	for id := range c.templateHints {
+		ids[id] = true
+	}
+	for id := range c.history {
+		ids[id] = true
+	}
+
+	report := make([]TemplateHeat, 0, len(ids))
+	for id := range ids {
+		entry := TemplateHeat{TemplateID: id, Configured: c.templateHints[id], Effective: c.templateHints[id]}
+		if level, weight, ok := c.observedHeat(id, now); ok {
+			history := c.history[id]
+			entry.Observed = level
+			entry.Weight = weight
+			entry.Observations = len(history)
+			entry.LastObserved = history[len(history)-1].Timestamp
+			if weight >= c.Feedback.MinWeight {
+				entry.Effective = level
+			}
+		}
+		report = append(report, entry)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].TemplateID < report[j].TemplateID })
+	return report
+}
@@ -0,0 +1,92 @@
+package phlegethon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObservedLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		obs  HeatObservation
+		want HeatLevel
+	}{
+		{"Idle", HeatObservation{ActualDuration: 5 * time.Second, PeakCPU: 0.2, PeakMemory: 128}, HeatCold},
+		{"Memory", HeatObservation{ActualDuration: 5 * time.Second, PeakMemory: 3000}, HeatHot},
+		{"Network", HeatObservation{ActualDuration: 5 * time.Second, PeakNetworkMbps: 200}, HeatWarm},
+		{"CPU", HeatObservation{ActualDuration: 5 * time.Second, PeakCPU: 6}, HeatInferno},
+		{"Duration", HeatObservation{ActualDuration: 2 * time.Hour}, HeatInferno},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ObservedLevel(tt.obs); got != tt.want {
+				t.Errorf("ObservedLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeatClassifier_ObservationsOverrideHint(t *testing.T) {
+	c := NewHeatClassifier()
+	c.AddHint("etl", HeatCold)
+	req := &SandboxRequest{TemplateID: "etl"}
+	now := time.Now()
+
+	hot := HeatObservation{TemplateID: "etl", ActualDuration: 10 * time.Minute, PeakCPU: 3, Timestamp: now}
+	for i := 0; i < 2; i++ {
+		c.Observe(hot)
+	}
+	if level, source := c.Classify(req); level != HeatCold || source != "template_hint" {
+		t.Errorf("too little evidence should keep the hint, got %v (%s)", level, source)
+	}
+
+	c.Observe(hot)
+	c.Observe(hot)
+	if level, source := c.Classify(req); level != HeatHot || source != "observed" {
+		t.Errorf("expected observed hot, got %v (%s)", level, source)
+	}
+
+	// An explicit hint still wins
+	if level, _ := c.Classify(&SandboxRequest{TemplateID: "etl", HeatHint: HeatWarm}); level != HeatWarm {
+		t.Errorf("expected explicit warm, got %v", level)
+	}
+
+	report := c.HeatReport(now)
+	if len(report) != 1 {
+		t.Fatalf("expected one template, got %+v", report)
+	}
+	got := report[0]
+	if got.Configured != HeatCold || got.Observed != HeatHot || got.Effective != HeatHot || got.Observations != 4 {
+		t.Errorf("unexpected report %+v", got)
+	}
+}
+
+func TestHeatClassifier_ObservationsDecay(t *testing.T) {
+	c := NewHeatClassifier()
+	c.Feedback = FeedbackConfig{HalfLife: time.Hour, MaxAge: 24 * time.Hour, MinWeight: 2}
+	now := time.Now()
+
+	// Old inferno runs are outweighed by recent cold ones
+	for i := 0; i < 4; i++ {
+		c.Observe(HeatObservation{TemplateID: "api", ActualDuration: time.Hour, Timestamp: now.Add(-5 * time.Hour)})
+		c.Observe(HeatObservation{TemplateID: "api", ActualDuration: time.Second, Timestamp: now})
+	}
+	level, weight, ok := c.ObservedHeat("api", now)
+	if !ok || level != HeatCold {
+		t.Errorf("expected recent cold runs to dominate, got %v (ok=%v)", level, ok)
+	}
+	if weight < 4 || weight > 4.5 {
+		t.Errorf("expected decayed weight just over 4, got %v", weight)
+	}
+
+	// Once everything is stale the template falls back to heuristics
+	if _, _, ok := c.ObservedHeat("api", now.Add(48*time.Hour)); ok {
+		t.Error("expected stale observations to be dropped")
+	}
+	if level, source := c.Classify(&SandboxRequest{TemplateID: "api"}); source != "heuristic" || level != HeatCold {
+		t.Errorf("expected heuristic classification, got %v (%s)", level, source)
+	}
+	if report := c.HeatReport(now); len(report) != 0 {
+		t.Errorf("expected no templates left, got %+v", report)
+	}
+}
@@ -2,6 +2,7 @@ package phlegethon
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
@@ -36,33 +37,37 @@ type ResourceClass struct {
 
 var DefaultResourceClasses = map[HeatLevel]*ResourceClass{
 	HeatCold: {
-		Name:        "ember",
-		Heat:        HeatCold,
-		CPUCores:    1,
-		MemoryMB:    512,
-		MaxDuration: 30 * time.Second,
+		Name:         "ember",
+		Heat:         HeatCold,
+		CPUCores:     1,
+		MemoryMB:     512,
+		MaxDuration:  30 * time.Second,
+		NetworkBurst: 100,
 	},
 	HeatWarm: {
-		Name:        "flame",
-		Heat:        HeatWarm,
-		CPUCores:    2,
-		MemoryMB:    2048,
-		MaxDuration: 5 * time.Minute,
+		Name:         "flame",
+		Heat:         HeatWarm,
+		CPUCores:     2,
+		MemoryMB:     2048,
+		MaxDuration:  5 * time.Minute,
+		NetworkBurst: 250,
 	},
 	HeatHot: {
-		Name:        "blaze",
-		Heat:        HeatHot,
-		CPUCores:    4,
-		MemoryMB:    8192,
-		MaxDuration: 30 * time.Minute,
+		Name:         "blaze",
+		Heat:         HeatHot,
+		CPUCores:     4,
+		MemoryMB:     8192,
+		MaxDuration:  30 * time.Minute,
+		NetworkBurst: 1000,
 	},
 	HeatInferno: {
-		Name:        "inferno",
-		Heat:        HeatInferno,
-		CPUCores:    8,
-		MemoryMB:    32768,
-		GPUCount:    1,
-		MaxDuration: 24 * time.Hour,
+		Name:         "inferno",
+		Heat:         HeatInferno,
+		CPUCores:     8,
+		MemoryMB:     32768,
+		GPUCount:     1,
+		MaxDuration:  24 * time.Hour,
+		NetworkBurst: 10000,
 	},
 }
 
@@ -121,15 +126,21 @@ type HeatRouter interface {
 
 // HeatObservation records actual usage for learning
 type HeatObservation struct {
-	TemplateID     string
-	ActualDuration time.Duration
-	PeakCPU        float64
-	PeakMemory     int64
-	Timestamp      time.Time
+	TemplateID      string
+	ActualDuration  time.Duration
+	PeakCPU         float64 // Cores
+	PeakMemory      int64   // MB
+	PeakNetworkMbps float64
+	Timestamp       time.Time
 }
 
 // HeatClassifier uses heuristics to classify workloads
 type HeatClassifier struct {
+	// Feedback controls how observations override configured heat
+	Feedback FeedbackConfig
+
+	mu sync.Mutex
+
 	// Historical data for learning
 	history map[string][]HeatObservation
 
@@ -139,6 +150,7 @@ type HeatClassifier struct {
 
 func NewHeatClassifier() *HeatClassifier {
 	return &HeatClassifier{
+		Feedback:      DefaultFeedbackConfig(),
 		history:       make(map[string][]HeatObservation),
 		templateHints: make(map[string]HeatLevel),
 	}
@@ -150,12 +162,20 @@ func (c *HeatClassifier) Classify(req *SandboxRequest) (HeatLevel, string) {
 		return req.HeatHint, "explicit"
 	}
 
-	// 2. Check template-based hint
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 2. Check observed behavior, once there is enough recent evidence
+	if level, weight, ok := c.observedHeat(req.TemplateID, time.Now()); ok && weight >= c.Feedback.MinWeight {
+		return level, "observed"
+	}
+
+	// 3. Check template-based hint
 	if hint, ok := c.templateHints[req.TemplateID]; ok {
 		return hint, "template_hint"
 	}
 
-	// 3. Use resource request as indicator
+	// 4. Use resource request as indicator
 	if req.MaxDuration > 10*time.Minute || req.CPUCores >= 4 {
 		return HeatInferno, "heuristic"
 	}
@@ -170,5 +190,7 @@ func (c *HeatClassifier) Classify(req *SandboxRequest) (HeatLevel, string) {
 }
 
 func (c *HeatClassifier) AddHint(templateID string, level HeatLevel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.templateHints[templateID] = level
 }
@@ -2,10 +2,13 @@ package plugins
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 	"github.com/tartarus-sandbox/tartarus/pkg/erinyes"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 	"github.com/tartarus-sandbox/tartarus/pkg/judges"
 )
 
@@ -216,3 +219,89 @@ func TestCompositeFury(t *testing.T) {
 		t.Error("expected both furies to be disarmed")
 	}
 }
+
+// MockAuthenticatorPlugin accepts a single token
+type MockAuthenticatorPlugin struct {
+	name      string
+	token     string
+	healthErr error
+}
+
+func (m *MockAuthenticatorPlugin) Name() string                     { return m.name }
+func (m *MockAuthenticatorPlugin) Version() string                  { return "1.0.0" }
+func (m *MockAuthenticatorPlugin) Type() PluginType                 { return PluginTypeAuthenticator }
+func (m *MockAuthenticatorPlugin) Init(config map[string]any) error { return nil }
+func (m *MockAuthenticatorPlugin) Close() error                     { return nil }
+
+func (m *MockAuthenticatorPlugin) Authenticate(ctx context.Context, cred *Credential) (*Identity, error) {
+	if cred.Token != m.token {
+		return nil, errors.New("unknown token")
+	}
+	return &Identity{ID: "kerberos:alice", TenantID: "acme", Roles: []string{"user"}}, nil
+}
+
+func (m *MockAuthenticatorPlugin) Health(ctx context.Context) error { return m.healthErr }
+
+type countingMetrics struct {
+	hermes.NoopMetrics
+	counters map[string]float64
+	gauges   map[string]float64
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{counters: make(map[string]float64), gauges: make(map[string]float64)}
+}
+
+func labelKey(name string, labels []hermes.Label) string {
+	for _, l := range labels {
+		name += "," + l.Key + "=" + l.Value
+	}
+	return name
+}
+
+func (m *countingMetrics) IncCounter(name string, value float64, labels ...hermes.Label) {
+	m.counters[labelKey(name, labels)] += value
+}
+
+func (m *countingMetrics) SetGauge(name string, value float64, labels ...hermes.Label) {
+	m.gauges[labelKey(name, labels)] = value
+}
+
+func TestAuthenticatorPluginAdapter(t *testing.T) {
+	ctx := context.Background()
+	metrics := newCountingMetrics()
+
+	sso := &MockAuthenticatorPlugin{name: "sso", token: "sso-token", healthErr: errors.New("idp unreachable")}
+	kerberos := &MockAuthenticatorPlugin{name: "kerberos", token: "krb-ticket"}
+	multi := cerberus.NewMultiAuthenticator(WrapAuthenticatorPlugins([]AuthenticatorPlugin{sso, kerberos})...).WithMetrics(metrics)
+
+	identity, err := multi.Authenticate(ctx, &cerberus.APIKeyCredential{Secret: "krb-ticket"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.ID != "kerberos:alice" || identity.Type != cerberus.IdentityTypeUser {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if identity.Attributes["auth_plugin"] != "kerberos" {
+		t.Errorf("expected auth_plugin attribute, got %v", identity.Attributes)
+	}
+
+	if _, err := multi.Authenticate(ctx, &cerberus.BearerTokenCredential{Token: "bogus"}); err == nil {
+		t.Error("expected error for unknown token")
+	}
+
+	if got := metrics.counters["cerberus_authenticator_attempts_total,source=sso,result=failure"]; got != 2 {
+		t.Errorf("sso failures = %v, want 2", got)
+	}
+	if got := metrics.counters["cerberus_authenticator_attempts_total,source=kerberos,result=success"]; got != 1 {
+		t.Errorf("kerberos successes = %v, want 1", got)
+	}
+
+	health := multi.Health(ctx)
+	if len(health) != 2 || health[0].Healthy || health[0].Error != "idp unreachable" || !health[1].Healthy {
+		t.Errorf("unexpected health %+v", health)
+	}
+	if got := metrics.gauges["cerberus_authenticator_healthy,source=sso"]; got != 0 {
+		t.Errorf("sso healthy gauge = %v, want 0", got)
+	}
+}
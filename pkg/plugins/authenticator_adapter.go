@@ -0,0 +1,106 @@
+package plugins
+
+import (
+	"context"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+)
+
+// AuthenticatorPluginAdapter wraps an AuthenticatorPlugin to implement
+// cerberus.NamedAuthenticator and cerberus.HealthChecker.
+type AuthenticatorPluginAdapter struct {
+	plugin AuthenticatorPlugin
+}
+
+// NewAuthenticatorPluginAdapter creates a new adapter for an authenticator plugin.
+func NewAuthenticatorPluginAdapter(plugin AuthenticatorPlugin) *AuthenticatorPluginAdapter {
+	return &AuthenticatorPluginAdapter{plugin: plugin}
+}
+
+// Authenticate implements cerberus.Authenticator.
+func (a *AuthenticatorPluginAdapter) Authenticate(ctx context.Context, creds cerberus.Credentials) (*cerberus.Identity, error) {
+	cred := toPluginCredential(creds)
+	if cred == nil {
+		return nil, cerberus.NewAuthenticationError("credential type not supported by plugin "+a.plugin.Name(), nil).
+			WithCode(cerberus.AuthCodeUnsupportedCredentials)
+	}
+
+	identity, err := a.plugin.Authenticate(ctx, cred)
+	if err != nil {
+		// Plugins may classify their refusals with a cerberus code
+		authErr := cerberus.NewAuthenticationError("plugin "+a.plugin.Name()+" rejected credentials", err)
+		if code := cerberus.AuthErrorCodeOf(err); code != "" {
+			authErr.Code = code
+		}
+		return nil, authErr
+	}
+	if identity == nil || identity.ID == "" {
+		return nil, cerberus.NewAuthenticationError("plugin "+a.plugin.Name()+" returned no identity", nil)
+	}
+	return toCerberusIdentity(identity, a.plugin.Name()), nil
+}
+
+// Health implements cerberus.HealthChecker.
+func (a *AuthenticatorPluginAdapter) Health(ctx context.Context) error {
+	return a.plugin.Health(ctx)
+}
+
+// Name returns the plugin name.
+func (a *AuthenticatorPluginAdapter) Name() string {
+	return a.plugin.Name()
+}
+
+// toPluginCredential converts cerberus credentials, returning nil for types
+// plugins are not given (internal and proxy identity tokens).
+func toPluginCredential(creds cerberus.Credentials) *Credential {
+	switch c := creds.(type) {
+	case *cerberus.APIKeyCredential:
+		return &Credential{Type: string(cerberus.CredentialTypeAPIKey), KeyID: c.KeyID, Token: c.Secret}
+	case *cerberus.BearerTokenCredential:
+		return &Credential{Type: string(cerberus.CredentialTypeOAuth2), Token: c.Token}
+	case *cerberus.OAuth2Credential:
+		return &Credential{Type: string(cerberus.CredentialTypeOAuth2), Token: c.AccessToken}
+	case *cerberus.MTLSCredential:
+		cred := &Credential{Type: string(cerberus.CredentialTypeMTLS)}
+		for _, cert := range c.ConnectionState.PeerCertificates {
+			cred.Certificates = append(cred.Certificates, cert.Raw)
+		}
+		return cred
+	}
+	return nil
+}
+
+func toCerberusIdentity(identity *Identity, plugin string) *cerberus.Identity {
+	identityType := cerberus.IdentityType(identity.Type)
+	if identityType == "" {
+		identityType = cerberus.IdentityTypeUser
+	}
+	attributes := make(map[string]string, len(identity.Attributes)+1)
+	for k, v := range identity.Attributes {
+		attributes[k] = v
+	}
+	// Record which plugin vouched for the identity
+	attributes["auth_plugin"] = plugin
+
+	return &cerberus.Identity{
+		ID:          identity.ID,
+		Type:        identityType,
+		TenantID:    identity.TenantID,
+		DisplayName: identity.DisplayName,
+		Roles:       identity.Roles,
+		Groups:      identity.Groups,
+		Attributes:  attributes,
+		AuthTime:    time.Now(),
+		ExpiresAt:   identity.ExpiresAt,
+	}
+}
+
+// WrapAuthenticatorPlugins wraps multiple authenticator plugins as cerberus authenticators.
+func WrapAuthenticatorPlugins(plugins []AuthenticatorPlugin) []cerberus.Authenticator {
+	var authenticators []cerberus.Authenticator
+	for _, p := range plugins {
+		authenticators = append(authenticators, NewAuthenticatorPluginAdapter(p))
+	}
+	return authenticators
+}
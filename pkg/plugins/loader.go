@@ -9,7 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"plugin"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
@@ -28,6 +30,7 @@ type LoadedPlugin struct {
 	Manifest *Manifest
 	Plugin   Plugin
 	Path     string
+	ModTime  time.Time // Entry point modification time, used for hot-swap
 }
 
 // NewLoader creates a new plugin loader.
@@ -91,28 +94,20 @@ func (l *Loader) LoadPlugin(ctx context.Context, pluginDir string) error {
 	}
 	l.mu.RUnlock()
 
-	// Load the shared object
-	soPath := filepath.Join(pluginDir, manifest.Spec.EntryPoint)
-	p, err := plugin.Open(soPath)
+	entryPath := filepath.Join(pluginDir, manifest.Spec.EntryPoint)
+	info, err := os.Stat(entryPath)
 	if err != nil {
-		return fmt.Errorf("failed to open plugin: %w", err)
+		return fmt.Errorf("failed to stat plugin entry point: %w", err)
 	}
 
-	// Look up the plugin symbol
-	sym, err := p.Lookup(PluginSymbol)
-	if err != nil {
-		return fmt.Errorf("plugin missing %s symbol: %w", PluginSymbol, err)
+	var plug Plugin
+	if manifest.Spec.ResolvedRuntime() == PluginRuntimeWasm {
+		plug, err = l.openWasm(ctx, manifest, entryPath)
+	} else {
+		plug, err = openNative(entryPath)
 	}
-
-	// Assert to Plugin interface
-	plug, ok := sym.(Plugin)
-	if !ok {
-		// Try pointer to Plugin
-		plugPtr, ok := sym.(*Plugin)
-		if !ok {
-			return fmt.Errorf("symbol %s does not implement Plugin interface", PluginSymbol)
-		}
-		plug = *plugPtr
+	if err != nil {
+		return err
 	}
 
 	// Validate type matches manifest
@@ -132,6 +127,7 @@ func (l *Loader) LoadPlugin(ctx context.Context, pluginDir string) error {
 		Manifest: manifest,
 		Plugin:   plug,
 		Path:     pluginDir,
+		ModTime:  info.ModTime(),
 	}
 	l.mu.Unlock()
 
@@ -139,11 +135,107 @@ func (l *Loader) LoadPlugin(ctx context.Context, pluginDir string) error {
 		"name":    manifest.Metadata.Name,
 		"version": manifest.Metadata.Version,
 		"type":    manifest.Spec.Type,
+		"runtime": manifest.Spec.ResolvedRuntime(),
 	})
 
 	return nil
 }
 
+// openNative loads a Go plugin shared object.
+func openNative(soPath string) (Plugin, error) {
+	p, err := plugin.Open(soPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin: %w", err)
+	}
+
+	// Look up the plugin symbol
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin missing %s symbol: %w", PluginSymbol, err)
+	}
+
+	// Assert to Plugin interface
+	plug, ok := sym.(Plugin)
+	if !ok {
+		// Try pointer to Plugin
+		plugPtr, ok := sym.(*Plugin)
+		if !ok {
+			return nil, fmt.Errorf("symbol %s does not implement Plugin interface", PluginSymbol)
+		}
+		plug = *plugPtr
+	}
+	return plug, nil
+}
+
+// openWasm compiles a Wasm judge module.
+func (l *Loader) openWasm(ctx context.Context, manifest *Manifest, wasmPath string) (Plugin, error) {
+	wasm, err := os.ReadFile(wasmPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wasm plugin: %w", err)
+	}
+	return NewWasmJudge(ctx, manifest.Metadata.Name, manifest.Metadata.Version, wasm, manifest.Spec.Limits, l.logger)
+}
+
+// ReloadPlugin swaps the module of a loaded Wasm plugin with the current
+// contents of its entry point. Native plugins cannot be reloaded in-process.
+func (l *Loader) ReloadPlugin(ctx context.Context, name string) error {
+	l.mu.RLock()
+	loaded, exists := l.loaded[name]
+	l.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("plugin '%s' not found", name)
+	}
+
+	wj, ok := loaded.Plugin.(*WasmJudge)
+	if !ok {
+		return fmt.Errorf("plugin '%s' is not a wasm plugin and cannot be reloaded", name)
+	}
+
+	entryPath := filepath.Join(loaded.Path, loaded.Manifest.Spec.EntryPoint)
+	info, err := os.Stat(entryPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat plugin entry point: %w", err)
+	}
+	wasm, err := os.ReadFile(entryPath)
+	if err != nil {
+		return fmt.Errorf("failed to read wasm plugin: %w", err)
+	}
+	if err := wj.Reload(ctx, wasm); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	loaded.ModTime = info.ModTime()
+	l.mu.Unlock()
+
+	l.logger.Info(ctx, "Reloaded plugin", map[string]any{
+		"name": name,
+	})
+	return nil
+}
+
+// ChangedPlugins returns the names of Wasm plugins whose entry point was
+// modified since it was loaded.
+func (l *Loader) ChangedPlugins() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var changed []string
+	for name, p := range l.loaded {
+		if p.Manifest.Spec.ResolvedRuntime() != PluginRuntimeWasm {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(p.Path, p.Manifest.Spec.EntryPoint))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(p.ModTime) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
 // UnloadPlugin unloads a plugin by name.
 func (l *Loader) UnloadPlugin(ctx context.Context, name string) error {
 	l.mu.Lock()
@@ -218,6 +310,22 @@ func (l *Loader) GetFuryPlugins() []FuryPlugin {
 	return result
 }
 
+// GetAuthenticatorPlugins returns all loaded authenticator plugins, ordered
+// by name so authentication order is stable.
+func (l *Loader) GetAuthenticatorPlugins() []AuthenticatorPlugin {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var result []AuthenticatorPlugin
+	for _, p := range l.loaded {
+		if ap, ok := p.Plugin.(AuthenticatorPlugin); ok {
+			result = append(result, ap)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result
+}
+
 // Close unloads all plugins.
 func (l *Loader) Close(ctx context.Context) {
 	l.mu.Lock()
@@ -6,6 +6,7 @@ package plugins
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 )
@@ -22,6 +23,7 @@ type LoadedPlugin struct {
 	Manifest *Manifest
 	Plugin   Plugin
 	Path     string
+	ModTime  time.Time
 }
 
 // NewLoader creates a new plugin loader (stub).
@@ -48,6 +50,16 @@ func (l *Loader) UnloadPlugin(ctx context.Context, name string) error {
 	return fmt.Errorf("plugin unloading not supported on this platform")
 }
 
+// ReloadPlugin returns an error on non-Linux platforms.
+func (l *Loader) ReloadPlugin(ctx context.Context, name string) error {
+	return fmt.Errorf("plugin reloading not supported on this platform")
+}
+
+// ChangedPlugins returns empty on non-Linux platforms.
+func (l *Loader) ChangedPlugins() []string {
+	return nil
+}
+
 // GetPlugin returns nil on non-Linux platforms.
 func (l *Loader) GetPlugin(name string) (*LoadedPlugin, bool) {
 	return nil, false
@@ -68,5 +80,10 @@ func (l *Loader) GetFuryPlugins() []FuryPlugin {
 	return nil
 }
 
+// GetAuthenticatorPlugins returns empty on non-Linux platforms.
+func (l *Loader) GetAuthenticatorPlugins() []AuthenticatorPlugin {
+	return nil
+}
+
 // Close is a no-op on non-Linux platforms.
 func (l *Loader) Close(ctx context.Context) {}
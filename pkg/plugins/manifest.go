@@ -3,6 +3,8 @@ package plugins
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -35,9 +37,15 @@ type ManifestSpec struct {
 	// Type is "judge" or "fury".
 	Type PluginType `yaml:"type"`
 
-	// EntryPoint is the .so file name (Linux) or path.
+	// EntryPoint is the .so file name (Linux), .wasm module, or path.
 	EntryPoint string `yaml:"entryPoint"`
 
+	// Runtime is "native" or "wasm". Empty infers it from the entry point.
+	Runtime PluginRuntime `yaml:"runtime"`
+
+	// Limits bound the resources a Wasm plugin may use per call.
+	Limits WasmLimits `yaml:"limits"`
+
 	// Config is plugin-specific configuration passed to Init().
 	Config map[string]any `yaml:"config"`
 
@@ -45,6 +53,35 @@ type ManifestSpec struct {
 	Dependencies []string `yaml:"dependencies"`
 }
 
+// PluginRuntime identifies how a plugin entry point is executed.
+type PluginRuntime string
+
+const (
+	PluginRuntimeNative PluginRuntime = "native"
+	PluginRuntimeWasm   PluginRuntime = "wasm"
+)
+
+// WasmLimits bound the execution of a Wasm plugin. Zero values use defaults.
+type WasmLimits struct {
+	// MaxMemoryMB caps the linear memory of a plugin instance.
+	MaxMemoryMB uint32 `yaml:"maxMemoryMB"`
+
+	// Timeout caps the wall-clock time of a single judge call.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// ResolvedRuntime returns the declared runtime, inferring wasm from a .wasm
+// entry point when none is declared.
+func (s *ManifestSpec) ResolvedRuntime() PluginRuntime {
+	if s.Runtime != "" {
+		return s.Runtime
+	}
+	if filepath.Ext(s.EntryPoint) == ".wasm" {
+		return PluginRuntimeWasm
+	}
+	return PluginRuntimeNative
+}
+
 // LoadManifest reads a manifest.yaml file and returns the parsed Manifest.
 func LoadManifest(path string) (*Manifest, error) {
 	data, err := os.ReadFile(path)
@@ -78,11 +115,22 @@ func (m *Manifest) Validate() error {
 	if m.Metadata.Version == "" {
 		return fmt.Errorf("manifest missing metadata.version")
 	}
-	if m.Spec.Type != PluginTypeJudge && m.Spec.Type != PluginTypeFury {
-		return fmt.Errorf("manifest spec.type must be 'judge' or 'fury', got '%s'", m.Spec.Type)
+	switch m.Spec.Type {
+	case PluginTypeJudge, PluginTypeFury, PluginTypeAuthenticator:
+	default:
+		return fmt.Errorf("manifest spec.type must be 'judge', 'fury' or 'authenticator', got '%s'", m.Spec.Type)
 	}
 	if m.Spec.EntryPoint == "" {
 		return fmt.Errorf("manifest missing spec.entryPoint")
 	}
+	switch m.Spec.ResolvedRuntime() {
+	case PluginRuntimeNative:
+	case PluginRuntimeWasm:
+		if m.Spec.Type != PluginTypeJudge {
+			return fmt.Errorf("wasm runtime is only supported for judge plugins")
+		}
+	default:
+		return fmt.Errorf("manifest spec.runtime must be 'native' or 'wasm', got '%s'", m.Spec.Runtime)
+	}
 	return nil
 }
@@ -112,6 +112,37 @@ spec:
 			wantErr:   true,
 			errSubstr: "spec.entryPoint",
 		},
+		{
+			name: "ValidWasmJudgeManifest",
+			content: `apiVersion: v1
+kind: TartarusPlugin
+metadata:
+  name: wasm-judge
+  version: 1.0.0
+spec:
+  type: judge
+  entryPoint: judge.wasm
+  limits:
+    maxMemoryMB: 16
+    timeout: 50ms
+`,
+			wantErr: false,
+		},
+		{
+			name: "WasmFuryUnsupported",
+			content: `apiVersion: v1
+kind: TartarusPlugin
+metadata:
+  name: wasm-fury
+  version: 1.0.0
+spec:
+  type: fury
+  runtime: wasm
+  entryPoint: fury.wasm
+`,
+			wantErr:   true,
+			errSubstr: "wasm runtime",
+		},
 	}
 
 	for _, tt := range tests {
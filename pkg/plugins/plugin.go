@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"context"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
@@ -10,8 +11,9 @@ import (
 type PluginType string
 
 const (
-	PluginTypeJudge PluginType = "judge"
-	PluginTypeFury  PluginType = "fury"
+	PluginTypeJudge         PluginType = "judge"
+	PluginTypeFury          PluginType = "fury"
+	PluginTypeAuthenticator PluginType = "authenticator"
 )
 
 // Verdict mirrors judges.Verdict for plugin isolation.
@@ -38,7 +40,7 @@ type Plugin interface {
 	// Version returns the semantic version string.
 	Version() string
 
-	// Type returns the plugin category (judge or fury).
+	// Type returns the plugin category (judge, fury or authenticator).
 	Type() PluginType
 
 	// Init is called once when the plugin is loaded.
@@ -82,6 +84,44 @@ type FuryPlugin interface {
 	Disarm(ctx context.Context, runID domain.SandboxID) error
 }
 
+// Credential mirrors cerberus credentials for plugin isolation. Type is
+// "api_key", "oauth2" or "mtls"; Token holds the API key or bearer token,
+// Certificates the DER-encoded client certificate chain.
+type Credential struct {
+	Type         string
+	KeyID        string
+	Token        string
+	Certificates [][]byte
+}
+
+// Identity mirrors cerberus.Identity for plugin isolation. Type is "user",
+// "service", "agent" or "system"; an empty Type is treated as "user".
+type Identity struct {
+	ID          string
+	Type        string
+	TenantID    string
+	DisplayName string
+	Roles       []string
+	Groups      []string
+	Attributes  map[string]string
+	ExpiresAt   time.Time
+}
+
+// AuthenticatorPlugin extends Plugin for custom authentication, such as
+// proprietary SSO or Kerberos.
+type AuthenticatorPlugin interface {
+	Plugin
+
+	// Authenticate verifies a credential and returns the identity it
+	// belongs to. Return an error for credentials the plugin rejects or
+	// does not handle; the next authenticator is then tried.
+	Authenticate(ctx context.Context, cred *Credential) (*Identity, error)
+
+	// Health reports whether the plugin can currently authenticate, e.g.
+	// whether its identity provider is reachable.
+	Health(ctx context.Context) error
+}
+
 // PluginSymbol is the symbol name that plugins must export.
 // The exported variable must implement Plugin interface.
 const PluginSymbol = "TartarusPlugin"
@@ -3,7 +3,10 @@ package plugins
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 	"github.com/tartarus-sandbox/tartarus/pkg/erinyes"
 	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 	"github.com/tartarus-sandbox/tartarus/pkg/judges"
@@ -14,9 +17,10 @@ type Registry struct {
 	loader *Loader
 	logger hermes.Logger
 
-	mu            sync.RWMutex
-	judgeChain    *judges.Chain
-	compositeFury *CompositeFury
+	mu             sync.RWMutex
+	judgeChain     *judges.Chain
+	compositeFury  *CompositeFury
+	authenticators []cerberus.Authenticator
 }
 
 // NewRegistry creates a new plugin registry.
@@ -53,6 +57,8 @@ func (r *Registry) rebuildIntegrations() {
 	furyPlugins := r.loader.GetFuryPlugins()
 	wrappedFuries := WrapFuryPlugins(furyPlugins)
 	r.compositeFury = NewCompositeFury(wrappedFuries...)
+
+	r.authenticators = WrapAuthenticatorPlugins(r.loader.GetAuthenticatorPlugins())
 }
 
 // LoadPlugin loads a plugin and updates integrations.
@@ -73,6 +79,85 @@ func (r *Registry) UnloadPlugin(ctx context.Context, name string) error {
 	return nil
 }
 
+// ReloadPlugin hot-swaps a Wasm plugin module from disk.
+func (r *Registry) ReloadPlugin(ctx context.Context, name string) error {
+	return r.loader.ReloadPlugin(ctx, name)
+}
+
+// Watch polls Wasm plugin entry points and hot-swaps modules that changed on
+// disk until ctx is cancelled.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, name := range r.loader.ChangedPlugins() {
+				if err := r.loader.ReloadPlugin(ctx, name); err != nil {
+					r.logger.Error(ctx, "Failed to reload plugin", map[string]any{
+						"name":  name,
+						"error": err.Error(),
+					})
+				}
+			}
+		}
+	}
+}
+
+// PreJudge returns a judge that always delegates to the current plugin
+// chain, so callers holding it observe loads, unloads and reloads.
+func (r *Registry) PreJudge() judges.PreJudge {
+	return registryJudge{r}
+}
+
+type registryJudge struct {
+	r *Registry
+}
+
+func (j registryJudge) PreAdmit(ctx context.Context, req *domain.SandboxRequest) (judges.Verdict, error) {
+	return j.r.GetJudgeChain().RunPre(ctx, req)
+}
+
+// Authenticator returns an authenticator that tries the currently loaded
+// authenticator plugins in name order, counting each plugin's successes and
+// failures in metrics.
+func (r *Registry) Authenticator(metrics hermes.Metrics) *RegistryAuthenticator {
+	return &RegistryAuthenticator{r: r, metrics: metrics}
+}
+
+// RegistryAuthenticator delegates to the registry's authenticator plugins,
+// so callers holding it observe loads and unloads.
+type RegistryAuthenticator struct {
+	r       *Registry
+	metrics hermes.Metrics
+}
+
+func (a *RegistryAuthenticator) current() *cerberus.MultiAuthenticator {
+	a.r.mu.RLock()
+	defer a.r.mu.RUnlock()
+	return cerberus.NewMultiAuthenticator(a.r.authenticators...).WithMetrics(a.metrics)
+}
+
+// Authenticate implements cerberus.Authenticator.
+func (a *RegistryAuthenticator) Authenticate(ctx context.Context, creds cerberus.Credentials) (*cerberus.Identity, error) {
+	return a.current().Authenticate(ctx, creds)
+}
+
+// Health checks every loaded authenticator plugin.
+func (a *RegistryAuthenticator) Health(ctx context.Context) []cerberus.AuthenticatorHealth {
+	return a.current().Health(ctx)
+}
+
+// HasAuthenticators reports whether any authenticator plugin is loaded.
+func (r *Registry) HasAuthenticators() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.authenticators) > 0
+}
+
 // GetJudgeChain returns the current judge chain including plugin judges.
 func (r *Registry) GetJudgeChain() *judges.Chain {
 	r.mu.RLock()
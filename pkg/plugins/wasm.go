@@ -0,0 +1,298 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Wasm judge ABI.
+//
+// A Wasm judge is a reactor module that exports its linear memory as "memory"
+// and a "pre_admit" function returning a Verdict as i32. It may also export
+// "post_hoc" with the same signature. Each call runs in a fresh instance, so
+// plugins cannot carry state between calls.
+//
+// The host module "tartarus" exposes the call input as JSON (the
+// SandboxRequest for pre_admit, the SandboxRun for post_hoc):
+//
+//	input_len() -> i32                      length of the input in bytes
+//	input_read(ptr i32)                     copy the input into guest memory
+//	config_len() -> i32                     length of the plugin config JSON
+//	config_read(ptr i32)                    copy the config into guest memory
+//	set_reason(ptr, len i32)                set the post_hoc classification reason
+//	set_label(kptr, klen, vptr, vlen i32)   add a post_hoc classification label
+//	log(ptr, len i32)                       write a message to the Olympus log
+const (
+	wasmHostModule   = "tartarus"
+	wasmExportPre    = "pre_admit"
+	wasmExportPost   = "post_hoc"
+	wasmExportMemory = "memory"
+
+	defaultWasmMaxMemoryMB = 32
+	defaultWasmTimeout     = 250 * time.Millisecond
+
+	wasmPageSize = 64 * 1024
+)
+
+var ErrWasmTimeout = errors.New("wasm plugin exceeded its time limit")
+
+// WasmJudge runs a judge compiled to WebAssembly in-process via wazero.
+// The module can be swapped at runtime with Reload; calls already in flight
+// finish on the previous module.
+type WasmJudge struct {
+	name    string
+	version string
+	limits  WasmLimits
+	logger  hermes.Logger
+	runtime wazero.Runtime
+
+	config []byte
+
+	mu       sync.RWMutex
+	compiled wazero.CompiledModule
+	hasPost  bool
+}
+
+// wasmCall carries per-invocation state to the host functions.
+type wasmCall struct {
+	input  []byte
+	config []byte
+	reason string
+	labels map[string]string
+}
+
+type wasmCallKey struct{}
+
+// NewWasmJudge compiles the module and prepares the host ABI.
+func NewWasmJudge(ctx context.Context, name, version string, wasm []byte, limits WasmLimits, logger hermes.Logger) (*WasmJudge, error) {
+	if limits.MaxMemoryMB == 0 {
+		limits.MaxMemoryMB = defaultWasmMaxMemoryMB
+	}
+	if limits.Timeout == 0 {
+		limits.Timeout = defaultWasmTimeout
+	}
+
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(limits.MaxMemoryMB*1024*1024/wasmPageSize).
+		WithCloseOnContextDone(true))
+
+	j := &WasmJudge{
+		name:    name,
+		version: version,
+		limits:  limits,
+		logger:  logger,
+		runtime: rt,
+		config:  []byte("{}"),
+	}
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	if err := j.instantiateHost(ctx); err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate host module: %w", err)
+	}
+	if err := j.Reload(ctx, wasm); err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *WasmJudge) Name() string     { return j.name }
+func (j *WasmJudge) Version() string  { return j.version }
+func (j *WasmJudge) Type() PluginType { return PluginTypeJudge }
+
+// Init records the plugin configuration so the module can read it.
+func (j *WasmJudge) Init(config map[string]any) error {
+	if config == nil {
+		return nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin config: %w", err)
+	}
+	j.config = data
+	return nil
+}
+
+// Close releases the compiled module and the wazero runtime.
+func (j *WasmJudge) Close() error {
+	return j.runtime.Close(context.Background())
+}
+
+// Reload compiles a new module and swaps it in. The previous module keeps
+// serving calls that already started.
+func (j *WasmJudge) Reload(ctx context.Context, wasm []byte) error {
+	compiled, err := j.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return fmt.Errorf("failed to compile wasm plugin: %w", err)
+	}
+
+	exports := compiled.ExportedFunctions()
+	if _, ok := exports[wasmExportPre]; !ok {
+		compiled.Close(ctx)
+		return fmt.Errorf("wasm plugin does not export %q", wasmExportPre)
+	}
+	if _, ok := compiled.ExportedMemories()[wasmExportMemory]; !ok {
+		compiled.Close(ctx)
+		return fmt.Errorf("wasm plugin does not export %q", wasmExportMemory)
+	}
+	_, hasPost := exports[wasmExportPost]
+
+	j.mu.Lock()
+	old := j.compiled
+	j.compiled = compiled
+	j.hasPost = hasPost
+	j.mu.Unlock()
+
+	if old != nil {
+		old.Close(ctx)
+	}
+	return nil
+}
+
+// PreAdmit evaluates the request in a fresh module instance.
+func (j *WasmJudge) PreAdmit(ctx context.Context, req *domain.SandboxRequest) (Verdict, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return VerdictReject, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	call := &wasmCall{input: input}
+	verdict, err := j.invoke(ctx, wasmExportPre, call)
+	if err != nil {
+		return VerdictReject, err
+	}
+	return verdict, nil
+}
+
+// PostHoc classifies a finished run. Modules without post_hoc return nil.
+func (j *WasmJudge) PostHoc(ctx context.Context, run *domain.SandboxRun) (*Classification, error) {
+	j.mu.RLock()
+	hasPost := j.hasPost
+	j.mu.RUnlock()
+	if !hasPost {
+		return nil, nil
+	}
+
+	input, err := json.Marshal(run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	call := &wasmCall{input: input, labels: map[string]string{}}
+	verdict, err := j.invoke(ctx, wasmExportPost, call)
+	if err != nil {
+		return nil, err
+	}
+	return &Classification{
+		Verdict: verdict,
+		Reason:  call.reason,
+		Labels:  call.labels,
+	}, nil
+}
+
+// invoke instantiates the current module, calls the named export and returns
+// its result as a Verdict.
+func (j *WasmJudge) invoke(ctx context.Context, export string, call *wasmCall) (Verdict, error) {
+	j.mu.RLock()
+	compiled := j.compiled
+	j.mu.RUnlock()
+
+	call.config = j.config
+
+	ctx, cancel := context.WithTimeout(ctx, j.limits.Timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, wasmCallKey{}, call)
+
+	// Anonymous instances may coexist, so concurrent calls do not collide.
+	mod, err := j.runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return VerdictReject, j.callError(ctx, "instantiate", err)
+	}
+	defer mod.Close(context.Background())
+
+	results, err := mod.ExportedFunction(export).Call(ctx)
+	if err != nil {
+		return VerdictReject, j.callError(ctx, export, err)
+	}
+	if len(results) != 1 {
+		return VerdictReject, fmt.Errorf("wasm plugin %s: %s must return a single i32", j.name, export)
+	}
+
+	verdict := Verdict(api.DecodeI32(results[0]))
+	switch verdict {
+	case VerdictAccept, VerdictReject, VerdictQuarantine:
+		return verdict, nil
+	default:
+		return VerdictReject, fmt.Errorf("wasm plugin %s: %s returned unknown verdict %d", j.name, export, verdict)
+	}
+}
+
+func (j *WasmJudge) callError(ctx context.Context, op string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("wasm plugin %s: %s: %w", j.name, op, ErrWasmTimeout)
+	}
+	return fmt.Errorf("wasm plugin %s: %s: %w", j.name, op, err)
+}
+
+// instantiateHost registers the "tartarus" host module implementing the ABI.
+func (j *WasmJudge) instantiateHost(ctx context.Context) error {
+	_, err := j.runtime.NewHostModuleBuilder(wasmHostModule).
+		NewFunctionBuilder().WithFunc(func(ctx context.Context) uint32 {
+		return uint32(len(callFrom(ctx).input))
+	}).Export("input_len").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, ptr uint32) {
+		m.Memory().Write(ptr, callFrom(ctx).input)
+	}).Export("input_read").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context) uint32 {
+		return uint32(len(callFrom(ctx).config))
+	}).Export("config_len").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, ptr uint32) {
+		m.Memory().Write(ptr, callFrom(ctx).config)
+	}).Export("config_read").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, ptr, size uint32) {
+		if b, ok := m.Memory().Read(ptr, size); ok {
+			callFrom(ctx).reason = string(b)
+		}
+	}).Export("set_reason").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, kptr, klen, vptr, vlen uint32) {
+		k, kok := m.Memory().Read(kptr, klen)
+		v, vok := m.Memory().Read(vptr, vlen)
+		call := callFrom(ctx)
+		if kok && vok && call.labels != nil {
+			call.labels[string(k)] = string(v)
+		}
+	}).Export("set_label").
+		NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, ptr, size uint32) {
+		if b, ok := m.Memory().Read(ptr, size); ok && j.logger != nil {
+			j.logger.Info(ctx, "Wasm plugin log", map[string]any{
+				"plugin":  j.name,
+				"message": string(b),
+			})
+		}
+	}).Export("log").
+		Instantiate(ctx)
+	return err
+}
+
+func callFrom(ctx context.Context) *wasmCall {
+	if call, ok := ctx.Value(wasmCallKey{}).(*wasmCall); ok {
+		return call
+	}
+	return &wasmCall{}
+}
@@ -0,0 +1,212 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+)
+
+// Function bodies for buildJudgeModule. Imported functions are
+// 0 = tartarus.input_len and 1 = tartarus.set_label.
+var (
+	wasmReturnAccept = []byte{0x41, 0x00}
+	wasmReturnReject = []byte{0x41, 0x01}
+	// input_len() > 1000
+	wasmRejectLargeInput = []byte{0x10, 0x00, 0x41, 0xe8, 0x07, 0x4b}
+	// set_label("k", "v"); return quarantine
+	wasmLabelQuarantine = []byte{0x41, 0x00, 0x41, 0x01, 0x41, 0x01, 0x41, 0x01, 0x10, 0x01, 0x41, 0x02}
+	// loop { br 0 }
+	wasmSpin = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x41, 0x00}
+)
+
+// buildJudgeModule assembles a minimal judge module exporting memory,
+// pre_admit and post_hoc. The bytes "kv" are placed at offset 0.
+func buildJudgeModule(preAdmit, postHoc []byte) []byte {
+	section := func(id byte, payload ...byte) []byte {
+		return append([]byte{id, byte(len(payload))}, payload...)
+	}
+	name := func(s string) []byte {
+		return append([]byte{byte(len(s))}, s...)
+	}
+	body := func(code []byte) []byte {
+		b := append([]byte{0x00}, code...)
+		b = append(b, 0x0b)
+		return append([]byte{byte(len(b))}, b...)
+	}
+
+	var imports []byte
+	imports = append(imports, 0x02)
+	imports = append(imports, name("tartarus")...)
+	imports = append(imports, name("input_len")...)
+	imports = append(imports, 0x00, 0x00)
+	imports = append(imports, name("tartarus")...)
+	imports = append(imports, name("set_label")...)
+	imports = append(imports, 0x00, 0x01)
+
+	var exports []byte
+	exports = append(exports, 0x03)
+	exports = append(exports, name("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	exports = append(exports, name("pre_admit")...)
+	exports = append(exports, 0x00, 0x02)
+	exports = append(exports, name("post_hoc")...)
+	exports = append(exports, 0x00, 0x03)
+
+	code := []byte{0x02}
+	code = append(code, body(preAdmit)...)
+	code = append(code, body(postHoc)...)
+
+	out := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// types: 0 = () -> i32, 1 = (i32, i32, i32, i32) -> ()
+	out = append(out, section(0x01, 0x02, 0x60, 0x00, 0x01, 0x7f, 0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x00)...)
+	out = append(out, section(0x02, imports...)...)
+	out = append(out, section(0x03, 0x02, 0x00, 0x00)...)
+	out = append(out, section(0x05, 0x01, 0x00, 0x01)...)
+	out = append(out, section(0x07, exports...)...)
+	out = append(out, section(0x0a, code...)...)
+	out = append(out, section(0x0b, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x02, 'k', 'v')...)
+	return out
+}
+
+func TestWasmJudge_PreAdmitReadsRequest(t *testing.T) {
+	ctx := context.Background()
+	j, err := NewWasmJudge(ctx, "size-judge", "1.0.0", buildJudgeModule(wasmRejectLargeInput, wasmReturnAccept), WasmLimits{}, hermes.NewNoopLogger())
+	if err != nil {
+		t.Fatalf("NewWasmJudge: %v", err)
+	}
+	defer j.Close()
+
+	v, err := j.PreAdmit(ctx, &domain.SandboxRequest{ID: "small"})
+	if err != nil {
+		t.Fatalf("PreAdmit: %v", err)
+	}
+	if v != VerdictAccept {
+		t.Errorf("expected accept for small request, got %v", v)
+	}
+
+	v, err = j.PreAdmit(ctx, &domain.SandboxRequest{ID: "large", Env: map[string]string{"BLOB": strings.Repeat("x", 2000)}})
+	if err != nil {
+		t.Fatalf("PreAdmit: %v", err)
+	}
+	if v != VerdictReject {
+		t.Errorf("expected reject for large request, got %v", v)
+	}
+}
+
+func TestWasmJudge_PostHocLabels(t *testing.T) {
+	ctx := context.Background()
+	j, err := NewWasmJudge(ctx, "label-judge", "1.0.0", buildJudgeModule(wasmReturnAccept, wasmLabelQuarantine), WasmLimits{}, nil)
+	if err != nil {
+		t.Fatalf("NewWasmJudge: %v", err)
+	}
+	defer j.Close()
+
+	cl, err := j.PostHoc(ctx, &domain.SandboxRun{ID: "run-1"})
+	if err != nil {
+		t.Fatalf("PostHoc: %v", err)
+	}
+	if cl.Verdict != VerdictQuarantine {
+		t.Errorf("expected quarantine, got %v", cl.Verdict)
+	}
+	if cl.Labels["k"] != "v" {
+		t.Errorf("expected label k=v, got %v", cl.Labels)
+	}
+}
+
+func TestWasmJudge_Timeout(t *testing.T) {
+	ctx := context.Background()
+	j, err := NewWasmJudge(ctx, "spin-judge", "1.0.0", buildJudgeModule(wasmSpin, wasmReturnAccept), WasmLimits{Timeout: 50 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatalf("NewWasmJudge: %v", err)
+	}
+	defer j.Close()
+
+	v, err := j.PreAdmit(ctx, &domain.SandboxRequest{ID: "req"})
+	if !errors.Is(err, ErrWasmTimeout) {
+		t.Fatalf("expected ErrWasmTimeout, got %v", err)
+	}
+	if v != VerdictReject {
+		t.Errorf("expected reject on timeout, got %v", v)
+	}
+}
+
+func TestWasmJudge_RejectsModuleWithoutPreAdmit(t *testing.T) {
+	// Valid module with no exports at all
+	empty := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	if _, err := NewWasmJudge(context.Background(), "empty", "1.0.0", empty, WasmLimits{}, nil); err == nil {
+		t.Fatal("expected error for module without pre_admit")
+	}
+}
+
+func TestLoader_WasmHotSwap(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "toggle-judge")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := `apiVersion: v1
+kind: TartarusPlugin
+metadata:
+  name: toggle-judge
+  version: 1.0.0
+spec:
+  type: judge
+  entryPoint: judge.wasm
+  limits:
+    maxMemoryMB: 1
+    timeout: 100ms
+`
+	if err := os.WriteFile(filepath.Join(pluginDir, "manifest.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wasmPath := filepath.Join(pluginDir, "judge.wasm")
+	if err := os.WriteFile(wasmPath, buildJudgeModule(wasmReturnAccept, wasmReturnAccept), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewRegistry(hermes.NewNoopLogger(), dir)
+	if err := registry.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	defer registry.Close(ctx)
+
+	judge := registry.PreJudge()
+	req := &domain.SandboxRequest{ID: "req"}
+
+	if v, err := judge.PreAdmit(ctx, req); err != nil || v != 0 {
+		t.Fatalf("expected accept before swap, got %v, %v", v, err)
+	}
+
+	// Replace the module on disk and bump its mtime
+	if err := os.WriteFile(wasmPath, buildJudgeModule(wasmReturnReject, wasmReturnAccept), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(wasmPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := registry.loader.ChangedPlugins()
+	if len(changed) != 1 || changed[0] != "toggle-judge" {
+		t.Fatalf("expected toggle-judge to be reported changed, got %v", changed)
+	}
+	if err := registry.ReloadPlugin(ctx, "toggle-judge"); err != nil {
+		t.Fatalf("ReloadPlugin: %v", err)
+	}
+
+	if v, err := judge.PreAdmit(ctx, req); err != nil || v != 1 {
+		t.Fatalf("expected reject after swap, got %v, %v", v, err)
+	}
+	if changed := registry.loader.ChangedPlugins(); len(changed) != 0 {
+		t.Errorf("expected no pending changes after reload, got %v", changed)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package tartarus
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func startInCgroup(cmd *exec.Cmd, dir string) (*os.File, error) {
+	return nil, fmt.Errorf("cgroups are only supported on Linux")
+}
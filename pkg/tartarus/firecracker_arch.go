@@ -0,0 +1,51 @@
+package tartarus
+
+import "github.com/tartarus-sandbox/tartarus/pkg/domain"
+
+// Kernel command lines per guest architecture. x86-only mitigations (pti,
+// mds, l1tf, tsx, vsyscall) are unknown to aarch64 kernels, which would hand
+// them to init as arguments, so arm64 uses its own equivalents.
+const (
+	// Standard kernel args (lighter, for performance)
+	standardKernelArgsAMD64 = "console=ttyS0 reboot=k panic=1 pci=off " +
+		"randomize_kstack_offset=on audit=1 " +
+		"init_on_alloc=0 init_on_free=0 " + // Performance optimization
+		"oops=panic"
+
+	// Hardened kernel args (comprehensive security)
+	hardenedKernelArgsAMD64 = "console=ttyS0 reboot=k panic=1 pci=off " +
+		"randomize_kstack_offset=on nosmt mitigations=auto audit=1 " +
+		"slub_debug=P page_poison=1 pti=on slab_nomerge " +
+		"init_on_alloc=1 init_on_free=1 " +
+		"mds=full,nosmt l1tf=full,force spec_store_bypass_disable=on " +
+		"tsx=off vsyscall=none debugfs=off oops=panic " +
+		"lockdown=confidentiality module.sig_enforce=1"
+
+	// Firecracker exposes an 8250 UART on aarch64; keep_bootcon keeps the
+	// early console so boot failures reach the console log.
+	standardKernelArgsARM64 = "keep_bootcon console=ttyS0 reboot=k panic=1 pci=off " +
+		"randomize_kstack_offset=on audit=1 " +
+		"init_on_alloc=0 init_on_free=0 " +
+		"oops=panic"
+
+	hardenedKernelArgsARM64 = "keep_bootcon console=ttyS0 reboot=k panic=1 pci=off " +
+		"randomize_kstack_offset=on mitigations=auto audit=1 " +
+		"slub_debug=P page_poison=1 kpti=1 slab_nomerge " +
+		"init_on_alloc=1 init_on_free=1 " +
+		"ssbd=force-on debugfs=off oops=panic " +
+		"lockdown=confidentiality module.sig_enforce=1"
+)
+
+// baseKernelArgs returns the kernel command line for the guest architecture.
+func baseKernelArgs(arch string, hardened bool) string {
+	if arch == domain.ArchARM64 {
+		if hardened {
+			return hardenedKernelArgsARM64
+		}
+		return standardKernelArgsARM64
+	}
+	if hardened {
+		return hardenedKernelArgsAMD64
+	}
+	return standardKernelArgsAMD64
+}
@@ -0,0 +1,34 @@
+package tartarus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func TestBaseKernelArgs_PerArch(t *testing.T) {
+	x86Only := []string{"pti=", "mds=", "l1tf=", "tsx=", "vsyscall="}
+
+	for _, hardened := range []bool{false, true} {
+		args := " " + baseKernelArgs(domain.ArchARM64, hardened)
+		for _, flag := range x86Only {
+			if strings.Contains(args, " "+flag) {
+				t.Errorf("arm64 args (hardened=%v) contain x86-only flag %q", hardened, flag)
+			}
+		}
+		if !strings.Contains(args, "console=ttyS0") {
+			t.Errorf("arm64 args (hardened=%v) missing serial console", hardened)
+		}
+	}
+
+	if !strings.Contains(baseKernelArgs(domain.ArchARM64, true), "kpti=1") {
+		t.Error("hardened arm64 args should enable kpti")
+	}
+	if !strings.Contains(baseKernelArgs(domain.ArchAMD64, true), "pti=on") {
+		t.Error("hardened amd64 args should enable pti")
+	}
+	if baseKernelArgs("", false) != standardKernelArgsAMD64 {
+		t.Error("unknown arch should fall back to amd64 args")
+	}
+}
@@ -0,0 +1,173 @@
+//go:build linux
+// +build linux
+
+package tartarus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// vmJail is the chroot and identity a jailed VM runs with.
+type vmJail struct {
+	Config       *JailerConfig
+	ID           string
+	Root         string // The chroot, on the host
+	UID          int
+	GID          int
+	ParentCgroup string
+}
+
+// prepareJail allocates the VM's UID/GID and creates its chroot, so the
+// files it needs can be linked in before the jailer starts.
+func (r *FirecrackerRuntime) prepareJail(id domain.SandboxID, cfg *JailerConfig) (*vmJail, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	uid, gid, err := r.jailIDs.acquire(cfg)
+	if err != nil {
+		return nil, err
+	}
+	jail := &vmJail{
+		Config:       cfg,
+		ID:           string(id),
+		Root:         filepath.Join(cfg.jailDir(string(id)), "root"),
+		UID:          uid,
+		GID:          gid,
+		ParentCgroup: cfg.ParentCgroup,
+	}
+	if jail.ParentCgroup == "" && r.Cgroup != "" {
+		if rel, err := filepath.Rel(CgroupFSRoot, r.Cgroup); err == nil && !strings.HasPrefix(rel, "..") {
+			jail.ParentCgroup = rel
+		}
+	}
+
+	// A chroot left by a VM of the same ID would leak its files into this one
+	if err := os.RemoveAll(cfg.jailDir(jail.ID)); err != nil {
+		r.jailIDs.releaseFor(cfg, uid)
+		return nil, fmt.Errorf("failed to clear jail of %s: %w", id, err)
+	}
+	if err := os.MkdirAll(filepath.Join(jail.Root, filepath.Dir(jailerSocket)), 0755); err != nil {
+		r.jailIDs.releaseFor(cfg, uid)
+		return nil, fmt.Errorf("failed to create jail of %s: %w", id, err)
+	}
+	if err := os.Chown(filepath.Join(jail.Root, filepath.Dir(jailerSocket)), uid, gid); err != nil {
+		jail.cleanup(r)
+		return nil, fmt.Errorf("failed to create jail of %s: %w", id, err)
+	}
+	return jail, nil
+}
+
+// link hard-links hostPath into the chroot and returns its path there.
+// Files the VM writes are handed to its UID; the others must be readable
+// by it already.
+func (j *vmJail) link(hostPath string, writable bool) (string, error) {
+	name := "/" + filepath.Base(hostPath)
+	dst := filepath.Join(j.Root, name)
+	if err := os.Link(hostPath, dst); err != nil {
+		return "", fmt.Errorf("failed to link %s into the jail (is %s on the same filesystem?): %w", hostPath, j.Config.chrootBase(), err)
+	}
+	if writable {
+		if err := os.Chown(dst, j.UID, j.GID); err != nil {
+			return "", fmt.Errorf("failed to hand %s to the jailed VM: %w", hostPath, err)
+		}
+	}
+	return name, nil
+}
+
+// apply rewrites the VM's configuration for the chroot: host paths are
+// linked in and replaced by their paths inside it. The VMM logs to the
+// console, since a log file outside the chroot cannot be opened.
+func (j *vmJail) apply(fcCfg *firecracker.Config, writable map[string]bool) error {
+	var err error
+	if fcCfg.KernelImagePath != "" {
+		if fcCfg.KernelImagePath, err = j.link(fcCfg.KernelImagePath, false); err != nil {
+			return err
+		}
+	}
+	for i, drive := range fcCfg.Drives {
+		hostPath := firecracker.StringValue(drive.PathOnHost)
+		jailed, err := j.link(hostPath, writable[hostPath])
+		if err != nil {
+			return err
+		}
+		fcCfg.Drives[i].PathOnHost = firecracker.String(jailed)
+	}
+	if fcCfg.Snapshot.MemFilePath != "" {
+		if fcCfg.Snapshot.MemFilePath, err = j.link(fcCfg.Snapshot.MemFilePath, false); err != nil {
+			return err
+		}
+		if fcCfg.Snapshot.SnapshotPath, err = j.link(fcCfg.Snapshot.SnapshotPath, false); err != nil {
+			return err
+		}
+	}
+	// Firecracker creates the vsock's Unix socket, so it goes in the
+	// directory the VM owns
+	for i := range fcCfg.VsockDevices {
+		fcCfg.VsockDevices[i].Path = filepath.Join(filepath.Dir(jailerSocket), filepath.Base(fcCfg.VsockDevices[i].Path))
+	}
+	fcCfg.SocketPath = filepath.Join(j.Root, jailerSocket)
+	fcCfg.LogPath = ""
+	// The SDK would check the paths on the host
+	fcCfg.DisableValidation = true
+	return nil
+}
+
+// command builds the jailer command. The PID namespace is created for the
+// jailer itself, which execs firecracker, so firecracker stays the process
+// the runtime starts and waits on.
+func (j *vmJail) command(ctx context.Context, fcArgs []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, j.Config.binary(), jailerArgs(j.Config, j.ID, j.UID, j.GID, j.ParentCgroup, fcArgs)...)
+	if j.Config.NewPIDNS {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWPID}
+	}
+	return cmd
+}
+
+// exportFile moves a file the VM wrote in its chroot to hostPath.
+func (j *vmJail) exportFile(name, hostPath string) error {
+	src := filepath.Join(j.Root, name)
+	if err := os.Rename(src, hostPath); err == nil {
+		return nil
+	}
+	// Across filesystems
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(hostPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// cleanup removes the chroot and the VM's cgroup, and frees its UID/GID.
+// The linked originals are left alone.
+func (j *vmJail) cleanup(r *FirecrackerRuntime) {
+	if err := os.RemoveAll(j.Config.jailDir(j.ID)); err != nil {
+		r.Logger.Warn("Failed to remove jail", "id", j.ID, "error", err)
+	}
+	if j.ParentCgroup != "" {
+		// Only empty once the VMM has exited
+		_ = os.Remove(filepath.Join(CgroupFSRoot, j.ParentCgroup, j.ID))
+	}
+	r.jailIDs.releaseFor(j.Config, j.UID)
+}
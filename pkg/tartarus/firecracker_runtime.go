@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -31,9 +32,25 @@ type FirecrackerRuntime struct {
 	KernelImage string
 	RootFSBase  string
 
+	// Arch is the host (and guest) CPU architecture. Firecracker does not
+	// emulate, so the kernel image must be built for it: a vmlinux ELF on
+	// x86_64, an uncompressed Image on aarch64.
+	Arch string
+
+	// Cgroup is the cgroup v2 directory firecracker processes are started
+	// in. Empty leaves them in the agent's cgroup.
+	Cgroup string
+
 	// State tracking: SandboxID -> *vmState
 	vms sync.Map
 
+	// UIDs/GIDs of the running jailed VMs
+	jailIDs jailIDs
+
+	// GuestAgent, if set, gives each VM a vsock device and starts the guest
+	// agent in it, for Exec, readiness checks and guest metrics
+	GuestAgent *GuestAgentConfig
+
 	// Secrets
 	Secrets cerberus.SecretProvider
 }
@@ -44,10 +61,14 @@ type vmState struct {
 	SocketPath  string
 	LogPath     string
 	ConsolePath string
+	SwapPath    string  // Swap disk, if the guest has one
+	VsockPath   string  // Host side of the guest agent's vsock, if it has one
+	Jail        *vmJail // Set if the VMM runs under the jailer
 	StartedAt   time.Time
 	Request     *domain.SandboxRequest
 	Config      VMConfig
 	ExitCode    *int
+	ClaimedExit *int // Exit code of the command run in a claimed VM, once it exits
 	mu          sync.Mutex
 }
 
@@ -66,6 +87,7 @@ func NewFirecrackerRuntime(logger *slog.Logger, socketDir, kernelImage, rootFSBa
 		SocketDir:   socketDir,
 		KernelImage: kernelImage,
 		RootFSBase:  rootFSBase,
+		Arch:        runtime.GOARCH,
 		Secrets:     secrets,
 	}
 }
@@ -91,8 +113,10 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 		rootFSPath = cfg.OverlayFS
 	}
 
-	// Helper to convert MB to int64
-	memSz := int64(req.Resources.Mem)
+	// Helper to convert MB to int64. Memory the policy lets the guest swap
+	// out is taken from its swap disk or zram instead of RAM.
+	ram, _ := req.MemoryNeeds()
+	memSz := int64(ram)
 	if memSz == 0 {
 		memSz = 128 // Default to 128MB
 	}
@@ -103,25 +127,7 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 
 	// Construct Kernel Args
 	// We want: console=ttyS0 reboot=k panic=1 pci=off init=/bin/sh -- -c "export VAR=VAL; exec cmd args..."
-	// Standard kernel args (lighter, for performance)
-	standardKernelArgs := "console=ttyS0 reboot=k panic=1 pci=off " +
-		"randomize_kstack_offset=on audit=1 " +
-		"init_on_alloc=0 init_on_free=0 " + // Performance optimization
-		"oops=panic"
-
-	// Hardened kernel args (comprehensive security)
-	hardenedKernelArgs := "console=ttyS0 reboot=k panic=1 pci=off " +
-		"randomize_kstack_offset=on nosmt mitigations=auto audit=1 " +
-		"slub_debug=P page_poison=1 pti=on slab_nomerge " +
-		"init_on_alloc=1 init_on_free=1 " +
-		"mds=full,nosmt l1tf=full,force spec_store_bypass_disable=on " +
-		"tsx=off vsyscall=none debugfs=off oops=panic " +
-		"lockdown=confidentiality module.sig_enforce=1"
-
-	kernelArgs := standardKernelArgs
-	if req.Hardened {
-		kernelArgs = hardenedKernelArgs
-	}
+	kernelArgs := baseKernelArgs(r.Arch, req.Hardened)
 
 	if len(req.Command) > 0 {
 		// Build the shell script
@@ -145,6 +151,11 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 			}
 		}
 
+		// Start the guest agent alongside the command
+		if r.GuestAgent != nil {
+			scriptBuilder.WriteString(r.GuestAgent.startScript())
+		}
+
 		// 1. Export Environment Variables
 		// Resolve secrets
 		// Use injected provider or fallback to Env
@@ -174,7 +185,12 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 			scriptBuilder.WriteString(fmt.Sprintf("export %s='%s'; ", k, val))
 		}
 
-		// 2. Build the command
+		// 2. Enable swap and apply process limits, refusing to run the
+		// command without them
+		scriptBuilder.WriteString(guestSwapScript(req.Swap, req.Resources.Mem))
+		scriptBuilder.WriteString(guestLimitsScript(req.Limits))
+
+		// 3. Build the command
 		// We assume req.Command[0] is the binary, and req.Args are arguments.
 		// If req.Args is empty, we just use req.Command.
 		// Actually domain.SandboxRequest has Command []string and Args []string.
@@ -217,6 +233,21 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 		return nil, fmt.Errorf("failed to generate seccomp json: %w", err)
 	}
 
+	// The jailer runs the VMM chrooted, as a UID of its own
+	var jail *vmJail
+	if cfg.Jailer != nil {
+		jail, err = r.prepareJail(req.ID, cfg.Jailer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	started := false
+	defer func() {
+		if jail != nil && !started {
+			jail.cleanup(r)
+		}
+	}()
+
 	var seccompPath string
 	if seccompJSON != "" {
 		seccompPath = filepath.Join(r.SocketDir, fmt.Sprintf("seccomp-%s.json", req.ID))
@@ -245,6 +276,31 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 		},
 	}
 
+	// Attach a sparse swap disk, which becomes the guest's second drive
+	// (guestSwapDevice). A restored snapshot keeps the drives it was taken with.
+	var swapPath string
+	if size := req.Swap.Size(req.Resources.Mem); size > 0 && !req.Swap.Zram && cfg.Snapshot.Path == "" {
+		swapPath = filepath.Join(r.SocketDir, fmt.Sprintf("fc-%s.swap", req.ID))
+		if err := createSwapDisk(swapPath, size); err != nil {
+			return nil, err
+		}
+		fcCfg.Drives = append(fcCfg.Drives, models.Drive{
+			DriveID:      firecracker.String("swap"),
+			PathOnHost:   firecracker.String(swapPath),
+			IsRootDevice: firecracker.Bool(false),
+			IsReadOnly:   firecracker.Bool(false),
+		})
+	}
+
+	// The guest agent is reached through a vsock device. A restored
+	// snapshot keeps the devices it was taken with.
+	var vsockPath string
+	if r.GuestAgent != nil && cfg.Snapshot.Path == "" {
+		vsockPath = filepath.Join(r.SocketDir, fmt.Sprintf("fc-%s.vsock", req.ID))
+		os.Remove(vsockPath)
+		fcCfg.VsockDevices = []firecracker.VsockDevice{{ID: "agent", Path: vsockPath, CID: guestAgentCID}}
+	}
+
 	// Add Network Interface if TapDevice is provided
 	if cfg.TapDevice != "" {
 		fcCfg.NetworkInterfaces = []firecracker.NetworkInterface{
@@ -260,6 +316,7 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 	// Create console log file
 	consoleFile, err := os.Create(consolePath)
 	if err != nil {
+		removeSwapDisk(swapPath)
 		return nil, fmt.Errorf("failed to create console log: %w", err)
 	}
 	// We don't close consoleFile here; we pass it to the cmd.
@@ -272,18 +329,6 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 	// No, NewMachine just creates the struct. machine.Start() starts it.
 	// But we build the cmd first.
 
-	cmd := firecracker.VMCommandBuilder{}.
-		WithSocketPath(socketPath).
-		Build(ctx)
-
-	// Append seccomp arg if present
-	if seccompPath != "" {
-		cmd.Args = append(cmd.Args, "--seccomp-filter", seccompPath)
-	}
-
-	cmd.Stdout = consoleFile
-	cmd.Stderr = consoleFile
-
 	// Check if we are restoring from a snapshot
 	if cfg.Snapshot.Path != "" {
 		r.Logger.Info("Restoring from snapshot", "id", req.ID, "snapshot", cfg.Snapshot.Path)
@@ -304,19 +349,73 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 		// For now, we assume if snapshot is present, we just resume it.
 	}
 
+	var cmd *exec.Cmd
+	if jail != nil {
+		// Only the per-VM drives are the VM's to write
+		writable := map[string]bool{cfg.OverlayFS: true, swapPath: true}
+		if err := jail.apply(&fcCfg, writable); err != nil {
+			consoleFile.Close()
+			removeSwapDisk(swapPath)
+			return nil, err
+		}
+		socketPath = fcCfg.SocketPath
+		logPath = ""
+		if vsockPath != "" {
+			vsockPath = filepath.Join(jail.Root, fcCfg.VsockDevices[0].Path)
+		}
+		fcArgs := []string{"--api-sock", jailerSocket}
+		if seccompPath != "" {
+			jailedSeccomp, err := jail.link(seccompPath, false)
+			if err != nil {
+				consoleFile.Close()
+				removeSwapDisk(swapPath)
+				return nil, err
+			}
+			fcArgs = append(fcArgs, "--seccomp-filter", jailedSeccomp)
+		}
+		cmd = jail.command(ctx, fcArgs)
+	} else {
+		cmd = firecracker.VMCommandBuilder{}.
+			WithSocketPath(socketPath).
+			Build(ctx)
+
+		// Append seccomp arg if present
+		if seccompPath != "" {
+			cmd.Args = append(cmd.Args, "--seccomp-filter", seccompPath)
+		}
+	}
+
+	cmd.Stdout = consoleFile
+	cmd.Stderr = consoleFile
+
+	// The jailer places the VMM in a cgroup of its own
+	if r.Cgroup != "" && jail == nil {
+		cgroupFile, err := startInCgroup(cmd, r.Cgroup)
+		if err != nil {
+			consoleFile.Close()
+			removeSwapDisk(swapPath)
+			return nil, err
+		}
+		// The child holds the cgroup once started
+		defer cgroupFile.Close()
+	}
+
 	machine, err := firecracker.NewMachine(ctx, fcCfg, firecracker.WithProcessRunner(cmd))
 	if err != nil {
 		consoleFile.Close()
+		removeSwapDisk(swapPath)
 		return nil, fmt.Errorf("failed to create machine: %w", err)
 	}
 
 	if err := machine.Start(ctx); err != nil {
 		consoleFile.Close()
+		removeSwapDisk(swapPath)
 		return nil, fmt.Errorf("failed to start machine: %w", err)
 	}
 
 	// Close our handle to the console file, the child process has its own.
 	consoleFile.Close()
+	started = true
 
 	// Store state
 	state := &vmState{
@@ -325,12 +424,25 @@ func (r *FirecrackerRuntime) Launch(ctx context.Context, req *domain.SandboxRequ
 		SocketPath:  socketPath,
 		LogPath:     logPath,
 		ConsolePath: consolePath,
+		SwapPath:    swapPath,
+		VsockPath:   vsockPath,
+		Jail:        jail,
 		StartedAt:   time.Now(),
 		Request:     req,
 		Config:      cfg,
 	}
 	r.vms.Store(req.ID, state)
 
+	if vsockPath != "" && r.GuestAgent.ReadyTimeout > 0 {
+		readyCtx, cancel := context.WithTimeout(ctx, r.GuestAgent.ReadyTimeout)
+		err := r.guestAgent(state).WaitReady(readyCtx, 100*time.Millisecond)
+		cancel()
+		if err != nil {
+			r.Kill(context.Background(), req.ID)
+			return nil, fmt.Errorf("sandbox %s: %w", req.ID, err)
+		}
+	}
+
 	return &domain.SandboxRun{
 		ID:        req.ID,
 		RequestID: req.ID,
@@ -360,33 +472,21 @@ func (r *FirecrackerRuntime) Inspect(ctx context.Context, id domain.SandboxID) (
 		}
 	}
 
-	// Get memory usage
-	var memUsage domain.Megabytes
+	// Sample usage from the VMM process, which holds the guest's memory
+	var usage ResourceUsage
 	if state.Cmd != nil && state.Cmd.Process != nil && status == domain.RunStatusRunning {
-		// Read /proc/<pid>/statm
-		// Format: size resident shared text lib data dt
-		// resident is in pages
-		pid := state.Cmd.Process.Pid
-		statmPath := fmt.Sprintf("/proc/%d/statm", pid)
-		if content, err := os.ReadFile(statmPath); err == nil {
-			fields := strings.Fields(string(content))
-			if len(fields) >= 2 {
-				var rssPages int64
-				fmt.Sscanf(fields[1], "%d", &rssPages)
-				// Assume 4KB pages
-				memUsage = domain.Megabytes(rssPages * 4 / 1024)
-			}
-		}
+		usage, _ = procUsage(state.Cmd.Process.Pid)
 	}
 
 	return &domain.SandboxRun{
-		ID:          state.Request.ID,
-		RequestID:   state.Request.ID,
-		Status:      status,
-		ExitCode:    state.ExitCode,
-		StartedAt:   state.StartedAt,
-		UpdatedAt:   time.Now(),
-		MemoryUsage: memUsage,
+		ID:           state.Request.ID,
+		RequestID:    state.Request.ID,
+		Status:       status,
+		ExitCode:     state.ExitCode,
+		StartedAt:    state.StartedAt,
+		UpdatedAt:    time.Now(),
+		MemoryUsage:  usage.Memory,
+		CPUUsageUsec: usage.CPUUsec,
 	}, nil
 }
 
@@ -403,15 +503,21 @@ func (r *FirecrackerRuntime) List(ctx context.Context) ([]domain.SandboxRun, err
 				status = domain.RunStatusFailed
 			}
 		}
+		var usage ResourceUsage
+		if state.Cmd != nil && state.Cmd.Process != nil && status == domain.RunStatusRunning {
+			usage, _ = procUsage(state.Cmd.Process.Pid)
+		}
 		state.mu.Unlock()
 
 		list = append(list, domain.SandboxRun{
-			ID:        state.Request.ID,
-			RequestID: state.Request.ID,
-			Status:    status,
-			ExitCode:  state.ExitCode,
-			StartedAt: state.StartedAt,
-			UpdatedAt: time.Now(),
+			ID:           state.Request.ID,
+			RequestID:    state.Request.ID,
+			Status:       status,
+			ExitCode:     state.ExitCode,
+			StartedAt:    state.StartedAt,
+			UpdatedAt:    time.Now(),
+			MemoryUsage:  usage.Memory,
+			CPUUsageUsec: usage.CPUUsec,
 		})
 		return true
 	})
@@ -431,10 +537,22 @@ func (r *FirecrackerRuntime) Kill(ctx context.Context, id domain.SandboxID) erro
 	if err := state.Machine.StopVMM(); err != nil {
 		r.Logger.Warn("StopVMM failed", "error", err)
 	}
+	// As PID 1 of its namespace, a jailed VMM ignores signals it does not
+	// handle, except SIGKILL
+	if state.Jail != nil && state.Cmd.Process != nil {
+		_ = state.Cmd.Process.Kill()
+	}
 
 	// Clean up
 	r.vms.Delete(id)
 	os.Remove(state.SocketPath)
+	if state.VsockPath != "" {
+		os.Remove(state.VsockPath)
+	}
+	removeSwapDisk(state.SwapPath)
+	if state.Jail != nil {
+		state.Jail.cleanup(r)
+	}
 	// We keep the log/console files for debugging/streaming?
 	// If we delete them, StreamLogs might fail if called after Kill.
 	// Usually we might want to keep them for a bit or let a reaper clean them up.
@@ -499,7 +617,7 @@ func (r *FirecrackerRuntime) StreamLogs(ctx context.Context, id domain.SandboxID
 
 func (r *FirecrackerRuntime) Allocation(ctx context.Context) (domain.ResourceCapacity, error) {
 	var cpu domain.MilliCPU
-	var mem domain.Megabytes
+	var mem, swap domain.Megabytes
 
 	r.vms.Range(func(key, value any) bool {
 		state := value.(*vmState)
@@ -508,15 +626,17 @@ func (r *FirecrackerRuntime) Allocation(ctx context.Context) (domain.ResourceCap
 		if state.ExitCode == nil {
 			cpu += domain.MilliCPU(state.Config.CPUs * 1000)
 			mem += domain.Megabytes(state.Config.MemoryMB)
+			swap += domain.Megabytes(state.Config.SwapMB)
 		}
 		state.mu.Unlock()
 		return true
 	})
 
 	return domain.ResourceCapacity{
-		CPU: cpu,
-		Mem: mem,
-		GPU: 0,
+		CPU:  cpu,
+		Mem:  mem,
+		GPU:  0,
+		Swap: swap,
 	}, nil
 }
 
@@ -534,6 +654,12 @@ func (r *FirecrackerRuntime) Wait(ctx context.Context, id domain.SandboxID) erro
 	state.mu.Lock()
 	defer state.mu.Unlock()
 
+	// A claimed VM is stopped once the command it was claimed for exits
+	if state.ClaimedExit != nil {
+		state.ExitCode = state.ClaimedExit
+		return nil
+	}
+
 	if state.Cmd.ProcessState != nil {
 		code := state.Cmd.ProcessState.ExitCode()
 		state.ExitCode = &code
@@ -610,6 +736,21 @@ func (r *FirecrackerRuntime) CreateSnapshot(ctx context.Context, id domain.Sandb
 		return fmt.Errorf("machine not initialized for %s", id)
 	}
 
+	// A jailed VMM can only write inside its chroot
+	if state.Jail != nil {
+		memName, diskName := "/snapshot.mem", "/snapshot.disk"
+		if err := state.Machine.CreateSnapshot(ctx, memName, diskName); err != nil {
+			return err
+		}
+		if err := state.Jail.exportFile(memName, memPath); err != nil {
+			return fmt.Errorf("failed to move snapshot memory out of the jail: %w", err)
+		}
+		if err := state.Jail.exportFile(diskName, diskPath); err != nil {
+			return fmt.Errorf("failed to move snapshot state out of the jail: %w", err)
+		}
+		return nil
+	}
+
 	return state.Machine.CreateSnapshot(ctx, memPath, diskPath)
 }
 
@@ -0,0 +1,109 @@
+//go:build linux
+// +build linux
+
+package tartarus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/cerberus"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// Claim resumes the paused VM warmID, tracks it as req.ID and runs req's
+// command in it through the guest agent, writing its output to the VM's
+// console log. Once the command exits the VM is stopped, and Wait reports
+// the command's exit code. VMs without a guest agent cannot be claimed.
+func (r *FirecrackerRuntime) Claim(ctx context.Context, warmID domain.SandboxID, req *domain.SandboxRequest) (*domain.SandboxRun, error) {
+	state, err := r.getState(warmID)
+	if err != nil {
+		return nil, err
+	}
+	if r.GuestAgent == nil || state.VsockPath == "" {
+		return nil, ErrClaimUnsupported
+	}
+	if _, loaded := r.vms.LoadOrStore(req.ID, state); loaded {
+		return nil, fmt.Errorf("sandbox %s already exists", req.ID)
+	}
+	if err := r.Resume(ctx, warmID); err != nil {
+		r.vms.Delete(req.ID)
+		return nil, fmt.Errorf("failed to resume pre-warmed sandbox %s: %w", warmID, err)
+	}
+	r.vms.Delete(warmID)
+	r.Logger.Info("Claimed pre-warmed sandbox", "id", req.ID, "warm_id", warmID)
+
+	reqCopy := *req
+	now := time.Now()
+	state.mu.Lock()
+	state.Request = &reqCopy
+	state.StartedAt = now
+	state.mu.Unlock()
+
+	go r.runClaimed(req.ID, state, r.claimCommand(ctx, req))
+
+	return &domain.SandboxRun{
+		ID:        req.ID,
+		RequestID: req.ID,
+		Status:    domain.RunStatusRunning,
+		StartedAt: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// claimCommand is req's command with its environment, resolving secret
+// references as Launch does.
+func (r *FirecrackerRuntime) claimCommand(ctx context.Context, req *domain.SandboxRequest) []string {
+	secretProvider := r.Secrets
+	if secretProvider == nil {
+		secretProvider = cerberus.NewEnvSecretProvider()
+	}
+
+	cmd := []string{"env"}
+	for k, v := range req.Env {
+		if strings.HasPrefix(v, "env:") || strings.HasPrefix(v, "vault:") {
+			if s, err := secretProvider.Resolve(ctx, v); err == nil {
+				v = s
+			} else {
+				r.Logger.Warn("Failed to resolve secret", "key", k, "ref", v, "error", err)
+			}
+		}
+		cmd = append(cmd, k+"="+v)
+	}
+	cmd = append(cmd, req.Command...)
+	return append(cmd, req.Args...)
+}
+
+// runClaimed runs the command of a claimed VM to completion, records its
+// exit code and stops the VM.
+func (r *FirecrackerRuntime) runClaimed(id domain.SandboxID, state *vmState, cmd []string) {
+	var out io.Writer = io.Discard
+	if console, err := os.OpenFile(state.ConsolePath, os.O_WRONLY|os.O_APPEND, 0); err == nil {
+		defer console.Close()
+		out = console
+	}
+
+	code := 0
+	if err := r.guestAgent(state).Exec(context.Background(), cmd, nil, out, out); err != nil {
+		var exit *domain.ExecExitError
+		if errors.As(err, &exit) {
+			code = exit.Code
+		} else {
+			r.Logger.Warn("Claimed command failed", "id", id, "error", err)
+			code = -1
+		}
+	}
+
+	state.mu.Lock()
+	state.ClaimedExit = &code
+	state.mu.Unlock()
+	if err := state.Machine.StopVMM(); err != nil {
+		r.Logger.Warn("StopVMM failed", "id", id, "error", err)
+	}
+}
@@ -5,18 +5,69 @@ package tartarus
 
 import (
 	"context"
-	"fmt"
 	"io"
+	"net"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
 
+// guestAgent returns a client for the guest agent of a VM.
+func (r *FirecrackerRuntime) guestAgent(state *vmState) *GuestAgentClient {
+	port := r.GuestAgent.port()
+	return &GuestAgentClient{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			return DialFirecrackerVsock(ctx, state.VsockPath, port)
+		},
+	}
+}
+
+// guestAgentFor returns a client for the guest agent of sandbox id, or
+// ErrGuestAgentUnavailable if the VM has none.
+func (r *FirecrackerRuntime) guestAgentFor(id domain.SandboxID) (*GuestAgentClient, error) {
+	state, err := r.getState(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.GuestAgent == nil || state.VsockPath == "" {
+		return nil, ErrGuestAgentUnavailable
+	}
+	return r.guestAgent(state), nil
+}
+
+// Exec runs cmd in the VM through its guest agent.
 func (r *FirecrackerRuntime) Exec(ctx context.Context, id domain.SandboxID, cmd []string, stdout, stderr io.Writer) error {
-	// Firecracker does not support exec without a guest agent.
-	// We return ErrNotImplemented for now.
-	return fmt.Errorf("exec not implemented for Firecracker runtime")
+	agent, err := r.guestAgentFor(id)
+	if err != nil {
+		return err
+	}
+	return agent.Exec(ctx, cmd, nil, stdout, stderr)
 }
 
+// ExecInteractive runs cmd in the VM through its guest agent, with stdin
+// streamed to it.
 func (r *FirecrackerRuntime) ExecInteractive(ctx context.Context, id domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
-	return fmt.Errorf("exec interactive not implemented for Firecracker runtime")
+	agent, err := r.guestAgentFor(id)
+	if err != nil {
+		return err
+	}
+	return agent.Exec(ctx, cmd, stdin, stdout, stderr)
+}
+
+// GuestHealth checks the VM's guest agent is up.
+func (r *FirecrackerRuntime) GuestHealth(ctx context.Context, id domain.SandboxID) (*GuestHealth, error) {
+	agent, err := r.guestAgentFor(id)
+	if err != nil {
+		return nil, err
+	}
+	return agent.Health(ctx)
+}
+
+// GuestMetrics reads the VM's memory, load and process count as its kernel
+// reports them.
+func (r *FirecrackerRuntime) GuestMetrics(ctx context.Context, id domain.SandboxID) (*GuestMetrics, error) {
+	agent, err := r.guestAgentFor(id)
+	if err != nil {
+		return nil, err
+	}
+	return agent.Metrics(ctx)
 }
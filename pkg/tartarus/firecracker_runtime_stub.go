@@ -14,7 +14,9 @@ import (
 
 // FirecrackerRuntime stub for non-Linux platforms
 type FirecrackerRuntime struct {
-	Logger *slog.Logger
+	Logger     *slog.Logger
+	Cgroup     string
+	GuestAgent *GuestAgentConfig
 }
 
 func NewFirecrackerRuntime(logger *slog.Logger, socketDir, kernelImage, rootFSBase string) *FirecrackerRuntime {
@@ -41,6 +43,10 @@ func (r *FirecrackerRuntime) Resume(ctx context.Context, id domain.SandboxID) er
 	return fmt.Errorf("Firecracker runtime not supported on non-Linux platforms")
 }
 
+func (r *FirecrackerRuntime) Claim(ctx context.Context, warmID domain.SandboxID, req *domain.SandboxRequest) (*domain.SandboxRun, error) {
+	return nil, fmt.Errorf("Firecracker runtime not supported on non-Linux platforms")
+}
+
 func (r *FirecrackerRuntime) CreateSnapshot(ctx context.Context, id domain.SandboxID, memPath, diskPath string) error {
 	return fmt.Errorf("Firecracker runtime not supported on non-Linux platforms")
 }
@@ -76,3 +82,11 @@ func (r *FirecrackerRuntime) Exec(ctx context.Context, id domain.SandboxID, cmd
 func (r *FirecrackerRuntime) ExecInteractive(ctx context.Context, id domain.SandboxID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	return fmt.Errorf("Firecracker runtime not supported on non-Linux platforms")
 }
+
+func (r *FirecrackerRuntime) GuestHealth(ctx context.Context, id domain.SandboxID) (*GuestHealth, error) {
+	return nil, fmt.Errorf("Firecracker runtime not supported on non-Linux platforms")
+}
+
+func (r *FirecrackerRuntime) GuestMetrics(ctx context.Context, id domain.SandboxID) (*GuestMetrics, error) {
+	return nil, fmt.Errorf("Firecracker runtime not supported on non-Linux platforms")
+}
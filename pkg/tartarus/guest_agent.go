@@ -0,0 +1,484 @@
+package tartarus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// Guest agent defaults.
+const (
+	// DefaultGuestAgentPort is the vsock port the guest agent listens on.
+	DefaultGuestAgentPort = 1024
+
+	// GuestAgentPath is where Erebus installs the guest agent in a rootfs.
+	GuestAgentPath = "/sbin/tartarus-guest-agent"
+
+	// guestAgentCID is the guest's vsock context ID. Each VM has a vsock
+	// device of its own, so every guest can use the same one.
+	guestAgentCID = 3
+
+	// maxGuestFrame bounds the payload of a frame.
+	maxGuestFrame = 1 << 20
+)
+
+// ErrGuestAgentUnavailable is returned for sandboxes without a guest agent
+// to reach.
+var ErrGuestAgentUnavailable = errors.New("sandbox has no guest agent")
+
+// GuestAgentConfig enables the guest agent of Firecracker VMs: a small
+// server inside the guest the runtime reaches over vsock to exec commands,
+// check readiness and read guest metrics, without SSH or the console.
+type GuestAgentConfig struct {
+	// Port is the vsock port the agent listens on (DefaultGuestAgentPort
+	// if 0).
+	Port uint32
+
+	// Path is the agent binary in the guest (GuestAgentPath if empty). The
+	// runtime starts it before the sandbox's command.
+	Path string
+
+	// ReadyTimeout, if positive, makes Launch wait for the agent to answer
+	// a health check, and fail the launch if it does not in time.
+	ReadyTimeout time.Duration
+}
+
+func (c *GuestAgentConfig) port() uint32 {
+	if c.Port == 0 {
+		return DefaultGuestAgentPort
+	}
+	return c.Port
+}
+
+func (c *GuestAgentConfig) path() string {
+	if c.Path == "" {
+		return GuestAgentPath
+	}
+	return c.Path
+}
+
+// startScript returns the shell that starts the agent in the background.
+func (c *GuestAgentConfig) startScript() string {
+	return fmt.Sprintf("'%s' -port %d & ", strings.ReplaceAll(c.path(), "'", "'\\''"), c.port())
+}
+
+// Guest agent operations.
+const (
+	guestOpExec    = "exec"
+	guestOpHealth  = "health"
+	guestOpMetrics = "metrics"
+)
+
+// guestRequest opens every guest agent connection, as a line of JSON.
+// Frames follow in both directions: for exec, the command's input from the
+// host and its output and result from the guest; for the others, one
+// domain.ExecStatus frame with the answer.
+type guestRequest struct {
+	Op  string   `json:"op"`
+	Cmd []string `json:"cmd,omitempty"`
+}
+
+// GuestHealth is the guest agent's answer to a readiness check.
+type GuestHealth struct {
+	Ready         bool    `json:"ready"`
+	Version       string  `json:"version,omitempty"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// GuestMetrics is what the guest kernel reports about itself.
+type GuestMetrics struct {
+	MemTotalKB     uint64  `json:"mem_total_kb"`
+	MemAvailableKB uint64  `json:"mem_available_kb"`
+	Load1          float64 `json:"load1"`
+	Load5          float64 `json:"load5"`
+	Load15         float64 `json:"load15"`
+	Processes      int     `json:"processes"`
+	UptimeSeconds  float64 `json:"uptime_seconds"`
+}
+
+// writeGuestFrame writes a frame: its channel, the length of data as a
+// big-endian uint32, and data.
+func writeGuestFrame(w io.Writer, ch domain.ExecChannel, data []byte) error {
+	buf := make([]byte, 5+len(data))
+	buf[0] = byte(ch)
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(data)))
+	copy(buf[5:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readGuestFrame(r io.Reader) (domain.ExecChannel, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > maxGuestFrame {
+		return 0, nil, fmt.Errorf("guest agent frame of %d bytes exceeds %d", n, maxGuestFrame)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return domain.ExecChannel(header[0]), data, nil
+}
+
+// frameWriter writes to one channel of a connection shared by several
+// writers.
+type frameWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+	ch domain.ExecChannel
+}
+
+func (f *frameWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for rest := p; len(rest) > 0; {
+		chunk := rest[:min(len(rest), maxGuestFrame)]
+		if err := writeGuestFrame(f.w, f.ch, chunk); err != nil {
+			return 0, err
+		}
+		rest = rest[len(chunk):]
+	}
+	return len(p), nil
+}
+
+// GuestAgentClient talks to a guest agent over connections from Dial.
+type GuestAgentClient struct {
+	Dial func(ctx context.Context) (net.Conn, error)
+}
+
+// open dials the agent and sends req. The connection is closed when ctx is
+// done.
+func (c *GuestAgentClient) open(ctx context.Context, req guestRequest) (net.Conn, func(), error) {
+	conn, err := c.Dial(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reach guest agent: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	line, _ := json.Marshal(req)
+	if _, err := conn.Write(append(line, '\n')); err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to send guest agent request: %w", err)
+	}
+	return conn, func() { stop(); conn.Close() }, nil
+}
+
+// call sends req and decodes the single status frame answering it into v.
+func (c *GuestAgentClient) call(ctx context.Context, req guestRequest, v any) error {
+	conn, done, err := c.open(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ch, data, err := readGuestFrame(conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to read guest agent answer: %w", err)
+	}
+	if ch != domain.ExecStatus {
+		return fmt.Errorf("unexpected guest agent frame on channel %d", ch)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Health checks the guest agent is up.
+func (c *GuestAgentClient) Health(ctx context.Context) (*GuestHealth, error) {
+	var health GuestHealth
+	if err := c.call(ctx, guestRequest{Op: guestOpHealth}, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// Metrics reads the guest's memory, load and process count.
+func (c *GuestAgentClient) Metrics(ctx context.Context) (*GuestMetrics, error) {
+	var metrics GuestMetrics
+	if err := c.call(ctx, guestRequest{Op: guestOpMetrics}, &metrics); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
+
+// WaitReady polls Health every interval until the agent reports ready or
+// ctx is done, in which case the last failure is returned.
+func (c *GuestAgentClient) WaitReady(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		health, err := c.Health(ctx)
+		if err == nil && health.Ready {
+			return nil
+		}
+		if err == nil {
+			err = errors.New("guest agent is not ready")
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("guest agent did not become ready: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Exec runs cmd in the guest, streaming stdin to it, if not nil, and its
+// output to stdout and stderr. It returns nil if the command exited 0, and
+// a *domain.ExecExitError for other exit codes. Cancelling ctx kills the
+// command.
+func (c *GuestAgentClient) Exec(ctx context.Context, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(cmd) == 0 {
+		return errors.New("no command to exec")
+	}
+	conn, done, err := c.open(ctx, guestRequest{Op: guestOpExec, Cmd: cmd})
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	// Input is sent as the guest reads it, which it may never do, and ends
+	// with the connection
+	go func() {
+		var mu sync.Mutex
+		if stdin != nil {
+			io.Copy(&frameWriter{mu: &mu, w: conn, ch: domain.ExecStdin}, stdin)
+		}
+		writeGuestFrame(conn, domain.ExecClose, nil)
+	}()
+
+	for {
+		ch, data, err := readGuestFrame(conn)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("guest agent connection lost: %w", err)
+		}
+		switch ch {
+		case domain.ExecStdout:
+			if stdout != nil {
+				stdout.Write(data)
+			}
+		case domain.ExecStderr:
+			if stderr != nil {
+				stderr.Write(data)
+			}
+		case domain.ExecStatus:
+			var result domain.ExecResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return fmt.Errorf("invalid exec result from guest agent: %w", err)
+			}
+			return result.Err()
+		}
+	}
+}
+
+// DialFirecrackerVsock connects to port in the guest through the host side
+// of a Firecracker vsock device, the Unix socket at udsPath.
+func DialFirecrackerVsock(ctx context.Context, udsPath string, port uint32) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", udsPath)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %d\n", port); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Read the acknowledgement a byte at a time, leaving what follows it
+	var line []byte
+	b := make([]byte, 1)
+	for len(line) < 64 {
+		if _, err := conn.Read(b); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("vsock handshake failed: %w", err)
+		}
+		if b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
+	}
+	if !strings.HasPrefix(string(line), "OK ") {
+		conn.Close()
+		return nil, fmt.Errorf("vsock handshake failed: %q", line)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// GuestAgentServer is the guest agent, serving the runtime from inside the
+// guest.
+type GuestAgentServer struct {
+	Version  string
+	ProcRoot string // Where procfs is mounted ("/proc" if empty)
+
+	started time.Time
+}
+
+// Serve answers the connections l accepts until it fails.
+func (s *GuestAgentServer) Serve(l net.Listener) error {
+	if s.started.IsZero() {
+		s.started = time.Now()
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn answers the request of one connection, and closes it.
+func (s *GuestAgentServer) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var req guestRequest
+	var mu sync.Mutex
+	status := &frameWriter{mu: &mu, w: conn, ch: domain.ExecStatus}
+	if err := json.Unmarshal(line, &req); err != nil {
+		data, _ := json.Marshal(domain.ExecResult{ExitCode: -1, Error: "invalid request"})
+		status.Write(data)
+		return
+	}
+	var answer any
+	switch req.Op {
+	case guestOpExec:
+		s.exec(req.Cmd, r, conn, &mu)
+		return
+	case guestOpHealth:
+		answer = GuestHealth{Ready: true, Version: s.Version, UptimeSeconds: s.uptime()}
+	case guestOpMetrics:
+		answer = s.metrics()
+	default:
+		answer = domain.ExecResult{ExitCode: -1, Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+	data, _ := json.Marshal(answer)
+	status.Write(data)
+}
+
+// exec runs cmd with its input read from r's frames and its output and
+// result written to w.
+func (s *GuestAgentServer) exec(cmd []string, r io.Reader, w io.Writer, mu *sync.Mutex) {
+	status := &frameWriter{mu: mu, w: w, ch: domain.ExecStatus}
+	if len(cmd) == 0 {
+		data, _ := json.Marshal(domain.ExecResult{ExitCode: -1, Error: "no command"})
+		status.Write(data)
+		return
+	}
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Stdout = &frameWriter{mu: mu, w: w, ch: domain.ExecStdout}
+	c.Stderr = &frameWriter{mu: mu, w: w, ch: domain.ExecStderr}
+	stdin, err := c.StdinPipe()
+	if err == nil {
+		err = c.Start()
+	}
+	if err != nil {
+		data, _ := json.Marshal(domain.ExecResultOf(err))
+		status.Write(data)
+		return
+	}
+
+	// Input until the host closes it; a cancel or a lost host kills the
+	// command
+	go func() {
+		defer stdin.Close()
+		for {
+			ch, data, err := readGuestFrame(r)
+			if err != nil || ch == domain.ExecCancel {
+				c.Process.Kill()
+				return
+			}
+			switch ch {
+			case domain.ExecStdin:
+				stdin.Write(data)
+			case domain.ExecClose:
+				stdin.Close()
+			}
+		}
+	}()
+
+	data, _ := json.Marshal(domain.ExecResultOf(c.Wait()))
+	status.Write(data)
+}
+
+func (s *GuestAgentServer) proc(name string) string {
+	root := s.ProcRoot
+	if root == "" {
+		root = "/proc"
+	}
+	return filepath.Join(root, name)
+}
+
+// uptime returns the guest's uptime from /proc/uptime, or the agent's own
+// if it cannot be read.
+func (s *GuestAgentServer) uptime() float64 {
+	if data, err := os.ReadFile(s.proc("uptime")); err == nil {
+		if field, _, ok := strings.Cut(string(data), " "); ok {
+			if v, err := strconv.ParseFloat(field, 64); err == nil {
+				return v
+			}
+		}
+	}
+	return time.Since(s.started).Seconds()
+}
+
+// metrics reads /proc/meminfo and /proc/loadavg. Values that cannot be read
+// are left zero.
+func (s *GuestAgentServer) metrics() GuestMetrics {
+	m := GuestMetrics{UptimeSeconds: s.uptime()}
+	if f, err := os.Open(s.proc("meminfo")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 {
+				continue
+			}
+			v, _ := strconv.ParseUint(fields[1], 10, 64)
+			switch fields[0] {
+			case "MemTotal:":
+				m.MemTotalKB = v
+			case "MemAvailable:":
+				m.MemAvailableKB = v
+			}
+		}
+		f.Close()
+	}
+	// e.g. "0.20 0.18 0.12 1/80 11206": loads, then running/total tasks
+	if data, err := os.ReadFile(s.proc("loadavg")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) >= 4 {
+			m.Load1, _ = strconv.ParseFloat(fields[0], 64)
+			m.Load5, _ = strconv.ParseFloat(fields[1], 64)
+			m.Load15, _ = strconv.ParseFloat(fields[2], 64)
+			if _, total, ok := strings.Cut(fields[3], "/"); ok {
+				m.Processes, _ = strconv.Atoi(total)
+			}
+		}
+	}
+	return m
+}
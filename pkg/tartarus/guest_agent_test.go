@@ -0,0 +1,159 @@
+package tartarus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// pipeClient returns a client whose connections are served by server.
+func pipeClient(server *GuestAgentServer) *GuestAgentClient {
+	return &GuestAgentClient{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			host, guest := net.Pipe()
+			go server.ServeConn(guest)
+			return host, nil
+		},
+	}
+}
+
+func TestGuestAgent_Exec(t *testing.T) {
+	ctx := context.Background()
+	client := pipeClient(&GuestAgentServer{})
+
+	var stdout, stderr bytes.Buffer
+	err := client.Exec(ctx, []string{"sh", "-c", "echo out; echo err >&2; exit 3"}, nil, &stdout, &stderr)
+	var exitErr *domain.ExecExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("unexpected output %q, %q", stdout.String(), stderr.String())
+	}
+
+	// Input is streamed to the command until it is closed
+	stdout.Reset()
+	if err := client.Exec(ctx, []string{"cat"}, strings.NewReader("hello guest"), &stdout, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "hello guest" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+
+	if err := client.Exec(ctx, []string{"/nonexistent/binary"}, nil, nil, nil); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+
+	// Cancelling the exec kills the command
+	cctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := client.Exec(cctx, []string{"sleep", "30"}, nil, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to end the exec, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("exec outlived its context")
+	}
+}
+
+func TestGuestAgent_HealthAndMetrics(t *testing.T) {
+	proc := t.TempDir()
+	os.WriteFile(filepath.Join(proc, "uptime"), []byte("42.50 80.00\n"), 0644)
+	os.WriteFile(filepath.Join(proc, "loadavg"), []byte("0.20 0.18 0.12 1/80 11206\n"), 0644)
+	os.WriteFile(filepath.Join(proc, "meminfo"), []byte("MemTotal:        1012364 kB\nMemFree:          512000 kB\nMemAvailable:     800000 kB\n"), 0644)
+	client := pipeClient(&GuestAgentServer{Version: "1.2.3", ProcRoot: proc})
+	ctx := context.Background()
+
+	health, err := client.Health(ctx)
+	if err != nil || !health.Ready || health.Version != "1.2.3" || health.UptimeSeconds != 42.5 {
+		t.Fatalf("unexpected health %+v, %v", health, err)
+	}
+	if err := client.WaitReady(ctx, 10*time.Millisecond); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	metrics, err := client.Metrics(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := GuestMetrics{MemTotalKB: 1012364, MemAvailableKB: 800000, Load1: 0.2, Load5: 0.18, Load15: 0.12, Processes: 80, UptimeSeconds: 42.5}
+	if *metrics != want {
+		t.Errorf("got %+v, want %+v", *metrics, want)
+	}
+}
+
+func TestGuestAgent_WaitReadyTimeout(t *testing.T) {
+	client := &GuestAgentClient{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.WaitReady(ctx, 10*time.Millisecond); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected the last failure, got %v", err)
+	}
+}
+
+// bufferedConn reads what a handshake left buffered before the rest.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+func TestDialFirecrackerVsock(t *testing.T) {
+	uds := filepath.Join(t.TempDir(), "vsock.sock")
+	l, err := net.Listen("unix", uds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Stands in for Firecracker, which forwards to the guest after the
+	// handshake
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			line, _ := r.ReadString('\n')
+			if line != "CONNECT 1024\n" {
+				conn.Close()
+				continue
+			}
+			conn.Write([]byte("OK 1073741824\n"))
+			server := &GuestAgentServer{}
+			server.ServeConn(&bufferedConn{Conn: conn, r: r})
+		}
+	}()
+
+	client := &GuestAgentClient{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			return DialFirecrackerVsock(ctx, uds, DefaultGuestAgentPort)
+		},
+	}
+	var stdout bytes.Buffer
+	if err := client.Exec(context.Background(), []string{"echo", "over vsock"}, nil, &stdout, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "over vsock\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+
+	if _, err := DialFirecrackerVsock(context.Background(), uds, 9); err == nil {
+		t.Error("expected a refused port to fail the handshake")
+	}
+}
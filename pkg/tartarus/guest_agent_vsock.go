@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package tartarus
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// ListenVsock listens on port of every vsock context ID of the machine, as
+// the guest agent does inside a VM.
+func ListenVsock(port uint32) (net.Listener, error) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vsock socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrVM{CID: unix.VMADDR_CID_ANY, Port: port}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind vsock port %d: %w", port, err)
+	}
+	if err := unix.Listen(fd, unix.SOMAXCONN); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to listen on vsock port %d: %w", port, err)
+	}
+	return &vsockListener{fd: fd, addr: vsockAddr{CID: unix.VMADDR_CID_ANY, Port: port}}, nil
+}
+
+type vsockAddr struct {
+	CID  uint32
+	Port uint32
+}
+
+func (a vsockAddr) Network() string { return "vsock" }
+func (a vsockAddr) String() string  { return fmt.Sprintf("%d:%d", a.CID, a.Port) }
+
+type vsockListener struct {
+	fd   int
+	addr vsockAddr
+}
+
+func (l *vsockListener) Accept() (net.Conn, error) {
+	fd, sa, err := unix.Accept4(l.fd, unix.SOCK_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	remote := vsockAddr{}
+	if vm, ok := sa.(*unix.SockaddrVM); ok {
+		remote = vsockAddr{CID: vm.CID, Port: vm.Port}
+	}
+	return &vsockConn{File: os.NewFile(uintptr(fd), "vsock:"+remote.String()), local: l.addr, remote: remote}, nil
+}
+
+// Close stops Accept, which closing the descriptor alone would not wake.
+func (l *vsockListener) Close() error {
+	unix.Shutdown(l.fd, unix.SHUT_RDWR)
+	return unix.Close(l.fd)
+}
+
+func (l *vsockListener) Addr() net.Addr { return l.addr }
+
+// vsockConn is an accepted vsock connection.
+type vsockConn struct {
+	*os.File
+	local, remote vsockAddr
+}
+
+func (c *vsockConn) LocalAddr() net.Addr  { return c.local }
+func (c *vsockConn) RemoteAddr() net.Addr { return c.remote }
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package tartarus
+
+import (
+	"fmt"
+	"net"
+)
+
+// ListenVsock is only supported on Linux.
+func ListenVsock(port uint32) (net.Listener, error) {
+	return nil, fmt.Errorf("vsock is only supported on linux")
+}
@@ -0,0 +1,136 @@
+//go:build linux
+// +build linux
+
+package tartarus
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"runtime"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// netnsDir is where named network namespaces are mounted, as with "ip netns".
+const netnsDir = "/var/run/netns"
+
+// setupGVisorNetwork moves a Styx attachment into a network namespace for
+// runsc and returns the namespace path.
+//
+// Styx attaches sandboxes with a TAP device on its bridge, which suits
+// microVMs. runsc instead drives the interfaces it finds in the sandbox's
+// network namespace, so the TAP is replaced by a veth pair: the host end
+// takes over the TAP's name and bridge, so the contract's firewall rules,
+// which match on the interface name, apply unchanged and Styx's Detach
+// removes it; the peer becomes eth0 in the namespace with the sandbox's
+// address and default route.
+func setupGVisorNetwork(id domain.SandboxID, cfg VMConfig) (string, error) {
+	tap, err := netlink.LinkByName(cfg.TapDevice)
+	if err != nil {
+		return "", fmt.Errorf("failed to find TAP %s: %w", cfg.TapDevice, err)
+	}
+	masterIndex := tap.Attrs().MasterIndex
+
+	name := "tartarus-" + string(id)
+	ns, err := newNamedNetns(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create network namespace: %w", err)
+	}
+	defer ns.Close()
+	path := filepath.Join(netnsDir, name)
+
+	if err := netlink.LinkDel(tap); err != nil {
+		teardownGVisorNetwork(path)
+		return "", fmt.Errorf("failed to remove TAP %s: %w", cfg.TapDevice, err)
+	}
+	la := netlink.NewLinkAttrs()
+	la.Name = cfg.TapDevice
+	la.MasterIndex = masterIndex
+	veth := &netlink.Veth{
+		LinkAttrs:     la,
+		PeerName:      "eth0",
+		PeerNamespace: netlink.NsFd(int(ns)),
+	}
+	if err := netlink.LinkAdd(veth); err != nil {
+		teardownGVisorNetwork(path)
+		return "", fmt.Errorf("failed to create veth %s: %w", cfg.TapDevice, err)
+	}
+	if err := netlink.LinkSetUp(veth); err != nil {
+		teardownGVisorNetwork(path)
+		return "", fmt.Errorf("failed to set veth %s up: %w", cfg.TapDevice, err)
+	}
+
+	if err := configureNetns(ns, cfg); err != nil {
+		teardownGVisorNetwork(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// configureNetns brings up the sandbox side of the veth pair.
+func configureNetns(ns netns.NsHandle, cfg VMConfig) error {
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace: %w", err)
+	}
+	defer h.Close()
+
+	if lo, err := h.LinkByName("lo"); err == nil {
+		if err := h.LinkSetUp(lo); err != nil {
+			return fmt.Errorf("failed to set lo up: %w", err)
+		}
+	}
+	eth, err := h.LinkByName("eth0")
+	if err != nil {
+		return fmt.Errorf("failed to find eth0 in network namespace: %w", err)
+	}
+	if cfg.IP.IsValid() {
+		addr := &netlink.Addr{IPNet: &net.IPNet{
+			IP:   cfg.IP.AsSlice(),
+			Mask: net.CIDRMask(cfg.CIDR.Bits(), cfg.IP.BitLen()),
+		}}
+		if err := h.AddrAdd(eth, addr); err != nil {
+			return fmt.Errorf("failed to assign %s to eth0: %w", cfg.IP, err)
+		}
+	}
+	if err := h.LinkSetUp(eth); err != nil {
+		return fmt.Errorf("failed to set eth0 up: %w", err)
+	}
+	if cfg.Gateway.IsValid() {
+		route := &netlink.Route{LinkIndex: eth.Attrs().Index, Gw: cfg.Gateway.AsSlice()}
+		if err := h.RouteAdd(route); err != nil {
+			return fmt.Errorf("failed to add default route via %s: %w", cfg.Gateway, err)
+		}
+	}
+	return nil
+}
+
+// newNamedNetns creates a network namespace mounted under netnsDir.
+// Creating it switches the calling thread into it, so that happens on a
+// throwaway goroutine whose locked thread exits with it.
+func newNamedNetns(name string) (netns.NsHandle, error) {
+	type result struct {
+		ns  netns.NsHandle
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		ns, err := netns.NewNamed(name)
+		done <- result{ns, err}
+	}()
+	r := <-done
+	return r.ns, r.err
+}
+
+// teardownGVisorNetwork deletes the sandbox's network namespace, which
+// destroys its veth pair.
+func teardownGVisorNetwork(path string) {
+	if path == "" {
+		return
+	}
+	_ = netns.DeleteNamed(filepath.Base(path))
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package tartarus
+
+import (
+	"fmt"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func setupGVisorNetwork(id domain.SandboxID, cfg VMConfig) (string, error) {
+	return "", fmt.Errorf("gVisor networking is only supported on Linux")
+}
+
+func teardownGVisorNetwork(path string) {}
@@ -30,6 +30,12 @@ type GVisorRuntime struct {
 	// Platform is the gVisor platform to use (e.g. "ptrace" or "kvm")
 	Platform string
 
+	// Cgroup is the cgroup v2 directory under which each sandbox gets its
+	// own cgroup, named after its ID, that runsc is started in. Inspect
+	// samples a sandbox's usage from it. Empty leaves runsc in the agent's
+	// cgroup and reports no usage.
+	Cgroup string
+
 	// containers tracks active gVisor containers
 	containers sync.Map // domain.SandboxID -> *gvisorContainer
 }
@@ -40,6 +46,8 @@ type gvisorContainer struct {
 	BundlePath  string
 	Request     *domain.SandboxRequest
 	Config      VMConfig
+	NetNS       string // Network namespace holding the Styx attachment, if any
+	CgroupDir   string // The sandbox's own cgroup, if any
 	StartedAt   time.Time
 	ExitCode    *int
 	Cmd         *exec.Cmd
@@ -70,8 +78,9 @@ func NewGVisorRuntime(logger *slog.Logger, runscPath, rootDir string) *GVisorRun
 	}
 }
 
-// createOCISpec creates an OCI runtime spec for the sandbox
-func (g *GVisorRuntime) createOCISpec(req *domain.SandboxRequest, cfg VMConfig) *specs.Spec {
+// createOCISpec creates an OCI runtime spec for the sandbox. netnsPath is
+// the network namespace to join; without one the sandbox gets a new, empty one.
+func (g *GVisorRuntime) createOCISpec(req *domain.SandboxRequest, cfg VMConfig, netnsPath string) *specs.Spec {
 	// Base spec
 	spec := &specs.Spec{
 		Version: "1.0.2",
@@ -91,7 +100,7 @@ func (g *GVisorRuntime) createOCISpec(req *domain.SandboxRequest, cfg VMConfig)
 		Linux: &specs.Linux{
 			Namespaces: []specs.LinuxNamespace{
 				{Type: specs.PIDNamespace},
-				{Type: specs.NetworkNamespace},
+				{Type: specs.NetworkNamespace, Path: netnsPath},
 				{Type: specs.IPCNamespace},
 				{Type: specs.UTSNamespace},
 				{Type: specs.MountNamespace},
@@ -107,12 +116,7 @@ func (g *GVisorRuntime) createOCISpec(req *domain.SandboxRequest, cfg VMConfig)
 	}
 
 	// Set resource limits
-	if req.Resources.Mem > 0 {
-		memLimit := int64(req.Resources.Mem) * 1024 * 1024
-		spec.Linux.Resources.Memory = &specs.LinuxMemory{
-			Limit: &memLimit,
-		}
-	}
+	spec.Linux.Resources.Memory = MemoryLimits(req)
 	if req.Resources.CPU > 0 {
 		quota := int64(req.Resources.CPU) * 100 // MilliCPU to quota
 		period := uint64(100000)
@@ -121,10 +125,29 @@ func (g *GVisorRuntime) createOCISpec(req *domain.SandboxRequest, cfg VMConfig)
 			Period: &period,
 		}
 	}
+	applyProcessLimits(spec, req.Limits)
 
 	return spec
 }
 
+// runscArgs builds the runsc command line. A networked sandbox uses runsc's
+// netstack on the interfaces of its network namespace; otherwise it only has
+// loopback.
+func (g *GVisorRuntime) runscArgs(bundlePath, sandboxID string, networked bool) []string {
+	network := "--network=none"
+	if networked {
+		network = "--network=sandbox"
+	}
+	return []string{
+		"--platform=" + g.Platform,
+		"--rootless=false",
+		network,
+		"run",
+		"--bundle", bundlePath,
+		sandboxID,
+	}
+}
+
 // Launch implements SandboxRuntime interface.
 func (g *GVisorRuntime) Launch(ctx context.Context, req *domain.SandboxRequest, cfg VMConfig) (*domain.SandboxRun, error) {
 	g.Logger.Info("Launching gVisor sandbox", "id", req.ID)
@@ -148,46 +171,79 @@ func (g *GVisorRuntime) Launch(ctx context.Context, req *domain.SandboxRequest,
 		rootfsPath = cfg.OverlayFS
 	}
 
+	// Plumb the Styx attachment into the sandbox's network namespace
+	var netnsPath string
+	if cfg.TapDevice != "" {
+		path, err := setupGVisorNetwork(req.ID, cfg)
+		if err != nil {
+			os.RemoveAll(bundlePath)
+			return nil, fmt.Errorf("failed to attach network: %w", err)
+		}
+		netnsPath = path
+	}
+
 	// Create OCI spec
-	spec := g.createOCISpec(req, cfg)
+	spec := g.createOCISpec(req, cfg, netnsPath)
 	spec.Root.Path = rootfsPath
 
 	// Write config.json
 	configPath := filepath.Join(bundlePath, "config.json")
 	configData, err := json.MarshalIndent(spec, "", "  ")
 	if err != nil {
+		teardownGVisorNetwork(netnsPath)
 		return nil, fmt.Errorf("failed to marshal spec: %w", err)
 	}
 	if err := os.WriteFile(configPath, configData, 0644); err != nil {
+		teardownGVisorNetwork(netnsPath)
 		return nil, fmt.Errorf("failed to write config: %w", err)
 	}
+	if err := verifyProcessLimits(configPath, req.Limits); err != nil {
+		os.RemoveAll(bundlePath)
+		teardownGVisorNetwork(netnsPath)
+		return nil, fmt.Errorf("process limits check failed: %w", err)
+	}
 
 	// Create console log file
 	consolePath := filepath.Join(bundlePath, "console.log")
 	consoleFile, err := os.Create(consolePath)
 	if err != nil {
+		teardownGVisorNetwork(netnsPath)
 		return nil, fmt.Errorf("failed to create console file: %w", err)
 	}
 
-	// Build runsc command
-	args := []string{
-		"--platform=" + g.Platform,
-		"--rootless=false",
-		"--network=sandbox",
-		"run",
-		"--bundle", bundlePath,
-		sandboxID,
-	}
-
-	cmd := exec.CommandContext(ctx, g.RunscPath, args...)
+	cmd := exec.CommandContext(ctx, g.RunscPath, g.runscArgs(bundlePath, sandboxID, netnsPath != "")...)
 	cmd.Stdout = consoleFile
 	cmd.Stderr = consoleFile
 	cmd.Dir = bundlePath
 
+	var cgroupDir string
+	var cgroupFile *os.File
+	if g.Cgroup != "" {
+		cgroupDir = filepath.Join(g.Cgroup, sandboxID)
+		if err = os.MkdirAll(cgroupDir, 0755); err == nil {
+			cgroupFile, err = startInCgroup(cmd, cgroupDir)
+		}
+		if err != nil {
+			consoleFile.Close()
+			os.Remove(cgroupDir)
+			os.RemoveAll(bundlePath)
+			teardownGVisorNetwork(netnsPath)
+			return nil, fmt.Errorf("failed to create sandbox cgroup: %w", err)
+		}
+	}
+
 	// Start the sandbox
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+	if cgroupFile != nil {
+		cgroupFile.Close()
+	}
+	if err != nil {
 		consoleFile.Close()
+		if cgroupDir != "" {
+			os.Remove(cgroupDir)
+		}
 		os.RemoveAll(bundlePath)
+		teardownGVisorNetwork(netnsPath)
 		return nil, fmt.Errorf("failed to start runsc: %w", err)
 	}
 
@@ -197,6 +253,8 @@ func (g *GVisorRuntime) Launch(ctx context.Context, req *domain.SandboxRequest,
 		BundlePath:  bundlePath,
 		Request:     req,
 		Config:      cfg,
+		NetNS:       netnsPath,
+		CgroupDir:   cgroupDir,
 		StartedAt:   time.Now(),
 		Cmd:         cmd,
 		ConsoleFile: consoleFile,
@@ -213,6 +271,10 @@ func (g *GVisorRuntime) Launch(ctx context.Context, req *domain.SandboxRequest,
 		if container.ConsoleFile != nil {
 			container.ConsoleFile.Close()
 		}
+		// runsc waits for the sandbox, so its cgroup is empty by now
+		if container.CgroupDir != "" {
+			os.Remove(container.CgroupDir)
+		}
 
 		if err != nil {
 			if exitErr, ok := err.(*exec.ExitError); ok {
@@ -264,21 +326,26 @@ func (g *GVisorRuntime) Inspect(ctx context.Context, id domain.SandboxID) (*doma
 		finishedAt = time.Now() // Approximate, ideally we capture this in the goroutine
 	}
 
-	// Try to get memory usage if possible (not implemented here)
-	var memUsage domain.Megabytes
+	// Sample usage from the sandbox's cgroup, which holds the sandbox,
+	// its gofer and runsc itself
+	var usage ResourceUsage
+	if container.CgroupDir != "" && status == domain.RunStatusRunning {
+		usage, _ = CgroupUsage(container.CgroupDir)
+	}
 
 	return &domain.SandboxRun{
-		ID:          container.ID,
-		RequestID:   container.Request.ID,
-		NodeID:      container.Request.NodeID,
-		Template:    container.Request.Template,
-		Status:      status,
-		ExitCode:    container.ExitCode,
-		StartedAt:   container.StartedAt,
-		FinishedAt:  finishedAt,
-		UpdatedAt:   time.Now(),
-		Metadata:    container.Request.Metadata,
-		MemoryUsage: memUsage,
+		ID:           container.ID,
+		RequestID:    container.Request.ID,
+		NodeID:       container.Request.NodeID,
+		Template:     container.Request.Template,
+		Status:       status,
+		ExitCode:     container.ExitCode,
+		StartedAt:    container.StartedAt,
+		FinishedAt:   finishedAt,
+		UpdatedAt:    time.Now(),
+		Metadata:     container.Request.Metadata,
+		MemoryUsage:  usage.Memory,
+		CPUUsageUsec: usage.CPUUsec,
 	}, nil
 }
 
@@ -321,8 +388,9 @@ func (g *GVisorRuntime) Kill(ctx context.Context, id domain.SandboxID) error {
 	deleteCmd := exec.CommandContext(ctx, g.RunscPath, "delete", "--force", container.SandboxID)
 	_ = deleteCmd.Run()
 
-	// Cleanup bundle
+	// Cleanup bundle and network namespace
 	os.RemoveAll(container.BundlePath)
+	teardownGVisorNetwork(container.NetNS)
 
 	g.containers.Delete(id)
 	return nil
@@ -358,6 +426,25 @@ func (g *GVisorRuntime) Resume(ctx context.Context, id domain.SandboxID) error {
 	return nil
 }
 
+// Throttle caps the sandbox's CPU by rewriting cpu.max of its own cgroup,
+// which needs Cgroup to be set.
+func (g *GVisorRuntime) Throttle(ctx context.Context, id domain.SandboxID, cpu domain.MilliCPU) error {
+	val, ok := g.containers.Load(id)
+	if !ok {
+		return fmt.Errorf("container not found: %s", id)
+	}
+	container := val.(*gvisorContainer)
+	if container.CgroupDir == "" {
+		return ErrThrottleUnsupported
+	}
+
+	quota := fmt.Sprintf("%d 100000", int64(cpu)*100)
+	if err := os.WriteFile(filepath.Join(container.CgroupDir, "cpu.max"), []byte(quota), 0644); err != nil {
+		return fmt.Errorf("failed to throttle sandbox: %w", err)
+	}
+	return nil
+}
+
 // CreateSnapshot implements SandboxRuntime interface.
 func (g *GVisorRuntime) CreateSnapshot(ctx context.Context, id domain.SandboxID, memPath, diskPath string) error {
 	val, ok := g.containers.Load(id)
@@ -492,23 +579,26 @@ func (g *GVisorRuntime) StreamLogs(ctx context.Context, id domain.SandboxID, w i
 // Allocation implements SandboxRuntime interface.
 func (g *GVisorRuntime) Allocation(ctx context.Context) (domain.ResourceCapacity, error) {
 	var cpu domain.MilliCPU
-	var mem domain.Megabytes
+	var mem, swap domain.Megabytes
 
 	g.containers.Range(func(_, value interface{}) bool {
 		container := value.(*gvisorContainer)
 		container.mu.Lock()
 		if container.ExitCode == nil {
+			ram, sw := container.Request.Swap.CgroupSplit(container.Request.Resources.Mem)
 			cpu += container.Request.Resources.CPU
-			mem += container.Request.Resources.Mem
+			mem += ram
+			swap += sw
 		}
 		container.mu.Unlock()
 		return true
 	})
 
 	return domain.ResourceCapacity{
-		CPU: cpu,
-		Mem: mem,
-		GPU: 0,
+		CPU:  cpu,
+		Mem:  mem,
+		GPU:  0,
+		Swap: swap,
 	}, nil
 }
 
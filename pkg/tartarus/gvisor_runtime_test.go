@@ -0,0 +1,142 @@
+package tartarus
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func TestGVisorRuntime_Network(t *testing.T) {
+	g := NewGVisorRuntime(slog.Default(), "/bin/runsc", t.TempDir())
+	req := &domain.SandboxRequest{ID: "sb-1", Command: []string{"/bin/true"}}
+
+	netnsOf := func(spec *specs.Spec) (string, bool) {
+		for _, ns := range spec.Linux.Namespaces {
+			if ns.Type == specs.NetworkNamespace {
+				return ns.Path, true
+			}
+		}
+		return "", false
+	}
+
+	// Attached sandboxes join the namespace holding their Styx interface
+	path, ok := netnsOf(g.createOCISpec(req, VMConfig{}, "/var/run/netns/tartarus-sb-1"))
+	if !ok || path != "/var/run/netns/tartarus-sb-1" {
+		t.Errorf("expected to join the sandbox namespace, got %q", path)
+	}
+	if args := g.runscArgs("/bundle", "sb-1", true); !slices.Contains(args, "--network=sandbox") {
+		t.Errorf("expected runsc netstack on the namespace interfaces, got %v", args)
+	}
+
+	// Others get a fresh namespace with loopback only
+	path, ok = netnsOf(g.createOCISpec(req, VMConfig{}, ""))
+	if !ok || path != "" {
+		t.Errorf("expected a new network namespace, got %q", path)
+	}
+	if args := g.runscArgs("/bundle", "sb-1", false); !slices.Contains(args, "--network=none") {
+		t.Errorf("expected no network, got %v", args)
+	}
+}
+
+func TestGVisorRuntime_ProcessLimits(t *testing.T) {
+	g := NewGVisorRuntime(slog.Default(), "/bin/runsc", t.TempDir())
+	limits := &domain.ProcessLimits{MaxPIDs: 256, MaxOpenFiles: 4096, CoreDumps: domain.CoreDumpsDisabled}
+	req := &domain.SandboxRequest{ID: "sb-1", Command: []string{"/bin/true"}, Limits: limits}
+
+	spec := g.createOCISpec(req, VMConfig{}, "")
+	if spec.Linux.Resources.Pids == nil || spec.Linux.Resources.Pids.Limit != 256 {
+		t.Errorf("expected PID limit 256, got %+v", spec.Linux.Resources.Pids)
+	}
+	rlimits := map[string]uint64{}
+	for _, r := range spec.Process.Rlimits {
+		rlimits[r.Type] = r.Hard
+	}
+	if n, ok := rlimits["RLIMIT_NOFILE"]; !ok || n != 4096 {
+		t.Errorf("expected RLIMIT_NOFILE 4096, got %v", rlimits)
+	}
+	if n, ok := rlimits["RLIMIT_CORE"]; !ok || n != 0 {
+		t.Errorf("expected core dumps disabled, got %v", rlimits)
+	}
+
+	// The launch-time check accepts the bundle written from the spec...
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(spec)
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyProcessLimits(configPath, limits); err != nil {
+		t.Errorf("expected limits to verify, got %v", err)
+	}
+
+	// ...and refuses one without the limits
+	data, _ = json.Marshal(g.createOCISpec(&domain.SandboxRequest{ID: "sb-1"}, VMConfig{}, ""))
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyProcessLimits(configPath, limits); err == nil {
+		t.Error("expected a spec without limits to fail the check")
+	}
+}
+
+func TestGuestLimitsScript(t *testing.T) {
+	script := guestLimitsScript(&domain.ProcessLimits{MaxPIDs: 256, MaxOpenFiles: 4096, CoreDumps: domain.CoreDumpsDisabled})
+	want := "ulimit -u 256 && ulimit -n 4096 && ulimit -c 0 || exit 125; "
+	if script != want {
+		t.Errorf("got %q, want %q", script, want)
+	}
+	if script := guestLimitsScript(&domain.ProcessLimits{CoreDumps: domain.CoreDumpsEnabled}); script != "" {
+		t.Errorf("expected no script, got %q", script)
+	}
+}
+
+func TestGVisorRuntime_Swap(t *testing.T) {
+	g := NewGVisorRuntime(slog.Default(), "/bin/runsc", t.TempDir())
+	swappiness := 80
+	req := &domain.SandboxRequest{
+		ID:        "sb-1",
+		Command:   []string{"/bin/true"},
+		Resources: domain.ResourceSpec{Mem: 8192},
+		Swap:      &domain.SwapPolicy{MaxMB: 2048, Swappiness: &swappiness},
+	}
+
+	memory := g.createOCISpec(req, VMConfig{}, "").Linux.Resources.Memory
+	if memory == nil || *memory.Limit != 6144<<20 || memory.Swap == nil || *memory.Swap != 8192<<20 {
+		t.Fatalf("expected 6 GiB of RAM and 2 GiB of swap, got %+v", memory)
+	}
+	if memory.Swappiness == nil || *memory.Swappiness != 80 {
+		t.Errorf("expected swappiness 80, got %v", memory.Swappiness)
+	}
+
+	// zram only applies to microVMs
+	req.Swap = &domain.SwapPolicy{MaxMB: 2048, Zram: true}
+	memory = g.createOCISpec(req, VMConfig{}, "").Linux.Resources.Memory
+	if *memory.Limit != 8192<<20 || memory.Swap != nil {
+		t.Errorf("expected all memory in RAM, got %+v", memory)
+	}
+}
+
+func TestGuestSwapScript(t *testing.T) {
+	swappiness := 100
+	script := guestSwapScript(&domain.SwapPolicy{MaxMB: 1024, Swappiness: &swappiness}, 4096)
+	want := "mount -t proc proc /proc 2>/dev/null; mount -t sysfs sysfs /sys 2>/dev/null; " +
+		"{ mkswap /dev/vdb >/dev/null && swapon /dev/vdb && echo 100 > /proc/sys/vm/swappiness; } || exit 125; "
+	if script != want {
+		t.Errorf("got %q, want %q", script, want)
+	}
+
+	// zram is capped at half the memory
+	script = guestSwapScript(&domain.SwapPolicy{MaxMB: 4096, Zram: true}, 4096)
+	if !strings.Contains(script, "echo 2048M > /sys/block/zram0/disksize") || !strings.Contains(script, "swapon -p 100 /dev/zram0") {
+		t.Errorf("unexpected zram script %q", script)
+	}
+	if script := guestSwapScript(nil, 4096); script != "" {
+		t.Errorf("expected no script, got %q", script)
+	}
+}
@@ -0,0 +1,160 @@
+package tartarus
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Jailer defaults, matching the Firecracker jailer's own.
+const (
+	DefaultJailerBinary     = "jailer"
+	DefaultJailerChrootBase = "/srv/jailer"
+
+	// jailerSocket is the VMM API socket, as a path inside the chroot.
+	jailerSocket = "/run/firecracker.socket"
+)
+
+// ErrJailerIDsExhausted is returned when every UID/GID the jailer may run
+// VMs as is in use.
+var ErrJailerIDsExhausted = errors.New("no free jailer UID/GID")
+
+// JailerConfig runs a sandbox's VMM under the Firecracker jailer instead of
+// directly as the agent's user: chrooted to a directory of its own holding
+// only the files the VM needs, in a new PID namespace, as a UID and GID of
+// its own, and in a cgroup of its own.
+type JailerConfig struct {
+	// Binary is the jailer executable (DefaultJailerBinary, looked up in
+	// PATH, if empty).
+	Binary string
+
+	// ExecFile is the firecracker binary the jailer copies into the chroot
+	// and execs. Required.
+	ExecFile string
+
+	// ChrootBaseDir holds the VMs' chroots, at
+	// <ChrootBaseDir>/<exec file name>/<sandbox ID>/root
+	// (DefaultJailerChrootBase if empty). The kernel, drives and snapshots
+	// are hard-linked into the chroot, so it must be on the same filesystem.
+	ChrootBaseDir string
+
+	// Each VM runs as a UID from [UID, UID+IDCount) and the GID at the
+	// same offset from GID, not shared with any other running VM, so VMs
+	// cannot reach each other's files or processes. With an IDCount below
+	// 1, every VM runs as UID and GID.
+	UID     int
+	GID     int
+	IDCount int
+
+	// NewPIDNS starts the VMM in a new PID namespace.
+	NewPIDNS bool
+
+	// NetNS, if set, is the path of a network namespace the VMM joins, such
+	// as /var/run/netns/sandboxes. The sandbox's TAP device must be in it.
+	NetNS string
+
+	// ParentCgroup is the cgroup v2 directory, relative to CgroupFSRoot,
+	// each VM gets a cgroup named after its sandbox ID under. If empty, the
+	// runtime's Cgroup is used.
+	ParentCgroup string
+}
+
+// Validate checks the configuration can launch VMs.
+func (c *JailerConfig) Validate() error {
+	if c.ExecFile == "" {
+		return fmt.Errorf("jailer: firecracker exec file is required")
+	}
+	if !filepath.IsAbs(c.ExecFile) {
+		return fmt.Errorf("jailer: exec file %q must be an absolute path", c.ExecFile)
+	}
+	if c.UID <= 0 || c.GID <= 0 {
+		return fmt.Errorf("jailer: VMs must not run as root (uid %d, gid %d)", c.UID, c.GID)
+	}
+	if filepath.IsAbs(c.ParentCgroup) {
+		return fmt.Errorf("jailer: parent cgroup %q must be relative to %s", c.ParentCgroup, CgroupFSRoot)
+	}
+	return nil
+}
+
+func (c *JailerConfig) binary() string {
+	if c.Binary == "" {
+		return DefaultJailerBinary
+	}
+	return c.Binary
+}
+
+func (c *JailerConfig) chrootBase() string {
+	if c.ChrootBaseDir == "" {
+		return DefaultJailerChrootBase
+	}
+	return c.ChrootBaseDir
+}
+
+// jailDir is the directory the jailer creates for a VM; the chroot is its
+// root subdirectory.
+func (c *JailerConfig) jailDir(id string) string {
+	return filepath.Join(c.chrootBase(), filepath.Base(c.ExecFile), id)
+}
+
+// jailerArgs returns the jailer's arguments for the VM id, running as
+// uid:gid, with fcArgs passed on to firecracker.
+func jailerArgs(c *JailerConfig, id string, uid, gid int, parentCgroup string, fcArgs []string) []string {
+	args := []string{
+		"--id", id,
+		"--exec-file", c.ExecFile,
+		"--uid", strconv.Itoa(uid),
+		"--gid", strconv.Itoa(gid),
+		"--chroot-base-dir", c.chrootBase(),
+		"--cgroup-version", "2",
+	}
+	if parentCgroup != "" {
+		args = append(args, "--parent-cgroup", parentCgroup)
+	}
+	if c.NetNS != "" {
+		args = append(args, "--netns", c.NetNS)
+	}
+	if len(fcArgs) > 0 {
+		args = append(args, "--")
+		args = append(args, fcArgs...)
+	}
+	return args
+}
+
+// jailIDs hands out the per-VM UID/GID offsets of a JailerConfig range.
+type jailIDs struct {
+	mu    sync.Mutex
+	inUse map[int]bool // By UID
+}
+
+// acquire returns a UID and GID no other VM runs as, unless the range is
+// shared.
+func (j *jailIDs) acquire(c *JailerConfig) (int, int, error) {
+	count := c.IDCount
+	if count < 1 {
+		return c.UID, c.GID, nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.inUse == nil {
+		j.inUse = make(map[int]bool)
+	}
+	for i := 0; i < count; i++ {
+		if !j.inUse[c.UID+i] {
+			j.inUse[c.UID+i] = true
+			return c.UID + i, c.GID + i, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: all %d in use", ErrJailerIDsExhausted, count)
+}
+
+// releaseFor frees a UID acquired from c.
+func (j *jailIDs) releaseFor(c *JailerConfig, uid int) {
+	if c.IDCount < 1 {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.inUse, uid)
+}
@@ -0,0 +1,77 @@
+package tartarus
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestJailerArgs(t *testing.T) {
+	cfg := &JailerConfig{ExecFile: "/usr/bin/firecracker", UID: 100000, GID: 100000, NetNS: "/var/run/netns/sandboxes"}
+	args := jailerArgs(cfg, "sb-1", 100003, 100003, "tartarus/firecracker", []string{"--api-sock", jailerSocket})
+	want := []string{
+		"--id", "sb-1",
+		"--exec-file", "/usr/bin/firecracker",
+		"--uid", "100003",
+		"--gid", "100003",
+		"--chroot-base-dir", DefaultJailerChrootBase,
+		"--cgroup-version", "2",
+		"--parent-cgroup", "tartarus/firecracker",
+		"--netns", "/var/run/netns/sandboxes",
+		"--", "--api-sock", "/run/firecracker.socket",
+	}
+	if !slices.Equal(args, want) {
+		t.Errorf("got %v, want %v", args, want)
+	}
+	if dir := cfg.jailDir("sb-1"); dir != "/srv/jailer/firecracker/sb-1" {
+		t.Errorf("unexpected jail dir %s", dir)
+	}
+}
+
+func TestJailIDs(t *testing.T) {
+	cfg := &JailerConfig{UID: 100000, GID: 200000, IDCount: 2}
+	var ids jailIDs
+
+	uid1, gid1, err := ids.acquire(cfg)
+	if err != nil || uid1 != 100000 || gid1 != 200000 {
+		t.Fatalf("got %d:%d, %v", uid1, gid1, err)
+	}
+	uid2, gid2, err := ids.acquire(cfg)
+	if err != nil || uid2 != 100001 || gid2 != 200001 {
+		t.Fatalf("got %d:%d, %v", uid2, gid2, err)
+	}
+	if _, _, err := ids.acquire(cfg); !errors.Is(err, ErrJailerIDsExhausted) {
+		t.Fatalf("expected the range to be exhausted, got %v", err)
+	}
+
+	// A released ID is handed out again
+	ids.releaseFor(cfg, uid1)
+	if uid, _, err := ids.acquire(cfg); err != nil || uid != uid1 {
+		t.Errorf("expected %d again, got %d, %v", uid1, uid, err)
+	}
+
+	// Without a range every VM shares the base IDs
+	shared := &JailerConfig{UID: 300000, GID: 300000}
+	for i := 0; i < 3; i++ {
+		if uid, gid, err := ids.acquire(shared); err != nil || uid != 300000 || gid != 300000 {
+			t.Errorf("got %d:%d, %v", uid, gid, err)
+		}
+	}
+}
+
+func TestJailerConfig_Validate(t *testing.T) {
+	valid := JailerConfig{ExecFile: "/usr/bin/firecracker", UID: 100000, GID: 100000}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for name, cfg := range map[string]JailerConfig{
+		"no exec file":    {UID: 1, GID: 1},
+		"relative exec":   {ExecFile: "firecracker", UID: 1, GID: 1},
+		"root":            {ExecFile: "/usr/bin/firecracker"},
+		"absolute cgroup": {ExecFile: "/usr/bin/firecracker", UID: 1, GID: 1, ParentCgroup: "/sys/fs/cgroup/x"},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
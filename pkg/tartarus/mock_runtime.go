@@ -148,17 +148,19 @@ func (r *MockRuntime) Allocation(ctx context.Context) (domain.ResourceCapacity,
 	defer r.mu.RUnlock()
 
 	var cpu domain.MilliCPU
-	var mem domain.Megabytes
+	var mem, swap domain.Megabytes
 
 	for _, cfg := range r.configs {
 		cpu += domain.MilliCPU(cfg.CPUs * 1000)
 		mem += domain.Megabytes(cfg.MemoryMB)
+		swap += domain.Megabytes(cfg.SwapMB)
 	}
 
 	return domain.ResourceCapacity{
-		CPU: cpu,
-		Mem: mem,
-		GPU: 0,
+		CPU:  cpu,
+		Mem:  mem,
+		GPU:  0,
+		Swap: swap,
 	}, nil
 }
 
@@ -226,6 +228,43 @@ func (r *MockRuntime) Resume(ctx context.Context, id domain.SandboxID) error {
 	return nil
 }
 
+// Claim resumes the paused sandbox warmID and tracks it as req.ID.
+func (r *MockRuntime) Claim(ctx context.Context, warmID domain.SandboxID, req *domain.SandboxRequest) (*domain.SandboxRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	warm, ok := r.runs[warmID]
+	if !ok {
+		return nil, errors.New("sandbox not found")
+	}
+	if _, ok := r.runs[req.ID]; ok {
+		return nil, fmt.Errorf("sandbox %s already exists", req.ID)
+	}
+	r.Logger.Info("Claiming pre-warmed sandbox", "id", req.ID, "warm_id", warmID)
+
+	reqCopy := *req
+	now := time.Now()
+	run := &domain.SandboxRun{
+		ID:        req.ID,
+		RequestID: req.ID,
+		NodeID:    warm.NodeID,
+		Template:  req.Template,
+		Status:    domain.RunStatusRunning,
+		StartedAt: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.runs[req.ID] = run
+	r.configs[req.ID] = r.configs[warmID]
+	r.requests[req.ID] = &reqCopy
+	r.waiters[req.ID] = r.waiters[warmID]
+	delete(r.runs, warmID)
+	delete(r.configs, warmID)
+	delete(r.requests, warmID)
+	delete(r.waiters, warmID)
+	delete(r.paused, warmID)
+	return run, nil
+}
+
 func (r *MockRuntime) CreateSnapshot(ctx context.Context, id domain.SandboxID, memPath, diskPath string) error {
 	r.mu.RLock()
 	_, ok := r.runs[id]
@@ -0,0 +1,106 @@
+package tartarus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// guestLimitExitCode is the exit code of a Firecracker guest that could not
+// apply its process limits. The command is never run in that case.
+const guestLimitExitCode = 125
+
+// applyProcessLimits sets the sandbox's PID cgroup limit and the
+// RLIMIT_NOFILE and RLIMIT_CORE of its process in an OCI spec.
+func applyProcessLimits(spec *specs.Spec, limits *domain.ProcessLimits) {
+	if limits == nil {
+		return
+	}
+	if limits.MaxPIDs > 0 {
+		spec.Linux.Resources.Pids = &specs.LinuxPids{Limit: limits.MaxPIDs}
+	}
+	if limits.MaxOpenFiles > 0 {
+		spec.Process.Rlimits = append(spec.Process.Rlimits, specs.POSIXRlimit{
+			Type: "RLIMIT_NOFILE",
+			Hard: limits.MaxOpenFiles,
+			Soft: limits.MaxOpenFiles,
+		})
+	}
+	if limits.CoreDumps == domain.CoreDumpsDisabled {
+		spec.Process.Rlimits = append(spec.Process.Rlimits, specs.POSIXRlimit{Type: "RLIMIT_CORE"})
+	}
+}
+
+// verifyProcessLimits reads back the bundle's config.json, which is what
+// runsc enforces, and checks that it carries the requested limits. A
+// sandbox whose limits did not make it into the bundle is never started.
+func verifyProcessLimits(configPath string, limits *domain.ProcessLimits) error {
+	if limits == nil {
+		return nil
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read back spec: %w", err)
+	}
+	var spec specs.Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return fmt.Errorf("failed to parse spec: %w", err)
+	}
+
+	if limits.MaxPIDs > 0 {
+		if spec.Linux == nil || spec.Linux.Resources == nil || spec.Linux.Resources.Pids == nil ||
+			spec.Linux.Resources.Pids.Limit != limits.MaxPIDs {
+			return fmt.Errorf("spec does not limit PIDs to %d", limits.MaxPIDs)
+		}
+	}
+	rlimit := func(name string) (specs.POSIXRlimit, bool) {
+		if spec.Process == nil {
+			return specs.POSIXRlimit{}, false
+		}
+		for _, r := range spec.Process.Rlimits {
+			if r.Type == name {
+				return r, true
+			}
+		}
+		return specs.POSIXRlimit{}, false
+	}
+	if limits.MaxOpenFiles > 0 {
+		if r, ok := rlimit("RLIMIT_NOFILE"); !ok || r.Hard != limits.MaxOpenFiles {
+			return fmt.Errorf("spec does not limit open files to %d", limits.MaxOpenFiles)
+		}
+	}
+	if limits.CoreDumps == domain.CoreDumpsDisabled {
+		if r, ok := rlimit("RLIMIT_CORE"); !ok || r.Hard != 0 {
+			return fmt.Errorf("spec does not disable core dumps")
+		}
+	}
+	return nil
+}
+
+// guestLimitsScript returns the shell prefix that applies process limits
+// inside a Firecracker guest before the command is exec'd. If any limit
+// cannot be applied, the guest exits with guestLimitExitCode instead of
+// running the command unconstrained.
+func guestLimitsScript(limits *domain.ProcessLimits) string {
+	if limits == nil {
+		return ""
+	}
+	var cmds []string
+	if limits.MaxPIDs > 0 {
+		cmds = append(cmds, fmt.Sprintf("ulimit -u %d", limits.MaxPIDs))
+	}
+	if limits.MaxOpenFiles > 0 {
+		cmds = append(cmds, fmt.Sprintf("ulimit -n %d", limits.MaxOpenFiles))
+	}
+	if limits.CoreDumps == domain.CoreDumpsDisabled {
+		cmds = append(cmds, "ulimit -c 0")
+	}
+	if len(cmds) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s || exit %d; ", strings.Join(cmds, " && "), guestLimitExitCode)
+}
@@ -2,12 +2,29 @@ package tartarus
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/netip"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
 
+// ErrThrottleUnsupported is returned by runtimes' Throttle when the
+// sandbox's CPU cannot be capped while it runs.
+var ErrThrottleUnsupported = errors.New("sandbox cannot be throttled")
+
+// ErrClaimUnsupported is returned by runtimes' Claim when the sandbox
+// cannot take over another request's workload.
+var ErrClaimUnsupported = errors.New("sandbox cannot be claimed")
+
+// Claimer is implemented by runtimes that keep pre-warmed sandboxes for
+// later requests. Claim resumes the paused sandbox warmID, tracks it as
+// req.ID from then on and starts req's command in it; the sandbox exits
+// with the command.
+type Claimer interface {
+	Claim(ctx context.Context, warmID domain.SandboxID, req *domain.SandboxRequest) (*domain.SandboxRun, error)
+}
+
 // SandboxRuntime is the abstraction implemented by the MicroVM backend.
 // Hecatoncheir Agent depends on this and does not care about Firecracker vs other VMM.
 
@@ -46,5 +63,10 @@ type VMConfig struct {
 	Gateway   netip.Addr
 	CIDR      netip.Prefix
 	CPUs      int
-	MemoryMB  int
+	MemoryMB  int // RAM; less than the request's memory when part of it may be swapped
+	SwapMB    int // Swap taken from the node
+
+	// Jailer, if set, runs a Firecracker VMM under the jailer. Other
+	// runtimes ignore it.
+	Jailer *JailerConfig
 }
@@ -0,0 +1,87 @@
+package tartarus
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// guestSwapDevice is the swap disk of a Firecracker guest: the drive
+// attached after the root device.
+const guestSwapDevice = "/dev/vdb"
+
+// MemoryLimits returns the OCI memory limits of a sandbox confined by a
+// cgroup: its memory and, if its policy allows swap, memory.swap.max and
+// memory.swappiness. OCI counts swap together with memory. It is nil for
+// requests without a memory size.
+func MemoryLimits(req *domain.SandboxRequest) *specs.LinuxMemory {
+	if req.Resources.Mem <= 0 {
+		return nil
+	}
+	ram, swap := req.Swap.CgroupSplit(req.Resources.Mem)
+	limit := int64(ram) * 1024 * 1024
+	memory := &specs.LinuxMemory{Limit: &limit}
+	if swap > 0 {
+		total := int64(ram+swap) * 1024 * 1024
+		memory.Swap = &total
+		if req.Swap.Swappiness != nil {
+			swappiness := uint64(*req.Swap.Swappiness)
+			memory.Swappiness = &swappiness
+		}
+	}
+	return memory
+}
+
+// guestSwapScript returns the shell prefix that enables swap inside a
+// Firecracker guest with mem of memory: zram, or the swap disk at
+// guestSwapDevice. If swap cannot be enabled, the guest exits with
+// guestLimitExitCode rather than run into the OOM killer.
+func guestSwapScript(policy *domain.SwapPolicy, mem domain.Megabytes) string {
+	size := policy.Size(mem)
+	if size == 0 {
+		return ""
+	}
+	var cmds []string
+	if policy.Zram {
+		cmds = append(cmds,
+			"{ [ -e /sys/block/zram0 ] || modprobe zram; }",
+			fmt.Sprintf("echo %dM > /sys/block/zram0/disksize", size),
+			"mkswap /dev/zram0 >/dev/null",
+			"swapon -p 100 /dev/zram0")
+	} else {
+		cmds = append(cmds,
+			fmt.Sprintf("mkswap %s >/dev/null", guestSwapDevice),
+			fmt.Sprintf("swapon %s", guestSwapDevice))
+	}
+	if policy.Swappiness != nil {
+		cmds = append(cmds, fmt.Sprintf("echo %d > /proc/sys/vm/swappiness", *policy.Swappiness))
+	}
+	// init=/bin/sh starts without /proc and /sys
+	mounts := "mount -t proc proc /proc 2>/dev/null; mount -t sysfs sysfs /sys 2>/dev/null; "
+	return fmt.Sprintf("%s{ %s; } || exit %d; ", mounts, strings.Join(cmds, " && "), guestLimitExitCode)
+}
+
+// createSwapDisk creates a sparse swap disk of size at path. The guest
+// formats it, so it takes host disk only as pages are swapped out.
+func createSwapDisk(path string, size domain.Megabytes) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create swap disk: %w", err)
+	}
+	defer f.Close()
+	if err := f.Truncate(int64(size) * 1024 * 1024); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to size swap disk: %w", err)
+	}
+	return nil
+}
+
+// removeSwapDisk removes a sandbox's swap disk, if it has one.
+func removeSwapDisk(path string) {
+	if path != "" {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,56 @@
+package tartarus
+
+import (
+	"sync"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// TailBuffer is an io.Writer that keeps only the last Limit bytes written.
+type TailBuffer struct {
+	mu        sync.Mutex
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+// NewTailBuffer returns a TailBuffer keeping at most limit bytes
+// (domain.DefaultResultTailBytes if limit <= 0).
+func NewTailBuffer(limit int) *TailBuffer {
+	if limit <= 0 {
+		limit = domain.DefaultResultTailBytes
+	}
+	return &TailBuffer{limit: limit}
+}
+
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := len(p)
+	if len(p) >= t.limit {
+		t.truncated = t.truncated || len(p) > t.limit || len(t.buf) > 0
+		t.buf = append(t.buf[:0], p[len(p)-t.limit:]...)
+		return n, nil
+	}
+	if over := len(t.buf) + len(p) - t.limit; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+		t.truncated = true
+	}
+	t.buf = append(t.buf, p...)
+	return n, nil
+}
+
+// String returns the retained tail.
+func (t *TailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// Truncated reports whether earlier output was discarded.
+func (t *TailBuffer) Truncated() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.truncated
+}
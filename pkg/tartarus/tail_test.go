@@ -0,0 +1,104 @@
+package tartarus
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func TestTailBuffer(t *testing.T) {
+	tail := NewTailBuffer(8)
+	tail.Write([]byte("hello "))
+	if tail.String() != "hello " || tail.Truncated() {
+		t.Fatalf("unexpected tail %q truncated=%v", tail.String(), tail.Truncated())
+	}
+	tail.Write([]byte("world"))
+	if got := tail.String(); got != "lo world" || !tail.Truncated() {
+		t.Fatalf("unexpected tail %q truncated=%v", got, tail.Truncated())
+	}
+	n, _ := tail.Write([]byte(strings.Repeat("x", 20) + "12345678"))
+	if n != 28 || tail.String() != "12345678" {
+		t.Fatalf("unexpected tail %q after %d bytes", tail.String(), n)
+	}
+}
+
+// procExitModule returns a WASI module with two pages of memory whose
+// _start calls proc_exit(code).
+func procExitModule(code byte) []byte {
+	name := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+
+	imports := []byte{1}
+	imports = append(imports, name("wasi_snapshot_preview1")...)
+	imports = append(imports, name("proc_exit")...)
+	imports = append(imports, 0x00, 0x00)
+
+	exports := []byte{2}
+	exports = append(exports, name("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	exports = append(exports, name("_start")...)
+	exports = append(exports, 0x00, 0x01)
+
+	body := []byte{0x00, 0x41, code, 0x10, 0x00, 0x0b}
+
+	section := func(id byte, payload []byte) []byte {
+		return append([]byte{id, byte(len(payload))}, payload...)
+	}
+	wasm := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	wasm = append(wasm, section(1, []byte{2, 0x60, 1, 0x7f, 0, 0x60, 0, 0})...)
+	wasm = append(wasm, section(2, imports)...)
+	wasm = append(wasm, section(3, []byte{1, 1})...)
+	wasm = append(wasm, section(5, []byte{1, 0x00, 2})...)
+	wasm = append(wasm, section(7, exports)...)
+	wasm = append(wasm, section(10, append([]byte{1, byte(len(body))}, body...))...)
+	return wasm
+}
+
+func TestWasmRuntime_Result(t *testing.T) {
+	tmpDir := t.TempDir()
+	modulePath := filepath.Join(tmpDir, "exit.wasm")
+	if err := os.WriteFile(modulePath, procExitModule(0), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := NewWasmRuntime(slog.New(slog.NewTextHandler(os.Stdout, nil)), tmpDir)
+	ctx := context.Background()
+	req := &domain.SandboxRequest{ID: "wasm-result", Template: "wasm-test"}
+
+	if _, err := rt.Launch(ctx, req, VMConfig{Snapshot: domain.SnapshotRef{Path: modulePath}}); err != nil {
+		t.Fatalf("Launch failed: %v", err)
+	}
+	if err := rt.Wait(ctx, req.ID); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	var run *domain.SandboxRun
+	for i := 0; i < 50; i++ {
+		var err error
+		if run, err = rt.Inspect(ctx, req.ID); err != nil {
+			t.Fatalf("Inspect failed: %v", err)
+		}
+		if run.Result != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if run.Status != domain.RunStatusSucceeded || run.ExitCode == nil || *run.ExitCode != 0 {
+		t.Fatalf("unexpected final run: %+v", run)
+	}
+	if run.Result == nil {
+		t.Fatal("expected result on finished run")
+	}
+	if run.Result.PeakMemory != 1 {
+		t.Errorf("expected peak memory rounded up to 1MB, got %d", run.Result.PeakMemory)
+	}
+	if run.Result.DurationMs < 0 {
+		t.Errorf("unexpected duration %d", run.Result.DurationMs)
+	}
+}
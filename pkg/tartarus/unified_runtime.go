@@ -297,6 +297,67 @@ func (u *UnifiedRuntime) Resume(ctx context.Context, id domain.SandboxID) error
 	return runtime.Resume(ctx, id)
 }
 
+// Throttle caps the sandbox's CPU if the runtime that owns it can.
+func (u *UnifiedRuntime) Throttle(ctx context.Context, id domain.SandboxID, cpu domain.MilliCPU) error {
+	runtime, err := u.delegateToRuntime(ctx, id, "throttle")
+	if err != nil {
+		return err
+	}
+	throttler, ok := runtime.(interface {
+		Throttle(ctx context.Context, id domain.SandboxID, cpu domain.MilliCPU) error
+	})
+	if !ok {
+		return ErrThrottleUnsupported
+	}
+	return throttler.Throttle(ctx, id, cpu)
+}
+
+// Claim hands the pre-warmed sandbox warmID to req if the runtime that owns
+// it keeps pre-warmed sandboxes.
+func (u *UnifiedRuntime) Claim(ctx context.Context, warmID domain.SandboxID, req *domain.SandboxRequest) (*domain.SandboxRun, error) {
+	runtime, err := u.delegateToRuntime(ctx, warmID, "claim")
+	if err != nil {
+		return nil, err
+	}
+	claimer, ok := runtime.(Claimer)
+	if !ok {
+		return nil, ErrClaimUnsupported
+	}
+	return claimer.Claim(ctx, warmID, req)
+}
+
+// GuestHealth checks the guest agent of the sandbox, if the runtime that
+// owns it has one.
+func (u *UnifiedRuntime) GuestHealth(ctx context.Context, id domain.SandboxID) (*GuestHealth, error) {
+	runtime, err := u.delegateToRuntime(ctx, id, "guest_health")
+	if err != nil {
+		return nil, err
+	}
+	agent, ok := runtime.(interface {
+		GuestHealth(ctx context.Context, id domain.SandboxID) (*GuestHealth, error)
+	})
+	if !ok {
+		return nil, ErrGuestAgentUnavailable
+	}
+	return agent.GuestHealth(ctx, id)
+}
+
+// GuestMetrics reads the sandbox's metrics from its guest agent, if the
+// runtime that owns it has one.
+func (u *UnifiedRuntime) GuestMetrics(ctx context.Context, id domain.SandboxID) (*GuestMetrics, error) {
+	runtime, err := u.delegateToRuntime(ctx, id, "guest_metrics")
+	if err != nil {
+		return nil, err
+	}
+	agent, ok := runtime.(interface {
+		GuestMetrics(ctx context.Context, id domain.SandboxID) (*GuestMetrics, error)
+	})
+	if !ok {
+		return nil, ErrGuestAgentUnavailable
+	}
+	return agent.GuestMetrics(ctx, id)
+}
+
 // CreateSnapshot implements SandboxRuntime interface.
 func (u *UnifiedRuntime) CreateSnapshot(ctx context.Context, id domain.SandboxID, memPath, diskPath string) error {
 	runtime, err := u.delegateToRuntime(ctx, id, "snapshot")
@@ -353,6 +414,7 @@ func (u *UnifiedRuntime) Allocation(ctx context.Context) (domain.ResourceCapacit
 		total.CPU += alloc.CPU
 		total.Mem += alloc.Mem
 		total.GPU += alloc.GPU
+		total.Swap += alloc.Swap
 	}
 
 	return total, nil
@@ -0,0 +1,99 @@
+package tartarus
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// CgroupFSRoot is where the cgroup v2 hierarchy is mounted.
+const CgroupFSRoot = "/sys/fs/cgroup"
+
+// ResourceUsage is what a sandbox has consumed so far, as reported by
+// Inspect in SandboxRun.MemoryUsage and SandboxRun.CPUUsageUsec.
+type ResourceUsage struct {
+	Memory  domain.Megabytes
+	CPUUsec uint64
+}
+
+// CgroupUsage reads the memory and CPU time charged to a cgroup v2
+// directory from memory.current and cpu.stat.
+func CgroupUsage(dir string) (ResourceUsage, error) {
+	var usage ResourceUsage
+	data, err := os.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return usage, err
+	}
+	memBytes, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return usage, fmt.Errorf("parsing memory.current: %w", err)
+	}
+	usage.Memory = domain.Megabytes(memBytes / 1024 / 1024)
+
+	f, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return usage, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if ok && key == "usage_usec" {
+			usage.CPUUsec, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	return usage, scanner.Err()
+}
+
+// CgroupDir resolves an OCI linux.cgroupsPath to its cgroup v2 directory.
+// Paths in systemd's "slice:prefix:name" form are not supported.
+func CgroupDir(cgroupsPath string) (string, bool) {
+	if !strings.HasPrefix(cgroupsPath, "/") {
+		return "", false
+	}
+	return filepath.Join(CgroupFSRoot, cgroupsPath), true
+}
+
+// procUsage reads the resident memory and CPU time of a single process
+// from /proc, for runtimes whose sandbox is one host process.
+func procUsage(pid int) (ResourceUsage, error) {
+	var usage ResourceUsage
+	// statm: size resident shared text lib data dt, in pages
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return usage, err
+	}
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return usage, fmt.Errorf("unexpected statm format")
+	}
+	rssPages, _ := strconv.ParseInt(fields[1], 10, 64)
+	usage.Memory = domain.Megabytes(rssPages * int64(os.Getpagesize()) / 1024 / 1024)
+
+	// stat: utime and stime are fields 14 and 15, in clock ticks. The
+	// command name may contain spaces, so count from its closing paren.
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return usage, err
+	}
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return usage, fmt.Errorf("unexpected stat format")
+	}
+	fields = strings.Fields(string(stat[end+1:]))
+	if len(fields) < 13 {
+		return usage, fmt.Errorf("unexpected stat format")
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	usage.CPUUsec = (utime + stime) * 1e6 / clockTicks
+	return usage, nil
+}
+
+// clockTicks is USER_HZ, which is 100 on every Linux platform we run on.
+const clockTicks = 100
@@ -0,0 +1,51 @@
+package tartarus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupUsage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"memory.current": "268435456\n",
+		"cpu.stat":       "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := CgroupUsage(dir)
+	if err != nil {
+		t.Fatalf("CgroupUsage: %v", err)
+	}
+	if usage.Memory != 256 || usage.CPUUsec != 1500000 {
+		t.Errorf("expected 256 MB and 1.5s of CPU, got %+v", usage)
+	}
+
+	if _, err := CgroupUsage(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without cgroup files")
+	}
+}
+
+func TestCgroupDir(t *testing.T) {
+	if dir, ok := CgroupDir("/tartarus/sb-1"); !ok || dir != "/sys/fs/cgroup/tartarus/sb-1" {
+		t.Errorf("unexpected cgroup dir %q", dir)
+	}
+	if _, ok := CgroupDir("system.slice:tartarus:sb-1"); ok {
+		t.Error("expected systemd cgroup paths to be unsupported")
+	}
+}
+
+func TestProcUsage(t *testing.T) {
+	usage, err := procUsage(os.Getpid())
+	if err != nil {
+		t.Skipf("no procfs: %v", err)
+	}
+	if usage.Memory == 0 {
+		t.Errorf("expected the test process to have resident memory, got %+v", usage)
+	}
+}
@@ -0,0 +1,247 @@
+package tartarus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WasmHostModule is the import module name of the Tartarus host functions.
+//
+// All functions return a negative status on failure:
+//
+//	artifact_read(name_ptr, name_len, buf_ptr, buf_len i32) i64
+//	    Copies up to buf_len bytes of the input artifact into the buffer and
+//	    returns its full size. Call with buf_len 0 to query the size.
+//	artifact_write(name_ptr, name_len, data_ptr, data_len i32) i32
+//	    Replaces the output artifact with the data. Returns 0.
+//	metric_emit(name_ptr, name_len i32, value f64) i32
+//	    Sets the user metric to value. Returns 0.
+//	log_emit(msg_ptr, msg_len i32) i32
+//	    Writes a log line to the agent log and the sandbox console. Returns 0.
+const WasmHostModule = "tartarus"
+
+// Host function status codes.
+const (
+	HostErrDenied   = -1 // Capability not granted
+	HostErrNotFound = -2 // Artifact does not exist
+	HostErrInvalid  = -3 // Bad name, out-of-bounds memory or I/O failure
+	HostErrQuota    = -4 // Write limit exceeded
+)
+
+const maxHostLogLine = 4096
+
+var (
+	artifactNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+	metricNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+)
+
+// ArtifactStore holds the input and output artifacts of Wasm sandboxes.
+type ArtifactStore interface {
+	ReadInput(ctx context.Context, id domain.SandboxID, name string) ([]byte, error)
+	WriteOutput(ctx context.Context, id domain.SandboxID, name string, data []byte) error
+}
+
+// DirArtifactStore keeps artifacts on the local filesystem under
+// Root/<sandbox>/in and Root/<sandbox>/out. Inputs are staged there before
+// launch; outputs are collected after the sandbox exits.
+type DirArtifactStore struct {
+	Root string
+}
+
+func (s *DirArtifactStore) ReadInput(ctx context.Context, id domain.SandboxID, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.Root, string(id), "in", name))
+}
+
+func (s *DirArtifactStore) WriteOutput(ctx context.Context, id domain.SandboxID, name string, data []byte) error {
+	dir := filepath.Join(s.Root, string(id), "out")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+type hostInstanceKey struct{}
+
+// hostInstance is the per-sandbox state the host functions run against.
+type hostInstance struct {
+	inst    *wasmInstance
+	caps    *domain.WasmCapabilities
+	console io.Writer
+	written int64
+}
+
+// ensureHostModules instantiates WASI and the Tartarus host module once per
+// wazero runtime. Host functions find their sandbox through the call context.
+func (w *WasmRuntime) ensureHostModules(ctx context.Context) error {
+	w.hostMu.Lock()
+	defer w.hostMu.Unlock()
+
+	if w.runtime.Module(wasi_snapshot_preview1.ModuleName) == nil {
+		if _, err := wasi_snapshot_preview1.Instantiate(ctx, w.runtime); err != nil {
+			return fmt.Errorf("failed to instantiate WASI: %w", err)
+		}
+	}
+	if w.runtime.Module(WasmHostModule) != nil {
+		return nil
+	}
+
+	_, err := w.runtime.NewHostModuleBuilder(WasmHostModule).
+		NewFunctionBuilder().WithFunc(w.artifactRead).Export("artifact_read").
+		NewFunctionBuilder().WithFunc(w.artifactWrite).Export("artifact_write").
+		NewFunctionBuilder().WithFunc(w.metricEmit).Export("metric_emit").
+		NewFunctionBuilder().WithFunc(w.logEmit).Export("log_emit").
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate %s host module: %w", WasmHostModule, err)
+	}
+	return nil
+}
+
+func (w *WasmRuntime) artifacts() ArtifactStore {
+	if w.Artifacts != nil {
+		return w.Artifacts
+	}
+	return &DirArtifactStore{Root: filepath.Join(w.WorkDir, "artifacts")}
+}
+
+func (w *WasmRuntime) artifactRead(ctx context.Context, m api.Module, namePtr, nameLen, bufPtr, bufLen uint32) int64 {
+	h, name, status := hostArtifactCall(ctx, m, namePtr, nameLen)
+	if status != 0 {
+		return int64(status)
+	}
+	if !h.caps.CanRead(name) {
+		w.deny(h, "artifact_read", name)
+		return HostErrDenied
+	}
+
+	data, err := w.artifacts().ReadInput(ctx, h.inst.ID, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return HostErrNotFound
+	}
+	if err != nil {
+		w.Logger.Error("Failed to read artifact", "id", h.inst.ID, "artifact", name, "error", err)
+		return HostErrInvalid
+	}
+
+	n := uint32(len(data))
+	if bufLen < n {
+		n = bufLen
+	}
+	if n > 0 && !m.Memory().Write(bufPtr, data[:n]) {
+		return HostErrInvalid
+	}
+	return int64(len(data))
+}
+
+func (w *WasmRuntime) artifactWrite(ctx context.Context, m api.Module, namePtr, nameLen, dataPtr, dataLen uint32) int32 {
+	h, name, status := hostArtifactCall(ctx, m, namePtr, nameLen)
+	if status != 0 {
+		return status
+	}
+	if !h.caps.CanWrite(name) {
+		w.deny(h, "artifact_write", name)
+		return HostErrDenied
+	}
+	if h.written+int64(dataLen) > h.caps.WriteLimit() {
+		return HostErrQuota
+	}
+
+	data, ok := m.Memory().Read(dataPtr, dataLen)
+	if !ok {
+		return HostErrInvalid
+	}
+	if err := w.artifacts().WriteOutput(ctx, h.inst.ID, name, data); err != nil {
+		w.Logger.Error("Failed to write artifact", "id", h.inst.ID, "artifact", name, "error", err)
+		return HostErrInvalid
+	}
+	h.written += int64(dataLen)
+	return 0
+}
+
+func (w *WasmRuntime) metricEmit(ctx context.Context, m api.Module, namePtr, nameLen uint32, value float64) int32 {
+	h, ok := ctx.Value(hostInstanceKey{}).(*hostInstance)
+	if !ok {
+		return HostErrDenied
+	}
+	if h.caps == nil || !h.caps.EmitMetrics {
+		w.deny(h, "metric_emit", "")
+		return HostErrDenied
+	}
+	if m.Memory() == nil {
+		return HostErrInvalid
+	}
+	name, ok := m.Memory().Read(namePtr, nameLen)
+	if !ok || !metricNamePattern.Match(name) {
+		return HostErrInvalid
+	}
+
+	if w.Metrics != nil {
+		w.Metrics.SetGauge("wasm_user_metric", value,
+			hermes.Label{Key: "user_metric", Value: string(name)},
+			hermes.Label{Key: "template", Value: string(h.inst.Request.Template)})
+	}
+	return 0
+}
+
+func (w *WasmRuntime) logEmit(ctx context.Context, m api.Module, msgPtr, msgLen uint32) int32 {
+	h, ok := ctx.Value(hostInstanceKey{}).(*hostInstance)
+	if !ok {
+		return HostErrDenied
+	}
+	if h.caps == nil || !h.caps.EmitLogs {
+		w.deny(h, "log_emit", "")
+		return HostErrDenied
+	}
+	if m.Memory() == nil {
+		return HostErrInvalid
+	}
+	if msgLen > maxHostLogLine {
+		msgLen = maxHostLogLine
+	}
+	msg, ok := m.Memory().Read(msgPtr, msgLen)
+	if !ok {
+		return HostErrInvalid
+	}
+
+	line := strings.TrimRight(string(msg), "\n")
+	w.Logger.Info("Wasm sandbox log", "id", h.inst.ID, "message", line)
+	fmt.Fprintf(h.console, "[tartarus] %s\n", line)
+	return 0
+}
+
+// hostArtifactCall resolves the calling sandbox and artifact name.
+func hostArtifactCall(ctx context.Context, m api.Module, namePtr, nameLen uint32) (*hostInstance, string, int32) {
+	h, ok := ctx.Value(hostInstanceKey{}).(*hostInstance)
+	if !ok {
+		return nil, "", HostErrDenied
+	}
+	if m.Memory() == nil {
+		return nil, "", HostErrInvalid
+	}
+	raw, ok := m.Memory().Read(namePtr, nameLen)
+	if !ok || !artifactNamePattern.Match(raw) {
+		return nil, "", HostErrInvalid
+	}
+	return h, string(raw), 0
+}
+
+func (w *WasmRuntime) deny(h *hostInstance, fn, name string) {
+	w.Logger.Info("Wasm host call denied", "id", h.inst.ID, "function", fn, "artifact", name)
+	if w.Metrics != nil {
+		w.Metrics.IncCounter("wasm_host_call_denied_total", 1,
+			hermes.Label{Key: "operation", Value: fn},
+			hermes.Label{Key: "template", Value: string(h.inst.Request.Template)})
+	}
+}
@@ -0,0 +1,130 @@
+package tartarus
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// copyArtifactModule returns a module whose _start reads the input artifact
+// "in.txt" into memory and writes what it read to the output "out.txt".
+func copyArtifactModule() []byte {
+	name := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+	section := func(id byte, payload []byte) []byte {
+		return append([]byte{id, byte(len(payload))}, payload...)
+	}
+
+	types := []byte{3,
+		0x60, 4, 0x7f, 0x7f, 0x7f, 0x7f, 1, 0x7f, // artifact_write
+		0x60, 4, 0x7f, 0x7f, 0x7f, 0x7f, 1, 0x7e, // artifact_read
+		0x60, 0, 0, // _start
+	}
+
+	imports := []byte{2}
+	imports = append(imports, name(WasmHostModule)...)
+	imports = append(imports, name("artifact_read")...)
+	imports = append(imports, 0x00, 1)
+	imports = append(imports, name(WasmHostModule)...)
+	imports = append(imports, name("artifact_write")...)
+	imports = append(imports, 0x00, 0)
+
+	exports := []byte{2}
+	exports = append(exports, name("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	exports = append(exports, name("_start")...)
+	exports = append(exports, 0x00, 2)
+
+	body := []byte{0x00,
+		0x41, 16, 0x41, 7, 0x41, 0xc0, 0x00, // out name, buffer at 64
+		0x41, 0, 0x41, 6, 0x41, 0xc0, 0x00, 0x41, 32, // artifact_read("in.txt", 64, 32)
+		0x10, 0, // call artifact_read
+		0xa7,    // i32.wrap_i64
+		0x10, 1, // call artifact_write
+		0x1a, // drop
+		0x0b,
+	}
+
+	data := []byte{2}
+	data = append(data, 0x00, 0x41, 0, 0x0b)
+	data = append(data, name("in.txt")...)
+	data = append(data, 0x00, 0x41, 16, 0x0b)
+	data = append(data, name("out.txt")...)
+
+	wasm := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	wasm = append(wasm, section(1, types)...)
+	wasm = append(wasm, section(2, imports)...)
+	wasm = append(wasm, section(3, []byte{1, 2})...)
+	wasm = append(wasm, section(5, []byte{1, 0x00, 1})...)
+	wasm = append(wasm, section(7, exports)...)
+	wasm = append(wasm, section(10, append([]byte{1, byte(len(body))}, body...))...)
+	wasm = append(wasm, section(11, data)...)
+	return wasm
+}
+
+func TestWasmRuntime_HostArtifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+	modulePath := filepath.Join(tmpDir, "copy.wasm")
+	if err := os.WriteFile(modulePath, copyArtifactModule(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := NewWasmRuntime(slog.New(slog.NewTextHandler(os.Stdout, nil)), tmpDir)
+	store := &DirArtifactStore{Root: filepath.Join(tmpDir, "artifacts")}
+	ctx := context.Background()
+
+	run := func(id domain.SandboxID, caps *domain.WasmCapabilities) string {
+		t.Helper()
+		inDir := filepath.Join(store.Root, string(id), "in")
+		if err := os.MkdirAll(inDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(inDir, "in.txt"), []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		req := &domain.SandboxRequest{ID: id, Template: "wasm-test", Wasm: caps}
+		if _, err := rt.Launch(ctx, req, VMConfig{Snapshot: domain.SnapshotRef{Path: modulePath}}); err != nil {
+			t.Fatalf("Launch failed: %v", err)
+		}
+		if err := rt.Wait(ctx, id); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+		out, err := os.ReadFile(filepath.Join(store.Root, string(id), "out", "out.txt"))
+		if os.IsNotExist(err) {
+			return ""
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+
+	// Both host modules are shared by consecutive runs
+	if got := run("granted", &domain.WasmCapabilities{ReadArtifacts: []string{"in.txt"}, WriteArtifacts: []string{"*"}}); got != "hello" {
+		t.Errorf("Expected output artifact %q, got %q", "hello", got)
+	}
+	if got := run("denied", nil); got != "" {
+		t.Errorf("Expected no output without grants, got %q", got)
+	}
+	if got := run("read-only", &domain.WasmCapabilities{ReadArtifacts: []string{"in.txt"}}); got != "" {
+		t.Errorf("Expected write to be denied, got %q", got)
+	}
+}
+
+func TestWasmCapabilities(t *testing.T) {
+	var none *domain.WasmCapabilities
+	if none.CanRead("a") || none.CanWrite("a") {
+		t.Error("nil capabilities must deny everything")
+	}
+	caps := &domain.WasmCapabilities{ReadArtifacts: []string{"a"}, MaxArtifactBytes: 10}
+	if !caps.CanRead("a") || caps.CanRead("b") || caps.CanWrite("a") {
+		t.Error("unexpected grant evaluation")
+	}
+	if caps.WriteLimit() != 10 || none.WriteLimit() != domain.DefaultMaxArtifactBytes {
+		t.Error("unexpected write limit")
+	}
+}
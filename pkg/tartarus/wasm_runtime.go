@@ -2,6 +2,7 @@ package tartarus
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -11,8 +12,9 @@ import (
 	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
 	"github.com/tetratelabs/wazero"
-	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 // WasmRuntime implements SandboxRuntime using WebAssembly (wazero).
@@ -26,6 +28,20 @@ type WasmRuntime struct {
 	// instances tracks active WASM executions
 	instances sync.Map // domain.SandboxID -> *wasmInstance
 
+	// TailBytes limits the stdout/stderr tail kept in the run result
+	// (domain.DefaultResultTailBytes if zero).
+	TailBytes int
+
+	// Artifacts backs the artifact host functions
+	// (a DirArtifactStore under WorkDir/artifacts if nil).
+	Artifacts ArtifactStore
+
+	// Metrics receives user metrics emitted through the host functions (optional).
+	Metrics hermes.Metrics
+
+	// hostMu guards instantiation of the shared WASI and host modules
+	hostMu sync.Mutex
+
 	// runtime is the wazero runtime instance
 	runtime wazero.Runtime
 }
@@ -40,6 +56,9 @@ type wasmInstance struct {
 	LogPath    string
 	ModulePath string
 	Cancel     context.CancelFunc
+	Stdout     *TailBuffer
+	Stderr     *TailBuffer
+	PeakMemory domain.Megabytes
 	mu         sync.Mutex
 }
 
@@ -91,6 +110,8 @@ func (w *WasmRuntime) Launch(ctx context.Context, req *domain.SandboxRequest, cf
 		StartedAt:  time.Now(),
 		LogPath:    logPath,
 		ModulePath: modulePath,
+		Stdout:     NewTailBuffer(w.TailBytes),
+		Stderr:     NewTailBuffer(w.TailBytes),
 	}
 
 	// Store instance
@@ -143,21 +164,23 @@ func (w *WasmRuntime) runWasmModule(ctx context.Context, inst *wasmInstance) int
 		return 1
 	}
 
-	// Create module config with WASI
+	// Create module config with WASI. Output goes to the console log and to
+	// the tails reported in the run result.
+	logWriter := w.getLogWriter(inst.LogPath)
 	config := wazero.NewModuleConfig().
-		WithStdout(w.getLogWriter(inst.LogPath)).
-		WithStderr(w.getLogWriter(inst.LogPath)).
+		WithStdout(io.MultiWriter(logWriter, inst.Stdout)).
+		WithStderr(io.MultiWriter(logWriter, inst.Stderr)).
 		WithArgs(append([]string{inst.ModulePath}, inst.Request.Args...)...).
-		WithStartFunctions("_start")
+		WithStartFunctions()
 
 	// Add environment variables
 	for k, v := range inst.Request.Env {
 		config = config.WithEnv(k, v)
 	}
 
-	// Instantiate WASI
-	if _, err := wasi_snapshot_preview1.Instantiate(ctx, w.runtime); err != nil {
-		w.Logger.Error("Failed to instantiate WASI", "error", err)
+	// Instantiate WASI and the capability-scoped Tartarus host functions
+	if err := w.ensureHostModules(ctx); err != nil {
+		w.Logger.Error("Failed to instantiate host modules", "error", err)
 		return 1
 	}
 
@@ -169,9 +192,34 @@ func (w *WasmRuntime) runWasmModule(ctx context.Context, inst *wasmInstance) int
 	}
 	defer mod.Close(ctx)
 
-	// Module execution happens during instantiation with _start
-	// If we reached here, execution completed successfully
-	return 0
+	start := mod.ExportedFunction("_start")
+	if start == nil {
+		w.Logger.Error("WASM module has no _start export", "path", inst.ModulePath)
+		return 1
+	}
+	_, err = start.Call(context.WithValue(ctx, hostInstanceKey{}, &hostInstance{
+		inst:    inst,
+		caps:    inst.Request.Wasm,
+		console: logWriter,
+	}))
+
+	// Linear memory only grows, so its final size is the peak
+	if mem := mod.Memory(); mem != nil {
+		inst.mu.Lock()
+		inst.PeakMemory = domain.Megabytes((uint64(mem.Size()) + 1<<20 - 1) >> 20)
+		inst.mu.Unlock()
+	}
+
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return int(exitErr.ExitCode())
+	default:
+		w.Logger.Error("WASM module trapped", "error", err)
+		return 1
+	}
 }
 
 func (w *WasmRuntime) getLogWriter(logPath string) io.Writer {
@@ -204,20 +252,36 @@ func (w *WasmRuntime) Inspect(ctx context.Context, id domain.SandboxID) (*domain
 	}
 
 	return &domain.SandboxRun{
-		ID:         inst.ID,
-		RequestID:  inst.Request.ID,
-		NodeID:     inst.Request.NodeID,
-		Template:   inst.Request.Template,
-		Status:     status,
-		ExitCode:   inst.ExitCode,
-		StartedAt:  inst.StartedAt,
-		FinishedAt: inst.FinishedAt,
-		CreatedAt:  inst.StartedAt,
-		UpdatedAt:  time.Now(),
-		Metadata:   inst.Request.Metadata,
+		ID:          inst.ID,
+		RequestID:   inst.Request.ID,
+		NodeID:      inst.Request.NodeID,
+		Template:    inst.Request.Template,
+		Status:      status,
+		ExitCode:    inst.ExitCode,
+		StartedAt:   inst.StartedAt,
+		FinishedAt:  inst.FinishedAt,
+		CreatedAt:   inst.StartedAt,
+		UpdatedAt:   time.Now(),
+		MemoryUsage: inst.PeakMemory,
+		Result:      inst.result(),
+		Metadata:    inst.Request.Metadata,
 	}, nil
 }
 
+// result summarizes a finished instance. Callers must hold inst.mu.
+func (inst *wasmInstance) result() *domain.RunResult {
+	if inst.ExitCode == nil {
+		return nil
+	}
+	return &domain.RunResult{
+		StdoutTail:      inst.Stdout.String(),
+		StderrTail:      inst.Stderr.String(),
+		OutputTruncated: inst.Stdout.Truncated() || inst.Stderr.Truncated(),
+		DurationMs:      inst.FinishedAt.Sub(inst.StartedAt).Milliseconds(),
+		PeakMemory:      inst.PeakMemory,
+	}
+}
+
 // List returns all active WASM sandboxes.
 func (w *WasmRuntime) List(ctx context.Context) ([]domain.SandboxRun, error) {
 	var runs []domain.SandboxRun
@@ -0,0 +1,216 @@
+package thanatos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/hermes"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+const (
+	// DefaultCrashBundleMaxFileBytes caps each guest file in a crash bundle
+	// when the policy sets no limit.
+	DefaultCrashBundleMaxFileBytes = 1 << 20
+
+	// DefaultCrashBundleTimeout bounds how long collection may delay a kill.
+	DefaultCrashBundleTimeout = 60 * time.Second
+)
+
+// CrashBundle is the manifest of a crash bundle. It is stored next to the
+// collected objects, and its key is linked from the run record.
+type CrashBundle struct {
+	SandboxID    domain.SandboxID  `json:"sandbox_id"`
+	Template     domain.TemplateID `json:"template,omitempty"`
+	Reason       string            `json:"reason"`
+	ConsoleLog   string            `json:"console_log,omitempty"`   // Erebus key of the console log
+	Files        map[string]string `json:"files,omitempty"`         // Guest path -> Erebus key
+	Truncated    []string          `json:"truncated,omitempty"`     // Guest paths cut at the size cap
+	SnapshotMem  string            `json:"snapshot_mem,omitempty"`  // Erebus key of the memory snapshot
+	SnapshotDisk string            `json:"snapshot_disk,omitempty"` // Erebus key of the disk snapshot
+	Errors       []string          `json:"errors,omitempty"`        // Parts that could not be collected
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// CrashBundler collects crash bundles from sandboxes that are about to be
+// destroyed, so their state can be inspected after the fact.
+type CrashBundler struct {
+	Runtime tartarus.SandboxRuntime
+	Store   erebus.Store
+	WorkDir string        // Scratch space for snapshot files
+	Timeout time.Duration // Upper bound on one collection (DefaultCrashBundleTimeout if zero)
+	Metrics hermes.Metrics
+	now     func() time.Time
+}
+
+// NewCrashBundler creates a crash bundler that stores bundles in store.
+func NewCrashBundler(runtime tartarus.SandboxRuntime, store erebus.Store, workDir string) *CrashBundler {
+	if workDir == "" {
+		workDir = os.TempDir()
+	}
+	return &CrashBundler{
+		Runtime: runtime,
+		Store:   store,
+		WorkDir: workDir,
+		Timeout: DefaultCrashBundleTimeout,
+		Metrics: hermes.NewNoopMetrics(),
+		now:     time.Now,
+	}
+}
+
+// CrashBundlePrefix returns the Erebus key prefix of a sandbox's crash bundle.
+func CrashBundlePrefix(id domain.SandboxID) string {
+	return fmt.Sprintf("crash-bundles/%s/", id)
+}
+
+// Collect gathers the guest files, console log and, if the policy asks for
+// it, a final snapshot of a running sandbox, and writes the bundle manifest.
+// Guest files are read first, while the guest still runs; the snapshot is
+// taken last because it pauses the VM. A part that cannot be collected is
+// recorded in the manifest rather than failing the bundle, so an error is
+// only returned when the manifest itself could not be stored.
+func (b *CrashBundler) Collect(ctx context.Context, id domain.SandboxID, template domain.TemplateID, reason string, policy *domain.CrashBundlePolicy) (string, *CrashBundle, error) {
+	if !policy.Enabled() {
+		return "", nil, fmt.Errorf("crash bundles are not enabled for sandbox %s", id)
+	}
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCrashBundleTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := b.now()
+	prefix := CrashBundlePrefix(id)
+	bundle := &CrashBundle{
+		SandboxID: id,
+		Template:  template,
+		Reason:    reason,
+		CreatedAt: start,
+	}
+
+	b.collectFiles(ctx, id, prefix, policy, bundle)
+
+	var console bytes.Buffer
+	if err := b.Runtime.StreamLogs(ctx, id, &console, false); err != nil {
+		bundle.Errors = append(bundle.Errors, fmt.Sprintf("console log: %v", err))
+	} else if err := b.Store.Put(ctx, prefix+"console.log", &console); err != nil {
+		bundle.Errors = append(bundle.Errors, fmt.Sprintf("console log: %v", err))
+	} else {
+		bundle.ConsoleLog = prefix + "console.log"
+	}
+
+	if policy.Snapshot {
+		if err := b.collectSnapshot(ctx, id, prefix, bundle); err != nil {
+			bundle.Errors = append(bundle.Errors, fmt.Sprintf("snapshot: %v", err))
+		}
+	}
+
+	manifest, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		b.record("failed", start)
+		return "", bundle, fmt.Errorf("failed to encode crash bundle manifest: %w", err)
+	}
+	key := prefix + "manifest.json"
+	if err := b.Store.Put(ctx, key, bytes.NewReader(manifest)); err != nil {
+		b.record("failed", start)
+		return "", bundle, fmt.Errorf("failed to store crash bundle manifest: %w", err)
+	}
+
+	if len(bundle.Errors) > 0 {
+		b.record("partial", start)
+	} else {
+		b.record("complete", start)
+	}
+	return key, bundle, nil
+}
+
+// collectFiles reads each guest path with head, stopping one byte past the
+// cap so truncation can be detected without transferring the whole file.
+func (b *CrashBundler) collectFiles(ctx context.Context, id domain.SandboxID, prefix string, policy *domain.CrashBundlePolicy, bundle *CrashBundle) {
+	limit := policy.MaxFileBytes
+	if limit <= 0 {
+		limit = DefaultCrashBundleMaxFileBytes
+	}
+	for _, p := range policy.Paths {
+		if !path.IsAbs(p) {
+			bundle.Errors = append(bundle.Errors, fmt.Sprintf("%s: not an absolute path", p))
+			continue
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := []string{"head", "-c", strconv.FormatInt(limit+1, 10), "--", p}
+		if err := b.Runtime.Exec(ctx, id, cmd, &stdout, &stderr); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			bundle.Errors = append(bundle.Errors, fmt.Sprintf("%s: %v", p, err))
+			continue
+		}
+		if int64(stdout.Len()) > limit {
+			stdout.Truncate(int(limit))
+			bundle.Truncated = append(bundle.Truncated, p)
+		}
+
+		key := prefix + "files/" + strings.TrimPrefix(path.Clean(p), "/")
+		if err := b.Store.Put(ctx, key, &stdout); err != nil {
+			bundle.Errors = append(bundle.Errors, fmt.Sprintf("%s: %v", p, err))
+			continue
+		}
+		if bundle.Files == nil {
+			bundle.Files = make(map[string]string)
+		}
+		bundle.Files[p] = key
+	}
+}
+
+// collectSnapshot snapshots the VM into the work directory and uploads the
+// memory and disk files.
+func (b *CrashBundler) collectSnapshot(ctx context.Context, id domain.SandboxID, prefix string, bundle *CrashBundle) error {
+	tmpDir, err := os.MkdirTemp(b.WorkDir, "crash-bundle-*")
+	if err != nil {
+		return fmt.Errorf("failed to create work dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	memPath := filepath.Join(tmpDir, "snapshot.mem")
+	diskPath := filepath.Join(tmpDir, "snapshot.disk")
+	if err := b.Runtime.CreateSnapshot(ctx, id, memPath, diskPath); err != nil {
+		return err
+	}
+	if err := b.upload(ctx, memPath, prefix+"snapshot.mem"); err != nil {
+		return err
+	}
+	bundle.SnapshotMem = prefix + "snapshot.mem"
+	if err := b.upload(ctx, diskPath, prefix+"snapshot.disk"); err != nil {
+		return err
+	}
+	bundle.SnapshotDisk = prefix + "snapshot.disk"
+	return nil
+}
+
+func (b *CrashBundler) upload(ctx context.Context, file, key string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := b.Store.Put(ctx, key, f); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *CrashBundler) record(result string, start time.Time) {
+	b.Metrics.IncCounter("thanatos_crash_bundles_total", 1, hermes.Label{Key: "result", Value: result})
+	b.Metrics.ObserveHistogram("thanatos_crash_bundle_seconds", b.now().Sub(start).Seconds())
+}
@@ -0,0 +1,99 @@
+package thanatos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+	"github.com/tartarus-sandbox/tartarus/pkg/erebus"
+	"github.com/tartarus-sandbox/tartarus/pkg/tartarus"
+)
+
+// guestRuntime serves `head -c N -- path` from an in-memory guest filesystem.
+type guestRuntime struct {
+	*tartarus.MockRuntime
+	files map[string]string
+}
+
+func (g *guestRuntime) Exec(ctx context.Context, id domain.SandboxID, cmd []string, stdout, stderr io.Writer) error {
+	n, _ := strconv.Atoi(cmd[2])
+	content, ok := g.files[cmd[4]]
+	if !ok {
+		io.WriteString(stderr, "head: "+cmd[4]+": No such file or directory")
+		return errors.New("exit status 1")
+	}
+	if len(content) > n {
+		content = content[:n]
+	}
+	_, err := io.WriteString(stdout, content)
+	return err
+}
+
+func readKey(t *testing.T, store erebus.Store, key string) string {
+	t.Helper()
+	rc, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCrashBundler_Collect(t *testing.T) {
+	ctx := context.Background()
+	runtime := &guestRuntime{
+		MockRuntime: tartarus.NewMockRuntime(slog.Default()),
+		files: map[string]string{
+			"/var/log/app.log": "panic: out of cheese",
+			"/tmp/big":         "0123456789",
+		},
+	}
+	req := &domain.SandboxRequest{ID: "crash-1", Template: "tpl"}
+	_, err := runtime.Launch(ctx, req, tartarus.VMConfig{CPUs: 1, MemoryMB: 64})
+	require.NoError(t, err)
+
+	store, err := erebus.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	bundler := NewCrashBundler(runtime, store, t.TempDir())
+
+	key, bundle, err := bundler.Collect(ctx, req.ID, req.Template, "runtime_exceeded", &domain.CrashBundlePolicy{
+		Snapshot:     true,
+		Paths:        []string{"/var/log/app.log", "/tmp/big", "/missing", "relative"},
+		MaxFileBytes: 4,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "crash-bundles/crash-1/manifest.json", key)
+
+	assert.Equal(t, "pani", readKey(t, store, bundle.Files["/var/log/app.log"]))
+	assert.Equal(t, "0123", readKey(t, store, "crash-bundles/crash-1/files/tmp/big"))
+	assert.ElementsMatch(t, []string{"/var/log/app.log", "/tmp/big"}, bundle.Truncated)
+	assert.Contains(t, readKey(t, store, bundle.ConsoleLog), "mock logs for crash-1")
+	assert.NotEmpty(t, bundle.SnapshotMem)
+	assert.NotEmpty(t, bundle.SnapshotDisk)
+
+	// Missing and invalid paths are recorded without failing the bundle
+	require.Len(t, bundle.Errors, 2)
+	assert.Contains(t, bundle.Errors[0], "No such file or directory")
+	assert.Contains(t, bundle.Errors[1], "not an absolute path")
+
+	var manifest CrashBundle
+	require.NoError(t, json.Unmarshal([]byte(readKey(t, store, key)), &manifest))
+	assert.Equal(t, "runtime_exceeded", manifest.Reason)
+	assert.Equal(t, bundle.Files, manifest.Files)
+}
+
+func TestCrashBundler_RequiresPolicy(t *testing.T) {
+	store, err := erebus.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	bundler := NewCrashBundler(tartarus.NewMockRuntime(slog.Default()), store, t.TempDir())
+
+	_, _, err = bundler.Collect(context.Background(), "crash-2", "tpl", "policy_breach", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,279 @@
+package themis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+// ErrPolicyNotFound is returned by GetScopedPolicy when no policy is stored
+// at the requested scope.
+var ErrPolicyNotFound = errors.New("policy not found")
+
+// ErrPolicyExists is returned by RestorePolicy when a policy was stored at
+// the scope after the deleted one.
+var ErrPolicyExists = errors.New("policy already exists")
+
+// Level is a layer of the policy hierarchy, from least to most specific.
+type Level int
+
+const (
+	LevelGlobal Level = iota
+	LevelTenant
+	LevelTemplate
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelGlobal:
+		return "global"
+	case LevelTenant:
+		return "tenant"
+	default:
+		return "template"
+	}
+}
+
+// Scope identifies where a policy sits in the hierarchy.
+type Scope struct {
+	TenantID   string
+	TemplateID domain.TemplateID
+}
+
+// GlobalScope is the scope of the global default policy.
+var GlobalScope = Scope{}
+
+// TenantScope returns the scope of a tenant-level policy.
+func TenantScope(tenantID string) Scope { return Scope{TenantID: tenantID} }
+
+// TemplateScope returns the scope of a template-level policy.
+func TemplateScope(tplID domain.TemplateID) Scope { return Scope{TemplateID: tplID} }
+
+// ScopeOf returns the scope a policy is stored at.
+func ScopeOf(p *domain.SandboxPolicy) Scope {
+	return Scope{TenantID: p.TenantID, TemplateID: p.TemplateID}
+}
+
+// Level returns the hierarchy level of the scope.
+func (s Scope) Level() Level {
+	switch {
+	case s.TemplateID != "":
+		return LevelTemplate
+	case s.TenantID != "":
+		return LevelTenant
+	default:
+		return LevelGlobal
+	}
+}
+
+// Validate rejects scopes that target both a tenant and a template.
+func (s Scope) Validate() error {
+	if s.TenantID != "" && s.TemplateID != "" {
+		return fmt.Errorf("policy may target a tenant or a template, not both (tenant %q, template %q)", s.TenantID, s.TemplateID)
+	}
+	return nil
+}
+
+// key is the storage key suffix for the scope. Template policies keep the
+// bare template ID so existing keys remain valid.
+func (s Scope) key() string {
+	switch s.Level() {
+	case LevelTemplate:
+		return string(s.TemplateID)
+	case LevelTenant:
+		return "@tenant:" + s.TenantID
+	default:
+		return "@global"
+	}
+}
+
+// DefaultPolicy is the built-in lockdown policy that sits beneath the
+// global layer. It also applies on its own when nothing is configured.
+func DefaultPolicy(tplID domain.TemplateID) *domain.SandboxPolicy {
+	return &domain.SandboxPolicy{
+		ID:         domain.PolicyID("lockdown-default"),
+		TemplateID: tplID,
+		Resources: domain.ResourceSpec{
+			CPU: 1000, // 1 CPU core (1000 milliCPU)
+			Mem: 128,  // 128 MB
+		},
+		NetworkPolicy: domain.NetworkPolicyRef{
+			ID:   "lockdown-no-net",
+			Name: "No Internet",
+		},
+		Tags: map[string]string{
+			"type": "default-lockdown",
+		},
+	}
+}
+
+// EffectivePolicy is the result of merging every layer that applies to a
+// (tenant, template) pair.
+type EffectivePolicy struct {
+	TenantID   string                  `json:"tenant_id,omitempty"`
+	TemplateID domain.TemplateID       `json:"template_id"`
+	Policy     *domain.SandboxPolicy   `json:"policy"`
+	Layers     []*domain.SandboxPolicy `json:"layers"` // Least to most specific, built-in default first
+}
+
+// Resolve computes the effective policy for tenantID and tplID by merging the
+// built-in default, the global policy, the tenant policy and the template
+// policy, in that order. Missing layers are skipped.
+func Resolve(ctx context.Context, repo Repository, tenantID string, tplID domain.TemplateID) (*EffectivePolicy, error) {
+	scopes := []Scope{GlobalScope}
+	if tenantID != "" {
+		scopes = append(scopes, TenantScope(tenantID))
+	}
+	if tplID != "" {
+		scopes = append(scopes, TemplateScope(tplID))
+	}
+
+	layers := []*domain.SandboxPolicy{DefaultPolicy(tplID)}
+	for _, scope := range scopes {
+		p, err := repo.GetScopedPolicy(ctx, scope)
+		if errors.Is(err, ErrPolicyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s policy: %w", scope.Level(), err)
+		}
+		layers = append(layers, p)
+	}
+
+	policy := Merge(layers...)
+	policy.TenantID = tenantID
+	policy.TemplateID = tplID
+	return &EffectivePolicy{
+		TenantID:   tenantID,
+		TemplateID: tplID,
+		Policy:     policy,
+		Layers:     layers,
+	}, nil
+}
+
+// ResolveRequest resolves the effective policy for req, using the tenant of
+// its authenticated submitter if any.
+func ResolveRequest(ctx context.Context, repo Repository, req *domain.SandboxRequest) (*EffectivePolicy, error) {
+	var tenantID string
+	if req.Submitter != nil {
+		tenantID = req.Submitter.TenantID
+	}
+	return Resolve(ctx, repo, tenantID, req.Template)
+}
+
+// Merge folds layers from least to most specific into a new policy. For each
+// field a later layer wins when it sets a non-zero value:
+//
+//   - resources: CPU, memory, GPU, TTL and profile are merged individually
+//   - network: replaced when the later layer names a network policy
+//   - retention: replaced as a whole when the later layer sets any field
+//   - run window: queue and completion limits are merged individually
+//   - wasm capabilities: replaced as a whole when the later layer sets them
+//   - quota: limits merged individually
+//   - integrity: replaced as a whole when the later layer sets it
+//   - hibernation: replaced as a whole when the later layer sets it
+//   - tags: merged key by key
+//   - entitlements: granted SKUs accumulate across layers
+//
+// ID and Version are taken from the most specific layer. The inputs are not
+// modified.
+func Merge(layers ...*domain.SandboxPolicy) *domain.SandboxPolicy {
+	out := &domain.SandboxPolicy{}
+	for _, l := range layers {
+		if l == nil {
+			continue
+		}
+		out.ID = l.ID
+		out.TenantID = l.TenantID
+		out.TemplateID = l.TemplateID
+		out.Version = l.Version
+
+		if l.Resources.CPU != 0 {
+			out.Resources.CPU = l.Resources.CPU
+		}
+		if l.Resources.Mem != 0 {
+			out.Resources.Mem = l.Resources.Mem
+		}
+		if l.Resources.GPU.Count != 0 || l.Resources.GPU.Type != "" {
+			out.Resources.GPU = l.Resources.GPU
+		}
+		if l.Resources.TTL != 0 {
+			out.Resources.TTL = l.Resources.TTL
+		}
+		if l.Resources.Profile != "" {
+			out.Resources.Profile = l.Resources.Profile
+		}
+
+		if l.NetworkPolicy.ID != "" {
+			out.NetworkPolicy = l.NetworkPolicy
+		}
+		if l.Retention != (domain.RetentionPolicy{}) {
+			out.Retention = l.Retention
+		}
+		if l.RunWindow.MaxQueueTime != 0 {
+			out.RunWindow.MaxQueueTime = l.RunWindow.MaxQueueTime
+		}
+		if l.RunWindow.MaxCompletionTime != 0 {
+			out.RunWindow.MaxCompletionTime = l.RunWindow.MaxCompletionTime
+		}
+
+		if l.Wasm != nil {
+			out.Wasm = l.Wasm
+		}
+		if l.Integrity != nil {
+			out.Integrity = l.Integrity
+		}
+		if l.CrashBundle != nil {
+			out.CrashBundle = l.CrashBundle
+		}
+		if l.Escalation != nil {
+			out.Escalation = l.Escalation
+		}
+		if l.Hooks != nil {
+			out.Hooks = l.Hooks
+		}
+		if l.Swap != nil {
+			out.Swap = l.Swap
+		}
+		if l.Retry != nil {
+			out.Retry = l.Retry
+		}
+		if l.Outputs != nil {
+			out.Outputs = l.Outputs
+		}
+		if l.Hibernation != nil {
+			out.Hibernation = l.Hibernation
+		}
+		if l.Premium != nil {
+			out.Premium = l.Premium
+		}
+		for _, sku := range l.Entitlements {
+			if !slices.Contains(out.Entitlements, sku) {
+				out.Entitlements = append(out.Entitlements, sku)
+			}
+		}
+		if l.Limits != nil {
+			limits := domain.ProcessLimits{}.Override(out.Limits).Override(l.Limits)
+			out.Limits = &limits
+		}
+		if l.Quota != nil {
+			q := domain.ResourceQuota{}
+			if out.Quota != nil {
+				q = *out.Quota
+			}
+			q = q.Override(*l.Quota)
+			out.Quota = &q
+		}
+
+		for k, v := range l.Tags {
+			if out.Tags == nil {
+				out.Tags = make(map[string]string)
+			}
+			out.Tags[k] = v
+		}
+	}
+	return out
+}
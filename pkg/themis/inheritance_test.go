@@ -0,0 +1,152 @@
+package themis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/tartarus-sandbox/tartarus/pkg/domain"
+)
+
+func seedHierarchy(t *testing.T, repo Repository) {
+	t.Helper()
+	ctx := context.Background()
+	policies := []*domain.SandboxPolicy{
+		{
+			ID:            "global",
+			Resources:     domain.ResourceSpec{CPU: 2000, Mem: 512, TTL: time.Hour},
+			NetworkPolicy: domain.NetworkPolicyRef{ID: "egress-proxy"},
+			Retention:     domain.RetentionPolicy{MaxAge: 24 * time.Hour},
+			Tags:          map[string]string{"owner": "platform", "tier": "standard"},
+		},
+		{
+			ID:        "tenant-acme",
+			TenantID:  "acme",
+			Resources: domain.ResourceSpec{Mem: 2048},
+			RunWindow: domain.RunWindowPolicy{MaxQueueTime: 10 * time.Minute},
+			Tags:      map[string]string{"tier": "gold"},
+		},
+		{
+			ID:         "tpl-python",
+			TemplateID: "python",
+			Resources:  domain.ResourceSpec{CPU: 4000},
+			Retention:  domain.RetentionPolicy{KeepOutputs: true},
+		},
+	}
+	for _, p := range policies {
+		if err := repo.UpsertPolicy(ctx, p); err != nil {
+			t.Fatalf("Upsert %s failed: %v", p.ID, err)
+		}
+	}
+}
+
+func TestResolve_Precedence(t *testing.T) {
+	s := miniredis.RunT(t)
+	redisRepo, err := NewRedisRepo(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+
+	for name, repo := range map[string]Repository{"memory": NewMemoryRepo(), "redis": redisRepo} {
+		t.Run(name, func(t *testing.T) {
+			seedHierarchy(t, repo)
+			ctx := context.Background()
+
+			eff, err := Resolve(ctx, repo, "acme", "python")
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			p := eff.Policy
+			if len(eff.Layers) != 4 {
+				t.Fatalf("expected default+global+tenant+template layers, got %d", len(eff.Layers))
+			}
+			if p.ID != "tpl-python" || p.TenantID != "acme" || p.TemplateID != "python" {
+				t.Errorf("unexpected identity: id=%s tenant=%s template=%s", p.ID, p.TenantID, p.TemplateID)
+			}
+			if p.Resources.CPU != 4000 {
+				t.Errorf("template CPU should win, got %d", p.Resources.CPU)
+			}
+			if p.Resources.Mem != 2048 {
+				t.Errorf("tenant memory should win over global, got %d", p.Resources.Mem)
+			}
+			if p.Resources.TTL != time.Hour {
+				t.Errorf("global TTL should be inherited, got %v", p.Resources.TTL)
+			}
+			if p.NetworkPolicy.ID != "egress-proxy" {
+				t.Errorf("global network should override built-in lockdown, got %s", p.NetworkPolicy.ID)
+			}
+			if p.Retention != (domain.RetentionPolicy{KeepOutputs: true}) {
+				t.Errorf("template retention should replace global as a whole, got %+v", p.Retention)
+			}
+			if p.RunWindow.MaxQueueTime != 10*time.Minute {
+				t.Errorf("tenant run window should be inherited, got %v", p.RunWindow.MaxQueueTime)
+			}
+			want := map[string]string{"type": "default-lockdown", "owner": "platform", "tier": "gold"}
+			for k, v := range want {
+				if p.Tags[k] != v {
+					t.Errorf("tag %s = %q, want %q", k, p.Tags[k], v)
+				}
+			}
+
+			// Another tenant only sees global and template layers
+			other, err := Resolve(ctx, repo, "globex", "python")
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if other.Policy.Resources.Mem != 512 || len(other.Layers) != 3 {
+				t.Errorf("expected global memory without tenant layer, got %d (%d layers)", other.Policy.Resources.Mem, len(other.Layers))
+			}
+
+			// Template-level lookups are unchanged
+			tpl, err := repo.GetPolicy(ctx, "python")
+			if err != nil || tpl.ID != "tpl-python" {
+				t.Errorf("GetPolicy returned %+v, %v", tpl, err)
+			}
+		})
+	}
+}
+
+func TestResolve_DefaultsWhenEmpty(t *testing.T) {
+	eff, err := Resolve(context.Background(), NewMemoryRepo(), "acme", "python")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if eff.Policy.ID != "lockdown-default" || eff.Policy.Resources.CPU != 1000 || eff.Policy.NetworkPolicy.ID != "lockdown-no-net" {
+		t.Errorf("expected built-in lockdown policy, got %+v", eff.Policy)
+	}
+}
+
+func TestUpsertPolicy_RejectsTenantAndTemplate(t *testing.T) {
+	err := NewMemoryRepo().UpsertPolicy(context.Background(), &domain.SandboxPolicy{ID: "bad", TenantID: "acme", TemplateID: "python"})
+	if err == nil {
+		t.Fatal("expected error for policy targeting both tenant and template")
+	}
+}
+
+func TestMerge_DoesNotModifyLayers(t *testing.T) {
+	base := &domain.SandboxPolicy{Tags: map[string]string{"a": "1"}}
+	top := &domain.SandboxPolicy{Tags: map[string]string{"a": "2"}}
+	merged := Merge(base, top)
+	if merged.Tags["a"] != "2" || base.Tags["a"] != "1" {
+		t.Errorf("unexpected merge: merged=%v base=%v", merged.Tags, base.Tags)
+	}
+}
+
+func TestMerge_ProcessLimitsPerField(t *testing.T) {
+	global := &domain.SandboxPolicy{Limits: &domain.ProcessLimits{MaxPIDs: 512, CoreDumps: domain.CoreDumpsDisabled}}
+	tenant := &domain.SandboxPolicy{Limits: &domain.ProcessLimits{MaxOpenFiles: 8192}}
+	merged := Merge(global, tenant)
+	want := domain.ProcessLimits{MaxPIDs: 512, MaxOpenFiles: 8192, CoreDumps: domain.CoreDumpsDisabled}
+	if merged.Limits == nil || *merged.Limits != want {
+		t.Errorf("expected limits merged per field, got %+v", merged.Limits)
+	}
+	if global.Limits.MaxOpenFiles != 0 {
+		t.Errorf("merge modified a layer: %+v", global.Limits)
+	}
+
+	err := NewMemoryRepo().UpsertPolicy(context.Background(), &domain.SandboxPolicy{ID: "bad", Limits: &domain.ProcessLimits{CoreDumps: "sometimes"}})
+	if err == nil {
+		t.Error("expected invalid core dump policy to be rejected")
+	}
+}
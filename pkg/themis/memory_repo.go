@@ -2,8 +2,10 @@ package themis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
@@ -11,51 +13,52 @@ import (
 // MemoryRepo is an in-memory implementation of the Repository interface.
 type MemoryRepo struct {
 	mu      sync.RWMutex
-	byTplID map[domain.TemplateID]*domain.SandboxPolicy
+	byScope map[Scope]*domain.SandboxPolicy
+	deleted map[Scope]*domain.SandboxPolicy
 }
 
 // NewMemoryRepo creates a new in-memory policy repository.
 func NewMemoryRepo() *MemoryRepo {
 	return &MemoryRepo{
-		byTplID: make(map[domain.TemplateID]*domain.SandboxPolicy),
+		byScope: make(map[Scope]*domain.SandboxPolicy),
+		deleted: make(map[Scope]*domain.SandboxPolicy),
 	}
 }
 
 // GetPolicy retrieves a policy for the given template ID.
 // If no policy exists, it returns a default lockdown policy.
 func (r *MemoryRepo) GetPolicy(ctx context.Context, tplID domain.TemplateID) (*domain.SandboxPolicy, error) {
+	policy, err := r.GetScopedPolicy(ctx, TemplateScope(tplID))
+	if errors.Is(err, ErrPolicyNotFound) {
+		// Return default lockdown policy
+		return DefaultPolicy(tplID), nil
+	}
+	return policy, err
+}
+
+// GetScopedPolicy retrieves the policy stored at exactly scope.
+func (r *MemoryRepo) GetScopedPolicy(ctx context.Context, scope Scope) (*domain.SandboxPolicy, error) {
 	r.mu.RLock()
-	policy, exists := r.byTplID[tplID]
-	r.mu.RUnlock()
+	defer r.mu.RUnlock()
 
-	if exists {
-		return policy, nil
+	policy, exists := r.byScope[scope]
+	if !exists {
+		return nil, ErrPolicyNotFound
 	}
-
-	// Return default lockdown policy
-	return &domain.SandboxPolicy{
-		ID:         domain.PolicyID("lockdown-default"),
-		TemplateID: tplID,
-		Resources: domain.ResourceSpec{
-			CPU: 1000, // 1 CPU core (1000 milliCPU)
-			Mem: 128,  // 128 MB
-		},
-		NetworkPolicy: domain.NetworkPolicyRef{
-			ID:   "lockdown-no-net",
-			Name: "No Internet",
-		},
-		Tags: map[string]string{
-			"type": "default-lockdown",
-		},
-	}, nil
+	return policy, nil
 }
 
 // UpsertPolicy inserts or updates a policy in the repository.
 func (r *MemoryRepo) UpsertPolicy(ctx context.Context, p *domain.SandboxPolicy) error {
+	if err := ValidatePolicy(p); err != nil {
+		return err
+	}
+	scope := ScopeOf(p)
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	existing, exists := r.byTplID[p.TemplateID]
+	existing, exists := r.byScope[scope]
 	var currentVersion int64
 	if exists {
 		currentVersion = existing.Version
@@ -66,7 +69,7 @@ func (r *MemoryRepo) UpsertPolicy(ctx context.Context, p *domain.SandboxPolicy)
 	}
 
 	p.Version++
-	r.byTplID[p.TemplateID] = p
+	r.byScope[scope] = p
 	return nil
 }
 
@@ -75,10 +78,69 @@ func (r *MemoryRepo) ListPolicies(ctx context.Context) ([]*domain.SandboxPolicy,
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	policies := make([]*domain.SandboxPolicy, 0, len(r.byTplID))
-	for _, p := range r.byTplID {
+	policies := make([]*domain.SandboxPolicy, 0, len(r.byScope))
+	for _, p := range r.byScope {
 		policies = append(policies, p)
 	}
 
 	return policies, nil
 }
+
+// DeletePolicy moves the policy at scope to the trash.
+func (r *MemoryRepo) DeletePolicy(ctx context.Context, scope Scope, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	policy, exists := r.byScope[scope]
+	if !exists {
+		return ErrPolicyNotFound
+	}
+	deleted := *policy
+	deleted.DeletedAt = at
+	r.deleted[scope] = &deleted
+	delete(r.byScope, scope)
+	return nil
+}
+
+// RestorePolicy moves a deleted policy back from the trash.
+func (r *MemoryRepo) RestorePolicy(ctx context.Context, scope Scope) (*domain.SandboxPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted, exists := r.deleted[scope]
+	if !exists {
+		return nil, ErrPolicyNotFound
+	}
+	if _, taken := r.byScope[scope]; taken {
+		return nil, ErrPolicyExists
+	}
+	restored := *deleted
+	restored.DeletedAt = time.Time{}
+	r.byScope[scope] = &restored
+	delete(r.deleted, scope)
+	return &restored, nil
+}
+
+// PurgePolicy removes a deleted policy from the trash.
+func (r *MemoryRepo) PurgePolicy(ctx context.Context, scope Scope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deleted[scope]; !exists {
+		return ErrPolicyNotFound
+	}
+	delete(r.deleted, scope)
+	return nil
+}
+
+// ListDeletedPolicies returns the policies in the trash.
+func (r *MemoryRepo) ListDeletedPolicies(ctx context.Context) ([]*domain.SandboxPolicy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policies := make([]*domain.SandboxPolicy, 0, len(r.deleted))
+	for _, p := range r.deleted {
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
@@ -2,6 +2,7 @@ package themis
 
 import (
 	"context"
+	"time"
 
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
 )
@@ -9,9 +10,25 @@ import (
 // Repository manages sandbox policies.
 
 type Repository interface {
+	// GetPolicy returns the template-level policy, or the built-in default.
+	// Use Resolve for the effective policy including global and tenant layers.
 	GetPolicy(ctx context.Context, tplID domain.TemplateID) (*domain.SandboxPolicy, error)
+	// GetScopedPolicy returns the policy stored at exactly scope, or
+	// ErrPolicyNotFound.
+	GetScopedPolicy(ctx context.Context, scope Scope) (*domain.SandboxPolicy, error)
+	// UpsertPolicy stores p at the scope given by its TenantID and TemplateID.
 	UpsertPolicy(ctx context.Context, p *domain.SandboxPolicy) error
 	ListPolicies(ctx context.Context) ([]*domain.SandboxPolicy, error)
+
+	// DeletePolicy moves the policy at scope to the trash, stamping
+	// DeletedAt. Deleted policies no longer apply.
+	DeletePolicy(ctx context.Context, scope Scope, at time.Time) error
+	// RestorePolicy moves a deleted policy back, or returns ErrPolicyExists
+	// if another policy has since been stored at its scope.
+	RestorePolicy(ctx context.Context, scope Scope) (*domain.SandboxPolicy, error)
+	// PurgePolicy removes a deleted policy for good.
+	PurgePolicy(ctx context.Context, scope Scope) error
+	ListDeletedPolicies(ctx context.Context) ([]*domain.SandboxPolicy, error)
 }
 
 // Validator checks a request against policy.
@@ -19,3 +36,33 @@ type Repository interface {
 type Validator interface {
 	ValidateRequest(ctx context.Context, req *domain.SandboxRequest, p *domain.SandboxPolicy) error
 }
+
+// ValidatePolicy checks the policy's scope and settings, as repositories do
+// before storing it.
+func ValidatePolicy(p *domain.SandboxPolicy) error {
+	if err := ScopeOf(p).Validate(); err != nil {
+		return err
+	}
+	if err := p.Limits.Validate(); err != nil {
+		return err
+	}
+	if err := p.Swap.Validate(); err != nil {
+		return err
+	}
+	if err := p.Hooks.Validate(); err != nil {
+		return err
+	}
+	if err := p.Retry.Validate(); err != nil {
+		return err
+	}
+	if err := p.Premium.Validate(); err != nil {
+		return err
+	}
+	if err := p.Outputs.Validate(); err != nil {
+		return err
+	}
+	if err := p.Escalation.Validate(); err != nil {
+		return err
+	}
+	return p.Quota.Validate()
+}
@@ -37,27 +37,20 @@ func NewRedisRepo(addr string, db int, password string) (*RedisRepo, error) {
 // GetPolicy retrieves a policy for the given template ID.
 // If no policy exists, it returns a default lockdown policy.
 func (r *RedisRepo) GetPolicy(ctx context.Context, tplID domain.TemplateID) (*domain.SandboxPolicy, error) {
-	key := fmt.Sprintf("themis:policy:%s", tplID)
-	val, err := r.client.Get(ctx, key).Result()
+	policy, err := r.GetScopedPolicy(ctx, TemplateScope(tplID))
+	if errors.Is(err, ErrPolicyNotFound) {
+		// Return default lockdown policy
+		return DefaultPolicy(tplID), nil
+	}
+	return policy, err
+}
+
+// GetScopedPolicy retrieves the policy stored at exactly scope.
+func (r *RedisRepo) GetScopedPolicy(ctx context.Context, scope Scope) (*domain.SandboxPolicy, error) {
+	val, err := r.client.Get(ctx, policyKey(scope)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			// Return default lockdown policy
-			return &domain.SandboxPolicy{
-				ID:         domain.PolicyID("lockdown-default"),
-				TemplateID: tplID,
-				Resources: domain.ResourceSpec{
-					CPU: 1000, // 1 CPU core (1000 milliCPU)
-					Mem: 128,  // 128 MB
-				},
-				NetworkPolicy: domain.NetworkPolicyRef{
-					ID:   "lockdown-no-net",
-					Name: "No Internet",
-				},
-				Tags: map[string]string{
-					"type": "default-lockdown",
-				},
-				Version: 0,
-			}, nil
+			return nil, ErrPolicyNotFound
 		}
 		return nil, fmt.Errorf("failed to get policy: %w", err)
 	}
@@ -70,9 +63,21 @@ func (r *RedisRepo) GetPolicy(ctx context.Context, tplID domain.TemplateID) (*do
 	return &policy, nil
 }
 
+func policyKey(scope Scope) string {
+	return "themis:policy:" + scope.key()
+}
+
+func deletedPolicyKey(scope Scope) string {
+	return "themis:deleted:" + scope.key()
+}
+
 // UpsertPolicy inserts or updates a policy in the repository using optimistic locking.
 func (r *RedisRepo) UpsertPolicy(ctx context.Context, p *domain.SandboxPolicy) error {
-	key := fmt.Sprintf("themis:policy:%s", p.TemplateID)
+	if err := ValidatePolicy(p); err != nil {
+		return err
+	}
+	scope := ScopeOf(p)
+	key := policyKey(scope)
 
 	// Optimistic locking with WATCH
 	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
@@ -155,3 +160,122 @@ func (r *RedisRepo) ListPolicies(ctx context.Context) ([]*domain.SandboxPolicy,
 
 	return policies, nil
 }
+
+// DeletePolicy moves the policy at scope to the trash.
+func (r *RedisRepo) DeletePolicy(ctx context.Context, scope Scope, at time.Time) error {
+	key, trashKey := policyKey(scope), deletedPolicyKey(scope)
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return ErrPolicyNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var policy domain.SandboxPolicy
+		if err := json.Unmarshal([]byte(val), &policy); err != nil {
+			return err
+		}
+		policy.DeletedAt = at
+		data, err := json.Marshal(&policy)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, trashKey, data, 0)
+			pipe.Del(ctx, key)
+			return nil
+		})
+		return err
+	}, key, trashKey)
+	if err != nil && !errors.Is(err, ErrPolicyNotFound) {
+		return fmt.Errorf("failed to delete policy: %w", err)
+	}
+	return err
+}
+
+// RestorePolicy moves a deleted policy back from the trash.
+func (r *RedisRepo) RestorePolicy(ctx context.Context, scope Scope) (*domain.SandboxPolicy, error) {
+	key, trashKey := policyKey(scope), deletedPolicyKey(scope)
+	var policy domain.SandboxPolicy
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, trashKey).Result()
+		if errors.Is(err, redis.Nil) {
+			return ErrPolicyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		n, err := tx.Exists(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrPolicyExists
+		}
+
+		if err := json.Unmarshal([]byte(val), &policy); err != nil {
+			return err
+		}
+		policy.DeletedAt = time.Time{}
+		data, err := json.Marshal(&policy)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			pipe.Del(ctx, trashKey)
+			return nil
+		})
+		return err
+	}, key, trashKey)
+	if err != nil {
+		if errors.Is(err, ErrPolicyNotFound) || errors.Is(err, ErrPolicyExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to restore policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// PurgePolicy removes a deleted policy from the trash.
+func (r *RedisRepo) PurgePolicy(ctx context.Context, scope Scope) error {
+	n, err := r.client.Del(ctx, deletedPolicyKey(scope)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to purge policy: %w", err)
+	}
+	if n == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// ListDeletedPolicies returns the policies in the trash.
+func (r *RedisRepo) ListDeletedPolicies(ctx context.Context) ([]*domain.SandboxPolicy, error) {
+	var policies []*domain.SandboxPolicy
+	iter := r.client.Scan(ctx, 0, "themis:deleted:*", 0).Iterator()
+
+	for iter.Next(ctx) {
+		val, err := r.client.Get(ctx, iter.Val()).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get deleted policy key %s: %w", iter.Val(), err)
+		}
+
+		var p domain.SandboxPolicy
+		if err := json.Unmarshal([]byte(val), &p); err != nil {
+			continue
+		}
+		policies = append(policies, &p)
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan deleted policies: %w", err)
+	}
+	return policies, nil
+}
@@ -3,6 +3,7 @@ package themis
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/tartarus-sandbox/tartarus/pkg/domain"
@@ -99,3 +100,86 @@ func TestRedisRepo_List(t *testing.T) {
 		t.Errorf("Expected 2 policies, got %d", len(list))
 	}
 }
+
+func TestRedisRepo_DeleteRestorePurge(t *testing.T) {
+	s := miniredis.RunT(t)
+	repo, err := NewRedisRepo(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+
+	ctx := context.Background()
+	scope := TenantScope("acme")
+	if err := repo.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "pol-acme", TenantID: "acme"}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	if err := repo.DeletePolicy(ctx, scope, at); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := repo.GetScopedPolicy(ctx, scope); err != ErrPolicyNotFound {
+		t.Errorf("Expected deleted policy to be hidden, got %v", err)
+	}
+	if err := repo.DeletePolicy(ctx, scope, at); err != ErrPolicyNotFound {
+		t.Errorf("Expected ErrPolicyNotFound deleting twice, got %v", err)
+	}
+
+	deleted, err := repo.ListDeletedPolicies(ctx)
+	if err != nil {
+		t.Fatalf("ListDeleted failed: %v", err)
+	}
+	if len(deleted) != 1 || !deleted[0].DeletedAt.Equal(at) {
+		t.Fatalf("Expected one deleted policy stamped %v, got %+v", at, deleted)
+	}
+
+	// Restore refuses to overwrite a policy created since
+	if err := repo.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "pol-acme-2", TenantID: "acme"}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if _, err := repo.RestorePolicy(ctx, scope); err != ErrPolicyExists {
+		t.Errorf("Expected ErrPolicyExists, got %v", err)
+	}
+
+	if err := repo.PurgePolicy(ctx, scope); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if _, err := repo.RestorePolicy(ctx, scope); err != ErrPolicyNotFound {
+		t.Errorf("Expected purged policy to be gone, got %v", err)
+	}
+	if err := repo.PurgePolicy(ctx, scope); err != ErrPolicyNotFound {
+		t.Errorf("Expected ErrPolicyNotFound purging twice, got %v", err)
+	}
+}
+
+func TestRedisRepo_Restore(t *testing.T) {
+	s := miniredis.RunT(t)
+	repo, err := NewRedisRepo(s.Addr(), 0, "")
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+
+	ctx := context.Background()
+	scope := TemplateScope("tpl-1")
+	if err := repo.UpsertPolicy(ctx, &domain.SandboxPolicy{ID: "pol-1", TemplateID: "tpl-1"}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if err := repo.DeletePolicy(ctx, scope, time.Now()); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	restored, err := repo.RestorePolicy(ctx, scope)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if restored.ID != "pol-1" || !restored.DeletedAt.IsZero() {
+		t.Errorf("Unexpected restored policy: %+v", restored)
+	}
+	got, err := repo.GetPolicy(ctx, "tpl-1")
+	if err != nil || got.ID != "pol-1" {
+		t.Errorf("Expected restored policy to be served again, got %v, %v", got, err)
+	}
+	if deleted, _ := repo.ListDeletedPolicies(ctx); len(deleted) != 0 {
+		t.Errorf("Expected empty trash, got %d", len(deleted))
+	}
+}
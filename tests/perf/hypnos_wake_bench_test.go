@@ -77,7 +77,7 @@ type HypnosWakeTimings struct {
 
 // TestHypnosWakeFromSleepPerformance tests the wake-from-sleep performance.
 func TestHypnosWakeFromSleepPerformance(t *testing.T) {
-	metrics := hermes.NewPrometheusMetrics()
+	metrics := hermes.NewCardinalityGuard(hermes.NewPrometheusMetrics(), hermes.CardinalityConfig{})
 	harness := NewPerfHarness(metrics)
 	ctx := context.Background()
 
@@ -486,7 +486,7 @@ func BenchmarkHypnosWake(b *testing.B) {
 
 // TestHypnosHibernationResumeTraces captures detailed traces for hibernation/resume.
 func TestHypnosHibernationResumeTraces(t *testing.T) {
-	metrics := hermes.NewPrometheusMetrics()
+	metrics := hermes.NewCardinalityGuard(hermes.NewPrometheusMetrics(), hermes.CardinalityConfig{})
 	harness := NewPerfHarness(metrics)
 	ctx := context.Background()
 